@@ -4,8 +4,9 @@ package assets
 import "embed"
 
 const (
-	MySQLMigrationDir    = "migrations/mysql"
-	PostgresMigrationDir = "migrations/postgres"
+	MySQLMigrationDir     = "migrations/mysql"
+	PostgresMigrationDir  = "migrations/postgres"
+	CassandraMigrationDir = "migrations/cassandra"
 )
 
 //go:embed migrations/*
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE tuple ADD COLUMN condition_expression TEXT NULL;
+ALTER TABLE tuple ADD COLUMN condition_parameters TEXT NULL;
+
+-- +goose Down
+ALTER TABLE tuple DROP COLUMN condition_parameters;
+ALTER TABLE tuple DROP COLUMN condition_expression;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE authorization_model ADD COLUMN annotations TEXT NULL;
+
+-- +goose Down
+ALTER TABLE authorization_model DROP COLUMN annotations;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX idx_changelog_inserted_at ON changelog (inserted_at);
+
+-- +goose Down
+DROP INDEX idx_changelog_inserted_at ON changelog;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE staged_write (
+    store CHAR(26) NOT NULL,
+    id CHAR(26) NOT NULL,
+    operation INTEGER NOT NULL,
+    object_type VARCHAR(256) NOT NULL,
+    object_id VARCHAR(256) NOT NULL,
+    relation VARCHAR(50) NOT NULL,
+    _user VARCHAR(512) NOT NULL,
+    staged_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_staged_write_store_id ON staged_write (store, id);
+
+-- +goose Down
+DROP TABLE staged_write;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE pinned_authorization_model (
+    store CHAR(26) NOT NULL,
+    authorization_model_id CHAR(26) NOT NULL,
+    pinned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (store)
+);
+
+-- +goose Down
+DROP TABLE pinned_authorization_model;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE tuple ADD COLUMN condition_expression TEXT;
+ALTER TABLE tuple ADD COLUMN condition_parameters TEXT;
+CREATE INDEX idx_tuple_condition ON tuple (store, object_type, relation) WHERE condition_expression IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tuple_condition;
+ALTER TABLE tuple DROP COLUMN condition_parameters;
+ALTER TABLE tuple DROP COLUMN condition_expression;
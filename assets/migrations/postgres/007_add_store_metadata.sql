@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE store ADD COLUMN description TEXT;
+
+CREATE TABLE store_label (
+	store TEXT NOT NULL,
+	label_key TEXT NOT NULL,
+	label_value TEXT NOT NULL,
+	PRIMARY KEY (store, label_key)
+);
+
+CREATE INDEX idx_store_label_key_value ON store_label (label_key, label_value);
+
+-- +goose Down
+DROP TABLE store_label;
+
+ALTER TABLE store DROP COLUMN description;
@@ -0,0 +1,6 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_reverse_lookup_user_covering ON tuple (store, object_type, relation, _user) INCLUDE (object_id, ulid, inserted_at, condition_expression, condition_parameters, expires_at);
+
+-- +goose Down
+DROP INDEX CONCURRENTLY IF EXISTS idx_reverse_lookup_user_covering;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE staged_write (
+	store TEXT NOT NULL,
+	id TEXT NOT NULL,
+	operation INTEGER NOT NULL,
+	object_type TEXT NOT NULL,
+	object_id TEXT NOT NULL,
+	relation TEXT NOT NULL,
+	_user TEXT NOT NULL,
+	staged_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_staged_write_store_id ON staged_write (store, id);
+
+-- +goose Down
+DROP TABLE staged_write;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE pinned_authorization_model (
+	store TEXT NOT NULL,
+	authorization_model_id TEXT NOT NULL,
+	pinned_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (store)
+);
+
+-- +goose Down
+DROP TABLE pinned_authorization_model;
@@ -0,0 +1,6 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+DROP INDEX CONCURRENTLY IF EXISTS idx_reverse_lookup_user;
+
+-- +goose Down
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_reverse_lookup_user ON tuple (store, object_type, relation, _user);
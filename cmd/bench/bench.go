@@ -0,0 +1,139 @@
+// Package bench contains the command to load test a datastore with the Check and ListObjects requests of a
+// synthetic store.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	datastoreEngineFlag  = "datastore-engine"
+	datastoreURIFlag     = "datastore-uri"
+	usersFlag            = "users"
+	groupsFlag           = "groups"
+	groupSizeFlag        = "group-size"
+	nestingDepthFlag     = "nesting-depth"
+	fanOutFlag           = "fan-out"
+	seedFlag             = "seed"
+	qpsFlag              = "qps"
+	durationFlag         = "duration"
+	concurrencyFlag      = "concurrency"
+	listObjectsRatioFlag = "list-objects-ratio"
+
+	defaultDuration = 30 * time.Second
+)
+
+func NewBenchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Load test a datastore with the Check and ListObjects requests of a synthetic store",
+		Long: `The bench command generates a synthetic store of the given shape directly in the datastore, then sends random Check and ListObjects requests for the viewers of its documents at the target QPS to an in-process server with the default settings, and reports the latency percentiles of every method.
+In the synthetic store, the users are members of groups nested in chains of --nesting-depth groups, and the viewers of a tree of folders --nesting-depth levels deep, with --fan-out subfolders per folder and --fan-out documents per folder of the last level, are the viewers of the documents. The same shape and seed always generate the same store.
+The synthetic store is left in the datastore. The datastore is configured like the server, by the config file, the environment or the flags below, and defaults to the in-memory datastore.`,
+		RunE:         runBench,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(datastoreEngineFlag, run.DefaultConfig().Datastore.Engine, "the datastore engine")
+	flags.String(datastoreURIFlag, "", "the connection uri to the datastore")
+	flags.Int(usersFlag, 1000, "the number of users of the synthetic store")
+	flags.Int(groupsFlag, 100, "the number of groups of the synthetic store")
+	flags.Int(groupSizeFlag, 20, "the number of users directly in each group")
+	flags.Int(nestingDepthFlag, 3, "the number of groups nested in each chain of groups, and the number of levels of the folder tree")
+	flags.Int(fanOutFlag, 10, "the number of subfolders of each folder, and of documents in each folder of the last level")
+	flags.Int64(seedFlag, 1, "the seed of the random assignments of the users and viewers, and of the random requests")
+	flags.Float64(qpsFlag, 100, "the target number of requests per second")
+	flags.Duration(durationFlag, defaultDuration, "how long the requests are sent for")
+	flags.Int(concurrencyFlag, 100, "the maximum number of requests in flight. The requests due while as many are in flight are skipped")
+	flags.Float64(listObjectsRatioFlag, 0.1, "the fraction of the requests which are ListObjects, the others being Checks")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runBench(cmd *cobra.Command, _ []string) error {
+	shape := Shape{
+		Users:        viper.GetInt(usersFlag),
+		Groups:       viper.GetInt(groupsFlag),
+		GroupSize:    viper.GetInt(groupSizeFlag),
+		NestingDepth: viper.GetInt(nestingDepthFlag),
+		FanOut:       viper.GetInt(fanOutFlag),
+		Seed:         viper.GetInt64(seedFlag),
+	}
+	loadOpts := LoadOptions{
+		QPS:              viper.GetFloat64(qpsFlag),
+		Duration:         viper.GetDuration(durationFlag),
+		Concurrency:      viper.GetInt(concurrencyFlag),
+		ListObjectsRatio: viper.GetFloat64(listObjectsRatioFlag),
+	}
+
+	if err := shape.validate(); err != nil {
+		return err
+	}
+
+	if err := loadOpts.validate(); err != nil {
+		return err
+	}
+
+	config, err := run.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := run.VerifyConfig(config); err != nil {
+		return err
+	}
+
+	datastore, err := run.NewDatastore(config, logger.NewNoopLogger())
+	if err != nil {
+		return err
+	}
+	defer datastore.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := cmd.OutOrStdout()
+
+	fmt.Fprintf(out, "generating a synthetic store with %d users, %d groups, %d folders and %d documents\n",
+		shape.Users, shape.Groups, shape.Folders(), shape.Documents())
+
+	store, err := Generate(ctx, datastore, shape)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "generated store %s with %d tuples\n", store.StoreID, store.Tuples)
+
+	s, err := server.NewServerWithOpts(server.WithDatastore(datastore))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	fmt.Fprintf(out, "sending %.1f requests per second for %s\n", loadOpts.QPS, loadOpts.Duration)
+
+	report, err := Load(ctx, s, store, loadOpts)
+	if err != nil {
+		return err
+	}
+
+	return report.Write(out)
+}
@@ -0,0 +1,109 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	shape := Shape{Users: 10, Groups: 4, GroupSize: 3, NestingDepth: 2, FanOut: 3, Seed: 1}
+	require.Equal(t, 4, shape.Folders())
+	require.Equal(t, 9, shape.Documents())
+
+	store, err := Generate(ctx, ds, shape)
+	require.NoError(t, err)
+
+	// 4*3 group members and 2 nested groups, 3 parents and 4 viewers of folders, 9 parents and 9 viewers of documents
+	require.Equal(t, 12+2+3+4+9+9, store.Tuples)
+
+	s := server.MustNewServerWithOpts(server.WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	resp, err := s.Read(ctx, &openfgav1.ReadRequest{
+		StoreId:  store.StoreID,
+		TupleKey: tuple.NewTupleKey("document:8", "parent", ""),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetTuples(), 1)
+	require.Equal(t, "folder:3", resp.GetTuples()[0].GetKey().GetUser())
+
+	resp, err = s.Read(ctx, &openfgav1.ReadRequest{
+		StoreId:  store.StoreID,
+		TupleKey: tuple.NewTupleKey("group:1", "member", ""),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetTuples(), 4)
+
+	// the same shape generates the same tuples
+	other, err := Generate(ctx, ds, shape)
+	require.NoError(t, err)
+
+	otherResp, err := s.Read(ctx, &openfgav1.ReadRequest{
+		StoreId:  other.StoreID,
+		TupleKey: tuple.NewTupleKey("group:1", "member", ""),
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, tupleStrings(resp.GetTuples()), tupleStrings(otherResp.GetTuples()))
+
+	_, err = Generate(ctx, ds, Shape{Users: 2, Groups: 1, GroupSize: 3, NestingDepth: 1, FanOut: 1})
+	require.EqualError(t, err, "the group size cannot be greater than the number of users")
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	store, err := Generate(ctx, ds, Shape{Users: 10, Groups: 4, GroupSize: 3, NestingDepth: 2, FanOut: 3, Seed: 1})
+	require.NoError(t, err)
+
+	s := server.MustNewServerWithOpts(server.WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	report, err := Load(ctx, s, store, LoadOptions{
+		QPS:              200,
+		Duration:         500 * time.Millisecond,
+		Concurrency:      10,
+		ListObjectsRatio: 0.5,
+	})
+	require.NoError(t, err)
+
+	check, listObjects := report.Methods[methodCheck], report.Methods[methodListObjects]
+	require.Positive(t, check.Requests)
+	require.Positive(t, listObjects.Requests)
+	require.Zero(t, check.Errors)
+	require.Zero(t, listObjects.Errors)
+	require.Positive(t, check.P50)
+	require.LessOrEqual(t, check.P50, check.P90)
+	require.LessOrEqual(t, check.P99, check.Max)
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	require.Contains(t, out.String(), "ListObjects")
+
+	_, err = Load(ctx, s, store, LoadOptions{QPS: 1, Duration: time.Second, Concurrency: 1, ListObjectsRatio: 2})
+	require.EqualError(t, err, "the ListObjects ratio must be between 0 and 1")
+}
+
+func tupleStrings(tuples []*openfgav1.Tuple) []string {
+	strings := make([]string, 0, len(tuples))
+	for _, t := range tuples {
+		strings = append(strings, tuple.TupleKeyToString(t.GetKey()))
+	}
+
+	return strings
+}
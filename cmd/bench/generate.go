@@ -0,0 +1,222 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// syntheticModel is the authorization model of the synthetic stores: the users are members of groups, the groups
+// are nested, and the viewers of the folders are the viewers of the documents and subfolders they hold.
+const syntheticModel = `
+type user
+
+type group
+  relations
+    define member: [user, group#member] as self
+
+type folder
+  relations
+    define parent: [folder] as self
+    define viewer: [user, group#member] as self or viewer from parent
+
+type document
+  relations
+    define parent: [folder] as self
+    define viewer: [user, group#member] as self or viewer from parent
+`
+
+// Shape is the shape of a synthetic store.
+type Shape struct {
+	// Users is the number of users.
+	Users int
+
+	// Groups is the number of groups, and GroupSize the number of users directly in each group.
+	Groups    int
+	GroupSize int
+
+	// NestingDepth is the number of groups nested in each chain of groups, and the number of levels of the folder
+	// tree.
+	NestingDepth int
+
+	// FanOut is the number of subfolders of each folder, and of documents in each folder of the last level.
+	FanOut int
+
+	// Seed seeds the random assignment of the users to the groups and of the viewers to the folders and documents,
+	// so that a shape always generates the same store.
+	Seed int64
+}
+
+// Folders returns the number of folders of the folder tree.
+func (s Shape) Folders() int {
+	folders := 0
+	for level := 0; level < s.NestingDepth; level++ {
+		folders += pow(s.FanOut, level)
+	}
+
+	return folders
+}
+
+// Documents returns the number of documents, in the folders of the last level of the folder tree.
+func (s Shape) Documents() int {
+	return pow(s.FanOut, s.NestingDepth)
+}
+
+func (s Shape) validate() error {
+	if s.Users <= 0 || s.Groups <= 0 || s.GroupSize <= 0 || s.NestingDepth <= 0 || s.FanOut <= 0 {
+		return errors.New("the users, groups, group size, nesting depth and fan-out must be greater than 0")
+	}
+
+	if s.GroupSize > s.Users {
+		return errors.New("the group size cannot be greater than the number of users")
+	}
+
+	return nil
+}
+
+// SyntheticStore is a store generated by Generate.
+type SyntheticStore struct {
+	StoreID              string
+	AuthorizationModelID string
+	Shape                Shape
+	Tuples               int
+}
+
+// Generate writes a synthetic store of the shape directly to the datastore: its authorization model, see
+// syntheticModel, and its tuples, in batches of at most the MaxTuplesPerWrite of the datastore.
+func Generate(ctx context.Context, ds storage.OpenFGADatastore, shape Shape) (*SyntheticStore, error) {
+	if err := shape.validate(); err != nil {
+		return nil, err
+	}
+
+	model, err := typesystem.ParseDSL(syntheticModel)
+	if err != nil {
+		return nil, err
+	}
+	model.Id = ulid.Make().String()
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{
+		Id:   ulid.Make().String(),
+		Name: fmt.Sprintf("bench-%d-%d-%d-%d-%d", shape.Users, shape.Groups, shape.GroupSize, shape.NestingDepth, shape.FanOut),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the store: %w", err)
+	}
+
+	if err := ds.WriteAuthorizationModel(ctx, store.GetId(), model); err != nil {
+		return nil, fmt.Errorf("failed to write the authorization model: %w", err)
+	}
+
+	synthetic := &SyntheticStore{
+		StoreID:              store.GetId(),
+		AuthorizationModelID: model.GetId(),
+		Shape:                shape,
+	}
+
+	batch := make([]*openfgav1.TupleKey, 0, ds.MaxTuplesPerWrite())
+	write := func(tk *openfgav1.TupleKey) error {
+		batch = append(batch, tk)
+		if len(batch) < ds.MaxTuplesPerWrite() {
+			return nil
+		}
+
+		return flush(ctx, ds, synthetic, &batch)
+	}
+
+	random := rand.New(rand.NewSource(shape.Seed))
+
+	for g := 0; g < shape.Groups; g++ {
+		for _, u := range random.Perm(shape.Users)[:shape.GroupSize] {
+			if err := write(tuple.NewTupleKey(groupObject(g), "member", userObject(u))); err != nil {
+				return nil, err
+			}
+		}
+
+		// the members of the previous group of the chain are members of the group
+		if g%shape.NestingDepth != 0 {
+			if err := write(tuple.NewTupleKey(groupObject(g), "member", groupObject(g-1)+"#member")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the folders are numbered level by level, so that the subfolders of folder f are f*FanOut+1 to f*FanOut+FanOut
+	folders := shape.Folders()
+	for f := 0; f < folders; f++ {
+		if f > 0 {
+			if err := write(tuple.NewTupleKey(folderObject(f), "parent", folderObject((f-1)/shape.FanOut))); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := write(tuple.NewTupleKey(folderObject(f), "viewer", groupObject(random.Intn(shape.Groups))+"#member")); err != nil {
+			return nil, err
+		}
+	}
+
+	// the folders of the last level are the last ones
+	firstLeaf := folders - pow(shape.FanOut, shape.NestingDepth-1)
+	for d := 0; d < shape.Documents(); d++ {
+		if err := write(tuple.NewTupleKey(documentObject(d), "parent", folderObject(firstLeaf+d/shape.FanOut))); err != nil {
+			return nil, err
+		}
+
+		if err := write(tuple.NewTupleKey(documentObject(d), "viewer", userObject(random.Intn(shape.Users)))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flush(ctx, ds, synthetic, &batch); err != nil {
+		return nil, err
+	}
+
+	return synthetic, nil
+}
+
+func flush(ctx context.Context, ds storage.OpenFGADatastore, synthetic *SyntheticStore, batch *[]*openfgav1.TupleKey) error {
+	if len(*batch) == 0 {
+		return nil
+	}
+
+	if err := ds.Write(ctx, synthetic.StoreID, nil, *batch); err != nil {
+		return fmt.Errorf("failed to write the tuples: %w", err)
+	}
+
+	synthetic.Tuples += len(*batch)
+	*batch = (*batch)[:0]
+
+	return nil
+}
+
+func pow(base, exponent int) int {
+	result := 1
+	for i := 0; i < exponent; i++ {
+		result *= base
+	}
+
+	return result
+}
+
+func userObject(u int) string {
+	return "user:" + strconv.Itoa(u)
+}
+
+func groupObject(g int) string {
+	return "group:" + strconv.Itoa(g)
+}
+
+func folderObject(f int) string {
+	return "folder:" + strconv.Itoa(f)
+}
+
+func documentObject(d int) string {
+	return "document:" + strconv.Itoa(d)
+}
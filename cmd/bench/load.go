@@ -0,0 +1,215 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+const (
+	methodCheck       = "Check"
+	methodListObjects = "ListObjects"
+)
+
+// LoadOptions define the load driven against a synthetic store.
+type LoadOptions struct {
+	// QPS is the target number of requests sent per second.
+	QPS float64
+
+	// Duration is how long the load is driven for.
+	Duration time.Duration
+
+	// Concurrency is the maximum number of requests in flight. The requests due while as many are in flight are
+	// skipped, and reported as such, since the target QPS is not sustained.
+	Concurrency int
+
+	// ListObjectsRatio is the fraction of the requests which are ListObjects, the others being Checks.
+	ListObjectsRatio float64
+}
+
+func (o LoadOptions) validate() error {
+	if o.QPS <= 0 || o.Duration <= 0 || o.Concurrency <= 0 {
+		return errors.New("the QPS, duration and concurrency must be greater than 0")
+	}
+
+	if o.ListObjectsRatio < 0 || o.ListObjectsRatio > 1 {
+		return errors.New("the ListObjects ratio must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Report is the outcome of a load.
+type Report struct {
+	Elapsed time.Duration
+
+	// Skipped is the number of requests which were not sent because too many were in flight.
+	Skipped int
+
+	// Methods are the reports of the requests of every method, keyed by method.
+	Methods map[string]*MethodReport
+}
+
+// MethodReport is the outcome of the requests of a method.
+type MethodReport struct {
+	Requests int
+	Errors   int
+
+	// The percentiles of the latencies of the successful requests.
+	P50, P90, P99, Max time.Duration
+
+	latencies []time.Duration
+}
+
+// Load sends random Check and ListObjects requests for the viewers of the documents of the synthetic store to the
+// server, at the target QPS, until the duration elapses or the context is cancelled, and reports their latencies.
+func Load(ctx context.Context, s *server.Server, store *SyntheticStore, opts LoadOptions) (*Report, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	report := &Report{Methods: map[string]*MethodReport{
+		methodCheck:       {},
+		methodListObjects: {},
+	}}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	inFlight := make(chan struct{}, opts.Concurrency)
+
+	random := rand.New(rand.NewSource(store.Shape.Seed))
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.QPS))
+	defer ticker.Stop()
+
+	start := time.Now()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			report.Skipped++
+			continue
+		}
+
+		method := methodCheck
+		if random.Float64() < opts.ListObjectsRatio {
+			method = methodListObjects
+		}
+		user := userObject(random.Intn(store.Shape.Users))
+		document := documentObject(random.Intn(store.Shape.Documents()))
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+
+			// the requests in flight when the duration elapses are not cut short
+			requestStart := time.Now()
+			err := send(context.Background(), s, store, method, document, user)
+			latency := time.Since(requestStart)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			methodReport := report.Methods[method]
+			methodReport.Requests++
+			if err != nil {
+				methodReport.Errors++
+				return
+			}
+			methodReport.latencies = append(methodReport.latencies, latency)
+		}()
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+
+	for _, methodReport := range report.Methods {
+		methodReport.summarize()
+	}
+
+	return report, nil
+}
+
+func send(ctx context.Context, s *server.Server, store *SyntheticStore, method, document, user string) error {
+	if method == methodListObjects {
+		_, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              store.StoreID,
+			AuthorizationModelId: store.AuthorizationModelID,
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 user,
+		})
+		return err
+	}
+
+	_, err := s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              store.StoreID,
+		AuthorizationModelId: store.AuthorizationModelID,
+		TupleKey:             tuple.NewTupleKey(document, "viewer", user),
+	})
+	return err
+}
+
+func (r *MethodReport) summarize() {
+	if len(r.latencies) == 0 {
+		return
+	}
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+
+	percentile := func(p float64) time.Duration {
+		return r.latencies[int(math.Ceil(p*float64(len(r.latencies))))-1]
+	}
+
+	r.P50 = percentile(0.5)
+	r.P90 = percentile(0.9)
+	r.P99 = percentile(0.99)
+	r.Max = r.latencies[len(r.latencies)-1]
+}
+
+// Write writes the report as a table.
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "METHOD\tREQUESTS\tERRORS\tQPS\tP50\tP90\tP99\tMAX")
+	for _, method := range []string{methodCheck, methodListObjects} {
+		m := r.Methods[method]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n",
+			method, m.Requests, m.Errors, float64(m.Requests)/r.Elapsed.Seconds(), m.P50, m.P90, m.P99, m.Max)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.Skipped > 0 {
+		_, err := fmt.Fprintf(w, "%d requests were skipped because too many were in flight: the target QPS was not sustained\n", r.Skipped)
+		return err
+	}
+
+	return nil
+}
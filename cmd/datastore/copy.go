@@ -0,0 +1,517 @@
+// Package datastore contains the commands operating on the datastores, such as copying the stores of a datastore
+// to a datastore of another engine.
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/sharded"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	sourceEngineFlag = "source-engine"
+	sourceURIFlag    = "source-uri"
+	targetEngineFlag = "target-engine"
+	targetURIFlag    = "target-uri"
+	storeIDsFlag     = "store-ids"
+	stateFileFlag    = "state-file"
+	verifyFlag       = "verify"
+
+	// changelogPageSize is the number of changes read at once from the changelog of the source.
+	changelogPageSize = 100
+)
+
+// errCopyMismatch makes the command exit with a non-zero status once the mismatches are reported.
+var errCopyMismatch = errors.New("the stores of the source and the target datastores do not match")
+
+func NewDatastoreCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "datastore",
+		Short: "Operate on the datastores",
+	}
+
+	cmd.AddCommand(newCopyCommand())
+
+	return cmd
+}
+
+func newCopyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "copy",
+		Short: "Copy the stores of a datastore to a datastore of another engine, e.g. from MySQL to Postgres",
+		Long: `The copy command copies the stores of the source datastore to the target datastore, whose schema must be migrated: the stores with their metadata, their authorization models with their annotations and assertions, and their tuples with their conditions and expiration times.
+Every store is copied once. The command records in the state file where the changelog of the source ended when each store was copied, and later runs catch up with the changes of the copied stores since: the tuples written and deleted, the authorization models written, deleted and pinned, and the stores deleted. The changelog of the target starts with the writes of the copied tuples, and continues with the changes caught up.
+To move the servers to the target datastore without downtime, copy the stores and catch up while the servers are running on the source, then stop the writes, catch up one last time with --verify, and start the servers on the target.`,
+		RunE:         runCopy,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(sourceEngineFlag, "", "the engine of the datastore the stores are copied from")
+	flags.String(sourceURIFlag, "", "the connection uri of the datastore the stores are copied from")
+	flags.String(targetEngineFlag, "", "the engine of the datastore the stores are copied to")
+	flags.String(targetURIFlag, "", "the connection uri of the datastore the stores are copied to")
+	flags.StringSlice(storeIDsFlag, nil, "the stores to copy, or empty to copy every store")
+	flags.String(stateFileFlag, "datastore-copy.json", "the file recording the progress of the copy, from which later runs catch up")
+	flags.Bool(verifyFlag, false, "compare the number of tuples per object type and relation, and the number of authorization models, of every store of the source and of its copy")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func newDatastore(engine, uri string) (storage.OpenFGADatastore, error) {
+	if engine == "" {
+		return nil, errors.New("missing datastore engine")
+	}
+
+	config := run.DefaultConfig()
+	config.Datastore.Engine = engine
+	config.Datastore.URI = uri
+
+	return run.NewDatastore(config, logger.NewNoopLogger())
+}
+
+func runCopy(_ *cobra.Command, _ []string) error {
+	src, err := newDatastore(viper.GetString(sourceEngineFlag), viper.GetString(sourceURIFlag))
+	if err != nil {
+		return fmt.Errorf("failed to open the source datastore: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := newDatastore(viper.GetString(targetEngineFlag), viper.GetString(targetURIFlag))
+	if err != nil {
+		return fmt.Errorf("failed to open the target datastore: %w", err)
+	}
+	defer dst.Close()
+
+	statePath := viper.GetString(stateFileFlag)
+	state, err := readCopyState(statePath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if err := Copy(ctx, src, dst, viper.GetStringSlice(storeIDsFlag), state, func() error {
+		return writeCopyState(statePath, state)
+	}); err != nil {
+		return err
+	}
+
+	if !viper.GetBool(verifyFlag) {
+		return nil
+	}
+
+	mismatches, err := Verify(ctx, src, dst, state)
+	if err != nil {
+		return err
+	}
+	for _, mismatch := range mismatches {
+		log.Println(mismatch)
+	}
+	if len(mismatches) > 0 {
+		return errCopyMismatch
+	}
+	log.Printf("verified %d stores", len(state.Stores))
+
+	return nil
+}
+
+// CopyState is the progress of a copy, keyed by store.
+type CopyState struct {
+	Stores map[string]*StoreCopyState `json:"stores"`
+}
+
+// StoreCopyState is the progress of the copy of a store.
+type StoreCopyState struct {
+	// ChangelogToken is where the changelog of the source was caught up to, a continuation token of ReadChanges.
+	ChangelogToken string `json:"changelog_token"`
+
+	// Deleted is set once the deletion of the store from the source was caught up.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+func readCopyState(path string) (*CopyState, error) {
+	state := &CopyState{Stores: map[string]*StoreCopyState{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read the state of the copy: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to read the state of the copy: %w", err)
+	}
+	if state.Stores == nil {
+		state.Stores = map[string]*StoreCopyState{}
+	}
+
+	return state, nil
+}
+
+func writeCopyState(path string, state *CopyState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the state is replaced atomically, so that it is never lost if the copy is interrupted
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to record the state of the copy: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Copy copies the stores of `src` which are not in the state yet to `dst`, and catches up the stores of the state
+// with the changes of `src` since they were copied or last caught up, see StoreCopyState. If storeIDs is empty,
+// every store of `src` is copied. The state is saved after every store.
+func Copy(ctx context.Context, src, dst storage.OpenFGADatastore, storeIDs []string, state *CopyState, save func() error) error {
+	if len(storeIDs) == 0 {
+		stores, err := listStoreIDs(ctx, src)
+		if err != nil {
+			return fmt.Errorf("failed to list the stores of the source datastore: %w", err)
+		}
+
+		// the stores copied before and deleted since are caught up too
+		known := map[string]struct{}{}
+		for _, id := range stores {
+			known[id] = struct{}{}
+		}
+		for id := range state.Stores {
+			if _, ok := known[id]; !ok {
+				stores = append(stores, id)
+			}
+		}
+		sort.Strings(stores)
+
+		storeIDs = stores
+	}
+
+	copied, caughtUp := 0, 0
+	for _, id := range storeIDs {
+		storeState, ok := state.Stores[id]
+		if ok {
+			if storeState.Deleted {
+				continue
+			}
+
+			changes, err := catchUpStore(ctx, src, dst, id, storeState)
+			if err != nil {
+				return fmt.Errorf("failed to catch up the store '%s': %w", id, err)
+			}
+			if changes > 0 {
+				log.Printf("caught up store '%s' with %d changes", id, changes)
+			}
+			caughtUp++
+		} else {
+			// the changes made while the store is copied are caught up from where the changelog ends before
+			token, err := changelogEnd(ctx, src, id, "")
+			if err != nil {
+				return fmt.Errorf("failed to read the changelog of the store '%s': %w", id, err)
+			}
+
+			if err := sharded.CopyStore(ctx, src, dst, id); err != nil {
+				return fmt.Errorf("failed to copy the store '%s': %w", id, err)
+			}
+			log.Printf("copied store '%s'", id)
+
+			state.Stores[id] = &StoreCopyState{ChangelogToken: token}
+			copied++
+		}
+
+		if err := save(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("%d stores copied, %d stores caught up", copied, caughtUp)
+
+	return nil
+}
+
+func listStoreIDs(ctx context.Context, ds storage.OpenFGADatastore) ([]string, error) {
+	var ids []string
+	opts := storage.PaginationOptions{PageSize: storage.DefaultPageSize}
+	for {
+		stores, contToken, err := ds.ListStores(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			ids = append(ids, store.GetId())
+		}
+
+		if len(contToken) == 0 {
+			return ids, nil
+		}
+		opts.From = string(contToken)
+	}
+}
+
+// changelogEnd returns the continuation token of ReadChanges at the end of the changelog of the store.
+func changelogEnd(ctx context.Context, ds storage.OpenFGADatastore, store, from string) (string, error) {
+	for {
+		changes, token, err := ds.ReadChanges(ctx, store, "", storage.PaginationOptions{PageSize: changelogPageSize, From: from}, 0)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return from, nil
+			}
+			return "", err
+		}
+
+		from = string(token)
+		if len(changes) < changelogPageSize {
+			return from, nil
+		}
+	}
+}
+
+// catchUpStore applies the changes of the store in `src` since the changelog token of the state to its copy in
+// `dst`, syncs its authorization models, and deletes the copy if the store was deleted. It returns the number of
+// tuple changes applied. The changes are applied idempotently, so the changes made while the store was copied are
+// applied again harmlessly.
+func catchUpStore(ctx context.Context, src, dst storage.OpenFGADatastore, store string, state *StoreCopyState) (int, error) {
+	if _, err := src.GetStore(ctx, store); err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return 0, err
+		}
+
+		if err := dst.DeleteStore(ctx, store); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return 0, err
+		}
+		log.Printf("deleted store '%s'", store)
+		state.Deleted = true
+
+		return 0, nil
+	}
+
+	if err := syncModels(ctx, src, dst, store); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for {
+		changes, token, err := src.ReadChanges(ctx, store, "", storage.PaginationOptions{PageSize: changelogPageSize, From: state.ChangelogToken}, 0)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return applied, nil
+			}
+			return applied, err
+		}
+
+		for _, change := range changes {
+			if err := applyChange(ctx, src, dst, store, change); err != nil {
+				return applied, err
+			}
+			applied++
+		}
+
+		state.ChangelogToken = string(token)
+		if len(changes) < changelogPageSize {
+			return applied, nil
+		}
+	}
+}
+
+// applyChange writes or deletes the tuple of the change in `dst`. A tuple written with a condition or an expiration
+// time which it still has in `src` is written with it.
+func applyChange(ctx context.Context, src, dst storage.OpenFGADatastore, store string, change *openfgav1.TupleChange) error {
+	tk := tupleUtils.NewTupleKey(change.GetTupleKey().GetObject(), change.GetTupleKey().GetRelation(), change.GetTupleKey().GetUser())
+
+	if change.GetOperation() == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+		return dst.Write(ctx, store, storage.Deletes{tk}, nil, storage.WithOnMissingDelete(storage.OnMissingDeleteIgnore))
+	}
+
+	ignoreDuplicate := storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore)
+	key := tupleUtils.TupleKeyToString(tk)
+
+	conditions, err := src.ReadTupleConditions(ctx, store, tk)
+	if err != nil {
+		return err
+	}
+	if condition, ok := conditions[key]; ok {
+		return dst.WriteWithCondition(ctx, store, nil, storage.Writes{tk}, condition, ignoreDuplicate)
+	}
+
+	expirations, err := src.ReadTupleExpirations(ctx, store, tk)
+	if err != nil {
+		return err
+	}
+	if expiresAt, ok := expirations[key]; ok {
+		return dst.WriteWithExpiry(ctx, store, nil, storage.Writes{tk}, expiresAt, ignoreDuplicate)
+	}
+
+	return dst.Write(ctx, store, nil, storage.Writes{tk}, ignoreDuplicate)
+}
+
+// syncModels writes the authorization models of the store written in `src` since it was copied to `dst`, deletes
+// the models deleted from `src`, and pins the model pinned in `src`.
+func syncModels(ctx context.Context, src, dst storage.OpenFGADatastore, store string) error {
+	srcModels, err := readModels(ctx, src, store)
+	if err != nil {
+		return err
+	}
+
+	dstModels, err := readModels(ctx, dst, store)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(srcModels))
+	for id := range srcModels {
+		ids = append(ids, id)
+	}
+	// the model IDs are ULIDs, so the latest model of the copy is the latest model of the store
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if _, ok := dstModels[id]; ok {
+			continue
+		}
+
+		annotations, err := src.ReadAuthorizationModelAnnotations(ctx, store, id)
+		if err != nil {
+			return err
+		}
+		if len(annotations) > 0 {
+			err = dst.WriteAuthorizationModelWithAnnotations(ctx, store, srcModels[id], annotations)
+		} else {
+			err = dst.WriteAuthorizationModel(ctx, store, srcModels[id])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write the authorization model '%s': %w", id, err)
+		}
+	}
+
+	// the assertions are replaced on every write, so they are synced for every model
+	for _, id := range ids {
+		assertions, err := src.ReadAssertions(ctx, store, id)
+		if err != nil {
+			return err
+		}
+		if len(assertions) > 0 {
+			if err := dst.WriteAssertions(ctx, store, id, assertions); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id := range dstModels {
+		if _, ok := srcModels[id]; !ok {
+			if err := dst.DeleteAuthorizationModel(ctx, store, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("failed to delete the authorization model '%s': %w", id, err)
+			}
+		}
+	}
+
+	srcPinned, err := src.ReadPinnedAuthorizationModelID(ctx, store)
+	if err != nil {
+		return err
+	}
+	dstPinned, err := dst.ReadPinnedAuthorizationModelID(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case srcPinned == dstPinned:
+		return nil
+	case srcPinned == "":
+		return dst.UnpinAuthorizationModel(ctx, store)
+	default:
+		return dst.PinAuthorizationModel(ctx, store, srcPinned)
+	}
+}
+
+func readModels(ctx context.Context, ds storage.OpenFGADatastore, store string) (map[string]*openfgav1.AuthorizationModel, error) {
+	models := map[string]*openfgav1.AuthorizationModel{}
+	opts := storage.PaginationOptions{PageSize: storage.DefaultPageSize}
+	for {
+		page, contToken, err := ds.ReadAuthorizationModels(ctx, store, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the authorization models: %w", err)
+		}
+		for _, model := range page {
+			models[model.GetId()] = model
+		}
+
+		if len(contToken) == 0 {
+			return models, nil
+		}
+		opts.From = string(contToken)
+	}
+}
+
+// Verify compares the number of tuples per object type and relation, and the number of authorization models, of
+// the stores of the state in `src` and in `dst`, and returns a description of every difference.
+func Verify(ctx context.Context, src, dst storage.OpenFGADatastore, state *CopyState) ([]string, error) {
+	ids := make([]string, 0, len(state.Stores))
+	for id, storeState := range state.Stores {
+		if !storeState.Deleted {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var mismatches []string
+	for _, id := range ids {
+		srcStats, err := src.ReadStoreStats(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the statistics of the store '%s' in the source datastore: %w", id, err)
+		}
+
+		dstStats, err := dst.ReadStoreStats(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the statistics of the store '%s' in the target datastore: %w", id, err)
+		}
+
+		if srcStats.AuthorizationModels != dstStats.AuthorizationModels {
+			mismatches = append(mismatches, fmt.Sprintf("store '%s': %d authorization models in the source, %d in the target", id, srcStats.AuthorizationModels, dstStats.AuthorizationModels))
+		}
+
+		relations := map[string]struct{}{}
+		for relation := range srcStats.Tuples {
+			relations[relation] = struct{}{}
+		}
+		for relation := range dstStats.Tuples {
+			relations[relation] = struct{}{}
+		}
+
+		sorted := make([]string, 0, len(relations))
+		for relation := range relations {
+			sorted = append(sorted, relation)
+		}
+		sort.Strings(sorted)
+
+		for _, relation := range sorted {
+			if srcStats.Tuples[relation] != dstStats.Tuples[relation] {
+				mismatches = append(mismatches, fmt.Sprintf("store '%s': %d '%s' tuples in the source, %d in the target", id, srcStats.Tuples[relation], relation, dstStats.Tuples[relation]))
+			}
+		}
+	}
+
+	return mismatches, nil
+}
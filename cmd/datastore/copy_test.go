@@ -0,0 +1,145 @@
+package datastore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestModel(t *testing.T, ds storage.OpenFGADatastore, store string) string {
+	t.Helper()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{
+				Type: "document",
+				Relations: map[string]*openfgav1.Userset{
+					"viewer": typesystem.This(),
+				},
+				Metadata: &openfgav1.Metadata{
+					Relations: map[string]*openfgav1.RelationMetadata{
+						"viewer": {DirectlyRelatedUserTypes: []*openfgav1.RelationReference{typesystem.DirectRelationReference("user", "")}},
+					},
+				},
+			},
+		},
+	}
+	require.NoError(t, ds.WriteAuthorizationModel(context.Background(), store, model))
+
+	return model.GetId()
+}
+
+func readTestTuples(t *testing.T, ds storage.OpenFGADatastore, store string) []string {
+	t.Helper()
+
+	iter, err := ds.Read(context.Background(), store, &openfgav1.TupleKey{Object: "document:"})
+	require.NoError(t, err)
+	defer iter.Stop()
+
+	var tuples []string
+	for {
+		tk, err := iter.Next()
+		if err != nil {
+			require.ErrorIs(t, err, storage.ErrIteratorDone)
+			return tuples
+		}
+		tuples = append(tuples, tuple.TupleKeyToString(tk.GetKey()))
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+
+	src := memory.New()
+	t.Cleanup(src.Close)
+	dst := memory.New()
+	t.Cleanup(dst.Close)
+
+	store := ulid.Make().String()
+	_, err := src.CreateStore(ctx, &openfgav1.Store{Id: store, Name: "copy"})
+	require.NoError(t, err)
+	deletedStore := ulid.Make().String()
+	_, err = src.CreateStore(ctx, &openfgav1.Store{Id: deletedStore, Name: "deleted"})
+	require.NoError(t, err)
+
+	writeTestModel(t, src, store)
+	require.NoError(t, src.Write(ctx, store, nil, storage.Writes{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+	}))
+
+	state := &CopyState{Stores: map[string]*StoreCopyState{}}
+	saves := 0
+	save := func() error {
+		saves++
+		return nil
+	}
+
+	require.NoError(t, Copy(ctx, src, dst, nil, state, save))
+	require.Len(t, state.Stores, 2)
+	require.Equal(t, 2, saves)
+	require.ElementsMatch(t, []string{"document:1#viewer@user:anne", "document:2#viewer@user:anne"}, readTestTuples(t, dst, store))
+
+	mismatches, err := Verify(ctx, src, dst, state)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	// the changes made since the copy are caught up
+	latest := writeTestModel(t, src, store)
+	require.NoError(t, src.PinAuthorizationModel(ctx, store, latest))
+	require.NoError(t, src.Write(ctx, store,
+		storage.Deletes{tuple.NewTupleKey("document:1", "viewer", "user:anne")},
+		storage.Writes{tuple.NewTupleKey("document:3", "viewer", "user:bob")},
+	))
+	require.NoError(t, src.DeleteStore(ctx, deletedStore))
+
+	mismatches, err = Verify(ctx, src, dst, state)
+	require.NoError(t, err)
+	require.NotEmpty(t, mismatches)
+
+	require.NoError(t, Copy(ctx, src, dst, nil, state, save))
+	require.ElementsMatch(t, []string{"document:2#viewer@user:anne", "document:3#viewer@user:bob"}, readTestTuples(t, dst, store))
+	require.True(t, state.Stores[deletedStore].Deleted)
+
+	pinned, err := dst.ReadPinnedAuthorizationModelID(ctx, store)
+	require.NoError(t, err)
+	require.Equal(t, latest, pinned)
+
+	_, err = dst.GetStore(ctx, deletedStore)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	mismatches, err = Verify(ctx, src, dst, state)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	// catching up again applies nothing
+	token := state.Stores[store].ChangelogToken
+	require.NoError(t, Copy(ctx, src, dst, nil, state, save))
+	require.Equal(t, token, state.Stores[store].ChangelogToken)
+	require.ElementsMatch(t, []string{"document:2#viewer@user:anne", "document:3#viewer@user:bob"}, readTestTuples(t, dst, store))
+}
+
+func TestCopyState(t *testing.T) {
+	path := t.TempDir() + "/state.json"
+
+	state, err := readCopyState(path)
+	require.NoError(t, err)
+	require.Empty(t, state.Stores)
+
+	state.Stores["01H"] = &StoreCopyState{ChangelogToken: "token"}
+	require.NoError(t, writeCopyState(path, state))
+
+	read, err := readCopyState(path)
+	require.NoError(t, err)
+	require.Equal(t, state, read)
+}
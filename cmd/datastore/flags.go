@@ -0,0 +1,21 @@
+package datastore
+
+import (
+	"github.com/openfga/openfga/cmd/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bindRunFlagsFunc binds the cobra cmd flags to the equivalent config value being managed
+// by viper. This bridges the config between cobra flags and viper flags.
+func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		util.MustBindPFlag(sourceEngineFlag, flags.Lookup(sourceEngineFlag))
+		util.MustBindPFlag(sourceURIFlag, flags.Lookup(sourceURIFlag))
+		util.MustBindPFlag(targetEngineFlag, flags.Lookup(targetEngineFlag))
+		util.MustBindPFlag(targetURIFlag, flags.Lookup(targetURIFlag))
+		util.MustBindPFlag(storeIDsFlag, flags.Lookup(storeIDsFlag))
+		util.MustBindPFlag(stateFileFlag, flags.Lookup(stateFileFlag))
+		util.MustBindPFlag(verifyFlag, flags.Lookup(verifyFlag))
+	}
+}
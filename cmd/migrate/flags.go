@@ -15,5 +15,8 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag(versionFlag, flags.Lookup(versionFlag))
 		util.MustBindPFlag(timeoutFlag, flags.Lookup(timeoutFlag))
 		util.MustBindPFlag(verboseMigrationFlag, flags.Lookup(verboseMigrationFlag))
+		util.MustBindPFlag(downFlag, flags.Lookup(downFlag))
+		util.MustBindPFlag(dryRunFlag, flags.Lookup(dryRunFlag))
+		util.MustBindPFlag(waitForDBFlag, flags.Lookup(waitForDBFlag))
 	}
 }
@@ -5,13 +5,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/openfga/openfga/assets"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/cassandra"
 	"github.com/pressly/goose/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -23,15 +30,20 @@ const (
 	versionFlag          = "version"
 	timeoutFlag          = "timeout"
 	verboseMigrationFlag = "verbose"
+	downFlag             = "down"
+	dryRunFlag           = "dry-run"
+	waitForDBFlag        = "wait-for-db"
 )
 
 func NewMigrateCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "migrate",
 		Short: "Run database schema migrations needed for the OpenFGA server",
-		Long:  `The migrate command is used to migrate the database schema needed for OpenFGA.`,
-		RunE:  runMigration,
-		Args:  cobra.NoArgs,
+		Long: `The migrate command is used to migrate the database schema needed for OpenFGA.
+The schema is migrated up to the latest version, or up or down to --version. With --down, the latest migration is rolled back, or every migration above --version.
+With --dry-run, the SQL of the migrations which would be applied is printed instead.`,
+		RunE: runMigration,
+		Args: cobra.NoArgs,
 	}
 
 	flags := cmd.Flags()
@@ -41,6 +53,9 @@ func NewMigrateCommand() *cobra.Command {
 	flags.Uint(versionFlag, 0, "the version to migrate to (if omitted the latest schema will be used)")
 	flags.Duration(timeoutFlag, 1*time.Minute, "a timeout after which the migration process will terminate")
 	flags.Bool(verboseMigrationFlag, false, "enable verbose migration logs (default false)")
+	flags.Bool(downFlag, false, "roll back the latest migration, or the migrations above --version")
+	flags.Bool(dryRunFlag, false, "print the SQL of the migrations which would be applied without applying them")
+	flags.Bool(waitForDBFlag, true, "retry connecting to the database until the timeout, or else fail on the first failed connection")
 
 	// NOTE: if you add a new flag here, update the function below, too
 
@@ -55,23 +70,31 @@ func runMigration(_ *cobra.Command, _ []string) error {
 	targetVersion := viper.GetUint(versionFlag)
 	timeout := viper.GetDuration(timeoutFlag)
 	verbose := viper.GetBool(verboseMigrationFlag)
+	down := viper.GetBool(downFlag)
+	dryRun := viper.GetBool(dryRunFlag)
+	waitForDB := viper.GetBool(waitForDBFlag)
 
 	goose.SetLogger(goose.NopLogger())
 	goose.SetVerbose(verbose)
 
 	var driver, dialect, migrationsPath string
 	switch engine {
-	case "memory":
-		log.Println("no migrations to run for `memory` datastore")
+	case "memory", "bolt":
+		log.Printf("no migrations to run for `%s` datastore", engine)
 		return nil
 	case "mysql":
 		driver = "mysql"
 		dialect = "mysql"
 		migrationsPath = assets.MySQLMigrationDir
-	case "postgres":
+	case "postgres", "cockroachdb":
 		driver = "pgx"
 		dialect = "postgres"
 		migrationsPath = assets.PostgresMigrationDir
+	case "cassandra":
+		if down || dryRun {
+			return fmt.Errorf("the cassandra datastore schema is not versioned: the migrations can't be rolled back or planned")
+		}
+		return migrateCassandra(uri, targetVersion, timeout)
 	case "":
 		return fmt.Errorf("missing datastore engine type")
 	default:
@@ -89,16 +112,17 @@ func runMigration(_ *cobra.Command, _ []string) error {
 		}
 	}()
 
-	policy := backoff.NewExponentialBackOff()
-	policy.MaxElapsedTime = timeout
-	err = backoff.Retry(func() error {
-		err = db.PingContext(context.Background())
-		if err != nil {
-			return err
-		}
-
-		return nil
-	}, policy)
+	var policy backoff.BackOff = &backoff.StopBackOff{}
+	if waitForDB {
+		exponential := backoff.NewExponentialBackOff()
+		exponential.MaxElapsedTime = timeout
+		policy = exponential
+	}
+	err = backoff.RetryNotify(func() error {
+		return db.PingContext(context.Background())
+	}, policy, func(err error, next time.Duration) {
+		log.Printf("waiting for the database, retrying in %s: %v", next.Round(time.Millisecond), err)
+	})
 	if err != nil {
 		log.Fatalf("failed to initialize database connection: %v", err)
 	}
@@ -109,33 +133,189 @@ func runMigration(_ *cobra.Command, _ []string) error {
 
 	goose.SetBaseFS(assets.EmbedMigrations)
 
-	currentVersion, err := goose.GetDBVersion(db)
+	var currentVersion int64
+	if dryRun {
+		// a dry run does not create the version table of goose if it does not exist yet
+		currentVersion, err = readDBVersion(db)
+	} else {
+		currentVersion, err = goose.GetDBVersion(db)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	log.Printf("current version %d", currentVersion)
 
-	if targetVersion == 0 {
-		log.Println("running all migrations")
-		if err := goose.Up(db, migrationsPath); err != nil {
+	migrations, err := goose.CollectMigrations(migrationsPath, 0, goose.MaxVersion)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	target, err := targetMigrationVersion(migrations, currentVersion, int64(targetVersion), down)
+	if err != nil {
+		return err
+	}
+
+	if target == currentVersion {
+		log.Println("nothing to do")
+		return nil
+	}
+
+	if dryRun {
+		return printMigrationPlan(os.Stdout, migrations, currentVersion, target)
+	}
+
+	log.Printf("migrating to %d", target)
+	if target < currentVersion {
+		if err := goose.DownTo(db, migrationsPath, target); err != nil {
 			log.Fatal(err)
 		}
 	} else {
-		log.Printf("migrating to %d", targetVersion)
-		targetInt64Version := int64(targetVersion)
-		if targetInt64Version < currentVersion {
-			if err := goose.DownTo(db, migrationsPath, targetInt64Version); err != nil {
-				log.Fatal(err)
+		if err := goose.UpTo(db, migrationsPath, target); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Println("migration done")
+
+	return nil
+}
+
+// targetMigrationVersion returns the version the schema is migrated to. It is the latest version, or else the
+// requested version, or with down the version before the current one, or else the requested version which must then
+// be lower than the current one.
+func targetMigrationVersion(migrations goose.Migrations, current, requested int64, down bool) (int64, error) {
+	if down {
+		if requested != 0 {
+			if requested >= current {
+				return 0, fmt.Errorf("the version to migrate down to must be lower than the current version %d", current)
 			}
-		} else if targetInt64Version > currentVersion {
-			if err := goose.UpTo(db, migrationsPath, targetInt64Version); err != nil {
-				log.Fatal(err)
+
+			return requested, nil
+		}
+
+		if current == 0 {
+			return 0, nil
+		}
+
+		previous, err := migrations.Previous(current)
+		if err != nil {
+			// the current migration is the first one
+			return 0, nil
+		}
+
+		return previous.Version, nil
+	}
+
+	if requested != 0 {
+		return requested, nil
+	}
+
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+
+	return last.Version, nil
+}
+
+// readDBVersion returns the current version of the schema like goose.GetDBVersion, or 0 if the version table of
+// goose does not exist, without creating it.
+func readDBVersion(db *sql.DB) (int64, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version_id) FROM goose_db_version WHERE is_applied").Scan(&version); err != nil {
+		// the database can be reached, so the version table does not exist yet
+		return 0, nil
+	}
+
+	return version.Int64, nil
+}
+
+// printMigrationPlan writes the SQL of the migrations from the current version to the target version: the up
+// statements of the migrations above the current version in ascending order, or the down statements of the
+// migrations above the target version in descending order.
+func printMigrationPlan(w io.Writer, migrations goose.Migrations, current, target int64) error {
+	up := target > current
+
+	var plan goose.Migrations
+	for _, migration := range migrations {
+		if up && migration.Version > current && migration.Version <= target {
+			plan = append(plan, migration)
+		}
+		if !up && migration.Version > target && migration.Version <= current {
+			plan = append([]*goose.Migration{migration}, plan...)
+		}
+	}
+
+	for _, migration := range plan {
+		script, err := fs.ReadFile(assets.EmbedMigrations, migration.Source)
+		if err != nil {
+			return err
+		}
+
+		direction := "down"
+		if up {
+			direction = "up"
+		}
+		fmt.Fprintf(w, "-- %s (%s)\n%s\n", filepath.Base(migration.Source), direction, migrationSQL(string(script), up))
+	}
+
+	return nil
+}
+
+// migrationSQL returns the up or down section of a goose SQL migration, without the goose annotations.
+func migrationSQL(script string, up bool) string {
+	section := "-- +goose Down"
+	if up {
+		section = "-- +goose Up"
+	}
+
+	var lines []string
+	inSection := false
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "-- +goose ") {
+			switch {
+			case strings.HasPrefix(trimmed, "-- +goose Up"), strings.HasPrefix(trimmed, "-- +goose Down"):
+				inSection = strings.HasPrefix(trimmed, section)
 			}
-		} else {
-			log.Println("nothing to do")
-			return nil
+			continue
 		}
+
+		if inSection {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// migrateCassandra creates the tables of the Cassandra datastore in the keyspace of the uri, which must exist. The
+// schema is not versioned, so the migrations can't be rolled back.
+func migrateCassandra(uri string, targetVersion uint, timeout time.Duration) error {
+	if targetVersion != 0 {
+		return fmt.Errorf("the cassandra datastore schema is not versioned: the version can't be set")
+	}
+
+	cluster, err := cassandra.NewClusterConfig(&storage.DatastoreConfig{URI: uri})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cluster.Timeout = timeout
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		log.Fatalf("failed to initialize database connection: %v", err)
+	}
+	defer session.Close()
+
+	log.Println("running all migrations")
+	if err := cassandra.Migrate(ctx, session); err != nil {
+		log.Fatal(err)
 	}
 
 	log.Println("migration done")
@@ -1,12 +1,16 @@
 package migrate
 
 import (
+	"bytes"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/openfga/openfga/assets"
 	"github.com/openfga/openfga/cmd"
 	"github.com/openfga/openfga/cmd/util"
+	"github.com/pressly/goose/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
@@ -103,3 +107,66 @@ func TestMigrateCommandConfigIsMerged(t *testing.T) {
 	cmd.SetArgs([]string{"migrate"})
 	require.Nil(t, cmd.Execute())
 }
+
+func TestMigrationPlan(t *testing.T) {
+	goose.SetBaseFS(assets.EmbedMigrations)
+	t.Cleanup(func() { goose.SetBaseFS(nil) })
+
+	migrations, err := goose.CollectMigrations(assets.PostgresMigrationDir, 0, goose.MaxVersion)
+	require.NoError(t, err)
+	latest := migrations[len(migrations)-1].Version
+
+	t.Run("target_version", func(t *testing.T) {
+		target, err := targetMigrationVersion(migrations, 3, 0, false)
+		require.NoError(t, err)
+		require.Equal(t, latest, target)
+
+		target, err = targetMigrationVersion(migrations, 3, 5, false)
+		require.NoError(t, err)
+		require.EqualValues(t, 5, target)
+
+		target, err = targetMigrationVersion(migrations, 5, 0, true)
+		require.NoError(t, err)
+		require.EqualValues(t, 4, target)
+
+		target, err = targetMigrationVersion(migrations, 1, 0, true)
+		require.NoError(t, err)
+		require.EqualValues(t, 0, target)
+
+		target, err = targetMigrationVersion(migrations, 5, 2, true)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, target)
+
+		_, err = targetMigrationVersion(migrations, 5, 5, true)
+		require.ErrorContains(t, err, "must be lower than the current version 5")
+	})
+
+	t.Run("up", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printMigrationPlan(&out, migrations, 9, 11))
+
+		plan := out.String()
+		require.Contains(t, plan, "-- 010_add_staged_write.sql (up)")
+		require.Contains(t, plan, "-- 011_add_pinned_authorization_model.sql (up)\nCREATE TABLE pinned_authorization_model")
+		require.Less(t, strings.Index(plan, "010_"), strings.Index(plan, "011_"))
+		require.NotContains(t, plan, "009_")
+		require.NotContains(t, plan, "+goose")
+		require.NotContains(t, plan, "DROP TABLE pinned_authorization_model")
+	})
+
+	t.Run("down", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, printMigrationPlan(&out, migrations, 11, 9))
+
+		plan := out.String()
+		require.Contains(t, plan, "-- 011_add_pinned_authorization_model.sql (down)\nDROP TABLE pinned_authorization_model;")
+		require.Less(t, strings.Index(plan, "011_"), strings.Index(plan, "010_"))
+		require.NotContains(t, plan, "CREATE TABLE pinned_authorization_model")
+	})
+}
+
+func TestMigrateCommandDownCassandra(t *testing.T) {
+	migrateCommand := NewMigrateCommand()
+	migrateCommand.SetArgs([]string{"--datastore-engine", "cassandra", "--datastore-uri", "cassandra://localhost:9042/openfga", "--down"})
+	require.ErrorContains(t, migrateCommand.Execute(), "can't be rolled back")
+}
@@ -5,8 +5,16 @@ import (
 	"os"
 
 	"github.com/openfga/openfga/cmd"
+	"github.com/openfga/openfga/cmd/bench"
+	"github.com/openfga/openfga/cmd/datastore"
 	"github.com/openfga/openfga/cmd/migrate"
+	"github.com/openfga/openfga/cmd/prunemodels"
+	"github.com/openfga/openfga/cmd/replay"
+	"github.com/openfga/openfga/cmd/reshard"
 	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/cmd/testmodel"
+	"github.com/openfga/openfga/cmd/tuples"
+	"github.com/openfga/openfga/cmd/validatemodel"
 	"github.com/openfga/openfga/cmd/validatemodels"
 )
 
@@ -19,9 +27,33 @@ func main() {
 	migrateCmd := migrate.NewMigrateCommand()
 	rootCmd.AddCommand(migrateCmd)
 
+	reshardCmd := reshard.NewReshardCommand()
+	rootCmd.AddCommand(reshardCmd)
+
 	validateModelsCmd := validatemodels.NewValidateCommand()
 	rootCmd.AddCommand(validateModelsCmd)
 
+	validateModelCmd := validatemodel.NewValidateModelCommand()
+	rootCmd.AddCommand(validateModelCmd)
+
+	testModelCmd := testmodel.NewTestModelCommand()
+	rootCmd.AddCommand(testModelCmd)
+
+	tuplesCmd := tuples.NewTuplesCommand()
+	rootCmd.AddCommand(tuplesCmd)
+
+	datastoreCmd := datastore.NewDatastoreCommand()
+	rootCmd.AddCommand(datastoreCmd)
+
+	pruneModelsCmd := prunemodels.NewPruneModelsCommand()
+	rootCmd.AddCommand(pruneModelsCmd)
+
+	replayCmd := replay.NewReplayCommand()
+	rootCmd.AddCommand(replayCmd)
+
+	benchCmd := bench.NewBenchCommand()
+	rootCmd.AddCommand(benchCmd)
+
 	versionCmd := cmd.NewVersionCommand()
 	rootCmd.AddCommand(versionCmd)
 
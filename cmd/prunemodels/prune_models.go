@@ -0,0 +1,157 @@
+// Package prunemodels contains the command to delete the old authorization models of the stores.
+package prunemodels
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	datastoreEngineFlag = "datastore-engine"
+	datastoreURIFlag    = "datastore-uri"
+	storeIDFlag         = "store-id"
+	retainFlag          = "retain"
+	archiveDirFlag      = "archive-dir"
+	dryRunFlag          = "dry-run"
+)
+
+func NewPruneModelsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune-models",
+		Short: "Delete the old authorization models of the stores",
+		Long: `The prune-models command deletes the authorization models of the stores but their newest ones, along with their annotations and assertions. The pinned model of a store is always retained.
+The pruned models can be archived first, in a gzip-compressed file per store in the format of the store backups.
+The datastore is configured like the server, by the config file, the environment or the flags below.`,
+		RunE: runPruneModels,
+		Args: cobra.NoArgs,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(datastoreEngineFlag, "", "the datastore engine")
+	flags.String(datastoreURIFlag, "", "the connection uri to the datastore")
+	flags.String(storeIDFlag, "", "the store whose models are pruned, or empty to prune the models of every store")
+	flags.Int(retainFlag, 10, "the number of the newest models of each store which are retained")
+	flags.String(archiveDirFlag, "", "the directory the pruned models of each store are archived to, as '<store id>.models.json.gz', before they are deleted")
+	flags.Bool(dryRunFlag, false, "list the models which would be pruned without deleting them")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runPruneModels(_ *cobra.Command, _ []string) error {
+	config, err := run.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := run.VerifyConfig(config); err != nil {
+		return err
+	}
+
+	datastore, err := run.NewDatastore(config, logger.NewNoopLogger())
+	if err != nil {
+		return err
+	}
+	defer datastore.Close()
+
+	ctx := context.Background()
+
+	storeIDs := []string{viper.GetString(storeIDFlag)}
+	if storeIDs[0] == "" {
+		storeIDs, err = listStoreIDs(ctx, datastore)
+		if err != nil {
+			return err
+		}
+	}
+
+	return PruneModels(ctx, datastore, storeIDs, viper.GetInt(retainFlag), viper.GetString(archiveDirFlag), viper.GetBool(dryRunFlag))
+}
+
+// PruneModels prunes the models of the stores, retaining the newest `retain` models of each store. If archiveDir is
+// not empty, the pruned models of each store are archived to a file of the directory before they are deleted.
+func PruneModels(ctx context.Context, datastore storage.OpenFGADatastore, storeIDs []string, retain int, archiveDir string, dryRun bool) error {
+	c := commands.NewPruneAuthorizationModelsCommand(datastore, logger.NewNoopLogger())
+
+	pruned := 0
+	for _, storeID := range storeIDs {
+		req := &commands.PruneAuthorizationModelsRequest{
+			StoreID:      storeID,
+			RetainLatest: retain,
+			DryRun:       dryRun,
+		}
+
+		var archive *os.File
+		if archiveDir != "" && !dryRun {
+			var err error
+			archive, err = os.Create(filepath.Join(archiveDir, storeID+".models.json.gz"))
+			if err != nil {
+				return fmt.Errorf("failed to create the archive of store '%s': %w", storeID, err)
+			}
+			req.Archive = archive
+		}
+
+		res, err := c.Execute(ctx, req)
+		if archive != nil {
+			if closeErr := archive.Close(); err == nil {
+				err = closeErr
+			}
+			if err == nil && len(res.PrunedAuthorizationModelIDs) == 0 {
+				_ = os.Remove(archive.Name())
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune the models of store '%s': %w", storeID, err)
+		}
+
+		for _, modelID := range res.PrunedAuthorizationModelIDs {
+			if dryRun {
+				log.Printf("would prune model '%s' of store '%s'", modelID, storeID)
+			} else {
+				log.Printf("pruned model '%s' of store '%s'", modelID, storeID)
+			}
+		}
+		pruned += len(res.PrunedAuthorizationModelIDs)
+	}
+
+	if dryRun {
+		log.Printf("%d models to prune", pruned)
+	} else {
+		log.Printf("%d models pruned", pruned)
+	}
+
+	return nil
+}
+
+func listStoreIDs(ctx context.Context, datastore storage.OpenFGADatastore) ([]string, error) {
+	var storeIDs []string
+	var from string
+	for {
+		stores, token, err := datastore.ListStores(ctx, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the stores: %w", err)
+		}
+
+		for _, store := range stores {
+			storeIDs = append(storeIDs, store.GetId())
+		}
+
+		if len(token) == 0 {
+			return storeIDs, nil
+		}
+		from = string(token)
+	}
+}
@@ -0,0 +1,23 @@
+package replay
+
+import (
+	"github.com/openfga/openfga/cmd/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bindRunFlagsFunc binds the cobra cmd flags to the equivalent config value being managed
+// by viper. This bridges the config between cobra flags and viper flags.
+func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		util.MustBindPFlag("datastore.engine", flags.Lookup(datastoreEngineFlag))
+		util.MustBindEnv("datastore.engine", "OPENFGA_DATASTORE_ENGINE")
+
+		util.MustBindPFlag("datastore.uri", flags.Lookup(datastoreURIFlag))
+		util.MustBindEnv("datastore.uri", "OPENFGA_DATASTORE_URI")
+
+		util.MustBindPFlag(fileFlag, flags.Lookup(fileFlag))
+		util.MustBindPFlag(modelFileFlag, flags.Lookup(modelFileFlag))
+		util.MustBindPFlag(storeIDFlag, flags.Lookup(storeIDFlag))
+	}
+}
@@ -0,0 +1,341 @@
+// Package replay contains the command to replay the recorded Check and ListObjects requests and report how their
+// results diverge from the recorded ones.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/cmd/validatemodel"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/recorder"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	datastoreEngineFlag = "datastore-engine"
+	datastoreURIFlag    = "datastore-uri"
+	fileFlag            = "file"
+	modelFileFlag       = "model-file"
+	storeIDFlag         = "store-id"
+
+	// sampleSize is the maximum number of the diverging objects reported for a ListObjects.
+	sampleSize = 10
+)
+
+// errReplayDiverged makes the command exit with a non-zero status once the divergences are reported.
+var errReplayDiverged = errors.New("the replayed requests diverged from the recorded ones")
+
+func NewReplayCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay the recorded Check and ListObjects requests and report how their results diverge from the recorded ones",
+		Long: `The replay command re-executes the Check and ListObjects requests recorded by a server with the requestRecording config against the tuples of a datastore, e.g. a copy of the production datastore, and reports every request whose result diverges from the recorded one.
+The requests are resolved with the authorization models they were recorded with, or with the model of --model-file, in JSON if its extension is .json or else in the DSL, to catch the regressions of a modified model before it is written. The model of --model-file is never written to the datastore.
+The ListObjects recorded with truncated results are inconclusive. Anonymized requests must be replayed against tuples anonymized with the same key.
+The datastore is configured like the server, by the config file, the environment or the flags below. The command fails if any request diverged or failed.`,
+		RunE:         runReplay,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(datastoreEngineFlag, "", "the datastore engine")
+	flags.String(datastoreURIFlag, "", "the connection uri to the datastore")
+	flags.String(fileFlag, "", "the file of the recorded requests")
+	flags.String(modelFileFlag, "", "the authorization model the requests are replayed with, instead of the models they were recorded with")
+	flags.String(storeIDFlag, "", "the store the requests are replayed against, instead of the stores they were recorded in")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runReplay(cmd *cobra.Command, _ []string) error {
+	path := viper.GetString(fileFlag)
+	if path == "" {
+		return fmt.Errorf("missing file of the recorded requests")
+	}
+
+	var model *openfgav1.AuthorizationModel
+	if modelPath := viper.GetString(modelFileFlag); modelPath != "" {
+		var err error
+		model, err = validatemodel.ReadModel(modelPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	config, err := run.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := run.VerifyConfig(config); err != nil {
+		return err
+	}
+
+	datastore, err := run.NewDatastore(config, logger.NewNoopLogger())
+	if err != nil {
+		return err
+	}
+	defer datastore.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the recorded requests: %w", err)
+	}
+	defer file.Close()
+
+	out := cmd.OutOrStdout()
+
+	result, err := Replay(context.Background(), datastore, model, viper.GetString(storeIDFlag), recorder.NewReader(file), out)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "replayed %d requests: %d matched, %d diverged, %d inconclusive, %d failed\n",
+		result.Replayed, result.Matched, result.Diverged, result.Inconclusive, result.Failed)
+
+	if result.Diverged > 0 || result.Failed > 0 {
+		return errReplayDiverged
+	}
+
+	return nil
+}
+
+// ReplayResult counts the outcomes of the replayed requests.
+type ReplayResult struct {
+	Replayed int
+	Matched  int
+	Diverged int
+
+	// Inconclusive requests can't be compared, i.e. the ListObjects recorded with truncated results.
+	Inconclusive int
+
+	// Failed requests returned an error when replayed.
+	Failed int
+}
+
+// Replay re-executes the recorded requests against the datastore and writes every divergence and failure to w. If
+// `model` is set, every request is resolved with it instead of the model it was recorded with, and if `storeID` is
+// set, every request is replayed against the store instead of the store it was recorded in. It returns an error if
+// the records can't be read.
+func Replay(
+	ctx context.Context,
+	datastore storage.OpenFGADatastore,
+	model *openfgav1.AuthorizationModel,
+	storeID string,
+	records *recorder.Reader,
+	w io.Writer,
+) (*ReplayResult, error) {
+	if model != nil {
+		if _, err := typesystem.NewAndValidate(ctx, model); err != nil {
+			return nil, fmt.Errorf("invalid authorization model: %w", err)
+		}
+
+		if model.GetId() == "" {
+			model.Id = ulid.Make().String()
+		}
+
+		datastore = &modelOverride{OpenFGADatastore: datastore, model: model}
+	}
+
+	// the results of the replayed ListObjects are never truncated: the sorted objects are only truncated to the
+	// max results if it is set
+	s, err := server.NewServerWithOpts(
+		server.WithDatastore(datastore),
+		server.WithListObjectsSortOrder(commands.ListObjectsSortedByObjectID),
+		server.WithListObjectsMaxResults(0),
+		server.WithListObjectsDeadline(0),
+		server.WithListObjectsPartialResults(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	result := &ReplayResult{}
+	for {
+		record, err := records.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+
+			return nil, err
+		}
+
+		if storeID != "" {
+			record.StoreID = storeID
+		}
+
+		modelID := record.AuthorizationModelID
+		if model != nil {
+			modelID = model.GetId()
+		}
+
+		result.Replayed++
+
+		var divergence string
+		switch record.Method {
+		case recorder.MethodCheck:
+			divergence, err = replayCheck(ctx, s, record, modelID)
+		case recorder.MethodListObjects:
+			if record.Truncated {
+				result.Inconclusive++
+				continue
+			}
+
+			divergence, err = replayListObjects(ctx, s, record, modelID)
+		}
+
+		switch {
+		case err != nil:
+			result.Failed++
+			fmt.Fprintf(w, "failed: %s: %v\n", describe(record), err)
+		case divergence != "":
+			result.Diverged++
+			fmt.Fprintf(w, "diverged: %s: %s\n", describe(record), divergence)
+		default:
+			result.Matched++
+		}
+	}
+}
+
+// replayCheck returns how the result of the Check diverges from the recorded one, if it does.
+func replayCheck(ctx context.Context, s *server.Server, record *recorder.Record, modelID string) (string, error) {
+	resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              record.StoreID,
+		AuthorizationModelId: modelID,
+		TupleKey:             tuple.NewTupleKey(record.Object, record.Relation, record.User),
+		ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: record.ContextualTuples},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.GetAllowed() == record.Allowed {
+		return "", nil
+	}
+
+	return fmt.Sprintf("recorded %s, replayed %s", decision(record.Allowed), decision(resp.GetAllowed())), nil
+}
+
+// replayListObjects returns how the objects of the ListObjects diverge from the recorded ones, if they do.
+func replayListObjects(ctx context.Context, s *server.Server, record *recorder.Record, modelID string) (string, error) {
+	resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+		StoreId:              record.StoreID,
+		AuthorizationModelId: modelID,
+		Type:                 record.ObjectType,
+		Relation:             record.Relation,
+		User:                 record.User,
+		ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: record.ContextualTuples},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	missing := difference(record.Objects, resp.GetObjects())
+	extra := difference(resp.GetObjects(), record.Objects)
+	if len(missing) == 0 && len(extra) == 0 {
+		return "", nil
+	}
+
+	var divergences []string
+	if len(missing) > 0 {
+		divergences = append(divergences, fmt.Sprintf("%d objects missing %v", len(missing), sample(missing)))
+	}
+	if len(extra) > 0 {
+		divergences = append(divergences, fmt.Sprintf("%d objects extra %v", len(extra), sample(extra)))
+	}
+
+	return strings.Join(divergences, ", "), nil
+}
+
+// difference returns the sorted objects of a that are not in b.
+func difference(a, b []string) []string {
+	inB := make(map[string]struct{}, len(b))
+	for _, object := range b {
+		inB[object] = struct{}{}
+	}
+
+	var objects []string
+	for _, object := range a {
+		if _, ok := inB[object]; !ok {
+			objects = append(objects, object)
+		}
+	}
+	sort.Strings(objects)
+
+	return objects
+}
+
+func sample(objects []string) []string {
+	if len(objects) > sampleSize {
+		return objects[:sampleSize]
+	}
+
+	return objects
+}
+
+func decision(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+
+	return "denied"
+}
+
+func describe(record *recorder.Record) string {
+	if record.Method == recorder.MethodCheck {
+		return fmt.Sprintf("Check %s#%s@%s in store %s", record.Object, record.Relation, record.User, record.StoreID)
+	}
+
+	return fmt.Sprintf("ListObjects %s#%s@%s in store %s", record.ObjectType, record.Relation, record.User, record.StoreID)
+}
+
+// modelOverride serves the authorization model of the replay in place of the models of the stores, without writing
+// it to the datastore.
+type modelOverride struct {
+	storage.OpenFGADatastore
+	model *openfgav1.AuthorizationModel
+}
+
+func (m *modelOverride) ReadAuthorizationModel(ctx context.Context, store, id string) (*openfgav1.AuthorizationModel, error) {
+	if id == m.model.GetId() {
+		return m.model, nil
+	}
+
+	return m.OpenFGADatastore.ReadAuthorizationModel(ctx, store, id)
+}
+
+// ReadAuthorizationModelAnnotations returns no annotations for the model of the replay, which were never written.
+func (m *modelOverride) ReadAuthorizationModelAnnotations(ctx context.Context, store, id string) (storage.ModelAnnotations, error) {
+	if id == m.model.GetId() {
+		return nil, nil
+	}
+
+	return m.OpenFGADatastore.ReadAuthorizationModelAnnotations(ctx, store, id)
+}
+
+func (m *modelOverride) FindLatestAuthorizationModelID(context.Context, string) (string, error) {
+	return m.model.GetId(), nil
+}
@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/recorder"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	var records bytes.Buffer
+	r, err := recorder.NewRecorder(&records)
+	require.NoError(t, err)
+
+	s := server.MustNewServerWithOpts(server.WithDatastore(ds), server.WithRecorder(r))
+	t.Cleanup(s.Close)
+
+	store, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+
+	model, err := typesystem.ParseDSL(`
+	type user
+
+	type document
+	  relations
+	    define owner: [user] as self
+	    define viewer: [user] as self or owner
+	`)
+	require.NoError(t, err)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         store.GetId(),
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: store.GetId(),
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "owner", "user:anne"),
+			tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  store.GetId(),
+		TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+		StoreId:  store.GetId(),
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:anne",
+	})
+	require.NoError(t, err)
+
+	t.Run("with_the_recorded_models", func(t *testing.T) {
+		var out bytes.Buffer
+		result, err := Replay(ctx, ds, nil, "", recorder.NewReader(bytes.NewReader(records.Bytes())), &out)
+		require.NoError(t, err)
+		require.Equal(t, &ReplayResult{Replayed: 2, Matched: 2}, result)
+		require.Empty(t, out.String())
+	})
+
+	t.Run("with_a_modified_model", func(t *testing.T) {
+		model, err := typesystem.ParseDSL(`
+		type user
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define viewer: [user] as self
+		`)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		result, err := Replay(ctx, ds, model, "", recorder.NewReader(bytes.NewReader(records.Bytes())), &out)
+		require.NoError(t, err)
+		require.Equal(t, &ReplayResult{Replayed: 2, Diverged: 2}, result)
+		require.Contains(t, out.String(), "diverged: Check document:1#viewer@user:anne in store "+store.GetId()+": recorded allowed, replayed denied")
+		require.Contains(t, out.String(), "diverged: ListObjects document#viewer@user:anne in store "+store.GetId()+": 1 objects missing [document:1]")
+
+		// the model was not written
+		latest, err := ds.FindLatestAuthorizationModelID(ctx, store.GetId())
+		require.NoError(t, err)
+		require.NotEqual(t, model.GetId(), latest)
+	})
+
+	t.Run("in_another_store", func(t *testing.T) {
+		var out bytes.Buffer
+		result, err := Replay(ctx, ds, nil, "01ARZ3NDEKTSV4RRFFQ69G5FAV", recorder.NewReader(bytes.NewReader(records.Bytes())), &out)
+		require.NoError(t, err)
+		require.Equal(t, &ReplayResult{Replayed: 2, Failed: 2}, result)
+	})
+}
@@ -0,0 +1,27 @@
+package reshard
+
+import (
+	"github.com/openfga/openfga/cmd/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bindRunFlagsFunc binds the cobra cmd flags to the equivalent config value being managed
+// by viper. This bridges the config between cobra flags and viper flags.
+func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		util.MustBindPFlag("datastore.engine", flags.Lookup(datastoreEngineFlag))
+		util.MustBindEnv("datastore.engine", "OPENFGA_DATASTORE_ENGINE")
+
+		util.MustBindPFlag("datastore.shards", flags.Lookup(datastoreShardsFlag))
+		util.MustBindEnv("datastore.shards", "OPENFGA_DATASTORE_SHARDS")
+
+		util.MustBindPFlag("datastore.drainingShards", flags.Lookup(datastoreDrainingShardsFlag))
+		util.MustBindEnv("datastore.drainingShards", "OPENFGA_DATASTORE_DRAINING_SHARDS", "OPENFGA_DATASTORE_DRAININGSHARDS")
+
+		util.MustBindPFlag("datastore.shardPins", flags.Lookup(datastoreShardPinsFlag))
+		util.MustBindEnv("datastore.shardPins", "OPENFGA_DATASTORE_SHARD_PINS", "OPENFGA_DATASTORE_SHARDPINS")
+
+		util.MustBindPFlag(dryRunFlag, flags.Lookup(dryRunFlag))
+	}
+}
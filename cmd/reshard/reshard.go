@@ -0,0 +1,103 @@
+// Package reshard contains the command to move the stores of a sharded datastore to the shards that own them.
+package reshard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage/sharded"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	datastoreEngineFlag         = "datastore-engine"
+	datastoreShardsFlag         = "datastore-shards"
+	datastoreDrainingShardsFlag = "datastore-draining-shards"
+	datastoreShardPinsFlag      = "datastore-shard-pins"
+	dryRunFlag                  = "dry-run"
+)
+
+func NewReshardCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reshard",
+		Short: "Move the stores of a sharded datastore to the shards that own them",
+		Long: `The reshard command moves the stores which are on a shard other than their owner, e.g. once a shard is added, drained or a store is pinned, to their owner.
+Each store is copied to its owner and then deleted from its old shard. The servers should be stopped while the stores are moved.
+The datastore is configured like the server, by the config file, the environment or the flags below.`,
+		RunE: runReshard,
+		Args: cobra.NoArgs,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(datastoreEngineFlag, "", "the datastore engine of the shards")
+	flags.StringSlice(datastoreShardsFlag, nil, "the shards the stores are spread across, as 'name=uri' pairs")
+	flags.StringSlice(datastoreDrainingShardsFlag, nil, "the names of the shards which own no stores, whose stores are moved to the other shards")
+	flags.StringSlice(datastoreShardPinsFlag, nil, "the stores pinned to a shard, as 'storeID=name' pairs")
+	flags.Bool(dryRunFlag, false, "list the moves without moving the stores")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runReshard(_ *cobra.Command, _ []string) error {
+	config, err := run.ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(config.Datastore.Shards) == 0 {
+		return errors.New("missing datastore shards")
+	}
+
+	if err := run.VerifyConfig(config); err != nil {
+		return err
+	}
+
+	datastore, err := run.NewDatastore(config, logger.NewNoopLogger())
+	if err != nil {
+		return err
+	}
+	defer datastore.Close()
+
+	ds, ok := datastore.(*sharded.Sharded)
+	if !ok {
+		return errors.New("the datastore is not sharded")
+	}
+
+	ctx := context.Background()
+
+	moves, err := ds.Moves(ctx)
+	if err != nil {
+		return err
+	}
+
+	dryRun := viper.GetBool(dryRunFlag)
+	for _, move := range moves {
+		if dryRun {
+			log.Printf("would move store '%s' from shard '%s' to shard '%s'", move.Store, move.From, move.To)
+			continue
+		}
+
+		if err := ds.Move(ctx, move); err != nil {
+			return fmt.Errorf("failed to move store '%s' from shard '%s' to shard '%s': %w", move.Store, move.From, move.To, err)
+		}
+		log.Printf("moved store '%s' from shard '%s' to shard '%s'", move.Store, move.From, move.To)
+	}
+
+	if dryRun {
+		log.Printf("%d stores to move", len(moves))
+	} else {
+		log.Printf("%d stores moved", len(moves))
+	}
+
+	return nil
+}
@@ -25,6 +25,9 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("grpc.tls.key", flags.Lookup("grpc-tls-key"))
 		util.MustBindEnv("grpc.tls.key", "OPENFGA_GRPC_TLS_KEY")
 
+		util.MustBindPFlag("grpc.tls.clientCA", flags.Lookup("grpc-tls-client-ca"))
+		util.MustBindEnv("grpc.tls.clientCA", "OPENFGA_GRPC_TLS_CLIENT_CA", "OPENFGA_GRPC_TLS_CLIENTCA")
+
 		command.MarkFlagsRequiredTogether("grpc-tls-enabled", "grpc-tls-cert", "grpc-tls-key")
 
 		util.MustBindPFlag("http.enabled", flags.Lookup("http-enabled"))
@@ -42,6 +45,9 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("http.tls.key", flags.Lookup("http-tls-key"))
 		util.MustBindEnv("http.tls.key", "OPENFGA_HTTP_TLS_KEY")
 
+		util.MustBindPFlag("http.tls.clientCA", flags.Lookup("http-tls-client-ca"))
+		util.MustBindEnv("http.tls.clientCA", "OPENFGA_HTTP_TLS_CLIENT_CA", "OPENFGA_HTTP_TLS_CLIENTCA")
+
 		command.MarkFlagsRequiredTogether("http-tls-enabled", "http-tls-cert", "http-tls-key")
 
 		util.MustBindPFlag("http.upstreamTimeout", flags.Lookup("http-upstream-timeout"))
@@ -59,12 +65,27 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("authn.preshared.keys", flags.Lookup("authn-preshared-keys"))
 		util.MustBindEnv("authn.preshared.keys", "OPENFGA_AUTHN_PRESHARED_KEYS")
 
+		util.MustBindPFlag("authn.preshared.keyScopes", flags.Lookup("authn-preshared-key-scopes"))
+		util.MustBindEnv("authn.preshared.keyScopes", "OPENFGA_AUTHN_PRESHARED_KEY_SCOPES", "OPENFGA_AUTHN_PRESHARED_KEYSCOPES")
+
+		util.MustBindPFlag("authn.scopedAccess", flags.Lookup("authn-scoped-access"))
+		util.MustBindEnv("authn.scopedAccess", "OPENFGA_AUTHN_SCOPED_ACCESS", "OPENFGA_AUTHN_SCOPEDACCESS")
+
 		util.MustBindPFlag("authn.oidc.audience", flags.Lookup("authn-oidc-audience"))
 		util.MustBindEnv("authn.oidc.audience", "OPENFGA_AUTHN_OIDC_AUDIENCE")
 
 		util.MustBindPFlag("authn.oidc.issuer", flags.Lookup("authn-oidc-issuer"))
 		util.MustBindEnv("authn.oidc.issuer", "OPENFGA_AUTHN_OIDC_ISSUER")
 
+		util.MustBindPFlag("authn.oidc.additionalIssuers", flags.Lookup("authn-oidc-additional-issuers"))
+		util.MustBindEnv("authn.oidc.additionalIssuers", "OPENFGA_AUTHN_OIDC_ADDITIONAL_ISSUERS", "OPENFGA_AUTHN_OIDC_ADDITIONALISSUERS")
+
+		util.MustBindPFlag("authn.oidc.requiredScopes", flags.Lookup("authn-oidc-required-scopes"))
+		util.MustBindEnv("authn.oidc.requiredScopes", "OPENFGA_AUTHN_OIDC_REQUIRED_SCOPES", "OPENFGA_AUTHN_OIDC_REQUIREDSCOPES")
+
+		util.MustBindPFlag("authn.oidc.jwksRefreshInterval", flags.Lookup("authn-oidc-jwks-refresh-interval"))
+		util.MustBindEnv("authn.oidc.jwksRefreshInterval", "OPENFGA_AUTHN_OIDC_JWKS_REFRESH_INTERVAL", "OPENFGA_AUTHN_OIDC_JWKSREFRESHINTERVAL")
+
 		util.MustBindPFlag("datastore.engine", flags.Lookup("datastore-engine"))
 		util.MustBindEnv("datastore.engine", "OPENFGA_DATASTORE_ENGINE")
 
@@ -92,6 +113,72 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("datastore.connMaxLifetime", flags.Lookup("datastore-conn-max-lifetime"))
 		util.MustBindEnv("datastore.connMaxLifetime", "OPENFGA_DATASTORE_CONN_MAX_LIFETIME", "OPENFGA_DATASTORE_CONNMAXLIFETIME")
 
+		util.MustBindPFlag("datastore.connAcquireTimeout", flags.Lookup("datastore-conn-acquire-timeout"))
+		util.MustBindEnv("datastore.connAcquireTimeout", "OPENFGA_DATASTORE_CONN_ACQUIRE_TIMEOUT", "OPENFGA_DATASTORE_CONNACQUIRETIMEOUT")
+
+		util.MustBindPFlag("datastore.maxTuples", flags.Lookup("datastore-max-tuples"))
+		util.MustBindEnv("datastore.maxTuples", "OPENFGA_DATASTORE_MAX_TUPLES", "OPENFGA_DATASTORE_MAXTUPLES")
+
+		util.MustBindPFlag("datastore.maxChanges", flags.Lookup("datastore-max-changes"))
+		util.MustBindEnv("datastore.maxChanges", "OPENFGA_DATASTORE_MAX_CHANGES", "OPENFGA_DATASTORE_MAXCHANGES")
+
+		util.MustBindPFlag("datastore.followerReadStaleness", flags.Lookup("datastore-follower-read-staleness"))
+		util.MustBindEnv("datastore.followerReadStaleness", "OPENFGA_DATASTORE_FOLLOWER_READ_STALENESS", "OPENFGA_DATASTORE_FOLLOWERREADSTALENESS")
+
+		util.MustBindPFlag("datastore.readURI", flags.Lookup("datastore-read-uri"))
+		util.MustBindEnv("datastore.readURI", "OPENFGA_DATASTORE_READ_URI", "OPENFGA_DATASTORE_READURI")
+
+		util.MustBindPFlag("datastore.replicaMaxLag", flags.Lookup("datastore-replica-max-lag"))
+		util.MustBindEnv("datastore.replicaMaxLag", "OPENFGA_DATASTORE_REPLICA_MAX_LAG", "OPENFGA_DATASTORE_REPLICAMAXLAG")
+
+		util.MustBindPFlag("datastore.serializeWrites", flags.Lookup("datastore-serialize-writes"))
+		util.MustBindEnv("datastore.serializeWrites", "OPENFGA_DATASTORE_SERIALIZE_WRITES", "OPENFGA_DATASTORE_SERIALIZEWRITES")
+
+		util.MustBindPFlag("datastore.shards", flags.Lookup("datastore-shards"))
+		util.MustBindEnv("datastore.shards", "OPENFGA_DATASTORE_SHARDS")
+
+		util.MustBindPFlag("datastore.drainingShards", flags.Lookup("datastore-draining-shards"))
+		util.MustBindEnv("datastore.drainingShards", "OPENFGA_DATASTORE_DRAINING_SHARDS", "OPENFGA_DATASTORE_DRAININGSHARDS")
+
+		util.MustBindPFlag("datastore.shardPins", flags.Lookup("datastore-shard-pins"))
+		util.MustBindEnv("datastore.shardPins", "OPENFGA_DATASTORE_SHARD_PINS", "OPENFGA_DATASTORE_SHARDPINS")
+
+		util.MustBindPFlag("datastore.tenants", flags.Lookup("datastore-tenants"))
+		util.MustBindEnv("datastore.tenants", "OPENFGA_DATASTORE_TENANTS")
+
+		util.MustBindPFlag("datastore.tenantRoutes", flags.Lookup("datastore-tenant-routes"))
+		util.MustBindEnv("datastore.tenantRoutes", "OPENFGA_DATASTORE_TENANT_ROUTES", "OPENFGA_DATASTORE_TENANTROUTES")
+
+		util.MustBindPFlag("datastore.userEncryptionKey", flags.Lookup("datastore-user-encryption-key"))
+		util.MustBindEnv("datastore.userEncryptionKey", "OPENFGA_DATASTORE_USER_ENCRYPTION_KEY", "OPENFGA_DATASTORE_USERENCRYPTIONKEY")
+
+		util.MustBindPFlag("continuationTokenFormat", flags.Lookup("continuation-token-format"))
+		util.MustBindEnv("continuationTokenFormat", "OPENFGA_CONTINUATION_TOKEN_FORMAT", "OPENFGA_CONTINUATIONTOKENFORMAT")
+
+		util.MustBindPFlag("tokenEncryption.key", flags.Lookup("token-encryption-key"))
+		util.MustBindEnv("tokenEncryption.key", "OPENFGA_TOKEN_ENCRYPTION_KEY", "OPENFGA_TOKENENCRYPTION_KEY")
+
+		util.MustBindPFlag("tokenEncryption.storeKeys", flags.Lookup("token-encryption-store-keys"))
+		util.MustBindEnv("tokenEncryption.storeKeys", "OPENFGA_TOKEN_ENCRYPTION_STORE_KEYS", "OPENFGA_TOKENENCRYPTION_STOREKEYS")
+
+		util.MustBindPFlag("tokenEncryption.keys", flags.Lookup("token-encryption-keys"))
+		util.MustBindEnv("tokenEncryption.keys", "OPENFGA_TOKEN_ENCRYPTION_KEYS", "OPENFGA_TOKENENCRYPTION_KEYS")
+
+		util.MustBindPFlag("tokenEncryption.primaryKeyID", flags.Lookup("token-encryption-primary-key-id"))
+		util.MustBindEnv("tokenEncryption.primaryKeyID", "OPENFGA_TOKEN_ENCRYPTION_PRIMARY_KEY_ID", "OPENFGA_TOKENENCRYPTION_PRIMARYKEYID")
+
+		util.MustBindPFlag("tokenSigning.algorithm", flags.Lookup("token-signing-algorithm"))
+		util.MustBindEnv("tokenSigning.algorithm", "OPENFGA_TOKEN_SIGNING_ALGORITHM", "OPENFGA_TOKENSIGNING_ALGORITHM")
+
+		util.MustBindPFlag("tokenSigning.key", flags.Lookup("token-signing-key"))
+		util.MustBindEnv("tokenSigning.key", "OPENFGA_TOKEN_SIGNING_KEY", "OPENFGA_TOKENSIGNING_KEY")
+
+		util.MustBindPFlag("tokenSigning.privateKeyPath", flags.Lookup("token-signing-private-key-path"))
+		util.MustBindEnv("tokenSigning.privateKeyPath", "OPENFGA_TOKEN_SIGNING_PRIVATE_KEY_PATH", "OPENFGA_TOKENSIGNING_PRIVATEKEYPATH")
+
+		util.MustBindPFlag("tokenSigning.ttl", flags.Lookup("token-signing-ttl"))
+		util.MustBindEnv("tokenSigning.ttl", "OPENFGA_TOKEN_SIGNING_TTL", "OPENFGA_TOKENSIGNING_TTL")
+
 		util.MustBindPFlag("playground.enabled", flags.Lookup("playground-enabled"))
 		util.MustBindEnv("playground.enabled", "OPENFGA_PLAYGROUND_ENABLED")
 
@@ -101,8 +188,17 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("profiler.enabled", flags.Lookup("profiler-enabled"))
 		util.MustBindEnv("profiler.enabled", "OPENFGA_PROFILER_ENABLED")
 
-		util.MustBindPFlag("profiler.addr", flags.Lookup("profiler-addr"))
-		util.MustBindEnv("profiler.addr", "OPENFGA_PROFILER_ADDRESS")
+		util.MustBindPFlag("profiler.maxProfileDuration", flags.Lookup("profiler-max-profile-duration"))
+		util.MustBindEnv("profiler.maxProfileDuration", "OPENFGA_PROFILER_MAX_PROFILE_DURATION", "OPENFGA_PROFILER_MAXPROFILEDURATION")
+
+		util.MustBindPFlag("profiler.autoCPUProfile.checkP99Threshold", flags.Lookup("profiler-auto-cpu-profile-check-p99-threshold"))
+		util.MustBindEnv("profiler.autoCPUProfile.checkP99Threshold", "OPENFGA_PROFILER_AUTO_CPU_PROFILE_CHECK_P99_THRESHOLD", "OPENFGA_PROFILER_AUTOCPUPROFILE_CHECKP99THRESHOLD")
+
+		util.MustBindPFlag("profiler.autoCPUProfile.duration", flags.Lookup("profiler-auto-cpu-profile-duration"))
+		util.MustBindEnv("profiler.autoCPUProfile.duration", "OPENFGA_PROFILER_AUTO_CPU_PROFILE_DURATION", "OPENFGA_PROFILER_AUTOCPUPROFILE_DURATION")
+
+		util.MustBindPFlag("profiler.autoCPUProfile.dir", flags.Lookup("profiler-auto-cpu-profile-dir"))
+		util.MustBindEnv("profiler.autoCPUProfile.dir", "OPENFGA_PROFILER_AUTO_CPU_PROFILE_DIR", "OPENFGA_PROFILER_AUTOCPUPROFILE_DIR")
 
 		util.MustBindPFlag("log.format", flags.Lookup("log-format"))
 		util.MustBindEnv("log.format", "OPENFGA_LOG_FORMAT")
@@ -131,6 +227,333 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("metrics.enableRPCHistograms", flags.Lookup("metrics-enable-rpc-histograms"))
 		util.MustBindEnv("metrics.enableRPCHistograms", "OPENFGA_METRICS_ENABLE_RPC_HISTOGRAMS")
 
+		util.MustBindPFlag("metrics.enablePerStoreLabels", flags.Lookup("metrics-enable-per-store-labels"))
+		util.MustBindEnv("metrics.enablePerStoreLabels", "OPENFGA_METRICS_ENABLE_PER_STORE_LABELS", "OPENFGA_METRICS_ENABLEPERSTORELABELS")
+
+		util.MustBindPFlag("metrics.maxStoreLabels", flags.Lookup("metrics-max-store-labels"))
+		util.MustBindEnv("metrics.maxStoreLabels", "OPENFGA_METRICS_MAX_STORE_LABELS", "OPENFGA_METRICS_MAXSTORELABELS")
+
+		util.MustBindPFlag("health.probeTimeout", flags.Lookup("health-probe-timeout"))
+		util.MustBindEnv("health.probeTimeout", "OPENFGA_HEALTH_PROBE_TIMEOUT", "OPENFGA_HEALTH_PROBETIMEOUT")
+
+		util.MustBindPFlag("reload.enabled", flags.Lookup("reload-enabled"))
+		util.MustBindEnv("reload.enabled", "OPENFGA_RELOAD_ENABLED")
+
+		util.MustBindPFlag("reload.watchInterval", flags.Lookup("reload-watch-interval"))
+		util.MustBindEnv("reload.watchInterval", "OPENFGA_RELOAD_WATCH_INTERVAL", "OPENFGA_RELOAD_WATCHINTERVAL")
+
+		util.MustBindPFlag("admin.enabled", flags.Lookup("admin-enabled"))
+		util.MustBindEnv("admin.enabled", "OPENFGA_ADMIN_ENABLED")
+
+		util.MustBindPFlag("admin.addr", flags.Lookup("admin-addr"))
+		util.MustBindEnv("admin.addr", "OPENFGA_ADMIN_ADDRESS")
+
+		util.MustBindPFlag("admin.presharedKeys", flags.Lookup("admin-preshared-keys"))
+		util.MustBindEnv("admin.presharedKeys", "OPENFGA_ADMIN_PRESHARED_KEYS", "OPENFGA_ADMIN_PRESHAREDKEYS")
+
+		util.MustBindPFlag("loadShedding.enabled", flags.Lookup("load-shedding-enabled"))
+		util.MustBindEnv("loadShedding.enabled", "OPENFGA_LOAD_SHEDDING_ENABLED", "OPENFGA_LOADSHEDDING_ENABLED")
+
+		util.MustBindPFlag("loadShedding.degradedLatencyThreshold", flags.Lookup("load-shedding-degraded-latency-threshold"))
+		util.MustBindEnv("loadShedding.degradedLatencyThreshold", "OPENFGA_LOAD_SHEDDING_DEGRADED_LATENCY_THRESHOLD", "OPENFGA_LOADSHEDDING_DEGRADEDLATENCYTHRESHOLD")
+
+		util.MustBindPFlag("loadShedding.criticalLatencyThreshold", flags.Lookup("load-shedding-critical-latency-threshold"))
+		util.MustBindEnv("loadShedding.criticalLatencyThreshold", "OPENFGA_LOAD_SHEDDING_CRITICAL_LATENCY_THRESHOLD", "OPENFGA_LOADSHEDDING_CRITICALLATENCYTHRESHOLD")
+
+		util.MustBindPFlag("loadShedding.errorRateThreshold", flags.Lookup("load-shedding-error-rate-threshold"))
+		util.MustBindEnv("loadShedding.errorRateThreshold", "OPENFGA_LOAD_SHEDDING_ERROR_RATE_THRESHOLD", "OPENFGA_LOADSHEDDING_ERRORRATETHRESHOLD")
+
+		util.MustBindPFlag("checkQueryCache.enabled", flags.Lookup("check-query-cache-enabled"))
+		util.MustBindEnv("checkQueryCache.enabled", "OPENFGA_CHECK_QUERY_CACHE_ENABLED", "OPENFGA_CHECKQUERYCACHE_ENABLED")
+
+		util.MustBindPFlag("checkDeduplication.enabled", flags.Lookup("check-deduplication-enabled"))
+		util.MustBindEnv("checkDeduplication.enabled", "OPENFGA_CHECK_DEDUPLICATION_ENABLED", "OPENFGA_CHECKDEDUPLICATION_ENABLED")
+
+		util.MustBindPFlag("typesystemCache.latestModelTTL", flags.Lookup("typesystem-cache-latest-model-ttl"))
+		util.MustBindEnv("typesystemCache.latestModelTTL", "OPENFGA_TYPESYSTEM_CACHE_LATEST_MODEL_TTL", "OPENFGA_TYPESYSTEMCACHE_LATESTMODELTTL")
+
+		util.MustBindPFlag("checkQueryCache.limit", flags.Lookup("check-query-cache-limit"))
+		util.MustBindEnv("checkQueryCache.limit", "OPENFGA_CHECK_QUERY_CACHE_LIMIT", "OPENFGA_CHECKQUERYCACHE_LIMIT")
+
+		util.MustBindPFlag("checkQueryCache.ttl", flags.Lookup("check-query-cache-ttl"))
+		util.MustBindEnv("checkQueryCache.ttl", "OPENFGA_CHECK_QUERY_CACHE_TTL", "OPENFGA_CHECKQUERYCACHE_TTL")
+
+		util.MustBindPFlag("listObjectsCache.enabled", flags.Lookup("list-objects-cache-enabled"))
+		util.MustBindEnv("listObjectsCache.enabled", "OPENFGA_LIST_OBJECTS_CACHE_ENABLED", "OPENFGA_LISTOBJECTSCACHE_ENABLED")
+
+		util.MustBindPFlag("listObjectsCache.limit", flags.Lookup("list-objects-cache-limit"))
+		util.MustBindEnv("listObjectsCache.limit", "OPENFGA_LIST_OBJECTS_CACHE_LIMIT", "OPENFGA_LISTOBJECTSCACHE_LIMIT")
+
+		util.MustBindPFlag("listObjectsCache.ttl", flags.Lookup("list-objects-cache-ttl"))
+		util.MustBindEnv("listObjectsCache.ttl", "OPENFGA_LIST_OBJECTS_CACHE_TTL", "OPENFGA_LISTOBJECTSCACHE_TTL")
+
+		util.MustBindPFlag("listObjectsCache.changelogInterval", flags.Lookup("list-objects-cache-changelog-interval"))
+		util.MustBindEnv("listObjectsCache.changelogInterval", "OPENFGA_LIST_OBJECTS_CACHE_CHANGELOG_INTERVAL", "OPENFGA_LISTOBJECTSCACHE_CHANGELOGINTERVAL")
+
+		util.MustBindPFlag("rateLimit.enabled", flags.Lookup("rate-limit-enabled"))
+		util.MustBindEnv("rateLimit.enabled", "OPENFGA_RATE_LIMIT_ENABLED", "OPENFGA_RATELIMIT_ENABLED")
+
+		util.MustBindPFlag("rateLimit.requestsPerSecond", flags.Lookup("rate-limit-requests-per-second"))
+		util.MustBindEnv("rateLimit.requestsPerSecond", "OPENFGA_RATE_LIMIT_REQUESTS_PER_SECOND", "OPENFGA_RATELIMIT_REQUESTSPERSECOND")
+
+		util.MustBindPFlag("rateLimit.burst", flags.Lookup("rate-limit-burst"))
+		util.MustBindEnv("rateLimit.burst", "OPENFGA_RATE_LIMIT_BURST", "OPENFGA_RATELIMIT_BURST")
+
+		util.MustBindPFlag("rateLimit.methods", flags.Lookup("rate-limit-methods"))
+		util.MustBindEnv("rateLimit.methods", "OPENFGA_RATE_LIMIT_METHODS", "OPENFGA_RATELIMIT_METHODS")
+
+		util.MustBindPFlag("rateLimit.maxInFlightRequests", flags.Lookup("rate-limit-max-in-flight-requests"))
+		util.MustBindEnv("rateLimit.maxInFlightRequests", "OPENFGA_RATE_LIMIT_MAX_IN_FLIGHT_REQUESTS", "OPENFGA_RATELIMIT_MAXINFLIGHTREQUESTS")
+
+		util.MustBindPFlag("rateLimit.perClient", flags.Lookup("rate-limit-per-client"))
+		util.MustBindEnv("rateLimit.perClient", "OPENFGA_RATE_LIMIT_PER_CLIENT", "OPENFGA_RATELIMIT_PERCLIENT")
+
+		util.MustBindPFlag("scheduler.enabled", flags.Lookup("scheduler-enabled"))
+		util.MustBindEnv("scheduler.enabled", "OPENFGA_SCHEDULER_ENABLED")
+
+		util.MustBindPFlag("scheduler.maxConcurrentRequests", flags.Lookup("scheduler-max-concurrent-requests"))
+		util.MustBindEnv("scheduler.maxConcurrentRequests", "OPENFGA_SCHEDULER_MAX_CONCURRENT_REQUESTS", "OPENFGA_SCHEDULER_MAXCONCURRENTREQUESTS")
+
+		util.MustBindPFlag("scheduler.maxConcurrentBatchRequests", flags.Lookup("scheduler-max-concurrent-batch-requests"))
+		util.MustBindEnv("scheduler.maxConcurrentBatchRequests", "OPENFGA_SCHEDULER_MAX_CONCURRENT_BATCH_REQUESTS", "OPENFGA_SCHEDULER_MAXCONCURRENTBATCHREQUESTS")
+
+		util.MustBindPFlag("scheduler.queueTimeout", flags.Lookup("scheduler-queue-timeout"))
+		util.MustBindEnv("scheduler.queueTimeout", "OPENFGA_SCHEDULER_QUEUE_TIMEOUT", "OPENFGA_SCHEDULER_QUEUETIMEOUT")
+
+		util.MustBindPFlag("scheduler.batchMethods", flags.Lookup("scheduler-batch-methods"))
+		util.MustBindEnv("scheduler.batchMethods", "OPENFGA_SCHEDULER_BATCH_METHODS", "OPENFGA_SCHEDULER_BATCHMETHODS")
+
+		util.MustBindPFlag("requestFeatureFlags.enabled", flags.Lookup("request-feature-flags-enabled"))
+		util.MustBindEnv("requestFeatureFlags.enabled", "OPENFGA_REQUEST_FEATURE_FLAGS_ENABLED", "OPENFGA_REQUESTFEATUREFLAGS_ENABLED")
+
+		util.MustBindPFlag("requestFeatureFlags.principals", flags.Lookup("request-feature-flags-principals"))
+		util.MustBindEnv("requestFeatureFlags.principals", "OPENFGA_REQUEST_FEATURE_FLAGS_PRINCIPALS", "OPENFGA_REQUESTFEATUREFLAGS_PRINCIPALS")
+
+		util.MustBindPFlag("requestFeatureFlags.resolveNodeLimit", flags.Lookup("request-feature-flags-resolve-node-limit"))
+		util.MustBindEnv("requestFeatureFlags.resolveNodeLimit", "OPENFGA_REQUEST_FEATURE_FLAGS_RESOLVE_NODE_LIMIT", "OPENFGA_REQUESTFEATUREFLAGS_RESOLVENODELIMIT")
+
+		util.MustBindPFlag("shadow.enabled", flags.Lookup("shadow-enabled"))
+		util.MustBindEnv("shadow.enabled", "OPENFGA_SHADOW_ENABLED")
+
+		util.MustBindPFlag("shadow.sampleRate", flags.Lookup("shadow-sample-rate"))
+		util.MustBindEnv("shadow.sampleRate", "OPENFGA_SHADOW_SAMPLE_RATE", "OPENFGA_SHADOW_SAMPLERATE")
+
+		util.MustBindPFlag("shadow.timeout", flags.Lookup("shadow-timeout"))
+		util.MustBindEnv("shadow.timeout", "OPENFGA_SHADOW_TIMEOUT")
+
+		util.MustBindPFlag("shadow.maxConcurrentEvaluations", flags.Lookup("shadow-max-concurrent-evaluations"))
+		util.MustBindEnv("shadow.maxConcurrentEvaluations", "OPENFGA_SHADOW_MAX_CONCURRENT_EVALUATIONS", "OPENFGA_SHADOW_MAXCONCURRENTEVALUATIONS")
+
+		util.MustBindPFlag("shadow.slowdownThreshold", flags.Lookup("shadow-slowdown-threshold"))
+		util.MustBindEnv("shadow.slowdownThreshold", "OPENFGA_SHADOW_SLOWDOWN_THRESHOLD", "OPENFGA_SHADOW_SLOWDOWNTHRESHOLD")
+
+		util.MustBindPFlag("shadow.check", flags.Lookup("shadow-check"))
+		util.MustBindEnv("shadow.check", "OPENFGA_SHADOW_CHECK")
+
+		util.MustBindPFlag("shadow.listObjectsStrategy", flags.Lookup("shadow-list-objects-strategy"))
+		util.MustBindEnv("shadow.listObjectsStrategy", "OPENFGA_SHADOW_LIST_OBJECTS_STRATEGY", "OPENFGA_SHADOW_LISTOBJECTSSTRATEGY")
+
+		util.MustBindPFlag("requestTimeout.default", flags.Lookup("request-timeout"))
+		util.MustBindEnv("requestTimeout.default", "OPENFGA_REQUEST_TIMEOUT", "OPENFGA_REQUESTTIMEOUT_DEFAULT")
+
+		util.MustBindPFlag("requestTimeout.methods", flags.Lookup("request-timeout-methods"))
+		util.MustBindEnv("requestTimeout.methods", "OPENFGA_REQUEST_TIMEOUT_METHODS", "OPENFGA_REQUESTTIMEOUT_METHODS")
+
+		util.MustBindPFlag("quotas.maxTuplesPerStore", flags.Lookup("quotas-max-tuples-per-store"))
+		util.MustBindEnv("quotas.maxTuplesPerStore", "OPENFGA_QUOTAS_MAX_TUPLES_PER_STORE", "OPENFGA_QUOTAS_MAXTUPLESPERSTORE")
+
+		util.MustBindPFlag("quotas.maxTypesPerAuthorizationModel", flags.Lookup("quotas-max-types-per-authorization-model"))
+		util.MustBindEnv("quotas.maxTypesPerAuthorizationModel", "OPENFGA_QUOTAS_MAX_TYPES_PER_AUTHORIZATION_MODEL", "OPENFGA_QUOTAS_MAXTYPESPERAUTHORIZATIONMODEL")
+
+		util.MustBindPFlag("quotas.maxRelationsPerType", flags.Lookup("quotas-max-relations-per-type"))
+		util.MustBindEnv("quotas.maxRelationsPerType", "OPENFGA_QUOTAS_MAX_RELATIONS_PER_TYPE", "OPENFGA_QUOTAS_MAXRELATIONSPERTYPE")
+
+		util.MustBindPFlag("quotas.maxRewriteDepth", flags.Lookup("quotas-max-rewrite-depth"))
+		util.MustBindEnv("quotas.maxRewriteDepth", "OPENFGA_QUOTAS_MAX_REWRITE_DEPTH", "OPENFGA_QUOTAS_MAXREWRITEDEPTH")
+
+		util.MustBindPFlag("quotas.maxAuthorizationModelSizeInBytes", flags.Lookup("quotas-max-authorization-model-size-in-bytes"))
+		util.MustBindEnv("quotas.maxAuthorizationModelSizeInBytes", "OPENFGA_QUOTAS_MAX_AUTHORIZATION_MODEL_SIZE_IN_BYTES", "OPENFGA_QUOTAS_MAXAUTHORIZATIONMODELSIZEINBYTES")
+
+		util.MustBindPFlag("quotas.maxWritesPerSecond", flags.Lookup("quotas-max-writes-per-second"))
+		util.MustBindEnv("quotas.maxWritesPerSecond", "OPENFGA_QUOTAS_MAX_WRITES_PER_SECOND", "OPENFGA_QUOTAS_MAXWRITESPERSECOND")
+
+		util.MustBindPFlag("listObjectsPlanner.enabled", flags.Lookup("listObjects-planner-enabled"))
+		util.MustBindEnv("listObjectsPlanner.enabled", "OPENFGA_LIST_OBJECTS_PLANNER_ENABLED", "OPENFGA_LISTOBJECTSPLANNER_ENABLED")
+
+		util.MustBindPFlag("listObjectsPlanner.statisticsTTL", flags.Lookup("listObjects-planner-statistics-ttl"))
+		util.MustBindEnv("listObjectsPlanner.statisticsTTL", "OPENFGA_LIST_OBJECTS_PLANNER_STATISTICS_TTL", "OPENFGA_LISTOBJECTSPLANNER_STATISTICSTTL")
+
+		util.MustBindPFlag("listObjectsPlanner.sampleSize", flags.Lookup("listObjects-planner-sample-size"))
+		util.MustBindEnv("listObjectsPlanner.sampleSize", "OPENFGA_LIST_OBJECTS_PLANNER_SAMPLE_SIZE", "OPENFGA_LISTOBJECTSPLANNER_SAMPLESIZE")
+
+		util.MustBindPFlag("storeStats.cacheTTL", flags.Lookup("store-stats-cache-ttl"))
+		util.MustBindEnv("storeStats.cacheTTL", "OPENFGA_STORE_STATS_CACHE_TTL", "OPENFGA_STORESTATS_CACHETTL")
+
+		util.MustBindPFlag("modelModules.dir", flags.Lookup("model-modules-dir"))
+		util.MustBindEnv("modelModules.dir", "OPENFGA_MODEL_MODULES_DIR", "OPENFGA_MODELMODULES_DIR")
+
+		util.MustBindPFlag("cache.backend", flags.Lookup("cache-backend"))
+		util.MustBindEnv("cache.backend", "OPENFGA_CACHE_BACKEND")
+
+		util.MustBindPFlag("cache.redis.addr", flags.Lookup("cache-redis-addr"))
+		util.MustBindEnv("cache.redis.addr", "OPENFGA_CACHE_REDIS_ADDR")
+
+		util.MustBindPFlag("cache.redis.password", flags.Lookup("cache-redis-password"))
+		util.MustBindEnv("cache.redis.password", "OPENFGA_CACHE_REDIS_PASSWORD")
+
+		util.MustBindPFlag("cache.redis.db", flags.Lookup("cache-redis-db"))
+		util.MustBindEnv("cache.redis.db", "OPENFGA_CACHE_REDIS_DB")
+
+		util.MustBindPFlag("cache.redis.keyPrefix", flags.Lookup("cache-redis-key-prefix"))
+		util.MustBindEnv("cache.redis.keyPrefix", "OPENFGA_CACHE_REDIS_KEY_PREFIX", "OPENFGA_CACHE_REDIS_KEYPREFIX")
+
+		util.MustBindPFlag("changelogExport.enabled", flags.Lookup("changelog-export-enabled"))
+		util.MustBindEnv("changelogExport.enabled", "OPENFGA_CHANGELOG_EXPORT_ENABLED", "OPENFGA_CHANGELOGEXPORT_ENABLED")
+
+		util.MustBindPFlag("changelogExport.sink", flags.Lookup("changelog-export-sink"))
+		util.MustBindEnv("changelogExport.sink", "OPENFGA_CHANGELOG_EXPORT_SINK", "OPENFGA_CHANGELOGEXPORT_SINK")
+
+		util.MustBindPFlag("changelogExport.pollInterval", flags.Lookup("changelog-export-poll-interval"))
+		util.MustBindEnv("changelogExport.pollInterval", "OPENFGA_CHANGELOG_EXPORT_POLL_INTERVAL", "OPENFGA_CHANGELOGEXPORT_POLLINTERVAL")
+
+		util.MustBindPFlag("changelogExport.pageSize", flags.Lookup("changelog-export-page-size"))
+		util.MustBindEnv("changelogExport.pageSize", "OPENFGA_CHANGELOG_EXPORT_PAGE_SIZE", "OPENFGA_CHANGELOGEXPORT_PAGESIZE")
+
+		util.MustBindPFlag("changelogExport.checkpointFile", flags.Lookup("changelog-export-checkpoint-file"))
+		util.MustBindEnv("changelogExport.checkpointFile", "OPENFGA_CHANGELOG_EXPORT_CHECKPOINT_FILE", "OPENFGA_CHANGELOGEXPORT_CHECKPOINTFILE")
+
+		util.MustBindPFlag("changelogExport.kafka.brokers", flags.Lookup("changelog-export-kafka-brokers"))
+		util.MustBindEnv("changelogExport.kafka.brokers", "OPENFGA_CHANGELOG_EXPORT_KAFKA_BROKERS", "OPENFGA_CHANGELOGEXPORT_KAFKA_BROKERS")
+
+		util.MustBindPFlag("changelogExport.kafka.topic", flags.Lookup("changelog-export-kafka-topic"))
+		util.MustBindEnv("changelogExport.kafka.topic", "OPENFGA_CHANGELOG_EXPORT_KAFKA_TOPIC", "OPENFGA_CHANGELOGEXPORT_KAFKA_TOPIC")
+
+		util.MustBindPFlag("changelogExport.nats.url", flags.Lookup("changelog-export-nats-url"))
+		util.MustBindEnv("changelogExport.nats.url", "OPENFGA_CHANGELOG_EXPORT_NATS_URL", "OPENFGA_CHANGELOGEXPORT_NATS_URL")
+
+		util.MustBindPFlag("changelogExport.nats.subject", flags.Lookup("changelog-export-nats-subject"))
+		util.MustBindEnv("changelogExport.nats.subject", "OPENFGA_CHANGELOG_EXPORT_NATS_SUBJECT", "OPENFGA_CHANGELOGEXPORT_NATS_SUBJECT")
+
+		util.MustBindPFlag("changelogExport.webhook.url", flags.Lookup("changelog-export-webhook-url"))
+		util.MustBindEnv("changelogExport.webhook.url", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_URL", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_URL")
+
+		util.MustBindPFlag("changelogExport.webhook.urls", flags.Lookup("changelog-export-webhook-urls"))
+		util.MustBindEnv("changelogExport.webhook.urls", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_URLS", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_URLS")
+
+		util.MustBindPFlag("changelogExport.webhook.secret", flags.Lookup("changelog-export-webhook-secret"))
+		util.MustBindEnv("changelogExport.webhook.secret", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_SECRET", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_SECRET")
+
+		util.MustBindPFlag("changelogExport.webhook.timeout", flags.Lookup("changelog-export-webhook-timeout"))
+		util.MustBindEnv("changelogExport.webhook.timeout", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_TIMEOUT", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_TIMEOUT")
+
+		util.MustBindPFlag("changelogExport.webhook.maxRetries", flags.Lookup("changelog-export-webhook-max-retries"))
+		util.MustBindEnv("changelogExport.webhook.maxRetries", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_MAX_RETRIES", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_MAXRETRIES")
+
+		util.MustBindPFlag("changelogExport.webhook.initialBackoff", flags.Lookup("changelog-export-webhook-initial-backoff"))
+		util.MustBindEnv("changelogExport.webhook.initialBackoff", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_INITIAL_BACKOFF", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_INITIALBACKOFF")
+
+		util.MustBindPFlag("changelogExport.webhook.maxBackoff", flags.Lookup("changelog-export-webhook-max-backoff"))
+		util.MustBindEnv("changelogExport.webhook.maxBackoff", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_MAX_BACKOFF", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_MAXBACKOFF")
+
+		util.MustBindPFlag("changelogExport.webhook.deadLetterFile", flags.Lookup("changelog-export-webhook-dead-letter-file"))
+		util.MustBindEnv("changelogExport.webhook.deadLetterFile", "OPENFGA_CHANGELOG_EXPORT_WEBHOOK_DEAD_LETTER_FILE", "OPENFGA_CHANGELOGEXPORT_WEBHOOK_DEADLETTERFILE")
+
+		util.MustBindPFlag("audit.enabled", flags.Lookup("audit-enabled"))
+		util.MustBindEnv("audit.enabled", "OPENFGA_AUDIT_ENABLED")
+
+		util.MustBindPFlag("audit.sink", flags.Lookup("audit-sink"))
+		util.MustBindEnv("audit.sink", "OPENFGA_AUDIT_SINK")
+
+		util.MustBindPFlag("audit.bufferSize", flags.Lookup("audit-buffer-size"))
+		util.MustBindEnv("audit.bufferSize", "OPENFGA_AUDIT_BUFFER_SIZE", "OPENFGA_AUDIT_BUFFERSIZE")
+
+		util.MustBindPFlag("audit.file.path", flags.Lookup("audit-file-path"))
+		util.MustBindEnv("audit.file.path", "OPENFGA_AUDIT_FILE_PATH")
+
+		util.MustBindPFlag("audit.syslog.network", flags.Lookup("audit-syslog-network"))
+		util.MustBindEnv("audit.syslog.network", "OPENFGA_AUDIT_SYSLOG_NETWORK")
+
+		util.MustBindPFlag("audit.syslog.addr", flags.Lookup("audit-syslog-addr"))
+		util.MustBindEnv("audit.syslog.addr", "OPENFGA_AUDIT_SYSLOG_ADDR")
+
+		util.MustBindPFlag("audit.syslog.tag", flags.Lookup("audit-syslog-tag"))
+		util.MustBindEnv("audit.syslog.tag", "OPENFGA_AUDIT_SYSLOG_TAG")
+
+		util.MustBindPFlag("audit.http.url", flags.Lookup("audit-http-url"))
+		util.MustBindEnv("audit.http.url", "OPENFGA_AUDIT_HTTP_URL")
+
+		util.MustBindPFlag("audit.kafka.brokers", flags.Lookup("audit-kafka-brokers"))
+		util.MustBindEnv("audit.kafka.brokers", "OPENFGA_AUDIT_KAFKA_BROKERS")
+
+		util.MustBindPFlag("audit.kafka.topic", flags.Lookup("audit-kafka-topic"))
+		util.MustBindEnv("audit.kafka.topic", "OPENFGA_AUDIT_KAFKA_TOPIC")
+
+		util.MustBindPFlag("decisionLog.enabled", flags.Lookup("decision-log-enabled"))
+		util.MustBindEnv("decisionLog.enabled", "OPENFGA_DECISION_LOG_ENABLED", "OPENFGA_DECISIONLOG_ENABLED")
+
+		util.MustBindPFlag("decisionLog.output", flags.Lookup("decision-log-output"))
+		util.MustBindEnv("decisionLog.output", "OPENFGA_DECISION_LOG_OUTPUT", "OPENFGA_DECISIONLOG_OUTPUT")
+
+		util.MustBindPFlag("decisionLog.sampleRate", flags.Lookup("decision-log-sample-rate"))
+		util.MustBindEnv("decisionLog.sampleRate", "OPENFGA_DECISION_LOG_SAMPLE_RATE", "OPENFGA_DECISIONLOG_SAMPLERATE")
+
+		util.MustBindPFlag("decisionLog.redactFields", flags.Lookup("decision-log-redact-fields"))
+		util.MustBindEnv("decisionLog.redactFields", "OPENFGA_DECISION_LOG_REDACT_FIELDS", "OPENFGA_DECISIONLOG_REDACTFIELDS")
+
+		util.MustBindPFlag("requestRecording.enabled", flags.Lookup("request-recording-enabled"))
+		util.MustBindEnv("requestRecording.enabled", "OPENFGA_REQUEST_RECORDING_ENABLED", "OPENFGA_REQUESTRECORDING_ENABLED")
+
+		util.MustBindPFlag("requestRecording.output", flags.Lookup("request-recording-output"))
+		util.MustBindEnv("requestRecording.output", "OPENFGA_REQUEST_RECORDING_OUTPUT", "OPENFGA_REQUESTRECORDING_OUTPUT")
+
+		util.MustBindPFlag("requestRecording.sampleRate", flags.Lookup("request-recording-sample-rate"))
+		util.MustBindEnv("requestRecording.sampleRate", "OPENFGA_REQUEST_RECORDING_SAMPLE_RATE", "OPENFGA_REQUESTRECORDING_SAMPLERATE")
+
+		util.MustBindPFlag("requestRecording.anonymizationKey", flags.Lookup("request-recording-anonymization-key"))
+		util.MustBindEnv("requestRecording.anonymizationKey", "OPENFGA_REQUEST_RECORDING_ANONYMIZATION_KEY", "OPENFGA_REQUESTRECORDING_ANONYMIZATIONKEY")
+
+		util.MustBindPFlag("writeWebhook.url", flags.Lookup("write-webhook-url"))
+		util.MustBindEnv("writeWebhook.url", "OPENFGA_WRITE_WEBHOOK_URL", "OPENFGA_WRITEWEBHOOK_URL")
+
+		util.MustBindPFlag("writeWebhook.timeout", flags.Lookup("write-webhook-timeout"))
+		util.MustBindEnv("writeWebhook.timeout", "OPENFGA_WRITE_WEBHOOK_TIMEOUT", "OPENFGA_WRITEWEBHOOK_TIMEOUT")
+
+		util.MustBindPFlag("writeWebhook.failOpen", flags.Lookup("write-webhook-fail-open"))
+		util.MustBindEnv("writeWebhook.failOpen", "OPENFGA_WRITE_WEBHOOK_FAIL_OPEN", "OPENFGA_WRITEWEBHOOK_FAILOPEN")
+
+		util.MustBindPFlag("tupleReaper.enabled", flags.Lookup("tuple-reaper-enabled"))
+		util.MustBindEnv("tupleReaper.enabled", "OPENFGA_TUPLE_REAPER_ENABLED", "OPENFGA_TUPLEREAPER_ENABLED")
+
+		util.MustBindPFlag("tupleReaper.interval", flags.Lookup("tuple-reaper-interval"))
+		util.MustBindEnv("tupleReaper.interval", "OPENFGA_TUPLE_REAPER_INTERVAL", "OPENFGA_TUPLEREAPER_INTERVAL")
+
+		util.MustBindPFlag("tupleReaper.batchSize", flags.Lookup("tuple-reaper-batch-size"))
+		util.MustBindEnv("tupleReaper.batchSize", "OPENFGA_TUPLE_REAPER_BATCH_SIZE", "OPENFGA_TUPLEREAPER_BATCHSIZE")
+
+		util.MustBindPFlag("storeRetention.period", flags.Lookup("store-retention-period"))
+		util.MustBindEnv("storeRetention.period", "OPENFGA_STORE_RETENTION_PERIOD", "OPENFGA_STORERETENTION_PERIOD")
+
+		util.MustBindPFlag("storeRetention.purgeEnabled", flags.Lookup("store-retention-purge-enabled"))
+		util.MustBindEnv("storeRetention.purgeEnabled", "OPENFGA_STORE_RETENTION_PURGE_ENABLED", "OPENFGA_STORERETENTION_PURGEENABLED")
+
+		util.MustBindPFlag("storeRetention.purgeInterval", flags.Lookup("store-retention-purge-interval"))
+		util.MustBindEnv("storeRetention.purgeInterval", "OPENFGA_STORE_RETENTION_PURGE_INTERVAL", "OPENFGA_STORERETENTION_PURGEINTERVAL")
+
+		util.MustBindPFlag("storeRetention.purgeBatchSize", flags.Lookup("store-retention-purge-batch-size"))
+		util.MustBindEnv("storeRetention.purgeBatchSize", "OPENFGA_STORE_RETENTION_PURGE_BATCH_SIZE", "OPENFGA_STORERETENTION_PURGEBATCHSIZE")
+
+		util.MustBindPFlag("changelogRetention.enabled", flags.Lookup("changelog-retention-enabled"))
+		util.MustBindEnv("changelogRetention.enabled", "OPENFGA_CHANGELOG_RETENTION_ENABLED", "OPENFGA_CHANGELOGRETENTION_ENABLED")
+
+		util.MustBindPFlag("changelogRetention.period", flags.Lookup("changelog-retention-period"))
+		util.MustBindEnv("changelogRetention.period", "OPENFGA_CHANGELOG_RETENTION_PERIOD", "OPENFGA_CHANGELOGRETENTION_PERIOD")
+
+		util.MustBindPFlag("changelogRetention.interval", flags.Lookup("changelog-retention-interval"))
+		util.MustBindEnv("changelogRetention.interval", "OPENFGA_CHANGELOG_RETENTION_INTERVAL", "OPENFGA_CHANGELOGRETENTION_INTERVAL")
+
+		util.MustBindPFlag("changelogRetention.batchSize", flags.Lookup("changelog-retention-batch-size"))
+		util.MustBindEnv("changelogRetention.batchSize", "OPENFGA_CHANGELOG_RETENTION_BATCH_SIZE", "OPENFGA_CHANGELOGRETENTION_BATCHSIZE")
+
 		util.MustBindPFlag("maxTuplesPerWrite", flags.Lookup("max-tuples-per-write"))
 		util.MustBindEnv("maxTuplesPerWrite", "OPENFGA_MAX_TUPLES_PER_WRITE", "OPENFGA_MAXTUPLESPERWRITE")
 
@@ -140,9 +563,18 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("maxConcurrentReadsForListObjects", flags.Lookup("max-concurrent-reads-for-list-objects"))
 		util.MustBindEnv("maxConcurrentReadsForListObjects", "OPENFGA_MAX_CONCURRENT_READS_FOR_LIST_OBJECTS", "OPENFGA_MAXCONCURRENTREADSFORLISTOBJECTS")
 
+		util.MustBindPFlag("listObjectsMaxConcurrentChecks", flags.Lookup("listObjects-max-concurrent-checks"))
+		util.MustBindEnv("listObjectsMaxConcurrentChecks", "OPENFGA_LIST_OBJECTS_MAX_CONCURRENT_CHECKS", "OPENFGA_LISTOBJECTSMAXCONCURRENTCHECKS")
+
 		util.MustBindPFlag("maxConcurrentReadsForCheck", flags.Lookup("max-concurrent-reads-for-check"))
 		util.MustBindEnv("maxConcurrentReadsForCheck", "OPENFGA_MAX_CONCURRENT_READS_FOR_CHECK", "OPENFGA_MAXCONCURRENTREADSFORCHECK")
 
+		util.MustBindPFlag("maxDispatchCountPerCheck", flags.Lookup("max-dispatch-count-per-check"))
+		util.MustBindEnv("maxDispatchCountPerCheck", "OPENFGA_MAX_DISPATCH_COUNT_PER_CHECK", "OPENFGA_MAXDISPATCHCOUNTPERCHECK")
+
+		util.MustBindPFlag("maxDatastoreReadsPerCheck", flags.Lookup("max-datastore-reads-per-check"))
+		util.MustBindEnv("maxDatastoreReadsPerCheck", "OPENFGA_MAX_DATASTORE_READS_PER_CHECK", "OPENFGA_MAXDATASTOREREADSPERCHECK")
+
 		util.MustBindPFlag("changelogHorizonOffset", flags.Lookup("changelog-horizon-offset"))
 		util.MustBindEnv("changelogHorizonOffset", "OPENFGA_CHANGELOG_HORIZON_OFFSET", "OPENFGA_CHANGELOGHORIZONOFFSET")
 
@@ -152,10 +584,28 @@ func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
 		util.MustBindPFlag("resolveNodeBreadthLimit", flags.Lookup("resolve-node-breadth-limit"))
 		util.MustBindEnv("resolveNodeBreadthLimit", "OPENFGA_RESOLVE_NODE_BREADTH_LIMIT", "OPENFGA_RESOLVENODEBREADTHLIMIT")
 
+		util.MustBindPFlag("expandDepth", flags.Lookup("expand-depth"))
+		util.MustBindEnv("expandDepth", "OPENFGA_EXPAND_DEPTH", "OPENFGA_EXPANDDEPTH")
+
+		util.MustBindPFlag("readOnly", flags.Lookup("read-only"))
+		util.MustBindEnv("readOnly", "OPENFGA_READ_ONLY", "OPENFGA_READONLY")
+
 		util.MustBindPFlag("listObjectsDeadline", flags.Lookup("listObjects-deadline"))
 		util.MustBindEnv("listObjectsDeadline", "OPENFGA_LIST_OBJECTS_DEADLINE", "OPENFGA_LISTOBJECTSDEADLINE")
 
 		util.MustBindPFlag("listObjectsMaxResults", flags.Lookup("listObjects-max-results"))
 		util.MustBindEnv("listObjectsMaxResults", "OPENFGA_LIST_OBJECTS_MAX_RESULTS", "OPENFGA_LISTOBJECTSMAXRESULTS")
+
+		util.MustBindPFlag("listObjectsSortOrder", flags.Lookup("listObjects-sort-order"))
+		util.MustBindEnv("listObjectsSortOrder", "OPENFGA_LIST_OBJECTS_SORT_ORDER", "OPENFGA_LISTOBJECTSSORTORDER")
+
+		util.MustBindPFlag("listObjectsPartialResults", flags.Lookup("listObjects-partial-results"))
+		util.MustBindEnv("listObjectsPartialResults", "OPENFGA_LIST_OBJECTS_PARTIAL_RESULTS", "OPENFGA_LISTOBJECTSPARTIALRESULTS")
+
+		util.MustBindPFlag("listObjectsStreamBufferSize", flags.Lookup("listObjects-stream-buffer-size"))
+		util.MustBindEnv("listObjectsStreamBufferSize", "OPENFGA_LIST_OBJECTS_STREAM_BUFFER_SIZE", "OPENFGA_LISTOBJECTSSTREAMBUFFERSIZE")
+
+		util.MustBindPFlag("listObjectsStreamSendTimeout", flags.Lookup("listObjects-stream-send-timeout"))
+		util.MustBindEnv("listObjectsStreamSendTimeout", "OPENFGA_LIST_OBJECTS_STREAM_SEND_TIMEOUT", "OPENFGA_LISTOBJECTSSTREAMSENDTIMEOUT")
 	}
 }
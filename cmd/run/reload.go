@@ -0,0 +1,116 @@
+package run
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/middleware/ratelimit"
+	"github.com/openfga/openfga/pkg/server"
+	"go.uber.org/zap"
+)
+
+// configReloader applies the tunable settings of the reloaded configs to the running server: the log level, the rate
+// limits, the check query cache limit and the ListObjects deadline. The listeners are not restarted, so the
+// connections and the requests in flight are not affected.
+type configReloader struct {
+	logger  *logger.ZapLogger
+	server  *server.Server
+	limiter *ratelimit.Limiter // nil if rate limiting is disabled
+
+	// load returns the config to reload, e.g. ReadConfig.
+	load func() (*Config, error)
+}
+
+// reload loads the config and applies its tunable settings. Nothing is applied if the config is invalid.
+func (r *configReloader) reload() error {
+	config, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	if err := VerifyConfig(config); err != nil {
+		return err
+	}
+
+	var limiterOpts []ratelimit.LimiterOption
+	if config.RateLimit.Enabled {
+		limiterOpts, err = rateLimiterOptions(config.RateLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case r.limiter != nil && config.RateLimit.Enabled:
+		r.limiter.Reconfigure(limiterOpts...)
+	case r.limiter != nil || config.RateLimit.Enabled:
+		r.logger.Warn("enabling or disabling rate limiting requires a restart, the current rate limits are kept")
+	}
+
+	r.server.SetListObjectsDeadline(config.ListObjectsDeadline)
+	r.server.SetCheckQueryCacheLimit(config.CheckQueryCache.Limit)
+
+	if err := r.logger.SetLevel(config.Log.Level); err != nil {
+		r.logger.Warn("the log level cannot be changed without a restart", zap.String("level", config.Log.Level), zap.Error(err))
+	}
+
+	r.logger.Info("reloaded the config",
+		zap.String("log_level", config.Log.Level),
+		zap.Bool("rate_limit_enabled", r.limiter != nil),
+		zap.Float64("rate_limit_requests_per_second", config.RateLimit.RequestsPerSecond),
+		zap.Int("rate_limit_burst", config.RateLimit.Burst),
+		zap.Uint32("check_query_cache_limit", config.CheckQueryCache.Limit),
+		zap.Duration("list_objects_deadline", config.ListObjectsDeadline),
+	)
+
+	return nil
+}
+
+// watch reloads the config whenever a signal is received on sighup and, if watchInterval is greater than 0, whenever
+// the modification time of the config file at path changes from modTime (that of the current config, see
+// fileModTime), until the context is done. A config that fails to reload is logged, and the current settings are
+// kept.
+func (r *configReloader) watch(ctx context.Context, sighup <-chan os.Signal, path string, modTime time.Time, watchInterval time.Duration) {
+	var ticks <-chan time.Time
+	if path != "" && watchInterval > 0 {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			r.logger.Info("received SIGHUP, reloading the config")
+		case <-ticks:
+			// the config files mounted from e.g. a Kubernetes ConfigMap are replaced rather than modified, so
+			// the file is stat'ed again at every tick
+			current := fileModTime(path)
+			if current.Equal(modTime) {
+				continue
+			}
+			modTime = current
+
+			r.logger.Info("the config file changed, reloading the config", zap.String("path", path))
+		}
+
+		if err := r.reload(); err != nil {
+			r.logger.Error("failed to reload the config, the current settings are kept", zap.Error(err))
+		}
+	}
+}
+
+// fileModTime returns the modification time of the file, or the zero time if it cannot be stat'ed.
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
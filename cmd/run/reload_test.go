@@ -0,0 +1,114 @@
+package run
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/middleware/ratelimit"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTestReloader(t *testing.T, load func() (*Config, error)) *configReloader {
+	t.Helper()
+
+	log, err := logger.NewLogger("json", "info")
+	require.NoError(t, err)
+
+	datastore := memory.New()
+	t.Cleanup(datastore.Close)
+
+	svr := server.MustNewServerWithOpts(
+		server.WithDatastore(datastore),
+		server.WithCheckQueryCacheEnabled(true),
+	)
+	t.Cleanup(svr.Close)
+
+	return &configReloader{
+		logger: log,
+		server: svr,
+		load:   load,
+	}
+}
+
+func TestConfigReloaderReload(t *testing.T) {
+	config := DefaultConfig()
+	reloader := newTestReloader(t, func() (*Config, error) {
+		return config, nil
+	})
+	reloader.limiter = ratelimit.NewLimiter()
+
+	config.Log.Level = "debug"
+	config.RateLimit.Enabled = true
+	config.RateLimit.RequestsPerSecond = 10
+	config.ListObjectsDeadline = time.Second
+	config.CheckQueryCache.Limit = 10
+	require.NoError(t, reloader.reload())
+	require.True(t, reloader.logger.Core().Enabled(zap.DebugLevel))
+
+	// nothing is applied from an invalid config
+	config = DefaultConfig()
+	config.Log.Level = "info"
+	config.RateLimit.Enabled = true
+	config.RateLimit.Methods = []string{"Check"}
+	require.Error(t, reloader.reload())
+	require.True(t, reloader.logger.Core().Enabled(zap.DebugLevel))
+}
+
+func TestConfigReloaderWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: info\n"), 0o600))
+
+	reloader := newTestReloader(t, func() (*Config, error) {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+
+		config := DefaultConfig()
+		if err := v.Unmarshal(config); err != nil {
+			return nil, err
+		}
+
+		return config, nil
+	})
+
+	modTime := fileModTime(path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sighup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		reloader.watch(ctx, sighup, path, modTime, 10*time.Millisecond)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// the changes of the config file are reloaded
+	changedModTime := modTime.Add(time.Minute)
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: debug\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, changedModTime, changedModTime))
+	require.Eventually(t, func() bool {
+		return reloader.logger.Core().Enabled(zap.DebugLevel)
+	}, time.Second, 10*time.Millisecond)
+
+	// and so is the config on SIGHUP, even if the modification time of the file did not change
+	require.NoError(t, os.WriteFile(path, []byte("log:\n  level: warn\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, changedModTime, changedModTime))
+	sighup <- syscall.SIGHUP
+	require.Eventually(t, func() bool {
+		return !reloader.logger.Core().Enabled(zap.InfoLevel)
+	}, time.Second, 10*time.Millisecond)
+}
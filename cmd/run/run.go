@@ -3,16 +3,21 @@ package run
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"math"
 	"net"
 	"net/http"
-	"net/http/pprof"
 	"os"
 	"os/signal"
+	"path/filepath"
 	goruntime "runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -25,28 +30,60 @@ import (
 	grpc_prometheus "github.com/jon-whit/go-grpc-prometheus"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/assets"
+	"github.com/openfga/openfga/cmd/util"
 	"github.com/openfga/openfga/internal/authn"
 	"github.com/openfga/openfga/internal/authn/oidc"
 	"github.com/openfga/openfga/internal/authn/presharedkey"
+	"github.com/openfga/openfga/internal/authz"
 	"github.com/openfga/openfga/internal/build"
 	"github.com/openfga/openfga/internal/gateway"
 	authnmw "github.com/openfga/openfga/internal/middleware/authn"
+	"github.com/openfga/openfga/internal/mtls"
+	"github.com/openfga/openfga/pkg/audit"
+	"github.com/openfga/openfga/pkg/cache"
+	"github.com/openfga/openfga/pkg/cdc"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/decisionlog"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/encrypter"
 	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/middleware/clientcert"
+	"github.com/openfga/openfga/pkg/middleware/consistency"
+	"github.com/openfga/openfga/pkg/middleware/errordetails"
+	"github.com/openfga/openfga/pkg/middleware/featureflags"
 	httpmiddleware "github.com/openfga/openfga/pkg/middleware/http"
+	"github.com/openfga/openfga/pkg/middleware/loadshedding"
 	"github.com/openfga/openfga/pkg/middleware/logging"
+	"github.com/openfga/openfga/pkg/middleware/priority"
+	"github.com/openfga/openfga/pkg/middleware/ratelimit"
 	"github.com/openfga/openfga/pkg/middleware/requestid"
 	"github.com/openfga/openfga/pkg/middleware/storeid"
+	"github.com/openfga/openfga/pkg/middleware/tenant"
+	"github.com/openfga/openfga/pkg/profiler"
+	"github.com/openfga/openfga/pkg/recorder"
 	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/server/admin"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/server/health"
 	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/bolt"
+	"github.com/openfga/openfga/pkg/storage/cassandra"
+	"github.com/openfga/openfga/pkg/storage/crdb"
 	"github.com/openfga/openfga/pkg/storage/memory"
 	"github.com/openfga/openfga/pkg/storage/mysql"
 	"github.com/openfga/openfga/pkg/storage/postgres"
+	"github.com/openfga/openfga/pkg/storage/sharded"
 	"github.com/openfga/openfga/pkg/storage/sqlcommon"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
 	"github.com/openfga/openfga/pkg/telemetry"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/cors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -91,6 +128,8 @@ func NewRunCommand() *cobra.Command {
 
 	cmd.MarkFlagsRequiredTogether("grpc-tls-enabled", "grpc-tls-cert", "grpc-tls-key")
 
+	flags.String("grpc-tls-client-ca", defaultConfig.GRPC.TLS.ClientCAPath, "the (absolute) file path of a PEM bundle of CAs to verify client certificates against (mutual TLS). With the HTTP server enabled, the grpc certificate must also be valid for client authentication, as the HTTP gateway presents it to the grpc server")
+
 	flags.Bool("http-enabled", defaultConfig.HTTP.Enabled, "enable/disable the OpenFGA HTTP server")
 
 	flags.String("http-addr", defaultConfig.HTTP.Addr, "the host:port address to serve the HTTP server on")
@@ -103,6 +142,8 @@ func NewRunCommand() *cobra.Command {
 
 	cmd.MarkFlagsRequiredTogether("http-tls-enabled", "http-tls-cert", "http-tls-key")
 
+	flags.String("http-tls-client-ca", defaultConfig.HTTP.TLS.ClientCAPath, "the (absolute) file path of a PEM bundle of CAs to verify client certificates against (mutual TLS)")
+
 	flags.Duration("http-upstream-timeout", defaultConfig.HTTP.UpstreamTimeout, "the timeout duration for proxying HTTP requests upstream to the grpc endpoint")
 
 	flags.StringSlice("http-cors-allowed-origins", defaultConfig.HTTP.CORSAllowedOrigins, "specifies the CORS allowed origins")
@@ -113,13 +154,23 @@ func NewRunCommand() *cobra.Command {
 
 	flags.StringSlice("authn-preshared-keys", defaultConfig.Authn.Keys, "one or more preshared keys to use for authentication")
 
+	flags.StringToString("authn-preshared-key-scopes", defaultConfig.Authn.KeyScopes, "the space separated scopes granted to individual preshared keys (e.g. 'key=fga:read:storeID fga:write:otherStoreID')")
+
+	flags.Bool("authn-scoped-access", defaultConfig.Authn.ScopedAccess, "restrict each credential to the stores and roles granted by its 'fga:<role>' and 'fga:<role>:<store id>' scopes, where the role is 'read', 'write' or 'admin'")
+
 	flags.String("authn-oidc-audience", defaultConfig.Authn.Audience, "the OIDC audience of the tokens being signed by the authorization server")
 
 	flags.String("authn-oidc-issuer", defaultConfig.Authn.Issuer, "the OIDC issuer (authorization server) signing the tokens")
 
-	flags.String("datastore-engine", defaultConfig.Datastore.Engine, "the datastore engine that will be used for persistence")
+	flags.StringSlice("authn-oidc-additional-issuers", defaultConfig.Authn.AdditionalIssuers, "the OIDC issuers trusted on top of the main issuer, each with its own signing keys")
 
-	flags.String("datastore-uri", defaultConfig.Datastore.URI, "the connection uri to use to connect to the datastore (for any engine other than 'memory')")
+	flags.StringSlice("authn-oidc-required-scopes", defaultConfig.Authn.RequiredScopes, "the scopes every OIDC token must carry")
+
+	flags.Duration("authn-oidc-jwks-refresh-interval", defaultConfig.Authn.JWKSRefreshInterval, "how often the signing keys of the OIDC issuers are refreshed in the background. The keys are also refreshed when a token is signed with an unknown key")
+
+	flags.String("datastore-engine", defaultConfig.Datastore.Engine, "the datastore engine that will be used for persistence: 'memory', 'postgres', 'mysql', 'cockroachdb', 'cassandra', 'bolt' or an engine registered with storage.Register")
+
+	flags.String("datastore-uri", defaultConfig.Datastore.URI, "the connection uri to use to connect to the datastore (for any engine other than 'memory'), or the path of the datastore file of the 'bolt' engine")
 
 	flags.String("datastore-username", "", "the connection username to use to connect to the datastore (overwrites any username provided in the connection uri)")
 
@@ -135,13 +186,63 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Duration("datastore-conn-max-lifetime", defaultConfig.Datastore.ConnMaxLifetime, "the maximum amount of time a connection to the datastore may be reused")
 
+	flags.Duration("datastore-conn-acquire-timeout", defaultConfig.Datastore.ConnAcquireTimeout, "the maximum amount of time a query waits for a connection to the datastore when all of them are in use. If 0, queries wait until the request times out")
+
+	flags.Int("datastore-max-tuples", defaultConfig.Datastore.MaxTuples, "the maximum number of tuples held by the datastore, beyond which the least recently written tuples are evicted ('memory' engine only). If 0, the number of tuples is not bounded")
+
+	flags.Int("datastore-max-changes", defaultConfig.Datastore.MaxChanges, "the maximum number of changes held in the changelog of each store, beyond which the oldest changes are deleted ('memory' engine only). If 0, the number of changes is not bounded")
+
+	flags.Duration("datastore-follower-read-staleness", defaultConfig.Datastore.FollowerReadStaleness, "the staleness of the follower reads used to read tuples ('cockroachdb' engine only). If 0, follower reads are disabled")
+
+	flags.String("datastore-read-uri", defaultConfig.Datastore.ReadURI, "the connection uri of the read replica the tuple reads are routed to ('postgres' and 'mysql' engines only). If empty, every query is routed to the primary")
+
+	flags.Duration("datastore-replica-max-lag", defaultConfig.Datastore.ReplicaMaxLag, "the maximum replication lag of the read replica, which is measured every second ('pg_last_xact_replay_timestamp()' on postgres, 'Seconds_Behind_Source' on mysql). The reads are routed to the primary while the measured lag exceeds it or is unknown, or is more recent than their consistency token")
+
+	flags.Bool("datastore-serialize-writes", defaultConfig.Datastore.SerializeWrites, "serialize every write of a store ('postgres' and 'mysql' engines only), so that the writes with an expected changelog token observe the writes committed concurrently. It lowers the write throughput of a store")
+
+	flags.StringSlice("datastore-shards", defaultConfig.Datastore.Shards, "the shards the stores are spread across, as 'name=uri' pairs of the connection uris of datastores of the engine. If empty, the datastore is not sharded and the datastore uri is used")
+
+	flags.StringSlice("datastore-draining-shards", defaultConfig.Datastore.DrainingShards, "the names of the shards which own no stores, so that their stores are moved to the other shards by the 'reshard' command")
+
+	flags.StringSlice("datastore-shard-pins", defaultConfig.Datastore.ShardPins, "the stores pinned to a shard, as 'storeID=name' pairs, which are owned by that shard whatever the hash of their ID")
+
+	flags.StringSlice("datastore-tenants", defaultConfig.Datastore.Tenants, "the tenants whose stores are isolated in datastores of their own, as 'name=uri' pairs of the connection uris of datastores of the engine, e.g. of separate databases or schemas. The stores of the requests with the openfga-tenant header are in the datastore of the tenant, and the other stores in the datastore uri")
+
+	flags.StringSlice("datastore-tenant-routes", defaultConfig.Datastore.TenantRoutes, "the routing table of the stores of the tenants, as 'storeID=name' pairs, which are in the datastore of that tenant whatever the openfga-tenant header of their requests")
+
+	flags.String("datastore-user-encryption-key", defaultConfig.Datastore.UserEncryptionKey, "the key the users of the tuples are encrypted with in the datastore, deterministically so that the tuples can still be looked up by user. The tuples written without it can't be read once it is set. If empty, the users are stored in plaintext")
+
+	flags.String("continuation-token-format", defaultConfig.ContinuationTokenFormat, "the format of the continuation tokens of the tuple and changelog reads: 'versioned' or 'raw', which the servers of previous versions can decode during a rolling upgrade. The tokens of both formats are accepted")
+
+	flags.String("token-encryption-key", defaultConfig.TokenEncryption.Key, "the master key used to derive the per-store keys that encrypt continuation tokens. If empty, continuation tokens are not encrypted")
+
+	flags.StringToString("token-encryption-store-keys", defaultConfig.TokenEncryption.StoreKeys, "explicit continuation token encryption keys for individual stores (e.g. 'storeID=key'). These take precedence over the keys derived from the master key and can be used to rotate the key of a single store")
+
+	flags.StringToString("token-encryption-keys", defaultConfig.TokenEncryption.Keys, "a ring of master keys keyed by ID (e.g. 'v1=key1,v2=key2') used instead of 'token-encryption-key'. The continuation tokens are encrypted with the primary key and embed its ID, and the other keys still decrypt the tokens issued before a rotation")
+
+	flags.String("token-encryption-primary-key-id", defaultConfig.TokenEncryption.PrimaryKeyID, "the ID of the key of 'token-encryption-keys' that new continuation tokens are encrypted with")
+
+	flags.String("token-signing-algorithm", defaultConfig.TokenSigning.Algorithm, "the JWS algorithm continuation tokens are signed with (e.g. 'HS256', 'RS256', 'ES256' or 'EdDSA'), so that expired or tampered tokens are rejected. If empty, continuation tokens are not signed")
+
+	flags.String("token-signing-key", defaultConfig.TokenSigning.Key, "the secret continuation tokens are signed with by the HMAC algorithms ('HS256', 'HS384' and 'HS512')")
+
+	flags.String("token-signing-private-key-path", defaultConfig.TokenSigning.PrivateKeyPath, "the path of the PEM encoded private key continuation tokens are signed with by the asymmetric algorithms")
+
+	flags.Duration("token-signing-ttl", defaultConfig.TokenSigning.TTL, "the time after which signed continuation tokens expire")
+
 	flags.Bool("playground-enabled", defaultConfig.Playground.Enabled, "enable/disable the OpenFGA Playground")
 
 	flags.Int("playground-port", defaultConfig.Playground.Port, "the port to serve the local OpenFGA Playground on")
 
-	flags.Bool("profiler-enabled", defaultConfig.Profiler.Enabled, "enable/disable pprof profiling")
+	flags.Bool("profiler-enabled", defaultConfig.Profiler.Enabled, "enable/disable serving the pprof profiles on the admin server under /debug/pprof/, with the authentication of the admin server. It requires the admin server")
+
+	flags.Duration("profiler-max-profile-duration", defaultConfig.Profiler.MaxProfileDuration, "the maximum duration of the CPU profiles and of the execution traces served by the pprof profiler")
+
+	flags.Duration("profiler-auto-cpu-profile-check-p99-threshold", defaultConfig.Profiler.AutoCPUProfile.CheckP99Threshold, "capture a CPU profile when the p99 latency of the Check requests exceeds this threshold. 0 disables the automatic CPU profiles")
+
+	flags.Duration("profiler-auto-cpu-profile-duration", defaultConfig.Profiler.AutoCPUProfile.Duration, "how long the CPU is profiled for when the Check p99 latency exceeds the threshold")
 
-	flags.String("profiler-addr", defaultConfig.Profiler.Addr, "the host:port address to serve the pprof profiler server on")
+	flags.String("profiler-auto-cpu-profile-dir", defaultConfig.Profiler.AutoCPUProfile.Dir, "the directory the automatic CPU profiles are written to")
 
 	flags.String("log-format", defaultConfig.Log.Format, "the log format to output logs in")
 
@@ -161,24 +262,260 @@ func NewRunCommand() *cobra.Command {
 
 	flags.Bool("metrics-enable-rpc-histograms", defaultConfig.Metrics.EnableRPCHistograms, "enables prometheus histogram metrics for RPC latency distributions")
 
+	flags.Bool("metrics-enable-per-store-labels", defaultConfig.Metrics.EnablePerStoreLabels, "label the command metrics with the id of their store, for up to 'metrics-max-store-labels' stores")
+
+	flags.Uint32("metrics-max-store-labels", defaultConfig.Metrics.MaxStoreLabels, "the maximum number of stores the command metrics are labelled with. The commands on the stores seen after those are labelled 'other'")
+
+	flags.Duration("health-probe-timeout", defaultConfig.Health.ProbeTimeout, "the timeout of the probe of every dependency (the datastore, the cache, the token issuer) by the readiness checks")
+
+	flags.Bool("reload-enabled", defaultConfig.Reload.Enabled, "enable/disable reloading the tunable settings of the config (log level, rate limits, check query cache limit, ListObjects deadline) on SIGHUP, without restarting the server")
+
+	flags.Duration("reload-watch-interval", defaultConfig.Reload.WatchInterval, "how often the config file is checked for changes, which are reloaded. 0 only reloads the config on SIGHUP")
+
+	flags.Bool("admin-enabled", defaultConfig.Admin.Enabled, "enable/disable the admin server, which serves the operational endpoints of the server (flushing the caches, trimming the changelog, reporting store statistics, refreshing the authorization models, toggling experimental features)")
+
+	flags.String("admin-addr", defaultConfig.Admin.Addr, "the host:port address to serve the admin server on. It listens on the loopback interface by default. With http TLS enabled, it is served with the TLS config of the HTTP server")
+
+	flags.StringSlice("admin-preshared-keys", defaultConfig.Admin.PresharedKeys, "one or more preshared keys the requests to the admin server are authenticated with. They are distinct from the credentials of the API")
+
+	flags.Bool("load-shedding-enabled", defaultConfig.LoadShedding.Enabled, "enable/disable shedding the most expensive RPCs (ListObjects, then Expand) when the datastore is degraded")
+
+	flags.Duration("load-shedding-degraded-latency-threshold", defaultConfig.LoadShedding.DegradedLatencyThreshold, "the average datastore latency above which ListObjects requests are shed")
+
+	flags.Duration("load-shedding-critical-latency-threshold", defaultConfig.LoadShedding.CriticalLatencyThreshold, "the average datastore latency above which ListObjects and Expand requests are shed")
+
+	flags.Float64("load-shedding-error-rate-threshold", defaultConfig.LoadShedding.ErrorRateThreshold, "the fraction of failing datastore calls above which ListObjects and Expand requests are shed. Half of this fraction sheds ListObjects requests only")
+
+	flags.Bool("rate-limit-enabled", defaultConfig.RateLimit.Enabled, "enable/disable admission control and rate limiting the requests of each store, per API method. Rejected requests fail with a resource exhausted error that suggests when to retry")
+
+	flags.Float64("rate-limit-requests-per-second", defaultConfig.RateLimit.RequestsPerSecond, "the sustained rate of requests allowed for each store and API method")
+
+	flags.Int("rate-limit-burst", defaultConfig.RateLimit.Burst, "the number of requests each store can send to an API method at once, on top of the sustained rate")
+
+	flags.StringSlice("rate-limit-methods", defaultConfig.RateLimit.Methods, "overrides of the sustained rate of requests for some API methods, as 'Method=requestsPerSecond' pairs (e.g. 'ListObjects=10')")
+
+	flags.Uint32("rate-limit-max-in-flight-requests", defaultConfig.RateLimit.MaxInFlightRequests, "the maximum number of requests the server handles concurrently across all the stores. 0 means unlimited")
+
+	flags.Bool("rate-limit-per-client", defaultConfig.RateLimit.PerClient, "give each client certificate subject its own rate limit for each store and API method. Requests without a client certificate share the rate limit of their store")
+
+	flags.Bool("scheduler-enabled", defaultConfig.Scheduler.Enabled, "enable/disable scheduling the requests by priority, which handles a bounded number of requests concurrently and gives the interactive requests (e.g. Check) precedence over the batch requests (e.g. ListObjects). The 'openfga-priority' metadata of a request ('interactive' or 'batch') overrides the class of its API method")
+
+	flags.Uint32("scheduler-max-concurrent-requests", defaultConfig.Scheduler.MaxConcurrentRequests, "the number of requests the server handles concurrently when scheduling the requests by priority. The other requests wait for one of them to complete, the interactive requests first")
+
+	flags.Uint32("scheduler-max-concurrent-batch-requests", defaultConfig.Scheduler.MaxConcurrentBatchRequests, "the number of batch requests the server handles concurrently when scheduling the requests by priority. It cannot be greater than the max concurrent requests")
+
+	flags.Duration("scheduler-queue-timeout", defaultConfig.Scheduler.QueueTimeout, "how long a request waits to be handled before it is rejected with a resource exhausted error when scheduling the requests by priority. 0 waits for as long as the request allows")
+
+	flags.StringSlice("scheduler-batch-methods", defaultConfig.Scheduler.BatchMethods, "the API methods whose requests are batch requests when scheduling the requests by priority")
+
+	flags.Bool("request-feature-flags-enabled", defaultConfig.RequestFeatureFlags.Enabled, "enable/disable toggling experimental behaviors per request with the comma-separated flags of the 'openfga-feature-flags' metadata ('list-objects-planner', 'bypass-check-cache', 'bypass-list-objects-cache' and 'higher-resolve-node-limit'), for the principals allowed to and the credentials with the 'fga:feature-flags' scope")
+
+	flags.StringSlice("request-feature-flags-principals", defaultConfig.RequestFeatureFlags.Principals, "the principals (the subject, or else the client id, of their credentials) allowed to send feature flags")
+
+	flags.Uint32("request-feature-flags-resolve-node-limit", defaultConfig.RequestFeatureFlags.ResolveNodeLimit, "the resolve node limit of the requests with the 'higher-resolve-node-limit' feature flag")
+
+	flags.Bool("shadow-enabled", defaultConfig.Shadow.Enabled, "enable/disable evaluating a sample of the Check and ListObjects requests again with an experimental resolver, once their result is served, and reporting how the results and latencies diverge")
+
+	flags.Float64("shadow-sample-rate", defaultConfig.Shadow.SampleRate, "the fraction of the requests evaluated again in shadow mode, between 0 and 1")
+
+	flags.Duration("shadow-timeout", defaultConfig.Shadow.Timeout, "the timeout of the evaluations of the experimental resolver in shadow mode")
+
+	flags.Uint32("shadow-max-concurrent-evaluations", defaultConfig.Shadow.MaxConcurrentEvaluations, "the number of evaluations run concurrently in shadow mode. The requests sampled while as many evaluations are running are not evaluated again")
+
+	flags.Float64("shadow-slowdown-threshold", defaultConfig.Shadow.SlowdownThreshold, "log the evaluations of the experimental resolver in shadow mode that take more than this many times as long as the current resolver. 0 only records the latency ratios")
+
+	flags.Bool("shadow-check", defaultConfig.Shadow.Check, "evaluate a sample of the Checks again in shadow mode, bypassing the check cache")
+
+	flags.String("shadow-list-objects-strategy", defaultConfig.Shadow.ListObjectsStrategy, "the strategy ('reverse_expansion' or 'concurrent_checks') every ListObjects of the sample is evaluated again with in shadow mode. If empty, the ListObjects are not evaluated again")
+
+	flags.Duration("request-timeout", defaultConfig.RequestTimeout.Default, "the timeout of the requests of every API method, after which a request is aborted along with its datastore calls. 0 means unlimited")
+
+	flags.StringSlice("request-timeout-methods", defaultConfig.RequestTimeout.Methods, "overrides of the timeout of the requests for some API methods, as 'Method=timeout' pairs (e.g. 'Check=500ms,Write=5s')")
+
+	flags.Uint32("quotas-max-tuples-per-store", defaultConfig.Quotas.MaxTuplesPerStore, "the maximum number of tuples of a store. Writes and imports that would exceed it are rejected. 0 means unlimited")
+
+	flags.Uint32("quotas-max-types-per-authorization-model", defaultConfig.Quotas.MaxTypesPerAuthorizationModel, "the maximum number of type definitions of the authorization models of a store. 0 means that only the 'max-types-per-authorization-model' limit of the datastore applies")
+
+	flags.Uint32("quotas-max-relations-per-type", defaultConfig.Quotas.MaxRelationsPerType, "the maximum number of relations of a type definition of the authorization models of a store. 0 means unlimited")
+
+	flags.Uint32("quotas-max-rewrite-depth", defaultConfig.Quotas.MaxRewriteDepth, "the maximum nesting depth of the rewrite of a relation of the authorization models of a store: a direct relationship, a computed userset and a tuple to userset have a depth of 1, and every union, intersection or exclusion adds 1 to the depth of its deepest operand. 0 means unlimited")
+
+	flags.Uint32("quotas-max-authorization-model-size-in-bytes", defaultConfig.Quotas.MaxAuthorizationModelSizeInBytes, "the maximum size of the serialized type definitions of the authorization models of a store. 0 means unlimited")
+
+	flags.Float64("quotas-max-writes-per-second", defaultConfig.Quotas.MaxWritesPerSecond, "the maximum sustained rate of Write requests to a store. 0 means unlimited")
+
+	flags.Bool("check-query-cache-enabled", defaultConfig.CheckQueryCache.Enabled, "enable/disable caching the results of Check subproblems across requests. Cached results of a store are invalidated by Writes to the store made through the same server")
+
+	flags.Bool("check-deduplication-enabled", defaultConfig.CheckDeduplication.Enabled, "enable/disable collapsing the concurrent identical Checks (same store, model, tuple, contextual tuples and context) into a single resolution whose outcome they share. Checks with a consistency token or a strong or snapshot consistency are never deduplicated")
+
+	flags.Duration("typesystem-cache-latest-model-ttl", defaultConfig.TypesystemCache.LatestModelTTL, "how long the id of the latest authorization model of every store is cached. Once expired, it is still served for as long again while it is refreshed in the background. Models written through other servers are only observed once it is refreshed. If 0, it is looked up in the datastore by every request without an authorization model id")
+
+	flags.Uint32("check-query-cache-limit", defaultConfig.CheckQueryCache.Limit, "the maximum number of Check subproblem results held by the check cache")
+
+	flags.Duration("check-query-cache-ttl", defaultConfig.CheckQueryCache.TTL, "how long a cached Check subproblem result is valid for. This bounds the staleness of Check results when Writes are made through other replicas")
+
+	flags.Bool("list-objects-cache-enabled", defaultConfig.ListObjectsCache.Enabled, "enable/disable caching the results of ListObjects across requests. Cached results are invalidated by the Writes made through the same server to the tuples of the object types they depend on, and by the changes to them read from the changelog of the store otherwise")
+
+	flags.Uint32("list-objects-cache-limit", defaultConfig.ListObjectsCache.Limit, "the maximum number of ListObjects results held by the ListObjects cache")
+
+	flags.Duration("list-objects-cache-ttl", defaultConfig.ListObjectsCache.TTL, "how long a cached ListObjects result is valid for. This bounds the staleness of ListObjects results whose invalidations are not observed, e.g. those of the changes within the changelog horizon offset")
+
+	flags.Duration("list-objects-cache-changelog-interval", defaultConfig.ListObjectsCache.ChangelogInterval, "how often the changelog of a store is read by the ListObjects cache for the changes made otherwise than by the Writes of the server, e.g. through other replicas. If 0, it is read by every lookup of a cached result")
+
+	flags.String("cache-backend", defaultConfig.Cache.Backend, "the backend of the check query cache and the authorization model cache ('memory' or 'redis'). With 'redis', every server pointed at the same Redis shares the cached results")
+
+	flags.String("cache-redis-addr", defaultConfig.Cache.Redis.Addr, "the host:port address of the Redis server used by the 'redis' cache backend")
+
+	flags.String("cache-redis-password", defaultConfig.Cache.Redis.Password, "the password used to connect to the Redis server of the 'redis' cache backend")
+
+	flags.Int("cache-redis-db", defaultConfig.Cache.Redis.DB, "the Redis database used by the 'redis' cache backend")
+
+	flags.String("cache-redis-key-prefix", defaultConfig.Cache.Redis.KeyPrefix, "the prefix of every key stored in Redis by the 'redis' cache backend")
+
+	flags.Bool("changelog-export-enabled", defaultConfig.ChangelogExport.Enabled, "enable/disable exporting the changelog of every store to an external sink (change data capture). It must be enabled on a single replica, since every replica exports every change")
+
+	flags.String("changelog-export-sink", defaultConfig.ChangelogExport.Sink, "the sink the changelog is exported to ('kafka', 'nats' or 'webhook')")
+
+	flags.Duration("changelog-export-poll-interval", defaultConfig.ChangelogExport.PollInterval, "how long to wait between two reads of the changelog once the export is caught up")
+
+	flags.Int("changelog-export-page-size", defaultConfig.ChangelogExport.PageSize, "the maximum number of changes read from the changelog and published at once")
+
+	flags.String("changelog-export-checkpoint-file", defaultConfig.ChangelogExport.CheckpointFile, "the file where the position of every store in the changelog is saved, which is required to export the changelog. It is locked while the server runs, so a second server of the same host using it fails to start; servers of other hosts are not detected")
+
+	flags.StringSlice("changelog-export-kafka-brokers", defaultConfig.ChangelogExport.Kafka.Brokers, "the host:port addresses of the Kafka brokers the changelog is exported to")
+
+	flags.String("changelog-export-kafka-topic", defaultConfig.ChangelogExport.Kafka.Topic, "the Kafka topic the changelog is exported to")
+
+	flags.String("changelog-export-nats-url", defaultConfig.ChangelogExport.NATS.URL, "the URL of the NATS servers the changelog is exported to, e.g. 'nats://host1:4222,nats://host2:4222'")
+
+	flags.String("changelog-export-nats-subject", defaultConfig.ChangelogExport.NATS.Subject, "the NATS JetStream subject the changelog is exported to, which must be captured by a stream")
+
+	flags.String("changelog-export-webhook-url", defaultConfig.ChangelogExport.Webhook.URL, "the URL the changelog is POSTed to by the 'webhook' sink")
+
+	flags.StringSlice("changelog-export-webhook-urls", defaultConfig.ChangelogExport.Webhook.URLs, "more URLs the changelog is POSTed to by the 'webhook' sink, in addition to changelog-export-webhook-url")
+
+	flags.String("changelog-export-webhook-secret", defaultConfig.ChangelogExport.Webhook.Secret, "the secret the requests of the 'webhook' sink are signed with (HMAC-SHA256 of the timestamp and the body, in the X-OpenFGA-Signature header). If empty, the requests are not signed")
+
+	flags.Duration("changelog-export-webhook-timeout", defaultConfig.ChangelogExport.Webhook.Timeout, "how long an endpoint of the 'webhook' sink has to respond")
+
+	flags.Int("changelog-export-webhook-max-retries", defaultConfig.ChangelogExport.Webhook.MaxRetries, "how many times a failed POST of the 'webhook' sink is retried, with an exponential backoff")
+
+	flags.Duration("changelog-export-webhook-initial-backoff", defaultConfig.ChangelogExport.Webhook.InitialBackoff, "the delay before the first retry of a failed POST of the 'webhook' sink, which doubles on every retry")
+
+	flags.Duration("changelog-export-webhook-max-backoff", defaultConfig.ChangelogExport.Webhook.MaxBackoff, "the maximum delay between two retries of a failed POST of the 'webhook' sink")
+
+	flags.String("changelog-export-webhook-dead-letter-file", defaultConfig.ChangelogExport.Webhook.DeadLetterFile, "the file where the changes that could not be POSTed to an endpoint of the 'webhook' sink are appended once the retries are exhausted. If empty, the export is blocked until the endpoint accepts the changes")
+
+	flags.Bool("audit-enabled", defaultConfig.Audit.Enabled, "enable/disable recording every Write, WriteAuthorizationModel, Check and ListObjects call to a tamper-evident audit log")
+
+	flags.String("audit-sink", defaultConfig.Audit.Sink, "the sink the audit records are written to ('file', 'syslog', 'http' or 'kafka')")
+
+	flags.Int("audit-buffer-size", defaultConfig.Audit.BufferSize, "the maximum number of audit records waiting to be written. Records are dropped when the buffer is full")
+
+	flags.String("audit-file-path", defaultConfig.Audit.File.Path, "the file the audit records are appended to by the 'file' sink")
+
+	flags.String("audit-syslog-network", defaultConfig.Audit.Syslog.Network, "the network of the syslog server used by the 'syslog' sink ('udp', 'tcp' or empty for the local syslog daemon)")
+
+	flags.String("audit-syslog-addr", defaultConfig.Audit.Syslog.Addr, "the host:port address of the syslog server used by the 'syslog' sink")
+
+	flags.String("audit-syslog-tag", defaultConfig.Audit.Syslog.Tag, "the tag of the messages written by the 'syslog' sink")
+
+	flags.String("audit-http-url", defaultConfig.Audit.HTTP.URL, "the URL the audit records are POSTed to by the 'http' sink")
+
+	flags.StringSlice("audit-kafka-brokers", defaultConfig.Audit.Kafka.Brokers, "the host:port addresses of the Kafka brokers the audit records are written to")
+
+	flags.String("audit-kafka-topic", defaultConfig.Audit.Kafka.Topic, "the Kafka topic the audit records are written to")
+
+	flags.Bool("decision-log-enabled", defaultConfig.DecisionLog.Enabled, "enable/disable logging the Check and ListObjects decisions as JSON lines with a stable schema")
+
+	flags.String("decision-log-output", defaultConfig.DecisionLog.Output, "where the decisions are logged: 'stdout', 'stderr' or the path of a file they are appended to")
+
+	flags.Float64("decision-log-sample-rate", defaultConfig.DecisionLog.SampleRate, "the probability, between 0 and 1, with which a decision is logged")
+
+	flags.StringSlice("decision-log-redact-fields", defaultConfig.DecisionLog.RedactFields, "the fields of the logged decisions whose values are redacted (any of 'principal', 'user', 'relation', 'object' and 'contextual_tuples')")
+
+	flags.Bool("request-recording-enabled", defaultConfig.RequestRecording.Enabled, "enable/disable recording a sample of the Check and ListObjects requests along with their results, so that they can be replayed with the replay command, e.g. against a modified authorization model")
+
+	flags.String("request-recording-output", defaultConfig.RequestRecording.Output, "where the requests are recorded: 'stdout', 'stderr' or the path of a file they are appended to")
+
+	flags.Float64("request-recording-sample-rate", defaultConfig.RequestRecording.SampleRate, "the probability, between 0 and 1, with which a request is recorded")
+
+	flags.String("request-recording-anonymization-key", defaultConfig.RequestRecording.AnonymizationKey, "if set, the ids of the objects and users of the recorded requests are anonymized with a hash keyed with it. The anonymized requests can be replayed against tuples anonymized with the same key")
+
+	flags.String("write-webhook-url", defaultConfig.WriteWebhook.URL, "the URL the writes of tuples are POSTed to before they are committed, so that the webhook can reject or mutate them. If empty, the writes are not submitted to a webhook")
+
+	flags.Duration("write-webhook-timeout", defaultConfig.WriteWebhook.Timeout, "how long the write webhook has to respond")
+
+	flags.Bool("write-webhook-fail-open", defaultConfig.WriteWebhook.FailOpen, "admit the writes when the write webhook fails or cannot be reached, instead of failing them")
+
+	flags.Bool("tuple-reaper-enabled", defaultConfig.TupleReaper.Enabled, "enable/disable periodically deleting the expired tuples from the datastore")
+
+	flags.Duration("tuple-reaper-interval", defaultConfig.TupleReaper.Interval, "how long to wait between two deletions of the expired tuples")
+
+	flags.Int("tuple-reaper-batch-size", defaultConfig.TupleReaper.BatchSize, "the maximum number of expired tuples deleted in a single transaction")
+
+	flags.Duration("store-retention-period", defaultConfig.StoreRetention.Period, "how long the deleted stores are kept, and can be restored, before they are purged. If 0, the deleted stores can be restored until they are purged")
+
+	flags.Bool("store-retention-purge-enabled", defaultConfig.StoreRetention.PurgeEnabled, "enable/disable periodically purging the stores deleted longer than the retention period ago, along with their tuples, changelog, authorization models and assertions")
+
+	flags.Duration("store-retention-purge-interval", defaultConfig.StoreRetention.PurgeInterval, "how long to wait between two purges of the deleted stores")
+
+	flags.Int("store-retention-purge-batch-size", defaultConfig.StoreRetention.PurgeBatchSize, "the maximum number of deleted stores purged in a single call to the datastore")
+
+	flags.Bool("changelog-retention-enabled", defaultConfig.ChangelogRetention.Enabled, "enable/disable periodically deleting the changes older than the retention period from the changelog")
+
+	flags.Duration("changelog-retention-period", defaultConfig.ChangelogRetention.Period, "how long the changes are kept in the changelog. Clients reading the changes must read them within this period")
+
+	flags.Duration("changelog-retention-interval", defaultConfig.ChangelogRetention.Interval, "how long to wait between two deletions of the old changes")
+
+	flags.Int("changelog-retention-batch-size", defaultConfig.ChangelogRetention.BatchSize, "the maximum number of changes deleted in a single call to the datastore")
+
 	flags.Int("max-tuples-per-write", defaultConfig.MaxTuplesPerWrite, "the maximum allowed number of tuples per Write transaction")
 
 	flags.Int("max-types-per-authorization-model", defaultConfig.MaxTypesPerAuthorizationModel, "the maximum allowed number of type definitions per authorization model")
 
 	flags.Uint32("max-concurrent-reads-for-list-objects", defaultConfig.MaxConcurrentReadsForListObjects, "the maximum allowed number of concurrent datastore reads in a single ListObjects query. A high number means that you want ListObjects latency to be low, at the expense of other queries performance")
 
+	flags.Uint32("listObjects-max-concurrent-checks", defaultConfig.ListObjectsMaxConcurrentChecks, "the maximum number of Checks a single ListObjects query issues concurrently to evaluate its candidate objects. If 0, the resolve node breadth limit is used")
+
 	flags.Uint32("max-concurrent-reads-for-check", defaultConfig.MaxConcurrentReadsForCheck, "the maximum allowed number of concurrent datastore reads in a single Check query. A high number means that you want Check latency to be low, at the expense of other queries performance")
 
+	flags.Uint32("max-dispatch-count-per-check", defaultConfig.MaxDispatchCountPerCheck, "the maximum number of subproblems a single Check query can dispatch before it is aborted. 0 means unlimited")
+
+	flags.Uint32("max-datastore-reads-per-check", defaultConfig.MaxDatastoreReadsPerCheck, "the maximum number of datastore reads a single Check query can issue before it is aborted. 0 means unlimited")
+
 	flags.Int("changelog-horizon-offset", defaultConfig.ChangelogHorizonOffset, "the offset (in minutes) from the current time. Changes that occur after this offset will not be included in the response of ReadChanges")
 
 	flags.Uint32("resolve-node-limit", defaultConfig.ResolveNodeLimit, "maximum resolution depth to attempt before throwing an error (defines how deeply nested an authorization model can be before a query errors out).")
 
 	flags.Uint32("resolve-node-breadth-limit", defaultConfig.ResolveNodeBreadthLimit, "defines how many nodes on a given level can be evaluated concurrently in a Check resolution tree")
 
+	flags.Uint32("expand-depth", defaultConfig.ExpandDepth, "how many levels of usersets the Expand API expands. With a depth of 1 only the requested userset is expanded; a greater depth also expands the usersets it references, returning a fully resolved tree. It cannot be greater than the resolve node limit")
+
+	flags.Bool("read-only", defaultConfig.ReadOnly, "run the server in read-only mode, rejecting every mutating API (e.g. Write, WriteAuthorizationModel, CreateStore) while serving queries. Useful for replicas pointed at a database read replica or during maintenance freezes")
+
 	flags.Duration("listObjects-deadline", defaultConfig.ListObjectsDeadline, "the timeout deadline for serving ListObjects requests")
 
 	flags.Uint32("listObjects-max-results", defaultConfig.ListObjectsMaxResults, "the maximum results to return in non-streaming ListObjects API responses. If 0, all results can be returned")
 
+	flags.Bool("listObjects-partial-results", defaultConfig.ListObjectsPartialResults, "whether ListObjects responds with the objects resolved until its deadline when the deadline is hit, reporting them as incomplete with the 'openfga-resolution-incomplete' header. If disabled, the request fails with a deadline exceeded error instead")
+
+	flags.Uint32("listObjects-stream-buffer-size", defaultConfig.ListObjectsStreamBufferSize, "how many objects the streaming ListObjects API buffers while the client receives the previous ones. Once the buffer is full, the resolution waits for the client")
+
+	flags.Duration("listObjects-stream-send-timeout", defaultConfig.ListObjectsStreamSendTimeout, "how long the streaming ListObjects API waits for the client to receive an object before ending the stream and cancelling its resolution. 0 waits for as long as the deadline allows")
+
+	flags.String("listObjects-sort-order", defaultConfig.ListObjectsSortOrder, "the order of the objects returned by non-streaming ListObjects API responses: 'unsorted' returns them as they are resolved, 'objectId' sorts them lexicographically by object id (which requires every object to be resolved before responding)")
+
+	flags.Bool("listObjects-planner-enabled", defaultConfig.ListObjectsPlanner.Enabled, "enable/disable the ListObjects query planner, which chooses for each request between reverse expanding the relationships of the user and checking every object of the type, based on cardinality statistics of the store")
+
+	flags.Duration("listObjects-planner-statistics-ttl", defaultConfig.ListObjectsPlanner.StatisticsTTL, "how long the statistics of a store collected by the ListObjects query planner are used before they are collected again")
+
+	flags.Uint32("listObjects-planner-sample-size", defaultConfig.ListObjectsPlanner.SampleSize, "the maximum number of tuples of a store read by the ListObjects query planner to collect its statistics. Stores with more tuples are always resolved with reverse expansion")
+
+	flags.Duration("store-stats-cache-ttl", defaultConfig.StoreStats.CacheTTL, "how long the statistics of a store (tuple counts by type and relation, model versions and changelog size) are cached before they are read from the datastore again. 0 disables the cache")
+
+	flags.String("model-modules-dir", defaultConfig.ModelModules.Dir, "a directory of authorization model modules written in the DSL ('<name>.fga' files), which the models written can include by name with the 'openfga-model-includes' gRPC metadata")
+
 	// NOTE: if you add a new flag here, update the function below, too
 
 	cmd.PreRun = bindRunFlagsFunc(flags)
@@ -189,7 +526,8 @@ func NewRunCommand() *cobra.Command {
 // DatastoreConfig defines OpenFGA server configurations for datastore specific settings.
 type DatastoreConfig struct {
 
-	// Engine is the datastore engine to use (e.g. 'memory', 'postgres', 'mysql')
+	// Engine is the datastore engine to use (e.g. 'memory', 'postgres', 'mysql', 'cockroachdb', 'cassandra',
+	// 'bolt'), or the name of an engine registered with storage.Register
 	Engine   string
 	URI      string
 	Username string
@@ -211,6 +549,60 @@ type DatastoreConfig struct {
 
 	// ConnMaxLifetime is the maximum amount of time a connection to the datastore may be reused.
 	ConnMaxLifetime time.Duration
+
+	// ConnAcquireTimeout is the maximum amount of time a query waits for a connection to the datastore when all of them
+	// are in use. If 0, queries wait until the request times out.
+	ConnAcquireTimeout time.Duration
+
+	// MaxTuples is the maximum number of tuples held by the datastore, beyond which the least recently written tuples
+	// are evicted. It only applies to the 'memory' engine, and the number of tuples is not bounded if it is 0.
+	MaxTuples int
+
+	// MaxChanges is the maximum number of changes held in the changelog of each store, beyond which the oldest changes
+	// are deleted. It only applies to the 'memory' engine, and the number of changes is not bounded if it is 0.
+	MaxChanges int
+
+	// FollowerReadStaleness is the staleness of the follower reads ('AS OF SYSTEM TIME') used to read
+	// tuples. It only applies to the 'cockroachdb' engine, and follower reads are disabled if it is 0.
+	FollowerReadStaleness time.Duration
+
+	// ReadURI is the connection uri of the read replica the tuple reads (e.g. of Read, Check and ListObjects)
+	// are routed to. It only applies to the 'postgres' and 'mysql' engines. The writes, the other reads and
+	// the reads with strong consistency are routed to the primary.
+	ReadURI string
+
+	// ReplicaMaxLag is the maximum replication lag of the read replica, which is measured every second. The
+	// reads are routed to the primary while the measured lag exceeds it or is unknown, or is more recent than
+	// their consistency token.
+	ReplicaMaxLag time.Duration
+
+	// SerializeWrites serializes every write of a store ('postgres' and 'mysql' engines only), so that the writes
+	// with an expected changelog token observe the writes committed concurrently. Otherwise, only the writes with an
+	// expected changelog token are serialized with each other. It lowers the write throughput of a store.
+	SerializeWrites bool
+
+	// Shards are the shards the stores are spread across, as 'name=uri' pairs of the connection uris of datastores
+	// of the engine. If empty, the datastore is not sharded and URI is used.
+	Shards []string
+
+	// DrainingShards are the names of the shards which own no stores, except those pinned to them.
+	DrainingShards []string
+
+	// ShardPins are the stores pinned to a shard, as 'storeID=name' pairs.
+	ShardPins []string
+
+	// Tenants are the tenants whose stores are isolated in datastores of their own, as 'name=uri' pairs of the
+	// connection uris of datastores of the engine, e.g. of separate databases or schemas. The stores of the requests
+	// with the tenant.Header metadata are in the datastore of the tenant, and the other stores in the datastore of URI
+	// or Shards.
+	Tenants []string
+
+	// TenantRoutes are the stores routed to a tenant, as 'storeID=name' pairs, whatever the tenant of their requests.
+	TenantRoutes []string
+
+	// UserEncryptionKey is the key the users of the tuples are encrypted with in the datastore. The encryption is
+	// deterministic, so that the tuples can still be looked up by user. If empty, the users are stored in plaintext.
+	UserEncryptionKey string
 }
 
 // GRPCConfig defines OpenFGA server configurations for grpc server specific settings.
@@ -238,6 +630,11 @@ type TLSConfig struct {
 	Enabled  bool
 	CertPath string `mapstructure:"cert"`
 	KeyPath  string `mapstructure:"key"`
+
+	// ClientCAPath is the file path of a PEM bundle of CAs. If set, the clients must present a certificate signed
+	// by one of them (mutual TLS), and its subject is the principal of their requests. The certificate, key and
+	// bundle are reloaded when they change on disk.
+	ClientCAPath string `mapstructure:"clientCA"`
 }
 
 // AuthnConfig defines OpenFGA server configurations for authentication specific settings.
@@ -247,18 +644,36 @@ type AuthnConfig struct {
 	Method                   string
 	*AuthnOIDCConfig         `mapstructure:"oidc"`
 	*AuthnPresharedKeyConfig `mapstructure:"preshared"`
+
+	// ScopedAccess restricts each credential to the stores and roles granted by its 'fga:<role>' and
+	// 'fga:<role>:<store id>' scopes, where the role is 'read', 'write' or 'admin'. Credentials without any such
+	// scope are denied access to the API.
+	ScopedAccess bool
 }
 
 // AuthnOIDCConfig defines configurations for the 'oidc' method of authentication.
 type AuthnOIDCConfig struct {
 	Issuer   string
 	Audience string
+
+	// AdditionalIssuers are the issuers trusted on top of Issuer, e.g. to accept the tokens of several identity
+	// providers.
+	AdditionalIssuers []string
+
+	// RequiredScopes are the scopes every token must carry.
+	RequiredScopes []string
+
+	// JWKSRefreshInterval is how often the keys of the issuers are refreshed in the background.
+	JWKSRefreshInterval time.Duration
 }
 
 // AuthnPresharedKeyConfig defines configurations for the 'preshared' method of authentication.
 type AuthnPresharedKeyConfig struct {
 	// Keys define the preshared keys to verify authn tokens against.
 	Keys []string
+
+	// KeyScopes define the space separated scopes granted to each preshared key (e.g. 'fga:read:<store id>').
+	KeyScopes map[string]string
 }
 
 // LogConfig defines OpenFGA server configurations for log specific settings. For production we
@@ -282,6 +697,75 @@ type OTLPTraceConfig struct {
 	Endpoint string
 }
 
+// TokenEncryptionConfig defines OpenFGA server configurations for the encryption of continuation tokens.
+type TokenEncryptionConfig struct {
+	// Key is the master key used to derive a distinct encryption key for each store. If empty,
+	// continuation tokens are not encrypted.
+	Key string
+
+	// StoreKeys maps store IDs to explicit encryption keys. A key configured here takes precedence
+	// over the key derived from the master key, so a leaked key of a single store can be rotated
+	// without invalidating the continuation tokens of every other store.
+	StoreKeys map[string]string
+
+	// Keys maps key IDs to master keys, and is used instead of Key so that the master key can be
+	// rotated: the continuation tokens are encrypted with the key PrimaryKeyID and embed its ID,
+	// and a previous key is kept in Keys until the tokens it encrypted are no longer in use.
+	Keys map[string]string
+
+	// PrimaryKeyID is the ID of the key in Keys that new continuation tokens are encrypted with.
+	PrimaryKeyID string
+}
+
+// TokenSigningConfig defines OpenFGA server configurations for the signing of continuation tokens.
+type TokenSigningConfig struct {
+	// Algorithm is the JWS algorithm continuation tokens are signed with (e.g. 'HS256', 'RS256',
+	// 'ES256' or 'EdDSA'). If empty, continuation tokens are not signed.
+	Algorithm string
+
+	// Key is the secret of the HMAC algorithms.
+	Key string
+
+	// PrivateKeyPath is the path of the PEM encoded private key of the asymmetric algorithms.
+	PrivateKeyPath string
+
+	// TTL is the time after which a signed continuation token expires and is rejected.
+	TTL time.Duration
+}
+
+// HealthConfig defines configurations for the health checks of the server.
+type HealthConfig struct {
+	// ProbeTimeout bounds the probe of every dependency (the datastore, the cache, the token issuer) by the
+	// readiness checks.
+	ProbeTimeout time.Duration
+}
+
+// ReloadConfig defines the reloads of the config at runtime, which apply its tunable settings (the log level, the
+// rate limits, the check query cache limit and the ListObjects deadline) without restarting the server. The other
+// settings are only applied on restart.
+type ReloadConfig struct {
+	// Enabled reloads the config whenever the server receives a SIGHUP.
+	Enabled bool
+
+	// WatchInterval is how often the config file is checked for changes, which are reloaded. 0 only reloads the
+	// config on SIGHUP.
+	WatchInterval time.Duration
+}
+
+// AdminConfig defines the admin server, which serves the operational endpoints of the server (flushing the caches,
+// trimming the changelog, ...), see admin.NewHandler.
+type AdminConfig struct {
+	Enabled bool
+
+	// Addr is the address the admin server listens on, the loopback interface by default. With HTTP TLS enabled, the
+	// admin server is served with the TLS config of the HTTP server, including its client CAs.
+	Addr string
+
+	// PresharedKeys are the keys the requests to the admin server are authenticated with, as bearer tokens. They
+	// are distinct from the credentials of the API.
+	PresharedKeys []string
+}
+
 // PlaygroundConfig defines OpenFGA server configurations for the Playground specific settings.
 type PlaygroundConfig struct {
 	Enabled bool
@@ -290,8 +774,28 @@ type PlaygroundConfig struct {
 
 // ProfilerConfig defines server configurations specific to pprof profiling.
 type ProfilerConfig struct {
+	// Enabled serves the pprof profiles on the admin server, with its authentication, see AdminConfig.
 	Enabled bool
-	Addr    string
+
+	// MaxProfileDuration is the maximum duration of the CPU profiles and of the execution traces served by the
+	// profiler.
+	MaxProfileDuration time.Duration
+
+	AutoCPUProfile AutoCPUProfileConfig
+}
+
+// AutoCPUProfileConfig defines configurations for capturing CPU profiles automatically when the Check latency
+// degrades.
+type AutoCPUProfileConfig struct {
+	// CheckP99Threshold is the p99 latency of the Check requests above which the CPU is profiled. 0 disables the
+	// automatic profiles.
+	CheckP99Threshold time.Duration
+
+	// Duration is how long the CPU is profiled for.
+	Duration time.Duration
+
+	// Dir is the directory the profiles are written to.
+	Dir string
 }
 
 // MetricConfig defines configurations for serving custom metrics from OpenFGA.
@@ -299,74 +803,553 @@ type MetricConfig struct {
 	Enabled             bool
 	Addr                string
 	EnableRPCHistograms bool
+
+	// EnablePerStoreLabels labels the command metrics (e.g. command_duration_ms) with the id of their store, for up
+	// to MaxStoreLabels stores. The commands on the stores seen after those are labelled "other".
+	EnablePerStoreLabels bool
+	MaxStoreLabels       uint32
 }
 
-type Config struct {
-	// If you change any of these settings, please update the documentation at https://github.com/openfga/openfga.dev/blob/main/docs/content/intro/setup-openfga.mdx
+// LoadSheddingConfig defines configurations for shedding the most expensive RPCs when the datastore is degraded.
+type LoadSheddingConfig struct {
+	Enabled bool
 
-	// ListObjectsDeadline defines the maximum amount of time to accumulate ListObjects results
-	// before the server will respond. This is to protect the server from misuse of the
-	// ListObjects endpoints. It cannot be larger than HTTPConfig.UpstreamTimeout.
-	ListObjectsDeadline time.Duration
+	// DegradedLatencyThreshold is the average datastore latency above which ListObjects requests are shed.
+	DegradedLatencyThreshold time.Duration
 
-	// ListObjectsMaxResults defines the maximum number of results to accumulate
-	// before the non-streaming ListObjects API will respond to the client.
-	// This is to protect the server from misuse of the ListObjects endpoints.
-	ListObjectsMaxResults uint32
+	// CriticalLatencyThreshold is the average datastore latency above which ListObjects and Expand requests are shed.
+	CriticalLatencyThreshold time.Duration
 
-	// MaxTuplesPerWrite defines the maximum number of tuples per Write endpoint.
-	MaxTuplesPerWrite int
+	// ErrorRateThreshold is the fraction of failing datastore calls above which ListObjects and Expand requests are shed.
+	ErrorRateThreshold float64
+}
 
-	// MaxTypesPerAuthorizationModel defines the maximum number of type definitions per authorization model for the WriteAuthorizationModel endpoint.
-	MaxTypesPerAuthorizationModel int
+// RateLimitConfig defines configurations for admission control and the rate limiting of the requests of each store.
+type RateLimitConfig struct {
+	Enabled bool
 
-	// MaxConcurrentReadsForListObjects defines the maximum number of concurrent database reads allowed in ListObjects queries
-	MaxConcurrentReadsForListObjects uint32
+	// RequestsPerSecond is the sustained rate of requests allowed for each store and API method.
+	RequestsPerSecond float64
 
-	// MaxConcurrentReadsForCheck defines the maximum number of concurrent database reads allowed in Check queries
-	MaxConcurrentReadsForCheck uint32
+	// Burst is the number of requests each store can send to an API method at once, on top of the sustained rate.
+	Burst int
 
-	// ChangelogHorizonOffset is an offset in minutes from the current time. Changes that occur after this offset will not be included in the response of ReadChanges.
-	ChangelogHorizonOffset int
+	// Methods overrides RequestsPerSecond for some API methods, as 'Method=requestsPerSecond' pairs.
+	Methods []string
 
-	// Experimentals is a list of the experimental features to enable in the OpenFGA server.
-	Experimentals []string
+	// MaxInFlightRequests is the maximum number of requests the server handles concurrently across all the stores. 0 means unlimited.
+	MaxInFlightRequests uint32
 
-	// ResolveNodeLimit indicates how deeply nested an authorization model can be before a query errors out.
+	// PerClient gives each client certificate subject its own rate limit for each store and API method.
+	PerClient bool
+}
+
+// SchedulerConfig defines the scheduling of the requests by priority, which gives the interactive requests
+// (e.g. Check) precedence over the batch requests (e.g. ListObjects).
+type SchedulerConfig struct {
+	Enabled bool
+
+	// MaxConcurrentRequests is the number of requests the server handles concurrently. The other requests wait
+	// for one of them to complete, the interactive requests first.
+	MaxConcurrentRequests uint32
+
+	// MaxConcurrentBatchRequests is the number of batch requests the server handles concurrently, which must not
+	// be greater than MaxConcurrentRequests.
+	MaxConcurrentBatchRequests uint32
+
+	// QueueTimeout is how long a request waits to be handled before it is rejected. 0 waits for as long as the
+	// request allows.
+	QueueTimeout time.Duration
+
+	// BatchMethods are the API methods whose requests are batch requests, unless the 'openfga-priority' metadata
+	// of a request says otherwise.
+	BatchMethods []string
+}
+
+// RequestFeatureFlagsConfig defines the experimental behaviors toggled per request by the feature flags of their
+// 'openfga-feature-flags' metadata, e.g. to canary changes of the resolvers on a slice of the traffic. See
+// featureflags.Flag.
+type RequestFeatureFlagsConfig struct {
+	Enabled bool
+
+	// Principals are the principals (the subject, or else the client id, of their credentials) allowed to send
+	// feature flags, along with the credentials with the 'fga:feature-flags' scope.
+	Principals []string
+
+	// ResolveNodeLimit is the resolve node limit of the requests with the 'higher-resolve-node-limit' feature flag.
 	ResolveNodeLimit uint32
+}
 
-	// ResolveNodeBreadthLimit indicates how many nodes on a given level can be evaluated concurrently in a query
-	ResolveNodeBreadthLimit uint32
+// ShadowConfig defines the shadow mode, which evaluates a sample of the Check and ListObjects requests again with an
+// experimental resolver, once their result is served, and reports how the results and latencies diverge from those of
+// the current resolver, e.g. to validate a rewrite of a resolver before it is enabled. See commands.ShadowEvaluator.
+type ShadowConfig struct {
+	Enabled bool
 
-	Datastore  DatastoreConfig
-	GRPC       GRPCConfig
-	HTTP       HTTPConfig
-	Authn      AuthnConfig
-	Log        LogConfig
-	Trace      TraceConfig
-	Playground PlaygroundConfig
-	Profiler   ProfilerConfig
-	Metrics    MetricConfig
+	// SampleRate is the fraction of the requests evaluated again, between 0 and 1.
+	SampleRate float64
+
+	// Timeout is the timeout of the evaluations of the experimental resolver.
+	Timeout time.Duration
+
+	// MaxConcurrentEvaluations is the number of evaluations run concurrently. The requests sampled while as many
+	// evaluations are running are not evaluated again.
+	MaxConcurrentEvaluations uint32
+
+	// SlowdownThreshold logs the evaluations of the experimental resolver that take more than SlowdownThreshold times
+	// as long as the current resolver. 0 only records the latency ratios.
+	SlowdownThreshold float64
+
+	// Check evaluates the Checks again, bypassing the check cache.
+	Check bool
+
+	// ListObjectsStrategy is the strategy ('reverse_expansion' or 'concurrent_checks') the ListObjects are evaluated
+	// again with. If empty, the ListObjects are not evaluated again.
+	ListObjectsStrategy string
 }
 
-// DefaultConfig returns the OpenFGA server default configurations.
+// RequestTimeoutConfig defines the timeouts of the requests of the API methods.
+type RequestTimeoutConfig struct {
+	// Default is the timeout of the requests of every API method. 0 means unlimited.
+	Default time.Duration
+
+	// Methods overrides Default for some API methods, as 'Method=timeout' pairs.
+	Methods []string
+}
+
+// QuotasConfig defines the quotas applied to every store. A zero quota is unlimited.
+type QuotasConfig struct {
+	// MaxTuplesPerStore is the maximum number of tuples of a store.
+	MaxTuplesPerStore uint32
+
+	// MaxTypesPerAuthorizationModel is the maximum number of type definitions of the authorization models of a store.
+	MaxTypesPerAuthorizationModel uint32
+
+	// MaxRelationsPerType is the maximum number of relations of a type definition of the authorization models of a store.
+	MaxRelationsPerType uint32
+
+	// MaxRewriteDepth is the maximum nesting depth of the rewrite of a relation of the authorization models of a store.
+	MaxRewriteDepth uint32
+
+	// MaxAuthorizationModelSizeInBytes is the maximum size of the serialized type definitions of the authorization
+	// models of a store.
+	MaxAuthorizationModelSizeInBytes uint32
+
+	// MaxWritesPerSecond is the maximum sustained rate of Write requests to a store.
+	MaxWritesPerSecond float64
+}
+
+// CheckQueryCacheConfig defines configurations for caching the results of Check subproblems.
+type CheckQueryCacheConfig struct {
+	Enabled bool
+
+	// Limit is the maximum number of Check subproblem results held by the cache.
+	Limit uint32
+
+	// TTL is how long a cached Check subproblem result is valid for.
+	TTL time.Duration
+}
+
+// ListObjectsCacheConfig defines configurations for caching the results of ListObjects.
+type ListObjectsCacheConfig struct {
+	Enabled bool
+
+	// Limit is the maximum number of ListObjects results held by the cache.
+	Limit uint32
+
+	// TTL is how long a cached ListObjects result is valid for.
+	TTL time.Duration
+
+	// ChangelogInterval is how often the changelog of a store is read for the changes made otherwise than by the
+	// Writes of the server.
+	ChangelogInterval time.Duration
+}
+
+// CheckDeduplicationConfig defines configurations for collapsing the concurrent identical Checks into a single
+// resolution.
+type CheckDeduplicationConfig struct {
+	Enabled bool
+}
+
+// TypesystemCacheConfig defines configurations for the cache of the authorization models.
+type TypesystemCacheConfig struct {
+	// LatestModelTTL is how long the ID of the latest authorization model of every store is cached. If 0, it is
+	// looked up in the datastore by every request without an authorization model id.
+	LatestModelTTL time.Duration
+}
+
+// ListObjectsPlannerConfig defines configurations for the ListObjects query planner.
+type ListObjectsPlannerConfig struct {
+	Enabled bool
+
+	// StatisticsTTL is how long the statistics of a store are used before they are collected again.
+	StatisticsTTL time.Duration
+
+	// SampleSize is the maximum number of tuples of a store read to collect its statistics.
+	SampleSize uint32
+}
+
+// StoreStatsConfig defines configurations for the statistics of the stores reported by the server.
+type StoreStatsConfig struct {
+	// CacheTTL is how long the statistics of a store are cached before they are read from the datastore again. If 0,
+	// they are not cached.
+	CacheTTL time.Duration
+}
+
+// ModelModulesConfig defines the modules that the authorization models written can include.
+type ModelModulesConfig struct {
+	// Dir is a directory of modules written in the DSL, see typesystem.ParseModule. The name of a module is the name
+	// of its '.fga' file without the extension. If empty, the models cannot include modules.
+	Dir string
+}
+
+// CacheConfig defines the backend of the server's caches.
+type CacheConfig struct {
+	// Backend is the cache backend to use ('memory' or 'redis').
+	Backend string
+
+	Redis RedisCacheConfig
+}
+
+// RedisCacheConfig defines configurations for the 'redis' cache backend.
+type RedisCacheConfig struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// KeyPrefix is prepended to every key, so that a Redis server can be shared by several deployments.
+	KeyPrefix string
+}
+
+// ChangelogExportConfig defines configurations for exporting the changelog of every store to an external sink.
+//
+// The changelog must be exported by a single replica, since the replicas don't coordinate: every replica exporting it
+// exports every change.
+type ChangelogExportConfig struct {
+	Enabled bool
+
+	// Sink is the sink the changelog is exported to ('kafka', 'nats' or 'webhook').
+	Sink string
+
+	// PollInterval is how long to wait between two reads of the changelog once the export is caught up.
+	PollInterval time.Duration
+
+	// PageSize is the maximum number of changes read from the changelog and published at once.
+	PageSize int
+
+	// CheckpointFile is the file where the position of every store in the changelog is saved, so that the changes
+	// are not exported again when the server restarts. It is locked while the server runs, so that two servers of the
+	// same host can't export the changelog with it. The lock is local to the host: servers of other hosts are not
+	// detected.
+	CheckpointFile string
+
+	Kafka   KafkaExportConfig
+	NATS    NATSExportConfig
+	Webhook WebhookExportConfig
+}
+
+// KafkaExportConfig defines configurations for the 'kafka' changelog export sink.
+type KafkaExportConfig struct {
+	Brokers []string
+	Topic   string
+}
+
+// NATSExportConfig defines configurations for the 'nats' changelog export sink.
+type NATSExportConfig struct {
+	URL     string
+	Subject string
+}
+
+// WebhookExportConfig defines configurations for the 'webhook' changelog export sink.
+type WebhookExportConfig struct {
+	URL string
+
+	// URLs are more URLs the changelog is POSTed to, in addition to URL. Every URL is retried and
+	// dead-lettered independently.
+	URLs []string
+
+	// Secret, if set, is the secret the requests are signed with, see cdc.SignatureHeader.
+	Secret string
+
+	// Timeout is how long an endpoint has to respond.
+	Timeout time.Duration
+
+	// MaxRetries is how many times a failed POST is retried, with an exponential backoff from
+	// InitialBackoff up to MaxBackoff.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// DeadLetterFile, if set, is the file where the changes that could not be POSTed to an endpoint are
+	// appended once the retries are exhausted, so that the export moves past them. If empty, the export
+	// is blocked until the endpoint accepts the changes.
+	DeadLetterFile string
+}
+
+// AuditConfig defines configurations for the audit log of the calls that change or query permissions.
+type AuditConfig struct {
+	Enabled bool
+
+	// Sink is the sink the audit records are written to ('file', 'syslog', 'http' or 'kafka').
+	Sink string
+
+	// BufferSize is the maximum number of audit records waiting to be written.
+	BufferSize int
+
+	File   AuditFileConfig
+	Syslog AuditSyslogConfig
+	HTTP   AuditHTTPConfig
+	Kafka  KafkaExportConfig
+}
+
+// AuditFileConfig defines configurations for the 'file' audit sink.
+type AuditFileConfig struct {
+	Path string
+}
+
+// AuditSyslogConfig defines configurations for the 'syslog' audit sink.
+type AuditSyslogConfig struct {
+	// Network and Addr locate the syslog server. If both are empty, the local syslog daemon is used.
+	Network string
+	Addr    string
+	Tag     string
+}
+
+// AuditHTTPConfig defines configurations for the 'http' audit sink.
+type AuditHTTPConfig struct {
+	URL string
+}
+
+// DecisionLogConfig defines configurations for the log of the Check and ListObjects decisions.
+type DecisionLogConfig struct {
+	Enabled bool
+
+	// Output is where the decisions are logged: 'stdout', 'stderr' or the path of a file they are appended to.
+	Output string
+
+	// SampleRate is the probability, between 0 and 1, with which a decision is logged.
+	SampleRate float64
+
+	// RedactFields are the fields of the logged decisions whose values are redacted.
+	RedactFields []string
+}
+
+// RequestRecordingConfig defines configurations for the recording of the Check and ListObjects requests, see
+// recorder.Recorder.
+type RequestRecordingConfig struct {
+	Enabled bool
+
+	// Output is where the requests are recorded: 'stdout', 'stderr' or the path of a file they are appended to.
+	Output string
+
+	// SampleRate is the probability, between 0 and 1, with which a request is recorded.
+	SampleRate float64
+
+	// AnonymizationKey anonymizes the ids of the objects and users of the recorded requests with a hash keyed with it,
+	// if set. See recorder.Anonymizer.
+	AnonymizationKey string
+}
+
+// WriteWebhookConfig defines configurations for the webhook admitting the writes of tuples before they are committed,
+// see writehook.Webhook.
+type WriteWebhookConfig struct {
+	// URL is the endpoint the writes are POSTed to. If empty, the writes are not submitted to a webhook.
+	URL string
+
+	// Timeout is how long the endpoint has to respond.
+	Timeout time.Duration
+
+	// FailOpen admits the writes when the endpoint fails or cannot be reached, instead of failing them.
+	FailOpen bool
+}
+
+// TupleReaperConfig defines configurations for deleting the expired tuples from the datastore.
+type TupleReaperConfig struct {
+	Enabled bool
+
+	// Interval is how long to wait between two deletions of the expired tuples.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of expired tuples deleted in a single transaction.
+	BatchSize int
+}
+
+// StoreRetentionConfig defines configurations for restoring and purging the deleted stores.
+type StoreRetentionConfig struct {
+	// Period is how long the deleted stores are kept, and can be restored, before they are purged.
+	Period time.Duration
+
+	// PurgeEnabled enables periodically purging the stores deleted longer than Period ago.
+	PurgeEnabled bool
+
+	// PurgeInterval is how long to wait between two purges of the deleted stores.
+	PurgeInterval time.Duration
+
+	// PurgeBatchSize is the maximum number of deleted stores purged in a single call to the datastore.
+	PurgeBatchSize int
+}
+
+// ChangelogRetentionConfig defines configurations for deleting the old changes from the changelog.
+type ChangelogRetentionConfig struct {
+	Enabled bool
+
+	// Period is how long the changes are kept in the changelog.
+	Period time.Duration
+
+	// Interval is how long to wait between two deletions of the old changes.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of changes deleted in a single call to the datastore.
+	BatchSize int
+}
+
+type Config struct {
+	// If you change any of these settings, please update the documentation at https://github.com/openfga/openfga.dev/blob/main/docs/content/intro/setup-openfga.mdx
+
+	// ListObjectsDeadline defines the maximum amount of time to accumulate ListObjects results
+	// before the server will respond. This is to protect the server from misuse of the
+	// ListObjects endpoints. It cannot be larger than HTTPConfig.UpstreamTimeout.
+	ListObjectsDeadline time.Duration
+
+	// ListObjectsMaxResults defines the maximum number of results to accumulate
+	// before the non-streaming ListObjects API will respond to the client.
+	// This is to protect the server from misuse of the ListObjects endpoints.
+	ListObjectsMaxResults uint32
+
+	// ListObjectsSortOrder defines the order of the objects returned by the non-streaming ListObjects API,
+	// either 'unsorted' or 'objectId'.
+	ListObjectsSortOrder string
+
+	// ListObjectsPartialResults defines whether ListObjects responds with the objects resolved until
+	// ListObjectsDeadline when it is hit, or fails with a deadline exceeded error.
+	ListObjectsPartialResults bool
+
+	// ListObjectsStreamBufferSize defines how many objects the streaming ListObjects API buffers while the
+	// client receives the previous ones.
+	ListObjectsStreamBufferSize uint32
+
+	// ListObjectsStreamSendTimeout defines how long the streaming ListObjects API waits for the client to
+	// receive an object before ending the stream. 0 waits for as long as ListObjectsDeadline allows.
+	ListObjectsStreamSendTimeout time.Duration
+
+	// MaxTuplesPerWrite defines the maximum number of tuples per Write endpoint.
+	MaxTuplesPerWrite int
+
+	// MaxTypesPerAuthorizationModel defines the maximum number of type definitions per authorization model for the WriteAuthorizationModel endpoint.
+	MaxTypesPerAuthorizationModel int
+
+	// MaxConcurrentReadsForListObjects defines the maximum number of concurrent database reads allowed in ListObjects queries
+	MaxConcurrentReadsForListObjects uint32
+
+	// ListObjectsMaxConcurrentChecks defines the maximum number of Checks a ListObjects query issues concurrently to
+	// evaluate its candidate objects. 0 uses ResolveNodeBreadthLimit.
+	ListObjectsMaxConcurrentChecks uint32
+
+	// MaxConcurrentReadsForCheck defines the maximum number of concurrent database reads allowed in Check queries
+	MaxConcurrentReadsForCheck uint32
+
+	// MaxDispatchCountPerCheck defines the maximum number of subproblems a single Check query can dispatch. 0 means unlimited.
+	MaxDispatchCountPerCheck uint32
+
+	// MaxDatastoreReadsPerCheck defines the maximum number of datastore reads a single Check query can issue. 0 means unlimited.
+	MaxDatastoreReadsPerCheck uint32
+
+	// ChangelogHorizonOffset is an offset in minutes from the current time. Changes that occur after this offset will not be included in the response of ReadChanges.
+	ChangelogHorizonOffset int
+
+	// Experimentals is a list of the experimental features to enable in the OpenFGA server.
+	Experimentals []string
+
+	// ResolveNodeLimit indicates how deeply nested an authorization model can be before a query errors out.
+	ResolveNodeLimit uint32
+
+	// ResolveNodeBreadthLimit indicates how many nodes on a given level can be evaluated concurrently in a query
+	ResolveNodeBreadthLimit uint32
+
+	// ExpandDepth indicates how many levels of usersets the Expand API expands. It cannot be greater than
+	// ResolveNodeLimit.
+	ExpandDepth uint32
+
+	// ReadOnly indicates that the server must reject every mutating API while still serving queries.
+	ReadOnly bool
+
+	// ContinuationTokenFormat is the format of the continuation tokens of the tuple and changelog reads: 'versioned'
+	// tokens carry a version byte, or 'raw' tokens are the pagination state of the datastore, which the servers of
+	// previous versions can decode (e.g. during a rolling upgrade). The tokens of both formats are accepted.
+	ContinuationTokenFormat string
+
+	Datastore  DatastoreConfig
+	GRPC       GRPCConfig
+	HTTP       HTTPConfig
+	Authn      AuthnConfig
+	Log        LogConfig
+	Trace      TraceConfig
+	Playground PlaygroundConfig
+	Profiler   ProfilerConfig
+	Metrics    MetricConfig
+	Health     HealthConfig
+	Reload     ReloadConfig
+	Admin      AdminConfig
+
+	TokenEncryption     TokenEncryptionConfig
+	TokenSigning        TokenSigningConfig
+	LoadShedding        LoadSheddingConfig
+	RateLimit           RateLimitConfig
+	Scheduler           SchedulerConfig
+	RequestFeatureFlags RequestFeatureFlagsConfig
+	Shadow              ShadowConfig
+	RequestTimeout      RequestTimeoutConfig
+	Quotas              QuotasConfig
+	CheckQueryCache     CheckQueryCacheConfig
+	ListObjectsCache    ListObjectsCacheConfig
+	CheckDeduplication  CheckDeduplicationConfig
+	TypesystemCache     TypesystemCacheConfig
+	ListObjectsPlanner  ListObjectsPlannerConfig
+	StoreStats          StoreStatsConfig
+	ModelModules        ModelModulesConfig
+	Cache               CacheConfig
+	ChangelogExport     ChangelogExportConfig
+	TupleReaper         TupleReaperConfig
+	StoreRetention      StoreRetentionConfig
+	ChangelogRetention  ChangelogRetentionConfig
+	Audit               AuditConfig
+	DecisionLog         DecisionLogConfig
+	RequestRecording    RequestRecordingConfig
+	WriteWebhook        WriteWebhookConfig
+}
+
+// DefaultConfig returns the OpenFGA server default configurations.
 func DefaultConfig() *Config {
 	return &Config{
 		MaxTuplesPerWrite:                100,
 		MaxTypesPerAuthorizationModel:    100,
 		MaxConcurrentReadsForCheck:       math.MaxUint32,
 		MaxConcurrentReadsForListObjects: math.MaxUint32,
+		ListObjectsMaxConcurrentChecks:   0,
+		MaxDispatchCountPerCheck:         0,
+		MaxDatastoreReadsPerCheck:        0,
 		ChangelogHorizonOffset:           0,
 		ResolveNodeLimit:                 25,
+		ExpandDepth:                      1,
 		ResolveNodeBreadthLimit:          100,
 		Experimentals:                    []string{},
 		ListObjectsDeadline:              3 * time.Second, // there is a 3-second timeout elsewhere
 		ListObjectsMaxResults:            1000,
+		ListObjectsSortOrder:             "unsorted",
+		ListObjectsPartialResults:        true,
+		ListObjectsStreamBufferSize:      100,
+		ListObjectsStreamSendTimeout:     0,
 		Datastore: DatastoreConfig{
-			Engine:       "memory",
-			MaxCacheSize: 100000,
-			MaxIdleConns: 10,
-			MaxOpenConns: 30,
+			Engine:         "memory",
+			MaxCacheSize:   100000,
+			MaxIdleConns:   10,
+			MaxOpenConns:   30,
+			ReplicaMaxLag:  5 * time.Second,
+			Shards:         []string{},
+			DrainingShards: []string{},
+			ShardPins:      []string{},
+			Tenants:        []string{},
+			TenantRoutes:   []string{},
 		},
 		GRPC: GRPCConfig{
 			Addr: "0.0.0.0:8081",
@@ -382,8 +1365,8 @@ func DefaultConfig() *Config {
 		},
 		Authn: AuthnConfig{
 			Method:                  "none",
-			AuthnPresharedKeyConfig: &AuthnPresharedKeyConfig{},
-			AuthnOIDCConfig:         &AuthnOIDCConfig{},
+			AuthnPresharedKeyConfig: &AuthnPresharedKeyConfig{KeyScopes: map[string]string{}},
+			AuthnOIDCConfig:         &AuthnOIDCConfig{JWKSRefreshInterval: oidc.DefaultJWKSRefreshInterval},
 		},
 		Log: LogConfig{
 			Format: "text",
@@ -397,18 +1380,176 @@ func DefaultConfig() *Config {
 			SampleRatio: 0.2,
 			ServiceName: "openfga",
 		},
+		ContinuationTokenFormat: "versioned",
+		TokenEncryption: TokenEncryptionConfig{
+			StoreKeys: map[string]string{},
+			Keys:      map[string]string{},
+		},
+		TokenSigning: TokenSigningConfig{
+			TTL: 24 * time.Hour,
+		},
+		LoadShedding: LoadSheddingConfig{
+			Enabled:                  false,
+			DegradedLatencyThreshold: 250 * time.Millisecond,
+			CriticalLatencyThreshold: 1 * time.Second,
+			ErrorRateThreshold:       0.5,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:             false,
+			RequestsPerSecond:   100,
+			Burst:               200,
+			Methods:             []string{},
+			MaxInFlightRequests: 0,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                    false,
+			MaxConcurrentRequests:      100,
+			MaxConcurrentBatchRequests: 50,
+			QueueTimeout:               5 * time.Second,
+			BatchMethods:               []string{"ListObjects", "StreamedListObjects", "Expand"},
+		},
+		RequestFeatureFlags: RequestFeatureFlagsConfig{
+			Enabled:          false,
+			Principals:       []string{},
+			ResolveNodeLimit: 50,
+		},
+		Shadow: ShadowConfig{
+			Enabled:                  false,
+			SampleRate:               0.01,
+			Timeout:                  10 * time.Second,
+			MaxConcurrentEvaluations: 10,
+			SlowdownThreshold:        0,
+			Check:                    false,
+			ListObjectsStrategy:      "",
+		},
+		RequestTimeout: RequestTimeoutConfig{
+			Default: 0,
+			Methods: []string{},
+		},
+		Quotas: QuotasConfig{
+			MaxTuplesPerStore:                0,
+			MaxTypesPerAuthorizationModel:    0,
+			MaxRelationsPerType:              0,
+			MaxRewriteDepth:                  0,
+			MaxAuthorizationModelSizeInBytes: 0,
+			MaxWritesPerSecond:               0,
+		},
+		CheckQueryCache: CheckQueryCacheConfig{
+			Enabled: false,
+			Limit:   10000,
+			TTL:     10 * time.Second,
+		},
+		ListObjectsCache: ListObjectsCacheConfig{
+			Enabled:           false,
+			Limit:             1000,
+			TTL:               10 * time.Second,
+			ChangelogInterval: time.Second,
+		},
+		ListObjectsPlanner: ListObjectsPlannerConfig{
+			Enabled:       false,
+			StatisticsTTL: time.Minute,
+			SampleSize:    100000,
+		},
+		StoreStats: StoreStatsConfig{
+			CacheTTL: 30 * time.Second,
+		},
+		ModelModules: ModelModulesConfig{
+			Dir: "",
+		},
+		Cache: CacheConfig{
+			Backend: "memory",
+			Redis: RedisCacheConfig{
+				Addr:      "localhost:6379",
+				KeyPrefix: "openfga/",
+			},
+		},
+		ChangelogExport: ChangelogExportConfig{
+			Enabled:      false,
+			Sink:         "kafka",
+			PollInterval: 5 * time.Second,
+			PageSize:     50,
+			Kafka: KafkaExportConfig{
+				Brokers: []string{},
+			},
+			Webhook: WebhookExportConfig{
+				URLs:           []string{},
+				Timeout:        10 * time.Second,
+				MaxRetries:     5,
+				InitialBackoff: 500 * time.Millisecond,
+				MaxBackoff:     30 * time.Second,
+			},
+		},
+		TupleReaper: TupleReaperConfig{
+			Enabled:   true,
+			Interval:  1 * time.Minute,
+			BatchSize: 100,
+		},
+		StoreRetention: StoreRetentionConfig{
+			Period:         storage.DefaultStoreRetentionPeriod,
+			PurgeEnabled:   false,
+			PurgeInterval:  1 * time.Hour,
+			PurgeBatchSize: 10,
+		},
+		ChangelogRetention: ChangelogRetentionConfig{
+			Enabled:   false,
+			Period:    storage.DefaultChangelogRetentionPeriod,
+			Interval:  1 * time.Hour,
+			BatchSize: 1000,
+		},
+		Audit: AuditConfig{
+			Enabled:    false,
+			Sink:       "file",
+			BufferSize: 10000,
+			Syslog: AuditSyslogConfig{
+				Tag: "openfga-audit",
+			},
+			Kafka: KafkaExportConfig{
+				Brokers: []string{},
+			},
+		},
+		DecisionLog: DecisionLogConfig{
+			Enabled:      false,
+			Output:       "stdout",
+			SampleRate:   1,
+			RedactFields: []string{},
+		},
+		RequestRecording: RequestRecordingConfig{
+			Enabled:          false,
+			Output:           "openfga-requests.jsonl",
+			SampleRate:       0.01,
+			AnonymizationKey: "",
+		},
+		WriteWebhook: WriteWebhookConfig{
+			Timeout: 5 * time.Second,
+		},
 		Playground: PlaygroundConfig{
 			Enabled: true,
 			Port:    3000,
 		},
 		Profiler: ProfilerConfig{
-			Enabled: false,
-			Addr:    ":3001",
+			Enabled:            false,
+			MaxProfileDuration: 30 * time.Second,
+			AutoCPUProfile: AutoCPUProfileConfig{
+				Duration: 10 * time.Second,
+			},
 		},
 		Metrics: MetricConfig{
 			Enabled:             true,
 			Addr:                "0.0.0.0:2112",
 			EnableRPCHistograms: false,
+			MaxStoreLabels:      100,
+		},
+		Health: HealthConfig{
+			ProbeTimeout: 3 * time.Second,
+		},
+		Reload: ReloadConfig{
+			Enabled:       false,
+			WatchInterval: 0,
+		},
+		Admin: AdminConfig{
+			Enabled:       false,
+			Addr:          "127.0.0.1:3002",
+			PresharedKeys: []string{},
 		},
 	}
 }
@@ -465,96 +1606,146 @@ func ReadConfig() (*Config, error) {
 	return config, nil
 }
 
-func VerifyConfig(cfg *Config) error {
-	if int(cfg.MaxConcurrentReadsForCheck) > cfg.Datastore.MaxOpenConns {
-		fmt.Printf("config 'maxConcurrentReadsForCheck' (%d) should not be higher than 'datastore.maxOpenConns' config (%d)\n", cfg.MaxConcurrentReadsForCheck, cfg.Datastore.MaxOpenConns)
+// NewDatastore returns the datastore of the config: the datastore of the engine connected to the datastore uri or, if
+// the config has shards, the datastore spreading the stores across them. If the config has tenants, the stores of the
+// tenants are routed to their datastores.
+func NewDatastore(config *Config, logger logger.Logger) (storage.OpenFGADatastore, error) {
+	var datastore storage.OpenFGADatastore
+	var err error
+	if len(config.Datastore.Shards) == 0 {
+		datastore, err = newDatastore(config, config.Datastore.URI, config.Datastore.ReadURI, logger)
+	} else {
+		datastore, err = newShardedDatastore(config.Datastore, func(uri string) (storage.OpenFGADatastore, error) {
+			return newDatastore(config, uri, "", logger)
+		})
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	if int(cfg.MaxConcurrentReadsForListObjects) > cfg.Datastore.MaxOpenConns {
-		fmt.Printf("config 'maxConcurrentReadsForListObjects' (%d) should not be higher than 'datastore.maxOpenConns' config (%d)\n", cfg.MaxConcurrentReadsForListObjects, cfg.Datastore.MaxOpenConns)
+	if len(config.Datastore.Tenants) == 0 {
+		return datastore, nil
 	}
 
-	if cfg.ListObjectsDeadline > cfg.HTTP.UpstreamTimeout {
-		return fmt.Errorf("config 'http.upstreamTimeout' (%s) cannot be lower than 'listObjectsDeadline' config (%s)", cfg.HTTP.UpstreamTimeout, cfg.ListObjectsDeadline)
+	tenants, err := newTenancyDatastore(config.Datastore, datastore, func(uri string) (storage.OpenFGADatastore, error) {
+		return newDatastore(config, uri, "", logger)
+	})
+	if err != nil {
+		datastore.Close()
+		return nil, err
 	}
 
-	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
-		return fmt.Errorf("config 'log.format' must be one of ['text', 'json']")
+	return tenants, nil
+}
+
+// newTenancyDatastore returns the datastore routing the stores of the tenants of the config to their datastores, which
+// are opened by `open`, and the other stores to the shared datastore.
+func newTenancyDatastore(cfg DatastoreConfig, shared storage.OpenFGADatastore, open func(uri string) (storage.OpenFGADatastore, error)) (*tenancy.Tenancy, error) {
+	tenantURIs, err := util.ParsePairs("datastore.tenants", "name=uri", cfg.Tenants)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.Log.Level != "none" &&
-		cfg.Log.Level != "debug" &&
-		cfg.Log.Level != "info" &&
-		cfg.Log.Level != "warn" &&
-		cfg.Log.Level != "error" &&
-		cfg.Log.Level != "panic" &&
-		cfg.Log.Level != "fatal" {
-		return fmt.Errorf("config 'log.level' must be one of ['none', 'debug', 'info', 'warn', 'error', 'panic', 'fatal']")
+	routePairs, err := util.ParsePairs("datastore.tenantRoutes", "storeID=name", cfg.TenantRoutes)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.Playground.Enabled {
-		if !cfg.HTTP.Enabled {
-			return errors.New("the HTTP server must be enabled to run the openfga playground")
-		}
+	routes := make(map[string]string, len(routePairs))
+	for _, route := range routePairs {
+		routes[route.Key] = route.Value
+	}
 
-		if !(cfg.Authn.Method == "none" || cfg.Authn.Method == "preshared") {
-			return errors.New("the playground only supports authn methods 'none' and 'preshared'")
+	var tenants []tenancy.Tenant
+	closeTenants := func() {
+		for _, tenant := range tenants {
+			if tenant.Datastore != nil {
+				tenant.Datastore.Close()
+			}
 		}
 	}
 
-	if cfg.HTTP.TLS.Enabled {
-		if cfg.HTTP.TLS.CertPath == "" || cfg.HTTP.TLS.KeyPath == "" {
-			return errors.New("'http.tls.cert' and 'http.tls.key' configs must be set")
+	for _, tenantURI := range tenantURIs {
+		ds, err := open(tenantURI.Value)
+		if err != nil {
+			closeTenants()
+			return nil, fmt.Errorf("tenant '%s': %w", tenantURI.Key, err)
 		}
+
+		tenants = append(tenants, tenancy.Tenant{Name: tenantURI.Key, Datastore: ds})
 	}
 
-	if cfg.GRPC.TLS.Enabled {
-		if cfg.GRPC.TLS.CertPath == "" || cfg.GRPC.TLS.KeyPath == "" {
-			return errors.New("'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
-		}
+	ds, err := tenancy.New(shared, tenants, tenancy.WithRoutes(routes))
+	if err != nil {
+		closeTenants()
+		return nil, fmt.Errorf("invalid config 'datastore.tenants': %w", err)
 	}
 
-	return nil
+	return ds, nil
 }
 
-func run(_ *cobra.Command, _ []string) {
-	config, err := ReadConfig()
+// newShardedDatastore returns the datastore spreading the stores across the shards of the config, whose datastores are
+// opened by `open`.
+func newShardedDatastore(cfg DatastoreConfig, open func(uri string) (storage.OpenFGADatastore, error)) (*sharded.Sharded, error) {
+	shardURIs, err := util.ParsePairs("datastore.shards", "name=uri", cfg.Shards)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	if err := RunServer(context.Background(), config); err != nil {
-		panic(err)
+	pinPairs, err := util.ParsePairs("datastore.shardPins", "storeID=name", cfg.ShardPins)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func RunServer(ctx context.Context, config *Config) error {
-	if err := VerifyConfig(config); err != nil {
-		return err
+	pins := make(map[string]string, len(pinPairs))
+	for _, pin := range pinPairs {
+		pins[pin.Key] = pin.Value
 	}
 
-	logger := logger.MustNewLogger(config.Log.Format, config.Log.Level)
+	var shards []sharded.Shard
+	closeShards := func() {
+		for _, shard := range shards {
+			if shard.Datastore != nil {
+				shard.Datastore.Close()
+			}
+		}
+	}
 
-	tp := sdktrace.NewTracerProvider()
-	if config.Trace.Enabled {
-		logger.Info(fmt.Sprintf("🕵 tracing enabled: sampling ratio is %v and sending traces to '%s'", config.Trace.SampleRatio, config.Trace.OTLP.Endpoint))
-		tp = telemetry.MustNewTracerProvider(
-			telemetry.WithOTLPEndpoint(config.Trace.OTLP.Endpoint),
-			telemetry.WithAttributes(
-				semconv.ServiceNameKey.String(config.Trace.ServiceName),
-				semconv.ServiceVersionKey.String(build.Version),
-			),
-			telemetry.WithSamplingRatio(config.Trace.SampleRatio),
-		)
+	names := make([]string, 0, len(shardURIs))
+	for _, shardURI := range shardURIs {
+		names = append(names, shardURI.Key)
+	}
+	for _, name := range cfg.DrainingShards {
+		if !util.Contains(names, name) {
+			return nil, fmt.Errorf("config 'datastore.drainingShards' names the unknown shard '%s'", name)
+		}
 	}
 
-	logger.Info(fmt.Sprintf("🧪 experimental features enabled: %v", config.Experimentals))
+	for _, shardURI := range shardURIs {
+		ds, err := open(shardURI.Value)
+		if err != nil {
+			closeShards()
+			return nil, fmt.Errorf("shard '%s': %w", shardURI.Key, err)
+		}
 
-	var experimentals []server.ExperimentalFeatureFlag
-	for _, feature := range config.Experimentals {
-		experimentals = append(experimentals, server.ExperimentalFeatureFlag(feature))
+		shards = append(shards, sharded.Shard{
+			Name:      shardURI.Key,
+			Datastore: ds,
+			Draining:  util.Contains(cfg.DrainingShards, shardURI.Key),
+		})
+	}
+
+	ds, err := sharded.New(shards, sharded.WithPins(pins))
+	if err != nil {
+		closeShards()
+		return nil, fmt.Errorf("invalid config 'datastore.shards': %w", err)
 	}
 
+	return ds, nil
+}
+
+// newDatastore returns the datastore of the engine of the config connected to the provided uris.
+func newDatastore(config *Config, uri, readURI string, logger logger.Logger) (storage.OpenFGADatastore, error) {
 	dsCfg := sqlcommon.NewConfig(
 		sqlcommon.WithUsername(config.Datastore.Username),
 		sqlcommon.WithPassword(config.Datastore.Password),
@@ -565,159 +1756,1236 @@ func RunServer(ctx context.Context, config *Config) error {
 		sqlcommon.WithMaxIdleConns(config.Datastore.MaxIdleConns),
 		sqlcommon.WithConnMaxIdleTime(config.Datastore.ConnMaxIdleTime),
 		sqlcommon.WithConnMaxLifetime(config.Datastore.ConnMaxLifetime),
+		sqlcommon.WithConnAcquireTimeout(config.Datastore.ConnAcquireTimeout),
+		sqlcommon.WithReadURI(readURI),
+		sqlcommon.WithReplicaMaxLag(config.Datastore.ReplicaMaxLag),
+		sqlcommon.WithSerializeWrites(config.Datastore.SerializeWrites),
 	)
 
-	var datastore storage.OpenFGADatastore
-	var err error
+	engineCfg := &storage.DatastoreConfig{
+		URI:                           uri,
+		Username:                      config.Datastore.Username,
+		Password:                      config.Datastore.Password,
+		Logger:                        logger,
+		MaxTuplesPerWrite:             config.MaxTuplesPerWrite,
+		MaxTypesPerAuthorizationModel: config.MaxTypesPerAuthorizationModel,
+		MaxOpenConns:                  config.Datastore.MaxOpenConns,
+		MaxIdleConns:                  config.Datastore.MaxIdleConns,
+		ConnMaxIdleTime:               config.Datastore.ConnMaxIdleTime,
+		ConnMaxLifetime:               config.Datastore.ConnMaxLifetime,
+	}
+
 	switch config.Datastore.Engine {
 	case "memory":
 		opts := []memory.StorageOption{
 			memory.WithMaxTypesPerAuthorizationModel(config.MaxTypesPerAuthorizationModel),
 			memory.WithMaxTuplesPerWrite(config.MaxTuplesPerWrite),
+			memory.WithMaxTuples(config.Datastore.MaxTuples),
+			memory.WithMaxChanges(config.Datastore.MaxChanges),
 		}
-		datastore = memory.New(opts...)
+		return memory.New(opts...), nil
 	case "mysql":
-		datastore, err = mysql.New(config.Datastore.URI, dsCfg)
+		datastore, err := mysql.New(uri, dsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize mysql datastore: %w", err)
+		}
+		return datastore, nil
+	case "postgres":
+		datastore, err := postgres.New(uri, dsCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize postgres datastore: %w", err)
+		}
+		return datastore, nil
+	case "cockroachdb":
+		datastore, err := crdb.New(uri, dsCfg, crdb.WithFollowerReadStaleness(config.Datastore.FollowerReadStaleness))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cockroachdb datastore: %w", err)
+		}
+		return datastore, nil
+	case "bolt":
+		datastore, err := bolt.New(
+			uri,
+			bolt.WithLogger(logger),
+			bolt.WithMaxTuplesPerWrite(config.MaxTuplesPerWrite),
+			bolt.WithMaxTypesPerAuthorizationModel(config.MaxTypesPerAuthorizationModel),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize bolt datastore: %w", err)
+		}
+		return datastore, nil
+	case "cassandra":
+		datastore, err := cassandra.New(engineCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cassandra datastore: %w", err)
+		}
+		return datastore, nil
+	default:
+		factory, ok := storage.LookupFactory(config.Datastore.Engine)
+		if !ok {
+			return nil, fmt.Errorf("storage engine '%s' is unsupported", config.Datastore.Engine)
+		}
+
+		datastore, err := factory(engineCfg)
 		if err != nil {
-			return fmt.Errorf("failed to initialize mysql datastore: %w", err)
+			return nil, fmt.Errorf("failed to initialize %s datastore: %w", config.Datastore.Engine, err)
+		}
+		return datastore, nil
+	}
+}
+
+// listObjectsSortOrders maps the values of Config.ListObjectsSortOrder to the sort orders they stand for.
+var listObjectsSortOrders = map[string]commands.ListObjectsSortOrder{
+	"unsorted": commands.ListObjectsUnsorted,
+	"objectId": commands.ListObjectsSortedByObjectID,
+}
+
+func VerifyConfig(cfg *Config) error {
+	if int(cfg.MaxConcurrentReadsForCheck) > cfg.Datastore.MaxOpenConns {
+		fmt.Printf("config 'maxConcurrentReadsForCheck' (%d) should not be higher than 'datastore.maxOpenConns' config (%d)\n", cfg.MaxConcurrentReadsForCheck, cfg.Datastore.MaxOpenConns)
+	}
+
+	if int(cfg.MaxConcurrentReadsForListObjects) > cfg.Datastore.MaxOpenConns {
+		fmt.Printf("config 'maxConcurrentReadsForListObjects' (%d) should not be higher than 'datastore.maxOpenConns' config (%d)\n", cfg.MaxConcurrentReadsForListObjects, cfg.Datastore.MaxOpenConns)
+	}
+
+	if cfg.Profiler.Enabled && !cfg.Admin.Enabled {
+		return errors.New("config 'profiler.enabled' requires the admin server, see 'admin.enabled'")
+	}
+
+	if cfg.Profiler.MaxProfileDuration <= 0 {
+		return errors.New("config 'profiler.maxProfileDuration' must be greater than 0")
+	}
+
+	if cfg.Profiler.AutoCPUProfile.CheckP99Threshold < 0 {
+		return errors.New("config 'profiler.autoCPUProfile.checkP99Threshold' cannot be negative")
+	}
+
+	if cfg.Profiler.AutoCPUProfile.CheckP99Threshold > 0 {
+		if cfg.Profiler.AutoCPUProfile.Dir == "" {
+			return errors.New("config 'profiler.autoCPUProfile.dir' is required when 'profiler.autoCPUProfile.checkP99Threshold' is set")
+		}
+
+		if cfg.Profiler.AutoCPUProfile.Duration <= 0 {
+			return errors.New("config 'profiler.autoCPUProfile.duration' must be greater than 0")
+		}
+	}
+
+	if cfg.Health.ProbeTimeout <= 0 {
+		return errors.New("config 'health.probeTimeout' must be greater than 0")
+	}
+
+	if cfg.Admin.Enabled && len(cfg.Admin.PresharedKeys) == 0 {
+		return errors.New("config 'admin.presharedKeys' is required when the admin server is enabled")
+	}
+
+	if cfg.Reload.WatchInterval < 0 {
+		return errors.New("config 'reload.watchInterval' cannot be negative")
+	}
+
+	if cfg.Reload.WatchInterval > 0 && !cfg.Reload.Enabled {
+		return errors.New("config 'reload.watchInterval' requires 'reload.enabled'")
+	}
+
+	if cfg.Metrics.EnablePerStoreLabels && cfg.Metrics.MaxStoreLabels == 0 {
+		return errors.New("config 'metrics.maxStoreLabels' must be greater than 0 when 'metrics.enablePerStoreLabels' is enabled")
+	}
+
+	if cfg.ListObjectsDeadline > cfg.HTTP.UpstreamTimeout {
+		return fmt.Errorf("config 'http.upstreamTimeout' (%s) cannot be lower than 'listObjectsDeadline' config (%s)", cfg.HTTP.UpstreamTimeout, cfg.ListObjectsDeadline)
+	}
+
+	if cfg.ExpandDepth == 0 || cfg.ExpandDepth > cfg.ResolveNodeLimit {
+		return fmt.Errorf("config 'expandDepth' (%d) must be between 1 and the 'resolveNodeLimit' config (%d)", cfg.ExpandDepth, cfg.ResolveNodeLimit)
+	}
+
+	if cfg.ListObjectsStreamSendTimeout < 0 {
+		return errors.New("config 'listObjectsStreamSendTimeout' cannot be negative")
+	}
+
+	if _, ok := listObjectsSortOrders[cfg.ListObjectsSortOrder]; !ok {
+		return fmt.Errorf("config 'listObjectsSortOrder' must be one of ['unsorted', 'objectId']")
+	}
+
+	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		return fmt.Errorf("config 'log.format' must be one of ['text', 'json']")
+	}
+
+	if cfg.Log.Level != "none" &&
+		cfg.Log.Level != "debug" &&
+		cfg.Log.Level != "info" &&
+		cfg.Log.Level != "warn" &&
+		cfg.Log.Level != "error" &&
+		cfg.Log.Level != "panic" &&
+		cfg.Log.Level != "fatal" {
+		return fmt.Errorf("config 'log.level' must be one of ['none', 'debug', 'info', 'warn', 'error', 'panic', 'fatal']")
+	}
+
+	if cfg.Authn.Method == "oidc" && cfg.Authn.JWKSRefreshInterval < 0 {
+		return errors.New("config 'authn.oidc.jwksRefreshInterval' cannot be negative")
+	}
+
+	if cfg.Authn.ScopedAccess && cfg.Authn.Method == "none" {
+		return errors.New("config 'authn.scopedAccess' requires an authn method other than 'none'")
+	}
+
+	presharedKeys := map[string]struct{}{}
+	for _, key := range cfg.Authn.Keys {
+		presharedKeys[key] = struct{}{}
+	}
+
+	for key, scopes := range cfg.Authn.KeyScopes {
+		if _, ok := presharedKeys[key]; !ok {
+			return errors.New("config 'authn.preshared.keyScopes' must only set the scopes of keys in 'authn.preshared.keys'")
+		}
+
+		for _, scope := range strings.Fields(scopes) {
+			if scope == featureflags.Scope {
+				continue
+			}
+
+			if _, _, _, err := authz.ParseScope(scope); err != nil {
+				return fmt.Errorf("config 'authn.preshared.keyScopes': %w", err)
+			}
+		}
+	}
+
+	if cfg.Playground.Enabled {
+		if !cfg.HTTP.Enabled {
+			return errors.New("the HTTP server must be enabled to run the openfga playground")
+		}
+
+		if !(cfg.Authn.Method == "none" || cfg.Authn.Method == "preshared") {
+			return errors.New("the playground only supports authn methods 'none' and 'preshared'")
+		}
+	}
+
+	if cfg.Datastore.FollowerReadStaleness != 0 && cfg.Datastore.Engine != "cockroachdb" {
+		return fmt.Errorf("config 'datastore.followerReadStaleness' is only supported by the 'cockroachdb' engine")
+	}
+
+	if cfg.Datastore.FollowerReadStaleness < 0 {
+		return fmt.Errorf("config 'datastore.followerReadStaleness' cannot be negative")
+	}
+
+	if cfg.Datastore.ReadURI != "" && cfg.Datastore.Engine != "postgres" && cfg.Datastore.Engine != "mysql" {
+		return fmt.Errorf("config 'datastore.readURI' is only supported by the 'postgres' and 'mysql' engines")
+	}
+
+	if cfg.Datastore.ReplicaMaxLag < 0 {
+		return fmt.Errorf("config 'datastore.replicaMaxLag' cannot be negative")
+	}
+
+	if len(cfg.Datastore.Shards) > 0 {
+		if cfg.Datastore.ReadURI != "" {
+			return fmt.Errorf("config 'datastore.readURI' is not supported with 'datastore.shards'")
+		}
+
+		// the shards are not opened, only their config is validated
+		noop := func(uri string) (storage.OpenFGADatastore, error) { return nil, nil }
+		if _, err := newShardedDatastore(cfg.Datastore, noop); err != nil {
+			return err
+		}
+	}
+
+	if len(cfg.Datastore.Tenants) > 0 {
+		// the tenants are not opened, only their config is validated
+		noop := func(uri string) (storage.OpenFGADatastore, error) { return nil, nil }
+		if _, err := newTenancyDatastore(cfg.Datastore, nil, noop); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Datastore.ConnAcquireTimeout < 0 {
+		return fmt.Errorf("config 'datastore.connAcquireTimeout' cannot be negative")
+	}
+
+	if cfg.Datastore.MaxTuples < 0 {
+		return fmt.Errorf("config 'datastore.maxTuples' cannot be negative")
+	}
+
+	if cfg.Datastore.MaxChanges < 0 {
+		return fmt.Errorf("config 'datastore.maxChanges' cannot be negative")
+	}
+
+	if cfg.CheckQueryCache.Enabled && cfg.CheckQueryCache.TTL <= 0 {
+		return fmt.Errorf("config 'checkQueryCache.ttl' must be greater than 0 when the check query cache is enabled")
+	}
+
+	if cfg.ListObjectsCache.Enabled && cfg.ListObjectsCache.TTL <= 0 {
+		return fmt.Errorf("config 'listObjectsCache.ttl' must be greater than 0 when the ListObjects cache is enabled")
+	}
+
+	if cfg.ListObjectsCache.ChangelogInterval < 0 {
+		return fmt.Errorf("config 'listObjectsCache.changelogInterval' cannot be negative")
+	}
+
+	if cfg.TypesystemCache.LatestModelTTL < 0 {
+		return fmt.Errorf("config 'typesystemCache.latestModelTTL' cannot be negative")
+	}
+
+	if cfg.ListObjectsPlanner.Enabled && cfg.ListObjectsPlanner.StatisticsTTL <= 0 {
+		return fmt.Errorf("config 'listObjectsPlanner.statisticsTTL' must be greater than 0 when the ListObjects query planner is enabled")
+	}
+
+	if cfg.StoreStats.CacheTTL < 0 {
+		return fmt.Errorf("config 'storeStats.cacheTTL' cannot be negative")
+	}
+
+	if cfg.Cache.Backend != "memory" && cfg.Cache.Backend != "redis" {
+		return fmt.Errorf("config 'cache.backend' must be one of ['memory', 'redis']")
+	}
+
+	if cfg.ChangelogExport.Enabled {
+		switch cfg.ChangelogExport.Sink {
+		case "kafka":
+			if len(cfg.ChangelogExport.Kafka.Brokers) == 0 || cfg.ChangelogExport.Kafka.Topic == "" {
+				return errors.New("'changelogExport.kafka.brokers' and 'changelogExport.kafka.topic' must be set to export the changelog to kafka")
+			}
+		case "nats":
+			if cfg.ChangelogExport.NATS.URL == "" || cfg.ChangelogExport.NATS.Subject == "" {
+				return errors.New("'changelogExport.nats.url' and 'changelogExport.nats.subject' must be set to export the changelog to nats")
+			}
+		case "webhook":
+			webhook := cfg.ChangelogExport.Webhook
+			if webhook.URL == "" && len(webhook.URLs) == 0 {
+				return errors.New("'changelogExport.webhook.url' or 'changelogExport.webhook.urls' must be set to export the changelog to a webhook")
+			}
+
+			if webhook.Timeout <= 0 {
+				return fmt.Errorf("config 'changelogExport.webhook.timeout' must be greater than 0")
+			}
+
+			if webhook.MaxRetries < 0 {
+				return fmt.Errorf("config 'changelogExport.webhook.maxRetries' cannot be negative")
+			}
+
+			if webhook.InitialBackoff <= 0 || webhook.MaxBackoff < webhook.InitialBackoff {
+				return fmt.Errorf("config 'changelogExport.webhook.initialBackoff' must be greater than 0 and at most 'changelogExport.webhook.maxBackoff'")
+			}
+		default:
+			return fmt.Errorf("config 'changelogExport.sink' must be one of ['kafka', 'nats', 'webhook']")
+		}
+
+		if cfg.ChangelogExport.PollInterval <= 0 {
+			return fmt.Errorf("config 'changelogExport.pollInterval' must be greater than 0")
+		}
+
+		if cfg.ChangelogExport.PageSize <= 0 {
+			return fmt.Errorf("config 'changelogExport.pageSize' must be greater than 0")
+		}
+
+		if cfg.ChangelogExport.CheckpointFile == "" {
+			return errors.New("config 'changelogExport.checkpointFile' must be set to export the changelog, so that the changes are not exported again when the server restarts")
+		}
+	}
+
+	if cfg.Audit.Enabled {
+		switch cfg.Audit.Sink {
+		case "file":
+			if cfg.Audit.File.Path == "" {
+				return errors.New("'audit.file.path' must be set to write the audit log to a file")
+			}
+		case "syslog":
+		case "http":
+			if cfg.Audit.HTTP.URL == "" {
+				return errors.New("'audit.http.url' must be set to write the audit log to an HTTP endpoint")
+			}
+		case "kafka":
+			if len(cfg.Audit.Kafka.Brokers) == 0 || cfg.Audit.Kafka.Topic == "" {
+				return errors.New("'audit.kafka.brokers' and 'audit.kafka.topic' must be set to write the audit log to kafka")
+			}
+		default:
+			return fmt.Errorf("config 'audit.sink' must be one of ['file', 'syslog', 'http', 'kafka']")
+		}
+
+		if cfg.Audit.BufferSize <= 0 {
+			return fmt.Errorf("config 'audit.bufferSize' must be greater than 0")
+		}
+	}
+
+	if cfg.DecisionLog.Enabled {
+		if cfg.DecisionLog.Output == "" {
+			return errors.New("config 'decisionLog.output' must be 'stdout', 'stderr' or the path of a file")
+		}
+
+		if cfg.DecisionLog.SampleRate < 0 || cfg.DecisionLog.SampleRate > 1 {
+			return fmt.Errorf("config 'decisionLog.sampleRate' must be between 0 and 1")
+		}
+
+		if _, err := decisionlog.NewEmitter(io.Discard, decisionlog.WithRedactedFields(cfg.DecisionLog.RedactFields...)); err != nil {
+			return fmt.Errorf("config 'decisionLog.redactFields': %w", err)
+		}
+	}
+
+	if cfg.RequestRecording.Enabled {
+		if cfg.RequestRecording.Output == "" {
+			return errors.New("config 'requestRecording.output' must be 'stdout', 'stderr' or the path of a file")
+		}
+
+		if cfg.RequestRecording.SampleRate < 0 || cfg.RequestRecording.SampleRate > 1 {
+			return fmt.Errorf("config 'requestRecording.sampleRate' must be between 0 and 1")
+		}
+	}
+
+	if cfg.WriteWebhook.URL != "" && cfg.WriteWebhook.Timeout <= 0 {
+		return fmt.Errorf("config 'writeWebhook.timeout' must be greater than 0")
+	}
+
+	if cfg.TupleReaper.Enabled {
+		if cfg.TupleReaper.Interval <= 0 {
+			return fmt.Errorf("config 'tupleReaper.interval' must be greater than 0")
+		}
+
+		if cfg.TupleReaper.BatchSize <= 0 {
+			return fmt.Errorf("config 'tupleReaper.batchSize' must be greater than 0")
+		}
+	}
+
+	if cfg.StoreRetention.Period < 0 {
+		return fmt.Errorf("config 'storeRetention.period' must not be negative")
+	}
+
+	if cfg.StoreRetention.PurgeEnabled {
+		if cfg.StoreRetention.PurgeInterval <= 0 {
+			return fmt.Errorf("config 'storeRetention.purgeInterval' must be greater than 0")
+		}
+
+		if cfg.StoreRetention.PurgeBatchSize <= 0 {
+			return fmt.Errorf("config 'storeRetention.purgeBatchSize' must be greater than 0")
+		}
+	}
+
+	if cfg.ChangelogRetention.Enabled {
+		if cfg.ChangelogRetention.Period <= 0 {
+			return fmt.Errorf("config 'changelogRetention.period' must be greater than 0")
+		}
+
+		if cfg.ChangelogRetention.Period <= time.Duration(cfg.ChangelogHorizonOffset)*time.Minute {
+			return fmt.Errorf("config 'changelogRetention.period' must be greater than 'changelogHorizonOffset'")
+		}
+
+		if cfg.ChangelogRetention.Interval <= 0 {
+			return fmt.Errorf("config 'changelogRetention.interval' must be greater than 0")
+		}
+
+		if cfg.ChangelogRetention.BatchSize <= 0 {
+			return fmt.Errorf("config 'changelogRetention.batchSize' must be greater than 0")
+		}
+	}
+
+	if cfg.TokenEncryption.Key != "" && len(cfg.TokenEncryption.Keys) > 0 {
+		return errors.New("only one of 'tokenEncryption.key' and 'tokenEncryption.keys' can be set")
+	}
+
+	if len(cfg.TokenEncryption.Keys) > 0 {
+		if _, ok := cfg.TokenEncryption.Keys[cfg.TokenEncryption.PrimaryKeyID]; !ok {
+			return errors.New("'tokenEncryption.primaryKeyID' must be the ID of one of the 'tokenEncryption.keys'")
+		}
+	} else if cfg.TokenEncryption.PrimaryKeyID != "" {
+		return errors.New("'tokenEncryption.keys' must be set when 'tokenEncryption.primaryKeyID' is provided")
+	}
+
+	if cfg.TokenEncryption.Key == "" && len(cfg.TokenEncryption.Keys) == 0 && len(cfg.TokenEncryption.StoreKeys) > 0 {
+		return errors.New("'tokenEncryption.key' or 'tokenEncryption.keys' must be set when 'tokenEncryption.storeKeys' are provided")
+	}
+
+	if cfg.ContinuationTokenFormat != "versioned" && cfg.ContinuationTokenFormat != "raw" {
+		return fmt.Errorf("config 'continuationTokenFormat' must be 'versioned' or 'raw'")
+	}
+
+	if cfg.TokenSigning.Algorithm != "" {
+		if cfg.TokenSigning.TTL <= 0 {
+			return errors.New("'tokenSigning.ttl' must be greater than 0")
+		}
+
+		if strings.HasPrefix(cfg.TokenSigning.Algorithm, "HS") {
+			if cfg.TokenSigning.Key == "" {
+				return fmt.Errorf("'tokenSigning.key' must be set to sign continuation tokens with '%s'", cfg.TokenSigning.Algorithm)
+			}
+		} else if cfg.TokenSigning.PrivateKeyPath == "" {
+			return fmt.Errorf("'tokenSigning.privateKeyPath' must be set to sign continuation tokens with '%s'", cfg.TokenSigning.Algorithm)
+		}
+	}
+
+	if cfg.LoadShedding.Enabled {
+		if cfg.LoadShedding.DegradedLatencyThreshold > cfg.LoadShedding.CriticalLatencyThreshold {
+			return fmt.Errorf("config 'loadShedding.criticalLatencyThreshold' (%s) cannot be lower than 'loadShedding.degradedLatencyThreshold' config (%s)", cfg.LoadShedding.CriticalLatencyThreshold, cfg.LoadShedding.DegradedLatencyThreshold)
+		}
+
+		if cfg.LoadShedding.ErrorRateThreshold < 0 || cfg.LoadShedding.ErrorRateThreshold > 1 {
+			return errors.New("config 'loadShedding.errorRateThreshold' must be between 0 and 1")
+		}
+	}
+
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.RequestsPerSecond <= 0 || cfg.RateLimit.Burst <= 0 {
+			return errors.New("configs 'rateLimit.requestsPerSecond' and 'rateLimit.burst' must be greater than 0 when rate limiting is enabled")
+		}
+
+		if _, err := parseRateLimitMethods(cfg.RateLimit.Methods); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Scheduler.Enabled {
+		if cfg.Scheduler.MaxConcurrentBatchRequests == 0 || cfg.Scheduler.MaxConcurrentBatchRequests > cfg.Scheduler.MaxConcurrentRequests {
+			return errors.New("config 'scheduler.maxConcurrentBatchRequests' must be greater than 0 and not greater than 'scheduler.maxConcurrentRequests'")
+		}
+
+		if cfg.Scheduler.QueueTimeout < 0 {
+			return errors.New("config 'scheduler.queueTimeout' cannot be negative")
+		}
+	}
+
+	if cfg.RequestFeatureFlags.Enabled {
+		if cfg.Authn.Method == "none" {
+			return errors.New("config 'requestFeatureFlags.enabled' requires an authn method other than 'none'")
+		}
+
+		if cfg.RequestFeatureFlags.ResolveNodeLimit < cfg.ResolveNodeLimit {
+			return errors.New("config 'requestFeatureFlags.resolveNodeLimit' cannot be less than 'resolveNodeLimit'")
+		}
+	}
+
+	if cfg.Shadow.Enabled {
+		if cfg.Shadow.SampleRate <= 0 || cfg.Shadow.SampleRate > 1 {
+			return errors.New("config 'shadow.sampleRate' must be greater than 0 and at most 1")
+		}
+
+		if cfg.Shadow.Timeout <= 0 {
+			return errors.New("config 'shadow.timeout' must be greater than 0")
+		}
+
+		if cfg.Shadow.MaxConcurrentEvaluations == 0 {
+			return errors.New("config 'shadow.maxConcurrentEvaluations' must be greater than 0")
+		}
+
+		if cfg.Shadow.SlowdownThreshold < 0 {
+			return errors.New("config 'shadow.slowdownThreshold' cannot be negative")
+		}
+
+		if !cfg.Shadow.Check && cfg.Shadow.ListObjectsStrategy == "" {
+			return errors.New("config 'shadow.enabled' requires 'shadow.check' or 'shadow.listObjectsStrategy'")
+		}
+
+		if cfg.Shadow.ListObjectsStrategy != "" {
+			if _, err := planner.ParseStrategy(cfg.Shadow.ListObjectsStrategy); err != nil {
+				return fmt.Errorf("config 'shadow.listObjectsStrategy': %w", err)
+			}
+		}
+	}
+
+	if cfg.RequestTimeout.Default < 0 {
+		return errors.New("config 'requestTimeout.default' cannot be negative")
+	}
+
+	if _, err := parseRequestTimeoutMethods(cfg.RequestTimeout.Methods); err != nil {
+		return err
+	}
+
+	if cfg.Quotas.MaxWritesPerSecond < 0 {
+		return errors.New("config 'quotas.maxWritesPerSecond' cannot be negative")
+	}
+
+	if cfg.HTTP.TLS.Enabled {
+		if cfg.HTTP.TLS.CertPath == "" || cfg.HTTP.TLS.KeyPath == "" {
+			return errors.New("'http.tls.cert' and 'http.tls.key' configs must be set")
+		}
+	}
+
+	if cfg.GRPC.TLS.Enabled {
+		if cfg.GRPC.TLS.CertPath == "" || cfg.GRPC.TLS.KeyPath == "" {
+			return errors.New("'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
+		}
+	}
+
+	if cfg.HTTP.TLS.ClientCAPath != "" && !cfg.HTTP.TLS.Enabled {
+		return errors.New("'http.tls.clientCA' config requires 'http.tls.enabled'")
+	}
+
+	if cfg.GRPC.TLS.ClientCAPath != "" && !cfg.GRPC.TLS.Enabled {
+		return errors.New("'grpc.tls.clientCA' config requires 'grpc.tls.enabled'")
+	}
+
+	return nil
+}
+
+func run(_ *cobra.Command, _ []string) {
+	config, err := ReadConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	if err := RunServer(context.Background(), config); err != nil {
+		panic(err)
+	}
+}
+
+func RunServer(ctx context.Context, config *Config) error {
+	if err := VerifyConfig(config); err != nil {
+		return err
+	}
+
+	logger := logger.MustNewLogger(config.Log.Format, config.Log.Level)
+
+	tp := sdktrace.NewTracerProvider()
+	if config.Trace.Enabled {
+		logger.Info(fmt.Sprintf("🕵 tracing enabled: sampling ratio is %v and sending traces to '%s'", config.Trace.SampleRatio, config.Trace.OTLP.Endpoint))
+		tp = telemetry.MustNewTracerProvider(
+			telemetry.WithOTLPEndpoint(config.Trace.OTLP.Endpoint),
+			telemetry.WithAttributes(
+				semconv.ServiceNameKey.String(config.Trace.ServiceName),
+				semconv.ServiceVersionKey.String(build.Version),
+			),
+			telemetry.WithSamplingRatio(config.Trace.SampleRatio),
+		)
+	}
+
+	logger.Info(fmt.Sprintf("🧪 experimental features enabled: %v", config.Experimentals))
+
+	var experimentals []server.ExperimentalFeatureFlag
+	for _, feature := range config.Experimentals {
+		experimentals = append(experimentals, server.ExperimentalFeatureFlag(feature))
+	}
+
+	datastore, err := NewDatastore(config, logger)
+	if err != nil {
+		return err
+	}
+	tenants, _ := datastore.(*tenancy.Tenancy)
+	datastore = storagewrappers.NewContextWrapper(datastore)
+
+	if config.Datastore.UserEncryptionKey != "" {
+		logger.Info("🔒 the users of the tuples are encrypted in the datastore")
+
+		userEncrypter, err := encrypter.NewDeterministicEncrypter(config.Datastore.UserEncryptionKey)
+		if err != nil {
+			return fmt.Errorf("failed to initialize the tuple user encrypter: %w", err)
+		}
+		datastore = storagewrappers.NewEncryptingDatastore(datastore, userEncrypter)
+	}
+
+	var healthMonitor *loadshedding.HealthMonitor
+	if config.LoadShedding.Enabled {
+		logger.Info(fmt.Sprintf("🚦 load shedding enabled: degraded above %s, critical above %s or an error rate of %v",
+			config.LoadShedding.DegradedLatencyThreshold, config.LoadShedding.CriticalLatencyThreshold, config.LoadShedding.ErrorRateThreshold))
+		healthMonitor = loadshedding.NewHealthMonitor(
+			loadshedding.WithDegradedLatencyThreshold(config.LoadShedding.DegradedLatencyThreshold),
+			loadshedding.WithCriticalLatencyThreshold(config.LoadShedding.CriticalLatencyThreshold),
+			loadshedding.WithErrorRateThreshold(config.LoadShedding.ErrorRateThreshold),
+		)
+		datastore = storagewrappers.NewObservedOpenFGADatastore(datastore, healthMonitor)
+	}
+
+	var limiter *ratelimit.Limiter
+	if config.RateLimit.Enabled {
+		logger.Info(fmt.Sprintf("🚦 rate limiting enabled: %v requests per second and a burst of %d per store and API method",
+			config.RateLimit.RequestsPerSecond, config.RateLimit.Burst))
+
+		limiterOpts, err := rateLimiterOptions(config.RateLimit)
+		if err != nil {
+			return err
+		}
+
+		limiter = ratelimit.NewLimiter(limiterOpts...)
+	}
+
+	// the dependencies probed by the readiness checks on top of the datastore
+	var healthDependencies []health.Dependency
+
+	var cacheBackend cache.Cache
+	if config.Cache.Backend == "redis" {
+		redisCache, err := cache.NewRedisCache(ctx, &redis.Options{
+			Addr:     config.Cache.Redis.Addr,
+			Password: config.Cache.Redis.Password,
+			DB:       config.Cache.Redis.DB,
+		}, cache.WithRedisKeyPrefix(config.Cache.Redis.KeyPrefix))
+		if err != nil {
+			return fmt.Errorf("failed to initialize redis cache: %w", err)
+		}
+
+		cacheBackend = redisCache
+		healthDependencies = append(healthDependencies, health.Dependency{Name: "cache", Probe: redisCache.Ping, Optional: true})
+
+		logger.Info(fmt.Sprintf("using redis cache backend at '%s'", config.Cache.Redis.Addr))
+		datastore = storagewrappers.NewSharedCachedOpenFGADatastore(datastore, cacheBackend)
+	}
+
+	datastore = storagewrappers.NewCachedOpenFGADatastore(datastore, config.Datastore.MaxCacheSize)
+
+	logger.Info(fmt.Sprintf("using '%v' storage engine", config.Datastore.Engine))
+
+	var authenticator authn.Authenticator
+	switch config.Authn.Method {
+	case "none":
+		logger.Warn("authentication is disabled")
+		authenticator = authn.NoopAuthenticator{}
+	case "preshared":
+		logger.Info("using 'preshared' authentication")
+		var presharedOpts []presharedkey.PresharedKeyAuthenticatorOption
+		for key, scopes := range config.Authn.KeyScopes {
+			presharedOpts = append(presharedOpts, presharedkey.WithKeyScopes(key, strings.Fields(scopes)))
+		}
+
+		authenticator, err = presharedkey.NewPresharedKeyAuthenticator(config.Authn.Keys, presharedOpts...)
+	case "oidc":
+		logger.Info("using 'oidc' authentication")
+		var oidcAuthenticator *oidc.RemoteOidcAuthenticator
+		oidcAuthenticator, err = oidc.NewRemoteOidcAuthenticator(config.Authn.Issuer, config.Authn.Audience,
+			oidc.WithAdditionalIssuers(config.Authn.AdditionalIssuers...),
+			oidc.WithRequiredScopes(config.Authn.RequiredScopes...),
+			oidc.WithJWKSRefreshInterval(config.Authn.JWKSRefreshInterval),
+		)
+		if err == nil {
+			authenticator = oidcAuthenticator
+			healthDependencies = append(healthDependencies, health.Dependency{Name: "token_issuer", Probe: oidcAuthenticator.Ping, Optional: true})
+		}
+	default:
+		return fmt.Errorf("unsupported authentication method '%v'", config.Authn.Method)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize authenticator: %w", err)
+	}
+
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		errordetails.NewUnaryInterceptor(),
+		requestid.NewUnaryInterceptor(),
+		grpc_validator.UnaryServerInterceptor(),
+		grpc_ctxtags.UnaryServerInterceptor(),
+		condition.NewUnaryInterceptor(),
+		consistency.NewUnaryInterceptor(),
+	}
+
+	streamingInterceptors := []grpc.StreamServerInterceptor{
+		errordetails.NewStreamingInterceptor(),
+		requestid.NewStreamingInterceptor(),
+		grpc_validator.StreamServerInterceptor(),
+		grpc_ctxtags.StreamServerInterceptor(),
+		condition.NewStreamingInterceptor(),
+		consistency.NewStreamingInterceptor(),
+	}
+
+	if tenants != nil {
+		unaryInterceptors = append(unaryInterceptors, tenant.NewUnaryInterceptor(tenants))
+		streamingInterceptors = append(streamingInterceptors, tenant.NewStreamingInterceptor(tenants))
+	}
+
+	if config.Metrics.Enabled {
+		unaryInterceptors = append(unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
+		streamingInterceptors = append(streamingInterceptors, grpc_prometheus.StreamServerInterceptor)
+
+		if config.Metrics.EnableRPCHistograms {
+			grpc_prometheus.EnableHandlingTimeHistogram()
+		}
+
+		if config.Metrics.EnablePerStoreLabels {
+			commands.EnablePerStoreMetrics(config.Metrics.MaxStoreLabels)
+		}
+	}
+
+	if config.Profiler.AutoCPUProfile.CheckP99Threshold > 0 {
+		logger.Info(fmt.Sprintf("capturing CPU profiles to '%s' when the Check p99 latency exceeds %s",
+			config.Profiler.AutoCPUProfile.Dir, config.Profiler.AutoCPUProfile.CheckP99Threshold))
+
+		trigger := profiler.NewCPUProfileTrigger(
+			config.Profiler.AutoCPUProfile.CheckP99Threshold,
+			config.Profiler.AutoCPUProfile.Dir,
+			profiler.WithCPUProfileDuration(config.Profiler.AutoCPUProfile.Duration),
+			profiler.WithLogger(logger),
+		)
+		unaryInterceptors = append(unaryInterceptors, profiler.NewUnaryInterceptor(trigger))
+	}
+
+	if config.Trace.Enabled {
+		unaryInterceptors = append(unaryInterceptors, otelgrpc.UnaryServerInterceptor())
+		streamingInterceptors = append(streamingInterceptors, otelgrpc.StreamServerInterceptor())
+	}
+
+	unaryInterceptors = append(unaryInterceptors,
+		storeid.NewUnaryInterceptor(),
+		logging.NewLoggingInterceptor(logger),
+	)
+
+	if healthMonitor != nil {
+		unaryInterceptors = append(unaryInterceptors, loadshedding.NewUnaryInterceptor(healthMonitor))
+		streamingInterceptors = append(streamingInterceptors, loadshedding.NewStreamingInterceptor(healthMonitor))
+	}
+
+	// the secret through which the HTTP gateway vouches for the client certificate subjects it forwards
+	gatewaySecret, err := newGatewaySecret()
+	if err != nil {
+		return err
+	}
+
+	unaryInterceptors = append(unaryInterceptors,
+		clientcert.NewUnaryInterceptor(gatewaySecret),
+		grpc_auth.UnaryServerInterceptor(authnmw.AuthFunc(authenticator)),
+	)
+
+	streamingInterceptors = append(streamingInterceptors,
+		clientcert.NewStreamingInterceptor(gatewaySecret),
+		grpc_auth.StreamServerInterceptor(authnmw.AuthFunc(authenticator)),
+	)
+
+	// auditing comes before authorization, so that the denied calls are audited too
+	var auditLogger *audit.Logger
+	if config.Audit.Enabled {
+		auditLogger, err = newAuditLogger(config.Audit, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("writing the audit log to '%s'", config.Audit.Sink))
+		unaryInterceptors = append(unaryInterceptors, audit.NewUnaryInterceptor(auditLogger))
+		streamingInterceptors = append(streamingInterceptors, audit.NewStreamingInterceptor(auditLogger))
+	}
+
+	var decisionLogOutput io.WriteCloser
+	if config.DecisionLog.Enabled {
+		var emitter *decisionlog.Emitter
+		decisionLogOutput, emitter, err = newDecisionLogEmitter(config.DecisionLog)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("logging %v of the decisions to '%s'", config.DecisionLog.SampleRate, config.DecisionLog.Output))
+		unaryInterceptors = append(unaryInterceptors, decisionlog.NewUnaryInterceptor(emitter))
+		streamingInterceptors = append(streamingInterceptors, decisionlog.NewStreamingInterceptor(emitter))
+	}
+
+	if config.Authn.ScopedAccess {
+		logger.Info("restricting credentials to the stores and roles of their scopes")
+		unaryInterceptors = append(unaryInterceptors, authz.NewUnaryInterceptor())
+		streamingInterceptors = append(streamingInterceptors, authz.NewStreamingInterceptor())
+	}
+
+	// the feature flags are authorized for the authenticated principals
+	if config.RequestFeatureFlags.Enabled {
+		logger.Info(fmt.Sprintf("toggling experimental behaviors per request for the principals %v and the credentials with the '%s' scope", config.RequestFeatureFlags.Principals, featureflags.Scope))
+		unaryInterceptors = append(unaryInterceptors, featureflags.NewUnaryInterceptor(config.RequestFeatureFlags.Principals))
+		streamingInterceptors = append(streamingInterceptors, featureflags.NewStreamingInterceptor(config.RequestFeatureFlags.Principals))
+	}
+
+	// rate limiting comes after authentication, so that unauthenticated requests do not consume the budget of a store
+	if limiter != nil {
+		unaryInterceptors = append(unaryInterceptors, ratelimit.NewUnaryInterceptor(limiter))
+		streamingInterceptors = append(streamingInterceptors, ratelimit.NewStreamingInterceptor(limiter))
+	}
+
+	// the requests are scheduled once they are admitted, so that the rejected requests do not wait for a slot
+	if config.Scheduler.Enabled {
+		logger.Info(fmt.Sprintf("scheduling the requests by priority with %d slots, %d of which for the batch requests of %v",
+			config.Scheduler.MaxConcurrentRequests, config.Scheduler.MaxConcurrentBatchRequests, config.Scheduler.BatchMethods))
+
+		scheduler := priority.NewScheduler(
+			priority.WithMaxConcurrentRequests(config.Scheduler.MaxConcurrentRequests),
+			priority.WithMaxConcurrentBatchRequests(config.Scheduler.MaxConcurrentBatchRequests),
+			priority.WithQueueTimeout(config.Scheduler.QueueTimeout),
+			priority.WithBatchMethods(config.Scheduler.BatchMethods),
+		)
+		unaryInterceptors = append(unaryInterceptors, priority.NewUnaryInterceptor(scheduler))
+		streamingInterceptors = append(streamingInterceptors, priority.NewStreamingInterceptor(scheduler))
+	}
+
+	streamingInterceptors = append(streamingInterceptors,
+		// The following interceptors wrap the server stream with our own
+		// wrapper and must come last.
+		storeid.NewStreamingInterceptor(),
+		logging.NewStreamingLoggingInterceptor(logger),
+	)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamingInterceptors...),
+	}
+
+	var grpcTLS *mtls.Reloader
+	if config.GRPC.TLS.Enabled {
+		if config.GRPC.TLS.CertPath == "" || config.GRPC.TLS.KeyPath == "" {
+			return errors.New("'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
+		}
+		grpcTLS, err = mtls.NewReloader(config.GRPC.TLS.CertPath, config.GRPC.TLS.KeyPath, mtls.WithClientCAPath(config.GRPC.TLS.ClientCAPath))
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, grpc.Creds(credentials.NewTLS(grpcTLS.ServerConfig())))
+
+		logger.Info("grpc TLS is enabled, serving connections using the provided certificate")
+		if grpcTLS.MutualTLS() {
+			logger.Info("grpc mutual TLS is enabled, clients must present a certificate signed by one of the provided CAs")
+		}
+	} else {
+		logger.Warn("grpc TLS is disabled, serving connections using insecure plaintext")
+	}
+
+	if config.Metrics.Enabled {
+		logger.Info(fmt.Sprintf("📈 starting metrics server on '%s'", config.Metrics.Addr))
+
+		go func() {
+			http.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(config.Metrics.Addr, nil); err != nil {
+				if err != http.ErrServerClosed {
+					logger.Fatal("failed to start prometheus metrics server", zap.Error(err))
+				}
+			}
+		}()
+	}
+
+	var changelogSink cdc.Sink
+	var changelogDeadLetters *cdc.FileDeadLetterQueue
+	var changelogCheckpointer *cdc.FileCheckpointer
+	var publisher *cdc.Publisher
+	if config.ChangelogExport.Enabled {
+		changelogCheckpointer, err = cdc.NewFileCheckpointer(config.ChangelogExport.CheckpointFile)
+		if err != nil {
+			return fmt.Errorf("failed to initialize the changelog export checkpoints: %w", err)
+		}
+
+		switch config.ChangelogExport.Sink {
+		case "kafka":
+			changelogSink = cdc.NewKafkaSink(config.ChangelogExport.Kafka.Brokers, config.ChangelogExport.Kafka.Topic)
+		case "nats":
+			changelogSink, err = cdc.NewNATSSink(config.ChangelogExport.NATS.URL, config.ChangelogExport.NATS.Subject)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the changelog export nats sink: %w", err)
+			}
+		case "webhook":
+			changelogSink, changelogDeadLetters, err = newWebhookChangelogSink(config.ChangelogExport.Webhook, logger)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the changelog export webhooks: %w", err)
+			}
+		}
+
+		publisherOpts := []cdc.PublisherOption{
+			cdc.WithLogger(logger),
+			cdc.WithPollInterval(config.ChangelogExport.PollInterval),
+			cdc.WithPageSize(config.ChangelogExport.PageSize),
+			cdc.WithHorizonOffset(time.Duration(config.ChangelogHorizonOffset) * time.Minute),
+			cdc.WithCheckpointer(changelogCheckpointer),
+		}
+
+		publisher = cdc.NewPublisher(datastore, changelogSink, publisherOpts...)
+
+		// the changes are exported as soon as they are committed, not only on the next poll
+		datastore = storagewrappers.NewNotifyingDatastore(datastore, publisher.Notify)
+	}
+
+	serverOpts := []server.OpenFGAServiceV1Option{
+		server.WithDatastore(datastore),
+		server.WithLogger(logger),
+		server.WithTransport(gateway.NewRPCTransport(logger)),
+		server.WithResolveNodeLimit(config.ResolveNodeLimit),
+		server.WithFlaggedResolveNodeLimit(config.RequestFeatureFlags.ResolveNodeLimit),
+		server.WithResolveNodeBreadthLimit(config.ResolveNodeBreadthLimit),
+		server.WithExpandDepth(config.ExpandDepth),
+		server.WithChangelogHorizonOffset(config.ChangelogHorizonOffset),
+		server.WithListObjectsDeadline(config.ListObjectsDeadline),
+		server.WithRequestTimeout(config.RequestTimeout.Default),
+		server.WithListObjectsMaxResults(config.ListObjectsMaxResults),
+		server.WithListObjectsSortOrder(listObjectsSortOrders[config.ListObjectsSortOrder]),
+		server.WithListObjectsPartialResults(config.ListObjectsPartialResults),
+		server.WithListObjectsStreamBufferSize(config.ListObjectsStreamBufferSize),
+		server.WithListObjectsStreamSendTimeout(config.ListObjectsStreamSendTimeout),
+		server.WithMaxConcurrentReadsForListObjects(config.MaxConcurrentReadsForListObjects),
+		server.WithListObjectsMaxConcurrentChecks(config.ListObjectsMaxConcurrentChecks),
+		server.WithMaxConcurrentReadsForCheck(config.MaxConcurrentReadsForCheck),
+		server.WithMaxDispatchCountPerCheck(config.MaxDispatchCountPerCheck),
+		server.WithMaxDatastoreReadsPerCheck(config.MaxDatastoreReadsPerCheck),
+		server.WithQuotas(quota.Limits{
+			MaxTuplesPerStore:                config.Quotas.MaxTuplesPerStore,
+			MaxTypesPerAuthorizationModel:    config.Quotas.MaxTypesPerAuthorizationModel,
+			MaxRelationsPerType:              config.Quotas.MaxRelationsPerType,
+			MaxRewriteDepth:                  config.Quotas.MaxRewriteDepth,
+			MaxAuthorizationModelSizeInBytes: config.Quotas.MaxAuthorizationModelSizeInBytes,
+			MaxWritesPerSecond:               config.Quotas.MaxWritesPerSecond,
+		}),
+		server.WithExperimentals(experimentals...),
+		server.WithReadOnly(config.ReadOnly),
+		server.WithStoreRetentionPeriod(config.StoreRetention.Period),
+		server.WithCheckQueryCacheEnabled(config.CheckQueryCache.Enabled),
+		server.WithCheckQueryCacheLimit(config.CheckQueryCache.Limit),
+		server.WithCheckQueryCacheTTL(config.CheckQueryCache.TTL),
+		server.WithListObjectsCacheEnabled(config.ListObjectsCache.Enabled),
+		server.WithListObjectsCacheLimit(config.ListObjectsCache.Limit),
+		server.WithListObjectsCacheTTL(config.ListObjectsCache.TTL),
+		server.WithListObjectsCacheChangelogInterval(config.ListObjectsCache.ChangelogInterval),
+		server.WithCheckDeduplicationEnabled(config.CheckDeduplication.Enabled),
+		server.WithLatestModelCacheTTL(config.TypesystemCache.LatestModelTTL),
+		server.WithListObjectsPlannerEnabled(config.ListObjectsPlanner.Enabled),
+		server.WithListObjectsPlannerStatisticsTTL(config.ListObjectsPlanner.StatisticsTTL),
+		server.WithListObjectsPlannerSampleSize(config.ListObjectsPlanner.SampleSize),
+		server.WithStoreStatsCacheTTL(config.StoreStats.CacheTTL),
+	}
+
+	if config.ModelModules.Dir != "" {
+		modules, err := loadModelModules(ctx, config.ModelModules.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to load the authorization model modules: %w", err)
+		}
+		logger.Info(fmt.Sprintf("loaded %d authorization model modules from '%s'", len(modules), config.ModelModules.Dir))
+
+		serverOpts = append(serverOpts, server.WithModelModules(modules...))
+	}
+
+	if cacheBackend != nil {
+		serverOpts = append(serverOpts, server.WithCacheBackend(cacheBackend))
+	}
+
+	if config.ChangelogRetention.Enabled {
+		serverOpts = append(serverOpts, server.WithChangelogRetentionPeriod(config.ChangelogRetention.Period))
+	}
+
+	requestTimeouts, err := parseRequestTimeoutMethods(config.RequestTimeout.Methods)
+	if err != nil {
+		return err
+	}
+	for method, timeout := range requestTimeouts {
+		serverOpts = append(serverOpts, server.WithMethodRequestTimeout(method, timeout))
+	}
+
+	var requestRecordingOutput io.WriteCloser
+	if config.RequestRecording.Enabled {
+		var requestRecorder *recorder.Recorder
+		requestRecordingOutput, requestRecorder, err = newRequestRecorder(config.RequestRecording)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("recording %v of the requests to '%s'", config.RequestRecording.SampleRate, config.RequestRecording.Output))
+		serverOpts = append(serverOpts, server.WithRecorder(requestRecorder))
+	}
+
+	if config.Shadow.Enabled {
+		logger.Info(fmt.Sprintf("evaluating %v of the requests again in shadow mode", config.Shadow.SampleRate))
+		serverOpts = append(serverOpts, server.WithShadowEvaluator(commands.NewShadowEvaluator(
+			commands.WithShadowLogger(logger),
+			commands.WithShadowSampleRate(config.Shadow.SampleRate),
+			commands.WithShadowTimeout(config.Shadow.Timeout),
+			commands.WithShadowMaxConcurrentEvaluations(config.Shadow.MaxConcurrentEvaluations),
+			commands.WithShadowSlowdownThreshold(config.Shadow.SlowdownThreshold),
+		)))
+
+		if config.Shadow.Check {
+			serverOpts = append(serverOpts, server.WithShadowCheck())
+		}
+
+		if config.Shadow.ListObjectsStrategy != "" {
+			strategy, err := planner.ParseStrategy(config.Shadow.ListObjectsStrategy)
+			if err != nil {
+				return err
+			}
+			serverOpts = append(serverOpts, server.WithShadowListObjects(commands.WithListObjectsStrategy(strategy)))
+		}
+	}
+
+	if config.CheckQueryCache.Enabled {
+		logger.Info(fmt.Sprintf("check query cache enabled with limit %d and TTL %s", config.CheckQueryCache.Limit, config.CheckQueryCache.TTL))
+	}
+
+	if config.ListObjectsCache.Enabled {
+		logger.Info(fmt.Sprintf("ListObjects cache enabled with limit %d, TTL %s and changelog interval %s", config.ListObjectsCache.Limit, config.ListObjectsCache.TTL, config.ListObjectsCache.ChangelogInterval))
+	}
+
+	if config.WriteWebhook.URL != "" {
+		logger.Info(fmt.Sprintf("the writes are admitted by the webhook at %s", config.WriteWebhook.URL))
+		serverOpts = append(serverOpts, server.WithWriteHooks(writehook.NewWebhook(config.WriteWebhook.URL,
+			writehook.WithWebhookTimeout(config.WriteWebhook.Timeout),
+			writehook.WithWebhookFailOpen(config.WriteWebhook.FailOpen),
+		)))
+	}
+
+	if config.ReadOnly {
+		logger.Warn("🔒 read-only mode is enabled, all mutating APIs will be rejected")
+	}
+
+	if config.ContinuationTokenFormat == "raw" {
+		serverOpts = append(serverOpts, server.WithTokenCodec(encoder.NewRawTokenCodec()))
+	}
+
+	var storeEncoder encoder.StoreEncoder
+	if config.TokenEncryption.Key != "" || len(config.TokenEncryption.Keys) > 0 {
+		var storeEncrypter *encrypter.StoreEncrypter
+		if len(config.TokenEncryption.Keys) > 0 {
+			storeEncrypter, err = encrypter.NewKeyRingStoreEncrypter(config.TokenEncryption.PrimaryKeyID, config.TokenEncryption.Keys, config.TokenEncryption.StoreKeys)
+		} else {
+			storeEncrypter, err = encrypter.NewStoreEncrypter(config.TokenEncryption.Key, config.TokenEncryption.StoreKeys)
 		}
-	case "postgres":
-		datastore, err = postgres.New(config.Datastore.URI, dsCfg)
 		if err != nil {
-			return fmt.Errorf("failed to initialize postgres datastore: %w", err)
+			return fmt.Errorf("failed to initialize continuation token encryption: %w", err)
 		}
-	default:
-		return fmt.Errorf("storage engine '%s' is unsupported", config.Datastore.Engine)
+
+		logger.Info(fmt.Sprintf("🔐 continuation tokens are encrypted with per-store keys (%d master keys in the key ring, %d explicit store keys)", len(config.TokenEncryption.Keys), len(config.TokenEncryption.StoreKeys)))
+		storeEncoder = encoder.NewStoreTokenEncoder(storeEncrypter, encoder.NewBase64Encoder())
 	}
-	datastore = storagewrappers.NewCachedOpenFGADatastore(storagewrappers.NewContextWrapper(datastore), config.Datastore.MaxCacheSize)
 
-	logger.Info(fmt.Sprintf("using '%v' storage engine", config.Datastore.Engine))
+	if config.TokenSigning.Algorithm != "" {
+		key := []byte(config.TokenSigning.Key)
+		if config.TokenSigning.PrivateKeyPath != "" {
+			key, err = os.ReadFile(config.TokenSigning.PrivateKeyPath)
+			if err != nil {
+				return fmt.Errorf("failed to read the continuation token signing key: %w", err)
+			}
+		}
 
-	var authenticator authn.Authenticator
-	switch config.Authn.Method {
-	case "none":
-		logger.Warn("authentication is disabled")
-		authenticator = authn.NoopAuthenticator{}
-	case "preshared":
-		logger.Info("using 'preshared' authentication")
-		authenticator, err = presharedkey.NewPresharedKeyAuthenticator(config.Authn.Keys)
-	case "oidc":
-		logger.Info("using 'oidc' authentication")
-		authenticator, err = oidc.NewRemoteOidcAuthenticator(config.Authn.Issuer, config.Authn.Audience)
-	default:
-		return fmt.Errorf("unsupported authentication method '%v'", config.Authn.Method)
-	}
-	if err != nil {
-		return fmt.Errorf("failed to initialize authenticator: %w", err)
-	}
+		signer, err := encoder.NewJWSEncoder(encoder.NewBase64Encoder(), config.TokenSigning.Algorithm, key, config.TokenSigning.TTL)
+		if err != nil {
+			return fmt.Errorf("failed to initialize continuation token signing: %w", err)
+		}
 
-	unaryInterceptors := []grpc.UnaryServerInterceptor{
-		requestid.NewUnaryInterceptor(),
-		grpc_validator.UnaryServerInterceptor(),
-		grpc_ctxtags.UnaryServerInterceptor(),
+		logger.Info(fmt.Sprintf("🔏 continuation tokens are signed with '%s' and expire after %s", config.TokenSigning.Algorithm, config.TokenSigning.TTL))
+		serverOpts = append(serverOpts, server.WithTokenEncoder(signer))
+		storeEncoder = encoder.NewJWSStoreEncoder(signer, storeEncoder)
 	}
 
-	streamingInterceptors := []grpc.StreamServerInterceptor{
-		requestid.NewStreamingInterceptor(),
-		grpc_validator.StreamServerInterceptor(),
-		grpc_ctxtags.StreamServerInterceptor(),
+	if storeEncoder != nil {
+		serverOpts = append(serverOpts, server.WithStoreTokenEncoder(storeEncoder))
 	}
 
-	if config.Metrics.Enabled {
-		unaryInterceptors = append(unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
-		streamingInterceptors = append(streamingInterceptors, grpc_prometheus.StreamServerInterceptor)
+	svr := server.MustNewServerWithOpts(serverOpts...)
 
-		if config.Metrics.EnableRPCHistograms {
-			grpc_prometheus.EnableHandlingTimeHistogram()
-		}
-	}
+	exportCtx, cancelExport := context.WithCancel(context.Background())
+	defer cancelExport()
+	exportDone := make(chan struct{})
+	if publisher != nil {
+		go func() {
+			publisher.Run(exportCtx)
+			close(exportDone)
+		}()
 
-	if config.Trace.Enabled {
-		unaryInterceptors = append(unaryInterceptors, otelgrpc.UnaryServerInterceptor())
-		streamingInterceptors = append(streamingInterceptors, otelgrpc.StreamServerInterceptor())
+		logger.Info(fmt.Sprintf("exporting the changelog to '%s'", config.ChangelogExport.Sink))
+	} else {
+		close(exportDone)
 	}
 
-	unaryInterceptors = append(unaryInterceptors,
-		storeid.NewUnaryInterceptor(),
-		logging.NewLoggingInterceptor(logger),
-		grpc_auth.UnaryServerInterceptor(authnmw.AuthFunc(authenticator)),
-	)
+	reaperCtx, cancelReaper := context.WithCancel(context.Background())
+	defer cancelReaper()
+	reaperDone := make(chan struct{})
+	if config.TupleReaper.Enabled {
+		reaper := storage.NewTupleReaper(datastore,
+			storage.WithTupleReaperLogger(logger),
+			storage.WithTupleReaperInterval(config.TupleReaper.Interval),
+			storage.WithTupleReaperBatchSize(config.TupleReaper.BatchSize),
+		)
+		go func() {
+			reaper.Run(reaperCtx)
+			close(reaperDone)
+		}()
+	} else {
+		close(reaperDone)
+	}
 
-	streamingInterceptors = append(streamingInterceptors,
-		grpc_auth.StreamServerInterceptor(authnmw.AuthFunc(authenticator)),
-		// The following interceptors wrap the server stream with our own
-		// wrapper and must come last.
-		storeid.NewStreamingInterceptor(),
-		logging.NewStreamingLoggingInterceptor(logger),
-	)
+	purgerCtx, cancelPurger := context.WithCancel(context.Background())
+	defer cancelPurger()
+	purgerDone := make(chan struct{})
+	if config.StoreRetention.PurgeEnabled {
+		purger := storage.NewStorePurger(datastore,
+			storage.WithStorePurgerLogger(logger),
+			storage.WithStorePurgerRetentionPeriod(config.StoreRetention.Period),
+			storage.WithStorePurgerInterval(config.StoreRetention.PurgeInterval),
+			storage.WithStorePurgerBatchSize(config.StoreRetention.PurgeBatchSize),
+		)
+		go func() {
+			purger.Run(purgerCtx)
+			close(purgerDone)
+		}()
+	} else {
+		close(purgerDone)
+	}
 
-	opts := []grpc.ServerOption{
-		grpc.ChainUnaryInterceptor(unaryInterceptors...),
-		grpc.ChainStreamInterceptor(streamingInterceptors...),
+	trimmerCtx, cancelTrimmer := context.WithCancel(context.Background())
+	defer cancelTrimmer()
+	trimmerDone := make(chan struct{})
+	if config.ChangelogRetention.Enabled {
+		trimmer := storage.NewChangelogTrimmer(datastore,
+			storage.WithChangelogTrimmerLogger(logger),
+			storage.WithChangelogTrimmerRetentionPeriod(config.ChangelogRetention.Period),
+			storage.WithChangelogTrimmerInterval(config.ChangelogRetention.Interval),
+			storage.WithChangelogTrimmerBatchSize(config.ChangelogRetention.BatchSize),
+		)
+		go func() {
+			trimmer.Run(trimmerCtx)
+			close(trimmerDone)
+		}()
+	} else {
+		close(trimmerDone)
 	}
 
-	if config.GRPC.TLS.Enabled {
-		if config.GRPC.TLS.CertPath == "" || config.GRPC.TLS.KeyPath == "" {
-			return errors.New("'grpc.tls.cert' and 'grpc.tls.key' configs must be set")
-		}
-		creds, err := credentials.NewServerTLSFromFile(config.GRPC.TLS.CertPath, config.GRPC.TLS.KeyPath)
+	var adminServer *http.Server
+	if config.Admin.Enabled {
+		adminAuthenticator, err := presharedkey.NewPresharedKeyAuthenticator(config.Admin.PresharedKeys)
 		if err != nil {
 			return err
 		}
 
-		opts = append(opts, grpc.Creds(creds))
+		adminOpts := []admin.HandlerOption{admin.WithAuthenticator(adminAuthenticator)}
+		if config.Profiler.Enabled {
+			logger.Info(fmt.Sprintf("🔬 serving the pprof profiles on the admin server on '%s'", config.Admin.Addr))
 
-		logger.Info("grpc TLS is enabled, serving connections using the provided certificate")
-	} else {
-		logger.Warn("grpc TLS is disabled, serving connections using insecure plaintext")
-	}
+			adminOpts = append(adminOpts, admin.WithProfiler(profiler.NewHandler(
+				profiler.WithMaxProfileDuration(config.Profiler.MaxProfileDuration),
+			)))
+		}
 
-	if config.Profiler.Enabled {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/debug/pprof/", pprof.Index)
-		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		adminServer = &http.Server{
+			Addr:    config.Admin.Addr,
+			Handler: admin.NewHandler(svr, adminOpts...),
+		}
+
+		// the admin server is served with the TLS config of the HTTP server
+		var adminTLS *mtls.Reloader
+		if config.HTTP.TLS.Enabled {
+			adminTLS, err = mtls.NewReloader(config.HTTP.TLS.CertPath, config.HTTP.TLS.KeyPath, mtls.WithClientCAPath(config.HTTP.TLS.ClientCAPath))
+			if err != nil {
+				return err
+			}
+			adminServer.TLSConfig = adminTLS.ServerConfig()
+		} else {
+			logger.Warn("http TLS is disabled, serving the admin server using insecure plaintext")
+		}
 
 		go func() {
-			logger.Info(fmt.Sprintf("🔬 starting pprof profiler on '%s'", config.Profiler.Addr))
+			logger.Info(fmt.Sprintf("🛠 starting the admin server on '%s'", config.Admin.Addr))
 
-			if err := http.ListenAndServe(config.Profiler.Addr, mux); err != nil {
-				if err != http.ErrServerClosed {
-					logger.Fatal("failed to start pprof profiler", zap.Error(err))
-				}
+			var err error
+			if adminTLS != nil {
+				err = adminServer.ListenAndServeTLS("", "")
+			} else {
+				err = adminServer.ListenAndServe()
+			}
+			if err != http.ErrServerClosed {
+				logger.Fatal("failed to start the admin server", zap.Error(err))
 			}
 		}()
 	}
 
-	if config.Metrics.Enabled {
-		logger.Info(fmt.Sprintf("📈 starting metrics server on '%s'", config.Metrics.Addr))
+	reloaderCtx, cancelReloader := context.WithCancel(context.Background())
+	defer cancelReloader()
+	reloaderDone := make(chan struct{})
+	if config.Reload.Enabled {
+		if config.Reload.WatchInterval > 0 {
+			logger.Info(fmt.Sprintf("🔁 config reloads enabled on SIGHUP and on changes of the config file, checked every %s", config.Reload.WatchInterval))
+		} else {
+			logger.Info("🔁 config reloads enabled on SIGHUP")
+		}
 
+		reloader := &configReloader{logger: logger, server: svr, limiter: limiter, load: ReadConfig}
+
+		configFile := viper.ConfigFileUsed()
+		configModTime := fileModTime(configFile)
+
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
 		go func() {
-			http.Handle("/metrics", promhttp.Handler())
-			if err := http.ListenAndServe(config.Metrics.Addr, nil); err != nil {
-				if err != http.ErrServerClosed {
-					logger.Fatal("failed to start prometheus metrics server", zap.Error(err))
-				}
-			}
+			defer signal.Stop(sighup)
+
+			reloader.watch(reloaderCtx, sighup, configFile, configModTime, config.Reload.WatchInterval)
+			close(reloaderDone)
 		}()
+	} else {
+		close(reloaderDone)
 	}
 
-	svr := server.MustNewServerWithOpts(
-		server.WithDatastore(datastore),
-		server.WithLogger(logger),
-		server.WithTransport(gateway.NewRPCTransport(logger)),
-		server.WithResolveNodeLimit(config.ResolveNodeLimit),
-		server.WithResolveNodeBreadthLimit(config.ResolveNodeBreadthLimit),
-		server.WithChangelogHorizonOffset(config.ChangelogHorizonOffset),
-		server.WithListObjectsDeadline(config.ListObjectsDeadline),
-		server.WithListObjectsMaxResults(config.ListObjectsMaxResults),
-		server.WithMaxConcurrentReadsForListObjects(config.MaxConcurrentReadsForListObjects),
-		server.WithMaxConcurrentReadsForCheck(config.MaxConcurrentReadsForCheck),
-		server.WithExperimentals(experimentals...),
-	)
-
 	logger.Info(
 		"🚀 starting openfga service...",
 		zap.String("version", build.Version),
@@ -728,8 +2996,13 @@ func RunServer(ctx context.Context, config *Config) error {
 
 	// nosemgrep: grpc-server-insecure-connection
 	grpcServer := grpc.NewServer(opts...)
-	openfgav1.RegisterOpenFGAServiceServer(grpcServer, svr)
-	healthServer := &health.Checker{TargetService: svr, TargetServiceName: openfgav1.OpenFGAService_ServiceDesc.ServiceName}
+	server.RegisterServer(grpcServer, svr)
+	healthServer := &health.Checker{
+		TargetService:     svr,
+		TargetServiceName: openfgav1.OpenFGAService_ServiceDesc.ServiceName,
+		Dependencies:      healthDependencies,
+		ProbeTimeout:      config.Health.ProbeTimeout,
+	}
 	healthv1pb.RegisterHealthServer(grpcServer, healthServer)
 	reflection.Register(grpcServer)
 
@@ -757,12 +3030,12 @@ func RunServer(ctx context.Context, config *Config) error {
 		dialOpts := []grpc.DialOption{
 			grpc.WithBlock(),
 		}
-		if config.GRPC.TLS.Enabled {
-			creds, err := credentials.NewClientTLSFromFile(config.GRPC.TLS.CertPath, "")
+		if grpcTLS != nil {
+			tlsConfig, err := grpcTLS.ClientConfig()
 			if err != nil {
 				logger.Fatal("", zap.Error(err))
 			}
-			dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 		} else {
 			dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
@@ -779,7 +3052,10 @@ func RunServer(ctx context.Context, config *Config) error {
 		muxOpts := []runtime.ServeMuxOption{
 			runtime.WithForwardResponseOption(httpmiddleware.HTTPResponseModifier),
 			runtime.WithErrorHandler(func(c context.Context, sr *runtime.ServeMux, mm runtime.Marshaler, w http.ResponseWriter, r *http.Request, e error) {
-				intCode := serverErrors.ConvertToEncodedErrorCode(status.Convert(e))
+				st := status.Convert(e)
+				httpmiddleware.SetRetryAfterHeader(w, st)
+
+				intCode := serverErrors.ConvertToEncodedErrorCode(st)
 				httpmiddleware.CustomHTTPErrorHandler(c, w, r, serverErrors.NewEncodedError(intCode, e.Error()))
 			}),
 			runtime.WithStreamErrorHandler(func(ctx context.Context, e error) *status.Status {
@@ -787,14 +3063,41 @@ func RunServer(ctx context.Context, config *Config) error {
 				encodedErr := serverErrors.NewEncodedError(intCode, e.Error())
 				return status.Convert(encodedErr)
 			}),
-			runtime.WithHealthzEndpoint(healthv1pb.NewHealthClient(conn)),
+			runtime.WithIncomingHeaderMatcher(httpmiddleware.IncomingHeaderMatcher),
 			runtime.WithOutgoingHeaderMatcher(func(s string) (string, bool) { return s, true }),
+			runtime.WithMetadata(clientcert.GatewayMetadata(gatewaySecret)),
+			// the authorization models can be written and read in the DSL
+			runtime.WithMarshalerOption(httpmiddleware.DSLContentType, httpmiddleware.NewDSLMarshaler()),
 		}
 		mux := runtime.NewServeMux(muxOpts...)
 		if err := openfgav1.RegisterOpenFGAServiceHandler(ctx, mux, conn); err != nil {
 			return err
 		}
 
+		// the Kubernetes liveness and readiness probes
+		for path, handler := range map[string]http.HandlerFunc{
+			"/healthz": healthServer.HandleLiveness,
+			"/readyz":  healthServer.HandleReadiness,
+		} {
+			handler := handler
+			if err := mux.HandlePath(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+				handler(w, r)
+			}); err != nil {
+				return err
+			}
+		}
+
+		var httpTLS *mtls.Reloader
+		if config.HTTP.TLS.Enabled {
+			if config.HTTP.TLS.CertPath == "" || config.HTTP.TLS.KeyPath == "" {
+				logger.Fatal("'http.tls.cert' and 'http.tls.key' configs must be set")
+			}
+			httpTLS, err = mtls.NewReloader(config.HTTP.TLS.CertPath, config.HTTP.TLS.KeyPath, mtls.WithClientCAPath(config.HTTP.TLS.ClientCAPath))
+			if err != nil {
+				return err
+			}
+		}
+
 		httpServer = &http.Server{
 			Addr: config.HTTP.Addr,
 			Handler: cors.New(cors.Options{
@@ -808,11 +3111,9 @@ func RunServer(ctx context.Context, config *Config) error {
 
 		go func() {
 			var err error
-			if config.HTTP.TLS.Enabled {
-				if config.HTTP.TLS.CertPath == "" || config.HTTP.TLS.KeyPath == "" {
-					logger.Fatal("'http.tls.cert' and 'http.tls.key' configs must be set")
-				}
-				err = httpServer.ListenAndServeTLS(config.HTTP.TLS.CertPath, config.HTTP.TLS.KeyPath)
+			if httpTLS != nil {
+				httpServer.TLSConfig = httpTLS.ServerConfig()
+				err = httpServer.ListenAndServeTLS("", "")
 			} else {
 				err = httpServer.ListenAndServe()
 			}
@@ -920,6 +3221,12 @@ func RunServer(ctx context.Context, config *Config) error {
 		}
 	}
 
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			logger.Info("failed to shutdown the admin server", zap.Error(err))
+		}
+	}
+
 	if httpServer != nil {
 		if err := httpServer.Shutdown(ctx); err != nil {
 			logger.Info("failed to shutdown the http server", zap.Error(err))
@@ -930,6 +3237,54 @@ func RunServer(ctx context.Context, config *Config) error {
 
 	authenticator.Close()
 
+	cancelExport()
+	<-exportDone
+	cancelReaper()
+	<-reaperDone
+	cancelPurger()
+	<-purgerDone
+	cancelTrimmer()
+	<-trimmerDone
+	cancelReloader()
+	<-reloaderDone
+	if changelogSink != nil {
+		if err := changelogSink.Close(); err != nil {
+			logger.Info("failed to close the changelog export sink", zap.Error(err))
+		}
+	}
+
+	if changelogDeadLetters != nil {
+		if err := changelogDeadLetters.Close(); err != nil {
+			logger.Info("failed to close the changelog export dead-letter file", zap.Error(err))
+		}
+	}
+
+	if changelogCheckpointer != nil {
+		if err := changelogCheckpointer.Close(); err != nil {
+			logger.Info("failed to close the changelog export checkpoint file", zap.Error(err))
+		}
+	}
+
+	if auditLogger != nil {
+		if err := auditLogger.Close(); err != nil {
+			logger.Info("failed to close the audit log", zap.Error(err))
+		}
+	}
+
+	if decisionLogOutput != nil {
+		_ = decisionLogOutput.Close()
+	}
+
+	if requestRecordingOutput != nil {
+		_ = requestRecordingOutput.Close()
+	}
+
+	svr.Close()
+
+	if cacheBackend != nil {
+		cacheBackend.Close()
+	}
+
 	datastore.Close()
 
 	_ = tp.ForceFlush(ctx)
@@ -939,3 +3294,239 @@ func RunServer(ctx context.Context, config *Config) error {
 
 	return nil
 }
+
+// rateLimiterOptions returns the options of the rate limiter of the config.
+func rateLimiterOptions(config RateLimitConfig) ([]ratelimit.LimiterOption, error) {
+	opts := []ratelimit.LimiterOption{
+		ratelimit.WithRequestsPerSecond(config.RequestsPerSecond),
+		ratelimit.WithBurst(config.Burst),
+		ratelimit.WithMaxInFlightRequests(config.MaxInFlightRequests),
+		ratelimit.WithPerClientBuckets(config.PerClient),
+	}
+
+	methods, err := parseRateLimitMethods(config.Methods)
+	if err != nil {
+		return nil, err
+	}
+	for method, rps := range methods {
+		opts = append(opts, ratelimit.WithMethodRequestsPerSecond(method, rps))
+	}
+
+	return opts, nil
+}
+
+// parseRateLimitMethods parses the 'Method=requestsPerSecond' pairs of the 'rateLimit.methods' config.
+func parseRateLimitMethods(pairs []string) (map[string]float64, error) {
+	methods := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		method, value, ok := strings.Cut(pair, "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("config 'rateLimit.methods' entry '%s' must be a 'Method=requestsPerSecond' pair", pair)
+		}
+
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("config 'rateLimit.methods' entry '%s' must have a requests per second greater than 0", pair)
+		}
+
+		methods[method] = rps
+	}
+
+	return methods, nil
+}
+
+func parseRequestTimeoutMethods(pairs []string) (map[string]time.Duration, error) {
+	methods := make(map[string]time.Duration, len(pairs))
+	for _, pair := range pairs {
+		method, value, ok := strings.Cut(pair, "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("config 'requestTimeout.methods' entry '%s' must be a 'Method=timeout' pair", pair)
+		}
+
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("config 'requestTimeout.methods' entry '%s' must have a timeout greater than 0", pair)
+		}
+
+		methods[method] = timeout
+	}
+
+	return methods, nil
+}
+
+// loadModelModules parses the '.fga' files of the directory as authorization model modules named by the files without
+// the extension, and validates them.
+func loadModelModules(ctx context.Context, dir string) ([]*typesystem.Module, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.fga"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	modules := make([]*typesystem.Module, 0, len(paths))
+	modulesByName := make(map[string]*typesystem.Module, len(paths))
+	for _, path := range paths {
+		dsl, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		module, err := typesystem.ParseModule(strings.TrimSuffix(filepath.Base(path), ".fga"), string(dsl))
+		if err != nil {
+			return nil, err
+		}
+
+		modules = append(modules, module)
+		modulesByName[module.Name] = module
+	}
+
+	if err := typesystem.ValidateModules(ctx, modulesByName); err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+// newGatewaySecret returns a random secret, through which the HTTP gateway vouches for the client certificate
+// subjects it forwards to the grpc server.
+func newGatewaySecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate the gateway secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// newWebhookChangelogSink constructs the sink POSTing the changelog to every URL of the config, retrying
+// every URL independently. If the config has a dead-letter file, the returned dead-letter queue appending
+// to it must be closed once the sink is closed.
+func newWebhookChangelogSink(config WebhookExportConfig, logger logger.Logger) (cdc.Sink, *cdc.FileDeadLetterQueue, error) {
+	retryOpts := []cdc.RetryingSinkOption{
+		cdc.WithRetryLogger(logger),
+		cdc.WithMaxRetries(config.MaxRetries),
+		cdc.WithBackoff(config.InitialBackoff, config.MaxBackoff),
+	}
+
+	var deadLetters *cdc.FileDeadLetterQueue
+	if config.DeadLetterFile != "" {
+		var err error
+		if deadLetters, err = cdc.NewFileDeadLetterQueue(config.DeadLetterFile); err != nil {
+			return nil, nil, err
+		}
+		retryOpts = append(retryOpts, cdc.WithDeadLetterQueue(deadLetters))
+	}
+
+	urls := config.URLs
+	if config.URL != "" {
+		urls = append([]string{config.URL}, urls...)
+	}
+
+	sinks := make([]cdc.Sink, 0, len(urls))
+	for _, url := range urls {
+		sinks = append(sinks, cdc.NewRetryingSink(cdc.NewWebhookSink(url,
+			cdc.WithWebhookSecret(config.Secret),
+			cdc.WithWebhookTimeout(config.Timeout),
+		), retryOpts...))
+	}
+
+	return cdc.MultiSink(sinks...), deadLetters, nil
+}
+
+// newAuditLogger constructs the audit.Logger writing to the sink of the config.
+func newAuditLogger(config AuditConfig, logger logger.Logger) (*audit.Logger, error) {
+	var sink audit.Sink
+	var err error
+	switch config.Sink {
+	case "file":
+		sink, err = audit.NewFileSink(config.File.Path)
+	case "syslog":
+		sink, err = audit.NewSyslogSink(config.Syslog.Network, config.Syslog.Addr, config.Syslog.Tag)
+	case "http":
+		sink = audit.NewHTTPSink(config.HTTP.URL)
+	case "kafka":
+		sink = audit.NewKafkaSink(config.Kafka.Brokers, config.Kafka.Topic)
+	default:
+		err = fmt.Errorf("unsupported audit sink '%s'", config.Sink)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the audit log: %w", err)
+	}
+
+	auditLogger, err := audit.NewLogger(sink, audit.WithLogger(logger), audit.WithBufferSize(config.BufferSize))
+	if err != nil {
+		_ = sink.Close()
+		return nil, fmt.Errorf("failed to initialize the audit log: %w", err)
+	}
+
+	return auditLogger, nil
+}
+
+// newDecisionLogEmitter opens the output of the decision log of the config, and constructs the
+// decisionlog.Emitter writing to it. The output must be closed once the server stopped.
+func newDecisionLogEmitter(config DecisionLogConfig) (io.WriteCloser, *decisionlog.Emitter, error) {
+	var output io.WriteCloser
+	switch config.Output {
+	case "stdout":
+		output = nopCloser{os.Stdout}
+	case "stderr":
+		output = nopCloser{os.Stderr}
+	default:
+		file, err := os.OpenFile(config.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open the decision log: %w", err)
+		}
+		output = file
+	}
+
+	emitter, err := decisionlog.NewEmitter(output,
+		decisionlog.WithSampleRate(config.SampleRate),
+		decisionlog.WithRedactedFields(config.RedactFields...),
+	)
+	if err != nil {
+		_ = output.Close()
+		return nil, nil, fmt.Errorf("failed to initialize the decision log: %w", err)
+	}
+
+	return output, emitter, nil
+}
+
+// newRequestRecorder opens the output of the request recording of the config, and constructs the recorder.Recorder
+// writing to it. The output must be closed once the server stopped.
+func newRequestRecorder(config RequestRecordingConfig) (io.WriteCloser, *recorder.Recorder, error) {
+	var output io.WriteCloser
+	switch config.Output {
+	case "stdout":
+		output = nopCloser{os.Stdout}
+	case "stderr":
+		output = nopCloser{os.Stderr}
+	default:
+		file, err := os.OpenFile(config.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open the request recording: %w", err)
+		}
+		output = file
+	}
+
+	opts := []recorder.RecorderOption{recorder.WithSampleRate(config.SampleRate)}
+	if config.AnonymizationKey != "" {
+		opts = append(opts, recorder.WithAnonymizationKey(config.AnonymizationKey))
+	}
+
+	requestRecorder, err := recorder.NewRecorder(output, opts...)
+	if err != nil {
+		_ = output.Close()
+		return nil, nil, fmt.Errorf("failed to initialize the request recording: %w", err)
+	}
+
+	return output, requestRecorder, nil
+}
+
+// nopCloser does not close the standard outputs along with the decision log.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -24,12 +24,18 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
 	"github.com/hashicorp/go-retryablehttp"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/cmd"
 	"github.com/openfga/openfga/cmd/util"
 	"github.com/openfga/openfga/internal/mocks"
+	httpmiddleware "github.com/openfga/openfga/pkg/middleware/http"
+	"github.com/openfga/openfga/pkg/server"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/server/health"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
@@ -37,9 +43,11 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
 	grpcbackoff "google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	healthv1pb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
@@ -128,7 +136,7 @@ func genCACert(t *testing.T) (*x509.Certificate, []byte, *rsa.PrivateKey) {
 	var rootTemplate = &x509.Certificate{
 		SerialNumber:          big.NewInt(1),
 		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
 		IsCA:                  true,
 		MaxPathLen:            2,
@@ -170,6 +178,33 @@ func genServerCert(t *testing.T, caCert *x509.Certificate, caKey *rsa.PrivateKey
 	return serverCert, serverPEM, priv
 }
 
+func genClientCert(t *testing.T, caCert *x509.Certificate, caKey *rsa.PrivateKey, commonName string) tls.Certificate {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var template = &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"Starfleet"},
+		},
+	}
+
+	_, clientPEM := genCert(t, template, caCert, &priv.PublicKey, caKey)
+
+	cert, err := tls.X509KeyPair(clientPEM, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(priv),
+	}))
+	require.NoError(t, err)
+
+	return cert
+}
+
 func writeToTempFile(t *testing.T, data []byte) *os.File {
 	file, err := os.CreateTemp("", "openfga_tls_test")
 	require.NoError(t, err)
@@ -229,6 +264,202 @@ func TestVerifyConfig(t *testing.T) {
 		require.EqualError(t, err, "config 'http.upstreamTimeout' (2s) cannot be lower than 'listObjectsDeadline' config (5m0s)")
 	})
 
+	t.Run("ExpandDepth_cannot_be_greater_than_ResolveNodeLimit", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ExpandDepth = 30
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'expandDepth' (30) must be between 1 and the 'resolveNodeLimit' config (25)")
+	})
+
+	t.Run("StoreStats_CacheTTL_cannot_be_negative", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.StoreStats.CacheTTL = -time.Second
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'storeStats.cacheTTL' cannot be negative")
+	})
+
+	t.Run("ListObjectsPlanner_StatisticsTTL_must_be_positive", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ListObjectsPlanner.Enabled = true
+		cfg.ListObjectsPlanner.StatisticsTTL = 0
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'listObjectsPlanner.statisticsTTL' must be greater than 0 when the ListObjects query planner is enabled")
+	})
+
+	t.Run("ListObjectsCache_TTL_must_be_positive", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ListObjectsCache.Enabled = true
+		cfg.ListObjectsCache.TTL = 0
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'listObjectsCache.ttl' must be greater than 0 when the ListObjects cache is enabled")
+	})
+
+	t.Run("ListObjectsCache_ChangelogInterval_cannot_be_negative", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ListObjectsCache.ChangelogInterval = -time.Second
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'listObjectsCache.changelogInterval' cannot be negative")
+	})
+
+	t.Run("Admin_requires_preshared_keys", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Admin.Enabled = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'admin.presharedKeys' is required when the admin server is enabled")
+	})
+
+	t.Run("Reload_watchInterval_requires_reloads", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Reload.WatchInterval = time.Minute
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'reload.watchInterval' requires 'reload.enabled'")
+	})
+
+	t.Run("RateLimit_methods_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.RateLimit.Enabled = true
+		cfg.RateLimit.Methods = []string{"ListObjects=10", "Check"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'rateLimit.methods' entry 'Check' must be a 'Method=requestsPerSecond' pair")
+	})
+
+	t.Run("RequestFeatureFlags_require_authn", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.RequestFeatureFlags.Enabled = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'requestFeatureFlags.enabled' requires an authn method other than 'none'")
+
+		cfg.Authn.Method = "preshared"
+		cfg.Authn.AuthnPresharedKeyConfig = &AuthnPresharedKeyConfig{Keys: []string{"key"}}
+		cfg.RequestFeatureFlags.ResolveNodeLimit = cfg.ResolveNodeLimit - 1
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'requestFeatureFlags.resolveNodeLimit' cannot be less than 'resolveNodeLimit'")
+	})
+
+	t.Run("Shadow_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Shadow.Enabled = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'shadow.enabled' requires 'shadow.check' or 'shadow.listObjectsStrategy'")
+
+		cfg.Shadow.ListObjectsStrategy = "breadth_first"
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'shadow.listObjectsStrategy': unknown ListObjects strategy 'breadth_first'")
+
+		cfg.Shadow.ListObjectsStrategy = "concurrent_checks"
+		require.NoError(t, VerifyConfig(cfg))
+
+		cfg.Shadow.SampleRate = 1.5
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'shadow.sampleRate' must be greater than 0 and at most 1")
+
+		cfg.Shadow.SampleRate = 1
+		cfg.Shadow.MaxConcurrentEvaluations = 0
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'shadow.maxConcurrentEvaluations' must be greater than 0")
+	})
+
+	t.Run("Scheduler_batch_slots_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Scheduler.Enabled = true
+		cfg.Scheduler.MaxConcurrentBatchRequests = cfg.Scheduler.MaxConcurrentRequests + 1
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'scheduler.maxConcurrentBatchRequests' must be greater than 0 and not greater than 'scheduler.maxConcurrentRequests'")
+	})
+
+	t.Run("Profiler_requires_the_admin_server", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Profiler.Enabled = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'profiler.enabled' requires the admin server, see 'admin.enabled'")
+	})
+
+	t.Run("Profiler_auto_CPU_profile_requires_a_dir", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Profiler.AutoCPUProfile.CheckP99Threshold = 100 * time.Millisecond
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'profiler.autoCPUProfile.dir' is required when 'profiler.autoCPUProfile.checkP99Threshold' is set")
+	})
+
+	t.Run("Metrics_max_store_labels_must_be_positive", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Metrics.EnablePerStoreLabels = true
+		cfg.Metrics.MaxStoreLabels = 0
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'metrics.maxStoreLabels' must be greater than 0 when 'metrics.enablePerStoreLabels' is enabled")
+	})
+
+	t.Run("RequestTimeout_methods_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.RequestTimeout.Methods = []string{"Check=500ms", "Write"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'requestTimeout.methods' entry 'Write' must be a 'Method=timeout' pair")
+
+		cfg.RequestTimeout.Methods = []string{"Check=0s"}
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'requestTimeout.methods' entry 'Check=0s' must have a timeout greater than 0")
+	})
+
+	t.Run("Datastore_shards_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Datastore.Shards = []string{"a=postgres://a", "b=postgres://b"}
+		cfg.Datastore.ShardPins = []string{"store=c"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "invalid config 'datastore.shards': the store 'store' is pinned to the unknown shard 'c'")
+
+		cfg.Datastore.ShardPins = nil
+		cfg.Datastore.DrainingShards = []string{"a", "b"}
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "invalid config 'datastore.shards': a sharded datastore needs at least one shard that is not draining")
+
+		cfg.Datastore.DrainingShards = []string{"a"}
+		require.NoError(t, VerifyConfig(cfg))
+	})
+
+	t.Run("Datastore_tenants_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Datastore.Tenants = []string{"acme=postgres://acme", "globex"}
+
+		err := VerifyConfig(cfg)
+		require.ErrorContains(t, err, "datastore.tenants")
+
+		cfg.Datastore.Tenants = []string{"acme=postgres://acme", "globex=postgres://globex"}
+		cfg.Datastore.TenantRoutes = []string{"store=initech"}
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "invalid config 'datastore.tenants': the store 'store' is routed to the unknown tenant 'initech'")
+
+		cfg.Datastore.TenantRoutes = []string{"store=acme"}
+		require.NoError(t, VerifyConfig(cfg))
+	})
+
+	t.Run("ListObjectsSortOrder_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ListObjectsSortOrder = "descending"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'listObjectsSortOrder' must be one of ['unsorted', 'objectId']")
+	})
+
 	t.Run("failing_to_set_http_cert_path_will_not_allow_server_to_start", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.HTTP.TLS = &TLSConfig{
@@ -288,6 +519,178 @@ func TestVerifyConfig(t *testing.T) {
 		err := VerifyConfig(cfg)
 		require.Error(t, err)
 	})
+
+	t.Run("client_CA_requires_TLS", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.GRPC.TLS.ClientCAPath = "some/path"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'grpc.tls.clientCA' config requires 'grpc.tls.enabled'")
+	})
+
+	t.Run("token_encryption_store_keys_require_a_master_key", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.TokenEncryption.StoreKeys = map[string]string{"01H8Y1HVCB2E4J6Y0E2VD3W3QA": "key"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'tokenEncryption.key' or 'tokenEncryption.keys' must be set when 'tokenEncryption.storeKeys' are provided")
+	})
+
+	t.Run("token_encryption_primary_key_must_be_in_the_key_ring", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.TokenEncryption.Keys = map[string]string{"v1": "key1"}
+		cfg.TokenEncryption.PrimaryKeyID = "v2"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'tokenEncryption.primaryKeyID' must be the ID of one of the 'tokenEncryption.keys'")
+
+		cfg.TokenEncryption.PrimaryKeyID = "v1"
+		cfg.TokenEncryption.Key = "key"
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "only one of 'tokenEncryption.key' and 'tokenEncryption.keys' can be set")
+	})
+
+	t.Run("continuation_token_format_must_be_known", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ContinuationTokenFormat = "json"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'continuationTokenFormat' must be 'versioned' or 'raw'")
+	})
+
+	t.Run("token_signing_requires_a_key", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.TokenSigning.Algorithm = "HS256"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'tokenSigning.key' must be set to sign continuation tokens with 'HS256'")
+
+		cfg.TokenSigning.Algorithm = "ES256"
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "'tokenSigning.privateKeyPath' must be set to sign continuation tokens with 'ES256'")
+	})
+
+	t.Run("audit_file_sink_requires_a_path", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Audit.Enabled = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'audit.file.path' must be set to write the audit log to a file")
+	})
+
+	t.Run("audit_sink_must_be_known", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Audit.Enabled = true
+		cfg.Audit.Sink = "stdout"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'audit.sink' must be one of ['file', 'syslog', 'http', 'kafka']")
+	})
+
+	t.Run("decision_log_sample_rate_must_be_a_probability", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.DecisionLog.Enabled = true
+		cfg.DecisionLog.SampleRate = 2
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'decisionLog.sampleRate' must be between 0 and 1")
+	})
+
+	t.Run("write_webhook_timeout_must_be_positive", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.WriteWebhook.URL = "http://localhost:9000/admit"
+		cfg.WriteWebhook.Timeout = 0
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'writeWebhook.timeout' must be greater than 0")
+	})
+
+	t.Run("changelog_export_webhook_backoff_must_be_bounded", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ChangelogExport.Enabled = true
+		cfg.ChangelogExport.Sink = "webhook"
+		cfg.ChangelogExport.Webhook.URLs = []string{"http://localhost:9000/changes"}
+		cfg.ChangelogExport.Webhook.InitialBackoff = time.Minute
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'changelogExport.webhook.initialBackoff' must be greater than 0 and at most 'changelogExport.webhook.maxBackoff'")
+	})
+
+	t.Run("changelog_export_nats_requires_a_subject", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ChangelogExport.Enabled = true
+		cfg.ChangelogExport.Sink = "nats"
+		cfg.ChangelogExport.NATS.URL = "nats://localhost:4222"
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "'changelogExport.nats.url' and 'changelogExport.nats.subject' must be set to export the changelog to nats")
+	})
+
+	t.Run("changelog_export_requires_a_checkpoint_file", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ChangelogExport.Enabled = true
+		cfg.ChangelogExport.Sink = "webhook"
+		cfg.ChangelogExport.Webhook.URLs = []string{"http://localhost:9000/changes"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'changelogExport.checkpointFile' must be set to export the changelog, so that the changes are not exported again when the server restarts")
+	})
+
+	t.Run("typesystem_cache_latest_model_ttl_cannot_be_negative", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.TypesystemCache.LatestModelTTL = -time.Second
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'typesystemCache.latestModelTTL' cannot be negative")
+	})
+
+	t.Run("decision_log_redact_fields_must_be_known", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.DecisionLog.Enabled = true
+		cfg.DecisionLog.RedactFields = []string{"store_id"}
+
+		err := VerifyConfig(cfg)
+		require.ErrorContains(t, err, "config 'decisionLog.redactFields': field 'store_id' cannot be redacted")
+	})
+
+	t.Run("request_recording_sample_rate_must_be_a_probability", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.RequestRecording.Enabled = true
+		cfg.RequestRecording.SampleRate = -0.5
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'requestRecording.sampleRate' must be between 0 and 1")
+	})
+
+	t.Run("scoped_access_requires_authentication", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Authn.ScopedAccess = true
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'authn.scopedAccess' requires an authn method other than 'none'")
+	})
+
+	t.Run("preshared_key_scopes_must_be_valid", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Authn.Method = "preshared"
+		cfg.Authn.Keys = []string{"KEYONE"}
+		cfg.Authn.KeyScopes = map[string]string{"KEYTWO": "fga:read"}
+
+		err := VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'authn.preshared.keyScopes' must only set the scopes of keys in 'authn.preshared.keys'")
+
+		cfg.Authn.KeyScopes = map[string]string{"KEYONE": "fga:read fga:owner:01H8Y1HVCB2E4J6Y0E2VD3W3QA"}
+
+		err = VerifyConfig(cfg)
+		require.EqualError(t, err, "config 'authn.preshared.keyScopes': invalid scope 'fga:owner:01H8Y1HVCB2E4J6Y0E2VD3W3QA': unknown role 'owner', must be one of 'read', 'write' or 'admin'")
+
+		cfg.Authn.KeyScopes = map[string]string{"KEYONE": "fga:read fga:feature-flags"}
+
+		err = VerifyConfig(cfg)
+		require.NoError(t, err)
+	})
 }
 
 func TestBuildServiceWithPresharedKeyAuthenticationFailsIfZeroKeys(t *testing.T) {
@@ -323,6 +726,70 @@ func TestBuildServiceWithNoAuth(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestBuildServiceWithStreamedRead(t *testing.T) {
+	cfg := MustDefaultConfigWithRandomPorts()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := RunServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
+
+	conn, err := grpc.Dial(cfg.GRPC.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := openfgav1.NewOpenFGAServiceClient(conn)
+
+	store, err := client.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "store"})
+	require.NoError(t, err)
+
+	_, err = client.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       store.GetId(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	_, err = client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: store.GetId(),
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	stream, err := conn.NewStream(ctx, &server.ServiceDesc.Streams[len(server.ServiceDesc.Streams)-1], "/openfga.v1.OpenFGAService/StreamedRead")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(&openfgav1.ReadRequest{StoreId: store.GetId()}))
+	require.NoError(t, stream.CloseSend())
+
+	var read []string
+	for {
+		var received openfgav1.Tuple
+		err := stream.RecvMsg(&received)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		read = append(read, tuple.TupleKeyToString(received.GetKey()))
+	}
+
+	require.ElementsMatch(t, []string{"document:1#viewer@user:jon", "document:2#viewer@user:jon"}, read)
+}
+
 func TestBuildServiceWithPresharedKeyAuthentication(t *testing.T) {
 	cfg := MustDefaultConfigWithRandomPorts()
 	cfg.Authn.Method = "preshared"
@@ -510,14 +977,18 @@ func tryGetStores(t *testing.T, test authTest, httpAddr string, retryClient *ret
 	}
 }
 
-func TestHTTPServerWithCORS(t *testing.T) {
+func TestBuildServiceWithScopedPresharedKeys(t *testing.T) {
 	cfg := MustDefaultConfigWithRandomPorts()
 	cfg.Authn.Method = "preshared"
+	cfg.Authn.ScopedAccess = true
 	cfg.Authn.AuthnPresharedKeyConfig = &AuthnPresharedKeyConfig{
-		Keys: []string{"KEYONE", "KEYTWO"},
+		Keys: []string{"ADMINKEY", "READKEY", "STOREKEY", "UNSCOPEDKEY"},
+		KeyScopes: map[string]string{
+			"ADMINKEY": "fga:admin",
+			"READKEY":  "openid fga:read",
+			"STOREKEY": "fga:write:01H8Y1HVCB2E4J6Y0E2VD3W3QA",
+		},
 	}
-	cfg.HTTP.CORSAllowedOrigins = []string{"http://openfga.dev", "http://localhost"}
-	cfg.HTTP.CORSAllowedHeaders = []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", "X-Custom-Header"}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -530,9 +1001,63 @@ func TestHTTPServerWithCORS(t *testing.T) {
 
 	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
 
-	type args struct {
-		origin string
-		header string
+	tests := []authTest{{
+		_name:              "admin_key_succeeds",
+		authHeader:         "Bearer ADMINKEY",
+		expectedStatusCode: 200,
+	}, {
+		_name:              "read_key_on_every_store_succeeds",
+		authHeader:         "Bearer READKEY",
+		expectedStatusCode: 200,
+	}, {
+		_name:      "key_scoped_to_a_store_fails",
+		authHeader: "Bearer STOREKEY",
+		expectedErrorResponse: &serverErrors.ErrorResponse{
+			Code:    "permission_denied",
+			Message: "the 'read' role on every store is required to call ListStores",
+		},
+		expectedStatusCode: 403,
+	}, {
+		_name:      "unscoped_key_fails",
+		authHeader: "Bearer UNSCOPEDKEY",
+		expectedErrorResponse: &serverErrors.ErrorResponse{
+			Code:    "permission_denied",
+			Message: "the 'read' role on every store is required to call ListStores",
+		},
+		expectedStatusCode: 403,
+	}}
+
+	retryClient := retryablehttp.NewClient()
+	for _, test := range tests {
+		t.Run(test._name, func(t *testing.T) {
+			tryGetStores(t, test, cfg.HTTP.Addr, retryClient)
+		})
+	}
+}
+
+func TestHTTPServerWithCORS(t *testing.T) {
+	cfg := MustDefaultConfigWithRandomPorts()
+	cfg.Authn.Method = "preshared"
+	cfg.Authn.AuthnPresharedKeyConfig = &AuthnPresharedKeyConfig{
+		Keys: []string{"KEYONE", "KEYTWO"},
+	}
+	cfg.HTTP.CORSAllowedOrigins = []string{"http://openfga.dev", "http://localhost"}
+	cfg.HTTP.CORSAllowedHeaders = []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", "X-Custom-Header"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := RunServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
+
+	type args struct {
+		origin string
+		header string
 	}
 	type want struct {
 		origin string
@@ -611,21 +1136,45 @@ func TestBuildServerWithOIDCAuthentication(t *testing.T) {
 	oidcServerPort, oidcServerPortReleaser := TCPRandomPort()
 	localOIDCServerURL := fmt.Sprintf("http://localhost:%d", oidcServerPort)
 
+	otherOIDCServerPort, otherOIDCServerPortReleaser := TCPRandomPort()
+	otherOIDCServerURL := fmt.Sprintf("http://localhost:%d", otherOIDCServerPort)
+
+	untrustedOIDCServerPort, untrustedOIDCServerPortReleaser := TCPRandomPort()
+	untrustedOIDCServerURL := fmt.Sprintf("http://localhost:%d", untrustedOIDCServerPort)
+
 	cfg := MustDefaultConfigWithRandomPorts()
 	cfg.Authn.Method = "oidc"
 	cfg.Authn.AuthnOIDCConfig = &AuthnOIDCConfig{
-		Audience: "openfga.dev",
-		Issuer:   localOIDCServerURL,
+		Audience:          "openfga.dev",
+		Issuer:            localOIDCServerURL,
+		AdditionalIssuers: []string{otherOIDCServerURL},
 	}
 
 	oidcServerPortReleaser()
+	otherOIDCServerPortReleaser()
+	untrustedOIDCServerPortReleaser()
 
 	trustedIssuerServer, err := mocks.NewMockOidcServer(localOIDCServerURL)
 	require.NoError(t, err)
 
+	otherTrustedIssuerServer, err := mocks.NewMockOidcServer(otherOIDCServerURL)
+	require.NoError(t, err)
+
+	untrustedIssuerServer, err := mocks.NewMockOidcServer(untrustedOIDCServerURL)
+	require.NoError(t, err)
+
 	trustedToken, err := trustedIssuerServer.GetToken("openfga.dev", "some-user")
 	require.NoError(t, err)
 
+	otherTrustedToken, err := otherTrustedIssuerServer.GetToken("openfga.dev", "some-user")
+	require.NoError(t, err)
+
+	untrustedToken, err := untrustedIssuerServer.GetToken("openfga.dev", "some-user")
+	require.NoError(t, err)
+
+	wrongAudienceToken, err := trustedIssuerServer.GetToken("other.dev", "some-user")
+	require.NoError(t, err)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -656,11 +1205,34 @@ func TestBuildServerWithOIDCAuthentication(t *testing.T) {
 			},
 			expectedStatusCode: 401,
 		},
+		{
+			_name:      "Token_of_an_untrusted_issuer_fails",
+			authHeader: "Bearer " + untrustedToken,
+			expectedErrorResponse: &serverErrors.ErrorResponse{
+				Code:    "auth_failed_invalid_issuer",
+				Message: "invalid issuer",
+			},
+			expectedStatusCode: 401,
+		},
+		{
+			_name:      "Token_with_wrong_audience_fails",
+			authHeader: "Bearer " + wrongAudienceToken,
+			expectedErrorResponse: &serverErrors.ErrorResponse{
+				Code:    "auth_failed_invalid_audience",
+				Message: "invalid audience",
+			},
+			expectedStatusCode: 401,
+		},
 		{
 			_name:              "Correct_token_succeeds",
 			authHeader:         "Bearer " + trustedToken,
 			expectedStatusCode: 200,
 		},
+		{
+			_name:              "Token_of_an_additional_issuer_succeeds",
+			authHeader:         "Bearer " + otherTrustedToken,
+			expectedStatusCode: 200,
+		},
 	}
 
 	retryClient := retryablehttp.NewClient()
@@ -675,6 +1247,65 @@ func TestBuildServerWithOIDCAuthentication(t *testing.T) {
 	}
 }
 
+func TestBuildServerWithOIDCRequiredScopes(t *testing.T) {
+	oidcServerPort, oidcServerPortReleaser := TCPRandomPort()
+	localOIDCServerURL := fmt.Sprintf("http://localhost:%d", oidcServerPort)
+
+	cfg := MustDefaultConfigWithRandomPorts()
+	cfg.Authn.Method = "oidc"
+	cfg.Authn.AuthnOIDCConfig = &AuthnOIDCConfig{
+		Audience:       "openfga.dev",
+		Issuer:         localOIDCServerURL,
+		RequiredScopes: []string{"openfga"},
+	}
+
+	oidcServerPortReleaser()
+
+	trustedIssuerServer, err := mocks.NewMockOidcServer(localOIDCServerURL)
+	require.NoError(t, err)
+
+	tokenWithScope, err := trustedIssuerServer.GetTokenWithScope("openfga.dev", "some-client", "profile openfga")
+	require.NoError(t, err)
+
+	tokenWithoutScope, err := trustedIssuerServer.GetTokenWithScope("openfga.dev", "some-client", "profile")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := RunServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
+
+	tests := []authTest{
+		{
+			_name:      "Token_without_the_required_scope_fails",
+			authHeader: "Bearer " + tokenWithoutScope,
+			expectedErrorResponse: &serverErrors.ErrorResponse{
+				Code:    "invalid_claims",
+				Message: "missing required scope",
+			},
+			expectedStatusCode: 401,
+		},
+		{
+			_name:              "Token_with_the_required_scope_succeeds",
+			authHeader:         "Bearer " + tokenWithScope,
+			expectedStatusCode: 200,
+		},
+	}
+
+	retryClient := retryablehttp.NewClient()
+	for _, test := range tests {
+		t.Run(test._name, func(t *testing.T) {
+			tryGetStores(t, test, cfg.HTTP.Addr, retryClient)
+		})
+	}
+}
+
 func TestHTTPServingTLS(t *testing.T) {
 	t.Run("enable_HTTP_TLS_is_false,_even_with_keys_set,_will_serve_plaintext", func(t *testing.T) {
 		certsAndKeys := createCertsAndKeys(t)
@@ -732,6 +1363,52 @@ func TestHTTPServingTLS(t *testing.T) {
 		_, err := client.Get(fmt.Sprintf("https://%s/healthz", cfg.HTTP.Addr))
 		require.NoError(t, err)
 	})
+
+	t.Run("enable_HTTP_TLS_is_true_will_serve_the_admin_server_with_TLS", func(t *testing.T) {
+		certsAndKeys := createCertsAndKeys(t)
+		defer certsAndKeys.Clean()
+
+		adminPort, adminPortReleaser := TCPRandomPort()
+		adminPortReleaser()
+
+		cfg := MustDefaultConfigWithRandomPorts()
+		cfg.HTTP.TLS = &TLSConfig{
+			Enabled:  true,
+			CertPath: certsAndKeys.serverCertFile,
+			KeyPath:  certsAndKeys.serverKeyFile,
+		}
+		cfg.HTTP.Addr = strings.ReplaceAll(cfg.HTTP.Addr, "0.0.0.0", "localhost")
+		cfg.Admin.Enabled = true
+		cfg.Admin.Addr = fmt.Sprintf("localhost:%d", adminPort)
+		cfg.Admin.PresharedKeys = []string{"admin-key"}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			if err := RunServer(ctx, cfg); err != nil {
+				log.Fatal(err)
+			}
+		}()
+
+		certPool := x509.NewCertPool()
+		certPool.AddCert(certsAndKeys.caCert)
+		client := retryablehttp.NewClient()
+		client.HTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs: certPool,
+			},
+		}
+
+		req, err := retryablehttp.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/admin/experimentals", cfg.Admin.Addr), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin-key")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
 }
 
 func TestGRPCServingTLS(t *testing.T) {
@@ -787,6 +1464,60 @@ func TestGRPCServingTLS(t *testing.T) {
 
 		ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, creds, false)
 	})
+
+	t.Run("grpc_mutual_TLS_requires_a_client_certificate", func(t *testing.T) {
+		caCert, caPEM, caKey := genCACert(t)
+		_, serverPEM, serverKey := genServerCert(t, caCert, caKey)
+		serverCertFile := writeToTempFile(t, serverPEM)
+		serverKeyFile := writeToTempFile(t, pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(serverKey),
+		}))
+		clientCAFile := writeToTempFile(t, caPEM)
+		defer func() {
+			os.Remove(serverCertFile.Name())
+			os.Remove(serverKeyFile.Name())
+			os.Remove(clientCAFile.Name())
+		}()
+
+		cfg := MustDefaultConfigWithRandomPorts()
+		cfg.HTTP.Enabled = false
+		cfg.GRPC.TLS = &TLSConfig{
+			Enabled:      true,
+			CertPath:     serverCertFile.Name(),
+			KeyPath:      serverKeyFile.Name(),
+			ClientCAPath: clientCAFile.Name(),
+		}
+		// Port for TLS cannot be 0.0.0.0
+		cfg.GRPC.Addr = strings.ReplaceAll(cfg.GRPC.Addr, "0.0.0.0", "localhost")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			if err := RunServer(ctx, cfg); err != nil {
+				log.Fatal(err)
+			}
+		}()
+
+		certPool := x509.NewCertPool()
+		certPool.AddCert(caCert)
+
+		creds := credentials.NewTLS(&tls.Config{
+			RootCAs:      certPool,
+			Certificates: []tls.Certificate{genClientCert(t, caCert, caKey, "some-client")},
+		})
+
+		ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, creds, false)
+
+		conn, err := grpc.Dial(cfg.GRPC.Addr, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(certPool, "")))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = healthv1pb.NewHealthClient(conn).Check(context.Background(), &healthv1pb.HealthCheckRequest{})
+		require.Error(t, err)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+	})
 }
 
 func TestHTTPServerDisabled(t *testing.T) {
@@ -820,6 +1551,99 @@ func TestHTTPServerEnabled(t *testing.T) {
 	}()
 
 	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
+
+	resp, err := retryablehttp.Get(fmt.Sprintf("http://%s/readyz", cfg.HTTP.Addr))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var report health.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	require.True(t, report.Ready)
+	require.Equal(t, health.DatastoreDependency, report.Dependencies[0].Name)
+}
+
+func TestHTTPServerAuthorizationModelDSL(t *testing.T) {
+	cfg := MustDefaultConfigWithRandomPorts()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := RunServer(ctx, cfg); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	ensureServiceUp(t, cfg.GRPC.Addr, cfg.HTTP.Addr, nil, true)
+
+	resp, err := retryablehttp.Post(fmt.Sprintf("http://%s/stores", cfg.HTTP.Addr), "application/json", strings.NewReader(`{"name": "dsl"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var store openfgav1.CreateStoreResponse
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, protojson.Unmarshal(body, &store))
+
+	dsl := `type user
+
+type document
+  relations
+    define owner: [user] as self
+    define viewer: [user, user:*] as self or owner
+`
+	modelsURL := fmt.Sprintf("http://%s/stores/%s/authorization-models", cfg.HTTP.Addr, store.GetId())
+
+	resp, err = retryablehttp.Post(modelsURL, httpmiddleware.DSLContentType, strings.NewReader(dsl))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var written openfgav1.WriteAuthorizationModelResponse
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, protojson.Unmarshal(body, &written))
+
+	req, err := retryablehttp.NewRequest(http.MethodGet, modelsURL+"/"+written.GetAuthorizationModelId(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", httpmiddleware.DSLContentType)
+
+	resp, err = retryablehttp.NewClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, httpmiddleware.DSLContentType, resp.Header.Get("Content-Type"))
+
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, dsl, string(body))
+
+	// the DSL is parsed by the server
+	resp, err = retryablehttp.Post(modelsURL, httpmiddleware.DSLContentType, strings.NewReader("type document relations define"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestLoadModelModules(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "users.fga"), []byte("type user\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rbac-core.fga"), []byte("include users\n\ntype role\n  relations\n    define assignee: [user] as self\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a module"), 0o600))
+
+	modules, err := loadModelModules(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+	require.Equal(t, "rbac-core", modules[0].Name)
+	require.Equal(t, []string{"users"}, modules[0].Includes)
+	require.Equal(t, "users", modules[1].Name)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "groups.fga"), []byte("include billing\n"), 0o600))
+	_, err = loadModelModules(ctx, dir)
+	require.ErrorIs(t, err, typesystem.ErrInvalidModules)
 }
 
 func TestDefaultConfig(t *testing.T) {
@@ -878,14 +1702,34 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.Equal(t, val.Bool(), cfg.Profiler.Enabled)
 
-	val = res.Get("properties.profiler.properties.addr.default")
+	val = res.Get("properties.profiler.properties.maxProfileDuration.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Profiler.MaxProfileDuration.String())
+
+	val = res.Get("properties.profiler.properties.autoCPUProfile.properties.checkP99Threshold.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Profiler.AutoCPUProfile.CheckP99Threshold.String())
+
+	val = res.Get("properties.profiler.properties.autoCPUProfile.properties.duration.default")
 	require.True(t, val.Exists())
-	require.Equal(t, val.String(), cfg.Profiler.Addr)
+	require.Equal(t, val.String(), cfg.Profiler.AutoCPUProfile.Duration.String())
+
+	val = res.Get("properties.profiler.properties.autoCPUProfile.properties.dir.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Profiler.AutoCPUProfile.Dir)
 
 	val = res.Get("properties.authn.properties.method.default")
 	require.True(t, val.Exists())
 	require.Equal(t, val.String(), cfg.Authn.Method)
 
+	val = res.Get("properties.authn.properties.scopedAccess.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Authn.ScopedAccess)
+
+	val = res.Get("definitions.oidc.properties.jwksRefreshInterval.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Authn.JWKSRefreshInterval.String())
+
 	val = res.Get("properties.log.properties.format.default")
 	require.True(t, val.Exists())
 	require.Equal(t, val.String(), cfg.Log.Format)
@@ -902,10 +1746,22 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.MaxConcurrentReadsForListObjects)
 
+	val = res.Get("properties.listObjectsMaxConcurrentChecks.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsMaxConcurrentChecks)
+
 	val = res.Get("properties.maxConcurrentReadsForCheck.default")
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.MaxConcurrentReadsForCheck)
 
+	val = res.Get("properties.maxDispatchCountPerCheck.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.MaxDispatchCountPerCheck)
+
+	val = res.Get("properties.maxDatastoreReadsPerCheck.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.MaxDatastoreReadsPerCheck)
+
 	val = res.Get("properties.changelogHorizonOffset.default")
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.ChangelogHorizonOffset)
@@ -918,6 +1774,134 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.ResolveNodeLimit)
 
+	val = res.Get("properties.expandDepth.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ExpandDepth)
+
+	val = res.Get("properties.listObjectsPlanner.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.ListObjectsPlanner.Enabled)
+
+	val = res.Get("properties.listObjectsPlanner.properties.statisticsTTL.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsPlanner.StatisticsTTL.String())
+
+	val = res.Get("properties.listObjectsPlanner.properties.sampleSize.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsPlanner.SampleSize)
+
+	val = res.Get("properties.listObjectsCache.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.ListObjectsCache.Enabled)
+
+	val = res.Get("properties.listObjectsCache.properties.limit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsCache.Limit)
+
+	val = res.Get("properties.listObjectsCache.properties.ttl.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsCache.TTL.String())
+
+	val = res.Get("properties.listObjectsCache.properties.changelogInterval.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsCache.ChangelogInterval.String())
+
+	val = res.Get("properties.storeStats.properties.cacheTTL.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.StoreStats.CacheTTL.String())
+
+	val = res.Get("properties.modelModules.properties.dir.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ModelModules.Dir)
+
+	val = res.Get("properties.rateLimit.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.RateLimit.Enabled)
+
+	val = res.Get("properties.rateLimit.properties.requestsPerSecond.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Float(), cfg.RateLimit.RequestsPerSecond)
+
+	val = res.Get("properties.rateLimit.properties.burst.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.RateLimit.Burst)
+
+	val = res.Get("properties.rateLimit.properties.perClient.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.RateLimit.PerClient)
+
+	val = res.Get("properties.audit.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Audit.Enabled)
+
+	val = res.Get("properties.audit.properties.sink.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Audit.Sink)
+
+	val = res.Get("properties.audit.properties.bufferSize.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Audit.BufferSize)
+
+	val = res.Get("properties.audit.properties.syslog.properties.tag.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Audit.Syslog.Tag)
+
+	val = res.Get("properties.decisionLog.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.DecisionLog.Enabled)
+
+	val = res.Get("properties.decisionLog.properties.output.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.DecisionLog.Output)
+
+	val = res.Get("properties.decisionLog.properties.sampleRate.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Float(), cfg.DecisionLog.SampleRate)
+
+	val = res.Get("properties.requestRecording.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.RequestRecording.Enabled)
+
+	val = res.Get("properties.requestRecording.properties.output.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.RequestRecording.Output)
+
+	val = res.Get("properties.requestRecording.properties.sampleRate.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Float(), cfg.RequestRecording.SampleRate)
+
+	val = res.Get("properties.rateLimit.properties.maxInFlightRequests.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.RateLimit.MaxInFlightRequests)
+
+	val = res.Get("properties.quotas.properties.maxTuplesPerStore.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Quotas.MaxTuplesPerStore)
+
+	val = res.Get("properties.quotas.properties.maxTypesPerAuthorizationModel.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Quotas.MaxTypesPerAuthorizationModel)
+
+	val = res.Get("properties.quotas.properties.maxRelationsPerType.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Quotas.MaxRelationsPerType)
+
+	val = res.Get("properties.quotas.properties.maxRewriteDepth.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Quotas.MaxRewriteDepth)
+
+	val = res.Get("properties.quotas.properties.maxAuthorizationModelSizeInBytes.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Quotas.MaxAuthorizationModelSizeInBytes)
+
+	val = res.Get("properties.quotas.properties.maxWritesPerSecond.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Float(), cfg.Quotas.MaxWritesPerSecond)
+
+	val = res.Get("properties.readOnly.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.ReadOnly)
+
 	val = res.Get("properties.grpc.properties.tls.properties.enabled.default")
 	require.True(t, val.Exists())
 	require.Equal(t, val.Bool(), cfg.GRPC.TLS.Enabled)
@@ -926,6 +1910,62 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.Equal(t, val.Bool(), cfg.HTTP.TLS.Enabled)
 
+	val = res.Get("properties.scheduler.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Scheduler.Enabled)
+
+	val = res.Get("properties.scheduler.properties.maxConcurrentRequests.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Scheduler.MaxConcurrentRequests)
+
+	val = res.Get("properties.scheduler.properties.maxConcurrentBatchRequests.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Scheduler.MaxConcurrentBatchRequests)
+
+	val = res.Get("properties.scheduler.properties.queueTimeout.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Scheduler.QueueTimeout.String())
+
+	val = res.Get("properties.requestFeatureFlags.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.RequestFeatureFlags.Enabled)
+
+	val = res.Get("properties.requestFeatureFlags.properties.resolveNodeLimit.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.RequestFeatureFlags.ResolveNodeLimit)
+
+	val = res.Get("properties.shadow.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Shadow.Enabled)
+
+	val = res.Get("properties.shadow.properties.sampleRate.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Float(), cfg.Shadow.SampleRate)
+
+	val = res.Get("properties.shadow.properties.timeout.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Shadow.Timeout.String())
+
+	val = res.Get("properties.shadow.properties.maxConcurrentEvaluations.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Shadow.MaxConcurrentEvaluations)
+
+	val = res.Get("properties.shadow.properties.slowdownThreshold.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Float(), cfg.Shadow.SlowdownThreshold)
+
+	val = res.Get("properties.shadow.properties.check.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Shadow.Check)
+
+	val = res.Get("properties.shadow.properties.listObjectsStrategy.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Shadow.ListObjectsStrategy)
+
+	val = res.Get("properties.requestTimeout.properties.default.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.RequestTimeout.Default.String())
+
 	val = res.Get("properties.listObjectsDeadline.default")
 	require.True(t, val.Exists())
 	require.Equal(t, val.String(), cfg.ListObjectsDeadline.String())
@@ -934,6 +1974,22 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.EqualValues(t, val.Int(), cfg.ListObjectsMaxResults)
 
+	val = res.Get("properties.listObjectsSortOrder.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsSortOrder)
+
+	val = res.Get("properties.listObjectsPartialResults.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.ListObjectsPartialResults)
+
+	val = res.Get("properties.listObjectsStreamBufferSize.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.ListObjectsStreamBufferSize)
+
+	val = res.Get("properties.listObjectsStreamSendTimeout.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.ListObjectsStreamSendTimeout.String())
+
 	val = res.Get("properties.experimentals.default")
 	require.True(t, val.Exists())
 	require.Equal(t, len(val.Array()), len(cfg.Experimentals))
@@ -950,6 +2006,34 @@ func TestDefaultConfig(t *testing.T) {
 	require.True(t, val.Exists())
 	require.Equal(t, val.Bool(), cfg.Metrics.EnableRPCHistograms)
 
+	val = res.Get("properties.metrics.properties.enablePerStoreLabels.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Metrics.EnablePerStoreLabels)
+
+	val = res.Get("properties.metrics.properties.maxStoreLabels.default")
+	require.True(t, val.Exists())
+	require.EqualValues(t, val.Int(), cfg.Metrics.MaxStoreLabels)
+
+	val = res.Get("properties.health.properties.probeTimeout.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Health.ProbeTimeout.String())
+
+	val = res.Get("properties.reload.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Reload.Enabled)
+
+	val = res.Get("properties.reload.properties.watchInterval.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Reload.WatchInterval.String())
+
+	val = res.Get("properties.admin.properties.enabled.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.Bool(), cfg.Admin.Enabled)
+
+	val = res.Get("properties.admin.properties.addr.default")
+	require.True(t, val.Exists())
+	require.Equal(t, val.String(), cfg.Admin.Addr)
+
 	val = res.Get("properties.trace.properties.serviceName.default")
 	require.True(t, val.Exists())
 	require.Equal(t, val.String(), cfg.Trace.ServiceName)
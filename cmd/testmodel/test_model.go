@@ -0,0 +1,303 @@
+// Package testmodel contains the command to run the tests of an authorization model against an in-memory datastore.
+package testmodel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/cmd/validatemodel"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+const fileFlag = "file"
+
+// errTestsFailed makes the command exit with a non-zero status once the failures are printed.
+var errTestsFailed = errors.New("the authorization model tests failed")
+
+// testFile is a file of tests of an authorization model, e.g.
+//
+//	modelFile: model.fga
+//	tuples:
+//	  - object: document:1
+//	    relation: viewer
+//	    user: user:anne
+//	tests:
+//	  - name: viewers
+//	    checkAssertions:
+//	      - tuple:
+//	          object: document:1
+//	          relation: viewer
+//	          user: user:anne
+//	        expectation: true
+//	    listObjectsAssertions:
+//	      - request:
+//	          user: user:anne
+//	          type: document
+//	          relation: viewer
+//	        expectation:
+//	          - document:1
+type testFile struct {
+	// Model is the authorization model in the DSL. It is read from ModelFile, relative to the test file, if empty.
+	Model     string
+	ModelFile string `yaml:"modelFile"`
+
+	// Tuples are written before every test.
+	Tuples []*openfgav1.TupleKey
+
+	Tests []*modelTest
+}
+
+// modelTest is run in its own store, in which the tuples of the file and of the test are written.
+type modelTest struct {
+	Name                  string
+	Tuples                []*openfgav1.TupleKey
+	CheckAssertions       []*checkAssertion       `yaml:"checkAssertions"`
+	ListObjectsAssertions []*listObjectsAssertion `yaml:"listObjectsAssertions"`
+}
+
+type checkAssertion struct {
+	Tuple            *openfgav1.TupleKey
+	ContextualTuples []*openfgav1.TupleKey `yaml:"contextualTuples"`
+	Expectation      bool
+}
+
+type listObjectsAssertion struct {
+	Request struct {
+		User     string
+		Type     string
+		Relation string
+	}
+	ContextualTuples []*openfgav1.TupleKey `yaml:"contextualTuples"`
+	Expectation      []string
+}
+
+func NewTestModelCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Run the Check and ListObjects tests of an authorization model against an in-memory datastore",
+		Long: "Write the authorization model and the tuples of a YAML test file to an ephemeral in-memory datastore, and run its Check and " +
+			"ListObjects assertions, without a running server.\n" +
+			"Every test is run in its own store. The model is either inlined in the test file ('model') or read from a file relative to it " +
+			"('modelFile'), in JSON if its extension is .json or else in the DSL.\n" +
+			"The command fails if the model is invalid or any assertion fails.",
+		RunE:         runTestModel,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.String(fileFlag, "", "the YAML test file")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runTestModel(cmd *cobra.Command, _ []string) error {
+	path := viper.GetString(fileFlag)
+	if path == "" {
+		return fmt.Errorf("missing test file")
+	}
+
+	tests, model, err := readTestFile(path)
+	if err != nil {
+		return err
+	}
+
+	failures, err := runTests(cmd.Context(), cmd.OutOrStdout(), tests, model)
+	if err != nil {
+		return err
+	}
+
+	if failures > 0 {
+		return errTestsFailed
+	}
+
+	return nil
+}
+
+func readTestFile(path string) (*testFile, *openfgav1.AuthorizationModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the test file: %w", err)
+	}
+
+	var tests testFile
+	if err := yaml.Unmarshal(data, &tests); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the test file: %w", err)
+	}
+
+	if tests.Model == "" {
+		if tests.ModelFile == "" {
+			return nil, nil, fmt.Errorf("the test file has neither a 'model' nor a 'modelFile'")
+		}
+
+		modelPath := tests.ModelFile
+		if !filepath.IsAbs(modelPath) {
+			modelPath = filepath.Join(filepath.Dir(path), modelPath)
+		}
+
+		model, err := validatemodel.ReadModel(modelPath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &tests, model, nil
+	}
+
+	model, err := typesystem.ParseDSL(tests.Model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the authorization model: %w", err)
+	}
+
+	return &tests, model, nil
+}
+
+// runTests runs the tests against a server backed by an in-memory datastore, writing the outcome of every test and
+// assertion to w, and returns the number of failed assertions. It returns an error if the model or the tuples
+// cannot be written.
+func runTests(ctx context.Context, w io.Writer, tests *testFile, model *openfgav1.AuthorizationModel) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// the model is invalid even if there is no test to write it for
+	if _, err := typesystem.NewAndValidate(ctx, model); err != nil {
+		return 0, fmt.Errorf("failed to write the authorization model: %w", err)
+	}
+
+	datastore := memory.New()
+	defer datastore.Close()
+
+	s, err := server.NewServerWithOpts(server.WithDatastore(datastore))
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	failures := 0
+	for i, test := range tests.Tests {
+		name := test.Name
+		if name == "" {
+			name = fmt.Sprintf("test %d", i+1)
+		}
+
+		store, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: name})
+		if err != nil {
+			return 0, err
+		}
+
+		writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         store.GetId(),
+			SchemaVersion:   model.GetSchemaVersion(),
+			TypeDefinitions: model.GetTypeDefinitions(),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to write the authorization model: %s", status.Convert(err).Message())
+		}
+		modelID := writeModelResp.GetAuthorizationModelId()
+
+		tuples := append(append([]*openfgav1.TupleKey{}, tests.Tuples...), test.Tuples...)
+		for start := 0; start < len(tuples); start += datastore.MaxTuplesPerWrite() {
+			end := start + datastore.MaxTuplesPerWrite()
+			if end > len(tuples) {
+				end = len(tuples)
+			}
+
+			_, err := s.Write(ctx, &openfgav1.WriteRequest{
+				StoreId:              store.GetId(),
+				AuthorizationModelId: modelID,
+				Writes:               &openfgav1.TupleKeys{TupleKeys: tuples[start:end]},
+			})
+			if err != nil {
+				return 0, fmt.Errorf("%s: failed to write the tuples: %s", name, status.Convert(err).Message())
+			}
+		}
+
+		testFailures := 0
+		for _, assertion := range test.CheckAssertions {
+			resp, err := s.Check(ctx, &openfgav1.CheckRequest{
+				StoreId:              store.GetId(),
+				AuthorizationModelId: modelID,
+				TupleKey:             assertion.Tuple,
+				ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: assertion.ContextualTuples},
+			})
+
+			switch {
+			case err != nil:
+				fmt.Fprintf(w, "    FAIL check %s: %s\n", assertion.Tuple, status.Convert(err).Message())
+			case resp.GetAllowed() != assertion.Expectation:
+				fmt.Fprintf(w, "    FAIL check %s: expected %t, got %t\n", assertion.Tuple, assertion.Expectation, resp.GetAllowed())
+			default:
+				continue
+			}
+			testFailures++
+		}
+
+		for _, assertion := range test.ListObjectsAssertions {
+			req := assertion.Request
+			description := fmt.Sprintf("user:\"%s\" type:\"%s\" relation:\"%s\"", req.User, req.Type, req.Relation)
+
+			resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+				StoreId:              store.GetId(),
+				AuthorizationModelId: modelID,
+				User:                 req.User,
+				Type:                 req.Type,
+				Relation:             req.Relation,
+				ContextualTuples:     &openfgav1.ContextualTupleKeys{TupleKeys: assertion.ContextualTuples},
+			})
+			if err != nil {
+				fmt.Fprintf(w, "    FAIL list objects %s: %s\n", description, status.Convert(err).Message())
+				testFailures++
+				continue
+			}
+
+			expected := append([]string{}, assertion.Expectation...)
+			sort.Strings(expected)
+			got := append([]string{}, resp.GetObjects()...)
+			sort.Strings(got)
+
+			if !equalObjects(expected, got) {
+				fmt.Fprintf(w, "    FAIL list objects %s: expected %v, got %v\n", description, expected, got)
+				testFailures++
+			}
+		}
+
+		if testFailures > 0 {
+			fmt.Fprintf(w, "FAIL %s (%d of %d assertions failed)\n", name, testFailures, len(test.CheckAssertions)+len(test.ListObjectsAssertions))
+		} else {
+			fmt.Fprintf(w, "PASS %s\n", name)
+		}
+		failures += testFailures
+	}
+
+	return failures, nil
+}
+
+func equalObjects(expected, got []string) bool {
+	if len(expected) != len(got) {
+		return false
+	}
+
+	for i := range expected {
+		if expected[i] != got[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package testmodel
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const model = `type user
+
+type folder
+  relations
+    define viewer: [user] as self
+
+type document
+  relations
+    define parent: [folder] as self
+    define viewer: [user] as self or viewer from parent
+`
+
+func runTestCommand(t *testing.T, tests string) (string, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model.fga"), []byte(model), 0o600))
+
+	path := filepath.Join(dir, "model_test.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(tests), 0o600))
+
+	var out bytes.Buffer
+	cmd := NewTestModelCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--file", path})
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestTestModelCommand(t *testing.T) {
+	out, err := runTestCommand(t, `
+modelFile: model.fga
+tuples:
+  - object: document:1
+    relation: parent
+    user: folder:1
+tests:
+  - name: inherited viewers
+    tuples:
+      - object: folder:1
+        relation: viewer
+        user: user:anne
+    checkAssertions:
+      - tuple:
+          object: document:1
+          relation: viewer
+          user: user:anne
+        expectation: true
+      - tuple:
+          object: document:1
+          relation: viewer
+          user: user:bob
+        expectation: false
+      - tuple:
+          object: document:1
+          relation: viewer
+          user: user:bob
+        contextualTuples:
+          - object: folder:1
+            relation: viewer
+            user: user:bob
+        expectation: true
+    listObjectsAssertions:
+      - request:
+          user: user:anne
+          type: document
+          relation: viewer
+        expectation:
+          - document:1
+  - name: isolated stores
+    checkAssertions:
+      - tuple:
+          object: document:1
+          relation: viewer
+          user: user:anne
+        expectation: false
+`)
+	require.NoError(t, err)
+	require.Equal(t, "PASS inherited viewers\nPASS isolated stores\n", out)
+}
+
+func TestTestModelCommandFailures(t *testing.T) {
+	out, err := runTestCommand(t, `
+modelFile: model.fga
+tests:
+  - name: failing
+    checkAssertions:
+      - tuple:
+          object: document:1
+          relation: viewer
+          user: user:anne
+        expectation: true
+    listObjectsAssertions:
+      - request:
+          user: user:anne
+          type: document
+          relation: viewer
+        expectation:
+          - document:1
+`)
+	require.ErrorIs(t, err, errTestsFailed)
+	require.Contains(t, out, "FAIL check")
+	require.Contains(t, out, "FAIL list objects")
+	require.Contains(t, out, "FAIL failing (2 of 2 assertions failed)")
+}
+
+func TestTestModelCommandInvalidModel(t *testing.T) {
+	_, err := runTestCommand(t, `
+model: |
+  type document
+    relations
+      define viewer: [user] as self
+tests:
+  - name: unreachable
+`)
+	require.ErrorContains(t, err, "failed to write the authorization model")
+
+	_, err = runTestCommand(t, `
+model: |
+  type document
+    relations
+      define viewer: [user] as self
+`)
+	require.ErrorContains(t, err, "failed to write the authorization model")
+
+	_, err = runTestCommand(t, `tests: []`)
+	require.ErrorContains(t, err, "neither a 'model' nor a 'modelFile'")
+}
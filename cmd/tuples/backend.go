@@ -0,0 +1,183 @@
+package tuples
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// backend reads and writes the tuples of a store, either through a running server or directly in a datastore.
+type backend interface {
+	// ResolveModel returns the authorization model the tuples are validated against, the latest model of the
+	// store if modelID is empty.
+	ResolveModel(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error)
+
+	// Write writes the tuples in a single transaction.
+	Write(ctx context.Context, storeID, modelID string, tuples []*openfgav1.TupleKey) error
+
+	// ReadPage returns a page of the tuples of the store, starting after the continuation token, and the
+	// continuation token of the next page, which is empty after the last page.
+	ReadPage(ctx context.Context, storeID string, pageSize int32, continuationToken string) ([]*openfgav1.TupleKey, string, error)
+
+	// MaxTuplesPerWrite is the maximum number of tuples written in a single transaction.
+	MaxTuplesPerWrite() int
+
+	Close()
+}
+
+// serverBackend reads and writes the tuples through the gRPC API of a running server.
+type serverBackend struct {
+	conn   *grpc.ClientConn
+	client openfgav1.OpenFGAServiceClient
+}
+
+// maxTuplesPerWriteRequest is the maximum number of tuples of a Write request accepted by the servers.
+const maxTuplesPerWriteRequest = 100
+
+func newServerBackend(ctx context.Context, addr, apiToken string, useTLS bool) (*serverBackend, error) {
+	dialOpts := []grpc.DialOption{grpc.WithBlock()}
+	if useTLS {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if apiToken != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken{token: apiToken, requireTLS: useTLS}))
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the server '%s': %w", addr, err)
+	}
+
+	return &serverBackend{conn: conn, client: openfgav1.NewOpenFGAServiceClient(conn)}, nil
+}
+
+func (b *serverBackend) ResolveModel(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
+	if modelID == "" {
+		resp, err := b.client.ReadAuthorizationModels(ctx, &openfgav1.ReadAuthorizationModelsRequest{
+			StoreId:  storeID,
+			PageSize: wrapperspb.Int32(1),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.GetAuthorizationModels()) == 0 {
+			return nil, fmt.Errorf("the store '%s' has no authorization model", storeID)
+		}
+
+		return typesystem.New(resp.GetAuthorizationModels()[0]), nil
+	}
+
+	resp, err := b.client.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+		StoreId: storeID,
+		Id:      modelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return typesystem.New(resp.GetAuthorizationModel()), nil
+}
+
+func (b *serverBackend) Write(ctx context.Context, storeID, modelID string, tuples []*openfgav1.TupleKey) error {
+	_, err := b.client.Write(ctx, &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Writes:               &openfgav1.TupleKeys{TupleKeys: tuples},
+	})
+
+	return err
+}
+
+func (b *serverBackend) ReadPage(ctx context.Context, storeID string, pageSize int32, continuationToken string) ([]*openfgav1.TupleKey, string, error) {
+	resp, err := b.client.Read(ctx, &openfgav1.ReadRequest{
+		StoreId:           storeID,
+		PageSize:          wrapperspb.Int32(pageSize),
+		ContinuationToken: continuationToken,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	tuples := make([]*openfgav1.TupleKey, 0, len(resp.GetTuples()))
+	for _, tuple := range resp.GetTuples() {
+		tuples = append(tuples, tuple.GetKey())
+	}
+
+	return tuples, resp.GetContinuationToken(), nil
+}
+
+func (b *serverBackend) MaxTuplesPerWrite() int {
+	return maxTuplesPerWriteRequest
+}
+
+func (b *serverBackend) Close() {
+	_ = b.conn.Close()
+}
+
+// bearerToken authenticates the requests with a preshared key.
+type bearerToken struct {
+	token      string
+	requireTLS bool
+}
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
+// datastoreBackend reads and writes the tuples directly in a datastore, e.g. to load a store before the servers
+// are started.
+type datastoreBackend struct {
+	datastore storage.OpenFGADatastore
+	resolver  typesystem.TypesystemResolverFunc
+}
+
+func newDatastoreBackend(datastore storage.OpenFGADatastore) *datastoreBackend {
+	return &datastoreBackend{
+		datastore: datastore,
+		resolver:  typesystem.MemoizedTypesystemResolverFunc(datastore),
+	}
+}
+
+func (b *datastoreBackend) ResolveModel(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
+	return b.resolver(ctx, storeID, modelID)
+}
+
+func (b *datastoreBackend) Write(ctx context.Context, storeID, _ string, tuples []*openfgav1.TupleKey) error {
+	return b.datastore.Write(ctx, storeID, nil, tuples)
+}
+
+func (b *datastoreBackend) ReadPage(ctx context.Context, storeID string, pageSize int32, continuationToken string) ([]*openfgav1.TupleKey, string, error) {
+	tuples, from, err := b.datastore.ReadPage(ctx, storeID, nil, storage.PaginationOptions{PageSize: int(pageSize), From: continuationToken})
+	if err != nil {
+		return nil, "", err
+	}
+
+	tupleKeys := make([]*openfgav1.TupleKey, 0, len(tuples))
+	for _, tuple := range tuples {
+		tupleKeys = append(tupleKeys, tuple.GetKey())
+	}
+
+	return tupleKeys, string(from), nil
+}
+
+func (b *datastoreBackend) MaxTuplesPerWrite() int {
+	return b.datastore.MaxTuplesPerWrite()
+}
+
+func (b *datastoreBackend) Close() {
+	b.datastore.Close()
+}
@@ -0,0 +1,171 @@
+package tuples
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// csvHeader is the header row of the CSV files, naming the fields of the tuples.
+var csvHeader = []string{"object", "relation", "user"}
+
+// jsonlTuple is a line of the JSONL files.
+type jsonlTuple struct {
+	Object   string `json:"object"`
+	Relation string `json:"relation"`
+	User     string `json:"user"`
+}
+
+// fileFormat returns the format of the file, which is inferred from its extension if it is not set.
+func fileFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	switch format {
+	case formatCSV, formatJSONL:
+		return format, nil
+	default:
+		return "", fmt.Errorf("the format must be one of ['%s', '%s'], or inferred from the extension of the file", formatCSV, formatJSONL)
+	}
+}
+
+// tupleReader reads the tuples of a file one record at a time.
+type tupleReader interface {
+	// Read returns the next tuple, or io.EOF once every tuple was read.
+	Read() (*openfgav1.TupleKey, error)
+}
+
+func newTupleReader(format string, r io.Reader) (tupleReader, error) {
+	if format == formatJSONL {
+		return &jsonlReader{scanner: bufio.NewScanner(r)}, nil
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return &csvReader{reader: reader}, nil
+		}
+		return nil, fmt.Errorf("failed to read the CSV header: %w", err)
+	}
+	if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("the CSV header must be '%s'", strings.Join(csvHeader, ","))
+	}
+
+	return &csvReader{reader: reader}, nil
+}
+
+type csvReader struct {
+	reader *csv.Reader
+}
+
+func (r *csvReader) Read() (*openfgav1.TupleKey, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return &openfgav1.TupleKey{Object: record[0], Relation: record[1], User: record[2]}, nil
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlReader) Read() (*openfgav1.TupleKey, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var tuple jsonlTuple
+		if err := json.Unmarshal([]byte(line), &tuple); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return &openfgav1.TupleKey{Object: tuple.Object, Relation: tuple.Relation, User: tuple.User}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// tupleWriter writes tuples to a file. Flush must be called once the tuples are written.
+type tupleWriter interface {
+	Write(*openfgav1.TupleKey) error
+	Flush() error
+}
+
+// newTupleWriter returns a writer of the format. The CSV header is only written if header is set, so that an
+// interrupted export can be resumed by appending to its file.
+func newTupleWriter(format string, w io.Writer, header bool) (tupleWriter, error) {
+	if format == formatJSONL {
+		return &jsonlWriter{writer: bufio.NewWriter(w)}, nil
+	}
+
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+	}
+
+	return &csvWriter{writer: writer}, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(tk *openfgav1.TupleKey) error {
+	return w.writer.Write([]string{tk.GetObject(), tk.GetRelation(), tk.GetUser()})
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonlWriter struct {
+	writer *bufio.Writer
+}
+
+func (w *jsonlWriter) Write(tk *openfgav1.TupleKey) error {
+	line, err := json.Marshal(jsonlTuple{Object: tk.GetObject(), Relation: tk.GetRelation(), User: tk.GetUser()})
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.writer.Write(line); err != nil {
+		return err
+	}
+
+	return w.writer.WriteByte('\n')
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.writer.Flush()
+}
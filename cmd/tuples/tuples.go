@@ -0,0 +1,455 @@
+// Package tuples contains the commands to import the tuples of a store from a file and to export them to a file.
+package tuples
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/cmd/run"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	datastoreEngineFlag = "datastore-engine"
+	datastoreURIFlag    = "datastore-uri"
+	serverAddrFlag      = "server-addr"
+	apiTokenFlag        = "api-token"
+	serverTLSFlag       = "server-tls"
+	storeIDFlag         = "store-id"
+	modelIDFlag         = "model-id"
+	fileFlag            = "file"
+	formatFlag          = "format"
+	batchSizeFlag       = "batch-size"
+	pageSizeFlag        = "page-size"
+	resumeFlag          = "resume"
+	dryRunFlag          = "dry-run"
+)
+
+// errInvalidTuples makes the import exit with a non-zero status once the invalid tuples are reported.
+var errInvalidTuples = errors.New("some tuples are invalid")
+
+func NewTuplesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tuples",
+		Short: "Import and export the tuples of a store",
+		Long: `The tuples commands import the tuples of a store from a CSV or JSONL file and export them to such a file.
+CSV files have an 'object,relation,user' header row, and JSONL files an {"object": ..., "relation": ..., "user": ...} object per line.
+The tuples are read and written through the gRPC API of a running server if --server-addr is set, or else directly in the datastore, which is configured like the server, by the config file, the environment or the flags.`,
+	}
+
+	cmd.AddCommand(newImportCommand(), newExportCommand())
+
+	return cmd
+}
+
+func newImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import the tuples of a store from a file",
+		Long: `Import the tuples of a file into a store, in batches each written in a single transaction.
+Every tuple is validated against the authorization model first, and the invalid tuples are reported and skipped: the command then fails once the valid tuples are imported.
+The progress of the import is recorded in '<file>.progress' after every batch, so that an interrupted import is resumed after its last written batch with --resume.
+With --dry-run, the tuples are only validated against the model.`,
+		RunE:         runImport,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	addConnectionFlags(flags)
+	flags.String(storeIDFlag, "", "the store the tuples are imported into")
+	flags.String(modelIDFlag, "", "the authorization model the tuples are validated against, or empty for the latest model of the store")
+	flags.String(fileFlag, "", "the CSV or JSONL file of the tuples")
+	flags.String(formatFlag, "", "the format of the file ('csv' or 'jsonl'), or empty to infer it from the extension of the file")
+	flags.Int(batchSizeFlag, 100, "the number of tuples written in a single transaction, capped by the maximum of the server or the datastore")
+	flags.Bool(resumeFlag, false, "resume an interrupted import after its last written batch")
+	flags.Bool(dryRunFlag, false, "validate the tuples against the model without writing them")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func newExportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the tuples of a store to a file",
+		Long: `Export every tuple of a store to a file, one page at a time.
+The progress of the export is recorded in '<file>.progress' after every page, so that an interrupted export is resumed after its last written page with --resume.`,
+		RunE:         runExport,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	addConnectionFlags(flags)
+	flags.String(storeIDFlag, "", "the store whose tuples are exported")
+	flags.String(fileFlag, "", "the CSV or JSONL file the tuples are exported to")
+	flags.String(formatFlag, "", "the format of the file ('csv' or 'jsonl'), or empty to infer it from the extension of the file")
+	flags.Int32(pageSizeFlag, 100, "the number of tuples read at once")
+	flags.Bool(resumeFlag, false, "resume an interrupted export after its last written page")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func addConnectionFlags(flags *pflag.FlagSet) {
+	flags.String(serverAddrFlag, "", "the gRPC address of a running server, or empty to connect to the datastore directly")
+	flags.String(apiTokenFlag, "", "the preshared key authenticating the requests to the server")
+	flags.Bool(serverTLSFlag, false, "connect to the server with TLS")
+	flags.String(datastoreEngineFlag, "", "the datastore engine, if the server address is empty")
+	flags.String(datastoreURIFlag, "", "the connection uri to the datastore, if the server address is empty")
+}
+
+func newBackend(ctx context.Context) (backend, error) {
+	if addr := viper.GetString(serverAddrFlag); addr != "" {
+		return newServerBackend(ctx, addr, viper.GetString(apiTokenFlag), viper.GetBool(serverTLSFlag))
+	}
+
+	config, err := run.ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := run.VerifyConfig(config); err != nil {
+		return nil, err
+	}
+
+	datastore, err := run.NewDatastore(config, logger.NewNoopLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	return newDatastoreBackend(datastore), nil
+}
+
+// readRequiredFlags returns the store and the file of the command, which must be set, and the format of the file.
+func readRequiredFlags() (string, string, string, error) {
+	storeID := viper.GetString(storeIDFlag)
+	if storeID == "" {
+		return "", "", "", errors.New("missing store id")
+	}
+
+	path := viper.GetString(fileFlag)
+	if path == "" {
+		return "", "", "", errors.New("missing tuples file")
+	}
+
+	format, err := fileFormat(viper.GetString(formatFlag), path)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return storeID, path, format, nil
+}
+
+func runImport(cmd *cobra.Command, _ []string) error {
+	storeID, path, format, err := readRequiredFlags()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the tuples file: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := newTupleReader(format, file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	b, err := newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	return importTuples(ctx, b, reader, cmd.OutOrStdout(), &importOptions{
+		storeID:      storeID,
+		modelID:      viper.GetString(modelIDFlag),
+		batchSize:    viper.GetInt(batchSizeFlag),
+		progressFile: path + ".progress",
+		resume:       viper.GetBool(resumeFlag),
+		dryRun:       viper.GetBool(dryRunFlag),
+	})
+}
+
+func runExport(cmd *cobra.Command, _ []string) error {
+	storeID, path, format, err := readRequiredFlags()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	b, err := newBackend(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	return exportTuples(ctx, b, cmd.OutOrStdout(), &exportOptions{
+		storeID:      storeID,
+		path:         path,
+		format:       format,
+		pageSize:     viper.GetInt32(pageSizeFlag),
+		progressFile: path + ".progress",
+		resume:       viper.GetBool(resumeFlag),
+	})
+}
+
+// progress is the state of an interrupted import or export, from which it is resumed.
+type progress struct {
+	// Records is the number of records of the file imported so far, whether they were valid or not.
+	Records int `json:"records,omitempty"`
+
+	// ContinuationToken is the token of the next page to export, and Offset the size of the file once the pages
+	// before it were written.
+	ContinuationToken string `json:"continuation_token,omitempty"`
+	Offset            int64  `json:"offset,omitempty"`
+}
+
+func readProgress(path string) (*progress, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the progress to resume from: %w", err)
+	}
+
+	var p progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to read the progress to resume from: %w", err)
+	}
+
+	return &p, nil
+}
+
+func writeProgress(path string, p *progress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	// the progress is replaced atomically, so that it is never lost if the command is interrupted
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to record the progress: %w", err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type importOptions struct {
+	storeID      string
+	modelID      string
+	batchSize    int
+	progressFile string
+	resume       bool
+	dryRun       bool
+}
+
+// importTuples validates the tuples of the reader against the model and writes the valid ones in batches,
+// recording the progress after every batch. The invalid tuples are reported to out, and errInvalidTuples is
+// returned once the valid tuples are written. Any other error aborts the import.
+func importTuples(ctx context.Context, b backend, reader tupleReader, out io.Writer, opts *importOptions) error {
+	typesys, err := b.ResolveModel(ctx, opts.storeID, opts.modelID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve the authorization model: %s", status.Convert(err).Message())
+	}
+	modelID := typesys.GetAuthorizationModelID()
+
+	batchSize := opts.batchSize
+	if batchSize <= 0 || batchSize > b.MaxTuplesPerWrite() {
+		batchSize = b.MaxTuplesPerWrite()
+	}
+
+	skip := 0
+	if opts.resume {
+		p, err := readProgress(opts.progressFile)
+		if err != nil {
+			return err
+		}
+		skip = p.Records
+		fmt.Fprintf(out, "resuming the import after %d records\n", skip)
+	}
+
+	var (
+		records  int
+		imported int
+		invalid  int
+		batch    = make([]*openfgav1.TupleKey, 0, batchSize)
+		seen     = map[string]struct{}{}
+	)
+
+	flush := func() error {
+		if len(batch) > 0 && !opts.dryRun {
+			if err := b.Write(ctx, opts.storeID, modelID, batch); err != nil {
+				return fmt.Errorf("failed to write the tuples of records %d to %d: %s", records-len(batch)+1, records, status.Convert(err).Message())
+			}
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		seen = map[string]struct{}{}
+
+		if opts.dryRun {
+			return nil
+		}
+
+		return writeProgress(opts.progressFile, &progress{Records: records})
+	}
+
+	for {
+		tk, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read the tuples file: %w", err)
+		}
+
+		records++
+		if records <= skip {
+			continue
+		}
+
+		if err := commands.ValidateTupleToWrite(typesys, tk); err != nil {
+			fmt.Fprintf(out, "record %d: invalid tuple '%s': %s\n", records, tuple.TupleKeyToString(tk), status.Convert(err).Message())
+			invalid++
+			continue
+		}
+
+		key := tuple.TupleKeyToString(tk)
+		if _, ok := seen[key]; ok {
+			fmt.Fprintf(out, "record %d: duplicate tuple '%s'\n", records, key)
+			invalid++
+			continue
+		}
+		seen[key] = struct{}{}
+
+		batch = append(batch, tk)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		fmt.Fprintf(out, "%d tuples are valid, %d are invalid\n", imported, invalid)
+	} else {
+		if err := os.Remove(opts.progressFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fmt.Fprintf(out, "imported %d tuples, skipped %d invalid tuples\n", imported, invalid)
+	}
+
+	if invalid > 0 {
+		return errInvalidTuples
+	}
+
+	return nil
+}
+
+type exportOptions struct {
+	storeID      string
+	path         string
+	format       string
+	pageSize     int32
+	progressFile string
+	resume       bool
+}
+
+// exportTuples writes the tuples of the store to the file one page at a time, recording the progress after every
+// page. A resumed export truncates the file to the pages written before the interruption, and appends the next
+// pages to it.
+func exportTuples(ctx context.Context, b backend, out io.Writer, opts *exportOptions) error {
+	p := &progress{}
+	if opts.resume {
+		var err error
+		if p, err = readProgress(opts.progressFile); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "resuming the export at offset %d of the file\n", p.Offset)
+	}
+
+	file, err := os.OpenFile(opts.path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open the tuples file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(p.Offset); err != nil {
+		return err
+	}
+	if _, err := file.Seek(p.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	writer, err := newTupleWriter(opts.format, file, !opts.resume)
+	if err != nil {
+		return err
+	}
+
+	exported := 0
+	for {
+		tuples, continuationToken, err := b.ReadPage(ctx, opts.storeID, opts.pageSize, p.ContinuationToken)
+		if err != nil {
+			return fmt.Errorf("failed to read the tuples: %s", status.Convert(err).Message())
+		}
+
+		for _, tk := range tuples {
+			if err := writer.Write(tk); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		exported += len(tuples)
+
+		if continuationToken == "" {
+			break
+		}
+
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		p = &progress{ContinuationToken: continuationToken, Offset: offset}
+		if err := writeProgress(opts.progressFile, p); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(opts.progressFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	fmt.Fprintf(out, "exported %d tuples\n", exported)
+
+	return nil
+}
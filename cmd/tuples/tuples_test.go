@@ -0,0 +1,238 @@
+package tuples
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newTestBackends returns a backend of the datastore, a backend of a server serving the datastore, and a store of
+// the datastore with an authorization model.
+func newTestBackends(t *testing.T) (map[string]backend, string) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	datastore := memory.New()
+	t.Cleanup(datastore.Close)
+
+	s := server.MustNewServerWithOpts(server.WithDatastore(datastore))
+	t.Cleanup(s.Close)
+
+	store, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "tuples"})
+	require.NoError(t, err)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       store.GetId(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define editor: [user] as self
+		    define viewer: [user] as self or editor
+		`),
+	})
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	openfgav1.RegisterOpenFGAServiceServer(grpcServer, s)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	serverBackend, err := newServerBackend(ctx, listener.Addr().String(), "", false)
+	require.NoError(t, err)
+	t.Cleanup(serverBackend.Close)
+
+	return map[string]backend{
+		"datastore": &datastoreBackend{datastore: datastore, resolver: typesystem.MemoizedTypesystemResolverFunc(datastore)},
+		"server":    serverBackend,
+	}, store.GetId()
+}
+
+func newReader(t *testing.T, format, data string) tupleReader {
+	t.Helper()
+
+	reader, err := newTupleReader(format, strings.NewReader(data))
+	require.NoError(t, err)
+
+	return reader
+}
+
+func TestImportExport(t *testing.T) {
+	for _, name := range []string{"datastore", "server"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			backends, storeID := newTestBackends(t)
+			b := backends[name]
+
+			dir := t.TempDir()
+			var out bytes.Buffer
+
+			err := importTuples(ctx, b, newReader(t, formatCSV, "object,relation,user\ndocument:1,viewer,user:anne\ndocument:1,editor,user:bob\ndocument:2,viewer,user:anne\n"), &out, &importOptions{
+				storeID:      storeID,
+				batchSize:    2,
+				progressFile: filepath.Join(dir, "tuples.csv.progress"),
+			})
+			require.NoError(t, err)
+			require.Contains(t, out.String(), "imported 3 tuples")
+			require.NoFileExists(t, filepath.Join(dir, "tuples.csv.progress"))
+
+			path := filepath.Join(dir, "tuples.jsonl")
+			err = exportTuples(ctx, b, &out, &exportOptions{
+				storeID:      storeID,
+				path:         path,
+				format:       formatJSONL,
+				pageSize:     1,
+				progressFile: path + ".progress",
+			})
+			require.NoError(t, err)
+			require.Contains(t, out.String(), "exported 3 tuples")
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []string{
+				`{"object":"document:1","relation":"viewer","user":"user:anne"}`,
+				`{"object":"document:1","relation":"editor","user":"user:bob"}`,
+				`{"object":"document:2","relation":"viewer","user":"user:anne"}`,
+			}, strings.Split(strings.TrimSpace(string(data)), "\n"))
+		})
+	}
+}
+
+func TestImportInvalidTuples(t *testing.T) {
+	backends, storeID := newTestBackends(t)
+	b := backends["datastore"]
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	tuples := `{"object":"document:1","relation":"viewer","user":"user:anne"}
+{"object":"folder:1","relation":"viewer","user":"user:anne"}
+
+{"object":"document:1","relation":"owner","user":"user:anne"}
+{"object":"document:2","relation":"viewer","user":"user:anne"}
+`
+
+	t.Run("dry_run", func(t *testing.T) {
+		var out bytes.Buffer
+		err := importTuples(ctx, b, newReader(t, formatJSONL, tuples), &out, &importOptions{
+			storeID:      storeID,
+			progressFile: filepath.Join(dir, "dry_run.progress"),
+			dryRun:       true,
+		})
+		require.ErrorIs(t, err, errInvalidTuples)
+		require.Contains(t, out.String(), "record 2: invalid tuple 'folder:1#viewer@user:anne'")
+		require.Contains(t, out.String(), "record 3: invalid tuple 'document:1#owner@user:anne'")
+		require.Contains(t, out.String(), "2 tuples are valid, 2 are invalid")
+		require.NoFileExists(t, filepath.Join(dir, "dry_run.progress"))
+
+		tuples, _, err := b.ReadPage(ctx, storeID, 100, "")
+		require.NoError(t, err)
+		require.Empty(t, tuples)
+	})
+
+	var out bytes.Buffer
+	err := importTuples(ctx, b, newReader(t, formatJSONL, tuples), &out, &importOptions{
+		storeID:      storeID,
+		progressFile: filepath.Join(dir, "import.progress"),
+	})
+	require.ErrorIs(t, err, errInvalidTuples)
+	require.Contains(t, out.String(), "imported 2 tuples, skipped 2 invalid tuples")
+
+	written, _, err := b.ReadPage(ctx, storeID, 100, "")
+	require.NoError(t, err)
+	require.Len(t, written, 2)
+}
+
+func TestResume(t *testing.T) {
+	backends, storeID := newTestBackends(t)
+	b := backends["datastore"]
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// the first batch of the interrupted import was written
+	require.NoError(t, b.Write(ctx, storeID, "", []*openfgav1.TupleKey{{Object: "document:1", Relation: "viewer", User: "user:anne"}}))
+	require.NoError(t, writeProgress(filepath.Join(dir, "tuples.csv.progress"), &progress{Records: 1}))
+
+	var out bytes.Buffer
+	err := importTuples(ctx, b, newReader(t, formatCSV, "object,relation,user\ndocument:1,viewer,user:anne\ndocument:2,viewer,user:anne\n"), &out, &importOptions{
+		storeID:      storeID,
+		batchSize:    1,
+		progressFile: filepath.Join(dir, "tuples.csv.progress"),
+		resume:       true,
+	})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "imported 1 tuples")
+
+	// the export was interrupted after its first page, and a part of its second page
+	path := filepath.Join(dir, "export.csv")
+	firstPage, continuationToken, err := b.ReadPage(ctx, storeID, 1, "")
+	require.NoError(t, err)
+	header := "object,relation,user\n"
+	line := firstPage[0].GetObject() + "," + firstPage[0].GetRelation() + "," + firstPage[0].GetUser() + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(header+line+"document:partial"), 0o600))
+	require.NoError(t, writeProgress(path+".progress", &progress{ContinuationToken: continuationToken, Offset: int64(len(header + line))}))
+
+	err = exportTuples(ctx, b, &out, &exportOptions{
+		storeID:      storeID,
+		path:         path,
+		format:       formatCSV,
+		pageSize:     1,
+		progressFile: path + ".progress",
+		resume:       true,
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	exported, err := newTupleReader(formatCSV, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var objects []string
+	for {
+		tk, err := exported.Read()
+		if err != nil {
+			break
+		}
+		objects = append(objects, tk.GetObject())
+	}
+	require.ElementsMatch(t, []string{"document:1", "document:2"}, objects)
+}
+
+func TestFileFormat(t *testing.T) {
+	format, err := fileFormat("", "tuples.jsonl")
+	require.NoError(t, err)
+	require.Equal(t, formatJSONL, format)
+
+	format, err = fileFormat("csv", "tuples.txt")
+	require.NoError(t, err)
+	require.Equal(t, formatCSV, format)
+
+	_, err = fileFormat("", "tuples.txt")
+	require.Error(t, err)
+
+	_, err = newTupleReader(formatCSV, strings.NewReader("user,relation,object\n"))
+	require.ErrorContains(t, err, "the CSV header must be 'object,relation,user'")
+}
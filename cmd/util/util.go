@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/openfga/openfga/pkg/storage"
@@ -45,6 +46,33 @@ func Index[E comparable](s []E, v E) int {
 	return -1
 }
 
+// Pair is a 'key=value' pair of a config entry.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// ParsePairs parses the 'key=value' pairs of the config entry `config`, in their order. The keys must be unique and
+// not empty, and `format` describes the pairs in the errors (e.g. 'name=uri').
+func ParsePairs(config, format string, entries []string) ([]Pair, error) {
+	pairs := make([]Pair, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("config '%s' entry '%s' must be a '%s' pair", config, entry, format)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("config '%s' has several entries for '%s'", config, key)
+		}
+		seen[key] = true
+
+		pairs = append(pairs, Pair{Key: key, Value: value})
+	}
+
+	return pairs, nil
+}
+
 func MustBootstrapDatastore(t testing.TB, engine string) (storagefixtures.DatastoreTestContainer, storage.OpenFGADatastore, string, error) {
 	container := storagefixtures.RunDatastoreTestContainer(t, engine)
 
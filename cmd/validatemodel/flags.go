@@ -0,0 +1,16 @@
+package validatemodel
+
+import (
+	"github.com/openfga/openfga/cmd/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bindRunFlags binds the cobra cmd flags to the equivalent config value being managed
+// by viper. This bridges the config between cobra flags and viper flags.
+func bindRunFlagsFunc(flags *pflag.FlagSet) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		util.MustBindPFlag(fileFlag, flags.Lookup(fileFlag))
+		util.MustBindPFlag(maxRewriteDepthFlag, flags.Lookup(maxRewriteDepthFlag))
+	}
+}
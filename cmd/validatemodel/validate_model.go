@@ -0,0 +1,107 @@
+// Package validatemodel contains the command to check and lint an authorization model file without writing it.
+package validatemodel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	fileFlag            = "file"
+	maxRewriteDepthFlag = "max-rewrite-depth"
+)
+
+// errInvalidModel makes the command exit with a non-zero status once the diagnostics are printed.
+var errInvalidModel = errors.New("the authorization model is invalid")
+
+func NewValidateModelCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "validate-model",
+		Aliases: []string{"validate"},
+		Short:   "Check and lint an authorization model file without writing it",
+		Long: "Check an authorization model file for the problems that would prevent writing it (such as undefined types and relations, " +
+			"unreachable relations and cycles) and for lint warnings (such as overly deep rewrites), and print every problem found as JSON.\n" +
+			"Files with a .json extension are read as an authorization model in JSON, and any other file as the DSL.\n" +
+			"The command fails if the model has any error.",
+		RunE:         runValidateModel,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.String(fileFlag, "", "the authorization model file")
+	flags.Int(maxRewriteDepthFlag, typesystem.DefaultMaxRewriteDepth, "the nesting depth of a rewrite above which a warning is reported")
+
+	// NOTE: if you add a new flag here, update the function below, too
+
+	cmd.PreRun = bindRunFlagsFunc(flags)
+
+	return cmd
+}
+
+func runValidateModel(cmd *cobra.Command, _ []string) error {
+	path := viper.GetString(fileFlag)
+	if path == "" {
+		return fmt.Errorf("missing authorization model file")
+	}
+
+	model, err := ReadModel(path)
+	if err != nil {
+		return err
+	}
+
+	c := commands.NewValidateAuthorizationModelCommand(nil,
+		commands.WithValidateAuthorizationModelLintOptions(typesystem.WithMaxRewriteDepth(viper.GetInt(maxRewriteDepthFlag))),
+	)
+	resp := c.Execute(context.Background(), &openfgav1.WriteAuthorizationModelRequest{
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	})
+
+	marshalled, err := json.MarshalIndent(resp, "", "    ")
+	if err != nil {
+		return fmt.Errorf("error gathering validation results: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(marshalled))
+
+	if !resp.Valid {
+		return errInvalidModel
+	}
+
+	return nil
+}
+
+// ReadModel reads the authorization model from a JSON file if its extension is .json, or from a DSL file.
+func ReadModel(path string) (*openfgav1.AuthorizationModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the authorization model: %w", err)
+	}
+
+	if filepath.Ext(path) == ".json" {
+		var model openfgav1.AuthorizationModel
+		if err := protojson.Unmarshal(data, &model); err != nil {
+			return nil, fmt.Errorf("failed to parse the authorization model: %w", err)
+		}
+
+		return &model, nil
+	}
+
+	model, err := typesystem.ParseDSL(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the authorization model: %w", err)
+	}
+
+	return model, nil
+}
@@ -44,6 +44,21 @@ func (n NoopAuthenticator) Close() {}
 type AuthClaims struct {
 	Subject string
 	Scopes  map[string]bool
+
+	// Issuer is the issuer of the token, if the authentication method has one.
+	Issuer string
+
+	// ClientID is the OAuth client the token was issued to, if known.
+	ClientID string
+}
+
+// Principal returns the identity of the authenticated caller: the subject if set, and the client id otherwise.
+func (c *AuthClaims) Principal() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+
+	return c.ClientID
 }
 
 // ContextWithAuthClaims injects the provided AuthClaims into the parent context.
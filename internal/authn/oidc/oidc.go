@@ -29,34 +29,104 @@ type RemoteOidcAuthenticator struct {
 	JwksURI string
 	JWKs    *keyfunc.JWKS
 
-	httpClient *http.Client
+	// AdditionalIssuers are the issuers trusted on top of IssuerURL, each with its own keys.
+	AdditionalIssuers []string
+
+	// RequiredScopes are the scopes every token must carry.
+	RequiredScopes []string
+
+	// issuerKeys are the keys of every trusted issuer, by issuer URL.
+	issuerKeys map[string]*keyfunc.JWKS
+
+	jwksRefreshInterval time.Duration
+	httpClient          *http.Client
 }
 
-var (
-	jwkRefreshInterval, _ = time.ParseDuration("48h")
+type RemoteOidcAuthenticatorOption func(oidc *RemoteOidcAuthenticator)
+
+// WithAdditionalIssuers trusts the tokens signed by the provided issuers on top of the main issuer, e.g. to accept
+// the tokens of several identity providers.
+func WithAdditionalIssuers(issuerURLs ...string) RemoteOidcAuthenticatorOption {
+	return func(oidc *RemoteOidcAuthenticator) {
+		oidc.AdditionalIssuers = append(oidc.AdditionalIssuers, issuerURLs...)
+	}
+}
+
+// WithRequiredScopes rejects the tokens that do not carry every one of the provided scopes.
+func WithRequiredScopes(scopes ...string) RemoteOidcAuthenticatorOption {
+	return func(oidc *RemoteOidcAuthenticator) {
+		oidc.RequiredScopes = append(oidc.RequiredScopes, scopes...)
+	}
+}
+
+// WithJWKSRefreshInterval sets how often the keys of the issuers are refreshed in the background. The keys are
+// also refreshed when a token is signed with an unknown key, at most once every jwksRefreshRateLimit. A zero
+// interval keeps DefaultJWKSRefreshInterval.
+func WithJWKSRefreshInterval(interval time.Duration) RemoteOidcAuthenticatorOption {
+	return func(oidc *RemoteOidcAuthenticator) {
+		if interval > 0 {
+			oidc.jwksRefreshInterval = interval
+		}
+	}
+}
 
+const (
+	// DefaultJWKSRefreshInterval is the default interval of the background refresh of the keys of the issuers.
+	DefaultJWKSRefreshInterval = 48 * time.Hour
+
+	// jwksRefreshRateLimit is the minimum delay between two refreshes of the keys of an issuer, so that tokens
+	// signed with unknown keys cannot make the server flood the issuer with requests.
+	jwksRefreshRateLimit = 5 * time.Minute
+)
+
+var (
 	errInvalidAudience = status.Error(codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_audience), "invalid audience")
 	errInvalidClaims   = status.Error(codes.Code(openfgav1.AuthErrorCode_invalid_claims), "invalid claims")
 	errInvalidIssuer   = status.Error(codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_issuer), "invalid issuer")
 	errInvalidSubject  = status.Error(codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_subject), "invalid subject")
 	errInvalidToken    = status.Error(codes.Code(openfgav1.AuthErrorCode_auth_failed_invalid_bearer_token), "invalid bearer token")
+	errMissingScope    = status.Error(codes.Code(openfgav1.AuthErrorCode_invalid_claims), "missing required scope")
 )
 
 var _ authn.Authenticator = (*RemoteOidcAuthenticator)(nil)
 var _ authn.OIDCAuthenticator = (*RemoteOidcAuthenticator)(nil)
 
-func NewRemoteOidcAuthenticator(issuerURL, audience string) (*RemoteOidcAuthenticator, error) {
+func NewRemoteOidcAuthenticator(issuerURL, audience string, opts ...RemoteOidcAuthenticatorOption) (*RemoteOidcAuthenticator, error) {
 	client := retryablehttp.NewClient()
 	client.Logger = nil
 	oidc := &RemoteOidcAuthenticator{
-		IssuerURL:  issuerURL,
-		Audience:   audience,
-		httpClient: client.StandardClient(),
+		IssuerURL:           issuerURL,
+		Audience:            audience,
+		issuerKeys:          map[string]*keyfunc.JWKS{},
+		jwksRefreshInterval: DefaultJWKSRefreshInterval,
+		httpClient:          client.StandardClient(),
 	}
+
+	for _, opt := range opts {
+		opt(oidc)
+	}
+
 	err := oidc.fetchKeys()
 	if err != nil {
 		return nil, err
 	}
+
+	oidc.issuerKeys[issuerURL] = oidc.JWKs
+
+	for _, issuer := range oidc.AdditionalIssuers {
+		if _, ok := oidc.issuerKeys[issuer]; ok {
+			continue
+		}
+
+		jwks, err := oidc.fetchIssuerKeys(issuer)
+		if err != nil {
+			oidc.Close()
+			return nil, err
+		}
+
+		oidc.issuerKeys[issuer] = jwks
+	}
+
 	return oidc, nil
 }
 
@@ -68,10 +138,26 @@ func (oidc *RemoteOidcAuthenticator) Authenticate(requestContext context.Context
 
 	jwtParser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
 
+	// the keys are those of the issuer of the token, which is verified again once the token is parsed
 	token, err := jwtParser.Parse(authHeader, func(token *jwt.Token) (any, error) {
-		return oidc.JWKs.Keyfunc(token)
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return nil, errInvalidClaims
+		}
+
+		issuer, _ := claims["iss"].(string)
+		jwks, ok := oidc.issuerKeys[issuer]
+		if !ok {
+			return nil, errInvalidIssuer
+		}
+
+		return jwks.Keyfunc(token)
 	})
 	if err != nil {
+		if errors.Is(err, errInvalidIssuer) {
+			return nil, errInvalidIssuer
+		}
+
 		return nil, errInvalidToken
 	}
 
@@ -84,7 +170,8 @@ func (oidc *RemoteOidcAuthenticator) Authenticate(requestContext context.Context
 		return nil, errInvalidClaims
 	}
 
-	if ok := claims.VerifyIssuer(oidc.IssuerURL, true); !ok {
+	issuer, _ := claims["iss"].(string)
+	if _, ok := oidc.issuerKeys[issuer]; !ok {
 		return nil, errInvalidIssuer
 	}
 
@@ -100,9 +187,20 @@ func (oidc *RemoteOidcAuthenticator) Authenticate(requestContext context.Context
 		}
 	}
 
+	// optional client id, set by the client credentials flow of most providers as 'client_id' or 'azp'
+	var clientID string
+	for _, key := range []string{"client_id", "azp"} {
+		if id, ok := claims[key].(string); ok && id != "" {
+			clientID = id
+			break
+		}
+	}
+
 	principal := &authn.AuthClaims{
-		Subject: subject,
-		Scopes:  make(map[string]bool),
+		Subject:  subject,
+		Issuer:   issuer,
+		ClientID: clientID,
+		Scopes:   make(map[string]bool),
 	}
 
 	// optional scopes
@@ -115,6 +213,12 @@ func (oidc *RemoteOidcAuthenticator) Authenticate(requestContext context.Context
 		}
 	}
 
+	for _, s := range oidc.RequiredScopes {
+		if !principal.Scopes[s] {
+			return nil, errMissingScope
+		}
+	}
+
 	return principal, nil
 }
 
@@ -136,19 +240,44 @@ func (oidc *RemoteOidcAuthenticator) fetchKeys() error {
 	return nil
 }
 
+// fetchIssuerKeys fetches the keys of one of the additional issuers.
+func (oidc *RemoteOidcAuthenticator) fetchIssuerKeys(issuerURL string) (*keyfunc.JWKS, error) {
+	oidcConfig, err := oidc.getConfiguration(issuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OIDC configuration of issuer %v: %w", issuerURL, err)
+	}
+
+	jwks, err := oidc.getKeys(oidcConfig.JWKsURI)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OIDC keys of issuer %v: %w", issuerURL, err)
+	}
+
+	return jwks, nil
+}
+
 func (oidc *RemoteOidcAuthenticator) GetKeys() (*keyfunc.JWKS, error) {
-	jwks, err := keyfunc.Get(oidc.JwksURI, keyfunc.Options{
-		Client:          oidc.httpClient,
-		RefreshInterval: jwkRefreshInterval,
+	return oidc.getKeys(oidc.JwksURI)
+}
+
+func (oidc *RemoteOidcAuthenticator) getKeys(jwksURI string) (*keyfunc.JWKS, error) {
+	jwks, err := keyfunc.Get(jwksURI, keyfunc.Options{
+		Client:            oidc.httpClient,
+		RefreshInterval:   oidc.jwksRefreshInterval,
+		RefreshRateLimit:  jwksRefreshRateLimit,
+		RefreshUnknownKID: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error fetching keys from %v: %w", oidc.JwksURI, err)
+		return nil, fmt.Errorf("error fetching keys from %v: %w", jwksURI, err)
 	}
 	return jwks, nil
 }
 
 func (oidc *RemoteOidcAuthenticator) GetConfiguration() (*authn.OidcConfig, error) {
-	wellKnown := strings.TrimSuffix(oidc.IssuerURL, "/") + "/.well-known/openid-configuration"
+	return oidc.getConfiguration(oidc.IssuerURL)
+}
+
+func (oidc *RemoteOidcAuthenticator) getConfiguration(issuerURL string) (*authn.OidcConfig, error) {
+	wellKnown := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
 	req, err := http.NewRequest("GET", wellKnown, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error forming request to get OIDC: %w", err)
@@ -184,6 +313,30 @@ func (oidc *RemoteOidcAuthenticator) GetConfiguration() (*authn.OidcConfig, erro
 	return oidcConfig, nil
 }
 
+// Ping verifies that the OIDC configuration of the issuer can be fetched. The tokens are verified with the keys
+// fetched beforehand, so they are still authenticated while the issuer cannot be reached.
+func (oidc *RemoteOidcAuthenticator) Ping(ctx context.Context) error {
+	wellKnown := strings.TrimSuffix(oidc.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, "GET", wellKnown, nil)
+	if err != nil {
+		return fmt.Errorf("error forming request to get OIDC: %w", err)
+	}
+
+	res, err := oidc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error getting OIDC: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code getting OIDC: %v", res.StatusCode)
+	}
+
+	return nil
+}
+
 func (oidc *RemoteOidcAuthenticator) Close() {
-	oidc.JWKs.EndBackground()
+	for _, jwks := range oidc.issuerKeys {
+		jwks.EndBackground()
+	}
 }
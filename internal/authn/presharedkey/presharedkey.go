@@ -10,11 +10,24 @@ import (
 
 type PresharedKeyAuthenticator struct {
 	ValidKeys map[string]struct{}
+
+	// KeyScopes are the scopes of the claims of the requests authenticated with each key.
+	KeyScopes map[string][]string
 }
 
 var _ authn.Authenticator = (*PresharedKeyAuthenticator)(nil)
 
-func NewPresharedKeyAuthenticator(validKeys []string) (*PresharedKeyAuthenticator, error) {
+type PresharedKeyAuthenticatorOption func(pka *PresharedKeyAuthenticator)
+
+// WithKeyScopes sets the scopes of the claims of the requests authenticated with the key, e.g. to scope the key
+// to specific stores.
+func WithKeyScopes(key string, scopes []string) PresharedKeyAuthenticatorOption {
+	return func(pka *PresharedKeyAuthenticator) {
+		pka.KeyScopes[key] = scopes
+	}
+}
+
+func NewPresharedKeyAuthenticator(validKeys []string, opts ...PresharedKeyAuthenticatorOption) (*PresharedKeyAuthenticator, error) {
 	if len(validKeys) < 1 {
 		return nil, errors.New("invalid auth configuration, please specify at least one key")
 	}
@@ -23,7 +36,18 @@ func NewPresharedKeyAuthenticator(validKeys []string) (*PresharedKeyAuthenticato
 		vKeys[k] = struct{}{}
 	}
 
-	return &PresharedKeyAuthenticator{ValidKeys: vKeys}, nil
+	pka := &PresharedKeyAuthenticator{ValidKeys: vKeys, KeyScopes: map[string][]string{}}
+	for _, opt := range opts {
+		opt(pka)
+	}
+
+	for k := range pka.KeyScopes {
+		if _, ok := vKeys[k]; !ok {
+			return nil, errors.New("invalid auth configuration, scopes are set for a key that is not one of the preshared keys")
+		}
+	}
+
+	return pka, nil
 }
 
 func (pka *PresharedKeyAuthenticator) Authenticate(ctx context.Context) (*authn.AuthClaims, error) {
@@ -33,9 +57,18 @@ func (pka *PresharedKeyAuthenticator) Authenticate(ctx context.Context) (*authn.
 	}
 
 	if _, found := pka.ValidKeys[authHeader]; found {
-		return &authn.AuthClaims{
+		claims := &authn.AuthClaims{
 			Subject: "", // no user information in this auth method
-		}, nil
+		}
+
+		if scopes, ok := pka.KeyScopes[authHeader]; ok {
+			claims.Scopes = make(map[string]bool, len(scopes))
+			for _, s := range scopes {
+				claims.Scopes[s] = true
+			}
+		}
+
+		return claims, nil
 	}
 
 	return nil, authn.ErrUnauthenticated
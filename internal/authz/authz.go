@@ -0,0 +1,239 @@
+// Package authz authorizes the calls to the OpenFGA API itself, based on the scopes of the authenticated
+// subject. A scope of the form "fga:<role>" grants the role on every store, and a scope of the form
+// "fga:<role>:<store id>" grants it on a single store. The roles are, from least to most privileged, "read",
+// "write" and "admin", and each role includes the privileges of the roles before it.
+package authz
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openfga/openfga/internal/authn"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	// ScopePrefix is the prefix of the scopes that grant access to the API. The other scopes are ignored.
+	ScopePrefix = "fga:"
+
+	// authorizedServicePrefix is the prefix of the full names of the authorized methods. The methods of the other
+	// services, such as the gRPC health checks, are never authorized.
+	authorizedServicePrefix = "/openfga.v1.OpenFGAService/"
+)
+
+// Role is the level of access to a store.
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleRead
+	RoleWrite
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleWrite:
+		return "write"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// ParseRole returns the Role with the provided name.
+func ParseRole(name string) (Role, error) {
+	switch name {
+	case "read":
+		return RoleRead, nil
+	case "write":
+		return RoleWrite, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, fmt.Errorf("unknown role '%s', must be one of 'read', 'write' or 'admin'", name)
+	}
+}
+
+// methodRoles are the roles required by the API methods. The methods that are not listed require the admin
+// role, so that new methods are denied until they are classified.
+var methodRoles = map[string]Role{
+	"Check":                   RoleRead,
+	"Expand":                  RoleRead,
+	"ListObjects":             RoleRead,
+	"StreamedListObjects":     RoleRead,
+	"Read":                    RoleRead,
+	"StreamedRead":            RoleRead,
+	"ReadChanges":             RoleRead,
+	"ReadAuthorizationModel":  RoleRead,
+	"ReadAuthorizationModels": RoleRead,
+	"ReadAssertions":          RoleRead,
+	"GetStore":                RoleRead,
+	"ListStores":              RoleRead,
+	"Write":                   RoleWrite,
+	"WriteAssertions":         RoleWrite,
+	"WriteAuthorizationModel": RoleWrite,
+	"CreateStore":             RoleAdmin,
+	"DeleteStore":             RoleAdmin,
+}
+
+// MethodRole returns the role required to call the API method with the provided name (e.g. "Check").
+func MethodRole(method string) Role {
+	if role, ok := methodRoles[method]; ok {
+		return role
+	}
+
+	return RoleAdmin
+}
+
+// Grants are the roles granted to a subject, by store id. The roles granted on every store are under the "*" key.
+type Grants map[string]Role
+
+const allStores = "*"
+
+// ParseScope parses a scope of the form "fga:<role>" or "fga:<role>:<store id>". It returns ok false if the
+// scope does not start with ScopePrefix, and an error if it does but is malformed.
+func ParseScope(scope string) (storeID string, role Role, ok bool, err error) {
+	rest, found := strings.CutPrefix(scope, ScopePrefix)
+	if !found {
+		return "", RoleNone, false, nil
+	}
+
+	roleName, storeID, scoped := strings.Cut(rest, ":")
+	role, err = ParseRole(roleName)
+	if err != nil {
+		return "", RoleNone, true, fmt.Errorf("invalid scope '%s': %w", scope, err)
+	}
+
+	if !scoped {
+		return allStores, role, true, nil
+	}
+
+	if storeID == "" {
+		return "", RoleNone, true, fmt.Errorf("invalid scope '%s': missing store id", scope)
+	}
+
+	return storeID, role, true, nil
+}
+
+// GrantsFromScopes returns the roles granted by the scopes. Malformed scopes grant nothing.
+func GrantsFromScopes(scopes map[string]bool) Grants {
+	grants := Grants{}
+	for scope, granted := range scopes {
+		if !granted {
+			continue
+		}
+
+		storeID, role, ok, err := ParseScope(scope)
+		if !ok || err != nil {
+			continue
+		}
+
+		if role > grants[storeID] {
+			grants[storeID] = role
+		}
+	}
+
+	return grants
+}
+
+// Role returns the role granted on the store with the provided id. If the id is empty, as for the methods that
+// are not scoped to a store (e.g. CreateStore or ListStores), only the roles granted on every store count.
+func (g Grants) Role(storeID string) Role {
+	role := g[allStores]
+	if storeID != "" && g[storeID] > role {
+		role = g[storeID]
+	}
+
+	return role
+}
+
+// Authorize returns serverErrors.PermissionDenied if the authenticated subject of the context is not allowed to
+// call the API method with the provided full name on the store with the provided id.
+func Authorize(ctx context.Context, fullMethod, storeID string) error {
+	if !strings.HasPrefix(fullMethod, authorizedServicePrefix) {
+		return nil
+	}
+
+	var scopes map[string]bool
+	if claims, ok := authn.AuthClaimsFromContext(ctx); ok {
+		scopes = claims.Scopes
+	}
+
+	method := path.Base(fullMethod)
+	required := MethodRole(method)
+	if GrantsFromScopes(scopes).Role(storeID) >= required {
+		return nil
+	}
+
+	if storeID == "" {
+		return serverErrors.PermissionDenied(fmt.Sprintf("the '%s' role on every store is required to call %s", required, method))
+	}
+
+	return serverErrors.PermissionDenied(fmt.Sprintf("the '%s' role on store '%s' is required to call %s", required, storeID, method))
+}
+
+type hasGetStoreID interface {
+	GetStoreId() string
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which rejects the requests whose authenticated
+// subject is not allowed to call the API method on the store of the request. It must run after the
+// authentication interceptor.
+func NewUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var storeID string
+		if r, ok := req.(hasGetStoreID); ok {
+			storeID = r.GetStoreId()
+		}
+
+		if err := Authorize(ctx, info.FullMethod, storeID); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which rejects the requests whose authenticated
+// subject is not allowed to call the API method on the store of the request. Since the store is only known once
+// the request message is received, the request is authorized when the handler receives its first message.
+func NewStreamingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, authorizedServicePrefix) {
+			return handler(srv, stream)
+		}
+
+		return handler(srv, &authorizedServerStream{ServerStream: stream, fullMethod: info.FullMethod})
+	}
+}
+
+type authorizedServerStream struct {
+	grpc.ServerStream
+	fullMethod string
+	received   bool
+}
+
+func (s *authorizedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.received {
+		return nil
+	}
+	s.received = true
+
+	var storeID string
+	if r, ok := m.(hasGetStoreID); ok {
+		storeID = r.GetStoreId()
+	}
+
+	return Authorize(s.ServerStream.Context(), s.fullMethod, storeID)
+}
@@ -0,0 +1,156 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func requirePermissionDenied(t *testing.T, err error) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(serverErrors.PermissionDeniedErrorCode), st.Code())
+}
+
+func contextWithScopes(scopes ...string) context.Context {
+	claims := &authn.AuthClaims{Scopes: map[string]bool{}}
+	for _, s := range scopes {
+		claims.Scopes[s] = true
+	}
+
+	return authn.ContextWithAuthClaims(context.Background(), claims)
+}
+
+func TestParseScope(t *testing.T) {
+	storeID, role, ok, err := ParseScope("fga:write:store-a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "store-a", storeID)
+	require.Equal(t, RoleWrite, role)
+
+	storeID, role, ok, err = ParseScope("fga:admin")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "*", storeID)
+	require.Equal(t, RoleAdmin, role)
+
+	_, _, ok, err = ParseScope("openid")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, ok, err = ParseScope("fga:owner")
+	require.ErrorContains(t, err, "unknown role 'owner'")
+	require.True(t, ok)
+
+	_, _, _, err = ParseScope("fga:read:")
+	require.ErrorContains(t, err, "missing store id")
+}
+
+func TestAuthorize(t *testing.T) {
+	const (
+		check       = "/openfga.v1.OpenFGAService/Check"
+		write       = "/openfga.v1.OpenFGAService/Write"
+		createStore = "/openfga.v1.OpenFGAService/CreateStore"
+		listStores  = "/openfga.v1.OpenFGAService/ListStores"
+	)
+
+	t.Run("roles_include_the_less_privileged_roles", func(t *testing.T) {
+		ctx := contextWithScopes("fga:write:store-a")
+
+		require.NoError(t, Authorize(ctx, check, "store-a"))
+		require.NoError(t, Authorize(ctx, write, "store-a"))
+		requirePermissionDenied(t, Authorize(ctx, "/openfga.v1.OpenFGAService/DeleteStore", "store-a"))
+	})
+
+	t.Run("store_scopes_only_grant_access_to_their_store", func(t *testing.T) {
+		ctx := contextWithScopes("fga:admin:store-a")
+
+		require.NoError(t, Authorize(ctx, write, "store-a"))
+		requirePermissionDenied(t, Authorize(ctx, check, "store-b"))
+
+		// the methods that are not scoped to a store require a role on every store
+		requirePermissionDenied(t, Authorize(ctx, listStores, ""))
+		requirePermissionDenied(t, Authorize(ctx, createStore, ""))
+	})
+
+	t.Run("scopes_on_every_store_combine_with_store_scopes", func(t *testing.T) {
+		ctx := contextWithScopes("fga:read", "fga:write:store-a", "openid")
+
+		require.NoError(t, Authorize(ctx, listStores, ""))
+		require.NoError(t, Authorize(ctx, check, "store-b"))
+		require.NoError(t, Authorize(ctx, write, "store-a"))
+		requirePermissionDenied(t, Authorize(ctx, write, "store-b"))
+		requirePermissionDenied(t, Authorize(ctx, createStore, ""))
+	})
+
+	t.Run("credentials_without_scopes_are_denied", func(t *testing.T) {
+		requirePermissionDenied(t, Authorize(contextWithScopes(), check, "store-a"))
+		requirePermissionDenied(t, Authorize(context.Background(), check, "store-a"))
+		requirePermissionDenied(t, Authorize(contextWithScopes("fga:read:"), check, ""))
+
+		// the methods of the other services are never authorized
+		require.NoError(t, Authorize(context.Background(), "/grpc.health.v1.Health/Check", ""))
+	})
+
+	t.Run("unknown_methods_require_the_admin_role", func(t *testing.T) {
+		requirePermissionDenied(t, Authorize(contextWithScopes("fga:write"), "/openfga.v1.OpenFGAService/NewMethod", "store-a"))
+		require.NoError(t, Authorize(contextWithScopes("fga:admin"), "/openfga.v1.OpenFGAService/NewMethod", "store-a"))
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Write"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	ctx := contextWithScopes("fga:write:store-a", "fga:read:store-b")
+
+	_, err := interceptor(ctx, &openfgav1.WriteRequest{StoreId: "store-a"}, info, handler)
+	require.NoError(t, err)
+
+	_, err = interceptor(ctx, &openfgav1.WriteRequest{StoreId: "store-b"}, info, handler)
+	requirePermissionDenied(t, err)
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *openfgav1.StreamedListObjectsRequest
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockServerStream) RecvMsg(m interface{}) error {
+	m.(*openfgav1.StreamedListObjectsRequest).StoreId = s.req.GetStoreId()
+	return nil
+}
+
+func TestStreamingInterceptor(t *testing.T) {
+	interceptor := NewStreamingInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/openfga.v1.OpenFGAService/StreamedListObjects"}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg(&openfgav1.StreamedListObjectsRequest{})
+	}
+
+	ctx := contextWithScopes("fga:read:store-a")
+
+	stream := &mockServerStream{ctx: ctx, req: &openfgav1.StreamedListObjectsRequest{StoreId: "store-a"}}
+	require.NoError(t, interceptor(nil, stream, info, handler))
+
+	stream = &mockServerStream{ctx: ctx, req: &openfgav1.StreamedListObjectsRequest{StoreId: "store-b"}}
+	requirePermissionDenied(t, interceptor(nil, stream, info, handler))
+}
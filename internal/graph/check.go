@@ -11,8 +11,11 @@ import (
 	"github.com/openfga/openfga/internal/validation"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/telemetry"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -20,6 +23,11 @@ import (
 
 var tracer = otel.Tracer("internal/graph/check")
 
+var checkResolversInFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "check_resolvers_in_flight",
+	Help: "Number of goroutines resolving Check subproblems concurrently, across all the queries. The fan-out of a subproblem is bounded by the resolve node breadth limit",
+})
+
 const (
 	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
 	defaultResolveNodeBreadthLimit    = 25
@@ -38,10 +46,69 @@ type ResolveCheckRequest struct {
 	TupleKey             *openfgav1.TupleKey
 	ContextualTuples     []*openfgav1.TupleKey
 	ResolutionMetadata   *ResolutionMetadata
+
+	// VisitedPaths are the tuple keys (see tuple.TupleKeyToString) of the subproblems the request was dispatched
+	// from, which the request must not reach again.
+	VisitedPaths map[string]struct{}
+
+	// Explain requests the explanation of an allowed outcome, see ResolveCheckResponse. Explained
+	// requests bypass the check cache.
+	Explain bool
 }
 
 type ResolveCheckResponse struct {
 	Allowed bool
+
+	// Explanation is the resolution path that led to an allowed outcome. It is only set if the request
+	// asked for it.
+	Explanation *CheckExplanation
+}
+
+func (r *ResolveCheckResponse) GetAllowed() bool {
+	if r != nil {
+		return r.Allowed
+	}
+
+	return false
+}
+
+// The operations of a CheckExplanation.
+const (
+	ExplanationDirect          = "direct"
+	ExplanationComputedUserset = "computed_userset"
+	ExplanationTupleToUserset  = "tuple_to_userset"
+	ExplanationUnion           = "union"
+	ExplanationIntersection    = "intersection"
+	ExplanationExclusion       = "exclusion"
+)
+
+// CheckExplanation is a node of the resolution path of an allowed Check: it explains why the user of
+// TupleKey has the relation of TupleKey with its object.
+type CheckExplanation struct {
+	TupleKey *openfgav1.TupleKey
+
+	// Operation is the rewrite that was resolved, one of the Explanation* constants.
+	Operation string
+
+	// Tuple is the tuple the node relies on, if any: the tuple that assigns the user (or a userset or a
+	// wildcard including the user) directly, or the tupleset tuple of a tuple to userset rewrite.
+	Tuple *openfgav1.TupleKey
+
+	// Contextual reports whether Tuple is one of the contextual tuples of the request.
+	Contextual bool
+
+	// Children explain the relations the node relies on: the relation of a userset or of the object of a
+	// tupleset tuple, the computed relation, or the operands of a set operation that made it allowed (for
+	// an exclusion, only the base).
+	Children []*CheckExplanation
+}
+
+func (e *CheckExplanation) GetChildren() []*CheckExplanation {
+	if e != nil {
+		return e.Children
+	}
+
+	return nil
 }
 
 func (r *ResolveCheckRequest) GetStoreID() string {
@@ -84,6 +151,33 @@ func (r *ResolveCheckRequest) GetResolutionMetadata() *ResolutionMetadata {
 	return nil
 }
 
+func (r *ResolveCheckRequest) GetVisitedPaths() map[string]struct{} {
+	if r != nil {
+		return r.VisitedPaths
+	}
+
+	return nil
+}
+
+// visitedPathsOf returns the visited paths of the subproblems dispatched by the request.
+func visitedPathsOf(req *ResolveCheckRequest) map[string]struct{} {
+	visited := make(map[string]struct{}, len(req.GetVisitedPaths())+1)
+	for key := range req.GetVisitedPaths() {
+		visited[key] = struct{}{}
+	}
+	visited[tuple.TupleKeyToString(req.GetTupleKey())] = struct{}{}
+
+	return visited
+}
+
+func (r *ResolveCheckRequest) GetExplain() bool {
+	if r != nil {
+		return r.Explain
+	}
+
+	return false
+}
+
 type setOperatorType int
 
 const (
@@ -93,7 +187,7 @@ const (
 )
 
 type checkOutcome struct {
-	resp *openfgav1.CheckResponse
+	resp *ResolveCheckResponse
 	err  error
 }
 
@@ -101,6 +195,13 @@ type LocalChecker struct {
 	ds                 storage.RelationshipTupleReader
 	concurrencyLimit   uint32
 	maxConcurrentReads uint32
+	cache              *CheckCache
+	maxDispatches      uint32
+	maxDatastoreReads  uint32
+
+	// cacheGenerations memoizes the store generations looked up in the cache, so that a
+	// resolution does not observe an invalidation halfway through.
+	cacheGenerations sync.Map
 }
 
 type LocalCheckerOption func(d *LocalChecker)
@@ -119,6 +220,30 @@ func WithMaxConcurrentReads(limit uint32) LocalCheckerOption {
 	}
 }
 
+// WithCheckCache makes the LocalChecker look up and store the outcome of every Check subproblem
+// in the provided cache.
+func WithCheckCache(cache *CheckCache) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.cache = cache
+	}
+}
+
+// WithMaxDispatchCount limits the number of subproblems a single Check request can dispatch. A request that
+// exceeds it is aborted with ErrDispatchLimitExceeded. A limit of 0 means unlimited.
+func WithMaxDispatchCount(max uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.maxDispatches = max
+	}
+}
+
+// WithMaxDatastoreReadsPerCheck limits the number of datastore reads a single Check request can issue. A
+// request that exceeds it is aborted with ErrDatastoreReadLimitExceeded. A limit of 0 means unlimited.
+func WithMaxDatastoreReadsPerCheck(max uint32) LocalCheckerOption {
+	return func(d *LocalChecker) {
+		d.maxDatastoreReads = max
+	}
+}
+
 // NewLocalChecker constructs a LocalChecker that can be used to evaluate a Check
 // request locally.
 func NewLocalChecker(ds storage.RelationshipTupleReader, opts ...LocalCheckerOption) *LocalChecker {
@@ -132,19 +257,19 @@ func NewLocalChecker(ds storage.RelationshipTupleReader, opts ...LocalCheckerOpt
 		opt(checker)
 	}
 
-	checker.ds = storagewrappers.NewBoundedConcurrencyTupleReader(checker.ds, checker.maxConcurrentReads)
+	checker.ds = newBudgetTupleReader(NewStatsTupleReader(storagewrappers.NewTracedTupleReader(storagewrappers.NewBoundedConcurrencyTupleReader(checker.ds, checker.maxConcurrentReads))))
 
 	return checker
 }
 
 // CheckHandlerFunc defines a function that evaluates a CheckResponse or returns an error
 // otherwise.
-type CheckHandlerFunc func(ctx context.Context) (*openfgav1.CheckResponse, error)
+type CheckHandlerFunc func(ctx context.Context) (*ResolveCheckResponse, error)
 
 // CheckFuncReducer defines a function that combines or reduces one or more CheckHandlerFunc into
 // a single CheckResponse with a maximum limit on the number of concurrent evaluations that can be
 // in flight at any given time.
-type CheckFuncReducer func(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*openfgav1.CheckResponse, error)
+type CheckFuncReducer func(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error)
 
 // resolver concurrently resolves one or more CheckHandlerFunc and yields the results on the provided resultChan.
 // Callers of the 'resolver' function should be sure to invoke the callback returned from this function to ensure
@@ -161,7 +286,10 @@ func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- ch
 		resolved := make(chan checkOutcome, 1)
 
 		go func() {
+			checkResolversInFlightGauge.Inc()
 			resp, err := fn(ctx)
+			checkResolversInFlightGauge.Dec()
+
 			resolved <- checkOutcome{resp, err}
 			<-limiter
 		}()
@@ -200,7 +328,7 @@ func resolver(ctx context.Context, concurrencyLimit uint32, resultChan chan<- ch
 
 // union implements a CheckFuncReducer that requires any of the provided CheckHandlerFunc to resolve
 // to an allowed outcome. The first allowed outcome causes premature termination of the reducer.
-func union(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*openfgav1.CheckResponse, error) {
+func union(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 	resultChan := make(chan checkOutcome, len(handlers))
@@ -230,12 +358,12 @@ func union(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandle
 		}
 	}
 
-	return &openfgav1.CheckResponse{Allowed: false}, err
+	return &ResolveCheckResponse{Allowed: false}, err
 }
 
 // intersection implements a CheckFuncReducer that requires all of the provided CheckHandlerFunc to resolve
 // to an allowed outcome. The first falsey or erroneous outcome causes premature termination of the reducer.
-func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*openfgav1.CheckResponse, error) {
+func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
 
 	ctx, cancel := context.WithCancel(ctx)
 	resultChan := make(chan checkOutcome, len(handlers))
@@ -249,6 +377,7 @@ func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...Chec
 	}()
 
 	var err error
+	var explanations []*CheckExplanation
 	for i := 0; i < len(handlers); i++ {
 		select {
 		case result := <-resultChan:
@@ -260,22 +389,24 @@ func intersection(ctx context.Context, concurrencyLimit uint32, handlers ...Chec
 			if !result.resp.GetAllowed() {
 				return result.resp, nil
 			}
+
+			explanations = append(explanations, result.resp.Explanation)
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
 
 	if err != nil {
-		return &openfgav1.CheckResponse{Allowed: false}, err
+		return &ResolveCheckResponse{Allowed: false}, err
 	}
 
-	return &openfgav1.CheckResponse{Allowed: true}, nil
+	return &ResolveCheckResponse{Allowed: true, Explanation: explanationOf(explanations...)}, nil
 }
 
 // exclusion implements a CheckFuncReducer that requires a 'base' CheckHandlerFunc to resolve to an allowed
 // outcome and a 'sub' CheckHandlerFunc to resolve to a falsey outcome. The base and sub computations are
 // handled concurrently relative to one another.
-func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*openfgav1.CheckResponse, error) {
+func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHandlerFunc) (*ResolveCheckResponse, error) {
 
 	if len(handlers) != 2 {
 		panic(fmt.Sprintf("expected two rewrite operands for exclusion operator, but got '%d'", len(handlers)))
@@ -302,6 +433,9 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 	limiter <- struct{}{}
 	wg.Add(1)
 	go func() {
+		checkResolversInFlightGauge.Inc()
+		defer checkResolversInFlightGauge.Dec()
+
 		resp, err := baseHandler(ctx)
 		baseChan <- checkOutcome{resp, err}
 		<-limiter
@@ -311,55 +445,129 @@ func exclusion(ctx context.Context, concurrencyLimit uint32, handlers ...CheckHa
 	limiter <- struct{}{}
 	wg.Add(1)
 	go func() {
+		checkResolversInFlightGauge.Inc()
+		defer checkResolversInFlightGauge.Dec()
+
 		resp, err := subHandler(ctx)
 		subChan <- checkOutcome{resp, err}
 		<-limiter
 		wg.Done()
 	}()
 
+	var baseExplanation *CheckExplanation
 	for i := 0; i < len(handlers); i++ {
 		select {
 		case baseResult := <-baseChan:
 			if baseResult.err != nil {
-				return &openfgav1.CheckResponse{Allowed: false}, baseResult.err
+				return &ResolveCheckResponse{Allowed: false}, baseResult.err
 			}
 
 			if !baseResult.resp.Allowed {
-				return &openfgav1.CheckResponse{Allowed: false}, nil
+				return &ResolveCheckResponse{Allowed: false}, nil
 			}
+
+			baseExplanation = baseResult.resp.Explanation
 		case subResult := <-subChan:
 			if subResult.err != nil {
-				return &openfgav1.CheckResponse{Allowed: false}, subResult.err
+				return &ResolveCheckResponse{Allowed: false}, subResult.err
 			}
 
 			if subResult.resp.Allowed {
-				return &openfgav1.CheckResponse{Allowed: false}, nil
+				return &ResolveCheckResponse{Allowed: false}, nil
 			}
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		}
 	}
 
-	return &openfgav1.CheckResponse{Allowed: true}, nil
+	return &ResolveCheckResponse{Allowed: true, Explanation: explanationOf(baseExplanation)}, nil
+}
+
+// explanationOf returns an explanation (to be completed by the caller) whose children are the given
+// explanations, or nil if none of them is set.
+func explanationOf(explanations ...*CheckExplanation) *CheckExplanation {
+	var children []*CheckExplanation
+	for _, explanation := range explanations {
+		if explanation != nil {
+			children = append(children, explanation)
+		}
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+
+	return &CheckExplanation{Children: children}
+}
+
+// explain wraps the handler so that the explanation of an allowed outcome is nested under a copy of the
+// given node. If the request does not ask for an explanation, the handler is returned as is.
+func explain(req *ResolveCheckRequest, node *CheckExplanation, handler CheckHandlerFunc) CheckHandlerFunc {
+	if !req.GetExplain() {
+		return handler
+	}
+
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
+		resp, err := handler(ctx)
+		if err != nil || !resp.GetAllowed() {
+			return resp, err
+		}
+
+		explained := *node
+		if resp.Explanation != nil {
+			explained.Children = []*CheckExplanation{resp.Explanation}
+		}
+
+		return &ResolveCheckResponse{Allowed: true, Explanation: &explained}, nil
+	}
+}
+
+// newExplanation returns the explanation of a leaf of the resolution path, or nil if the request does not
+// ask for an explanation.
+func newExplanation(req *ResolveCheckRequest, operation string, tuple *openfgav1.TupleKey) *CheckExplanation {
+	if !req.GetExplain() {
+		return nil
+	}
+
+	return &CheckExplanation{
+		TupleKey:  req.GetTupleKey(),
+		Operation: operation,
+		Tuple:     tuple,
+	}
+}
+
+// markContextualTuples sets CheckExplanation.Contextual on the nodes of the explanation that rely on one of
+// the contextual tuples.
+func markContextualTuples(explanation *CheckExplanation, contextualTuples map[string]struct{}) {
+	if explanation == nil {
+		return
+	}
+
+	if explanation.Tuple != nil {
+		_, explanation.Contextual = contextualTuples[tuple.TupleKeyToString(explanation.Tuple)]
+	}
+
+	for _, child := range explanation.Children {
+		markContextualTuples(child, contextualTuples)
+	}
 }
 
 // dispatch dispatches the provided Check request to the CheckResolver this LocalChecker
 // was constructed with.
 func (c *LocalChecker) dispatch(ctx context.Context, req *ResolveCheckRequest) CheckHandlerFunc {
-	return func(ctx context.Context) (*openfgav1.CheckResponse, error) {
-		resp, err := c.ResolveCheck(ctx, req)
-		if err != nil {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
+		if err := checkBudgetFromContext(ctx).dispatch(); err != nil {
 			return nil, err
 		}
 
-		return &openfgav1.CheckResponse{
-			Allowed: resp.Allowed,
-		}, nil
+		ResolutionStatsFromContext(ctx).addDispatch()
+		return c.ResolveCheck(ctx, req)
 	}
 }
 
 // ResolveCheck resolves a node out of a tree of evaluations. If the depth of the tree has gotten too large,
-// evaluation is aborted and an error is returned. The depth is NOT increased on computed usersets.
+// evaluation is aborted and an error is returned. The depth is NOT increased on computed usersets. If the node is
+// one of the nodes it was dispatched from, evaluation is aborted with ErrCycleDetected.
 func (c *LocalChecker) ResolveCheck(
 	ctx context.Context,
 	req *ResolveCheckRequest,
@@ -373,6 +581,30 @@ func (c *LocalChecker) ResolveCheck(
 		return nil, ErrResolutionDepthExceeded
 	}
 
+	if _, ok := req.GetVisitedPaths()[tuple.TupleKeyToString(req.GetTupleKey())]; ok {
+		span.SetAttributes(attribute.Bool("cycle_detected", true))
+		return nil, ErrCycleDetected
+	}
+
+	if (c.maxDispatches > 0 || c.maxDatastoreReads > 0) && checkBudgetFromContext(ctx) == nil {
+		ctx = contextWithCheckBudget(ctx, &checkBudget{maxDispatches: c.maxDispatches, maxReads: c.maxDatastoreReads})
+	}
+
+	// the explained resolutions are not memoized, since the memo does not hold the explanations of the outcomes
+	memo := checkMemoFromContext(ctx)
+	if memo == nil && !req.GetExplain() {
+		memo = &checkMemo{}
+		ctx = contextWithCheckMemo(ctx, memo)
+	}
+
+	if allowed, ok := memo.get(req); ok {
+		span.SetAttributes(attribute.Bool("memoized", true), attribute.Bool("allowed", allowed))
+		return &ResolveCheckResponse{Allowed: allowed}, nil
+	}
+
+	stats := ResolutionStatsFromContext(ctx)
+	stats.observeDepth(req.GetResolutionMetadata().Depth)
+
 	typesys, ok := typesystem.TypesystemFromContext(ctx)
 	if !ok {
 		panic("typesystem missing in context")
@@ -382,28 +614,89 @@ func (c *LocalChecker) ResolveCheck(
 	relation := req.GetTupleKey().GetRelation()
 
 	objectType, _ := tuple.SplitObject(object)
+	span.SetAttributes(
+		attribute.String("object_type", objectType),
+		attribute.String("relation", relation),
+		attribute.Int("remaining_depth", int(req.GetResolutionMetadata().Depth)),
+	)
+
 	rel, err := typesys.GetRelation(objectType, relation)
 	if err != nil {
 		return nil, fmt.Errorf("relation '%s' undefined for object type '%s'", relation, objectType)
 	}
 
+	var cacheKey string
+	if c.cache != nil && !req.GetExplain() {
+		// the expirations of the tuples read by the resolution bound the lifetime of the outcomes it caches. They
+		// are recorded for the whole resolution, which only ever shortens the lifetime of the outcome of a
+		// subproblem.
+		if storage.TupleExpirationsFromContext(ctx) == nil {
+			ctx = storage.ContextWithTupleExpirations(ctx, &storage.TupleExpirations{})
+		}
+
+		if generation, err := c.cacheGeneration(ctx, req.GetStoreID()); err == nil {
+			cacheKey = c.cache.key(ctx, req, generation)
+			allowed, ok := c.cache.get(ctx, cacheKey)
+			stats.addCacheLookup(ok)
+			if ok {
+				span.SetAttributes(attribute.Bool("cached", true), attribute.Bool("allowed", allowed))
+				return &ResolveCheckResponse{Allowed: allowed}, nil
+			}
+		}
+	}
+
 	resp, err := union(ctx, c.concurrencyLimit, c.checkRewrite(ctx, req, rel.GetRewrite()))
 	if err != nil {
+		telemetry.TraceError(span, err)
 		return nil, err
 	}
+	span.SetAttributes(attribute.Bool("allowed", resp.Allowed))
+
+	if cacheKey != "" {
+		c.cache.set(ctx, cacheKey, resp.Allowed)
+	}
+
+	memo.set(req, resp.Allowed)
+
+	if resp.Explanation != nil && len(req.GetContextualTuples()) > 0 {
+		contextualTuples := make(map[string]struct{}, len(req.GetContextualTuples()))
+		for _, tk := range req.GetContextualTuples() {
+			contextualTuples[tuple.TupleKeyToString(tk)] = struct{}{}
+		}
+
+		markContextualTuples(resp.Explanation, contextualTuples)
+	}
 
 	return &ResolveCheckResponse{
-		Allowed: resp.Allowed,
+		Allowed:     resp.Allowed,
+		Explanation: resp.Explanation,
 	}, nil
 }
 
+// cacheGeneration returns the generation of the store in the check cache. It is only looked up
+// once per store for the lifetime of the LocalChecker.
+func (c *LocalChecker) cacheGeneration(ctx context.Context, storeID string) (string, error) {
+	if generation, ok := c.cacheGenerations.Load(storeID); ok {
+		return generation.(string), nil
+	}
+
+	generation, err := c.cache.generation(ctx, storeID)
+	if err != nil {
+		return "", err
+	}
+
+	actual, _ := c.cacheGenerations.LoadOrStore(storeID, generation)
+
+	return actual.(string), nil
+}
+
 // checkDirect composes two CheckHandlerFunc which evaluate direct relationships with the provided
 // 'object#relation'. The first handler looks up direct matches on the provided 'object#relation@user',
 // while the second handler looks up relationships between the target 'object#relation' and any usersets
 // related to it.
 func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckRequest) CheckHandlerFunc {
 
-	return func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
 		typesys, ok := typesystem.TypesystemFromContext(parentctx) // note: use of 'parentctx' not 'ctx' - this is important
 		if !ok {
 			return nil, fmt.Errorf("typesystem missing in context")
@@ -417,17 +710,17 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 		objectType := tuple.GetType(tk.GetObject())
 		relation := tk.GetRelation()
 
-		fn1 := func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+		fn1 := func(ctx context.Context) (*ResolveCheckResponse, error) {
 			ctx, span := tracer.Start(ctx, "checkDirectUserTuple", trace.WithAttributes(attribute.String("tuple_key", tk.String())))
 			defer span.End()
 
 			t, err := c.ds.ReadUserTuple(ctx, storeID, tk)
 			if err != nil {
 				if errors.Is(err, storage.ErrNotFound) {
-					return &openfgav1.CheckResponse{Allowed: false}, nil
+					return &ResolveCheckResponse{Allowed: false}, nil
 				}
 
-				return &openfgav1.CheckResponse{Allowed: false}, err
+				return &ResolveCheckResponse{Allowed: false}, err
 			}
 
 			// filter out invalid tuples yielded by the database query
@@ -435,9 +728,9 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 
 			if t != nil && err == nil {
 				span.SetAttributes(attribute.Bool("allowed", true))
-				return &openfgav1.CheckResponse{Allowed: true}, nil
+				return &ResolveCheckResponse{Allowed: true, Explanation: newExplanation(req, ExplanationDirect, t.GetKey())}, nil
 			}
-			return &openfgav1.CheckResponse{Allowed: false}, nil
+			return &ResolveCheckResponse{Allowed: false}, nil
 		}
 
 		var checkFuncs []CheckHandlerFunc
@@ -455,7 +748,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 			}
 		}
 
-		fn2 := func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+		fn2 := func(ctx context.Context) (*ResolveCheckResponse, error) {
 			ctx, span := tracer.Start(ctx, "checkDirectUsersetTuples", trace.WithAttributes(attribute.String("userset", tuple.ToObjectRelationString(tk.Object, tk.Relation))))
 			defer span.End()
 
@@ -471,7 +764,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 				AllowedUserTypeRestrictions: allowedUserTypeRestrictions,
 			})
 			if err != nil {
-				return &openfgav1.CheckResponse{Allowed: false}, err
+				return &ResolveCheckResponse{Allowed: false}, err
 			}
 			defer iter.Stop()
 
@@ -490,7 +783,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 						break
 					}
 
-					return &openfgav1.CheckResponse{Allowed: false}, err
+					return &ResolveCheckResponse{Allowed: false}, err
 				}
 
 				usersetObject, usersetRelation := tuple.SplitObjectRelation(t.GetUser())
@@ -498,7 +791,7 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 				// for 1.0 models, if the user is '*' then we're done searching
 				if usersetObject == tuple.Wildcard && typesys.GetSchemaVersion() == typesystem.SchemaVersion1_0 {
 					span.SetAttributes(attribute.Bool("allowed", true))
-					return &openfgav1.CheckResponse{Allowed: true}, nil
+					return &ResolveCheckResponse{Allowed: true, Explanation: newExplanation(req, ExplanationDirect, t)}, nil
 				}
 
 				// for 1.1 models, if the user value is a typed wildcard and the type of the wildcard
@@ -509,14 +802,14 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 
 					if tuple.GetType(tk.GetUser()) == wildcardType {
 						span.SetAttributes(attribute.Bool("allowed", true))
-						return &openfgav1.CheckResponse{Allowed: true}, nil
+						return &ResolveCheckResponse{Allowed: true, Explanation: newExplanation(req, ExplanationDirect, t)}, nil
 					}
 
 					continue
 				}
 
 				if usersetRelation != "" {
-					handlers = append(handlers, c.dispatch(
+					handlers = append(handlers, explain(req, newExplanation(req, ExplanationDirect, t), c.dispatch(
 						ctx,
 						&ResolveCheckRequest{
 							StoreID:              storeID,
@@ -525,12 +818,15 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 							ResolutionMetadata: &ResolutionMetadata{
 								Depth: req.GetResolutionMetadata().Depth - 1,
 							},
-						}))
+							VisitedPaths:     visitedPathsOf(req),
+							Explain:          req.GetExplain(),
+							ContextualTuples: req.GetContextualTuples(),
+						})))
 				}
 			}
 
 			if len(handlers) == 0 {
-				return &openfgav1.CheckResponse{Allowed: false}, nil
+				return &ResolveCheckResponse{Allowed: false}, nil
 
 			}
 
@@ -545,11 +841,11 @@ func (c *LocalChecker) checkDirect(parentctx context.Context, req *ResolveCheckR
 
 // checkComputedUserset evaluates the Check request with the rewritten relation (e.g. the computed userset relation).
 func (c *LocalChecker) checkComputedUserset(parentctx context.Context, req *ResolveCheckRequest, rewrite *openfgav1.Userset_ComputedUserset) CheckHandlerFunc {
-	return func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
 		ctx, span := tracer.Start(ctx, "checkComputedUserset")
 		defer span.End()
 
-		return c.dispatch(
+		return explain(req, newExplanation(req, ExplanationComputedUserset, nil), c.dispatch(
 			ctx,
 			&ResolveCheckRequest{
 				StoreID:              req.GetStoreID(),
@@ -562,7 +858,10 @@ func (c *LocalChecker) checkComputedUserset(parentctx context.Context, req *Reso
 				ResolutionMetadata: &ResolutionMetadata{
 					Depth: req.ResolutionMetadata.Depth - 1,
 				},
-			})(ctx)
+				VisitedPaths:     visitedPathsOf(req),
+				Explain:          req.GetExplain(),
+				ContextualTuples: req.GetContextualTuples(),
+			}))(ctx)
 	}
 }
 
@@ -570,7 +869,7 @@ func (c *LocalChecker) checkComputedUserset(parentctx context.Context, req *Reso
 // of them evaluates the computed userset of the TTU rewrite rule for them.
 func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequest, rewrite *openfgav1.Userset) CheckHandlerFunc {
 
-	return func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
 		typesys, ok := typesystem.TypesystemFromContext(parentctx) // note: use of 'parentctx' not 'ctx' - this is important
 		if !ok {
 			return nil, fmt.Errorf("typesystem missing in context")
@@ -596,7 +895,7 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 			tuple.NewTupleKey(object, tuplesetRelation, ""),
 		)
 		if err != nil {
-			return &openfgav1.CheckResponse{Allowed: false}, err
+			return &ResolveCheckResponse{Allowed: false}, err
 		}
 		defer iter.Stop()
 
@@ -615,7 +914,7 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 					break
 				}
 
-				return &openfgav1.CheckResponse{Allowed: false}, err
+				return &ResolveCheckResponse{Allowed: false}, err
 			}
 
 			userObj, _ := tuple.SplitObjectRelation(t.GetUser())
@@ -632,7 +931,7 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 				}
 			}
 
-			handlers = append(handlers, c.dispatch(
+			handlers = append(handlers, explain(req, newExplanation(req, ExplanationTupleToUserset, t), c.dispatch(
 				ctx,
 				&ResolveCheckRequest{
 					StoreID:              req.GetStoreID(),
@@ -641,11 +940,14 @@ func (c *LocalChecker) checkTTU(parentctx context.Context, req *ResolveCheckRequ
 					ResolutionMetadata: &ResolutionMetadata{
 						Depth: req.GetResolutionMetadata().Depth - 1,
 					},
-				}))
+					VisitedPaths:     visitedPathsOf(req),
+					Explain:          req.GetExplain(),
+					ContextualTuples: req.GetContextualTuples(),
+				})))
 		}
 
 		if len(handlers) == 0 {
-			return &openfgav1.CheckResponse{Allowed: false}, nil
+			return &ResolveCheckResponse{Allowed: false}, nil
 		}
 
 		return union(ctx, c.concurrencyLimit, handlers...)
@@ -666,15 +968,15 @@ func (c *LocalChecker) checkSetOperation(
 	switch setOpType {
 	case unionSetOperator, intersectionSetOperator, exclusionSetOperator:
 		if setOpType == unionSetOperator {
-			reducerKey = "union"
+			reducerKey = ExplanationUnion
 		}
 
 		if setOpType == intersectionSetOperator {
-			reducerKey = "intersection"
+			reducerKey = ExplanationIntersection
 		}
 
 		if setOpType == exclusionSetOperator {
-			reducerKey = "exclusion"
+			reducerKey = ExplanationExclusion
 		}
 
 		for _, child := range children {
@@ -684,11 +986,25 @@ func (c *LocalChecker) checkSetOperation(
 		panic("unexpected set operator type encountered")
 	}
 
-	return func(ctx context.Context) (*openfgav1.CheckResponse, error) {
+	return func(ctx context.Context) (*ResolveCheckResponse, error) {
 		ctx, span := tracer.Start(ctx, reducerKey)
 		defer span.End()
 
-		return reducer(ctx, c.concurrencyLimit, handlers...)
+		resp, err := reducer(ctx, c.concurrencyLimit, handlers...)
+		if err != nil || !resp.GetAllowed() || !req.GetExplain() {
+			return resp, err
+		}
+
+		// a union yields the outcome of the operand that made it allowed, while the other reducers
+		// yield the explanations of their operands as children
+		explanation := newExplanation(req, reducerKey, nil)
+		if setOpType == unionSetOperator {
+			explanation.Children = explanationOf(resp.Explanation).GetChildren()
+		} else {
+			explanation.Children = resp.Explanation.GetChildren()
+		}
+
+		return &ResolveCheckResponse{Allowed: true, Explanation: explanation}, nil
 	}
 }
 
@@ -0,0 +1,309 @@
+package graph
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/cache"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	defaultMaxCheckCacheSize = 10000
+	defaultCheckCacheTTL     = 10 * time.Second
+
+	// checkCacheKeyPrefix is bumped whenever the format of the cached values changes, so that servers
+	// running different versions and sharing a cache backend never read each other's values.
+	checkCacheKeyPrefix = "check/v2/"
+)
+
+var (
+	checkCacheTotalCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "check_cache_total_count",
+		Help: "The total number of Check subproblems looked up in the check cache.",
+	})
+
+	checkCacheHitCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "check_cache_hit_count",
+		Help: "The total number of Check subproblems that were resolved from the check cache.",
+	})
+)
+
+// CheckCache caches the outcome of Check subproblems across requests. Entries are keyed by
+// (tenant, store, authorization model, tuple key, contextual tuples), and every entry of a store is
+// namespaced by a generation that is changed by InvalidateStore, so that results computed before
+// a Write to the store are never served after it. Entries are evicted once their TTL expires.
+//
+// By default entries are held in memory and the generations are local to the process, so with
+// multiple replicas a Write on one replica is only observed by the others once their cached entries
+// expire. If a shared backend is provided with WithCheckCacheBackend, both the entries and the
+// generations are stored in it, so every server sharing the backend shares the resolved subproblems
+// and observes the invalidations of the others.
+//
+// An entry is never served once one of the tuples its outcome was resolved from expires: its TTL is
+// capped at the earliest expiration recorded in the storage.TupleExpirations of the resolution, which
+// the entries served from the cache record too.
+type CheckCache struct {
+	backend cache.Cache
+	shared  bool
+	maxSize int64
+	ttl     time.Duration
+
+	mu          sync.RWMutex
+	generations map[string]uint64
+}
+
+type CheckCacheOption func(c *CheckCache)
+
+// WithCheckCacheMaxSize sets the maximum number of entries held by the in-memory cache. It is
+// ignored if a backend is provided with WithCheckCacheBackend.
+func WithCheckCacheMaxSize(maxSize int64) CheckCacheOption {
+	return func(c *CheckCache) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithCheckCacheTTL sets how long a cached Check result is valid for.
+func WithCheckCacheTTL(ttl time.Duration) CheckCacheOption {
+	return func(c *CheckCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithCheckCacheBackend stores the cached Check results and the store generations in the provided
+// backend, which is assumed to be shared with other servers. The backend is not closed by Stop.
+func WithCheckCacheBackend(backend cache.Cache) CheckCacheOption {
+	return func(c *CheckCache) {
+		c.backend = backend
+		c.shared = true
+	}
+}
+
+// NewCheckCache constructs a CheckCache. Stop must be called once the cache is no longer needed.
+func NewCheckCache(opts ...CheckCacheOption) *CheckCache {
+	c := &CheckCache{
+		maxSize:     defaultMaxCheckCacheSize,
+		ttl:         defaultCheckCacheTTL,
+		generations: map[string]uint64{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.backend == nil {
+		c.backend = cache.NewInMemoryCache(c.maxSize)
+	}
+
+	return c
+}
+
+// InvalidateStore invalidates every cached Check result of the provided store. It must be called
+// whenever the tuples of the store are mutated.
+func (c *CheckCache) InvalidateStore(ctx context.Context, storeID string) error {
+	if c.shared {
+		// the generation must outlive the entries stored under the previous generation, otherwise
+		// they would become reachable again once it expires
+		return c.backend.Set(ctx, generationKey(ctx, storeID), []byte(ulid.Make().String()), 2*c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[storage.StoreKey(ctx, storeID)]++
+
+	return nil
+}
+
+// Flush invalidates every cached Check result. It fails with ErrSharedCheckCacheFlush if a backend is provided with
+// WithCheckCacheBackend, see InvalidateStore.
+func (c *CheckCache) Flush() error {
+	inMemory, ok := c.backend.(*cache.InMemoryCache)
+	if !ok || c.shared {
+		return ErrSharedCheckCacheFlush
+	}
+
+	inMemory.Clear()
+
+	return nil
+}
+
+// SetMaxSize changes the maximum number of entries held by the in-memory cache at runtime. It has no effect if a
+// backend is provided with WithCheckCacheBackend.
+func (c *CheckCache) SetMaxSize(maxSize int64) {
+	if inMemory, ok := c.backend.(*cache.InMemoryCache); ok && !c.shared {
+		inMemory.SetMaxSize(maxSize)
+	}
+}
+
+// Stop releases the resources held by the cache.
+func (c *CheckCache) Stop() {
+	if !c.shared {
+		c.backend.Close()
+	}
+}
+
+// generation returns the current generation of the store.
+func (c *CheckCache) generation(ctx context.Context, storeID string) (string, error) {
+	if c.shared {
+		value, err := c.backend.Get(ctx, generationKey(ctx, storeID))
+		if err != nil {
+			if errors.Is(err, cache.ErrNotFound) {
+				return "", nil
+			}
+
+			return "", err
+		}
+
+		return string(value), nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return strconv.FormatUint(c.generations[storage.StoreKey(ctx, storeID)], 10), nil
+}
+
+// get returns the cached outcome stored under the key, if any, and records the earliest expiration of the
+// tuples it was resolved from in the storage.TupleExpirations of the context. Backend errors are treated as
+// misses.
+func (c *CheckCache) get(ctx context.Context, key string) (bool, bool) {
+	checkCacheTotalCounter.Inc()
+
+	value, err := c.backend.Get(ctx, key)
+	if err != nil {
+		return false, false
+	}
+
+	allowed, expiresAt, ok := decodeCheckCacheValue(value)
+	if !ok || (!expiresAt.IsZero() && !expiresAt.After(time.Now())) {
+		return false, false
+	}
+
+	checkCacheHitCounter.Inc()
+	storage.TupleExpirationsFromContext(ctx).Observe(expiresAt)
+
+	return allowed, true
+}
+
+// set caches the outcome under the key until the TTL or the earliest expiration recorded in the
+// storage.TupleExpirations of the context, whichever comes first.
+func (c *CheckCache) set(ctx context.Context, key string, allowed bool) {
+	ttl := c.ttl
+
+	expiresAt := storage.TupleExpirationsFromContext(ctx).Earliest()
+	if !expiresAt.IsZero() {
+		if untilExpiry := time.Until(expiresAt); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+
+		if ttl <= 0 {
+			return
+		}
+	}
+
+	_ = c.backend.Set(ctx, key, encodeCheckCacheValue(allowed, expiresAt), ttl)
+}
+
+// encodeCheckCacheValue encodes a cached outcome as a byte holding the outcome, followed by the earliest
+// expiration of the tuples it was resolved from in Unix nanoseconds, if any.
+func encodeCheckCacheValue(allowed bool, expiresAt time.Time) []byte {
+	value := []byte{0}
+	if allowed {
+		value[0] = 1
+	}
+
+	if !expiresAt.IsZero() {
+		value = binary.BigEndian.AppendUint64(value, uint64(expiresAt.UnixNano()))
+	}
+
+	return value
+}
+
+func decodeCheckCacheValue(value []byte) (bool, time.Time, bool) {
+	switch len(value) {
+	case 1:
+		return value[0] == 1, time.Time{}, true
+	case 9:
+		return value[0] == 1, time.Unix(0, int64(binary.BigEndian.Uint64(value[1:]))), true
+	default:
+		return false, time.Time{}, false
+	}
+}
+
+// key returns the cache key of the provided request under the provided store generation. The
+// generation must be looked up before the request is resolved, so that a result computed
+// concurrently with an invalidation is stored under the previous generation. The key accounts
+// for the request context the conditions of the tuples are evaluated with, see condition.FromContext.
+func (c *CheckCache) key(ctx context.Context, req *ResolveCheckRequest, generation string) string {
+	tk := req.GetTupleKey()
+
+	return fmt.Sprintf("%s%s/%s/%s/%s#%s@%s/%x/%x",
+		checkCacheKeyPrefix,
+		storage.StoreKey(ctx, req.GetStoreID()),
+		generation,
+		req.GetAuthorizationModelID(),
+		tk.GetObject(),
+		tk.GetRelation(),
+		tk.GetUser(),
+		contextualTuplesHash(req.GetContextualTuples()),
+		requestContextHash(condition.FromContext(ctx)),
+	)
+}
+
+// requestContextHash returns a hash of the provided request context.
+func requestContextHash(requestContext *structpb.Struct) uint64 {
+	if len(requestContext.GetFields()) == 0 {
+		return 0
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(requestContext)
+	if err != nil {
+		return 0
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+
+	return h.Sum64()
+}
+
+func generationKey(ctx context.Context, storeID string) string {
+	return fmt.Sprintf("%sgeneration/%s", checkCacheKeyPrefix, storage.StoreKey(ctx, storeID))
+}
+
+// contextualTuplesHash returns a hash of the provided contextual tuples that does not depend on their order.
+func contextualTuplesHash(contextualTuples []*openfgav1.TupleKey) uint64 {
+	if len(contextualTuples) == 0 {
+		return 0
+	}
+
+	keys := make([]string, 0, len(contextualTuples))
+	for _, tk := range contextualTuples {
+		keys = append(keys, tuple.TupleKeyToString(tk))
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, key := range keys {
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return h.Sum64()
+}
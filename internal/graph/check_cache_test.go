@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/cache"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCheckCache(t *testing.T) {
+	ctx := context.Background()
+
+	req := func(contextualTuples ...*openfgav1.TupleKey) *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:              "store",
+			AuthorizationModelID: "model",
+			TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			ContextualTuples:     contextualTuples,
+		}
+	}
+
+	t.Run("key_does_not_depend_on_contextual_tuples_order", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		ctxTuple1 := tuple.NewTupleKey("document:1", "viewer", "user:jon")
+		ctxTuple2 := tuple.NewTupleKey("document:1", "editor", "user:jon")
+
+		require.Equal(t, checkCache.key(ctx, req(ctxTuple1, ctxTuple2), ""), checkCache.key(ctx, req(ctxTuple2, ctxTuple1), ""))
+		require.NotEqual(t, checkCache.key(ctx, req(), ""), checkCache.key(ctx, req(ctxTuple1), ""))
+	})
+
+	t.Run("key_depends_on_the_request_context", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		requestContext, err := structpb.NewStruct(map[string]interface{}{"ip": "10.0.0.1"})
+		require.NoError(t, err)
+
+		require.Equal(t, checkCache.key(ctx, req(), ""), checkCache.key(condition.NewContext(ctx, &structpb.Struct{}), req(), ""))
+		require.NotEqual(t, checkCache.key(ctx, req(), ""), checkCache.key(condition.NewContext(ctx, requestContext), req(), ""))
+	})
+
+	t.Run("stores_of_different_tenants_are_cached_separately", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		acmeCtx := storage.ContextWithTenant(ctx, "acme")
+		require.NotEqual(t, checkCache.key(ctx, req(), ""), checkCache.key(acmeCtx, req(), ""))
+
+		generation, err := checkCache.generation(acmeCtx, "store")
+		require.NoError(t, err)
+		checkCache.set(acmeCtx, checkCache.key(acmeCtx, req(), generation), true)
+
+		// invalidating the store of another tenant leaves the results of the tenant cached
+		require.NoError(t, checkCache.InvalidateStore(ctx, "store"))
+
+		generation, err = checkCache.generation(acmeCtx, "store")
+		require.NoError(t, err)
+		_, ok := checkCache.get(acmeCtx, checkCache.key(acmeCtx, req(), generation))
+		require.True(t, ok)
+	})
+
+	t.Run("invalidate_store", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		generation, err := checkCache.generation(ctx, "store")
+		require.NoError(t, err)
+
+		key := checkCache.key(ctx, req(), generation)
+		checkCache.set(ctx, key, true)
+
+		allowed, ok := checkCache.get(ctx, key)
+		require.True(t, ok)
+		require.True(t, allowed)
+
+		require.NoError(t, checkCache.InvalidateStore(ctx, "store"))
+
+		generation, err = checkCache.generation(ctx, "store")
+		require.NoError(t, err)
+
+		_, ok = checkCache.get(ctx, checkCache.key(ctx, req(), generation))
+		require.False(t, ok)
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		key := checkCache.key(ctx, req(), "")
+		checkCache.set(ctx, key, true)
+
+		_, ok := checkCache.get(ctx, key)
+		require.True(t, ok)
+
+		require.NoError(t, checkCache.Flush())
+
+		_, ok = checkCache.get(ctx, key)
+		require.False(t, ok)
+
+		backend := cache.NewInMemoryCache(100)
+		t.Cleanup(backend.Close)
+
+		require.ErrorIs(t, NewCheckCache(WithCheckCacheBackend(backend)).Flush(), ErrSharedCheckCacheFlush)
+	})
+
+	t.Run("shared_backend", func(t *testing.T) {
+		backend := cache.NewInMemoryCache(100)
+		t.Cleanup(backend.Close)
+
+		checkCache1 := NewCheckCache(WithCheckCacheBackend(backend))
+		checkCache2 := NewCheckCache(WithCheckCacheBackend(backend))
+
+		generation, err := checkCache1.generation(ctx, "store")
+		require.NoError(t, err)
+		checkCache1.set(ctx, checkCache1.key(ctx, req(), generation), false)
+
+		// results resolved by one server are served to the other
+		generation, err = checkCache2.generation(ctx, "store")
+		require.NoError(t, err)
+		allowed, ok := checkCache2.get(ctx, checkCache2.key(ctx, req(), generation))
+		require.True(t, ok)
+		require.False(t, allowed)
+
+		// and invalidations made by one server are observed by the other
+		require.NoError(t, checkCache2.InvalidateStore(ctx, "store"))
+
+		generation, err = checkCache1.generation(ctx, "store")
+		require.NoError(t, err)
+		_, ok = checkCache1.get(ctx, checkCache1.key(ctx, req(), generation))
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+var checkDeduplicatedCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "check_deduplicated_count",
+	Help: "The total number of Checks that shared the outcome of an identical concurrent Check instead of being resolved.",
+})
+
+// CheckDeduplicator collapses the concurrent resolutions of identical Checks into one: a Check that arrives
+// while an identical Check is being resolved waits for its outcome instead of being resolved again. Checks
+// are identical if they have the same tenant, store, authorization model, tuple key, contextual tuples, request
+// context (see condition.FromContext) and resolution depth.
+//
+// A Check sharing the outcome of a Check that started before it may not observe the writes committed in
+// between, like a Check served by the check cache, so Checks that must observe the latest writes must not be
+// deduplicated, nor the Checks that must bypass the check cache. A Check sharing the outcome of another
+// reports the ResolutionStats of the other, see ContextWithResolutionStats.
+type CheckDeduplicator struct {
+	group singleflight.Group
+}
+
+func NewCheckDeduplicator() *CheckDeduplicator {
+	return &CheckDeduplicator{}
+}
+
+// ResolveCheck resolves the request with the resolver, unless an identical request is being resolved, in which
+// case it returns the outcome of that request. If the identical request is cancelled, the request is resolved
+// with the resolver instead.
+func (d *CheckDeduplicator) ResolveCheck(ctx context.Context, req *ResolveCheckRequest, resolver CheckResolver) (*ResolveCheckResponse, error) {
+	resolved := false
+	resultChan := d.group.DoChan(d.key(ctx, req), func() (interface{}, error) {
+		resolved = true
+		resp, err := resolver.ResolveCheck(ctx, req)
+		return &deduplicatedCheck{resp: resp, stats: ResolutionStatsFromContext(ctx)}, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultChan:
+		if resolved {
+			if result.Err != nil {
+				return nil, result.Err
+			}
+
+			return result.Val.(*deduplicatedCheck).resp, nil
+		}
+
+		if errors.Is(result.Err, context.Canceled) || errors.Is(result.Err, context.DeadlineExceeded) {
+			// the identical request was cancelled, not this one
+			return resolver.ResolveCheck(ctx, req)
+		}
+
+		checkDeduplicatedCounter.Inc()
+
+		shared := result.Val.(*deduplicatedCheck)
+		ResolutionStatsFromContext(ctx).add(shared.stats)
+
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		return shared.resp, nil
+	}
+}
+
+// deduplicatedCheck is the outcome of a Check shared with the identical Checks, along with the statistics of its
+// resolution.
+type deduplicatedCheck struct {
+	resp  *ResolveCheckResponse
+	stats *ResolutionStats
+}
+
+// key returns the key under which the identical requests are collapsed.
+func (d *CheckDeduplicator) key(ctx context.Context, req *ResolveCheckRequest) string {
+	tk := req.GetTupleKey()
+
+	return fmt.Sprintf("%s/%s/%s#%s@%s/%x/%x/%t/%d",
+		storage.StoreKey(ctx, req.GetStoreID()),
+		req.GetAuthorizationModelID(),
+		tk.GetObject(),
+		tk.GetRelation(),
+		tk.GetUser(),
+		contextualTuplesHash(req.GetContextualTuples()),
+		requestContextHash(condition.FromContext(ctx)),
+		req.GetExplain(),
+		req.GetResolutionMetadata().GetDepth(),
+	)
+}
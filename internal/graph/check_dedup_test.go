@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingCheckResolver counts its resolutions, which block until release is closed. Every resolution counts a
+// datastore query.
+type blockingCheckResolver struct {
+	resolutions atomic.Int32
+	release     chan struct{}
+}
+
+func (r *blockingCheckResolver) ResolveCheck(ctx context.Context, _ *ResolveCheckRequest) (*ResolveCheckResponse, error) {
+	r.resolutions.Add(1)
+	ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.release:
+		return &ResolveCheckResponse{Allowed: true}, nil
+	}
+}
+
+func TestCheckDeduplicator(t *testing.T) {
+	req := func(object string) *ResolveCheckRequest {
+		return &ResolveCheckRequest{
+			StoreID:              "store",
+			AuthorizationModelID: "model",
+			TupleKey:             tuple.NewTupleKey(object, "viewer", "user:jon"),
+		}
+	}
+
+	t.Run("identical_concurrent_checks_are_resolved_once", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				resp, err := deduplicator.ResolveCheck(context.Background(), req("document:1"), resolver)
+				require.NoError(t, err)
+				require.True(t, resp.GetAllowed())
+			}()
+		}
+
+		// let every Check join the first one before it completes
+		require.Eventually(t, func() bool { return resolver.resolutions.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(resolver.release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), resolver.resolutions.Load())
+	})
+
+	t.Run("different_checks_are_resolved_separately", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+		close(resolver.release)
+
+		_, err := deduplicator.ResolveCheck(context.Background(), req("document:1"), resolver)
+		require.NoError(t, err)
+		_, err = deduplicator.ResolveCheck(context.Background(), req("document:2"), resolver)
+		require.NoError(t, err)
+
+		require.Equal(t, int32(2), resolver.resolutions.Load())
+	})
+
+	t.Run("checks_of_different_tenants_are_resolved_separately", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+
+		done := make(chan struct{})
+		for _, tenant := range []string{"acme", "globex"} {
+			ctx := storage.ContextWithTenant(context.Background(), tenant)
+
+			go func() {
+				_, err := deduplicator.ResolveCheck(ctx, req("document:1"), resolver)
+				require.NoError(t, err)
+				done <- struct{}{}
+			}()
+		}
+
+		require.Eventually(t, func() bool { return resolver.resolutions.Load() == 2 }, time.Second, time.Millisecond)
+		close(resolver.release)
+		<-done
+		<-done
+	})
+
+	t.Run("checks_of_different_depths_are_resolved_separately", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+
+		done := make(chan struct{})
+		for _, depth := range []uint32{25, 50} {
+			req := req("document:1")
+			req.ResolutionMetadata = &ResolutionMetadata{Depth: depth}
+
+			go func() {
+				_, err := deduplicator.ResolveCheck(context.Background(), req, resolver)
+				require.NoError(t, err)
+				done <- struct{}{}
+			}()
+		}
+
+		require.Eventually(t, func() bool { return resolver.resolutions.Load() == 2 }, time.Second, time.Millisecond)
+		close(resolver.release)
+		<-done
+		<-done
+	})
+
+	t.Run("identical_checks_report_the_stats_of_the_resolved_check", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+
+		stats := []*ResolutionStats{{}, {}}
+		var wg sync.WaitGroup
+		for _, s := range stats {
+			ctx := ContextWithResolutionStats(context.Background(), s)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, err := deduplicator.ResolveCheck(ctx, req("document:1"), resolver)
+				require.NoError(t, err)
+			}()
+		}
+
+		require.Eventually(t, func() bool { return resolver.resolutions.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		close(resolver.release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), resolver.resolutions.Load())
+		require.Equal(t, uint32(1), stats[0].DatastoreQueries())
+		require.Equal(t, uint32(1), stats[1].DatastoreQueries())
+	})
+
+	t.Run("a_cancelled_check_does_not_fail_the_identical_checks", func(t *testing.T) {
+		deduplicator := NewCheckDeduplicator()
+		resolver := &blockingCheckResolver{release: make(chan struct{})}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err := deduplicator.ResolveCheck(ctx, req("document:1"), resolver)
+			done <- err
+		}()
+		require.Eventually(t, func() bool { return resolver.resolutions.Load() == 1 }, time.Second, time.Millisecond)
+
+		joined := make(chan bool)
+		go func() {
+			resp, err := deduplicator.ResolveCheck(context.Background(), req("document:1"), resolver)
+			require.NoError(t, err)
+			joined <- resp.GetAllowed()
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+
+		close(resolver.release)
+		require.True(t, <-joined)
+		require.Equal(t, int32(2), resolver.resolutions.Load())
+	})
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+const checkMemoCtxKey ctxKey = "check-memo"
+
+// checkMemo memoizes the outcomes of the subproblems of a Check resolution, keyed by object#relation@user, so
+// that a subproblem reached several times, e.g. by the branches of a diamond-shaped graph, is resolved once. A
+// checkMemo is attached to the context of the resolution by the LocalChecker, and it is safe for concurrent use.
+//
+// Only the outcomes resolved without an error are memoized: they don't depend on the path the subproblem was
+// reached from, unlike the cycles and the exceeded resolution depths, which are reported as errors. Since the
+// subproblems of a resolution share its store, authorization model, contextual tuples and request context, they
+// are not part of the key.
+//
+// The concurrent resolutions of a subproblem are not collapsed into one, since the resolutions of two subproblems
+// that reach each other could wait on each other forever.
+type checkMemo struct {
+	outcomes sync.Map
+}
+
+func contextWithCheckMemo(parent context.Context, memo *checkMemo) context.Context {
+	return context.WithValue(parent, checkMemoCtxKey, memo)
+}
+
+func checkMemoFromContext(ctx context.Context) *checkMemo {
+	memo, _ := ctx.Value(checkMemoCtxKey).(*checkMemo)
+	return memo
+}
+
+// get returns the memoized outcome of the request, if any.
+func (m *checkMemo) get(req *ResolveCheckRequest) (bool, bool) {
+	if m == nil {
+		return false, false
+	}
+
+	allowed, ok := m.outcomes.Load(tuple.TupleKeyToString(req.GetTupleKey()))
+	if !ok {
+		return false, false
+	}
+
+	return allowed.(bool), true
+}
+
+func (m *checkMemo) set(req *ResolveCheckRequest, allowed bool) {
+	if m == nil {
+		return
+	}
+
+	m.outcomes.Store(tuple.TupleKeyToString(req.GetTupleKey()), allowed)
+}
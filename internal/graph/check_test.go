@@ -2,12 +2,15 @@ package graph
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	parser "github.com/craigpastro/openfga-dsl-parser/v2"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/stretchr/testify/require"
@@ -73,6 +76,58 @@ func TestResolveCheckDeterministic(t *testing.T) {
 	require.Nil(t, resp)
 }
 
+func TestResolveCheckCycleDetected(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:1", "member", "group:2#member"),
+		tuple.NewTupleKey("group:2", "member", "group:3#member"),
+		tuple.NewTupleKey("group:3", "member", "group:1#member"),
+		tuple.NewTupleKey("group:3", "member", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	checker := NewLocalChecker(ds)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+		define member: [user, group#member] as self
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	t.Run("allowed_before_the_cycle", func(t *testing.T) {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("group:1", "member", "user:jon"),
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+	})
+
+	t.Run("cycle_detected_before_the_depth_is_exceeded", func(t *testing.T) {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("group:1", "member", "user:maria"),
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+		})
+		require.ErrorIs(t, err, ErrCycleDetected)
+		require.Nil(t, resp)
+	})
+}
+
 func TestCheckWithOneConcurrentGoroutineCausesNoDeadlock(t *testing.T) {
 	const concurrencyLimit = 1
 	ds := memory.New()
@@ -119,3 +174,391 @@ func TestCheckWithOneConcurrentGoroutineCausesNoDeadlock(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, resp.Allowed)
 }
+
+func TestResolveCheckExplanation(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:ann"),
+	})
+	require.NoError(t, err)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+	    define member: [user] as self
+	type folder
+	  relations
+	    define viewer: [user] as self
+	type document
+	  relations
+	    define parent: [folder] as self
+	    define owner: [group#member] as self
+	    define editor as owner
+	    define viewer: [user] as self or editor
+	    define reader as viewer from parent
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	// render flattens the explanation into one line per node, indented by depth
+	var render func(e *CheckExplanation, indent string) []string
+	render = func(e *CheckExplanation, indent string) []string {
+		line := indent + e.Operation + " " + tuple.TupleKeyToString(e.TupleKey)
+		if e.Tuple != nil {
+			line += " by " + tuple.TupleKeyToString(e.Tuple)
+		}
+		if e.Contextual {
+			line += " (contextual)"
+		}
+
+		lines := []string{line}
+		for _, child := range e.Children {
+			lines = append(lines, render(child, indent+"  ")...)
+		}
+		return lines
+	}
+
+	checker := NewLocalChecker(ds)
+
+	t.Run("rewrites_and_usersets_are_explained", func(t *testing.T) {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+			Explain:            true,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+		require.Equal(t, []string{
+			"union document:1#viewer@user:jon",
+			"  computed_userset document:1#viewer@user:jon",
+			"    computed_userset document:1#editor@user:jon",
+			"      direct document:1#owner@user:jon by document:1#owner@group:eng#member",
+			"        direct group:eng#member@user:jon by group:eng#member@user:jon",
+		}, render(resp.Explanation, ""))
+	})
+
+	t.Run("contextual_tuples_are_marked", func(t *testing.T) {
+		contextualTuples := []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "parent", "folder:x")}
+		checker := NewLocalChecker(storagewrappers.NewCombinedTupleReader(ds, contextualTuples))
+
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("document:1", "reader", "user:ann"),
+			ContextualTuples:   contextualTuples,
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+			Explain:            true,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+		require.Equal(t, []string{
+			"tuple_to_userset document:1#reader@user:ann by document:1#parent@folder:x (contextual)",
+			"  direct folder:x#viewer@user:ann by folder:x#viewer@user:ann",
+		}, render(resp.Explanation, ""))
+	})
+
+	t.Run("no_explanation_unless_requested", func(t *testing.T) {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+		})
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+		require.Nil(t, resp.Explanation)
+	})
+}
+
+func TestResolveCheckResolutionStats(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+	    define member: [user] as self
+	type document
+	  relations
+	    define owner: [group#member] as self
+	    define editor as owner
+	    define viewer: [user] as self or editor
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	stats := &ResolutionStats{}
+	ctx = ContextWithResolutionStats(ctx, stats)
+
+	// a denied Check resolves every branch, so the statistics are deterministic
+	resp, err := NewLocalChecker(ds).ResolveCheck(ctx, &ResolveCheckRequest{
+		StoreID:            storeID,
+		TupleKey:           tuple.NewTupleKey("document:1", "viewer", "user:maria"),
+		ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+	})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+
+	// document:1#viewer (2 queries) -> document:1#editor -> document:1#owner (1 query) -> group:eng#member (2 queries)
+	require.Equal(t, uint32(5), stats.DatastoreQueries())
+	require.Equal(t, uint32(3), stats.Dispatches())
+	require.Equal(t, uint32(4), stats.MaxDepth())
+
+	lookups, hits := stats.CacheLookups()
+	require.Zero(t, lookups)
+	require.Zero(t, hits)
+
+	t.Run("cache_lookups", func(t *testing.T) {
+		checkCache := NewCheckCache()
+		t.Cleanup(checkCache.Stop)
+
+		checker := NewLocalChecker(ds, WithCheckCache(checkCache))
+
+		for i := 0; i < 2; i++ {
+			_, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+				StoreID:            storeID,
+				TupleKey:           tuple.NewTupleKey("document:1", "editor", "user:maria"),
+				ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+			})
+			require.NoError(t, err)
+		}
+
+		// the first Check misses document:1#editor, document:1#owner and group:eng#member, the second one hits
+		// document:1#editor
+		lookups, hits := stats.CacheLookups()
+		require.Equal(t, uint32(4), lookups)
+		require.Equal(t, uint32(1), hits)
+	})
+}
+
+func TestResolveCheckCacheBoundedByTupleExpirations(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+	})
+	require.NoError(t, err)
+
+	err = ds.WriteWithExpiry(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+	}, time.Now().Add(100*time.Millisecond))
+	require.NoError(t, err)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+	    define member: [user] as self
+	type document
+	  relations
+	    define viewer: [group#member] as self
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	checkCache := NewCheckCache(WithCheckCacheTTL(time.Minute))
+	t.Cleanup(checkCache.Stop)
+
+	checker := NewLocalChecker(ds, WithCheckCache(checkCache))
+
+	check := func() bool {
+		resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+			StoreID:            storeID,
+			TupleKey:           tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+		})
+		require.NoError(t, err)
+
+		return resp.Allowed
+	}
+
+	require.True(t, check())
+
+	// the outcomes resolved from the expiring tuple are cached until it expires, including the outcome of
+	// document:1#viewer, which is served from the cache without reading it again
+	stats := &ResolutionStats{}
+	ctx = ContextWithResolutionStats(ctx, stats)
+	require.True(t, check())
+
+	_, hits := stats.CacheLookups()
+	require.Equal(t, uint32(1), hits)
+
+	time.Sleep(150 * time.Millisecond)
+
+	require.False(t, check())
+}
+
+func TestResolveCheckMemoizesSubproblems(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	// every group:g<i> reaches group:g<i+1> through both group:a<i> and group:b<i>, so that group:g<levels> is
+	// reached through 2^levels paths
+	const levels = 10
+
+	var tuples []*openfgav1.TupleKey
+	for i := 0; i < levels; i++ {
+		next := fmt.Sprintf("group:g%d#member", i+1)
+		tuples = append(tuples,
+			tuple.NewTupleKey(fmt.Sprintf("group:g%d", i), "member", fmt.Sprintf("group:a%d#member", i)),
+			tuple.NewTupleKey(fmt.Sprintf("group:g%d", i), "member", fmt.Sprintf("group:b%d#member", i)),
+			tuple.NewTupleKey(fmt.Sprintf("group:a%d", i), "member", next),
+			tuple.NewTupleKey(fmt.Sprintf("group:b%d", i), "member", next),
+		)
+	}
+
+	err := ds.Write(context.Background(), storeID, nil, tuples)
+	require.NoError(t, err)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+	    define member: [user, group#member] as self
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	stats := &ResolutionStats{}
+	ctx = ContextWithResolutionStats(ctx, stats)
+
+	// the branches are resolved one at a time, so that every subproblem reached again was resolved already
+	checker := NewLocalChecker(ds, WithResolveNodeBreadthLimit(1))
+
+	resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+		StoreID:            storeID,
+		TupleKey:           tuple.NewTupleKey("group:g0", "member", "user:maria"),
+		ResolutionMetadata: &ResolutionMetadata{Depth: 100},
+	})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+
+	// every group#member is resolved once, with 2 queries
+	require.Equal(t, uint32(2*(3*levels+1)), stats.DatastoreQueries())
+}
+
+func TestResolveCheckLimits(t *testing.T) {
+	ds := memory.New()
+	defer ds.Close()
+
+	storeID := ulid.Make().String()
+
+	err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "owner", "group:eng#member"),
+		tuple.NewTupleKey("group:eng", "member", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	typedefs := parser.MustParse(`
+	type user
+	type group
+	  relations
+	    define member: [user] as self
+	type document
+	  relations
+	    define owner: [group#member] as self
+	    define editor as owner
+	    define viewer: [user] as self or editor
+	`)
+
+	ctx := typesystem.ContextWithTypesystem(context.Background(), typesystem.New(
+		&openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			TypeDefinitions: typedefs,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+		},
+	))
+
+	// a denied Check of document:1#viewer dispatches 3 subproblems and issues 5 datastore reads, see
+	// TestResolveCheckResolutionStats
+	tests := []struct {
+		name          string
+		opts          []LocalCheckerOption
+		expectedError error
+	}{
+		{
+			name: "unlimited",
+		},
+		{
+			name: "within_the_limits",
+			opts: []LocalCheckerOption{WithMaxDispatchCount(3), WithMaxDatastoreReadsPerCheck(5)},
+		},
+		{
+			name:          "too_many_dispatches",
+			opts:          []LocalCheckerOption{WithMaxDispatchCount(2)},
+			expectedError: ErrDispatchLimitExceeded,
+		},
+		{
+			name:          "too_many_datastore_reads",
+			opts:          []LocalCheckerOption{WithMaxDatastoreReadsPerCheck(4)},
+			expectedError: ErrDatastoreReadLimitExceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := NewLocalChecker(ds, test.opts...)
+
+			// the limits apply to each request, so consecutive requests do not share them
+			for i := 0; i < 2; i++ {
+				resp, err := checker.ResolveCheck(ctx, &ResolveCheckRequest{
+					StoreID:            storeID,
+					TupleKey:           tuple.NewTupleKey("document:1", "viewer", "user:maria"),
+					ResolutionMetadata: &ResolutionMetadata{Depth: 25},
+				})
+				if test.expectedError != nil {
+					require.ErrorIs(t, err, test.expectedError)
+					require.ErrorIs(t, err, ErrResolutionLimitExceeded)
+					continue
+				}
+
+				require.NoError(t, err)
+				require.False(t, resp.Allowed)
+			}
+		})
+	}
+}
@@ -21,6 +21,20 @@ var (
 	ErrResolutionDepthExceeded = errors.New("resolution depth exceeded")
 	ErrTargetError             = errors.New("graph: target incorrectly specified")
 	ErrNotImplemented          = errors.New("graph: intersection and exclusion are not yet implemented")
+
+	// ErrCycleDetected is returned when a resolution reaches a subproblem it is already resolving, which it would
+	// otherwise resolve again until it exceeds the resolution depth.
+	ErrCycleDetected = errors.New("cycle detected")
+
+	// ErrResolutionLimitExceeded is wrapped by the errors returned when a resolution exceeds one of its
+	// per-request limits.
+	ErrResolutionLimitExceeded    = errors.New("resolution limit exceeded")
+	ErrDispatchLimitExceeded      = fmt.Errorf("%w: too many dispatches", ErrResolutionLimitExceeded)
+	ErrDatastoreReadLimitExceeded = fmt.Errorf("%w: too many datastore reads", ErrResolutionLimitExceeded)
+
+	// ErrSharedCheckCacheFlush is returned when every result of a check cache stored in a shared backend is flushed,
+	// since they can only be invalidated store by store.
+	ErrSharedCheckCacheFlush = errors.New("the results of a shared check cache can only be invalidated store by store")
 )
 
 type findIngressOption int
@@ -45,6 +59,14 @@ type ResolutionMetadata struct {
 	Depth uint32
 }
 
+func (m *ResolutionMetadata) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+
+	return 0
+}
+
 // RelationshipIngressType is used to define an enum of the type of ingresses between
 // source object references and target user references that exist in the graph of
 // relationships.
@@ -174,6 +196,20 @@ func (g *ConnectedObjectGraph) PrunedRelationshipIngresses(target *openfgav1.Rel
 	return g.findIngresses(target, source, map[string]struct{}{}, resolveAnyIngress)
 }
 
+// PrunedRelationshipIngressesForRewrite computes the same ingresses as PrunedRelationshipIngresses, but only through the
+// provided rewrite of the target relation (e.g. the base of an exclusion) instead of the whole definition of the relation.
+func (g *ConnectedObjectGraph) PrunedRelationshipIngressesForRewrite(
+	target *openfgav1.RelationReference,
+	targetRewrite *openfgav1.Userset,
+	source *openfgav1.RelationReference,
+) ([]*RelationshipIngress, error) {
+	visited := map[string]struct{}{
+		tuple.ToObjectRelationString(target.GetType(), target.GetRelation()): {},
+	}
+
+	return g.findIngressesWithTargetRewrite(target, source, targetRewrite, visited, resolveAnyIngress)
+}
+
 func (g *ConnectedObjectGraph) findIngresses(
 	target *openfgav1.RelationReference,
 	source *openfgav1.RelationReference,
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"context"
+	"sync/atomic"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const checkBudgetCtxKey ctxKey = "check-budget"
+
+// checkBudget limits the number of subproblems a Check resolution dispatches and the number of datastore reads
+// it issues. A zero limit is unlimited. A checkBudget is attached to the context of the resolution by the
+// LocalChecker, and it is safe for concurrent use.
+type checkBudget struct {
+	maxDispatches uint32
+	maxReads      uint32
+
+	dispatches atomic.Uint32
+	reads      atomic.Uint32
+}
+
+func contextWithCheckBudget(parent context.Context, budget *checkBudget) context.Context {
+	return context.WithValue(parent, checkBudgetCtxKey, budget)
+}
+
+func checkBudgetFromContext(ctx context.Context) *checkBudget {
+	budget, _ := ctx.Value(checkBudgetCtxKey).(*checkBudget)
+	return budget
+}
+
+// dispatch charges a dispatch to the budget, and returns ErrDispatchLimitExceeded if it is exhausted.
+func (b *checkBudget) dispatch() error {
+	if b == nil || b.maxDispatches == 0 {
+		return nil
+	}
+
+	if b.dispatches.Add(1) > b.maxDispatches {
+		return ErrDispatchLimitExceeded
+	}
+
+	return nil
+}
+
+// read charges a datastore read to the budget, and returns ErrDatastoreReadLimitExceeded if it is exhausted.
+func (b *checkBudget) read() error {
+	if b == nil || b.maxReads == 0 {
+		return nil
+	}
+
+	if b.reads.Add(1) > b.maxReads {
+		return ErrDatastoreReadLimitExceeded
+	}
+
+	return nil
+}
+
+type budgetTupleReader struct {
+	storage.RelationshipTupleReader
+}
+
+var _ storage.RelationshipTupleReader = (*budgetTupleReader)(nil)
+
+// newBudgetTupleReader returns a wrapper over a datastore that charges the Read, ReadUserTuple,
+// ReadUsersetTuples and ReadStartingWithUser calls to the checkBudget of their context, if any.
+func newBudgetTupleReader(wrapped storage.RelationshipTupleReader) storage.RelationshipTupleReader {
+	return &budgetTupleReader{RelationshipTupleReader: wrapped}
+}
+
+func (r *budgetTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	if err := checkBudgetFromContext(ctx).read(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.Read(ctx, store, tupleKey)
+}
+
+func (r *budgetTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	if err := checkBudgetFromContext(ctx).read(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+}
+
+func (r *budgetTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	if err := checkBudgetFromContext(ctx).read(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func (r *budgetTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	if err := checkBudgetFromContext(ctx).read(); err != nil {
+		return nil, err
+	}
+
+	return r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
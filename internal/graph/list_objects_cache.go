@@ -0,0 +1,446 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/karlseguin/ccache/v3"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultMaxListObjectsCacheSize           = 1000
+	defaultListObjectsCacheTTL               = 10 * time.Second
+	defaultListObjectsCacheChangelogInterval = time.Second
+
+	// listObjectsCacheChangelogPageSize is the number of changes read from the changelog per page, and
+	// listObjectsCacheMaxChangelogPages the number of pages read per poll of the changelog of a store. If there are more
+	// changes left, every cached result of the store is invalidated and the changes left are read by the next poll.
+	listObjectsCacheChangelogPageSize = 100
+	listObjectsCacheMaxChangelogPages = 10
+
+	// listObjectsCacheClockSkew is how long before the first lookup of a store the changes of its changelog are
+	// observed from, which accounts for the skew between the clocks of the server and of the datastore.
+	listObjectsCacheClockSkew = time.Minute
+)
+
+var (
+	listObjectsCacheTotalCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "list_objects_cache_total_count",
+		Help: "The total number of ListObjects looked up in the ListObjects cache.",
+	})
+
+	listObjectsCacheHitCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "list_objects_cache_hit_count",
+		Help: "The total number of ListObjects that were served from the ListObjects cache.",
+	})
+)
+
+// ListObjectsCache caches the results of ListObjects across requests. Entries are keyed by (store, authorization
+// model, object type, relation, user, contextual tuples, request context), and every entry records the object types
+// whose tuples its result depends on, i.e. those read to resolve the relation (see ObjectTypesRead). An entry is
+// invalidated by the changes to the tuples of any of these object types, and otherwise expires after its TTL.
+//
+// The writes made through the server invalidate the entries as soon as they are committed, see
+// InvalidateObjectTypes. The writes made otherwise, e.g. through other replicas, are observed from the changelog of
+// the store, which is read at most once per changelog interval by the lookups of the store. Since the changes are
+// only read from the changelog once they are older than the horizon offset, the TTL must be longer than the
+// changelog interval and horizon offset to bound the staleness of the results.
+//
+// An entry is never served once one of the tuples its result was resolved from expires: its TTL is capped at the
+// earliest expiration recorded in the storage.TupleExpirations of the context it is set with. The changes of the
+// stores which have not been used for longer than the TTL, and whose entries have thus all expired, are dropped.
+type ListObjectsCache struct {
+	datastore         storage.ChangelogBackend
+	maxSize           int64
+	ttl               time.Duration
+	changelogInterval time.Duration
+	horizonOffset     time.Duration
+
+	entries *ccache.Cache[*listObjectsCacheEntry]
+
+	mu      sync.Mutex
+	stores  map[string]*listObjectsCacheStore
+	sweptAt time.Time
+}
+
+// listObjectsCacheEntry is a cached ListObjects result.
+type listObjectsCacheEntry struct {
+	objects []string
+
+	// resolvedAt is the time the resolution of the result started, so the changes made since may not be observed
+	resolvedAt time.Time
+
+	objectTypes []string
+}
+
+// listObjectsCacheStore holds the times of the last changes to the tuples of a store.
+type listObjectsCacheStore struct {
+	// usedAt is the time the store was last looked up, guarded by the mutex of the cache
+	usedAt time.Time
+
+	// pollMu serializes the polls of the changelog
+	pollMu   sync.Mutex
+	polledAt time.Time
+	token    string
+
+	// since is the time the changes of the changelog are observed from until the first change is read
+	since time.Time
+
+	mu            sync.RWMutex
+	changedAt     map[string]time.Time
+	invalidatedAt time.Time
+}
+
+type ListObjectsCacheOption func(c *ListObjectsCache)
+
+// WithListObjectsCacheMaxSize sets the maximum number of ListObjects results held by the cache.
+func WithListObjectsCacheMaxSize(maxSize int64) ListObjectsCacheOption {
+	return func(c *ListObjectsCache) {
+		c.maxSize = maxSize
+	}
+}
+
+// WithListObjectsCacheTTL sets how long a cached ListObjects result is valid for.
+func WithListObjectsCacheTTL(ttl time.Duration) ListObjectsCacheOption {
+	return func(c *ListObjectsCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithListObjectsCacheChangelogInterval sets how often the changelog of a store is read for the changes made
+// otherwise than through the server. If 0, it is read by every lookup of a cached result.
+func WithListObjectsCacheChangelogInterval(interval time.Duration) ListObjectsCacheOption {
+	return func(c *ListObjectsCache) {
+		c.changelogInterval = interval
+	}
+}
+
+// WithListObjectsCacheHorizonOffset sets the horizon offset the changelog is read with, see
+// storage.ChangelogBackend.
+func WithListObjectsCacheHorizonOffset(offset time.Duration) ListObjectsCacheOption {
+	return func(c *ListObjectsCache) {
+		c.horizonOffset = offset
+	}
+}
+
+// NewListObjectsCache constructs a ListObjectsCache reading the changes of the stores from the changelog of the
+// datastore. Stop must be called once the cache is no longer needed.
+func NewListObjectsCache(datastore storage.ChangelogBackend, opts ...ListObjectsCacheOption) *ListObjectsCache {
+	c := &ListObjectsCache{
+		datastore:         datastore,
+		maxSize:           defaultMaxListObjectsCacheSize,
+		ttl:               defaultListObjectsCacheTTL,
+		changelogInterval: defaultListObjectsCacheChangelogInterval,
+		stores:            map[string]*listObjectsCacheStore{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.entries = ccache.New(ccache.Configure[*listObjectsCacheEntry]().MaxSize(c.maxSize))
+
+	return c
+}
+
+// Get returns the cached objects of the request, if any. The request must carry the resolved authorization model
+// id. Failures to read the changelog of the store are treated as misses.
+//
+// The changelog of the store is read by the lookup whether the result is cached or not, so that the changes it reads
+// are not attributed to the result resolved by a miss, which must start once the lookup returns.
+func (c *ListObjectsCache) Get(ctx context.Context, req *openfgav1.ListObjectsRequest) ([]string, bool) {
+	listObjectsCacheTotalCounter.Inc()
+
+	// the changes of the store are observed from its first lookup, so it must happen before its first resolution
+	store := c.store(ctx, req.GetStoreId())
+
+	if err := c.poll(ctx, req.GetStoreId(), store); err != nil {
+		return nil, false
+	}
+
+	item := c.entries.Get(c.key(ctx, req))
+	if item == nil || item.Expired() {
+		return nil, false
+	}
+	entry := item.Value()
+
+	if store.changedSince(entry.resolvedAt, entry.objectTypes) {
+		return nil, false
+	}
+
+	listObjectsCacheHitCounter.Inc()
+
+	return append([]string(nil), entry.objects...), true
+}
+
+// Set caches the objects of the request, whose resolution started at resolvedAt, until the TTL or the earliest
+// expiration recorded in the storage.TupleExpirations of the context, whichever comes first. The request must carry
+// the resolved authorization model id, that of the typesystem.
+func (c *ListObjectsCache) Set(ctx context.Context, typesys *typesystem.TypeSystem, req *openfgav1.ListObjectsRequest, objects []string, resolvedAt time.Time) {
+	ttl := c.ttl
+
+	expiresAt := storage.TupleExpirationsFromContext(ctx).Earliest()
+	if !expiresAt.IsZero() {
+		if untilExpiry := time.Until(expiresAt); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+
+		if ttl <= 0 {
+			return
+		}
+	}
+
+	c.entries.Set(c.key(ctx, req), &listObjectsCacheEntry{
+		objects:     append([]string(nil), objects...),
+		resolvedAt:  resolvedAt,
+		objectTypes: ObjectTypesRead(typesys, req.GetType(), req.GetRelation()),
+	}, ttl)
+}
+
+// InvalidateObjectTypes invalidates every cached result of the store which depends on the tuples of any of the
+// object types. It must be called once the tuples of the object types are mutated.
+func (c *ListObjectsCache) InvalidateObjectTypes(ctx context.Context, storeID string, objectTypes ...string) {
+	store := c.store(ctx, storeID)
+	now := time.Now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for _, objectType := range objectTypes {
+		store.changedAt[objectType] = now
+	}
+}
+
+// InvalidateStore invalidates every cached result of the store.
+func (c *ListObjectsCache) InvalidateStore(ctx context.Context, storeID string) {
+	store := c.store(ctx, storeID)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.invalidatedAt = time.Now()
+}
+
+// DeleteStore invalidates every cached result of the store and drops its changes. It must be called once the store
+// is deleted.
+func (c *ListObjectsCache) DeleteStore(ctx context.Context, storeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the results cached before are invalidated by the store being looked up again, see store
+	delete(c.stores, storage.StoreKey(ctx, storeID))
+}
+
+// Flush invalidates every cached result.
+func (c *ListObjectsCache) Flush() {
+	c.entries.Clear()
+}
+
+// SetMaxSize changes the maximum number of results held by the cache at runtime.
+func (c *ListObjectsCache) SetMaxSize(maxSize int64) {
+	c.entries.SetMaxSize(maxSize)
+}
+
+// Stop releases the resources held by the cache.
+func (c *ListObjectsCache) Stop() {
+	c.entries.Stop()
+}
+
+// store returns the changes of the store, which are observed from the first call for the store. Since the changes
+// made before are unknown, the results cached before are invalidated. The stores which have not been used for longer
+// than the TTL are dropped at most once per TTL.
+func (c *ListObjectsCache) store(ctx context.Context, storeID string) *listObjectsCacheStore {
+	key := storage.StoreKey(ctx, storeID)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.sweptAt) >= c.ttl {
+		for key, store := range c.stores {
+			if now.Sub(store.usedAt) > c.ttl {
+				delete(c.stores, key)
+			}
+		}
+		c.sweptAt = now
+	}
+
+	store, ok := c.stores[key]
+	if !ok {
+		store = &listObjectsCacheStore{
+			since:         now.Add(-listObjectsCacheClockSkew),
+			changedAt:     map[string]time.Time{},
+			invalidatedAt: now,
+		}
+		c.stores[key] = store
+	}
+	store.usedAt = now
+
+	return store
+}
+
+// poll reads the changes made to the store since the last poll from its changelog, unless it was read within the
+// changelog interval.
+func (c *ListObjectsCache) poll(ctx context.Context, storeID string, store *listObjectsCacheStore) error {
+	store.pollMu.Lock()
+	defer store.pollMu.Unlock()
+
+	if !store.polledAt.IsZero() && time.Since(store.polledAt) < c.changelogInterval {
+		return nil
+	}
+
+	objectTypes := map[string]struct{}{}
+	complete := false
+	for page := 0; page < listObjectsCacheMaxChangelogPages; page++ {
+		filter := storage.ReadChangesFilter{}
+		if store.token == "" {
+			filter.StartTime = store.since
+		}
+
+		changes, token, err := c.datastore.ReadChangesWithFilter(ctx, storeID, filter, storage.PaginationOptions{
+			PageSize: listObjectsCacheChangelogPageSize,
+			From:     store.token,
+		}, c.horizonOffset)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				complete = true
+				break
+			}
+
+			return err
+		}
+
+		for _, change := range changes {
+			objectTypes[tuple.GetType(change.GetTupleKey().GetObject())] = struct{}{}
+		}
+		store.token = string(token)
+
+		if len(changes) < listObjectsCacheChangelogPageSize {
+			complete = true
+			break
+		}
+	}
+
+	// the results resolved before the changes were read may not have observed them
+	now := time.Now()
+	store.polledAt = now
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if !complete {
+		store.invalidatedAt = now
+	}
+	for objectType := range objectTypes {
+		store.changedAt[objectType] = now
+	}
+
+	return nil
+}
+
+// changedSince reports whether the tuples of any of the object types of the store may have changed since the time.
+func (s *listObjectsCacheStore) changedSince(since time.Time, objectTypes []string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.invalidatedAt.Before(since) {
+		return true
+	}
+
+	for _, objectType := range objectTypes {
+		if changedAt, ok := s.changedAt[objectType]; ok && !changedAt.Before(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// key returns the cache key of the request. The key accounts for the request context the conditions of the tuples
+// are evaluated with, see condition.FromContext.
+func (c *ListObjectsCache) key(ctx context.Context, req *openfgav1.ListObjectsRequest) string {
+	return fmt.Sprintf("%s/%s/%s#%s@%s/%x/%x",
+		storage.StoreKey(ctx, req.GetStoreId()),
+		req.GetAuthorizationModelId(),
+		req.GetType(),
+		req.GetRelation(),
+		req.GetUser(),
+		contextualTuplesHash(req.GetContextualTuples().GetTupleKeys()),
+		requestContextHash(condition.FromContext(ctx)),
+	)
+}
+
+// ObjectTypesRead returns the object types of the tuples that may be read to resolve the relation of the object
+// type, i.e. the object type itself if the relation is directly assignable or a tupleset, and those of the relations
+// it is rewritten to. The type restrictions of the model, which ListObjects requires, tell which object types the
+// users of the tuples of a relation are.
+func ObjectTypesRead(typesys *typesystem.TypeSystem, objectType, relation string) []string {
+	objectTypes := map[string]struct{}{}
+	collectObjectTypesRead(typesys, objectType, relation, objectTypes, map[string]struct{}{})
+
+	types := make([]string, 0, len(objectTypes))
+	for objectType := range objectTypes {
+		types = append(types, objectType)
+	}
+
+	return types
+}
+
+func collectObjectTypesRead(typesys *typesystem.TypeSystem, objectType, relation string, objectTypes, visited map[string]struct{}) {
+	key := tuple.ToObjectRelationString(objectType, relation)
+	if _, ok := visited[key]; ok {
+		return
+	}
+	visited[key] = struct{}{}
+
+	rel, err := typesys.GetRelation(objectType, relation)
+	if err != nil {
+		return
+	}
+
+	var walk func(rewrite *openfgav1.Userset)
+	walk = func(rewrite *openfgav1.Userset) {
+		switch rw := rewrite.GetUserset().(type) {
+		case *openfgav1.Userset_This:
+			objectTypes[objectType] = struct{}{}
+
+			directlyRelatedTypes, _ := typesys.GetDirectlyRelatedUserTypes(objectType, relation)
+			for _, ref := range directlyRelatedTypes {
+				if ref.GetRelation() != "" {
+					collectObjectTypesRead(typesys, ref.GetType(), ref.GetRelation(), objectTypes, visited)
+				}
+			}
+		case *openfgav1.Userset_ComputedUserset:
+			collectObjectTypesRead(typesys, objectType, rw.ComputedUserset.GetRelation(), objectTypes, visited)
+		case *openfgav1.Userset_TupleToUserset:
+			objectTypes[objectType] = struct{}{}
+
+			tuplesetTypes, _ := typesys.GetDirectlyRelatedUserTypes(objectType, rw.TupleToUserset.GetTupleset().GetRelation())
+			for _, ref := range tuplesetTypes {
+				collectObjectTypesRead(typesys, ref.GetType(), rw.TupleToUserset.GetComputedUserset().GetRelation(), objectTypes, visited)
+			}
+		case *openfgav1.Userset_Union:
+			for _, child := range rw.Union.GetChild() {
+				walk(child)
+			}
+		case *openfgav1.Userset_Intersection:
+			for _, child := range rw.Intersection.GetChild() {
+				walk(child)
+			}
+		case *openfgav1.Userset_Difference:
+			walk(rw.Difference.GetBase())
+			walk(rw.Difference.GetSubtract())
+		}
+	}
+	walk(rel.GetRewrite())
+}
@@ -0,0 +1,224 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListObjectsCache(t *testing.T) {
+	ctx := context.Background()
+
+	typesys := typesystem.New(&openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type group
+		  relations
+		    define member: [user] as self
+
+		type folder
+		  relations
+		    define viewer: [user, group#member] as self
+
+		type document
+		  relations
+		    define parent: [folder] as self
+		    define viewer: [user] as self or viewer from parent
+
+		type label
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+
+	req := func(storeID string) *openfgav1.ListObjectsRequest {
+		return &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: typesys.GetAuthorizationModelID(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 "user:jon",
+		}
+	}
+
+	t.Run("object_types_read", func(t *testing.T) {
+		objectTypes := ObjectTypesRead(typesys, "document", "viewer")
+		sort.Strings(objectTypes)
+
+		require.Equal(t, []string{"document", "folder", "group"}, objectTypes)
+		require.Equal(t, []string{"label"}, ObjectTypesRead(typesys, "label", "viewer"))
+	})
+
+	t.Run("invalidate_object_types", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		cache := NewListObjectsCache(memory.New())
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1"}, time.Now())
+
+		objects, ok := cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+		require.Equal(t, []string{"document:1"}, objects)
+
+		cache.InvalidateObjectTypes(ctx, storeID, "label")
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+
+		cache.InvalidateObjectTypes(ctx, storeID, "group")
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		// a result resolved after the invalidation is cached again
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1", "document:2"}, time.Now())
+
+		objects, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+		require.Equal(t, []string{"document:1", "document:2"}, objects)
+	})
+
+	t.Run("invalidate_store", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		cache := NewListObjectsCache(memory.New())
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1"}, time.Now())
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+
+		cache.InvalidateStore(ctx, "other")
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+
+		cache.InvalidateStore(ctx, storeID)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+	})
+
+	t.Run("delete_store", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		cache := NewListObjectsCache(memory.New())
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1"}, time.Now())
+
+		cache.DeleteStore(ctx, storeID)
+		require.Empty(t, cache.stores)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+	})
+
+	t.Run("idle_stores_are_dropped", func(t *testing.T) {
+		cache := NewListObjectsCache(memory.New(), WithListObjectsCacheTTL(10*time.Millisecond))
+		t.Cleanup(cache.Stop)
+
+		idle := ulid.Make().String()
+		_, ok := cache.Get(ctx, req(idle))
+		require.False(t, ok)
+
+		time.Sleep(20 * time.Millisecond)
+
+		storeID := ulid.Make().String()
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		require.Len(t, cache.stores, 1)
+		require.Contains(t, cache.stores, storage.StoreKey(ctx, storeID))
+	})
+
+	t.Run("bounded_by_the_tuple_expirations", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		cache := NewListObjectsCache(memory.New(), WithListObjectsCacheTTL(time.Minute))
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		expirations := &storage.TupleExpirations{}
+		expirations.Observe(time.Now().Add(20 * time.Millisecond))
+		cache.Set(storage.ContextWithTupleExpirations(ctx, expirations), typesys, req(storeID), []string{"document:1"}, time.Now())
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+
+		time.Sleep(30 * time.Millisecond)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+	})
+
+	t.Run("expires_after_ttl", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		cache := NewListObjectsCache(memory.New(), WithListObjectsCacheTTL(time.Millisecond))
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1"}, time.Now())
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+	})
+
+	t.Run("invalidated_by_the_changes_of_the_changelog", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		cache := NewListObjectsCache(ds, WithListObjectsCacheChangelogInterval(0))
+		t.Cleanup(cache.Stop)
+
+		_, ok := cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+
+		cache.Set(ctx, typesys, req(storeID), []string{"document:1"}, time.Now())
+
+		// the changes to the tuples the result does not depend on don't invalidate it
+		err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("label:1", "viewer", "user:jon")})
+		require.NoError(t, err)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.True(t, ok)
+
+		err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("folder:1", "viewer", "user:jon")})
+		require.NoError(t, err)
+
+		_, ok = cache.Get(ctx, req(storeID))
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,238 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const resolutionStatsCtxKey ctxKey = "resolution-stats"
+
+// ResolutionStats accumulates statistics about the resolution of a query: how many datastore queries and
+// dispatches it took, how deep it went and how effective the check cache was. A ResolutionStats is attached to
+// the context of the query with ContextWithResolutionStats, and it is safe for concurrent use.
+type ResolutionStats struct {
+	mu               sync.Mutex
+	datastoreQueries uint32
+	dispatches       uint32
+	maxRemaining     uint32
+	minRemaining     uint32
+	cacheLookups     uint32
+	cacheHits        uint32
+	incomplete       bool
+}
+
+// ContextWithResolutionStats attaches the provided ResolutionStats to the parent context.
+func ContextWithResolutionStats(parent context.Context, stats *ResolutionStats) context.Context {
+	return context.WithValue(parent, resolutionStatsCtxKey, stats)
+}
+
+// ResolutionStatsFromContext returns the ResolutionStats attached to the provided context, or nil. The
+// methods of ResolutionStats are no-ops on a nil ResolutionStats.
+func ResolutionStatsFromContext(ctx context.Context) *ResolutionStats {
+	stats, _ := ctx.Value(resolutionStatsCtxKey).(*ResolutionStats)
+	return stats
+}
+
+// DatastoreQueries is the number of datastore queries issued.
+func (s *ResolutionStats) DatastoreQueries() uint32 {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.datastoreQueries
+}
+
+// Dispatches is the number of subproblems dispatched, i.e. the number of relations resolved on top of the
+// relations of the query.
+func (s *ResolutionStats) Dispatches() uint32 {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dispatches
+}
+
+// MaxDepth is the number of levels of the deepest resolution path (1 if only the relation of the query was
+// resolved), or 0 if nothing was resolved.
+func (s *ResolutionStats) MaxDepth() uint32 {
+	if s == nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxRemaining == 0 {
+		return 0
+	}
+
+	return s.maxRemaining - s.minRemaining + 1
+}
+
+// CacheLookups is the number of subproblems looked up in the check cache, and CacheHits the number of those
+// that were found.
+func (s *ResolutionStats) CacheLookups() (lookups uint32, hits uint32) {
+	if s == nil {
+		return 0, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cacheLookups, s.cacheHits
+}
+
+// MarkIncomplete records that the resolution was cut short by its deadline, so its results may be incomplete.
+func (s *ResolutionStats) MarkIncomplete() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.incomplete = true
+}
+
+// Incomplete reports whether the resolution was cut short by its deadline, see MarkIncomplete.
+func (s *ResolutionStats) Incomplete() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.incomplete
+}
+
+// AddDatastoreQuery counts a datastore query. The reads of the datastores wrapped with NewStatsTupleReader are
+// counted already.
+func (s *ResolutionStats) AddDatastoreQuery() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.datastoreQueries++
+}
+
+func (s *ResolutionStats) addDispatch() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dispatches++
+}
+
+// observeDepth records the remaining resolution depth of a resolved subproblem. The subproblems of the
+// query start with the same (maximum) remaining depth, and every dispatch decreases it.
+func (s *ResolutionStats) observeDepth(remaining uint32) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if remaining > s.maxRemaining {
+		s.maxRemaining = remaining
+	}
+
+	if s.minRemaining == 0 || remaining < s.minRemaining {
+		s.minRemaining = remaining
+	}
+}
+
+// add adds the statistics of another resolution, e.g. one whose outcome the query shares.
+func (s *ResolutionStats) add(other *ResolutionStats) {
+	if s == nil || other == nil {
+		return
+	}
+
+	other.mu.Lock()
+	o := ResolutionStats{
+		datastoreQueries: other.datastoreQueries,
+		dispatches:       other.dispatches,
+		maxRemaining:     other.maxRemaining,
+		minRemaining:     other.minRemaining,
+		cacheLookups:     other.cacheLookups,
+		cacheHits:        other.cacheHits,
+		incomplete:       other.incomplete,
+	}
+	other.mu.Unlock()
+
+	if o.maxRemaining != 0 {
+		s.observeDepth(o.maxRemaining)
+		s.observeDepth(o.minRemaining)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.datastoreQueries += o.datastoreQueries
+	s.dispatches += o.dispatches
+	s.cacheLookups += o.cacheLookups
+	s.cacheHits += o.cacheHits
+	s.incomplete = s.incomplete || o.incomplete
+}
+
+func (s *ResolutionStats) addCacheLookup(hit bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cacheLookups++
+	if hit {
+		s.cacheHits++
+	}
+}
+
+type statsTupleReader struct {
+	storage.RelationshipTupleReader
+}
+
+var _ storage.RelationshipTupleReader = (*statsTupleReader)(nil)
+
+// NewStatsTupleReader returns a wrapper over a datastore that counts the Read, ReadUserTuple,
+// ReadUsersetTuples and ReadStartingWithUser calls in the ResolutionStats of their context, if any.
+func NewStatsTupleReader(wrapped storage.RelationshipTupleReader) storage.RelationshipTupleReader {
+	return &statsTupleReader{RelationshipTupleReader: wrapped}
+}
+
+func (r *statsTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	return r.RelationshipTupleReader.Read(ctx, store, tupleKey)
+}
+
+func (r *statsTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	return r.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+}
+
+func (r *statsTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	return r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func (r *statsTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	return r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+}
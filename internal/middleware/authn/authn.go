@@ -4,9 +4,18 @@ import (
 	"context"
 
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"github.com/openfga/openfga/internal/authn"
 )
 
+const (
+	principalKey = "principal"
+	issuerKey    = "issuer"
+)
+
+// AuthFunc returns a grpc_auth.AuthFunc which authenticates the requests with the authenticator and injects the
+// resulting claims into the request context. The principal and issuer of the claims are also added to the tags of
+// the request, so that they are part of the request logs.
 func AuthFunc(authenticator authn.Authenticator) grpc_auth.AuthFunc {
 	return func(ctx context.Context) (context.Context, error) {
 		claims, err := authenticator.Authenticate(ctx)
@@ -14,6 +23,14 @@ func AuthFunc(authenticator authn.Authenticator) grpc_auth.AuthFunc {
 			return nil, err
 		}
 
+		tags := grpc_ctxtags.Extract(ctx)
+		if principal := claims.Principal(); principal != "" {
+			tags.Set(principalKey, principal)
+		}
+		if claims.Issuer != "" {
+			tags.Set(issuerKey, claims.Issuer)
+		}
+
 		return authn.ContextWithAuthClaims(ctx, claims), nil
 	}
 }
@@ -41,7 +41,10 @@ func NewMockOidcServer(issuerURL string) (*mockOidcServer, error) {
 func (server mockOidcServer) start() {
 	port := strings.Split(server.issuerURL, ":")[2]
 
-	http.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+	// every server has its own mux, so that several issuers can be mocked at once
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
 		err := json.NewEncoder(w).Encode(map[string]string{
 			"issuer":   server.issuerURL,
 			"jwks_uri": fmt.Sprintf("%s/jwks.json", server.issuerURL),
@@ -51,7 +54,7 @@ func (server mockOidcServer) start() {
 		}
 	})
 
-	http.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
 
 		err := json.NewEncoder(w).Encode(map[string]interface{}{
 			"keys": []map[string]string{
@@ -69,7 +72,7 @@ func (server mockOidcServer) start() {
 	})
 
 	go func() {
-		log.Fatal(http.ListenAndServe(":"+port, nil))
+		log.Fatal(http.ListenAndServe(":"+port, mux))
 	}()
 }
 
@@ -82,3 +85,15 @@ func (server mockOidcServer) GetToken(audience, subject string) (string, error)
 	token.Header["kid"] = kidHeader
 	return token.SignedString(server.privateKey)
 }
+
+// GetTokenWithScope returns a token like GetToken, with the provided space separated scopes in its 'scope' claim.
+func (server mockOidcServer) GetTokenWithScope(audience, subject, scope string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   server.issuerURL,
+		"aud":   []string{audience},
+		"sub":   subject,
+		"scope": scope,
+	})
+	token.Header["kid"] = kidHeader
+	return token.SignedString(server.privateKey)
+}
@@ -82,6 +82,22 @@ func (mr *MockTupleBackendMockRecorder) ReadPage(ctx, store, tk, opts interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPage", reflect.TypeOf((*MockTupleBackend)(nil).ReadPage), ctx, store, tk, opts)
 }
 
+// ReadPageWithFilter mocks base method.
+func (m *MockTupleBackend) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPageWithFilter", ctx, store, filter, opts)
+	ret0, _ := ret[0].([]*openfgav1.Tuple)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadPageWithFilter indicates an expected call of ReadPageWithFilter.
+func (mr *MockTupleBackendMockRecorder) ReadPageWithFilter(ctx, store, filter, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPageWithFilter", reflect.TypeOf((*MockTupleBackend)(nil).ReadPageWithFilter), ctx, store, filter, opts)
+}
+
 // ReadStartingWithUser mocks base method.
 func (m *MockTupleBackend) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
 	m.ctrl.T.Helper()
@@ -128,17 +144,22 @@ func (mr *MockTupleBackendMockRecorder) ReadUsersetTuples(ctx, store, filter int
 }
 
 // Write mocks base method.
-func (m *MockTupleBackend) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes) error {
+func (m *MockTupleBackend) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes, opts ...storage.TupleWriteOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", ctx, store, d, w)
+	varargs := []interface{}{ctx, store, d, w}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Write", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write.
-func (mr *MockTupleBackendMockRecorder) Write(ctx, store, d, w interface{}) *gomock.Call {
+func (mr *MockTupleBackendMockRecorder) Write(ctx, store, d, w interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockTupleBackend)(nil).Write), ctx, store, d, w)
+	varargs := append([]interface{}{ctx, store, d, w}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockTupleBackend)(nil).Write), varargs...)
 }
 
 // MockRelationshipTupleReader is a mock of RelationshipTupleReader interface.
@@ -278,17 +299,22 @@ func (mr *MockRelationshipTupleWriterMockRecorder) MaxTuplesPerWrite() *gomock.C
 }
 
 // Write mocks base method.
-func (m *MockRelationshipTupleWriter) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes) error {
+func (m *MockRelationshipTupleWriter) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes, opts ...storage.TupleWriteOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", ctx, store, d, w)
+	varargs := []interface{}{ctx, store, d, w}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Write", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write.
-func (mr *MockRelationshipTupleWriterMockRecorder) Write(ctx, store, d, w interface{}) *gomock.Call {
+func (mr *MockRelationshipTupleWriterMockRecorder) Write(ctx, store, d, w interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockRelationshipTupleWriter)(nil).Write), ctx, store, d, w)
+	varargs := append([]interface{}{ctx, store, d, w}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockRelationshipTupleWriter)(nil).Write), varargs...)
 }
 
 // MockAuthorizationModelReadBackend is a mock of AuthorizationModelReadBackend interface.
@@ -344,6 +370,21 @@ func (mr *MockAuthorizationModelReadBackendMockRecorder) ReadAuthorizationModel(
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelReadBackend)(nil).ReadAuthorizationModel), ctx, store, id)
 }
 
+// ReadAuthorizationModelAnnotations mocks base method.
+func (m *MockAuthorizationModelReadBackend) ReadAuthorizationModelAnnotations(ctx context.Context, store, id string) (storage.ModelAnnotations, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAuthorizationModelAnnotations", ctx, store, id)
+	ret0, _ := ret[0].(storage.ModelAnnotations)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAuthorizationModelAnnotations indicates an expected call of ReadAuthorizationModelAnnotations.
+func (mr *MockAuthorizationModelReadBackendMockRecorder) ReadAuthorizationModelAnnotations(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModelAnnotations", reflect.TypeOf((*MockAuthorizationModelReadBackend)(nil).ReadAuthorizationModelAnnotations), ctx, store, id)
+}
+
 // ReadAuthorizationModels mocks base method.
 func (m *MockAuthorizationModelReadBackend) ReadAuthorizationModels(ctx context.Context, store string, options storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
 	m.ctrl.T.Helper()
@@ -360,6 +401,21 @@ func (mr *MockAuthorizationModelReadBackendMockRecorder) ReadAuthorizationModels
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModels", reflect.TypeOf((*MockAuthorizationModelReadBackend)(nil).ReadAuthorizationModels), ctx, store, options)
 }
 
+// ReadPinnedAuthorizationModelID mocks base method.
+func (m *MockAuthorizationModelReadBackend) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPinnedAuthorizationModelID", ctx, store)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPinnedAuthorizationModelID indicates an expected call of ReadPinnedAuthorizationModelID.
+func (mr *MockAuthorizationModelReadBackendMockRecorder) ReadPinnedAuthorizationModelID(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPinnedAuthorizationModelID", reflect.TypeOf((*MockAuthorizationModelReadBackend)(nil).ReadPinnedAuthorizationModelID), ctx, store)
+}
+
 // MockTypeDefinitionWriteBackend is a mock of TypeDefinitionWriteBackend interface.
 type MockTypeDefinitionWriteBackend struct {
 	ctrl     *gomock.Controller
@@ -383,6 +439,20 @@ func (m *MockTypeDefinitionWriteBackend) EXPECT() *MockTypeDefinitionWriteBacken
 	return m.recorder
 }
 
+// DeleteAuthorizationModel mocks base method.
+func (m *MockTypeDefinitionWriteBackend) DeleteAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthorizationModel indicates an expected call of DeleteAuthorizationModel.
+func (mr *MockTypeDefinitionWriteBackendMockRecorder) DeleteAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthorizationModel", reflect.TypeOf((*MockTypeDefinitionWriteBackend)(nil).DeleteAuthorizationModel), ctx, store, id)
+}
+
 // MaxTypesPerAuthorizationModel mocks base method.
 func (m *MockTypeDefinitionWriteBackend) MaxTypesPerAuthorizationModel() int {
 	m.ctrl.T.Helper()
@@ -411,6 +481,71 @@ func (mr *MockTypeDefinitionWriteBackendMockRecorder) WriteAuthorizationModel(ct
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModel", reflect.TypeOf((*MockTypeDefinitionWriteBackend)(nil).WriteAuthorizationModel), ctx, store, model)
 }
 
+// WriteAuthorizationModelWithAnnotations mocks base method.
+func (m *MockTypeDefinitionWriteBackend) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteAuthorizationModelWithAnnotations", ctx, store, model, annotations)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteAuthorizationModelWithAnnotations indicates an expected call of WriteAuthorizationModelWithAnnotations.
+func (mr *MockTypeDefinitionWriteBackendMockRecorder) WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModelWithAnnotations", reflect.TypeOf((*MockTypeDefinitionWriteBackend)(nil).WriteAuthorizationModelWithAnnotations), ctx, store, model, annotations)
+}
+
+// MockPinnedAuthorizationModelBackend is a mock of PinnedAuthorizationModelBackend interface.
+type MockPinnedAuthorizationModelBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockPinnedAuthorizationModelBackendMockRecorder
+}
+
+// MockPinnedAuthorizationModelBackendMockRecorder is the mock recorder for MockPinnedAuthorizationModelBackend.
+type MockPinnedAuthorizationModelBackendMockRecorder struct {
+	mock *MockPinnedAuthorizationModelBackend
+}
+
+// NewMockPinnedAuthorizationModelBackend creates a new mock instance.
+func NewMockPinnedAuthorizationModelBackend(ctrl *gomock.Controller) *MockPinnedAuthorizationModelBackend {
+	mock := &MockPinnedAuthorizationModelBackend{ctrl: ctrl}
+	mock.recorder = &MockPinnedAuthorizationModelBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPinnedAuthorizationModelBackend) EXPECT() *MockPinnedAuthorizationModelBackendMockRecorder {
+	return m.recorder
+}
+
+// PinAuthorizationModel mocks base method.
+func (m *MockPinnedAuthorizationModelBackend) PinAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinAuthorizationModel indicates an expected call of PinAuthorizationModel.
+func (mr *MockPinnedAuthorizationModelBackendMockRecorder) PinAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinAuthorizationModel", reflect.TypeOf((*MockPinnedAuthorizationModelBackend)(nil).PinAuthorizationModel), ctx, store, id)
+}
+
+// UnpinAuthorizationModel mocks base method.
+func (m *MockPinnedAuthorizationModelBackend) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpinAuthorizationModel", ctx, store)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnpinAuthorizationModel indicates an expected call of UnpinAuthorizationModel.
+func (mr *MockPinnedAuthorizationModelBackendMockRecorder) UnpinAuthorizationModel(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinAuthorizationModel", reflect.TypeOf((*MockPinnedAuthorizationModelBackend)(nil).UnpinAuthorizationModel), ctx, store)
+}
+
 // MockAuthorizationModelBackend is a mock of AuthorizationModelBackend interface.
 type MockAuthorizationModelBackend struct {
 	ctrl     *gomock.Controller
@@ -434,6 +569,20 @@ func (m *MockAuthorizationModelBackend) EXPECT() *MockAuthorizationModelBackendM
 	return m.recorder
 }
 
+// DeleteAuthorizationModel mocks base method.
+func (m *MockAuthorizationModelBackend) DeleteAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthorizationModel indicates an expected call of DeleteAuthorizationModel.
+func (mr *MockAuthorizationModelBackendMockRecorder) DeleteAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).DeleteAuthorizationModel), ctx, store, id)
+}
+
 // FindLatestAuthorizationModelID mocks base method.
 func (m *MockAuthorizationModelBackend) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
 	m.ctrl.T.Helper()
@@ -463,6 +612,20 @@ func (mr *MockAuthorizationModelBackendMockRecorder) MaxTypesPerAuthorizationMod
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxTypesPerAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).MaxTypesPerAuthorizationModel))
 }
 
+// PinAuthorizationModel mocks base method.
+func (m *MockAuthorizationModelBackend) PinAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinAuthorizationModel indicates an expected call of PinAuthorizationModel.
+func (mr *MockAuthorizationModelBackendMockRecorder) PinAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).PinAuthorizationModel), ctx, store, id)
+}
+
 // ReadAuthorizationModel mocks base method.
 func (m *MockAuthorizationModelBackend) ReadAuthorizationModel(ctx context.Context, store, id string) (*openfgav1.AuthorizationModel, error) {
 	m.ctrl.T.Helper()
@@ -478,6 +641,21 @@ func (mr *MockAuthorizationModelBackendMockRecorder) ReadAuthorizationModel(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).ReadAuthorizationModel), ctx, store, id)
 }
 
+// ReadAuthorizationModelAnnotations mocks base method.
+func (m *MockAuthorizationModelBackend) ReadAuthorizationModelAnnotations(ctx context.Context, store, id string) (storage.ModelAnnotations, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAuthorizationModelAnnotations", ctx, store, id)
+	ret0, _ := ret[0].(storage.ModelAnnotations)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAuthorizationModelAnnotations indicates an expected call of ReadAuthorizationModelAnnotations.
+func (mr *MockAuthorizationModelBackendMockRecorder) ReadAuthorizationModelAnnotations(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModelAnnotations", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).ReadAuthorizationModelAnnotations), ctx, store, id)
+}
+
 // ReadAuthorizationModels mocks base method.
 func (m *MockAuthorizationModelBackend) ReadAuthorizationModels(ctx context.Context, store string, options storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
 	m.ctrl.T.Helper()
@@ -494,6 +672,35 @@ func (mr *MockAuthorizationModelBackendMockRecorder) ReadAuthorizationModels(ctx
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModels", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).ReadAuthorizationModels), ctx, store, options)
 }
 
+// ReadPinnedAuthorizationModelID mocks base method.
+func (m *MockAuthorizationModelBackend) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPinnedAuthorizationModelID", ctx, store)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPinnedAuthorizationModelID indicates an expected call of ReadPinnedAuthorizationModelID.
+func (mr *MockAuthorizationModelBackendMockRecorder) ReadPinnedAuthorizationModelID(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPinnedAuthorizationModelID", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).ReadPinnedAuthorizationModelID), ctx, store)
+}
+
+// UnpinAuthorizationModel mocks base method.
+func (m *MockAuthorizationModelBackend) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpinAuthorizationModel", ctx, store)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnpinAuthorizationModel indicates an expected call of UnpinAuthorizationModel.
+func (mr *MockAuthorizationModelBackendMockRecorder) UnpinAuthorizationModel(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).UnpinAuthorizationModel), ctx, store)
+}
+
 // WriteAuthorizationModel mocks base method.
 func (m *MockAuthorizationModelBackend) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
 	m.ctrl.T.Helper()
@@ -508,6 +715,20 @@ func (mr *MockAuthorizationModelBackendMockRecorder) WriteAuthorizationModel(ctx
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModel", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).WriteAuthorizationModel), ctx, store, model)
 }
 
+// WriteAuthorizationModelWithAnnotations mocks base method.
+func (m *MockAuthorizationModelBackend) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteAuthorizationModelWithAnnotations", ctx, store, model, annotations)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteAuthorizationModelWithAnnotations indicates an expected call of WriteAuthorizationModelWithAnnotations.
+func (mr *MockAuthorizationModelBackendMockRecorder) WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModelWithAnnotations", reflect.TypeOf((*MockAuthorizationModelBackend)(nil).WriteAuthorizationModelWithAnnotations), ctx, store, model, annotations)
+}
+
 // MockStoresBackend is a mock of StoresBackend interface.
 type MockStoresBackend struct {
 	ctrl     *gomock.Controller
@@ -591,6 +812,81 @@ func (mr *MockStoresBackendMockRecorder) ListStores(ctx, paginationOptions inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStores", reflect.TypeOf((*MockStoresBackend)(nil).ListStores), ctx, paginationOptions)
 }
 
+// ListStoresWithFilter mocks base method.
+func (m *MockStoresBackend) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStoresWithFilter", ctx, filter, paginationOptions)
+	ret0, _ := ret[0].([]*openfgav1.Store)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListStoresWithFilter indicates an expected call of ListStoresWithFilter.
+func (mr *MockStoresBackendMockRecorder) ListStoresWithFilter(ctx, filter, paginationOptions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStoresWithFilter", reflect.TypeOf((*MockStoresBackend)(nil).ListStoresWithFilter), ctx, filter, paginationOptions)
+}
+
+// PurgeDeletedStores mocks base method.
+func (m *MockStoresBackend) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedStores", ctx, deletedBefore, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedStores indicates an expected call of PurgeDeletedStores.
+func (mr *MockStoresBackendMockRecorder) PurgeDeletedStores(ctx, deletedBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedStores", reflect.TypeOf((*MockStoresBackend)(nil).PurgeDeletedStores), ctx, deletedBefore, limit)
+}
+
+// ReadStoreMetadata mocks base method.
+func (m *MockStoresBackend) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadStoreMetadata", ctx, id)
+	ret0, _ := ret[0].(*storage.StoreMetadata)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadStoreMetadata indicates an expected call of ReadStoreMetadata.
+func (mr *MockStoresBackendMockRecorder) ReadStoreMetadata(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStoreMetadata", reflect.TypeOf((*MockStoresBackend)(nil).ReadStoreMetadata), ctx, id)
+}
+
+// UndeleteStore mocks base method.
+func (m *MockStoresBackend) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UndeleteStore", ctx, id, deletedAfter)
+	ret0, _ := ret[0].(*openfgav1.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UndeleteStore indicates an expected call of UndeleteStore.
+func (mr *MockStoresBackendMockRecorder) UndeleteStore(ctx, id, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UndeleteStore", reflect.TypeOf((*MockStoresBackend)(nil).UndeleteStore), ctx, id, deletedAfter)
+}
+
+// WriteStoreMetadata mocks base method.
+func (m *MockStoresBackend) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteStoreMetadata", ctx, id, metadata)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteStoreMetadata indicates an expected call of WriteStoreMetadata.
+func (mr *MockStoresBackendMockRecorder) WriteStoreMetadata(ctx, id, metadata interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteStoreMetadata", reflect.TypeOf((*MockStoresBackend)(nil).WriteStoreMetadata), ctx, id, metadata)
+}
+
 // MockAssertionsBackend is a mock of AssertionsBackend interface.
 type MockAssertionsBackend struct {
 	ctrl     *gomock.Controller
@@ -666,6 +962,21 @@ func (m *MockChangelogBackend) EXPECT() *MockChangelogBackendMockRecorder {
 	return m.recorder
 }
 
+// DeleteChanges mocks base method.
+func (m *MockChangelogBackend) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteChanges", ctx, store, before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteChanges indicates an expected call of DeleteChanges.
+func (mr *MockChangelogBackendMockRecorder) DeleteChanges(ctx, store, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChanges", reflect.TypeOf((*MockChangelogBackend)(nil).DeleteChanges), ctx, store, before, limit)
+}
+
 // ReadChanges mocks base method.
 func (m *MockChangelogBackend) ReadChanges(ctx context.Context, store, objectType string, paginationOptions storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
 	m.ctrl.T.Helper()
@@ -682,118 +993,612 @@ func (mr *MockChangelogBackendMockRecorder) ReadChanges(ctx, store, objectType,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChanges", reflect.TypeOf((*MockChangelogBackend)(nil).ReadChanges), ctx, store, objectType, paginationOptions, horizonOffset)
 }
 
-// MockOpenFGADatastore is a mock of OpenFGADatastore interface.
-type MockOpenFGADatastore struct {
+// ReadChangesWithFilter mocks base method.
+func (m *MockChangelogBackend) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, paginationOptions storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadChangesWithFilter", ctx, store, filter, paginationOptions, horizonOffset)
+	ret0, _ := ret[0].([]*openfgav1.TupleChange)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadChangesWithFilter indicates an expected call of ReadChangesWithFilter.
+func (mr *MockChangelogBackendMockRecorder) ReadChangesWithFilter(ctx, store, filter, paginationOptions, horizonOffset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChangesWithFilter", reflect.TypeOf((*MockChangelogBackend)(nil).ReadChangesWithFilter), ctx, store, filter, paginationOptions, horizonOffset)
+}
+
+// MockTupleExpirationBackend is a mock of TupleExpirationBackend interface.
+type MockTupleExpirationBackend struct {
 	ctrl     *gomock.Controller
-	recorder *MockOpenFGADatastoreMockRecorder
+	recorder *MockTupleExpirationBackendMockRecorder
 }
 
-// MockOpenFGADatastoreMockRecorder is the mock recorder for MockOpenFGADatastore.
-type MockOpenFGADatastoreMockRecorder struct {
-	mock *MockOpenFGADatastore
+// MockTupleExpirationBackendMockRecorder is the mock recorder for MockTupleExpirationBackend.
+type MockTupleExpirationBackendMockRecorder struct {
+	mock *MockTupleExpirationBackend
 }
 
-// NewMockOpenFGADatastore creates a new mock instance.
-func NewMockOpenFGADatastore(ctrl *gomock.Controller) *MockOpenFGADatastore {
-	mock := &MockOpenFGADatastore{ctrl: ctrl}
-	mock.recorder = &MockOpenFGADatastoreMockRecorder{mock}
+// NewMockTupleExpirationBackend creates a new mock instance.
+func NewMockTupleExpirationBackend(ctrl *gomock.Controller) *MockTupleExpirationBackend {
+	mock := &MockTupleExpirationBackend{ctrl: ctrl}
+	mock.recorder = &MockTupleExpirationBackendMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockOpenFGADatastore) EXPECT() *MockOpenFGADatastoreMockRecorder {
+func (m *MockTupleExpirationBackend) EXPECT() *MockTupleExpirationBackendMockRecorder {
 	return m.recorder
 }
 
-// Close mocks base method.
-func (m *MockOpenFGADatastore) Close() {
+// DeleteExpiredTuples mocks base method.
+func (m *MockTupleExpirationBackend) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Close")
+	ret := m.ctrl.Call(m, "DeleteExpiredTuples", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Close indicates an expected call of Close.
-func (mr *MockOpenFGADatastoreMockRecorder) Close() *gomock.Call {
+// DeleteExpiredTuples indicates an expected call of DeleteExpiredTuples.
+func (mr *MockTupleExpirationBackendMockRecorder) DeleteExpiredTuples(ctx, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockOpenFGADatastore)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredTuples", reflect.TypeOf((*MockTupleExpirationBackend)(nil).DeleteExpiredTuples), ctx, limit)
 }
 
-// CreateStore mocks base method.
-func (m *MockOpenFGADatastore) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+// ReadTupleExpirations mocks base method.
+func (m *MockTupleExpirationBackend) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateStore", ctx, store)
-	ret0, _ := ret[0].(*openfgav1.Store)
+	ret := m.ctrl.Call(m, "ReadTupleExpirations", ctx, store, filter)
+	ret0, _ := ret[0].(map[string]time.Time)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateStore indicates an expected call of CreateStore.
-func (mr *MockOpenFGADatastoreMockRecorder) CreateStore(ctx, store interface{}) *gomock.Call {
+// ReadTupleExpirations indicates an expected call of ReadTupleExpirations.
+func (mr *MockTupleExpirationBackendMockRecorder) ReadTupleExpirations(ctx, store, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).CreateStore), ctx, store)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTupleExpirations", reflect.TypeOf((*MockTupleExpirationBackend)(nil).ReadTupleExpirations), ctx, store, filter)
 }
 
-// DeleteStore mocks base method.
-func (m *MockOpenFGADatastore) DeleteStore(ctx context.Context, id string) error {
+// WriteWithExpiry mocks base method.
+func (m *MockTupleExpirationBackend) WriteWithExpiry(ctx context.Context, store string, d storage.Deletes, w storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteStore", ctx, id)
+	varargs := []interface{}{ctx, store, d, w, expiresAt}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WriteWithExpiry", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteStore indicates an expected call of DeleteStore.
-func (mr *MockOpenFGADatastoreMockRecorder) DeleteStore(ctx, id interface{}) *gomock.Call {
+// WriteWithExpiry indicates an expected call of WriteWithExpiry.
+func (mr *MockTupleExpirationBackendMockRecorder) WriteWithExpiry(ctx, store, d, w, expiresAt interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).DeleteStore), ctx, id)
+	varargs := append([]interface{}{ctx, store, d, w, expiresAt}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithExpiry", reflect.TypeOf((*MockTupleExpirationBackend)(nil).WriteWithExpiry), varargs...)
 }
 
-// FindLatestAuthorizationModelID mocks base method.
-func (m *MockOpenFGADatastore) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindLatestAuthorizationModelID", ctx, store)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+// MockTupleConditionBackend is a mock of TupleConditionBackend interface.
+type MockTupleConditionBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockTupleConditionBackendMockRecorder
 }
 
-// FindLatestAuthorizationModelID indicates an expected call of FindLatestAuthorizationModelID.
-func (mr *MockOpenFGADatastoreMockRecorder) FindLatestAuthorizationModelID(ctx, store interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLatestAuthorizationModelID", reflect.TypeOf((*MockOpenFGADatastore)(nil).FindLatestAuthorizationModelID), ctx, store)
+// MockTupleConditionBackendMockRecorder is the mock recorder for MockTupleConditionBackend.
+type MockTupleConditionBackendMockRecorder struct {
+	mock *MockTupleConditionBackend
 }
 
-// GetStore mocks base method.
-func (m *MockOpenFGADatastore) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+// NewMockTupleConditionBackend creates a new mock instance.
+func NewMockTupleConditionBackend(ctrl *gomock.Controller) *MockTupleConditionBackend {
+	mock := &MockTupleConditionBackend{ctrl: ctrl}
+	mock.recorder = &MockTupleConditionBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTupleConditionBackend) EXPECT() *MockTupleConditionBackendMockRecorder {
+	return m.recorder
+}
+
+// ReadTupleConditions mocks base method.
+func (m *MockTupleConditionBackend) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetStore", ctx, id)
-	ret0, _ := ret[0].(*openfgav1.Store)
+	ret := m.ctrl.Call(m, "ReadTupleConditions", ctx, store, filter)
+	ret0, _ := ret[0].(map[string]*storage.TupleCondition)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetStore indicates an expected call of GetStore.
-func (mr *MockOpenFGADatastoreMockRecorder) GetStore(ctx, id interface{}) *gomock.Call {
+// ReadTupleConditions indicates an expected call of ReadTupleConditions.
+func (mr *MockTupleConditionBackendMockRecorder) ReadTupleConditions(ctx, store, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).GetStore), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTupleConditions", reflect.TypeOf((*MockTupleConditionBackend)(nil).ReadTupleConditions), ctx, store, filter)
 }
 
-// IsReady mocks base method.
-func (m *MockOpenFGADatastore) IsReady(ctx context.Context) (bool, error) {
+// WriteWithCondition mocks base method.
+func (m *MockTupleConditionBackend) WriteWithCondition(ctx context.Context, store string, d storage.Deletes, w storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsReady", ctx)
-	ret0, _ := ret[0].(bool)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	varargs := []interface{}{ctx, store, d, w, condition}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WriteWithCondition", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// IsReady indicates an expected call of IsReady.
-func (mr *MockOpenFGADatastoreMockRecorder) IsReady(ctx interface{}) *gomock.Call {
+// WriteWithCondition indicates an expected call of WriteWithCondition.
+func (mr *MockTupleConditionBackendMockRecorder) WriteWithCondition(ctx, store, d, w, condition interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockOpenFGADatastore)(nil).IsReady), ctx)
+	varargs := append([]interface{}{ctx, store, d, w, condition}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithCondition", reflect.TypeOf((*MockTupleConditionBackend)(nil).WriteWithCondition), varargs...)
 }
 
-// ListStores mocks base method.
-func (m *MockOpenFGADatastore) ListStores(ctx context.Context, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
-	m.ctrl.T.Helper()
+// MockStagedWriteBackend is a mock of StagedWriteBackend interface.
+type MockStagedWriteBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockStagedWriteBackendMockRecorder
+}
+
+// MockStagedWriteBackendMockRecorder is the mock recorder for MockStagedWriteBackend.
+type MockStagedWriteBackendMockRecorder struct {
+	mock *MockStagedWriteBackend
+}
+
+// NewMockStagedWriteBackend creates a new mock instance.
+func NewMockStagedWriteBackend(ctrl *gomock.Controller) *MockStagedWriteBackend {
+	mock := &MockStagedWriteBackend{ctrl: ctrl}
+	mock.recorder = &MockStagedWriteBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStagedWriteBackend) EXPECT() *MockStagedWriteBackendMockRecorder {
+	return m.recorder
+}
+
+// CommitStagedWrite mocks base method.
+func (m *MockStagedWriteBackend) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, store, id}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CommitStagedWrite", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CommitStagedWrite indicates an expected call of CommitStagedWrite.
+func (mr *MockStagedWriteBackendMockRecorder) CommitStagedWrite(ctx, store, id interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, store, id}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitStagedWrite", reflect.TypeOf((*MockStagedWriteBackend)(nil).CommitStagedWrite), varargs...)
+}
+
+// DiscardStagedWrite mocks base method.
+func (m *MockStagedWriteBackend) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiscardStagedWrite", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DiscardStagedWrite indicates an expected call of DiscardStagedWrite.
+func (mr *MockStagedWriteBackendMockRecorder) DiscardStagedWrite(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscardStagedWrite", reflect.TypeOf((*MockStagedWriteBackend)(nil).DiscardStagedWrite), ctx, store, id)
+}
+
+// StageWrite mocks base method.
+func (m *MockStagedWriteBackend) StageWrite(ctx context.Context, store, id string, d storage.Deletes, w storage.Writes) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageWrite", ctx, store, id, d, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageWrite indicates an expected call of StageWrite.
+func (mr *MockStagedWriteBackendMockRecorder) StageWrite(ctx, store, id, d, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageWrite", reflect.TypeOf((*MockStagedWriteBackend)(nil).StageWrite), ctx, store, id, d, w)
+}
+
+// MockSnapshotReader is a mock of SnapshotReader interface.
+type MockSnapshotReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockSnapshotReaderMockRecorder
+}
+
+// MockSnapshotReaderMockRecorder is the mock recorder for MockSnapshotReader.
+type MockSnapshotReaderMockRecorder struct {
+	mock *MockSnapshotReader
+}
+
+// NewMockSnapshotReader creates a new mock instance.
+func NewMockSnapshotReader(ctrl *gomock.Controller) *MockSnapshotReader {
+	mock := &MockSnapshotReader{ctrl: ctrl}
+	mock.recorder = &MockSnapshotReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSnapshotReader) EXPECT() *MockSnapshotReaderMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockSnapshotReader) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockSnapshotReaderMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSnapshotReader)(nil).Close))
+}
+
+// Read mocks base method.
+func (m *MockSnapshotReader) Read(arg0 context.Context, arg1 string, arg2 *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0, arg1, arg2)
+	ret0, _ := ret[0].(storage.TupleIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockSnapshotReaderMockRecorder) Read(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockSnapshotReader)(nil).Read), arg0, arg1, arg2)
+}
+
+// ReadPage mocks base method.
+func (m *MockSnapshotReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPage", ctx, store, tk, opts)
+	ret0, _ := ret[0].([]*openfgav1.Tuple)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadPage indicates an expected call of ReadPage.
+func (mr *MockSnapshotReaderMockRecorder) ReadPage(ctx, store, tk, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPage", reflect.TypeOf((*MockSnapshotReader)(nil).ReadPage), ctx, store, tk, opts)
+}
+
+// ReadStartingWithUser mocks base method.
+func (m *MockSnapshotReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadStartingWithUser", ctx, store, filter)
+	ret0, _ := ret[0].(storage.TupleIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadStartingWithUser indicates an expected call of ReadStartingWithUser.
+func (mr *MockSnapshotReaderMockRecorder) ReadStartingWithUser(ctx, store, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStartingWithUser", reflect.TypeOf((*MockSnapshotReader)(nil).ReadStartingWithUser), ctx, store, filter)
+}
+
+// ReadTupleConditions mocks base method.
+func (m *MockSnapshotReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadTupleConditions", ctx, store, filter)
+	ret0, _ := ret[0].(map[string]*storage.TupleCondition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadTupleConditions indicates an expected call of ReadTupleConditions.
+func (mr *MockSnapshotReaderMockRecorder) ReadTupleConditions(ctx, store, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTupleConditions", reflect.TypeOf((*MockSnapshotReader)(nil).ReadTupleConditions), ctx, store, filter)
+}
+
+// ReadUserTuple mocks base method.
+func (m *MockSnapshotReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadUserTuple", ctx, store, tk)
+	ret0, _ := ret[0].(*openfgav1.Tuple)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadUserTuple indicates an expected call of ReadUserTuple.
+func (mr *MockSnapshotReaderMockRecorder) ReadUserTuple(ctx, store, tk interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadUserTuple", reflect.TypeOf((*MockSnapshotReader)(nil).ReadUserTuple), ctx, store, tk)
+}
+
+// ReadUsersetTuples mocks base method.
+func (m *MockSnapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadUsersetTuples", ctx, store, filter)
+	ret0, _ := ret[0].(storage.TupleIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadUsersetTuples indicates an expected call of ReadUsersetTuples.
+func (mr *MockSnapshotReaderMockRecorder) ReadUsersetTuples(ctx, store, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadUsersetTuples", reflect.TypeOf((*MockSnapshotReader)(nil).ReadUsersetTuples), ctx, store, filter)
+}
+
+// MockSnapshotBackend is a mock of SnapshotBackend interface.
+type MockSnapshotBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockSnapshotBackendMockRecorder
+}
+
+// MockSnapshotBackendMockRecorder is the mock recorder for MockSnapshotBackend.
+type MockSnapshotBackendMockRecorder struct {
+	mock *MockSnapshotBackend
+}
+
+// NewMockSnapshotBackend creates a new mock instance.
+func NewMockSnapshotBackend(ctrl *gomock.Controller) *MockSnapshotBackend {
+	mock := &MockSnapshotBackend{ctrl: ctrl}
+	mock.recorder = &MockSnapshotBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSnapshotBackend) EXPECT() *MockSnapshotBackendMockRecorder {
+	return m.recorder
+}
+
+// Snapshot mocks base method.
+func (m *MockSnapshotBackend) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx, store)
+	ret0, _ := ret[0].(storage.SnapshotReader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockSnapshotBackendMockRecorder) Snapshot(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockSnapshotBackend)(nil).Snapshot), ctx, store)
+}
+
+// MockStoreStatsBackend is a mock of StoreStatsBackend interface.
+type MockStoreStatsBackend struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreStatsBackendMockRecorder
+}
+
+// MockStoreStatsBackendMockRecorder is the mock recorder for MockStoreStatsBackend.
+type MockStoreStatsBackendMockRecorder struct {
+	mock *MockStoreStatsBackend
+}
+
+// NewMockStoreStatsBackend creates a new mock instance.
+func NewMockStoreStatsBackend(ctrl *gomock.Controller) *MockStoreStatsBackend {
+	mock := &MockStoreStatsBackend{ctrl: ctrl}
+	mock.recorder = &MockStoreStatsBackendMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStoreStatsBackend) EXPECT() *MockStoreStatsBackendMockRecorder {
+	return m.recorder
+}
+
+// ReadStoreStats mocks base method.
+func (m *MockStoreStatsBackend) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadStoreStats", ctx, store)
+	ret0, _ := ret[0].(*storage.StoreStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadStoreStats indicates an expected call of ReadStoreStats.
+func (mr *MockStoreStatsBackendMockRecorder) ReadStoreStats(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStoreStats", reflect.TypeOf((*MockStoreStatsBackend)(nil).ReadStoreStats), ctx, store)
+}
+
+// MockOpenFGADatastore is a mock of OpenFGADatastore interface.
+type MockOpenFGADatastore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOpenFGADatastoreMockRecorder
+}
+
+// MockOpenFGADatastoreMockRecorder is the mock recorder for MockOpenFGADatastore.
+type MockOpenFGADatastoreMockRecorder struct {
+	mock *MockOpenFGADatastore
+}
+
+// NewMockOpenFGADatastore creates a new mock instance.
+func NewMockOpenFGADatastore(ctrl *gomock.Controller) *MockOpenFGADatastore {
+	mock := &MockOpenFGADatastore{ctrl: ctrl}
+	mock.recorder = &MockOpenFGADatastoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOpenFGADatastore) EXPECT() *MockOpenFGADatastoreMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockOpenFGADatastore) Close() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Close")
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockOpenFGADatastoreMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockOpenFGADatastore)(nil).Close))
+}
+
+// CommitStagedWrite mocks base method.
+func (m *MockOpenFGADatastore) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, store, id}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CommitStagedWrite", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CommitStagedWrite indicates an expected call of CommitStagedWrite.
+func (mr *MockOpenFGADatastoreMockRecorder) CommitStagedWrite(ctx, store, id interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, store, id}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitStagedWrite", reflect.TypeOf((*MockOpenFGADatastore)(nil).CommitStagedWrite), varargs...)
+}
+
+// CreateStore mocks base method.
+func (m *MockOpenFGADatastore) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateStore", ctx, store)
+	ret0, _ := ret[0].(*openfgav1.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateStore indicates an expected call of CreateStore.
+func (mr *MockOpenFGADatastoreMockRecorder) CreateStore(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).CreateStore), ctx, store)
+}
+
+// DeleteAuthorizationModel mocks base method.
+func (m *MockOpenFGADatastore) DeleteAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAuthorizationModel indicates an expected call of DeleteAuthorizationModel.
+func (mr *MockOpenFGADatastoreMockRecorder) DeleteAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).DeleteAuthorizationModel), ctx, store, id)
+}
+
+// DeleteChanges mocks base method.
+func (m *MockOpenFGADatastore) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteChanges", ctx, store, before, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteChanges indicates an expected call of DeleteChanges.
+func (mr *MockOpenFGADatastoreMockRecorder) DeleteChanges(ctx, store, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChanges", reflect.TypeOf((*MockOpenFGADatastore)(nil).DeleteChanges), ctx, store, before, limit)
+}
+
+// DeleteExpiredTuples mocks base method.
+func (m *MockOpenFGADatastore) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredTuples", ctx, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredTuples indicates an expected call of DeleteExpiredTuples.
+func (mr *MockOpenFGADatastoreMockRecorder) DeleteExpiredTuples(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredTuples", reflect.TypeOf((*MockOpenFGADatastore)(nil).DeleteExpiredTuples), ctx, limit)
+}
+
+// DeleteStore mocks base method.
+func (m *MockOpenFGADatastore) DeleteStore(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteStore", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteStore indicates an expected call of DeleteStore.
+func (mr *MockOpenFGADatastoreMockRecorder) DeleteStore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).DeleteStore), ctx, id)
+}
+
+// DiscardStagedWrite mocks base method.
+func (m *MockOpenFGADatastore) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiscardStagedWrite", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DiscardStagedWrite indicates an expected call of DiscardStagedWrite.
+func (mr *MockOpenFGADatastoreMockRecorder) DiscardStagedWrite(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiscardStagedWrite", reflect.TypeOf((*MockOpenFGADatastore)(nil).DiscardStagedWrite), ctx, store, id)
+}
+
+// FindLatestAuthorizationModelID mocks base method.
+func (m *MockOpenFGADatastore) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLatestAuthorizationModelID", ctx, store)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLatestAuthorizationModelID indicates an expected call of FindLatestAuthorizationModelID.
+func (mr *MockOpenFGADatastoreMockRecorder) FindLatestAuthorizationModelID(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLatestAuthorizationModelID", reflect.TypeOf((*MockOpenFGADatastore)(nil).FindLatestAuthorizationModelID), ctx, store)
+}
+
+// GetStore mocks base method.
+func (m *MockOpenFGADatastore) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStore", ctx, id)
+	ret0, _ := ret[0].(*openfgav1.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStore indicates an expected call of GetStore.
+func (mr *MockOpenFGADatastoreMockRecorder) GetStore(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).GetStore), ctx, id)
+}
+
+// IsReady mocks base method.
+func (m *MockOpenFGADatastore) IsReady(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsReady", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsReady indicates an expected call of IsReady.
+func (mr *MockOpenFGADatastoreMockRecorder) IsReady(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockOpenFGADatastore)(nil).IsReady), ctx)
+}
+
+// ListStores mocks base method.
+func (m *MockOpenFGADatastore) ListStores(ctx context.Context, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListStores", ctx, paginationOptions)
 	ret0, _ := ret[0].([]*openfgav1.Store)
 	ret1, _ := ret[1].([]byte)
@@ -807,6 +1612,22 @@ func (mr *MockOpenFGADatastoreMockRecorder) ListStores(ctx, paginationOptions in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStores", reflect.TypeOf((*MockOpenFGADatastore)(nil).ListStores), ctx, paginationOptions)
 }
 
+// ListStoresWithFilter mocks base method.
+func (m *MockOpenFGADatastore) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStoresWithFilter", ctx, filter, paginationOptions)
+	ret0, _ := ret[0].([]*openfgav1.Store)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListStoresWithFilter indicates an expected call of ListStoresWithFilter.
+func (mr *MockOpenFGADatastoreMockRecorder) ListStoresWithFilter(ctx, filter, paginationOptions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStoresWithFilter", reflect.TypeOf((*MockOpenFGADatastore)(nil).ListStoresWithFilter), ctx, filter, paginationOptions)
+}
+
 // MaxTuplesPerWrite mocks base method.
 func (m *MockOpenFGADatastore) MaxTuplesPerWrite() int {
 	m.ctrl.T.Helper()
@@ -835,6 +1656,35 @@ func (mr *MockOpenFGADatastoreMockRecorder) MaxTypesPerAuthorizationModel() *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxTypesPerAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).MaxTypesPerAuthorizationModel))
 }
 
+// PinAuthorizationModel mocks base method.
+func (m *MockOpenFGADatastore) PinAuthorizationModel(ctx context.Context, store, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PinAuthorizationModel", ctx, store, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PinAuthorizationModel indicates an expected call of PinAuthorizationModel.
+func (mr *MockOpenFGADatastoreMockRecorder) PinAuthorizationModel(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PinAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).PinAuthorizationModel), ctx, store, id)
+}
+
+// PurgeDeletedStores mocks base method.
+func (m *MockOpenFGADatastore) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedStores", ctx, deletedBefore, limit)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedStores indicates an expected call of PurgeDeletedStores.
+func (mr *MockOpenFGADatastoreMockRecorder) PurgeDeletedStores(ctx, deletedBefore, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedStores", reflect.TypeOf((*MockOpenFGADatastore)(nil).PurgeDeletedStores), ctx, deletedBefore, limit)
+}
+
 // Read mocks base method.
 func (m *MockOpenFGADatastore) Read(arg0 context.Context, arg1 string, arg2 *openfgav1.TupleKey) (storage.TupleIterator, error) {
 	m.ctrl.T.Helper()
@@ -880,6 +1730,21 @@ func (mr *MockOpenFGADatastoreMockRecorder) ReadAuthorizationModel(ctx, store, i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadAuthorizationModel), ctx, store, id)
 }
 
+// ReadAuthorizationModelAnnotations mocks base method.
+func (m *MockOpenFGADatastore) ReadAuthorizationModelAnnotations(ctx context.Context, store, id string) (storage.ModelAnnotations, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAuthorizationModelAnnotations", ctx, store, id)
+	ret0, _ := ret[0].(storage.ModelAnnotations)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAuthorizationModelAnnotations indicates an expected call of ReadAuthorizationModelAnnotations.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadAuthorizationModelAnnotations(ctx, store, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAuthorizationModelAnnotations", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadAuthorizationModelAnnotations), ctx, store, id)
+}
+
 // ReadAuthorizationModels mocks base method.
 func (m *MockOpenFGADatastore) ReadAuthorizationModels(ctx context.Context, store string, options storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
 	m.ctrl.T.Helper()
@@ -912,6 +1777,22 @@ func (mr *MockOpenFGADatastoreMockRecorder) ReadChanges(ctx, store, objectType,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChanges", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadChanges), ctx, store, objectType, paginationOptions, horizonOffset)
 }
 
+// ReadChangesWithFilter mocks base method.
+func (m *MockOpenFGADatastore) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, paginationOptions storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadChangesWithFilter", ctx, store, filter, paginationOptions, horizonOffset)
+	ret0, _ := ret[0].([]*openfgav1.TupleChange)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadChangesWithFilter indicates an expected call of ReadChangesWithFilter.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadChangesWithFilter(ctx, store, filter, paginationOptions, horizonOffset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadChangesWithFilter", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadChangesWithFilter), ctx, store, filter, paginationOptions, horizonOffset)
+}
+
 // ReadPage mocks base method.
 func (m *MockOpenFGADatastore) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
 	m.ctrl.T.Helper()
@@ -928,6 +1809,37 @@ func (mr *MockOpenFGADatastoreMockRecorder) ReadPage(ctx, store, tk, opts interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPage", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadPage), ctx, store, tk, opts)
 }
 
+// ReadPageWithFilter mocks base method.
+func (m *MockOpenFGADatastore) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPageWithFilter", ctx, store, filter, opts)
+	ret0, _ := ret[0].([]*openfgav1.Tuple)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadPageWithFilter indicates an expected call of ReadPageWithFilter.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadPageWithFilter(ctx, store, filter, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPageWithFilter", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadPageWithFilter), ctx, store, filter, opts)
+}
+
+// ReadPinnedAuthorizationModelID mocks base method.
+func (m *MockOpenFGADatastore) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadPinnedAuthorizationModelID", ctx, store)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadPinnedAuthorizationModelID indicates an expected call of ReadPinnedAuthorizationModelID.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadPinnedAuthorizationModelID(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadPinnedAuthorizationModelID", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadPinnedAuthorizationModelID), ctx, store)
+}
+
 // ReadStartingWithUser mocks base method.
 func (m *MockOpenFGADatastore) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
 	m.ctrl.T.Helper()
@@ -943,6 +1855,66 @@ func (mr *MockOpenFGADatastoreMockRecorder) ReadStartingWithUser(ctx, store, fil
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStartingWithUser", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadStartingWithUser), ctx, store, filter)
 }
 
+// ReadStoreMetadata mocks base method.
+func (m *MockOpenFGADatastore) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadStoreMetadata", ctx, id)
+	ret0, _ := ret[0].(*storage.StoreMetadata)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadStoreMetadata indicates an expected call of ReadStoreMetadata.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadStoreMetadata(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStoreMetadata", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadStoreMetadata), ctx, id)
+}
+
+// ReadStoreStats mocks base method.
+func (m *MockOpenFGADatastore) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadStoreStats", ctx, store)
+	ret0, _ := ret[0].(*storage.StoreStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadStoreStats indicates an expected call of ReadStoreStats.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadStoreStats(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadStoreStats", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadStoreStats), ctx, store)
+}
+
+// ReadTupleConditions mocks base method.
+func (m *MockOpenFGADatastore) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadTupleConditions", ctx, store, filter)
+	ret0, _ := ret[0].(map[string]*storage.TupleCondition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadTupleConditions indicates an expected call of ReadTupleConditions.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadTupleConditions(ctx, store, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTupleConditions", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadTupleConditions), ctx, store, filter)
+}
+
+// ReadTupleExpirations mocks base method.
+func (m *MockOpenFGADatastore) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadTupleExpirations", ctx, store, filter)
+	ret0, _ := ret[0].(map[string]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadTupleExpirations indicates an expected call of ReadTupleExpirations.
+func (mr *MockOpenFGADatastoreMockRecorder) ReadTupleExpirations(ctx, store, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadTupleExpirations", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadTupleExpirations), ctx, store, filter)
+}
+
 // ReadUserTuple mocks base method.
 func (m *MockOpenFGADatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
 	m.ctrl.T.Helper()
@@ -973,18 +1945,81 @@ func (mr *MockOpenFGADatastoreMockRecorder) ReadUsersetTuples(ctx, store, filter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadUsersetTuples", reflect.TypeOf((*MockOpenFGADatastore)(nil).ReadUsersetTuples), ctx, store, filter)
 }
 
+// Snapshot mocks base method.
+func (m *MockOpenFGADatastore) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx, store)
+	ret0, _ := ret[0].(storage.SnapshotReader)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockOpenFGADatastoreMockRecorder) Snapshot(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockOpenFGADatastore)(nil).Snapshot), ctx, store)
+}
+
+// StageWrite mocks base method.
+func (m *MockOpenFGADatastore) StageWrite(ctx context.Context, store, id string, d storage.Deletes, w storage.Writes) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageWrite", ctx, store, id, d, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageWrite indicates an expected call of StageWrite.
+func (mr *MockOpenFGADatastoreMockRecorder) StageWrite(ctx, store, id, d, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageWrite", reflect.TypeOf((*MockOpenFGADatastore)(nil).StageWrite), ctx, store, id, d, w)
+}
+
+// UndeleteStore mocks base method.
+func (m *MockOpenFGADatastore) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UndeleteStore", ctx, id, deletedAfter)
+	ret0, _ := ret[0].(*openfgav1.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UndeleteStore indicates an expected call of UndeleteStore.
+func (mr *MockOpenFGADatastoreMockRecorder) UndeleteStore(ctx, id, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UndeleteStore", reflect.TypeOf((*MockOpenFGADatastore)(nil).UndeleteStore), ctx, id, deletedAfter)
+}
+
+// UnpinAuthorizationModel mocks base method.
+func (m *MockOpenFGADatastore) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpinAuthorizationModel", ctx, store)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnpinAuthorizationModel indicates an expected call of UnpinAuthorizationModel.
+func (mr *MockOpenFGADatastoreMockRecorder) UnpinAuthorizationModel(ctx, store interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpinAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).UnpinAuthorizationModel), ctx, store)
+}
+
 // Write mocks base method.
-func (m *MockOpenFGADatastore) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes) error {
+func (m *MockOpenFGADatastore) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes, opts ...storage.TupleWriteOption) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", ctx, store, d, w)
+	varargs := []interface{}{ctx, store, d, w}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Write", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write.
-func (mr *MockOpenFGADatastoreMockRecorder) Write(ctx, store, d, w interface{}) *gomock.Call {
+func (mr *MockOpenFGADatastoreMockRecorder) Write(ctx, store, d, w interface{}, opts ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockOpenFGADatastore)(nil).Write), ctx, store, d, w)
+	varargs := append([]interface{}{ctx, store, d, w}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockOpenFGADatastore)(nil).Write), varargs...)
 }
 
 // WriteAssertions mocks base method.
@@ -1014,3 +2049,69 @@ func (mr *MockOpenFGADatastoreMockRecorder) WriteAuthorizationModel(ctx, store,
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModel", reflect.TypeOf((*MockOpenFGADatastore)(nil).WriteAuthorizationModel), ctx, store, model)
 }
+
+// WriteAuthorizationModelWithAnnotations mocks base method.
+func (m *MockOpenFGADatastore) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteAuthorizationModelWithAnnotations", ctx, store, model, annotations)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteAuthorizationModelWithAnnotations indicates an expected call of WriteAuthorizationModelWithAnnotations.
+func (mr *MockOpenFGADatastoreMockRecorder) WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteAuthorizationModelWithAnnotations", reflect.TypeOf((*MockOpenFGADatastore)(nil).WriteAuthorizationModelWithAnnotations), ctx, store, model, annotations)
+}
+
+// WriteStoreMetadata mocks base method.
+func (m *MockOpenFGADatastore) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteStoreMetadata", ctx, id, metadata)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteStoreMetadata indicates an expected call of WriteStoreMetadata.
+func (mr *MockOpenFGADatastoreMockRecorder) WriteStoreMetadata(ctx, id, metadata interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteStoreMetadata", reflect.TypeOf((*MockOpenFGADatastore)(nil).WriteStoreMetadata), ctx, id, metadata)
+}
+
+// WriteWithCondition mocks base method.
+func (m *MockOpenFGADatastore) WriteWithCondition(ctx context.Context, store string, d storage.Deletes, w storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, store, d, w, condition}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WriteWithCondition", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteWithCondition indicates an expected call of WriteWithCondition.
+func (mr *MockOpenFGADatastoreMockRecorder) WriteWithCondition(ctx, store, d, w, condition interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, store, d, w, condition}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithCondition", reflect.TypeOf((*MockOpenFGADatastore)(nil).WriteWithCondition), varargs...)
+}
+
+// WriteWithExpiry mocks base method.
+func (m *MockOpenFGADatastore) WriteWithExpiry(ctx context.Context, store string, d storage.Deletes, w storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, store, d, w, expiresAt}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "WriteWithExpiry", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteWithExpiry indicates an expected call of WriteWithExpiry.
+func (mr *MockOpenFGADatastoreMockRecorder) WriteWithExpiry(ctx, store, d, w, expiresAt interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, store, d, w, expiresAt}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteWithExpiry", reflect.TypeOf((*MockOpenFGADatastore)(nil).WriteWithExpiry), varargs...)
+}
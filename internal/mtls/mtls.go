@@ -0,0 +1,215 @@
+// Package mtls builds the TLS configurations of the servers, with optional verification of the client
+// certificates (mutual TLS). The certificate, key and client CA bundle are reloaded when they change on disk, so
+// that they can be rotated without restarting the server.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is the default minimum delay between two checks of the files for changes.
+const DefaultReloadInterval = 10 * time.Second
+
+// Reloader holds the certificate, key and client CA bundle of a server, and reloads them when their files change.
+// Reloader instances may be safely shared by multiple goroutines.
+type Reloader struct {
+	certPath       string
+	keyPath        string
+	clientCAPath   string
+	reloadInterval time.Duration
+	now            func() time.Time
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    map[string]time.Time
+	lastChecked time.Time
+}
+
+type ReloaderOption func(r *Reloader)
+
+// WithClientCAPath enables mutual TLS: the clients must present a certificate signed by one of the CAs of the PEM
+// bundle at the provided path.
+func WithClientCAPath(path string) ReloaderOption {
+	return func(r *Reloader) {
+		r.clientCAPath = path
+	}
+}
+
+// WithReloadInterval sets the minimum delay between two checks of the files for changes.
+func WithReloadInterval(interval time.Duration) ReloaderOption {
+	return func(r *Reloader) {
+		r.reloadInterval = interval
+	}
+}
+
+// NewReloader loads the certificate and key, and the client CA bundle if any, and returns an error if they cannot
+// be loaded.
+func NewReloader(certPath, keyPath string, opts ...ReloaderOption) (*Reloader, error) {
+	r := &Reloader{
+		certPath:       certPath,
+		keyPath:        keyPath,
+		reloadInterval: DefaultReloadInterval,
+		now:            time.Now,
+		modTimes:       map[string]time.Time{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.lastChecked = r.now()
+
+	return r, nil
+}
+
+// MutualTLS reports whether the clients must present a certificate.
+func (r *Reloader) MutualTLS() bool {
+	return r.clientCAPath != ""
+}
+
+// ServerConfig returns the TLS configuration of a server that presents the current certificate and, with mutual
+// TLS, requires and verifies the client certificates against the current client CA bundle.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+
+			if clientCAs != nil {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = clientCAs
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+// Certificate returns the current certificate.
+func (r *Reloader) Certificate() *tls.Certificate {
+	cert, _ := r.current()
+	return cert
+}
+
+// ClientConfig returns the TLS configuration of the in-process clients of the server, such as the HTTP gateway.
+// They trust the certificate of the server and, with mutual TLS, present it as their client certificate, so it
+// must also be valid for client authentication.
+func (r *Reloader) ClientConfig() (*tls.Config, error) {
+	pem, err := os.ReadFile(r.certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the TLS certificate: %w", err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("failed to load the TLS certificate: no PEM encoded certificate found")
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs,
+	}
+
+	if r.MutualTLS() {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// current returns the current certificate and client CA bundle, reloading them first if their files changed.
+// A failed reload is retried on the next check and the previous certificate and bundle are kept meanwhile.
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.lastChecked) >= r.reloadInterval {
+		r.lastChecked = now
+		if r.changed() {
+			_ = r.loadLocked()
+		}
+	}
+
+	return r.cert, r.clientCAs
+}
+
+func (r *Reloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.loadLocked()
+}
+
+func (r *Reloader) loadLocked() error {
+	modTimes := map[string]time.Time{}
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the TLS certificate and key: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAPath != "" {
+		pem, err := os.ReadFile(r.clientCAPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the client CA bundle: %w", err)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("failed to load the client CA bundle: no PEM encoded certificate found")
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+
+	return nil
+}
+
+// changed reports whether any of the files was modified since it was last loaded.
+func (r *Reloader) changed() bool {
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reloader) paths() []string {
+	paths := []string{r.certPath, r.keyPath}
+	if r.clientCAPath != "" {
+		paths = append(paths, r.clientCAPath)
+	}
+
+	return paths
+}
@@ -0,0 +1,132 @@
+package mtls
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// genCert returns a self-signed CA certificate and its key, PEM encoded.
+func genCert(t *testing.T, commonName string) ([]byte, []byte) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// current returns the common name of the certificate presented by the server, and whether the client CA bundle
+// trusts the provided CA certificate.
+func current(t *testing.T, r *Reloader, caPEM []byte) (string, bool) {
+	cfg, err := r.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+
+	block, _ := pem.Decode(caPEM)
+	ca, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	_, err = ca.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs})
+
+	return leaf.Subject.CommonName, err == nil
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	caPath := filepath.Join(dir, "ca.pem")
+
+	modTime := time.Now().Add(-time.Hour)
+
+	cert, key := genCert(t, "server-1")
+	writeFile(t, certPath, cert, modTime)
+	writeFile(t, keyPath, key, modTime)
+
+	ca1, _ := genCert(t, "ca-1")
+	ca2, _ := genCert(t, "ca-2")
+	writeFile(t, caPath, ca1, modTime)
+
+	r, err := NewReloader(certPath, keyPath, WithClientCAPath(caPath), WithReloadInterval(0))
+	require.NoError(t, err)
+	require.True(t, r.MutualTLS())
+
+	server, trusted := current(t, r, ca1)
+	require.Equal(t, "server-1", server)
+	require.True(t, trusted)
+
+	cfg, err := r.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+
+	t.Run("the_files_are_reloaded_when_they_change", func(t *testing.T) {
+		modTime = modTime.Add(time.Minute)
+
+		writeFile(t, caPath, ca2, modTime)
+
+		cert, key := genCert(t, "server-2")
+		writeFile(t, certPath, cert, modTime)
+		writeFile(t, keyPath, key, modTime)
+
+		server, trusted := current(t, r, ca2)
+		require.Equal(t, "server-2", server)
+		require.True(t, trusted)
+
+		_, trusted = current(t, r, ca1)
+		require.False(t, trusted)
+	})
+
+	t.Run("invalid_files_keep_the_previous_ones", func(t *testing.T) {
+		modTime = modTime.Add(time.Minute)
+		writeFile(t, caPath, []byte("not a certificate"), modTime)
+
+		server, trusted := current(t, r, ca2)
+		require.Equal(t, "server-2", server)
+		require.True(t, trusted)
+	})
+
+	t.Run("without_client_CA_the_clients_are_not_verified", func(t *testing.T) {
+		r, err := NewReloader(certPath, keyPath)
+		require.NoError(t, err)
+		require.False(t, r.MutualTLS())
+
+		cfg, err := r.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		require.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+	})
+
+	t.Run("invalid_client_CA_fails", func(t *testing.T) {
+		_, err := NewReloader(certPath, keyPath, WithClientCAPath(caPath))
+		require.ErrorContains(t, err, "no PEM encoded certificate found")
+	})
+}
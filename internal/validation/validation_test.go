@@ -680,3 +680,56 @@ func TestValidateTuple(t *testing.T) {
 		})
 	}
 }
+
+func FuzzValidateTuple(f *testing.F) {
+	model, err := typesystem.ParseDSL(`type user
+
+type group
+  relations
+    define member: [user, user:*, group#member] as self
+
+type folder
+  relations
+    define viewer: [user, group#member] as self
+
+type document
+  relations
+    define parent: [folder] as self
+    define viewer: [user, user:*, group#member] as self or viewer from parent
+`)
+	require.NoError(f, err)
+
+	typesystems := []*typesystem.TypeSystem{
+		typesystem.New(model),
+		// a 1.0 model, which allows the untyped users
+		typesystem.New(&openfgav1.AuthorizationModel{TypeDefinitions: model.GetTypeDefinitions()}),
+	}
+
+	for _, tk := range []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "viewer", "user:*"),
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("document:1", "parent", "folder:*"),
+		tuple.NewTupleKey("document:*", "viewer", "*"),
+		tuple.NewTupleKey("group#group1:member", "relation", "user:jon"),
+		tuple.NewTupleKey(":", "#", "@"),
+		tuple.NewTupleKey("document:", "viewer", ":anne"),
+	} {
+		f.Add(tk.GetObject(), tk.GetRelation(), tk.GetUser())
+	}
+
+	f.Fuzz(func(t *testing.T, object, relation, user string) {
+		tk := tuple.NewTupleKey(object, relation, user)
+		for _, typesys := range typesystems {
+			if err := ValidateTuple(typesys, tk); err != nil {
+				continue
+			}
+
+			// the valid tuples are well formed
+			require.True(t, tuple.IsValidObject(object))
+			require.True(t, tuple.IsValidRelation(relation))
+			require.True(t, tuple.IsValidUser(user))
+		}
+	})
+}
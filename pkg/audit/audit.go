@@ -0,0 +1,295 @@
+// Package audit records the calls to the API that change or query the permissions of a store (who called which
+// method, on which store, with which request, and what was decided) to a pluggable Sink (e.g. a file, syslog, an
+// HTTP endpoint or Kafka).
+//
+// The records form a hash chain: every record carries the hash of the record before it, and its own hash covers
+// its content and that previous hash. A record that is modified, removed or inserted after the fact breaks the
+// chain, which Verify detects.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBufferSize    = 10000
+	defaultBatchSize     = 100
+	defaultWriteAttempts = 3
+	defaultWriteTimeout  = 10 * time.Second
+	writeRetryDelay      = 100 * time.Millisecond
+)
+
+var (
+	droppedRecordsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "audit_log_dropped_records_count",
+		Help: "Number of audit records that were dropped because the audit log buffer was full",
+	})
+
+	failedRecordsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "audit_log_failed_records_count",
+		Help: "Number of audit records that could not be written to the audit log sink",
+	})
+)
+
+// Record is the audit record of a call to the API.
+type Record struct {
+	// Sequence is the position of the record in the audit log of the server, starting at 1.
+	Sequence uint64 `json:"sequence"`
+
+	Time                 time.Time       `json:"time"`
+	Principal            string          `json:"principal,omitempty"`
+	Method               string          `json:"method"`
+	StoreID              string          `json:"store_id,omitempty"`
+	AuthorizationModelID string          `json:"authorization_model_id,omitempty"`
+	RequestID            string          `json:"request_id,omitempty"`
+	Request              json.RawMessage `json:"request,omitempty"`
+
+	// Decision summarizes the outcome of the call, e.g. 'allowed' or 'denied' for a Check.
+	Decision string `json:"decision"`
+
+	// Code is the gRPC status code of the call, and Error its error message if it failed.
+	Code  uint32 `json:"code"`
+	Error string `json:"error,omitempty"`
+
+	LatencyMs float64 `json:"latency_ms"`
+
+	// PrevHash is the Hash of the previous record, and Hash the hash of this record.
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// computeHash returns the hex encoded SHA-256 of the JSON encoding of the record without its Hash.
+func (r *Record) computeHash() (string, error) {
+	unhashed := *r
+	unhashed.Hash = ""
+
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sink is the destination of the audit records.
+type Sink interface {
+	// Write writes the records, in order.
+	Write(ctx context.Context, records []*Record) error
+
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// chainResumer is implemented by the sinks that can read back the last record they wrote, so that the hash
+// chain continues across restarts.
+type chainResumer interface {
+	LastRecord() (*Record, error)
+}
+
+// Logger hashes and writes the audit records to a Sink in the background, in batches. Records are dropped, and
+// counted in metrics, when the buffer is full or when they cannot be written after a few attempts. A record that
+// could not be written leaves a gap in the chain that Verify reports. Logger instances may be safely shared by
+// multiple goroutines.
+type Logger struct {
+	sink          Sink
+	logger        logger.Logger
+	bufferSize    int
+	batchSize     int
+	writeAttempts int
+
+	records chan *Record
+	done    chan struct{}
+
+	// sequence and prevHash are only accessed by the background goroutine
+	sequence uint64
+	prevHash string
+}
+
+type LoggerOption func(l *Logger)
+
+// WithBufferSize sets the number of records the Logger buffers before dropping records.
+func WithBufferSize(size int) LoggerOption {
+	return func(l *Logger) {
+		l.bufferSize = size
+	}
+}
+
+// WithBatchSize sets the maximum number of records written to the sink at once.
+func WithBatchSize(size int) LoggerOption {
+	return func(l *Logger) {
+		l.batchSize = size
+	}
+}
+
+func WithLogger(logger logger.Logger) LoggerOption {
+	return func(l *Logger) {
+		l.logger = logger
+	}
+}
+
+// NewLogger constructs a Logger writing to the sink, and starts writing in the background until Close is called.
+// If the sink can read back the last record it wrote, the hash chain continues from it.
+func NewLogger(sink Sink, opts ...LoggerOption) (*Logger, error) {
+	l := &Logger{
+		sink:          sink,
+		logger:        logger.NewNoopLogger(),
+		bufferSize:    defaultBufferSize,
+		batchSize:     defaultBatchSize,
+		writeAttempts: defaultWriteAttempts,
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if resumer, ok := sink.(chainResumer); ok {
+		last, err := resumer.LastRecord()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the last audit record: %w", err)
+		}
+
+		if last != nil {
+			l.sequence = last.Sequence
+			l.prevHash = last.Hash
+		}
+	}
+
+	l.records = make(chan *Record, l.bufferSize)
+	go l.run()
+
+	return l, nil
+}
+
+// Log queues the record to be written. It never blocks: the record is dropped if the buffer is full.
+func (l *Logger) Log(record *Record) {
+	select {
+	case l.records <- record:
+	default:
+		droppedRecordsCounter.Inc()
+	}
+}
+
+// Close writes the queued records and closes the sink. Log must not be called after Close.
+func (l *Logger) Close() error {
+	close(l.records)
+	<-l.done
+
+	return l.sink.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for record := range l.records {
+		batch := []*Record{record}
+
+	fill:
+		for len(batch) < l.batchSize {
+			select {
+			case record, ok := <-l.records:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, record)
+			default:
+				break fill
+			}
+		}
+
+		l.write(batch)
+	}
+}
+
+// write chains the records and writes them to the sink.
+func (l *Logger) write(batch []*Record) {
+	for _, record := range batch {
+		l.sequence++
+		record.Sequence = l.sequence
+		record.PrevHash = l.prevHash
+
+		hash, err := record.computeHash()
+		if err != nil {
+			l.logger.Error("failed to hash the audit record", zap.Error(err))
+			continue
+		}
+
+		record.Hash = hash
+		l.prevHash = hash
+	}
+
+	var err error
+	for attempt := 0; attempt < l.writeAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(writeRetryDelay * time.Duration(attempt))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultWriteTimeout)
+		err = l.sink.Write(ctx, batch)
+		cancel()
+
+		if err == nil {
+			return
+		}
+	}
+
+	failedRecordsCounter.Add(float64(len(batch)))
+	l.logger.Error(fmt.Sprintf("failed to write %d audit records", len(batch)), zap.Error(err))
+}
+
+// Verify reads the records from r, encoded as JSON lines as written by the FileSink, and returns an error
+// identifying the first record whose hash or link to the previous record is invalid.
+func Verify(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	var prev *Record
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to parse the audit record after sequence %d: %w", sequenceOf(prev), err)
+		}
+
+		hash, err := record.computeHash()
+		if err != nil {
+			return err
+		}
+
+		if hash != record.Hash {
+			return fmt.Errorf("audit record %d has been modified", record.Sequence)
+		}
+
+		if prev != nil && (record.PrevHash != prev.Hash || record.Sequence != prev.Sequence+1) {
+			return fmt.Errorf("audit records are missing or out of order between %d and %d", prev.Sequence, record.Sequence)
+		}
+
+		prev = &record
+	}
+
+	return scanner.Err()
+}
+
+func sequenceOf(r *Record) uint64 {
+	if r == nil {
+		return 0
+	}
+
+	return r.Sequence
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	records []*Record
+}
+
+func (s *memorySink) Write(_ context.Context, records []*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+func TestFileSinkChainAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	l, err := NewLogger(sink)
+	require.NoError(t, err)
+	l.Log(&Record{Method: "Write", StoreID: "store1", Decision: DecisionOK})
+	l.Log(&Record{Method: "Check", StoreID: "store1", Decision: DecisionAllowed})
+	require.NoError(t, l.Close())
+
+	// the chain continues across restarts
+	sink, err = NewFileSink(path)
+	require.NoError(t, err)
+
+	l, err = NewLogger(sink)
+	require.NoError(t, err)
+	l.Log(&Record{Method: "Check", StoreID: "store1", Decision: DecisionDenied})
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, Verify(bytes.NewReader(data)))
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 3)
+	require.Contains(t, string(lines[2]), `"sequence":3`)
+
+	t.Run("modified_record", func(t *testing.T) {
+		tampered := bytes.Replace(data, []byte(DecisionDenied), []byte(DecisionAllowed), 1)
+		require.ErrorContains(t, Verify(bytes.NewReader(tampered)), "audit record 3 has been modified")
+	})
+
+	t.Run("removed_record", func(t *testing.T) {
+		removed := bytes.Join([][]byte{lines[0], lines[2]}, []byte("\n"))
+		require.ErrorContains(t, Verify(bytes.NewReader(removed)), "missing or out of order between 1 and 3")
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	sink := &memorySink{}
+	l, err := NewLogger(sink)
+	require.NoError(t, err)
+
+	interceptor := NewUnaryInterceptor(l)
+	ctx := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Subject: "alice"})
+
+	check := &openfgav1.CheckRequest{
+		StoreId:              "store1",
+		AuthorizationModelId: "model1",
+		TupleKey:             &openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:bob"},
+	}
+	_, err = interceptor(ctx, check, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &openfgav1.CheckResponse{Allowed: true}, nil
+		})
+	require.NoError(t, err)
+
+	_, err = interceptor(ctx, &openfgav1.WriteRequest{StoreId: "store1"}, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Write"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.PermissionDenied, "denied")
+		})
+	require.Error(t, err)
+
+	// the other methods are not audited
+	_, err = interceptor(ctx, &openfgav1.ReadRequest{StoreId: "store1"}, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Read"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &openfgav1.ReadResponse{}, nil
+		})
+	require.NoError(t, err)
+
+	require.NoError(t, l.Close())
+	require.Len(t, sink.records, 2)
+
+	checked := sink.records[0]
+	require.Equal(t, "Check", checked.Method)
+	require.Equal(t, "alice", checked.Principal)
+	require.Equal(t, "store1", checked.StoreID)
+	require.Equal(t, "model1", checked.AuthorizationModelID)
+	require.Equal(t, DecisionAllowed, checked.Decision)
+	require.Contains(t, string(checked.Request), `"user:bob"`)
+
+	written := sink.records[1]
+	require.Equal(t, "Write", written.Method)
+	require.Equal(t, DecisionError, written.Decision)
+	require.Equal(t, uint32(codes.PermissionDenied), written.Code)
+	require.Equal(t, checked.Hash, written.PrevHash)
+}
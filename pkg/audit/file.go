@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// maxRecordSize is the maximum size of a record read back from a file.
+const maxRecordSize = 16 * 1024 * 1024
+
+// FileSink appends the records as JSON lines to a file, and syncs the file after every write.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ Sink = (*FileSink)(nil)
+
+// NewFileSink constructs a FileSink which appends to the file at the path, creating it if necessary.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit log file: %w", err)
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Write(_ context.Context, records []*Record) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// LastRecord returns the last record in the file, or nil if the file is empty.
+func (s *FileSink) LastRecord() (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	if size > maxRecordSize {
+		size = maxRecordSize
+	}
+
+	tail := make([]byte, size)
+	if _, err := s.file.ReadAt(tail, info.Size()-size); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return nil, nil
+	}
+
+	line := tail[bytes.LastIndexByte(tail, '\n')+1:]
+
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse the last audit record: %w", err)
+	}
+
+	return &record, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HTTPSink writes the records by POSTing them as a JSON array to an HTTP endpoint. Any response status other
+// than 2xx is treated as a failure.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ Sink = (*HTTPSink)(nil)
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: defaultWriteTimeout},
+	}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *HTTPSink) Close() error {
+	return nil
+}
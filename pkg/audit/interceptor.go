@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/openfga/openfga/pkg/middleware/clientcert"
+	"github.com/openfga/openfga/pkg/middleware/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+	DecisionOK      = "ok"
+	DecisionError   = "error"
+)
+
+// auditedMethods are the full names of the methods which are audited.
+var auditedMethods = map[string]bool{
+	"/openfga.v1.OpenFGAService/Write":                   true,
+	"/openfga.v1.OpenFGAService/WriteAuthorizationModel": true,
+	"/openfga.v1.OpenFGAService/Check":                   true,
+	"/openfga.v1.OpenFGAService/ListObjects":             true,
+	"/openfga.v1.OpenFGAService/StreamedListObjects":     true,
+}
+
+type hasGetStoreID interface {
+	GetStoreId() string
+}
+
+type hasGetAuthorizationModelID interface {
+	GetAuthorizationModelId() string
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which audits the calls to the Write,
+// WriteAuthorizationModel, Check and ListObjects methods. It must run after the authentication interceptor
+// and before the interceptors that may deny the call, so that the denied calls are audited too.
+func NewUnaryInterceptor(l *Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !auditedMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		record := newRecord(ctx, info.FullMethod, req, start)
+		if r, ok := resp.(hasGetAuthorizationModelID); ok && record.AuthorizationModelID == "" {
+			record.AuthorizationModelID = r.GetAuthorizationModelId()
+		}
+
+		record.Decision = decision(resp)
+		setError(record, err)
+		l.Log(record)
+
+		return resp, err
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which audits the calls to the
+// StreamedListObjects method, with the number of objects streamed as its decision.
+func NewStreamingInterceptor(l *Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !auditedMethods[info.FullMethod] {
+			return handler(srv, stream)
+		}
+
+		start := time.Now()
+		audited := &auditedServerStream{ServerStream: stream}
+		err := handler(srv, audited)
+
+		record := newRecord(stream.Context(), info.FullMethod, audited.req, start)
+		record.Decision = fmt.Sprintf("%d objects", audited.sent)
+		setError(record, err)
+		l.Log(record)
+
+		return err
+	}
+}
+
+type auditedServerStream struct {
+	grpc.ServerStream
+	req  interface{}
+	sent int
+}
+
+func (s *auditedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.req == nil {
+		s.req = m
+	}
+
+	return nil
+}
+
+func (s *auditedServerStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.sent++
+	return nil
+}
+
+func newRecord(ctx context.Context, fullMethod string, req interface{}, start time.Time) *Record {
+	record := &Record{
+		Time:      start.UTC(),
+		Method:    path.Base(fullMethod),
+		Principal: principal(ctx),
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	record.RequestID, _ = requestid.FromContext(ctx)
+
+	if r, ok := req.(hasGetStoreID); ok {
+		record.StoreID = r.GetStoreId()
+	}
+
+	if r, ok := req.(hasGetAuthorizationModelID); ok {
+		record.AuthorizationModelID = r.GetAuthorizationModelId()
+	}
+
+	if m, ok := req.(proto.Message); ok {
+		if data, err := protojson.Marshal(m); err == nil {
+			record.Request = json.RawMessage(data)
+		}
+	}
+
+	return record
+}
+
+// principal returns the authenticated caller, falling back to the subject of the client certificate.
+func principal(ctx context.Context) string {
+	if claims, ok := authn.AuthClaimsFromContext(ctx); ok && claims.Principal() != "" {
+		return claims.Principal()
+	}
+
+	subject, _ := clientcert.SubjectFromContext(ctx)
+	return subject
+}
+
+func decision(resp interface{}) string {
+	switch r := resp.(type) {
+	case *openfgav1.CheckResponse:
+		if r.GetAllowed() {
+			return DecisionAllowed
+		}
+		return DecisionDenied
+	case *openfgav1.ListObjectsResponse:
+		return fmt.Sprintf("%d objects", len(r.GetObjects()))
+	default:
+		return DecisionOK
+	}
+}
+
+func setError(record *Record, err error) {
+	if err == nil {
+		return
+	}
+
+	s := status.Convert(err)
+	record.Decision = DecisionError
+	record.Code = uint32(s.Code())
+	record.Error = s.Message()
+}
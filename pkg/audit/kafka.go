@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink writes every record as a JSON message to a Kafka topic. Messages are written to a single
+// partition, so that consumers receive the records in the order of the hash chain.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+// NewKafkaSink constructs a KafkaSink which writes to the topic through the provided brokers. Write only
+// returns once every in-sync replica has acknowledged the messages.
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (s *KafkaSink) Write(ctx context.Context, records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, 0, len(records))
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		// a constant key keeps every record in the same partition
+		messages = append(messages, kafka.Message{
+			Key:   []byte("audit"),
+			Value: value,
+		})
+	}
+
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
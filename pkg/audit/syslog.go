@@ -0,0 +1,45 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"log/syslog"
+)
+
+// SyslogSink writes every record as a JSON message to syslog, with the info severity of the auth facility.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+var _ Sink = (*SyslogSink)(nil)
+
+// NewSyslogSink constructs a SyslogSink. If network and addr are empty, it writes to the local syslog daemon.
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	writer, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogSink{writer: writer}, nil
+}
+
+func (s *SyslogSink) Write(_ context.Context, records []*Record) error {
+	for _, record := range records {
+		message, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		if err := s.writer.Info(string(message)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
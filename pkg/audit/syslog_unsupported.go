@@ -0,0 +1,25 @@
+//go:build windows || plan9
+
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// SyslogSink is not supported on this platform.
+type SyslogSink struct{}
+
+var _ Sink = (*SyslogSink)(nil)
+
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	return nil, errors.New("the syslog audit sink is not supported on this platform")
+}
+
+func (s *SyslogSink) Write(_ context.Context, _ []*Record) error {
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	return nil
+}
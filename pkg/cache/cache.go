@@ -0,0 +1,24 @@
+// Package cache contains key-value cache implementations that can be shared by the server's caches.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get when the key is not in the cache or has expired.
+var ErrNotFound = errors.New("cache: key not found")
+
+// Cache is a key-value cache of opaque values. Implementations must be safe for concurrent use.
+// Caches are best-effort: callers must treat any error as a cache miss.
+type Cache interface {
+	// Get returns the value stored under the key, or ErrNotFound if there is none.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores the value under the key for the provided ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Close releases the resources held by the cache.
+	Close()
+}
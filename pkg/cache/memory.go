@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/karlseguin/ccache/v3"
+)
+
+// InMemoryCache is a Cache local to the process, bounded by a maximum number of entries.
+type InMemoryCache struct {
+	cache *ccache.Cache[[]byte]
+}
+
+var _ Cache = (*InMemoryCache)(nil)
+
+func NewInMemoryCache(maxSize int64) *InMemoryCache {
+	return &InMemoryCache{
+		cache: ccache.New(ccache.Configure[[]byte]().MaxSize(maxSize)),
+	}
+}
+
+func (c *InMemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	item := c.cache.Get(key)
+	if item == nil || item.Expired() {
+		return nil, ErrNotFound
+	}
+
+	return item.Value(), nil
+}
+
+func (c *InMemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.cache.Set(key, value, ttl)
+	return nil
+}
+
+// SetMaxSize changes the maximum number of entries of the cache at runtime. The entries beyond the new maximum are
+// evicted.
+func (c *InMemoryCache) SetMaxSize(maxSize int64) {
+	c.cache.SetMaxSize(maxSize)
+}
+
+// Clear drops every entry of the cache.
+func (c *InMemoryCache) Clear() {
+	c.cache.Clear()
+}
+
+func (c *InMemoryCache) Close() {
+	c.cache.Stop()
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCache(t *testing.T) {
+	ctx := context.Background()
+
+	c := NewInMemoryCache(10)
+	t.Cleanup(c.Close)
+
+	_, err := c.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Minute))
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	require.NoError(t, c.Set(ctx, "expired", []byte("value"), -time.Minute))
+
+	_, err = c.Get(ctx, "expired")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInMemoryCacheSetMaxSize(t *testing.T) {
+	ctx := context.Background()
+
+	c := NewInMemoryCache(10)
+	t.Cleanup(c.Close)
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, c.Set(ctx, key, []byte("value"), time.Minute))
+	}
+
+	c.SetMaxSize(2)
+
+	require.Eventually(t, func() bool {
+		return c.cache.ItemCount() <= 2
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache is a Cache backed by Redis, so that every server pointed at the same Redis
+// shares its entries.
+type RedisCache struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+var _ Cache = (*RedisCache)(nil)
+
+type RedisCacheOption func(c *RedisCache)
+
+// WithRedisKeyPrefix prefixes every key stored by the cache, so that a Redis instance can be
+// shared by several OpenFGA deployments.
+func WithRedisKeyPrefix(prefix string) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.keyPrefix = prefix
+	}
+}
+
+// NewRedisCache constructs a RedisCache and verifies that the Redis server can be reached.
+func NewRedisCache(ctx context.Context, redisOpts *redis.Options, opts ...RedisCacheOption) (*RedisCache, error) {
+	c := &RedisCache{
+		client: redis.NewClient(redisOpts),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		_ = c.client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, c.keyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.keyPrefix+key, value, ttl).Err()
+}
+
+// Ping verifies that the Redis server can be reached.
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *RedisCache) Close() {
+	_ = c.client.Close()
+}
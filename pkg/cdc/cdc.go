@@ -0,0 +1,284 @@
+// Package cdc contains a change data capture publisher that tails the changelog of every store
+// and exports the tuple changes to an external sink (Kafka, NATS JetStream or a webhook).
+//
+// A changelog must be exported by a single publisher: the publishers don't coordinate, so every
+// publisher exports every change. The FileCheckpointer only prevents two publishers of the same host
+// from sharing its checkpoint file; publishers of different hosts are not detected.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var tracer = otel.Tracer("openfga/pkg/cdc")
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultPageSize     = storage.DefaultPageSize
+)
+
+// Event is a tuple change of a store, as published to a Sink.
+type Event struct {
+	StoreID string
+	Change  *openfgav1.TupleChange
+}
+
+type eventJSON struct {
+	StoreID string          `json:"store_id"`
+	Change  json.RawMessage `json:"change"`
+}
+
+// MarshalJSON encodes the event as `{"store_id": "...", "change": {...}}`, where the change is
+// encoded with the protobuf JSON mapping of openfgav1.TupleChange. Unpopulated fields are emitted,
+// so that the operation is always present even when it is TUPLE_OPERATION_WRITE (the zero value).
+func (e *Event) MarshalJSON() ([]byte, error) {
+	change, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(e.Change)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(eventJSON{StoreID: e.StoreID, Change: change})
+}
+
+// Sink is the destination of the exported tuple changes. The package provides the Kafka, NATS and
+// webhook sinks; other destinations can be supported by implementing Sink.
+type Sink interface {
+	// Publish publishes the events, in order. The events must be durably accepted by the sink
+	// when Publish returns without an error, since the checkpoint is advanced past them afterwards.
+	Publish(ctx context.Context, events []*Event) error
+
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// Datastore is the subset of the datastore the Publisher reads from.
+type Datastore interface {
+	storage.ChangelogBackend
+	ListStores(ctx context.Context, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error)
+}
+
+// Publisher periodically reads the changes of every store from the changelog and publishes them to a Sink.
+// The position of every store in the changelog is saved in a Checkpointer once its changes are published,
+// so the delivery is at-least-once: if the publisher stops between a Publish and the following checkpoint,
+// the same changes are published again when it restarts.
+type Publisher struct {
+	datastore     Datastore
+	sink          Sink
+	checkpointer  Checkpointer
+	logger        logger.Logger
+	pollInterval  time.Duration
+	pageSize      int
+	horizonOffset time.Duration
+
+	notified chan struct{}
+	mu       sync.Mutex
+	pending  map[string]struct{}
+}
+
+type PublisherOption func(p *Publisher)
+
+// WithCheckpointer sets where the position of every store in the changelog is saved. Defaults to an in-memory
+// checkpointer, in which case every change is published again when the server restarts.
+func WithCheckpointer(checkpointer Checkpointer) PublisherOption {
+	return func(p *Publisher) {
+		p.checkpointer = checkpointer
+	}
+}
+
+func WithLogger(l logger.Logger) PublisherOption {
+	return func(p *Publisher) {
+		p.logger = l
+	}
+}
+
+// WithPollInterval sets how long the publisher waits between two reads of the changelog once it is caught up.
+func WithPollInterval(interval time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		p.pollInterval = interval
+	}
+}
+
+// WithPageSize sets the maximum number of changes read from the changelog and published at once.
+func WithPageSize(pageSize int) PublisherOption {
+	return func(p *Publisher) {
+		p.pageSize = pageSize
+	}
+}
+
+// WithHorizonOffset sets the offset from the current time under which changes are not exported yet,
+// see server.WithChangelogHorizonOffset.
+func WithHorizonOffset(offset time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		p.horizonOffset = offset
+	}
+}
+
+func NewPublisher(ds Datastore, sink Sink, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		datastore:    ds,
+		sink:         sink,
+		checkpointer: NewInMemoryCheckpointer(),
+		logger:       logger.NewNoopLogger(),
+		pollInterval: defaultPollInterval,
+		pageSize:     defaultPageSize,
+		notified:     make(chan struct{}, 1),
+		pending:      map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Notify notifies the publisher that changes were committed to the store, so that Run publishes them
+// without waiting for the next poll. It does not block.
+func (p *Publisher) Notify(storeID string) {
+	p.mu.Lock()
+	p.pending[storeID] = struct{}{}
+	p.mu.Unlock()
+
+	select {
+	case p.notified <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes the changes of every store until the context is cancelled, and the changes of the stores
+// it is notified of as soon as it is notified, see Notify. Errors are logged and the failed stores are
+// retried on the next poll.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error("failed to export the changelog", zap.Error(err))
+		}
+
+		if !p.waitForPoll(ctx, ticker) {
+			return
+		}
+	}
+}
+
+// waitForPoll publishes the changes of the stores the publisher is notified of until the next poll is due,
+// and returns false if the context is cancelled in the meantime.
+func (p *Publisher) waitForPoll(ctx context.Context, ticker *time.Ticker) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			return true
+		case <-p.notified:
+			for _, storeID := range p.takePending() {
+				if err := p.exportStore(ctx, storeID); err != nil && ctx.Err() == nil {
+					p.logger.Error("failed to export the changelog", zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// takePending returns the stores the publisher was notified of since the last call, and forgets them.
+func (p *Publisher) takePending() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	storeIDs := make([]string, 0, len(p.pending))
+	for storeID := range p.pending {
+		storeIDs = append(storeIDs, storeID)
+	}
+	p.pending = map[string]struct{}{}
+
+	return storeIDs
+}
+
+// Poll publishes every change that has not been published yet, for every store.
+func (p *Publisher) Poll(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "cdc.Poll")
+	defer span.End()
+
+	var errs []error
+	var from string
+	for {
+		stores, token, err := p.datastore.ListStores(ctx, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return err
+		}
+
+		for _, store := range stores {
+			if err := p.exportStore(ctx, store.GetId()); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				errs = append(errs, err)
+			}
+		}
+
+		if len(token) == 0 {
+			break
+		}
+		from = string(token)
+	}
+
+	return errors.Join(errs...)
+}
+
+// exportStore publishes the changes of the store that have not been published yet, one page at a time,
+// checkpointing after every page.
+func (p *Publisher) exportStore(ctx context.Context, storeID string) error {
+	ctx, span := tracer.Start(ctx, "cdc.exportStore", trace.WithAttributes(attribute.String("store_id", storeID)))
+	defer span.End()
+
+	from, err := p.checkpointer.Load(ctx, storeID)
+	if err != nil {
+		return fmt.Errorf("failed to load the checkpoint of store '%s': %w", storeID, err)
+	}
+
+	for {
+		changes, token, err := p.datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: p.pageSize, From: from}, p.horizonOffset)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil // caught up
+			}
+
+			return fmt.Errorf("failed to read the changes of store '%s': %w", storeID, err)
+		}
+
+		events := make([]*Event, 0, len(changes))
+		for _, change := range changes {
+			events = append(events, &Event{StoreID: storeID, Change: change})
+		}
+
+		if err := p.sink.Publish(ctx, events); err != nil {
+			return fmt.Errorf("failed to publish the changes of store '%s': %w", storeID, err)
+		}
+
+		from = string(token)
+		if err := p.checkpointer.Save(ctx, storeID, from); err != nil {
+			return fmt.Errorf("failed to save the checkpoint of store '%s': %w", storeID, err)
+		}
+
+		if len(changes) < p.pageSize {
+			return nil
+		}
+	}
+}
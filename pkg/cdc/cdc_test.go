@@ -0,0 +1,300 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	events []*Event
+	err    error
+}
+
+func (s *recordingSink) Publish(_ context.Context, events []*Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	return nil
+}
+
+func (s *recordingSink) objects() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var objects []string
+	for _, event := range s.events {
+		objects = append(objects, event.StoreID+"/"+event.Change.GetTupleKey().GetObject())
+	}
+
+	return objects
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	store1 := ulid.Make().String()
+	store2 := ulid.Make().String()
+	for _, storeID := range []string{store1, store2} {
+		_, err := ds.CreateStore(ctx, &openfgav1.Store{Id: storeID, Name: storeID})
+		require.NoError(t, err)
+	}
+
+	write := func(storeID, object string) {
+		err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:jon")})
+		require.NoError(t, err)
+	}
+
+	write(store1, "document:1")
+	write(store1, "document:2")
+	write(store1, "document:3")
+	write(store2, "document:4")
+
+	sink := &recordingSink{}
+	publisher := NewPublisher(ds, sink, WithPageSize(2))
+
+	require.NoError(t, publisher.Poll(ctx))
+	require.ElementsMatch(t, []string{
+		store1 + "/document:1",
+		store1 + "/document:2",
+		store1 + "/document:3",
+		store2 + "/document:4",
+	}, sink.objects())
+
+	// changes which were already published are not published again
+	require.NoError(t, publisher.Poll(ctx))
+	require.Len(t, sink.objects(), 4)
+
+	// changes that failed to be published are published on the next poll
+	write(store2, "document:5")
+
+	sink.err = errors.New("sink unavailable")
+	require.ErrorIs(t, publisher.Poll(ctx), sink.err)
+
+	sink.err = nil
+	require.NoError(t, publisher.Poll(ctx))
+	require.Len(t, sink.objects(), 5)
+	require.Equal(t, store2+"/document:5", sink.objects()[4])
+
+	t.Run("notified_stores_are_published_before_the_next_poll", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		publisher := NewPublisher(ds, sink, WithPollInterval(time.Hour), WithCheckpointer(publisher.checkpointer))
+		done := make(chan struct{})
+		go func() {
+			publisher.Run(ctx)
+			close(done)
+		}()
+
+		write(store1, "document:6")
+		publisher.Notify(store1)
+
+		require.Eventually(t, func() bool {
+			return len(sink.objects()) == 6
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		<-done
+	})
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+
+	checkpointer, err := NewFileCheckpointer(path)
+	require.NoError(t, err)
+
+	checkpoint, err := checkpointer.Load(ctx, "store")
+	require.NoError(t, err)
+	require.Empty(t, checkpoint)
+
+	require.NoError(t, checkpointer.Save(ctx, "store", "token"))
+
+	// the checkpoint file is not used by two publishers at once
+	_, err = NewFileCheckpointer(path)
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		require.ErrorIs(t, err, ErrCheckpointFileLocked)
+	}
+
+	require.NoError(t, checkpointer.Close())
+
+	// the checkpoints survive a restart
+	checkpointer, err = NewFileCheckpointer(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = checkpointer.Close() })
+
+	checkpoint, err = checkpointer.Load(ctx, "store")
+	require.NoError(t, err)
+	require.Equal(t, "token", checkpoint)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	sink := NewWebhookSink(srv.URL)
+
+	err := sink.Publish(context.Background(), []*Event{{
+		StoreID: "store",
+		Change: &openfgav1.TupleChange{
+			TupleKey:  tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+			Operation: openfgav1.TupleOperation_TUPLE_OPERATION_WRITE,
+		},
+	}})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	require.Equal(t, "store", received[0]["store_id"])
+	require.Equal(t, "TUPLE_OPERATION_WRITE", received[0]["change"].(map[string]any)["operation"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+
+	err = NewWebhookSink(failing.URL).Publish(context.Background(), []*Event{{StoreID: "store", Change: &openfgav1.TupleChange{}}})
+	require.Error(t, err)
+
+	t.Run("requests_are_signed_with_the_secret", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			if r.Header.Get(SignatureHeader) != Sign([]byte("secret"), r.Header.Get(TimestampHeader), body) {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		t.Cleanup(srv.Close)
+
+		events := []*Event{{StoreID: "store", Change: &openfgav1.TupleChange{}}}
+		require.NoError(t, NewWebhookSink(srv.URL, WithWebhookSecret("secret")).Publish(context.Background(), events))
+		require.Error(t, NewWebhookSink(srv.URL, WithWebhookSecret("other")).Publish(context.Background(), events))
+	})
+}
+
+func TestNATSMessage(t *testing.T) {
+	change := &openfgav1.TupleChange{
+		TupleKey:  tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		Operation: openfgav1.TupleOperation_TUPLE_OPERATION_WRITE,
+		Timestamp: timestamppb.New(time.Unix(1, 0)),
+	}
+
+	msg, err := natsMessage("changes", &Event{StoreID: "store", Change: change})
+	require.NoError(t, err)
+	require.Equal(t, "changes", msg.Subject)
+	require.Equal(t, "store/1000000000/TUPLE_OPERATION_WRITE/document:1#viewer@user:jon", msg.Header.Get(nats.MsgIdHdr))
+
+	var received map[string]any
+	require.NoError(t, json.Unmarshal(msg.Data, &received))
+	require.Equal(t, "store", received["store_id"])
+
+	// the same change of another store is another message
+	other, err := natsMessage("changes", &Event{StoreID: "other", Change: change})
+	require.NoError(t, err)
+	require.NotEqual(t, msg.Header.Get(nats.MsgIdHdr), other.Header.Get(nats.MsgIdHdr))
+}
+
+type flakySink struct {
+	recordingSink
+	failures int
+}
+
+func (s *flakySink) Publish(ctx context.Context, events []*Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+
+	return s.recordingSink.Publish(ctx, events)
+}
+
+func TestRetryingSink(t *testing.T) {
+	ctx := context.Background()
+	events := []*Event{{StoreID: "store", Change: &openfgav1.TupleChange{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:jon")}}}
+
+	t.Run("failed_publishes_are_retried", func(t *testing.T) {
+		sink := &flakySink{failures: 2}
+
+		err := NewRetryingSink(sink, WithMaxRetries(2), WithBackoff(time.Millisecond, time.Millisecond)).Publish(ctx, events)
+		require.NoError(t, err)
+		require.Equal(t, []string{"store/document:1"}, sink.objects())
+	})
+
+	t.Run("the_publish_fails_once_the_retries_are_exhausted", func(t *testing.T) {
+		sink := &flakySink{failures: 3}
+
+		err := NewRetryingSink(sink, WithMaxRetries(2), WithBackoff(time.Millisecond, time.Millisecond)).Publish(ctx, events)
+		require.Error(t, err)
+		require.Empty(t, sink.objects())
+	})
+
+	t.Run("the_events_are_put_in_the_dead_letter_queue_once_the_retries_are_exhausted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dead-letters.jsonl")
+		deadLetters, err := NewFileDeadLetterQueue(path)
+		require.NoError(t, err)
+
+		sink := NewRetryingSink(&flakySink{failures: 3},
+			WithMaxRetries(2),
+			WithBackoff(time.Millisecond, time.Millisecond),
+			WithDeadLetterQueue(deadLetters),
+		)
+		require.NoError(t, sink.Publish(ctx, events))
+		require.NoError(t, deadLetters.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var deadLetter struct {
+			Error  string           `json:"error"`
+			Events []map[string]any `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(data, &deadLetter))
+		require.Equal(t, "sink unavailable", deadLetter.Error)
+		require.Len(t, deadLetter.Events, 1)
+		require.Equal(t, "store", deadLetter.Events[0]["store_id"])
+	})
+}
+
+func TestMultiSink(t *testing.T) {
+	ok := &recordingSink{}
+	failing := &recordingSink{err: errors.New("sink unavailable")}
+
+	err := MultiSink(ok, failing).Publish(context.Background(), []*Event{{StoreID: "store", Change: &openfgav1.TupleChange{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:jon")}}})
+	require.ErrorIs(t, err, failing.err)
+	require.Equal(t, []string{"store/document:1"}, ok.objects())
+}
@@ -0,0 +1,166 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpointer saves the position of every store in the changelog, i.e. the ReadChanges continuation
+// token from which the next changes of the store must be read.
+type Checkpointer interface {
+	// Load returns the checkpoint of the store, or an empty string if there is none.
+	Load(ctx context.Context, storeID string) (string, error)
+
+	// Save saves the checkpoint of the store.
+	Save(ctx context.Context, storeID string, checkpoint string) error
+}
+
+// InMemoryCheckpointer is a Checkpointer which does not persist the checkpoints.
+type InMemoryCheckpointer struct {
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+var _ Checkpointer = (*InMemoryCheckpointer)(nil)
+
+func NewInMemoryCheckpointer() *InMemoryCheckpointer {
+	return &InMemoryCheckpointer{checkpoints: map[string]string{}}
+}
+
+func (c *InMemoryCheckpointer) Load(_ context.Context, storeID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.checkpoints[storeID], nil
+}
+
+func (c *InMemoryCheckpointer) Save(_ context.Context, storeID string, checkpoint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkpoints[storeID] = checkpoint
+
+	return nil
+}
+
+// ErrCheckpointFileLocked is returned by NewFileCheckpointer if the checkpoint file is used by another
+// FileCheckpointer of the same host.
+var ErrCheckpointFileLocked = errors.New("the checkpoint file is used by another publisher")
+
+// FileCheckpointer is a Checkpointer which persists the checkpoints of every store in a JSON file.
+// The file is replaced atomically on every Save, so it is never left partially written.
+//
+// The checkpoint file is locked until Close, so that two publishers of the same host cannot share it:
+// the lock is an exclusive lock of a '.lock' file next to it, which is released if the process exits.
+// The lock is local to the host, e.g. it doesn't prevent two replicas with their own volume, or sharing
+// a network file system, from exporting the same changelog. It is not enforced on Windows and Plan 9.
+type FileCheckpointer struct {
+	path string
+	lock *os.File
+
+	mu          sync.Mutex
+	checkpoints map[string]string
+}
+
+var _ Checkpointer = (*FileCheckpointer)(nil)
+
+// NewFileCheckpointer constructs a FileCheckpointer and loads the checkpoints saved in the file, if it exists.
+// It fails with ErrCheckpointFileLocked if the file is used by another FileCheckpointer.
+func NewFileCheckpointer(path string) (*FileCheckpointer, error) {
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &FileCheckpointer{
+		path:        path,
+		lock:        lock,
+		checkpoints: map[string]string{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+
+		_ = lock.Close()
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.checkpoints); err != nil {
+		_ = lock.Close()
+		return nil, fmt.Errorf("failed to parse checkpoint file '%s': %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Close releases the lock of the checkpoint file.
+func (c *FileCheckpointer) Close() error {
+	return c.lock.Close()
+}
+
+func (c *FileCheckpointer) Load(_ context.Context, storeID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.checkpoints[storeID], nil
+}
+
+func (c *FileCheckpointer) Save(_ context.Context, storeID string, checkpoint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, ok := c.checkpoints[storeID]
+	c.checkpoints[storeID] = checkpoint
+
+	if err := c.flush(); err != nil {
+		// keep the in-memory checkpoints consistent with the file
+		if ok {
+			c.checkpoints[storeID] = previous
+		} else {
+			delete(c.checkpoints, storeID)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// flush writes the checkpoints to a temporary file and renames it over the checkpoint file.
+func (c *FileCheckpointer) flush() error {
+	data, err := json.Marshal(c.checkpoints)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
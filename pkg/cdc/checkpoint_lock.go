@@ -0,0 +1,30 @@
+//go:build !windows && !plan9
+
+package cdc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile opens the file, creating it if needed, and locks it exclusively until it is closed. It fails with
+// ErrCheckpointFileLocked if the file is locked already.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrCheckpointFileLocked
+		}
+
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package cdc
+
+import (
+	"os"
+)
+
+// lockFile opens the file, creating it if needed. Locking it is not supported on this platform.
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+}
@@ -0,0 +1,66 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetterQueue persists the events that could not be published, so that they can be inspected and
+// published again once the sink is fixed.
+type DeadLetterQueue interface {
+	// Put persists the events, which failed to be published with the error.
+	Put(ctx context.Context, events []*Event, cause error) error
+}
+
+// DeadLetter is a batch of events in a dead-letter queue.
+type DeadLetter struct {
+	FailedAt time.Time `json:"failed_at"`
+	Error    string    `json:"error"`
+	Events   []*Event  `json:"events"`
+}
+
+// FileDeadLetterQueue is a DeadLetterQueue which appends the dead letters to a file, one JSON encoded
+// DeadLetter per line.
+type FileDeadLetterQueue struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ DeadLetterQueue = (*FileDeadLetterQueue)(nil)
+
+// NewFileDeadLetterQueue constructs a FileDeadLetterQueue appending to the file, which is created if it does not exist.
+func NewFileDeadLetterQueue(path string) (*FileDeadLetterQueue, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDeadLetterQueue{file: file}, nil
+}
+
+func (q *FileDeadLetterQueue) Put(_ context.Context, events []*Event, cause error) error {
+	line, err := json.Marshal(&DeadLetter{
+		FailedAt: time.Now().UTC(),
+		Error:    cause.Error(),
+		Events:   events,
+	})
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return q.file.Sync()
+}
+
+func (q *FileDeadLetterQueue) Close() error {
+	return q.file.Close()
+}
@@ -0,0 +1,54 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink publishes every event as a JSON message to a Kafka topic. Messages are keyed by
+// store and object, so the changes of an object are delivered in order within its partition.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+var _ Sink = (*KafkaSink)(nil)
+
+// NewKafkaSink constructs a KafkaSink which publishes to the topic through the provided brokers.
+// Publish only returns once every in-sync replica has acknowledged the messages.
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+func (s *KafkaSink) Publish(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:   []byte(event.StoreID + "/" + event.Change.GetTupleKey().GetObject()),
+			Value: value,
+		})
+	}
+
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
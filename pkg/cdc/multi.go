@@ -0,0 +1,45 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type multiSink []Sink
+
+// MultiSink returns the sink publishing the events to every sink concurrently. The publish fails if it fails
+// for any of the sinks, in which case the events are published again to every sink, so the sinks should be
+// RetryingSinks with a dead-letter queue for a failing sink not to hold back the others.
+func MultiSink(sinks ...Sink) Sink {
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+
+	return multiSink(sinks)
+}
+
+func (m multiSink) Publish(ctx context.Context, events []*Event) error {
+	errs := make([]error, len(m))
+
+	var wg sync.WaitGroup
+	for i, sink := range m {
+		wg.Add(1)
+		go func(i int, sink Sink) {
+			defer wg.Done()
+			errs[i] = sink.Publish(ctx, events)
+		}(i, sink)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (m multiSink) Close() error {
+	var errs []error
+	for _, sink := range m {
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
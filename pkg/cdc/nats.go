@@ -0,0 +1,92 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// NATSSink publishes every event as a JSON message to a subject of NATS JetStream, which must be captured by a
+// stream. Every message carries a Nats-Msg-Id header identifying its change, so that the changes published again
+// after a restart are deduplicated by the stream within its duplicate window.
+type NATSSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+var _ Sink = (*NATSSink)(nil)
+
+// NewNATSSink connects to the NATS servers of the URL (e.g. 'nats://host1:4222,nats://host2:4222') and constructs
+// a NATSSink which publishes to the subject. Publish only returns once the stream has acknowledged the messages.
+func NewNATSSink(url string, subject string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("openfga-changelog-export"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to initialize the nats jetstream context: %w", err)
+	}
+
+	return &NATSSink{conn: conn, js: js, subject: subject}, nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, events []*Event) error {
+	// the messages are published asynchronously, in order, and their acknowledgements awaited afterwards
+	acks := make([]nats.PubAckFuture, 0, len(events))
+	for _, event := range events {
+		msg, err := natsMessage(s.subject, event)
+		if err != nil {
+			return err
+		}
+
+		ack, err := s.js.PublishMsgAsync(msg)
+		if err != nil {
+			return err
+		}
+
+		acks = append(acks, ack)
+	}
+
+	for _, ack := range acks {
+		select {
+		case <-ack.Ok():
+		case err := <-ack.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (s *NATSSink) Close() error {
+	return s.conn.Drain()
+}
+
+// natsMessage returns the message of the event published to the subject. Its id is made of the store, the time,
+// the operation and the tuple of the change, which identify it.
+func natsMessage(subject string, event *Event) (*nats.Msg, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s/%d/%s/%s",
+		event.StoreID,
+		event.Change.GetTimestamp().AsTime().UnixNano(),
+		event.Change.GetOperation(),
+		tuple.TupleKeyToString(event.Change.GetTupleKey()),
+	))
+
+	return msg, nil
+}
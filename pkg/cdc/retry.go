@@ -0,0 +1,106 @@
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/openfga/openfga/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// RetryingSink publishes the events to a Sink, retrying the failed publishes with an exponential backoff.
+// Once the retries are exhausted, the events are put in the dead-letter queue of the sink if it has one,
+// in which case the publish succeeds so that the export moves past them, and the publish fails otherwise.
+type RetryingSink struct {
+	sink           Sink
+	deadLetters    DeadLetterQueue
+	logger         logger.Logger
+	maxRetries     uint64
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+var _ Sink = (*RetryingSink)(nil)
+
+type RetryingSinkOption func(s *RetryingSink)
+
+// WithMaxRetries sets how many times a failed publish is retried. Defaults to 5.
+func WithMaxRetries(maxRetries int) RetryingSinkOption {
+	return func(s *RetryingSink) {
+		s.maxRetries = uint64(maxRetries)
+	}
+}
+
+// WithBackoff sets the delay before the first retry, which doubles on every retry up to maxBackoff.
+// Defaults to 500ms and 30s.
+func WithBackoff(initialBackoff, maxBackoff time.Duration) RetryingSinkOption {
+	return func(s *RetryingSink) {
+		s.initialBackoff = initialBackoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// WithDeadLetterQueue sets where the events that could not be published are put once the retries are exhausted.
+func WithDeadLetterQueue(deadLetters DeadLetterQueue) RetryingSinkOption {
+	return func(s *RetryingSink) {
+		s.deadLetters = deadLetters
+	}
+}
+
+func WithRetryLogger(l logger.Logger) RetryingSinkOption {
+	return func(s *RetryingSink) {
+		s.logger = l
+	}
+}
+
+func NewRetryingSink(sink Sink, opts ...RetryingSinkOption) *RetryingSink {
+	s := &RetryingSink{
+		sink:           sink,
+		logger:         logger.NewNoopLogger(),
+		maxRetries:     defaultMaxRetries,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *RetryingSink) Publish(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = s.initialBackoff
+	b.MaxInterval = s.maxBackoff
+	b.MaxElapsedTime = 0 // bounded by the number of retries
+
+	err := backoff.RetryNotify(func() error {
+		return s.sink.Publish(ctx, events)
+	}, backoff.WithContext(backoff.WithMaxRetries(b, s.maxRetries), ctx), func(err error, delay time.Duration) {
+		s.logger.Warn("failed to publish the changes, retrying", zap.Error(err), zap.Duration("delay", delay))
+	})
+	if err == nil || ctx.Err() != nil || s.deadLetters == nil {
+		return err
+	}
+
+	s.logger.Error("failed to publish the changes, putting them in the dead-letter queue", zap.Error(err), zap.Int("events", len(events)))
+
+	return s.deadLetters.Put(ctx, events, err)
+}
+
+// Close closes the sink. The dead-letter queue, which can be shared by several sinks, is not closed.
+func (s *RetryingSink) Close() error {
+	return s.sink.Close()
+}
@@ -0,0 +1,115 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+
+	// SignatureHeader is the header of the requests of a WebhookSink carrying the signature of the request,
+	// as `sha256=<hex>`, when the sink has a signing secret. See Sign.
+	SignatureHeader = "X-OpenFGA-Signature"
+
+	// TimestampHeader is the header of the requests of a WebhookSink carrying the Unix time at which the
+	// request was signed, so that the endpoints can reject replayed requests.
+	TimestampHeader = "X-OpenFGA-Timestamp"
+)
+
+// WebhookSink publishes the events by POSTing them as a JSON array to an HTTP endpoint. Any
+// response status other than 2xx is treated as a failure, and the events are published again.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	secret []byte
+}
+
+var _ Sink = (*WebhookSink)(nil)
+
+type WebhookSinkOption func(s *WebhookSink)
+
+// WithWebhookSecret signs every request with the secret, see SignatureHeader.
+func WithWebhookSecret(secret string) WebhookSinkOption {
+	return func(s *WebhookSink) {
+		s.secret = []byte(secret)
+	}
+}
+
+// WithWebhookTimeout sets how long the endpoint has to respond. Defaults to 10s.
+func WithWebhookTimeout(timeout time.Duration) WebhookSinkOption {
+	return func(s *WebhookSink) {
+		s.client.Timeout = timeout
+	}
+}
+
+func NewWebhookSink(url string, opts ...WebhookSinkOption) *WebhookSink {
+	s := &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Sign returns the signature of a request of a WebhookSink, as sent in the SignatureHeader: the hex encoded
+// HMAC-SHA256 with the secret of the timestamp of the request (see TimestampHeader), a '.' and the body.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", s.url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
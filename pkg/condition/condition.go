@@ -0,0 +1,135 @@
+// Package condition compiles and evaluates the conditions attached to tuples. A condition is a CEL
+// expression (see https://github.com/google/cel-spec) that must evaluate to a bool. The expression can
+// reference the parameters written with the tuple as `params` and the context supplied with the request
+// as `context`, for example:
+//
+//	inCIDR(context.ip, params.cidr)
+//	context.time < params.expires_at
+//
+// Besides the standard CEL functions, the inCIDR(ip, cidr) function reports whether an IP address
+// belongs to a CIDR range.
+package condition
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ParametersVariable is the name of the variable holding the parameters written with the tuple.
+	ParametersVariable = "params"
+
+	// ContextVariable is the name of the variable holding the context supplied with the request.
+	ContextVariable = "context"
+
+	// maxEvaluationCost bounds the cost of evaluating a condition, so that a condition can't be used
+	// to exhaust the resources of the server.
+	maxEvaluationCost = 10000
+)
+
+var (
+	ErrInvalidCondition = errors.New("invalid condition")
+	ErrEvaluationFailed = errors.New("failed to evaluate condition")
+
+	env = mustNewEnv()
+)
+
+func mustNewEnv() *cel.Env {
+	env, err := cel.NewEnv(
+		cel.Variable(ParametersVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(ContextVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+		cel.Function("inCIDR",
+			cel.Overload("inCIDR_string_string",
+				[]*cel.Type{cel.StringType, cel.StringType},
+				cel.BoolType,
+				cel.BinaryBinding(inCIDR),
+			),
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return env
+}
+
+func inCIDR(lhs, rhs ref.Val) ref.Val {
+	ip, err := netip.ParseAddr(fmt.Sprint(lhs.Value()))
+	if err != nil {
+		return types.NewErr("inCIDR: invalid ip address: %v", err)
+	}
+
+	prefix, err := netip.ParsePrefix(fmt.Sprint(rhs.Value()))
+	if err != nil {
+		return types.NewErr("inCIDR: invalid cidr: %v", err)
+	}
+
+	return types.Bool(prefix.Contains(ip))
+}
+
+// Condition is a compiled condition, ready to be evaluated. A Condition may be safely shared by
+// multiple goroutines.
+type Condition struct {
+	expression string
+	program    cel.Program
+}
+
+// Compile compiles the expression of a condition, returning an error wrapping ErrInvalidCondition if
+// the expression is not valid or does not evaluate to a bool.
+func Compile(expression string) (*Condition, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, issues.Err())
+	}
+
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("%w: the expression must evaluate to a bool, not %s", ErrInvalidCondition, ast.OutputType())
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(maxEvaluationCost))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCondition, err)
+	}
+
+	return &Condition{expression: expression, program: program}, nil
+}
+
+// Expression returns the expression the condition was compiled from.
+func (c *Condition) Expression() string {
+	return c.expression
+}
+
+// Evaluate evaluates the condition with the provided parameters and request context, either of which may
+// be nil. It returns an error wrapping ErrEvaluationFailed if the expression references a parameter or a
+// context value that is not provided, or if it does not evaluate to a bool.
+func (c *Condition) Evaluate(parameters, requestContext *structpb.Struct) (bool, error) {
+	out, _, err := c.program.Eval(map[string]any{
+		ParametersVariable: asMap(parameters),
+		ContextVariable:    asMap(requestContext),
+	})
+	if err != nil {
+		return false, fmt.Errorf("%w '%s': %s", ErrEvaluationFailed, c.expression, err)
+	}
+
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%w '%s': the expression evaluated to %v, not a bool", ErrEvaluationFailed, c.expression, out.Value())
+	}
+
+	return allowed, nil
+}
+
+func asMap(s *structpb.Struct) map[string]any {
+	if s == nil {
+		return map[string]any{}
+	}
+
+	return s.AsMap()
+}
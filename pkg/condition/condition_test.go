@@ -0,0 +1,115 @@
+package condition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCompile(t *testing.T) {
+	_, err := Compile("inCIDR(context.ip, params.cidr)")
+	require.NoError(t, err)
+
+	_, err = Compile("context.ip ==")
+	require.ErrorIs(t, err, ErrInvalidCondition)
+
+	_, err = Compile(`"not a bool"`)
+	require.ErrorIs(t, err, ErrInvalidCondition)
+
+	_, err = Compile("unknown.field")
+	require.ErrorIs(t, err, ErrInvalidCondition)
+}
+
+func TestEvaluate(t *testing.T) {
+	parameters, err := structpb.NewStruct(map[string]interface{}{"cidr": "10.0.0.0/8", "max_amount": 100})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		expression     string
+		requestContext map[string]interface{}
+		expected       bool
+		expectedErr    error
+	}{
+		{
+			name:           "ip_in_range",
+			expression:     "inCIDR(context.ip, params.cidr)",
+			requestContext: map[string]interface{}{"ip": "10.1.2.3"},
+			expected:       true,
+		},
+		{
+			name:           "ip_out_of_range",
+			expression:     "inCIDR(context.ip, params.cidr)",
+			requestContext: map[string]interface{}{"ip": "192.168.0.1"},
+			expected:       false,
+		},
+		{
+			name:           "invalid_ip",
+			expression:     "inCIDR(context.ip, params.cidr)",
+			requestContext: map[string]interface{}{"ip": "not-an-ip"},
+			expectedErr:    ErrEvaluationFailed,
+		},
+		{
+			name:        "missing_context_value",
+			expression:  "inCIDR(context.ip, params.cidr)",
+			expectedErr: ErrEvaluationFailed,
+		},
+		{
+			name:           "numbers_compare_across_types",
+			expression:     "context.amount <= params.max_amount",
+			requestContext: map[string]interface{}{"amount": 42},
+			expected:       true,
+		},
+		{
+			name:           "dynamic_result_must_be_a_bool",
+			expression:     "context.amount",
+			requestContext: map[string]interface{}{"amount": 42},
+			expectedErr:    ErrEvaluationFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := Compile(test.expression)
+			require.NoError(t, err)
+
+			requestContext, err := structpb.NewStruct(test.requestContext)
+			require.NoError(t, err)
+
+			allowed, err := c.Evaluate(parameters, requestContext)
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, test.expected, allowed)
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}
+
+	var got *structpb.Struct
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = FromContext(ctx)
+		return nil, nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ContextHeader, `{"ip": "10.0.0.1"}`))
+	_, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", got.GetFields()["ip"].GetStringValue())
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(ContextHeader, `not json`))
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
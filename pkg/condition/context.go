@@ -0,0 +1,78 @@
+package condition
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ContextHeader is the gRPC metadata key of the request context, a JSON object. Over HTTP it is sent
+// with the Grpc-Metadata- prefix, i.e. as the Grpc-Metadata-Openfga-Condition-Context header.
+const ContextHeader = "openfga-condition-context"
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying the request context the conditions of the tuples are evaluated with.
+func NewContext(ctx context.Context, requestContext *structpb.Struct) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestContext)
+}
+
+// FromContext returns the request context carried by ctx, or nil if there is none.
+func FromContext(ctx context.Context) *structpb.Struct {
+	requestContext, _ := ctx.Value(ctxKey{}).(*structpb.Struct)
+	return requestContext
+}
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor that injects the request context sent in the
+// ContextHeader metadata into the context, rejecting requests whose header is not a JSON object.
+func NewUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextFromMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor is the streaming counterpart of NewUnaryInterceptor.
+func NewStreamingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextFromMetadata(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func contextFromMetadata(ctx context.Context) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, ContextHeader)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	requestContext := &structpb.Struct{}
+	if err := protojson.Unmarshal([]byte(values[0]), requestContext); err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("the %s header must be a JSON object: %s", ContextHeader, err))
+	}
+
+	return NewContext(ctx, requestContext), nil
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
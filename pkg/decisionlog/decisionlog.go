@@ -0,0 +1,348 @@
+// Package decisionlog emits a structured log of the authorization decisions made by Check and ListObjects, for
+// consumption by a SIEM. Unlike the audit log, the decision log is sampled, and the identifying fields of the
+// entries can be redacted.
+//
+// The entries are written as JSON lines following a stable schema: fields are only ever added to Entry, and
+// any incompatible change increments SchemaVersion.
+package decisionlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/openfga/openfga/pkg/middleware/clientcert"
+	"github.com/openfga/openfga/pkg/middleware/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SchemaVersion is the version of the schema of the entries.
+	SchemaVersion = 1
+
+	// RedactedValue replaces the value of the redacted fields.
+	RedactedValue = "[redacted]"
+
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+	DecisionError   = "error"
+)
+
+// The fields that can be redacted.
+const (
+	FieldPrincipal        = "principal"
+	FieldUser             = "user"
+	FieldRelation         = "relation"
+	FieldObject           = "object"
+	FieldContextualTuples = "contextual_tuples"
+)
+
+// RedactableFields are the names of the fields that can be redacted.
+var RedactableFields = []string{FieldPrincipal, FieldUser, FieldRelation, FieldObject, FieldContextualTuples}
+
+// Entry is an authorization decision.
+type Entry struct {
+	SchemaVersion        int       `json:"schema_version"`
+	Time                 time.Time `json:"time"`
+	RequestID            string    `json:"request_id,omitempty"`
+	Method               string    `json:"method"`
+	StoreID              string    `json:"store_id"`
+	AuthorizationModelID string    `json:"authorization_model_id,omitempty"`
+	Principal            string    `json:"principal,omitempty"`
+
+	User     string `json:"user"`
+	Relation string `json:"relation"`
+
+	// Object is the object of a Check, and ObjectType the type of the objects of a ListObjects.
+	Object     string `json:"object,omitempty"`
+	ObjectType string `json:"object_type,omitempty"`
+
+	ContextualTuples []string `json:"contextual_tuples,omitempty"`
+
+	// Decision is 'allowed' or 'denied' for a Check, the number of objects for a ListObjects, and 'error' if the
+	// call failed.
+	Decision    string `json:"decision"`
+	ObjectCount *int   `json:"object_count,omitempty"`
+	Code        uint32 `json:"code"`
+	Error       string `json:"error,omitempty"`
+
+	LatencyMs float64 `json:"latency_ms"`
+
+	// SampleRate is the probability with which the decisions were logged, so that counts can be extrapolated.
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// Emitter samples, redacts and writes the decisions. Emitter instances may be safely shared by multiple
+// goroutines.
+type Emitter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+
+	sampleRate float64
+	redacted   map[string]bool
+
+	// sample returns a number in [0, 1) which is compared to the sample rate
+	sample func() float64
+}
+
+type EmitterOption func(e *Emitter)
+
+// WithSampleRate sets the probability, between 0 and 1, with which a decision is logged. Defaults to 1.
+func WithSampleRate(rate float64) EmitterOption {
+	return func(e *Emitter) {
+		e.sampleRate = rate
+	}
+}
+
+// WithRedactedFields sets the fields of the entries whose values are replaced by RedactedValue.
+func WithRedactedFields(fields ...string) EmitterOption {
+	return func(e *Emitter) {
+		for _, field := range fields {
+			e.redacted[field] = true
+		}
+	}
+}
+
+// NewEmitter constructs an Emitter which writes the entries as JSON lines to w. It returns an error if the
+// sample rate is not between 0 and 1, or if a redacted field is not one of RedactableFields.
+func NewEmitter(w io.Writer, opts ...EmitterOption) (*Emitter, error) {
+	e := &Emitter{
+		encoder:    json.NewEncoder(w),
+		sampleRate: 1,
+		redacted:   map[string]bool{},
+		sample:     rand.Float64,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.sampleRate < 0 || e.sampleRate > 1 {
+		return nil, fmt.Errorf("the sample rate must be between 0 and 1")
+	}
+
+	for field := range e.redacted {
+		if !isRedactable(field) {
+			return nil, fmt.Errorf("field '%s' cannot be redacted, must be one of %v", field, RedactableFields)
+		}
+	}
+
+	return e, nil
+}
+
+func isRedactable(field string) bool {
+	for _, redactable := range RedactableFields {
+		if field == redactable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sampled returns true if the next decision must be logged.
+func (e *Emitter) sampled() bool {
+	if e.sampleRate >= 1 {
+		return true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.sample() < e.sampleRate
+}
+
+// Emit redacts and writes the entry.
+func (e *Emitter) Emit(entry *Entry) error {
+	entry.SchemaVersion = SchemaVersion
+	entry.SampleRate = e.sampleRate
+	e.redact(entry)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.encoder.Encode(entry)
+}
+
+func (e *Emitter) redact(entry *Entry) {
+	redactString := func(field string, value *string) {
+		if e.redacted[field] && *value != "" {
+			*value = RedactedValue
+		}
+	}
+
+	redactString(FieldPrincipal, &entry.Principal)
+	redactString(FieldUser, &entry.User)
+	redactString(FieldRelation, &entry.Relation)
+	redactString(FieldObject, &entry.Object)
+
+	if e.redacted[FieldContextualTuples] {
+		for i := range entry.ContextualTuples {
+			entry.ContextualTuples[i] = RedactedValue
+		}
+	}
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which logs the sampled Check and ListObjects
+// decisions. Failures to write the log are ignored.
+func NewUnaryInterceptor(e *Emitter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var entry *Entry
+		switch r := req.(type) {
+		case *openfgav1.CheckRequest:
+			entry = checkEntry(r)
+		case *openfgav1.ListObjectsRequest:
+			entry = listObjectsEntry(r)
+		}
+
+		if entry == nil || !e.sampled() {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		switch r := resp.(type) {
+		case *openfgav1.CheckResponse:
+			entry.Decision = DecisionDenied
+			if r.GetAllowed() {
+				entry.Decision = DecisionAllowed
+			}
+		case *openfgav1.ListObjectsResponse:
+			setObjectCount(entry, len(r.GetObjects()))
+		}
+
+		finish(ctx, entry, info.FullMethod, start, err)
+		_ = e.Emit(entry)
+
+		return resp, err
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which logs the sampled StreamedListObjects
+// decisions, with the number of objects streamed.
+func NewStreamingInterceptor(e *Emitter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if path.Base(info.FullMethod) != "StreamedListObjects" || !e.sampled() {
+			return handler(srv, stream)
+		}
+
+		start := time.Now()
+		logged := &loggedServerStream{ServerStream: stream}
+		err := handler(srv, logged)
+
+		entry := &Entry{}
+		if logged.req != nil {
+			entry = listObjectsEntry(&openfgav1.ListObjectsRequest{
+				StoreId:              logged.req.GetStoreId(),
+				AuthorizationModelId: logged.req.GetAuthorizationModelId(),
+				Type:                 logged.req.GetType(),
+				Relation:             logged.req.GetRelation(),
+				User:                 logged.req.GetUser(),
+				ContextualTuples:     logged.req.GetContextualTuples(),
+			})
+		}
+		setObjectCount(entry, logged.sent)
+
+		finish(stream.Context(), entry, info.FullMethod, start, err)
+		_ = e.Emit(entry)
+
+		return err
+	}
+}
+
+type loggedServerStream struct {
+	grpc.ServerStream
+	req  *openfgav1.StreamedListObjectsRequest
+	sent int
+}
+
+func (s *loggedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if r, ok := m.(*openfgav1.StreamedListObjectsRequest); ok && s.req == nil {
+		s.req = r
+	}
+
+	return nil
+}
+
+func (s *loggedServerStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+
+	s.sent++
+	return nil
+}
+
+func checkEntry(req *openfgav1.CheckRequest) *Entry {
+	return &Entry{
+		StoreID:              req.GetStoreId(),
+		AuthorizationModelID: req.GetAuthorizationModelId(),
+		User:                 req.GetTupleKey().GetUser(),
+		Relation:             req.GetTupleKey().GetRelation(),
+		Object:               req.GetTupleKey().GetObject(),
+		ContextualTuples:     tupleStrings(req.GetContextualTuples().GetTupleKeys()),
+	}
+}
+
+func listObjectsEntry(req *openfgav1.ListObjectsRequest) *Entry {
+	return &Entry{
+		StoreID:              req.GetStoreId(),
+		AuthorizationModelID: req.GetAuthorizationModelId(),
+		User:                 req.GetUser(),
+		Relation:             req.GetRelation(),
+		ObjectType:           req.GetType(),
+		ContextualTuples:     tupleStrings(req.GetContextualTuples().GetTupleKeys()),
+	}
+}
+
+func tupleStrings(tupleKeys []*openfgav1.TupleKey) []string {
+	if len(tupleKeys) == 0 {
+		return nil
+	}
+
+	tuples := make([]string, 0, len(tupleKeys))
+	for _, tk := range tupleKeys {
+		tuples = append(tuples, fmt.Sprintf("%s#%s@%s", tk.GetObject(), tk.GetRelation(), tk.GetUser()))
+	}
+
+	return tuples
+}
+
+func setObjectCount(entry *Entry, count int) {
+	entry.ObjectCount = &count
+	entry.Decision = fmt.Sprintf("%d objects", count)
+}
+
+// finish sets the fields of the entry that are known once the call returned.
+func finish(ctx context.Context, entry *Entry, fullMethod string, start time.Time, err error) {
+	entry.Time = start.UTC()
+	entry.Method = path.Base(fullMethod)
+	entry.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	entry.RequestID, _ = requestid.FromContext(ctx)
+
+	if claims, ok := authn.AuthClaimsFromContext(ctx); ok && claims.Principal() != "" {
+		entry.Principal = claims.Principal()
+	} else {
+		entry.Principal, _ = clientcert.SubjectFromContext(ctx)
+	}
+
+	if err != nil {
+		s := status.Convert(err)
+		entry.Decision = DecisionError
+		entry.Code = uint32(s.Code())
+		entry.Error = s.Message()
+	}
+}
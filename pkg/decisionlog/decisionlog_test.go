@@ -0,0 +1,111 @@
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var checkInfo = &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}
+
+func checkRequest() *openfgav1.CheckRequest {
+	return &openfgav1.CheckRequest{
+		StoreId:  "store1",
+		TupleKey: &openfgav1.TupleKey{Object: "doc:1", Relation: "viewer", User: "user:anne"},
+		ContextualTuples: &openfgav1.ContextualTupleKeys{
+			TupleKeys: []*openfgav1.TupleKey{{Object: "group:eng", Relation: "member", User: "user:anne"}},
+		},
+	}
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var entry map[string]interface{}
+		require.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestCheckDecision(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewEmitter(&buf, WithRedactedFields(FieldUser, FieldContextualTuples))
+	require.NoError(t, err)
+
+	interceptor := NewUnaryInterceptor(e)
+	ctx := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Subject: "service-a"})
+
+	_, err = interceptor(ctx, checkRequest(), checkInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &openfgav1.CheckResponse{Allowed: true}, nil
+	})
+	require.NoError(t, err)
+
+	_, err = interceptor(ctx, checkRequest(), checkInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "invalid")
+	})
+	require.Error(t, err)
+
+	entries := decode(t, &buf)
+	require.Len(t, entries, 2)
+
+	allowed := entries[0]
+	require.EqualValues(t, SchemaVersion, allowed["schema_version"])
+	require.Equal(t, "Check", allowed["method"])
+	require.Equal(t, "store1", allowed["store_id"])
+	require.Equal(t, "service-a", allowed["principal"])
+	require.Equal(t, RedactedValue, allowed["user"])
+	require.Equal(t, "viewer", allowed["relation"])
+	require.Equal(t, "doc:1", allowed["object"])
+	require.Equal(t, []interface{}{RedactedValue}, allowed["contextual_tuples"])
+	require.Equal(t, DecisionAllowed, allowed["decision"])
+	require.EqualValues(t, 1, allowed["sample_rate"])
+
+	failed := entries[1]
+	require.Equal(t, DecisionError, failed["decision"])
+	require.EqualValues(t, codes.InvalidArgument, failed["code"])
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	e, err := NewEmitter(&buf, WithSampleRate(0.5))
+	require.NoError(t, err)
+
+	samples := []float64{0.1, 0.7, 0.4, 0.9}
+	e.sample = func() float64 {
+		s := samples[0]
+		samples = samples[1:]
+		return s
+	}
+
+	interceptor := NewUnaryInterceptor(e)
+	for i := 0; i < 4; i++ {
+		_, err = interceptor(context.Background(), checkRequest(), checkInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &openfgav1.CheckResponse{}, nil
+		})
+		require.NoError(t, err)
+	}
+
+	entries := decode(t, &buf)
+	require.Len(t, entries, 2)
+	require.EqualValues(t, 0.5, entries[0]["sample_rate"])
+	require.Equal(t, DecisionDenied, entries[0]["decision"])
+}
+
+func TestNewEmitterValidation(t *testing.T) {
+	_, err := NewEmitter(&bytes.Buffer{}, WithSampleRate(1.5))
+	require.EqualError(t, err, "the sample rate must be between 0 and 1")
+
+	_, err = NewEmitter(&bytes.Buffer{}, WithRedactedFields("store_id"))
+	require.ErrorContains(t, err, "field 'store_id' cannot be redacted")
+}
@@ -0,0 +1,152 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// ContinuationTokenVersion is the version of the continuation tokens marshalled by the ProtoTokenCodec.
+	ContinuationTokenVersion byte = 1
+
+	// maxContinuationTokenVersion is the largest byte reserved for the versions of the continuation tokens. The
+	// tokens of the datastores are printable (e.g. JSON), so a token which starts with a larger byte is a
+	// datastore token issued before the tokens were versioned.
+	maxContinuationTokenVersion byte = 0x1f
+)
+
+const (
+	// TokenKindRead is the kind of the continuation tokens of the tuple reads.
+	TokenKindRead = "read"
+
+	// TokenKindChanges is the kind of the continuation tokens of the changelog reads.
+	TokenKindChanges = "changes"
+)
+
+// protobuf field numbers of a version 1 continuation token
+const (
+	tokenKindField       protowire.Number = 1
+	tokenPaginationField protowire.Number = 2
+)
+
+// ContinuationToken is the content of a continuation token, before it is encoded with an Encoder.
+type ContinuationToken struct {
+	// Kind is the kind of the API which issued the token, e.g. TokenKindRead, so that the token can't be passed to
+	// another API. It is empty for the tokens issued before the tokens were versioned.
+	Kind string
+
+	// Pagination is the opaque pagination state of the datastore.
+	Pagination []byte
+}
+
+// TokenCodec marshals the continuation tokens in a given format. Every TokenCodec unmarshals the tokens of all the
+// formats (see UnmarshalContinuationToken), so that the format can be changed without invalidating the outstanding
+// tokens.
+type TokenCodec interface {
+	Marshal(token ContinuationToken) ([]byte, error)
+	Unmarshal(data []byte) (ContinuationToken, error)
+}
+
+// ProtoTokenCodec implements the TokenCodec interface with a version byte followed by the token as a protobuf
+// message. New fields can be added to the message, which the servers that don't know them ignore.
+type ProtoTokenCodec struct{}
+
+var _ TokenCodec = (*ProtoTokenCodec)(nil)
+
+// NewProtoTokenCodec constructs a ProtoTokenCodec.
+func NewProtoTokenCodec() *ProtoTokenCodec {
+	return &ProtoTokenCodec{}
+}
+
+// Marshal marshals the token as a version 1 token, or returns empty data if the token has no pagination state.
+func (c *ProtoTokenCodec) Marshal(token ContinuationToken) ([]byte, error) {
+	if len(token.Pagination) == 0 {
+		return nil, nil
+	}
+
+	data := []byte{ContinuationTokenVersion}
+	if token.Kind != "" {
+		data = protowire.AppendTag(data, tokenKindField, protowire.BytesType)
+		data = protowire.AppendString(data, token.Kind)
+	}
+	data = protowire.AppendTag(data, tokenPaginationField, protowire.BytesType)
+	data = protowire.AppendBytes(data, token.Pagination)
+
+	return data, nil
+}
+
+// Unmarshal unmarshals a token of any format, see UnmarshalContinuationToken.
+func (c *ProtoTokenCodec) Unmarshal(data []byte) (ContinuationToken, error) {
+	return UnmarshalContinuationToken(data)
+}
+
+// RawTokenCodec implements the TokenCodec interface with the pagination state of the datastore only, which is the
+// format of the tokens issued before the tokens were versioned. It lets the servers issue tokens that the servers
+// which don't know the versioned tokens can decode, e.g. during a rolling upgrade.
+type RawTokenCodec struct{}
+
+var _ TokenCodec = (*RawTokenCodec)(nil)
+
+// NewRawTokenCodec constructs a RawTokenCodec.
+func NewRawTokenCodec() *RawTokenCodec {
+	return &RawTokenCodec{}
+}
+
+// Marshal returns the pagination state of the token.
+func (c *RawTokenCodec) Marshal(token ContinuationToken) ([]byte, error) {
+	return token.Pagination, nil
+}
+
+// Unmarshal unmarshals a token of any format, see UnmarshalContinuationToken.
+func (c *RawTokenCodec) Unmarshal(data []byte) (ContinuationToken, error) {
+	return UnmarshalContinuationToken(data)
+}
+
+// UnmarshalContinuationToken unmarshals a token marshalled by any TokenCodec. The tokens which don't start with a
+// version byte are the pagination state of the datastore, and have no kind.
+func UnmarshalContinuationToken(data []byte) (ContinuationToken, error) {
+	if len(data) == 0 {
+		return ContinuationToken{}, nil
+	}
+
+	switch version := data[0]; {
+	case version == ContinuationTokenVersion:
+		return unmarshalProtoToken(data[1:])
+	case version <= maxContinuationTokenVersion:
+		return ContinuationToken{}, fmt.Errorf("unsupported continuation token version %d", version)
+	default:
+		return ContinuationToken{Pagination: data}, nil
+	}
+}
+
+func unmarshalProtoToken(data []byte) (ContinuationToken, error) {
+	var token ContinuationToken
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return ContinuationToken{}, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case num == tokenKindField && typ == protowire.BytesType:
+			token.Kind, n = protowire.ConsumeString(data)
+		case num == tokenPaginationField && typ == protowire.BytesType:
+			token.Pagination, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return ContinuationToken{}, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+
+	if len(token.Pagination) == 0 {
+		return ContinuationToken{}, errors.New("the continuation token has no pagination state")
+	}
+
+	return token, nil
+}
@@ -0,0 +1,142 @@
+package encoder
+
+import (
+	"testing"
+
+	"github.com/openfga/openfga/pkg/encrypter"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestContinuationTokenCodecs(t *testing.T) {
+	token := ContinuationToken{Kind: TokenKindRead, Pagination: []byte(`{"ulid":"01H8Y1HVCB2E4J6Y0E2VD3W3QA"}`)}
+
+	t.Run("proto_roundtrips", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+		require.Equal(t, ContinuationTokenVersion, data[0])
+
+		got, err := NewProtoTokenCodec().Unmarshal(data)
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("empty_pagination_is_marshalled_to_an_empty_token", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(ContinuationToken{Kind: TokenKindRead})
+		require.NoError(t, err)
+		require.Empty(t, data)
+
+		got, err := NewProtoTokenCodec().Unmarshal(nil)
+		require.NoError(t, err)
+		require.Empty(t, got.Pagination)
+	})
+
+	t.Run("raw_tokens_are_unmarshalled_by_every_codec", func(t *testing.T) {
+		data, err := NewRawTokenCodec().Marshal(token)
+		require.NoError(t, err)
+		require.Equal(t, token.Pagination, data)
+
+		for _, codec := range []TokenCodec{NewProtoTokenCodec(), NewRawTokenCodec()} {
+			got, err := codec.Unmarshal(data)
+			require.NoError(t, err)
+			require.Equal(t, ContinuationToken{Pagination: token.Pagination}, got)
+		}
+	})
+
+	t.Run("proto_tokens_are_unmarshalled_by_the_raw_codec", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+
+		got, err := NewRawTokenCodec().Unmarshal(data)
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("unknown_fields_are_ignored", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+
+		data = protowire.AppendTag(data, 15, protowire.VarintType)
+		data = protowire.AppendVarint(data, 42)
+
+		got, err := UnmarshalContinuationToken(data)
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+
+	t.Run("unsupported_versions_are_rejected", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+		data[0] = ContinuationTokenVersion + 1
+
+		_, err = UnmarshalContinuationToken(data)
+		require.ErrorContains(t, err, "unsupported continuation token version")
+	})
+
+	t.Run("malformed_tokens_are_rejected", func(t *testing.T) {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+
+		_, err = UnmarshalContinuationToken(data[:len(data)-1])
+		require.Error(t, err)
+
+		_, err = UnmarshalContinuationToken([]byte{ContinuationTokenVersion})
+		require.Error(t, err)
+	})
+}
+
+func FuzzUnmarshalContinuationToken(f *testing.F) {
+	for _, token := range []ContinuationToken{
+		{Kind: TokenKindRead, Pagination: []byte(`{"ulid":"01H8Y1HVCB2E4J6Y0E2VD3W3QA"}`)},
+		{Kind: TokenKindChanges, Pagination: []byte(`3|document`)},
+		{Pagination: []byte(`1`)},
+	} {
+		data, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(f, err)
+		f.Add(data)
+	}
+	f.Add([]byte(`{"ulid":"01H8Y1HVCB2E4J6Y0E2VD3W3QA"}`))
+	f.Add([]byte{ContinuationTokenVersion, 0x12, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		token, err := UnmarshalContinuationToken(data)
+		if err != nil || len(token.Pagination) == 0 {
+			return
+		}
+
+		// the tokens which unmarshal remarshal to a token with the same content
+		marshalled, err := NewProtoTokenCodec().Marshal(token)
+		require.NoError(t, err)
+
+		got, err := UnmarshalContinuationToken(marshalled)
+		require.NoError(t, err)
+		require.Equal(t, token, got)
+	})
+}
+
+func FuzzDecodeContinuationToken(f *testing.F) {
+	gcm, err := encrypter.NewGCMEncrypter("key")
+	require.NoError(f, err)
+
+	e := NewTokenEncoder(gcm, NewBase64Encoder())
+
+	data, err := NewProtoTokenCodec().Marshal(ContinuationToken{Kind: TokenKindRead, Pagination: []byte(`{"ulid":"01H8Y1HVCB2E4J6Y0E2VD3W3QA"}`)})
+	require.NoError(f, err)
+
+	token, err := e.Encode(data)
+	require.NoError(f, err)
+
+	f.Add(token)
+	f.Add("")
+	f.Add("AAAA")
+	f.Add("not a token")
+
+	f.Fuzz(func(t *testing.T, token string) {
+		decoded, err := e.Decode(token)
+		if err != nil {
+			return
+		}
+
+		_, _ = UnmarshalContinuationToken(decoded)
+	})
+}
@@ -0,0 +1,184 @@
+package encoder
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// jwsClaims are the claims of a signed continuation token. The token encoded by the wrapped
+// Encoder is carried in the 'tok' claim.
+type jwsClaims struct {
+	Token string `json:"tok"`
+	jwt.RegisteredClaims
+}
+
+// JWSEncoder implements the Encoder interface by signing the tokens encoded by another Encoder
+// as a JWS (a JWT in compact serialization) which expires after a TTL, so that the tokens can't
+// be tampered with or replayed indefinitely. The tokens are signed with an HMAC secret or with an
+// asymmetric private key, depending on the algorithm.
+type JWSEncoder struct {
+	encoder Encoder
+	method  jwt.SigningMethod
+	parser  *jwt.Parser
+	ttl     time.Duration
+
+	signingKey      any
+	verificationKey any
+
+	// subject is the store the tokens are bound to, if any.
+	subject string
+
+	now func() time.Time
+}
+
+var _ Encoder = (*JWSEncoder)(nil)
+
+// NewJWSEncoder constructs a JWSEncoder which signs the tokens encoded by `encoder` with the JWS
+// algorithm `algorithm` (e.g. 'HS256', 'RS256', 'ES256' or 'EdDSA'), and rejects them once the
+// TTL has elapsed. The key is the secret of the HMAC algorithms, or the PEM encoded private key of
+// the asymmetric algorithms, whose public key verifies the tokens.
+func NewJWSEncoder(encoder Encoder, algorithm string, key []byte, ttl time.Duration) (*JWSEncoder, error) {
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("'%s' is not a supported JWS algorithm", algorithm)
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New("the TTL of the signed tokens must be greater than 0")
+	}
+
+	if len(key) == 0 {
+		return nil, errors.New("a key must be provided to sign the tokens")
+	}
+
+	var signer crypto.Signer
+	var err error
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return newJWSEncoder(encoder, method, key, key, ttl), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		signer, err = jwt.ParseRSAPrivateKeyFromPEM(key)
+	case *jwt.SigningMethodECDSA:
+		signer, err = jwt.ParseECPrivateKeyFromPEM(key)
+	case *jwt.SigningMethodEd25519:
+		var edKey crypto.PrivateKey
+		edKey, err = jwt.ParseEdPrivateKeyFromPEM(key)
+		if err == nil {
+			signer, _ = edKey.(crypto.Signer)
+		}
+	default:
+		return nil, fmt.Errorf("'%s' is not a supported JWS algorithm", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key for '%s': %w", algorithm, err)
+	}
+
+	return newJWSEncoder(encoder, method, signer, signer.Public(), ttl), nil
+}
+
+func newJWSEncoder(encoder Encoder, method jwt.SigningMethod, signingKey, verificationKey any, ttl time.Duration) *JWSEncoder {
+	return &JWSEncoder{
+		encoder:         encoder,
+		method:          method,
+		parser:          jwt.NewParser(jwt.WithValidMethods([]string{method.Alg()}), jwt.WithoutClaimsValidation()),
+		ttl:             ttl,
+		signingKey:      signingKey,
+		verificationKey: verificationKey,
+		now:             time.Now,
+	}
+}
+
+// forStore returns a copy of the encoder wrapping `encoder`, whose tokens are bound to the store.
+func (e *JWSEncoder) forStore(encoder Encoder, storeID string) *JWSEncoder {
+	clone := *e
+	clone.encoder = encoder
+	clone.subject = storeID
+
+	return &clone
+}
+
+// Decode verifies the signature and the expiration of the token, and decodes the token it
+// carries with the wrapped Encoder.
+func (e *JWSEncoder) Decode(s string) ([]byte, error) {
+	if s == "" {
+		return e.encoder.Decode(s)
+	}
+
+	var claims jwsClaims
+	_, err := e.parser.ParseWithClaims(s, &claims, func(*jwt.Token) (any, error) {
+		return e.verificationKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == nil || !e.now().Before(claims.ExpiresAt.Time) {
+		return nil, errors.New("the token has expired")
+	}
+
+	if claims.Subject != e.subject {
+		return nil, errors.New("the token was issued for another store")
+	}
+
+	return e.encoder.Decode(claims.Token)
+}
+
+// Encode encodes the data with the wrapped Encoder, and signs the result with an expiration.
+func (e *JWSEncoder) Encode(data []byte) (string, error) {
+	if len(data) == 0 {
+		return e.encoder.Encode(data)
+	}
+
+	encoded, err := e.encoder.Encode(data)
+	if err != nil {
+		return "", err
+	}
+
+	now := e.now()
+	token := jwt.NewWithClaims(e.method, jwsClaims{
+		Token: encoded,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   e.subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(e.ttl)),
+		},
+	})
+
+	return token.SignedString(e.signingKey)
+}
+
+// JWSStoreEncoder implements the StoreEncoder interface by signing the tokens of each store with
+// a JWSEncoder, which binds them to the store so that they can't be used with another store.
+type JWSStoreEncoder struct {
+	signer *JWSEncoder
+	stores StoreEncoder
+}
+
+var _ StoreEncoder = (*JWSStoreEncoder)(nil)
+
+// NewJWSStoreEncoder constructs a JWSStoreEncoder which signs the tokens encoded by the encoder
+// that `stores` resolves for each store, or by the Encoder wrapped by `signer` if `stores` is nil.
+func NewJWSStoreEncoder(signer *JWSEncoder, stores StoreEncoder) *JWSStoreEncoder {
+	return &JWSStoreEncoder{
+		signer: signer,
+		stores: stores,
+	}
+}
+
+// ForStore returns a JWSEncoder which signs the tokens of the provided store.
+func (e *JWSStoreEncoder) ForStore(storeID string) (Encoder, error) {
+	encoder := e.signer.encoder
+	if e.stores != nil {
+		var err error
+		encoder, err = e.stores.ForStore(storeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return e.signer.forStore(encoder, storeID), nil
+}
@@ -0,0 +1,118 @@
+package encoder
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/openfga/openfga/pkg/encrypter"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJWSEncoder(t *testing.T) {
+	_, err := NewJWSEncoder(NewBase64Encoder(), "none", []byte("key"), time.Hour)
+	require.ErrorContains(t, err, "not a supported JWS algorithm")
+
+	_, err = NewJWSEncoder(NewBase64Encoder(), "HS256", []byte("key"), 0)
+	require.ErrorContains(t, err, "TTL")
+
+	_, err = NewJWSEncoder(NewBase64Encoder(), "HS256", nil, time.Hour)
+	require.ErrorContains(t, err, "key must be provided")
+
+	_, err = NewJWSEncoder(NewBase64Encoder(), "ES256", []byte("not a pem key"), time.Hour)
+	require.ErrorContains(t, err, "failed to parse the private key")
+}
+
+func TestJWSEncoder(t *testing.T) {
+	want := []byte("some random string")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+
+	for algorithm, key := range map[string][]byte{"HS256": []byte("secret"), "ES256": ecPEM} {
+		t.Run(algorithm, func(t *testing.T) {
+			e, err := NewJWSEncoder(NewBase64Encoder(), algorithm, key, time.Minute)
+			require.NoError(t, err)
+
+			token, err := e.Encode(want)
+			require.NoError(t, err)
+
+			got, err := e.Decode(token)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+
+			_, err = e.Decode(token[:len(token)-2] + "AA")
+			require.Error(t, err)
+
+			e.now = func() time.Time { return time.Now().Add(time.Minute) }
+			_, err = e.Decode(token)
+			require.ErrorContains(t, err, "expired")
+		})
+	}
+
+	t.Run("empty_tokens_are_not_signed", func(t *testing.T) {
+		e, err := NewJWSEncoder(NewBase64Encoder(), "HS256", []byte("secret"), time.Minute)
+		require.NoError(t, err)
+
+		token, err := e.Encode(nil)
+		require.NoError(t, err)
+		require.Empty(t, token)
+
+		got, err := e.Decode("")
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+
+	t.Run("tokens_signed_with_another_key_are_rejected", func(t *testing.T) {
+		e, err := NewJWSEncoder(NewBase64Encoder(), "HS256", []byte("secret"), time.Minute)
+		require.NoError(t, err)
+
+		token, err := e.Encode(want)
+		require.NoError(t, err)
+
+		other, err := NewJWSEncoder(NewBase64Encoder(), "HS256", []byte("other"), time.Minute)
+		require.NoError(t, err)
+
+		_, err = other.Decode(token)
+		require.Error(t, err)
+	})
+}
+
+func TestJWSStoreEncoder(t *testing.T) {
+	want := []byte("some random string")
+
+	signer, err := NewJWSEncoder(NewBase64Encoder(), "HS256", []byte("secret"), time.Minute)
+	require.NoError(t, err)
+
+	storeEncrypter, err := encrypter.NewStoreEncrypter("master", nil)
+	require.NoError(t, err)
+
+	for name, stores := range map[string]StoreEncoder{"signed": nil, "signed_and_encrypted": NewStoreTokenEncoder(storeEncrypter, NewBase64Encoder())} {
+		t.Run(name, func(t *testing.T) {
+			e := NewJWSStoreEncoder(signer, stores)
+
+			store1, err := e.ForStore("store1")
+			require.NoError(t, err)
+
+			token, err := store1.Encode(want)
+			require.NoError(t, err)
+
+			got, err := store1.Decode(token)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+
+			store2, err := e.ForStore("store2")
+			require.NoError(t, err)
+
+			_, err = store2.Decode(token)
+			require.Error(t, err)
+		})
+	}
+}
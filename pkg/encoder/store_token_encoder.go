@@ -0,0 +1,38 @@
+package encoder
+
+import (
+	"github.com/openfga/openfga/pkg/encrypter"
+)
+
+// StoreEncoder resolves the Encoder that must be used for the continuation tokens
+// belonging to a particular store.
+type StoreEncoder interface {
+	ForStore(storeID string) (Encoder, error)
+}
+
+// StoreTokenEncoder implements the StoreEncoder interface by building a TokenEncoder
+// with a per-store encrypter.
+type StoreTokenEncoder struct {
+	encrypter *encrypter.StoreEncrypter
+	encoder   Encoder
+}
+
+var _ StoreEncoder = (*StoreTokenEncoder)(nil)
+
+// NewStoreTokenEncoder constructs a StoreTokenEncoder with the provided store encrypter and encoder.
+func NewStoreTokenEncoder(encrypter *encrypter.StoreEncrypter, encoder Encoder) *StoreTokenEncoder {
+	return &StoreTokenEncoder{
+		encrypter: encrypter,
+		encoder:   encoder,
+	}
+}
+
+// ForStore returns a TokenEncoder which encrypts data with the key of the provided store.
+func (e *StoreTokenEncoder) ForStore(storeID string) (Encoder, error) {
+	storeEncrypter, err := e.encrypter.ForStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenEncoder(storeEncrypter, e.encoder), nil
+}
@@ -0,0 +1,68 @@
+package encrypter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// DeterministicEncrypter encrypts the same data to the same ciphertext, so that the encrypted data can be looked up
+// by equality. The nonce of the GCM block cipher is derived from the data with HMAC-SHA256 rather than drawn at
+// random, which only reveals whether two ciphertexts are of the same data.
+type DeterministicEncrypter struct {
+	cipherMode cipher.AEAD
+	nonceKey   []byte
+}
+
+var _ Encrypter = (*DeterministicEncrypter)(nil)
+
+func NewDeterministicEncrypter(key string) (*DeterministicEncrypter, error) {
+	if key == "" {
+		return nil, errors.New("a key must be provided")
+	}
+
+	c, err := aes.NewCipher(create32ByteKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeterministicEncrypter{
+		cipherMode: gcm,
+		nonceKey:   []byte(deriveStoreKey(key, "deterministic-nonce")),
+	}, nil
+}
+
+// Decrypt decrypts a byte array encrypted by Encrypt.
+func (e *DeterministicEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	nonceSize := e.cipherMode.NonceSize()
+	if len(data) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	return e.cipherMode.Open(nil, nonce, ciphertext, nil)
+}
+
+// Encrypt encrypts the given byte array using cipher.NewGCM block cipher, with a nonce derived from the byte array.
+func (e *DeterministicEncrypter) Encrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	mac := hmac.New(sha256.New, e.nonceKey)
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:e.cipherMode.NonceSize()]
+
+	return e.cipherMode.Seal(nonce, nonce, data, nil), nil
+}
@@ -56,3 +56,35 @@ func TestEncryptDecrypt(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestDeterministicEncrypter(t *testing.T) {
+	_, err := NewDeterministicEncrypter("")
+	require.Error(t, err)
+
+	encrypter, err := NewDeterministicEncrypter("key")
+	require.NoError(t, err)
+
+	want := []byte("user:jon")
+
+	encoded, err := encrypter.Encrypt(want)
+	require.NoError(t, err)
+	require.NotEqual(t, want, encoded)
+
+	again, err := encrypter.Encrypt(want)
+	require.NoError(t, err)
+	require.Equal(t, encoded, again)
+
+	other, err := encrypter.Encrypt([]byte("user:anne"))
+	require.NoError(t, err)
+	require.NotEqual(t, encoded, other)
+
+	got, err := encrypter.Decrypt(encoded)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	e2, err := NewDeterministicEncrypter("anotherkey")
+	require.NoError(t, err)
+
+	_, err = e2.Decrypt(encoded)
+	require.Error(t, err)
+}
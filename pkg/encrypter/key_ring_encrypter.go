@@ -0,0 +1,118 @@
+package encrypter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// keyRingVersion is the first byte of the data encrypted by a KeyRingEncrypter, followed by the length of the key ID,
+// the key ID and the data encrypted with that key.
+const keyRingVersion byte = 1
+
+// KeyRingEncrypter encrypts data with its primary key, and embeds the ID of the key in the encrypted data so that
+// it is decrypted with the same key. The keys which are no longer primary are kept in the ring until the data
+// encrypted with them (e.g. continuation tokens) has expired, which lets the key be rotated without invalidating all
+// the data at once.
+//
+// The data encrypted by a GCMEncrypter, without a key ID, is decrypted with whichever key of the ring it was
+// encrypted with, so that a single key can be rotated to a key ring.
+type KeyRingEncrypter struct {
+	primaryID  string
+	encrypters map[string]*GCMEncrypter
+
+	// ids are the sorted key IDs, so that the keys are tried in a stable order.
+	ids []string
+}
+
+var _ Encrypter = (*KeyRingEncrypter)(nil)
+
+// NewKeyRingEncrypter constructs a KeyRingEncrypter from the keys keyed by ID, which encrypts with the key
+// `primaryID`. The IDs must be between 1 and 255 bytes long.
+func NewKeyRingEncrypter(primaryID string, keys map[string]string) (*KeyRingEncrypter, error) {
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("the primary key '%s' is not in the key ring", primaryID)
+	}
+
+	e := &KeyRingEncrypter{
+		primaryID:  primaryID,
+		encrypters: make(map[string]*GCMEncrypter, len(keys)),
+		ids:        make([]string, 0, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("the key ID '%s' must be between 1 and 255 bytes long", id)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("the key '%s' must not be empty", id)
+		}
+
+		gcm, err := NewGCMEncrypter(key)
+		if err != nil {
+			return nil, err
+		}
+
+		e.encrypters[id] = gcm
+		e.ids = append(e.ids, id)
+	}
+	sort.Strings(e.ids)
+
+	return e, nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt with the key whose ID it embeds, or data encrypted by a GCMEncrypter
+// with any key of the ring.
+func (e *KeyRingEncrypter) Decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	if id, ciphertext, ok := e.split(data); ok {
+		if decrypted, err := e.encrypters[id].Decrypt(ciphertext); err == nil {
+			return decrypted, nil
+		}
+	}
+
+	// the data may have been encrypted without a key ID
+	for _, id := range e.ids {
+		if decrypted, err := e.encrypters[id].Decrypt(data); err == nil {
+			return decrypted, nil
+		}
+	}
+
+	return nil, errors.New("the data was not encrypted with a key of the key ring")
+}
+
+// split returns the key ID embedded in the data and the data encrypted with it, if the data embeds the ID of a key
+// of the ring.
+func (e *KeyRingEncrypter) split(data []byte) (string, []byte, bool) {
+	if len(data) < 2 || data[0] != keyRingVersion || len(data) < 2+int(data[1]) {
+		return "", nil, false
+	}
+
+	id := string(data[2 : 2+int(data[1])])
+	if _, ok := e.encrypters[id]; !ok {
+		return "", nil, false
+	}
+
+	return id, data[2+int(data[1]):], true
+}
+
+// Encrypt encrypts the data with the primary key, and prefixes it with the ID of the key.
+func (e *KeyRingEncrypter) Encrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	ciphertext, err := e.encrypters[e.primaryID].Encrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]byte, 0, 2+len(e.primaryID)+len(ciphertext))
+	encrypted = append(encrypted, keyRingVersion, byte(len(e.primaryID)))
+	encrypted = append(encrypted, e.primaryID...)
+
+	return append(encrypted, ciphertext...), nil
+}
@@ -0,0 +1,68 @@
+package encrypter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyRingEncrypter(t *testing.T) {
+	_, err := NewKeyRingEncrypter("v2", map[string]string{"v1": "key1"})
+	require.ErrorContains(t, err, "not in the key ring")
+
+	_, err = NewKeyRingEncrypter("v1", map[string]string{"v1": ""})
+	require.ErrorContains(t, err, "must not be empty")
+
+	_, err = NewKeyRingEncrypter("", map[string]string{"": "key"})
+	require.ErrorContains(t, err, "between 1 and 255 bytes")
+}
+
+func TestKeyRingEncrypter(t *testing.T) {
+	want := []byte("some random string")
+
+	t.Run("rotated_keys_decrypt_the_data_of_the_previous_keys", func(t *testing.T) {
+		before, err := NewKeyRingEncrypter("v1", map[string]string{"v1": "key1"})
+		require.NoError(t, err)
+
+		encrypted, err := before.Encrypt(want)
+		require.NoError(t, err)
+
+		after, err := NewKeyRingEncrypter("v2", map[string]string{"v1": "key1", "v2": "key2"})
+		require.NoError(t, err)
+
+		got, err := after.Decrypt(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		rotated, err := after.Encrypt(want)
+		require.NoError(t, err)
+
+		_, err = before.Decrypt(rotated)
+		require.Error(t, err)
+
+		retired, err := NewKeyRingEncrypter("v2", map[string]string{"v2": "key2"})
+		require.NoError(t, err)
+
+		_, err = retired.Decrypt(encrypted)
+		require.Error(t, err)
+
+		got, err = retired.Decrypt(rotated)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+
+	t.Run("data_encrypted_without_a_key_id_is_decrypted", func(t *testing.T) {
+		gcm, err := NewGCMEncrypter("key1")
+		require.NoError(t, err)
+
+		encrypted, err := gcm.Encrypt(want)
+		require.NoError(t, err)
+
+		ring, err := NewKeyRingEncrypter("v2", map[string]string{"v1": "key1", "v2": "key2"})
+		require.NoError(t, err)
+
+		got, err := ring.Decrypt(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+}
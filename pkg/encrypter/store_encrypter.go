@@ -0,0 +1,132 @@
+package encrypter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// StoreEncrypter resolves a distinct Encrypter for each store. Stores with an explicitly
+// configured key use that key, and all other stores use a key derived from the master key
+// and the store ID. This allows the key of a single store to be rotated (by configuring an
+// explicit key for it) without invalidating the continuation tokens issued for other stores.
+//
+// A StoreEncrypter constructed with NewKeyRingStoreEncrypter derives a key ring per store
+// from a ring of master keys instead, which allows the master key to be rotated without
+// invalidating all the outstanding continuation tokens at once.
+type StoreEncrypter struct {
+	masterKey string
+	storeKeys map[string]string
+
+	primaryKeyID string
+	masterKeys   map[string]string
+
+	encrypters sync.Map // map: store id => Encrypter
+}
+
+// NewStoreEncrypter constructs a StoreEncrypter from the provided master key and the explicit
+// per-store key overrides (keyed by store ID).
+func NewStoreEncrypter(masterKey string, storeKeys map[string]string) (*StoreEncrypter, error) {
+	if masterKey == "" {
+		return nil, errors.New("a master key must be provided")
+	}
+
+	keys, err := copyStoreKeys(storeKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreEncrypter{
+		masterKey: masterKey,
+		storeKeys: keys,
+	}, nil
+}
+
+// NewKeyRingStoreEncrypter constructs a StoreEncrypter from the provided master keys (keyed by
+// key ID) and the explicit per-store key overrides (keyed by store ID). The stores without an
+// explicit key use a KeyRingEncrypter of the keys derived from each master key, which encrypts
+// with the key derived from the master key `primaryKeyID`.
+func NewKeyRingStoreEncrypter(primaryKeyID string, masterKeys, storeKeys map[string]string) (*StoreEncrypter, error) {
+	if _, ok := masterKeys[primaryKeyID]; !ok {
+		return nil, errors.New("the primary master key '" + primaryKeyID + "' must be provided")
+	}
+
+	master := make(map[string]string, len(masterKeys))
+	for id, key := range masterKeys {
+		if key == "" {
+			return nil, errors.New("the master key '" + id + "' must not be empty")
+		}
+
+		master[id] = key
+	}
+
+	keys, err := copyStoreKeys(storeKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// validate the key IDs up front rather than on the first request of each store
+	if _, err := NewKeyRingEncrypter(primaryKeyID, master); err != nil {
+		return nil, err
+	}
+
+	return &StoreEncrypter{
+		storeKeys:    keys,
+		primaryKeyID: primaryKeyID,
+		masterKeys:   master,
+	}, nil
+}
+
+func copyStoreKeys(storeKeys map[string]string) (map[string]string, error) {
+	keys := make(map[string]string, len(storeKeys))
+	for storeID, key := range storeKeys {
+		if key == "" {
+			return nil, errors.New("the key for store '" + storeID + "' must not be empty")
+		}
+
+		keys[storeID] = key
+	}
+
+	return keys, nil
+}
+
+// ForStore returns the Encrypter that must be used for the data belonging to the provided store.
+func (s *StoreEncrypter) ForStore(storeID string) (Encrypter, error) {
+	if e, ok := s.encrypters.Load(storeID); ok {
+		return e.(Encrypter), nil
+	}
+
+	e, err := s.newEncrypter(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := s.encrypters.LoadOrStore(storeID, e)
+	return actual.(Encrypter), nil
+}
+
+func (s *StoreEncrypter) newEncrypter(storeID string) (Encrypter, error) {
+	if key, ok := s.storeKeys[storeID]; ok {
+		return NewGCMEncrypter(key)
+	}
+
+	if len(s.masterKeys) == 0 {
+		return NewGCMEncrypter(deriveStoreKey(s.masterKey, storeID))
+	}
+
+	keys := make(map[string]string, len(s.masterKeys))
+	for id, masterKey := range s.masterKeys {
+		keys[id] = deriveStoreKey(masterKey, storeID)
+	}
+
+	return NewKeyRingEncrypter(s.primaryKeyID, keys)
+}
+
+// deriveStoreKey derives a store specific key from the master key using HMAC-SHA256.
+func deriveStoreKey(masterKey, storeID string) string {
+	mac := hmac.New(sha256.New, []byte(masterKey))
+	mac.Write([]byte(storeID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
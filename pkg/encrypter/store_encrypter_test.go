@@ -0,0 +1,115 @@
+package encrypter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStoreEncrypterRequiresMasterKey(t *testing.T) {
+	_, err := NewStoreEncrypter("", nil)
+	require.Error(t, err)
+
+	_, err = NewStoreEncrypter("master", map[string]string{"store1": ""})
+	require.Error(t, err)
+}
+
+func TestStoreEncrypter(t *testing.T) {
+	want := []byte("some random string")
+
+	t.Run("same_store_roundtrips", func(t *testing.T) {
+		s, err := NewStoreEncrypter("master", nil)
+		require.NoError(t, err)
+
+		e, err := s.ForStore("store1")
+		require.NoError(t, err)
+
+		encrypted, err := e.Encrypt(want)
+		require.NoError(t, err)
+
+		e, err = s.ForStore("store1")
+		require.NoError(t, err)
+
+		got, err := e.Decrypt(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+
+	t.Run("tokens_cannot_be_decrypted_with_another_store_key", func(t *testing.T) {
+		s, err := NewStoreEncrypter("master", nil)
+		require.NoError(t, err)
+
+		e1, err := s.ForStore("store1")
+		require.NoError(t, err)
+
+		e2, err := s.ForStore("store2")
+		require.NoError(t, err)
+
+		encrypted, err := e1.Encrypt(want)
+		require.NoError(t, err)
+
+		_, err = e2.Decrypt(encrypted)
+		require.Error(t, err)
+	})
+
+	t.Run("rotating_one_store_key_does_not_affect_other_stores", func(t *testing.T) {
+		before, err := NewStoreEncrypter("master", nil)
+		require.NoError(t, err)
+
+		after, err := NewStoreEncrypter("master", map[string]string{"store1": "rotated"})
+		require.NoError(t, err)
+
+		e, err := before.ForStore("store2")
+		require.NoError(t, err)
+		encrypted, err := e.Encrypt(want)
+		require.NoError(t, err)
+
+		e, err = after.ForStore("store2")
+		require.NoError(t, err)
+		got, err := e.Decrypt(encrypted)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		e, err = before.ForStore("store1")
+		require.NoError(t, err)
+		encrypted, err = e.Encrypt(want)
+		require.NoError(t, err)
+
+		e, err = after.ForStore("store1")
+		require.NoError(t, err)
+		_, err = e.Decrypt(encrypted)
+		require.Error(t, err)
+	})
+
+	t.Run("rotating_the_master_key_keeps_outstanding_tokens_valid", func(t *testing.T) {
+		single, err := NewStoreEncrypter("master", nil)
+		require.NoError(t, err)
+
+		e, err := single.ForStore("store1")
+		require.NoError(t, err)
+		legacy, err := e.Encrypt(want)
+		require.NoError(t, err)
+
+		before, err := NewKeyRingStoreEncrypter("v1", map[string]string{"v1": "master"}, nil)
+		require.NoError(t, err)
+
+		e, err = before.ForStore("store1")
+		require.NoError(t, err)
+		encrypted, err := e.Encrypt(want)
+		require.NoError(t, err)
+
+		after, err := NewKeyRingStoreEncrypter("v2", map[string]string{"v1": "master", "v2": "rotated"}, nil)
+		require.NoError(t, err)
+
+		e, err = after.ForStore("store1")
+		require.NoError(t, err)
+		for _, token := range [][]byte{legacy, encrypted} {
+			got, err := e.Decrypt(token)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		}
+
+		_, err = NewKeyRingStoreEncrypter("v3", map[string]string{"v1": "master"}, nil)
+		require.Error(t, err)
+	})
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/openfga/openfga/internal/build"
@@ -31,6 +32,9 @@ type Logger interface {
 // It provides additional methods such as ones that logs based on context.
 type ZapLogger struct {
 	*zap.Logger
+
+	// level is the level of the loggers built by NewLogger, which can be changed at runtime with SetLevel.
+	level *zap.AtomicLevel
 }
 
 func (l *ZapLogger) With(fields ...zap.Field) {
@@ -88,7 +92,7 @@ func (l *ZapLogger) FatalWithContext(ctx context.Context, msg string, fields ...
 // NewNoopLogger provides noop logger that satisfies the logger interface.
 func NewNoopLogger() *ZapLogger {
 	return &ZapLogger{
-		zap.NewNop(),
+		Logger: zap.NewNop(),
 	}
 }
 
@@ -97,26 +101,15 @@ func NewLogger(logFormat, logLevel string) (*ZapLogger, error) {
 		return NewNoopLogger(), nil
 	}
 
-	var level zapcore.Level
-	switch logLevel {
-	case "debug":
-		level = zap.DebugLevel
-	case "info":
-		level = zap.InfoLevel
-	case "warn":
-		level = zap.WarnLevel
-	case "error":
-		level = zap.ErrorLevel
-	case "panic":
-		level = zap.PanicLevel
-	case "fatal":
-		level = zap.FatalLevel
-	default:
-		return nil, fmt.Errorf("unknown log level: %s", logLevel)
+	level, err := parseLevel(logLevel)
+	if err != nil {
+		return nil, err
 	}
 
+	atomicLevel := zap.NewAtomicLevelAt(level)
+
 	cfg := zap.NewProductionConfig()
-	cfg.Level = zap.NewAtomicLevelAt(level)
+	cfg.Level = atomicLevel
 	cfg.EncoderConfig.TimeKey = "timestamp"
 	cfg.EncoderConfig.CallerKey = "" // remove the "caller" field
 	cfg.DisableStacktrace = true
@@ -137,7 +130,43 @@ func NewLogger(logFormat, logLevel string) (*ZapLogger, error) {
 		log = log.With(zap.String("build.version", build.Version), zap.String("build.commit", build.Commit))
 	}
 
-	return &ZapLogger{log}, nil
+	return &ZapLogger{Logger: log, level: &atomicLevel}, nil
+}
+
+// SetLevel changes the level of the logger at runtime, e.g. when the config of the server is reloaded. It fails
+// for the loggers that were not built by NewLogger with a level other than "none", whose level cannot change.
+func (l *ZapLogger) SetLevel(logLevel string) error {
+	if l.level == nil {
+		return errors.New("the level of the logger cannot be changed")
+	}
+
+	level, err := parseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+
+	l.level.SetLevel(level)
+
+	return nil
+}
+
+func parseLevel(logLevel string) (zapcore.Level, error) {
+	switch logLevel {
+	case "debug":
+		return zap.DebugLevel, nil
+	case "info":
+		return zap.InfoLevel, nil
+	case "warn":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	case "panic":
+		return zap.PanicLevel, nil
+	case "fatal":
+		return zap.FatalLevel, nil
+	default:
+		return zapcore.InvalidLevel, fmt.Errorf("unknown log level: %s", logLevel)
+	}
 }
 
 func MustNewLogger(logFormat, logLevel string) *ZapLogger {
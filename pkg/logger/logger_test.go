@@ -33,7 +33,7 @@ func TestWithoutContext(t *testing.T) {
 		},
 	} {
 		observerLogger, logs := observer.New(zap.DebugLevel)
-		dut := ZapLogger{zap.New(observerLogger)}
+		dut := ZapLogger{Logger: zap.New(observerLogger)}
 		const testMessage = "ABC"
 		switch tc.name {
 		case "Info":
@@ -82,7 +82,7 @@ func TestWithContext(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			observerLogger, logs := observer.New(zap.DebugLevel)
-			dut := ZapLogger{zap.New(observerLogger)}
+			dut := ZapLogger{Logger: zap.New(observerLogger)}
 			const testMessage = "ABC"
 			switch tc.name {
 			case "InfoWithContext":
@@ -110,7 +110,7 @@ func TestWithContext(t *testing.T) {
 
 func TestWithFields(t *testing.T) {
 	observerLogger, logs := observer.New(zap.DebugLevel)
-	logger := ZapLogger{zap.New(observerLogger)}
+	logger := ZapLogger{Logger: zap.New(observerLogger)}
 	logger.With(
 		zap.String("TestOption", "Message"),
 	)
@@ -124,3 +124,18 @@ func TestWithFields(t *testing.T) {
 	}
 	require.Equal(t, expectedZapFields, actualMessage.ContextMap())
 }
+
+func TestSetLevel(t *testing.T) {
+	logger, err := NewLogger("json", "info")
+	require.NoError(t, err)
+
+	require.False(t, logger.Core().Enabled(zap.DebugLevel))
+
+	require.NoError(t, logger.SetLevel("debug"))
+	require.True(t, logger.Core().Enabled(zap.DebugLevel))
+
+	require.Error(t, logger.SetLevel("verbose"))
+	require.True(t, logger.Core().Enabled(zap.DebugLevel))
+
+	require.Error(t, NewNoopLogger().SetLevel("debug"))
+}
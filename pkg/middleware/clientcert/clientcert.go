@@ -0,0 +1,125 @@
+// Package clientcert contains middleware that surfaces the subject of the client certificate of a request as the
+// principal of the request, both for the requests sent directly to the gRPC server and for the requests proxied by
+// the HTTP gateway.
+package clientcert
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type ctxKey string
+
+const (
+	subjectCtxKey ctxKey = "client-cert-subject"
+
+	principalKey = "principal"
+
+	// subjectHeader and gatewaySecretHeader are the metadata headers through which the HTTP gateway forwards the
+	// subject of the client certificate of the HTTP request. The subject is only trusted along with the secret
+	// shared by the gateway and the gRPC server, so that gRPC clients cannot impersonate other clients.
+	subjectHeader       = "openfga-client-cert-subject"
+	gatewaySecretHeader = "openfga-gateway-secret"
+)
+
+// SubjectFromContext returns the subject of the client certificate of the request, if any.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectCtxKey).(string)
+	return subject, ok
+}
+
+// ContextWithSubject injects the subject of a client certificate into the parent context.
+func ContextWithSubject(parent context.Context, subject string) context.Context {
+	return context.WithValue(parent, subjectCtxKey, subject)
+}
+
+// subject returns the subject of the verified client certificate of the connection of the request or, for the
+// requests proxied by the HTTP gateway, the subject forwarded by the gateway.
+func subject(ctx context.Context, gatewaySecret string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if secrets := md.Get(gatewaySecretHeader); len(secrets) > 0 {
+			if gatewaySecret == "" || len(secrets) != 1 ||
+				subtle.ConstantTimeCompare([]byte(secrets[0]), []byte(gatewaySecret)) != 1 {
+				return ""
+			}
+
+			// the connection is the gateway's, so only the forwarded subject identifies the client. HTTP clients
+			// can add metadata of their own, so a subject that is not the only one is not trusted.
+			if subjects := md.Get(subjectHeader); len(subjects) == 1 {
+				return subjects[0]
+			}
+
+			return ""
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0].Subject.String()
+}
+
+func contextWithPrincipal(ctx context.Context, gatewaySecret string) context.Context {
+	s := subject(ctx, gatewaySecret)
+	if s == "" {
+		return ctx
+	}
+
+	grpc_ctxtags.Extract(ctx).Set(principalKey, s)
+
+	return ContextWithSubject(ctx, s)
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which injects the subject of the client certificate of
+// the request into the context, and adds it to the tags of the request as its principal. The subject forwarded by
+// the HTTP gateway is only trusted along with the gatewaySecret.
+func NewUnaryInterceptor(gatewaySecret string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(contextWithPrincipal(ctx, gatewaySecret), req)
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which injects the subject of the client
+// certificate of the request into the context, and adds it to the tags of the request as its principal.
+func NewStreamingInterceptor(gatewaySecret string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: contextWithPrincipal(stream.Context(), gatewaySecret)})
+	}
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// GatewayMetadata returns a function for runtime.WithMetadata which forwards the gatewaySecret and the subject of
+// the verified client certificate of the HTTP requests, if any, to the gRPC server.
+func GatewayMetadata(gatewaySecret string) func(context.Context, *http.Request) metadata.MD {
+	return func(_ context.Context, r *http.Request) metadata.MD {
+		// the subject is always set, so that a subject added by the HTTP client is never the only one
+		var subject string
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			subject = r.TLS.VerifiedChains[0][0].Subject.String()
+		}
+
+		return metadata.Pairs(gatewaySecretHeader, gatewaySecret, subjectHeader, subject)
+	}
+}
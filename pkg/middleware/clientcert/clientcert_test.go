@@ -0,0 +1,73 @@
+package clientcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const secret = "gateway-secret"
+
+func contextWithPeerCertificate(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor(secret)
+
+	principal := func(ctx context.Context) string {
+		var subject string
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			subject, _ = SubjectFromContext(ctx)
+			return nil, nil
+		})
+		require.NoError(t, err)
+
+		return subject
+	}
+
+	t.Run("subject_of_the_verified_client_certificate", func(t *testing.T) {
+		require.Equal(t, "CN=client-a", principal(contextWithPeerCertificate("client-a")))
+		require.Equal(t, "", principal(context.Background()))
+	})
+
+	t.Run("subject_forwarded_by_the_gateway", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(contextWithPeerCertificate("gateway"),
+			metadata.Pairs(gatewaySecretHeader, secret, subjectHeader, "CN=client-b"))
+		require.Equal(t, "CN=client-b", principal(ctx))
+
+		// the HTTP requests without a client certificate do not inherit the identity of the gateway
+		ctx = metadata.NewIncomingContext(contextWithPeerCertificate("gateway"),
+			metadata.Pairs(gatewaySecretHeader, secret, subjectHeader, ""))
+		require.Equal(t, "", principal(ctx))
+	})
+
+	t.Run("forwarded_subject_is_not_trusted_without_the_secret", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(contextWithPeerCertificate("client-a"),
+			metadata.Pairs(subjectHeader, "CN=client-b"))
+		require.Equal(t, "CN=client-a", principal(ctx))
+
+		ctx = metadata.NewIncomingContext(contextWithPeerCertificate("client-a"),
+			metadata.Pairs(gatewaySecretHeader, "guess", subjectHeader, "CN=client-b"))
+		require.Equal(t, "", principal(ctx))
+	})
+
+	t.Run("subject_added_by_the_HTTP_client_is_not_trusted", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(),
+			metadata.Join(metadata.Pairs(subjectHeader, "CN=admin"), GatewayMetadata(secret)(context.Background(), &http.Request{})))
+		require.Equal(t, "", principal(ctx))
+	})
+}
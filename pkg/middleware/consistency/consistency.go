@@ -0,0 +1,78 @@
+// Package consistency contains middleware to read the consistency requirements of the requests.
+package consistency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenHeader is the gRPC metadata key of the consistency token returned by the writes and sent with the reads that
+// must observe them, see storage.ContextWithConsistencyToken. Over HTTP it is sent as the
+// Grpc-Metadata-Openfga-Consistency-Token header.
+const TokenHeader = "openfga-consistency-token"
+
+// Header is the gRPC metadata key of the consistency of a request, see server.Consistency. With Strong, the reads of
+// the request are made with storage.ContextWithStrongConsistency.
+const Header = "openfga-consistency"
+
+// Strong is the value of the Header metadata of the requests whose reads must not be stale.
+const Strong = "strong"
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor that injects the consistency token sent in the
+// TokenHeader metadata and the strong consistency of the Header metadata into the context, rejecting requests whose
+// token is invalid.
+func NewUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextFromMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor is the streaming counterpart of NewUnaryInterceptor.
+func NewStreamingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextFromMetadata(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func contextFromMetadata(ctx context.Context) (context.Context, error) {
+	if values := metadata.ValueFromIncomingContext(ctx, Header); len(values) > 0 && values[0] == Strong {
+		ctx = storage.ContextWithStrongConsistency(ctx)
+	}
+
+	values := metadata.ValueFromIncomingContext(ctx, TokenHeader)
+	if len(values) == 0 || values[0] == "" {
+		return ctx, nil
+	}
+
+	ctx, err := storage.ContextWithConsistencyToken(ctx, values[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("the %s header must be a token returned by a write", TokenHeader))
+	}
+
+	return ctx, nil
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,55 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}
+
+	var got time.Time
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = storage.ConsistencyTimeFromContext(ctx)
+		return nil, nil
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.True(t, got.IsZero())
+
+	before := time.Now()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenHeader, ulid.Make().String()))
+	_, err = interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	require.True(t, got.After(before))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenHeader, "not a token"))
+	_, err = interceptor(ctx, nil, info, handler)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	var staleReadsAllowed bool
+	strongHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		staleReadsAllowed = storage.StaleReadsAllowed(ctx, time.Second)
+		return nil, nil
+	}
+
+	_, err = interceptor(context.Background(), nil, info, strongHandler)
+	require.NoError(t, err)
+	require.True(t, staleReadsAllowed)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, Strong))
+	_, err = interceptor(ctx, nil, info, strongHandler)
+	require.NoError(t, err)
+	require.False(t, staleReadsAllowed)
+}
@@ -0,0 +1,32 @@
+// Package errordetails contains middleware to attach the structured description of the errors to their status.
+package errordetails
+
+import (
+	"context"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"google.golang.org/grpc"
+)
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which attaches the structured description of the
+// errors of the RPCs to their status (see serverErrors.WithDetails). It must come first, so that it describes
+// the errors of the other interceptors too.
+func NewUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, serverErrors.WithDetails(err)
+		}
+
+		return resp, nil
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which attaches the structured description of
+// the errors of the RPCs to their status (see serverErrors.WithDetails). It must come first, so that it
+// describes the errors of the other interceptors too.
+func NewStreamingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return serverErrors.WithDetails(handler(srv, stream))
+	}
+}
@@ -0,0 +1,173 @@
+// Package featureflags contains middleware to toggle experimental behaviors per request, e.g. to canary changes of
+// the resolvers on a slice of the traffic.
+package featureflags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/openfga/openfga/internal/authz"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the gRPC metadata key of the comma-separated feature flags of a request. Over HTTP it is sent as the
+// Grpc-Metadata-Openfga-Feature-Flags header.
+const Header = "openfga-feature-flags"
+
+// Scope is the scope of the credentials allowed to send feature flags, whatever their principal. It is namespaced
+// like the scopes of package authz, which do not grant it.
+const Scope = authz.ScopePrefix + "feature-flags"
+
+// Flag is an experimental behavior toggled per request.
+type Flag string
+
+const (
+	// ListObjectsPlanner plans the ListObjects of the request with the query planner, even if it is not enabled.
+	ListObjectsPlanner Flag = "list-objects-planner"
+
+	// BypassCheckCache neither looks up the Checks of the request in the check cache nor caches them.
+	BypassCheckCache Flag = "bypass-check-cache"
+
+	// BypassListObjectsCache neither looks up the ListObjects of the request in the ListObjects cache nor caches it.
+	BypassListObjectsCache Flag = "bypass-list-objects-cache"
+
+	// HigherResolveNodeLimit resolves the request with the higher resolve node limit of the server.
+	HigherResolveNodeLimit Flag = "higher-resolve-node-limit"
+)
+
+var flags = []Flag{ListObjectsPlanner, BypassCheckCache, BypassListObjectsCache, HigherResolveNodeLimit}
+
+type flagsCtxKey struct{}
+
+// ContextWithFlags returns a context whose request has the feature flags.
+func ContextWithFlags(ctx context.Context, flags ...Flag) context.Context {
+	return context.WithValue(ctx, flagsCtxKey{}, flags)
+}
+
+// FlagsFromContext returns the feature flags of the request of the context.
+func FlagsFromContext(ctx context.Context) []Flag {
+	flags, _ := ctx.Value(flagsCtxKey{}).([]Flag)
+	return flags
+}
+
+// Enabled reports whether the request of the context has the feature flag.
+func Enabled(ctx context.Context, flag Flag) bool {
+	for _, f := range FlagsFromContext(ctx) {
+		if f == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseFlags parses comma-separated feature flags, returning an error if one of them is unknown.
+func ParseFlags(value string) ([]Flag, error) {
+	var parsed []Flag
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		known := false
+		for _, flag := range flags {
+			if Flag(name) == flag {
+				parsed = append(parsed, flag)
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown feature flag '%s'", name)
+		}
+	}
+
+	return parsed, nil
+}
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor that injects the feature flags of the Header metadata
+// into the context. It rejects the requests with unknown flags, and the requests with flags whose authenticated
+// principal is neither one of `principals` nor has the Scope. It must run after the authentication interceptor.
+func NewUnaryInterceptor(principals []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextFromMetadata(ctx, principals)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor is the streaming counterpart of NewUnaryInterceptor.
+func NewStreamingInterceptor(principals []string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextFromMetadata(stream.Context(), principals)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func contextFromMetadata(ctx context.Context, principals []string) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, Header)
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	parsed, err := ParseFlags(strings.Join(values, ","))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid %s header: %v", Header, err))
+	}
+	if len(parsed) == 0 {
+		return ctx, nil
+	}
+
+	if !authorized(ctx, principals) {
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("the caller is not allowed to send the %s header", Header))
+	}
+
+	return ContextWithFlags(ctx, parsed...), nil
+}
+
+// authorized reports whether the authenticated principal of the context may send feature flags.
+func authorized(ctx context.Context, principals []string) bool {
+	claims, ok := authn.AuthClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	if claims.Scopes[Scope] {
+		return true
+	}
+
+	principal := claims.Principal()
+	if principal == "" {
+		return false
+	}
+
+	for _, p := range principals {
+		if p == principal {
+			return true
+		}
+	}
+
+	return false
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
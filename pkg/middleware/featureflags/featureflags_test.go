@@ -0,0 +1,61 @@
+package featureflags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openfga/openfga/internal/authn"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseFlags(t *testing.T) {
+	flags, err := ParseFlags(" list-objects-planner,,bypass-check-cache ")
+	require.NoError(t, err)
+	require.Equal(t, []Flag{ListObjectsPlanner, BypassCheckCache}, flags)
+
+	_, err = ParseFlags("list-objects-planner,faster-checks")
+	require.EqualError(t, err, "unknown feature flag 'faster-checks'")
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	interceptor := NewUnaryInterceptor([]string{"canary-client"})
+	info := &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}
+
+	var got []Flag
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = FlagsFromContext(ctx)
+		return nil, nil
+	}
+
+	withHeader := func(ctx context.Context, value string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(Header, value))
+	}
+
+	_, err := interceptor(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	canary := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{ClientID: "canary-client"})
+	_, err = interceptor(withHeader(canary, "higher-resolve-node-limit"), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, []Flag{HigherResolveNodeLimit}, got)
+
+	_, err = interceptor(withHeader(canary, "faster-checks"), nil, info, handler)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	scoped := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Scopes: map[string]bool{Scope: true}})
+	_, err = interceptor(withHeader(scoped, "bypass-check-cache"), nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, []Flag{BypassCheckCache}, got)
+
+	other := authn.ContextWithAuthClaims(context.Background(), &authn.AuthClaims{Subject: "other"})
+	_, err = interceptor(withHeader(other, "bypass-check-cache"), nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = interceptor(withHeader(context.Background(), "bypass-check-cache"), nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"io"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DSLContentType is the media type of the authorization models written in the DSL, see typesystem.ParseDSL.
+const DSLContentType = "application/vnd.openfga.dsl"
+
+// DSLMarshaler is the runtime.Marshaler of the DSLContentType. It reads the body of the WriteAuthorizationModel
+// requests, and writes the body of the ReadAuthorizationModel responses, in the DSL. The other requests and responses
+// are read and written in JSON, like with the default marshaler.
+//
+// It is registered with runtime.WithMarshalerOption(DSLContentType, NewDSLMarshaler()): the models are then written
+// with the Content-Type header set to DSLContentType, and read with the Accept header set to DSLContentType.
+type DSLMarshaler struct {
+	*runtime.JSONPb
+}
+
+var _ runtime.Marshaler = (*DSLMarshaler)(nil)
+
+func NewDSLMarshaler() *DSLMarshaler {
+	return &DSLMarshaler{
+		JSONPb: &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		},
+	}
+}
+
+func (m *DSLMarshaler) ContentType(v interface{}) string {
+	if _, ok := v.(*openfgav1.ReadAuthorizationModelResponse); ok {
+		return DSLContentType
+	}
+
+	return m.JSONPb.ContentType(v)
+}
+
+func (m *DSLMarshaler) Marshal(v interface{}) ([]byte, error) {
+	resp, ok := v.(*openfgav1.ReadAuthorizationModelResponse)
+	if !ok {
+		return m.JSONPb.Marshal(v)
+	}
+
+	dsl, err := typesystem.FormatDSL(resp.GetAuthorizationModel())
+	if err != nil {
+		return nil, errors.ValidationError(err)
+	}
+
+	return []byte(dsl), nil
+}
+
+func (m *DSLMarshaler) Unmarshal(data []byte, v interface{}) error {
+	req, ok := v.(*openfgav1.WriteAuthorizationModelRequest)
+	if !ok {
+		return m.JSONPb.Unmarshal(data, v)
+	}
+
+	model, err := typesystem.ParseDSL(string(data))
+	if err != nil {
+		return err
+	}
+
+	req.SchemaVersion = model.GetSchemaVersion()
+	req.TypeDefinitions = model.GetTypeDefinitions()
+
+	return nil
+}
+
+func (m *DSLMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		return m.Unmarshal(data, v)
+	})
+}
+
+func (m *DSLMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v interface{}) error {
+		data, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"bytes"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDSLMarshaler(t *testing.T) {
+	m := NewDSLMarshaler()
+
+	dsl := "type user\n\ntype document\n  relations\n    define viewer: [user] as self\n"
+
+	var req openfgav1.WriteAuthorizationModelRequest
+	require.NoError(t, m.NewDecoder(bytes.NewBufferString(dsl)).Decode(&req))
+	require.Equal(t, typesystem.SchemaVersion1_1, req.GetSchemaVersion())
+	require.Len(t, req.GetTypeDefinitions(), 2)
+
+	resp := &openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: &openfgav1.AuthorizationModel{
+		SchemaVersion:   req.GetSchemaVersion(),
+		TypeDefinitions: req.GetTypeDefinitions(),
+	}}
+	require.Equal(t, DSLContentType, m.ContentType(resp))
+
+	data, err := m.Marshal(resp)
+	require.NoError(t, err)
+	require.Equal(t, dsl, string(data))
+
+	t.Run("other_messages_are_json", func(t *testing.T) {
+		resp := &openfgav1.WriteAuthorizationModelResponse{AuthorizationModelId: "01GXSA8YR785C4FYS3C0RTG7B1"}
+		require.Equal(t, "application/json", m.ContentType(resp))
+
+		data, err := m.Marshal(resp)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"authorization_model_id": "01GXSA8YR785C4FYS3C0RTG7B1"}`, string(data))
+
+		var req openfgav1.CreateStoreRequest
+		require.NoError(t, m.Unmarshal([]byte(`{"name": "store"}`), &req))
+		require.Equal(t, "store", req.GetName())
+	})
+
+	t.Run("invalid_dsl", func(t *testing.T) {
+		var req openfgav1.WriteAuthorizationModelRequest
+		require.ErrorIs(t, m.Unmarshal([]byte("type document relations define"), &req), typesystem.ErrInvalidDSL)
+	})
+
+	t.Run("schema_1_0_model", func(t *testing.T) {
+		_, err := m.Marshal(&openfgav1.ReadAuthorizationModelResponse{AuthorizationModel: &openfgav1.AuthorizationModel{
+			SchemaVersion: typesystem.SchemaVersion1_0,
+		}})
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"net/textproto"
 	"strconv"
@@ -13,13 +14,33 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/openfga/openfga/pkg/server/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 )
 
 // XHttpCode is used for overriding the standard HTTP code
 const XHttpCode = "x-http-code"
 
+// traceHeaders are the W3C trace context headers, forwarded to the gRPC server so that the traces of the
+// requests continue those of their clients.
+var traceHeaders = map[string]struct{}{
+	"traceparent": {},
+	"tracestate":  {},
+	"baggage":     {},
+}
+
+// IncomingHeaderMatcher forwards the W3C trace context headers of the HTTP requests to the gRPC server as
+// metadata, along with the headers forwarded by runtime.DefaultHeaderMatcher.
+func IncomingHeaderMatcher(key string) (string, bool) {
+	if _, ok := traceHeaders[strings.ToLower(key)]; ok {
+		return strings.ToLower(key), true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
 // HTTPResponseModifier is a helper function to override the HTTP status code
 func HTTPResponseModifier(ctx context.Context, w http.ResponseWriter, p proto.Message) error {
 	md, ok := runtime.ServerMetadataFromContext(ctx)
@@ -63,6 +84,18 @@ func handleForwardResponseTrailer(w http.ResponseWriter, md runtime.ServerMetada
 	}
 }
 
+// SetRetryAfterHeader sets the Retry-After header of the response to the retry delay of the error status, if
+// it carries a RetryInfo. The delay is rounded up to the next second.
+func SetRetryAfterHeader(w http.ResponseWriter, st *status.Status) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			delay := info.GetRetryDelay().AsDuration()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			return
+		}
+	}
+}
+
 // CustomHTTPErrorHandler provides handling of custom error object
 // It is very similar to runtime.DefaultHTTPErrorHandler except it takes in the EncodedError object
 func CustomHTTPErrorHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, err *errors.EncodedError) {
@@ -7,12 +7,14 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/server/errors"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestCustomHTTPErrorHandler(t *testing.T) {
@@ -72,3 +74,29 @@ func TestCustomHTTPErrorHandlerSpecialEncoding(t *testing.T) {
 	expectedData := "{\"code\":\"assertions_too_many_items\",\"message\":\"invalid character '<' looking for beginning of value,\"}"
 	require.Equal(t, expectedData, strings.TrimSpace(string(data)))
 }
+
+func TestSetRetryAfterHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetRetryAfterHeader(w, status.Convert(errors.RateLimitExceeded("slow down", 1500*time.Millisecond)))
+	require.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	w = httptest.NewRecorder()
+	SetRetryAfterHeader(w, status.Convert(errors.RateLimitExceeded("slow down", 0)))
+	require.Empty(t, w.Header().Get("Retry-After"))
+
+	e := errors.NewEncodedError(int32(openfgav1.InternalErrorCode_resource_exhausted), "slow down")
+	require.Equal(t, http.StatusTooManyRequests, e.HTTPStatusCode)
+}
+
+func TestIncomingHeaderMatcher(t *testing.T) {
+	key, ok := IncomingHeaderMatcher("Traceparent")
+	require.True(t, ok)
+	require.Equal(t, "traceparent", key)
+
+	key, ok = IncomingHeaderMatcher("Grpc-Metadata-Openfga-Priority")
+	require.True(t, ok)
+	require.Equal(t, "Openfga-Priority", key)
+
+	_, ok = IncomingHeaderMatcher("X-Custom-Header")
+	require.False(t, ok)
+}
@@ -0,0 +1,265 @@
+// Package loadshedding contains middleware that sheds the most expensive RPCs when the datastore is struggling.
+package loadshedding
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+// HealthLevel describes how healthy the datastore currently is, based on the
+// latency and errors observed for the most recent datastore calls.
+type HealthLevel int32
+
+const (
+	// Healthy means that no RPCs are shed.
+	Healthy HealthLevel = iota
+
+	// Degraded means that the most expensive RPCs (ListObjects) are shed.
+	Degraded
+
+	// Critical means that all expensive RPCs (ListObjects and Expand) are shed.
+	Critical
+)
+
+func (l HealthLevel) String() string {
+	switch l {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Critical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
+	defaultDegradedLatencyThreshold = 250 * time.Millisecond
+	defaultCriticalLatencyThreshold = 1 * time.Second
+	defaultErrorRateThreshold       = 0.5
+	defaultDecayInterval            = time.Second
+
+	// smoothingFactor is the weight given to every new observation in the exponentially
+	// weighted moving averages of the datastore latency and error rate.
+	smoothingFactor = 0.1
+)
+
+var (
+	datastoreHealthLevelGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "datastore_health_level",
+		Help: "The datastore health level as seen by the load shedder (0 = healthy, 1 = degraded, 2 = critical)",
+	})
+
+	shedRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "load_shedding_shed_requests_count",
+		Help: "Number of requests that were rejected by the load shedder because the datastore is unhealthy",
+	}, []string{"grpc_method"})
+)
+
+// shedLevels maps the full gRPC method names to the minimum health level at which they are shed.
+// ListObjects is the most expensive RPC, so it is the first to be shed, followed by Expand.
+var shedLevels = map[string]HealthLevel{
+	"/openfga.v1.OpenFGAService/ListObjects":         Degraded,
+	"/openfga.v1.OpenFGAService/StreamedListObjects": Degraded,
+	"/openfga.v1.OpenFGAService/Expand":              Critical,
+}
+
+// HealthMonitor tracks an exponentially weighted moving average of the latency and
+// error rate of datastore calls and derives a HealthLevel from them.
+//
+// The averages decay while no datastore calls are observed, e.g. because every RPC
+// calling the datastore is shed, so that the datastore doesn't stay unhealthy forever.
+// HealthMonitor instances may be safely shared by multiple goroutines.
+type HealthMonitor struct {
+	degradedLatencyThreshold time.Duration
+	criticalLatencyThreshold time.Duration
+	errorRateThreshold       float64
+	decayInterval            time.Duration
+
+	mu           sync.Mutex
+	avgLatency   float64 // nanoseconds
+	avgErrorRate float64
+	observedAt   time.Time
+	decayedAt    time.Time
+
+	level atomic.Int32
+}
+
+type HealthMonitorOption func(m *HealthMonitor)
+
+// WithDegradedLatencyThreshold sets the average datastore latency above which the
+// datastore is considered Degraded.
+func WithDegradedLatencyThreshold(d time.Duration) HealthMonitorOption {
+	return func(m *HealthMonitor) {
+		m.degradedLatencyThreshold = d
+	}
+}
+
+// WithCriticalLatencyThreshold sets the average datastore latency above which the
+// datastore is considered Critical.
+func WithCriticalLatencyThreshold(d time.Duration) HealthMonitorOption {
+	return func(m *HealthMonitor) {
+		m.criticalLatencyThreshold = d
+	}
+}
+
+// WithErrorRateThreshold sets the fraction (between 0 and 1) of failing datastore calls above
+// which the datastore is considered Critical. Half of this fraction makes it Degraded.
+func WithErrorRateThreshold(rate float64) HealthMonitorOption {
+	return func(m *HealthMonitor) {
+		m.errorRateThreshold = rate
+	}
+}
+
+// WithDecayInterval sets how fast the averages decay while no datastore calls are observed:
+// every interval without calls weighs like a fast and successful call. Defaults to a second.
+func WithDecayInterval(d time.Duration) HealthMonitorOption {
+	return func(m *HealthMonitor) {
+		m.decayInterval = d
+	}
+}
+
+// NewHealthMonitor constructs a HealthMonitor which starts in the Healthy level.
+func NewHealthMonitor(opts ...HealthMonitorOption) *HealthMonitor {
+	m := &HealthMonitor{
+		degradedLatencyThreshold: defaultDegradedLatencyThreshold,
+		criticalLatencyThreshold: defaultCriticalLatencyThreshold,
+		errorRateThreshold:       defaultErrorRateThreshold,
+		decayInterval:            defaultDecayInterval,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// ObserveDatastoreCall records the latency and the outcome of a single datastore call.
+func (m *HealthMonitor) ObserveDatastoreCall(latency time.Duration, err error) {
+	errValue := 0.0
+	if err != nil {
+		errValue = 1.0
+	}
+
+	now := time.Now()
+
+	m.mu.Lock()
+	m.decay(now)
+	m.observedAt = now
+	m.avgLatency = smoothingFactor*float64(latency) + (1-smoothingFactor)*m.avgLatency
+	m.avgErrorRate = smoothingFactor*errValue + (1-smoothingFactor)*m.avgErrorRate
+	level := m.computeLevel()
+	m.mu.Unlock()
+
+	m.setLevel(level)
+}
+
+// decay decays the averages for the time elapsed since the last datastore call, minus one decay
+// interval, which has not been accounted for yet. It must be called with m.mu held.
+func (m *HealthMonitor) decay(now time.Time) {
+	if m.decayInterval <= 0 {
+		return
+	}
+
+	from := m.observedAt.Add(m.decayInterval)
+	if m.decayedAt.After(from) {
+		from = m.decayedAt
+	}
+
+	if !now.After(from) {
+		return
+	}
+
+	weight := math.Pow(1-smoothingFactor, float64(now.Sub(from))/float64(m.decayInterval))
+	m.avgLatency *= weight
+	m.avgErrorRate *= weight
+	m.decayedAt = now
+}
+
+func (m *HealthMonitor) setLevel(level HealthLevel) {
+	if HealthLevel(m.level.Swap(int32(level))) != level {
+		datastoreHealthLevelGauge.Set(float64(level))
+	}
+}
+
+// computeLevel derives the HealthLevel from the current averages. It must be called with m.mu held.
+func (m *HealthMonitor) computeLevel() HealthLevel {
+	avgLatency := time.Duration(math.Round(m.avgLatency))
+
+	if (m.criticalLatencyThreshold > 0 && avgLatency > m.criticalLatencyThreshold) ||
+		(m.errorRateThreshold > 0 && m.avgErrorRate > m.errorRateThreshold) {
+		return Critical
+	}
+
+	if (m.degradedLatencyThreshold > 0 && avgLatency > m.degradedLatencyThreshold) ||
+		(m.errorRateThreshold > 0 && m.avgErrorRate > m.errorRateThreshold/2) {
+		return Degraded
+	}
+
+	return Healthy
+}
+
+// Level returns the current health level of the datastore.
+func (m *HealthMonitor) Level() HealthLevel {
+	level := HealthLevel(m.level.Load())
+	if level == Healthy {
+		// the averages only grow with the datastore calls
+		return level
+	}
+
+	m.mu.Lock()
+	m.decay(time.Now())
+	level = m.computeLevel()
+	m.mu.Unlock()
+
+	m.setLevel(level)
+
+	return level
+}
+
+// shouldShed reports whether the provided gRPC method must be rejected given the current health level.
+func (m *HealthMonitor) shouldShed(fullMethod string) bool {
+	minLevel, ok := shedLevels[fullMethod]
+	if !ok {
+		return false
+	}
+
+	return m.Level() >= minLevel
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which rejects the most expensive
+// RPCs with serverErrors.ServerOverloaded while the datastore is unhealthy.
+func NewUnaryInterceptor(m *HealthMonitor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if m.shouldShed(info.FullMethod) {
+			shedRequestsCounter.WithLabelValues(info.FullMethod).Inc()
+			return nil, serverErrors.ServerOverloaded
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which rejects the most expensive
+// RPCs with serverErrors.ServerOverloaded while the datastore is unhealthy.
+func NewStreamingInterceptor(m *HealthMonitor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if m.shouldShed(info.FullMethod) {
+			shedRequestsCounter.WithLabelValues(info.FullMethod).Inc()
+			return serverErrors.ServerOverloaded
+		}
+
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,101 @@
+package loadshedding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	t.Run("starts_healthy", func(t *testing.T) {
+		m := NewHealthMonitor()
+		require.Equal(t, Healthy, m.Level())
+	})
+
+	t.Run("slow_datastore_is_degraded_then_critical", func(t *testing.T) {
+		m := NewHealthMonitor(
+			WithDegradedLatencyThreshold(100*time.Millisecond),
+			WithCriticalLatencyThreshold(time.Second),
+		)
+
+		for i := 0; i < 50; i++ {
+			m.ObserveDatastoreCall(500*time.Millisecond, nil)
+		}
+		require.Equal(t, Degraded, m.Level())
+
+		for i := 0; i < 50; i++ {
+			m.ObserveDatastoreCall(5*time.Second, nil)
+		}
+		require.Equal(t, Critical, m.Level())
+
+		for i := 0; i < 100; i++ {
+			m.ObserveDatastoreCall(time.Millisecond, nil)
+		}
+		require.Equal(t, Healthy, m.Level())
+	})
+
+	t.Run("idle_datastore_recovers", func(t *testing.T) {
+		m := NewHealthMonitor(
+			WithDegradedLatencyThreshold(100*time.Millisecond),
+			WithCriticalLatencyThreshold(time.Second),
+			WithDecayInterval(time.Millisecond),
+		)
+
+		for i := 0; i < 50; i++ {
+			m.ObserveDatastoreCall(5*time.Second, nil)
+		}
+
+		// no datastore calls are observed while the expensive RPCs are shed
+		require.Eventually(t, func() bool {
+			return m.Level() == Healthy
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("failing_datastore_is_critical", func(t *testing.T) {
+		m := NewHealthMonitor(WithErrorRateThreshold(0.5))
+
+		for i := 0; i < 50; i++ {
+			m.ObserveDatastoreCall(time.Millisecond, errors.New("connection refused"))
+		}
+		require.Equal(t, Critical, m.Level())
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	m := NewHealthMonitor(
+		WithDegradedLatencyThreshold(100*time.Millisecond),
+		WithCriticalLatencyThreshold(time.Second),
+	)
+	for i := 0; i < 50; i++ {
+		m.ObserveDatastoreCall(500*time.Millisecond, nil)
+	}
+
+	interceptor := NewUnaryInterceptor(m)
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/ListObjects"}, handler)
+	require.ErrorIs(t, err, serverErrors.ServerOverloaded)
+
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Expand"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	for i := 0; i < 50; i++ {
+		m.ObserveDatastoreCall(5*time.Second, nil)
+	}
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Expand"}, handler)
+	require.ErrorIs(t, err, serverErrors.ServerOverloaded)
+
+	resp, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+}
@@ -0,0 +1,323 @@
+// Package priority contains middleware that schedules the requests of the server by priority, so that the
+// long-running batch requests (e.g. ListObjects) cannot starve the interactive ones (e.g. Check).
+package priority
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header is the gRPC metadata key of the class of a request, either "interactive" or "batch", which overrides
+	// the class of its API method. Over HTTP it is sent as the Grpc-Metadata-Openfga-Priority header.
+	Header = "openfga-priority"
+
+	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
+	defaultMaxConcurrentRequests      = 100
+	defaultMaxConcurrentBatchRequests = 50
+	defaultQueueTimeout               = 5 * time.Second
+
+	// queueRetryAfter is the delay suggested to the requests rejected because they waited too long for a slot.
+	queueRetryAfter = time.Second
+
+	// scheduledServicePrefix is the prefix of the full names of the scheduled methods. The methods of the other
+	// services, such as the gRPC health checks, are never queued.
+	scheduledServicePrefix = "/openfga.v1.OpenFGAService/"
+)
+
+// Class is the scheduling class of a request.
+type Class int
+
+const (
+	// Interactive requests are latency sensitive. They are granted slots before the batch requests.
+	Interactive Class = iota
+
+	// Batch requests are long-running. They are granted slots once no interactive request is waiting, and they
+	// can only hold some of the slots.
+	Batch
+)
+
+func (c Class) String() string {
+	if c == Batch {
+		return "batch"
+	}
+
+	return "interactive"
+}
+
+// ParseClass returns the class with the name, either "interactive" or "batch".
+func ParseClass(name string) (Class, bool) {
+	switch name {
+	case "interactive":
+		return Interactive, true
+	case "batch":
+		return Batch, true
+	default:
+		return Interactive, false
+	}
+}
+
+// DefaultBatchMethods are the API methods whose requests are batch requests by default.
+var DefaultBatchMethods = []string{"ListObjects", "StreamedListObjects", "Expand"}
+
+var (
+	runningRequestsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scheduler_running_requests",
+		Help: "Number of requests holding a slot of the scheduler, by scheduling class",
+	}, []string{"class"})
+
+	queuedRequestsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "scheduler_queued_requests",
+		Help: "Number of requests waiting for a slot of the scheduler, by scheduling class",
+	}, []string{"class"})
+
+	queueWaitHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_queue_wait_ms",
+		Help:    "Time spent by the requests waiting for a slot of the scheduler, by scheduling class",
+		Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
+	}, []string{"class"})
+
+	rejectedRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_rejected_requests_count",
+		Help: "Number of requests that were rejected because they waited too long for a slot of the scheduler, by scheduling class",
+	}, []string{"class"})
+)
+
+// Scheduler bounds the number of requests the server handles concurrently with a number of slots. When every slot
+// is taken, the requests wait for one in a queue per class: the waiting interactive requests are granted a slot
+// before the waiting batch requests, and the batch requests can only hold some of the slots, so that some are
+// always left for the interactive requests. Scheduler instances may be safely shared by multiple goroutines.
+type Scheduler struct {
+	maxConcurrentRequests      int
+	maxConcurrentBatchRequests int
+	queueTimeout               time.Duration
+	batchMethods               map[string]struct{}
+
+	mu      sync.Mutex
+	running [2]int
+	queues  [2]*list.List
+}
+
+type SchedulerOption func(s *Scheduler)
+
+// WithMaxConcurrentRequests sets the number of slots, i.e. the maximum number of requests handled concurrently.
+// Defaults to 100.
+func WithMaxConcurrentRequests(max uint32) SchedulerOption {
+	return func(s *Scheduler) {
+		s.maxConcurrentRequests = int(max)
+	}
+}
+
+// WithMaxConcurrentBatchRequests sets the number of slots the batch requests can hold. Defaults to 50.
+func WithMaxConcurrentBatchRequests(max uint32) SchedulerOption {
+	return func(s *Scheduler) {
+		s.maxConcurrentBatchRequests = int(max)
+	}
+}
+
+// WithQueueTimeout sets how long a request waits for a slot before it is rejected with
+// serverErrors.RateLimitExceeded. A timeout of 0 waits for as long as the request allows. Defaults to 5s.
+func WithQueueTimeout(timeout time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.queueTimeout = timeout
+	}
+}
+
+// WithBatchMethods sets the API methods (e.g. "ListObjects") whose requests are batch requests, unless their
+// Header says otherwise. Defaults to DefaultBatchMethods.
+func WithBatchMethods(methods []string) SchedulerOption {
+	return func(s *Scheduler) {
+		s.batchMethods = make(map[string]struct{}, len(methods))
+		for _, method := range methods {
+			s.batchMethods[method] = struct{}{}
+		}
+	}
+}
+
+// NewScheduler constructs a Scheduler.
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		maxConcurrentRequests:      defaultMaxConcurrentRequests,
+		maxConcurrentBatchRequests: defaultMaxConcurrentBatchRequests,
+		queueTimeout:               defaultQueueTimeout,
+		queues:                     [2]*list.List{list.New(), list.New()},
+	}
+	WithBatchMethods(DefaultBatchMethods)(s)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Classify returns the class of a request to the API method with the provided full name, which is the class
+// named by the Header of the request if it has a valid one.
+func (s *Scheduler) Classify(ctx context.Context, fullMethod string) Class {
+	if values := metadata.ValueFromIncomingContext(ctx, Header); len(values) > 0 {
+		if class, ok := ParseClass(values[0]); ok {
+			return class
+		}
+	}
+
+	if _, ok := s.batchMethods[path.Base(fullMethod)]; ok {
+		return Batch
+	}
+
+	return Interactive
+}
+
+// waiter is a request waiting for a slot. Its ready channel is closed once it is granted one.
+type waiter struct {
+	ready chan struct{}
+}
+
+// Acquire waits for a slot for a request of the class, and returns the function to call to release it once the
+// request has been handled. It fails with serverErrors.RateLimitExceeded if no slot is granted within the queue
+// timeout, or with serverErrors.RequestDeadlineExceeded or serverErrors.RequestCancelled if the context is done
+// first.
+func (s *Scheduler) Acquire(ctx context.Context, class Class) (func(), error) {
+	s.mu.Lock()
+	if s.canRun(class) && s.queues[Interactive].Len() == 0 && (class == Interactive || s.queues[Batch].Len() == 0) {
+		s.start(class)
+		s.mu.Unlock()
+		return s.releaser(class), nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	elem := s.queues[class].PushBack(w)
+	queuedRequestsGauge.WithLabelValues(class.String()).Inc()
+	s.mu.Unlock()
+
+	start := time.Now()
+
+	var timeout <-chan time.Time
+	if s.queueTimeout > 0 {
+		timer := time.NewTimer(s.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		queueWaitHistogram.WithLabelValues(class.String()).Observe(float64(time.Since(start).Milliseconds()))
+		return s.releaser(class), nil
+	case <-timeout:
+		rejectedRequestsCounter.WithLabelValues(class.String()).Inc()
+		err = serverErrors.RateLimitExceeded("The server is handling too many requests. Please retry later", queueRetryAfter)
+	case <-ctx.Done():
+		err = serverErrors.RequestDeadlineExceeded
+		if errors.Is(ctx.Err(), context.Canceled) {
+			err = serverErrors.RequestCancelled
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		// the slot was granted while giving up, so it must be handed over to another request
+		s.release(class)
+	default:
+		s.queues[class].Remove(elem)
+		queuedRequestsGauge.WithLabelValues(class.String()).Dec()
+	}
+
+	return nil, err
+}
+
+// canRun reports whether a request of the class can take a slot. It must be called with s.mu held.
+func (s *Scheduler) canRun(class Class) bool {
+	if s.running[Interactive]+s.running[Batch] >= s.maxConcurrentRequests {
+		return false
+	}
+
+	return class == Interactive || s.running[Batch] < s.maxConcurrentBatchRequests
+}
+
+// start gives a slot to a request of the class. It must be called with s.mu held.
+func (s *Scheduler) start(class Class) {
+	s.running[class]++
+	runningRequestsGauge.WithLabelValues(class.String()).Inc()
+}
+
+// releaser returns the function releasing a slot of the class, which only releases it once.
+func (s *Scheduler) releaser(class Class) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.release(class)
+		})
+	}
+}
+
+// release releases a slot of the class and grants the free slots to the waiting requests, the interactive ones
+// first. It must be called with s.mu held.
+func (s *Scheduler) release(class Class) {
+	s.running[class]--
+	runningRequestsGauge.WithLabelValues(class.String()).Dec()
+
+	for _, next := range []Class{Interactive, Batch} {
+		for s.queues[next].Len() > 0 && s.canRun(next) {
+			w := s.queues[next].Remove(s.queues[next].Front()).(*waiter)
+			queuedRequestsGauge.WithLabelValues(next.String()).Dec()
+
+			s.start(next)
+			close(w.ready)
+		}
+	}
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which handles every request once it has acquired a
+// slot of the Scheduler.
+func NewUnaryInterceptor(s *Scheduler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, scheduledServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		release, err := s.Acquire(ctx, s.Classify(ctx, info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which handles every stream once it has acquired
+// a slot of the Scheduler, which it holds until the stream ends.
+func NewStreamingInterceptor(s *Scheduler) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, scheduledServicePrefix) {
+			return handler(srv, stream)
+		}
+
+		ctx := stream.Context()
+
+		release, err := s.Acquire(ctx, s.Classify(ctx, info.FullMethod))
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, stream)
+	}
+}
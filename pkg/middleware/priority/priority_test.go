@@ -0,0 +1,164 @@
+package priority
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	checkMethod       = "/openfga.v1.OpenFGAService/Check"
+	listObjectsMethod = "/openfga.v1.OpenFGAService/ListObjects"
+)
+
+func TestClassify(t *testing.T) {
+	s := NewScheduler()
+
+	require.Equal(t, Interactive, s.Classify(context.Background(), checkMethod))
+	require.Equal(t, Batch, s.Classify(context.Background(), listObjectsMethod))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "batch"))
+	require.Equal(t, Batch, s.Classify(ctx, checkMethod))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "interactive"))
+	require.Equal(t, Interactive, s.Classify(ctx, listObjectsMethod))
+
+	// an invalid class is ignored
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "urgent"))
+	require.Equal(t, Batch, s.Classify(ctx, listObjectsMethod))
+
+	s = NewScheduler(WithBatchMethods([]string{"Check"}))
+	require.Equal(t, Batch, s.Classify(context.Background(), checkMethod))
+	require.Equal(t, Interactive, s.Classify(context.Background(), listObjectsMethod))
+}
+
+// acquireAsync acquires a slot in the background, and returns the channel receiving its release function once
+// it is granted.
+func acquireAsync(t *testing.T, s *Scheduler, class Class) <-chan func() {
+	t.Helper()
+
+	granted := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(context.Background(), class)
+		if err == nil {
+			granted <- release
+		}
+	}()
+
+	return granted
+}
+
+func requireWaiting(t *testing.T, s *Scheduler, class Class, n int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.queues[class].Len() == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("interactive_requests_are_granted_slots_first", func(t *testing.T) {
+		s := NewScheduler(WithMaxConcurrentRequests(1), WithMaxConcurrentBatchRequests(1), WithQueueTimeout(0))
+
+		release, err := s.Acquire(context.Background(), Batch)
+		require.NoError(t, err)
+
+		batch := acquireAsync(t, s, Batch)
+		requireWaiting(t, s, Batch, 1)
+
+		interactive := acquireAsync(t, s, Interactive)
+		requireWaiting(t, s, Interactive, 1)
+
+		release()
+
+		releaseInteractive := <-interactive
+		require.Empty(t, batch)
+
+		releaseInteractive()
+		(<-batch)()
+	})
+
+	t.Run("batch_requests_leave_slots_to_interactive_requests", func(t *testing.T) {
+		s := NewScheduler(WithMaxConcurrentRequests(2), WithMaxConcurrentBatchRequests(1), WithQueueTimeout(0))
+
+		releaseBatch, err := s.Acquire(context.Background(), Batch)
+		require.NoError(t, err)
+
+		batch := acquireAsync(t, s, Batch)
+		requireWaiting(t, s, Batch, 1)
+
+		releaseInteractive, err := s.Acquire(context.Background(), Interactive)
+		require.NoError(t, err)
+		releaseInteractive()
+
+		releaseBatch()
+		(<-batch)()
+	})
+
+	t.Run("waiting_requests_time_out", func(t *testing.T) {
+		s := NewScheduler(WithMaxConcurrentRequests(1), WithQueueTimeout(10*time.Millisecond))
+
+		release, err := s.Acquire(context.Background(), Interactive)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = s.Acquire(context.Background(), Interactive)
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.InternalErrorCode_resource_exhausted), st.Code())
+		requireWaiting(t, s, Interactive, 0)
+	})
+
+	t.Run("waiting_requests_are_cancelled_with_their_context", func(t *testing.T) {
+		s := NewScheduler(WithMaxConcurrentRequests(1), WithQueueTimeout(0))
+
+		release, err := s.Acquire(context.Background(), Interactive)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = s.Acquire(ctx, Interactive)
+		require.ErrorIs(t, err, serverErrors.RequestDeadlineExceeded)
+		requireWaiting(t, s, Interactive, 0)
+
+		// the slot is not leaked by the cancelled request
+		release()
+		release, err = s.Acquire(context.Background(), Interactive)
+		require.NoError(t, err)
+		release()
+	})
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	s := NewScheduler(WithMaxConcurrentRequests(1), WithQueueTimeout(10*time.Millisecond))
+	interceptor := NewUnaryInterceptor(s)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: checkMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+		return nil, err
+	}
+
+	// the nested request waits for the slot held by the outer one, and times out
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: listObjectsMethod}, handler)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(openfgav1.InternalErrorCode_resource_exhausted), st.Code())
+
+	// the methods of the other services are not scheduled
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	require.NoError(t, err)
+}
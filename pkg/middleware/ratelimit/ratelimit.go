@@ -0,0 +1,304 @@
+// Package ratelimit contains middleware that limits the rate of the requests of each store and the number of
+// requests the server admits concurrently, so that a single noisy store cannot degrade every other store.
+package ratelimit
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openfga/openfga/pkg/middleware/clientcert"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+const (
+	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
+	defaultRequestsPerSecond = 100
+	defaultBurst             = 200
+
+	// pruneInterval is how often the buckets that are full again are dropped, to bound the memory used by the
+	// buckets of stores that no longer send requests. A full bucket is indistinguishable from a new one.
+	pruneInterval = time.Minute
+
+	// inFlightRetryAfter is the delay suggested to the requests rejected because too many requests are in flight.
+	inFlightRetryAfter = time.Second
+
+	// limitedServicePrefix is the prefix of the full names of the limited methods. The methods of the other
+	// services, such as the gRPC health checks, are never limited.
+	limitedServicePrefix = "/openfga.v1.OpenFGAService/"
+)
+
+var rejectedRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rate_limit_rejected_requests_count",
+	Help: "Number of requests that were rejected by the rate limiter, by the reason of the rejection",
+}, []string{"grpc_method", "reason"})
+
+type bucketKey struct {
+	storeID string
+	method  string
+	client  string
+}
+
+// Limiter enforces a token bucket per store and API method, and a limit on the number of requests in flight
+// across the whole server. Requests that are not scoped to a store (e.g. CreateStore or ListStores) share the
+// bucket of their API method. Limiter instances may be safely shared by multiple goroutines.
+type Limiter struct {
+	maxInFlightRequests atomic.Uint32
+	perClient           atomic.Bool
+
+	inFlight atomic.Int64
+
+	mu                      sync.Mutex
+	requestsPerSecond       float64
+	burst                   int
+	methodRequestsPerSecond map[string]float64
+	buckets                 map[bucketKey]*rate.Limiter
+	lastPruned              time.Time
+}
+
+type LimiterOption func(l *Limiter)
+
+// WithRequestsPerSecond sets the sustained rate of requests allowed for each store and API method.
+func WithRequestsPerSecond(rps float64) LimiterOption {
+	return func(l *Limiter) {
+		l.requestsPerSecond = rps
+	}
+}
+
+// WithBurst sets the number of requests each store can send to an API method at once, on top of the sustained
+// rate.
+func WithBurst(burst int) LimiterOption {
+	return func(l *Limiter) {
+		l.burst = burst
+	}
+}
+
+// WithMethodRequestsPerSecond overrides the sustained rate of requests allowed for each store on the API method
+// with the provided name (e.g. "ListObjects").
+func WithMethodRequestsPerSecond(method string, rps float64) LimiterOption {
+	return func(l *Limiter) {
+		l.methodRequestsPerSecond[method] = rps
+	}
+}
+
+// WithMaxInFlightRequests sets the maximum number of requests the server handles concurrently, across all the
+// stores. A limit of 0 means unlimited.
+func WithMaxInFlightRequests(max uint32) LimiterOption {
+	return func(l *Limiter) {
+		l.maxInFlightRequests.Store(max)
+	}
+}
+
+// WithPerClientBuckets gives each client certificate subject its own token buckets, so that a client of a store
+// cannot exhaust the rate limit of the other clients of the store. The requests without a client certificate share
+// the buckets of their store.
+func WithPerClientBuckets(enabled bool) LimiterOption {
+	return func(l *Limiter) {
+		l.perClient.Store(enabled)
+	}
+}
+
+// NewLimiter constructs a Limiter.
+func NewLimiter(opts ...LimiterOption) *Limiter {
+	l := &Limiter{
+		requestsPerSecond:       defaultRequestsPerSecond,
+		burst:                   defaultBurst,
+		methodRequestsPerSecond: map[string]float64{},
+		buckets:                 map[bucketKey]*rate.Limiter{},
+		lastPruned:              time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Reconfigure replaces the options of the limiter at runtime, e.g. when the config of the server is reloaded: the
+// options that are not provided are reset to their defaults. The existing buckets keep their tokens, so that a
+// reconfiguration does not refill them, and the requests in flight are not affected.
+func (l *Limiter) Reconfigure(opts ...LimiterOption) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requestsPerSecond = defaultRequestsPerSecond
+	l.burst = defaultBurst
+	l.methodRequestsPerSecond = map[string]float64{}
+	l.maxInFlightRequests.Store(0)
+	l.perClient.Store(false)
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	now := time.Now()
+	for key, b := range l.buckets {
+		b.SetLimitAt(now, rate.Limit(l.methodRate(key.method)))
+		b.SetBurstAt(now, l.burst)
+	}
+}
+
+// admit admits a request in flight, and returns the function to call once it has been handled. It returns an
+// error if too many requests are already in flight.
+func (l *Limiter) admit(fullMethod string) (func(), error) {
+	maxInFlightRequests := l.maxInFlightRequests.Load()
+	if maxInFlightRequests == 0 {
+		return func() {}, nil
+	}
+
+	if l.inFlight.Add(1) > int64(maxInFlightRequests) {
+		l.inFlight.Add(-1)
+		rejectedRequestsCounter.WithLabelValues(fullMethod, "in_flight").Inc()
+		return nil, serverErrors.RateLimitExceeded("The server is handling too many requests. Please retry later", inFlightRetryAfter)
+	}
+
+	return func() { l.inFlight.Add(-1) }, nil
+}
+
+// client returns the client whose buckets the request of the context takes tokens from, which is empty unless
+// the clients have their own buckets.
+func (l *Limiter) client(ctx context.Context) string {
+	if !l.perClient.Load() {
+		return ""
+	}
+
+	subject, _ := clientcert.SubjectFromContext(ctx)
+	return subject
+}
+
+// allow takes a token from the bucket of the store, API method and client, and returns an error carrying the delay
+// after which a token is available if there is none.
+func (l *Limiter) allow(fullMethod, storeID, client string) error {
+	now := time.Now()
+
+	res := l.bucket(bucketKey{storeID: storeID, method: path.Base(fullMethod), client: client}, now).ReserveN(now, 1)
+	if !res.OK() {
+		rejectedRequestsCounter.WithLabelValues(fullMethod, "rate_limit").Inc()
+		return serverErrors.RateLimitExceeded("The rate limit of the store has been exceeded", 0)
+	}
+
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		rejectedRequestsCounter.WithLabelValues(fullMethod, "rate_limit").Inc()
+		return serverErrors.RateLimitExceeded("The rate limit of the store has been exceeded", delay)
+	}
+
+	return nil
+}
+
+// bucket returns the token bucket of the key, creating it if needed.
+func (l *Limiter) bucket(key bucketKey, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPruned) > pruneInterval {
+		for k, b := range l.buckets {
+			if b.TokensAt(now) >= float64(b.Burst()) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPruned = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = rate.NewLimiter(rate.Limit(l.methodRate(key.method)), l.burst)
+		l.buckets[key] = b
+	}
+
+	return b
+}
+
+// methodRate returns the sustained rate of requests allowed on the API method. l.mu must be held.
+func (l *Limiter) methodRate(method string) float64 {
+	if rps, ok := l.methodRequestsPerSecond[method]; ok {
+		return rps
+	}
+
+	return l.requestsPerSecond
+}
+
+type hasGetStoreID interface {
+	GetStoreId() string
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which rejects the requests that exceed the rate
+// limit of their store and API method, or the maximum number of requests in flight, with
+// serverErrors.RateLimitExceeded.
+func NewUnaryInterceptor(l *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, limitedServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		done, err := l.admit(info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		var storeID string
+		if r, ok := req.(hasGetStoreID); ok {
+			storeID = r.GetStoreId()
+		}
+
+		if err := l.allow(info.FullMethod, storeID, l.client(ctx)); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor creates a grpc.StreamServerInterceptor which rejects the requests that exceed the rate
+// limit of their store and API method, or the maximum number of requests in flight, with
+// serverErrors.RateLimitExceeded. Since the store is only known once the request message is received, the rate
+// limit is enforced when the handler receives its first message.
+func NewStreamingInterceptor(l *Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasPrefix(info.FullMethod, limitedServicePrefix) {
+			return handler(srv, stream)
+		}
+
+		done, err := l.admit(info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		return handler(srv, &limitedServerStream{ServerStream: stream, limiter: l, fullMethod: info.FullMethod})
+	}
+}
+
+type limitedServerStream struct {
+	grpc.ServerStream
+	limiter    *Limiter
+	fullMethod string
+	received   bool
+}
+
+func (s *limitedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.received {
+		return nil
+	}
+	s.received = true
+
+	var storeID string
+	if r, ok := m.(hasGetStoreID); ok {
+		storeID = r.GetStoreId()
+	}
+
+	return s.limiter.allow(s.fullMethod, storeID, s.limiter.client(s.ServerStream.Context()))
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/middleware/clientcert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	checkMethod       = "/openfga.v1.OpenFGAService/Check"
+	listObjectsMethod = "/openfga.v1.OpenFGAService/ListObjects"
+)
+
+func requireRateLimited(t *testing.T, err error, retryable bool) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(openfgav1.InternalErrorCode_resource_exhausted), st.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retryInfo = info
+		}
+	}
+
+	if retryable {
+		require.NotNil(t, retryInfo)
+		require.Positive(t, retryInfo.GetRetryDelay().AsDuration())
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	call := func(interceptor grpc.UnaryServerInterceptor, method, storeID string) error {
+		_, err := interceptor(context.Background(), &openfgav1.CheckRequest{StoreId: storeID}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	t.Run("each_store_has_its_own_bucket", func(t *testing.T) {
+		interceptor := NewUnaryInterceptor(NewLimiter(WithRequestsPerSecond(0.001), WithBurst(2)))
+
+		require.NoError(t, call(interceptor, checkMethod, "store-a"))
+		require.NoError(t, call(interceptor, checkMethod, "store-a"))
+		requireRateLimited(t, call(interceptor, checkMethod, "store-a"), true)
+
+		// the other stores and the other API methods of the same store are not affected
+		require.NoError(t, call(interceptor, checkMethod, "store-b"))
+		require.NoError(t, call(interceptor, listObjectsMethod, "store-a"))
+
+		// the methods of the other services are never limited
+		for i := 0; i < 3; i++ {
+			require.NoError(t, call(interceptor, "/grpc.health.v1.Health/Check", ""))
+		}
+	})
+
+	t.Run("methods_can_override_the_rate", func(t *testing.T) {
+		interceptor := NewUnaryInterceptor(NewLimiter(
+			WithRequestsPerSecond(1000),
+			WithBurst(1),
+			WithMethodRequestsPerSecond("ListObjects", 0.001),
+		))
+
+		require.NoError(t, call(interceptor, listObjectsMethod, "store-a"))
+		requireRateLimited(t, call(interceptor, listObjectsMethod, "store-a"), true)
+	})
+
+	t.Run("clients_can_have_their_own_buckets", func(t *testing.T) {
+		interceptor := NewUnaryInterceptor(NewLimiter(WithRequestsPerSecond(0.001), WithBurst(1), WithPerClientBuckets(true)))
+
+		callAs := func(client string) error {
+			ctx := clientcert.ContextWithSubject(context.Background(), client)
+			_, err := interceptor(ctx, &openfgav1.CheckRequest{StoreId: "store-a"}, &grpc.UnaryServerInfo{FullMethod: checkMethod}, handler)
+			return err
+		}
+
+		require.NoError(t, callAs("CN=client-a"))
+		requireRateLimited(t, callAs("CN=client-a"), true)
+		require.NoError(t, callAs("CN=client-b"))
+	})
+
+	t.Run("too_many_requests_in_flight", func(t *testing.T) {
+		l := NewLimiter(WithMaxInFlightRequests(1))
+		interceptor := NewUnaryInterceptor(l)
+
+		var nestedErr error
+		_, err := interceptor(context.Background(), &openfgav1.CheckRequest{StoreId: "store-a"}, &grpc.UnaryServerInfo{FullMethod: checkMethod},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				nestedErr = call(interceptor, checkMethod, "store-b")
+				return "ok", nil
+			})
+		require.NoError(t, err)
+		requireRateLimited(t, nestedErr, true)
+
+		// the request is no longer in flight once handled
+		require.NoError(t, call(interceptor, checkMethod, "store-b"))
+	})
+
+	t.Run("reconfigure", func(t *testing.T) {
+		l := NewLimiter(WithRequestsPerSecond(0.001), WithBurst(1))
+		interceptor := NewUnaryInterceptor(l)
+
+		require.NoError(t, call(interceptor, checkMethod, "store-a"))
+		requireRateLimited(t, call(interceptor, checkMethod, "store-a"), true)
+
+		// the existing buckets are not refilled, but take the new rate
+		l.Reconfigure(WithRequestsPerSecond(1000), WithBurst(1))
+		require.Eventually(t, func() bool {
+			return call(interceptor, checkMethod, "store-a") == nil
+		}, time.Second, time.Millisecond)
+
+		// the options that are not provided are reset to their defaults
+		l.Reconfigure(WithMaxInFlightRequests(1))
+		require.Equal(t, uint32(1), l.maxInFlightRequests.Load())
+		l.Reconfigure()
+		require.Zero(t, l.maxInFlightRequests.Load())
+		require.Equal(t, float64(defaultRequestsPerSecond), l.requestsPerSecond)
+	})
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	req *openfgav1.StreamedListObjectsRequest
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *mockServerStream) RecvMsg(m interface{}) error {
+	m.(*openfgav1.StreamedListObjectsRequest).StoreId = s.req.GetStoreId()
+	return nil
+}
+
+func TestStreamingInterceptor(t *testing.T) {
+	interceptor := NewStreamingInterceptor(NewLimiter(WithRequestsPerSecond(0.001), WithBurst(1)))
+	info := &grpc.StreamServerInfo{FullMethod: "/openfga.v1.OpenFGAService/StreamedListObjects"}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg(&openfgav1.StreamedListObjectsRequest{})
+	}
+
+	stream := &mockServerStream{req: &openfgav1.StreamedListObjectsRequest{StoreId: "store-a"}}
+
+	require.NoError(t, interceptor(nil, stream, info, handler))
+	requireRateLimited(t, interceptor(nil, stream, info, handler), true)
+}
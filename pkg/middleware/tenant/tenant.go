@@ -0,0 +1,102 @@
+// Package tenant contains middleware to route the requests of the tenants to their datastores.
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the gRPC metadata key of the tenant of a request, whose stores are in the datastore of the tenant, see
+// tenancy.ContextWithTenant. Over HTTP it is sent as the Grpc-Metadata-Openfga-Tenant header.
+const Header = "openfga-tenant"
+
+type hasGetStoreID interface {
+	GetStoreId() string
+}
+
+// NewUnaryInterceptor returns a grpc.UnaryServerInterceptor that injects the tenant of the Header metadata into the
+// context. It rejects the requests of unknown tenants, and the requests for a store that the routing table routes to
+// another tenant.
+func NewUnaryInterceptor(t *tenancy.Tenancy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := contextFromMetadata(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkRoute(ctx, t, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamingInterceptor is the streaming counterpart of NewUnaryInterceptor. The store of every message received
+// is checked against the routing table.
+func NewStreamingInterceptor(t *tenancy.Tenancy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := contextFromMetadata(stream.Context(), t)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &wrappedServerStream{ServerStream: stream, ctx: ctx, tenancy: t})
+	}
+}
+
+func contextFromMetadata(ctx context.Context, t *tenancy.Tenancy) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, Header)
+	if len(values) == 0 || values[0] == "" {
+		return ctx, nil
+	}
+
+	if !t.HasTenant(values[0]) {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("the %s header names the unknown tenant '%s'", Header, values[0]))
+	}
+
+	return tenancy.ContextWithTenant(ctx, values[0]), nil
+}
+
+// checkRoute rejects the request if its store is routed to another tenant than the tenant of the context.
+func checkRoute(ctx context.Context, t *tenancy.Tenancy, req interface{}) error {
+	r, ok := req.(hasGetStoreID)
+	if !ok {
+		return nil
+	}
+
+	tenant, ok := tenancy.TenantFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if routed, ok := t.Route(r.GetStoreId()); ok && routed != tenant {
+		return status.Error(codes.PermissionDenied, fmt.Sprintf("the store '%s' does not belong to the tenant '%s'", r.GetStoreId(), tenant))
+	}
+
+	return nil
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	tenancy *tenancy.Tenancy
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *wrappedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return checkRoute(s.ctx, s.tenancy, m)
+}
@@ -0,0 +1,54 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryInterceptor(t *testing.T) {
+	tenants, err := tenancy.New(memory.New(), []tenancy.Tenant{
+		{Name: "acme", Datastore: memory.New()},
+		{Name: "globex", Datastore: memory.New()},
+	}, tenancy.WithRoutes(map[string]string{"acme-store": "acme"}))
+	require.NoError(t, err)
+	t.Cleanup(tenants.Close)
+
+	interceptor := NewUnaryInterceptor(tenants)
+	info := &grpc.UnaryServerInfo{FullMethod: "/openfga.v1.OpenFGAService/Check"}
+
+	var got string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = tenancy.TenantFromContext(ctx)
+		return nil, nil
+	}
+
+	_, err = interceptor(context.Background(), &openfgav1.CheckRequest{StoreId: "acme-store"}, info, handler)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "acme"))
+	_, err = interceptor(ctx, &openfgav1.CheckRequest{StoreId: "acme-store"}, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "acme", got)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "globex"))
+	_, err = interceptor(ctx, &openfgav1.CheckRequest{StoreId: "acme-store"}, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = interceptor(ctx, &openfgav1.CheckRequest{StoreId: "other-store"}, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "globex", got)
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, "initech"))
+	_, err = interceptor(ctx, &openfgav1.CheckRequest{StoreId: "other-store"}, info, handler)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
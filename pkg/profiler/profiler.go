@@ -0,0 +1,255 @@
+// Package profiler serves the pprof profiles of the server, and captures CPU profiles automatically when the
+// latency of the Check requests degrades.
+package profiler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
+	defaultMaxProfileDuration = 30 * time.Second
+	defaultCPUProfileDuration = 10 * time.Second
+	defaultCPUProfileCooldown = 10 * time.Minute
+
+	// defaultLatencyWindow is the number of latencies the p99 is computed over.
+	defaultLatencyWindow = 1000
+
+	// the durations of the profiles requested without the 'seconds' parameter, see net/http/pprof
+	defaultProfileSeconds = 30
+	defaultTraceSeconds   = 1
+
+	checkMethod = "/openfga.v1.OpenFGAService/Check"
+)
+
+type handler struct {
+	mux                *http.ServeMux
+	maxProfileDuration time.Duration
+}
+
+type HandlerOption func(h *handler)
+
+// WithMaxProfileDuration sets the maximum duration of the CPU profiles and of the execution traces, which are
+// otherwise as long as requested. Defaults to 30s.
+func WithMaxProfileDuration(d time.Duration) HandlerOption {
+	return func(h *handler) {
+		h.maxProfileDuration = d
+	}
+}
+
+// NewHandler returns the http.Handler serving the pprof profiles (see net/http/pprof) under /debug/pprof/:
+// the runtime profiles (heap, goroutine, ...), the CPU profile and the execution trace. It doesn't authenticate the
+// requests, so it must be served behind an authenticated server, see admin.WithProfiler.
+func NewHandler(opts ...HandlerOption) http.Handler {
+	h := &handler{
+		mux:                http.NewServeMux(),
+		maxProfileDuration: defaultMaxProfileDuration,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("/debug/pprof/", httppprof.Index)
+	h.mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+	h.mux.HandleFunc("/debug/pprof/profile", h.limitDuration(httppprof.Profile, defaultProfileSeconds))
+	h.mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+	h.mux.HandleFunc("/debug/pprof/trace", h.limitDuration(httppprof.Trace, defaultTraceSeconds))
+
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// limitDuration rejects the requests for a profile longer than the maximum profile duration. The profiles
+// requested without the 'seconds' parameter last defaultSeconds.
+func (h *handler) limitDuration(next http.HandlerFunc, defaultSeconds float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seconds := defaultSeconds
+		if value := r.URL.Query().Get("seconds"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "the 'seconds' parameter must be a positive number", http.StatusBadRequest)
+				return
+			}
+			seconds = parsed
+		}
+
+		if time.Duration(seconds*float64(time.Second)) > h.maxProfileDuration {
+			http.Error(w, fmt.Sprintf("profiles cannot be longer than %s", h.maxProfileDuration), http.StatusBadRequest)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// CPUProfileTrigger captures a CPU profile when the p99 of the latencies it observes exceeds a threshold. The p99
+// is computed over every window of latencies, and the profiles are written to a directory, at most once per
+// cooldown. CPUProfileTrigger instances may be safely shared by multiple goroutines.
+type CPUProfileTrigger struct {
+	threshold time.Duration
+	dir       string
+	duration  time.Duration
+	cooldown  time.Duration
+	window    int
+	logger    logger.Logger
+
+	mu          sync.Mutex
+	latencies   []time.Duration
+	capturing   bool
+	lastCapture time.Time
+}
+
+type CPUProfileTriggerOption func(t *CPUProfileTrigger)
+
+// WithCPUProfileDuration sets how long the CPU is profiled for. Defaults to 10s.
+func WithCPUProfileDuration(d time.Duration) CPUProfileTriggerOption {
+	return func(t *CPUProfileTrigger) {
+		t.duration = d
+	}
+}
+
+// WithCPUProfileCooldown sets the minimum delay between the starts of two profiles. Defaults to 10m.
+func WithCPUProfileCooldown(d time.Duration) CPUProfileTriggerOption {
+	return func(t *CPUProfileTrigger) {
+		t.cooldown = d
+	}
+}
+
+// WithLatencyWindow sets the number of latencies the p99 is computed over. Defaults to 1000.
+func WithLatencyWindow(n int) CPUProfileTriggerOption {
+	return func(t *CPUProfileTrigger) {
+		t.window = n
+	}
+}
+
+func WithLogger(l logger.Logger) CPUProfileTriggerOption {
+	return func(t *CPUProfileTrigger) {
+		t.logger = l
+	}
+}
+
+// NewCPUProfileTrigger constructs a CPUProfileTrigger capturing a CPU profile in the directory once the p99 of the
+// observed latencies exceeds the threshold.
+func NewCPUProfileTrigger(threshold time.Duration, dir string, opts ...CPUProfileTriggerOption) *CPUProfileTrigger {
+	t := &CPUProfileTrigger{
+		threshold: threshold,
+		dir:       dir,
+		duration:  defaultCPUProfileDuration,
+		cooldown:  defaultCPUProfileCooldown,
+		window:    defaultLatencyWindow,
+		logger:    logger.NewNoopLogger(),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.latencies = make([]time.Duration, 0, t.window)
+
+	return t
+}
+
+// Observe records a latency. Once a window of latencies is recorded, their p99 is compared to the threshold.
+func (t *CPUProfileTrigger) Observe(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.latencies = append(t.latencies, latency)
+	if len(t.latencies) < t.window {
+		return
+	}
+
+	p99 := percentile(t.latencies, 0.99)
+	t.latencies = t.latencies[:0]
+
+	if p99 <= t.threshold || t.capturing || (!t.lastCapture.IsZero() && time.Since(t.lastCapture) < t.cooldown) {
+		return
+	}
+
+	t.capturing = true
+	t.lastCapture = time.Now()
+
+	go t.capture(p99)
+}
+
+// capture profiles the CPU for the profile duration, and writes the profile to the directory.
+func (t *CPUProfileTrigger) capture(p99 time.Duration) {
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		t.capturing = false
+	}()
+
+	path := filepath.Join(t.dir, fmt.Sprintf("cpu-%s.pprof", time.Now().UTC().Format("20060102T150405Z")))
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.logger.Error("failed to create the CPU profile file", zap.String("path", path), zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	// fails if the CPU is already being profiled, e.g. through the /debug/pprof/profile endpoint
+	if err := pprof.StartCPUProfile(f); err != nil {
+		t.logger.Warn("failed to start the CPU profile", zap.Error(err))
+		_ = os.Remove(path)
+		return
+	}
+
+	t.logger.Info("capturing a CPU profile because the Check p99 latency exceeds the threshold",
+		zap.Duration("p99", p99),
+		zap.Duration("threshold", t.threshold),
+		zap.String("path", path),
+	)
+
+	time.Sleep(t.duration)
+	pprof.StopCPUProfile()
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the latencies, which it sorts.
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	i := int(float64(len(latencies))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return latencies[i]
+}
+
+// NewUnaryInterceptor creates a grpc.UnaryServerInterceptor which reports the latency of the Check requests to
+// the CPUProfileTrigger.
+func NewUnaryInterceptor(t *CPUProfileTrigger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod != checkMethod {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		defer func() {
+			t.Observe(time.Since(start))
+		}()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,76 @@
+package profiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(WithMaxProfileDuration(10 * time.Second))
+
+	serve := func(target string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve("/debug/pprof/"))
+
+	// the CPU profiles last 30s by default
+	require.Equal(t, http.StatusBadRequest, serve("/debug/pprof/profile"))
+	require.Equal(t, http.StatusBadRequest, serve("/debug/pprof/profile?seconds=11"))
+	require.Equal(t, http.StatusBadRequest, serve("/debug/pprof/trace?seconds=-1"))
+	require.Equal(t, http.StatusOK, serve("/debug/pprof/trace?seconds=0.01"))
+}
+
+func TestCPUProfileTrigger(t *testing.T) {
+	t.Run("captures_a_profile_when_the_p99_exceeds_the_threshold", func(t *testing.T) {
+		dir := t.TempDir()
+		trigger := NewCPUProfileTrigger(10*time.Millisecond, dir, WithLatencyWindow(100), WithCPUProfileDuration(10*time.Millisecond))
+
+		for i := 0; i < 98; i++ {
+			trigger.Observe(time.Millisecond)
+		}
+		trigger.Observe(20 * time.Millisecond)
+		trigger.Observe(20 * time.Millisecond)
+
+		require.Eventually(t, func() bool {
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+
+			trigger.mu.Lock()
+			defer trigger.mu.Unlock()
+
+			return len(entries) == 1 && !trigger.capturing
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// no other profile is captured until the cooldown is over
+		for i := 0; i < 100; i++ {
+			trigger.Observe(20 * time.Millisecond)
+		}
+
+		trigger.mu.Lock()
+		defer trigger.mu.Unlock()
+		require.False(t, trigger.capturing)
+	})
+
+	t.Run("does_not_capture_a_profile_below_the_threshold", func(t *testing.T) {
+		dir := t.TempDir()
+		trigger := NewCPUProfileTrigger(10*time.Millisecond, dir, WithLatencyWindow(100))
+
+		for i := 0; i < 99; i++ {
+			trigger.Observe(time.Millisecond)
+		}
+		trigger.Observe(20 * time.Millisecond)
+
+		trigger.mu.Lock()
+		defer trigger.mu.Unlock()
+		require.False(t, trigger.capturing)
+	})
+}
@@ -0,0 +1,240 @@
+// Package recorder records a sample of the Check and ListObjects requests served, along with their results, so that
+// they can be replayed later, e.g. against a modified authorization model to catch the regressions of a refactor
+// before it is written. See the replay command.
+//
+// The records are written as JSON lines. The ids of the objects and users can be anonymized with a keyed hash,
+// which is consistent across records, so that anonymized records can still be replayed against tuples anonymized
+// with the same key, see Anonymizer.
+package recorder
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+const (
+	MethodCheck       = "Check"
+	MethodListObjects = "ListObjects"
+)
+
+// Record is a recorded request along with its result.
+type Record struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	StoreID string    `json:"store_id"`
+
+	// AuthorizationModelID is the id of the authorization model the request was resolved with, even if the request
+	// did not set one.
+	AuthorizationModelID string `json:"authorization_model_id"`
+
+	// Object is the object of a Check, and ObjectType the type of the objects of a ListObjects.
+	Object     string `json:"object,omitempty"`
+	ObjectType string `json:"object_type,omitempty"`
+
+	Relation         string                `json:"relation"`
+	User             string                `json:"user"`
+	ContextualTuples []*openfgav1.TupleKey `json:"contextual_tuples,omitempty"`
+
+	// Allowed is the result of a Check, and Objects the result of a ListObjects.
+	Allowed bool     `json:"allowed,omitempty"`
+	Objects []string `json:"objects,omitempty"`
+
+	// Truncated is set if the Objects may only be some of the objects, because the ListObjects hit its max results or
+	// its deadline.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Anonymized is set if the ids of the objects and users were anonymized.
+	Anonymized bool `json:"anonymized,omitempty"`
+}
+
+// Recorder samples, anonymizes and writes the records. Recorder instances may be safely shared by multiple
+// goroutines.
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+
+	sampleRate float64
+	anonymizer *Anonymizer
+
+	// sample returns a number in [0, 1) which is compared to the sample rate
+	sample func() float64
+}
+
+type RecorderOption func(r *Recorder)
+
+// WithSampleRate sets the probability, between 0 and 1, with which a request is recorded. Defaults to 1.
+func WithSampleRate(rate float64) RecorderOption {
+	return func(r *Recorder) {
+		r.sampleRate = rate
+	}
+}
+
+// WithAnonymizationKey anonymizes the ids of the objects and users of the records with the key, see Anonymizer.
+func WithAnonymizationKey(key string) RecorderOption {
+	return func(r *Recorder) {
+		r.anonymizer = NewAnonymizer(key)
+	}
+}
+
+// NewRecorder constructs a Recorder which writes the records as JSON lines to w. It returns an error if the sample
+// rate is not between 0 and 1.
+func NewRecorder(w io.Writer, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		encoder:    json.NewEncoder(w),
+		sampleRate: 1,
+		sample:     rand.Float64,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.sampleRate < 0 || r.sampleRate > 1 {
+		return nil, fmt.Errorf("the sample rate must be between 0 and 1")
+	}
+
+	return r, nil
+}
+
+// Sample returns true if the next request must be recorded.
+func (r *Recorder) Sample() bool {
+	if r.sampleRate >= 1 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sample() < r.sampleRate
+}
+
+// Record anonymizes and writes the record.
+func (r *Recorder) Record(record *Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	if r.anonymizer != nil {
+		r.anonymizer.anonymize(record)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.encoder.Encode(record)
+}
+
+// Reader reads the records written by a Recorder.
+type Reader struct {
+	decoder *json.Decoder
+	line    int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{decoder: json.NewDecoder(r)}
+}
+
+// Next returns the next record, or io.EOF once every record was read.
+func (r *Reader) Next() (*Record, error) {
+	r.line++
+
+	var record Record
+	if err := r.decoder.Decode(&record); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("failed to read record %d: %w", r.line, err)
+	}
+
+	if record.Method != MethodCheck && record.Method != MethodListObjects {
+		return nil, fmt.Errorf("record %d has an unknown method '%s'", r.line, record.Method)
+	}
+
+	return &record, nil
+}
+
+// Anonymizer replaces the ids of the objects and users with a keyed hash of them. The types, relations and
+// wildcards are kept, so that the anonymized requests resolve against the anonymized tuples as the original requests
+// resolve against the original tuples.
+type Anonymizer struct {
+	key []byte
+}
+
+func NewAnonymizer(key string) *Anonymizer {
+	return &Anonymizer{key: []byte(key)}
+}
+
+// ID returns the anonymized id.
+func (a *Anonymizer) ID(id string) string {
+	if id == "" || id == tuple.Wildcard {
+		return id
+	}
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(id))
+
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Object anonymizes the id of an object, e.g. 'document:1'.
+func (a *Anonymizer) Object(object string) string {
+	objectType, id := tuple.SplitObject(object)
+	if objectType == "" {
+		return a.ID(id)
+	}
+
+	return tuple.BuildObject(objectType, a.ID(id))
+}
+
+// User anonymizes the id of a user, e.g. 'user:anne', 'group:eng#member' or 'user:*'.
+func (a *Anonymizer) User(user string) string {
+	object, relation := tuple.SplitObjectRelation(user)
+	if relation == "" {
+		return a.Object(object)
+	}
+
+	return tuple.ToObjectRelationString(a.Object(object), relation)
+}
+
+// TupleKey returns the tuple key with its object and user anonymized.
+func (a *Anonymizer) TupleKey(tk *openfgav1.TupleKey) *openfgav1.TupleKey {
+	return tuple.NewTupleKey(a.Object(tk.GetObject()), tk.GetRelation(), a.User(tk.GetUser()))
+}
+
+func (a *Anonymizer) anonymize(record *Record) {
+	if record.Object != "" {
+		record.Object = a.Object(record.Object)
+	}
+	record.User = a.User(record.User)
+
+	// the slices may be those of the request and response, which are not modified
+	if record.ContextualTuples != nil {
+		contextualTuples := make([]*openfgav1.TupleKey, 0, len(record.ContextualTuples))
+		for _, tk := range record.ContextualTuples {
+			contextualTuples = append(contextualTuples, a.TupleKey(tk))
+		}
+		record.ContextualTuples = contextualTuples
+	}
+
+	if record.Objects != nil {
+		objects := make([]string, 0, len(record.Objects))
+		for _, object := range record.Objects {
+			objects = append(objects, a.Object(object))
+		}
+		record.Objects = objects
+	}
+
+	record.Anonymized = true
+}
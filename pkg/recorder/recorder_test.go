@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Record(&Record{
+		Method:               MethodCheck,
+		StoreID:              "store1",
+		AuthorizationModelID: "model1",
+		Object:               "doc:1",
+		Relation:             "viewer",
+		User:                 "user:anne",
+		ContextualTuples:     []*openfgav1.TupleKey{tuple.NewTupleKey("group:eng", "member", "user:anne")},
+		Allowed:              true,
+	}))
+	require.NoError(t, r.Record(&Record{
+		Method:     MethodListObjects,
+		StoreID:    "store1",
+		ObjectType: "doc",
+		Relation:   "viewer",
+		User:       "user:anne",
+		Objects:    []string{"doc:1", "doc:2"},
+		Truncated:  true,
+	}))
+
+	reader := NewReader(&buf)
+
+	check, err := reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, MethodCheck, check.Method)
+	require.Equal(t, "model1", check.AuthorizationModelID)
+	require.Equal(t, "doc:1", check.Object)
+	require.True(t, check.Allowed)
+	require.False(t, check.Time.IsZero())
+	require.Len(t, check.ContextualTuples, 1)
+	require.Equal(t, "group:eng#member@user:anne", tuple.TupleKeyToString(check.ContextualTuples[0]))
+
+	listObjects, err := reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, MethodListObjects, listObjects.Method)
+	require.Equal(t, []string{"doc:1", "doc:2"}, listObjects.Objects)
+	require.True(t, listObjects.Truncated)
+
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestReadInvalidRecords(t *testing.T) {
+	_, err := NewReader(strings.NewReader(`{"method":"Expand"}`)).Next()
+	require.EqualError(t, err, "record 1 has an unknown method 'Expand'")
+
+	reader := NewReader(strings.NewReader(`{"method":"Check"}` + "\n{"))
+	_, err = reader.Next()
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.ErrorContains(t, err, "failed to read record 2")
+}
+
+func TestNewRecorderValidatesTheSampleRate(t *testing.T) {
+	_, err := NewRecorder(io.Discard, WithSampleRate(1.5))
+	require.EqualError(t, err, "the sample rate must be between 0 and 1")
+}
+
+func TestSample(t *testing.T) {
+	r, err := NewRecorder(io.Discard, WithSampleRate(0.5))
+	require.NoError(t, err)
+
+	r.sample = func() float64 { return 0.4 }
+	require.True(t, r.Sample())
+
+	r.sample = func() float64 { return 0.6 }
+	require.False(t, r.Sample())
+}
+
+func TestAnonymization(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewRecorder(&buf, WithAnonymizationKey("secret"))
+	require.NoError(t, err)
+
+	contextualTuples := []*openfgav1.TupleKey{tuple.NewTupleKey("group:eng", "member", "user:anne")}
+	objects := []string{"doc:1"}
+	require.NoError(t, r.Record(&Record{
+		Method:           MethodCheck,
+		Object:           "doc:1",
+		Relation:         "viewer",
+		User:             "group:eng#member",
+		ContextualTuples: contextualTuples,
+	}))
+	require.NoError(t, r.Record(&Record{
+		Method:     MethodListObjects,
+		ObjectType: "doc",
+		Relation:   "viewer",
+		User:       "user:*",
+		Objects:    objects,
+	}))
+
+	// the request and the response are not modified
+	require.Equal(t, "group:eng#member@user:anne", tuple.TupleKeyToString(contextualTuples[0]))
+	require.Equal(t, []string{"doc:1"}, objects)
+
+	a := NewAnonymizer("secret")
+	reader := NewReader(&buf)
+
+	check, err := reader.Next()
+	require.NoError(t, err)
+	require.True(t, check.Anonymized)
+	require.Equal(t, "doc:"+a.ID("1"), check.Object)
+	require.Equal(t, "group:"+a.ID("eng")+"#member", check.User)
+	require.Equal(t, a.TupleKey(contextualTuples[0]).String(), check.ContextualTuples[0].String())
+	require.NotContains(t, tuple.TupleKeyToString(check.ContextualTuples[0]), "anne")
+
+	listObjects, err := reader.Next()
+	require.NoError(t, err)
+	require.Equal(t, "user:*", listObjects.User)
+	require.Equal(t, []string{check.Object}, listObjects.Objects)
+
+	// the ids are anonymized consistently with the key, and differently with another key
+	require.Equal(t, a.ID("1"), NewAnonymizer("secret").ID("1"))
+	require.NotEqual(t, a.ID("1"), NewAnonymizer("other").ID("1"))
+}
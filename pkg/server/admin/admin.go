@@ -0,0 +1,230 @@
+// Package admin serves the operational endpoints of an OpenFGA server over HTTP: flushing the caches, trimming the
+// changelog, reporting the statistics of a store, refreshing the authorization models and toggling the experimental
+// features, without restarting the server.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openfga/openfga/internal/authn"
+	httpmiddleware "github.com/openfga/openfga/pkg/middleware/http"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/server/commands"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type handler struct {
+	mux           *http.ServeMux
+	server        *server.Server
+	authenticator authn.Authenticator
+	profiler      http.Handler
+}
+
+type HandlerOption func(h *handler)
+
+// WithProfiler serves the pprof profiles of the profiler under /debug/pprof/, see profiler.NewHandler, with the
+// authentication of the other endpoints. By default, the profiles are not served.
+func WithProfiler(profiler http.Handler) HandlerOption {
+	return func(h *handler) {
+		h.profiler = profiler
+	}
+}
+
+// WithAuthenticator requires the requests to be authenticated by the authenticator, with the bearer token of their
+// Authorization header. By default, the requests are not authenticated.
+func WithAuthenticator(authenticator authn.Authenticator) HandlerOption {
+	return func(h *handler) {
+		h.authenticator = authenticator
+	}
+}
+
+// NewHandler returns the http.Handler serving the operational endpoints of the server under /admin/:
+//
+//   - POST /admin/caches/flush[?store_id=]: drops the cached Check results and authorization models of the store, or
+//     of every store.
+//   - POST /admin/changelog/trim?older_than=<duration>[&store_id=]: deletes the changes older than the duration from
+//     the changelog of the store, or of every store.
+//   - GET /admin/stores/statistics?store_id=: reports the cardinality statistics of the tuples of the store.
+//   - GET /admin/stores/stats?store_id=: reports the number of tuples per object type and relation, of authorization
+//     model versions and of changes of the store.
+//   - POST /admin/typesystems/refresh[?store_id=]: reads the authorization models of the store, or of every store,
+//     from the datastore again.
+//   - GET /admin/experimentals: lists the enabled experimental features.
+//   - POST /admin/experimentals?flag=&enabled=<bool>: enables or disables an experimental feature.
+//   - GET /debug/pprof/...: serves the pprof profiles, if enabled with WithProfiler.
+//
+// The endpoints of a store accept a tenant parameter naming the tenant the store belongs to, whose datastore and
+// cached entries they act on, see tenancy.ContextWithTenant.
+func NewHandler(svr *server.Server, opts ...HandlerOption) http.Handler {
+	h := &handler{
+		mux:    http.NewServeMux(),
+		server: svr,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("/admin/caches/flush", post(h.flushCaches))
+	h.mux.HandleFunc("/admin/changelog/trim", post(h.trimChangelog))
+	h.mux.HandleFunc("/admin/stores/statistics", get(h.storeStatistics))
+	h.mux.HandleFunc("/admin/stores/stats", get(h.storeStats))
+	h.mux.HandleFunc("/admin/typesystems/refresh", post(h.refreshTypesystems))
+	h.mux.HandleFunc("/admin/experimentals", h.experimentals)
+	if h.profiler != nil {
+		h.mux.Handle("/debug/pprof/", h.profiler)
+	}
+
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.authenticator != nil {
+		ctx := metadata.NewIncomingContext(r.Context(), metadata.Pairs("authorization", r.Header.Get("Authorization")))
+		if _, err := h.authenticator.Authenticate(ctx); err != nil {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if tenant := r.URL.Query().Get("tenant"); tenant != "" {
+		r = r.WithContext(tenancy.ContextWithTenant(r.Context(), tenant))
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *handler) flushCaches(w http.ResponseWriter, r *http.Request) {
+	storeID := r.URL.Query().Get("store_id")
+
+	if err := h.server.FlushCaches(r.Context(), storeID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, map[string]string{"store_id": storeID})
+}
+
+func (h *handler) trimChangelog(w http.ResponseWriter, r *http.Request) {
+	olderThan, err := time.ParseDuration(r.URL.Query().Get("older_than"))
+	if err != nil || olderThan <= 0 {
+		writeError(w, r, serverErrors.ValidationError(errors.New("the 'older_than' parameter must be a positive duration")))
+		return
+	}
+
+	resp, err := h.server.TrimChangelog(r.Context(), &commands.TrimChangelogRequest{
+		StoreID: r.URL.Query().Get("store_id"),
+		Before:  time.Now().Add(-olderThan),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, resp)
+}
+
+func (h *handler) storeStatistics(w http.ResponseWriter, r *http.Request) {
+	storeID := r.URL.Query().Get("store_id")
+	if storeID == "" {
+		writeError(w, r, serverErrors.ValidationError(errors.New("the 'store_id' parameter is required")))
+		return
+	}
+
+	stats, err := h.server.StoreStatistics(r.Context(), storeID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (h *handler) storeStats(w http.ResponseWriter, r *http.Request) {
+	storeID := r.URL.Query().Get("store_id")
+	if storeID == "" {
+		writeError(w, r, serverErrors.ValidationError(errors.New("the 'store_id' parameter is required")))
+		return
+	}
+
+	resp, err := h.server.GetStoreStats(r.Context(), &commands.GetStoreStatsRequest{StoreID: storeID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, resp)
+}
+
+func (h *handler) refreshTypesystems(w http.ResponseWriter, r *http.Request) {
+	storeID := r.URL.Query().Get("store_id")
+
+	h.server.RefreshTypesystems(r.Context(), storeID)
+
+	writeJSON(w, map[string]string{"store_id": storeID})
+}
+
+func (h *handler) experimentals(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		flag := r.URL.Query().Get("flag")
+		if flag == "" {
+			writeError(w, r, serverErrors.ValidationError(errors.New("the 'flag' parameter is required")))
+			return
+		}
+
+		enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+		if err != nil {
+			writeError(w, r, serverErrors.ValidationError(errors.New("the 'enabled' parameter must be a boolean")))
+			return
+		}
+
+		h.server.SetExperimental(server.ExperimentalFeatureFlag(flag), enabled)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, map[string][]server.ExperimentalFeatureFlag{"experimentals": h.server.Experimentals()})
+}
+
+// get and post restrict the handler to the requests with the method.
+func get(next http.HandlerFunc) http.HandlerFunc {
+	return allowMethod(http.MethodGet, next)
+}
+
+func post(next http.HandlerFunc) http.HandlerFunc {
+	return allowMethod(http.MethodPost, next)
+}
+
+func allowMethod(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes the error like the errors of the API methods served over HTTP.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	code := serverErrors.ConvertToEncodedErrorCode(status.Convert(err))
+	httpmiddleware.CustomHTTPErrorHandler(r.Context(), w, r, serverErrors.NewEncodedError(code, err.Error()))
+}
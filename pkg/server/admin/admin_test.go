@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/authn/presharedkey"
+	"github.com/openfga/openfga/pkg/profiler"
+	"github.com/openfga/openfga/pkg/server"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+const adminKey = "admin-key"
+
+func newTestHandler(t *testing.T, opts ...server.OpenFGAServiceV1Option) (http.Handler, *server.Server) {
+	t.Helper()
+
+	datastore := memory.New()
+	t.Cleanup(datastore.Close)
+
+	svr := server.MustNewServerWithOpts(append([]server.OpenFGAServiceV1Option{server.WithDatastore(datastore)}, opts...)...)
+	t.Cleanup(svr.Close)
+
+	authenticator, err := presharedkey.NewPresharedKeyAuthenticator([]string{adminKey})
+	require.NoError(t, err)
+
+	return NewHandler(svr, WithAuthenticator(authenticator)), svr
+}
+
+func serve(t *testing.T, h http.Handler, method, target string, body interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+adminKey)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if body != nil && w.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(w.Body).Decode(body))
+	}
+
+	return w.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	for _, authorization := range []string{"", "Bearer api-key"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/experimentals", nil)
+		req.Header.Set("Authorization", authorization)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/admin/experimentals", nil))
+}
+
+func TestProfiler(t *testing.T) {
+	h, svr := newTestHandler(t)
+	require.Equal(t, http.StatusNotFound, serve(t, h, http.MethodGet, "/debug/pprof/", nil))
+
+	authenticator, err := presharedkey.NewPresharedKeyAuthenticator([]string{adminKey})
+	require.NoError(t, err)
+	h = NewHandler(svr, WithAuthenticator(authenticator), WithProfiler(profiler.NewHandler()))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/debug/pprof/", nil))
+}
+
+func TestFlushCaches(t *testing.T) {
+	h, _ := newTestHandler(t, server.WithCheckQueryCacheEnabled(true))
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/caches/flush", nil))
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/caches/flush?store_id=01GXSA8YR785C4FYS3C0RTG7B1", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, serve(t, h, http.MethodGet, "/admin/caches/flush", nil))
+}
+
+func TestTrimChangelog(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	var resp commands.TrimChangelogResponse
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/changelog/trim?older_than=24h", &resp))
+	require.Zero(t, resp.DeletedChanges)
+
+	require.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/admin/changelog/trim", nil))
+	require.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/admin/changelog/trim?older_than=-1h", nil))
+}
+
+func TestStoreStatistics(t *testing.T) {
+	h, svr := newTestHandler(t)
+
+	store, err := svr.CreateStore(context.Background(), &openfgav1.CreateStoreRequest{Name: "store"})
+	require.NoError(t, err)
+
+	var stats planner.Statistics
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/admin/stores/statistics?store_id="+store.GetId(), &stats))
+	require.True(t, stats.Complete)
+
+	require.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodGet, "/admin/stores/statistics", nil))
+	require.Equal(t, http.StatusNotFound, serve(t, h, http.MethodGet, "/admin/stores/statistics?store_id=01GXSA8YR785C4FYS3C0RTG7B1", nil))
+}
+
+func TestStoreStats(t *testing.T) {
+	h, svr := newTestHandler(t)
+
+	store, err := svr.CreateStore(context.Background(), &openfgav1.CreateStoreRequest{Name: "store"})
+	require.NoError(t, err)
+
+	var stats commands.GetStoreStatsResponse
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodGet, "/admin/stores/stats?store_id="+store.GetId(), &stats))
+	require.Empty(t, stats.Tuples)
+	require.Zero(t, stats.AuthorizationModels)
+
+	require.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodGet, "/admin/stores/stats", nil))
+	require.Equal(t, http.StatusNotFound, serve(t, h, http.MethodGet, "/admin/stores/stats?store_id=01GXSA8YR785C4FYS3C0RTG7B1", nil))
+}
+
+func TestRefreshTypesystems(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/typesystems/refresh", nil))
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/typesystems/refresh?store_id=01GXSA8YR785C4FYS3C0RTG7B1", nil))
+}
+
+func TestExperimentals(t *testing.T) {
+	h, svr := newTestHandler(t)
+
+	var resp struct {
+		Experimentals []server.ExperimentalFeatureFlag `json:"experimentals"`
+	}
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/experimentals?flag=feature&enabled=true", &resp))
+	require.Equal(t, []server.ExperimentalFeatureFlag{"feature"}, resp.Experimentals)
+	require.True(t, svr.IsExperimentallyEnabled("feature"))
+
+	require.Equal(t, http.StatusOK, serve(t, h, http.MethodPost, "/admin/experimentals?flag=feature&enabled=false", &resp))
+	require.Empty(t, resp.Experimentals)
+	require.False(t, svr.IsExperimentallyEnabled("feature"))
+
+	require.Equal(t, http.StatusBadRequest, serve(t, h, http.MethodPost, "/admin/experimentals?flag=feature", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, serve(t, h, http.MethodDelete, "/admin/experimentals", nil))
+}
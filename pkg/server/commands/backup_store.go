@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// backupFormatVersion is bumped whenever the backup archive format changes in a backward incompatible way.
+const backupFormatVersion = 1
+
+// The kinds of the records of a backup archive.
+const (
+	backupRecordHeader     = "header"
+	backupRecordModel      = "model"
+	backupRecordAssertions = "assertions"
+	backupRecordTuple      = "tuple"
+	backupRecordChange     = "change"
+	backupRecordTrailer    = "trailer"
+)
+
+var ErrInvalidBackup = errors.New("invalid backup archive")
+
+// backupRecord is a record of a backup archive. A backup archive is a gzip-compressed stream of JSON records,
+// one per line: a header, every authorization model (oldest first) each followed by its assertions, every
+// tuple, the changes that occurred while the tuples were read and a trailer. Protobuf messages are encoded
+// with the protobuf JSON mapping.
+type backupRecord struct {
+	Kind string `json:"kind"`
+
+	// header
+	Version int             `json:"version,omitempty"`
+	Store   json.RawMessage `json:"store,omitempty"`
+
+	// model
+	Model json.RawMessage `json:"model,omitempty"`
+
+	// assertions
+	AuthorizationModelID string          `json:"authorization_model_id,omitempty"`
+	Assertions           json.RawMessage `json:"assertions,omitempty"`
+
+	// tuple
+	Tuple json.RawMessage `json:"tuple,omitempty"`
+
+	// change
+	Change json.RawMessage `json:"change,omitempty"`
+
+	// trailer
+	Records int `json:"records,omitempty"`
+}
+
+// BackupStoreCommand writes a store (tuples, every authorization model version and assertions) to a
+// portable archive, see RestoreStoreCommand.
+type BackupStoreCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+}
+
+func NewBackupStoreCommand(datastore storage.OpenFGADatastore, logger logger.Logger) *BackupStoreCommand {
+	return &BackupStoreCommand{
+		datastore: datastore,
+		logger:    logger,
+	}
+}
+
+// Execute writes the backup archive of the store to w. The tuples are read while the store may be written to,
+// so the changes that occur while they are read are recorded in the archive as well and replayed on restore:
+// the restored store holds the tuples of the store at the end of the backup.
+func (c *BackupStoreCommand) Execute(ctx context.Context, storeID string, w io.Writer) error {
+	ctx, span := tracer.Start(ctx, "backupStore")
+	defer span.End()
+
+	store, err := c.datastore.GetStore(ctx, storeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.StoreIDNotFound
+		}
+
+		return serverErrors.HandleError("", err)
+	}
+
+	snapshot, err := changelogEnd(ctx, c.datastore, storeID, "", 0)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	bw := &backupWriter{enc: json.NewEncoder(gz)}
+
+	bw.writeProto(backupRecordHeader, store, func(r *backupRecord, raw json.RawMessage) {
+		r.Version = backupFormatVersion
+		r.Store = raw
+	})
+
+	if err := c.backupModels(ctx, storeID, bw); err != nil {
+		return err
+	}
+
+	var from string
+	for bw.err == nil {
+		tuples, token, err := c.datastore.ReadPage(ctx, storeID, nil, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+
+		for _, tuple := range tuples {
+			bw.writeProto(backupRecordTuple, tuple.GetKey(), func(r *backupRecord, raw json.RawMessage) { r.Tuple = raw })
+		}
+
+		if len(token) == 0 {
+			break
+		}
+		from = string(token)
+	}
+
+	from = snapshot
+	for bw.err == nil {
+		changes, token, err := c.datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: changelogPageSize, From: from}, 0)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				break
+			}
+
+			return serverErrors.HandleError("", err)
+		}
+
+		for _, change := range changes {
+			bw.writeProto(backupRecordChange, change, func(r *backupRecord, raw json.RawMessage) { r.Change = raw })
+		}
+
+		if len(changes) < changelogPageSize {
+			break
+		}
+		from = string(token)
+	}
+
+	bw.write(&backupRecord{Kind: backupRecordTrailer, Records: bw.records})
+	if bw.err != nil {
+		return serverErrors.NewInternalError("", bw.err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return serverErrors.NewInternalError("", err)
+	}
+
+	return nil
+}
+
+// backupModels writes every authorization model of the store, oldest first, each followed by its assertions.
+func (c *BackupStoreCommand) backupModels(ctx context.Context, storeID string, bw *backupWriter) error {
+	var models []*openfgav1.AuthorizationModel
+	var from string
+	for {
+		page, token, err := c.datastore.ReadAuthorizationModels(ctx, storeID, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+
+		models = append(models, page...)
+
+		if len(token) == 0 {
+			break
+		}
+		from = string(token)
+	}
+
+	// model ids are ULIDs, so they sort by creation time
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].GetId() < models[j].GetId()
+	})
+
+	for _, model := range models {
+		bw.writeProto(backupRecordModel, model, func(r *backupRecord, raw json.RawMessage) { r.Model = raw })
+
+		assertions, err := c.datastore.ReadAssertions(ctx, storeID, model.GetId())
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+
+		if len(assertions) > 0 {
+			bw.writeProto(backupRecordAssertions, &openfgav1.Assertions{Assertions: assertions}, func(r *backupRecord, raw json.RawMessage) {
+				r.AuthorizationModelID = model.GetId()
+				r.Assertions = raw
+			})
+		}
+	}
+
+	return nil
+}
+
+// backupWriter writes the records of a backup archive. Once a write fails, every following write is a
+// no-op and err holds the error.
+type backupWriter struct {
+	enc     *json.Encoder
+	records int
+	err     error
+}
+
+func (w *backupWriter) writeProto(kind string, msg proto.Message, set func(r *backupRecord, raw json.RawMessage)) {
+	if w.err != nil {
+		return
+	}
+
+	raw, err := protojson.Marshal(msg)
+	if err != nil {
+		w.err = err
+		return
+	}
+
+	r := &backupRecord{Kind: kind}
+	set(r, raw)
+	w.write(r)
+}
+
+func (w *backupWriter) write(r *backupRecord) {
+	if w.err != nil {
+		return
+	}
+
+	w.err = w.enc.Encode(r)
+	w.records++
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupAndRestoreStore(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "backup"})
+	require.NoError(t, err)
+	storeID := store.GetId()
+
+	var modelIDs []string
+	for _, relations := range []string{
+		`define viewer: [user] as self`,
+		`define viewer: [user] as self
+		    define editor: [user] as self`,
+	} {
+		model := &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: parser.MustParse(fmt.Sprintf(`
+		type user
+
+		type document
+		  relations
+		    %s
+		`, relations)),
+		}
+		require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+		modelIDs = append(modelIDs, model.GetId())
+	}
+
+	assertions := []*openfgav1.Assertion{{
+		TupleKey:    tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		Expectation: true,
+	}}
+	require.NoError(t, ds.WriteAssertions(ctx, storeID, modelIDs[0], assertions))
+
+	var tupleKeys []*openfgav1.TupleKey
+	for i := 0; i < 5; i++ {
+		tupleKeys = append(tupleKeys, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+	require.NoError(t, ds.Write(ctx, storeID, nil, tupleKeys))
+	require.NoError(t, ds.Write(ctx, storeID, tupleKeys[:1], nil))
+
+	var archive bytes.Buffer
+	err = NewBackupStoreCommand(ds, logger.NewNoopLogger()).Execute(ctx, storeID, &archive)
+	require.NoError(t, err)
+
+	restoreCmd := NewRestoreStoreCommand(ds, logger.NewNoopLogger())
+
+	t.Run("the_store_is_restored", func(t *testing.T) {
+		restored, err := restoreCmd.Execute(ctx, &RestoreStoreRequest{Archive: bytes.NewReader(archive.Bytes())})
+		require.NoError(t, err)
+		require.NotEqual(t, storeID, restored.GetId())
+		require.Equal(t, "backup", restored.GetName())
+
+		for _, modelID := range modelIDs {
+			_, err := ds.ReadAuthorizationModel(ctx, restored.GetId(), modelID)
+			require.NoError(t, err)
+		}
+
+		latest, err := ds.FindLatestAuthorizationModelID(ctx, restored.GetId())
+		require.NoError(t, err)
+		require.Equal(t, modelIDs[1], latest)
+
+		restoredAssertions, err := ds.ReadAssertions(ctx, restored.GetId(), modelIDs[0])
+		require.NoError(t, err)
+		require.Len(t, restoredAssertions, 1)
+		require.Equal(t, "document:1", restoredAssertions[0].GetTupleKey().GetObject())
+
+		tuples, _, err := ds.ReadPage(ctx, restored.GetId(), nil, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+
+		var objects []string
+		for _, tuple := range tuples {
+			objects = append(objects, tuple.GetKey().GetObject())
+		}
+		require.ElementsMatch(t, []string{"document:1", "document:2", "document:3", "document:4"}, objects)
+	})
+
+	t.Run("the_store_name_can_be_overridden", func(t *testing.T) {
+		restored, err := restoreCmd.Execute(ctx, &RestoreStoreRequest{Archive: bytes.NewReader(archive.Bytes()), Name: "copy"})
+		require.NoError(t, err)
+		require.Equal(t, "copy", restored.GetName())
+	})
+
+	t.Run("a_truncated_archive_is_rejected", func(t *testing.T) {
+		_, err := restoreCmd.Execute(ctx, &RestoreStoreRequest{Archive: bytes.NewReader(archive.Bytes()[:archive.Len()/2])})
+		require.ErrorContains(t, err, ErrInvalidBackup.Error())
+	})
+
+	t.Run("an_unknown_store_cannot_be_backed_up", func(t *testing.T) {
+		err := NewBackupStoreCommand(ds, logger.NewNoopLogger()).Execute(ctx, ulid.Make().String(), &bytes.Buffer{})
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+	})
+}
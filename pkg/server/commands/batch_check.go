@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultMaxChecksPerBatchCheck      = 50
+	defaultMaxConcurrentChecksPerBatch = 10
+)
+
+// BatchCheckRequest is a request to evaluate many Checks against the same store and authorization model.
+type BatchCheckRequest struct {
+	StoreID              string
+	AuthorizationModelID string
+
+	// TupleKeys are the (user, relation, object) tuples to check.
+	TupleKeys []*openfgav1.TupleKey
+
+	// ContextualTuples are shared by all the Checks in the batch.
+	ContextualTuples []*openfgav1.TupleKey
+}
+
+// BatchCheckResult is the outcome of a single Check in a BatchCheckRequest. If Err
+// is non-nil, Allowed must be ignored.
+type BatchCheckResult struct {
+	TupleKey *openfgav1.TupleKey
+	Allowed  bool
+	Err      error
+}
+
+// BatchCheckResponse contains one BatchCheckResult per tuple key in the request, in the same order.
+type BatchCheckResponse struct {
+	Results []*BatchCheckResult
+}
+
+// BatchCheckQuery evaluates many Checks concurrently. All the Checks in a batch share the same
+// resolved typesystem, contextual tuples and check resolver, and identical tuple keys in the same
+// batch are only resolved once.
+type BatchCheckQuery struct {
+	datastore               storage.RelationshipTupleReader
+	logger                  logger.Logger
+	resolveNodeLimit        uint32
+	resolveNodeBreadthLimit uint32
+	maxConcurrentReads      uint32
+	maxDispatchCount        uint32
+	maxDatastoreReads       uint32
+	maxChecksPerBatch       uint32
+	maxConcurrentChecks     uint32
+	checkCache              *graph.CheckCache
+}
+
+type BatchCheckQueryOption func(q *BatchCheckQuery)
+
+func WithBatchCheckLogger(l logger.Logger) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.logger = l
+	}
+}
+
+// WithBatchCheckResolveNodeLimit see server.WithResolveNodeLimit
+func WithBatchCheckResolveNodeLimit(limit uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.resolveNodeLimit = limit
+	}
+}
+
+// WithBatchCheckResolveNodeBreadthLimit see server.WithResolveNodeBreadthLimit
+func WithBatchCheckResolveNodeBreadthLimit(limit uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.resolveNodeBreadthLimit = limit
+	}
+}
+
+// WithBatchCheckMaxConcurrentReads see server.WithMaxConcurrentReadsForCheck
+func WithBatchCheckMaxConcurrentReads(max uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.maxConcurrentReads = max
+	}
+}
+
+// WithBatchCheckMaxDispatchCount see server.WithMaxDispatchCountPerCheck
+func WithBatchCheckMaxDispatchCount(max uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.maxDispatchCount = max
+	}
+}
+
+// WithBatchCheckMaxDatastoreReads see server.WithMaxDatastoreReadsPerCheck
+func WithBatchCheckMaxDatastoreReads(max uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.maxDatastoreReads = max
+	}
+}
+
+// WithMaxChecksPerBatch sets the maximum number of tuple keys that can be provided in a single BatchCheckRequest.
+func WithMaxChecksPerBatch(max uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.maxChecksPerBatch = max
+	}
+}
+
+// WithMaxConcurrentChecksPerBatch sets the maximum number of Checks of a single batch that are evaluated concurrently.
+func WithMaxConcurrentChecksPerBatch(max uint32) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.maxConcurrentChecks = max
+	}
+}
+
+// WithBatchCheckCache sets the cache used to look up and store the outcome of Check subproblems. If nil, no cache is used.
+func WithBatchCheckCache(cache *graph.CheckCache) BatchCheckQueryOption {
+	return func(q *BatchCheckQuery) {
+		q.checkCache = cache
+	}
+}
+
+func NewBatchCheckQuery(ds storage.RelationshipTupleReader, opts ...BatchCheckQueryOption) *BatchCheckQuery {
+	query := &BatchCheckQuery{
+		datastore:               ds,
+		logger:                  logger.NewNoopLogger(),
+		resolveNodeLimit:        defaultResolveNodeLimit,
+		resolveNodeBreadthLimit: defaultResolveNodeBreadthLimit,
+		maxConcurrentReads:      defaultMaxConcurrentReads,
+		maxChecksPerBatch:       defaultMaxChecksPerBatchCheck,
+		maxConcurrentChecks:     defaultMaxConcurrentChecksPerBatch,
+	}
+
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	return query
+}
+
+// Execute evaluates every tuple key in the request. The typesystem of the resolved
+// authorization model must be present in the context. Errors that only concern a single
+// tuple key (e.g. a validation error) are reported in its BatchCheckResult, while errors
+// that concern the whole batch (e.g. invalid contextual tuples) are returned directly.
+func (q *BatchCheckQuery) Execute(ctx context.Context, req *BatchCheckRequest) (*BatchCheckResponse, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		panic("typesystem missing in context")
+	}
+
+	if q.maxChecksPerBatch > 0 && len(req.TupleKeys) > int(q.maxChecksPerBatch) {
+		return nil, serverErrors.ExceededEntityLimit("checks in a batch", int(q.maxChecksPerBatch))
+	}
+
+	for _, ctxTuple := range req.ContextualTuples {
+		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
+			return nil, serverErrors.HandleTupleValidateError(err)
+		}
+	}
+
+	checkerOpts := []graph.LocalCheckerOption{
+		graph.WithResolveNodeBreadthLimit(q.resolveNodeBreadthLimit),
+		graph.WithMaxConcurrentReads(q.maxConcurrentReads),
+		graph.WithMaxDispatchCount(q.maxDispatchCount),
+		graph.WithMaxDatastoreReadsPerCheck(q.maxDatastoreReads),
+	}
+	if q.checkCache != nil {
+		checkerOpts = append(checkerOpts, graph.WithCheckCache(q.checkCache))
+	}
+
+	checkResolver := graph.NewLocalChecker(
+		storagewrappers.NewCombinedTupleReader(q.datastore, req.ContextualTuples),
+		checkerOpts...,
+	)
+
+	// identical tuple keys are only resolved once and share the same result
+	uniqueResults := map[string]*BatchCheckResult{}
+	results := make([]*BatchCheckResult, 0, len(req.TupleKeys))
+	for _, tk := range req.TupleKeys {
+		key := tuple.TupleKeyToString(tk)
+
+		result, ok := uniqueResults[key]
+		if !ok {
+			result = &BatchCheckResult{TupleKey: tk}
+			uniqueResults[key] = result
+		}
+
+		results = append(results, result)
+	}
+
+	g := new(errgroup.Group)
+	if q.maxConcurrentChecks > 0 {
+		g.SetLimit(int(q.maxConcurrentChecks))
+	}
+
+	for _, result := range uniqueResults {
+		result := result
+
+		g.Go(func() error {
+			result.Allowed, result.Err = q.check(ctx, checkResolver, typesys, req, result.TupleKey)
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	return &BatchCheckResponse{Results: results}, nil
+}
+
+// check evaluates a single tuple key of the batch.
+func (q *BatchCheckQuery) check(
+	ctx context.Context,
+	checkResolver graph.CheckResolver,
+	typesys *typesystem.TypeSystem,
+	req *BatchCheckRequest,
+	tk *openfgav1.TupleKey,
+) (bool, error) {
+	if tk.GetUser() == "" || tk.GetRelation() == "" || tk.GetObject() == "" {
+		return false, serverErrors.InvalidCheckInput
+	}
+
+	if err := validation.ValidateUserObjectRelation(typesys, tk); err != nil {
+		return false, serverErrors.ValidationError(err)
+	}
+
+	resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
+		StoreID:              req.StoreID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		TupleKey:             tk,
+		ContextualTuples:     req.ContextualTuples,
+		ResolutionMetadata: &graph.ResolutionMetadata{
+			Depth: q.resolveNodeLimit,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, graph.ErrResolutionDepthExceeded) {
+			return false, serverErrors.AuthorizationModelResolutionTooComplex
+		}
+
+		if errors.Is(err, graph.ErrCycleDetected) {
+			return false, serverErrors.CycleDetected
+		}
+
+		if errors.Is(err, graph.ErrResolutionLimitExceeded) {
+			return false, serverErrors.ResolutionLimitExceeded(err)
+		}
+
+		return false, serverErrors.HandleError("", err)
+	}
+
+	return resp.Allowed, nil
+}
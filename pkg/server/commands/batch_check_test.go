@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCheckQuery(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	}
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+	})
+	require.NoError(t, err)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+
+	t.Run("returns_one_result_per_tuple_key_in_order", func(t *testing.T) {
+		tupleKeys := []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+			tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+			tuple.NewTupleKey("document:2", "viewer", "user:bob"),
+			tuple.NewTupleKey("document:1", "editor", "user:anne"),
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		}
+
+		resp, err := NewBatchCheckQuery(ds).Execute(ctx, &BatchCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: model.Id,
+			TupleKeys:            tupleKeys,
+			ContextualTuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:2", "viewer", "user:bob"),
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, len(tupleKeys))
+
+		require.True(t, resp.Results[0].Allowed)
+		require.NoError(t, resp.Results[0].Err)
+
+		require.False(t, resp.Results[1].Allowed)
+		require.NoError(t, resp.Results[1].Err)
+
+		require.True(t, resp.Results[2].Allowed)
+		require.NoError(t, resp.Results[2].Err)
+
+		require.Error(t, resp.Results[3].Err)
+
+		require.True(t, resp.Results[4].Allowed)
+		require.NoError(t, resp.Results[4].Err)
+	})
+
+	t.Run("too_many_checks", func(t *testing.T) {
+		_, err := NewBatchCheckQuery(ds, WithMaxChecksPerBatch(1)).Execute(ctx, &BatchCheckRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: model.Id,
+			TupleKeys: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+				tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+			},
+		})
+		require.ErrorIs(t, err, serverErrors.ExceededEntityLimit("checks in a batch", 1))
+	})
+}
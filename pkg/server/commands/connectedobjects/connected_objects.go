@@ -15,6 +15,8 @@ import (
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -23,6 +25,11 @@ import (
 
 var tracer = otel.Tracer("openfga/pkg/server/commands/connected_objects")
 
+var reverseExpandWorkersInFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "reverse_expand_workers_in_flight",
+	Help: "Number of goroutines expanding the relationships of ListObjects queries concurrently, across all the queries. The fan-out of a query is bounded by the resolve node breadth limit",
+})
+
 const (
 	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
 	defaultResolveNodeLimit        = 25
@@ -36,6 +43,10 @@ type ConnectedObjectsRequest struct {
 	Relation         string
 	User             IsUserRef
 	ContextualTuples []*openfgav1.TupleKey
+
+	// targetRewrite, if set, restricts the expansion to one operand of the definition of the target relation,
+	// see reverseExpandSetOperation.
+	targetRewrite *openfgav1.Userset
 }
 
 type IsUserRef interface {
@@ -217,7 +228,25 @@ func (c *ConnectedObjectsQuery) execute(
 	span.SetAttributes(
 		attribute.String("_sourceUserRef", sourceUserRef.String()),
 		attribute.String("_targetObjRef", targetObjRef.String()))
-	ingresses, err := g.PrunedRelationshipIngresses(targetObjRef, sourceUserRef)
+
+	if req.targetRewrite == nil {
+		rewrite, err := c.setOperationRewrite(g, targetObjRef)
+		if err != nil {
+			return err
+		}
+
+		if rewrite != nil {
+			return c.reverseExpandSetOperation(ctx, req, rewrite, resultChan, foundObjectsMap, foundCount)
+		}
+	}
+
+	var ingresses []*graph.RelationshipIngress
+	var err error
+	if req.targetRewrite != nil {
+		ingresses, err = g.PrunedRelationshipIngressesForRewrite(targetObjRef, req.targetRewrite, sourceUserRef)
+	} else {
+		ingresses, err = g.PrunedRelationshipIngresses(targetObjRef, sourceUserRef)
+	}
 	if err != nil {
 		return err
 	}
@@ -228,11 +257,12 @@ func (c *ConnectedObjectsQuery) execute(
 	for i, ingress := range ingresses {
 		span.SetAttributes(attribute.String(fmt.Sprintf("_ingress %d", i), ingress.String()))
 		innerLoopIngress := ingress
-		subg.Go(func() error {
+		goWorker(subg, func() error {
 			r := &reverseExpandRequest{
 				storeID:          storeID,
 				ingress:          innerLoopIngress,
 				targetObjectRef:  targetObjRef,
+				targetRewrite:    req.targetRewrite,
 				sourceUserRef:    req.User,
 				contextualTuples: req.ContextualTuples,
 			}
@@ -254,6 +284,7 @@ func (c *ConnectedObjectsQuery) execute(
 						},
 					},
 					ContextualTuples: req.ContextualTuples,
+					targetRewrite:    req.targetRewrite,
 				}, resultChan, foundObjectsMap, foundCount)
 
 			case graph.TupleToUsersetIngress:
@@ -270,7 +301,8 @@ func (c *ConnectedObjectsQuery) execute(
 // Execute yields all the objects of the provided objectType that
 // the given user has a specific relation with. The results will be limited by the request
 // maxResults. If a 0 maxResults is provided then all objects of the provided objectType will be
-// returned.
+// returned. Sending a result blocks until it is received, or until the context is done, so the resolution
+// stops once ctx is cancelled.
 func (c *ConnectedObjectsQuery) Execute(
 	ctx context.Context,
 	req *ConnectedObjectsRequest,
@@ -296,6 +328,7 @@ type reverseExpandRequest struct {
 	storeID          string
 	ingress          *graph.RelationshipIngress
 	targetObjectRef  *openfgav1.RelationReference
+	targetRewrite    *openfgav1.Userset
 	sourceUserRef    IsUserRef
 	contextualTuples []*openfgav1.TupleKey
 }
@@ -390,9 +423,12 @@ func (c *ConnectedObjectsQuery) reverseExpandTupleToUserset(
 				resultStatus = RequiresFurtherEvalStatus
 			}
 
-			resultChan <- &ConnectedObjectsResult{
+			err := sendResult(ctx, resultChan, &ConnectedObjectsResult{
 				Object:       foundObject,
 				ResultStatus: resultStatus,
+			})
+			if err != nil {
+				return err
 			}
 		}
 
@@ -417,13 +453,14 @@ func (c *ConnectedObjectsQuery) reverseExpandTupleToUserset(
 			}
 		}
 
-		subg.Go(func() error {
+		goWorker(subg, func() error {
 			return c.execute(subgctx, &ConnectedObjectsRequest{
 				StoreID:          store,
 				ObjectType:       targetObjectType,
 				Relation:         targetObjectRel,
 				User:             sourceUserRef,
 				ContextualTuples: req.contextualTuples,
+				targetRewrite:    req.targetRewrite,
 			}, resultChan, foundObjectsMap, foundCount)
 		})
 	}
@@ -539,9 +576,12 @@ func (c *ConnectedObjectsQuery) reverseExpandDirect(
 				resultStatus = RequiresFurtherEvalStatus
 			}
 
-			resultChan <- &ConnectedObjectsResult{
+			err := sendResult(ctx, resultChan, &ConnectedObjectsResult{
 				Object:       foundObject,
 				ResultStatus: resultStatus,
+			})
+			if err != nil {
+				return err
 			}
 		}
 
@@ -552,16 +592,276 @@ func (c *ConnectedObjectsQuery) reverseExpandDirect(
 			},
 		}
 
-		subg.Go(func() error {
+		goWorker(subg, func() error {
 			return c.execute(subgctx, &ConnectedObjectsRequest{
 				StoreID:          store,
 				ObjectType:       targetObjectType,
 				Relation:         targetObjectRel,
 				User:             sourceUserRef,
 				ContextualTuples: req.contextualTuples,
+				targetRewrite:    req.targetRewrite,
 			}, resultChan, foundObjectsMap, foundCount)
 		})
 	}
 
 	return subg.Wait()
 }
+
+// setOperationRewrite returns the rewrite of the target relation if it is an intersection or an exclusion that can
+// be evaluated natively by reverseExpandSetOperation, or nil otherwise.
+//
+// Relations that are reachable from themselves (e.g. 'define viewer: [user, document#viewer] but not blocked') are
+// not evaluated natively, because the usersets found through the operands of the relation are usersets of the
+// whole relation. Those fall back to reverse expanding the first operand of the rewrite and requiring further
+// evaluation of the results.
+func (c *ConnectedObjectsQuery) setOperationRewrite(g *graph.ConnectedObjectGraph, target *openfgav1.RelationReference) (*openfgav1.Userset, error) {
+	relation, err := c.typesystem.GetRelation(target.GetType(), target.GetRelation())
+	if err != nil {
+		return nil, err
+	}
+
+	rewrite := relation.GetRewrite()
+	switch rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_Intersection, *openfgav1.Userset_Difference:
+	default:
+		return nil, nil
+	}
+
+	selfIngresses, err := g.RelationshipIngresses(target, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(selfIngresses) > 0 {
+		return nil, nil
+	}
+
+	return rewrite, nil
+}
+
+// reverseExpandSetOperation reverse expands each operand of an intersection or exclusion rewrite of the target
+// relation into a set of candidate objects, and then combines the candidate sets. For example, for
+// 'define admin: [user] as self but not blocked' the objects the user is blocked on are removed from the objects the
+// user is directly related to, instead of checking every one of the latter.
+//
+// A result only requires further evaluation if it was found through an operand that requires further evaluation
+// itself (e.g. a nested intersection reached through a tupleset).
+func (c *ConnectedObjectsQuery) reverseExpandSetOperation(
+	ctx context.Context,
+	req *ConnectedObjectsRequest,
+	rewrite *openfgav1.Userset,
+	resultChan chan<- *ConnectedObjectsResult,
+	foundObjectsMap *sync.Map,
+	foundCount *uint32,
+) error {
+	ctx, span := tracer.Start(ctx, "reverseExpandSetOperation", trace.WithAttributes(
+		attribute.String("object_type", req.ObjectType),
+		attribute.String("relation", req.Relation),
+		attribute.String("user", req.User.String()),
+	))
+	defer span.End()
+
+	objects, err := c.reverseExpandRewrite(ctx, req, rewrite)
+	if err != nil {
+		return err
+	}
+
+	for object, resultStatus := range objects {
+		if _, ok := foundObjectsMap.LoadOrStore(object, struct{}{}); ok {
+			continue
+		}
+
+		if foundCount != nil && atomic.AddUint32(foundCount, 1) > c.maxResults {
+			break
+		}
+
+		err := sendResult(ctx, resultChan, &ConnectedObjectsResult{
+			Object:       object,
+			ResultStatus: resultStatus,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// goWorker runs fn in the group, counting it in reverseExpandWorkersInFlightGauge while it runs.
+func goWorker(g *errgroup.Group, fn func() error) {
+	g.Go(func() error {
+		reverseExpandWorkersInFlightGauge.Inc()
+		defer reverseExpandWorkersInFlightGauge.Dec()
+
+		return fn()
+	})
+}
+
+// sendResult sends the result, unless the context is done first because the results are no longer consumed.
+func sendResult(ctx context.Context, resultChan chan<- *ConnectedObjectsResult, result *ConnectedObjectsResult) error {
+	select {
+	case resultChan <- result:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reverseExpandRewrite returns all the objects of the target type that the user may be related to through the
+// provided rewrite of the target relation, along with the status of each result.
+func (c *ConnectedObjectsQuery) reverseExpandRewrite(
+	ctx context.Context,
+	req *ConnectedObjectsRequest,
+	rewrite *openfgav1.Userset,
+) (map[string]ConditionalResultStatus, error) {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_ComputedUserset:
+		// e.g. 'but not blocked' is the set of objects the user is blocked on
+		return c.collect(ctx, &ConnectedObjectsRequest{
+			StoreID:          req.StoreID,
+			ObjectType:       req.ObjectType,
+			Relation:         rw.ComputedUserset.GetRelation(),
+			User:             req.User,
+			ContextualTuples: req.ContextualTuples,
+		})
+	case *openfgav1.Userset_Union:
+		operands, err := c.reverseExpandRewrites(ctx, req, rw.Union.GetChild())
+		if err != nil {
+			return nil, err
+		}
+
+		objects := map[string]ConditionalResultStatus{}
+		for _, operand := range operands {
+			for object, resultStatus := range operand {
+				if objects[object] != NoFurtherEvalStatus {
+					objects[object] = resultStatus
+				}
+			}
+		}
+
+		return objects, nil
+	case *openfgav1.Userset_Intersection:
+		operands, err := c.reverseExpandRewrites(ctx, req, rw.Intersection.GetChild())
+		if err != nil {
+			return nil, err
+		}
+
+		objects := map[string]ConditionalResultStatus{}
+
+	candidates:
+		for object, resultStatus := range operands[0] {
+			for _, operand := range operands[1:] {
+				operandStatus, ok := operand[object]
+				if !ok {
+					continue candidates
+				}
+
+				if operandStatus == RequiresFurtherEvalStatus {
+					resultStatus = RequiresFurtherEvalStatus
+				}
+			}
+
+			objects[object] = resultStatus
+		}
+
+		return objects, nil
+	case *openfgav1.Userset_Difference:
+		operands, err := c.reverseExpandRewrites(ctx, req, []*openfgav1.Userset{
+			rw.Difference.GetBase(),
+			rw.Difference.GetSubtract(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		objects := map[string]ConditionalResultStatus{}
+		for object, resultStatus := range operands[0] {
+			if subtractStatus, ok := operands[1][object]; ok {
+				if subtractStatus == NoFurtherEvalStatus {
+					continue
+				}
+
+				// the user may or may not be subtracted, so Check decides
+				resultStatus = RequiresFurtherEvalStatus
+			}
+
+			objects[object] = resultStatus
+		}
+
+		return objects, nil
+	default:
+		// direct relationships and tuple to usersets are expanded through the ingresses of the rewrite alone
+		return c.collect(ctx, &ConnectedObjectsRequest{
+			StoreID:          req.StoreID,
+			ObjectType:       req.ObjectType,
+			Relation:         req.Relation,
+			User:             req.User,
+			ContextualTuples: req.ContextualTuples,
+			targetRewrite:    rewrite,
+		})
+	}
+}
+
+// reverseExpandRewrites concurrently reverse expands each of the provided rewrites, see reverseExpandRewrite.
+func (c *ConnectedObjectsQuery) reverseExpandRewrites(
+	ctx context.Context,
+	req *ConnectedObjectsRequest,
+	rewrites []*openfgav1.Userset,
+) ([]map[string]ConditionalResultStatus, error) {
+	operands := make([]map[string]ConditionalResultStatus, len(rewrites))
+
+	subg, subgctx := errgroup.WithContext(ctx)
+	subg.SetLimit(int(c.resolveNodeBreadthLimit))
+
+	for i, rewrite := range rewrites {
+		i, rewrite := i, rewrite
+		goWorker(subg, func() error {
+			objects, err := c.reverseExpandRewrite(subgctx, req, rewrite)
+			if err != nil {
+				return err
+			}
+
+			operands[i] = objects
+			return nil
+		})
+	}
+
+	if err := subg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return operands, nil
+}
+
+// collect reverse expands the request and returns all the objects found, regardless of the max results, since a
+// partial operand of an intersection or exclusion would lead to wrong results.
+func (c *ConnectedObjectsQuery) collect(
+	ctx context.Context,
+	req *ConnectedObjectsRequest,
+) (map[string]ConditionalResultStatus, error) {
+	resultChan := make(chan *ConnectedObjectsResult, 1)
+	done := make(chan struct{})
+
+	objects := map[string]ConditionalResultStatus{}
+	go func() {
+		defer close(done)
+
+		for res := range resultChan {
+			if objects[res.Object] != NoFurtherEvalStatus {
+				objects[res.Object] = res.ResultStatus
+			}
+		}
+	}()
+
+	var foundObjects sync.Map
+	err := c.execute(ctx, req, resultChan, &foundObjects, nil)
+
+	close(resultChan)
+	<-done
+
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
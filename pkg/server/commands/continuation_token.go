@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/openfga/openfga/pkg/encoder"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// decodePaginationToken decodes a continuation token issued by an API of the kind, and returns the pagination state
+// of the datastore it carries. The tokens issued before the tokens were versioned have no kind and are accepted by
+// every API.
+func decodePaginationToken(e encoder.Encoder, kind, contToken string) (string, error) {
+	decoded, err := e.Decode(contToken)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := encoder.UnmarshalContinuationToken(decoded)
+	if err != nil {
+		return "", err
+	}
+
+	if token.Kind != "" && token.Kind != kind {
+		return "", fmt.Errorf("the continuation token was issued for '%s', not '%s'", token.Kind, kind)
+	}
+
+	return string(token.Pagination), nil
+}
+
+// ExpectedChangelogTokenOption returns the option of a write that is only applied if the changelog of the store has not
+// changed since the continuation token, returned by a ReadChanges that read the latest change of the store. See
+// storage.WithExpectedChangelogToken.
+func ExpectedChangelogTokenOption(e encoder.Encoder, contToken string) (storage.TupleWriteOption, error) {
+	pagination, err := decodePaginationToken(e, encoder.TokenKindChanges, contToken)
+	if err != nil {
+		return nil, serverErrors.InvalidContinuationToken
+	}
+
+	return storage.WithExpectedChangelogToken(pagination), nil
+}
+
+// encodePaginationToken marshals the pagination state of the datastore as a continuation token of the kind with the
+// codec, and encodes it.
+func encodePaginationToken(e encoder.Encoder, codec encoder.TokenCodec, kind string, pagination []byte) (string, error) {
+	marshalled, err := codec.Marshal(encoder.ContinuationToken{Kind: kind, Pagination: pagination})
+	if err != nil {
+		return "", err
+	}
+
+	return e.Encode(marshalled)
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginationTokens(t *testing.T) {
+	e := encoder.NewBase64Encoder()
+	pagination := []byte(`{"ulid":"01H8Y1HVCB2E4J6Y0E2VD3W3QA"}`)
+
+	t.Run("versioned_tokens_are_bound_to_their_kind", func(t *testing.T) {
+		token, err := encodePaginationToken(e, encoder.NewProtoTokenCodec(), encoder.TokenKindRead, pagination)
+		require.NoError(t, err)
+
+		got, err := decodePaginationToken(e, encoder.TokenKindRead, token)
+		require.NoError(t, err)
+		require.Equal(t, string(pagination), got)
+
+		_, err = decodePaginationToken(e, encoder.TokenKindChanges, token)
+		require.Error(t, err)
+	})
+
+	t.Run("raw_tokens_are_accepted_by_every_kind", func(t *testing.T) {
+		token, err := encodePaginationToken(e, encoder.NewRawTokenCodec(), encoder.TokenKindRead, pagination)
+		require.NoError(t, err)
+
+		legacy, err := e.Encode(pagination)
+		require.NoError(t, err)
+		require.Equal(t, legacy, token)
+
+		for _, kind := range []string{encoder.TokenKindRead, encoder.TokenKindChanges} {
+			got, err := decodePaginationToken(e, kind, token)
+			require.NoError(t, err)
+			require.Equal(t, string(pagination), got)
+		}
+	})
+
+	t.Run("empty_tokens_are_empty_pagination", func(t *testing.T) {
+		token, err := encodePaginationToken(e, encoder.NewProtoTokenCodec(), encoder.TokenKindChanges, nil)
+		require.NoError(t, err)
+		require.Empty(t, token)
+
+		got, err := decodePaginationToken(e, encoder.TokenKindChanges, "")
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// DeleteAuthorizationModelCommand deletes an authorization model of a store along with its annotations and
+// assertions. The active model of the store, which the requests without an authorization model id are evaluated
+// against, cannot be deleted: it is the pinned model of the store, or else its latest model.
+type DeleteAuthorizationModelCommand struct {
+	backend storage.AuthorizationModelBackend
+	logger  logger.Logger
+}
+
+func NewDeleteAuthorizationModelCommand(backend storage.AuthorizationModelBackend, logger logger.Logger) *DeleteAuthorizationModelCommand {
+	return &DeleteAuthorizationModelCommand{
+		backend: backend,
+		logger:  logger,
+	}
+}
+
+func (c *DeleteAuthorizationModelCommand) Execute(ctx context.Context, storeID, modelID string) error {
+	if _, err := ulid.Parse(modelID); err != nil {
+		return serverErrors.AuthorizationModelNotFound(modelID)
+	}
+
+	activeModelID, err := activeAuthorizationModelID(ctx, c.backend, storeID)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	if modelID == activeModelID {
+		return serverErrors.ValidationError(fmt.Errorf("the authorization model '%s' is the active model of the store and cannot be deleted", modelID))
+	}
+
+	err = c.backend.DeleteAuthorizationModel(ctx, storeID, modelID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.AuthorizationModelNotFound(modelID)
+		}
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// activeAuthorizationModelID returns the id of the pinned model of the store, or else of its latest model, or
+// an empty string if the store has no model.
+func activeAuthorizationModelID(ctx context.Context, backend storage.AuthorizationModelReadBackend, storeID string) (string, error) {
+	modelID, err := backend.ReadPinnedAuthorizationModelID(ctx, storeID)
+	if err != nil || modelID != "" {
+		return modelID, err
+	}
+
+	modelID, err = backend.FindLatestAuthorizationModelID(ctx, storeID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	}
+
+	return modelID, err
+}
+
+// PruneAuthorizationModelsRequest is a request to delete the old authorization models of a store.
+type PruneAuthorizationModelsRequest struct {
+	StoreID string
+
+	// RetainLatest is the number of the newest models of the store which are retained. It must be at least 1.
+	RetainLatest int
+
+	// Archive, if set, receives the pruned models and their assertions before they are deleted, as a
+	// gzip-compressed stream of JSON records in the format of the backup archives (see BackupStoreCommand).
+	Archive io.Writer
+
+	// DryRun lists the models which would be pruned without archiving or deleting them.
+	DryRun bool
+}
+
+type PruneAuthorizationModelsResponse struct {
+	// PrunedAuthorizationModelIDs are the ids of the pruned models, oldest first.
+	PrunedAuthorizationModelIDs []string `json:"pruned_authorization_model_ids"`
+}
+
+// PruneAuthorizationModelsCommand deletes the authorization models of a store but its newest ones, which keeps
+// the model versions from accumulating forever. The pinned model of the store is always retained.
+type PruneAuthorizationModelsCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+}
+
+func NewPruneAuthorizationModelsCommand(datastore storage.OpenFGADatastore, logger logger.Logger) *PruneAuthorizationModelsCommand {
+	return &PruneAuthorizationModelsCommand{
+		datastore: datastore,
+		logger:    logger,
+	}
+}
+
+func (c *PruneAuthorizationModelsCommand) Execute(ctx context.Context, req *PruneAuthorizationModelsRequest) (*PruneAuthorizationModelsResponse, error) {
+	ctx, span := tracer.Start(ctx, "pruneAuthorizationModels")
+	defer span.End()
+
+	if req.RetainLatest < 1 {
+		return nil, serverErrors.ValidationError(fmt.Errorf("at least the latest authorization model must be retained"))
+	}
+
+	var models []*openfgav1.AuthorizationModel
+	var from string
+	for {
+		page, token, err := c.datastore.ReadAuthorizationModels(ctx, req.StoreID, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		models = append(models, page...)
+
+		if len(token) == 0 {
+			break
+		}
+		from = string(token)
+	}
+
+	pinnedModelID, err := c.datastore.ReadPinnedAuthorizationModelID(ctx, req.StoreID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	// model ids are ULIDs, so they sort by creation time
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].GetId() < models[j].GetId()
+	})
+
+	var pruned []*openfgav1.AuthorizationModel
+	if len(models) > req.RetainLatest {
+		for _, model := range models[:len(models)-req.RetainLatest] {
+			if model.GetId() != pinnedModelID {
+				pruned = append(pruned, model)
+			}
+		}
+	}
+
+	res := &PruneAuthorizationModelsResponse{PrunedAuthorizationModelIDs: make([]string, 0, len(pruned))}
+	for _, model := range pruned {
+		res.PrunedAuthorizationModelIDs = append(res.PrunedAuthorizationModelIDs, model.GetId())
+	}
+
+	if req.DryRun || len(pruned) == 0 {
+		return res, nil
+	}
+
+	if req.Archive != nil {
+		if err := c.archive(ctx, req.StoreID, pruned, req.Archive); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, model := range pruned {
+		err := c.datastore.DeleteAuthorizationModel(ctx, req.StoreID, model.GetId())
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.HandleError("", err)
+		}
+	}
+
+	c.logger.InfoWithContext(ctx, "pruned authorization models",
+		zap.String("store_id", req.StoreID),
+		zap.Int("count", len(pruned)),
+	)
+
+	return res, nil
+}
+
+// archive writes the models, each followed by its assertions, to w.
+func (c *PruneAuthorizationModelsCommand) archive(ctx context.Context, storeID string, models []*openfgav1.AuthorizationModel, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	bw := &backupWriter{enc: json.NewEncoder(gz)}
+
+	for _, model := range models {
+		bw.writeProto(backupRecordModel, model, func(r *backupRecord, raw json.RawMessage) { r.Model = raw })
+
+		assertions, err := c.datastore.ReadAssertions(ctx, storeID, model.GetId())
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+
+		if len(assertions) > 0 {
+			bw.writeProto(backupRecordAssertions, &openfgav1.Assertions{Assertions: assertions}, func(r *backupRecord, raw json.RawMessage) {
+				r.AuthorizationModelID = model.GetId()
+				r.Assertions = raw
+			})
+		}
+	}
+
+	if bw.err != nil {
+		return serverErrors.NewInternalError("", bw.err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return serverErrors.NewInternalError("", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAuthorizationModel(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+	writeModel := func() string {
+		model := &openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+		}
+		require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+		return model.GetId()
+	}
+
+	oldModelID := writeModel()
+	pinnedModelID := writeModel()
+	latestModelID := writeModel()
+
+	c := NewDeleteAuthorizationModelCommand(ds, logger.NewNoopLogger())
+
+	t.Run("the_latest_model_cannot_be_deleted_if_no_model_is_pinned", func(t *testing.T) {
+		err := c.Execute(ctx, storeID, latestModelID)
+		require.ErrorContains(t, err, "is the active model of the store")
+	})
+
+	t.Run("the_pinned_model_cannot_be_deleted", func(t *testing.T) {
+		require.NoError(t, ds.PinAuthorizationModel(ctx, storeID, pinnedModelID))
+
+		err := c.Execute(ctx, storeID, pinnedModelID)
+		require.ErrorContains(t, err, "is the active model of the store")
+	})
+
+	t.Run("the_other_models_are_deleted", func(t *testing.T) {
+		require.NoError(t, c.Execute(ctx, storeID, oldModelID))
+
+		// the latest model is staged while another model is pinned
+		require.NoError(t, c.Execute(ctx, storeID, latestModelID))
+
+		err := c.Execute(ctx, storeID, oldModelID)
+		require.ErrorIs(t, err, serverErrors.AuthorizationModelNotFound(oldModelID))
+	})
+}
+
+func TestPruneAuthorizationModels(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+	var modelIDs []string
+	for i := 0; i < 5; i++ {
+		model := &openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+		}
+		require.NoError(t, ds.WriteAuthorizationModel(ctx, storeID, model))
+		modelIDs = append(modelIDs, model.GetId())
+	}
+
+	require.NoError(t, ds.WriteAssertions(ctx, storeID, modelIDs[0], []*openfgav1.Assertion{
+		{TupleKey: &openfgav1.TupleKey{Object: "user:jon", Relation: "viewer", User: "user:jon"}},
+	}))
+	require.NoError(t, ds.PinAuthorizationModel(ctx, storeID, modelIDs[1]))
+
+	c := NewPruneAuthorizationModelsCommand(ds, logger.NewNoopLogger())
+
+	_, err := c.Execute(ctx, &PruneAuthorizationModelsRequest{StoreID: storeID})
+	require.ErrorContains(t, err, "at least the latest authorization model must be retained")
+
+	res, err := c.Execute(ctx, &PruneAuthorizationModelsRequest{StoreID: storeID, RetainLatest: 2, DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{modelIDs[0], modelIDs[2]}, res.PrunedAuthorizationModelIDs)
+
+	var archive bytes.Buffer
+	res, err = c.Execute(ctx, &PruneAuthorizationModelsRequest{StoreID: storeID, RetainLatest: 2, Archive: &archive})
+	require.NoError(t, err)
+	require.Equal(t, []string{modelIDs[0], modelIDs[2]}, res.PrunedAuthorizationModelIDs)
+
+	// the pinned model and the newest models are retained
+	models, _, err := ds.ReadAuthorizationModels(ctx, storeID, storage.PaginationOptions{PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, models, 3)
+
+	// the archive holds the pruned models and their assertions
+	gz, err := gzip.NewReader(&archive)
+	require.NoError(t, err)
+
+	var kinds []string
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		var record backupRecord
+		require.NoError(t, dec.Decode(&record))
+		kinds = append(kinds, record.Kind)
+	}
+	require.Equal(t, []string{backupRecordModel, backupRecordAssertions, backupRecordModel}, kinds)
+
+	res, err = c.Execute(ctx, &PruneAuthorizationModelsRequest{StoreID: storeID, RetainLatest: 2})
+	require.NoError(t, err)
+	require.Empty(t, res.PrunedAuthorizationModelIDs)
+}
@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"go.uber.org/zap"
+)
+
+// DeleteOrphanedTuplesRequest is a request to delete the tuples of a store which reference types or relations
+// that are not defined in an authorization model.
+type DeleteOrphanedTuplesRequest struct {
+	StoreID string
+
+	// AuthorizationModelID is the authorization model the tuples are checked against, or the latest one of the
+	// store if empty.
+	AuthorizationModelID string
+
+	// DryRun reports the orphaned tuples without deleting them.
+	DryRun bool
+}
+
+type DeleteOrphanedTuplesResponse struct {
+	AuthorizationModelID string `json:"authorization_model_id"`
+	TuplesScanned        int    `json:"tuples_scanned"`
+
+	// OrphanedTupleCount is the number of orphaned tuples, of which at most the maximum number of reported
+	// orphaned tuples are listed in OrphanedTuples.
+	OrphanedTupleCount int             `json:"orphaned_tuple_count"`
+	OrphanedTuples     []*InvalidTuple `json:"orphaned_tuples"`
+
+	// DeletedTuples is the number of orphaned tuples deleted, which is 0 for a dry run.
+	DeletedTuples int `json:"deleted_tuples"`
+}
+
+// DeleteOrphanedTuplesCommand scans the tuples of a store for the tuples left behind by model changes, that is the
+// tuples whose object type, relation, user type or userset relation is no longer defined, and deletes them in
+// batches. Unlike the AuthorizationModelImpactQuery, it ignores the tuples which only violate a type restriction.
+type DeleteOrphanedTuplesCommand struct {
+	datastore         storage.OpenFGADatastore
+	logger            logger.Logger
+	checkCache        *graph.CheckCache
+	batchSize         int
+	maxReportedTuples int
+}
+
+type DeleteOrphanedTuplesCommandOption func(c *DeleteOrphanedTuplesCommand)
+
+// WithDeleteOrphanedTuplesBatchSize sets the maximum number of tuples deleted in a single write. It is capped by the
+// maximum number of tuples per write of the datastore.
+func WithDeleteOrphanedTuplesBatchSize(batchSize int) DeleteOrphanedTuplesCommandOption {
+	return func(c *DeleteOrphanedTuplesCommand) {
+		c.batchSize = batchSize
+	}
+}
+
+// WithMaxReportedOrphanedTuples sets the maximum number of orphaned tuples listed in the response. The orphaned
+// tuples beyond this number are only counted.
+func WithMaxReportedOrphanedTuples(max int) DeleteOrphanedTuplesCommandOption {
+	return func(c *DeleteOrphanedTuplesCommand) {
+		c.maxReportedTuples = max
+	}
+}
+
+// WithDeleteOrphanedTuplesCheckCache sets the check cache that is invalidated after every deleted batch.
+func WithDeleteOrphanedTuplesCheckCache(cache *graph.CheckCache) DeleteOrphanedTuplesCommandOption {
+	return func(c *DeleteOrphanedTuplesCommand) {
+		c.checkCache = cache
+	}
+}
+
+func NewDeleteOrphanedTuplesCommand(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...DeleteOrphanedTuplesCommandOption) *DeleteOrphanedTuplesCommand {
+	c := &DeleteOrphanedTuplesCommand{
+		datastore:         datastore,
+		logger:            logger,
+		batchSize:         datastore.MaxTuplesPerWrite(),
+		maxReportedTuples: defaultMaxReportedInvalidTuples,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.batchSize <= 0 || c.batchSize > datastore.MaxTuplesPerWrite() {
+		c.batchSize = datastore.MaxTuplesPerWrite()
+	}
+
+	return c
+}
+
+// Execute scans the store and deletes its orphaned tuples. The typesystem of the authorization model the tuples are
+// checked against must be present in the context.
+func (c *DeleteOrphanedTuplesCommand) Execute(ctx context.Context, req *DeleteOrphanedTuplesRequest) (*DeleteOrphanedTuplesResponse, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		panic("typesystem missing in context")
+	}
+
+	resp := &DeleteOrphanedTuplesResponse{
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		OrphanedTuples:       []*InvalidTuple{},
+	}
+
+	// the tuples deleted must still exist, so they are not read from a read replica
+	iter, err := c.datastore.Read(storage.ContextWithStrongConsistency(ctx), req.StoreID, nil)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+	defer iter.Stop()
+
+	batch := make([]*openfgav1.TupleKey, 0, c.batchSize)
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		resp.TuplesScanned++
+
+		reason, orphaned := orphanedReason(typesys, t.GetKey())
+		if !orphaned {
+			continue
+		}
+
+		resp.OrphanedTupleCount++
+		if len(resp.OrphanedTuples) < c.maxReportedTuples {
+			resp.OrphanedTuples = append(resp.OrphanedTuples, &InvalidTuple{TupleKey: t.GetKey(), Reason: reason})
+		}
+
+		if req.DryRun {
+			continue
+		}
+
+		batch = append(batch, tuple.NewTupleKey(t.GetKey().GetObject(), t.GetKey().GetRelation(), t.GetKey().GetUser()))
+		if len(batch) == c.batchSize {
+			if err := c.deleteBatch(ctx, req.StoreID, batch); err != nil {
+				return nil, err
+			}
+			resp.DeletedTuples += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		if err := c.deleteBatch(ctx, req.StoreID, batch); err != nil {
+			return nil, err
+		}
+		resp.DeletedTuples += len(batch)
+	}
+
+	c.logger.InfoWithContext(ctx, "scanned the store for orphaned tuples",
+		zap.String("store_id", req.StoreID),
+		zap.String("authorization_model_id", resp.AuthorizationModelID),
+		zap.Bool("dry_run", req.DryRun),
+		zap.Int("tuples_scanned", resp.TuplesScanned),
+		zap.Int("orphaned_tuples", resp.OrphanedTupleCount),
+		zap.Int("deleted_tuples", resp.DeletedTuples),
+	)
+
+	return resp, nil
+}
+
+func (c *DeleteOrphanedTuplesCommand) deleteBatch(ctx context.Context, storeID string, batch []*openfgav1.TupleKey) error {
+	if err := c.datastore.Write(ctx, storeID, batch, nil); err != nil {
+		// a tuple deleted concurrently fails the whole batch, which a rerun completes
+		return serverErrors.HandleError("", err)
+	}
+
+	if c.checkCache != nil {
+		if err := c.checkCache.InvalidateStore(ctx, storeID); err != nil {
+			c.logger.WarnWithContext(ctx, "failed to invalidate the check cache of the store", zap.String("store_id", storeID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// orphanedReason returns why a tuple is orphaned under the typesystem, if it references a type or relation which is
+// not defined.
+func orphanedReason(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) (string, bool) {
+	objectType := tuple.GetType(tk.GetObject())
+	if _, ok := typesys.GetTypeDefinition(objectType); !ok {
+		return fmt.Sprintf("the type '%s' is not defined", objectType), true
+	}
+
+	if _, err := typesys.GetRelation(objectType, tk.GetRelation()); err != nil {
+		return fmt.Sprintf("the relation '%s' is not defined", tuple.ToObjectRelationString(objectType, tk.GetRelation())), true
+	}
+
+	// untyped users of schema 1.0 models have no type to check
+	userObject, userRelation := tuple.SplitObjectRelation(tk.GetUser())
+	userType := tuple.GetType(userObject)
+	if userType == "" {
+		return "", false
+	}
+
+	if _, ok := typesys.GetTypeDefinition(userType); !ok {
+		return fmt.Sprintf("the user type '%s' is not defined", userType), true
+	}
+
+	if userRelation != "" {
+		if _, err := typesys.GetRelation(userType, userRelation); err != nil {
+			return fmt.Sprintf("the relation '%s' of the userset is not defined", tuple.ToObjectRelationString(userType, userRelation)), true
+		}
+	}
+
+	return "", false
+}
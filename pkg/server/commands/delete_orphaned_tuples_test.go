@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteOrphanedTuplesCommand(t *testing.T) {
+	ctx := context.Background()
+
+	typesys := typesystem.New(&openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type group
+		  relations
+		    define member: [user] as self
+
+		type document
+		  relations
+		    define viewer: [user, group#member] as self
+		`),
+	})
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	valid := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:eng", "member", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+	}
+
+	orphaned := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("folder:x", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "editor", "user:anne"),
+		tuple.NewTupleKey("document:2", "viewer", "employee:carl"),
+		tuple.NewTupleKey("document:2", "viewer", "group:eng#admin"),
+	}
+
+	setup := func(t *testing.T) (storage.OpenFGADatastore, string) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		storeID := ulid.Make().String()
+		err := ds.Write(ctx, storeID, nil, append(append([]*openfgav1.TupleKey{}, valid...), orphaned...))
+		require.NoError(t, err)
+
+		return ds, storeID
+	}
+
+	readAll := func(t *testing.T, ds storage.OpenFGADatastore, storeID string) []*openfgav1.TupleKey {
+		iter, err := ds.Read(ctx, storeID, nil)
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var tupleKeys []*openfgav1.TupleKey
+		for {
+			tup, err := iter.Next()
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return tupleKeys
+			}
+			tupleKeys = append(tupleKeys, tup.GetKey())
+		}
+	}
+
+	t.Run("dry_run_reports_the_orphaned_tuples", func(t *testing.T) {
+		ds, storeID := setup(t)
+
+		resp, err := NewDeleteOrphanedTuplesCommand(ds, logger.NewNoopLogger()).Execute(ctx, &DeleteOrphanedTuplesRequest{
+			StoreID: storeID,
+			DryRun:  true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, typesys.GetAuthorizationModelID(), resp.AuthorizationModelID)
+		require.Equal(t, len(valid)+len(orphaned), resp.TuplesScanned)
+		require.Equal(t, len(orphaned), resp.OrphanedTupleCount)
+		require.Zero(t, resp.DeletedTuples)
+
+		reasons := make([]string, 0, len(resp.OrphanedTuples))
+		for _, orphan := range resp.OrphanedTuples {
+			reasons = append(reasons, orphan.Reason)
+		}
+		require.ElementsMatch(t, []string{
+			"the type 'folder' is not defined",
+			"the relation 'document#editor' is not defined",
+			"the user type 'employee' is not defined",
+			"the relation 'group#admin' of the userset is not defined",
+		}, reasons)
+
+		require.Len(t, readAll(t, ds, storeID), len(valid)+len(orphaned))
+	})
+
+	t.Run("orphaned_tuples_are_deleted_in_batches", func(t *testing.T) {
+		ds, storeID := setup(t)
+
+		resp, err := NewDeleteOrphanedTuplesCommand(ds, logger.NewNoopLogger(),
+			WithDeleteOrphanedTuplesBatchSize(3),
+			WithMaxReportedOrphanedTuples(1),
+		).Execute(ctx, &DeleteOrphanedTuplesRequest{StoreID: storeID})
+		require.NoError(t, err)
+		require.Equal(t, len(orphaned), resp.OrphanedTupleCount)
+		require.Len(t, resp.OrphanedTuples, 1)
+		require.Equal(t, len(orphaned), resp.DeletedTuples)
+
+		remaining := make([]string, 0, len(valid))
+		for _, tk := range readAll(t, ds, storeID) {
+			remaining = append(remaining, tuple.TupleKeyToString(tk))
+		}
+
+		expected := make([]string, 0, len(valid))
+		for _, tk := range valid {
+			expected = append(expected, tuple.TupleKeyToString(tk))
+		}
+		require.ElementsMatch(t, expected, remaining)
+	})
+}
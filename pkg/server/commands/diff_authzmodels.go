@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/typesystem"
+)
+
+// DiffAuthorizationModelsRequest is a request to compare two authorization models of a store.
+type DiffAuthorizationModelsRequest struct {
+	StoreID                  string
+	FromAuthorizationModelID string
+	ToAuthorizationModelID   string
+}
+
+// DiffAuthorizationModelsQuery returns the structured difference between two authorization models of a store.
+// See typesystem.Diff.
+type DiffAuthorizationModelsQuery struct {
+	backend storage.AuthorizationModelReadBackend
+	logger  logger.Logger
+}
+
+func NewDiffAuthorizationModelsQuery(backend storage.AuthorizationModelReadBackend, logger logger.Logger) *DiffAuthorizationModelsQuery {
+	return &DiffAuthorizationModelsQuery{backend: backend, logger: logger}
+}
+
+func (q *DiffAuthorizationModelsQuery) Execute(ctx context.Context, req *DiffAuthorizationModelsRequest) (*typesystem.ModelDiff, error) {
+	from, err := q.readModel(ctx, req.StoreID, req.FromAuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := q.readModel(ctx, req.StoreID, req.ToAuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return typesystem.Diff(from, to), nil
+}
+
+func (q *DiffAuthorizationModelsQuery) readModel(ctx context.Context, storeID, modelID string) (*openfgav1.AuthorizationModel, error) {
+	model, err := q.backend.ReadAuthorizationModel(ctx, storeID, modelID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.AuthorizationModelNotFound(modelID)
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return model, nil
+}
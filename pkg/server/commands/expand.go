@@ -9,20 +9,55 @@ import (
 	"github.com/openfga/openfga/pkg/logger"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
 	tupleUtils "github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
 )
 
 // ExpandQuery resolves a target TupleKey into a UsersetTree by expanding type definitions.
 type ExpandQuery struct {
-	logger    logger.Logger
-	datastore storage.OpenFGADatastore
+	logger           logger.Logger
+	datastore        storage.OpenFGADatastore
+	tupleReader      storage.RelationshipTupleReader
+	depth            uint32
+	contextualTuples []*openfgav1.TupleKey
+}
+
+type ExpandQueryOption func(q *ExpandQuery)
+
+// WithExpandDepth sets how many levels of usersets are expanded. With a depth of 1 (the default) only the
+// target userset is expanded, and its leaves reference the usersets it is computed from (computed usersets,
+// tuple to usersets and usersets assigned directly). With a greater depth those usersets are expanded as
+// well, recursively, until depth levels of usersets have been expanded: a leaf referencing a userset is
+// then replaced by a union node holding the expansion of that userset.
+func WithExpandDepth(depth uint32) ExpandQueryOption {
+	return func(q *ExpandQuery) {
+		q.depth = depth
+	}
+}
+
+// WithExpandContextualTuples sets tuples that are considered in addition to the tuples in the datastore
+// while expanding, so that callers can preview the effect of hypothetical tuples on the expansion tree.
+// The contextual tuples are validated against the authorization model of the request.
+func WithExpandContextualTuples(tupleKeys []*openfgav1.TupleKey) ExpandQueryOption {
+	return func(q *ExpandQuery) {
+		q.contextualTuples = tupleKeys
+	}
 }
 
 // NewExpandQuery creates a new ExpandQuery using the supplied backends for retrieving data.
-func NewExpandQuery(datastore storage.OpenFGADatastore, logger logger.Logger) *ExpandQuery {
-	return &ExpandQuery{logger: logger, datastore: datastore}
+func NewExpandQuery(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...ExpandQueryOption) *ExpandQuery {
+	q := &ExpandQuery{logger: logger, datastore: datastore, depth: 1}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	q.tupleReader = storagewrappers.NewCombinedTupleReader(datastore, q.contextualTuples)
+
+	return q
 }
 
 func (q *ExpandQuery) Execute(ctx context.Context, req *openfgav1.ExpandRequest) (*openfgav1.ExpandResponse, error) {
@@ -60,6 +95,12 @@ func (q *ExpandQuery) Execute(ctx context.Context, req *openfgav1.ExpandRequest)
 		return nil, serverErrors.ValidationError(err)
 	}
 
+	for _, ctxTuple := range q.contextualTuples {
+		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
+			return nil, serverErrors.HandleTupleValidateError(err)
+		}
+	}
+
 	err = validation.ValidateRelation(typesys, tk)
 	if err != nil {
 		return nil, serverErrors.ValidationError(err)
@@ -86,6 +127,17 @@ func (q *ExpandQuery) Execute(ctx context.Context, req *openfgav1.ExpandRequest)
 		return nil, err
 	}
 
+	if q.depth > 1 {
+		state := &expansion{
+			path:     map[string]struct{}{toObjectRelation(tk): {}},
+			resolved: map[string]*openfgav1.UsersetTree_Node{},
+		}
+		root, err = q.expandNode(ctx, store, root, typesys, q.depth-1, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &openfgav1.ExpandResponse{
 		Tree: &openfgav1.UsersetTree{
 			Root: root,
@@ -126,7 +178,7 @@ func (q *ExpandQuery) resolveThis(ctx context.Context, store string, tk *openfga
 	ctx, span := tracer.Start(ctx, "resolveThis")
 	defer span.End()
 
-	tupleIter, err := q.datastore.Read(ctx, store, tk)
+	tupleIter, err := q.tupleReader.Read(ctx, store, tk)
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
@@ -236,7 +288,7 @@ func (q *ExpandQuery) resolveTupleToUserset(
 		tsKey.Relation = tk.GetRelation()
 	}
 
-	tupleIter, err := q.datastore.Read(ctx, store, tsKey)
+	tupleIter, err := q.tupleReader.Read(ctx, store, tsKey)
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
@@ -402,6 +454,178 @@ func (q *ExpandQuery) resolveUsersets(
 	return out, nil
 }
 
+// expansion is the state of the expansion of the usersets referenced by the tree of an ExpandQuery.
+type expansion struct {
+	// path holds the usersets being expanded, which are left as references to avoid expanding cycles forever.
+	path map[string]struct{}
+
+	// resolved memoizes the usersets resolved by the request, keyed by object#relation, so that a userset
+	// referenced several times, e.g. by the branches of a diamond-shaped graph, is read from the datastore once.
+	resolved map[string]*openfgav1.UsersetTree_Node
+}
+
+// expandNode replaces the leaves of the node that reference usersets with the expansion of those usersets,
+// down to depth more levels of usersets, see expansion.
+func (q *ExpandQuery) expandNode(
+	ctx context.Context,
+	store string,
+	node *openfgav1.UsersetTree_Node,
+	typesys *typesystem.TypeSystem,
+	depth uint32,
+	state *expansion,
+) (*openfgav1.UsersetTree_Node, error) {
+	switch n := node.GetValue().(type) {
+	case *openfgav1.UsersetTree_Node_Union:
+		return node, q.expandNodes(ctx, store, n.Union.GetNodes(), typesys, depth, state)
+	case *openfgav1.UsersetTree_Node_Intersection:
+		return node, q.expandNodes(ctx, store, n.Intersection.GetNodes(), typesys, depth, state)
+	case *openfgav1.UsersetTree_Node_Difference:
+		nodes := []*openfgav1.UsersetTree_Node{n.Difference.GetBase(), n.Difference.GetSubtract()}
+		if err := q.expandNodes(ctx, store, nodes, typesys, depth, state); err != nil {
+			return nil, err
+		}
+		n.Difference.Base, n.Difference.Subtract = nodes[0], nodes[1]
+
+		return node, nil
+	case *openfgav1.UsersetTree_Node_Leaf:
+		var children []*openfgav1.UsersetTree_Node
+
+		switch leaf := n.Leaf.GetValue().(type) {
+		case *openfgav1.UsersetTree_Leaf_Users:
+			var users []string
+			for _, user := range leaf.Users.GetUsers() {
+				if !tupleUtils.IsObjectRelation(user) {
+					users = append(users, user)
+					continue
+				}
+
+				child, err := q.expandObjectRelation(ctx, store, user, typesys, depth, state)
+				if err != nil {
+					return nil, err
+				}
+
+				if child == nil {
+					users = append(users, user)
+					continue
+				}
+				children = append(children, child)
+			}
+
+			if len(children) == 0 {
+				return node, nil
+			}
+
+			if len(users) > 0 {
+				leaf.Users.Users = users
+				children = append([]*openfgav1.UsersetTree_Node{node}, children...)
+			}
+		case *openfgav1.UsersetTree_Leaf_Computed:
+			child, err := q.expandObjectRelation(ctx, store, leaf.Computed.GetUserset(), typesys, depth, state)
+			if err != nil || child == nil {
+				return node, err
+			}
+			children = append(children, child)
+		case *openfgav1.UsersetTree_Leaf_TupleToUserset:
+			for _, computed := range leaf.TupleToUserset.GetComputed() {
+				child, err := q.expandObjectRelation(ctx, store, computed.GetUserset(), typesys, depth, state)
+				if err != nil {
+					return nil, err
+				}
+
+				if child == nil {
+					child = &openfgav1.UsersetTree_Node{
+						Name: node.GetName(),
+						Value: &openfgav1.UsersetTree_Node_Leaf{
+							Leaf: &openfgav1.UsersetTree_Leaf{
+								Value: &openfgav1.UsersetTree_Leaf_Computed{Computed: computed},
+							},
+						},
+					}
+				}
+				children = append(children, child)
+			}
+
+			if len(children) == 0 {
+				return node, nil
+			}
+		}
+
+		return &openfgav1.UsersetTree_Node{
+			Name: node.GetName(),
+			Value: &openfgav1.UsersetTree_Node_Union{
+				Union: &openfgav1.UsersetTree_Nodes{
+					Nodes: children,
+				},
+			},
+		}, nil
+	default:
+		return node, nil
+	}
+}
+
+// expandNodes expands the nodes in place, see expandNode.
+func (q *ExpandQuery) expandNodes(
+	ctx context.Context,
+	store string,
+	nodes []*openfgav1.UsersetTree_Node,
+	typesys *typesystem.TypeSystem,
+	depth uint32,
+	state *expansion,
+) error {
+	for i, node := range nodes {
+		expanded, err := q.expandNode(ctx, store, node, typesys, depth, state)
+		if err != nil {
+			return err
+		}
+		nodes[i] = expanded
+	}
+
+	return nil
+}
+
+// expandObjectRelation expands the userset (an object#relation) referenced by a leaf, down to depth levels of
+// usersets. It returns nil if the userset is not expanded, either because it is being expanded already
+// (see expansion) or because the relation is not defined in the model.
+func (q *ExpandQuery) expandObjectRelation(
+	ctx context.Context,
+	store string,
+	objectRelation string,
+	typesys *typesystem.TypeSystem,
+	depth uint32,
+	state *expansion,
+) (*openfgav1.UsersetTree_Node, error) {
+	if _, ok := state.path[objectRelation]; ok {
+		return nil, nil
+	}
+
+	object, relation := tupleUtils.SplitObjectRelation(objectRelation)
+	rel, err := typesys.GetRelation(tupleUtils.GetType(object), relation)
+	if err != nil {
+		return nil, nil
+	}
+
+	resolved, ok := state.resolved[objectRelation]
+	if !ok {
+		resolved, err = q.resolveUserset(ctx, store, rel.GetRewrite(), tupleUtils.NewTupleKey(object, relation, ""), typesys)
+		if err != nil {
+			return nil, err
+		}
+		state.resolved[objectRelation] = resolved
+	}
+
+	// the node is expanded in place, so the memoized one must not be
+	node := proto.Clone(resolved).(*openfgav1.UsersetTree_Node)
+
+	if depth <= 1 {
+		return node, nil
+	}
+
+	state.path[objectRelation] = struct{}{}
+	defer delete(state.path, objectRelation)
+
+	return q.expandNode(ctx, store, node, typesys, depth-1, state)
+}
+
 func toObjectRelation(tk *openfgav1.TupleKey) string {
 	return tupleUtils.ToObjectRelationString(tk.GetObject(), tk.GetRelation())
 }
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// changelogPageSize is the number of changes read at once when scanning the changelog.
+const changelogPageSize = 1000
+
+// ExportTuplesRequest is a request to export the tuples of a store.
+type ExportTuplesRequest struct {
+	StoreID string
+
+	// Type optionally restricts the export to the tuples whose object is of this type.
+	Type string
+
+	// PageSize is the maximum number of tuples per message.
+	PageSize int32
+
+	// ContinuationToken resumes an interrupted export, see ExportTuplesResponse.
+	ContinuationToken string
+
+	// Snapshot requests a snapshot marker, see ExportTuplesResponse. It is ignored when resuming an export.
+	Snapshot bool
+}
+
+// ExportTuplesResponse is a page of exported tuples.
+type ExportTuplesResponse struct {
+	Tuples []*openfgav1.Tuple
+
+	// ContinuationToken resumes the export after the tuples of this message. It is empty in the last message.
+	ContinuationToken string
+
+	// SnapshotToken is a ReadChanges continuation token (for the requested type) pointing at the end of the
+	// changelog when the export started. It is only set if a snapshot was requested. The export reads the
+	// live tuples of the store, so it may or may not include the changes that occurred while it was running:
+	// replaying the changes from the snapshot token once the export is done makes the copy consistent.
+	SnapshotToken string
+}
+
+// ExportTuplesServer is the server side of an ExportTuples stream.
+type ExportTuplesServer interface {
+	Context() context.Context
+	Send(*ExportTuplesResponse) error
+}
+
+// exportContinuationToken is the decoded continuation token of an export. The snapshot is carried along
+// so that a resumed export keeps reporting the snapshot of the original export.
+type exportContinuationToken struct {
+	From     string `json:"from"`
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// ExportTuplesCommand streams every tuple of a store, in a stable order.
+type ExportTuplesCommand struct {
+	datastore     storage.OpenFGADatastore
+	logger        logger.Logger
+	encoder       encoder.Encoder
+	codec         encoder.TokenCodec
+	horizonOffset time.Duration
+}
+
+type ExportTuplesCommandOption func(c *ExportTuplesCommand)
+
+// WithExportTuplesTokenCodec sets the format of the snapshot tokens issued by the ExportTuplesCommand, which are
+// ReadChanges continuation tokens.
+func WithExportTuplesTokenCodec(codec encoder.TokenCodec) ExportTuplesCommandOption {
+	return func(c *ExportTuplesCommand) {
+		c.codec = codec
+	}
+}
+
+// NewExportTuplesCommand creates an ExportTuplesCommand. The horizonOffset (in minutes) is used to compute
+// the snapshot marker, see NewReadChangesQuery.
+func NewExportTuplesCommand(datastore storage.OpenFGADatastore, logger logger.Logger, tokenEncoder encoder.Encoder, horizonOffset int, opts ...ExportTuplesCommandOption) *ExportTuplesCommand {
+	c := &ExportTuplesCommand{
+		datastore:     datastore,
+		logger:        logger,
+		encoder:       tokenEncoder,
+		codec:         encoder.NewProtoTokenCodec(),
+		horizonOffset: time.Duration(horizonOffset) * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Execute streams the tuples of the store one page at a time, starting after the continuation token of the
+// request (or from the beginning if it is empty). Every message carries the continuation token from which
+// the export can be resumed if the stream is interrupted.
+func (c *ExportTuplesCommand) Execute(ctx context.Context, req *ExportTuplesRequest, srv ExportTuplesServer) error {
+	token, err := c.decodeContinuationToken(req.ContinuationToken)
+	if err != nil {
+		return serverErrors.InvalidContinuationToken
+	}
+
+	if req.ContinuationToken == "" && req.Snapshot {
+		token.Snapshot, err = changelogEnd(ctx, c.datastore, req.StoreID, req.Type, c.horizonOffset)
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+	}
+
+	var snapshotToken string
+	if req.Snapshot || token.Snapshot != "" {
+		snapshotToken, err = encodePaginationToken(c.encoder, c.codec, encoder.TokenKindChanges, []byte(token.Snapshot))
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+	}
+
+	var tk *openfgav1.TupleKey
+	if req.Type != "" {
+		tk = &openfgav1.TupleKey{Object: req.Type + ":"}
+	}
+
+	pageSize := storage.NewPaginationOptions(req.PageSize, "").PageSize
+
+	for {
+		tuples, from, err := c.datastore.ReadPage(ctx, req.StoreID, tk, storage.PaginationOptions{PageSize: pageSize, From: token.From})
+		if err != nil {
+			return serverErrors.HandleError("", err)
+		}
+
+		var contToken string
+		if len(from) > 0 {
+			token.From = string(from)
+			contToken, err = c.encodeContinuationToken(token)
+			if err != nil {
+				return serverErrors.HandleError("", err)
+			}
+		}
+
+		if err := srv.Send(&ExportTuplesResponse{
+			Tuples:            tuples,
+			ContinuationToken: contToken,
+			SnapshotToken:     snapshotToken,
+		}); err != nil {
+			return serverErrors.NewInternalError("", err)
+		}
+
+		if contToken == "" {
+			return nil
+		}
+	}
+}
+
+// changelogEnd returns the (unencoded) ReadChanges continuation token that points at the end of the changelog
+// of the store, or an empty token if the changelog is empty.
+func changelogEnd(ctx context.Context, backend storage.ChangelogBackend, storeID, objectType string, horizonOffset time.Duration) (string, error) {
+	var from string
+	for {
+		changes, token, err := backend.ReadChanges(ctx, storeID, objectType, storage.PaginationOptions{PageSize: changelogPageSize, From: from}, horizonOffset)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return from, nil
+			}
+
+			return "", err
+		}
+
+		from = string(token)
+		if len(changes) < changelogPageSize {
+			return from, nil
+		}
+	}
+}
+
+func (c *ExportTuplesCommand) decodeContinuationToken(contToken string) (*exportContinuationToken, error) {
+	decoded, err := c.encoder.Decode(contToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var token exportContinuationToken
+	if len(decoded) == 0 {
+		return &token, nil
+	}
+
+	if err := json.Unmarshal(decoded, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (c *ExportTuplesCommand) encodeContinuationToken(token *exportContinuationToken) (string, error) {
+	marshalled, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return c.encoder.Encode(marshalled)
+}
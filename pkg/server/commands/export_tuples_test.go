@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+type mockExportTuplesServer struct {
+	ctx       context.Context
+	responses []*ExportTuplesResponse
+}
+
+func (m *mockExportTuplesServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockExportTuplesServer) Send(resp *ExportTuplesResponse) error {
+	m.responses = append(m.responses, resp)
+	return nil
+}
+
+func TestExportTuplesCommand(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	var tupleKeys []*openfgav1.TupleKey
+	for i := 0; i < 5; i++ {
+		tupleKeys = append(tupleKeys, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+	tupleKeys = append(tupleKeys, tuple.NewTupleKey("folder:1", "viewer", "user:jon"))
+
+	err := ds.Write(ctx, storeID, nil, tupleKeys)
+	require.NoError(t, err)
+
+	cmd := NewExportTuplesCommand(ds, logger.NewNoopLogger(), encoder.NewBase64Encoder(), 0)
+
+	exportedObjects := func(responses []*ExportTuplesResponse) []string {
+		var objects []string
+		for _, resp := range responses {
+			for _, tuple := range resp.Tuples {
+				objects = append(objects, tuple.GetKey().GetObject())
+			}
+		}
+		return objects
+	}
+
+	t.Run("every_tuple_is_exported", func(t *testing.T) {
+		srv := &mockExportTuplesServer{ctx: ctx}
+
+		err := cmd.Execute(ctx, &ExportTuplesRequest{StoreID: storeID, PageSize: 4}, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.responses, 2)
+		require.NotEmpty(t, srv.responses[0].ContinuationToken)
+		require.Empty(t, srv.responses[1].ContinuationToken)
+		require.Len(t, exportedObjects(srv.responses), 6)
+	})
+
+	t.Run("the_export_can_be_filtered_by_type", func(t *testing.T) {
+		srv := &mockExportTuplesServer{ctx: ctx}
+
+		err := cmd.Execute(ctx, &ExportTuplesRequest{StoreID: storeID, Type: "folder"}, srv)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"folder:1"}, exportedObjects(srv.responses))
+	})
+
+	t.Run("the_export_can_be_resumed_with_the_snapshot", func(t *testing.T) {
+		srv := &mockExportTuplesServer{ctx: ctx}
+
+		err := cmd.Execute(ctx, &ExportTuplesRequest{StoreID: storeID, PageSize: 2, Snapshot: true}, srv)
+		require.NoError(t, err)
+		require.Len(t, srv.responses, 3)
+
+		snapshotToken := srv.responses[0].SnapshotToken
+		require.NotEmpty(t, snapshotToken)
+
+		resumed := &mockExportTuplesServer{ctx: ctx}
+		err = cmd.Execute(ctx, &ExportTuplesRequest{StoreID: storeID, PageSize: 2, ContinuationToken: srv.responses[0].ContinuationToken}, resumed)
+		require.NoError(t, err)
+
+		require.Equal(t, exportedObjects(srv.responses[1:]), exportedObjects(resumed.responses))
+		require.Equal(t, snapshotToken, resumed.responses[0].SnapshotToken)
+
+		// the snapshot points at the end of the changelog when the export started
+		err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:new", "viewer", "user:jon")})
+		require.NoError(t, err)
+
+		changes, err := NewReadChangesQuery(ds, logger.NewNoopLogger(), encoder.NewBase64Encoder(), 0).Execute(ctx, &openfgav1.ReadChangesRequest{
+			StoreId:           storeID,
+			ContinuationToken: snapshotToken,
+		})
+		require.NoError(t, err)
+		require.Len(t, changes.GetChanges(), 1)
+		require.Equal(t, "document:new", changes.GetChanges()[0].GetTupleKey().GetObject())
+	})
+
+	t.Run("invalid_continuation_token", func(t *testing.T) {
+		err := cmd.Execute(ctx, &ExportTuplesRequest{StoreID: storeID, ContinuationToken: "foo"}, &mockExportTuplesServer{ctx: ctx})
+		require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+	})
+}
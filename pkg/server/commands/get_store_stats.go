@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const defaultStoreStatsCacheTTL = 30 * time.Second
+
+type GetStoreStatsRequest struct {
+	StoreID string
+}
+
+type GetStoreStatsResponse struct {
+	// Tuples is the number of tuples of the store per object type and relation, keyed by 'objectType#relation'.
+	Tuples map[string]int64 `json:"tuples"`
+
+	// TotalTuples is the sum of Tuples.
+	TotalTuples int64 `json:"total_tuples"`
+
+	AuthorizationModels int64 `json:"authorization_models"`
+	Changes             int64 `json:"changes"`
+
+	// ComputedAt is when the statistics were read from the datastore. They are cached for the cache TTL.
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// GetStoreStatsCommand reports the sizes of the data of a store, aggregated by the datastore. The statistics of a
+// store are cached for the cache TTL, so the command is meant to be long-lived, like the server holding it.
+type GetStoreStatsCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+	cacheTTL  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*GetStoreStatsResponse
+}
+
+type GetStoreStatsCommandOption func(c *GetStoreStatsCommand)
+
+// WithStoreStatsCacheTTL sets how long the statistics of a store are cached before they are read again. They are
+// not cached if it is 0.
+func WithStoreStatsCacheTTL(ttl time.Duration) GetStoreStatsCommandOption {
+	return func(c *GetStoreStatsCommand) {
+		c.cacheTTL = ttl
+	}
+}
+
+func NewGetStoreStatsCommand(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...GetStoreStatsCommandOption) *GetStoreStatsCommand {
+	c := &GetStoreStatsCommand{
+		datastore: datastore,
+		logger:    logger,
+		cacheTTL:  defaultStoreStatsCacheTTL,
+		cache:     map[string]*GetStoreStatsResponse{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *GetStoreStatsCommand) Execute(ctx context.Context, req *GetStoreStatsRequest) (*GetStoreStatsResponse, error) {
+	if _, err := c.datastore.GetStore(ctx, req.StoreID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.StoreIDNotFound
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	key := storage.StoreKey(ctx, req.StoreID)
+
+	c.mu.Lock()
+	cached := c.cache[key]
+	c.mu.Unlock()
+
+	if cached != nil && time.Since(cached.ComputedAt) < c.cacheTTL {
+		return cached, nil
+	}
+
+	stats, err := c.datastore.ReadStoreStats(ctx, req.StoreID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	resp := &GetStoreStatsResponse{
+		Tuples:              stats.Tuples,
+		AuthorizationModels: stats.AuthorizationModels,
+		Changes:             stats.Changes,
+		ComputedAt:          time.Now(),
+	}
+	if resp.Tuples == nil {
+		resp.Tuples = map[string]int64{}
+	}
+	for _, count := range resp.Tuples {
+		resp.TotalTuples += count
+	}
+
+	c.logger.DebugWithContext(ctx, "read the statistics of the store",
+		zap.String("store_id", req.StoreID),
+		zap.Int64("total_tuples", resp.TotalTuples),
+	)
+
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.cache[key] = resp
+		c.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// Invalidate drops the cached statistics of the store of the tenant of the context, or of every store if the store is
+// empty.
+func (c *GetStoreStatsCommand) Invalidate(ctx context.Context, storeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if storeID == "" {
+		c.cache = map[string]*GetStoreStatsResponse{}
+		return
+	}
+
+	delete(c.cache, storage.StoreKey(ctx, storeID))
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStoreStatsCommand(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "payments"})
+	require.NoError(t, err)
+
+	write := func(t *testing.T, object string) {
+		err := ds.Write(ctx, store.Id, nil, []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:anne")})
+		require.NoError(t, err)
+	}
+
+	t.Run("stats_are_aggregated", func(t *testing.T) {
+		write(t, "document:1")
+		write(t, "document:2")
+
+		resp, err := NewGetStoreStatsCommand(ds, logger.NewNoopLogger()).Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"document#viewer": 2}, resp.Tuples)
+		require.Equal(t, int64(2), resp.TotalTuples)
+		require.Equal(t, int64(2), resp.Changes)
+	})
+
+	t.Run("stats_are_cached_until_invalidated", func(t *testing.T) {
+		cmd := NewGetStoreStatsCommand(ds, logger.NewNoopLogger(), WithStoreStatsCacheTTL(time.Hour))
+
+		resp, err := cmd.Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		total := resp.TotalTuples
+
+		write(t, "folder:1")
+
+		resp, err = cmd.Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		require.Equal(t, total, resp.TotalTuples)
+
+		cmd.Invalidate(ctx, store.Id)
+
+		resp, err = cmd.Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		require.Equal(t, total+1, resp.TotalTuples)
+	})
+
+	t.Run("stats_are_not_cached_without_a_ttl", func(t *testing.T) {
+		cmd := NewGetStoreStatsCommand(ds, logger.NewNoopLogger(), WithStoreStatsCacheTTL(0))
+
+		resp, err := cmd.Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		total := resp.TotalTuples
+
+		write(t, "folder:2")
+
+		resp, err = cmd.Execute(ctx, &GetStoreStatsRequest{StoreID: store.Id})
+		require.NoError(t, err)
+		require.Equal(t, total+1, resp.TotalTuples)
+	})
+
+	t.Run("unknown_store", func(t *testing.T) {
+		_, err := NewGetStoreStatsCommand(ds, logger.NewNoopLogger()).Execute(ctx, &GetStoreStatsRequest{StoreID: ulid.Make().String()})
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+	})
+}
@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
+	"go.uber.org/zap"
+)
+
+// ImportTuplesRequest is a message of an ImportTuples stream.
+type ImportTuplesRequest struct {
+	// StoreID, AuthorizationModelID and IgnoreDuplicates are only read from the first message of the stream.
+	StoreID              string
+	AuthorizationModelID string
+
+	// IgnoreDuplicates skips the tuples that already exist instead of reporting them as failures.
+	IgnoreDuplicates bool
+
+	TupleKeys []*openfgav1.TupleKey
+}
+
+// ImportTuplesFailure is a tuple that could not be imported.
+type ImportTuplesFailure struct {
+	TupleKey *openfgav1.TupleKey
+	Err      error
+}
+
+// ImportTuplesProgress reports the outcome of a batch of an ImportTuples stream, along with the totals of
+// the stream so far.
+type ImportTuplesProgress struct {
+	// Batch is the index of the batch, starting from 0.
+	Batch int
+
+	// Written is the number of tuples of the batch that were written.
+	Written int
+
+	// Failures are the tuples of the batch that could not be written.
+	Failures []*ImportTuplesFailure
+
+	TotalWritten int
+	TotalFailed  int
+}
+
+// ImportTuplesServer is the server side of an ImportTuples stream. Recv must return io.EOF once the client
+// has sent every tuple.
+type ImportTuplesServer interface {
+	Context() context.Context
+	Recv() (*ImportTuplesRequest, error)
+	Send(*ImportTuplesProgress) error
+}
+
+// ImportTuplesCommand writes a stream of tuples in batches of at most MaxTuplesPerWrite tuples.
+type ImportTuplesCommand struct {
+	datastore          storage.OpenFGADatastore
+	logger             logger.Logger
+	typesystemResolver typesystem.TypesystemResolverFunc
+	batchSize          int
+	checkCache         *graph.CheckCache
+	quotas             *quota.Enforcer
+	hook               writehook.Hook
+}
+
+type ImportTuplesCommandOption func(c *ImportTuplesCommand)
+
+func WithImportTuplesLogger(l logger.Logger) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.logger = l
+	}
+}
+
+// WithImportTuplesTypesystemResolver sets how the authorization model the tuples are validated against is
+// resolved. Defaults to typesystem.MemoizedTypesystemResolverFunc.
+func WithImportTuplesTypesystemResolver(resolver typesystem.TypesystemResolverFunc) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.typesystemResolver = resolver
+	}
+}
+
+// WithImportTuplesBatchSize sets the maximum number of tuples written in a single transaction. It is capped
+// by the MaxTuplesPerWrite of the datastore.
+func WithImportTuplesBatchSize(batchSize int) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.batchSize = batchSize
+	}
+}
+
+// WithImportTuplesCheckCache sets the check cache that is invalidated after every written batch.
+func WithImportTuplesCheckCache(cache *graph.CheckCache) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.checkCache = cache
+	}
+}
+
+// WithImportTuplesQuotas enforces the tuple quotas of the stores. A batch that would take the store over its
+// quota fails as a whole. If nil, no quotas are enforced.
+func WithImportTuplesQuotas(quotas *quota.Enforcer) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.quotas = quotas
+	}
+}
+
+// WithImportTuplesHook submits every batch to the hook before its tuples are validated and written, see
+// writehook.Hook. A batch rejected by the hook fails as a whole.
+func WithImportTuplesHook(hook writehook.Hook) ImportTuplesCommandOption {
+	return func(c *ImportTuplesCommand) {
+		c.hook = hook
+	}
+}
+
+func NewImportTuplesCommand(datastore storage.OpenFGADatastore, opts ...ImportTuplesCommandOption) *ImportTuplesCommand {
+	c := &ImportTuplesCommand{
+		datastore: datastore,
+		logger:    logger.NewNoopLogger(),
+		batchSize: datastore.MaxTuplesPerWrite(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.typesystemResolver == nil {
+		c.typesystemResolver = typesystem.MemoizedTypesystemResolverFunc(datastore)
+	}
+
+	if c.batchSize <= 0 || c.batchSize > datastore.MaxTuplesPerWrite() {
+		c.batchSize = datastore.MaxTuplesPerWrite()
+	}
+
+	return c
+}
+
+// Execute writes the tuples received from the stream until the client closes it. Every tuple is validated
+// against the authorization model resolved from the first message, and the valid tuples are written in
+// batches. The outcome of every batch is sent to the client as soon as the batch is written: invalid tuples
+// and tuples whose batch failed to be written are reported as failures, and the import carries on with the
+// next batch. An error is returned only if the stream itself fails.
+func (c *ImportTuplesCommand) Execute(ctx context.Context, srv ImportTuplesServer) error {
+	first, err := srv.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	storeID := first.StoreID
+	if storeID == "" {
+		return serverErrors.ValidationError(errors.New("the first message of the stream must set the store id"))
+	}
+
+	typesys, err := c.typesystemResolver(ctx, storeID, first.AuthorizationModelID)
+	if err != nil {
+		return err
+	}
+
+	var writeOpts []storage.TupleWriteOption
+	if first.IgnoreDuplicates {
+		writeOpts = append(writeOpts, storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore))
+	}
+
+	progress := &ImportTuplesProgress{}
+	pending := make([]*openfgav1.TupleKey, 0, c.batchSize)
+
+	flush := func() error {
+		c.writeBatch(ctx, storeID, typesys, pending, writeOpts, progress)
+		if err := srv.Send(progress); err != nil {
+			return serverErrors.NewInternalError("", err)
+		}
+
+		progress = &ImportTuplesProgress{
+			Batch:        progress.Batch + 1,
+			TotalWritten: progress.TotalWritten,
+			TotalFailed:  progress.TotalFailed,
+		}
+		pending = pending[:0]
+
+		return nil
+	}
+
+	req := first
+	for {
+		for _, tk := range req.TupleKeys {
+			pending = append(pending, tk)
+			if len(pending) == c.batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		req, err = srv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	if len(pending) > 0 {
+		return flush()
+	}
+
+	return nil
+}
+
+// writeBatch validates and writes the tuples, recording the outcome in the progress.
+func (c *ImportTuplesCommand) writeBatch(
+	ctx context.Context,
+	storeID string,
+	typesys *typesystem.TypeSystem,
+	tupleKeys []*openfgav1.TupleKey,
+	writeOpts []storage.TupleWriteOption,
+	progress *ImportTuplesProgress,
+) {
+	ctx, span := tracer.Start(ctx, "importTuples.writeBatch")
+	defer span.End()
+
+	deletes, admitted, err := admitWrite(ctx, c.hook, storeID, typesys.GetAuthorizationModelID(), nil, tupleKeys)
+	if err != nil {
+		for _, tk := range tupleKeys {
+			progress.Failures = append(progress.Failures, &ImportTuplesFailure{TupleKey: tk, Err: err})
+		}
+		progress.TotalFailed += len(progress.Failures)
+
+		return
+	}
+
+	seen := make(map[string]struct{}, len(admitted))
+	writes := make([]*openfgav1.TupleKey, 0, len(admitted))
+	for _, tk := range admitted {
+		if err := ValidateTupleToWrite(typesys, tk); err != nil {
+			progress.Failures = append(progress.Failures, &ImportTuplesFailure{TupleKey: tk, Err: err})
+			continue
+		}
+
+		key := tupleUtils.TupleKeyToString(tk)
+		if _, ok := seen[key]; ok {
+			progress.Failures = append(progress.Failures, &ImportTuplesFailure{TupleKey: tk, Err: serverErrors.DuplicateTupleInWrite(tk)})
+			continue
+		}
+		seen[key] = struct{}{}
+
+		writes = append(writes, tk)
+	}
+
+	if len(deletes)+len(writes) > 0 {
+		if err := c.writeTuples(ctx, storeID, deletes, writes, writeOpts); err != nil {
+			for _, tk := range writes {
+				progress.Failures = append(progress.Failures, &ImportTuplesFailure{TupleKey: tk, Err: err})
+			}
+		} else {
+			progress.Written = len(writes)
+
+			if c.checkCache != nil {
+				if err := c.checkCache.InvalidateStore(ctx, storeID); err != nil {
+					c.logger.WarnWithContext(ctx, "failed to invalidate the check cache of the store", zap.String("store_id", storeID), zap.Error(err))
+				}
+			}
+		}
+	}
+
+	progress.TotalWritten += progress.Written
+	progress.TotalFailed += len(progress.Failures)
+}
+
+// writeTuples writes the tuples, along with the deletes admitted by the hook, unless they would take the store over
+// its tuple quota.
+func (c *ImportTuplesCommand) writeTuples(ctx context.Context, storeID string, deletes, writes []*openfgav1.TupleKey, writeOpts []storage.TupleWriteOption) error {
+	if c.quotas != nil {
+		if err := c.quotas.CheckTupleCount(ctx, storeID, len(deletes), len(writes)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.datastore.Write(ctx, storeID, deletes, writes, writeOpts...); err != nil {
+		return handleError(err)
+	}
+
+	if c.quotas != nil {
+		c.quotas.RecordWrite(storeID, len(deletes), len(writes))
+	}
+
+	return nil
+}
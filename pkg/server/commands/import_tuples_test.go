@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+type mockImportTuplesServer struct {
+	ctx      context.Context
+	requests []*ImportTuplesRequest
+	progress []*ImportTuplesProgress
+}
+
+func (m *mockImportTuplesServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockImportTuplesServer) Recv() (*ImportTuplesRequest, error) {
+	if len(m.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := m.requests[0]
+	m.requests = m.requests[1:]
+
+	return req, nil
+}
+
+func (m *mockImportTuplesServer) Send(progress *ImportTuplesProgress) error {
+	m.progress = append(m.progress, progress)
+	return nil
+}
+
+func TestImportTuplesCommand(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.WriteAuthorizationModel(ctx, storeID, &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		    define can_view as viewer
+		`),
+	})
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:existing", "viewer", "user:jon")})
+	require.NoError(t, err)
+
+	var tupleKeys []*openfgav1.TupleKey
+	for i := 0; i < 5; i++ {
+		tupleKeys = append(tupleKeys, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+
+	t.Run("tuples_are_written_in_batches", func(t *testing.T) {
+		srv := &mockImportTuplesServer{
+			ctx: ctx,
+			requests: []*ImportTuplesRequest{
+				{StoreID: storeID, TupleKeys: tupleKeys[:3]},
+				{TupleKeys: tupleKeys[3:]},
+			},
+		}
+
+		err := NewImportTuplesCommand(ds, WithImportTuplesBatchSize(2)).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.progress, 3)
+		for i, progress := range srv.progress {
+			require.Equal(t, i, progress.Batch)
+			require.Empty(t, progress.Failures)
+		}
+		require.Equal(t, 5, srv.progress[2].TotalWritten)
+
+		tuples, _, err := ds.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, tuples, 6)
+	})
+
+	t.Run("failures_are_reported_per_batch", func(t *testing.T) {
+		srv := &mockImportTuplesServer{
+			ctx: ctx,
+			requests: []*ImportTuplesRequest{
+				{StoreID: storeID, TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:a", "viewer", "user:jon"),
+					tuple.NewTupleKey("document:b", "can_view", "user:jon"),
+					tuple.NewTupleKey("document:existing", "viewer", "user:jon"),
+					tuple.NewTupleKey("document:c", "viewer", "user:jon"),
+				}},
+			},
+		}
+
+		err := NewImportTuplesCommand(ds, WithImportTuplesBatchSize(2)).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.progress, 2)
+
+		// the indirect relation is rejected, the valid tuple is written
+		require.Equal(t, 1, srv.progress[0].Written)
+		require.Len(t, srv.progress[0].Failures, 1)
+		require.Equal(t, "can_view", srv.progress[0].Failures[0].TupleKey.GetRelation())
+
+		// the existing tuple fails the whole batch
+		require.Equal(t, 0, srv.progress[1].Written)
+		require.Len(t, srv.progress[1].Failures, 2)
+		require.Equal(t, 1, srv.progress[1].TotalWritten)
+		require.Equal(t, 3, srv.progress[1].TotalFailed)
+	})
+
+	t.Run("duplicates_are_ignored", func(t *testing.T) {
+		srv := &mockImportTuplesServer{
+			ctx: ctx,
+			requests: []*ImportTuplesRequest{
+				{StoreID: storeID, IgnoreDuplicates: true, TupleKeys: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:existing", "viewer", "user:jon"),
+					tuple.NewTupleKey("document:d", "viewer", "user:jon"),
+				}},
+			},
+		}
+
+		err := NewImportTuplesCommand(ds).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.progress, 1)
+		require.Empty(t, srv.progress[0].Failures)
+	})
+
+	t.Run("the_first_message_must_set_the_store", func(t *testing.T) {
+		srv := &mockImportTuplesServer{
+			ctx:      ctx,
+			requests: []*ImportTuplesRequest{{TupleKeys: tupleKeys}},
+		}
+
+		err := NewImportTuplesCommand(ds).Execute(ctx, srv)
+		require.Error(t, err)
+	})
+}
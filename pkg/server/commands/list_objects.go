@@ -2,9 +2,14 @@ package commands
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,8 +17,10 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/encoder"
 	"github.com/openfga/openfga/pkg/logger"
 	"github.com/openfga/openfga/pkg/server/commands/connectedobjects"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
@@ -22,6 +29,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -45,6 +53,16 @@ var (
 		Name: "list_objects_no_further_eval_required_count",
 		Help: "Number of objects in a ListObjects call that needed to issue a Check call to determine a final result",
 	})
+
+	listObjectsChecksInFlightGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "list_objects_checks_in_flight",
+		Help: "Number of Checks issued by ListObjects queries to evaluate their candidate objects that are in flight, across all the queries",
+	})
+
+	listObjectsStrategyCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "list_objects_strategy_count",
+		Help: "Number of ListObjects calls resolved with each strategy chosen by the query planner",
+	}, []string{"strategy"})
 )
 
 type ListObjectsQuery struct {
@@ -55,8 +73,31 @@ type ListObjectsQuery struct {
 	resolveNodeLimit        uint32
 	resolveNodeBreadthLimit uint32
 	maxConcurrentReads      uint32
+	encoder                 encoder.Encoder
+	sortOrder               ListObjectsSortOrder
+	planner                 *planner.Planner
+	strategy                *planner.Strategy
+	partialResults          bool
+	streamBufferSize        uint32
+	streamSendTimeout       time.Duration
+	maxConcurrentChecks     uint32
 }
 
+// ListObjectsSortOrder is the order of the objects returned by ListObjectsQuery.Execute.
+type ListObjectsSortOrder int
+
+const (
+	// ListObjectsUnsorted returns the objects in the order they are resolved in. Since objects are resolved
+	// concurrently, the order (and, when the results are truncated, the objects themselves) may differ from
+	// one request to the next.
+	ListObjectsUnsorted ListObjectsSortOrder = iota
+
+	// ListObjectsSortedByObjectID returns the objects sorted lexicographically by object id. When the results
+	// are truncated to the max results, the first objects in that order are returned. This requires every
+	// object to be resolved (until the deadline is hit) before the response is sent.
+	ListObjectsSortedByObjectID
+)
+
 type ListObjectsQueryOption func(d *ListObjectsQuery)
 
 // WithMaxConcurrentReads see server.WithMaxConcurrentReadsForListObjects
@@ -92,12 +133,77 @@ func WithResolveNodeBreadthLimit(limit uint32) ListObjectsQueryOption {
 	}
 }
 
+// WithListObjectsMaxConcurrentChecks sets how many Checks a query issues concurrently to evaluate the candidate
+// objects which require further evaluation. Defaults to 0, which uses the resolve node breadth limit.
+func WithListObjectsMaxConcurrentChecks(max uint32) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.maxConcurrentChecks = max
+	}
+}
+
 func WithLogger(l logger.Logger) ListObjectsQueryOption {
 	return func(d *ListObjectsQuery) {
 		d.logger = l
 	}
 }
 
+// WithListObjectsEncoder sets the encoder of the continuation tokens of ExecutePage. Defaults to
+// encoder.NewBase64Encoder().
+func WithListObjectsEncoder(e encoder.Encoder) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.encoder = e
+	}
+}
+
+// WithListObjectsSortOrder sets the order of the objects returned by Execute. Defaults to ListObjectsUnsorted.
+// It does not apply to ExecuteStreamed, and ExecutePage always sorts by object id.
+func WithListObjectsSortOrder(order ListObjectsSortOrder) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.sortOrder = order
+	}
+}
+
+// WithListObjectsPlanner sets the planner that chooses how each request is resolved. Without a planner, requests are
+// always resolved with planner.ReverseExpansion.
+func WithListObjectsPlanner(p *planner.Planner) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.planner = p
+	}
+}
+
+// WithListObjectsStrategy resolves every request with the strategy, whatever the planner chooses.
+func WithListObjectsStrategy(strategy planner.Strategy) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.strategy = &strategy
+	}
+}
+
+// WithListObjectsPartialResults sets whether the objects resolved until the deadline of the query are returned when
+// the deadline is hit, with the graph.ResolutionStats of the context marked incomplete. If disabled, hitting the
+// deadline fails the query with serverErrors.RequestDeadlineExceeded instead. Defaults to true.
+func WithListObjectsPartialResults(enabled bool) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.partialResults = enabled
+	}
+}
+
+// WithListObjectsStreamBufferSize sets how many objects ExecuteStreamed buffers while the client receives the
+// previous ones. Once the buffer is full, the resolution waits for the client. Defaults to 100.
+func WithListObjectsStreamBufferSize(size uint32) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.streamBufferSize = size
+	}
+}
+
+// WithListObjectsStreamSendTimeout sets how long ExecuteStreamed waits for the client to receive an object before
+// it fails with serverErrors.StreamSendTimeout, which cancels the resolution. Defaults to 0, which waits for as
+// long as the deadline of the query allows.
+func WithListObjectsStreamSendTimeout(timeout time.Duration) ListObjectsQueryOption {
+	return func(d *ListObjectsQuery) {
+		d.streamSendTimeout = timeout
+	}
+}
+
 func NewListObjectsQuery(ds storage.RelationshipTupleReader, opts ...ListObjectsQueryOption) *ListObjectsQuery {
 	query := &ListObjectsQuery{
 		datastore:               ds,
@@ -107,6 +213,9 @@ func NewListObjectsQuery(ds storage.RelationshipTupleReader, opts ...ListObjects
 		resolveNodeLimit:        defaultResolveNodeLimit,
 		resolveNodeBreadthLimit: defaultResolveNodeBreadthLimit,
 		maxConcurrentReads:      defaultMaxConcurrentReads,
+		encoder:                 encoder.NewBase64Encoder(),
+		partialResults:          true,
+		streamBufferSize:        streamedBufferSize,
 	}
 
 	for _, opt := range opts {
@@ -201,25 +310,43 @@ func (q *ListObjectsQuery) evaluate(
 			}
 		}
 
+		userRef := typesystem.DirectRelationReference(userObjType, userRel)
+		if tuple.IsTypedWildcard(userObj) {
+			userRef = typesystem.WildcardRelationReference(userObjType)
+		}
+
+		strategy := planner.ReverseExpansion
+		switch {
+		case q.strategy != nil:
+			strategy = *q.strategy
+		case q.planner != nil:
+			strategy = q.planner.Plan(ctx, typesys, req.GetStoreId(), targetObjectType, targetRelation, userRef)
+		}
+		listObjectsStrategyCounter.WithLabelValues(strategy.String()).Inc()
+
 		connectedObjectsResChan := make(chan *connectedobjects.ConnectedObjectsResult, 1)
 		var objectsFound = new(uint32)
 
-		connectedObjectsQuery := connectedobjects.NewConnectedObjectsQuery(q.datastore, typesys,
+		connectedObjectsQuery := connectedobjects.NewConnectedObjectsQuery(graph.NewStatsTupleReader(storagewrappers.NewTracedTupleReader(q.datastore)), typesys,
 			connectedobjects.WithResolveNodeLimit(q.resolveNodeLimit),
 			connectedobjects.WithResolveNodeBreadthLimit(q.resolveNodeBreadthLimit),
 			connectedobjects.WithMaxResults(maxResults),
 		)
 
 		go func() {
-			err = connectedObjectsQuery.Execute(ctx, &connectedobjects.ConnectedObjectsRequest{
-				StoreID:          req.GetStoreId(),
-				ObjectType:       targetObjectType,
-				Relation:         targetRelation,
-				User:             sourceUserRef,
-				ContextualTuples: req.GetContextualTuples().GetTupleKeys(),
-			}, connectedObjectsResChan)
+			if strategy == planner.ConcurrentChecks {
+				err = q.listObjectsOfType(ctx, req, connectedObjectsResChan)
+			} else {
+				err = connectedObjectsQuery.Execute(ctx, &connectedobjects.ConnectedObjectsRequest{
+					StoreID:          req.GetStoreId(),
+					ObjectType:       targetObjectType,
+					Relation:         targetRelation,
+					User:             sourceUserRef,
+					ContextualTuples: req.GetContextualTuples().GetTupleKeys(),
+				}, connectedObjectsResChan)
+			}
 			if err != nil {
-				resultsChan <- ListObjectsResult{Err: err}
+				sendListObjectsResult(ctx, resultsChan, ListObjectsResult{Err: err})
 			}
 
 			close(connectedObjectsResChan)
@@ -231,7 +358,11 @@ func (q *ListObjectsQuery) evaluate(
 			graph.WithMaxConcurrentReads(q.maxConcurrentReads),
 		)
 
-		concurrencyLimiterCh := make(chan struct{}, q.resolveNodeBreadthLimit)
+		maxConcurrentChecks := q.maxConcurrentChecks
+		if maxConcurrentChecks == 0 {
+			maxConcurrentChecks = q.resolveNodeBreadthLimit
+		}
+		concurrencyLimiterCh := make(chan struct{}, maxConcurrentChecks)
 
 		wg := sync.WaitGroup{}
 
@@ -241,7 +372,7 @@ func (q *ListObjectsQuery) evaluate(
 				noFurtherEvalRequiredCounter.Inc()
 
 				if atomic.AddUint32(objectsFound, 1) <= maxResults {
-					resultsChan <- ListObjectsResult{ObjectID: res.Object}
+					sendListObjectsResult(ctx, resultsChan, ListObjectsResult{ObjectID: res.Object})
 				}
 
 				continue
@@ -258,6 +389,9 @@ func (q *ListObjectsQuery) evaluate(
 
 				concurrencyLimiterCh <- struct{}{}
 
+				listObjectsChecksInFlightGauge.Inc()
+				defer listObjectsChecksInFlightGauge.Dec()
+
 				resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
 					StoreID:              req.GetStoreId(),
 					AuthorizationModelID: req.GetAuthorizationModelId(),
@@ -268,12 +402,12 @@ func (q *ListObjectsQuery) evaluate(
 					},
 				})
 				if err != nil {
-					resultsChan <- ListObjectsResult{Err: err}
+					sendListObjectsResult(ctx, resultsChan, ListObjectsResult{Err: err})
 					return
 				}
 
 				if resp.Allowed && atomic.AddUint32(objectsFound, 1) <= maxResults {
-					resultsChan <- ListObjectsResult{ObjectID: res.Object}
+					sendListObjectsResult(ctx, resultsChan, ListObjectsResult{ObjectID: res.Object})
 				}
 			}(res)
 		}
@@ -288,29 +422,137 @@ func (q *ListObjectsQuery) evaluate(
 	return nil
 }
 
+// sendListObjectsResult sends the result, unless the context is done first because the results are no longer
+// consumed. Errors are dropped once the context is done, since the consumer handles its deadline itself.
+func sendListObjectsResult(ctx context.Context, resultsChan chan<- ListObjectsResult, result ListObjectsResult) {
+	if result.Err != nil && ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case resultsChan <- result:
+	case <-ctx.Done():
+	}
+}
+
+// listObjectsOfType sends every object of the requested type, found in the tuples of the store or in the
+// contextual tuples, as a result that requires further evaluation. This is how candidates are found with the
+// planner.ConcurrentChecks strategy.
+func (q *ListObjectsQuery) listObjectsOfType(
+	ctx context.Context,
+	req listObjectsRequest,
+	resultChan chan<- *connectedobjects.ConnectedObjectsResult,
+) error {
+	seen := map[string]struct{}{}
+	send := func(object string) error {
+		if _, ok := seen[object]; ok {
+			return nil
+		}
+		seen[object] = struct{}{}
+
+		select {
+		case resultChan <- &connectedobjects.ConnectedObjectsResult{
+			Object:       object,
+			ResultStatus: connectedobjects.RequiresFurtherEvalStatus,
+		}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for _, tk := range req.GetContextualTuples().GetTupleKeys() {
+		if tuple.GetType(tk.GetObject()) == req.GetType() {
+			if err := send(tk.GetObject()); err != nil {
+				return err
+			}
+		}
+	}
+
+	iter, err := graph.NewStatsTupleReader(storagewrappers.NewTracedTupleReader(q.datastore)).Read(ctx, req.GetStoreId(), &openfgav1.TupleKey{
+		Object: tuple.BuildObject(req.GetType(), ""),
+	})
+	if err != nil {
+		return err
+	}
+	defer iter.Stop()
+
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := send(t.GetKey().GetObject()); err != nil {
+			return err
+		}
+	}
+}
+
 // Execute the ListObjectsQuery, returning a list of object IDs up to a maximum of q.listObjectsMaxResults
-// or until q.listObjectsDeadline is hit, whichever happens first.
+// or until q.listObjectsDeadline is hit, whichever happens first. The objects are ordered according to
+// q.sortOrder.
 func (q *ListObjectsQuery) Execute(
 	ctx context.Context,
 	req *openfgav1.ListObjectsRequest,
 ) (*openfgav1.ListObjectsResponse, error) {
+	ctx, done := observeCommand(ctx, "ListObjects", req.GetStoreId())
+	defer done()
 
-	resultsChan := make(chan ListObjectsResult, 1)
 	maxResults := q.listObjectsMaxResults
+
+	if q.sortOrder == ListObjectsSortedByObjectID {
+		objects, _, err := q.collect(ctx, req, math.MaxUint32, streamedBufferSize)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(objects)
+		if maxResults > 0 && uint32(len(objects)) > maxResults {
+			objects = objects[:maxResults]
+		}
+
+		return &openfgav1.ListObjectsResponse{
+			Objects: objects,
+		}, nil
+	}
+
+	bufferSize := uint32(1)
 	if maxResults > 0 {
-		resultsChan = make(chan ListObjectsResult, maxResults)
+		bufferSize = maxResults
 	}
 
-	timeoutCtx := ctx
-	if q.listObjectsDeadline != 0 {
-		var cancel context.CancelFunc
-		timeoutCtx, cancel = context.WithTimeout(ctx, q.listObjectsDeadline)
-		defer cancel()
+	objects, _, err := q.collect(ctx, req, maxResults, bufferSize)
+	if err != nil {
+		return nil, err
 	}
 
+	return &openfgav1.ListObjectsResponse{
+		Objects: objects,
+	}, nil
+}
+
+// collect evaluates the request and collects up to maxResults objects, or the objects found until
+// q.listObjectsDeadline is hit, in which case it reports them as incomplete (see q.deadlineExceeded).
+func (q *ListObjectsQuery) collect(
+	ctx context.Context,
+	req listObjectsRequest,
+	maxResults uint32,
+	bufferSize uint32,
+) ([]string, bool, error) {
+
+	resultsChan := make(chan ListObjectsResult, bufferSize)
+
+	timeoutCtx, cancel := q.resolutionContext(ctx)
+	defer cancel()
+
 	err := q.evaluate(timeoutCtx, req, resultsChan, maxResults)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	objects := make([]string, 0)
@@ -319,51 +561,209 @@ func (q *ListObjectsQuery) Execute(
 		select {
 
 		case <-timeoutCtx.Done():
-			q.logger.WarnWithContext(
-				ctx, "list objects timeout with list object configuration timeout",
-				zap.String("timeout duration", q.listObjectsDeadline.String()),
-			)
-			return &openfgav1.ListObjectsResponse{
-				Objects: objects,
-			}, nil
+			if err := q.deadlineExceeded(ctx); err != nil {
+				return nil, false, err
+			}
+			return objects, true, nil
 
 		case result, channelOpen := <-resultsChan:
 			if result.Err != nil {
 				if errors.Is(result.Err, serverErrors.AuthorizationModelResolutionTooComplex) {
-					return nil, result.Err
+					return nil, false, result.Err
 				}
-				return nil, serverErrors.HandleError("", result.Err)
+				return nil, false, serverErrors.HandleError("", result.Err)
 			}
 
 			if !channelOpen {
-				return &openfgav1.ListObjectsResponse{
-					Objects: objects,
-				}, nil
+				return objects, false, nil
 			}
 			objects = append(objects, result.ObjectID)
 		}
 	}
 }
 
+// resolutionContext returns the context of the resolution of the query, which is done once q.listObjectsDeadline
+// is hit. The resolution must be cancelled once its results are no longer consumed, so that its goroutines don't
+// outlive the query.
+func (q *ListObjectsQuery) resolutionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if q.listObjectsDeadline != 0 {
+		return context.WithTimeout(ctx, q.listObjectsDeadline)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// deadlineExceeded handles q.listObjectsDeadline (or the deadline of the request) being hit before every object
+// is resolved: it marks the graph.ResolutionStats of the context incomplete, or it returns
+// serverErrors.RequestDeadlineExceeded if partial results are disabled.
+func (q *ListObjectsQuery) deadlineExceeded(ctx context.Context) error {
+	q.logger.WarnWithContext(
+		ctx, "list objects timeout with list object configuration timeout",
+		zap.String("timeout duration", q.listObjectsDeadline.String()),
+		zap.Bool("partial_results", q.partialResults),
+	)
+
+	if !q.partialResults {
+		return serverErrors.RequestDeadlineExceeded
+	}
+
+	graph.ResolutionStatsFromContext(ctx).MarkIncomplete()
+
+	return nil
+}
+
+// ListObjectsPageRequest is a request for a page of the objects returned by ListObjects.
+type ListObjectsPageRequest struct {
+	Request *openfgav1.ListObjectsRequest
+
+	// PageSize is the maximum number of objects of the page. It defaults to, and is capped by, the max results
+	// of the query.
+	PageSize int32
+
+	// ContinuationToken is the continuation token of the previous page, or empty for the first page.
+	ContinuationToken string
+}
+
+// ListObjectsPageResponse is a page of the objects returned by ListObjects, sorted by object id.
+type ListObjectsPageResponse struct {
+	Objects []string
+
+	// ContinuationToken fetches the next page. It is empty in the last page, and in an incomplete page.
+	ContinuationToken string
+
+	// Incomplete reports that the deadline was hit before every object was resolved, so the page may be missing
+	// objects. It has no continuation token, since the objects missing before its last object would be skipped by
+	// the next page.
+	Incomplete bool
+}
+
+// listObjectsContinuationToken is the decoded continuation token of ExecutePage.
+type listObjectsContinuationToken struct {
+	// After is the last object of the previous page.
+	After string `json:"after"`
+
+	// Query identifies the request the token was issued for, see listObjectsQueryHash.
+	Query string `json:"query"`
+}
+
+// ExecutePage executes the ListObjectsQuery and returns a page of the objects sorted by object id, starting
+// after the object the continuation token of the request points at. Since the objects are resolved
+// concurrently, every page resolves all the objects (until q.listObjectsDeadline is hit) and then sorts them,
+// so that the pages are consistent with each other as long as the tuples of the store don't change: every page
+// costs as much as resolving all the objects.
+//
+// The continuation token is only issued if every object was resolved. A page continuing a previous one fails with
+// serverErrors.RequestDeadlineExceeded if the deadline is hit, since the objects missing from it can't be told
+// apart from the objects of the previous pages.
+func (q *ListObjectsQuery) ExecutePage(
+	ctx context.Context,
+	req *ListObjectsPageRequest,
+) (*ListObjectsPageResponse, error) {
+	ctx, done := observeCommand(ctx, "ListObjects", req.Request.GetStoreId())
+	defer done()
+
+	queryHash, err := listObjectsQueryHash(req.Request)
+	if err != nil {
+		return nil, serverErrors.NewInternalError("", err)
+	}
+
+	var token listObjectsContinuationToken
+	if req.ContinuationToken != "" {
+		decoded, err := q.encoder.Decode(req.ContinuationToken)
+		if err != nil {
+			return nil, serverErrors.InvalidContinuationToken
+		}
+
+		if err := json.Unmarshal(decoded, &token); err != nil || token.Query != queryHash {
+			return nil, serverErrors.InvalidContinuationToken
+		}
+	}
+
+	pageSize := q.listObjectsMaxResults
+	if req.PageSize > 0 && (pageSize == 0 || uint32(req.PageSize) < pageSize) {
+		pageSize = uint32(req.PageSize)
+	}
+
+	objects, incomplete, err := q.collect(ctx, req.Request, math.MaxUint32, streamedBufferSize)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(objects)
+
+	if incomplete && req.ContinuationToken != "" {
+		return nil, serverErrors.RequestDeadlineExceeded
+	}
+
+	start := sort.SearchStrings(objects, token.After)
+	for start < len(objects) && objects[start] <= token.After {
+		start++
+	}
+	objects = objects[start:]
+
+	resp := &ListObjectsPageResponse{Objects: objects, Incomplete: incomplete}
+	if pageSize > 0 && uint32(len(objects)) > pageSize {
+		resp.Objects = objects[:pageSize]
+		if incomplete {
+			return resp, nil
+		}
+
+		marshalled, err := json.Marshal(&listObjectsContinuationToken{
+			After: resp.Objects[pageSize-1],
+			Query: queryHash,
+		})
+		if err != nil {
+			return nil, serverErrors.NewInternalError("", err)
+		}
+
+		resp.ContinuationToken, err = q.encoder.Encode(marshalled)
+		if err != nil {
+			return nil, serverErrors.NewInternalError("", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// listObjectsQueryHash identifies a ListObjects request, including its authorization model and contextual tuples,
+// so that the continuation token of a request cannot be used to page through another.
+func listObjectsQueryHash(req *openfgav1.ListObjectsRequest) (string, error) {
+	contextualTuples, err := proto.MarshalOptions{Deterministic: true}.Marshal(req.GetContextualTuples())
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{
+		req.GetStoreId(),
+		req.GetAuthorizationModelId(),
+		req.GetType(),
+		req.GetRelation(),
+		req.GetUser(),
+	}, "|")))
+	h.Write([]byte("|"))
+	h.Write(contextualTuples)
+
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
 // ExecuteStreamed executes the ListObjectsQuery, returning a stream of object IDs.
 // It ignores the value of q.listObjectsMaxResults and returns all available results
-// until q.listObjectsDeadline is hit
+// until q.listObjectsDeadline is hit (see q.deadlineExceeded)
 func (q *ListObjectsQuery) ExecuteStreamed(
 	ctx context.Context,
 	req *openfgav1.StreamedListObjectsRequest,
 	srv openfgav1.OpenFGAService_StreamedListObjectsServer,
 ) error {
+	ctx, done := observeCommand(ctx, "StreamedListObjects", req.GetStoreId())
+	defer done()
 
 	maxResults := uint32(math.MaxUint32)
-	// make a buffered channel so that writer goroutines aren't blocked when attempting to send a result
-	resultsChan := make(chan ListObjectsResult, streamedBufferSize)
+	// make a buffered channel so that writer goroutines aren't blocked while the client receives the previous
+	// results; once it is full, they wait for the client
+	resultsChan := make(chan ListObjectsResult, q.streamBufferSize)
 
-	timeoutCtx := ctx
-	if q.listObjectsDeadline != 0 {
-		var cancel context.CancelFunc
-		timeoutCtx, cancel = context.WithTimeout(ctx, q.listObjectsDeadline)
-		defer cancel()
-	}
+	timeoutCtx, cancel := q.resolutionContext(ctx)
+	defer cancel()
 
 	err := q.evaluate(timeoutCtx, req, resultsChan, maxResults)
 	if err != nil {
@@ -374,11 +774,7 @@ func (q *ListObjectsQuery) ExecuteStreamed(
 		select {
 
 		case <-timeoutCtx.Done():
-			q.logger.WarnWithContext(
-				ctx, "list objects timeout with list object configuration timeout",
-				zap.String("timeout duration", q.listObjectsDeadline.String()),
-			)
-			return nil
+			return q.deadlineExceeded(ctx)
 
 		case result, channelOpen := <-resultsChan:
 			if !channelOpen {
@@ -394,11 +790,43 @@ func (q *ListObjectsQuery) ExecuteStreamed(
 				return serverErrors.HandleError("", result.Err)
 			}
 
-			if err := srv.Send(&openfgav1.StreamedListObjectsResponse{
+			if err := q.send(srv, &openfgav1.StreamedListObjectsResponse{
 				Object: result.ObjectID,
 			}); err != nil {
+				if errors.Is(err, serverErrors.StreamSendTimeout) {
+					q.logger.WarnWithContext(
+						ctx, "list objects client did not receive a result in time",
+						zap.String("send timeout", q.streamSendTimeout.String()),
+					)
+					return err
+				}
+
 				return serverErrors.NewInternalError("", err)
 			}
 		}
 	}
 }
+
+// send sends the response to the client of ExecuteStreamed. If the client does not receive it within
+// q.streamSendTimeout, it fails with serverErrors.StreamSendTimeout; the stream must then be ended without
+// sending anything else, which also unblocks the pending send.
+func (q *ListObjectsQuery) send(srv openfgav1.OpenFGAService_StreamedListObjectsServer, resp *openfgav1.StreamedListObjectsResponse) error {
+	if q.streamSendTimeout <= 0 {
+		return srv.Send(resp)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Send(resp)
+	}()
+
+	timer := time.NewTimer(q.streamSendTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-timer.C:
+		return serverErrors.StreamSendTimeout
+	}
+}
@@ -25,6 +25,16 @@ func NewListStoresQuery(storesBackend storage.StoresBackend, logger logger.Logge
 }
 
 func (q *ListStoresQuery) Execute(ctx context.Context, req *openfgav1.ListStoresRequest) (*openfgav1.ListStoresResponse, error) {
+	return q.execute(ctx, req, nil)
+}
+
+// ExecuteWithFilter is like Execute, but only the stores selected by the filter are listed, in the order of the
+// filter. The continuation token of the request must come from a response for the same filter.
+func (q *ListStoresQuery) ExecuteWithFilter(ctx context.Context, req *openfgav1.ListStoresRequest, filter storage.ListStoresFilter) (*openfgav1.ListStoresResponse, error) {
+	return q.execute(ctx, req, &filter)
+}
+
+func (q *ListStoresQuery) execute(ctx context.Context, req *openfgav1.ListStoresRequest, filter *storage.ListStoresFilter) (*openfgav1.ListStoresResponse, error) {
 	decodedContToken, err := q.encoder.Decode(req.GetContinuationToken())
 	if err != nil {
 		return nil, serverErrors.InvalidContinuationToken
@@ -32,7 +42,13 @@ func (q *ListStoresQuery) Execute(ctx context.Context, req *openfgav1.ListStores
 
 	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), string(decodedContToken))
 
-	stores, continuationToken, err := q.storesBackend.ListStores(ctx, paginationOptions)
+	var stores []*openfgav1.Store
+	var continuationToken []byte
+	if filter == nil {
+		stores, continuationToken, err = q.storesBackend.ListStores(ctx, paginationOptions)
+	} else {
+		stores, continuationToken, err = q.storesBackend.ListStoresWithFilter(ctx, *filter, paginationOptions)
+	}
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
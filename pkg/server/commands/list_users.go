@@ -0,0 +1,573 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"go.uber.org/zap"
+)
+
+// ListUsersRequest is a request to list all the users that have a relation with an object.
+type ListUsersRequest struct {
+	StoreID              string
+	AuthorizationModelID string
+	Object               string
+	Relation             string
+
+	// UserFilters optionally restricts the returned users to the provided user types (e.g. 'user').
+	UserFilters []string
+
+	ContextualTuples []*openfgav1.TupleKey
+}
+
+// ListUsersResponse contains the users (e.g. 'user:anne') and typed wildcards (e.g. 'user:*')
+// that have the requested relation with the requested object.
+type ListUsersResponse struct {
+	Users []string
+
+	// ExcludedUsers contains the users that are excluded from the typed wildcards in Users by an
+	// exclusion (e.g. 'define viewer: [user:*] but not blocked'), so that 'user:*' and 'user:anne'
+	// mean every user except anne.
+	ExcludedUsers []string
+}
+
+// ListUsersQuery is the inverse of ListObjectsQuery: given an object and a relation, it lists the
+// users that have that relation with the object. It expands the relation's rewrite rules forward
+// from the object, and if the relation involves an intersection or an exclusion every candidate
+// user is confirmed with a Check. A typed wildcard granted through an exclusion is returned along
+// with the users excluded from it (e.g. 'define viewer: [user:*] but not blocked').
+type ListUsersQuery struct {
+	datastore               storage.RelationshipTupleReader
+	logger                  logger.Logger
+	listUsersDeadline       time.Duration
+	listUsersMaxResults     uint32
+	resolveNodeLimit        uint32
+	resolveNodeBreadthLimit uint32
+	maxConcurrentReads      uint32
+}
+
+type ListUsersQueryOption func(q *ListUsersQuery)
+
+func WithListUsersLogger(l logger.Logger) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.logger = l
+	}
+}
+
+// WithListUsersDeadline sets the maximum amount of time to accumulate ListUsers results.
+func WithListUsersDeadline(deadline time.Duration) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.listUsersDeadline = deadline
+	}
+}
+
+// WithListUsersMaxResults sets the maximum number of users to return. If 0, all users are returned.
+func WithListUsersMaxResults(max uint32) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.listUsersMaxResults = max
+	}
+}
+
+// WithListUsersResolveNodeLimit see server.WithResolveNodeLimit
+func WithListUsersResolveNodeLimit(limit uint32) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.resolveNodeLimit = limit
+	}
+}
+
+// WithListUsersResolveNodeBreadthLimit see server.WithResolveNodeBreadthLimit
+func WithListUsersResolveNodeBreadthLimit(limit uint32) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.resolveNodeBreadthLimit = limit
+	}
+}
+
+// WithListUsersMaxConcurrentReads see server.WithMaxConcurrentReadsForListObjects
+func WithListUsersMaxConcurrentReads(max uint32) ListUsersQueryOption {
+	return func(q *ListUsersQuery) {
+		q.maxConcurrentReads = max
+	}
+}
+
+func NewListUsersQuery(ds storage.RelationshipTupleReader, opts ...ListUsersQueryOption) *ListUsersQuery {
+	query := &ListUsersQuery{
+		datastore:               ds,
+		logger:                  logger.NewNoopLogger(),
+		listUsersDeadline:       defaultListObjectsDeadline,
+		listUsersMaxResults:     defaultListObjectsMaxResults,
+		resolveNodeLimit:        defaultResolveNodeLimit,
+		resolveNodeBreadthLimit: defaultResolveNodeBreadthLimit,
+		maxConcurrentReads:      defaultMaxConcurrentReads,
+	}
+
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	return query
+}
+
+// listUsersExpansion holds the state of a single ListUsers evaluation.
+type listUsersExpansion struct {
+	ds          storage.RelationshipTupleReader
+	typesys     *typesystem.TypeSystem
+	storeID     string
+	userFilters map[string]struct{}
+
+	// visited contains the 'object#relation' pairs that have already been expanded
+	visited map[string]struct{}
+
+	// expandSubtracts makes the expansion of an exclusion expand the subtracted users as well, so that the
+	// users excluded from a typed wildcard can be found
+	expandSubtracts bool
+
+	// candidates contains the users found so far, in the order they were found
+	candidates   []string
+	candidateSet map[string]struct{}
+}
+
+// Execute lists the users that have the requested relation with the requested object. The typesystem
+// of the resolved authorization model must be present in the context.
+func (q *ListUsersQuery) Execute(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		panic("typesystem missing in context")
+	}
+
+	if !typesystem.IsSchemaVersionSupported(typesys.GetSchemaVersion()) {
+		return nil, serverErrors.ValidationError(typesystem.ErrInvalidSchemaVersion)
+	}
+
+	tk := tuple.NewTupleKey(req.Object, req.Relation, "")
+	if err := validation.ValidateObject(typesys, tk); err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+
+	objectType := tuple.GetType(req.Object)
+	if _, err := typesys.GetRelation(objectType, req.Relation); err != nil {
+		if errors.Is(err, typesystem.ErrObjectTypeUndefined) {
+			return nil, serverErrors.TypeNotFound(objectType)
+		}
+
+		if errors.Is(err, typesystem.ErrRelationUndefined) {
+			return nil, serverErrors.RelationNotFound(req.Relation, objectType, tk)
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	userFilters := make(map[string]struct{}, len(req.UserFilters))
+	for _, userType := range req.UserFilters {
+		if _, ok := typesys.GetTypeDefinition(userType); !ok {
+			return nil, serverErrors.TypeNotFound(userType)
+		}
+
+		userFilters[userType] = struct{}{}
+	}
+
+	for _, ctxTuple := range req.ContextualTuples {
+		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
+			return nil, serverErrors.HandleTupleValidateError(err)
+		}
+	}
+
+	if q.listUsersDeadline != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.listUsersDeadline)
+		defer cancel()
+	}
+
+	ds := storagewrappers.NewBoundedConcurrencyTupleReader(
+		storagewrappers.NewCombinedTupleReader(q.datastore, req.ContextualTuples),
+		q.maxConcurrentReads,
+	)
+
+	e := &listUsersExpansion{
+		ds:           ds,
+		typesys:      typesys,
+		storeID:      req.StoreID,
+		userFilters:  userFilters,
+		visited:      map[string]struct{}{},
+		candidateSet: map[string]struct{}{},
+	}
+
+	needsCheck, err := e.expand(ctx, req.Object, req.Relation, q.resolveNodeLimit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			q.logger.WarnWithContext(
+				ctx, "list users timeout with list users configuration timeout",
+				zap.String("timeout duration", q.listUsersDeadline.String()),
+			)
+
+			// unconfirmed candidates can only be returned if none of them needs to be confirmed by a Check
+			if q.involvesIntersectionOrExclusion(typesys, objectType, req.Relation) {
+				return &ListUsersResponse{Users: []string{}, ExcludedUsers: []string{}}, nil
+			}
+
+			return &ListUsersResponse{Users: q.truncate(e.candidates), ExcludedUsers: []string{}}, nil
+		}
+
+		if errors.Is(err, serverErrors.AuthorizationModelResolutionTooComplex) || errors.Is(err, serverErrors.CycleDetected) {
+			return nil, err
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	if !needsCheck {
+		return &ListUsersResponse{Users: q.truncate(e.candidates), ExcludedUsers: []string{}}, nil
+	}
+
+	// the relation involves an intersection or an exclusion, so the candidates found by expanding every
+	// branch of the rewrite are only potential users and must be confirmed
+	checkResolver := graph.NewLocalChecker(ds, graph.WithResolveNodeBreadthLimit(q.resolveNodeBreadthLimit))
+
+	users := make([]string, 0, len(e.candidates))
+	for _, candidate := range e.candidates {
+		if q.listUsersMaxResults > 0 && uint32(len(users)) >= q.listUsersMaxResults {
+			break
+		}
+
+		resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
+			StoreID:              req.StoreID,
+			AuthorizationModelID: typesys.GetAuthorizationModelID(),
+			TupleKey:             tuple.NewTupleKey(req.Object, req.Relation, candidate),
+			ContextualTuples:     req.ContextualTuples,
+			ResolutionMetadata: &graph.ResolutionMetadata{
+				Depth: q.resolveNodeLimit,
+			},
+		})
+		if err != nil {
+			if errors.Is(err, graph.ErrResolutionDepthExceeded) {
+				return nil, serverErrors.AuthorizationModelResolutionTooComplex
+			}
+
+			if errors.Is(err, graph.ErrCycleDetected) {
+				return nil, serverErrors.CycleDetected
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		if resp.Allowed {
+			users = append(users, candidate)
+		}
+	}
+
+	excludedUsers, err := q.excludedUsers(ctx, req, ds, checkResolver, users)
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+
+		// a typed wildcard can't be returned without all the users excluded from it
+		users = withoutTypedWildcards(users)
+		excludedUsers = []string{}
+	}
+
+	return &ListUsersResponse{Users: users, ExcludedUsers: excludedUsers}, nil
+}
+
+// excludedUsers returns the users that are excluded from the typed wildcards among the provided users, which
+// have already been confirmed with a Check. It expands the rewrite of the requested relation again, this time
+// including the subtracted operands of the exclusions, and every user found with the type of a typed wildcard
+// (e.g. 'user:anne' for 'user:*') that doesn't have the requested relation is excluded.
+func (q *ListUsersQuery) excludedUsers(
+	ctx context.Context,
+	req *ListUsersRequest,
+	ds storage.RelationshipTupleReader,
+	checkResolver graph.CheckResolver,
+	users []string,
+) ([]string, error) {
+	excludedUsers := []string{}
+
+	wildcardTypes := map[string]struct{}{}
+	userSet := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		userSet[user] = struct{}{}
+
+		if tuple.IsTypedWildcard(user) {
+			wildcardTypes[tuple.GetType(user)] = struct{}{}
+		}
+	}
+
+	if len(wildcardTypes) == 0 {
+		return excludedUsers, nil
+	}
+
+	typesys, _ := typesystem.TypesystemFromContext(ctx)
+
+	e := &listUsersExpansion{
+		ds:              ds,
+		typesys:         typesys,
+		storeID:         req.StoreID,
+		userFilters:     wildcardTypes,
+		expandSubtracts: true,
+		visited:         map[string]struct{}{},
+		candidateSet:    map[string]struct{}{},
+	}
+
+	if _, err := e.expand(ctx, req.Object, req.Relation, q.resolveNodeLimit); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, serverErrors.AuthorizationModelResolutionTooComplex) || errors.Is(err, serverErrors.CycleDetected) {
+			return nil, err
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	for _, candidate := range e.candidates {
+		if tuple.IsTypedWildcard(candidate) {
+			continue
+		}
+
+		if _, ok := userSet[candidate]; ok {
+			continue
+		}
+
+		resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
+			StoreID:              req.StoreID,
+			AuthorizationModelID: typesys.GetAuthorizationModelID(),
+			TupleKey:             tuple.NewTupleKey(req.Object, req.Relation, candidate),
+			ContextualTuples:     req.ContextualTuples,
+			ResolutionMetadata: &graph.ResolutionMetadata{
+				Depth: q.resolveNodeLimit,
+			},
+		})
+		if err != nil {
+			if errors.Is(err, graph.ErrResolutionDepthExceeded) {
+				return nil, serverErrors.AuthorizationModelResolutionTooComplex
+			}
+
+			if errors.Is(err, graph.ErrCycleDetected) {
+				return nil, serverErrors.CycleDetected
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		if !resp.Allowed {
+			excludedUsers = append(excludedUsers, candidate)
+		}
+	}
+
+	return excludedUsers, nil
+}
+
+// withoutTypedWildcards returns the provided users without the typed wildcards among them.
+func withoutTypedWildcards(users []string) []string {
+	filtered := make([]string, 0, len(users))
+	for _, user := range users {
+		if !tuple.IsTypedWildcard(user) {
+			filtered = append(filtered, user)
+		}
+	}
+
+	return filtered
+}
+
+// involvesIntersectionOrExclusion reports whether the provided relation may involve an intersection or an exclusion.
+// If this cannot be determined, it conservatively returns true.
+func (q *ListUsersQuery) involvesIntersectionOrExclusion(typesys *typesystem.TypeSystem, objectType, relation string) bool {
+	intersection, err := typesys.RelationInvolvesIntersection(objectType, relation)
+	if err != nil || intersection {
+		return true
+	}
+
+	exclusion, err := typesys.RelationInvolvesExclusion(objectType, relation)
+	return err != nil || exclusion
+}
+
+// truncate returns at most q.listUsersMaxResults users.
+func (q *ListUsersQuery) truncate(users []string) []string {
+	if q.listUsersMaxResults > 0 && uint32(len(users)) > q.listUsersMaxResults {
+		return users[:q.listUsersMaxResults]
+	}
+
+	return users
+}
+
+// expand collects the users related to 'object#relation' into e.candidates. It reports whether
+// any of the expanded rewrites involves an intersection or an exclusion, in which case the
+// candidates are a superset of the actual users.
+func (e *listUsersExpansion) expand(ctx context.Context, object, relation string, depth uint32) (bool, error) {
+	if depth == 0 {
+		return false, serverErrors.AuthorizationModelResolutionTooComplex
+	}
+
+	key := tuple.ToObjectRelationString(object, relation)
+	if _, ok := e.visited[key]; ok {
+		return false, nil
+	}
+	e.visited[key] = struct{}{}
+
+	rel, err := e.typesys.GetRelation(tuple.GetType(object), relation)
+	if err != nil {
+		// the relation is not defined on this type (e.g. a tupleset pointing to a type without the computed relation)
+		return false, nil
+	}
+
+	return e.expandRewrite(ctx, object, relation, rel.GetRewrite(), depth)
+}
+
+func (e *listUsersExpansion) expandRewrite(ctx context.Context, object, relation string, rewrite *openfgav1.Userset, depth uint32) (bool, error) {
+	switch rw := rewrite.Userset.(type) {
+	case *openfgav1.Userset_This:
+		return e.expandDirect(ctx, object, relation, depth)
+	case *openfgav1.Userset_ComputedUserset:
+		return e.expand(ctx, object, rw.ComputedUserset.GetRelation(), depth)
+	case *openfgav1.Userset_TupleToUserset:
+		return e.expandTTU(ctx, object, rw.TupleToUserset, depth)
+	case *openfgav1.Userset_Union:
+		return e.expandChildren(ctx, object, relation, rw.Union.GetChild(), depth, false)
+	case *openfgav1.Userset_Intersection:
+		return e.expandChildren(ctx, object, relation, rw.Intersection.GetChild(), depth, true)
+	case *openfgav1.Userset_Difference:
+		// only the base can contribute users, the subtracted users are removed by the final Check
+		if _, err := e.expandRewrite(ctx, object, relation, rw.Difference.GetBase(), depth); err != nil {
+			return false, err
+		}
+
+		if e.expandSubtracts {
+			if _, err := e.expandRewrite(ctx, object, relation, rw.Difference.GetSubtract(), depth); err != nil {
+				return false, err
+			}
+		}
+
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected userset rewrite type encountered")
+	}
+}
+
+func (e *listUsersExpansion) expandChildren(ctx context.Context, object, relation string, children []*openfgav1.Userset, depth uint32, needsCheck bool) (bool, error) {
+	for _, child := range children {
+		childNeedsCheck, err := e.expandRewrite(ctx, object, relation, child, depth)
+		if err != nil {
+			return false, err
+		}
+
+		needsCheck = needsCheck || childNeedsCheck
+	}
+
+	return needsCheck, nil
+}
+
+// expandDirect reads the tuples directly assigned to 'object#relation' and follows the usersets among them.
+func (e *listUsersExpansion) expandDirect(ctx context.Context, object, relation string, depth uint32) (bool, error) {
+	iter, err := e.ds.Read(ctx, e.storeID, tuple.NewTupleKey(object, relation, ""))
+	if err != nil {
+		return false, err
+	}
+	defer iter.Stop()
+
+	var usersets []string
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if err == storage.ErrIteratorDone {
+				break
+			}
+
+			return false, err
+		}
+
+		user := t.GetKey().GetUser()
+		if tuple.IsObjectRelation(user) {
+			usersets = append(usersets, user)
+			continue
+		}
+
+		e.addCandidate(user)
+	}
+
+	needsCheck := false
+	for _, userset := range usersets {
+		usersetObject, usersetRelation := tuple.SplitObjectRelation(userset)
+
+		usersetNeedsCheck, err := e.expand(ctx, usersetObject, usersetRelation, depth-1)
+		if err != nil {
+			return false, err
+		}
+
+		needsCheck = needsCheck || usersetNeedsCheck
+	}
+
+	return needsCheck, nil
+}
+
+// expandTTU reads the tupleset of 'object' and expands the computed relation on each of the related objects.
+func (e *listUsersExpansion) expandTTU(ctx context.Context, object string, ttu *openfgav1.TupleToUserset, depth uint32) (bool, error) {
+	iter, err := e.ds.Read(ctx, e.storeID, tuple.NewTupleKey(object, ttu.GetTupleset().GetRelation(), ""))
+	if err != nil {
+		return false, err
+	}
+	defer iter.Stop()
+
+	var tuplesetObjects []string
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if err == storage.ErrIteratorDone {
+				break
+			}
+
+			return false, err
+		}
+
+		user := t.GetKey().GetUser()
+		if tuple.IsObjectRelation(user) || tuple.IsWildcard(user) {
+			// tuplesets only relate objects directly
+			continue
+		}
+
+		tuplesetObjects = append(tuplesetObjects, user)
+	}
+
+	needsCheck := false
+	for _, tuplesetObject := range tuplesetObjects {
+		tuplesetNeedsCheck, err := e.expand(ctx, tuplesetObject, ttu.GetComputedUserset().GetRelation(), depth-1)
+		if err != nil {
+			return false, err
+		}
+
+		needsCheck = needsCheck || tuplesetNeedsCheck
+	}
+
+	return needsCheck, nil
+}
+
+// addCandidate records a user (or a typed wildcard) if it matches the user filters.
+func (e *listUsersExpansion) addCandidate(user string) {
+	if len(e.userFilters) > 0 {
+		userType, _ := tuple.SplitObject(user)
+		if _, ok := e.userFilters[userType]; !ok {
+			return
+		}
+	}
+
+	if _, ok := e.candidateSet[user]; ok {
+		return
+	}
+
+	e.candidateSet[user] = struct{}{}
+	e.candidates = append(e.candidates, user)
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListUsersQuery(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type group
+		  relations
+		    define member: [user, group#member] as self
+
+		type folder
+		  relations
+		    define viewer: [user] as self
+
+		type document
+		  relations
+		    define parent: [folder] as self
+		    define blocked: [user] as self
+		    define editor: [user] as self
+		    define viewer: [user, user:*, group#member] as self or editor or viewer from parent
+		    define can_view as viewer but not blocked
+		`),
+	}
+
+	err := ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "editor", "user:bob"),
+		tuple.NewTupleKey("document:1", "blocked", "user:charlie"),
+		tuple.NewTupleKey("group:eng", "member", "user:charlie"),
+		tuple.NewTupleKey("group:eng", "member", "group:platform#member"),
+		tuple.NewTupleKey("group:platform", "member", "user:dave"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:erin"),
+		tuple.NewTupleKey("document:2", "viewer", "user:*"),
+		tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:2", "blocked", "user:charlie"),
+		tuple.NewTupleKey("document:2", "blocked", "user:anne"),
+	})
+	require.NoError(t, err)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+
+	tests := []struct {
+		name                  string
+		request               *ListUsersRequest
+		expectedUsers         []string
+		expectedExcludedUsers []string
+		expectedError         error
+	}{
+		{
+			name: "direct_computed_userset_and_ttu",
+			request: &ListUsersRequest{
+				Object:   "document:1",
+				Relation: "viewer",
+			},
+			expectedUsers: []string{"user:anne", "user:bob", "user:charlie", "user:dave", "user:erin"},
+		},
+		{
+			name: "exclusion",
+			request: &ListUsersRequest{
+				Object:   "document:1",
+				Relation: "can_view",
+			},
+			expectedUsers: []string{"user:anne", "user:bob", "user:dave", "user:erin"},
+		},
+		{
+			name: "user_filters",
+			request: &ListUsersRequest{
+				Object:      "document:1",
+				Relation:    "editor",
+				UserFilters: []string{"group"},
+			},
+			expectedUsers: []string{},
+		},
+		{
+			name: "wildcard",
+			request: &ListUsersRequest{
+				Object:   "document:2",
+				Relation: "viewer",
+			},
+			expectedUsers: []string{"user:*", "user:anne"},
+		},
+		{
+			name: "wildcard_exclusion",
+			request: &ListUsersRequest{
+				Object:   "document:2",
+				Relation: "can_view",
+			},
+			expectedUsers:         []string{"user:*"},
+			expectedExcludedUsers: []string{"user:anne", "user:charlie"},
+		},
+		{
+			name: "wildcard_exclusion_with_user_filters",
+			request: &ListUsersRequest{
+				Object:      "document:2",
+				Relation:    "can_view",
+				UserFilters: []string{"group"},
+			},
+			expectedUsers: []string{},
+		},
+		{
+			name: "contextual_tuples",
+			request: &ListUsersRequest{
+				Object:   "document:3",
+				Relation: "viewer",
+				ContextualTuples: []*openfgav1.TupleKey{
+					tuple.NewTupleKey("document:3", "editor", "user:frank"),
+				},
+			},
+			expectedUsers: []string{"user:frank"},
+		},
+		{
+			name: "undefined_relation",
+			request: &ListUsersRequest{
+				Object:   "document:1",
+				Relation: "owner",
+			},
+			expectedError: serverErrors.RelationNotFound("owner", "document", tuple.NewTupleKey("document:1", "owner", "")),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.request.StoreID = storeID
+			test.request.AuthorizationModelID = model.Id
+
+			resp, err := NewListUsersQuery(ds).Execute(ctx, test.request)
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.ElementsMatch(t, test.expectedUsers, resp.Users)
+			require.ElementsMatch(t, test.expectedExcludedUsers, resp.ExcludedUsers)
+		})
+	}
+}
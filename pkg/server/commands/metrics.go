@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// otherStoresLabel is the store_id label of the commands on the stores seen once the per-store labels limit is
+// reached, see EnablePerStoreMetrics.
+const otherStoresLabel = "other"
+
+var (
+	commandDurationHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                            "command_duration_ms",
+		Help:                            "Time spent executing a command (e.g. Check, ListObjects), by command and store",
+		Buckets:                         []float64{1, 3, 5, 10, 25, 50, 100, 250, 500, 1000, 5000}, // milliseconds
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	}, []string{"command", "store_id"})
+
+	commandDatastoreQueriesHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "command_datastore_query_count",
+		Help:    "Number of datastore round trips of a command, by command and store",
+		Buckets: []float64{0, 1, 3, 5, 10, 25, 50, 100, 250, 500, 1000},
+	}, []string{"command", "store_id"})
+
+	commandDispatchesHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "command_dispatch_count",
+		Help:    "Number of subproblems dispatched by the resolver of a command, by command and store",
+		Buckets: []float64{0, 1, 3, 5, 10, 25, 50, 100, 250, 500, 1000},
+	}, []string{"command", "store_id"})
+
+	commandCheckCacheLookupsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "command_check_cache_lookup_count",
+		Help: "Number of subproblems of a command looked up in the check cache, by command, store and result (hit or miss)",
+	}, []string{"command", "store_id", "result"})
+)
+
+// storeLabels guards the cardinality of the store_id label of the command metrics.
+var storeLabels = &storeLabeler{}
+
+// EnablePerStoreMetrics labels the command metrics with the id of their store, for up to maxStores stores: the
+// commands on the stores seen after those are labelled "other". Unless enabled, the store_id label is empty.
+func EnablePerStoreMetrics(maxStores uint32) {
+	storeLabels.enable(maxStores)
+}
+
+// storeLabeler hands out the store_id labels of the command metrics. It is safe for concurrent use.
+type storeLabeler struct {
+	mu        sync.RWMutex
+	enabled   bool
+	maxStores int
+	stores    map[string]struct{}
+}
+
+func (l *storeLabeler) enable(maxStores uint32) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.enabled = true
+	l.maxStores = int(maxStores)
+	l.stores = make(map[string]struct{})
+}
+
+// label returns the store_id label of a command on the store.
+func (l *storeLabeler) label(storeID string) string {
+	l.mu.RLock()
+	enabled := l.enabled
+	_, seen := l.stores[storeID]
+	l.mu.RUnlock()
+
+	if !enabled {
+		return ""
+	}
+
+	if seen {
+		return storeID
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.stores[storeID]; ok {
+		return storeID
+	}
+
+	if len(l.stores) >= l.maxStores {
+		return otherStoresLabel
+	}
+
+	l.stores[storeID] = struct{}{}
+
+	return storeID
+}
+
+// observeCommand starts observing the execution of a command on the store, and returns the context to execute it
+// with and the function to call once it is done, which records its metrics. The datastore queries, dispatches and
+// check cache lookups of the command are those counted in the graph.ResolutionStats of the returned context: the
+// one of ctx if any (its counts must then only be those of the command), or a new one.
+func observeCommand(ctx context.Context, command string, storeID string) (context.Context, func()) {
+	stats := graph.ResolutionStatsFromContext(ctx)
+	if stats == nil {
+		stats = &graph.ResolutionStats{}
+		ctx = graph.ContextWithResolutionStats(ctx, stats)
+	}
+
+	start := time.Now()
+
+	return ctx, func() {
+		store := storeLabels.label(storeID)
+
+		commandDurationHistogram.WithLabelValues(command, store).Observe(float64(time.Since(start).Milliseconds()))
+		commandDatastoreQueriesHistogram.WithLabelValues(command, store).Observe(float64(stats.DatastoreQueries()))
+		commandDispatchesHistogram.WithLabelValues(command, store).Observe(float64(stats.Dispatches()))
+
+		if lookups, hits := stats.CacheLookups(); lookups > 0 {
+			commandCheckCacheLookupsCounter.WithLabelValues(command, store, "hit").Add(float64(hits))
+			commandCheckCacheLookupsCounter.WithLabelValues(command, store, "miss").Add(float64(lookups - hits))
+		}
+	}
+}
+
+// ObserveCheck is like observeCommand for the Check command, which is executed by the server itself.
+func ObserveCheck(ctx context.Context, storeID string) (context.Context, func()) {
+	return observeCommand(ctx, "Check", storeID)
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreLabeler(t *testing.T) {
+	l := &storeLabeler{}
+	require.Equal(t, "", l.label("store1"))
+
+	l.enable(2)
+	require.Equal(t, "store1", l.label("store1"))
+	require.Equal(t, "store2", l.label("store2"))
+	require.Equal(t, otherStoresLabel, l.label("store3"))
+
+	// the stores seen before the limit was reached keep their label
+	require.Equal(t, "store1", l.label("store1"))
+	require.Equal(t, otherStoresLabel, l.label("store3"))
+}
+
+func TestObserveCommand(t *testing.T) {
+	t.Run("reuses_the_stats_of_the_context", func(t *testing.T) {
+		stats := &graph.ResolutionStats{}
+		ctx := graph.ContextWithResolutionStats(context.Background(), stats)
+
+		ctx, done := observeCommand(ctx, "Read", "store")
+		graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+		done()
+
+		require.EqualValues(t, 1, stats.DatastoreQueries())
+	})
+
+	t.Run("attaches_stats_to_the_context", func(t *testing.T) {
+		ctx, done := observeCommand(context.Background(), "Read", "store")
+		defer done()
+
+		require.NotNil(t, graph.ResolutionStatsFromContext(ctx))
+	})
+}
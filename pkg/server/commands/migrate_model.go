@@ -0,0 +1,400 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// MigrateModelRequest is a request to migrate a schema 1.0 authorization model to schema 1.1.
+type MigrateModelRequest struct {
+	StoreID string
+
+	// AuthorizationModelID is the schema 1.0 model to migrate, or the latest model of the store if empty.
+	AuthorizationModelID string
+
+	// TypeRestrictions are the directly related user types of the relations, keyed by 'type#relation'. They take
+	// precedence over the types inferred from the tuples of the store.
+	TypeRestrictions map[string][]*openfgav1.RelationReference
+
+	// DryRun returns the migrated model without writing it.
+	DryRun bool
+}
+
+// MigrateModelResponse is the migrated authorization model.
+type MigrateModelResponse struct {
+	// AuthorizationModelID is the id of the written model, which is empty if the request is a dry run.
+	AuthorizationModelID string `json:"authorization_model_id,omitempty"`
+
+	Model *openfgav1.AuthorizationModel `json:"model"`
+
+	// Warnings describe the parts of the migration which may not be equivalent to the schema 1.0 model, e.g.
+	// tuples whose users cannot be expressed as a type restriction.
+	Warnings []string `json:"warnings"`
+}
+
+// MigrateModelCommand converts a schema 1.0 authorization model into a schema 1.1 model and writes it as a new
+// model of the store. The directly related user types of the relations are inferred from the tuples of the store,
+// unless they are provided in the request.
+type MigrateModelCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+	writeOpts []WriteAuthorizationModelCommandOption
+}
+
+type MigrateModelCommandOption func(c *MigrateModelCommand)
+
+// WithMigrateModelWriteOptions sets the options of the command writing the migrated model.
+func WithMigrateModelWriteOptions(opts ...WriteAuthorizationModelCommandOption) MigrateModelCommandOption {
+	return func(c *MigrateModelCommand) {
+		c.writeOpts = opts
+	}
+}
+
+func NewMigrateModelCommand(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...MigrateModelCommandOption) *MigrateModelCommand {
+	c := &MigrateModelCommand{
+		datastore: datastore,
+		logger:    logger,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Execute migrates the schema 1.0 model of the request.
+func (c *MigrateModelCommand) Execute(ctx context.Context, req *MigrateModelRequest) (*MigrateModelResponse, error) {
+	source, err := c.readModel(ctx, req.StoreID, req.AuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.GetSchemaVersion() != typesystem.SchemaVersion1_0 {
+		return nil, serverErrors.ValidationError(fmt.Errorf("the authorization model '%s' has schema version '%s', only models with schema version '%s' can be migrated", source.GetId(), source.GetSchemaVersion(), typesystem.SchemaVersion1_0))
+	}
+
+	inferred, warnings, err := c.inferTypeRestrictions(ctx, req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	typeDefinitions, migrationWarnings, err := migrateTypeDefinitions(source.GetTypeDefinitions(), inferred, req.TypeRestrictions)
+	if err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+	warnings = append(warnings, migrationWarnings...)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:              source.GetId(),
+		SchemaVersion:   typesystem.SchemaVersion1_1,
+		TypeDefinitions: typeDefinitions,
+	}
+
+	if _, err := typesystem.NewAndValidate(ctx, model); err != nil {
+		return nil, serverErrors.InvalidAuthorizationModelInput(err)
+	}
+
+	resp := &MigrateModelResponse{
+		Model:    model,
+		Warnings: warnings,
+	}
+
+	if req.DryRun {
+		return resp, nil
+	}
+
+	annotations, err := c.datastore.ReadAuthorizationModelAnnotations(ctx, req.StoreID, source.GetId())
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	writeReq := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         req.StoreID,
+		SchemaVersion:   model.GetSchemaVersion(),
+		TypeDefinitions: model.GetTypeDefinitions(),
+	}
+
+	write := NewWriteAuthorizationModelCommand(c.datastore, c.logger, c.writeOpts...)
+
+	var writeResp *openfgav1.WriteAuthorizationModelResponse
+	if len(annotations) == 0 {
+		writeResp, err = write.Execute(ctx, writeReq)
+	} else {
+		writeResp, err = write.ExecuteWithAnnotations(ctx, writeReq, annotations)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	model.Id = writeResp.GetAuthorizationModelId()
+	resp.AuthorizationModelID = model.GetId()
+
+	c.logger.InfoWithContext(ctx, "migrated authorization model",
+		zap.String("store_id", req.StoreID),
+		zap.String("source_authorization_model_id", source.GetId()),
+		zap.String("authorization_model_id", model.GetId()),
+		zap.Int("warnings", len(warnings)),
+	)
+
+	return resp, nil
+}
+
+func (c *MigrateModelCommand) readModel(ctx context.Context, storeID, modelID string) (*openfgav1.AuthorizationModel, error) {
+	if modelID == "" {
+		latestID, err := c.datastore.FindLatestAuthorizationModelID(ctx, storeID)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, serverErrors.LatestAuthorizationModelNotFound(storeID)
+			}
+			return nil, serverErrors.HandleError("", err)
+		}
+
+		modelID = latestID
+	}
+
+	model, err := c.datastore.ReadAuthorizationModel(ctx, storeID, modelID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.AuthorizationModelNotFound(modelID)
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return model, nil
+}
+
+// inferTypeRestrictions scans the tuples of the store and returns the user types of each relation, keyed by
+// 'type#relation'. Users which cannot be expressed as a type restriction are reported as warnings.
+func (c *MigrateModelCommand) inferTypeRestrictions(ctx context.Context, storeID string) (map[string]map[string]*openfgav1.RelationReference, []string, error) {
+	iter, err := c.datastore.Read(ctx, storeID, nil)
+	if err != nil {
+		return nil, nil, serverErrors.HandleError("", err)
+	}
+	defer iter.Stop()
+
+	inferred := map[string]map[string]*openfgav1.RelationReference{}
+	untyped := map[string]struct{}{}
+
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+			return nil, nil, serverErrors.HandleError("", err)
+		}
+
+		key := tuple.ToObjectRelationString(tuple.GetType(t.GetKey().GetObject()), t.GetKey().GetRelation())
+
+		ref, ok := userRelationReference(t.GetKey().GetUser())
+		if !ok {
+			untyped[key] = struct{}{}
+			continue
+		}
+
+		if _, ok := inferred[key]; !ok {
+			inferred[key] = map[string]*openfgav1.RelationReference{}
+		}
+		inferred[key][relationReferenceString(ref)] = ref
+	}
+
+	warnings := make([]string, 0, len(untyped))
+	for key := range untyped {
+		warnings = append(warnings, fmt.Sprintf("the relation '%s' has tuples with untyped users, which are not valid under schema version %s", key, typesystem.SchemaVersion1_1))
+	}
+	sort.Strings(warnings)
+
+	return inferred, warnings, nil
+}
+
+// migrateTypeDefinitions returns a copy of the type definitions with the type restrictions of their directly
+// assignable relations, and the type definitions of the user types which are not defined in the model.
+func migrateTypeDefinitions(
+	typeDefinitions []*openfgav1.TypeDefinition,
+	inferred map[string]map[string]*openfgav1.RelationReference,
+	overrides map[string][]*openfgav1.RelationReference,
+) ([]*openfgav1.TypeDefinition, []string, error) {
+	var warnings []string
+
+	defined := map[string]struct{}{}
+	migrated := make([]*openfgav1.TypeDefinition, 0, len(typeDefinitions))
+	for _, typeDefinition := range typeDefinitions {
+		defined[typeDefinition.GetType()] = struct{}{}
+		migrated = append(migrated, proto.Clone(typeDefinition).(*openfgav1.TypeDefinition))
+	}
+
+	for key := range overrides {
+		objectType, relation := tuple.SplitObjectRelation(key)
+		if _, ok := defined[objectType]; !ok {
+			return nil, nil, fmt.Errorf("type restrictions were provided for '%s', but the type '%s' is not defined", key, objectType)
+		}
+
+		rewrite := findRelationRewrite(migrated, objectType, relation)
+		if rewrite == nil || !typesystem.RewriteContainsSelf(rewrite) {
+			return nil, nil, fmt.Errorf("type restrictions were provided for '%s', which is not a directly assignable relation", key)
+		}
+	}
+
+	userTypes := map[string]struct{}{}
+	for _, typeDefinition := range migrated {
+		objectType := typeDefinition.GetType()
+
+		relations := make([]string, 0, len(typeDefinition.GetRelations()))
+		for relation := range typeDefinition.GetRelations() {
+			relations = append(relations, relation)
+		}
+		sort.Strings(relations)
+
+		for _, relation := range relations {
+			rewrite := typeDefinition.GetRelations()[relation]
+			if !typesystem.RewriteContainsSelf(rewrite) {
+				continue
+			}
+
+			key := tuple.ToObjectRelationString(objectType, relation)
+
+			restrictions, ok := overrides[key]
+			if !ok {
+				restrictions = sortedRelationReferences(inferred[key])
+			}
+
+			if len(restrictions) == 0 {
+				if !removeThisFromUnion(rewrite) {
+					return nil, nil, fmt.Errorf("the directly related user types of '%s' cannot be inferred because the relation has no typed tuples, provide its type restrictions", key)
+				}
+
+				warnings = append(warnings, fmt.Sprintf("the relation '%s' has no typed tuples, so it is no longer directly assignable", key))
+				continue
+			}
+
+			for _, ref := range restrictions {
+				userTypes[ref.GetType()] = struct{}{}
+			}
+
+			if typeDefinition.Metadata == nil {
+				typeDefinition.Metadata = &openfgav1.Metadata{}
+			}
+			if typeDefinition.Metadata.Relations == nil {
+				typeDefinition.Metadata.Relations = map[string]*openfgav1.RelationMetadata{}
+			}
+			typeDefinition.Metadata.Relations[relation] = &openfgav1.RelationMetadata{
+				DirectlyRelatedUserTypes: restrictions,
+			}
+		}
+	}
+
+	missing := make([]string, 0, len(userTypes))
+	for userType := range userTypes {
+		if _, ok := defined[userType]; !ok {
+			missing = append(missing, userType)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, userType := range missing {
+		migrated = append(migrated, &openfgav1.TypeDefinition{Type: userType})
+		warnings = append(warnings, fmt.Sprintf("the type '%s' was added because it is a directly related user type", userType))
+	}
+
+	return migrated, warnings, nil
+}
+
+// userRelationReference returns the type restriction matching a user of a tuple, which is false for the untyped
+// users of schema 1.0 models.
+func userRelationReference(user string) (*openfgav1.RelationReference, bool) {
+	if tuple.IsObjectRelation(user) {
+		object, relation := tuple.SplitObjectRelation(user)
+		objectType, _ := tuple.SplitObject(object)
+		if objectType == "" {
+			return nil, false
+		}
+		return typesystem.DirectRelationReference(objectType, relation), true
+	}
+
+	objectType, objectID := tuple.SplitObject(user)
+	if objectType == "" {
+		return nil, false
+	}
+
+	if objectID == tuple.Wildcard {
+		return typesystem.WildcardRelationReference(objectType), true
+	}
+
+	return typesystem.DirectRelationReference(objectType, ""), true
+}
+
+func relationReferenceString(ref *openfgav1.RelationReference) string {
+	if ref.GetWildcard() != nil {
+		return ref.GetType() + ":" + tuple.Wildcard
+	}
+
+	if ref.GetRelation() != "" {
+		return tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation())
+	}
+
+	return ref.GetType()
+}
+
+func sortedRelationReferences(refs map[string]*openfgav1.RelationReference) []*openfgav1.RelationReference {
+	keys := make([]string, 0, len(refs))
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*openfgav1.RelationReference, 0, len(keys))
+	for _, key := range keys {
+		sorted = append(sorted, refs[key])
+	}
+
+	return sorted
+}
+
+func findRelationRewrite(typeDefinitions []*openfgav1.TypeDefinition, objectType, relation string) *openfgav1.Userset {
+	for _, typeDefinition := range typeDefinitions {
+		if typeDefinition.GetType() == objectType {
+			return typeDefinition.GetRelations()[relation]
+		}
+	}
+
+	return nil
+}
+
+// removeThisFromUnion removes the direct relationship from a rewrite which is a union of it and other rewrites,
+// and returns false if the direct relationship is anywhere else.
+func removeThisFromUnion(rewrite *openfgav1.Userset) bool {
+	union := rewrite.GetUnion()
+	if union == nil {
+		return false
+	}
+
+	children := make([]*openfgav1.Userset, 0, len(union.GetChild()))
+	for _, child := range union.GetChild() {
+		if _, ok := child.Userset.(*openfgav1.Userset_This); ok {
+			continue
+		}
+		if typesystem.RewriteContainsSelf(child) {
+			return false
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 0 {
+		return false
+	}
+
+	union.Child = children
+	return true
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateModelCommand(t *testing.T) {
+	ctx := context.Background()
+
+	legacyModel := func() *openfgav1.AuthorizationModel {
+		return &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_0,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{
+					Type: "group",
+					Relations: map[string]*openfgav1.Userset{
+						"member": typesystem.This(),
+					},
+				},
+				{
+					Type: "folder",
+					Relations: map[string]*openfgav1.Userset{
+						"viewer": typesystem.This(),
+					},
+				},
+				{
+					Type: "document",
+					Relations: map[string]*openfgav1.Userset{
+						"owner":  typesystem.This(),
+						"parent": typesystem.This(),
+						"editor": typesystem.Union(typesystem.This(), typesystem.ComputedUserset("owner")),
+						"viewer": typesystem.Union(
+							typesystem.This(),
+							typesystem.ComputedUserset("editor"),
+							typesystem.TupleToUserset("parent", "viewer"),
+						),
+					},
+				},
+			},
+		}
+	}
+
+	setup := func(t *testing.T, model *openfgav1.AuthorizationModel, tupleKeys []*openfgav1.TupleKey) (storage.OpenFGADatastore, string) {
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		storeID := ulid.Make().String()
+
+		err := ds.WriteAuthorizationModelWithAnnotations(ctx, storeID, model, storage.ModelAnnotations{
+			"document": {Annotations: map[string]string{typesystem.OwnerAnnotation: "docs-team"}},
+		})
+		require.NoError(t, err)
+
+		err = ds.Write(ctx, storeID, nil, tupleKeys)
+		require.NoError(t, err)
+
+		return ds, storeID
+	}
+
+	tupleKeys := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:eng", "member", "user:anne"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:carl"),
+		tuple.NewTupleKey("document:1", "owner", "user:bob"),
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:2", "viewer", "user:*"),
+		tuple.NewTupleKey("document:3", "viewer", "anne"),
+	}
+
+	t.Run("type_restrictions_are_inferred_from_the_tuples", func(t *testing.T) {
+		source := legacyModel()
+		ds, storeID := setup(t, source, tupleKeys)
+
+		resp, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{StoreID: storeID})
+		require.NoError(t, err)
+		require.NotEmpty(t, resp.AuthorizationModelID)
+		require.NotEqual(t, source.GetId(), resp.AuthorizationModelID)
+		require.Equal(t, []string{
+			"the relation 'document#viewer' has tuples with untyped users, which are not valid under schema version 1.1",
+			"the relation 'document#editor' has no typed tuples, so it is no longer directly assignable",
+			"the type 'user' was added because it is a directly related user type",
+		}, resp.Warnings)
+
+		typesys := typesystem.New(resp.Model)
+
+		restrictions, err := typesys.GetDirectlyRelatedUserTypes("document", "viewer")
+		require.NoError(t, err)
+		require.Equal(t, []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("group", "member"),
+			typesystem.WildcardRelationReference("user"),
+		}, restrictions)
+
+		restrictions, err = typesys.GetDirectlyRelatedUserTypes("document", "parent")
+		require.NoError(t, err)
+		require.Equal(t, []*openfgav1.RelationReference{typesystem.DirectRelationReference("folder", "")}, restrictions)
+
+		editor, err := typesys.GetRelation("document", "editor")
+		require.NoError(t, err)
+		require.False(t, typesys.IsDirectlyAssignable(editor))
+
+		latestID, err := ds.FindLatestAuthorizationModelID(ctx, storeID)
+		require.NoError(t, err)
+		require.Equal(t, resp.AuthorizationModelID, latestID)
+
+		annotations, err := ds.ReadAuthorizationModelAnnotations(ctx, storeID, latestID)
+		require.NoError(t, err)
+		require.Equal(t, "docs-team", annotations["document"].GetAnnotations()[typesystem.OwnerAnnotation])
+	})
+
+	t.Run("dry_run_does_not_write_the_model", func(t *testing.T) {
+		source := legacyModel()
+		ds, storeID := setup(t, source, tupleKeys)
+
+		resp, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{
+			StoreID:              storeID,
+			AuthorizationModelID: source.GetId(),
+			DryRun:               true,
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.AuthorizationModelID)
+		require.Equal(t, typesystem.SchemaVersion1_1, resp.Model.GetSchemaVersion())
+
+		latestID, err := ds.FindLatestAuthorizationModelID(ctx, storeID)
+		require.NoError(t, err)
+		require.Equal(t, source.GetId(), latestID)
+	})
+
+	t.Run("type_restrictions_of_the_request_take_precedence", func(t *testing.T) {
+		ds, storeID := setup(t, legacyModel(), tupleKeys)
+
+		resp, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{
+			StoreID: storeID,
+			TypeRestrictions: map[string][]*openfgav1.RelationReference{
+				"document#editor": {typesystem.DirectRelationReference("user", "")},
+			},
+			DryRun: true,
+		})
+		require.NoError(t, err)
+
+		typesys := typesystem.New(resp.Model)
+		restrictions, err := typesys.GetDirectlyRelatedUserTypes("document", "editor")
+		require.NoError(t, err)
+		require.Equal(t, []*openfgav1.RelationReference{typesystem.DirectRelationReference("user", "")}, restrictions)
+	})
+
+	t.Run("relations_without_tuples_require_type_restrictions", func(t *testing.T) {
+		ds, storeID := setup(t, legacyModel(), nil)
+
+		_, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{StoreID: storeID})
+		require.ErrorContains(t, err, "the directly related user types of 'group#member' cannot be inferred")
+	})
+
+	t.Run("type_restrictions_of_undefined_relations_are_rejected", func(t *testing.T) {
+		ds, storeID := setup(t, legacyModel(), tupleKeys)
+
+		_, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{
+			StoreID: storeID,
+			TypeRestrictions: map[string][]*openfgav1.RelationReference{
+				"document#reader": {typesystem.DirectRelationReference("user", "")},
+			},
+		})
+		require.ErrorContains(t, err, "'document#reader', which is not a directly assignable relation")
+	})
+
+	t.Run("only_schema_1_0_models_are_migrated", func(t *testing.T) {
+		model := legacyModel()
+		model.SchemaVersion = typesystem.SchemaVersion1_1
+		ds, storeID := setup(t, model, nil)
+
+		_, err := NewMigrateModelCommand(ds, logger.NewNoopLogger()).Execute(ctx, &MigrateModelRequest{StoreID: storeID})
+		require.ErrorContains(t, err, "only models with schema version '1.0' can be migrated")
+	})
+}
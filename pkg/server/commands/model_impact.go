@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"google.golang.org/grpc/status"
+)
+
+const defaultMaxReportedInvalidTuples = 1000
+
+// AuthorizationModelImpactRequest is a request to analyze the impact of writing a proposed authorization model
+// to a store, without writing it.
+type AuthorizationModelImpactRequest struct {
+	StoreID string
+
+	// SchemaVersion and TypeDefinitions are the proposed authorization model.
+	SchemaVersion   string
+	TypeDefinitions []*openfgav1.TypeDefinition
+
+	// IncludeAssertions re-evaluates the assertions of the current authorization model under the proposed
+	// authorization model.
+	IncludeAssertions bool
+
+	// AuthorizationModelID is the current authorization model, or the latest one of the store if empty.
+	AuthorizationModelID string
+}
+
+// InvalidTuple is an existing tuple which is invalid under the proposed authorization model.
+type InvalidTuple struct {
+	TupleKey *openfgav1.TupleKey `json:"tuple_key"`
+	Reason   string              `json:"reason"`
+}
+
+// FlippedAssertion is an assertion whose result differs between the current and the proposed authorization
+// models. Error is set if the assertion cannot be evaluated under the proposed authorization model.
+type FlippedAssertion struct {
+	TupleKey        *openfgav1.TupleKey `json:"tuple_key"`
+	Expectation     bool                `json:"expectation"`
+	CurrentAllowed  bool                `json:"current_allowed"`
+	ProposedAllowed bool                `json:"proposed_allowed"`
+	Error           string              `json:"error,omitempty"`
+}
+
+// AuthorizationModelImpactResponse is the impact of a proposed authorization model.
+type AuthorizationModelImpactResponse struct {
+	TuplesScanned int `json:"tuples_scanned"`
+
+	// InvalidTupleCount is the number of invalid tuples, of which at most the maximum number of reported
+	// invalid tuples are listed in InvalidTuples.
+	InvalidTupleCount int             `json:"invalid_tuple_count"`
+	InvalidTuples     []*InvalidTuple `json:"invalid_tuples"`
+
+	AssertionsEvaluated int                 `json:"assertions_evaluated"`
+	FlippedAssertions   []*FlippedAssertion `json:"flipped_assertions"`
+}
+
+// AuthorizationModelImpactQuery scans the tuples of a store to report the tuples which would become invalid
+// under a proposed authorization model, and optionally the assertions whose result would flip.
+type AuthorizationModelImpactQuery struct {
+	datastore                storage.OpenFGADatastore
+	logger                   logger.Logger
+	maxReportedInvalidTuples int
+	checkOpts                []BatchCheckQueryOption
+}
+
+type AuthorizationModelImpactQueryOption func(q *AuthorizationModelImpactQuery)
+
+// WithMaxReportedInvalidTuples sets the maximum number of invalid tuples listed in the response. The invalid
+// tuples beyond this number are only counted.
+func WithMaxReportedInvalidTuples(max int) AuthorizationModelImpactQueryOption {
+	return func(q *AuthorizationModelImpactQuery) {
+		q.maxReportedInvalidTuples = max
+	}
+}
+
+// WithAuthorizationModelImpactCheckOptions sets the options of the Checks evaluating the assertions.
+func WithAuthorizationModelImpactCheckOptions(opts ...BatchCheckQueryOption) AuthorizationModelImpactQueryOption {
+	return func(q *AuthorizationModelImpactQuery) {
+		q.checkOpts = opts
+	}
+}
+
+func NewAuthorizationModelImpactQuery(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...AuthorizationModelImpactQueryOption) *AuthorizationModelImpactQuery {
+	q := &AuthorizationModelImpactQuery{
+		datastore:                datastore,
+		logger:                   logger,
+		maxReportedInvalidTuples: defaultMaxReportedInvalidTuples,
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Execute analyzes the impact of the proposed authorization model. If req.IncludeAssertions is true, the
+// typesystem of the current authorization model must be present in the context.
+func (q *AuthorizationModelImpactQuery) Execute(ctx context.Context, req *AuthorizationModelImpactRequest) (*AuthorizationModelImpactResponse, error) {
+	schemaVersion := req.SchemaVersion
+	if schemaVersion == "" {
+		schemaVersion = typesystem.SchemaVersion1_1
+	}
+
+	proposed, err := typesystem.NewAndValidate(ctx, &openfgav1.AuthorizationModel{
+		Id:              ulid.Make().String(),
+		SchemaVersion:   schemaVersion,
+		TypeDefinitions: req.TypeDefinitions,
+	})
+	if err != nil {
+		return nil, serverErrors.InvalidAuthorizationModelInput(err)
+	}
+
+	resp := &AuthorizationModelImpactResponse{
+		InvalidTuples:     []*InvalidTuple{},
+		FlippedAssertions: []*FlippedAssertion{},
+	}
+
+	if err := q.scanTuples(ctx, req.StoreID, proposed, resp); err != nil {
+		return nil, err
+	}
+
+	if req.IncludeAssertions {
+		current, ok := typesystem.TypesystemFromContext(ctx)
+		if !ok {
+			panic("typesystem missing in context")
+		}
+
+		if err := q.evaluateAssertions(ctx, req.StoreID, current, proposed, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (q *AuthorizationModelImpactQuery) scanTuples(ctx context.Context, storeID string, proposed *typesystem.TypeSystem, resp *AuthorizationModelImpactResponse) error {
+	iter, err := q.datastore.Read(ctx, storeID, nil)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+	defer iter.Stop()
+
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return nil
+			}
+			return serverErrors.HandleError("", err)
+		}
+
+		resp.TuplesScanned++
+
+		if err := validation.ValidateTuple(proposed, t.GetKey()); err != nil {
+			resp.InvalidTupleCount++
+			if len(resp.InvalidTuples) < q.maxReportedInvalidTuples {
+				resp.InvalidTuples = append(resp.InvalidTuples, &InvalidTuple{TupleKey: t.GetKey(), Reason: err.Error()})
+			}
+		}
+	}
+}
+
+func (q *AuthorizationModelImpactQuery) evaluateAssertions(ctx context.Context, storeID string, current, proposed *typesystem.TypeSystem, resp *AuthorizationModelImpactResponse) error {
+	assertions, err := q.datastore.ReadAssertions(ctx, storeID, current.GetAuthorizationModelID())
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	if len(assertions) == 0 {
+		return nil
+	}
+
+	tupleKeys := make([]*openfgav1.TupleKey, 0, len(assertions))
+	for _, assertion := range assertions {
+		tupleKeys = append(tupleKeys, assertion.GetTupleKey())
+	}
+
+	// the batch is as large as the assertions, and the proposed model must not share the cache of the stored models
+	opts := append(append([]BatchCheckQueryOption{}, q.checkOpts...), WithMaxChecksPerBatch(0), WithBatchCheckCache(nil))
+	check := NewBatchCheckQuery(storagewrappers.NewConditionEvaluatingTupleReader(q.datastore), opts...)
+
+	currentResults, err := check.Execute(typesystem.ContextWithTypesystem(ctx, current), &BatchCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: current.GetAuthorizationModelID(),
+		TupleKeys:            tupleKeys,
+	})
+	if err != nil {
+		return err
+	}
+
+	proposedResults, err := check.Execute(typesystem.ContextWithTypesystem(ctx, proposed), &BatchCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: proposed.GetAuthorizationModelID(),
+		TupleKeys:            tupleKeys,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp.AssertionsEvaluated = len(assertions)
+	for i, assertion := range assertions {
+		currentResult, proposedResult := currentResults.Results[i], proposedResults.Results[i]
+		if currentResult.Err == nil && proposedResult.Err == nil && currentResult.Allowed == proposedResult.Allowed {
+			continue
+		}
+
+		flipped := &FlippedAssertion{
+			TupleKey:        assertion.GetTupleKey(),
+			Expectation:     assertion.GetExpectation(),
+			CurrentAllowed:  currentResult.Allowed,
+			ProposedAllowed: proposedResult.Allowed,
+		}
+		if proposedResult.Err != nil {
+			flipped.ProposedAllowed = false
+			flipped.Error = status.Convert(proposedResult.Err).Message()
+		}
+
+		resp.FlippedAssertions = append(resp.FlippedAssertions, flipped)
+	}
+
+	return nil
+}
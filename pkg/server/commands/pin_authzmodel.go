@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// PinAuthorizationModelCommand pins an authorization model of a store, so that the requests without an
+// authorization model id are evaluated against it instead of the latest model of the store. A new model can
+// then be written and tested by id before it is pinned.
+type PinAuthorizationModelCommand struct {
+	backend storage.AuthorizationModelBackend
+	logger  logger.Logger
+}
+
+func NewPinAuthorizationModelCommand(backend storage.AuthorizationModelBackend, logger logger.Logger) *PinAuthorizationModelCommand {
+	return &PinAuthorizationModelCommand{
+		backend: backend,
+		logger:  logger,
+	}
+}
+
+func (c *PinAuthorizationModelCommand) Execute(ctx context.Context, storeID, modelID string) error {
+	if _, err := ulid.Parse(modelID); err != nil {
+		return serverErrors.AuthorizationModelNotFound(modelID)
+	}
+
+	err := c.backend.PinAuthorizationModel(ctx, storeID, modelID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.AuthorizationModelNotFound(modelID)
+		}
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// UnpinAuthorizationModelCommand unpins the pinned authorization model of a store, if any, so that the requests
+// without an authorization model id are evaluated against the latest model of the store again.
+type UnpinAuthorizationModelCommand struct {
+	backend storage.AuthorizationModelBackend
+	logger  logger.Logger
+}
+
+func NewUnpinAuthorizationModelCommand(backend storage.AuthorizationModelBackend, logger logger.Logger) *UnpinAuthorizationModelCommand {
+	return &UnpinAuthorizationModelCommand{
+		backend: backend,
+		logger:  logger,
+	}
+}
+
+func (c *UnpinAuthorizationModelCommand) Execute(ctx context.Context, storeID string) error {
+	if err := c.backend.UnpinAuthorizationModel(ctx, storeID); err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+// ReadPinnedAuthorizationModelQuery returns the id of the pinned authorization model of a store, or an empty
+// string if no model is pinned.
+type ReadPinnedAuthorizationModelQuery struct {
+	backend storage.AuthorizationModelReadBackend
+	logger  logger.Logger
+}
+
+func NewReadPinnedAuthorizationModelQuery(backend storage.AuthorizationModelReadBackend, logger logger.Logger) *ReadPinnedAuthorizationModelQuery {
+	return &ReadPinnedAuthorizationModelQuery{
+		backend: backend,
+		logger:  logger,
+	}
+}
+
+func (q *ReadPinnedAuthorizationModelQuery) Execute(ctx context.Context, storeID string) (string, error) {
+	modelID, err := q.backend.ReadPinnedAuthorizationModelID(ctx, storeID)
+	if err != nil {
+		return "", serverErrors.HandleError("", err)
+	}
+
+	return modelID, nil
+}
@@ -0,0 +1,315 @@
+// Package planner chooses the strategy used to resolve a ListObjects request, based on cardinality statistics
+// collected from the tuples of the store.
+package planner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("openfga/pkg/server/commands/planner")
+
+const (
+	defaultStatisticsTTL     = time.Minute
+	defaultSampleSize        = 100_000
+	defaultStatisticsTimeout = 30 * time.Second
+	statisticsPageSize       = 1000
+)
+
+// Strategy is a way of resolving a ListObjects request.
+type Strategy int
+
+const (
+	// ReverseExpansion walks the relationships backwards from the user to the objects it is related to, and only
+	// checks the objects found through intersections or exclusions. See connectedobjects.ConnectedObjectsQuery.
+	ReverseExpansion Strategy = iota
+
+	// ConcurrentChecks lists every object of the requested type and concurrently checks the relation on each of
+	// them.
+	ConcurrentChecks
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case ReverseExpansion:
+		return "reverse_expansion"
+	case ConcurrentChecks:
+		return "concurrent_checks"
+	default:
+		return "undefined"
+	}
+}
+
+// ParseStrategy returns the Strategy named `name`, see Strategy.String.
+func ParseStrategy(name string) (Strategy, error) {
+	for _, s := range []Strategy{ReverseExpansion, ConcurrentChecks} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown ListObjects strategy '%s'", name)
+}
+
+// Statistics are the cardinality statistics of the tuples of a store.
+type Statistics struct {
+	// Tuples is the number of tuples of each relation, keyed by 'objectType#relation'.
+	Tuples map[string]uint32
+
+	// Users is the number of distinct users of each relation, keyed by 'objectType#relation'.
+	Users map[string]uint32
+
+	// Objects is the number of distinct objects of each object type.
+	Objects map[string]uint32
+
+	// Complete is false if the store holds more tuples than the sample size, in which case the statistics only
+	// describe the first tuples of the store and every count is a lower bound.
+	Complete bool
+
+	// CollectedAt is when the statistics were collected.
+	CollectedAt time.Time
+}
+
+// Planner chooses the Strategy of each ListObjects request. The statistics of a store are collected the first
+// time a request is planned for it and cached for the statistics TTL. Planning never waits for the statistics to
+// be collected: until they are available, requests are resolved with ReverseExpansion.
+type Planner struct {
+	datastore     storage.RelationshipTupleReader
+	logger        logger.Logger
+	statisticsTTL time.Duration
+	sampleSize    uint32
+
+	mu         sync.Mutex
+	statistics map[string]*Statistics
+	collecting map[string]struct{}
+}
+
+type PlannerOption func(p *Planner)
+
+// WithStatisticsTTL sets how long the statistics of a store are used before they are collected again.
+func WithStatisticsTTL(ttl time.Duration) PlannerOption {
+	return func(p *Planner) {
+		p.statisticsTTL = ttl
+	}
+}
+
+// WithSampleSize sets the maximum number of tuples of a store read to collect its statistics.
+func WithSampleSize(size uint32) PlannerOption {
+	return func(p *Planner) {
+		p.sampleSize = size
+	}
+}
+
+func WithLogger(l logger.Logger) PlannerOption {
+	return func(p *Planner) {
+		p.logger = l
+	}
+}
+
+func New(ds storage.RelationshipTupleReader, opts ...PlannerOption) *Planner {
+	p := &Planner{
+		datastore:     ds,
+		logger:        logger.NewNoopLogger(),
+		statisticsTTL: defaultStatisticsTTL,
+		sampleSize:    defaultSampleSize,
+		statistics:    map[string]*Statistics{},
+		collecting:    map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Plan chooses the strategy to list the objects of the objectType that the user is related to through the
+// relation. The user is a reference to the type (and relation, if the user is a userset) of the user of the
+// request.
+//
+// The cost of the reverse expansion is estimated as the number of tuples it reads: for each relation it goes
+// through, the average number of tuples per user of the relation. The cost of the concurrent checks is estimated
+// as one read per relation that could relate the user to the object, for each object of the type. ConcurrentChecks
+// is only chosen if it is estimated to be cheaper and the statistics of the store are complete, since the number
+// of objects of a sampled store is unknown.
+func (p *Planner) Plan(
+	ctx context.Context,
+	typesys *typesystem.TypeSystem,
+	storeID, objectType, relation string,
+	user *openfgav1.RelationReference,
+) Strategy {
+	_, span := tracer.Start(ctx, "planner.Plan", trace.WithAttributes(
+		attribute.String("object_type", objectType),
+		attribute.String("relation", relation),
+	))
+	defer span.End()
+
+	stats := p.cachedStatistics(ctx, storeID)
+	if stats == nil || !stats.Complete {
+		return ReverseExpansion
+	}
+
+	g := graph.BuildConnectedObjectGraph(typesys)
+
+	ingresses, err := g.RelationshipIngresses(typesystem.DirectRelationReference(objectType, relation), user)
+	if err != nil || len(ingresses) == 0 {
+		return ReverseExpansion
+	}
+
+	expansionCost := 1.0
+	for _, ingress := range ingresses {
+		ref := ingress.Ingress
+		if ingress.Type == graph.TupleToUsersetIngress {
+			ref = ingress.TuplesetRelation
+		}
+
+		key := tuple.ToObjectRelationString(ref.GetType(), ref.GetRelation())
+		if users := stats.Users[key]; users > 0 {
+			expansionCost += float64(stats.Tuples[key]) / float64(users)
+		}
+	}
+
+	checksCost := float64(stats.Objects[objectType]) * float64(len(ingresses))
+
+	strategy := ReverseExpansion
+	if checksCost < expansionCost {
+		strategy = ConcurrentChecks
+	}
+
+	span.SetAttributes(
+		attribute.Float64("expansion_cost", expansionCost),
+		attribute.Float64("checks_cost", checksCost),
+		attribute.String("strategy", strategy.String()),
+	)
+
+	return strategy
+}
+
+// Statistics returns the statistics of the store, collecting them if they are not cached or have expired.
+func (p *Planner) Statistics(ctx context.Context, storeID string) (*Statistics, error) {
+	p.mu.Lock()
+	stats := p.statistics[storeID]
+	p.mu.Unlock()
+
+	if stats != nil && time.Since(stats.CollectedAt) < p.statisticsTTL {
+		return stats, nil
+	}
+
+	stats, err := p.collect(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.statistics[storeID] = stats
+	p.mu.Unlock()
+
+	return stats, nil
+}
+
+// cachedStatistics returns the cached statistics of the store, or nil if there are none. If the statistics are
+// missing or have expired, they are collected in the background. Expired statistics are returned until then.
+func (p *Planner) cachedStatistics(ctx context.Context, storeID string) *Statistics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.statistics[storeID]
+	if stats != nil && time.Since(stats.CollectedAt) < p.statisticsTTL {
+		return stats
+	}
+
+	if _, ok := p.collecting[storeID]; !ok {
+		p.collecting[storeID] = struct{}{}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(tenancy.WithTenantOf(context.Background(), ctx), defaultStatisticsTimeout)
+			defer cancel()
+
+			stats, err := p.collect(ctx, storeID)
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			delete(p.collecting, storeID)
+			if err != nil {
+				p.logger.Warn(fmt.Sprintf("failed to collect the statistics of store '%s': %v", storeID, err))
+				return
+			}
+
+			p.statistics[storeID] = stats
+		}()
+	}
+
+	return stats
+}
+
+// collect reads up to the sample size tuples of the store and counts them.
+func (p *Planner) collect(ctx context.Context, storeID string) (*Statistics, error) {
+	ctx, span := tracer.Start(ctx, "planner.collect")
+	defer span.End()
+
+	stats := &Statistics{
+		Tuples:      map[string]uint32{},
+		Users:       map[string]uint32{},
+		Objects:     map[string]uint32{},
+		Complete:    true,
+		CollectedAt: time.Now(),
+	}
+
+	users := map[string]map[string]struct{}{}
+	objects := map[string]struct{}{}
+
+	var read uint32
+	var from string
+	for {
+		tuples, token, err := p.datastore.ReadPage(ctx, storeID, nil, storage.PaginationOptions{PageSize: statisticsPageSize, From: from})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tuples {
+			if read == p.sampleSize {
+				stats.Complete = false
+				return stats, nil
+			}
+			read++
+
+			tk := t.GetKey()
+			objectType := tuple.GetType(tk.GetObject())
+			key := tuple.ToObjectRelationString(objectType, tk.GetRelation())
+
+			stats.Tuples[key]++
+
+			if users[key] == nil {
+				users[key] = map[string]struct{}{}
+			}
+			if _, ok := users[key][tk.GetUser()]; !ok {
+				users[key][tk.GetUser()] = struct{}{}
+				stats.Users[key]++
+			}
+
+			if _, ok := objects[tk.GetObject()]; !ok {
+				objects[tk.GetObject()] = struct{}{}
+				stats.Objects[objectType]++
+			}
+		}
+
+		if len(token) == 0 {
+			return stats, nil
+		}
+		from = string(token)
+	}
+}
@@ -0,0 +1,105 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanner(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	typesys := typesystem.New(&openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+
+	user := typesystem.DirectRelationReference("user", "")
+
+	// few documents, each viewable by many users
+	fewObjectsStore := ulid.Make().String()
+	var tuples []*openfgav1.TupleKey
+	for i := 0; i < 10; i++ {
+		for _, object := range []string{"document:1", "document:2"} {
+			tuples = append(tuples, tuple.NewTupleKey(object, "viewer", fmt.Sprintf("user:%d", i)))
+		}
+	}
+	require.NoError(t, ds.Write(ctx, fewObjectsStore, nil, tuples))
+
+	// many documents, each viewable by a single user
+	manyObjectsStore := ulid.Make().String()
+	tuples = nil
+	for i := 0; i < 20; i++ {
+		tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", fmt.Sprintf("user:%d", i)))
+	}
+	require.NoError(t, ds.Write(ctx, manyObjectsStore, nil, tuples))
+
+	t.Run("statistics_count_the_tuples_of_the_store", func(t *testing.T) {
+		stats, err := New(ds).Statistics(ctx, fewObjectsStore)
+		require.NoError(t, err)
+		require.True(t, stats.Complete)
+		require.Equal(t, map[string]uint32{"document#viewer": 20}, stats.Tuples)
+		require.Equal(t, map[string]uint32{"document#viewer": 10}, stats.Users)
+		require.Equal(t, map[string]uint32{"document": 2}, stats.Objects)
+	})
+
+	t.Run("statistics_are_sampled", func(t *testing.T) {
+		stats, err := New(ds, WithSampleSize(5)).Statistics(ctx, fewObjectsStore)
+		require.NoError(t, err)
+		require.False(t, stats.Complete)
+		require.Equal(t, uint32(5), stats.Tuples["document#viewer"])
+	})
+
+	t.Run("reverse_expansion_without_statistics", func(t *testing.T) {
+		p := New(ds)
+		require.Equal(t, ReverseExpansion, p.Plan(ctx, typesys, fewObjectsStore, "document", "viewer", user))
+
+		// the statistics are collected in the background
+		require.Eventually(t, func() bool {
+			return p.Plan(ctx, typesys, fewObjectsStore, "document", "viewer", user) == ConcurrentChecks
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("concurrent_checks_for_few_objects", func(t *testing.T) {
+		p := New(ds)
+		_, err := p.Statistics(ctx, fewObjectsStore)
+		require.NoError(t, err)
+
+		require.Equal(t, ConcurrentChecks, p.Plan(ctx, typesys, fewObjectsStore, "document", "viewer", user))
+	})
+
+	t.Run("reverse_expansion_for_many_objects", func(t *testing.T) {
+		p := New(ds)
+		_, err := p.Statistics(ctx, manyObjectsStore)
+		require.NoError(t, err)
+
+		require.Equal(t, ReverseExpansion, p.Plan(ctx, typesys, manyObjectsStore, "document", "viewer", user))
+	})
+
+	t.Run("reverse_expansion_for_sampled_statistics", func(t *testing.T) {
+		p := New(ds, WithSampleSize(5))
+		_, err := p.Statistics(ctx, fewObjectsStore)
+		require.NoError(t, err)
+
+		require.Equal(t, ReverseExpansion, p.Plan(ctx, typesys, fewObjectsStore, "document", "viewer", user))
+	})
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/pkg/encoder"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+)
+
+const defaultMaxPointInTimeChanges = 100000
+
+// PointInTime identifies a past state of a store, against which Checks and Reads are evaluated. Exactly one of AsOf
+// and ContinuationToken must be set.
+//
+// The state is reconstructed by undoing the changes of the changelog that occurred after the point in time, so it
+// must be within the retention period of the changelog. The tuples that expired since are read as they were at the
+// point in time, unless they were deleted since. The changelog does not record the conditions and expirations of the
+// tuples deleted since, so they are restored by Read, but the Checks which read them are rejected.
+type PointInTime struct {
+	// AsOf is the time at which the state of the store is evaluated.
+	AsOf time.Time
+
+	// ContinuationToken is a ReadChanges continuation token of a request without type: the state of the store is the
+	// one right after the changes read up to the token.
+	ContinuationToken string
+}
+
+// PointInTimeResolver builds readers over the past states of the stores from their changelog.
+type PointInTimeResolver struct {
+	datastore       storage.OpenFGADatastore
+	encoder         encoder.Encoder
+	retentionPeriod time.Duration
+	maxChanges      int
+}
+
+type PointInTimeResolverOption func(r *PointInTimeResolver)
+
+// WithPointInTimeChangelogRetentionPeriod sets how long the changes are kept in the changelog. The points in time
+// older than the retention period are rejected. If 0, every point in time is accepted.
+func WithPointInTimeChangelogRetentionPeriod(retentionPeriod time.Duration) PointInTimeResolverOption {
+	return func(r *PointInTimeResolver) {
+		r.retentionPeriod = retentionPeriod
+	}
+}
+
+// WithPointInTimeMaxChanges sets the maximum number of changes that can be undone to reconstruct a past state of a
+// store.
+func WithPointInTimeMaxChanges(maxChanges int) PointInTimeResolverOption {
+	return func(r *PointInTimeResolver) {
+		r.maxChanges = maxChanges
+	}
+}
+
+// NewPointInTimeResolver creates a PointInTimeResolver. The encoder decodes the continuation tokens of the points in
+// time, as it encodes those of ReadChanges.
+func NewPointInTimeResolver(datastore storage.OpenFGADatastore, encoder encoder.Encoder, opts ...PointInTimeResolverOption) *PointInTimeResolver {
+	r := &PointInTimeResolver{
+		datastore:  datastore,
+		encoder:    encoder,
+		maxChanges: defaultMaxPointInTimeChanges,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Resolve returns a reader over the tuples of the store as they were at the point in time.
+func (r *PointInTimeResolver) Resolve(ctx context.Context, storeID string, pointInTime PointInTime) (storagewrappers.ConditionalTupleReader, error) {
+	if pointInTime.AsOf.IsZero() == (pointInTime.ContinuationToken == "") {
+		return nil, serverErrors.ValidationError(fmt.Errorf("exactly one of the time and the continuation token of the point in time must be set"))
+	}
+
+	var from string
+	asOf := pointInTime.AsOf
+	if pointInTime.ContinuationToken != "" {
+		decoded, err := decodePaginationToken(r.encoder, encoder.TokenKindChanges, pointInTime.ContinuationToken)
+		if err != nil {
+			return nil, serverErrors.InvalidContinuationToken
+		}
+		from = decoded
+
+		tokenTime, ok := storage.ContinuationTokenTime(decoded)
+		if ok {
+			asOf = tokenTime
+		} else if r.retentionPeriod > 0 {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the continuation token of the point in time can't be checked against the retention period of the changelog"))
+		}
+	}
+
+	if !asOf.IsZero() {
+		if asOf.After(time.Now()) {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the point in time must be in the past"))
+		}
+
+		if r.retentionPeriod > 0 && asOf.Before(time.Now().Add(-r.retentionPeriod)) {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the point in time must be within the retention period of the changelog (%s)", r.retentionPeriod))
+		}
+	}
+
+	changes, err := storage.ReadChangesAfter(ctx, r.datastore, storeID, pointInTime.AsOf, from, r.maxChanges)
+	if err != nil {
+		if errors.Is(err, storage.ErrTooManyChanges) {
+			return nil, serverErrors.ValidationError(fmt.Errorf("more than %d changes occurred after the point in time", r.maxChanges))
+		}
+		if errors.Is(err, storage.ErrMismatchObjectType) {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the continuation token of the point in time must be one of a ReadChanges request without type"))
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	if asOf.IsZero() {
+		// the token holds no time: the state of the store is the same until the first change after it
+		asOf = time.Now()
+		if len(changes) > 0 {
+			asOf = changes[0].GetTimestamp().AsTime().Add(-time.Nanosecond)
+		}
+	}
+
+	return storagewrappers.NewPointInTimeTupleReader(r.datastore, changes, asOf), nil
+}
+
+// AuthorizationModelID returns the ID of the latest authorization model of the store at the point in time, or an
+// empty ID if it can't be determined from the point in time, in which case the latest model applies.
+func (r *PointInTimeResolver) AuthorizationModelID(ctx context.Context, storeID string, pointInTime PointInTime) (string, error) {
+	if pointInTime.AsOf.IsZero() {
+		return "", nil
+	}
+
+	// the models are read newest first, and the time of a model is the one of its ULID
+	var from string
+	for {
+		models, token, err := r.datastore.ReadAuthorizationModels(ctx, storeID, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from})
+		if err != nil {
+			return "", serverErrors.HandleError("", err)
+		}
+
+		for _, model := range models {
+			id, err := ulid.Parse(model.GetId())
+			if err != nil {
+				return "", serverErrors.HandleError("", err)
+			}
+
+			if !ulid.Time(id.Time()).After(pointInTime.AsOf) {
+				return model.GetId(), nil
+			}
+		}
+
+		if len(token) == 0 {
+			return "", serverErrors.ValidationError(fmt.Errorf("the store had no authorization model at the point in time"))
+		}
+		from = string(token)
+	}
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointInTimeResolver(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	storeID := ulid.Make().String()
+
+	anne := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+	bob := tuple.NewTupleKey("document:1", "viewer", "user:bob")
+	group := tuple.NewTupleKey("document:1", "viewer", "group:eng#member")
+
+	err := ds.WriteAuthorizationModel(ctx, storeID, &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+		},
+	})
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{anne, group})
+	require.NoError(t, err)
+
+	_, token, err := ds.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: storage.DefaultPageSize}, 0)
+	require.NoError(t, err)
+
+	time.Sleep(time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(time.Millisecond)
+
+	latestModel := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{Type: "group"},
+		},
+	}
+	err = ds.WriteAuthorizationModel(ctx, storeID, latestModel)
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, []*openfgav1.TupleKey{anne, group}, []*openfgav1.TupleKey{bob})
+	require.NoError(t, err)
+
+	encodedToken, err := encoder.NewBase64Encoder().Encode(token)
+	require.NoError(t, err)
+
+	resolver := NewPointInTimeResolver(ds, encoder.NewBase64Encoder())
+
+	readUsers := func(t *testing.T, reader storage.RelationshipTupleReader) []string {
+		iter, err := reader.Read(ctx, storeID, &openfgav1.TupleKey{Object: "document:1", Relation: "viewer"})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var users []string
+		for {
+			tup, err := iter.Next()
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return users
+			}
+			users = append(users, tup.GetKey().GetUser())
+		}
+	}
+
+	for name, pointInTime := range map[string]PointInTime{
+		"as_of_a_time":               {AsOf: asOf},
+		"as_of_a_continuation_token": {ContinuationToken: encodedToken},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := resolver.Resolve(ctx, storeID, pointInTime)
+			require.NoError(t, err)
+
+			require.ElementsMatch(t, []string{"user:anne", "group:eng#member"}, readUsers(t, reader))
+
+			_, err = reader.ReadUserTuple(ctx, storeID, bob)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+
+			tup, err := reader.ReadUserTuple(ctx, storeID, anne)
+			require.NoError(t, err)
+			require.Equal(t, anne.GetUser(), tup.GetKey().GetUser())
+
+			iter, err := reader.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{Object: "document:1", Relation: "viewer"})
+			require.NoError(t, err)
+			tup, err = iter.Next()
+			require.NoError(t, err)
+			require.Equal(t, group.GetUser(), tup.GetKey().GetUser())
+			_, err = iter.Next()
+			require.ErrorIs(t, err, storage.ErrIteratorDone)
+			iter.Stop()
+
+			// the conditions of the tuples deleted since are unknown
+			_, err = reader.ReadTupleConditions(ctx, storeID, anne)
+			require.ErrorIs(t, err, storagewrappers.ErrUnknownPointInTimeCondition)
+
+			_, err = reader.ReadTupleConditions(ctx, storeID, bob)
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("the_tuples_expired_since_are_read", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := ds.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{anne}, time.Now().Add(50*time.Millisecond))
+		require.NoError(t, err)
+
+		asOf := time.Now()
+		time.Sleep(100 * time.Millisecond)
+
+		_, err = ds.ReadUserTuple(ctx, storeID, anne)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		reader, err := resolver.Resolve(ctx, storeID, PointInTime{AsOf: asOf})
+		require.NoError(t, err)
+
+		_, err = reader.ReadUserTuple(ctx, storeID, anne)
+		require.NoError(t, err)
+	})
+
+	t.Run("the_current_state_is_read_as_of_now", func(t *testing.T) {
+		reader, err := resolver.Resolve(ctx, storeID, PointInTime{AsOf: time.Now()})
+		require.NoError(t, err)
+		require.Equal(t, []string{"user:bob"}, readUsers(t, reader))
+	})
+
+	t.Run("the_latest_model_at_the_point_in_time_is_resolved", func(t *testing.T) {
+		modelID, err := resolver.AuthorizationModelID(ctx, storeID, PointInTime{AsOf: asOf})
+		require.NoError(t, err)
+		require.NotEqual(t, latestModel.GetId(), modelID)
+		require.NotEmpty(t, modelID)
+
+		modelID, err = resolver.AuthorizationModelID(ctx, storeID, PointInTime{AsOf: time.Now()})
+		require.NoError(t, err)
+		require.Equal(t, latestModel.GetId(), modelID)
+	})
+
+	t.Run("invalid_points_in_time_are_rejected", func(t *testing.T) {
+		_, err := resolver.Resolve(ctx, storeID, PointInTime{})
+		require.ErrorContains(t, err, "exactly one of the time and the continuation token")
+
+		_, err = resolver.Resolve(ctx, storeID, PointInTime{AsOf: time.Now().Add(time.Hour)})
+		require.ErrorContains(t, err, "the point in time must be in the past")
+
+		_, err = NewPointInTimeResolver(ds, encoder.NewBase64Encoder(), WithPointInTimeChangelogRetentionPeriod(time.Hour)).
+			Resolve(ctx, storeID, PointInTime{AsOf: time.Now().Add(-2 * time.Hour)})
+		require.ErrorContains(t, err, "within the retention period of the changelog")
+
+		// the tokens of the memory datastore hold no time
+		_, err = NewPointInTimeResolver(ds, encoder.NewBase64Encoder(), WithPointInTimeChangelogRetentionPeriod(time.Hour)).
+			Resolve(ctx, storeID, PointInTime{ContinuationToken: encodedToken})
+		require.ErrorContains(t, err, "can't be checked against the retention period of the changelog")
+
+		_, err = NewPointInTimeResolver(ds, encoder.NewBase64Encoder(), WithPointInTimeMaxChanges(1)).
+			Resolve(ctx, storeID, PointInTime{AsOf: asOf})
+		require.ErrorContains(t, err, "more than 1 changes occurred after the point in time")
+	})
+}
@@ -0,0 +1,321 @@
+// Package quota enforces per-store quotas on the number of tuples of a store, the size and complexity of its
+// authorization models and the rate of its writes.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+)
+
+var tracer = otel.Tracer("openfga/pkg/server/commands/quota")
+
+const (
+	defaultTupleCountTTL     = 10 * time.Minute
+	defaultTupleCountTimeout = time.Minute
+	tupleCountPageSize       = 1000
+)
+
+// Limits are the quotas of a store. A zero limit is unlimited.
+type Limits struct {
+	// MaxTuplesPerStore is the maximum number of tuples of the store.
+	MaxTuplesPerStore uint32
+
+	// MaxTypesPerAuthorizationModel is the maximum number of type definitions of an authorization model.
+	MaxTypesPerAuthorizationModel uint32
+
+	// MaxRelationsPerType is the maximum number of relations of a type definition.
+	MaxRelationsPerType uint32
+
+	// MaxRewriteDepth is the maximum nesting depth of the rewrite of a relation, see typesystem.RewriteDepth.
+	MaxRewriteDepth uint32
+
+	// MaxAuthorizationModelSizeInBytes is the maximum size of the serialized type definitions of an authorization
+	// model.
+	MaxAuthorizationModelSizeInBytes uint32
+
+	// MaxWritesPerSecond is the maximum sustained rate of Write requests to the store.
+	MaxWritesPerSecond float64
+}
+
+// IsZero reports whether every limit is unlimited.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+type tupleCount struct {
+	count      int64
+	countedAt  time.Time
+	refreshing bool
+}
+
+// Enforcer enforces the quotas of the stores. The number of tuples of a store is counted the first time it is
+// needed and then kept up to date with the writes made through the Enforcer, and counted again in the
+// background every tuple count TTL to account for the writes made through other servers. The tuple quota is
+// therefore approximate: with several servers, a store may exceed it by the writes each server accepts within
+// the TTL. Enforcer instances may be safely shared by multiple goroutines.
+type Enforcer struct {
+	datastore     storage.RelationshipTupleReader
+	logger        logger.Logger
+	limits        Limits
+	storeLimits   map[string]Limits
+	tupleCountTTL time.Duration
+
+	mu            sync.Mutex
+	tupleCounts   map[string]*tupleCount
+	writeLimiters map[string]*rate.Limiter
+}
+
+type EnforcerOption func(e *Enforcer)
+
+// WithStoreLimits overrides the limits of the store with the provided id.
+func WithStoreLimits(storeID string, limits Limits) EnforcerOption {
+	return func(e *Enforcer) {
+		e.storeLimits[storeID] = limits
+	}
+}
+
+// WithTupleCountTTL sets how often the tuples of a store are counted again.
+func WithTupleCountTTL(ttl time.Duration) EnforcerOption {
+	return func(e *Enforcer) {
+		e.tupleCountTTL = ttl
+	}
+}
+
+func WithLogger(l logger.Logger) EnforcerOption {
+	return func(e *Enforcer) {
+		e.logger = l
+	}
+}
+
+// NewEnforcer constructs an Enforcer that applies the limits to every store, unless overridden with
+// WithStoreLimits.
+func NewEnforcer(ds storage.RelationshipTupleReader, limits Limits, opts ...EnforcerOption) *Enforcer {
+	e := &Enforcer{
+		datastore:     ds,
+		logger:        logger.NewNoopLogger(),
+		limits:        limits,
+		storeLimits:   map[string]Limits{},
+		tupleCountTTL: defaultTupleCountTTL,
+		tupleCounts:   map[string]*tupleCount{},
+		writeLimiters: map[string]*rate.Limiter{},
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Limits returns the limits of the store.
+func (e *Enforcer) Limits(storeID string) Limits {
+	if limits, ok := e.storeLimits[storeID]; ok {
+		return limits
+	}
+
+	return e.limits
+}
+
+// CheckAuthorizationModel returns an error if the authorization model exceeds the quotas of the store.
+func (e *Enforcer) CheckAuthorizationModel(storeID string, typeDefinitions []*openfgav1.TypeDefinition) error {
+	limits := e.Limits(storeID)
+
+	if limits.MaxTypesPerAuthorizationModel > 0 && len(typeDefinitions) > int(limits.MaxTypesPerAuthorizationModel) {
+		return serverErrors.ExceededEntityLimit("type definitions in an authorization model", int(limits.MaxTypesPerAuthorizationModel))
+	}
+
+	if limits.MaxRelationsPerType > 0 {
+		for _, td := range typeDefinitions {
+			if len(td.GetRelations()) > int(limits.MaxRelationsPerType) {
+				return serverErrors.ExceededEntityLimit(fmt.Sprintf("relations of type '%s'", td.GetType()), int(limits.MaxRelationsPerType))
+			}
+		}
+	}
+
+	if limits.MaxRewriteDepth > 0 {
+		for _, td := range typeDefinitions {
+			// the relations are checked in order, so that the same one is reported for the same model
+			relations := make([]string, 0, len(td.GetRelations()))
+			for relation := range td.GetRelations() {
+				relations = append(relations, relation)
+			}
+			sort.Strings(relations)
+
+			for _, relation := range relations {
+				if typesystem.RewriteDepth(td.GetRelations()[relation]) > int(limits.MaxRewriteDepth) {
+					return serverErrors.ExceededEntityLimit(fmt.Sprintf("nested rewrites of relation '%s#%s'", td.GetType(), relation), int(limits.MaxRewriteDepth))
+				}
+			}
+		}
+	}
+
+	if limits.MaxAuthorizationModelSizeInBytes > 0 {
+		size := 0
+		for _, td := range typeDefinitions {
+			size += proto.Size(td)
+		}
+
+		if size > int(limits.MaxAuthorizationModelSizeInBytes) {
+			return serverErrors.ExceededEntityLimit("bytes of the serialized type definitions of an authorization model", int(limits.MaxAuthorizationModelSizeInBytes))
+		}
+	}
+
+	return nil
+}
+
+// AllowWrite takes a write from the write rate quota of the store, and returns an error carrying the delay
+// after which a write is allowed if the quota is exhausted.
+func (e *Enforcer) AllowWrite(storeID string) error {
+	limits := e.Limits(storeID)
+	if limits.MaxWritesPerSecond <= 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	limiter, ok := e.writeLimiters[storeID]
+	if !ok {
+		burst := int(limits.MaxWritesPerSecond)
+		if burst < 1 {
+			burst = 1
+		}
+
+		limiter = rate.NewLimiter(rate.Limit(limits.MaxWritesPerSecond), burst)
+		e.writeLimiters[storeID] = limiter
+	}
+	e.mu.Unlock()
+
+	now := time.Now()
+	res := limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return serverErrors.RateLimitExceeded(fmt.Sprintf("The store has exceeded its quota of %v writes per second", limits.MaxWritesPerSecond), delay)
+	}
+
+	return nil
+}
+
+// CheckTupleCount returns an error if writing the tuples and deleting the deletes would take the store over its
+// tuple quota. Requests that do not increase the number of tuples are always allowed.
+func (e *Enforcer) CheckTupleCount(ctx context.Context, storeID string, deletes, writes int) error {
+	limits := e.Limits(storeID)
+	if limits.MaxTuplesPerStore == 0 || writes <= deletes {
+		return nil
+	}
+
+	count, err := e.tupleCount(ctx, storeID)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+
+	if count+int64(writes-deletes) > int64(limits.MaxTuplesPerStore) {
+		return serverErrors.QuotaExceeded("tuples", int(limits.MaxTuplesPerStore))
+	}
+
+	return nil
+}
+
+// RecordWrite updates the number of tuples of the store after a successful write. Deletes of missing tuples and
+// writes of existing tuples make the count drift until the tuples are counted again.
+func (e *Enforcer) RecordWrite(storeID string, deletes, writes int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if c, ok := e.tupleCounts[storeID]; ok {
+		c.count += int64(writes - deletes)
+		if c.count < 0 {
+			c.count = 0
+		}
+	}
+}
+
+// tupleCount returns the number of tuples of the store. The tuples are counted synchronously the first time,
+// and in the background once the count has expired.
+func (e *Enforcer) tupleCount(ctx context.Context, storeID string) (int64, error) {
+	e.mu.Lock()
+	c, ok := e.tupleCounts[storeID]
+	if ok {
+		count := c.count
+		if time.Since(c.countedAt) > e.tupleCountTTL && !c.refreshing {
+			c.refreshing = true
+			go e.refreshTupleCount(tenancy.WithTenantOf(context.Background(), ctx), storeID)
+		}
+		e.mu.Unlock()
+
+		return count, nil
+	}
+	e.mu.Unlock()
+
+	count, err := e.countTuples(ctx, storeID)
+	if err != nil {
+		return 0, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// a concurrent request may have counted the tuples and recorded writes in the meantime
+	if c, ok := e.tupleCounts[storeID]; ok {
+		return c.count, nil
+	}
+
+	e.tupleCounts[storeID] = &tupleCount{count: count, countedAt: time.Now()}
+
+	return count, nil
+}
+
+// refreshTupleCount counts the tuples of the store in the background. The context only carries the tenant of the
+// request, see tenancy.WithTenantOf.
+func (e *Enforcer) refreshTupleCount(ctx context.Context, storeID string) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTupleCountTimeout)
+	defer cancel()
+
+	count, err := e.countTuples(ctx, storeID)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c := e.tupleCounts[storeID]
+	c.refreshing = false
+	if err != nil {
+		e.logger.Warn(fmt.Sprintf("failed to count the tuples of store '%s': %v", storeID, err))
+		return
+	}
+
+	c.count = count
+	c.countedAt = time.Now()
+}
+
+// countTuples reads every tuple of the store and counts them.
+func (e *Enforcer) countTuples(ctx context.Context, storeID string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "quota.countTuples")
+	defer span.End()
+
+	var count int64
+	var from string
+	for {
+		tuples, token, err := e.datastore.ReadPage(ctx, storeID, nil, storage.PaginationOptions{PageSize: tupleCountPageSize, From: from})
+		if err != nil {
+			return 0, err
+		}
+
+		count += int64(len(tuples))
+
+		if len(token) == 0 {
+			return count, nil
+		}
+		from = string(token)
+	}
+}
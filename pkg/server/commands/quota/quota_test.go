@@ -0,0 +1,124 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func requireResourceExhausted(t *testing.T, err error) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(openfgav1.InternalErrorCode_resource_exhausted), st.Code())
+}
+
+func TestEnforcer(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	t.Run("tuples_per_store", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		var tuples []*openfgav1.TupleKey
+		for i := 0; i < 8; i++ {
+			tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+		}
+		require.NoError(t, ds.Write(ctx, storeID, nil, tuples))
+
+		e := NewEnforcer(ds, Limits{MaxTuplesPerStore: 10})
+
+		require.NoError(t, e.CheckTupleCount(ctx, storeID, 0, 2))
+		requireResourceExhausted(t, e.CheckTupleCount(ctx, storeID, 0, 3))
+
+		// deletes make room for writes, and requests that do not add tuples are always allowed
+		require.NoError(t, e.CheckTupleCount(ctx, storeID, 1, 3))
+		require.NoError(t, e.CheckTupleCount(ctx, storeID, 5, 0))
+
+		// the count is kept up to date with the recorded writes
+		e.RecordWrite(storeID, 0, 2)
+		requireResourceExhausted(t, e.CheckTupleCount(ctx, storeID, 0, 1))
+
+		e.RecordWrite(storeID, 4, 0)
+		require.NoError(t, e.CheckTupleCount(ctx, storeID, 0, 4))
+	})
+
+	t.Run("tuple_count_is_refreshed", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		e := NewEnforcer(ds, Limits{MaxTuplesPerStore: 1}, WithTupleCountTTL(time.Millisecond))
+		require.NoError(t, e.CheckTupleCount(ctx, storeID, 0, 1))
+
+		// a write made through another server is only seen once the tuples are counted again
+		require.NoError(t, ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")}))
+
+		require.Eventually(t, func() bool {
+			return e.CheckTupleCount(ctx, storeID, 0, 1) != nil
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("authorization_model_size", func(t *testing.T) {
+		typedefs := parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define viewer: [user] as self or owner
+		`)
+
+		require.NoError(t, NewEnforcer(ds, Limits{}).CheckAuthorizationModel("store", typedefs))
+		require.NoError(t, NewEnforcer(ds, Limits{MaxTypesPerAuthorizationModel: 2, MaxRelationsPerType: 2}).CheckAuthorizationModel("store", typedefs))
+
+		err := NewEnforcer(ds, Limits{MaxTypesPerAuthorizationModel: 1}).CheckAuthorizationModel("store", typedefs)
+		require.ErrorContains(t, err, "The number of type definitions in an authorization model exceeds the allowed limit of 1")
+
+		err = NewEnforcer(ds, Limits{MaxRelationsPerType: 1}).CheckAuthorizationModel("store", typedefs)
+		require.ErrorContains(t, err, "The number of relations of type 'document' exceeds the allowed limit of 1")
+
+		require.NoError(t, NewEnforcer(ds, Limits{MaxRewriteDepth: 2}).CheckAuthorizationModel("store", typedefs))
+
+		err = NewEnforcer(ds, Limits{MaxRewriteDepth: 1}).CheckAuthorizationModel("store", typedefs)
+		require.ErrorContains(t, err, "The number of nested rewrites of relation 'document#viewer' exceeds the allowed limit of 1")
+
+		require.NoError(t, NewEnforcer(ds, Limits{MaxAuthorizationModelSizeInBytes: 1024}).CheckAuthorizationModel("store", typedefs))
+
+		err = NewEnforcer(ds, Limits{MaxAuthorizationModelSizeInBytes: 16}).CheckAuthorizationModel("store", typedefs)
+		require.ErrorContains(t, err, "The number of bytes of the serialized type definitions of an authorization model exceeds the allowed limit of 16")
+	})
+
+	t.Run("writes_per_second", func(t *testing.T) {
+		e := NewEnforcer(ds, Limits{MaxWritesPerSecond: 2})
+
+		require.NoError(t, e.AllowWrite("store-a"))
+		require.NoError(t, e.AllowWrite("store-a"))
+		requireResourceExhausted(t, e.AllowWrite("store-a"))
+
+		// every store has its own quota
+		require.NoError(t, e.AllowWrite("store-b"))
+	})
+
+	t.Run("store_limits_override_the_defaults", func(t *testing.T) {
+		e := NewEnforcer(ds, Limits{MaxWritesPerSecond: 1}, WithStoreLimits("unlimited", Limits{}))
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, e.AllowWrite("unlimited"))
+		}
+
+		require.NoError(t, e.AllowWrite("limited"))
+		requireResourceExhausted(t, e.AllowWrite("limited"))
+	})
+}
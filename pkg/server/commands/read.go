@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/pkg/encoder"
 	"github.com/openfga/openfga/pkg/logger"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
@@ -21,46 +22,157 @@ type ReadQuery struct {
 	datastore storage.OpenFGADatastore
 	logger    logger.Logger
 	encoder   encoder.Encoder
+	codec     encoder.TokenCodec
+}
+
+type ReadQueryOption func(q *ReadQuery)
+
+// WithReadTokenCodec sets the format of the continuation tokens issued by the ReadQuery.
+func WithReadTokenCodec(codec encoder.TokenCodec) ReadQueryOption {
+	return func(q *ReadQuery) {
+		q.codec = codec
+	}
+}
+
+// ReadWithFilterRequest is a ReadRequest with the filters that the openfgav1.ReadRequest does not support, which
+// are pushed down into the datastore query.
+type ReadWithFilterRequest struct {
+	*openfgav1.ReadRequest
+
+	// Relations matches the tuples of any of the relations. It can't be combined with the relation of the tuple key.
+	Relations []string
+
+	// UserTypes matches the tuples whose user matches any of the type restrictions, e.g. 'user' to only read the
+	// users of type user, excluding the wildcard and the usersets. See storage.ReadFilter.
+	UserTypes []*openfgav1.RelationReference
+}
+
+// StreamedReadServer is the server side of a streamed Read. Every tuple that matches the
+// request is sent individually.
+type StreamedReadServer interface {
+	Context() context.Context
+	Send(*openfgav1.Tuple) error
 }
 
 // NewReadQuery creates a ReadQuery using the provided OpenFGA datastore implementation.
-func NewReadQuery(datastore storage.OpenFGADatastore, logger logger.Logger, encoder encoder.Encoder) *ReadQuery {
-	return &ReadQuery{
+func NewReadQuery(datastore storage.OpenFGADatastore, logger logger.Logger, tokenEncoder encoder.Encoder, opts ...ReadQueryOption) *ReadQuery {
+	q := &ReadQuery{
 		datastore: datastore,
 		logger:    logger,
-		encoder:   encoder,
+		encoder:   tokenEncoder,
+		codec:     encoder.NewProtoTokenCodec(),
 	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
 }
 
 // Execute the ReadQuery, returning paginated `openfga.Tuple`(s) that match the tuple. Return all tuples if the tuple is
 // nil or empty.
 func (q *ReadQuery) Execute(ctx context.Context, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
+	ctx, done := observeCommand(ctx, "Read", req.GetStoreId())
+	defer done()
+
+	return q.read(ctx, q.datastore, req)
+}
+
+// ExecuteAsOf is like Execute, but reads the tuples of the store as they were at a point in time. The tuples which
+// existed at the point in time but not anymore are all returned with the first page.
+func (q *ReadQuery) ExecuteAsOf(ctx context.Context, req *openfgav1.ReadRequest, resolver *PointInTimeResolver, pointInTime PointInTime) (*openfgav1.ReadResponse, error) {
+	ctx, done := observeCommand(ctx, "Read", req.GetStoreId())
+	defer done()
+
+	if err := validateReadTupleKey(req.GetTupleKey()); err != nil {
+		return nil, err
+	}
+
+	reader, err := resolver.Resolve(ctx, req.GetStoreId(), pointInTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.read(ctx, reader, req)
+}
+
+func (q *ReadQuery) read(ctx context.Context, reader storage.RelationshipTupleReader, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
 	store := req.GetStoreId()
 	tk := req.GetTupleKey()
 
-	// Restrict our reads due to some compatibility issues in one of our storage implementations.
-	if tk != nil {
-		objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
-		if objectType == "" || (objectID == "" && tk.GetUser() == "") {
-			return nil, serverErrors.ValidationError(
-				fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and both the object id and user cannot be empty"),
-			)
+	if err := validateReadTupleKey(tk); err != nil {
+		return nil, err
+	}
+
+	decodedContToken, err := decodePaginationToken(q.encoder, encoder.TokenKindRead, req.GetContinuationToken())
+	if err != nil {
+		return nil, serverErrors.InvalidContinuationToken
+	}
+
+	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), decodedContToken)
+
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	tuples, contToken, err := reader.ReadPage(ctx, store, tk, paginationOptions)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	encodedContToken, err := encodePaginationToken(q.encoder, q.codec, encoder.TokenKindRead, contToken)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return &openfgav1.ReadResponse{
+		Tuples:            tuples,
+		ContinuationToken: encodedContToken,
+	}, nil
+}
+
+// ExecuteWithFilter is like Execute, but the tuples are also filtered by the relations and the user types of the
+// request.
+func (q *ReadQuery) ExecuteWithFilter(ctx context.Context, req *ReadWithFilterRequest) (*openfgav1.ReadResponse, error) {
+	ctx, done := observeCommand(ctx, "Read", req.GetStoreId())
+	defer done()
+
+	tk := req.GetTupleKey()
+
+	if err := validateReadTupleKey(tk); err != nil {
+		return nil, err
+	}
+
+	if tk.GetRelation() != "" && len(req.Relations) > 0 {
+		return nil, serverErrors.ValidationError(
+			fmt.Errorf("the relation of the 'tuple_key' field can't be combined with the relations filter"),
+		)
+	}
+
+	for _, userType := range req.UserTypes {
+		if userType.GetType() == "" {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the type of every user type of the filter is required"))
 		}
 	}
 
-	decodedContToken, err := q.encoder.Decode(req.GetContinuationToken())
+	decodedContToken, err := decodePaginationToken(q.encoder, encoder.TokenKindRead, req.GetContinuationToken())
 	if err != nil {
 		return nil, serverErrors.InvalidContinuationToken
 	}
 
-	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), string(decodedContToken))
+	filter := storage.NewReadFilter(tk)
+	if len(req.Relations) > 0 {
+		filter.Relations = req.Relations
+	}
+	filter.UserTypes = req.UserTypes
+
+	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), decodedContToken)
 
-	tuples, contToken, err := q.datastore.ReadPage(ctx, store, tk, paginationOptions)
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	tuples, contToken, err := q.datastore.ReadPageWithFilter(ctx, req.GetStoreId(), filter, paginationOptions)
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
 
-	encodedContToken, err := q.encoder.Encode(contToken)
+	encodedContToken, err := encodePaginationToken(q.encoder, q.codec, encoder.TokenKindRead, contToken)
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
@@ -70,3 +182,53 @@ func (q *ReadQuery) Execute(ctx context.Context, req *openfgav1.ReadRequest) (*o
 		ContinuationToken: encodedContToken,
 	}, nil
 }
+
+// ExecuteStreamed executes the ReadQuery, streaming every `openfga.Tuple` that matches the tuple to the
+// provided server. All tuples are streamed if the tuple is nil or empty. The tuples are read directly from
+// the datastore iterator, so the page size and continuation token of the request are ignored.
+func (q *ReadQuery) ExecuteStreamed(ctx context.Context, req *openfgav1.ReadRequest, srv StreamedReadServer) error {
+	ctx, done := observeCommand(ctx, "StreamedRead", req.GetStoreId())
+	defer done()
+
+	tk := req.GetTupleKey()
+
+	if err := validateReadTupleKey(tk); err != nil {
+		return err
+	}
+
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	iter, err := q.datastore.Read(ctx, req.GetStoreId(), tk)
+	if err != nil {
+		return serverErrors.HandleError("", err)
+	}
+	defer iter.Stop()
+
+	for {
+		tuple, err := iter.Next()
+		if err != nil {
+			if err == storage.ErrIteratorDone {
+				return nil
+			}
+
+			return serverErrors.HandleError("", err)
+		}
+
+		if err := srv.Send(tuple); err != nil {
+			return serverErrors.NewInternalError("", err)
+		}
+	}
+}
+
+// validateReadTupleKey restricts our reads due to some compatibility issues in one of our storage implementations.
+func validateReadTupleKey(tk *openfgav1.TupleKey) error {
+	if tk != nil {
+		objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
+		if objectType == "" || (objectID == "" && tk.GetUser() == "") {
+			return serverErrors.ValidationError(
+				fmt.Errorf("the 'tuple_key' field was provided but the object type field is required and both the object id and user cannot be empty"),
+			)
+		}
+	}
+
+	return nil
+}
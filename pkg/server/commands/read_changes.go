@@ -6,38 +6,88 @@ import (
 	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/pkg/encoder"
 	"github.com/openfga/openfga/pkg/logger"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 )
 
+// ReadChangesWithFilterRequest is a ReadChangesRequest with the filters that the openfgav1.ReadChangesRequest does not
+// support. The continuation tokens are bound to the type of the request, like those of a ReadChangesRequest, so the
+// other filters can be changed between two pages.
+type ReadChangesWithFilterRequest struct {
+	*openfgav1.ReadChangesRequest
+
+	Relation string
+
+	// Operation returns either the writes or the deletes of tuples.
+	Operation *openfgav1.TupleOperation
+
+	// StartTime returns the changes that occurred at or after it.
+	StartTime time.Time
+}
+
 type ReadChangesQuery struct {
 	backend       storage.ChangelogBackend
 	logger        logger.Logger
 	encoder       encoder.Encoder
+	codec         encoder.TokenCodec
 	horizonOffset time.Duration
 }
 
+type ReadChangesQueryOption func(q *ReadChangesQuery)
+
+// WithReadChangesTokenCodec sets the format of the continuation tokens issued by the ReadChangesQuery.
+func WithReadChangesTokenCodec(codec encoder.TokenCodec) ReadChangesQueryOption {
+	return func(q *ReadChangesQuery) {
+		q.codec = codec
+	}
+}
+
 // NewReadChangesQuery creates a ReadChangesQuery with specified `ChangelogBackend` and `typeDefinitionReadBackend` to use for storage
-func NewReadChangesQuery(backend storage.ChangelogBackend, logger logger.Logger, encoder encoder.Encoder, horizonOffset int) *ReadChangesQuery {
-	return &ReadChangesQuery{
+func NewReadChangesQuery(backend storage.ChangelogBackend, logger logger.Logger, tokenEncoder encoder.Encoder, horizonOffset int, opts ...ReadChangesQueryOption) *ReadChangesQuery {
+	q := &ReadChangesQuery{
 		backend:       backend,
 		logger:        logger,
-		encoder:       encoder,
+		encoder:       tokenEncoder,
+		codec:         encoder.NewProtoTokenCodec(),
 		horizonOffset: time.Duration(horizonOffset) * time.Minute,
 	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
 }
 
 // Execute the ReadChangesQuery, returning paginated `openfga.TupleChange`(s) and a possibly non-empty continuation token.
 func (q *ReadChangesQuery) Execute(ctx context.Context, req *openfgav1.ReadChangesRequest) (*openfgav1.ReadChangesResponse, error) {
-	decodedContToken, err := q.encoder.Decode(req.GetContinuationToken())
+	return q.ExecuteWithFilter(ctx, &ReadChangesWithFilterRequest{ReadChangesRequest: req})
+}
+
+// ExecuteWithFilter is like Execute, but the changes are also filtered by the relation, the operation and the start
+// time of the request.
+func (q *ReadChangesQuery) ExecuteWithFilter(ctx context.Context, req *ReadChangesWithFilterRequest) (*openfgav1.ReadChangesResponse, error) {
+	ctx, done := observeCommand(ctx, "ReadChanges", req.GetStoreId())
+	defer done()
+
+	decodedContToken, err := decodePaginationToken(q.encoder, encoder.TokenKindChanges, req.GetContinuationToken())
 	if err != nil {
 		return nil, serverErrors.InvalidContinuationToken
 	}
-	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), string(decodedContToken))
+	paginationOptions := storage.NewPaginationOptions(req.GetPageSize().GetValue(), decodedContToken)
+
+	filter := storage.ReadChangesFilter{
+		ObjectType: req.GetType(),
+		Relation:   req.Relation,
+		Operation:  req.Operation,
+		StartTime:  req.StartTime,
+	}
 
-	changes, contToken, err := q.backend.ReadChanges(ctx, req.StoreId, req.Type, paginationOptions, q.horizonOffset)
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	changes, contToken, err := q.backend.ReadChangesWithFilter(ctx, req.GetStoreId(), filter, paginationOptions, q.horizonOffset)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return &openfgav1.ReadChangesResponse{
@@ -47,7 +97,7 @@ func (q *ReadChangesQuery) Execute(ctx context.Context, req *openfgav1.ReadChang
 		return nil, serverErrors.HandleError("", err)
 	}
 
-	encodedContToken, err := q.encoder.Encode(contToken)
+	encodedContToken, err := encodePaginationToken(q.encoder, q.codec, encoder.TokenKindChanges, contToken)
 	if err != nil {
 		return nil, serverErrors.HandleError("", err)
 	}
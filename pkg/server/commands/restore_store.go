@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// RestoreStoreRequest is a request to restore a backup archive into a new store.
+type RestoreStoreRequest struct {
+	// Archive is the backup archive, see BackupStoreCommand.
+	Archive io.Reader
+
+	// Name is the name of the new store. Defaults to the name of the backed up store.
+	Name string
+}
+
+// RestoreStoreCommand restores a backup archive written by BackupStoreCommand into a new store. The
+// authorization models keep their ids, so requests that reference a model id keep working against the
+// restored store.
+type RestoreStoreCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+}
+
+func NewRestoreStoreCommand(datastore storage.OpenFGADatastore, logger logger.Logger) *RestoreStoreCommand {
+	return &RestoreStoreCommand{
+		datastore: datastore,
+		logger:    logger,
+	}
+}
+
+// Execute creates a new store and restores the archive into it. If the archive is invalid or truncated, the
+// store created so far is deleted and a validation error is returned.
+func (c *RestoreStoreCommand) Execute(ctx context.Context, req *RestoreStoreRequest) (*openfgav1.CreateStoreResponse, error) {
+	ctx, span := tracer.Start(ctx, "restoreStore")
+	defer span.End()
+
+	gz, err := gzip.NewReader(req.Archive)
+	if err != nil {
+		return nil, serverErrors.ValidationError(fmt.Errorf("%w: %v", ErrInvalidBackup, err))
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(gz))
+
+	var header backupRecord
+	if err := dec.Decode(&header); err != nil || header.Kind != backupRecordHeader {
+		return nil, serverErrors.ValidationError(fmt.Errorf("%w: missing header", ErrInvalidBackup))
+	}
+
+	if header.Version != backupFormatVersion {
+		return nil, serverErrors.ValidationError(fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, header.Version))
+	}
+
+	var backedUp openfgav1.Store
+	if err := protojson.Unmarshal(header.Store, &backedUp); err != nil {
+		return nil, serverErrors.ValidationError(fmt.Errorf("%w: %v", ErrInvalidBackup, err))
+	}
+
+	name := req.Name
+	if name == "" {
+		name = backedUp.GetName()
+	}
+
+	store, err := c.datastore.CreateStore(ctx, &openfgav1.Store{
+		Id:   ulid.Make().String(),
+		Name: name,
+	})
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	if err := c.restore(ctx, store.GetId(), dec); err != nil {
+		if deleteErr := c.datastore.DeleteStore(ctx, store.GetId()); deleteErr != nil {
+			c.logger.ErrorWithContext(ctx, fmt.Sprintf("failed to delete partially restored store '%s': %v", store.GetId(), deleteErr))
+		}
+
+		if errors.Is(err, ErrInvalidBackup) {
+			return nil, serverErrors.ValidationError(err)
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return &openfgav1.CreateStoreResponse{
+		Id:        store.Id,
+		Name:      store.Name,
+		CreatedAt: store.CreatedAt,
+		UpdatedAt: store.UpdatedAt,
+	}, nil
+}
+
+// restore restores the records that follow the header into the store.
+func (c *RestoreStoreCommand) restore(ctx context.Context, storeID string, dec *json.Decoder) error {
+	writeOpts := []storage.TupleWriteOption{
+		storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore),
+		storage.WithOnMissingDelete(storage.OnMissingDeleteIgnore),
+	}
+
+	var pending []*openfgav1.TupleKey
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		err := c.datastore.Write(ctx, storeID, nil, pending, writeOpts...)
+		pending = pending[:0]
+
+		return err
+	}
+
+	records := 1 // the header
+	for {
+		var r backupRecord
+		if err := dec.Decode(&r); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%w: missing trailer", ErrInvalidBackup)
+			}
+
+			return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+
+		switch r.Kind {
+		case backupRecordModel:
+			var model openfgav1.AuthorizationModel
+			if err := unmarshalBackupRecord(r.Model, &model); err != nil {
+				return err
+			}
+
+			if err := c.datastore.WriteAuthorizationModel(ctx, storeID, &model); err != nil {
+				return err
+			}
+		case backupRecordAssertions:
+			var assertions openfgav1.Assertions
+			if err := unmarshalBackupRecord(r.Assertions, &assertions); err != nil {
+				return err
+			}
+
+			if err := c.datastore.WriteAssertions(ctx, storeID, r.AuthorizationModelID, assertions.GetAssertions()); err != nil {
+				return err
+			}
+		case backupRecordTuple:
+			var tk openfgav1.TupleKey
+			if err := unmarshalBackupRecord(r.Tuple, &tk); err != nil {
+				return err
+			}
+
+			pending = append(pending, &tk)
+			if len(pending) == c.datastore.MaxTuplesPerWrite() {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case backupRecordChange:
+			if err := flush(); err != nil {
+				return err
+			}
+
+			var change openfgav1.TupleChange
+			if err := unmarshalBackupRecord(r.Change, &change); err != nil {
+				return err
+			}
+
+			var deletes, writes []*openfgav1.TupleKey
+			if change.GetOperation() == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+				deletes = []*openfgav1.TupleKey{change.GetTupleKey()}
+			} else {
+				writes = []*openfgav1.TupleKey{change.GetTupleKey()}
+			}
+
+			if err := c.datastore.Write(ctx, storeID, deletes, writes, writeOpts...); err != nil {
+				return err
+			}
+		case backupRecordTrailer:
+			if r.Records != records {
+				return fmt.Errorf("%w: expected %d records but found %d", ErrInvalidBackup, r.Records, records)
+			}
+
+			return flush()
+		default:
+			return fmt.Errorf("%w: unexpected record '%s'", ErrInvalidBackup, r.Kind)
+		}
+
+		records++
+	}
+}
+
+func unmarshalBackupRecord(raw json.RawMessage, msg proto.Message) error {
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/status"
+)
+
+const defaultMaxConcurrentAssertions = 10
+
+// RunAssertionsRequest is a request to evaluate the assertions of an authorization model.
+type RunAssertionsRequest struct {
+	StoreID              string
+	AuthorizationModelID string
+}
+
+// AssertionResult is the outcome of a single assertion.
+type AssertionResult struct {
+	TupleKey    *openfgav1.TupleKey `json:"tuple_key"`
+	Expectation bool                `json:"expectation"`
+	Allowed     bool                `json:"allowed"`
+	Passed      bool                `json:"passed"`
+
+	// Explanation is the resolution path that allowed the user of a failed assertion which expected the user
+	// to be denied. A failed assertion which expected the user to be allowed has no resolution path.
+	Explanation *graph.CheckExplanation `json:"explanation,omitempty"`
+
+	// Error is set if the assertion could not be evaluated, in which case the assertion failed.
+	Error string `json:"error,omitempty"`
+}
+
+// RunAssertionsResponse contains one AssertionResult per assertion, in the order the assertions were written.
+type RunAssertionsResponse struct {
+	Passed      bool               `json:"passed"`
+	PassedCount int                `json:"passed_count"`
+	FailedCount int                `json:"failed_count"`
+	Results     []*AssertionResult `json:"results"`
+}
+
+// RunAssertionsCommand evaluates the assertions of an authorization model against the tuples of the store,
+// and reports whether each of them passed.
+type RunAssertionsCommand struct {
+	datastore               storage.OpenFGADatastore
+	logger                  logger.Logger
+	resolveNodeLimit        uint32
+	checkerOpts             []graph.LocalCheckerOption
+	maxConcurrentAssertions uint32
+}
+
+type RunAssertionsCommandOption func(c *RunAssertionsCommand)
+
+func WithRunAssertionsLogger(l logger.Logger) RunAssertionsCommandOption {
+	return func(c *RunAssertionsCommand) {
+		c.logger = l
+	}
+}
+
+// WithRunAssertionsResolveNodeLimit see server.WithResolveNodeLimit
+func WithRunAssertionsResolveNodeLimit(limit uint32) RunAssertionsCommandOption {
+	return func(c *RunAssertionsCommand) {
+		c.resolveNodeLimit = limit
+	}
+}
+
+// WithRunAssertionsCheckerOptions sets the options of the check resolver evaluating the assertions.
+func WithRunAssertionsCheckerOptions(opts ...graph.LocalCheckerOption) RunAssertionsCommandOption {
+	return func(c *RunAssertionsCommand) {
+		c.checkerOpts = opts
+	}
+}
+
+// WithMaxConcurrentAssertions sets the maximum number of assertions that are evaluated concurrently.
+func WithMaxConcurrentAssertions(max uint32) RunAssertionsCommandOption {
+	return func(c *RunAssertionsCommand) {
+		c.maxConcurrentAssertions = max
+	}
+}
+
+func NewRunAssertionsCommand(datastore storage.OpenFGADatastore, opts ...RunAssertionsCommandOption) *RunAssertionsCommand {
+	c := &RunAssertionsCommand{
+		datastore:               datastore,
+		logger:                  logger.NewNoopLogger(),
+		resolveNodeLimit:        defaultResolveNodeLimit,
+		maxConcurrentAssertions: defaultMaxConcurrentAssertions,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Execute evaluates every assertion of the authorization model. The typesystem of the resolved authorization
+// model must be present in the context.
+func (c *RunAssertionsCommand) Execute(ctx context.Context, req *RunAssertionsRequest) (*RunAssertionsResponse, error) {
+	typesys, ok := typesystem.TypesystemFromContext(ctx)
+	if !ok {
+		panic("typesystem missing in context")
+	}
+
+	assertions, err := c.datastore.ReadAssertions(ctx, req.StoreID, typesys.GetAuthorizationModelID())
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	checkResolver := graph.NewLocalChecker(storagewrappers.NewConditionEvaluatingTupleReader(c.datastore), c.checkerOpts...)
+
+	results := make([]*AssertionResult, 0, len(assertions))
+	for _, assertion := range assertions {
+		results = append(results, &AssertionResult{
+			TupleKey:    assertion.GetTupleKey(),
+			Expectation: assertion.GetExpectation(),
+		})
+	}
+
+	g := new(errgroup.Group)
+	if c.maxConcurrentAssertions > 0 {
+		g.SetLimit(int(c.maxConcurrentAssertions))
+	}
+
+	for _, result := range results {
+		result := result
+
+		g.Go(func() error {
+			c.run(ctx, checkResolver, typesys, req.StoreID, result)
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+
+	resp := &RunAssertionsResponse{Results: results}
+	for _, result := range results {
+		if result.Passed {
+			resp.PassedCount++
+		} else {
+			resp.FailedCount++
+		}
+	}
+	resp.Passed = resp.FailedCount == 0
+
+	return resp, nil
+}
+
+// run evaluates a single assertion, explaining why the user is allowed if the assertion expected otherwise.
+func (c *RunAssertionsCommand) run(
+	ctx context.Context,
+	checkResolver graph.CheckResolver,
+	typesys *typesystem.TypeSystem,
+	storeID string,
+	result *AssertionResult,
+) {
+	tk := result.TupleKey
+	if err := validation.ValidateUserObjectRelation(typesys, tk); err != nil {
+		result.Error = status.Convert(serverErrors.ValidationError(err)).Message()
+		return
+	}
+
+	resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(),
+		TupleKey:             tk,
+		ResolutionMetadata: &graph.ResolutionMetadata{
+			Depth: c.resolveNodeLimit,
+		},
+		Explain: !result.Expectation,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, graph.ErrResolutionDepthExceeded):
+			err = serverErrors.AuthorizationModelResolutionTooComplex
+		case errors.Is(err, graph.ErrCycleDetected):
+			err = serverErrors.CycleDetected
+		case errors.Is(err, graph.ErrResolutionLimitExceeded):
+			err = serverErrors.ResolutionLimitExceeded(err)
+		}
+
+		result.Error = status.Convert(err).Message()
+		return
+	}
+
+	result.Allowed = resp.GetAllowed()
+	result.Passed = result.Allowed == result.Expectation
+	if !result.Passed {
+		result.Explanation = resp.Explanation
+	}
+}
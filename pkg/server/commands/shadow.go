@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const defaultShadowTimeout = 10 * time.Second
+
+var (
+	shadowEvaluationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shadow_evaluation_count",
+		Help: "Number of requests evaluated again with the experimental resolver, by method and outcome (match, mismatch, inconclusive, error or dropped)",
+	}, []string{"method", "outcome"})
+
+	shadowLatencyRatioHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shadow_latency_ratio",
+		Help:    "Duration of the experimental resolver divided by the duration of the current resolver, by method",
+		Buckets: []float64{0.25, 0.5, 0.75, 0.9, 1, 1.1, 1.25, 1.5, 2, 4, 8},
+	}, []string{"method"})
+)
+
+// ShadowOutcome is the outcome of the comparison of the result of the experimental resolver with the result of the
+// current resolver.
+type ShadowOutcome string
+
+const (
+	ShadowMatch    ShadowOutcome = "match"
+	ShadowMismatch ShadowOutcome = "mismatch"
+
+	// ShadowInconclusive results can't be compared, e.g. the truncated results of ListObjects.
+	ShadowInconclusive ShadowOutcome = "inconclusive"
+
+	shadowError   ShadowOutcome = "error"
+	shadowDropped ShadowOutcome = "dropped"
+)
+
+// ShadowEvaluation evaluates a request with the experimental resolver and compares its result with the result of the
+// current resolver. The fields describe the results if they diverge.
+type ShadowEvaluation func(ctx context.Context) (ShadowOutcome, []zap.Field, error)
+
+// ShadowEvaluator evaluates a sample of the requests again with an experimental resolver, in the background once the
+// result of the current resolver is served, and reports the divergences of their results and latencies. It is how
+// resolver changes are validated on the production traffic before they are enabled.
+type ShadowEvaluator struct {
+	logger              logger.Logger
+	sampleRate          float64
+	timeout             time.Duration
+	slowdownThreshold   float64
+	evaluationsInFlight chan struct{}
+}
+
+type ShadowEvaluatorOption func(e *ShadowEvaluator)
+
+func WithShadowLogger(l logger.Logger) ShadowEvaluatorOption {
+	return func(e *ShadowEvaluator) {
+		e.logger = l
+	}
+}
+
+// WithShadowSampleRate sets the fraction of the requests evaluated again, between 0 and 1. Defaults to 1.
+func WithShadowSampleRate(rate float64) ShadowEvaluatorOption {
+	return func(e *ShadowEvaluator) {
+		e.sampleRate = rate
+	}
+}
+
+// WithShadowTimeout sets the timeout of the evaluations of the experimental resolver. Defaults to 10s.
+func WithShadowTimeout(timeout time.Duration) ShadowEvaluatorOption {
+	return func(e *ShadowEvaluator) {
+		e.timeout = timeout
+	}
+}
+
+// WithShadowMaxConcurrentEvaluations sets the number of evaluations run concurrently. The requests sampled while
+// as many evaluations are running are dropped, so that the evaluations never pile up. Defaults to 10.
+func WithShadowMaxConcurrentEvaluations(max uint32) ShadowEvaluatorOption {
+	return func(e *ShadowEvaluator) {
+		e.evaluationsInFlight = make(chan struct{}, max)
+	}
+}
+
+// WithShadowSlowdownThreshold logs the evaluations of the experimental resolver that take more than `threshold`
+// times as long as the current resolver. If 0, the default, only their latency ratio is recorded.
+func WithShadowSlowdownThreshold(threshold float64) ShadowEvaluatorOption {
+	return func(e *ShadowEvaluator) {
+		e.slowdownThreshold = threshold
+	}
+}
+
+func NewShadowEvaluator(opts ...ShadowEvaluatorOption) *ShadowEvaluator {
+	e := &ShadowEvaluator{
+		logger:              logger.NewNoopLogger(),
+		sampleRate:          1,
+		timeout:             defaultShadowTimeout,
+		evaluationsInFlight: make(chan struct{}, 10),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Sample reports whether a request is evaluated again. It must be called before the request is resolved, so that
+// the unsampled requests don't pay for the evaluation.
+func (e *ShadowEvaluator) Sample() bool {
+	return e.sampleRate >= 1 || rand.Float64() < e.sampleRate
+}
+
+// Evaluate runs the evaluation in the background, with a context detached from the request which only carries its
+// tenant, see tenancy.WithTenantOf. `currentDuration` is how long the current resolver took to resolve the request.
+func (e *ShadowEvaluator) Evaluate(ctx context.Context, method, storeID string, currentDuration time.Duration, evaluate ShadowEvaluation) {
+	select {
+	case e.evaluationsInFlight <- struct{}{}:
+	default:
+		shadowEvaluationsCounter.WithLabelValues(method, string(shadowDropped)).Inc()
+		return
+	}
+
+	go func() {
+		defer func() { <-e.evaluationsInFlight }()
+
+		e.evaluate(tenancy.WithTenantOf(context.Background(), ctx), method, storeID, currentDuration, evaluate)
+	}()
+}
+
+func (e *ShadowEvaluator) evaluate(ctx context.Context, method, storeID string, currentDuration time.Duration, evaluate ShadowEvaluation) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	start := time.Now()
+	outcome, fields, err := evaluate(ctx)
+	experimentalDuration := time.Since(start)
+
+	fields = append([]zap.Field{
+		zap.String("method", method),
+		zap.String("store_id", storeID),
+		zap.Duration("current_duration", currentDuration),
+		zap.Duration("experimental_duration", experimentalDuration),
+	}, fields...)
+
+	if err != nil {
+		shadowEvaluationsCounter.WithLabelValues(method, string(shadowError)).Inc()
+		e.logger.Warn("the experimental resolver failed", append(fields, zap.Error(err))...)
+		return
+	}
+
+	shadowEvaluationsCounter.WithLabelValues(method, string(outcome)).Inc()
+	if outcome == ShadowMismatch {
+		e.logger.Warn("the experimental resolver diverged from the current resolver", fields...)
+	}
+
+	if currentDuration <= 0 {
+		return
+	}
+
+	ratio := float64(experimentalDuration) / float64(currentDuration)
+	shadowLatencyRatioHistogram.WithLabelValues(method).Observe(ratio)
+
+	if e.slowdownThreshold > 0 && ratio > e.slowdownThreshold {
+		e.logger.Info("the experimental resolver was slower than the current resolver", fields...)
+	}
+}
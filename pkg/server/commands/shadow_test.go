@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestShadowEvaluator(t *testing.T) {
+	t.Run("reports_the_outcomes", func(t *testing.T) {
+		observerLogger, logs := observer.New(zap.InfoLevel)
+		e := NewShadowEvaluator(
+			WithShadowLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+			WithShadowSlowdownThreshold(2),
+		)
+
+		matches := testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(ShadowMatch)))
+		mismatches := testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(ShadowMismatch)))
+		failures := testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(shadowError)))
+
+		e.Evaluate(context.Background(), "Test", "store", time.Hour, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			return ShadowMatch, nil, nil
+		})
+		e.Evaluate(context.Background(), "Test", "store", time.Hour, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			return ShadowMismatch, []zap.Field{zap.Bool("current_allowed", true)}, nil
+		})
+		e.Evaluate(context.Background(), "Test", "store", time.Hour, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			return "", nil, errors.New("boom")
+		})
+
+		require.Eventually(t, func() bool {
+			return testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(ShadowMatch))) == matches+1 &&
+				testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(ShadowMismatch))) == mismatches+1 &&
+				testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(shadowError))) == failures+1
+		}, time.Second, 10*time.Millisecond)
+
+		require.Eventually(t, func() bool {
+			return logs.FilterMessage("the experimental resolver diverged from the current resolver").FilterField(zap.Bool("current_allowed", true)).Len() == 1 &&
+				logs.FilterMessage("the experimental resolver failed").Len() == 1
+		}, time.Second, 10*time.Millisecond)
+		require.Zero(t, logs.FilterMessage("the experimental resolver was slower than the current resolver").Len())
+	})
+
+	t.Run("logs_the_slowdowns", func(t *testing.T) {
+		observerLogger, logs := observer.New(zap.InfoLevel)
+		e := NewShadowEvaluator(
+			WithShadowLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+			WithShadowSlowdownThreshold(2),
+		)
+
+		e.Evaluate(context.Background(), "Test", "store", time.Nanosecond, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			time.Sleep(time.Millisecond)
+			return ShadowMatch, nil, nil
+		})
+
+		require.Eventually(t, func() bool {
+			return logs.FilterMessage("the experimental resolver was slower than the current resolver").Len() == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("detaches_the_context_but_keeps_the_tenant", func(t *testing.T) {
+		e := NewShadowEvaluator(WithShadowTimeout(time.Minute))
+
+		ctx, cancel := context.WithCancel(tenancy.ContextWithTenant(context.Background(), "acme"))
+		cancel()
+
+		done := make(chan error, 1)
+		e.Evaluate(ctx, "Test", "store", time.Second, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			tenant, _ := tenancy.TenantFromContext(ctx)
+			if tenant != "acme" {
+				done <- errors.New("the tenant was not carried")
+			} else {
+				done <- ctx.Err()
+			}
+			return ShadowMatch, nil, nil
+		})
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			require.FailNow(t, "the evaluation did not run")
+		}
+	})
+
+	t.Run("drops_the_evaluations_over_the_concurrency_limit", func(t *testing.T) {
+		e := NewShadowEvaluator(WithShadowMaxConcurrentEvaluations(1))
+
+		dropped := testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(shadowDropped)))
+
+		release := make(chan struct{})
+		e.Evaluate(context.Background(), "Test", "store", time.Second, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			<-release
+			return ShadowMatch, nil, nil
+		})
+		e.Evaluate(context.Background(), "Test", "store", time.Second, func(ctx context.Context) (ShadowOutcome, []zap.Field, error) {
+			return ShadowMatch, nil, nil
+		})
+		close(release)
+
+		require.Equal(t, dropped+1, testutil.ToFloat64(shadowEvaluationsCounter.WithLabelValues("Test", string(shadowDropped))))
+	})
+
+	t.Run("samples_the_requests", func(t *testing.T) {
+		require.True(t, NewShadowEvaluator().Sample())
+		require.False(t, NewShadowEvaluator(WithShadowSampleRate(0)).Sample())
+	})
+}
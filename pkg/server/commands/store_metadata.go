@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const (
+	maxStoreDescriptionLength = 1024
+	maxStoreLabels            = 64
+	maxStoreLabelKeyLength    = 63
+	maxStoreLabelValueLength  = 255
+)
+
+// WriteStoreMetadataCommand replaces the description and the labels of a store.
+type WriteStoreMetadataCommand struct {
+	storesBackend storage.StoresBackend
+	logger        logger.Logger
+}
+
+func NewWriteStoreMetadataCommand(storesBackend storage.StoresBackend, logger logger.Logger) *WriteStoreMetadataCommand {
+	return &WriteStoreMetadataCommand{
+		storesBackend: storesBackend,
+		logger:        logger,
+	}
+}
+
+func (c *WriteStoreMetadataCommand) Execute(ctx context.Context, storeID string, metadata *storage.StoreMetadata) error {
+	if metadata == nil {
+		metadata = &storage.StoreMetadata{}
+	}
+
+	if err := validateStoreMetadata(metadata); err != nil {
+		return serverErrors.ValidationError(err)
+	}
+
+	err := c.storesBackend.WriteStoreMetadata(ctx, storeID, metadata)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return serverErrors.StoreIDNotFound
+		}
+		return serverErrors.HandleError("", err)
+	}
+
+	return nil
+}
+
+func validateStoreMetadata(metadata *storage.StoreMetadata) error {
+	if utf8.RuneCountInString(metadata.Description) > maxStoreDescriptionLength {
+		return fmt.Errorf("the description must be at most %d characters", maxStoreDescriptionLength)
+	}
+
+	if len(metadata.Labels) > maxStoreLabels {
+		return fmt.Errorf("a store can have at most %d labels", maxStoreLabels)
+	}
+
+	for key, value := range metadata.Labels {
+		if key == "" || utf8.RuneCountInString(key) > maxStoreLabelKeyLength {
+			return fmt.Errorf("the label key '%s' must be between 1 and %d characters", key, maxStoreLabelKeyLength)
+		}
+
+		if utf8.RuneCountInString(value) > maxStoreLabelValueLength {
+			return fmt.Errorf("the value of the label '%s' must be at most %d characters", key, maxStoreLabelValueLength)
+		}
+	}
+
+	return nil
+}
+
+// ReadStoreMetadataQuery returns the description and the labels of a store.
+type ReadStoreMetadataQuery struct {
+	storesBackend storage.StoresBackend
+	logger        logger.Logger
+}
+
+func NewReadStoreMetadataQuery(storesBackend storage.StoresBackend, logger logger.Logger) *ReadStoreMetadataQuery {
+	return &ReadStoreMetadataQuery{
+		storesBackend: storesBackend,
+		logger:        logger,
+	}
+}
+
+func (q *ReadStoreMetadataQuery) Execute(ctx context.Context, storeID string) (*storage.StoreMetadata, error) {
+	metadata, err := q.storesBackend.ReadStoreMetadata(ctx, storeID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.StoreIDNotFound
+		}
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return metadata, nil
+}
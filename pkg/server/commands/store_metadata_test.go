@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "payments"})
+	require.NoError(t, err)
+
+	_, err = ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "billing"})
+	require.NoError(t, err)
+
+	write := NewWriteStoreMetadataCommand(ds, logger.NewNoopLogger())
+	read := NewReadStoreMetadataQuery(ds, logger.NewNoopLogger())
+
+	t.Run("metadata_is_written_and_filters_the_stores", func(t *testing.T) {
+		metadata := &storage.StoreMetadata{
+			Description: "the payments of the customers",
+			Labels:      map[string]string{"team": "payments"},
+		}
+		require.NoError(t, write.Execute(ctx, store.Id, metadata))
+
+		got, err := read.Execute(ctx, store.Id)
+		require.NoError(t, err)
+		require.Equal(t, metadata, got)
+
+		resp, err := NewListStoresQuery(ds, logger.NewNoopLogger(), encoder.NewBase64Encoder()).ExecuteWithFilter(ctx, &openfgav1.ListStoresRequest{}, storage.ListStoresFilter{
+			Labels: map[string]string{"team": "payments"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Stores, 1)
+		require.Equal(t, store.Id, resp.Stores[0].Id)
+	})
+
+	t.Run("invalid_metadata_is_rejected", func(t *testing.T) {
+		for _, metadata := range []*storage.StoreMetadata{
+			{Description: strings.Repeat("a", maxStoreDescriptionLength+1)},
+			{Labels: map[string]string{"": "empty"}},
+			{Labels: map[string]string{strings.Repeat("k", maxStoreLabelKeyLength+1): "long"}},
+			{Labels: map[string]string{"team": strings.Repeat("v", maxStoreLabelValueLength+1)}},
+		} {
+			err := write.Execute(ctx, store.Id, metadata)
+			require.ErrorContains(t, err, "must be")
+		}
+	})
+
+	t.Run("metadata_of_non-existent_store_is_not_found", func(t *testing.T) {
+		err := write.Execute(ctx, ulid.Make().String(), &storage.StoreMetadata{Description: "missing"})
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+
+		_, err = read.Execute(ctx, ulid.Make().String())
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+	})
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.uber.org/zap"
+)
+
+const defaultTrimChangelogBatchSize = 1000
+
+// TrimChangelogRequest is a request to delete the changes that occurred before a point in time.
+type TrimChangelogRequest struct {
+	// StoreID is the store whose changelog is trimmed, or empty to trim the changelog of every store.
+	StoreID string
+
+	Before time.Time
+}
+
+type TrimChangelogResponse struct {
+	DeletedChanges int `json:"deleted_changes"`
+}
+
+// TrimChangelogCommand deletes the old changes of the changelog on demand, like the storage.ChangelogTrimmer
+// does periodically. The continuation tokens of ReadChanges remain valid.
+type TrimChangelogCommand struct {
+	datastore storage.OpenFGADatastore
+	logger    logger.Logger
+	batchSize int
+}
+
+type TrimChangelogCommandOption func(c *TrimChangelogCommand)
+
+// WithTrimChangelogBatchSize sets the maximum number of changes deleted in a single call to the datastore.
+func WithTrimChangelogBatchSize(batchSize int) TrimChangelogCommandOption {
+	return func(c *TrimChangelogCommand) {
+		c.batchSize = batchSize
+	}
+}
+
+func NewTrimChangelogCommand(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...TrimChangelogCommandOption) *TrimChangelogCommand {
+	c := &TrimChangelogCommand{
+		datastore: datastore,
+		logger:    logger,
+		batchSize: defaultTrimChangelogBatchSize,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *TrimChangelogCommand) Execute(ctx context.Context, req *TrimChangelogRequest) (*TrimChangelogResponse, error) {
+	if req.Before.IsZero() || req.Before.After(time.Now()) {
+		return nil, serverErrors.ValidationError(fmt.Errorf("the changelog must be trimmed before a point in time in the past"))
+	}
+
+	if req.StoreID != "" {
+		if _, err := c.datastore.GetStore(ctx, req.StoreID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, serverErrors.StoreIDNotFound
+			}
+			return nil, serverErrors.HandleError("", err)
+		}
+	}
+
+	deleted, err := storage.TrimChangelog(ctx, c.datastore, req.StoreID, req.Before, c.batchSize)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	c.logger.InfoWithContext(ctx, "trimmed the changelog",
+		zap.String("store_id", req.StoreID),
+		zap.Time("before", req.Before),
+		zap.Int("deleted_changes", deleted),
+	)
+
+	return &TrimChangelogResponse{DeletedChanges: deleted}, nil
+}
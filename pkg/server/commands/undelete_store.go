@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// UndeleteStoreCommand restores a deleted store, with its tuples, authorization models and assertions, if it was
+// deleted within the retention period.
+type UndeleteStoreCommand struct {
+	storesBackend   storage.StoresBackend
+	logger          logger.Logger
+	retentionPeriod time.Duration
+}
+
+type UndeleteStoreCommandOption func(c *UndeleteStoreCommand)
+
+// WithUndeleteStoreRetentionPeriod sets how long after their deletion the stores can be restored. If 0, a store
+// can be restored until it is purged.
+func WithUndeleteStoreRetentionPeriod(retentionPeriod time.Duration) UndeleteStoreCommandOption {
+	return func(c *UndeleteStoreCommand) {
+		c.retentionPeriod = retentionPeriod
+	}
+}
+
+func NewUndeleteStoreCommand(storesBackend storage.StoresBackend, logger logger.Logger, opts ...UndeleteStoreCommandOption) *UndeleteStoreCommand {
+	c := &UndeleteStoreCommand{
+		storesBackend:   storesBackend,
+		logger:          logger,
+		retentionPeriod: storage.DefaultStoreRetentionPeriod,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func (c *UndeleteStoreCommand) Execute(ctx context.Context, storeID string) (*openfgav1.Store, error) {
+	var deletedAfter time.Time
+	if c.retentionPeriod > 0 {
+		deletedAfter = time.Now().Add(-c.retentionPeriod)
+	}
+
+	store, err := c.storesBackend.UndeleteStore(ctx, storeID, deletedAfter)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, serverErrors.StoreIDNotFound
+		}
+		return nil, serverErrors.HandleError("Error restoring store", err)
+	}
+
+	c.logger.InfoWithContext(ctx, "restored deleted store", zap.String("store_id", store.Id))
+
+	return store, nil
+}
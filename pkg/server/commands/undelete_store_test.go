@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndeleteStoreCommand(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	createDeletedStore := func(t *testing.T) string {
+		store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "payments"})
+		require.NoError(t, err)
+
+		_, err = NewDeleteStoreCommand(ds, logger.NewNoopLogger()).Execute(ctx, &openfgav1.DeleteStoreRequest{StoreId: store.Id})
+		require.NoError(t, err)
+
+		return store.Id
+	}
+
+	t.Run("store_deleted_within_the_retention_period_is_restored", func(t *testing.T) {
+		storeID := createDeletedStore(t)
+
+		store, err := NewUndeleteStoreCommand(ds, logger.NewNoopLogger()).Execute(ctx, storeID)
+		require.NoError(t, err)
+		require.Equal(t, storeID, store.Id)
+
+		_, err = NewGetStoreQuery(ds, logger.NewNoopLogger()).Execute(ctx, &openfgav1.GetStoreRequest{StoreId: storeID})
+		require.NoError(t, err)
+	})
+
+	t.Run("store_deleted_before_the_retention_period_is_not_restored", func(t *testing.T) {
+		storeID := createDeletedStore(t)
+		time.Sleep(10 * time.Millisecond)
+
+		_, err := NewUndeleteStoreCommand(ds, logger.NewNoopLogger(), WithUndeleteStoreRetentionPeriod(time.Millisecond)).Execute(ctx, storeID)
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+	})
+
+	t.Run("store_which_is_not_deleted_is_not_found", func(t *testing.T) {
+		_, err := NewUndeleteStoreCommand(ds, logger.NewNoopLogger()).Execute(ctx, ulid.Make().String())
+		require.ErrorIs(t, err, serverErrors.StoreIDNotFound)
+	})
+}
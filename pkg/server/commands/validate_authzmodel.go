@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"google.golang.org/grpc/status"
+)
+
+// RuleLimit reports an authorization model exceeding the limits of the datastore or the quotas of the store.
+const RuleLimit = "limit"
+
+// ValidateAuthorizationModelResponse lists the problems found in an authorization model. The model can be
+// written if and only if Valid is true, in which case the diagnostics are only warnings.
+type ValidateAuthorizationModelResponse struct {
+	Valid       bool                     `json:"valid"`
+	Diagnostics []*typesystem.Diagnostic `json:"diagnostics"`
+}
+
+// ValidateAuthorizationModelCommand checks an authorization model as WriteAuthorizationModelCommand would, and
+// lints it, without writing it.
+type ValidateAuthorizationModelCommand struct {
+	backend     storage.TypeDefinitionWriteBackend
+	quotas      *quota.Enforcer
+	lintOptions []typesystem.LintOption
+}
+
+type ValidateAuthorizationModelCommandOption func(c *ValidateAuthorizationModelCommand)
+
+// WithValidateAuthorizationModelQuotas checks the authorization model size quotas of the stores.
+func WithValidateAuthorizationModelQuotas(quotas *quota.Enforcer) ValidateAuthorizationModelCommandOption {
+	return func(c *ValidateAuthorizationModelCommand) {
+		c.quotas = quotas
+	}
+}
+
+// WithValidateAuthorizationModelLintOptions sets the options of the lint rules, see typesystem.Lint.
+func WithValidateAuthorizationModelLintOptions(opts ...typesystem.LintOption) ValidateAuthorizationModelCommandOption {
+	return func(c *ValidateAuthorizationModelCommand) {
+		c.lintOptions = opts
+	}
+}
+
+// NewValidateAuthorizationModelCommand constructs a ValidateAuthorizationModelCommand. If backend is nil, the
+// limits of the datastore are not checked.
+func NewValidateAuthorizationModelCommand(backend storage.TypeDefinitionWriteBackend, opts ...ValidateAuthorizationModelCommandOption) *ValidateAuthorizationModelCommand {
+	c := &ValidateAuthorizationModelCommand{
+		backend: backend,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Execute the command using the supplied request.
+func (c *ValidateAuthorizationModelCommand) Execute(_ context.Context, req *openfgav1.WriteAuthorizationModelRequest) *ValidateAuthorizationModelResponse {
+	var diagnostics []*typesystem.Diagnostic
+
+	if c.backend != nil && len(req.GetTypeDefinitions()) > c.backend.MaxTypesPerAuthorizationModel() {
+		diagnostics = append(diagnostics, limitError(serverErrors.ExceededEntityLimit("type definitions in an authorization model", c.backend.MaxTypesPerAuthorizationModel())))
+	}
+
+	if c.quotas != nil {
+		if err := c.quotas.CheckAuthorizationModel(req.GetStoreId(), req.GetTypeDefinitions()); err != nil {
+			diagnostics = append(diagnostics, limitError(err))
+		}
+	}
+
+	schemaVersion := req.GetSchemaVersion()
+	if schemaVersion == "" {
+		schemaVersion = typesystem.SchemaVersion1_1
+	}
+
+	diagnostics = append(diagnostics, typesystem.Lint(&openfgav1.AuthorizationModel{
+		SchemaVersion:   schemaVersion,
+		TypeDefinitions: req.GetTypeDefinitions(),
+	}, c.lintOptions...)...)
+
+	if diagnostics == nil {
+		diagnostics = []*typesystem.Diagnostic{}
+	}
+
+	return &ValidateAuthorizationModelResponse{
+		Valid:       !typesystem.HasErrors(diagnostics),
+		Diagnostics: diagnostics,
+	}
+}
+
+func limitError(err error) *typesystem.Diagnostic {
+	return &typesystem.Diagnostic{
+		Rule:     RuleLimit,
+		Severity: typesystem.SeverityError,
+		Message:  status.Convert(err).Message(),
+	}
+}
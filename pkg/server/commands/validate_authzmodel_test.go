@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAuthorizationModel(t *testing.T) {
+	ds := memory.New(memory.WithMaxTypesPerAuthorizationModel(2))
+	defer ds.Close()
+
+	cmd := NewValidateAuthorizationModelCommand(ds)
+
+	t.Run("valid_model_with_warnings", func(t *testing.T) {
+		resp := cmd.Execute(context.Background(), &openfgav1.WriteAuthorizationModelRequest{
+			StoreId: "store",
+			TypeDefinitions: parser.MustParse(`
+			type user
+
+			type document
+			  relations
+			    define editor: [user] as self
+			    define viewer as editor or editor
+			`),
+		})
+
+		require.True(t, resp.Valid)
+		require.Len(t, resp.Diagnostics, 1)
+		require.Equal(t, typesystem.RuleDuplicateOperand, resp.Diagnostics[0].Rule)
+		require.Equal(t, typesystem.SeverityWarning, resp.Diagnostics[0].Severity)
+	})
+
+	t.Run("every_problem_is_reported", func(t *testing.T) {
+		resp := cmd.Execute(context.Background(), &openfgav1.WriteAuthorizationModelRequest{
+			StoreId: "store",
+			TypeDefinitions: parser.MustParse(`
+			type user
+			type team
+
+			type document
+			  relations
+			    define viewer: [user] as self
+			    define editor as owner
+			`),
+		})
+
+		require.False(t, resp.Valid)
+		require.Len(t, resp.Diagnostics, 3)
+		require.Equal(t, RuleLimit, resp.Diagnostics[0].Rule)
+		require.Equal(t, "The number of type definitions in an authorization model exceeds the allowed limit of 2", resp.Diagnostics[0].Message)
+		require.Equal(t, typesystem.RuleUndefinedRelation, resp.Diagnostics[1].Rule)
+		require.Equal(t, typesystem.RuleUnusedType, resp.Diagnostics[2].Rule)
+	})
+}
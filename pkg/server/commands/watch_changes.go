@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+const (
+	defaultWatchChangesPollInterval      = 1 * time.Second
+	defaultWatchChangesHeartbeatInterval = 30 * time.Second
+)
+
+// WatchChangesServer is the server side of a WatchChanges stream.
+type WatchChangesServer interface {
+	Context() context.Context
+	Send(*openfgav1.ReadChangesResponse) error
+}
+
+// WatchChangesQuery tails the changelog of a store and streams the new changes to the client as they occur.
+type WatchChangesQuery struct {
+	readChangesQuery  *ReadChangesQuery
+	logger            logger.Logger
+	encoder           encoder.Encoder
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+}
+
+type WatchChangesQueryOption func(q *WatchChangesQuery)
+
+// WithWatchChangesPollInterval sets how long to wait between two reads of the changelog once the stream is caught up.
+func WithWatchChangesPollInterval(interval time.Duration) WatchChangesQueryOption {
+	return func(q *WatchChangesQuery) {
+		q.pollInterval = interval
+	}
+}
+
+// WithWatchChangesHeartbeatInterval sets how long the stream can stay idle before a heartbeat is sent.
+func WithWatchChangesHeartbeatInterval(interval time.Duration) WatchChangesQueryOption {
+	return func(q *WatchChangesQuery) {
+		q.heartbeatInterval = interval
+	}
+}
+
+// WithWatchChangesTokenCodec sets the format of the continuation tokens issued by the WatchChangesQuery.
+func WithWatchChangesTokenCodec(codec encoder.TokenCodec) WatchChangesQueryOption {
+	return func(q *WatchChangesQuery) {
+		q.readChangesQuery.codec = codec
+	}
+}
+
+// NewWatchChangesQuery creates a WatchChangesQuery with the specified `ChangelogBackend`. See NewReadChangesQuery.
+func NewWatchChangesQuery(backend storage.ChangelogBackend, logger logger.Logger, encoder encoder.Encoder, horizonOffset int, opts ...WatchChangesQueryOption) *WatchChangesQuery {
+	q := &WatchChangesQuery{
+		readChangesQuery:  NewReadChangesQuery(backend, logger, encoder, horizonOffset),
+		logger:            logger,
+		encoder:           encoder,
+		pollInterval:      defaultWatchChangesPollInterval,
+		heartbeatInterval: defaultWatchChangesHeartbeatInterval,
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q
+}
+
+// Execute streams the changes of the store that occur after the continuation token of the request (or
+// every change if it is empty) until the client cancels the stream. Every message carries the continuation
+// token from which the stream can be resumed. If no change occurs for the heartbeat interval, a message
+// without changes (a heartbeat) is sent.
+func (q *WatchChangesQuery) Execute(ctx context.Context, req *openfgav1.ReadChangesRequest, srv WatchChangesServer) error {
+	if _, err := decodePaginationToken(q.encoder, encoder.TokenKindChanges, req.GetContinuationToken()); err != nil {
+		return serverErrors.InvalidContinuationToken
+	}
+
+	pageSize := storage.NewPaginationOptions(req.GetPageSize().GetValue(), "").PageSize
+	token := req.GetContinuationToken()
+	lastSent := time.Now()
+
+	for {
+		resp, err := q.readChangesQuery.Execute(ctx, &openfgav1.ReadChangesRequest{
+			StoreId:           req.GetStoreId(),
+			Type:              req.GetType(),
+			PageSize:          req.GetPageSize(),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		token = resp.GetContinuationToken()
+
+		if len(resp.GetChanges()) > 0 || time.Since(lastSent) >= q.heartbeatInterval {
+			if err := srv.Send(resp); err != nil {
+				return serverErrors.NewInternalError("", err)
+			}
+
+			lastSent = time.Now()
+		}
+
+		// a full page means that more changes are probably available already
+		if len(resp.GetChanges()) >= pageSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
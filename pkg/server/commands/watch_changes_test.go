@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encoder"
+	"github.com/openfga/openfga/pkg/logger"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWatchChangesServer struct {
+	ctx       context.Context
+	responses chan *openfgav1.ReadChangesResponse
+}
+
+func (m *mockWatchChangesServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockWatchChangesServer) Send(resp *openfgav1.ReadChangesResponse) error {
+	m.responses <- resp
+	return nil
+}
+
+func TestWatchChangesQuery(t *testing.T) {
+	storeID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	write := func(object string) {
+		err := ds.Write(context.Background(), storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:jon")})
+		require.NoError(t, err)
+	}
+
+	watch := func(t *testing.T, continuationToken string) (*mockWatchChangesServer, func() error) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		srv := &mockWatchChangesServer{ctx: ctx, responses: make(chan *openfgav1.ReadChangesResponse, 100)}
+		q := NewWatchChangesQuery(ds, logger.NewNoopLogger(), encoder.NewBase64Encoder(), 0,
+			WithWatchChangesPollInterval(5*time.Millisecond),
+			WithWatchChangesHeartbeatInterval(50*time.Millisecond),
+		)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- q.Execute(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID, ContinuationToken: continuationToken}, srv)
+		}()
+
+		return srv, func() error {
+			cancel()
+			return <-done
+		}
+	}
+
+	receive := func(t *testing.T, srv *mockWatchChangesServer) *openfgav1.ReadChangesResponse {
+		select {
+		case resp := <-srv.responses:
+			return resp
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for a response")
+			return nil
+		}
+	}
+
+	write("document:1")
+
+	srv, stop := watch(t, "")
+
+	resp := receive(t, srv)
+	require.Len(t, resp.GetChanges(), 1)
+	require.Equal(t, "document:1", resp.GetChanges()[0].GetTupleKey().GetObject())
+
+	// changes are pushed as they occur
+	write("document:2")
+
+	resp = receive(t, srv)
+	require.Len(t, resp.GetChanges(), 1)
+	require.Equal(t, "document:2", resp.GetChanges()[0].GetTupleKey().GetObject())
+	token := resp.GetContinuationToken()
+
+	// heartbeats are sent while the stream is idle
+	resp = receive(t, srv)
+	require.Empty(t, resp.GetChanges())
+	require.Equal(t, token, resp.GetContinuationToken())
+
+	require.NoError(t, stop())
+
+	// the stream can be resumed from a continuation token
+	write("document:3")
+
+	srv, stop = watch(t, token)
+
+	resp = receive(t, srv)
+	require.Len(t, resp.GetChanges(), 1)
+	require.Equal(t, "document:3", resp.GetChanges()[0].GetTupleKey().GetObject())
+
+	require.NoError(t, stop())
+
+	t.Run("invalid_continuation_token", func(t *testing.T) {
+		_, stop := watch(t, "invalid")
+		require.ErrorIs(t, stop(), serverErrors.InvalidContinuationToken)
+	})
+}
@@ -4,48 +4,252 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/condition"
 	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	tupleUtils "github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
 )
 
 const (
 	IndirectWriteErrorReason = "Attempting to write directly to an indirect only relationship"
 )
 
+var ErrExpiryNotInFuture = errors.New("the expiry of the tuples must be in the future")
+
 // WriteCommand is used to Write and Delete tuples. Instances may be safely shared by multiple goroutines.
 type WriteCommand struct {
 	logger    logger.Logger
 	datastore storage.OpenFGADatastore
+	quotas    *quota.Enforcer
+	hook      writehook.Hook
+	committed func(req *openfgav1.WriteRequest)
+}
+
+type WriteCommandOption func(c *WriteCommand)
+
+// WithWriteQuotas enforces the tuple and write rate quotas of the stores. If nil, no quotas are enforced.
+func WithWriteQuotas(quotas *quota.Enforcer) WriteCommandOption {
+	return func(c *WriteCommand) {
+		c.quotas = quotas
+	}
+}
+
+// WithWriteHook submits the writes to the hook before they are validated and committed, see writehook.Hook. If nil,
+// the writes are committed as is.
+func WithWriteHook(hook writehook.Hook) WriteCommandOption {
+	return func(c *WriteCommand) {
+		c.hook = hook
+	}
+}
+
+// WithWriteCommitted calls `committed` with the deletes and writes of every write committed, i.e. those admitted by the
+// write hook rather than those requested.
+func WithWriteCommitted(committed func(req *openfgav1.WriteRequest)) WriteCommandOption {
+	return func(c *WriteCommand) {
+		c.committed = committed
+	}
 }
 
 // NewWriteCommand creates a WriteCommand with specified storage.TupleBackend to use for storage.
-func NewWriteCommand(datastore storage.OpenFGADatastore, logger logger.Logger) *WriteCommand {
-	return &WriteCommand{
+func NewWriteCommand(datastore storage.OpenFGADatastore, logger logger.Logger, opts ...WriteCommandOption) *WriteCommand {
+	c := &WriteCommand{
 		logger:    logger,
 		datastore: datastore,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-// Execute deletes and writes the specified tuples. Deletes are applied first, then writes.
-func (c *WriteCommand) Execute(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteResponse, error) {
+// Execute deletes and writes the specified tuples. Deletes are applied first, then writes. The options control
+// whether writing an existing tuple or deleting a missing tuple fails the request, see storage.TupleWriteOptions.
+func (c *WriteCommand) Execute(ctx context.Context, req *openfgav1.WriteRequest, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, done := observeCommand(ctx, "Write", req.GetStoreId())
+	defer done()
+
+	req, err := c.admit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.validateWriteRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	if err := c.enforceQuotas(ctx, req); err != nil {
+		return nil, err
+	}
+
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	err = c.datastore.Write(ctx, req.GetStoreId(), req.GetDeletes().GetTupleKeys(), req.GetWrites().GetTupleKeys(), opts...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	c.recordWrite(req)
+
+	return &openfgav1.WriteResponse{}, nil
+}
+
+// ExecuteWithExpiry is like Execute, but the written tuples expire at `expiresAt`. Once expired, they are
+// no longer returned by any read and they are eventually deleted from the datastore.
+func (c *WriteCommand) ExecuteWithExpiry(ctx context.Context, req *openfgav1.WriteRequest, expiresAt time.Time, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, done := observeCommand(ctx, "Write", req.GetStoreId())
+	defer done()
+
+	if !expiresAt.After(time.Now()) {
+		return nil, serverErrors.ValidationError(ErrExpiryNotInFuture)
+	}
+
+	req, err := c.admit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.validateWriteRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	if err := c.enforceQuotas(ctx, req); err != nil {
+		return nil, err
+	}
+
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	err = c.datastore.WriteWithExpiry(ctx, req.GetStoreId(), req.GetDeletes().GetTupleKeys(), req.GetWrites().GetTupleKeys(), expiresAt, opts...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	c.recordWrite(req)
+
+	return &openfgav1.WriteResponse{}, nil
+}
+
+// ExecuteWithCondition is like Execute, but the written tuples are conditional: Check and ListObjects only
+// consider them when the condition, evaluated with the parameters of the condition and the request context
+// of the query, is satisfied. See package condition.
+func (c *WriteCommand) ExecuteWithCondition(ctx context.Context, req *openfgav1.WriteRequest, tupleCondition *storage.TupleCondition, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, done := observeCommand(ctx, "Write", req.GetStoreId())
+	defer done()
+
+	if _, err := condition.Compile(tupleCondition.Expression); err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+
+	req, err := c.admit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := c.validateWriteRequest(ctx, req); err != nil {
 		return nil, err
 	}
 
-	err := c.datastore.Write(ctx, req.GetStoreId(), req.GetDeletes().GetTupleKeys(), req.GetWrites().GetTupleKeys())
+	if err := c.enforceQuotas(ctx, req); err != nil {
+		return nil, err
+	}
+
+	graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
+	err = c.datastore.WriteWithCondition(ctx, req.GetStoreId(), req.GetDeletes().GetTupleKeys(), req.GetWrites().GetTupleKeys(), tupleCondition, opts...)
 	if err != nil {
 		return nil, handleError(err)
 	}
 
+	c.recordWrite(req)
+
 	return &openfgav1.WriteResponse{}, nil
 }
 
+// admit returns the request with the deletes and writes admitted by the hook.
+func (c *WriteCommand) admit(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteRequest, error) {
+	if c.hook == nil {
+		return req, nil
+	}
+
+	deletes, writes, err := admitWrite(ctx, c.hook, req.GetStoreId(), req.GetAuthorizationModelId(), req.GetDeletes().GetTupleKeys(), req.GetWrites().GetTupleKeys())
+	if err != nil {
+		return nil, err
+	}
+
+	admitted := &openfgav1.WriteRequest{
+		StoreId:              req.GetStoreId(),
+		AuthorizationModelId: req.GetAuthorizationModelId(),
+	}
+	if len(deletes) > 0 {
+		admitted.Deletes = &openfgav1.TupleKeys{TupleKeys: deletes}
+	}
+	if len(writes) > 0 {
+		admitted.Writes = &openfgav1.TupleKeys{TupleKeys: writes}
+	}
+
+	return admitted, nil
+}
+
+// admitWrite submits the deletes and writes to the hook, and returns those to commit. The writes rejected by the hook
+// fail with a validation error. If the hook is nil, the deletes and writes are returned as is.
+func admitWrite(ctx context.Context, hook writehook.Hook, storeID, modelID string, deletes, writes []*openfgav1.TupleKey) ([]*openfgav1.TupleKey, []*openfgav1.TupleKey, error) {
+	if hook == nil {
+		return deletes, writes, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "admitWrite")
+	defer span.End()
+
+	admitted, err := hook.Admit(ctx, &writehook.Write{
+		StoreID:              storeID,
+		AuthorizationModelID: modelID,
+		Deletes:              deletes,
+		Writes:               writes,
+	})
+	if err != nil {
+		var rejected *writehook.RejectedError
+		if errors.As(err, &rejected) {
+			return nil, nil, serverErrors.ValidationError(err)
+		}
+
+		return nil, nil, serverErrors.HandleError("", err)
+	}
+
+	return admitted.Deletes, admitted.Writes, nil
+}
+
+// enforceQuotas returns an error if the request would take the store over its tuple quota or exceeds its
+// write rate quota.
+func (c *WriteCommand) enforceQuotas(ctx context.Context, req *openfgav1.WriteRequest) error {
+	if c.quotas == nil {
+		return nil
+	}
+
+	deletes, writes := len(req.GetDeletes().GetTupleKeys()), len(req.GetWrites().GetTupleKeys())
+	if err := c.quotas.CheckTupleCount(ctx, req.GetStoreId(), deletes, writes); err != nil {
+		return err
+	}
+
+	return c.quotas.AllowWrite(req.GetStoreId())
+}
+
+func (c *WriteCommand) recordWrite(req *openfgav1.WriteRequest) {
+	if c.quotas != nil {
+		c.quotas.RecordWrite(req.GetStoreId(), len(req.GetDeletes().GetTupleKeys()), len(req.GetWrites().GetTupleKeys()))
+	}
+
+	if c.committed != nil {
+		c.committed(req)
+	}
+}
+
 func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.WriteRequest) error {
 	ctx, span := tracer.Start(ctx, "validateWriteRequest")
 	defer span.End()
@@ -61,6 +265,7 @@ func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.
 
 	if len(writes) > 0 {
 
+		graph.ResolutionStatsFromContext(ctx).AddDatastoreQuery()
 		authModel, err := c.datastore.ReadAuthorizationModel(ctx, store, modelID)
 		if err != nil {
 			if errors.Is(err, storage.ErrNotFound) {
@@ -76,29 +281,8 @@ func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.
 		typesys := typesystem.New(authModel)
 
 		for _, tk := range writes {
-			err := validation.ValidateTuple(typesys, tk)
-			if err != nil {
-				return serverErrors.ValidationError(err)
-			}
-
-			objectType, _ := tupleUtils.SplitObject(tk.GetObject())
-
-			relation, err := typesys.GetRelation(objectType, tk.GetRelation())
-			if err != nil {
-				if errors.Is(err, typesystem.ErrObjectTypeUndefined) {
-					return serverErrors.TypeNotFound(objectType)
-				}
-
-				if errors.Is(err, typesystem.ErrRelationUndefined) {
-					return serverErrors.RelationNotFound(tk.GetRelation(), objectType, tk)
-				}
-
-				return serverErrors.HandleError("", err)
-			}
-
-			// Validate that we are not trying to write to an indirect-only relationship
-			if !typesystem.RewriteContainsSelf(relation.GetRewrite()) {
-				return serverErrors.HandleTupleValidateError(&tupleUtils.IndirectWriteError{Reason: IndirectWriteErrorReason, TupleKey: tk})
+			if err := ValidateTupleToWrite(typesys, tk); err != nil {
+				return err
 			}
 		}
 	}
@@ -121,6 +305,36 @@ func (c *WriteCommand) validateWriteRequest(ctx context.Context, req *openfgav1.
 	return nil
 }
 
+// ValidateTupleToWrite ensures the tuple is valid according to the typesystem and that its relation can be written directly.
+func ValidateTupleToWrite(typesys *typesystem.TypeSystem, tk *openfgav1.TupleKey) error {
+	err := validation.ValidateTuple(typesys, tk)
+	if err != nil {
+		return serverErrors.ValidationError(err)
+	}
+
+	objectType, _ := tupleUtils.SplitObject(tk.GetObject())
+
+	relation, err := typesys.GetRelation(objectType, tk.GetRelation())
+	if err != nil {
+		if errors.Is(err, typesystem.ErrObjectTypeUndefined) {
+			return serverErrors.TypeNotFound(objectType)
+		}
+
+		if errors.Is(err, typesystem.ErrRelationUndefined) {
+			return serverErrors.RelationNotFound(tk.GetRelation(), objectType, tk)
+		}
+
+		return serverErrors.HandleError("", err)
+	}
+
+	// Validate that we are not trying to write to an indirect-only relationship
+	if !typesystem.RewriteContainsSelf(relation.GetRewrite()) {
+		return serverErrors.HandleTupleValidateError(&tupleUtils.IndirectWriteError{Reason: IndirectWriteErrorReason, TupleKey: tk})
+	}
+
+	return nil
+}
+
 // validateNoDuplicatesAndCorrectSize ensures the deletes and writes contain no duplicates and length fits.
 func (c *WriteCommand) validateNoDuplicatesAndCorrectSize(deletes []*openfgav1.TupleKey, writes []*openfgav1.TupleKey) error {
 	tuples := map[string]struct{}{}
@@ -152,6 +366,12 @@ func handleError(err error) error {
 		return serverErrors.WriteFailedDueToInvalidInput(nil)
 	} else if errors.Is(err, storage.ErrInvalidWriteInput) {
 		return serverErrors.WriteFailedDueToInvalidInput(err)
+	} else if errors.Is(err, storage.ErrChangelogConflict) {
+		return serverErrors.ChangelogConflict
+	} else if errors.Is(err, storage.ErrExpectedChangelogTokenUnsupported) {
+		return serverErrors.ExpectedChangelogTokenUnsupported
+	} else if errors.Is(err, storage.ErrInvalidContinuationToken) {
+		return serverErrors.InvalidContinuationToken
 	}
 
 	return serverErrors.HandleError("", err)
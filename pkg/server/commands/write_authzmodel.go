@@ -6,6 +6,7 @@ import (
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/typesystem"
@@ -15,25 +16,59 @@ import (
 type WriteAuthorizationModelCommand struct {
 	backend storage.TypeDefinitionWriteBackend
 	logger  logger.Logger
+	quotas  *quota.Enforcer
+}
+
+type WriteAuthorizationModelCommandOption func(c *WriteAuthorizationModelCommand)
+
+// WithWriteAuthorizationModelQuotas enforces the authorization model size quotas of the stores. If nil, only the
+// limits of the datastore are enforced.
+func WithWriteAuthorizationModelQuotas(quotas *quota.Enforcer) WriteAuthorizationModelCommandOption {
+	return func(c *WriteAuthorizationModelCommand) {
+		c.quotas = quotas
+	}
 }
 
 func NewWriteAuthorizationModelCommand(
 	backend storage.TypeDefinitionWriteBackend,
 	logger logger.Logger,
+	opts ...WriteAuthorizationModelCommandOption,
 ) *WriteAuthorizationModelCommand {
-	return &WriteAuthorizationModelCommand{
+	c := &WriteAuthorizationModelCommand{
 		backend: backend,
 		logger:  logger,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // Execute the command using the supplied request.
 func (w *WriteAuthorizationModelCommand) Execute(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	return w.execute(ctx, req, nil)
+}
+
+// ExecuteWithAnnotations is like Execute, but the annotations of the types and relations of the model are
+// written along with it. They must only reference the types and relations defined in the model.
+func (w *WriteAuthorizationModelCommand) ExecuteWithAnnotations(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest, annotations storage.ModelAnnotations) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	return w.execute(ctx, req, annotations)
+}
+
+func (w *WriteAuthorizationModelCommand) execute(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest, annotations storage.ModelAnnotations) (*openfgav1.WriteAuthorizationModelResponse, error) {
 	// Until this is solved: https://github.com/envoyproxy/protoc-gen-validate/issues/74
 	if len(req.GetTypeDefinitions()) > w.backend.MaxTypesPerAuthorizationModel() {
 		return nil, serverErrors.ExceededEntityLimit("type definitions in an authorization model", w.backend.MaxTypesPerAuthorizationModel())
 	}
 
+	if w.quotas != nil {
+		if err := w.quotas.CheckAuthorizationModel(req.GetStoreId(), req.GetTypeDefinitions()); err != nil {
+			return nil, err
+		}
+	}
+
 	// Fill in the schema version for old requests, which don't contain it, while we migrate to the new schema version.
 	if req.SchemaVersion == "" {
 		req.SchemaVersion = typesystem.SchemaVersion1_1
@@ -45,12 +80,20 @@ func (w *WriteAuthorizationModelCommand) Execute(ctx context.Context, req *openf
 		TypeDefinitions: req.GetTypeDefinitions(),
 	}
 
-	_, err := typesystem.NewAndValidate(ctx, model)
+	typesys, err := typesystem.NewAndValidate(ctx, model)
 	if err != nil {
 		return nil, serverErrors.InvalidAuthorizationModelInput(err)
 	}
 
-	err = w.backend.WriteAuthorizationModel(ctx, req.GetStoreId(), model)
+	if annotations == nil {
+		err = w.backend.WriteAuthorizationModel(ctx, req.GetStoreId(), model)
+	} else {
+		if err := typesys.ValidateAnnotations(annotations); err != nil {
+			return nil, serverErrors.InvalidAuthorizationModelInput(err)
+		}
+
+		err = w.backend.WriteAuthorizationModelWithAnnotations(ctx, req.GetStoreId(), model, annotations)
+	}
 	if err != nil {
 		return nil, serverErrors.NewInternalError("Error writing authorization model configuration", err)
 	}
@@ -3,17 +3,24 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
 	"github.com/golang/mock/gomock"
+	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	mockstorage "github.com/openfga/openfga/internal/mocks"
 	"github.com/openfga/openfga/pkg/logger"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage/memory"
 	"github.com/openfga/openfga/pkg/testutils"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestValidateNoDuplicatesAndCorrectSize(t *testing.T) {
@@ -163,3 +170,71 @@ func TestValidateWriteRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteCommandWithHook(t *testing.T) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+	modelID := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.WriteAuthorizationModel(ctx, storeID, &openfgav1.AuthorizationModel{
+		Id:            modelID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	hook := writehook.HookFunc(func(ctx context.Context, write *writehook.Write) (*writehook.Write, error) {
+		mutated := *write
+		mutated.Writes = nil
+		for _, tk := range write.Writes {
+			if tk.GetRelation() == "owner" {
+				return nil, writehook.Reject("the owners are protected")
+			}
+			mutated.Writes = append(mutated.Writes, tuple.NewTupleKey(strings.ToLower(tk.GetObject()), tk.GetRelation(), tk.GetUser()))
+		}
+
+		return &mutated, nil
+	})
+
+	var committed *openfgav1.WriteRequest
+	cmd := NewWriteCommand(ds, logger.NewNoopLogger(), WithWriteHook(hook), WithWriteCommitted(func(req *openfgav1.WriteRequest) {
+		committed = req
+	}))
+
+	t.Run("the_hook_mutates_the_write", func(t *testing.T) {
+		_, err := cmd.Execute(ctx, &openfgav1.WriteRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Writes:               &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:README", "viewer", "user:jon")}},
+		})
+		require.NoError(t, err)
+
+		_, err = ds.ReadUserTuple(ctx, storeID, tuple.NewTupleKey("document:readme", "viewer", "user:jon"))
+		require.NoError(t, err)
+
+		require.Equal(t, "document:readme", committed.GetWrites().GetTupleKeys()[0].GetObject())
+	})
+
+	t.Run("the_hook_rejects_the_write", func(t *testing.T) {
+		_, err := cmd.Execute(ctx, &openfgav1.WriteRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: modelID,
+			Writes:               &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:readme", "owner", "user:jon")}},
+		})
+		require.ErrorContains(t, err, "the owners are protected")
+
+		e, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), e.Code())
+	})
+}
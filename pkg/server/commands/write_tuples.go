@@ -0,0 +1,440 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
+	"github.com/openfga/openfga/pkg/storage"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
+	"go.uber.org/zap"
+)
+
+// WriteTuplesRequest is a message of a WriteTuples stream.
+type WriteTuplesRequest struct {
+	// StoreID, AuthorizationModelID and Atomic are only read from the first message of the stream.
+	StoreID              string
+	AuthorizationModelID string
+
+	// Atomic commits every tuple of the stream in a single transaction once the client closes the stream, instead
+	// of committing them chunk by chunk.
+	Atomic bool
+
+	Deletes []*openfgav1.TupleKey
+	Writes  []*openfgav1.TupleKey
+}
+
+// WriteTuplesResult reports the outcome of a chunk of a WriteTuples stream, along with the totals of the stream
+// so far.
+type WriteTuplesResult struct {
+	// Chunk is the index of the chunk, starting from 0.
+	Chunk int
+
+	// Deletes and Writes are the number of tuples of the chunk deleted and written.
+	Deletes int
+	Writes  int
+
+	// Committed reports whether the tuples are committed. In atomic mode, the chunks are only staged, and a
+	// last result, whose Chunk is the number of chunks, reports the commit of the whole stream.
+	Committed bool
+
+	TotalDeletes int
+	TotalWrites  int
+}
+
+// WriteTuplesServer is the server side of a WriteTuples stream. Recv must return io.EOF once the client has sent
+// every tuple.
+type WriteTuplesServer interface {
+	Context() context.Context
+	Recv() (*WriteTuplesRequest, error)
+	Send(*WriteTuplesResult) error
+}
+
+// WriteTuplesCommand deletes and writes a stream of tuples in chunks of at most MaxTuplesPerWrite tuples, so that
+// the clients do not need to know the write limits of the server.
+type WriteTuplesCommand struct {
+	datastore          storage.OpenFGADatastore
+	logger             logger.Logger
+	typesystemResolver typesystem.TypesystemResolverFunc
+	chunkSize          int
+	checkCache         *graph.CheckCache
+	quotas             *quota.Enforcer
+	hook               writehook.Hook
+}
+
+type WriteTuplesCommandOption func(c *WriteTuplesCommand)
+
+func WithWriteTuplesLogger(l logger.Logger) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.logger = l
+	}
+}
+
+// WithWriteTuplesTypesystemResolver sets how the authorization model the written tuples are validated against is
+// resolved. Defaults to typesystem.MemoizedTypesystemResolverFunc.
+func WithWriteTuplesTypesystemResolver(resolver typesystem.TypesystemResolverFunc) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.typesystemResolver = resolver
+	}
+}
+
+// WithWriteTuplesChunkSize sets the maximum number of tuples of a chunk. It is capped by the MaxTuplesPerWrite of
+// the datastore.
+func WithWriteTuplesChunkSize(chunkSize int) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.chunkSize = chunkSize
+	}
+}
+
+// WithWriteTuplesCheckCache sets the check cache that is invalidated after every commit.
+func WithWriteTuplesCheckCache(cache *graph.CheckCache) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.checkCache = cache
+	}
+}
+
+// WithWriteTuplesQuotas enforces the tuple quotas of the stores. If nil, no quotas are enforced.
+func WithWriteTuplesQuotas(quotas *quota.Enforcer) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.quotas = quotas
+	}
+}
+
+// WithWriteTuplesHook submits every chunk to the hook before it is committed or staged, see writehook.Hook. A chunk
+// rejected by the hook ends the stream with a validation error.
+func WithWriteTuplesHook(hook writehook.Hook) WriteTuplesCommandOption {
+	return func(c *WriteTuplesCommand) {
+		c.hook = hook
+	}
+}
+
+func NewWriteTuplesCommand(datastore storage.OpenFGADatastore, opts ...WriteTuplesCommandOption) *WriteTuplesCommand {
+	c := &WriteTuplesCommand{
+		datastore: datastore,
+		logger:    logger.NewNoopLogger(),
+		chunkSize: datastore.MaxTuplesPerWrite(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.typesystemResolver == nil {
+		c.typesystemResolver = typesystem.MemoizedTypesystemResolverFunc(datastore)
+	}
+
+	if c.chunkSize <= 0 || c.chunkSize > datastore.MaxTuplesPerWrite() {
+		c.chunkSize = datastore.MaxTuplesPerWrite()
+	}
+
+	return c
+}
+
+// writeTuplesChunk is the pending chunk of a WriteTuples stream.
+type writeTuplesChunk struct {
+	deletes []*openfgav1.TupleKey
+	writes  []*openfgav1.TupleKey
+	tuples  map[string]struct{}
+}
+
+func (c *writeTuplesChunk) size() int {
+	return len(c.deletes) + len(c.writes)
+}
+
+func (c *writeTuplesChunk) add(tk *openfgav1.TupleKey, write bool) {
+	if write {
+		c.writes = append(c.writes, tk)
+	} else {
+		c.deletes = append(c.deletes, tk)
+	}
+	c.tuples[tupleUtils.TupleKeyToString(tk)] = struct{}{}
+}
+
+func (c *writeTuplesChunk) reset() {
+	c.deletes = nil
+	c.writes = nil
+	c.tuples = map[string]struct{}{}
+}
+
+// Execute deletes and writes the tuples received from the stream until the client closes it. The written tuples
+// are validated against the authorization model resolved from the first message, and the tuples are committed in
+// chunks, deletes before writes, as soon as a chunk is full. A tuple already in the pending chunk starts a new
+// chunk, so that the operations on a tuple are applied in the order they are received. The result of every chunk
+// is sent to the client once it is committed, and the first chunk that fails ends the stream with its error: the
+// chunks before it stay committed.
+//
+// In atomic mode, the chunks are staged in the datastore as they are received, see storage.StagedWriteBackend,
+// and they are all committed in a single transaction once the client closes the stream: either every tuple of the
+// stream is committed, or none is. A tuple may then appear only once in the stream.
+func (c *WriteTuplesCommand) Execute(ctx context.Context, srv WriteTuplesServer) error {
+	first, err := srv.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return err
+	}
+
+	storeID := first.StoreID
+	if storeID == "" {
+		return serverErrors.ValidationError(errors.New("the first message of the stream must set the store id"))
+	}
+
+	typesys, err := c.typesystemResolver(ctx, storeID, first.AuthorizationModelID)
+	if err != nil {
+		return err
+	}
+
+	if first.Atomic {
+		return c.executeAtomic(ctx, srv, storeID, typesys, first)
+	}
+
+	result := &WriteTuplesResult{}
+	chunk := &writeTuplesChunk{}
+	chunk.reset()
+
+	flush := func() error {
+		if err := c.admitChunk(ctx, storeID, typesys, chunk); err != nil {
+			return err
+		}
+
+		if err := c.commitChunk(ctx, storeID, chunk); err != nil {
+			return err
+		}
+
+		result.Deletes, result.Writes, result.Committed = len(chunk.deletes), len(chunk.writes), true
+		result.TotalDeletes += result.Deletes
+		result.TotalWrites += result.Writes
+		if err := srv.Send(result); err != nil {
+			return serverErrors.NewInternalError("", err)
+		}
+
+		result = &WriteTuplesResult{
+			Chunk:        result.Chunk + 1,
+			TotalDeletes: result.TotalDeletes,
+			TotalWrites:  result.TotalWrites,
+		}
+		chunk.reset()
+
+		return nil
+	}
+
+	err = c.receive(srv, typesys, first, func(tk *openfgav1.TupleKey, write bool) error {
+		if _, ok := chunk.tuples[tupleUtils.TupleKeyToString(tk)]; ok {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		chunk.add(tk, write)
+		if chunk.size() == c.chunkSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if chunk.size() > 0 {
+		return flush()
+	}
+
+	return nil
+}
+
+// executeAtomic stages the chunks of the stream, and commits them once the client closes the stream. The staged
+// write is discarded if the stream fails.
+func (c *WriteTuplesCommand) executeAtomic(ctx context.Context, srv WriteTuplesServer, storeID string, typesys *typesystem.TypeSystem, first *WriteTuplesRequest) (err error) {
+	stagedID := ulid.Make().String()
+	defer func() {
+		if err != nil {
+			if discardErr := c.datastore.DiscardStagedWrite(ctx, storeID, stagedID); discardErr != nil {
+				c.logger.WarnWithContext(ctx, "failed to discard the staged write", zap.String("store_id", storeID), zap.String("staged_write_id", stagedID), zap.Error(discardErr))
+			}
+		}
+	}()
+
+	result := &WriteTuplesResult{}
+	chunk := &writeTuplesChunk{}
+	chunk.reset()
+	tuples := map[string]struct{}{}
+
+	flush := func() error {
+		if err := c.admitChunk(ctx, storeID, typesys, chunk); err != nil {
+			return err
+		}
+
+		if err := c.datastore.StageWrite(ctx, storeID, stagedID, chunk.deletes, chunk.writes); err != nil {
+			return handleError(err)
+		}
+
+		result.Deletes, result.Writes = len(chunk.deletes), len(chunk.writes)
+		result.TotalDeletes += result.Deletes
+		result.TotalWrites += result.Writes
+		if err := srv.Send(result); err != nil {
+			return serverErrors.NewInternalError("", err)
+		}
+
+		result = &WriteTuplesResult{
+			Chunk:        result.Chunk + 1,
+			TotalDeletes: result.TotalDeletes,
+			TotalWrites:  result.TotalWrites,
+		}
+		chunk.reset()
+
+		return nil
+	}
+
+	err = c.receive(srv, typesys, first, func(tk *openfgav1.TupleKey, write bool) error {
+		key := tupleUtils.TupleKeyToString(tk)
+		if _, ok := tuples[key]; ok {
+			return serverErrors.DuplicateTupleInWrite(tk)
+		}
+		tuples[key] = struct{}{}
+
+		chunk.add(tk, write)
+		if chunk.size() == c.chunkSize {
+			return flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if chunk.size() > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	if result.TotalDeletes+result.TotalWrites == 0 {
+		return nil
+	}
+
+	if err := c.commit(ctx, storeID, result.TotalDeletes, result.TotalWrites, func() error {
+		return c.datastore.CommitStagedWrite(ctx, storeID, stagedID)
+	}); err != nil {
+		return err
+	}
+
+	result.Committed = true
+	if err := srv.Send(result); err != nil {
+		return serverErrors.NewInternalError("", err)
+	}
+
+	return nil
+}
+
+// receive validates the tuples received from the stream, starting with the first message, and calls add with every
+// tuple, in the order they are received. The deletes of a message are added before its writes.
+func (c *WriteTuplesCommand) receive(srv WriteTuplesServer, typesys *typesystem.TypeSystem, first *WriteTuplesRequest, add func(tk *openfgav1.TupleKey, write bool) error) error {
+	req := first
+	for {
+		for _, tk := range req.Deletes {
+			if !tupleUtils.IsValidUser(tk.GetUser()) {
+				return serverErrors.ValidationError(
+					&tupleUtils.InvalidTupleError{
+						Cause:    fmt.Errorf("the 'user' field is malformed"),
+						TupleKey: tk,
+					},
+				)
+			}
+
+			if err := add(tk, false); err != nil {
+				return err
+			}
+		}
+
+		for _, tk := range req.Writes {
+			if err := ValidateTupleToWrite(typesys, tk); err != nil {
+				return err
+			}
+
+			if err := add(tk, true); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		req, err = srv.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// admitChunk replaces the deletes and writes of the chunk by those admitted by the hook, validating the admitted writes.
+func (c *WriteTuplesCommand) admitChunk(ctx context.Context, storeID string, typesys *typesystem.TypeSystem, chunk *writeTuplesChunk) error {
+	if c.hook == nil {
+		return nil
+	}
+
+	deletes, writes, err := admitWrite(ctx, c.hook, storeID, typesys.GetAuthorizationModelID(), chunk.deletes, chunk.writes)
+	if err != nil {
+		return err
+	}
+
+	for _, tk := range writes {
+		if err := ValidateTupleToWrite(typesys, tk); err != nil {
+			return err
+		}
+	}
+
+	chunk.deletes, chunk.writes = deletes, writes
+
+	return nil
+}
+
+// commitChunk writes the chunk in a single transaction.
+func (c *WriteTuplesCommand) commitChunk(ctx context.Context, storeID string, chunk *writeTuplesChunk) error {
+	ctx, span := tracer.Start(ctx, "writeTuples.commitChunk")
+	defer span.End()
+
+	return c.commit(ctx, storeID, len(chunk.deletes), len(chunk.writes), func() error {
+		return c.datastore.Write(ctx, storeID, chunk.deletes, chunk.writes)
+	})
+}
+
+// commit runs the write, unless it would take the store over its tuple quota, and then invalidates the check cache
+// of the store.
+func (c *WriteTuplesCommand) commit(ctx context.Context, storeID string, deletes, writes int, write func() error) error {
+	if c.quotas != nil {
+		if err := c.quotas.CheckTupleCount(ctx, storeID, deletes, writes); err != nil {
+			return err
+		}
+	}
+
+	if err := write(); err != nil {
+		return handleError(err)
+	}
+
+	if c.quotas != nil {
+		c.quotas.RecordWrite(storeID, deletes, writes)
+	}
+
+	if c.checkCache != nil {
+		if err := c.checkCache.InvalidateStore(ctx, storeID); err != nil {
+			c.logger.WarnWithContext(ctx, "failed to invalidate the check cache of the store", zap.String("store_id", storeID), zap.Error(err))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/stretchr/testify/require"
+)
+
+type mockWriteTuplesServer struct {
+	ctx      context.Context
+	requests []*WriteTuplesRequest
+	results  []*WriteTuplesResult
+}
+
+func (m *mockWriteTuplesServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockWriteTuplesServer) Recv() (*WriteTuplesRequest, error) {
+	if len(m.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	req := m.requests[0]
+	m.requests = m.requests[1:]
+
+	return req, nil
+}
+
+func (m *mockWriteTuplesServer) Send(result *WriteTuplesResult) error {
+	m.results = append(m.results, result)
+	return nil
+}
+
+func TestWriteTuplesCommand(t *testing.T) {
+	ctx := context.Background()
+
+	newStore := func(t *testing.T) (storage.OpenFGADatastore, string) {
+		storeID := ulid.Make().String()
+
+		ds := memory.New()
+		t.Cleanup(ds.Close)
+
+		err := ds.WriteAuthorizationModel(ctx, storeID, &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: parser.MustParse(`
+			type user
+
+			type document
+			  relations
+			    define viewer: [user] as self
+			    define can_view as viewer
+			`),
+		})
+		require.NoError(t, err)
+
+		err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:existing", "viewer", "user:jon")})
+		require.NoError(t, err)
+
+		return ds, storeID
+	}
+
+	readObjects := func(t *testing.T, ds storage.OpenFGADatastore, storeID string) int {
+		tuples, _, err := ds.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: 20})
+		require.NoError(t, err)
+		return len(tuples)
+	}
+
+	var tupleKeys []*openfgav1.TupleKey
+	for i := 0; i < 5; i++ {
+		tupleKeys = append(tupleKeys, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+
+	t.Run("tuples_are_committed_in_chunks", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Deletes: []*openfgav1.TupleKey{tuple.NewTupleKey("document:existing", "viewer", "user:jon")}, Writes: tupleKeys[:2]},
+				{Writes: tupleKeys[2:]},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds, WithWriteTuplesChunkSize(2)).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.results, 3)
+		for i, result := range srv.results {
+			require.Equal(t, i, result.Chunk)
+			require.True(t, result.Committed)
+		}
+		require.Equal(t, 1, srv.results[0].Deletes)
+		require.Equal(t, 1, srv.results[2].TotalDeletes)
+		require.Equal(t, 5, srv.results[2].TotalWrites)
+
+		require.Equal(t, 5, readObjects(t, ds, storeID))
+	})
+
+	t.Run("a_repeated_tuple_starts_a_new_chunk", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Writes: tupleKeys[:1]},
+				{Deletes: tupleKeys[:1]},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		require.Len(t, srv.results, 2)
+		require.Equal(t, 1, readObjects(t, ds, storeID))
+	})
+
+	t.Run("the_failed_chunk_ends_the_stream", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Writes: tupleKeys[:2]},
+				{Writes: []*openfgav1.TupleKey{tuple.NewTupleKey("document:existing", "viewer", "user:jon")}},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds, WithWriteTuplesChunkSize(2)).Execute(ctx, srv)
+		require.Error(t, err)
+
+		require.Len(t, srv.results, 1)
+		require.Equal(t, 3, readObjects(t, ds, storeID))
+	})
+
+	t.Run("atomic_writes_are_committed_together", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Atomic: true, Writes: tupleKeys[:3]},
+				{Writes: tupleKeys[3:]},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds, WithWriteTuplesChunkSize(2)).Execute(ctx, srv)
+		require.NoError(t, err)
+
+		// three staged chunks, then the commit
+		require.Len(t, srv.results, 4)
+		for _, result := range srv.results[:3] {
+			require.False(t, result.Committed)
+		}
+		require.True(t, srv.results[3].Committed)
+		require.Equal(t, 3, srv.results[3].Chunk)
+		require.Equal(t, 5, srv.results[3].TotalWrites)
+
+		require.Equal(t, 6, readObjects(t, ds, storeID))
+	})
+
+	t.Run("atomic_writes_are_all_or_nothing", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Atomic: true, Writes: tupleKeys[:3]},
+				{Writes: []*openfgav1.TupleKey{tuple.NewTupleKey("document:existing", "viewer", "user:jon")}},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds, WithWriteTuplesChunkSize(2)).Execute(ctx, srv)
+		require.Error(t, err)
+
+		require.Equal(t, 1, readObjects(t, ds, storeID))
+	})
+
+	t.Run("atomic_writes_reject_repeated_tuples", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Atomic: true, Writes: tupleKeys[:1]},
+				{Deletes: tupleKeys[:1]},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds).Execute(ctx, srv)
+		require.Error(t, err)
+
+		require.Equal(t, 1, readObjects(t, ds, storeID))
+	})
+
+	t.Run("invalid_tuples_are_rejected", func(t *testing.T) {
+		ds, storeID := newStore(t)
+
+		srv := &mockWriteTuplesServer{
+			ctx: ctx,
+			requests: []*WriteTuplesRequest{
+				{StoreID: storeID, Writes: []*openfgav1.TupleKey{tuple.NewTupleKey("document:a", "can_view", "user:jon")}},
+			},
+		}
+
+		err := NewWriteTuplesCommand(ds).Execute(ctx, srv)
+		require.Error(t, err)
+		require.Empty(t, srv.results)
+	})
+}
@@ -0,0 +1,221 @@
+package errors
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details of the errors returned by the server.
+const ErrorDomain = "openfga.dev"
+
+// The metadata keys of the ErrorInfo details of the errors returned by the server.
+const (
+	ErrorInfoCodeKey      = "code"
+	ErrorInfoRetryableKey = "retryable"
+	ErrorInfoFieldKey     = "field"
+)
+
+// ErrorDetails is the structured description of an error returned by the server, which clients can rely on
+// instead of matching the error message. It is carried by the status of the error as an ErrorInfo detail, whose
+// reason is the Reason and whose metadata has the Code, Retryable and Field, and as a BadRequest detail with a
+// violation of the Field if the error is about one.
+type ErrorDetails struct {
+	// Code is the error code, a value of one of the error code enums of the API (or PermissionDeniedErrorCode).
+	Code int32
+
+	// Reason is the name of the error code, e.g. "invalid_continuation_token".
+	Reason string
+
+	// Retryable reports whether the request may succeed if it is retried as is, possibly after a delay.
+	Retryable bool
+
+	// Field is the path of the field of the request the error is about, e.g. "tuple_key.object", if any.
+	Field string
+}
+
+// errorCodeFields are the fields of the requests the errors of the codes are about.
+var errorCodeFields = map[int32]string{
+	int32(openfgav1.ErrorCode_authorization_model_not_found):                    "authorization_model_id",
+	int32(openfgav1.ErrorCode_authorization_model_assertions_not_found):         "authorization_model_id",
+	int32(openfgav1.ErrorCode_invalid_continuation_token):                       "continuation_token",
+	int32(openfgav1.ErrorCode_query_string_type_continuation_token_mismatch):    "type",
+	int32(openfgav1.ErrorCode_invalid_check_input):                              "tuple_key",
+	int32(openfgav1.ErrorCode_invalid_expand_input):                             "tuple_key",
+	int32(openfgav1.ErrorCode_invalid_tuple):                                    "tuple_key",
+	int32(openfgav1.ErrorCode_invalid_object_format):                            "tuple_key.object",
+	int32(openfgav1.ErrorCode_type_not_found):                                   "tuple_key.object",
+	int32(openfgav1.ErrorCode_relation_not_found):                               "tuple_key.relation",
+	int32(openfgav1.ErrorCode_invalid_write_input):                              "writes",
+	int32(openfgav1.ErrorCode_cannot_allow_duplicate_tuples_in_one_request):     "writes",
+	int32(openfgav1.ErrorCode_invalid_authorization_model):                      "type_definitions",
+	int32(openfgav1.NotFoundErrorCode_store_id_not_found):                       "store_id",
+	int32(openfgav1.ErrorCode_latest_authorization_model_not_found):             "store_id",
+	int32(openfgav1.ErrorCode_write_failed_due_to_invalid_input):                "writes",
+	int32(openfgav1.ErrorCode_cannot_allow_multiple_references_to_one_relation): "type_definitions",
+}
+
+// retryableErrorCodes are the codes of the errors which are transient: the server was overloaded or
+// unavailable, the request conflicted with a concurrent one or it ran out of time.
+var retryableErrorCodes = map[int32]struct{}{
+	int32(openfgav1.InternalErrorCode_resource_exhausted): {},
+	int32(openfgav1.InternalErrorCode_unavailable):        {},
+	int32(openfgav1.InternalErrorCode_aborted):            {},
+	int32(openfgav1.InternalErrorCode_deadline_exceeded):  {},
+}
+
+// validationFieldRegexp matches the fields named by the errors of the request validators, e.g.
+// "invalid CheckRequest.TupleKey: embedded message failed validation | caused by: invalid CheckRequestTupleKey.User".
+var validationFieldRegexp = regexp.MustCompile(`invalid [A-Za-z0-9]+\.([A-Za-z0-9]+)`)
+
+// Describe returns the structured description of the error. If the status of the error already carries an
+// ErrorInfo of the ErrorDomain, the description is read from it.
+func Describe(err error) *ErrorDetails {
+	st := statusOf(err)
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == ErrorDomain {
+			code, _ := strconv.ParseInt(info.GetMetadata()[ErrorInfoCodeKey], 10, 32)
+			return &ErrorDetails{
+				Code:      int32(code),
+				Reason:    info.GetReason(),
+				Retryable: info.GetMetadata()[ErrorInfoRetryableKey] == "true",
+				Field:     info.GetMetadata()[ErrorInfoFieldKey],
+			}
+		}
+	}
+
+	code := ConvertToEncodedErrorCode(st)
+	details := &ErrorDetails{
+		Code:   code,
+		Reason: NewEncodedError(code, "").Code(),
+		Field:  errorCodeFields[code],
+	}
+
+	if _, ok := retryableErrorCodes[code]; ok {
+		details.Retryable = true
+	}
+
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.RetryInfo:
+			details.Retryable = true
+		case *errdetails.BadRequest:
+			if violations := detail.GetFieldViolations(); len(violations) > 0 {
+				details.Field = violations[0].GetField()
+			}
+		}
+	}
+
+	if details.Field == "" && st.Code() == codes.InvalidArgument {
+		details.Field = validationField(st.Message())
+	}
+
+	return details
+}
+
+// WithDetails returns the error with its structured description (see Describe) attached to its status, along
+// with the details the status already carries. The error is returned as is if it is nil or already carries an
+// ErrorInfo of the ErrorDomain.
+func WithDetails(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st := statusOf(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == ErrorDomain {
+			return err
+		}
+	}
+
+	details := Describe(err)
+
+	info := &errdetails.ErrorInfo{
+		Reason: details.Reason,
+		Domain: ErrorDomain,
+		Metadata: map[string]string{
+			ErrorInfoCodeKey:      strconv.Itoa(int(details.Code)),
+			ErrorInfoRetryableKey: strconv.FormatBool(details.Retryable),
+		},
+	}
+
+	if details.Field != "" {
+		info.Metadata[ErrorInfoFieldKey] = details.Field
+	}
+
+	detailed := []proto.Message{info}
+	if details.Field != "" && !hasBadRequest(st) {
+		detailed = append(detailed, &errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: details.Field, Description: st.Message()}},
+		})
+	}
+
+	p := st.Proto()
+	for _, detail := range detailed {
+		// the details are marshalled deterministically, so that the error received by a client is still the same
+		// as the error (see errors.Is), whose status is compared by value
+		a := &anypb.Any{}
+		if err := anypb.MarshalFrom(a, detail, proto.MarshalOptions{Deterministic: true}); err != nil {
+			return st.Err()
+		}
+		p.Details = append(p.Details, a)
+	}
+
+	return status.FromProto(p).Err()
+}
+
+func hasBadRequest(st *status.Status) bool {
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.BadRequest); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// statusOf returns the status of the error. The status of an InternalError is its public status.
+func statusOf(err error) *status.Status {
+	var internalError InternalError
+	if errors.As(err, &internalError) {
+		return status.Convert(internalError.public)
+	}
+
+	return status.Convert(err)
+}
+
+// validationField returns the path of the field named by the error message of a request validator, in snake
+// case, or an empty string if the message does not name one.
+func validationField(message string) string {
+	var path []string
+	for _, match := range validationFieldRegexp.FindAllStringSubmatch(message, -1) {
+		path = append(path, snakeCase(match[1]))
+	}
+
+	return strings.Join(path, ".")
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
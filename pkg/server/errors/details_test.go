@@ -0,0 +1,139 @@
+package errors
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected *ErrorDetails
+	}{
+		{
+			name: "validation_error_about_a_field",
+			err:  AuthorizationModelNotFound("01GS89AJC3R3PFQ9BNY5ZF6Q97"),
+			expected: &ErrorDetails{
+				Code:   int32(openfgav1.ErrorCode_authorization_model_not_found),
+				Reason: "authorization_model_not_found",
+				Field:  "authorization_model_id",
+			},
+		},
+		{
+			name: "request_validator_error",
+			err:  status.Error(codes.InvalidArgument, "invalid CheckRequest.TupleKey: embedded message failed validation | caused by: invalid CheckRequestTupleKey.User: value length must be at most 512 bytes"),
+			expected: &ErrorDetails{
+				Code:   int32(openfgav1.ErrorCode_validation_error),
+				Reason: "validation_error",
+				Field:  "tuple_key.user",
+			},
+		},
+		{
+			name: "retryable_error",
+			err:  ServerOverloaded,
+			expected: &ErrorDetails{
+				Code:      int32(openfgav1.InternalErrorCode_unavailable),
+				Reason:    "unavailable",
+				Retryable: true,
+			},
+		},
+		{
+			name: "retryable_error_with_retry_info",
+			err:  RateLimitExceeded("rate limit exceeded", time.Second),
+			expected: &ErrorDetails{
+				Code:      int32(openfgav1.InternalErrorCode_resource_exhausted),
+				Reason:    "resource_exhausted",
+				Retryable: true,
+			},
+		},
+		{
+			name: "internal_error",
+			err:  NewInternalError("", errors.New("internal")),
+			expected: &ErrorDetails{
+				Code:   int32(openfgav1.InternalErrorCode_internal_error),
+				Reason: "internal_error",
+			},
+		},
+		{
+			name: "permission_denied",
+			err:  PermissionDenied("not allowed"),
+			expected: &ErrorDetails{
+				Code:   PermissionDeniedErrorCode,
+				Reason: "permission_denied",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, Describe(test.err))
+
+			// the description survives the round trip through the status details
+			err := WithDetails(test.err)
+			require.Equal(t, test.expected, Describe(err))
+			require.Equal(t, err, WithDetails(err))
+		})
+	}
+}
+
+func TestWithDetails(t *testing.T) {
+	require.NoError(t, WithDetails(nil))
+
+	t.Run("keeps_the_code_message_and_details", func(t *testing.T) {
+		original := status.Convert(RateLimitExceeded("rate limit exceeded", time.Second))
+
+		st := status.Convert(WithDetails(original.Err()))
+		require.Equal(t, original.Code(), st.Code())
+		require.Equal(t, original.Message(), st.Message())
+		require.Len(t, st.Details(), 2)
+		require.IsType(t, &errdetails.RetryInfo{}, st.Details()[0])
+
+		info, ok := st.Details()[1].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		require.Equal(t, ErrorDomain, info.GetDomain())
+		require.Equal(t, "resource_exhausted", info.GetReason())
+		require.Equal(t, map[string]string{
+			ErrorInfoCodeKey:      strconv.Itoa(int(openfgav1.InternalErrorCode_resource_exhausted)),
+			ErrorInfoRetryableKey: "true",
+		}, info.GetMetadata())
+	})
+
+	t.Run("field_violation", func(t *testing.T) {
+		st := status.Convert(WithDetails(InvalidContinuationToken))
+		require.Len(t, st.Details(), 2)
+
+		badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Len(t, badRequest.GetFieldViolations(), 1)
+		require.Equal(t, "continuation_token", badRequest.GetFieldViolations()[0].GetField())
+	})
+
+	t.Run("internal_error_does_not_leak_internals", func(t *testing.T) {
+		st := status.Convert(WithDetails(NewInternalError("", errors.New("internal"))))
+		require.Equal(t, codes.Code(openfgav1.InternalErrorCode_internal_error), st.Code())
+		require.Equal(t, InternalServerErrorMsg, st.Message())
+	})
+
+	t.Run("errors_received_by_clients_are_the_same", func(t *testing.T) {
+		// the status is sent to the client in its marshalled form
+		b, err := proto.Marshal(status.Convert(WithDetails(AuthorizationModelNotFound("01GS89AJC3R3PFQ9BNY5ZF6Q97"))).Proto())
+		require.NoError(t, err)
+
+		received := &spb.Status{}
+		require.NoError(t, proto.Unmarshal(b, received))
+
+		require.ErrorIs(t, status.FromProto(received).Err(), AuthorizationModelNotFound("01GS89AJC3R3PFQ9BNY5ZF6Q97"))
+		require.NotErrorIs(t, status.FromProto(received).Err(), AuthorizationModelNotFound("01GS89AJC3R3PFQ9BNY5ZF6Q98"))
+	})
+}
@@ -15,6 +15,10 @@ const (
 	cFirstValidationErrorCode      int32 = 2000
 	cFirstInternalErrorCode        int32 = 4000
 	cFirstUnknownEndpointErrorCode int32 = 5000
+
+	// PermissionDeniedErrorCode is the code of the errors returned when an authenticated subject is not allowed to
+	// call an API method on a store. It is in the authentication range, but mapped to HTTP 403 instead of 401.
+	PermissionDeniedErrorCode int32 = 1020
 )
 
 type ErrorResponse struct {
@@ -83,7 +87,11 @@ func NewEncodedError(errorCode int32, message string) *EncodedError {
 	var httpStatusCode int
 	var grpcStatusCode codes.Code
 	var code string
-	if errorCode >= cFirstAuthenticationErrorCode && errorCode < cFirstValidationErrorCode {
+	if errorCode == PermissionDeniedErrorCode {
+		httpStatusCode = http.StatusForbidden
+		code = "permission_denied"
+		grpcStatusCode = codes.PermissionDenied
+	} else if errorCode >= cFirstAuthenticationErrorCode && errorCode < cFirstValidationErrorCode {
 		httpStatusCode = http.StatusUnauthorized
 		code = openfgav1.AuthErrorCode(errorCode).String()
 		grpcStatusCode = codes.Unauthenticated
@@ -91,6 +99,10 @@ func NewEncodedError(errorCode int32, message string) *EncodedError {
 		httpStatusCode = http.StatusBadRequest
 		code = openfgav1.ErrorCode(errorCode).String()
 		grpcStatusCode = codes.InvalidArgument
+	} else if errorCode == int32(openfgav1.InternalErrorCode_resource_exhausted) {
+		httpStatusCode = http.StatusTooManyRequests
+		code = openfgav1.InternalErrorCode(errorCode).String()
+		grpcStatusCode = codes.ResourceExhausted
 	} else if errorCode >= cFirstInternalErrorCode && errorCode < cFirstUnknownEndpointErrorCode {
 		httpStatusCode = http.StatusInternalServerError
 		code = openfgav1.InternalErrorCode(errorCode).String()
@@ -226,6 +238,8 @@ func ConvertToEncodedErrorCode(statusError *status.Status) int32 {
 		return int32(openfgav1.InternalErrorCode_already_exists)
 	case codes.ResourceExhausted:
 		return int32(openfgav1.InternalErrorCode_resource_exhausted)
+	case codes.PermissionDenied:
+		return PermissionDeniedErrorCode
 	case codes.FailedPrecondition:
 		return int32(openfgav1.InternalErrorCode_failed_precondition)
 	case codes.Aborted:
@@ -39,6 +39,15 @@ func TestEncodedError(t *testing.T) {
 			expectedCodeString:     "auth_failed_invalid_subject",
 			isValidEncodedError:    true,
 		},
+		{
+			_name:                  "auth_error:_permission_denied",
+			errorCode:              PermissionDeniedErrorCode,
+			message:                "error message",
+			expectedHTTPStatusCode: http.StatusForbidden,
+			expectedCode:           1020,
+			expectedCodeString:     "permission_denied",
+			isValidEncodedError:    true,
+		},
 		{
 			_name:                  "auth_error:_invalid_audience",
 			errorCode:              int32(openfgav1.AuthErrorCode_auth_failed_invalid_audience),
@@ -180,6 +189,11 @@ func TestConvertToEncodedErrorCode(t *testing.T) {
 			status:            status.New(codes.ResourceExhausted, "other error"),
 			expectedErrorCode: int32(openfgav1.InternalErrorCode_resource_exhausted),
 		},
+		{
+			_name:             "permission_denied",
+			status:            status.New(codes.PermissionDenied, "other error"),
+			expectedErrorCode: PermissionDeniedErrorCode,
+		},
 		{
 			_name:             "failed_precondition",
 			status:            status.New(codes.FailedPrecondition, "other error"),
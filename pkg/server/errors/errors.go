@@ -2,29 +2,41 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/condition"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/tuple"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const InternalServerErrorMsg = "Internal Server Error"
 
 var (
 	// AuthorizationModelResolutionTooComplex is used to avoid stack overflows
-	AuthorizationModelResolutionTooComplex = status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_resolution_too_complex), "Authorization Model resolution required too many rewrite rules to be resolved. Check your authorization model for infinite recursion or too much nesting")
-	InvalidWriteInput                      = status.Error(codes.Code(openfgav1.ErrorCode_invalid_write_input), "Invalid input. Make sure you provide at least one write, or at least one delete")
-	InvalidContinuationToken               = status.Error(codes.Code(openfgav1.ErrorCode_invalid_continuation_token), "Invalid continuation token")
-	InvalidCheckInput                      = status.Error(codes.Code(openfgav1.ErrorCode_invalid_check_input), "Invalid input. Make sure you provide a user, object and relation")
-	InvalidExpandInput                     = status.Error(codes.Code(openfgav1.ErrorCode_invalid_expand_input), "Invalid input. Make sure you provide an object and a relation")
-	UnsupportedUserSet                     = status.Error(codes.Code(openfgav1.ErrorCode_unsupported_user_set), "Userset is not supported (right now)")
-	StoreIDNotFound                        = status.Error(codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found), "Store ID not found")
-	MismatchObjectType                     = status.Error(codes.Code(openfgav1.ErrorCode_query_string_type_continuation_token_mismatch), "The type in the querystring and the continuation token don't match")
-	RequestCancelled                       = status.Error(codes.Code(openfgav1.InternalErrorCode_cancelled), "Request Cancelled")
+	AuthorizationModelResolutionTooComplex = newError(codes.Code(openfgav1.ErrorCode_authorization_model_resolution_too_complex), "Authorization Model resolution required too many rewrite rules to be resolved. Check your authorization model for infinite recursion or too much nesting")
+	CycleDetected                          = newError(codes.Code(openfgav1.ErrorCode_authorization_model_resolution_too_complex), "Authorization Model resolution encountered a cycle. Check your relationship tuples for usersets or parents that lead back to themselves")
+	InvalidWriteInput                      = newError(codes.Code(openfgav1.ErrorCode_invalid_write_input), "Invalid input. Make sure you provide at least one write, or at least one delete")
+	InvalidContinuationToken               = newError(codes.Code(openfgav1.ErrorCode_invalid_continuation_token), "Invalid continuation token")
+	InvalidCheckInput                      = newError(codes.Code(openfgav1.ErrorCode_invalid_check_input), "Invalid input. Make sure you provide a user, object and relation")
+	InvalidExpandInput                     = newError(codes.Code(openfgav1.ErrorCode_invalid_expand_input), "Invalid input. Make sure you provide an object and a relation")
+	UnsupportedUserSet                     = newError(codes.Code(openfgav1.ErrorCode_unsupported_user_set), "Userset is not supported (right now)")
+	StoreIDNotFound                        = newError(codes.Code(openfgav1.NotFoundErrorCode_store_id_not_found), "Store ID not found")
+	MismatchObjectType                     = newError(codes.Code(openfgav1.ErrorCode_query_string_type_continuation_token_mismatch), "The type in the querystring and the continuation token don't match")
+	RequestCancelled                       = newError(codes.Code(openfgav1.InternalErrorCode_cancelled), "Request Cancelled")
+	ServerOverloaded                       = newError(codes.Code(openfgav1.InternalErrorCode_unavailable), "The server is shedding load because the datastore is degraded. Please retry later")
+	ReadOnlyMode                           = newError(codes.Code(openfgav1.InternalErrorCode_failed_precondition), "The server is running in read-only mode and does not accept writes")
+	ChangelogConflict                      = newError(codes.Code(openfgav1.InternalErrorCode_aborted), "The changelog of the store has changed since the expected changelog token. Read the changes and retry")
+	ExpectedChangelogTokenUnsupported      = newError(codes.Code(openfgav1.InternalErrorCode_failed_precondition), "The datastore does not support expected changelog tokens")
+	RequestDeadlineExceeded                = newError(codes.Code(openfgav1.InternalErrorCode_deadline_exceeded), "The request exceeded its deadline")
+	StreamSendTimeout                      = newError(codes.Code(openfgav1.InternalErrorCode_deadline_exceeded), "The client did not receive the streamed results in time")
 )
 
 type InternalError struct {
@@ -59,24 +71,30 @@ func NewInternalError(public string, internal error) InternalError {
 	}
 }
 
+// newError returns the error of the code and message with its structured description attached to its status (see
+// WithDetails), so that the errors the clients receive, which carry it, are still the same as the error.
+func newError(code codes.Code, msg string) error {
+	return WithDetails(status.Error(code, msg))
+}
+
 func ValidationError(cause error) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_validation_error), cause.Error())
+	return newError(codes.Code(openfgav1.ErrorCode_validation_error), cause.Error())
 }
 
 func AssertionsNotForAuthorizationModelFound(modelID string) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_assertions_not_found), fmt.Sprintf("No assertions found for authorization model '%s'", modelID))
+	return newError(codes.Code(openfgav1.ErrorCode_authorization_model_assertions_not_found), fmt.Sprintf("No assertions found for authorization model '%s'", modelID))
 }
 
 func AuthorizationModelNotFound(modelID string) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), fmt.Sprintf("Authorization Model '%s' not found", modelID))
+	return newError(codes.Code(openfgav1.ErrorCode_authorization_model_not_found), fmt.Sprintf("Authorization Model '%s' not found", modelID))
 }
 
 func LatestAuthorizationModelNotFound(store string) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_latest_authorization_model_not_found), fmt.Sprintf("No authorization models found for store '%s'", store))
+	return newError(codes.Code(openfgav1.ErrorCode_latest_authorization_model_not_found), fmt.Sprintf("No authorization models found for store '%s'", store))
 }
 
 func TypeNotFound(objectType string) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_type_not_found), fmt.Sprintf("type '%s' not found", objectType))
+	return newError(codes.Code(openfgav1.ErrorCode_type_not_found), fmt.Sprintf("type '%s' not found", objectType))
 }
 
 func RelationNotFound(relation string, objectType string, tk *openfgav1.TupleKey) error {
@@ -86,40 +104,74 @@ func RelationNotFound(relation string, objectType string, tk *openfgav1.TupleKey
 		msg += fmt.Sprintf(" for tuple '%s'", tuple.TupleKeyToString(tk))
 	}
 
-	return status.Error(codes.Code(openfgav1.ErrorCode_relation_not_found), msg)
+	return newError(codes.Code(openfgav1.ErrorCode_relation_not_found), msg)
 }
 
 func ExceededEntityLimit(entity string, limit int) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_exceeded_entity_limit),
+	return newError(codes.Code(openfgav1.ErrorCode_exceeded_entity_limit),
 		fmt.Sprintf("The number of %s exceeds the allowed limit of %d", entity, limit))
 }
 
+// ResolutionLimitExceeded is used when a query exceeds one of its per-request resolution limits, such as the
+// maximum number of dispatches or datastore reads of a Check.
+func ResolutionLimitExceeded(cause error) error {
+	return newError(codes.Code(openfgav1.InternalErrorCode_resource_exhausted),
+		fmt.Sprintf("Authorization Model resolution exceeded the limits of a single request: %v", cause))
+}
+
+// RateLimitExceeded is used when a request is rejected because its store, or the whole server, is receiving
+// more requests than allowed. If retryAfter is positive, the status carries a RetryInfo with the delay after
+// which the request may be retried.
+func RateLimitExceeded(msg string, retryAfter time.Duration) error {
+	st := status.New(codes.Code(openfgav1.InternalErrorCode_resource_exhausted), msg)
+	if retryAfter > 0 {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			st = withDetails
+		}
+	}
+
+	return WithDetails(st.Err())
+}
+
+// QuotaExceeded is used when a request would take a store over one of its quotas, such as its maximum number
+// of tuples.
+func QuotaExceeded(quota string, limit int) error {
+	return newError(codes.Code(openfgav1.InternalErrorCode_resource_exhausted),
+		fmt.Sprintf("The store has reached its quota of %d %s", limit, quota))
+}
+
+// PermissionDenied is used when the authenticated subject is not allowed to call an API method, or to call it
+// on the store of the request.
+func PermissionDenied(reason string) error {
+	return newError(codes.Code(PermissionDeniedErrorCode), reason)
+}
+
 func InvalidTuple(reason string, tuple *openfgav1.TupleKey) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_invalid_tuple), fmt.Sprintf("Invalid tuple '%s'. Reason: %s", tuple.String(), reason))
+	return newError(codes.Code(openfgav1.ErrorCode_invalid_tuple), fmt.Sprintf("Invalid tuple '%s'. Reason: %s", tuple.String(), reason))
 }
 
 // InvalidObjectFormat is used when an object does not have a type and id part
 func InvalidObjectFormat(tuple *openfgav1.TupleKey) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_invalid_object_format), fmt.Sprintf("Invalid object format for tuple '%s'", tuple.String()))
+	return newError(codes.Code(openfgav1.ErrorCode_invalid_object_format), fmt.Sprintf("Invalid object format for tuple '%s'", tuple.String()))
 }
 
 func DuplicateTupleInWrite(tk *openfgav1.TupleKey) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_cannot_allow_duplicate_tuples_in_one_request), fmt.Sprintf("duplicate tuple in write: user: '%s', relation: '%s', object: '%s'", tk.GetUser(), tk.GetRelation(), tk.GetObject()))
+	return newError(codes.Code(openfgav1.ErrorCode_cannot_allow_duplicate_tuples_in_one_request), fmt.Sprintf("duplicate tuple in write: user: '%s', relation: '%s', object: '%s'", tk.GetUser(), tk.GetRelation(), tk.GetObject()))
 }
 
 func WriteToIndirectRelationError(reason string, tk *openfgav1.TupleKey) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_invalid_tuple), fmt.Sprintf("Invalid tuple '%s'. Reason: %s", tk.String(), reason))
+	return newError(codes.Code(openfgav1.ErrorCode_invalid_tuple), fmt.Sprintf("Invalid tuple '%s'. Reason: %s", tk.String(), reason))
 }
 
 func WriteFailedDueToInvalidInput(err error) error {
 	if err != nil {
-		return status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), err.Error())
+		return newError(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), err.Error())
 	}
-	return status.Error(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "Write failed due to invalid input")
+	return newError(codes.Code(openfgav1.ErrorCode_write_failed_due_to_invalid_input), "Write failed due to invalid input")
 }
 
 func InvalidAuthorizationModelInput(err error) error {
-	return status.Error(codes.Code(openfgav1.ErrorCode_invalid_authorization_model), err.Error())
+	return newError(codes.Code(openfgav1.ErrorCode_invalid_authorization_model), err.Error())
 }
 
 // HandleError is used to hide internal errors from users. Use `public` to return an error message to the user.
@@ -130,6 +182,10 @@ func HandleError(public string, err error) error {
 		return MismatchObjectType
 	} else if errors.Is(err, storage.ErrCancelled) {
 		return RequestCancelled
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return RequestDeadlineExceeded
+	} else if errors.Is(err, condition.ErrEvaluationFailed) || errors.Is(err, condition.ErrInvalidCondition) {
+		return ValidationError(err)
 	}
 	return NewInternalError(public, err)
 }
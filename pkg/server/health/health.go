@@ -3,6 +3,10 @@ package health
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
 
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	"google.golang.org/grpc/codes"
@@ -10,15 +14,65 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+const (
+	// LivenessService is the service name of the health checks of the liveness of the server, which is serving
+	// as long as the server is up.
+	LivenessService = "liveness"
+
+	// ReadinessService is the service name of the health checks of the readiness of the server, which is serving
+	// once the server and its required dependencies are ready. The checks of the target service and of the
+	// whole server ("") are readiness checks too.
+	ReadinessService = "readiness"
+
+	// DatastoreDependency is the name of the datastore in the reports of the readiness of the server.
+	DatastoreDependency = "datastore"
+
+	// same value as run.DefaultConfig() (TODO break the import cycle, remove this hardcoded value and import that constant here)
+	defaultProbeTimeout = 3 * time.Second
+)
+
 // TargetService defines an interface that services can implement for server health checks.
 type TargetService interface {
 	IsReady(ctx context.Context) (bool, error)
 }
 
+// Dependency is a dependency of the server whose health is probed by the readiness checks.
+type Dependency struct {
+	Name string
+
+	// Probe returns an error if the dependency is unhealthy.
+	Probe func(ctx context.Context) error
+
+	// Optional dependencies are reported, but their failures do not make the server unready, e.g. a cache
+	// whose failures are treated as misses.
+	Optional bool
+}
+
+// DependencyStatus is the outcome of the probe of a Dependency.
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Optional  bool   `json:"optional,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Report is the outcome of a readiness check.
+type Report struct {
+	Ready        bool               `json:"ready"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
 type Checker struct {
 	healthv1pb.UnimplementedHealthServer
 	TargetService
 	TargetServiceName string
+
+	// Dependencies are probed by the readiness checks, on top of the datastore (the TargetService).
+	Dependencies []Dependency
+
+	// ProbeTimeout bounds every probe of a readiness check. Defaults to 3s.
+	ProbeTimeout time.Duration
 }
 
 var _ grpc_auth.ServiceAuthFuncOverride = (*Checker)(nil)
@@ -29,23 +83,114 @@ func (o *Checker) AuthFuncOverride(ctx context.Context, fullMethodName string) (
 }
 
 func (o *Checker) Check(ctx context.Context, req *healthv1pb.HealthCheckRequest) (*healthv1pb.HealthCheckResponse, error) {
-	requestedService := req.GetService()
-	if requestedService == "" || requestedService == o.TargetServiceName {
-		ready, err := o.TargetService.IsReady(ctx)
-		if err != nil {
-			return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_NOT_SERVING}, err
-		}
-
-		if !ready {
+	switch req.GetService() {
+	case LivenessService:
+		return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_SERVING}, nil
+	case "", ReadinessService, o.TargetServiceName:
+		if !o.Readiness(ctx).Ready {
 			return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_NOT_SERVING}, nil
 		}
 
 		return &healthv1pb.HealthCheckResponse{Status: healthv1pb.HealthCheckResponse_SERVING}, nil
+	default:
+		return nil, status.Errorf(codes.NotFound, "service '%s' is not registered with the Health server", req.GetService())
 	}
-
-	return nil, status.Errorf(codes.NotFound, "service '%s' is not registered with the Health server", requestedService)
 }
 
 func (o *Checker) Watch(req *healthv1pb.HealthCheckRequest, server healthv1pb.Health_WatchServer) error {
 	return status.Error(codes.Unimplemented, "unimplemented streaming endpoint")
 }
+
+// Readiness probes the datastore and the dependencies concurrently, each within the probe timeout. The server is
+// ready if the datastore and every required dependency are healthy.
+func (o *Checker) Readiness(ctx context.Context) *Report {
+	dependencies := append([]Dependency{{Name: DatastoreDependency, Probe: o.probeTargetService}}, o.Dependencies...)
+
+	report := &Report{
+		Ready:        true,
+		Dependencies: make([]DependencyStatus, len(dependencies)),
+	}
+
+	var wg sync.WaitGroup
+	for i, dependency := range dependencies {
+		wg.Add(1)
+		go func(i int, dependency Dependency) {
+			defer wg.Done()
+
+			report.Dependencies[i] = o.probe(ctx, dependency)
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	for _, dependency := range report.Dependencies {
+		if !dependency.Healthy && !dependency.Optional {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+func (o *Checker) probe(ctx context.Context, dependency Dependency) DependencyStatus {
+	timeout := o.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dependency.Probe(ctx)
+
+	result := DependencyStatus{
+		Name:      dependency.Name,
+		Healthy:   err == nil,
+		Optional:  dependency.Optional,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+func (o *Checker) probeTargetService(ctx context.Context) error {
+	ready, err := o.TargetService.IsReady(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !ready {
+		return errNotReady
+	}
+
+	return nil
+}
+
+var errNotReady = status.Error(codes.Unavailable, "not ready")
+
+// HandleLiveness serves the liveness of the server over HTTP (e.g. at /healthz): 200 as long as the server is up.
+func (o *Checker) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": healthv1pb.HealthCheckResponse_SERVING.String()})
+}
+
+// HandleReadiness serves the Report of the readiness of the server over HTTP (e.g. at /readyz): 200 if the server
+// is ready, and 503 otherwise.
+func (o *Checker) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := o.Readiness(r.Context())
+
+	code := http.StatusOK
+	if !report.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, code, report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
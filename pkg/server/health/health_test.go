@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthv1pb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type targetService struct {
+	ready bool
+	err   error
+}
+
+func (t *targetService) IsReady(ctx context.Context) (bool, error) {
+	return t.ready, t.err
+}
+
+func TestChecker(t *testing.T) {
+	failing := func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}
+
+	t.Run("ready_with_failing_optional_dependencies", func(t *testing.T) {
+		c := &Checker{
+			TargetService:     &targetService{ready: true},
+			TargetServiceName: "openfga.v1.OpenFGAService",
+			Dependencies:      []Dependency{{Name: "cache", Probe: failing, Optional: true}},
+		}
+
+		report := c.Readiness(context.Background())
+		require.True(t, report.Ready)
+		require.Len(t, report.Dependencies, 2)
+		require.Equal(t, DatastoreDependency, report.Dependencies[0].Name)
+		require.True(t, report.Dependencies[0].Healthy)
+		require.Equal(t, "cache", report.Dependencies[1].Name)
+		require.False(t, report.Dependencies[1].Healthy)
+		require.Equal(t, "connection refused", report.Dependencies[1].Error)
+
+		for _, service := range []string{"", ReadinessService, "openfga.v1.OpenFGAService"} {
+			resp, err := c.Check(context.Background(), &healthv1pb.HealthCheckRequest{Service: service})
+			require.NoError(t, err)
+			require.Equal(t, healthv1pb.HealthCheckResponse_SERVING, resp.GetStatus())
+		}
+	})
+
+	t.Run("not_ready_with_failing_required_dependencies", func(t *testing.T) {
+		c := &Checker{
+			TargetService: &targetService{ready: true},
+			Dependencies:  []Dependency{{Name: "queue", Probe: failing}},
+		}
+
+		require.False(t, c.Readiness(context.Background()).Ready)
+	})
+
+	t.Run("not_ready_until_the_datastore_is_ready", func(t *testing.T) {
+		c := &Checker{TargetService: &targetService{err: errors.New("timeout")}}
+
+		resp, err := c.Check(context.Background(), &healthv1pb.HealthCheckRequest{Service: ReadinessService})
+		require.NoError(t, err)
+		require.Equal(t, healthv1pb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+		// the server is alive nonetheless
+		resp, err = c.Check(context.Background(), &healthv1pb.HealthCheckRequest{Service: LivenessService})
+		require.NoError(t, err)
+		require.Equal(t, healthv1pb.HealthCheckResponse_SERVING, resp.GetStatus())
+	})
+
+	t.Run("probes_time_out", func(t *testing.T) {
+		c := &Checker{
+			TargetService: &targetService{ready: true},
+			Dependencies: []Dependency{{Name: "token_issuer", Probe: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}},
+			ProbeTimeout: 10 * time.Millisecond,
+		}
+
+		report := c.Readiness(context.Background())
+		require.False(t, report.Ready)
+		require.Equal(t, context.DeadlineExceeded.Error(), report.Dependencies[1].Error)
+	})
+
+	t.Run("unknown_services_are_not_found", func(t *testing.T) {
+		c := &Checker{TargetService: &targetService{ready: true}}
+
+		_, err := c.Check(context.Background(), &healthv1pb.HealthCheckRequest{Service: "other"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestHTTPHandlers(t *testing.T) {
+	c := &Checker{TargetService: &targetService{ready: false}}
+
+	w := httptest.NewRecorder()
+	c.HandleLiveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c.HandleReadiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.False(t, report.Ready)
+	require.Equal(t, "not ready", status.Convert(errNotReady).Message())
+	require.Contains(t, report.Dependencies[0].Error, "not ready")
+}
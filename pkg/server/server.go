@@ -5,9 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
@@ -15,19 +20,29 @@ import (
 	"github.com/openfga/openfga/internal/gateway"
 	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/internal/validation"
+	"github.com/openfga/openfga/pkg/cache"
 	"github.com/openfga/openfga/pkg/encoder"
 	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/middleware/consistency"
+	"github.com/openfga/openfga/pkg/middleware/featureflags"
 	httpmiddleware "github.com/openfga/openfga/pkg/middleware/http"
+	"github.com/openfga/openfga/pkg/recorder"
 	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/storagewrappers"
+	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
+	"github.com/openfga/openfga/pkg/writehook"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 type ExperimentalFeatureFlag string
@@ -36,18 +51,107 @@ const (
 	AuthorizationModelIDHeader = "openfga-authorization-model-id"
 	authorizationModelIDKey    = "authorization_model_id"
 
+	// The headers reporting the resolution statistics of Check and (non-streaming) ListObjects requests, see
+	// graph.ResolutionStats. The cache hit ratio is only reported if the check cache was looked up.
+	ResolutionDatastoreQueriesHeader = "openfga-resolution-datastore-queries"
+	ResolutionDispatchesHeader       = "openfga-resolution-dispatches"
+	ResolutionMaxDepthHeader         = "openfga-resolution-max-depth"
+	ResolutionCacheHitRatioHeader    = "openfga-resolution-cache-hit-ratio"
+
+	// ResolutionIncompleteHeader is set to "true" if the ListObjects deadline was hit before every object was
+	// resolved, so the objects returned may be incomplete, see WithListObjectsPartialResults. StreamedListObjects
+	// sends it as a trailer.
+	ResolutionIncompleteHeader = "openfga-resolution-incomplete"
+
+	// ExpectedChangelogTokenHeader is the gRPC metadata key of the continuation token of a ReadChanges that the
+	// changelog of the store must still end at for a Write to be applied, see Server.Write. Over HTTP it is sent as
+	// the Grpc-Metadata-Openfga-Expected-Changelog-Token header.
+	ExpectedChangelogTokenHeader = "openfga-expected-changelog-token"
+
+	// ModelIncludesHeader is the gRPC metadata key of the comma-separated names of the modules included by the
+	// model of a WriteAuthorizationModel, see WithModelModules. Over HTTP it is sent as the
+	// Grpc-Metadata-Openfga-Model-Includes header.
+	ModelIncludesHeader = "openfga-model-includes"
+
+	// ConsistencyHeader is the gRPC metadata key of the consistency of a request, see Consistency. Over HTTP it is
+	// sent as the Grpc-Metadata-Openfga-Consistency header.
+	ConsistencyHeader = consistency.Header
+
 	// same values as run.DefaultConfig() (TODO break the import cycle, remove these hardcoded values and import those constants here)
 	defaultChangelogHorizonOffset           = 0
 	defaultResolveNodeLimit                 = 25
 	defaultResolveNodeBreadthLimit          = 100
 	defaultListObjectsDeadline              = 3 * time.Second
 	defaultListObjectsMaxResults            = 1000
+	defaultListObjectsStreamBufferSize      = 100
+	defaultExpandDepth                      = 1
 	defaultMaxConcurrentReadsForCheck       = math.MaxUint32
 	defaultMaxConcurrentReadsForListObjects = math.MaxUint32
+	defaultMaxDispatchCountPerCheck         = 0
+	defaultMaxDatastoreReadsPerCheck        = 0
+	defaultCheckQueryCacheLimit             = 10000
+	defaultCheckQueryCacheTTL               = 10 * time.Second
+	defaultListObjectsCacheLimit            = 1000
+	defaultListObjectsCacheTTL              = 10 * time.Second
+	defaultListObjectsChangelogInterval     = time.Second
+	defaultListObjectsPlannerStatisticsTTL  = time.Minute
+	defaultListObjectsPlannerSampleSize     = 100000
+	defaultStoreStatsCacheTTL               = 30 * time.Second
+	defaultFlaggedResolveNodeLimit          = 50
 )
 
 var tracer = otel.Tracer("openfga/pkg/server")
 
+// Consistency is the consistency of the datastore reads issued to resolve a request.
+type Consistency string
+
+const (
+	// ConsistencyDefault lets every read observe the latest writes, so the reads of a request may observe the writes
+	// made while it is resolved. The tuple reads may be stale if the datastore serves them from a read replica or a
+	// follower read, unless the request has a consistency token, see storage.ContextWithConsistencyToken.
+	ConsistencyDefault Consistency = ""
+
+	// ConsistencyStrong is like ConsistencyDefault, but the tuple reads are never stale: they are served by the
+	// primary of the datastore, see storage.ContextWithStrongConsistency. The consistency.NewUnaryInterceptor and
+	// consistency.NewStreamingInterceptor interceptors apply it to the requests with the ConsistencyHeader metadata.
+	ConsistencyStrong Consistency = consistency.Strong
+
+	// ConsistencySnapshot makes every read of a request observe the same snapshot of the store, see
+	// storage.SnapshotBackend, so that its result is internally consistent. Checks with this consistency bypass the
+	// check cache.
+	ConsistencySnapshot Consistency = "snapshot"
+)
+
+type consistencyCtxKey struct{}
+
+// ContextWithConsistency returns a context carrying the consistency of a request, which takes precedence over the
+// ConsistencyHeader metadata of the request.
+func ContextWithConsistency(ctx context.Context, consistency Consistency) context.Context {
+	if consistency == ConsistencyStrong {
+		ctx = storage.ContextWithStrongConsistency(ctx)
+	}
+
+	return context.WithValue(ctx, consistencyCtxKey{}, consistency)
+}
+
+// consistencyFromContext returns the consistency of the request, set with ContextWithConsistency or sent in the
+// ConsistencyHeader metadata.
+func consistencyFromContext(ctx context.Context) (Consistency, error) {
+	consistency, ok := ctx.Value(consistencyCtxKey{}).(Consistency)
+	if !ok {
+		if values := metadata.ValueFromIncomingContext(ctx, ConsistencyHeader); len(values) > 0 {
+			consistency = Consistency(values[0])
+		}
+	}
+
+	switch consistency {
+	case ConsistencyDefault, ConsistencyStrong, ConsistencySnapshot:
+		return consistency, nil
+	default:
+		return "", serverErrors.ValidationError(fmt.Errorf("the consistency must be empty, '%s' or '%s', got '%s'", ConsistencyStrong, ConsistencySnapshot, consistency))
+	}
+}
+
 // A Server implements the OpenFGA service backend as both
 // a GRPC and HTTP server.
 type Server struct {
@@ -56,17 +160,66 @@ type Server struct {
 	logger                           logger.Logger
 	datastore                        storage.OpenFGADatastore
 	encoder                          encoder.Encoder
+	storeEncoder                     encoder.StoreEncoder
+	tokenCodec                       encoder.TokenCodec
 	transport                        gateway.Transport
 	resolveNodeLimit                 uint32
+	flaggedResolveNodeLimit          uint32
 	resolveNodeBreadthLimit          uint32
 	changelogHorizonOffset           int
-	listObjectsDeadline              time.Duration
+	listObjectsDeadline              atomic.Int64 // a time.Duration, see SetListObjectsDeadline
 	listObjectsMaxResults            uint32
+	requestTimeout                   time.Duration
+	methodRequestTimeouts            map[string]time.Duration
+	listObjectsSortOrder             commands.ListObjectsSortOrder
+	listObjectsPartialResults        bool
+	listObjectsStreamBufferSize      uint32
+	listObjectsStreamSendTimeout     time.Duration
+	listObjectsMaxConcurrentChecks   uint32
+	listObjectsPlannerEnabled        bool
+	listObjectsPlannerStatisticsTTL  time.Duration
+	listObjectsPlannerSampleSize     uint32
+	listObjectsPlanner               *planner.Planner
+	flaggedListObjectsPlanner        *planner.Planner
+	shadowEvaluator                  *commands.ShadowEvaluator
+	recorder                         *recorder.Recorder
+	shadowCheck                      bool
+	shadowCheckOptions               []graph.LocalCheckerOption
+	shadowListObjects                bool
+	shadowListObjectsOptions         []commands.ListObjectsQueryOption
+	expandDepth                      uint32
 	maxConcurrentReadsForListObjects uint32
 	maxConcurrentReadsForCheck       uint32
+	maxDispatchCountPerCheck         uint32
+	maxDatastoreReadsPerCheck        uint32
+	experimentalsMu                  sync.RWMutex
 	experimentals                    []ExperimentalFeatureFlag
-
-	typesystemResolver typesystem.TypesystemResolverFunc
+	readOnly                         bool
+	storeRetentionPeriod             time.Duration
+	changelogRetentionPeriod         time.Duration
+	checkQueryCacheEnabled           bool
+	checkDeduplicationEnabled        bool
+	checkDeduplicator                *graph.CheckDeduplicator
+	checkQueryCacheLimit             uint32
+	checkQueryCacheTTL               time.Duration
+	cacheBackend                     cache.Cache
+	checkCache                       *graph.CheckCache
+	listObjectsCacheEnabled          bool
+	listObjectsCacheLimit            uint32
+	listObjectsCacheTTL              time.Duration
+	listObjectsChangelogInterval     time.Duration
+	listObjectsCache                 *graph.ListObjectsCache
+	quotas                           quota.Limits
+	storeQuotas                      map[string]quota.Limits
+	quotaEnforcer                    *quota.Enforcer
+	writeHooks                       []writehook.Hook
+	storeStatsCacheTTL               time.Duration
+	storeStats                       *commands.GetStoreStatsCommand
+	modelModules                     map[string]*typesystem.Module
+
+	latestModelCacheTTL time.Duration
+	typesystems         *typesystem.TypesystemResolver
+	typesystemResolver  typesystem.TypesystemResolverFunc
 }
 
 type OpenFGAServiceV1Option func(s *Server)
@@ -89,6 +242,24 @@ func WithTokenEncoder(encoder encoder.Encoder) OpenFGAServiceV1Option {
 	}
 }
 
+// WithStoreTokenEncoder sets the encoder used for the continuation tokens of store scoped APIs
+// (e.g. Read, ReadChanges and ReadAuthorizationModels). If provided, it takes precedence over the
+// encoder set with WithTokenEncoder for those APIs, so that each store's tokens are encoded with
+// its own key.
+func WithStoreTokenEncoder(storeEncoder encoder.StoreEncoder) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storeEncoder = storeEncoder
+	}
+}
+
+// WithTokenCodec sets the format of the continuation tokens of the tuple and changelog reads. The tokens of every
+// format are accepted whatever the codec, so the format can be changed without invalidating the outstanding tokens.
+func WithTokenCodec(codec encoder.TokenCodec) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.tokenCodec = codec
+	}
+}
+
 func WithTransport(t gateway.Transport) OpenFGAServiceV1Option {
 	return func(s *Server) {
 		s.transport = t
@@ -104,6 +275,50 @@ func WithResolveNodeLimit(limit uint32) OpenFGAServiceV1Option {
 	}
 }
 
+// WithFlaggedResolveNodeLimit sets the resolve node limit of the requests with the
+// featureflags.HigherResolveNodeLimit feature flag, see WithResolveNodeLimit.
+func WithFlaggedResolveNodeLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.flaggedResolveNodeLimit = limit
+	}
+}
+
+// WithShadowEvaluator evaluates a sample of the Check and ListObjects requests again with the experimental resolvers
+// of WithShadowCheck and WithShadowListObjects, and reports how their results and latencies diverge from those of the
+// current resolvers. See commands.ShadowEvaluator.
+func WithShadowEvaluator(e *commands.ShadowEvaluator) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.shadowEvaluator = e
+	}
+}
+
+// WithShadowCheck sets the experimental resolver of the Checks evaluated by the shadow evaluator: the resolver of
+// the Checks built with the options, after those of the server. The experimental resolver bypasses the check cache.
+// Explained Checks and Checks as of a point in time are not evaluated again.
+func WithShadowCheck(opts ...graph.LocalCheckerOption) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.shadowCheck = true
+		s.shadowCheckOptions = opts
+	}
+}
+
+// WithShadowListObjects sets the experimental resolver of the ListObjects evaluated by the shadow evaluator: the
+// query built with the options, after those of the server, e.g. commands.WithListObjectsStrategy.
+func WithShadowListObjects(opts ...commands.ListObjectsQueryOption) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.shadowListObjects = true
+		s.shadowListObjectsOptions = opts
+	}
+}
+
+// WithRecorder records a sample of the Checks and ListObjects served, along with their results, see recorder.Recorder.
+// Explained Checks and Checks as of a point in time are not recorded.
+func WithRecorder(r *recorder.Recorder) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.recorder = r
+	}
+}
+
 // WithResolveNodeBreadthLimit sets a limit on the number of goroutines that can be created
 // when evaluating a subtree of a Check or ListObjects call.
 // Thinking of a Check request as a tree of evaluations, this option controls,
@@ -125,7 +340,28 @@ func WithChangelogHorizonOffset(offset int) OpenFGAServiceV1Option {
 
 func WithListObjectsDeadline(deadline time.Duration) OpenFGAServiceV1Option {
 	return func(s *Server) {
-		s.listObjectsDeadline = deadline
+		s.listObjectsDeadline.Store(int64(deadline))
+	}
+}
+
+// WithRequestTimeout bounds the duration of the requests of every API method, unless overridden for the method by
+// WithMethodRequestTimeout. A request is aborted, and the datastore calls it is waiting for are cancelled, once
+// the timeout elapses, unless the client set an earlier deadline. A timeout of 0 means unlimited, which is the
+// default.
+func WithRequestTimeout(timeout time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.requestTimeout = timeout
+	}
+}
+
+// WithMethodRequestTimeout overrides the timeout set by WithRequestTimeout for the requests of an API method, named
+// after its RPC (e.g. "Check"). The variants of a method (e.g. ExplainCheck and CheckAsOf) share its timeout.
+func WithMethodRequestTimeout(method string, timeout time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		if s.methodRequestTimeouts == nil {
+			s.methodRequestTimeouts = map[string]time.Duration{}
+		}
+		s.methodRequestTimeouts[method] = timeout
 	}
 }
 
@@ -135,6 +371,102 @@ func WithListObjectsMaxResults(limit uint32) OpenFGAServiceV1Option {
 	}
 }
 
+// WithListObjectsSortOrder sets the order of the objects returned by ListObjects, see
+// commands.ListObjectsSortOrder. Defaults to commands.ListObjectsUnsorted.
+func WithListObjectsSortOrder(order commands.ListObjectsSortOrder) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsSortOrder = order
+	}
+}
+
+// WithListObjectsPartialResults sets whether ListObjects returns the objects resolved until its deadline when the
+// deadline is hit, reporting them as incomplete with the ResolutionIncompleteHeader. If disabled, hitting the
+// deadline fails the request with a deadline exceeded error instead. Defaults to true.
+func WithListObjectsPartialResults(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsPartialResults = enabled
+	}
+}
+
+// WithListObjectsStreamBufferSize sets how many objects StreamedListObjects buffers while the client receives the
+// previous ones, see commands.WithListObjectsStreamBufferSize. Defaults to 100.
+func WithListObjectsStreamBufferSize(size uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsStreamBufferSize = size
+	}
+}
+
+// WithListObjectsStreamSendTimeout sets how long StreamedListObjects waits for the client to receive an object
+// before ending the stream, see commands.WithListObjectsStreamSendTimeout. Defaults to 0, which waits for as long
+// as the deadline of the request allows.
+func WithListObjectsStreamSendTimeout(timeout time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsStreamSendTimeout = timeout
+	}
+}
+
+// WithListObjectsMaxConcurrentChecks sets how many Checks a ListObjects request issues concurrently to evaluate
+// its candidate objects, see commands.WithListObjectsMaxConcurrentChecks. Defaults to 0, which uses the resolve
+// node breadth limit.
+func WithListObjectsMaxConcurrentChecks(max uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsMaxConcurrentChecks = max
+	}
+}
+
+// WithExpandDepth sets how many levels of usersets the Expand API expands, see commands.WithExpandDepth.
+// Defaults to 1.
+func WithExpandDepth(depth uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.expandDepth = depth
+	}
+}
+
+// WithListObjectsPlannerEnabled enables the query planner that chooses, for each ListObjects request, between
+// reverse expanding the relationships of the user and concurrently checking every object of the type, based on
+// cardinality statistics of the tuples of the store. See planner.Planner.
+func WithListObjectsPlannerEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsPlannerEnabled = enabled
+	}
+}
+
+// WithListObjectsPlannerStatisticsTTL sets how long the statistics of a store collected by the ListObjects query
+// planner are used before they are collected again.
+func WithListObjectsPlannerStatisticsTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsPlannerStatisticsTTL = ttl
+	}
+}
+
+// WithListObjectsPlannerSampleSize sets the maximum number of tuples of a store read by the ListObjects query
+// planner to collect its statistics. The planner never chooses to check every object of a type in stores with
+// more tuples than this.
+func WithListObjectsPlannerSampleSize(size uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsPlannerSampleSize = size
+	}
+}
+
+// WithStoreStatsCacheTTL sets how long the statistics of a store returned by GetStoreStats are cached before they
+// are read from the datastore again. They are not cached if it is 0.
+func WithStoreStatsCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storeStatsCacheTTL = ttl
+	}
+}
+
+// WithModelModules sets the modules that the authorization models written can include by name with the
+// ModelIncludesHeader. The included modules are flattened into the models, and the module of each included type is
+// recorded in its typesystem.ModuleAnnotation. The modules must be valid, see typesystem.ValidateModules.
+func WithModelModules(modules ...*typesystem.Module) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		for _, module := range modules {
+			s.modelModules[module.Name] = module
+		}
+	}
+}
+
 // WithMaxConcurrentReadsForListObjects sets a limit on the number of datastore reads that can be in flight for a given ListObjects call.
 // This number should be set depending on the RPS expected for Check and ListObjects APIs, the number of OpenFGA replicas running,
 // and the number of connections the datastore allows.
@@ -161,6 +493,163 @@ func WithMaxConcurrentReadsForCheck(max uint32) OpenFGAServiceV1Option {
 	}
 }
 
+// WithMaxDispatchCountPerCheck sets a limit on the number of subproblems a single Check can dispatch, so that a
+// pathological authorization model cannot starve the server. A Check that exceeds it fails with a resource
+// exhausted error. A limit of 0 means unlimited.
+func WithMaxDispatchCountPerCheck(max uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.maxDispatchCountPerCheck = max
+	}
+}
+
+// WithMaxDatastoreReadsPerCheck sets a limit on the number of datastore reads a single Check can issue. Unlike
+// WithMaxConcurrentReadsForCheck, which only bounds the reads in flight, this bounds the total. A Check that
+// exceeds it fails with a resource exhausted error. A limit of 0 means unlimited.
+func WithMaxDatastoreReadsPerCheck(max uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.maxDatastoreReadsPerCheck = max
+	}
+}
+
+// WithQuotas sets the quotas applied to every store: the maximum number of tuples of a store, the maximum size of
+// its authorization models and the maximum rate of its writes. Requests that exceed them are rejected by Write,
+// ImportTuples and WriteAuthorizationModel. Zero limits are unlimited.
+func WithQuotas(limits quota.Limits) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.quotas = limits
+	}
+}
+
+// WithStoreQuotas overrides the quotas of the store with the provided id, see WithQuotas.
+func WithStoreQuotas(storeID string, limits quota.Limits) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storeQuotas[storeID] = limits
+	}
+}
+
+// WithReadOnly puts the server in read-only mode. In read-only mode every mutating API
+// (e.g. Write, WriteAuthorizationModel, WriteAssertions, CreateStore and DeleteStore) is rejected
+// with serverErrors.ReadOnlyMode, while queries such as Check, Read, Expand and ListObjects are still served.
+// This is intended for replicas pointed at a database read replica or for freezing writes during a migration.
+func WithReadOnly(readOnly bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.readOnly = readOnly
+	}
+}
+
+// WithWriteHooks sets the hooks that admit the writes of Write, ImportTuples and WriteTuples before they are committed,
+// in turn, see writehook.Chain. A hook can reject a write, which fails with a validation error, or mutate it.
+func WithWriteHooks(hooks ...writehook.Hook) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.writeHooks = append(s.writeHooks, hooks...)
+	}
+}
+
+// WithStoreRetentionPeriod sets how long after their deletion the stores can be restored with UndeleteStore. It
+// should match the retention period of the storage.StorePurger purging the deleted stores. If 0, a store can be
+// restored until it is purged.
+func WithStoreRetentionPeriod(retentionPeriod time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.storeRetentionPeriod = retentionPeriod
+	}
+}
+
+// WithChangelogRetentionPeriod sets how long the changes are kept in the changelog, see storage.ChangelogTrimmer.
+// The Checks and Reads as of a point in time older than the retention period are rejected. If 0, the changelog is
+// assumed to be complete.
+func WithChangelogRetentionPeriod(retentionPeriod time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.changelogRetentionPeriod = retentionPeriod
+	}
+}
+
+// WithCheckQueryCacheEnabled enables caching of the outcome of Check subproblems across requests.
+// Cached results of a store are invalidated by every Write to the store made through this server,
+// and otherwise expire after the TTL set with WithCheckQueryCacheTTL.
+func WithCheckQueryCacheEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkQueryCacheEnabled = enabled
+	}
+}
+
+// WithCheckDeduplicationEnabled enables collapsing the concurrent identical Checks into a single resolution whose
+// outcome they share, see graph.CheckDeduplicator. The Checks with a consistency token or a strong or snapshot
+// consistency, which must observe the latest writes, are never deduplicated.
+func WithCheckDeduplicationEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkDeduplicationEnabled = enabled
+	}
+}
+
+// WithLatestModelCacheTTL caches the ID of the latest authorization model of every store for the TTL, so that the
+// requests without an authorization model id do not look it up in the datastore, see typesystem.WithLatestModelTTL.
+// The cached ID of a store is refreshed when a model is written to the store through this server, and otherwise in
+// the background once the TTL expires. Defaults to 0, which does not cache the latest models.
+func WithLatestModelCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.latestModelCacheTTL = ttl
+	}
+}
+
+// WithCheckQueryCacheLimit sets the maximum number of Check subproblem results held by the check cache.
+func WithCheckQueryCacheLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkQueryCacheLimit = limit
+	}
+}
+
+// WithCheckQueryCacheTTL sets how long a cached Check subproblem result is valid for. Since Writes
+// made through other replicas do not invalidate the cache of this server, this bounds the staleness
+// of Check results in deployments with more than one replica.
+func WithCheckQueryCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.checkQueryCacheTTL = ttl
+	}
+}
+
+// WithListObjectsCacheEnabled enables caching of the results of ListObjects across requests, see
+// graph.ListObjectsCache. A cached result is invalidated by the Writes made through this server to the tuples of the
+// object types it depends on, by the changes to them read from the changelog of the store otherwise, e.g. the Writes
+// made through other replicas, and expires after the TTL set with WithListObjectsCacheTTL.
+func WithListObjectsCacheEnabled(enabled bool) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsCacheEnabled = enabled
+	}
+}
+
+// WithListObjectsCacheLimit sets the maximum number of ListObjects results held by the ListObjects cache.
+func WithListObjectsCacheLimit(limit uint32) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsCacheLimit = limit
+	}
+}
+
+// WithListObjectsCacheTTL sets how long a cached ListObjects result is valid for, which bounds the staleness of the
+// results whose invalidations are not observed, e.g. those of the changes within the changelog horizon offset.
+func WithListObjectsCacheTTL(ttl time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsCacheTTL = ttl
+	}
+}
+
+// WithListObjectsCacheChangelogInterval sets how often the changelog of a store is read by the ListObjects cache for
+// the changes made otherwise than by the Writes of this server. If 0, it is read by every lookup of a cached result.
+func WithListObjectsCacheChangelogInterval(interval time.Duration) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.listObjectsChangelogInterval = interval
+	}
+}
+
+// WithCacheBackend sets a cache shared with the other servers of the deployment (e.g. a cache.RedisCache).
+// If set, the check cache stores its entries and invalidations in it instead of in memory, so that
+// resolved Check subproblems are shared and Writes made through any server invalidate them everywhere.
+// The backend is not closed by the server.
+func WithCacheBackend(backend cache.Cache) OpenFGAServiceV1Option {
+	return func(s *Server) {
+		s.cacheBackend = backend
+	}
+}
+
 func WithExperimentals(experimentals ...ExperimentalFeatureFlag) OpenFGAServiceV1Option {
 	return func(s *Server) {
 		s.experimentals = experimentals
@@ -181,16 +670,34 @@ func NewServerWithOpts(opts ...OpenFGAServiceV1Option) (*Server, error) {
 	s := &Server{
 		logger:                           logger.NewNoopLogger(),
 		encoder:                          encoder.NewBase64Encoder(),
+		tokenCodec:                       encoder.NewProtoTokenCodec(),
 		transport:                        gateway.NewNoopTransport(),
 		changelogHorizonOffset:           defaultChangelogHorizonOffset,
 		resolveNodeLimit:                 defaultResolveNodeLimit,
+		flaggedResolveNodeLimit:          defaultFlaggedResolveNodeLimit,
 		resolveNodeBreadthLimit:          defaultResolveNodeBreadthLimit,
-		listObjectsDeadline:              defaultListObjectsDeadline,
 		listObjectsMaxResults:            defaultListObjectsMaxResults,
+		listObjectsPartialResults:        true,
+		listObjectsStreamBufferSize:      defaultListObjectsStreamBufferSize,
+		expandDepth:                      defaultExpandDepth,
 		maxConcurrentReadsForCheck:       defaultMaxConcurrentReadsForCheck,
 		maxConcurrentReadsForListObjects: defaultMaxConcurrentReadsForListObjects,
+		maxDispatchCountPerCheck:         defaultMaxDispatchCountPerCheck,
+		maxDatastoreReadsPerCheck:        defaultMaxDatastoreReadsPerCheck,
+		checkQueryCacheLimit:             defaultCheckQueryCacheLimit,
+		checkQueryCacheTTL:               defaultCheckQueryCacheTTL,
+		listObjectsCacheLimit:            defaultListObjectsCacheLimit,
+		listObjectsCacheTTL:              defaultListObjectsCacheTTL,
+		listObjectsChangelogInterval:     defaultListObjectsChangelogInterval,
+		listObjectsPlannerStatisticsTTL:  defaultListObjectsPlannerStatisticsTTL,
+		listObjectsPlannerSampleSize:     defaultListObjectsPlannerSampleSize,
 		experimentals:                    make([]ExperimentalFeatureFlag, 0, 10),
+		storeQuotas:                      map[string]quota.Limits{},
+		storeRetentionPeriod:             storage.DefaultStoreRetentionPeriod,
+		storeStatsCacheTTL:               defaultStoreStatsCacheTTL,
+		modelModules:                     map[string]*typesystem.Module{},
 	}
+	s.listObjectsDeadline.Store(int64(defaultListObjectsDeadline))
 
 	for _, opt := range opts {
 		opt(s)
@@ -200,239 +707,1808 @@ func NewServerWithOpts(opts ...OpenFGAServiceV1Option) (*Server, error) {
 		return nil, fmt.Errorf("a datastore option must be provided")
 	}
 
-	s.typesystemResolver = typesystem.MemoizedTypesystemResolverFunc(s.datastore)
+	s.typesystems = typesystem.NewTypesystemResolver(s.datastore, typesystem.WithLatestModelTTL(s.latestModelCacheTTL))
+	s.typesystemResolver = s.typesystems.Resolve
 
-	return s, nil
-}
+	if s.checkQueryCacheEnabled {
+		checkCacheOpts := []graph.CheckCacheOption{
+			graph.WithCheckCacheMaxSize(int64(s.checkQueryCacheLimit)),
+			graph.WithCheckCacheTTL(s.checkQueryCacheTTL),
+		}
+		if s.cacheBackend != nil {
+			checkCacheOpts = append(checkCacheOpts, graph.WithCheckCacheBackend(s.cacheBackend))
+		}
 
-func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequest) (*openfgav1.ListObjectsResponse, error) {
+		s.checkCache = graph.NewCheckCache(checkCacheOpts...)
+	}
 
-	targetObjectType := req.GetType()
+	if s.listObjectsCacheEnabled {
+		s.listObjectsCache = graph.NewListObjectsCache(s.datastore,
+			graph.WithListObjectsCacheMaxSize(int64(s.listObjectsCacheLimit)),
+			graph.WithListObjectsCacheTTL(s.listObjectsCacheTTL),
+			graph.WithListObjectsCacheChangelogInterval(s.listObjectsChangelogInterval),
+			graph.WithListObjectsCacheHorizonOffset(time.Duration(s.changelogHorizonOffset)*time.Minute),
+		)
+	}
 
-	ctx, span := tracer.Start(ctx, "ListObjects", trace.WithAttributes(
-		attribute.String("object_type", targetObjectType),
-		attribute.String("relation", req.GetRelation()),
-		attribute.String("user", req.GetUser()),
-	))
-	defer span.End()
+	if s.checkDeduplicationEnabled {
+		s.checkDeduplicator = graph.NewCheckDeduplicator()
+	}
 
-	storeID := req.GetStoreId()
+	if !s.quotas.IsZero() || len(s.storeQuotas) > 0 {
+		quotaOpts := []quota.EnforcerOption{quota.WithLogger(s.logger)}
+		for storeID, limits := range s.storeQuotas {
+			quotaOpts = append(quotaOpts, quota.WithStoreLimits(storeID, limits))
+		}
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
-	if err != nil {
-		return nil, err
+		s.quotaEnforcer = quota.NewEnforcer(s.datastore, s.quotas, quotaOpts...)
 	}
 
-	q := commands.NewListObjectsQuery(s.datastore,
-		commands.WithLogger(s.logger),
-		commands.WithListObjectsDeadline(s.listObjectsDeadline),
-		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
-		commands.WithResolveNodeLimit(s.resolveNodeLimit),
-		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
-		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
-	)
+	s.storeStats = commands.NewGetStoreStatsCommand(s.datastore, s.logger, commands.WithStoreStatsCacheTTL(s.storeStatsCacheTTL))
 
-	return q.Execute(
-		typesystem.ContextWithTypesystem(ctx, typesys),
-		&openfgav1.ListObjectsRequest{
-			StoreId:              storeID,
-			ContextualTuples:     req.GetContextualTuples(),
-			AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
-			Type:                 targetObjectType,
-			Relation:             req.Relation,
-			User:                 req.User,
-		},
+	// the planner of the requests with the featureflags.ListObjectsPlanner feature flag only collects the statistics
+	// of their stores
+	s.flaggedListObjectsPlanner = planner.New(s.datastore,
+		planner.WithStatisticsTTL(s.listObjectsPlannerStatisticsTTL),
+		planner.WithSampleSize(s.listObjectsPlannerSampleSize),
+		planner.WithLogger(s.logger),
 	)
-}
+	if s.listObjectsPlannerEnabled {
+		s.listObjectsPlanner = s.flaggedListObjectsPlanner
+	}
 
-func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest, srv openfgav1.OpenFGAService_StreamedListObjectsServer) error {
-	ctx := srv.Context()
-	ctx, span := tracer.Start(ctx, "StreamedListObjects", trace.WithAttributes(
-		attribute.String("object_type", req.GetType()),
-		attribute.String("relation", req.GetRelation()),
-		attribute.String("user", req.GetUser()),
-	))
-	defer span.End()
+	return s, nil
+}
 
-	storeID := req.GetStoreId()
+// IsExperimentallyEnabled returns whether the experimental feature is enabled.
+func (s *Server) IsExperimentallyEnabled(flag ExperimentalFeatureFlag) bool {
+	s.experimentalsMu.RLock()
+	defer s.experimentalsMu.RUnlock()
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
-	if err != nil {
-		return err
+	for _, experimental := range s.experimentals {
+		if experimental == flag {
+			return true
+		}
 	}
 
-	q := commands.NewListObjectsQuery(s.datastore,
-		commands.WithLogger(s.logger),
-		commands.WithListObjectsDeadline(s.listObjectsDeadline),
-		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
-		commands.WithResolveNodeLimit(s.resolveNodeLimit),
-		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
-		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
-	)
+	return false
+}
 
-	req.AuthorizationModelId = typesys.GetAuthorizationModelID() // the resolved model id
-	return q.ExecuteStreamed(
-		typesystem.ContextWithTypesystem(ctx, typesys),
-		req,
-		srv,
-	)
+// Experimentals returns the experimental features that are enabled.
+func (s *Server) Experimentals() []ExperimentalFeatureFlag {
+	s.experimentalsMu.RLock()
+	defer s.experimentalsMu.RUnlock()
+
+	return append([]ExperimentalFeatureFlag{}, s.experimentals...)
 }
 
-func (s *Server) Read(ctx context.Context, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
-	tk := req.GetTupleKey()
-	ctx, span := tracer.Start(ctx, "Read", trace.WithAttributes(
-		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
-		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
-		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
-	))
-	defer span.End()
+// SetExperimental enables or disables the experimental feature at runtime.
+func (s *Server) SetExperimental(flag ExperimentalFeatureFlag, enabled bool) {
+	s.experimentalsMu.Lock()
+	defer s.experimentalsMu.Unlock()
 
-	q := commands.NewReadQuery(s.datastore, s.logger, s.encoder)
-	return q.Execute(ctx, &openfgav1.ReadRequest{
-		StoreId:           req.GetStoreId(),
-		TupleKey:          tk,
-		PageSize:          req.GetPageSize(),
-		ContinuationToken: req.GetContinuationToken(),
-	})
+	experimentals := make([]ExperimentalFeatureFlag, 0, len(s.experimentals)+1)
+	for _, experimental := range s.experimentals {
+		if experimental != flag {
+			experimentals = append(experimentals, experimental)
+		}
+	}
+	if enabled {
+		experimentals = append(experimentals, flag)
+	}
+
+	s.experimentals = experimentals
 }
 
-func (s *Server) Write(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteResponse, error) {
-	ctx, span := tracer.Start(ctx, "Write")
+// FlushCaches drops the cached Check and ListObjects results, TypeSystems and statistics of the store, or of every store if the store
+// is empty. The Check results of every store cannot be flushed from a shared cache backend, see WithCacheBackend.
+func (s *Server) FlushCaches(ctx context.Context, storeID string) error {
+	ctx, span := tracer.Start(ctx, "FlushCaches", trace.WithAttributes(attribute.String("store_id", storeID)))
 	defer span.End()
 
-	storeID := req.GetStoreId()
+	s.typesystems.Refresh(ctx, storeID)
+	s.storeStats.Invalidate(ctx, storeID)
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.AuthorizationModelId)
-	if err != nil {
-		return nil, err
+	if s.listObjectsCache != nil {
+		if storeID != "" {
+			s.listObjectsCache.InvalidateStore(ctx, storeID)
+		} else {
+			s.listObjectsCache.Flush()
+		}
 	}
 
-	cmd := commands.NewWriteCommand(s.datastore, s.logger)
-	return cmd.Execute(ctx, &openfgav1.WriteRequest{
-		StoreId:              storeID,
-		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
+	if s.checkCache == nil {
+		return nil
+	}
+
+	if storeID != "" {
+		return s.checkCache.InvalidateStore(ctx, storeID)
+	}
+
+	if err := s.checkCache.Flush(); err != nil {
+		return serverErrors.ValidationError(err)
+	}
+
+	return nil
+}
+
+// RefreshTypesystems drops the cached TypeSystems and latest model of the store of the tenant of the context, or of
+// every store if the store is empty, so that the authorization models are read from the datastore again.
+func (s *Server) RefreshTypesystems(ctx context.Context, storeID string) {
+	s.typesystems.Refresh(ctx, storeID)
+}
+
+// StoreStatistics returns the cardinality statistics of the tuples of the store, see planner.Statistics. They are
+// those of the ListObjects query planner if it is enabled, and are collected on every call otherwise.
+func (s *Server) StoreStatistics(ctx context.Context, storeID string) (*planner.Statistics, error) {
+	ctx, span := tracer.Start(ctx, "StoreStatistics", trace.WithAttributes(attribute.String("store_id", storeID)))
+	defer span.End()
+
+	if _, err := s.GetStore(ctx, &openfgav1.GetStoreRequest{StoreId: storeID}); err != nil {
+		return nil, err
+	}
+
+	p := s.listObjectsPlanner
+	if p == nil {
+		p = planner.New(s.datastore, planner.WithSampleSize(s.listObjectsPlannerSampleSize), planner.WithLogger(s.logger))
+	}
+
+	return p.Statistics(ctx, storeID)
+}
+
+// GetStoreStats returns the number of tuples per object type and relation, of authorization model versions and of
+// changes of the store, aggregated by the datastore. See commands.GetStoreStatsCommand.
+func (s *Server) GetStoreStats(ctx context.Context, req *commands.GetStoreStatsRequest) (*commands.GetStoreStatsResponse, error) {
+	ctx, span := tracer.Start(ctx, "GetStoreStats", trace.WithAttributes(attribute.String("store_id", req.StoreID)))
+	defer span.End()
+
+	return s.storeStats.Execute(ctx, req)
+}
+
+// SetListObjectsDeadline changes the deadline of the ListObjects and ListUsers requests at runtime, e.g. when the
+// config of the server is reloaded. The requests in progress keep their deadline.
+func (s *Server) SetListObjectsDeadline(deadline time.Duration) {
+	s.listObjectsDeadline.Store(int64(deadline))
+}
+
+// SetCheckQueryCacheLimit changes the maximum number of Check subproblem results held by the check cache at runtime,
+// e.g. when the config of the server is reloaded. It has no effect if the check cache is disabled or shared.
+func (s *Server) SetCheckQueryCacheLimit(limit uint32) {
+	if s.checkCache != nil {
+		s.checkCache.SetMaxSize(int64(limit))
+	}
+}
+
+// Close releases the resources held by the server. It does not close the datastore.
+func (s *Server) Close() {
+	if s.checkCache != nil {
+		s.checkCache.Stop()
+	}
+
+	if s.listObjectsCache != nil {
+		s.listObjectsCache.Stop()
+	}
+}
+
+// checkResolverOptions returns the options of the check resolvers used to evaluate the Checks of the request.
+func (s *Server) checkResolverOptions(ctx context.Context) []graph.LocalCheckerOption {
+	opts := []graph.LocalCheckerOption{
+		graph.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		graph.WithMaxConcurrentReads(s.maxConcurrentReadsForCheck),
+		graph.WithMaxDispatchCount(s.maxDispatchCountPerCheck),
+		graph.WithMaxDatastoreReadsPerCheck(s.maxDatastoreReadsPerCheck),
+	}
+
+	if checkCache := s.checkCacheForRequest(ctx); checkCache != nil {
+		opts = append(opts, graph.WithCheckCache(checkCache))
+	}
+
+	return opts
+}
+
+// checkCacheForRequest returns the check cache the Checks of the request are looked up in, or nil if they must not
+// be. The results cached before the write of the consistency token of the request may still be cached, in which
+// case the cache is bypassed, see storage.ContextWithConsistencyToken. The requests with the
+// featureflags.BypassCheckCache feature flag bypass it too.
+func (s *Server) checkCacheForRequest(ctx context.Context) *graph.CheckCache {
+	if s.checkCache == nil || featureflags.Enabled(ctx, featureflags.BypassCheckCache) {
+		return nil
+	}
+
+	consistencyTime := storage.ConsistencyTimeFromContext(ctx)
+	if !consistencyTime.IsZero() && time.Since(consistencyTime) <= s.checkQueryCacheTTL {
+		return nil
+	}
+
+	return s.checkCache
+}
+
+// listObjectsCacheForRequest returns the ListObjects cache the ListObjects of the request is looked up in, or nil if it
+// must not be. The ListObjects which must observe the latest writes, i.e. those with a consistency token or a strong or
+// snapshot consistency, bypass the cache, as do the requests with the featureflags.BypassListObjectsCache feature flag.
+func (s *Server) listObjectsCacheForRequest(ctx context.Context, snapshot storage.SnapshotReader) *graph.ListObjectsCache {
+	if s.listObjectsCache == nil || snapshot != nil || featureflags.Enabled(ctx, featureflags.BypassListObjectsCache) {
+		return nil
+	}
+
+	consistency, err := consistencyFromContext(ctx)
+	if err != nil || consistency != ConsistencyDefault || !storage.ConsistencyTimeFromContext(ctx).IsZero() {
+		return nil
+	}
+
+	return s.listObjectsCache
+}
+
+// resolveNodeLimitForRequest returns the resolve node limit of the request, which is higher with the
+// featureflags.HigherResolveNodeLimit feature flag, see WithFlaggedResolveNodeLimit.
+func (s *Server) resolveNodeLimitForRequest(ctx context.Context) uint32 {
+	if featureflags.Enabled(ctx, featureflags.HigherResolveNodeLimit) && s.flaggedResolveNodeLimit > s.resolveNodeLimit {
+		return s.flaggedResolveNodeLimit
+	}
+
+	return s.resolveNodeLimit
+}
+
+// listObjectsPlannerForRequest returns the query planner of the ListObjects of the request, if any. The requests with
+// the featureflags.ListObjectsPlanner feature flag are planned even if the planner is not enabled.
+func (s *Server) listObjectsPlannerForRequest(ctx context.Context) *planner.Planner {
+	if featureflags.Enabled(ctx, featureflags.ListObjectsPlanner) {
+		return s.flaggedListObjectsPlanner
+	}
+
+	return s.listObjectsPlanner
+}
+
+func (s *Server) ListObjects(ctx context.Context, req *openfgav1.ListObjectsRequest) (*openfgav1.ListObjectsResponse, error) {
+
+	targetObjectType := req.GetType()
+
+	ctx, span := tracer.Start(ctx, "ListObjects", trace.WithAttributes(
+		attribute.String("object_type", targetObjectType),
+		attribute.String("relation", req.GetRelation()),
+		attribute.String("user", req.GetUser()),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ListObjects")
+	defer cancel()
+
+	storeID := req.GetStoreId()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if err != nil {
+		return nil, err
+	}
+
+	var ds storagewrappers.ConditionalTupleReader = s.datastore
+	snapshot, err := s.snapshot(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		defer snapshot.Close()
+		ds = snapshot
+	}
+
+	opts := []commands.ListObjectsQueryOption{
+		commands.WithLogger(s.logger),
+		commands.WithListObjectsDeadline(time.Duration(s.listObjectsDeadline.Load())),
+		commands.WithListObjectsPartialResults(s.listObjectsPartialResults),
+		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
+		commands.WithResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+		commands.WithListObjectsMaxConcurrentChecks(s.listObjectsMaxConcurrentChecks),
+		commands.WithListObjectsSortOrder(s.listObjectsSortOrder),
+		commands.WithListObjectsPlanner(s.listObjectsPlannerForRequest(ctx)),
+	}
+	q := commands.NewListObjectsQuery(storagewrappers.NewConditionEvaluatingTupleReader(ds), opts...)
+
+	resolveReq := &openfgav1.ListObjectsRequest{
+		StoreId:              storeID,
+		ContextualTuples:     req.GetContextualTuples(),
+		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
+		Type:                 targetObjectType,
+		Relation:             req.Relation,
+		User:                 req.User,
+	}
+
+	listObjectsCache := s.listObjectsCacheForRequest(ctx, snapshot)
+	if listObjectsCache != nil {
+		if objects, ok := listObjectsCache.Get(ctx, resolveReq); ok {
+			return &openfgav1.ListObjectsResponse{Objects: objects}, nil
+		}
+
+		ctx = storage.ContextWithTupleExpirations(ctx, &storage.TupleExpirations{})
+	}
+
+	// the changes read from the changelog by the lookup of the cache were made before the resolution started
+	start := time.Now()
+
+	shadowed := s.shadowEvaluator != nil && s.shadowListObjects && s.shadowEvaluator.Sample()
+
+	stats := &graph.ResolutionStats{}
+
+	resp, err := q.Execute(
+		graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesys), stats),
+		resolveReq,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	setResolutionStatsHeaders(ctx, stats)
+
+	// the results cut short by the deadline may be any subset of the objects
+	if listObjectsCache != nil && !stats.Incomplete() {
+		listObjectsCache.Set(ctx, typesys, resolveReq, resp.GetObjects(), start)
+	}
+
+	if shadowed {
+		s.shadowEvaluateListObjects(ctx, typesys, opts, resolveReq, resp.GetObjects(), time.Since(start))
+	}
+
+	if s.recorder != nil && s.recorder.Sample() {
+		s.record(ctx, &recorder.Record{
+			Method:               recorder.MethodListObjects,
+			StoreID:              storeID,
+			AuthorizationModelID: typesys.GetAuthorizationModelID(),
+			ObjectType:           targetObjectType,
+			Relation:             req.GetRelation(),
+			User:                 req.GetUser(),
+			ContextualTuples:     req.GetContextualTuples().GetTupleKeys(),
+			Objects:              resp.GetObjects(),
+			Truncated:            stats.Incomplete() || (s.listObjectsMaxResults > 0 && len(resp.GetObjects()) >= int(s.listObjectsMaxResults)),
+		})
+	}
+
+	return resp, nil
+}
+
+// record writes the record with the recorder of WithRecorder. Failures to write it are logged and otherwise ignored.
+func (s *Server) record(ctx context.Context, record *recorder.Record) {
+	if err := s.recorder.Record(record); err != nil {
+		s.logger.WarnWithContext(ctx, "failed to record the request", zap.String("method", record.Method), zap.Error(err))
+	}
+}
+
+// shadowListObjectsSampleSize is the maximum number of the diverging objects logged by a ListObjects mismatch.
+const shadowListObjectsSampleSize = 10
+
+// shadowEvaluateListObjects evaluates the ListObjects again with the experimental resolver of WithShadowListObjects, in
+// the background. It reads the current tuples of the store, even if the ListObjects read a snapshot.
+func (s *Server) shadowEvaluateListObjects(
+	ctx context.Context,
+	typesys *typesystem.TypeSystem,
+	opts []commands.ListObjectsQueryOption,
+	req *openfgav1.ListObjectsRequest,
+	objects []string,
+	duration time.Duration,
+) {
+	opts = append(opts[:len(opts):len(opts)], s.shadowListObjectsOptions...)
+
+	s.shadowEvaluator.Evaluate(ctx, "ListObjects", req.GetStoreId(), duration, func(ctx context.Context) (commands.ShadowOutcome, []zap.Field, error) {
+		q := commands.NewListObjectsQuery(storagewrappers.NewConditionEvaluatingTupleReader(s.datastore), opts...)
+
+		resp, err := q.Execute(typesystem.ContextWithTypesystem(ctx, typesys), req)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// a truncated result may hold any subset of the objects
+		if s.listObjectsMaxResults > 0 && (len(objects) >= int(s.listObjectsMaxResults) || len(resp.GetObjects()) >= int(s.listObjectsMaxResults)) {
+			return commands.ShadowInconclusive, nil, nil
+		}
+
+		missing, extra := diffObjects(objects, resp.GetObjects())
+		if len(missing) == 0 && len(extra) == 0 {
+			return commands.ShadowMatch, nil, nil
+		}
+
+		return commands.ShadowMismatch, []zap.Field{
+			zap.String("object_type", req.GetType()),
+			zap.String("relation", req.GetRelation()),
+			zap.String("user", req.GetUser()),
+			zap.String("authorization_model_id", req.GetAuthorizationModelId()),
+			zap.Int("missing_count", len(missing)),
+			zap.Strings("missing_sample", sampleObjects(missing)),
+			zap.Int("extra_count", len(extra)),
+			zap.Strings("extra_sample", sampleObjects(extra)),
+		}, nil
+	})
+}
+
+// diffObjects returns the sorted objects of current that are missing from experimental, and those of experimental
+// that are not in current.
+func diffObjects(current, experimental []string) (missing, extra []string) {
+	inCurrent := make(map[string]struct{}, len(current))
+	for _, object := range current {
+		inCurrent[object] = struct{}{}
+	}
+
+	inExperimental := make(map[string]struct{}, len(experimental))
+	for _, object := range experimental {
+		inExperimental[object] = struct{}{}
+		if _, ok := inCurrent[object]; !ok {
+			extra = append(extra, object)
+		}
+	}
+
+	for _, object := range current {
+		if _, ok := inExperimental[object]; !ok {
+			missing = append(missing, object)
+		}
+	}
+
+	sort.Strings(missing)
+	sort.Strings(extra)
+
+	return missing, extra
+}
+
+// sampleObjects returns the first shadowListObjectsSampleSize objects.
+func sampleObjects(objects []string) []string {
+	if len(objects) > shadowListObjectsSampleSize {
+		return objects[:shadowListObjectsSampleSize]
+	}
+
+	return objects
+}
+
+// ListObjectsPage returns a page of the objects returned by ListObjects, sorted by object id. The continuation
+// token of a page fetches the next one.
+func (s *Server) ListObjectsPage(ctx context.Context, req *commands.ListObjectsPageRequest) (*commands.ListObjectsPageResponse, error) {
+	ctx, span := tracer.Start(ctx, "ListObjectsPage", trace.WithAttributes(
+		attribute.String("object_type", req.Request.GetType()),
+		attribute.String("relation", req.Request.GetRelation()),
+		attribute.String("user", req.Request.GetUser()),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ListObjects")
+	defer cancel()
+
+	storeID := req.Request.GetStoreId()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.Request.GetAuthorizationModelId())
+	if err != nil {
+		return nil, err
+	}
+
+	tokenEncoder, err := s.encoderForStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds storagewrappers.ConditionalTupleReader = s.datastore
+	snapshot, err := s.snapshot(ctx, storeID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		defer snapshot.Close()
+		ds = snapshot
+	}
+
+	q := commands.NewListObjectsQuery(storagewrappers.NewConditionEvaluatingTupleReader(ds),
+		commands.WithLogger(s.logger),
+		commands.WithListObjectsDeadline(time.Duration(s.listObjectsDeadline.Load())),
+		commands.WithListObjectsPartialResults(s.listObjectsPartialResults),
+		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
+		commands.WithResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+		commands.WithListObjectsMaxConcurrentChecks(s.listObjectsMaxConcurrentChecks),
+		commands.WithListObjectsEncoder(tokenEncoder),
+		commands.WithListObjectsPlanner(s.listObjectsPlannerForRequest(ctx)),
+	)
+
+	stats := &graph.ResolutionStats{}
+
+	resp, err := q.ExecutePage(
+		graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesys), stats),
+		&commands.ListObjectsPageRequest{
+			Request: &openfgav1.ListObjectsRequest{
+				StoreId:              storeID,
+				ContextualTuples:     req.Request.GetContextualTuples(),
+				AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
+				Type:                 req.Request.GetType(),
+				Relation:             req.Request.GetRelation(),
+				User:                 req.Request.GetUser(),
+			},
+			PageSize:          req.PageSize,
+			ContinuationToken: req.ContinuationToken,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	setResolutionStatsHeaders(ctx, stats)
+
+	return resp, nil
+}
+
+func (s *Server) StreamedListObjects(req *openfgav1.StreamedListObjectsRequest, srv openfgav1.OpenFGAService_StreamedListObjectsServer) error {
+	ctx := srv.Context()
+	ctx, span := tracer.Start(ctx, "StreamedListObjects", trace.WithAttributes(
+		attribute.String("object_type", req.GetType()),
+		attribute.String("relation", req.GetRelation()),
+		attribute.String("user", req.GetUser()),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "StreamedListObjects")
+	defer cancel()
+
+	storeID := req.GetStoreId()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if err != nil {
+		return err
+	}
+
+	var ds storagewrappers.ConditionalTupleReader = s.datastore
+	snapshot, err := s.snapshot(ctx, storeID)
+	if err != nil {
+		return err
+	}
+	if snapshot != nil {
+		defer snapshot.Close()
+		ds = snapshot
+	}
+
+	q := commands.NewListObjectsQuery(storagewrappers.NewConditionEvaluatingTupleReader(ds),
+		commands.WithLogger(s.logger),
+		commands.WithListObjectsDeadline(time.Duration(s.listObjectsDeadline.Load())),
+		commands.WithListObjectsPartialResults(s.listObjectsPartialResults),
+		commands.WithListObjectsMaxResults(s.listObjectsMaxResults),
+		commands.WithResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+		commands.WithListObjectsMaxConcurrentChecks(s.listObjectsMaxConcurrentChecks),
+		commands.WithListObjectsPlanner(s.listObjectsPlannerForRequest(ctx)),
+		commands.WithListObjectsStreamBufferSize(s.listObjectsStreamBufferSize),
+		commands.WithListObjectsStreamSendTimeout(s.listObjectsStreamSendTimeout),
+	)
+
+	stats := &graph.ResolutionStats{}
+
+	req.AuthorizationModelId = typesys.GetAuthorizationModelID() // the resolved model id
+	err = q.ExecuteStreamed(
+		graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesys), stats),
+		req,
+		srv,
+	)
+
+	if stats.Incomplete() {
+		srv.SetTrailer(metadata.Pairs(ResolutionIncompleteHeader, "true"))
+	}
+
+	return err
+}
+
+// ListUsers lists the users that have a relation with an object, optionally filtered by user type.
+// It is the inverse of ListObjects.
+func (s *Server) ListUsers(ctx context.Context, req *commands.ListUsersRequest) (*commands.ListUsersResponse, error) {
+	ctx, span := tracer.Start(ctx, "ListUsers", trace.WithAttributes(
+		attribute.String("object", req.Object),
+		attribute.String("relation", req.Relation),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ListUsers")
+	defer cancel()
+
+	typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewListUsersQuery(storagewrappers.NewConditionEvaluatingTupleReader(s.datastore),
+		commands.WithListUsersLogger(s.logger),
+		commands.WithListUsersDeadline(time.Duration(s.listObjectsDeadline.Load())),
+		commands.WithListUsersMaxResults(s.listObjectsMaxResults),
+		commands.WithListUsersResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithListUsersResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithListUsersMaxConcurrentReads(s.maxConcurrentReadsForListObjects),
+	)
+
+	return q.Execute(typesystem.ContextWithTypesystem(ctx, typesys), &commands.ListUsersRequest{
+		StoreID:              req.StoreID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(), // the resolved model id
+		Object:               req.Object,
+		Relation:             req.Relation,
+		UserFilters:          req.UserFilters,
+		ContextualTuples:     req.ContextualTuples,
+	})
+}
+
+func (s *Server) Read(ctx context.Context, req *openfgav1.ReadRequest) (*openfgav1.ReadResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "Read", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Read")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewReadQuery(s.datastore, s.logger, tokenEncoder, commands.WithReadTokenCodec(s.tokenCodec))
+	return q.Execute(ctx, &openfgav1.ReadRequest{
+		StoreId:           req.GetStoreId(),
+		TupleKey:          tk,
+		PageSize:          req.GetPageSize(),
+		ContinuationToken: req.GetContinuationToken(),
+	})
+}
+
+// ReadWithFilter is like Read, but the tuples can also be filtered by several relations at once and by the types of
+// their users, e.g. to only read the users of a group excluding the nested groups. See commands.ReadWithFilterRequest.
+func (s *Server) ReadWithFilter(ctx context.Context, req *commands.ReadWithFilterRequest) (*openfgav1.ReadResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "ReadWithFilter", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+		attribute.StringSlice("relations", req.Relations),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Read")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewReadQuery(s.datastore, s.logger, tokenEncoder, commands.WithReadTokenCodec(s.tokenCodec))
+	return q.ExecuteWithFilter(ctx, req)
+}
+
+// ReadAsOf reads the tuples of a store as they were at a point in time, see commands.PointInTime. The tuples which
+// existed at the point in time but not anymore are all returned with the first page.
+func (s *Server) ReadAsOf(ctx context.Context, req *openfgav1.ReadRequest, pointInTime commands.PointInTime) (*openfgav1.ReadResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "ReadAsOf", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+		attribute.String("as_of", pointInTime.AsOf.String()),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Read")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := s.pointInTimeResolver(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewReadQuery(s.datastore, s.logger, tokenEncoder, commands.WithReadTokenCodec(s.tokenCodec))
+	return q.ExecuteAsOf(ctx, req, resolver, pointInTime)
+}
+
+// StreamedRead streams every tuple that matches the request to the provided server, without requiring
+// the client to loop over continuation tokens. The page size and continuation token of the request are ignored.
+func (s *Server) StreamedRead(req *openfgav1.ReadRequest, srv commands.StreamedReadServer) error {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(srv.Context(), "StreamedRead", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "StreamedRead")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return err
+	}
+
+	q := commands.NewReadQuery(s.datastore, s.logger, tokenEncoder)
+	return q.ExecuteStreamed(ctx, &openfgav1.ReadRequest{
+		StoreId:  req.GetStoreId(),
+		TupleKey: tk,
+	}, srv)
+}
+
+// StreamedReadServer is the OpenFGAService server with the StreamedRead method, which the API does not define.
+type StreamedReadServer interface {
+	openfgav1.OpenFGAServiceServer
+	StreamedRead(req *openfgav1.ReadRequest, srv commands.StreamedReadServer) error
+}
+
+var _ StreamedReadServer = (*Server)(nil)
+
+// ServiceDesc is openfgav1.OpenFGAService_ServiceDesc with the StreamedRead server-streaming method added, so that
+// StreamedRead is served as '/openfga.v1.OpenFGAService/StreamedRead' and goes through the same interceptors as the
+// other methods of the service. Register it with RegisterServer rather than openfgav1.RegisterOpenFGAServiceServer.
+var ServiceDesc = func() grpc.ServiceDesc {
+	desc := openfgav1.OpenFGAService_ServiceDesc
+	desc.HandlerType = (*StreamedReadServer)(nil)
+	desc.Streams = append(append([]grpc.StreamDesc(nil), desc.Streams...), grpc.StreamDesc{
+		StreamName:    "StreamedRead",
+		Handler:       streamedReadHandler,
+		ServerStreams: true,
+	})
+
+	return desc
+}()
+
+// RegisterServer registers the server on the gRPC server, with the methods of ServiceDesc.
+func RegisterServer(registrar grpc.ServiceRegistrar, srv StreamedReadServer) {
+	registrar.RegisterService(&ServiceDesc, srv)
+}
+
+func streamedReadHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(openfgav1.ReadRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	return srv.(StreamedReadServer).StreamedRead(req, &streamedReadServerStream{ServerStream: stream})
+}
+
+type streamedReadServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *streamedReadServerStream) Send(tuple *openfgav1.Tuple) error {
+	return s.ServerStream.SendMsg(tuple)
+}
+
+// Write deletes and writes tuples. The response has a consistency token in its consistency.TokenHeader metadata,
+// which the subsequent reads send to observe the write, see storage.ContextWithConsistencyToken: the ULID of the last
+// change the write committed in the changelog. A write which changed nothing has no token. The Go callers get the
+// token by passing storage.WithCommittedChangeULID to WriteWithOptions.
+//
+// A request with the ExpectedChangelogTokenHeader metadata is only applied if the changelog of the store still ends
+// at the continuation token of the metadata, returned by an unfiltered ReadChanges that read the latest change of the
+// store (an empty token expects a store without changes). Otherwise, it fails with serverErrors.ChangelogConflict,
+// so that the writers reconciling the store from its changes don't overwrite the concurrent writes.
+func (s *Server) Write(ctx context.Context, req *openfgav1.WriteRequest) (*openfgav1.WriteResponse, error) {
+	ctx, span := tracer.Start(ctx, "Write")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Write")
+	defer cancel()
+
+	return s.write(ctx, req, nil, nil)
+}
+
+// WriteWithOptions is like Write, but the options control whether writing a tuple that already exists or deleting
+// a tuple that does not exist fails the request. For example, storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore)
+// and storage.WithOnMissingDelete(storage.OnMissingDeleteIgnore) make the request idempotent.
+func (s *Server) WriteWithOptions(ctx context.Context, req *openfgav1.WriteRequest, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, span := tracer.Start(ctx, "WriteWithOptions")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Write")
+	defer cancel()
+
+	return s.write(ctx, req, nil, nil, opts...)
+}
+
+// WriteWithExpiry is like Write, but the written tuples expire at `expiresAt`. Expired tuples are ignored
+// by every query (e.g. Read, Check and ListObjects) and they are eventually deleted by the tuple reaper.
+// Note that Check results that are cached before a tuple expires may be served until their TTL expires.
+func (s *Server) WriteWithExpiry(ctx context.Context, req *openfgav1.WriteRequest, expiresAt time.Time, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, span := tracer.Start(ctx, "WriteWithExpiry")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Write")
+	defer cancel()
+
+	return s.write(ctx, req, &expiresAt, nil, opts...)
+}
+
+// WriteWithCondition is like Write, but the written tuples are only considered by Check, ListObjects and
+// ListUsers when their condition is satisfied. The condition is evaluated with the request context sent
+// with the query, see condition.NewContext and condition.ContextHeader. Read, ReadChanges and Expand
+// return conditional tuples like any other tuple.
+func (s *Server) WriteWithCondition(ctx context.Context, req *openfgav1.WriteRequest, tupleCondition *storage.TupleCondition, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	ctx, span := tracer.Start(ctx, "WriteWithCondition")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Write")
+	defer cancel()
+
+	if tupleCondition == nil {
+		return nil, serverErrors.ValidationError(errors.New("a condition is required"))
+	}
+
+	return s.write(ctx, req, nil, tupleCondition, opts...)
+}
+
+func (s *Server) write(ctx context.Context, req *openfgav1.WriteRequest, expiresAt *time.Time, tupleCondition *storage.TupleCondition, opts ...storage.TupleWriteOption) (*openfgav1.WriteResponse, error) {
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	storeID := req.GetStoreId()
+
+	if values := metadata.ValueFromIncomingContext(ctx, ExpectedChangelogTokenHeader); len(values) > 0 {
+		tokenEncoder, err := s.encoderForStore(storeID)
+		if err != nil {
+			return nil, err
+		}
+
+		opt, err := commands.ExpectedChangelogTokenOption(tokenEncoder, values[0])
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	// the consistency token is the ULID of the last change committed, which the caller may also ask for
+	committed := storage.NewTupleWriteOptions(opts...).CommittedChangeULID
+	if committed == nil {
+		committed = new(string)
+		opts = append(opts, storage.WithCommittedChangeULID(committed))
+	}
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.AuthorizationModelId)
+	if err != nil {
+		return nil, err
+	}
+
+	writeReq := &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
 		Writes:               req.GetWrites(),
 		Deletes:              req.GetDeletes(),
+	}
+
+	s.warnDeprecatedRelations(ctx, typesys, storeID, req.GetWrites().GetTupleKeys())
+
+	cmd := commands.NewWriteCommand(s.datastore, s.logger,
+		commands.WithWriteQuotas(s.quotaEnforcer),
+		commands.WithWriteHook(s.writeHook()),
+		commands.WithWriteCommitted(func(admitted *openfgav1.WriteRequest) {
+			// the hooks may have dropped or rewritten tuples, so only the object types committed are invalidated
+			if s.listObjectsCache != nil {
+				s.listObjectsCache.InvalidateObjectTypes(ctx, storeID, writtenObjectTypes(admitted)...)
+			}
+		}),
+	)
+
+	var res *openfgav1.WriteResponse
+	if expiresAt != nil {
+		res, err = cmd.ExecuteWithExpiry(ctx, writeReq, *expiresAt, opts...)
+	} else if tupleCondition != nil {
+		res, err = cmd.ExecuteWithCondition(ctx, writeReq, tupleCondition, opts...)
+	} else {
+		res, err = cmd.Execute(ctx, writeReq, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.checkCache != nil {
+		if err := s.checkCache.InvalidateStore(ctx, storeID); err != nil {
+			s.logger.WarnWithContext(ctx, "failed to invalidate the check cache of the store", zap.String("store_id", storeID), zap.Error(err))
+		}
+	}
+
+	if *committed != "" {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(consistency.TokenHeader, *committed))
+	}
+
+	return res, nil
+}
+
+// writtenObjectTypes returns the object types of the tuples written and deleted by the request.
+func writtenObjectTypes(req *openfgav1.WriteRequest) []string {
+	var objectTypes []string
+	seen := map[string]struct{}{}
+	for _, tupleKeys := range [][]*openfgav1.TupleKey{req.GetWrites().GetTupleKeys(), req.GetDeletes().GetTupleKeys()} {
+		for _, tk := range tupleKeys {
+			objectType := tuple.GetType(tk.GetObject())
+			if _, ok := seen[objectType]; !ok {
+				seen[objectType] = struct{}{}
+				objectTypes = append(objectTypes, objectType)
+			}
+		}
+	}
+
+	return objectTypes
+}
+
+// writeHook returns the hook chaining the write hooks of the server, or nil if there are none.
+func (s *Server) writeHook() writehook.Hook {
+	if len(s.writeHooks) == 0 {
+		return nil
+	}
+
+	return writehook.Chain(s.writeHooks...)
+}
+
+// warnDeprecatedRelations logs a warning for every relation or type annotated as deprecated that tuples are
+// written to.
+func (s *Server) warnDeprecatedRelations(ctx context.Context, typesys *typesystem.TypeSystem, storeID string, writes []*openfgav1.TupleKey) {
+	warned := map[string]struct{}{}
+	for _, tk := range writes {
+		objectType := tuple.GetType(tk.GetObject())
+
+		deprecation, ok := typesys.GetDeprecation(objectType, tk.GetRelation())
+		if !ok {
+			continue
+		}
+
+		relation := tuple.ToObjectRelationString(objectType, tk.GetRelation())
+		if _, ok := warned[relation]; ok {
+			continue
+		}
+		warned[relation] = struct{}{}
+
+		s.logger.WarnWithContext(ctx, "writing tuples to a deprecated relation",
+			zap.String("store_id", storeID),
+			zap.String("authorization_model_id", typesys.GetAuthorizationModelID()),
+			zap.String("relation", relation),
+			zap.String("deprecation", deprecation),
+		)
+	}
+}
+
+// ImportTuples writes a stream of tuples in batches, reporting the outcome of every batch to the client.
+// See commands.ImportTuplesCommand.
+func (s *Server) ImportTuples(srv commands.ImportTuplesServer) error {
+	ctx, span := tracer.Start(srv.Context(), "ImportTuples")
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
+	}
+
+	cmd := commands.NewImportTuplesCommand(s.datastore,
+		commands.WithImportTuplesLogger(s.logger),
+		commands.WithImportTuplesTypesystemResolver(s.resolveTypesystem),
+		commands.WithImportTuplesCheckCache(s.checkCache),
+		commands.WithImportTuplesQuotas(s.quotaEnforcer),
+		commands.WithImportTuplesHook(s.writeHook()),
+	)
+
+	return cmd.Execute(ctx, srv)
+}
+
+// WriteTuples deletes and writes a stream of tuples in chunks of at most MaxTuplesPerWrite tuples, reporting the
+// result of every chunk to the client. See commands.WriteTuplesCommand.
+func (s *Server) WriteTuples(srv commands.WriteTuplesServer) error {
+	ctx, span := tracer.Start(srv.Context(), "WriteTuples")
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
+	}
+
+	cmd := commands.NewWriteTuplesCommand(s.datastore,
+		commands.WithWriteTuplesLogger(s.logger),
+		commands.WithWriteTuplesTypesystemResolver(s.resolveTypesystem),
+		commands.WithWriteTuplesCheckCache(s.checkCache),
+		commands.WithWriteTuplesQuotas(s.quotaEnforcer),
+		commands.WithWriteTuplesHook(s.writeHook()),
+	)
+
+	return cmd.Execute(ctx, srv)
+}
+
+func (s *Server) Check(ctx context.Context, req *openfgav1.CheckRequest) (*openfgav1.CheckResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "Check", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Check")
+	defer cancel()
+
+	resp, err := s.check(ctx, req, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &openfgav1.CheckResponse{
+		Allowed: resp.Allowed,
+	}
+
+	span.SetAttributes(attribute.KeyValue{Key: "allowed", Value: attribute.BoolValue(res.GetAllowed())})
+	return res, nil
+}
+
+// ExplainCheck evaluates a Check like Check does and, if the user is allowed, also returns the resolution
+// path that led to the outcome: which tuples (including contextual tuples) and which rewrites were used,
+// see graph.CheckExplanation. Explained Checks bypass the check cache.
+func (s *Server) ExplainCheck(ctx context.Context, req *openfgav1.CheckRequest) (*graph.ResolveCheckResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "ExplainCheck", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Check")
+	defer cancel()
+
+	resp, err := s.check(ctx, req, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.KeyValue{Key: "allowed", Value: attribute.BoolValue(resp.GetAllowed())})
+	return resp, nil
+}
+
+// CheckAsOf evaluates a Check like Check does, but against the tuples of the store as they were at a point in time.
+// Unless the request has an authorization model ID, the latest model at the point in time is used. See
+// commands.PointInTime. Checks as of a point in time bypass the check cache.
+func (s *Server) CheckAsOf(ctx context.Context, req *openfgav1.CheckRequest, pointInTime commands.PointInTime) (*openfgav1.CheckResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "CheckAsOf", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+		attribute.String("as_of", pointInTime.AsOf.String()),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Check")
+	defer cancel()
+
+	if req.GetAuthorizationModelId() == "" {
+		resolver, err := s.pointInTimeResolver(req.GetStoreId())
+		if err != nil {
+			return nil, err
+		}
+
+		modelID, err := resolver.AuthorizationModelID(ctx, req.GetStoreId(), pointInTime)
+		if err != nil {
+			return nil, err
+		}
+
+		req = proto.Clone(req).(*openfgav1.CheckRequest)
+		req.AuthorizationModelId = modelID
+	}
+
+	resp, err := s.check(ctx, req, false, &pointInTime)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &openfgav1.CheckResponse{
+		Allowed: resp.Allowed,
+	}
+
+	span.SetAttributes(attribute.KeyValue{Key: "allowed", Value: attribute.BoolValue(res.GetAllowed())})
+	return res, nil
+}
+
+// snapshot returns the reader of the snapshot of the store the reads of the request must observe, according to its
+// consistency, or nil if the request doesn't have the snapshot consistency. The caller must close the snapshot.
+func (s *Server) snapshot(ctx context.Context, storeID string) (storage.SnapshotReader, error) {
+	consistency, err := consistencyFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if consistency != ConsistencySnapshot {
+		return nil, nil
+	}
+
+	snapshot, err := s.datastore.Snapshot(ctx, storeID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return snapshot, nil
+}
+
+// pointInTimeResolver returns the resolver of the past states of the store.
+func (s *Server) pointInTimeResolver(storeID string) (*commands.PointInTimeResolver, error) {
+	tokenEncoder, err := s.encoderForStore(storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return commands.NewPointInTimeResolver(s.datastore, tokenEncoder,
+		commands.WithPointInTimeChangelogRetentionPeriod(s.changelogRetentionPeriod),
+	), nil
+}
+
+// check validates and resolves a Check, optionally explaining an allowed outcome. If the point in time is set, the
+// Check is resolved against the tuples of the store at that point in time.
+func (s *Server) check(ctx context.Context, req *openfgav1.CheckRequest, explain bool, pointInTime *commands.PointInTime) (*graph.ResolveCheckResponse, error) {
+	tk := req.GetTupleKey()
+	if tk.GetUser() == "" || tk.GetRelation() == "" || tk.GetObject() == "" {
+		return nil, serverErrors.InvalidCheckInput
+	}
+
+	storeID := req.GetStoreId()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validation.ValidateUserObjectRelation(typesys, tk); err != nil {
+		return nil, serverErrors.ValidationError(err)
+	}
+
+	for _, ctxTuple := range req.GetContextualTuples().GetTupleKeys() {
+		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
+			return nil, serverErrors.HandleTupleValidateError(err)
+		}
+	}
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	stats := &graph.ResolutionStats{}
+	ctx = graph.ContextWithResolutionStats(ctx, stats)
+
+	ctx, done := commands.ObserveCheck(ctx, storeID)
+	defer done()
+
+	var ds storagewrappers.ConditionalTupleReader = s.datastore
+	checkOpts := s.checkResolverOptions(ctx)
+	if pointInTime != nil {
+		resolver, err := s.pointInTimeResolver(storeID)
+		if err != nil {
+			return nil, err
+		}
+
+		ds, err = resolver.Resolve(ctx, storeID, *pointInTime)
+		if err != nil {
+			return nil, err
+		}
+
+		// the cached results are those of the current tuples
+		checkOpts = append(checkOpts, graph.WithCheckCache(nil))
+	} else {
+		snapshot, err := s.snapshot(ctx, storeID)
+		if err != nil {
+			return nil, err
+		}
+
+		if snapshot != nil {
+			defer snapshot.Close()
+			ds = snapshot
+
+			// the cached results may not be those of the snapshot
+			checkOpts = append(checkOpts, graph.WithCheckCache(nil))
+		}
+	}
+
+	checkResolver := graph.NewLocalChecker(
+		storagewrappers.NewCombinedTupleReader(storagewrappers.NewConditionEvaluatingTupleReader(ds), req.ContextualTuples.GetTupleKeys()),
+		checkOpts...,
+	)
+
+	resolveReq := &graph.ResolveCheckRequest{
+		StoreID:              req.GetStoreId(),
+		AuthorizationModelID: typesys.GetAuthorizationModelID(), // the resolved model id
+		TupleKey:             req.GetTupleKey(),
+		ContextualTuples:     req.ContextualTuples.GetTupleKeys(),
+		ResolutionMetadata: &graph.ResolutionMetadata{
+			Depth: s.resolveNodeLimitForRequest(ctx),
+		},
+		Explain: explain,
+	}
+
+	shadowed := s.shadowEvaluator != nil && s.shadowCheck && !explain && pointInTime == nil && s.shadowEvaluator.Sample()
+	start := time.Now()
+
+	var resp *graph.ResolveCheckResponse
+	if s.checkDeduplicator != nil && pointInTime == nil && s.mayDeduplicateCheck(ctx) {
+		resp, err = s.checkDeduplicator.ResolveCheck(ctx, resolveReq, checkResolver)
+	} else {
+		resp, err = checkResolver.ResolveCheck(ctx, resolveReq)
+	}
+	if err != nil {
+		if errors.Is(err, graph.ErrResolutionDepthExceeded) {
+			return nil, serverErrors.AuthorizationModelResolutionTooComplex
+		}
+
+		if errors.Is(err, graph.ErrCycleDetected) {
+			return nil, serverErrors.CycleDetected
+		}
+
+		if errors.Is(err, graph.ErrResolutionLimitExceeded) {
+			return nil, serverErrors.ResolutionLimitExceeded(err)
+		}
+
+		if errors.Is(err, storagewrappers.ErrUnknownPointInTimeCondition) {
+			return nil, serverErrors.ValidationError(fmt.Errorf("the Check depends on tuples deleted after the point in time, whose conditions and expirations are not recorded in the changelog"))
+		}
+
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	setResolutionStatsHeaders(ctx, stats)
+
+	if shadowed {
+		s.shadowEvaluateCheck(ctx, typesys, checkOpts, resolveReq, resp.GetAllowed(), time.Since(start))
+	}
+
+	if s.recorder != nil && !explain && pointInTime == nil && s.recorder.Sample() {
+		s.record(ctx, &recorder.Record{
+			Method:               recorder.MethodCheck,
+			StoreID:              storeID,
+			AuthorizationModelID: typesys.GetAuthorizationModelID(),
+			Object:               tk.GetObject(),
+			Relation:             tk.GetRelation(),
+			User:                 tk.GetUser(),
+			ContextualTuples:     req.GetContextualTuples().GetTupleKeys(),
+			Allowed:              resp.GetAllowed(),
+		})
+	}
+
+	return resp, nil
+}
+
+// shadowEvaluateCheck evaluates the Check again with the experimental resolver of WithShadowCheck, in the background.
+// It reads the current tuples of the store, even if the Check read a snapshot.
+func (s *Server) shadowEvaluateCheck(
+	ctx context.Context,
+	typesys *typesystem.TypeSystem,
+	checkOpts []graph.LocalCheckerOption,
+	req *graph.ResolveCheckRequest,
+	allowed bool,
+	duration time.Duration,
+) {
+	opts := append(append(checkOpts[:len(checkOpts):len(checkOpts)], graph.WithCheckCache(nil)), s.shadowCheckOptions...)
+
+	s.shadowEvaluator.Evaluate(ctx, "Check", req.StoreID, duration, func(ctx context.Context) (commands.ShadowOutcome, []zap.Field, error) {
+		checkResolver := graph.NewLocalChecker(
+			storagewrappers.NewCombinedTupleReader(storagewrappers.NewConditionEvaluatingTupleReader(s.datastore), req.ContextualTuples),
+			opts...,
+		)
+
+		resp, err := checkResolver.ResolveCheck(typesystem.ContextWithTypesystem(ctx, typesys), &graph.ResolveCheckRequest{
+			StoreID:              req.StoreID,
+			AuthorizationModelID: req.AuthorizationModelID,
+			TupleKey:             req.TupleKey,
+			ContextualTuples:     req.ContextualTuples,
+			ResolutionMetadata: &graph.ResolutionMetadata{
+				Depth: req.ResolutionMetadata.Depth,
+			},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+
+		if resp.GetAllowed() == allowed {
+			return commands.ShadowMatch, nil, nil
+		}
+
+		return commands.ShadowMismatch, []zap.Field{
+			zap.String("tuple_key", tuple.TupleKeyToString(req.TupleKey)),
+			zap.String("authorization_model_id", req.AuthorizationModelID),
+			zap.Bool("current_allowed", allowed),
+			zap.Bool("experimental_allowed", resp.GetAllowed()),
+		}, nil
+	})
+}
+
+// withRequestTimeout returns a context bounded by the timeout of the API method, see WithRequestTimeout. The
+// earlier deadline of the parent context, if any, is kept.
+func (s *Server) withRequestTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	timeout, ok := s.methodRequestTimeouts[method]
+	if !ok {
+		timeout = s.requestTimeout
+	}
+
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// mayDeduplicateCheck reports whether the Check of the request may share the outcome of an identical concurrent
+// Check, which it may not if it must observe the latest writes or bypass the check cache, since the outcome it
+// would share may have been served by the cache.
+func (s *Server) mayDeduplicateCheck(ctx context.Context) bool {
+	if featureflags.Enabled(ctx, featureflags.BypassCheckCache) {
+		return false
+	}
+
+	consistency, err := consistencyFromContext(ctx)
+	if err != nil || consistency != ConsistencyDefault {
+		return false
+	}
+
+	return storage.ConsistencyTimeFromContext(ctx).IsZero()
+}
+
+// BatchCheck evaluates many Checks against the same store and authorization model in a single call.
+// The Checks are resolved concurrently and share the same resolved authorization model and contextual tuples.
+func (s *Server) BatchCheck(ctx context.Context, req *commands.BatchCheckRequest) (*commands.BatchCheckResponse, error) {
+	ctx, span := tracer.Start(ctx, "BatchCheck", trace.WithAttributes(
+		attribute.Int("checks", len(req.TupleKeys)),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "BatchCheck")
+	defer cancel()
+
+	typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ds storagewrappers.ConditionalTupleReader = s.datastore
+	checkCache := s.checkCacheForRequest(ctx)
+
+	snapshot, err := s.snapshot(ctx, req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		defer snapshot.Close()
+		ds = snapshot
+
+		// the cached results may not be those of the snapshot
+		checkCache = nil
+	}
+
+	q := commands.NewBatchCheckQuery(storagewrappers.NewConditionEvaluatingTupleReader(ds),
+		commands.WithBatchCheckLogger(s.logger),
+		commands.WithBatchCheckResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithBatchCheckResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+		commands.WithBatchCheckMaxConcurrentReads(s.maxConcurrentReadsForCheck),
+		commands.WithBatchCheckMaxDispatchCount(s.maxDispatchCountPerCheck),
+		commands.WithBatchCheckMaxDatastoreReads(s.maxDatastoreReadsPerCheck),
+		commands.WithBatchCheckCache(checkCache),
+	)
+
+	return q.Execute(typesystem.ContextWithTypesystem(ctx, typesys), &commands.BatchCheckRequest{
+		StoreID:              req.StoreID,
+		AuthorizationModelID: typesys.GetAuthorizationModelID(), // the resolved model id
+		TupleKeys:            req.TupleKeys,
+		ContextualTuples:     req.ContextualTuples,
+	})
+}
+
+func (s *Server) Expand(ctx context.Context, req *openfgav1.ExpandRequest) (*openfgav1.ExpandResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "Expand", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Expand")
+	defer cancel()
+
+	return s.expand(ctx, req, nil)
+}
+
+// ExpandWithContextualTuples expands the userset of the request as Expand does, but considers the
+// contextual tuples in addition to the tuples in the datastore. This lets callers preview the effect of
+// hypothetical tuples on the expansion tree without writing them.
+func (s *Server) ExpandWithContextualTuples(ctx context.Context, req *openfgav1.ExpandRequest, contextualTuples *openfgav1.ContextualTupleKeys) (*openfgav1.ExpandResponse, error) {
+	tk := req.GetTupleKey()
+	ctx, span := tracer.Start(ctx, "ExpandWithContextualTuples", trace.WithAttributes(
+		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
+		attribute.Int("contextual_tuples", len(contextualTuples.GetTupleKeys())),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "Expand")
+	defer cancel()
+
+	return s.expand(ctx, req, contextualTuples.GetTupleKeys())
+}
+
+func (s *Server) expand(ctx context.Context, req *openfgav1.ExpandRequest, contextualTuples []*openfgav1.TupleKey) (*openfgav1.ExpandResponse, error) {
+	tk := req.GetTupleKey()
+	storeID := req.GetStoreId()
+
+	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewExpandQuery(s.datastore, s.logger,
+		commands.WithExpandDepth(s.expandDepth),
+		commands.WithExpandContextualTuples(contextualTuples),
+	)
+	return q.Execute(ctx, &openfgav1.ExpandRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
+		TupleKey:             tk,
 	})
 }
 
-func (s *Server) Check(ctx context.Context, req *openfgav1.CheckRequest) (*openfgav1.CheckResponse, error) {
-	tk := req.GetTupleKey()
-	ctx, span := tracer.Start(ctx, "Check", trace.WithAttributes(
-		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
-		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
-		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
+func (s *Server) ReadAuthorizationModel(ctx context.Context, req *openfgav1.ReadAuthorizationModelRequest) (*openfgav1.ReadAuthorizationModelResponse, error) {
+	ctx, span := tracer.Start(ctx, "ReadAuthorizationModel", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(req.GetId())},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ReadAuthorizationModel")
+	defer cancel()
+
+	q := commands.NewReadAuthorizationModelQuery(s.datastore, s.logger)
+	return q.Execute(ctx, req)
+}
+
+func (s *Server) WriteAuthorizationModel(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	ctx, span := tracer.Start(ctx, "WriteAuthorizationModel")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "WriteAuthorizationModel")
+	defer cancel()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	req, annotations, err := s.includeModelModules(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c := commands.NewWriteAuthorizationModelCommand(s.datastore, s.logger, commands.WithWriteAuthorizationModelQuotas(s.quotaEnforcer))
+
+	var res *openfgav1.WriteAuthorizationModelResponse
+	if annotations == nil {
+		res, err = c.Execute(ctx, req)
+	} else {
+		res, err = c.ExecuteWithAnnotations(ctx, req, annotations)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.typesystems.Invalidate(ctx, req.GetStoreId())
+
+	s.transport.SetHeader(ctx, httpmiddleware.XHttpCode, strconv.Itoa(http.StatusCreated))
+
+	return res, nil
+}
+
+// WriteAuthorizationModelWithAnnotations is like WriteAuthorizationModel, but the key/value annotations (e.g.
+// descriptions, owners or deprecations, see typesystem.DeprecatedAnnotation) of the types and relations of
+// the model are written along with it.
+func (s *Server) WriteAuthorizationModelWithAnnotations(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest, annotations storage.ModelAnnotations) (*openfgav1.WriteAuthorizationModelResponse, error) {
+	ctx, span := tracer.Start(ctx, "WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "WriteAuthorizationModel")
+	defer cancel()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	req, annotations, err := s.includeModelModules(ctx, req, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	if annotations == nil {
+		annotations = storage.ModelAnnotations{}
+	}
+
+	c := commands.NewWriteAuthorizationModelCommand(s.datastore, s.logger, commands.WithWriteAuthorizationModelQuotas(s.quotaEnforcer))
+	res, err := c.ExecuteWithAnnotations(ctx, req, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	s.typesystems.Invalidate(ctx, req.GetStoreId())
+
+	return res, nil
+}
+
+// includeModelModules flattens the modules named by the ModelIncludesHeader of the request into a copy of the
+// request, and adds the typesystem.ModuleAnnotation of the included types to a copy of the annotations. The request
+// and the annotations are returned as is if the header is not set.
+func (s *Server) includeModelModules(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest, annotations storage.ModelAnnotations) (*openfgav1.WriteAuthorizationModelRequest, storage.ModelAnnotations, error) {
+	var includes []string
+	for _, value := range metadata.ValueFromIncomingContext(ctx, ModelIncludesHeader) {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				includes = append(includes, name)
+			}
+		}
+	}
+	if len(includes) == 0 {
+		return req, annotations, nil
+	}
+
+	if req.GetSchemaVersion() == typesystem.SchemaVersion1_0 {
+		return nil, nil, serverErrors.InvalidAuthorizationModelInput(fmt.Errorf("%w: only the models of schema version %s can include modules", typesystem.ErrInvalidModules, typesystem.SchemaVersion1_1))
+	}
+
+	typeDefinitions, moduleAnnotations, err := typesystem.FlattenModules(req.GetTypeDefinitions(), includes, s.modelModules)
+	if err != nil {
+		return nil, nil, serverErrors.InvalidAuthorizationModelInput(err)
+	}
+
+	s.logger.DebugWithContext(ctx, "included modules in the authorization model",
+		zap.String("store_id", req.GetStoreId()),
+		zap.Strings("modules", includes),
+	)
+
+	flattened := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:         req.GetStoreId(),
+		SchemaVersion:   req.GetSchemaVersion(),
+		TypeDefinitions: typeDefinitions,
+	}
+
+	merged := storage.ModelAnnotations{}
+	for objectType, typeAnnotations := range annotations {
+		merged[objectType] = typeAnnotations
+	}
+	for objectType, moduleTypeAnnotations := range moduleAnnotations {
+		typeAnnotations := &storage.TypeAnnotations{
+			Annotations: map[string]string{},
+			Relations:   merged[objectType].GetRelations(),
+		}
+		for key, value := range merged[objectType].GetAnnotations() {
+			typeAnnotations.Annotations[key] = value
+		}
+		// the module a type was included from is recorded by the server only
+		for key, value := range moduleTypeAnnotations.GetAnnotations() {
+			typeAnnotations.Annotations[key] = value
+		}
+		merged[objectType] = typeAnnotations
+	}
+
+	return flattened, merged, nil
+}
+
+// ReadAuthorizationModelAnnotations returns the annotations of the types and relations of an authorization
+// model, or of the pinned or else the latest authorization model of the store if modelID is empty.
+func (s *Server) ReadAuthorizationModelAnnotations(ctx context.Context, storeID, modelID string) (storage.ModelAnnotations, error) {
+	ctx, span := tracer.Start(ctx, "ReadAuthorizationModelAnnotations", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(modelID)},
 	))
 	defer span.End()
 
-	if tk.GetUser() == "" || tk.GetRelation() == "" || tk.GetObject() == "" {
-		return nil, serverErrors.InvalidCheckInput
+	typesys, err := s.resolveTypesystem(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
 	}
 
-	storeID := req.GetStoreId()
+	return typesys.GetAnnotations(), nil
+}
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
-	if err != nil {
-		return nil, err
+// PinAuthorizationModel pins an authorization model of the store, so that the Checks, ListObjects and the other
+// requests without an authorization model id are evaluated against it instead of the latest model of the store.
+// The models written afterwards are staged: they are only used by the requests that give their id until they
+// are pinned.
+func (s *Server) PinAuthorizationModel(ctx context.Context, storeID, modelID string) error {
+	ctx, span := tracer.Start(ctx, "PinAuthorizationModel", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(modelID)},
+	))
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
 	}
 
-	if err := validation.ValidateUserObjectRelation(typesys, tk); err != nil {
-		return nil, serverErrors.ValidationError(err)
+	c := commands.NewPinAuthorizationModelCommand(s.datastore, s.logger)
+	if err := c.Execute(ctx, storeID, modelID); err != nil {
+		return err
 	}
 
-	for _, ctxTuple := range req.GetContextualTuples().GetTupleKeys() {
-		if err := validation.ValidateTuple(typesys, ctxTuple); err != nil {
-			return nil, serverErrors.HandleTupleValidateError(err)
-		}
+	s.typesystems.Invalidate(ctx, storeID)
+
+	return nil
+}
+
+// UnpinAuthorizationModel unpins the pinned authorization model of the store, if any, so that the requests
+// without an authorization model id are evaluated against the latest model of the store again.
+func (s *Server) UnpinAuthorizationModel(ctx context.Context, storeID string) error {
+	ctx, span := tracer.Start(ctx, "UnpinAuthorizationModel")
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
 	}
 
-	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+	c := commands.NewUnpinAuthorizationModelCommand(s.datastore, s.logger)
+	if err := c.Execute(ctx, storeID); err != nil {
+		return err
+	}
 
-	checkResolver := graph.NewLocalChecker(
-		storagewrappers.NewCombinedTupleReader(s.datastore, req.ContextualTuples.GetTupleKeys()),
-		graph.WithResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
-		graph.WithMaxConcurrentReads(s.maxConcurrentReadsForCheck),
-	)
+	s.typesystems.Invalidate(ctx, storeID)
 
-	resp, err := checkResolver.ResolveCheck(ctx, &graph.ResolveCheckRequest{
-		StoreID:              req.GetStoreId(),
-		AuthorizationModelID: typesys.GetAuthorizationModelID(), // the resolved model id
-		TupleKey:             req.GetTupleKey(),
-		ContextualTuples:     req.ContextualTuples.GetTupleKeys(),
-		ResolutionMetadata: &graph.ResolutionMetadata{
-			Depth: s.resolveNodeLimit,
-		},
-	})
-	if err != nil {
-		if errors.Is(err, graph.ErrResolutionDepthExceeded) {
-			return nil, serverErrors.AuthorizationModelResolutionTooComplex
-		}
+	return nil
+}
 
-		return nil, serverErrors.HandleError("", err)
+// ReadPinnedAuthorizationModelID returns the id of the pinned authorization model of the store, or an empty
+// string if no model is pinned.
+func (s *Server) ReadPinnedAuthorizationModelID(ctx context.Context, storeID string) (string, error) {
+	ctx, span := tracer.Start(ctx, "ReadPinnedAuthorizationModelID")
+	defer span.End()
+
+	q := commands.NewReadPinnedAuthorizationModelQuery(s.datastore, s.logger)
+	return q.Execute(ctx, storeID)
+}
+
+// DeleteAuthorizationModel deletes an authorization model of the store along with its annotations and assertions.
+// The pinned model of the store, or its latest model if no model is pinned, cannot be deleted.
+func (s *Server) DeleteAuthorizationModel(ctx context.Context, storeID, modelID string) error {
+	ctx, span := tracer.Start(ctx, "DeleteAuthorizationModel", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(modelID)},
+	))
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
 	}
 
-	res := &openfgav1.CheckResponse{
-		Allowed: resp.Allowed,
+	c := commands.NewDeleteAuthorizationModelCommand(s.datastore, s.logger)
+	if err := c.Execute(ctx, storeID, modelID); err != nil {
+		return err
+	}
+
+	s.typesystems.InvalidateModel(ctx, storeID, modelID)
+
+	return nil
+}
+
+// PruneAuthorizationModels deletes the authorization models of the store but its newest ones and its pinned
+// model, optionally archiving them first. See commands.PruneAuthorizationModelsCommand.
+func (s *Server) PruneAuthorizationModels(ctx context.Context, req *commands.PruneAuthorizationModelsRequest) (*commands.PruneAuthorizationModelsResponse, error) {
+	ctx, span := tracer.Start(ctx, "PruneAuthorizationModels")
+	defer span.End()
+
+	if s.readOnly && !req.DryRun {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	c := commands.NewPruneAuthorizationModelsCommand(s.datastore, s.logger)
+	res, err := c.Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		for _, modelID := range res.PrunedAuthorizationModelIDs {
+			s.typesystems.InvalidateModel(ctx, req.StoreID, modelID)
+		}
 	}
 
-	span.SetAttributes(attribute.KeyValue{Key: "allowed", Value: attribute.BoolValue(res.GetAllowed())})
 	return res, nil
 }
 
-func (s *Server) Expand(ctx context.Context, req *openfgav1.ExpandRequest) (*openfgav1.ExpandResponse, error) {
-	tk := req.GetTupleKey()
-	ctx, span := tracer.Start(ctx, "Expand", trace.WithAttributes(
-		attribute.KeyValue{Key: "object", Value: attribute.StringValue(tk.GetObject())},
-		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(tk.GetRelation())},
-		attribute.KeyValue{Key: "user", Value: attribute.StringValue(tk.GetUser())},
-	))
+// ValidateAuthorizationModel checks and lints the authorization model of the request without writing it,
+// and returns every problem found. See commands.ValidateAuthorizationModelCommand.
+func (s *Server) ValidateAuthorizationModel(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*commands.ValidateAuthorizationModelResponse, error) {
+	ctx, span := tracer.Start(ctx, "ValidateAuthorizationModel")
 	defer span.End()
 
-	storeID := req.GetStoreId()
+	c := commands.NewValidateAuthorizationModelCommand(s.datastore, commands.WithValidateAuthorizationModelQuotas(s.quotaEnforcer))
+	return c.Execute(ctx, req), nil
+}
 
-	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
+// RunAssertions evaluates the assertions of an authorization model against the tuples of the store, and
+// reports whether each of them passed. See commands.RunAssertionsCommand.
+func (s *Server) RunAssertions(ctx context.Context, req *commands.RunAssertionsRequest) (*commands.RunAssertionsResponse, error) {
+	ctx, span := tracer.Start(ctx, "RunAssertions")
+	defer span.End()
+
+	typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
 	if err != nil {
 		return nil, err
 	}
 
-	q := commands.NewExpandQuery(s.datastore, s.logger)
-	return q.Execute(ctx, &openfgav1.ExpandRequest{
-		StoreId:              storeID,
-		AuthorizationModelId: typesys.GetAuthorizationModelID(), // the resolved model id
-		TupleKey:             tk,
-	})
+	c := commands.NewRunAssertionsCommand(s.datastore,
+		commands.WithRunAssertionsLogger(s.logger),
+		commands.WithRunAssertionsResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+		commands.WithRunAssertionsCheckerOptions(s.checkResolverOptions(ctx)...),
+	)
+
+	resp, err := c.Execute(typesystem.ContextWithTypesystem(ctx, typesys), req)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Bool("passed", resp.Passed))
+	return resp, nil
 }
 
-func (s *Server) ReadAuthorizationModel(ctx context.Context, req *openfgav1.ReadAuthorizationModelRequest) (*openfgav1.ReadAuthorizationModelResponse, error) {
-	ctx, span := tracer.Start(ctx, "ReadAuthorizationModel", trace.WithAttributes(
-		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(req.GetId())},
+// DiffAuthorizationModels compares two authorization models of a store, and flags the changes that may grant
+// access the previous model did not. See typesystem.Diff.
+func (s *Server) DiffAuthorizationModels(ctx context.Context, req *commands.DiffAuthorizationModelsRequest) (*typesystem.ModelDiff, error) {
+	ctx, span := tracer.Start(ctx, "DiffAuthorizationModels", trace.WithAttributes(
+		attribute.String("from_authorization_model_id", req.FromAuthorizationModelID),
+		attribute.String("to_authorization_model_id", req.ToAuthorizationModelID),
 	))
 	defer span.End()
 
-	q := commands.NewReadAuthorizationModelQuery(s.datastore, s.logger)
+	q := commands.NewDiffAuthorizationModelsQuery(s.datastore, s.logger)
 	return q.Execute(ctx, req)
 }
 
-func (s *Server) WriteAuthorizationModel(ctx context.Context, req *openfgav1.WriteAuthorizationModelRequest) (*openfgav1.WriteAuthorizationModelResponse, error) {
-	ctx, span := tracer.Start(ctx, "WriteAuthorizationModel")
+// AnalyzeAuthorizationModelImpact reports the tuples of a store which would become invalid under a proposed
+// authorization model, and optionally the assertions whose result would flip, without writing the model.
+// See commands.AuthorizationModelImpactQuery.
+func (s *Server) AnalyzeAuthorizationModelImpact(ctx context.Context, req *commands.AuthorizationModelImpactRequest) (*commands.AuthorizationModelImpactResponse, error) {
+	ctx, span := tracer.Start(ctx, "AnalyzeAuthorizationModelImpact", trace.WithAttributes(
+		attribute.Bool("include_assertions", req.IncludeAssertions),
+	))
 	defer span.End()
 
-	c := commands.NewWriteAuthorizationModelCommand(s.datastore, s.logger)
-	res, err := c.Execute(ctx, req)
-	if err != nil {
-		return nil, err
+	if req.IncludeAssertions {
+		typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
+		if err != nil {
+			return nil, err
+		}
+		ctx = typesystem.ContextWithTypesystem(ctx, typesys)
 	}
 
-	s.transport.SetHeader(ctx, httpmiddleware.XHttpCode, strconv.Itoa(http.StatusCreated))
+	q := commands.NewAuthorizationModelImpactQuery(s.datastore, s.logger,
+		commands.WithAuthorizationModelImpactCheckOptions(
+			commands.WithBatchCheckLogger(s.logger),
+			commands.WithBatchCheckResolveNodeLimit(s.resolveNodeLimitForRequest(ctx)),
+			commands.WithBatchCheckResolveNodeBreadthLimit(s.resolveNodeBreadthLimit),
+			commands.WithBatchCheckMaxConcurrentReads(s.maxConcurrentReadsForCheck),
+			commands.WithBatchCheckMaxDispatchCount(s.maxDispatchCountPerCheck),
+			commands.WithBatchCheckMaxDatastoreReads(s.maxDatastoreReadsPerCheck),
+		),
+	)
+	return q.Execute(ctx, req)
+}
 
-	return res, nil
+// MigrateAuthorizationModel converts a schema 1.0 authorization model of a store into a schema 1.1 model, and
+// writes it as the latest model of the store unless the request is a dry run. See commands.MigrateModelCommand.
+func (s *Server) MigrateAuthorizationModel(ctx context.Context, req *commands.MigrateModelRequest) (*commands.MigrateModelResponse, error) {
+	ctx, span := tracer.Start(ctx, "MigrateAuthorizationModel", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(req.AuthorizationModelID)},
+		attribute.Bool("dry_run", req.DryRun),
+	))
+	defer span.End()
+
+	if s.readOnly && !req.DryRun {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	c := commands.NewMigrateModelCommand(s.datastore, s.logger,
+		commands.WithMigrateModelWriteOptions(commands.WithWriteAuthorizationModelQuotas(s.quotaEnforcer)),
+	)
+	return c.Execute(ctx, req)
 }
 
 func (s *Server) ReadAuthorizationModels(ctx context.Context, req *openfgav1.ReadAuthorizationModelsRequest) (*openfgav1.ReadAuthorizationModelsResponse, error) {
 	ctx, span := tracer.Start(ctx, "ReadAuthorizationModels")
 	defer span.End()
 
-	c := commands.NewReadAuthorizationModelsQuery(s.datastore, s.logger, s.encoder)
+	ctx, cancel := s.withRequestTimeout(ctx, "ReadAuthorizationModels")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	c := commands.NewReadAuthorizationModelsQuery(s.datastore, s.logger, tokenEncoder)
 	return c.Execute(ctx, req)
 }
 
@@ -440,6 +2516,13 @@ func (s *Server) WriteAssertions(ctx context.Context, req *openfgav1.WriteAssert
 	ctx, span := tracer.Start(ctx, "WriteAssertions")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "WriteAssertions")
+	defer cancel()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
 	storeID := req.GetStoreId()
 
 	typesys, err := s.resolveTypesystem(ctx, storeID, req.GetAuthorizationModelId())
@@ -466,6 +2549,9 @@ func (s *Server) ReadAssertions(ctx context.Context, req *openfgav1.ReadAssertio
 	ctx, span := tracer.Start(ctx, "ReadAssertions")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "ReadAssertions")
+	defer cancel()
+
 	typesys, err := s.resolveTypesystem(ctx, req.GetStoreId(), req.GetAuthorizationModelId())
 	if err != nil {
 		return nil, err
@@ -481,14 +2567,108 @@ func (s *Server) ReadChanges(ctx context.Context, req *openfgav1.ReadChangesRequ
 	))
 	defer span.End()
 
-	q := commands.NewReadChangesQuery(s.datastore, s.logger, s.encoder, s.changelogHorizonOffset)
+	ctx, cancel := s.withRequestTimeout(ctx, "ReadChanges")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewReadChangesQuery(s.datastore, s.logger, tokenEncoder, s.changelogHorizonOffset, commands.WithReadChangesTokenCodec(s.tokenCodec))
 	return q.Execute(ctx, req)
 }
 
+// ReadChangesWithFilter is like ReadChanges, but the changes can also be filtered by relation, by operation and by
+// start time, so that the consumers syncing a single relation don't have to read the whole changelog.
+// See commands.ReadChangesWithFilterRequest.
+func (s *Server) ReadChangesWithFilter(ctx context.Context, req *commands.ReadChangesWithFilterRequest) (*openfgav1.ReadChangesResponse, error) {
+	ctx, span := tracer.Start(ctx, "ReadChangesWithFilter", trace.WithAttributes(
+		attribute.KeyValue{Key: "type", Value: attribute.StringValue(req.GetType())},
+		attribute.KeyValue{Key: "relation", Value: attribute.StringValue(req.Relation)},
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ReadChanges")
+	defer cancel()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return nil, err
+	}
+
+	q := commands.NewReadChangesQuery(s.datastore, s.logger, tokenEncoder, s.changelogHorizonOffset, commands.WithReadChangesTokenCodec(s.tokenCodec))
+	return q.ExecuteWithFilter(ctx, req)
+}
+
+// WatchChanges streams the changes of the store to the provided server as they occur, until the client cancels
+// the stream. The stream starts after the continuation token of the request, or at the beginning of the
+// changelog if it is empty, and every message carries the continuation token from which it can be resumed.
+// Messages without changes are heartbeats sent when the stream has been idle.
+func (s *Server) WatchChanges(req *openfgav1.ReadChangesRequest, srv commands.WatchChangesServer) error {
+	ctx, span := tracer.Start(srv.Context(), "WatchChanges", trace.WithAttributes(
+		attribute.KeyValue{Key: "type", Value: attribute.StringValue(req.GetType())},
+	))
+	defer span.End()
+
+	tokenEncoder, err := s.encoderForStore(req.GetStoreId())
+	if err != nil {
+		return err
+	}
+
+	q := commands.NewWatchChangesQuery(s.datastore, s.logger, tokenEncoder, s.changelogHorizonOffset, commands.WithWatchChangesTokenCodec(s.tokenCodec))
+	return q.Execute(ctx, req, srv)
+}
+
+// ExportTuples streams every tuple of a store. See commands.ExportTuplesCommand.
+func (s *Server) ExportTuples(req *commands.ExportTuplesRequest, srv commands.ExportTuplesServer) error {
+	ctx, span := tracer.Start(srv.Context(), "ExportTuples", trace.WithAttributes(
+		attribute.KeyValue{Key: "type", Value: attribute.StringValue(req.Type)},
+	))
+	defer span.End()
+
+	tokenEncoder, err := s.encoderForStore(req.StoreID)
+	if err != nil {
+		return err
+	}
+
+	cmd := commands.NewExportTuplesCommand(s.datastore, s.logger, tokenEncoder, s.changelogHorizonOffset, commands.WithExportTuplesTokenCodec(s.tokenCodec))
+	return cmd.Execute(ctx, req, srv)
+}
+
+// BackupStore writes a backup archive of the store (tuples, every authorization model version and
+// assertions) to w. See RestoreStore.
+func (s *Server) BackupStore(ctx context.Context, storeID string, w io.Writer) error {
+	ctx, span := tracer.Start(ctx, "BackupStore")
+	defer span.End()
+
+	return commands.NewBackupStoreCommand(s.datastore, s.logger).Execute(ctx, storeID, w)
+}
+
+// RestoreStore restores a backup archive written by BackupStore into a new store, preserving the
+// authorization model ids.
+func (s *Server) RestoreStore(ctx context.Context, req *commands.RestoreStoreRequest) (*openfgav1.CreateStoreResponse, error) {
+	ctx, span := tracer.Start(ctx, "RestoreStore")
+	defer span.End()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	return commands.NewRestoreStoreCommand(s.datastore, s.logger).Execute(ctx, req)
+}
+
 func (s *Server) CreateStore(ctx context.Context, req *openfgav1.CreateStoreRequest) (*openfgav1.CreateStoreResponse, error) {
 	ctx, span := tracer.Start(ctx, "CreateStore")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "CreateStore")
+	defer cancel()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
 	c := commands.NewCreateStoreCommand(s.datastore, s.logger)
 	res, err := c.Execute(ctx, req)
 	if err != nil {
@@ -504,21 +2684,88 @@ func (s *Server) DeleteStore(ctx context.Context, req *openfgav1.DeleteStoreRequ
 	ctx, span := tracer.Start(ctx, "DeleteStore")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "DeleteStore")
+	defer cancel()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
 	cmd := commands.NewDeleteStoreCommand(s.datastore, s.logger)
 	res, err := cmd.Execute(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
+	s.typesystems.Invalidate(ctx, req.GetStoreId())
+
+	if s.listObjectsCache != nil {
+		s.listObjectsCache.DeleteStore(ctx, req.GetStoreId())
+	}
+
 	s.transport.SetHeader(ctx, httpmiddleware.XHttpCode, strconv.Itoa(http.StatusNoContent))
 
 	return res, nil
 }
 
+// UndeleteStore restores a store deleted within the retention period, see WithStoreRetentionPeriod, and returns it.
+func (s *Server) UndeleteStore(ctx context.Context, storeID string) (*openfgav1.Store, error) {
+	ctx, span := tracer.Start(ctx, "UndeleteStore")
+	defer span.End()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	cmd := commands.NewUndeleteStoreCommand(s.datastore, s.logger, commands.WithUndeleteStoreRetentionPeriod(s.storeRetentionPeriod))
+	return cmd.Execute(ctx, storeID)
+}
+
+// TrimChangelog deletes the changes that occurred before a point in time from the changelog of a store, or of every
+// store if the store is empty. See commands.TrimChangelogCommand.
+func (s *Server) TrimChangelog(ctx context.Context, req *commands.TrimChangelogRequest) (*commands.TrimChangelogResponse, error) {
+	ctx, span := tracer.Start(ctx, "TrimChangelog")
+	defer span.End()
+
+	if s.readOnly {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	cmd := commands.NewTrimChangelogCommand(s.datastore, s.logger)
+	return cmd.Execute(ctx, req)
+}
+
+// DeleteOrphanedTuples deletes the tuples of a store which reference types or relations that are no longer defined
+// in an authorization model, or only reports them for a dry run. See commands.DeleteOrphanedTuplesCommand.
+func (s *Server) DeleteOrphanedTuples(ctx context.Context, req *commands.DeleteOrphanedTuplesRequest) (*commands.DeleteOrphanedTuplesResponse, error) {
+	ctx, span := tracer.Start(ctx, "DeleteOrphanedTuples", trace.WithAttributes(
+		attribute.KeyValue{Key: authorizationModelIDKey, Value: attribute.StringValue(req.AuthorizationModelID)},
+		attribute.Bool("dry_run", req.DryRun),
+	))
+	defer span.End()
+
+	if s.readOnly && !req.DryRun {
+		return nil, serverErrors.ReadOnlyMode
+	}
+
+	typesys, err := s.resolveTypesystem(ctx, req.StoreID, req.AuthorizationModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := commands.NewDeleteOrphanedTuplesCommand(s.datastore, s.logger,
+		commands.WithDeleteOrphanedTuplesCheckCache(s.checkCache),
+	)
+	return cmd.Execute(typesystem.ContextWithTypesystem(ctx, typesys), req)
+}
+
 func (s *Server) GetStore(ctx context.Context, req *openfgav1.GetStoreRequest) (*openfgav1.GetStoreResponse, error) {
 	ctx, span := tracer.Start(ctx, "GetStore")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "GetStore")
+	defer cancel()
+
 	q := commands.NewGetStoreQuery(s.datastore, s.logger)
 	return q.Execute(ctx, req)
 }
@@ -527,10 +2774,51 @@ func (s *Server) ListStores(ctx context.Context, req *openfgav1.ListStoresReques
 	ctx, span := tracer.Start(ctx, "ListStores")
 	defer span.End()
 
+	ctx, cancel := s.withRequestTimeout(ctx, "ListStores")
+	defer cancel()
+
 	q := commands.NewListStoresQuery(s.datastore, s.logger, s.encoder)
 	return q.Execute(ctx, req)
 }
 
+// ListStoresWithFilter is like ListStores, but only the stores selected by the filter, e.g. by a name prefix or by
+// labels, are listed in the order of the filter.
+func (s *Server) ListStoresWithFilter(ctx context.Context, req *openfgav1.ListStoresRequest, filter storage.ListStoresFilter) (*openfgav1.ListStoresResponse, error) {
+	ctx, span := tracer.Start(ctx, "ListStoresWithFilter", trace.WithAttributes(
+		attribute.Int("labels", len(filter.Labels)),
+		attribute.Bool("sort_by_name", filter.SortOrder == storage.StoreSortByName),
+	))
+	defer span.End()
+
+	ctx, cancel := s.withRequestTimeout(ctx, "ListStores")
+	defer cancel()
+
+	q := commands.NewListStoresQuery(s.datastore, s.logger, s.encoder)
+	return q.ExecuteWithFilter(ctx, req, filter)
+}
+
+// WriteStoreMetadata replaces the description and the labels of a store.
+func (s *Server) WriteStoreMetadata(ctx context.Context, storeID string, metadata *storage.StoreMetadata) error {
+	ctx, span := tracer.Start(ctx, "WriteStoreMetadata")
+	defer span.End()
+
+	if s.readOnly {
+		return serverErrors.ReadOnlyMode
+	}
+
+	c := commands.NewWriteStoreMetadataCommand(s.datastore, s.logger)
+	return c.Execute(ctx, storeID, metadata)
+}
+
+// ReadStoreMetadata returns the description and the labels of a store.
+func (s *Server) ReadStoreMetadata(ctx context.Context, storeID string) (*storage.StoreMetadata, error) {
+	ctx, span := tracer.Start(ctx, "ReadStoreMetadata")
+	defer span.End()
+
+	q := commands.NewReadStoreMetadataQuery(s.datastore, s.logger)
+	return q.Execute(ctx, storeID)
+}
+
 // IsReady reports whether this OpenFGA server instance is ready to accept
 // traffic.
 func (s *Server) IsReady(ctx context.Context) (bool, error) {
@@ -541,6 +2829,39 @@ func (s *Server) IsReady(ctx context.Context) (bool, error) {
 	return s.datastore.IsReady(ctx)
 }
 
+// encoderForStore returns the continuation token encoder to use for the provided store.
+func (s *Server) encoderForStore(storeID string) (encoder.Encoder, error) {
+	if s.storeEncoder == nil {
+		return s.encoder, nil
+	}
+
+	tokenEncoder, err := s.storeEncoder.ForStore(storeID)
+	if err != nil {
+		return nil, serverErrors.HandleError("", err)
+	}
+
+	return tokenEncoder, nil
+}
+
+// setResolutionStatsHeaders reports the resolution statistics of a request in its response headers.
+func setResolutionStatsHeaders(ctx context.Context, stats *graph.ResolutionStats) {
+	md := metadata.Pairs(
+		ResolutionDatastoreQueriesHeader, strconv.FormatUint(uint64(stats.DatastoreQueries()), 10),
+		ResolutionDispatchesHeader, strconv.FormatUint(uint64(stats.Dispatches()), 10),
+		ResolutionMaxDepthHeader, strconv.FormatUint(uint64(stats.MaxDepth()), 10),
+	)
+
+	if lookups, hits := stats.CacheLookups(); lookups > 0 {
+		md.Append(ResolutionCacheHitRatioHeader, strconv.FormatFloat(float64(hits)/float64(lookups), 'f', 2, 64))
+	}
+
+	if stats.Incomplete() {
+		md.Append(ResolutionIncompleteHeader, "true")
+	}
+
+	_ = grpc.SetHeader(ctx, md)
+}
+
 // resolveTypesystem resolves the underlying TypeSystem given the storeID and modelID and
 // it sets some response metadata based on the model resolution.
 func (s *Server) resolveTypesystem(ctx context.Context, storeID, modelID string) (*typesystem.TypeSystem, error) {
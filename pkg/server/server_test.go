@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"testing"
 	"time"
 
@@ -15,7 +16,14 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
 	mockstorage "github.com/openfga/openfga/internal/mocks"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/middleware/featureflags"
+	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
+	"github.com/openfga/openfga/pkg/server/commands/quota"
 	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/server/test"
 	"github.com/openfga/openfga/pkg/storage"
@@ -27,9 +35,13 @@ import (
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func init() {
@@ -151,6 +163,10 @@ func TestCheckDoesNotThrowBecauseDirectTupleWasFound(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().
 		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
@@ -160,6 +176,11 @@ func TestCheckDoesNotThrowBecauseDirectTupleWasFound(t *testing.T) {
 			TypeDefinitions: typedefs,
 		}, nil)
 
+	mockDatastore.EXPECT().
+		ReadTupleConditions(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
+
 	// it could happen that one of the following two mocks won't be necessary because the goroutine will be short-circuited
 	mockDatastore.EXPECT().
 		ReadUserTuple(gomock.Any(), storeID, gomock.Any()).
@@ -210,6 +231,10 @@ func TestOperationsWithInvalidModel(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().
 		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
@@ -293,6 +318,10 @@ func TestShortestPathToSolutionWins(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().
 		ReadAuthorizationModel(gomock.Any(), storeID, modelID).
@@ -302,6 +331,11 @@ func TestShortestPathToSolutionWins(t *testing.T) {
 			TypeDefinitions: typedefs,
 		}, nil)
 
+	mockDatastore.EXPECT().
+		ReadTupleConditions(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
+
 	// it could happen that one of the following two mocks won't be necessary because the goroutine will be short-circuited
 	mockDatastore.EXPECT().
 		ReadUserTuple(gomock.Any(), storeID, gomock.Any()).
@@ -354,6 +388,11 @@ func TestResolveAuthorizationModel(t *testing.T) {
 		defer mockController.Finish()
 
 		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().
+			ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(nil, nil)
+		mockDatastore.EXPECT().ReadPinnedAuthorizationModelID(gomock.Any(), store).Return("", nil)
 		mockDatastore.EXPECT().FindLatestAuthorizationModelID(gomock.Any(), store).Return("", storage.ErrNotFound)
 
 		s := MustNewServerWithOpts(
@@ -374,6 +413,11 @@ func TestResolveAuthorizationModel(t *testing.T) {
 		defer mockController.Finish()
 
 		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().
+			ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(nil, nil)
+		mockDatastore.EXPECT().ReadPinnedAuthorizationModelID(gomock.Any(), store).Return("", nil)
 		mockDatastore.EXPECT().FindLatestAuthorizationModelID(gomock.Any(), store).Return(modelID, nil)
 		mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).Return(
 			&openfgav1.AuthorizationModel{
@@ -401,6 +445,10 @@ func TestResolveAuthorizationModel(t *testing.T) {
 		defer mockController.Finish()
 
 		mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+		mockDatastore.EXPECT().
+			ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(nil, nil)
 
 		s := MustNewServerWithOpts(
 			WithDatastore(mockDatastore),
@@ -446,6 +494,10 @@ func BenchmarkListObjectsNoRaceCondition(b *testing.B) {
     `)
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion:   typesystem.SchemaVersion1_1,
@@ -491,6 +543,10 @@ func TestListObjects_Unoptimized_UnhappyPaths(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion: typesystem.SchemaVersion1_1,
@@ -545,6 +601,10 @@ func TestListObjects_Unoptimized_UnhappyPaths_Known_Error(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion: typesystem.SchemaVersion1_1,
@@ -599,6 +659,10 @@ func TestListObjects_UnhappyPaths(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion: typesystem.SchemaVersion1_1,
@@ -672,6 +736,10 @@ func TestListObjects_UnhappyPaths_Known_Error(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion: typesystem.SchemaVersion1_1,
@@ -744,6 +812,10 @@ func TestAuthorizationModelInvalidSchemaVersion(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	mockDatastore.EXPECT().ReadAuthorizationModel(gomock.Any(), store, modelID).AnyTimes().Return(&openfgav1.AuthorizationModel{
 		SchemaVersion: typesystem.SchemaVersion1_0,
@@ -878,25 +950,1433 @@ func TestDefaultMaxConcurrentReadSettings(t *testing.T) {
 	require.EqualValues(t, math.MaxUint32, s.maxConcurrentReadsForListObjects)
 }
 
-func MustBootstrapDatastore(t testing.TB, engine string) storage.OpenFGADatastore {
-	testDatastore := storagefixtures.RunDatastoreTestContainer(t, engine)
+func TestReadOnlyMode(t *testing.T) {
+	ctx := context.Background()
 
-	uri := testDatastore.GetConnectionURI(true)
+	ds := memory.New()
+	t.Cleanup(ds.Close)
 
-	var ds storage.OpenFGADatastore
-	var err error
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+	require.NoError(t, err)
 
-	switch engine {
-	case "memory":
-		ds = memory.New()
-	case "postgres":
-		ds, err = postgres.New(uri, sqlcommon.NewConfig())
-	case "mysql":
-		ds, err = mysql.New(uri, sqlcommon.NewConfig())
-	default:
-		t.Fatalf("'%s' is not a supported datastore engine", engine)
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithReadOnly(true),
+	)
+
+	_, err = s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.ErrorIs(t, err, serverErrors.ReadOnlyMode)
+
+	_, err = s.DeleteStore(ctx, &openfgav1.DeleteStoreRequest{StoreId: store.Id})
+	require.ErrorIs(t, err, serverErrors.ReadOnlyMode)
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{StoreId: store.Id})
+	require.ErrorIs(t, err, serverErrors.ReadOnlyMode)
+
+	_, err = s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{StoreId: store.Id})
+	require.ErrorIs(t, err, serverErrors.ReadOnlyMode)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{StoreId: store.Id})
+	require.ErrorIs(t, err, serverErrors.ReadOnlyMode)
+
+	getStoreResp, err := s.GetStore(ctx, &openfgav1.GetStoreRequest{StoreId: store.Id})
+	require.NoError(t, err)
+	require.Equal(t, store.Id, getStoreResp.GetId())
+}
+
+func TestWriteWithExpectedChangelogToken(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	write := func(token, object string) error {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs(ExpectedChangelogTokenHeader, token))
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes:  &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey(object, "viewer", "user:jon")}},
+		})
+		return err
 	}
+
+	// the store has no changes yet
+	require.NoError(t, write("", "document:1"))
+
+	readChangesResp, err := s.ReadChanges(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID})
 	require.NoError(t, err)
+	token := readChangesResp.GetContinuationToken()
 
-	return ds
+	// a concurrent write moves the changelog past the token
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes:  &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:2", "viewer", "user:jon")}},
+	})
+	require.NoError(t, err)
+
+	err = write(token, "document:3")
+	require.ErrorIs(t, err, serverErrors.ChangelogConflict)
+
+	readChangesResp, err = s.ReadChanges(ctx, &openfgav1.ReadChangesRequest{StoreId: storeID, ContinuationToken: token})
+	require.NoError(t, err)
+	require.Len(t, readChangesResp.GetChanges(), 1)
+
+	require.NoError(t, write(readChangesResp.GetContinuationToken(), "document:3"))
+
+	err = write("invalid", "document:4")
+	require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+}
+
+func TestCheckQueryCacheInvalidatedOnWrite(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Minute),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	checkResp, err := s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	// a write that bypasses the server does not invalidate the cache, so the cached result is served
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")})
+	require.NoError(t, err)
+
+	checkResp, err = s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	// a write through the server invalidates every cached result of the store
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	checkResp, err = s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+}
+
+func TestListObjectsCacheInvalidatedOnWrite(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithListObjectsCacheEnabled(true),
+		WithListObjectsCacheTTL(time.Minute),
+		WithListObjectsCacheChangelogInterval(time.Minute),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type folder
+		  relations
+		    define viewer: [user] as self
+
+		type document
+		  relations
+		    define parent: [folder] as self
+		    define viewer: [user] as self or viewer from parent
+
+		type label
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	write := func(tk *openfgav1.TupleKey) {
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId: storeID,
+			Writes:  &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{tk}},
+		})
+		require.NoError(t, err)
+	}
+
+	listObjects := func() []string {
+		resp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Type:                 "document",
+			Relation:             "viewer",
+			User:                 "user:jon",
+		})
+		require.NoError(t, err)
+
+		objects := resp.GetObjects()
+		sort.Strings(objects)
+
+		return objects
+	}
+
+	write(tuple.NewTupleKey("document:1", "viewer", "user:jon"))
+	require.Equal(t, []string{"document:1"}, listObjects())
+
+	// a write that bypasses the server is not observed until the changelog is read again, so the cached result is
+	// served
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:2", "viewer", "user:jon")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:1"}, listObjects())
+
+	// a write through the server to an object type the result does not depend on does not invalidate it
+	write(tuple.NewTupleKey("label:1", "viewer", "user:jon"))
+	require.Equal(t, []string{"document:1"}, listObjects())
+
+	// a write through the server to an object type the result depends on invalidates it
+	write(tuple.NewTupleKey("document:3", "parent", "folder:1"))
+	write(tuple.NewTupleKey("folder:1", "viewer", "user:jon"))
+	require.Equal(t, []string{"document:1", "document:2", "document:3"}, listObjects())
+}
+
+func TestConsistencyTokenBypassesCheckCache(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Minute),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	checkResp, err := s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	// a write through another server doesn't invalidate the cache of this one
+	var token string
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")}, storage.WithCommittedChangeULID(&token))
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	checkResp, err = s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	tokenCtx, err := storage.ContextWithConsistencyToken(ctx, token)
+	require.NoError(t, err)
+
+	checkResp, err = s.Check(tokenCtx, checkReq)
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+}
+
+func TestSnapshotConsistency(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithCheckQueryCacheEnabled(true),
+		WithCheckQueryCacheTTL(time.Minute),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	}
+
+	checkResp, err := s.Check(ctx, checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	// a write that bypasses the server does not invalidate the cache
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:jon")})
+	require.NoError(t, err)
+
+	t.Run("snapshot_checks_bypass_the_check_cache", func(t *testing.T) {
+		checkResp, err := s.Check(ContextWithConsistency(ctx, ConsistencySnapshot), checkReq)
+		require.NoError(t, err)
+		require.True(t, checkResp.GetAllowed())
+	})
+
+	t.Run("snapshot_batch_checks_bypass_the_check_cache", func(t *testing.T) {
+		batchCheckReq := &commands.BatchCheckRequest{
+			StoreID:   storeID,
+			TupleKeys: []*openfgav1.TupleKey{checkReq.GetTupleKey()},
+		}
+
+		batchCheckResp, err := s.BatchCheck(ctx, batchCheckReq)
+		require.NoError(t, err)
+		require.False(t, batchCheckResp.Results[0].Allowed)
+
+		batchCheckResp, err = s.BatchCheck(ContextWithConsistency(ctx, ConsistencySnapshot), batchCheckReq)
+		require.NoError(t, err)
+		require.NoError(t, batchCheckResp.Results[0].Err)
+		require.True(t, batchCheckResp.Results[0].Allowed)
+	})
+
+	t.Run("the_consistency_is_read_from_the_metadata", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs(ConsistencyHeader, string(ConsistencySnapshot)))
+
+		listObjectsResp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:1"}, listObjectsResp.GetObjects())
+	})
+
+	t.Run("an_unknown_consistency_is_rejected", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs(ConsistencyHeader, "eventual"))
+
+		_, err := s.Check(ctx, checkReq)
+		require.ErrorContains(t, err, "the consistency must be empty, 'strong' or 'snapshot'")
+	})
+}
+
+func TestCheckResolutionLimits(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithMaxDispatchCountPerCheck(1),
+	)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define editor: [user] as self or owner
+		    define viewer: [user] as self or editor
+		`),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tuple.NewTupleKey("document:1", "editor", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	})
+	require.Error(t, err)
+	e, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.Code(openfgav1.InternalErrorCode_resource_exhausted), e.Code())
+
+	batchResp, err := s.BatchCheck(ctx, &commands.BatchCheckRequest{
+		StoreID:              storeID,
+		AuthorizationModelID: writeModelResp.GetAuthorizationModelId(),
+		TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "user:jon"),
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, batchResp.Results[0].Err)
+	require.ErrorContains(t, batchResp.Results[1].Err, graph.ErrDispatchLimitExceeded.Error())
+}
+
+func TestMethodRequestTimeouts(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithRequestTimeout(time.Minute),
+		WithMethodRequestTimeout("Check", time.Nanosecond),
+	)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+	})
+	require.ErrorIs(t, err, serverErrors.RequestDeadlineExceeded)
+
+	listObjectsResp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+		Type:                 "document",
+		Relation:             "viewer",
+		User:                 "user:jon",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:1"}, listObjectsResp.GetObjects())
+}
+
+func TestStoreQuotas(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithQuotas(quota.Limits{MaxTuplesPerStore: 2, MaxRelationsPerType: 2}),
+	)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define editor: [user] as self
+		    define viewer: [user] as self
+		`),
+	})
+	require.ErrorContains(t, err, "The number of relations of type 'document' exceeds the allowed limit of 2")
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+
+	write := func(objects ...string) error {
+		var tuples []*openfgav1.TupleKey
+		for _, object := range objects {
+			tuples = append(tuples, tuple.NewTupleKey(object, "viewer", "user:jon"))
+		}
+
+		_, err := s.Write(ctx, &openfgav1.WriteRequest{
+			StoreId:              storeID,
+			AuthorizationModelId: writeModelResp.GetAuthorizationModelId(),
+			Writes:               &openfgav1.TupleKeys{TupleKeys: tuples},
+		})
+		return err
+	}
+
+	require.NoError(t, write("document:1", "document:2"))
+
+	err = write("document:3")
+	require.ErrorIs(t, err, serverErrors.QuotaExceeded("tuples", 2))
+}
+
+func MustBootstrapDatastore(t testing.TB, engine string) storage.OpenFGADatastore {
+	testDatastore := storagefixtures.RunDatastoreTestContainer(t, engine)
+
+	uri := testDatastore.GetConnectionURI(true)
+
+	var ds storage.OpenFGADatastore
+	var err error
+
+	switch engine {
+	case "memory":
+		ds = memory.New()
+	case "postgres":
+		ds, err = postgres.New(uri, sqlcommon.NewConfig())
+	case "mysql":
+		ds, err = mysql.New(uri, sqlcommon.NewConfig())
+	default:
+		t.Fatalf("'%s' is not a supported datastore engine", engine)
+	}
+	require.NoError(t, err)
+
+	return ds
+}
+
+func TestDiffAuthorizationModels(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModel := func(dsl string) string {
+		resp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: parser.MustParse(dsl),
+		})
+		require.NoError(t, err)
+		return resp.GetAuthorizationModelId()
+	}
+
+	fromModelID := writeModel(`
+	type user
+
+	type document
+	  relations
+	    define viewer: [user] as self
+	`)
+
+	toModelID := writeModel(`
+	type user
+
+	type document
+	  relations
+	    define viewer: [user, user:*] as self
+	`)
+
+	diff, err := s.DiffAuthorizationModels(ctx, &commands.DiffAuthorizationModelsRequest{
+		StoreID:                  storeID,
+		FromAuthorizationModelID: fromModelID,
+		ToAuthorizationModelID:   toModelID,
+	})
+	require.NoError(t, err)
+	require.True(t, diff.PotentiallyExpandsAccess)
+	require.Len(t, diff.ChangedRelations, 1)
+	require.Equal(t, []string{"user:*"}, diff.ChangedRelations[0].AddedTypeRestrictions)
+
+	_, err = s.DiffAuthorizationModels(ctx, &commands.DiffAuthorizationModelsRequest{
+		StoreID:                  storeID,
+		FromAuthorizationModelID: fromModelID,
+		ToAuthorizationModelID:   ulid.Make().String(),
+	})
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestPinnedAuthorizationModel(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModel := func(dsl string) string {
+		resp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:         storeID,
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: parser.MustParse(dsl),
+		})
+		require.NoError(t, err)
+		return resp.GetAuthorizationModelId()
+	}
+
+	activeModelID := writeModel(`
+	type user
+
+	type document
+	  relations
+	    define viewer: [user] as self
+	`)
+
+	err = s.PinAuthorizationModel(ctx, storeID, activeModelID)
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		}},
+	})
+	require.NoError(t, err)
+
+	// the staged model makes the viewers editors, and is not used until it is pinned
+	stagedModelID := writeModel(`
+	type user
+
+	type document
+	  relations
+	    define editor: [user] as self
+	    define viewer: [user] as self or editor
+	`)
+
+	check := func() (*openfgav1.CheckResponse, error) {
+		return s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewTupleKey("document:1", "editor", "user:jon"),
+		})
+	}
+
+	_, err = check()
+	require.ErrorContains(t, err, "relation 'document#editor' not found")
+
+	pinnedModelID, err := s.ReadPinnedAuthorizationModelID(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, activeModelID, pinnedModelID)
+
+	err = s.PinAuthorizationModel(ctx, storeID, stagedModelID)
+	require.NoError(t, err)
+
+	resp, err := check()
+	require.NoError(t, err)
+	require.False(t, resp.GetAllowed())
+
+	err = s.PinAuthorizationModel(ctx, storeID, ulid.Make().String())
+	require.ErrorContains(t, err, "not found")
+
+	err = s.UnpinAuthorizationModel(ctx, storeID)
+	require.NoError(t, err)
+
+	pinnedModelID, err = s.ReadPinnedAuthorizationModelID(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, pinnedModelID)
+}
+
+func TestExpandWithContextualTuples(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		}},
+	})
+	require.NoError(t, err)
+
+	req := &openfgav1.ExpandRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", ""),
+	}
+
+	resp, err := s.ExpandWithContextualTuples(ctx, req, &openfgav1.ContextualTupleKeys{
+		TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+			tuple.NewTupleKey("document:2", "viewer", "user:charlie"),
+		},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"user:anne", "user:bob"}, resp.GetTree().GetRoot().GetLeaf().GetUsers().GetUsers())
+
+	// the contextual tuples are not persisted
+	resp, err = s.Expand(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user:anne"}, resp.GetTree().GetRoot().GetLeaf().GetUsers().GetUsers())
+
+	_, err = s.ExpandWithContextualTuples(ctx, req, &openfgav1.ContextualTupleKeys{
+		TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "owner", "user:bob"),
+		},
+	})
+	require.Error(t, err)
+}
+
+func TestConditionalTuples(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	parameters, err := structpb.NewStruct(map[string]interface{}{"cidr": "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	_, err = s.WriteWithCondition(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		}},
+	}, &storage.TupleCondition{Expression: "inCIDR(context.ip, params.cidr)", Parameters: parameters})
+	require.NoError(t, err)
+
+	_, err = s.WriteWithCondition(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+		}},
+	}, &storage.TupleCondition{Expression: "context.ip =="})
+	require.ErrorContains(t, err, "invalid condition")
+
+	contextWithIP := func(ip string) context.Context {
+		requestContext, err := structpb.NewStruct(map[string]interface{}{"ip": ip})
+		require.NoError(t, err)
+		return condition.NewContext(ctx, requestContext)
+	}
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+	}
+
+	checkResp, err := s.Check(contextWithIP("10.1.2.3"), checkReq)
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+
+	checkResp, err = s.Check(contextWithIP("192.168.0.1"), checkReq)
+	require.NoError(t, err)
+	require.False(t, checkResp.GetAllowed())
+
+	// the condition can't be evaluated without the ip
+	_, err = s.Check(ctx, checkReq)
+	require.Equal(t, codes.Code(openfgav1.ErrorCode_validation_error), status.Code(err))
+
+	listObjectsReq := &openfgav1.ListObjectsRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Type:                 "document",
+		Relation:             "viewer",
+		User:                 "user:anne",
+	}
+
+	listObjectsResp, err := s.ListObjects(contextWithIP("10.1.2.3"), listObjectsReq)
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:1"}, listObjectsResp.GetObjects())
+
+	listObjectsResp, err = s.ListObjects(contextWithIP("192.168.0.1"), listObjectsReq)
+	require.NoError(t, err)
+	require.Empty(t, listObjectsResp.GetObjects())
+}
+
+func TestAnalyzeAuthorizationModelImpact(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type team
+		  relations
+		    define member: [user] as self
+
+		type document
+		  relations
+		    define editor: [user] as self
+		    define viewer: [user, team#member] as self or editor
+		`),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "user:anne"),
+			tuple.NewTupleKey("document:1", "viewer", "team:eng#member"),
+			tuple.NewTupleKey("document:2", "viewer", "user:bob"),
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Assertions: []*openfgav1.Assertion{
+			{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:anne"), Expectation: true},
+			{TupleKey: tuple.NewTupleKey("document:2", "viewer", "user:bob"), Expectation: true},
+		},
+	})
+	require.NoError(t, err)
+
+	// viewers can no longer be teams, and editors are no longer viewers
+	resp, err := s.AnalyzeAuthorizationModelImpact(ctx, &commands.AuthorizationModelImpactRequest{
+		StoreID:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type team
+		  relations
+		    define member: [user] as self
+
+		type document
+		  relations
+		    define editor: [user] as self
+		    define viewer: [user] as self
+		`),
+		IncludeAssertions: true,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, resp.TuplesScanned)
+	require.Equal(t, 1, resp.InvalidTupleCount)
+	require.Equal(t, "team:eng#member", resp.InvalidTuples[0].TupleKey.GetUser())
+
+	require.Equal(t, 2, resp.AssertionsEvaluated)
+	require.Len(t, resp.FlippedAssertions, 1)
+	require.Equal(t, "user:anne", resp.FlippedAssertions[0].TupleKey.GetUser())
+	require.True(t, resp.FlippedAssertions[0].CurrentAllowed)
+	require.False(t, resp.FlippedAssertions[0].ProposedAllowed)
+
+	resp, err = s.AnalyzeAuthorizationModelImpact(ctx, &commands.AuthorizationModelImpactRequest{
+		StoreID:         storeID,
+		SchemaVersion:   typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`type document`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, resp.InvalidTupleCount)
+	require.Empty(t, resp.FlippedAssertions)
+}
+
+func TestRunAssertions(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	writeModelResp, err := s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define editor: [user] as self
+		    define viewer: [user] as self or editor
+		`),
+	})
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "editor", "user:anne"),
+		}},
+	})
+	require.NoError(t, err)
+
+	_, err = s.WriteAssertions(ctx, &openfgav1.WriteAssertionsRequest{
+		StoreId:              storeID,
+		AuthorizationModelId: modelID,
+		Assertions: []*openfgav1.Assertion{
+			{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:anne"), Expectation: true},
+			{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:anne"), Expectation: false},
+			{TupleKey: tuple.NewTupleKey("document:1", "viewer", "user:bob"), Expectation: true},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := s.RunAssertions(ctx, &commands.RunAssertionsRequest{StoreID: storeID})
+	require.NoError(t, err)
+	require.False(t, resp.Passed)
+	require.Equal(t, 1, resp.PassedCount)
+	require.Equal(t, 2, resp.FailedCount)
+	require.Len(t, resp.Results, 3)
+
+	require.True(t, resp.Results[0].Passed)
+	require.Nil(t, resp.Results[0].Explanation)
+
+	// the failed assertion expecting a denial explains why the user is allowed
+	require.False(t, resp.Results[1].Passed)
+	require.True(t, resp.Results[1].Allowed)
+	require.NotNil(t, resp.Results[1].Explanation)
+	require.Equal(t, graph.ExplanationUnion, resp.Results[1].Explanation.Operation)
+
+	require.False(t, resp.Results[2].Passed)
+	require.False(t, resp.Results[2].Allowed)
+}
+
+func TestAuthorizationModelAnnotations(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	observerLogger, logs := observer.New(zap.WarnLevel)
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+	)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	req := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type document
+		  relations
+		    define reader: [user] as self
+		    define viewer: [user] as self or reader
+		`),
+	}
+
+	annotations := storage.ModelAnnotations{
+		"document": {
+			Annotations: map[string]string{typesystem.OwnerAnnotation: "docs-team"},
+			Relations: map[string]map[string]string{
+				"reader": {typesystem.DeprecatedAnnotation: "use viewer instead"},
+			},
+		},
+	}
+
+	_, err = s.WriteAuthorizationModelWithAnnotations(ctx, req, storage.ModelAnnotations{
+		"folder": {Annotations: map[string]string{typesystem.OwnerAnnotation: "docs-team"}},
+	})
+	require.ErrorContains(t, err, typesystem.ErrInvalidAnnotations.Error())
+
+	writeModelResp, err := s.WriteAuthorizationModelWithAnnotations(ctx, req, annotations)
+	require.NoError(t, err)
+	modelID := writeModelResp.GetAuthorizationModelId()
+
+	got, err := s.ReadAuthorizationModelAnnotations(ctx, storeID, modelID)
+	require.NoError(t, err)
+	require.Equal(t, annotations, got)
+
+	got, err = s.ReadAuthorizationModelAnnotations(ctx, storeID, "")
+	require.NoError(t, err)
+	require.Equal(t, annotations, got)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		}},
+	})
+	require.NoError(t, err)
+	require.Zero(t, logs.FilterMessage("writing tuples to a deprecated relation").Len())
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("document:1", "reader", "user:anne"),
+			tuple.NewTupleKey("document:2", "reader", "user:anne"),
+		}},
+	})
+	require.NoError(t, err)
+
+	warnings := logs.FilterMessage("writing tuples to a deprecated relation").All()
+	require.Len(t, warnings, 1)
+	require.Equal(t, "document#reader", warnings[0].ContextMap()["relation"])
+	require.Equal(t, "use viewer instead", warnings[0].ContextMap()["deprecation"])
+}
+
+func TestWriteAuthorizationModelWithModules(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	users, err := typesystem.ParseModule("users", `type user`)
+	require.NoError(t, err)
+
+	rbacCore, err := typesystem.ParseModule("rbac-core", `
+	include users
+
+	type role
+	  relations
+	    define assignee: [user] as self
+	`)
+	require.NoError(t, err)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithModelModules(users, rbacCore),
+	)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	req := &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type document
+		  relations
+		    define viewer: [user, role#assignee] as self
+		`),
+	}
+
+	// the model references types it does not define without the modules
+	_, err = s.WriteAuthorizationModel(ctx, req)
+	require.Error(t, err)
+
+	includesCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(ModelIncludesHeader, "rbac-core"))
+
+	writeModelResp, err := s.WriteAuthorizationModel(includesCtx, req)
+	require.NoError(t, err)
+	require.Len(t, req.GetTypeDefinitions(), 1)
+
+	readModelResp, err := s.ReadAuthorizationModel(ctx, &openfgav1.ReadAuthorizationModelRequest{
+		StoreId: storeID,
+		Id:      writeModelResp.GetAuthorizationModelId(),
+	})
+	require.NoError(t, err)
+	require.Len(t, readModelResp.GetAuthorizationModel().GetTypeDefinitions(), 3)
+
+	annotations, err := s.ReadAuthorizationModelAnnotations(ctx, storeID, writeModelResp.GetAuthorizationModelId())
+	require.NoError(t, err)
+	require.Equal(t, storage.ModelAnnotations{
+		"user": {Annotations: map[string]string{typesystem.ModuleAnnotation: "users"}},
+		"role": {Annotations: map[string]string{typesystem.ModuleAnnotation: "rbac-core"}},
+	}, annotations)
+
+	t.Run("with_annotations", func(t *testing.T) {
+		writeModelResp, err := s.WriteAuthorizationModelWithAnnotations(includesCtx, req, storage.ModelAnnotations{
+			"role":     {Annotations: map[string]string{typesystem.OwnerAnnotation: "iam-team", typesystem.ModuleAnnotation: "other"}},
+			"document": {Annotations: map[string]string{typesystem.OwnerAnnotation: "docs-team"}},
+		})
+		require.NoError(t, err)
+
+		annotations, err := s.ReadAuthorizationModelAnnotations(ctx, storeID, writeModelResp.GetAuthorizationModelId())
+		require.NoError(t, err)
+		require.Equal(t, storage.ModelAnnotations{
+			"user":     {Annotations: map[string]string{typesystem.ModuleAnnotation: "users"}},
+			"role":     {Annotations: map[string]string{typesystem.OwnerAnnotation: "iam-team", typesystem.ModuleAnnotation: "rbac-core"}},
+			"document": {Annotations: map[string]string{typesystem.OwnerAnnotation: "docs-team"}},
+		}, annotations)
+	})
+
+	t.Run("undefined_module", func(t *testing.T) {
+		_, err := s.WriteAuthorizationModel(metadata.NewIncomingContext(ctx, metadata.Pairs(ModelIncludesHeader, "rbac-core, billing")), req)
+		require.ErrorContains(t, err, "the module 'billing' is not defined")
+	})
+
+	t.Run("schema_1_0", func(t *testing.T) {
+		_, err := s.WriteAuthorizationModel(includesCtx, &openfgav1.WriteAuthorizationModelRequest{
+			StoreId:       storeID,
+			SchemaVersion: typesystem.SchemaVersion1_0,
+		})
+		require.ErrorContains(t, err, "only the models of schema version 1.1 can include modules")
+	})
+}
+
+func TestSetTunablesAtRuntime(t *testing.T) {
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithListObjectsDeadline(time.Second),
+		WithCheckQueryCacheEnabled(true),
+	)
+	t.Cleanup(s.Close)
+
+	require.Equal(t, time.Second, time.Duration(s.listObjectsDeadline.Load()))
+
+	s.SetListObjectsDeadline(5 * time.Second)
+	require.Equal(t, 5*time.Second, time.Duration(s.listObjectsDeadline.Load()))
+
+	s.SetCheckQueryCacheLimit(10)
+
+	// the check cache limit is ignored if the check cache is disabled
+	s = MustNewServerWithOpts(WithDatastore(ds))
+	t.Cleanup(s.Close)
+
+	s.SetCheckQueryCacheLimit(10)
+}
+
+func TestRequestFeatureFlags(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithResolveNodeLimit(2),
+		WithFlaggedResolveNodeLimit(10),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type folder
+		  relations
+		    define parent: [folder] as self
+		    define viewer: [user] as self or viewer from parent
+		`),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:a", "viewer", "user:anne"),
+			tuple.NewTupleKey("folder:b", "parent", "folder:a"),
+			tuple.NewTupleKey("folder:c", "parent", "folder:b"),
+			tuple.NewTupleKey("folder:d", "parent", "folder:c"),
+		}},
+	})
+	require.NoError(t, err)
+
+	checkReq := &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewTupleKey("folder:d", "viewer", "user:anne"),
+	}
+
+	_, err = s.Check(ctx, checkReq)
+	require.ErrorIs(t, err, serverErrors.AuthorizationModelResolutionTooComplex)
+
+	flaggedCtx := featureflags.ContextWithFlags(ctx, featureflags.HigherResolveNodeLimit, featureflags.BypassCheckCache)
+	checkResp, err := s.Check(flaggedCtx, checkReq)
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+
+	require.Nil(t, s.listObjectsPlannerForRequest(ctx))
+	require.NotNil(t, s.listObjectsPlannerForRequest(featureflags.ContextWithFlags(ctx, featureflags.ListObjectsPlanner)))
+}
+
+func TestShadowEvaluation(t *testing.T) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	// every evaluation is reported as a slowdown, which tells the evaluations that ran from those that failed
+	observerLogger, logs := observer.New(zap.InfoLevel)
+	s := MustNewServerWithOpts(
+		WithDatastore(ds),
+		WithShadowEvaluator(commands.NewShadowEvaluator(
+			commands.WithShadowLogger(&logger.ZapLogger{Logger: zap.New(observerLogger)}),
+			commands.WithShadowSlowdownThreshold(math.SmallestNonzeroFloat64),
+		)),
+		WithShadowCheck(),
+		WithShadowListObjects(commands.WithListObjectsStrategy(planner.ConcurrentChecks)),
+	)
+	t.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(t, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type folder
+		  relations
+		    define parent: [folder] as self
+		    define viewer: [user] as self or viewer from parent
+		`),
+	})
+	require.NoError(t, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:a", "viewer", "user:anne"),
+			tuple.NewTupleKey("folder:b", "parent", "folder:a"),
+		}},
+	})
+	require.NoError(t, err)
+
+	checkResp, err := s.Check(ctx, &openfgav1.CheckRequest{
+		StoreId:  storeID,
+		TupleKey: tuple.NewTupleKey("folder:c", "viewer", "user:anne"),
+		ContextualTuples: &openfgav1.ContextualTupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:c", "parent", "folder:b"),
+		}},
+	})
+	require.NoError(t, err)
+	require.True(t, checkResp.GetAllowed())
+
+	listObjectsResp, err := s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "folder",
+		Relation: "viewer",
+		User:     "user:anne",
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"folder:a", "folder:b"}, listObjectsResp.GetObjects())
+
+	require.Eventually(t, func() bool {
+		slowdowns := logs.FilterMessage("the experimental resolver was slower than the current resolver")
+		return slowdowns.FilterField(zap.String("method", "Check")).Len() == 1 &&
+			slowdowns.FilterField(zap.String("method", "ListObjects")).Len() == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Zero(t, logs.FilterMessage("the experimental resolver diverged from the current resolver").Len())
+	require.Zero(t, logs.FilterMessage("the experimental resolver failed").Len())
+}
+
+func FuzzCheck(f *testing.F) {
+	ctx := context.Background()
+
+	ds := memory.New()
+	f.Cleanup(ds.Close)
+
+	s := MustNewServerWithOpts(WithDatastore(ds))
+	f.Cleanup(s.Close)
+
+	createStoreResp, err := s.CreateStore(ctx, &openfgav1.CreateStoreRequest{Name: "openfga-test"})
+	require.NoError(f, err)
+	storeID := createStoreResp.GetId()
+
+	_, err = s.WriteAuthorizationModel(ctx, &openfgav1.WriteAuthorizationModelRequest{
+		StoreId:       storeID,
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type group
+		  relations
+		    define member: [user, user:*, group#member] as self
+
+		type folder
+		  relations
+		    define parent: [folder] as self
+		    define viewer: [user, group#member] as self or viewer from parent
+		`),
+	})
+	require.NoError(f, err)
+
+	_, err = s.Write(ctx, &openfgav1.WriteRequest{
+		StoreId: storeID,
+		Writes: &openfgav1.TupleKeys{TupleKeys: []*openfgav1.TupleKey{
+			tuple.NewTupleKey("folder:a", "viewer", "group:eng#member"),
+			tuple.NewTupleKey("folder:b", "parent", "folder:a"),
+			tuple.NewTupleKey("group:eng", "member", "user:*"),
+		}},
+	})
+	require.NoError(f, err)
+
+	for _, tk := range []*openfgav1.TupleKey{
+		tuple.NewTupleKey("folder:b", "viewer", "user:anne"),
+		tuple.NewTupleKey("folder:b", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("folder:*", "viewer", "user:*"),
+		tuple.NewTupleKey("folder#b:viewer", "parent", "folder:"),
+		tuple.NewTupleKey(":b", "viewer", "user"),
+		tuple.NewTupleKey("folder:b#viewer", "viewer#", "group:#member"),
+	} {
+		f.Add(tk.GetObject(), tk.GetRelation(), tk.GetUser())
+	}
+
+	f.Fuzz(func(t *testing.T, object, relation, user string) {
+		// the malformed requests are errors, not panics
+		tk := tuple.NewTupleKey(object, relation, user)
+		_, _ = s.Check(ctx, &openfgav1.CheckRequest{
+			StoreId:  storeID,
+			TupleKey: tuple.NewTupleKey("folder:b", "viewer", user),
+			ContextualTuples: &openfgav1.ContextualTupleKeys{TupleKeys: []*openfgav1.TupleKey{
+				tk,
+			}},
+		})
+		_, _ = s.Check(ctx, &openfgav1.CheckRequest{StoreId: storeID, TupleKey: tk})
+		_, _ = s.ListObjects(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     tuple.GetType(object),
+			Relation: relation,
+			User:     user,
+		})
+		_, _ = s.Expand(ctx, &openfgav1.ExpandRequest{StoreId: storeID, TupleKey: tuple.NewTupleKey(object, relation, "")})
+		_, _ = s.Read(ctx, &openfgav1.ReadRequest{StoreId: storeID, TupleKey: tk})
+	})
 }
@@ -54,8 +54,169 @@ func ConnectedObjectsTest(t *testing.T, ds storage.OpenFGADatastore) {
 			tuples: []*openfgav1.TupleKey{
 				tuple.NewTupleKey("document:1", "viewer", "user:jon"),
 				tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:1", "allowed", "user:jon"),
 				tuple.NewTupleKey("document:3", "allowed", "user:jon"),
 			},
+			expectedResult: []*connectedobjects.ConnectedObjectsResult{
+				{
+					Object:       "document:1",
+					ResultStatus: connectedobjects.NoFurtherEvalStatus,
+				},
+			},
+		},
+		{
+			name: "basic_exclusion",
+			request: &connectedobjects.ConnectedObjectsRequest{
+				StoreID:    ulid.Make().String(),
+				ObjectType: "document",
+				Relation:   "admin",
+				User: &connectedobjects.UserRefObject{
+					Object: &openfgav1.Object{
+						Type: "user",
+						Id:   "jon",
+					},
+				},
+				ContextualTuples: []*openfgav1.TupleKey{},
+			},
+			model: `
+				type user
+
+				type group
+				  relations
+				    define member: [user] as self
+
+				type document
+				  relations
+				    define blocked: [user, group#member] as self
+				    define admin: [user] as self but not blocked
+				`,
+			tuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "admin", "user:jon"),
+				tuple.NewTupleKey("document:2", "admin", "user:jon"),
+				tuple.NewTupleKey("document:3", "admin", "user:jon"),
+				tuple.NewTupleKey("document:2", "blocked", "user:jon"),
+				tuple.NewTupleKey("group:eng", "member", "user:jon"),
+				tuple.NewTupleKey("document:3", "blocked", "group:eng#member"),
+				tuple.NewTupleKey("document:4", "blocked", "user:jon"),
+			},
+			expectedResult: []*connectedobjects.ConnectedObjectsResult{
+				{
+					Object:       "document:1",
+					ResultStatus: connectedobjects.NoFurtherEvalStatus,
+				},
+			},
+		},
+		{
+			name: "exclusion_of_a_nested_intersection",
+			request: &connectedobjects.ConnectedObjectsRequest{
+				StoreID:    ulid.Make().String(),
+				ObjectType: "document",
+				Relation:   "viewer",
+				User: &connectedobjects.UserRefObject{
+					Object: &openfgav1.Object{
+						Type: "user",
+						Id:   "jon",
+					},
+				},
+				ContextualTuples: []*openfgav1.TupleKey{},
+			},
+			model: `
+				type user
+
+				type document
+				  relations
+				    define restricted: [user] as self
+				    define allowed: [user] as self
+				    define blocked: [user] as self and restricted
+				    define viewer: [user] as self but not blocked
+				`,
+			tuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:2", "blocked", "user:jon"),
+				tuple.NewTupleKey("document:2", "restricted", "user:jon"),
+				tuple.NewTupleKey("document:3", "blocked", "user:jon"),
+			},
+			expectedResult: []*connectedobjects.ConnectedObjectsResult{
+				{
+					Object:       "document:1",
+					ResultStatus: connectedobjects.NoFurtherEvalStatus,
+				},
+				{
+					Object:       "document:3",
+					ResultStatus: connectedobjects.NoFurtherEvalStatus,
+				},
+			},
+		},
+		{
+			name: "intersection_through_a_ttu_operand",
+			request: &connectedobjects.ConnectedObjectsRequest{
+				StoreID:    ulid.Make().String(),
+				ObjectType: "document",
+				Relation:   "viewer",
+				User: &connectedobjects.UserRefObject{
+					Object: &openfgav1.Object{
+						Type: "user",
+						Id:   "jon",
+					},
+				},
+				ContextualTuples: []*openfgav1.TupleKey{},
+			},
+			model: `
+				type user
+
+				type folder
+				  relations
+				    define viewer: [user] as self
+
+				type document
+				  relations
+				    define parent: [folder] as self
+				    define allowed: [user] as self
+				    define viewer as allowed and viewer from parent
+				`,
+			tuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "parent", "folder:X"),
+				tuple.NewTupleKey("document:2", "parent", "folder:X"),
+				tuple.NewTupleKey("folder:X", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:1", "allowed", "user:jon"),
+				tuple.NewTupleKey("document:3", "allowed", "user:jon"),
+			},
+			expectedResult: []*connectedobjects.ConnectedObjectsResult{
+				{
+					Object:       "document:1",
+					ResultStatus: connectedobjects.NoFurtherEvalStatus,
+				},
+			},
+		},
+		{
+			name: "recursive_exclusion_requires_further_evaluation",
+			request: &connectedobjects.ConnectedObjectsRequest{
+				StoreID:    ulid.Make().String(),
+				ObjectType: "document",
+				Relation:   "viewer",
+				User: &connectedobjects.UserRefObject{
+					Object: &openfgav1.Object{
+						Type: "user",
+						Id:   "jon",
+					},
+				},
+				ContextualTuples: []*openfgav1.TupleKey{},
+			},
+			model: `
+				type user
+
+				type document
+				  relations
+				    define blocked: [user] as self
+				    define viewer: [user, document#viewer] as self but not blocked
+				`,
+			tuples: []*openfgav1.TupleKey{
+				tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+				tuple.NewTupleKey("document:1", "blocked", "user:jon"),
+				tuple.NewTupleKey("document:2", "viewer", "document:1#viewer"),
+			},
 			expectedResult: []*connectedobjects.ConnectedObjectsResult{
 				{
 					Object:       "document:1",
@@ -3,8 +3,10 @@ package test
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
 	"github.com/google/go-cmp/cmp"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
@@ -1054,3 +1056,165 @@ func TestExpandQueryErrors(t *testing.T, datastore storage.OpenFGADatastore) {
 		})
 	}
 }
+
+func TestExpandQueryWithDepth(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type group
+		  relations
+		    define member: [user, group#member] as self
+		type folder
+		  relations
+		    define viewer: [user] as self
+		type document
+		  relations
+		    define parent: [folder] as self
+		    define editor: [user] as self
+		    define viewer: [user, group#member] as self or editor or viewer from parent
+		`),
+	}
+	err := datastore.WriteAuthorizationModel(ctx, store, model)
+	require.NoError(t, err)
+
+	err = datastore.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "editor", "user:ann"),
+		tuple.NewTupleKey("document:1", "parent", "folder:x"),
+		tuple.NewTupleKey("folder:x", "viewer", "user:bob"),
+		tuple.NewTupleKey("group:eng", "member", "user:maria"),
+		tuple.NewTupleKey("group:eng", "member", "group:eng#member"),
+	})
+	require.NoError(t, err)
+
+	// leafUsers returns the users of the users leaves of the tree
+	var leafUsers func(node *openfgav1.UsersetTree_Node) []string
+	leafUsers = func(node *openfgav1.UsersetTree_Node) []string {
+		var users []string
+		for _, child := range node.GetUnion().GetNodes() {
+			users = append(users, leafUsers(child)...)
+		}
+		return append(users, node.GetLeaf().GetUsers().GetUsers()...)
+	}
+
+	tests := []struct {
+		depth    uint32
+		expected []string
+	}{
+		{
+			depth:    1,
+			expected: []string{"user:jon", "group:eng#member"},
+		},
+		{
+			depth:    2,
+			expected: []string{"user:jon", "user:ann", "user:bob", "user:maria", "group:eng#member"},
+		},
+		{
+			// group:eng#member is not expanded again within its own expansion
+			depth:    5,
+			expected: []string{"user:jon", "user:ann", "user:bob", "user:maria", "group:eng#member"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("depth_%d", test.depth), func(t *testing.T) {
+			query := commands.NewExpandQuery(datastore, logger.NewNoopLogger(), commands.WithExpandDepth(test.depth))
+			resp, err := query.Execute(ctx, &openfgav1.ExpandRequest{
+				StoreId:              store,
+				AuthorizationModelId: model.Id,
+				TupleKey:             tuple.NewTupleKey("document:1", "viewer", ""),
+			})
+			require.NoError(t, err)
+
+			root := resp.GetTree().GetRoot()
+			require.Equal(t, "document:1#viewer", root.GetName())
+			require.ElementsMatch(t, test.expected, leafUsers(root))
+		})
+	}
+}
+
+// readCountingDatastore counts the Read calls made to the datastore.
+type readCountingDatastore struct {
+	storage.OpenFGADatastore
+	reads atomic.Int32
+}
+
+func (d *readCountingDatastore) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	d.reads.Add(1)
+	return d.OpenFGADatastore.Read(ctx, store, tk)
+}
+
+func TestExpandQueryMemoizesUsersets(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type group
+		  relations
+		    define member: [user, group#member] as self
+		type document
+		  relations
+		    define viewer: [group#member] as self
+		`),
+	}
+	err := datastore.WriteAuthorizationModel(ctx, store, model)
+	require.NoError(t, err)
+
+	// every group:g<i> reaches group:g<i+1> through both group:a<i> and group:b<i>, so that group:g<levels> is
+	// referenced by 2^levels paths
+	const levels = 4
+
+	tuples := []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "group:g0#member")}
+	for i := 0; i < levels; i++ {
+		next := fmt.Sprintf("group:g%d#member", i+1)
+		tuples = append(tuples,
+			tuple.NewTupleKey(fmt.Sprintf("group:g%d", i), "member", fmt.Sprintf("group:a%d#member", i)),
+			tuple.NewTupleKey(fmt.Sprintf("group:g%d", i), "member", fmt.Sprintf("group:b%d#member", i)),
+			tuple.NewTupleKey(fmt.Sprintf("group:a%d", i), "member", next),
+			tuple.NewTupleKey(fmt.Sprintf("group:b%d", i), "member", next),
+		)
+	}
+	tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("group:g%d", levels), "member", "user:maria"))
+
+	err = datastore.Write(ctx, store, nil, tuples)
+	require.NoError(t, err)
+
+	counting := &readCountingDatastore{OpenFGADatastore: datastore}
+
+	query := commands.NewExpandQuery(counting, logger.NewNoopLogger(), commands.WithExpandDepth(3*levels+2))
+	resp, err := query.Execute(ctx, &openfgav1.ExpandRequest{
+		StoreId:              store,
+		AuthorizationModelId: model.Id,
+		TupleKey:             tuple.NewTupleKey("document:1", "viewer", ""),
+	})
+	require.NoError(t, err)
+
+	// countUsers returns the number of times the user appears in the users leaves of the tree
+	var countUsers func(node *openfgav1.UsersetTree_Node, user string) int
+	countUsers = func(node *openfgav1.UsersetTree_Node, user string) int {
+		count := 0
+		for _, child := range node.GetUnion().GetNodes() {
+			count += countUsers(child, user)
+		}
+		for _, u := range node.GetLeaf().GetUsers().GetUsers() {
+			if u == user {
+				count++
+			}
+		}
+		return count
+	}
+
+	// group:g<levels> is expanded along every path, but its users are read once, like those of every other userset
+	require.Equal(t, 1<<levels, countUsers(resp.GetTree().GetRoot(), "user:maria"))
+	require.Equal(t, int32(1+3*levels+1), counting.reads.Load())
+}
@@ -10,8 +10,11 @@ import (
 	parser "github.com/craigpastro/openfga-dsl-parser/v2"
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/internal/graph"
 	"github.com/openfga/openfga/internal/mocks"
 	"github.com/openfga/openfga/pkg/server/commands"
+	"github.com/openfga/openfga/pkg/server/commands/planner"
+	serverErrors "github.com/openfga/openfga/pkg/server/errors"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
@@ -255,6 +258,348 @@ func TestListObjectsRespectsMaxResults(t *testing.T, ds storage.OpenFGADatastore
 	}
 }
 
+func TestListObjectsDeadlineExceeded(t *testing.T, ds storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	err = ds.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	})
+	require.NoError(t, err)
+
+	datastore := mocks.NewMockSlowDataStorage(ds, 200*time.Millisecond)
+	req := &openfgav1.ListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	}
+
+	t.Run("partial_results", func(t *testing.T) {
+		stats := &graph.ResolutionStats{}
+		ctx := graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesystem.New(model)), stats)
+
+		q := commands.NewListObjectsQuery(datastore, commands.WithListObjectsDeadline(10*time.Millisecond))
+
+		res, err := q.Execute(ctx, req)
+		require.NoError(t, err)
+		require.Empty(t, res.GetObjects())
+		require.True(t, stats.Incomplete())
+
+		page, err := q.ExecutePage(ctx, &commands.ListObjectsPageRequest{Request: req})
+		require.NoError(t, err)
+		require.True(t, page.Incomplete)
+		require.Empty(t, page.ContinuationToken)
+
+		// an incomplete page can't continue a previous page, since it could skip the objects missing from it
+		first, err := commands.NewListObjectsQuery(ds).ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request:  req,
+			PageSize: 1,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, first.ContinuationToken)
+
+		_, err = q.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request:           req,
+			ContinuationToken: first.ContinuationToken,
+		})
+		require.ErrorIs(t, err, serverErrors.RequestDeadlineExceeded)
+	})
+
+	t.Run("deadline_exceeded_error", func(t *testing.T) {
+		stats := &graph.ResolutionStats{}
+		ctx := graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesystem.New(model)), stats)
+
+		q := commands.NewListObjectsQuery(datastore,
+			commands.WithListObjectsDeadline(10*time.Millisecond),
+			commands.WithListObjectsPartialResults(false),
+		)
+
+		_, err := q.Execute(ctx, req)
+		require.ErrorIs(t, err, serverErrors.RequestDeadlineExceeded)
+
+		err = q.ExecuteStreamed(ctx, &openfgav1.StreamedListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		}, &mockStreamServer{channel: make(chan string, 1)})
+		require.ErrorIs(t, err, serverErrors.RequestDeadlineExceeded)
+		require.False(t, stats.Incomplete())
+	})
+
+	t.Run("complete_results", func(t *testing.T) {
+		stats := &graph.ResolutionStats{}
+		ctx := graph.ContextWithResolutionStats(typesystem.ContextWithTypesystem(ctx, typesystem.New(model)), stats)
+
+		res, err := commands.NewListObjectsQuery(ds).Execute(ctx, req)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"document:1", "document:2"}, res.GetObjects())
+		require.False(t, stats.Incomplete())
+	})
+}
+
+func TestStreamedListObjectsSlowClient(t *testing.T, ds storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type document
+		  relations
+		    define viewer: [user] as self
+		`),
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	var tuples []*openfgav1.TupleKey
+	for i := 0; i < 10; i++ {
+		tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("document:%d", i), "viewer", "user:jon"))
+	}
+	err = ds.Write(ctx, storeID, nil, tuples)
+	require.NoError(t, err)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+	req := &openfgav1.StreamedListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	}
+
+	t.Run("send_timeout", func(t *testing.T) {
+		q := commands.NewListObjectsQuery(ds,
+			commands.WithListObjectsDeadline(time.Minute),
+			commands.WithListObjectsStreamBufferSize(1),
+			commands.WithListObjectsStreamSendTimeout(20*time.Millisecond),
+		)
+
+		// nobody receives from the channel, so the first send blocks
+		server := &mockStreamServer{channel: make(chan string)}
+
+		start := time.Now()
+		err := q.ExecuteStreamed(ctx, req, server)
+		require.ErrorIs(t, err, serverErrors.StreamSendTimeout)
+		require.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("small_buffer", func(t *testing.T) {
+		q := commands.NewListObjectsQuery(ds, commands.WithListObjectsStreamBufferSize(1))
+
+		server := &mockStreamServer{channel: make(chan string, len(tuples))}
+
+		err := q.ExecuteStreamed(ctx, req, server)
+		require.NoError(t, err)
+		close(server.channel)
+
+		var objects []string
+		for object := range server.channel {
+			objects = append(objects, object)
+		}
+		require.Len(t, objects, len(tuples))
+	})
+}
+
+func TestListObjectsMaxConcurrentChecks(t *testing.T, ds storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type document
+		  relations
+		    define allowed: [user] as self
+		    define viewer: [user] as self and allowed
+		`),
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	var tuples []*openfgav1.TupleKey
+	var expected []string
+	for i := 0; i < 10; i++ {
+		object := fmt.Sprintf("document:%d", i)
+		tuples = append(tuples, tuple.NewTupleKey(object, "viewer", "user:jon"))
+		if i%2 == 0 {
+			tuples = append(tuples, tuple.NewTupleKey(object, "allowed", "user:jon"))
+			expected = append(expected, object)
+		}
+	}
+	err = ds.Write(ctx, storeID, nil, tuples)
+	require.NoError(t, err)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+
+	for _, maxConcurrentChecks := range []uint32{0, 1, 3} {
+		q := commands.NewListObjectsQuery(ds,
+			commands.WithListObjectsMaxConcurrentChecks(maxConcurrentChecks),
+			commands.WithResolveNodeBreadthLimit(1),
+		)
+
+		res, err := q.Execute(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:jon",
+		})
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, res.GetObjects())
+	}
+}
+
+func TestListObjectsPagination(t *testing.T, ds storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+		type document
+		  relations
+		    define blocked: [user] as self
+		    define viewer: [user] as self but not blocked
+		`),
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	var tuples []*openfgav1.TupleKey
+	var allResults []string
+	for i := 0; i < 7; i++ {
+		object := fmt.Sprintf("document:%d", i)
+		tuples = append(tuples, tuple.NewTupleKey(object, "viewer", "user:jon"))
+		allResults = append(allResults, object)
+	}
+	err = ds.Write(ctx, storeID, nil, tuples)
+	require.NoError(t, err)
+
+	ctx = typesystem.ContextWithTypesystem(ctx, typesystem.New(model))
+
+	listObjectsQuery := commands.NewListObjectsQuery(ds, commands.WithListObjectsMaxResults(5))
+
+	request := &openfgav1.ListObjectsRequest{
+		StoreId:  storeID,
+		Type:     "document",
+		Relation: "viewer",
+		User:     "user:jon",
+	}
+
+	t.Run("pages_are_sorted_and_cover_every_object", func(t *testing.T) {
+		var objects []string
+		var contToken string
+		for i := 0; ; i++ {
+			require.Less(t, i, len(allResults))
+
+			res, err := listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+				Request:           request,
+				PageSize:          3,
+				ContinuationToken: contToken,
+			})
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(res.Objects), 3)
+			require.IsIncreasing(t, res.Objects)
+
+			objects = append(objects, res.Objects...)
+
+			contToken = res.ContinuationToken
+			if contToken == "" {
+				break
+			}
+		}
+
+		require.Equal(t, allResults, objects)
+	})
+
+	t.Run("page_size_is_capped_by_max_results", func(t *testing.T) {
+		res, err := listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request:  request,
+			PageSize: 100,
+		})
+		require.NoError(t, err)
+		require.Equal(t, allResults[:5], res.Objects)
+		require.NotEmpty(t, res.ContinuationToken)
+	})
+
+	t.Run("execute_returns_the_first_objects_when_sorted_by_object_id", func(t *testing.T) {
+		sortedQuery := commands.NewListObjectsQuery(ds,
+			commands.WithListObjectsMaxResults(5),
+			commands.WithListObjectsSortOrder(commands.ListObjectsSortedByObjectID),
+		)
+
+		res, err := sortedQuery.Execute(ctx, request)
+		require.NoError(t, err)
+		require.Equal(t, allResults[:5], res.Objects)
+	})
+
+	t.Run("continuation_token_of_another_request_is_rejected", func(t *testing.T) {
+		res, err := listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request:  request,
+			PageSize: 1,
+		})
+		require.NoError(t, err)
+
+		_, err = listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request: &openfgav1.ListObjectsRequest{
+				StoreId:  storeID,
+				Type:     "document",
+				Relation: "viewer",
+				User:     "user:maria",
+			},
+			ContinuationToken: res.ContinuationToken,
+		})
+		require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+
+		_, err = listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request: &openfgav1.ListObjectsRequest{
+				StoreId:              storeID,
+				AuthorizationModelId: ulid.Make().String(),
+				Type:                 "document",
+				Relation:             "viewer",
+				User:                 "user:jon",
+			},
+			ContinuationToken: res.ContinuationToken,
+		})
+		require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+
+		_, err = listObjectsQuery.ExecutePage(ctx, &commands.ListObjectsPageRequest{
+			Request: &openfgav1.ListObjectsRequest{
+				StoreId:  storeID,
+				Type:     "document",
+				Relation: "viewer",
+				User:     "user:jon",
+				ContextualTuples: &openfgav1.ContextualTupleKeys{
+					TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "blocked", "user:jon")},
+				},
+			},
+			ContinuationToken: res.ContinuationToken,
+		})
+		require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
+	})
+}
+
 // Used to avoid compiler optimizations (see https://dave.cheney.net/2013/06/30/how-to-write-benchmarks-in-go)
 var listObjectsResponse *openfgav1.ListObjectsResponse //nolint
 
@@ -388,3 +733,87 @@ func BenchmarkListObjectsWithConcurrentChecks(b *testing.B, ds storage.OpenFGADa
 
 	listObjectsResponse = r
 }
+
+func TestListObjectsWithPlanner(t *testing.T, ds storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: typesystem.SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(`
+		type user
+
+		type group
+		  relations
+		    define member: [user] as self
+
+		type document
+		  relations
+		    define blocked: [user] as self
+		    define viewer: [user, group#member] as self but not blocked
+		`),
+	}
+	err := ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	// few documents, and users that are members of many groups
+	var tuples []*openfgav1.TupleKey
+	for i := 0; i < 20; i++ {
+		for g := 0; g < 10; g++ {
+			tuples = append(tuples, tuple.NewTupleKey(fmt.Sprintf("group:%d", g), "member", fmt.Sprintf("user:%d", i)))
+		}
+	}
+	tuples = append(tuples,
+		tuple.NewTupleKey("document:1", "viewer", "group:0#member"),
+		tuple.NewTupleKey("document:2", "viewer", "group:1#member"),
+		tuple.NewTupleKey("document:2", "blocked", "user:1"),
+		tuple.NewTupleKey("document:3", "viewer", "user:2"),
+	)
+	err = ds.Write(ctx, storeID, nil, tuples)
+	require.NoError(t, err)
+
+	typesys := typesystem.New(model)
+	ctx = typesystem.ContextWithTypesystem(ctx, typesys)
+
+	p := planner.New(ds)
+	_, err = p.Statistics(ctx, storeID)
+	require.NoError(t, err)
+
+	strategy := p.Plan(ctx, typesys, storeID, "document", "viewer", typesystem.DirectRelationReference("user", ""))
+	require.Equal(t, planner.ConcurrentChecks, strategy)
+
+	listObjectsQuery := commands.NewListObjectsQuery(ds,
+		commands.WithListObjectsPlanner(p),
+		commands.WithListObjectsSortOrder(commands.ListObjectsSortedByObjectID),
+	)
+
+	for user, expected := range map[string][]string{
+		"user:1": {"document:1"},
+		"user:2": {"document:1", "document:2", "document:3"},
+		"user:x": {},
+	} {
+		res, err := listObjectsQuery.Execute(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, res.Objects, user)
+	}
+
+	t.Run("contextual_tuples_are_listed", func(t *testing.T) {
+		res, err := listObjectsQuery.Execute(ctx, &openfgav1.ListObjectsRequest{
+			StoreId:  storeID,
+			Type:     "document",
+			Relation: "viewer",
+			User:     "user:x",
+			ContextualTuples: &openfgav1.ContextualTupleKeys{
+				TupleKeys: []*openfgav1.TupleKey{tuple.NewTupleKey("document:4", "viewer", "user:x")},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"document:4"}, res.Objects)
+	})
+}
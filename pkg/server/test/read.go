@@ -637,3 +637,62 @@ func ReadAllTuplesInvalidContinuationTokenTest(t *testing.T, datastore storage.O
 	})
 	require.ErrorIs(t, err, serverErrors.InvalidContinuationToken)
 }
+
+type mockStreamedReadServer struct {
+	ctx    context.Context
+	tuples []*openfgav1.Tuple
+}
+
+func (m *mockStreamedReadServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockStreamedReadServer) Send(tuple *openfgav1.Tuple) error {
+	m.tuples = append(m.tuples, tuple)
+	return nil
+}
+
+func StreamedReadAllTuplesTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	logger := logger.NewNoopLogger()
+	store := ulid.Make().String()
+
+	writes := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("repo:openfga/foo", "admin", "github|jon.allie"),
+		tuple.NewTupleKey("repo:openfga/bar", "admin", "github|jon.allie"),
+		tuple.NewTupleKey("repo:openfga/baz", "writer", "github|jon.allie"),
+	}
+	err := datastore.Write(ctx, store, nil, writes)
+	require.NoError(t, err)
+
+	cmd := commands.NewReadQuery(datastore, logger, encoder.NewBase64Encoder())
+
+	srv := &mockStreamedReadServer{ctx: ctx}
+	err = cmd.ExecuteStreamed(ctx, &openfgav1.ReadRequest{
+		StoreId:  store,
+		PageSize: wrapperspb.Int32(1),
+	}, srv)
+	require.NoError(t, err)
+
+	var receivedTuples []*openfgav1.TupleKey
+	for _, tuple := range srv.tuples {
+		receivedTuples = append(receivedTuples, tuple.Key)
+	}
+
+	cmpOpts := []cmp.Option{
+		cmpopts.IgnoreUnexported(openfgav1.TupleKey{}),
+		cmpopts.SortSlices(func(a, b *openfgav1.TupleKey) bool { return a.GetObject() < b.GetObject() }),
+	}
+
+	if diff := cmp.Diff(writes, receivedTuples, cmpOpts...); diff != "" {
+		t.Errorf("Tuple mismatch (-want +got):\n%s", diff)
+	}
+
+	srv = &mockStreamedReadServer{ctx: ctx}
+	err = cmd.ExecuteStreamed(ctx, &openfgav1.ReadRequest{
+		StoreId:  store,
+		TupleKey: tuple.NewTupleKey("repo:", "admin", ""),
+	}, srv)
+	require.Error(t, err)
+	require.Empty(t, srv.tuples)
+}
@@ -17,6 +17,8 @@ func RunQueryTests(t *testing.T, ds storage.OpenFGADatastore) {
 	t.Run("TestReadAuthorizationModel", func(t *testing.T) { ReadAuthorizationModelTest(t, ds) })
 	t.Run("TestExpandQuery", func(t *testing.T) { TestExpandQuery(t, ds) })
 	t.Run("TestExpandQueryErrors", func(t *testing.T) { TestExpandQueryErrors(t, ds) })
+	t.Run("TestExpandQueryWithDepth", func(t *testing.T) { TestExpandQueryWithDepth(t, ds) })
+	t.Run("TestExpandQueryMemoizesUsersets", func(t *testing.T) { TestExpandQueryMemoizesUsersets(t, ds) })
 
 	t.Run("TestGetStoreQuery", func(t *testing.T) { TestGetStoreQuery(t, ds) })
 	t.Run("TestGetStoreSucceeds", func(t *testing.T) { TestGetStoreSucceeds(t, ds) })
@@ -28,6 +30,7 @@ func RunQueryTests(t *testing.T, ds storage.OpenFGADatastore) {
 	t.Run("TestReadQueryError", func(t *testing.T) { ReadQueryErrorTest(t, ds) })
 	t.Run("TestReadAllTuples", func(t *testing.T) { ReadAllTuplesTest(t, ds) })
 	t.Run("TestReadAllTuplesInvalidContinuationToken", func(t *testing.T) { ReadAllTuplesInvalidContinuationTokenTest(t, ds) })
+	t.Run("TestStreamedReadAllTuples", func(t *testing.T) { StreamedReadAllTuplesTest(t, ds) })
 
 	t.Run("TestReadAuthorizationModelsWithoutPaging",
 		func(t *testing.T) { TestReadAuthorizationModelsWithoutPaging(t, ds) },
@@ -47,6 +50,11 @@ func RunQueryTests(t *testing.T, ds storage.OpenFGADatastore) {
 	)
 
 	t.Run("TestListObjectsRespectsMaxResults", func(t *testing.T) { TestListObjectsRespectsMaxResults(t, ds) })
+	t.Run("TestListObjectsPagination", func(t *testing.T) { TestListObjectsPagination(t, ds) })
+	t.Run("TestListObjectsDeadlineExceeded", func(t *testing.T) { TestListObjectsDeadlineExceeded(t, ds) })
+	t.Run("TestStreamedListObjectsSlowClient", func(t *testing.T) { TestStreamedListObjectsSlowClient(t, ds) })
+	t.Run("TestListObjectsMaxConcurrentChecks", func(t *testing.T) { TestListObjectsMaxConcurrentChecks(t, ds) })
+	t.Run("TestListObjectsWithPlanner", func(t *testing.T) { TestListObjectsWithPlanner(t, ds) })
 	t.Run("TestConnectedObjects", func(t *testing.T) { ConnectedObjectsTest(t, ds) })
 }
 
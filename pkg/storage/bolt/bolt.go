@@ -0,0 +1,2088 @@
+// Package bolt contains an implementation of the storage interface that persists the data in a single file with the
+// embedded bbolt key/value store, for the deployments of a single server which keep their state across restarts
+// without an external database.
+//
+// The tuples of a store are keyed by object in the tuples bucket, and indexed by user and by ULID, the order in which
+// they were written, in the tuples_by_user and tuples_by_ulid buckets. bbolt serializes the writes, so each write is
+// validated and applied in a single transaction.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/logger"
+	"github.com/openfga/openfga/pkg/storage"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var tracer = otel.Tracer("openfga/pkg/storage/bolt")
+
+var (
+	// the top-level buckets
+	storesBucket        = []byte("stores")
+	storeMetadataBucket = []byte("store_metadata")
+	storeDataBucket     = []byte("store_data")
+	expirationsBucket   = []byte("expirations")
+	stagedWritesBucket  = []byte("staged_writes")
+
+	// the buckets of the data of a store, nested in its bucket of the store_data bucket
+	tuplesBucket       = []byte("tuples")
+	tuplesByUserBucket = []byte("tuples_by_user")
+	tuplesByULIDBucket = []byte("tuples_by_ulid")
+	changesBucket      = []byte("changes")
+	modelsBucket       = []byte("authorization_models")
+	annotationsBucket  = []byte("authorization_model_annotations")
+	assertionsBucket   = []byte("assertions")
+
+	// the key of the id of the pinned authorization model, in the bucket of the store
+	pinnedModelKey = []byte("pinned_authorization_model")
+)
+
+const (
+	defaultOpenTimeout = 10 * time.Second
+
+	// compactTxMaxSize is the size of the data copied by each transaction of a compaction.
+	compactTxMaxSize = 64 * 1024 * 1024
+)
+
+type Bolt struct {
+	// mu guards db, which Compact replaces. The reads and writes hold it for reading.
+	mu   sync.RWMutex
+	db   *bbolt.DB
+	path string
+
+	logger                        logger.Logger
+	openTimeout                   time.Duration
+	maxTuplesPerWrite             int
+	maxTypesPerAuthorizationModel int
+}
+
+var _ storage.OpenFGADatastore = (*Bolt)(nil)
+
+// Option defines a function to set options for the bolt datastore.
+type Option func(*Bolt)
+
+func WithMaxTuplesPerWrite(n int) Option {
+	return func(b *Bolt) { b.maxTuplesPerWrite = n }
+}
+
+func WithMaxTypesPerAuthorizationModel(n int) Option {
+	return func(b *Bolt) { b.maxTypesPerAuthorizationModel = n }
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(b *Bolt) { b.logger = l }
+}
+
+// WithOpenTimeout sets the time to wait for the lock of the datastore file, held by the process which opened it.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(b *Bolt) { b.openTimeout = timeout }
+}
+
+// New returns a datastore persisting its data in the file of the path, which is created if it does not exist. The
+// file may only be opened by one process at a time.
+func New(path string, opts ...Option) (*Bolt, error) {
+	b := &Bolt{
+		path:                          path,
+		logger:                        logger.NewNoopLogger(),
+		openTimeout:                   defaultOpenTimeout,
+		maxTuplesPerWrite:             storage.DefaultMaxTuplesPerWrite,
+		maxTypesPerAuthorizationModel: storage.DefaultMaxTypesPerAuthorizationModel,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	db, err := b.open(path)
+	if err != nil {
+		return nil, err
+	}
+	b.db = db
+
+	return b, nil
+}
+
+// open opens the file of the path, creating the top-level buckets if needed.
+func (b *Bolt) open(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: b.openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt datastore '%s': %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{storesBucket, storeMetadataBucket, storeDataBucket, expirationsBucket, stagedWritesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt datastore '%s': %w", path, err)
+	}
+
+	return db, nil
+}
+
+// view runs fn in a read-only transaction.
+func (b *Bolt) view(fn func(tx *bbolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.View(fn)
+}
+
+// update runs fn in a read-write transaction, which is committed unless fn returns an error.
+func (b *Bolt) update(fn func(tx *bbolt.Tx) error) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.db.Update(fn)
+}
+
+// Close closes the datastore file.
+func (b *Bolt) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.db.Close(); err != nil {
+		b.logger.Error("failed to close bolt datastore", zap.Error(err))
+	}
+}
+
+// IsReady reports whether the datastore file is open.
+func (b *Bolt) IsReady(ctx context.Context) (bool, error) {
+	err := b.view(func(tx *bbolt.Tx) error {
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MaxTuplesPerWrite returns the maximum number of tuples allowed in one write operation
+func (b *Bolt) MaxTuplesPerWrite() int {
+	return b.maxTuplesPerWrite
+}
+
+// MaxTypesPerAuthorizationModel returns the maximum number of types allowed in a type definition
+func (b *Bolt) MaxTypesPerAuthorizationModel() int {
+	return b.maxTypesPerAuthorizationModel
+}
+
+// Backup writes a copy of the datastore file to w, which can be opened by New. The copy is consistent, as it is
+// written from a read-only transaction, and the datastore is read and written meanwhile. It returns the number of
+// bytes written.
+func (b *Bolt) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	_, span := tracer.Start(ctx, "bolt.Backup")
+	defer span.End()
+
+	var n int64
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+
+	return n, err
+}
+
+// Compact rewrites the datastore file without its free pages, which bbolt keeps to reuse them rather than shrinking
+// the file, to reclaim the space of the deleted data. The reads and writes wait for the end of the compaction, except
+// those of the open snapshots, which keep reading the file they were taken from until they are closed.
+func (b *Bolt) Compact(ctx context.Context) error {
+	_, span := tracer.Start(ctx, "bolt.Compact")
+	defer span.End()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	compactPath := b.path + ".compact"
+	if err := os.Remove(compactPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// the buckets are copied by the compaction
+	compacted, err := bbolt.Open(compactPath, 0o600, &bbolt.Options{Timeout: b.openTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to compact bolt datastore: %w", err)
+	}
+
+	err = bbolt.Compact(compacted, b.db, compactTxMaxSize)
+	if closeErr := compacted.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(compactPath, b.path)
+	}
+	if err != nil {
+		_ = os.Remove(compactPath)
+		return fmt.Errorf("failed to compact bolt datastore: %w", err)
+	}
+
+	// the file is reopened by its path, as bbolt opens it again to write backups
+	db, err := b.open(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to reopen compacted bolt datastore: %w", err)
+	}
+
+	old := b.db
+	b.db = db
+
+	// closing the file waits for the snapshots reading it
+	go func() {
+		if err := old.Close(); err != nil {
+			b.logger.Error("failed to close compacted bolt datastore file", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// storeBuckets are the buckets of the data of a store.
+type storeBuckets struct {
+	tuples       *bbolt.Bucket
+	tuplesByUser *bbolt.Bucket
+	tuplesByULID *bbolt.Bucket
+	changes      *bbolt.Bucket
+	models       *bbolt.Bucket
+	annotations  *bbolt.Bucket
+	assertions   *bbolt.Bucket
+}
+
+// readStoreBuckets returns the buckets of the store, or nil if no data was written to the store.
+func readStoreBuckets(tx *bbolt.Tx, store string) *storeBuckets {
+	bucket := tx.Bucket(storeDataBucket).Bucket([]byte(store))
+	if bucket == nil {
+		return nil
+	}
+
+	return &storeBuckets{
+		tuples:       bucket.Bucket(tuplesBucket),
+		tuplesByUser: bucket.Bucket(tuplesByUserBucket),
+		tuplesByULID: bucket.Bucket(tuplesByULIDBucket),
+		changes:      bucket.Bucket(changesBucket),
+		models:       bucket.Bucket(modelsBucket),
+		annotations:  bucket.Bucket(annotationsBucket),
+		assertions:   bucket.Bucket(assertionsBucket),
+	}
+}
+
+// writeStoreBuckets returns the buckets of the store, creating them if needed.
+func writeStoreBuckets(tx *bbolt.Tx, store string) (*storeBuckets, error) {
+	bucket, err := tx.Bucket(storeDataBucket).CreateBucketIfNotExists([]byte(store))
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]*bbolt.Bucket, 0, 7)
+	for _, name := range [][]byte{tuplesBucket, tuplesByUserBucket, tuplesByULIDBucket, changesBucket, modelsBucket, annotationsBucket, assertionsBucket} {
+		nested, err := bucket.CreateBucketIfNotExists(name)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, nested)
+	}
+
+	return &storeBuckets{
+		tuples:       buckets[0],
+		tuplesByUser: buckets[1],
+		tuplesByULID: buckets[2],
+		changes:      buckets[3],
+		models:       buckets[4],
+		annotations:  buckets[5],
+		assertions:   buckets[6],
+	}, nil
+}
+
+// joinKey returns the key made of the parts, separated by zero bytes.
+func joinKey(parts ...string) []byte {
+	return []byte(strings.Join(parts, "\x00"))
+}
+
+// prefixKey returns the prefix of the keys starting with the parts.
+func prefixKey(parts ...string) []byte {
+	return append(joinKey(parts...), 0)
+}
+
+// tupleKey returns the key of the tuple in the tuples bucket.
+func tupleKey(tk *openfgav1.TupleKey) []byte {
+	objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
+	return joinKey(objectType, objectID, tk.GetRelation(), tk.GetUser())
+}
+
+// userKey returns the key of the tuple in the tuples_by_user bucket.
+func userKey(tk *openfgav1.TupleKey) []byte {
+	objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
+	return joinKey(tk.GetUser(), objectType, tk.GetRelation(), objectID)
+}
+
+// parseTupleKey returns the tuple of the key of the tuples bucket.
+func parseTupleKey(key []byte) *openfgav1.TupleKey {
+	parts := strings.SplitN(string(key), "\x00", 4)
+	if len(parts) != 4 {
+		return &openfgav1.TupleKey{}
+	}
+
+	return tupleUtils.NewTupleKey(tupleUtils.BuildObject(parts[0], parts[1]), parts[2], parts[3])
+}
+
+// parseUserKey returns the key of the tuples bucket of the key of the tuples_by_user bucket.
+func parseUserKey(key []byte) []byte {
+	parts := strings.SplitN(string(key), "\x00", 4)
+	if len(parts) != 4 {
+		return nil
+	}
+
+	return joinKey(parts[1], parts[3], parts[2], parts[0])
+}
+
+// expirationKey returns the key of the expiration of the tuple of the store in the expirations bucket, ordered by
+// expiration time.
+func expirationKey(expiresAt time.Time, store string, key []byte) []byte {
+	k := make([]byte, 8, 8+len(store)+1+len(key))
+	binary.BigEndian.PutUint64(k, uint64(expiresAt.UnixNano()))
+	k = append(k, store...)
+	k = append(k, 0)
+	return append(k, key...)
+}
+
+// parseExpirationKey returns the expiration time, the store and the key of the tuple of the key of the expirations
+// bucket.
+func parseExpirationKey(k []byte) (time.Time, string, []byte) {
+	expiresAt := time.Unix(0, int64(binary.BigEndian.Uint64(k[:8]))).UTC()
+	store, key, _ := bytes.Cut(k[8:], []byte{0})
+	return expiresAt, string(store), key
+}
+
+// tupleRecord is the value of a tuple of the tuples bucket.
+type tupleRecord struct {
+	ULID                string          `json:"ulid"`
+	InsertedAt          time.Time       `json:"inserted_at"`
+	ExpiresAt           *time.Time      `json:"expires_at,omitempty"`
+	ConditionExpression string          `json:"condition_expression,omitempty"`
+	ConditionParameters json.RawMessage `json:"condition_parameters,omitempty"`
+}
+
+// expired reports whether the tuple is expired at `now`.
+func (r *tupleRecord) expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !r.ExpiresAt.After(now)
+}
+
+// condition returns the condition of the tuple, or nil if it is not conditional.
+func (r *tupleRecord) condition() (*storage.TupleCondition, error) {
+	if r.ConditionExpression == "" {
+		return nil, nil
+	}
+
+	condition := &storage.TupleCondition{Expression: r.ConditionExpression}
+	if len(r.ConditionParameters) > 0 {
+		condition.Parameters = &structpb.Struct{}
+		if err := protojson.Unmarshal(r.ConditionParameters, condition.Parameters); err != nil {
+			return nil, err
+		}
+	}
+
+	return condition, nil
+}
+
+// tupleEntry is a tuple read from the tuples bucket.
+type tupleEntry struct {
+	key    *openfgav1.TupleKey
+	record tupleRecord
+}
+
+func (e *tupleEntry) tuple() *openfgav1.Tuple {
+	return &openfgav1.Tuple{
+		Key:       e.key,
+		Timestamp: timestamppb.New(e.record.InsertedAt),
+	}
+}
+
+func tuplesOf(entries []*tupleEntry) []*openfgav1.Tuple {
+	tuples := make([]*openfgav1.Tuple, 0, len(entries))
+	for _, e := range entries {
+		tuples = append(tuples, e.tuple())
+	}
+
+	return tuples
+}
+
+// observeExpirations records the expirations of the tuples read in the tracker of the context, so the results computed
+// from them are not cached past the earliest of them.
+func observeExpirations(ctx context.Context, entries ...*tupleEntry) {
+	expirations := storage.TupleExpirationsFromContext(ctx)
+	for _, e := range entries {
+		if e.record.ExpiresAt != nil {
+			expirations.Observe(*e.record.ExpiresAt)
+		}
+	}
+}
+
+// readRecord returns the record of the tuple of the key of the tuples bucket, or nil if it does not exist. The tuple
+// may be expired.
+func readRecord(tuples *bbolt.Bucket, key []byte) (*tupleRecord, error) {
+	value := tuples.Get(key)
+	if value == nil {
+		return nil, nil
+	}
+
+	var record tupleRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// readTuples returns the tuples of the store matching the filter which are not expired at `now`, in no particular
+// order. The tuples of an object, of a user or of an object type are read from the keys with their prefix.
+func readTuples(tx *bbolt.Tx, store string, filter storage.ReadFilter, now time.Time) ([]*tupleEntry, error) {
+	buckets := readStoreBuckets(tx, store)
+	if buckets == nil {
+		return nil, nil
+	}
+
+	var entries []*tupleEntry
+	add := func(key, value []byte) error {
+		entry := &tupleEntry{key: parseTupleKey(key)}
+		if err := json.Unmarshal(value, &entry.record); err != nil {
+			return err
+		}
+
+		if !entry.record.expired(now) && filter.Matches(entry.key) {
+			entries = append(entries, entry)
+		}
+		return nil
+	}
+
+	objectType, objectID := tupleUtils.SplitObject(filter.Object)
+	switch {
+	case objectID != "":
+		prefix := prefixKey(objectType, objectID)
+		if len(filter.Relations) == 1 {
+			prefix = prefixKey(objectType, objectID, filter.Relations[0])
+		}
+
+		c := buckets.tuples.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if err := add(k, v); err != nil {
+				return nil, err
+			}
+		}
+	case filter.User != "":
+		prefix := prefixKey(filter.User)
+		if objectType != "" {
+			prefix = prefixKey(filter.User, objectType)
+		}
+
+		c := buckets.tuplesByUser.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			key := parseUserKey(k)
+			if v := buckets.tuples.Get(key); v != nil {
+				if err := add(key, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case objectType != "":
+		prefix := prefixKey(objectType)
+
+		c := buckets.tuples.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if err := add(k, v); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		err := buckets.tuples.ForEach(func(k, v []byte) error {
+			return add(k, v)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// readPage returns a page of the tuples of the store matching the filter, ordered by ULID, and the ULID of the last
+// tuple of the page as continuation token if there are more.
+func readPage(tx *bbolt.Tx, store string, filter storage.ReadFilter, opts storage.PaginationOptions, now time.Time) ([]*openfgav1.Tuple, []byte, error) {
+	if opts.From != "" {
+		if _, err := ulid.Parse(opts.From); err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+	}
+
+	entries, err := readTuples(tx, store, filter, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].record.ULID < entries[j].record.ULID
+	})
+
+	from := sort.Search(len(entries), func(i int) bool {
+		return entries[i].record.ULID > opts.From
+	})
+	entries = entries[from:]
+
+	if opts.PageSize > 0 && len(entries) > opts.PageSize {
+		entries = entries[:opts.PageSize]
+		return tuplesOf(entries), []byte(entries[len(entries)-1].record.ULID), nil
+	}
+
+	return tuplesOf(entries), nil, nil
+}
+
+func readUserTuple(tx *bbolt.Tx, store string, tk *openfgav1.TupleKey, now time.Time) (*tupleEntry, error) {
+	buckets := readStoreBuckets(tx, store)
+	if buckets == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	record, err := readRecord(buckets.tuples, tupleKey(tk))
+	if err != nil {
+		return nil, err
+	}
+
+	if record == nil || record.expired(now) {
+		return nil, storage.ErrNotFound
+	}
+
+	return &tupleEntry{key: tupleUtils.NewTupleKey(tk.GetObject(), tk.GetRelation(), tk.GetUser()), record: *record}, nil
+}
+
+func readUsersetTuples(tx *bbolt.Tx, store string, filter storage.ReadUsersetTuplesFilter, now time.Time) ([]*tupleEntry, error) {
+	entries, err := readTuples(tx, store, storage.ReadFilter{
+		Object:    filter.Object,
+		Relations: []string{filter.Relation},
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var usersets []*tupleEntry
+	for _, entry := range entries {
+		user := entry.key.GetUser()
+		if tupleUtils.GetUserTypeFromUser(user) != tupleUtils.UserSet {
+			continue
+		}
+
+		if len(filter.AllowedUserTypeRestrictions) == 0 { // 1.0 model
+			usersets = append(usersets, entry)
+			continue
+		}
+
+		// 1.1 model: see if the tuple found is of an allowed type
+		userType := tupleUtils.GetType(user)
+		_, userRelation := tupleUtils.SplitObjectRelation(user)
+		for _, allowedType := range filter.AllowedUserTypeRestrictions {
+			if allowedType.GetType() == userType && allowedType.GetRelation() == userRelation {
+				usersets = append(usersets, entry)
+				break
+			}
+		}
+	}
+
+	return usersets, nil
+}
+
+func readStartingWithUser(tx *bbolt.Tx, store string, filter storage.ReadStartingWithUserFilter, now time.Time) ([]*tupleEntry, error) {
+	var matches []*tupleEntry
+	for _, userFilter := range filter.UserFilter {
+		targetUser := userFilter.GetObject()
+		if userFilter.GetRelation() != "" {
+			targetUser = tupleUtils.GetObjectRelationAsString(userFilter)
+		}
+
+		entries, err := readTuples(tx, store, storage.ReadFilter{
+			Object:    filter.ObjectType + ":",
+			Relations: []string{filter.Relation},
+			User:      targetUser,
+		}, now)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, entries...)
+	}
+
+	return matches, nil
+}
+
+func readTupleConditions(tx *bbolt.Tx, store string, filter *openfgav1.TupleKey, now time.Time) (map[string]*storage.TupleCondition, error) {
+	entries, err := readTuples(tx, store, storage.NewReadFilter(filter), now)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := map[string]*storage.TupleCondition{}
+	for _, entry := range entries {
+		condition, err := entry.record.condition()
+		if err != nil {
+			return nil, err
+		}
+
+		if condition != nil {
+			conditions[tupleUtils.TupleKeyToString(entry.key)] = condition
+		}
+	}
+
+	return conditions, nil
+}
+
+func readTupleExpirations(tx *bbolt.Tx, store string, filter *openfgav1.TupleKey, now time.Time) (map[string]time.Time, error) {
+	entries, err := readTuples(tx, store, storage.NewReadFilter(filter), now)
+	if err != nil {
+		return nil, err
+	}
+
+	expirations := map[string]time.Time{}
+	for _, entry := range entries {
+		if entry.record.ExpiresAt != nil {
+			expirations[tupleUtils.TupleKeyToString(entry.key)] = *entry.record.ExpiresAt
+		}
+	}
+
+	return expirations, nil
+}
+
+func (b *Bolt) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	_, span := tracer.Start(ctx, "bolt.Read")
+	defer span.End()
+
+	var entries []*tupleEntry
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		entries, err = readTuples(tx, store, storage.NewReadFilter(tk), storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+func (b *Bolt) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadPage")
+	defer span.End()
+
+	return b.readPage(ctx, store, storage.NewReadFilter(tk), opts)
+}
+
+// ReadPageWithFilter see storage.TupleBackend.ReadPageWithFilter.
+func (b *Bolt) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadPageWithFilter")
+	defer span.End()
+
+	return b.readPage(ctx, store, filter, opts)
+}
+
+func (b *Bolt) readPage(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	var tuples []*openfgav1.Tuple
+	var token []byte
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		tuples, token, err = readPage(tx, store, filter, opts, storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tuples, token, nil
+}
+
+func (b *Bolt) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadUserTuple")
+	defer span.End()
+
+	var entry *tupleEntry
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		entry, err = readUserTuple(tx, store, tk, storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entry)
+	return entry.tuple(), nil
+}
+
+func (b *Bolt) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadUsersetTuples")
+	defer span.End()
+
+	var entries []*tupleEntry
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		entries, err = readUsersetTuples(tx, store, filter, storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+func (b *Bolt) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadStartingWithUser")
+	defer span.End()
+
+	var entries []*tupleEntry
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		entries, err = readStartingWithUser(tx, store, filter, storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+// ReadTupleExpirations see storage.TupleExpirationBackend.ReadTupleExpirations.
+func (b *Bolt) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadTupleExpirations")
+	defer span.End()
+
+	var expirations map[string]time.Time
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		expirations, err = readTupleExpirations(tx, store, filter, time.Now())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expirations, nil
+}
+
+// ReadTupleConditions see storage.TupleConditionBackend.ReadTupleConditions.
+func (b *Bolt) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadTupleConditions")
+	defer span.End()
+
+	var conditions map[string]*storage.TupleCondition
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		conditions, err = readTupleConditions(tx, store, filter, storage.ExpirationTime(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conditions, nil
+}
+
+func (b *Bolt) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "bolt.Write")
+	defer span.End()
+
+	return b.write(store, deletes, writes, nil, nil, storage.NewTupleWriteOptions(opts...))
+}
+
+// WriteWithExpiry see storage.TupleExpirationBackend.WriteWithExpiry.
+func (b *Bolt) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "bolt.WriteWithExpiry")
+	defer span.End()
+
+	return b.write(store, deletes, writes, &expiresAt, nil, storage.NewTupleWriteOptions(opts...))
+}
+
+// WriteWithCondition see storage.TupleConditionBackend.WriteWithCondition.
+func (b *Bolt) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "bolt.WriteWithCondition")
+	defer span.End()
+
+	return b.write(store, deletes, writes, nil, condition, storage.NewTupleWriteOptions(opts...))
+}
+
+// write validates the deletes and writes against the tuples of the store, and then applies them along with their
+// changes, in a single transaction. The expired tuples deleted or written behave as if they had been deleted, so they
+// are deleted before the deletes and writes are validated.
+func (b *Bolt) write(store string, deletes storage.Deletes, writes storage.Writes, expiresAt *time.Time, condition *storage.TupleCondition, opts storage.TupleWriteOptions) error {
+	if len(deletes)+len(writes) > b.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	var record tupleRecord
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		record.ExpiresAt = &utc
+	}
+	if condition != nil {
+		record.ConditionExpression = condition.Expression
+
+		if condition.Parameters != nil {
+			marshalled, err := protojson.Marshal(condition.Parameters)
+			if err != nil {
+				return err
+			}
+			record.ConditionParameters = marshalled
+		}
+	}
+
+	var lastChange string
+	err := b.update(func(tx *bbolt.Tx) error {
+		var err error
+		lastChange, err = writeTx(tx, store, deletes, writes, record, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	opts.CommitChange(lastChange)
+	return nil
+}
+
+// writeTx validates and applies the deletes and writes in the transaction, and returns the ULID of the last change, or
+// an empty ULID if the write changed nothing. The tuples written get the expiration and condition of the record.
+func writeTx(tx *bbolt.Tx, store string, deletes storage.Deletes, writes storage.Writes, record tupleRecord, opts storage.TupleWriteOptions) (string, error) {
+	buckets, err := writeStoreBuckets(tx, store)
+	if err != nil {
+		return "", err
+	}
+
+	latest, _ := buckets.changes.Cursor().Last()
+	if opts.ExpectedChangelogToken != nil {
+		// the token is the ULID of the last change read, which must be the last change of the store
+		expected, _, _ := strings.Cut(*opts.ExpectedChangelogToken, "|")
+		if string(latest) != expected {
+			return "", storage.ErrChangelogConflict
+		}
+	}
+	latestChange := string(latest)
+
+	now := time.Now().UTC()
+
+	exists := func(tk *openfgav1.TupleKey) (bool, error) {
+		existing, err := readRecord(buckets.tuples, tupleKey(tk))
+		if err != nil || existing == nil {
+			return false, err
+		}
+
+		if existing.expired(now) {
+			return false, deleteTuple(tx, buckets, store, tk, existing, now)
+		}
+
+		return true, nil
+	}
+
+	for _, tk := range deletes {
+		ok, err := exists(tk)
+		if err != nil {
+			return "", err
+		}
+		if !ok && opts.OnMissingDelete != storage.OnMissingDeleteIgnore {
+			return "", storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE)
+		}
+	}
+
+	for _, tk := range writes {
+		ok, err := exists(tk)
+		if err != nil {
+			return "", err
+		}
+		if ok && opts.OnDuplicateInsert != storage.OnDuplicateInsertIgnore {
+			return "", storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_WRITE)
+		}
+	}
+
+	for _, tk := range deletes {
+		existing, err := readRecord(buckets.tuples, tupleKey(tk))
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			continue
+		}
+
+		if err := deleteTuple(tx, buckets, store, tk, existing, now); err != nil {
+			return "", err
+		}
+	}
+
+	for _, tk := range writes {
+		key := tupleKey(tk)
+		if buckets.tuples.Get(key) != nil {
+			continue
+		}
+
+		record.ULID = newULID(now)
+		record.InsertedAt = now
+		if err := insertTuple(tx, buckets, store, tk, &record); err != nil {
+			return "", err
+		}
+
+		if err := insertChange(buckets, tk, openfgav1.TupleOperation_TUPLE_OPERATION_WRITE, now); err != nil {
+			return "", err
+		}
+	}
+
+	// the changes are keyed by ULID
+	if last, _ := buckets.changes.Cursor().Last(); string(last) != latestChange {
+		return string(last), nil
+	}
+
+	return "", nil
+}
+
+// StageWrite see storage.StagedWriteBackend.StageWrite. The staged tuples are keyed by store, staged write and
+// operation in the staged_writes bucket.
+func (b *Bolt) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	_, span := tracer.Start(ctx, "bolt.StageWrite")
+	defer span.End()
+
+	if len(deletes)+len(writes) > b.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	return b.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(stagedWritesBucket)
+		for operation, tupleKeys := range map[openfgav1.TupleOperation][]*openfgav1.TupleKey{
+			openfgav1.TupleOperation_TUPLE_OPERATION_DELETE: deletes,
+			openfgav1.TupleOperation_TUPLE_OPERATION_WRITE:  writes,
+		} {
+			for _, tk := range tupleKeys {
+				key := append(prefixKey(store, id, operation.String()), tupleKey(tk)...)
+				if err := bucket.Put(key, nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// CommitStagedWrite see storage.StagedWriteBackend.CommitStagedWrite.
+func (b *Bolt) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "bolt.CommitStagedWrite")
+	defer span.End()
+
+	o := storage.NewTupleWriteOptions(opts...)
+
+	var lastChange string
+	err := b.update(func(tx *bbolt.Tx) error {
+		var deletes storage.Deletes
+		var writes storage.Writes
+		var keys [][]byte
+
+		prefix := prefixKey(store, id)
+		cursor := tx.Bucket(stagedWritesBucket).Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			operation, key, _ := bytes.Cut(k[len(prefix):], []byte{0})
+			if string(operation) == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE.String() {
+				deletes = append(deletes, parseTupleKey(key))
+			} else {
+				writes = append(writes, parseTupleKey(key))
+			}
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		var err error
+		lastChange, err = writeTx(tx, store, deletes, writes, tupleRecord{}, o)
+		if err != nil {
+			return err
+		}
+
+		return deleteKeys(tx.Bucket(stagedWritesBucket), keys)
+	})
+	if err != nil {
+		return err
+	}
+
+	o.CommitChange(lastChange)
+	return nil
+}
+
+// DiscardStagedWrite see storage.StagedWriteBackend.DiscardStagedWrite.
+func (b *Bolt) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	_, span := tracer.Start(ctx, "bolt.DiscardStagedWrite")
+	defer span.End()
+
+	return b.update(func(tx *bbolt.Tx) error {
+		var keys [][]byte
+
+		prefix := prefixKey(store, id)
+		cursor := tx.Bucket(stagedWritesBucket).Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		return deleteKeys(tx.Bucket(stagedWritesBucket), keys)
+	})
+}
+
+// deleteKeys deletes the keys from the bucket. The keys are collected before they are deleted, as deleting the keys
+// while iterating over them with a cursor skips some of them.
+func deleteKeys(bucket *bbolt.Bucket, keys [][]byte) error {
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newULID returns a ULID of the time.
+func newULID(now time.Time) string {
+	return ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
+}
+
+// insertTuple inserts the tuple, and indexes it by user, by ULID and by expiration time.
+func insertTuple(tx *bbolt.Tx, buckets *storeBuckets, store string, tk *openfgav1.TupleKey, record *tupleRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	key := tupleKey(tk)
+	if err := buckets.tuples.Put(key, value); err != nil {
+		return err
+	}
+	if err := buckets.tuplesByUser.Put(userKey(tk), nil); err != nil {
+		return err
+	}
+	if err := buckets.tuplesByULID.Put([]byte(record.ULID), key); err != nil {
+		return err
+	}
+
+	if record.ExpiresAt != nil {
+		return tx.Bucket(expirationsBucket).Put(expirationKey(*record.ExpiresAt, store, key), nil)
+	}
+
+	return nil
+}
+
+// deleteTuple deletes the tuple and its indexes, recording the delete in the changelog.
+func deleteTuple(tx *bbolt.Tx, buckets *storeBuckets, store string, tk *openfgav1.TupleKey, record *tupleRecord, now time.Time) error {
+	key := tupleKey(tk)
+	if err := buckets.tuples.Delete(key); err != nil {
+		return err
+	}
+	if err := buckets.tuplesByUser.Delete(userKey(tk)); err != nil {
+		return err
+	}
+	if err := buckets.tuplesByULID.Delete([]byte(record.ULID)); err != nil {
+		return err
+	}
+
+	if record.ExpiresAt != nil {
+		if err := tx.Bucket(expirationsBucket).Delete(expirationKey(*record.ExpiresAt, store, key)); err != nil {
+			return err
+		}
+	}
+
+	return insertChange(buckets, tk, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, now)
+}
+
+// insertChange records the change of the tuple in the changelog, keyed by a ULID greater than the ULIDs of the
+// previous changes, so that the changelog is ordered like the writes.
+func insertChange(buckets *storeBuckets, tk *openfgav1.TupleKey, operation openfgav1.TupleOperation, now time.Time) error {
+	value, err := proto.Marshal(&openfgav1.TupleChange{
+		TupleKey:  tupleUtils.NewTupleKey(tk.GetObject(), tk.GetRelation(), tk.GetUser()),
+		Operation: operation,
+		Timestamp: timestamppb.New(now),
+	})
+	if err != nil {
+		return err
+	}
+
+	last, _ := buckets.changes.Cursor().Last()
+
+	return buckets.changes.Put([]byte(storage.NextChangeULID(now, string(last))), value)
+}
+
+// DeleteExpiredTuples see storage.TupleExpirationBackend.DeleteExpiredTuples. The tuples are deleted from the oldest
+// to the most recently expired.
+func (b *Bolt) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "bolt.DeleteExpiredTuples")
+	defer span.End()
+
+	var deleted int
+	err := b.update(func(tx *bbolt.Tx) error {
+		now := time.Now().UTC()
+
+		var expired [][]byte
+		c := tx.Bucket(expirationsBucket).Cursor()
+		for k, _ := c.First(); k != nil && len(expired) < limit; k, _ = c.Next() {
+			expiresAt, _, _ := parseExpirationKey(k)
+			if expiresAt.After(now) {
+				break
+			}
+			expired = append(expired, append([]byte(nil), k...))
+		}
+
+		for _, k := range expired {
+			_, store, key := parseExpirationKey(k)
+
+			buckets := readStoreBuckets(tx, store)
+			if buckets == nil {
+				if err := tx.Bucket(expirationsBucket).Delete(k); err != nil {
+					return err
+				}
+				continue
+			}
+
+			record, err := readRecord(buckets.tuples, key)
+			if err != nil {
+				return err
+			}
+
+			if record == nil {
+				if err := tx.Bucket(expirationsBucket).Delete(k); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := deleteTuple(tx, buckets, store, parseTupleKey(key), record, now); err != nil {
+				return err
+			}
+			deleted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// Snapshot see storage.SnapshotBackend.Snapshot. The reads of the snapshot are run in a read-only transaction, which
+// observes the state of the datastore when it was started.
+func (b *Bolt) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	_, span := tracer.Start(ctx, "bolt.Snapshot")
+	defer span.End()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotReader{tx: tx}, nil
+}
+
+func (b *Bolt) ReadChanges(ctx context.Context, store, objectType string, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadChanges")
+	defer span.End()
+
+	return b.ReadChangesWithFilter(ctx, store, storage.ReadChangesFilter{ObjectType: objectType}, opts, horizonOffset)
+}
+
+// ReadChangesWithFilter see storage.ChangelogBackend.ReadChangesWithFilter. The continuation token is the ULID of the
+// last change read, followed by the object type of the filter.
+func (b *Bolt) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadChangesWithFilter")
+	defer span.End()
+
+	var from string
+	if opts.From != "" {
+		var objectType string
+		var ok bool
+		from, objectType, ok = strings.Cut(opts.From, "|")
+		if !ok {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+
+		if objectType != filter.ObjectType {
+			return nil, nil, storage.ErrMismatchObjectType
+		}
+
+		if _, err := ulid.Parse(from); err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+	}
+
+	pageSize := storage.DefaultPageSize
+	if opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+
+	horizon := time.Now().UTC().Add(-horizonOffset)
+
+	var changes []*openfgav1.TupleChange
+	last := from
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil {
+			return nil
+		}
+
+		c := buckets.changes.Cursor()
+		k, v := c.First()
+		if from != "" {
+			k, v = c.Seek([]byte(from))
+			if k != nil && string(k) == from {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil && len(changes) < pageSize; k, v = c.Next() {
+			var change openfgav1.TupleChange
+			if err := proto.Unmarshal(v, &change); err != nil {
+				return err
+			}
+
+			if change.GetTimestamp().AsTime().After(horizon) {
+				break
+			}
+
+			last = string(k)
+			if matchChange(filter, &change) {
+				changes = append(changes, &change)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(changes) == 0 {
+		return nil, nil, storage.ErrNotFound
+	}
+
+	return changes, []byte(last + "|" + filter.ObjectType), nil
+}
+
+// matchChange reports whether the change matches the filter, see storage.ReadChangesFilter.
+func matchChange(filter storage.ReadChangesFilter, change *openfgav1.TupleChange) bool {
+	if filter.ObjectType != "" && tupleUtils.GetType(change.GetTupleKey().GetObject()) != filter.ObjectType {
+		return false
+	}
+	if filter.Relation != "" && change.GetTupleKey().GetRelation() != filter.Relation {
+		return false
+	}
+	if filter.Operation != nil && change.GetOperation() != *filter.Operation {
+		return false
+	}
+	if !filter.StartTime.IsZero() && change.GetTimestamp().AsTime().Before(filter.StartTime) {
+		return false
+	}
+
+	return true
+}
+
+// DeleteChanges see storage.ChangelogBackend.DeleteChanges.
+func (b *Bolt) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "bolt.DeleteChanges")
+	defer span.End()
+
+	var deleted int
+	err := b.update(func(tx *bbolt.Tx) error {
+		stores := []string{store}
+		if store == "" {
+			stores = nil
+
+			// the buckets are ordered by name
+			err := tx.Bucket(storeDataBucket).ForEach(func(k, _ []byte) error {
+				stores = append(stores, string(k))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, id := range stores {
+			buckets := readStoreBuckets(tx, id)
+			if buckets == nil {
+				continue
+			}
+
+			// the changes are in the order in which they occurred
+			c := buckets.changes.Cursor()
+			for k, v := c.First(); k != nil && deleted < limit; k, v = c.Next() {
+				var change openfgav1.TupleChange
+				if err := proto.Unmarshal(v, &change); err != nil {
+					return err
+				}
+
+				if !change.GetTimestamp().AsTime().Before(before) {
+					break
+				}
+
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// ReadStoreStats see storage.StoreStatsBackend.ReadStoreStats.
+func (b *Bolt) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadStoreStats")
+	defer span.End()
+
+	stats := &storage.StoreStats{Tuples: map[string]int64{}}
+	err := b.view(func(tx *bbolt.Tx) error {
+		entries, err := readTuples(tx, store, storage.ReadFilter{}, time.Now())
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			objectType := tupleUtils.GetType(entry.key.GetObject())
+			stats.Tuples[tupleUtils.ToObjectRelationString(objectType, entry.key.GetRelation())]++
+		}
+
+		if buckets := readStoreBuckets(tx, store); buckets != nil {
+			stats.AuthorizationModels = int64(buckets.models.Stats().KeyN)
+			stats.Changes = int64(buckets.changes.Stats().KeyN)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (b *Bolt) ReadAuthorizationModel(ctx context.Context, store string, id string) (*openfgav1.AuthorizationModel, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadAuthorizationModel")
+	defer span.End()
+
+	var model *openfgav1.AuthorizationModel
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil {
+			return storage.ErrNotFound
+		}
+
+		value := buckets.models.Get([]byte(id))
+		if value == nil {
+			return storage.ErrNotFound
+		}
+
+		model = &openfgav1.AuthorizationModel{}
+		return proto.Unmarshal(value, model)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// ReadAuthorizationModels see storage.AuthorizationModelReadBackend.ReadAuthorizationModels. The models are returned
+// from the newest to the oldest, and the continuation token is the ID of the last model returned.
+func (b *Bolt) ReadAuthorizationModels(ctx context.Context, store string, opts storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadAuthorizationModels")
+	defer span.End()
+
+	pageSize := storage.DefaultPageSize
+	if opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+
+	var models []*openfgav1.AuthorizationModel
+	var token []byte
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil {
+			return nil
+		}
+
+		// the IDs are ULIDs, so the last model is the newest
+		c := buckets.models.Cursor()
+		k, v := c.Last()
+		if opts.From != "" {
+			if k, _ = c.Seek([]byte(opts.From)); k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if len(models) == pageSize {
+				token = []byte(models[pageSize-1].GetId())
+				break
+			}
+
+			var model openfgav1.AuthorizationModel
+			if err := proto.Unmarshal(v, &model); err != nil {
+				return err
+			}
+			models = append(models, &model)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return models, token, nil
+}
+
+func (b *Bolt) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	_, span := tracer.Start(ctx, "bolt.FindLatestAuthorizationModelID")
+	defer span.End()
+
+	var id string
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil {
+			return storage.ErrNotFound
+		}
+
+		k, _ := buckets.models.Cursor().Last()
+		if k == nil {
+			return storage.ErrNotFound
+		}
+
+		id = string(k)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// ReadAuthorizationModelAnnotations see storage.AuthorizationModelReadBackend.ReadAuthorizationModelAnnotations.
+func (b *Bolt) ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (storage.ModelAnnotations, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadAuthorizationModelAnnotations")
+	defer span.End()
+
+	annotations := storage.ModelAnnotations{}
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil || buckets.models.Get([]byte(id)) == nil {
+			return storage.ErrNotFound
+		}
+
+		if value := buckets.annotations.Get([]byte(id)); value != nil {
+			return json.Unmarshal(value, &annotations)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+func (b *Bolt) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
+	_, span := tracer.Start(ctx, "bolt.WriteAuthorizationModel")
+	defer span.End()
+
+	return b.writeAuthorizationModel(store, model, nil)
+}
+
+// WriteAuthorizationModelWithAnnotations see storage.TypeDefinitionWriteBackend.WriteAuthorizationModelWithAnnotations.
+func (b *Bolt) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	_, span := tracer.Start(ctx, "bolt.WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	return b.writeAuthorizationModel(store, model, annotations)
+}
+
+func (b *Bolt) writeAuthorizationModel(store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	typeDefinitions := model.GetTypeDefinitions()
+
+	if len(typeDefinitions) > b.MaxTypesPerAuthorizationModel() {
+		return storage.ExceededMaxTypeDefinitionsLimitError(b.maxTypesPerAuthorizationModel)
+	}
+
+	if len(typeDefinitions) < 1 {
+		return nil
+	}
+
+	value, err := proto.Marshal(model)
+	if err != nil {
+		return err
+	}
+
+	return b.update(func(tx *bbolt.Tx) error {
+		buckets, err := writeStoreBuckets(tx, store)
+		if err != nil {
+			return err
+		}
+
+		if err := buckets.models.Put([]byte(model.GetId()), value); err != nil {
+			return err
+		}
+
+		if len(annotations) == 0 {
+			return nil
+		}
+
+		marshalled, err := json.Marshal(annotations)
+		if err != nil {
+			return err
+		}
+
+		return buckets.annotations.Put([]byte(model.GetId()), marshalled)
+	})
+}
+
+// DeleteAuthorizationModel see storage.TypeDefinitionWriteBackend.DeleteAuthorizationModel.
+func (b *Bolt) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	_, span := tracer.Start(ctx, "bolt.DeleteAuthorizationModel")
+	defer span.End()
+
+	return b.update(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil || buckets.models.Get([]byte(id)) == nil {
+			return storage.ErrNotFound
+		}
+
+		for _, bucket := range []*bbolt.Bucket{buckets.models, buckets.annotations, buckets.assertions} {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		bucket := tx.Bucket(storeDataBucket).Bucket([]byte(store))
+		if string(bucket.Get(pinnedModelKey)) == id {
+			return bucket.Delete(pinnedModelKey)
+		}
+
+		return nil
+	})
+}
+
+// PinAuthorizationModel see storage.PinnedAuthorizationModelBackend.PinAuthorizationModel.
+func (b *Bolt) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	_, span := tracer.Start(ctx, "bolt.PinAuthorizationModel")
+	defer span.End()
+
+	return b.update(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil || buckets.models.Get([]byte(id)) == nil {
+			return storage.ErrNotFound
+		}
+
+		return tx.Bucket(storeDataBucket).Bucket([]byte(store)).Put(pinnedModelKey, []byte(id))
+	})
+}
+
+// UnpinAuthorizationModel see storage.PinnedAuthorizationModelBackend.UnpinAuthorizationModel.
+func (b *Bolt) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	_, span := tracer.Start(ctx, "bolt.UnpinAuthorizationModel")
+	defer span.End()
+
+	return b.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(storeDataBucket).Bucket([]byte(store))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.Delete(pinnedModelKey)
+	})
+}
+
+// ReadPinnedAuthorizationModelID see storage.AuthorizationModelReadBackend.ReadPinnedAuthorizationModelID.
+func (b *Bolt) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadPinnedAuthorizationModelID")
+	defer span.End()
+
+	var id string
+	err := b.view(func(tx *bbolt.Tx) error {
+		if bucket := tx.Bucket(storeDataBucket).Bucket([]byte(store)); bucket != nil {
+			id = string(bucket.Get(pinnedModelKey))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (b *Bolt) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
+	_, span := tracer.Start(ctx, "bolt.WriteAssertions")
+	defer span.End()
+
+	value, err := proto.Marshal(&openfgav1.Assertions{Assertions: assertions})
+	if err != nil {
+		return err
+	}
+
+	return b.update(func(tx *bbolt.Tx) error {
+		buckets, err := writeStoreBuckets(tx, store)
+		if err != nil {
+			return err
+		}
+
+		return buckets.assertions.Put([]byte(modelID), value)
+	})
+}
+
+func (b *Bolt) ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadAssertions")
+	defer span.End()
+
+	assertions := []*openfgav1.Assertion{}
+	err := b.view(func(tx *bbolt.Tx) error {
+		buckets := readStoreBuckets(tx, store)
+		if buckets == nil {
+			return nil
+		}
+
+		value := buckets.assertions.Get([]byte(modelID))
+		if value == nil {
+			return nil
+		}
+
+		var stored openfgav1.Assertions
+		if err := proto.Unmarshal(value, &stored); err != nil {
+			return err
+		}
+		assertions = stored.GetAssertions()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return assertions, nil
+}
+
+// readStore returns the store with the provided id, or storage.ErrNotFound if it does not exist or, unless
+// `deleted`, if it was deleted.
+func readStore(tx *bbolt.Tx, id string, deleted bool) (*openfgav1.Store, error) {
+	value := tx.Bucket(storesBucket).Get([]byte(id))
+	if value == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	var store openfgav1.Store
+	if err := proto.Unmarshal(value, &store); err != nil {
+		return nil, err
+	}
+
+	if !deleted && store.GetDeletedAt() != nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return &store, nil
+}
+
+func putStore(tx *bbolt.Tx, store *openfgav1.Store) error {
+	value, err := proto.Marshal(store)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(storesBucket).Put([]byte(store.GetId()), value)
+}
+
+func (b *Bolt) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	_, span := tracer.Start(ctx, "bolt.CreateStore")
+	defer span.End()
+
+	now := timestamppb.New(time.Now().UTC())
+	created := &openfgav1.Store{
+		Id:        store.GetId(),
+		Name:      store.GetName(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := b.update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(storesBucket).Get([]byte(store.GetId())) != nil {
+			return storage.ErrCollision
+		}
+
+		return putStore(tx, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (b *Bolt) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+	_, span := tracer.Start(ctx, "bolt.GetStore")
+	defer span.End()
+
+	var store *openfgav1.Store
+	err := b.view(func(tx *bbolt.Tx) error {
+		var err error
+		store, err = readStore(tx, id, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (b *Bolt) ListStores(ctx context.Context, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ListStores")
+	defer span.End()
+
+	return b.listStores(storage.ListStoresFilter{}, opts)
+}
+
+// ListStoresWithFilter see storage.StoresBackend.ListStoresWithFilter.
+func (b *Bolt) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	_, span := tracer.Start(ctx, "bolt.ListStoresWithFilter")
+	defer span.End()
+
+	return b.listStores(filter, opts)
+}
+
+// storeContToken is the continuation token of the stores, the ID and, if they are sorted by name, the name of the
+// next store.
+type storeContToken struct {
+	Ulid string `json:"ulid"`
+	Name string `json:"name,omitempty"`
+}
+
+func (b *Bolt) listStores(filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	var token *storeContToken
+	if opts.From != "" {
+		token = &storeContToken{}
+		if err := json.Unmarshal([]byte(opts.From), token); err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+	}
+
+	sortByName := filter.SortOrder == storage.StoreSortByName
+
+	var stores []*openfgav1.Store
+	err := b.view(func(tx *bbolt.Tx) error {
+		// the stores are ordered by ID
+		return tx.Bucket(storesBucket).ForEach(func(k, v []byte) error {
+			var store openfgav1.Store
+			if err := proto.Unmarshal(v, &store); err != nil {
+				return err
+			}
+
+			if store.GetDeletedAt() != nil || !strings.HasPrefix(store.GetName(), filter.NamePrefix) {
+				return nil
+			}
+
+			if token != nil {
+				if sortByName && (store.GetName() < token.Name || (store.GetName() == token.Name && store.GetId() < token.Ulid)) {
+					return nil
+				}
+				if !sortByName && store.GetId() < token.Ulid {
+					return nil
+				}
+			}
+
+			if len(filter.Labels) > 0 {
+				metadata, err := readStoreMetadata(tx, store.GetId())
+				if err != nil {
+					return err
+				}
+				if !hasLabels(metadata, filter.Labels) {
+					return nil
+				}
+			}
+
+			stores = append(stores, &store)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sortByName {
+		sort.SliceStable(stores, func(i, j int) bool {
+			return stores[i].GetName() < stores[j].GetName()
+		})
+	}
+
+	if len(stores) == 0 {
+		return nil, nil, nil
+	}
+
+	pageSize := storage.DefaultPageSize
+	if opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+
+	if len(stores) > pageSize {
+		next := storeContToken{Ulid: stores[pageSize].GetId()}
+		if sortByName {
+			next.Name = stores[pageSize].GetName()
+		}
+
+		contToken, err := json.Marshal(next)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return stores[:pageSize], contToken, nil
+	}
+
+	return stores, nil, nil
+}
+
+func hasLabels(metadata *storage.StoreMetadata, labels map[string]string) bool {
+	for key, value := range labels {
+		if label, ok := metadata.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (b *Bolt) DeleteStore(ctx context.Context, id string) error {
+	_, span := tracer.Start(ctx, "bolt.DeleteStore")
+	defer span.End()
+
+	return b.update(func(tx *bbolt.Tx) error {
+		store, err := readStore(tx, id, false)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		store.DeletedAt = timestamppb.New(time.Now().UTC())
+		return putStore(tx, store)
+	})
+}
+
+// UndeleteStore see storage.StoresBackend.UndeleteStore.
+func (b *Bolt) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	_, span := tracer.Start(ctx, "bolt.UndeleteStore")
+	defer span.End()
+
+	var store *openfgav1.Store
+	err := b.update(func(tx *bbolt.Tx) error {
+		var err error
+		store, err = readStore(tx, id, true)
+		if err != nil {
+			return err
+		}
+
+		if store.GetDeletedAt() == nil || store.GetDeletedAt().AsTime().Before(deletedAfter) {
+			return storage.ErrNotFound
+		}
+
+		store.DeletedAt = nil
+		store.UpdatedAt = timestamppb.New(time.Now().UTC())
+		return putStore(tx, store)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// PurgeDeletedStores see storage.StoresBackend.PurgeDeletedStores.
+func (b *Bolt) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "bolt.PurgeDeletedStores")
+	defer span.End()
+
+	var purged int
+	err := b.update(func(tx *bbolt.Tx) error {
+		var stores []*openfgav1.Store
+		err := tx.Bucket(storesBucket).ForEach(func(k, v []byte) error {
+			var store openfgav1.Store
+			if err := proto.Unmarshal(v, &store); err != nil {
+				return err
+			}
+
+			if store.GetDeletedAt() != nil && store.GetDeletedAt().AsTime().Before(deletedBefore) {
+				stores = append(stores, &store)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// from the first deleted to the last deleted
+		sort.Slice(stores, func(i, j int) bool {
+			return stores[i].GetDeletedAt().AsTime().Before(stores[j].GetDeletedAt().AsTime())
+		})
+		if len(stores) > limit {
+			stores = stores[:limit]
+		}
+
+		for _, store := range stores {
+			if err := purgeStore(tx, store.GetId()); err != nil {
+				return err
+			}
+		}
+
+		purged = len(stores)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// purgeStore deletes the store and all its data.
+func purgeStore(tx *bbolt.Tx, id string) error {
+	if buckets := readStoreBuckets(tx, id); buckets != nil {
+		expirations := tx.Bucket(expirationsBucket)
+		err := buckets.tuples.ForEach(func(k, v []byte) error {
+			var record tupleRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+
+			if record.ExpiresAt != nil {
+				return expirations.Delete(expirationKey(*record.ExpiresAt, id, k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(storeDataBucket).DeleteBucket([]byte(id)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Bucket(storeMetadataBucket).Delete([]byte(id)); err != nil {
+		return err
+	}
+
+	return tx.Bucket(storesBucket).Delete([]byte(id))
+}
+
+// readStoreMetadata returns the metadata of the store, which is empty if none was written.
+func readStoreMetadata(tx *bbolt.Tx, id string) (*storage.StoreMetadata, error) {
+	metadata := &storage.StoreMetadata{}
+
+	value := tx.Bucket(storeMetadataBucket).Get([]byte(id))
+	if value == nil {
+		return metadata, nil
+	}
+
+	if err := json.Unmarshal(value, metadata); err != nil {
+		return nil, err
+	}
+
+	if len(metadata.Labels) == 0 {
+		metadata.Labels = nil
+	}
+
+	return metadata, nil
+}
+
+// WriteStoreMetadata see storage.StoresBackend.WriteStoreMetadata.
+func (b *Bolt) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	_, span := tracer.Start(ctx, "bolt.WriteStoreMetadata")
+	defer span.End()
+
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return b.update(func(tx *bbolt.Tx) error {
+		store, err := readStore(tx, id, false)
+		if err != nil {
+			return err
+		}
+
+		store.UpdatedAt = timestamppb.New(time.Now().UTC())
+		if err := putStore(tx, store); err != nil {
+			return err
+		}
+
+		return tx.Bucket(storeMetadataBucket).Put([]byte(id), value)
+	})
+}
+
+// ReadStoreMetadata see storage.StoresBackend.ReadStoreMetadata.
+func (b *Bolt) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	_, span := tracer.Start(ctx, "bolt.ReadStoreMetadata")
+	defer span.End()
+
+	var metadata *storage.StoreMetadata
+	err := b.view(func(tx *bbolt.Tx) error {
+		if _, err := readStore(tx, id, false); err != nil {
+			return err
+		}
+
+		var err error
+		metadata, err = readStoreMetadata(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
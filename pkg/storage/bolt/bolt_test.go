@@ -0,0 +1,97 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/test"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoltDatastore(t *testing.T) {
+	ds, err := New(filepath.Join(t.TempDir(), "openfga.db"))
+	require.NoError(t, err)
+	defer ds.Close()
+	test.RunAllTests(t, ds)
+}
+
+func TestBoltPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "openfga.db")
+	store := "01HEB3W1Z8W1V5Y6WNRJ0Q8QGC"
+	tk := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+
+	ds, err := New(path)
+	require.NoError(t, err)
+
+	_, err = ds.CreateStore(ctx, &openfgav1.Store{Id: store, Name: "store"})
+	require.NoError(t, err)
+	require.NoError(t, ds.Write(ctx, store, nil, []*openfgav1.TupleKey{tk}))
+	ds.Close()
+
+	ds, err = New(path)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	_, err = ds.GetStore(ctx, store)
+	require.NoError(t, err)
+	_, err = ds.ReadUserTuple(ctx, store, tk)
+	require.NoError(t, err)
+}
+
+func TestBoltCompactAndBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openfga.db")
+	store := "01HEB3W1Z8W1V5Y6WNRJ0Q8QGC"
+
+	ds, err := New(path)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	var writes []*openfgav1.TupleKey
+	for i := 0; i < 100; i++ {
+		writes = append(writes, tuple.NewTupleKey("document:"+tuple.BuildObject("doc", string(rune('a'+i%26)))+string(rune('0'+i/26)), "viewer", "user:anne"))
+	}
+	require.NoError(t, ds.Write(ctx, store, nil, writes))
+	require.NoError(t, ds.Write(ctx, store, writes[1:], nil))
+
+	snapshot, err := ds.Snapshot(ctx, store)
+	require.NoError(t, err)
+
+	require.NoError(t, ds.Compact(ctx))
+
+	// the snapshot taken before the compaction still reads the tuples it observed
+	tuples, _, err := snapshot.ReadPage(ctx, store, &openfgav1.TupleKey{}, storage.PaginationOptions{})
+	require.NoError(t, err)
+	require.Len(t, tuples, 1)
+	snapshot.Close()
+
+	_, err = ds.ReadUserTuple(ctx, store, writes[0])
+	require.NoError(t, err)
+	require.NoError(t, ds.Write(ctx, store, nil, writes[1:2]))
+
+	_, err = os.Stat(path + ".compact")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	var backup bytes.Buffer
+	_, err = ds.Backup(ctx, &backup)
+	require.NoError(t, err)
+
+	backupPath := filepath.Join(dir, "backup.db")
+	require.NoError(t, os.WriteFile(backupPath, backup.Bytes(), 0o600))
+
+	restored, err := New(backupPath)
+	require.NoError(t, err)
+	defer restored.Close()
+
+	tuples, _, err = restored.ReadPage(ctx, store, &openfgav1.TupleKey{}, storage.PaginationOptions{})
+	require.NoError(t, err)
+	require.Len(t, tuples, 2)
+}
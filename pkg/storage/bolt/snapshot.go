@@ -0,0 +1,92 @@
+package bolt
+
+import (
+	"context"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.etcd.io/bbolt"
+)
+
+var _ storage.SnapshotReader = (*snapshotReader)(nil)
+
+// snapshotReader reads the tuples from a read-only transaction, which observes the state of the datastore when it was
+// started. A transaction can't be used concurrently, so the reads are serialized.
+type snapshotReader struct {
+	mu sync.Mutex
+	tx *bbolt.Tx
+}
+
+func (s *snapshotReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := readTuples(s.tx, store, storage.NewReadFilter(tk), storage.ExpirationTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+func (s *snapshotReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readPage(s.tx, store, storage.NewReadFilter(tk), opts, storage.ExpirationTime(ctx))
+}
+
+func (s *snapshotReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := readUserTuple(s.tx, store, tk, storage.ExpirationTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entry)
+	return entry.tuple(), nil
+}
+
+func (s *snapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := readUsersetTuples(s.tx, store, filter, storage.ExpirationTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+func (s *snapshotReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := readStartingWithUser(s.tx, store, filter, storage.ExpirationTime(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	observeExpirations(ctx, entries...)
+	return storage.NewStaticTupleIterator(tuplesOf(entries)), nil
+}
+
+func (s *snapshotReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readTupleConditions(s.tx, store, filter, storage.ExpirationTime(ctx))
+}
+
+func (s *snapshotReader) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = s.tx.Rollback()
+}
@@ -0,0 +1,65 @@
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/openfga/openfga/assets"
+)
+
+// Migrate creates the tables of the datastore in the keyspace of the session, by running the statements of the
+// embedded migration scripts in the order of their names. The statements are idempotent, so Migrate may be run
+// against a keyspace whose schema is already up to date.
+func Migrate(ctx context.Context, session *gocql.Session) error {
+	entries, err := fs.ReadDir(assets.EmbedMigrations, assets.CassandraMigrationDir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".cql") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		script, err := fs.ReadFile(assets.EmbedMigrations, path.Join(assets.CassandraMigrationDir, name))
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range statements(string(script)) {
+			if err := session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+				return fmt.Errorf("failed to run migration %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// statements returns the statements of the script, which are separated by semicolons, without the comments.
+func statements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+
+	return stmts
+}
@@ -0,0 +1,134 @@
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+)
+
+var _ storage.SnapshotReader = (*snapshotReader)(nil)
+
+// snapshotReader reads the tuples of a store from an in-memory copy of its tuples. Cassandra has no snapshot reads, so
+// the copy is made by reading every tuple of the store, in the order in which they were written, on the first read of
+// the reader. The writes committed while the copy is made may or may not be observed.
+type snapshotReader struct {
+	datastore *Cassandra
+	store     string
+
+	once   sync.Once
+	reader storage.SnapshotReader
+	err    error
+}
+
+// load copies the tuples of the store, once.
+func (s *snapshotReader) load(ctx context.Context) (storage.SnapshotReader, error) {
+	s.once.Do(func() {
+		s.reader, s.err = s.datastore.copyStore(ctx, s.store)
+	})
+
+	return s.reader, s.err
+}
+
+// copyStore returns a reader of a copy of the tuples of the store.
+func (c *Cassandra) copyStore(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	queries, err := c.dayQueries(ctx, store, "")
+	if err != nil {
+		return nil, err
+	}
+
+	iter := newTupleIterator(queries, storage.ReadFilter{}, time.Now())
+	defer iter.Stop()
+
+	var tuples []*openfgav1.Tuple
+	expirations := map[*openfgav1.Tuple]time.Time{}
+	conditions := map[*openfgav1.Tuple]*storage.TupleCondition{}
+	for {
+		record, err := iter.nextRecord()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return memory.NewSnapshot(store, tuples, expirations, conditions), nil
+			}
+			return nil, err
+		}
+
+		t := record.tuple()
+		tuples = append(tuples, t)
+
+		if !record.expiresAt.IsZero() {
+			expirations[t] = record.expiresAt
+		}
+
+		condition, err := record.condition()
+		if err != nil {
+			return nil, err
+		}
+		if condition != nil {
+			conditions[t] = condition
+		}
+	}
+}
+
+func (s *snapshotReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.Read(ctx, store, tk)
+}
+
+func (s *snapshotReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reader.ReadPage(ctx, store, tk, opts)
+}
+
+func (s *snapshotReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ReadUserTuple(ctx, store, tk)
+}
+
+func (s *snapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ReadUsersetTuples(ctx, store, filter)
+}
+
+func (s *snapshotReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ReadStartingWithUser(ctx, store, filter)
+}
+
+func (s *snapshotReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	reader, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.ReadTupleConditions(ctx, store, filter)
+}
+
+func (s *snapshotReader) Close() {
+	if s.reader != nil {
+		s.reader.Close()
+	}
+}
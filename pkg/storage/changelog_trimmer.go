@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultChangelogRetentionPeriod = 30 * 24 * time.Hour
+
+	defaultChangelogTrimmerInterval  = 1 * time.Hour
+	defaultChangelogTrimmerBatchSize = 1000
+)
+
+// ChangelogTrimmer periodically deletes the changes older than the retention period from the changelog of every
+// store, see ChangelogBackend.DeleteChanges.
+type ChangelogTrimmer struct {
+	backend         ChangelogBackend
+	logger          logger.Logger
+	retentionPeriod time.Duration
+	interval        time.Duration
+	batchSize       int
+}
+
+type ChangelogTrimmerOption func(t *ChangelogTrimmer)
+
+// WithChangelogTrimmerRetentionPeriod sets how long the changes are kept in the changelog.
+func WithChangelogTrimmerRetentionPeriod(retentionPeriod time.Duration) ChangelogTrimmerOption {
+	return func(t *ChangelogTrimmer) {
+		t.retentionPeriod = retentionPeriod
+	}
+}
+
+// WithChangelogTrimmerInterval sets how long the trimmer waits between two deletions of the old changes.
+func WithChangelogTrimmerInterval(interval time.Duration) ChangelogTrimmerOption {
+	return func(t *ChangelogTrimmer) {
+		t.interval = interval
+	}
+}
+
+// WithChangelogTrimmerBatchSize sets the maximum number of changes deleted in a single call to the datastore.
+func WithChangelogTrimmerBatchSize(batchSize int) ChangelogTrimmerOption {
+	return func(t *ChangelogTrimmer) {
+		t.batchSize = batchSize
+	}
+}
+
+func WithChangelogTrimmerLogger(l logger.Logger) ChangelogTrimmerOption {
+	return func(t *ChangelogTrimmer) {
+		t.logger = l
+	}
+}
+
+func NewChangelogTrimmer(backend ChangelogBackend, opts ...ChangelogTrimmerOption) *ChangelogTrimmer {
+	t := &ChangelogTrimmer{
+		backend:         backend,
+		logger:          logger.NewNoopLogger(),
+		retentionPeriod: DefaultChangelogRetentionPeriod,
+		interval:        defaultChangelogTrimmerInterval,
+		batchSize:       defaultChangelogTrimmerBatchSize,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Run deletes the old changes every interval until the context is cancelled. Errors are logged and the deletion
+// is retried on the next interval.
+func (t *ChangelogTrimmer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := t.Trim(ctx)
+		if err != nil && ctx.Err() == nil {
+			t.logger.Error("failed to trim the changelog", zap.Error(err))
+		}
+
+		if deleted > 0 {
+			t.logger.Debug("trimmed the changelog", zap.Int("count", deleted))
+		}
+	}
+}
+
+// Trim deletes every change older than the retention period, one batch at a time, and returns the number of
+// changes deleted.
+func (t *ChangelogTrimmer) Trim(ctx context.Context) (int, error) {
+	return TrimChangelog(ctx, t.backend, "", time.Now().Add(-t.retentionPeriod), t.batchSize)
+}
+
+// TrimChangelog deletes every change that occurred before `before` in the provided store, or in every store if
+// it is empty, in batches of `batchSize` changes, and returns the number of changes deleted.
+func TrimChangelog(ctx context.Context, backend ChangelogBackend, store string, before time.Time, batchSize int) (int, error) {
+	var total int
+	for {
+		deleted, err := backend.DeleteChanges(ctx, store, before, batchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+
+		if deleted < batchSize {
+			return total, nil
+		}
+	}
+}
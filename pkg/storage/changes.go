@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+const readChangesAfterPageSize = 1000
+
+// ErrTooManyChanges is returned by ReadChangesAfter when more changes than the limit occurred after the point in time.
+var ErrTooManyChanges = errors.New("too many changes occurred after the point in time")
+
+// ReadChangesAfter returns the changes of a store that occurred after a point in time, oldest first. The changes are
+// read from the changelog starting after the continuation token `from`, or at the beginning of the changelog if it is
+// empty, and those that occurred at or before `after` are skipped, unless it is zero. Unlike ReadChanges, the changes
+// within the horizon offset are included. If more than `limit` changes are left, ErrTooManyChanges is returned.
+func ReadChangesAfter(ctx context.Context, backend ChangelogBackend, store string, after time.Time, from string, limit int) ([]*openfgav1.TupleChange, error) {
+	filter := ReadChangesFilter{StartTime: after}
+
+	var changes []*openfgav1.TupleChange
+	for {
+		page, token, err := backend.ReadChangesWithFilter(ctx, store, filter, PaginationOptions{PageSize: readChangesAfterPageSize, From: from}, 0)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return changes, nil
+			}
+			return nil, err
+		}
+
+		for _, change := range page {
+			if !after.IsZero() && !change.GetTimestamp().AsTime().After(after) {
+				continue
+			}
+
+			if len(changes) == limit {
+				return nil, ErrTooManyChanges
+			}
+			changes = append(changes, change)
+		}
+
+		if len(page) < readChangesAfterPageSize {
+			return changes, nil
+		}
+		from = string(token)
+	}
+}
+
+// ContinuationTokenTime returns the time of the change up to which a ReadChanges continuation token of a datastore
+// read the changelog, from the ULID of the change it holds: as '<ULID>|<object type>', or as the 'ulid' of a JSON
+// object for the SQL datastores. It returns false if the token holds no ULID, like those of the memory datastore,
+// which are positions in the changelog.
+func ContinuationTokenTime(token string) (time.Time, bool) {
+	id, _, _ := strings.Cut(token, "|")
+
+	var object struct {
+		ULID string `json:"ulid"`
+	}
+	if err := json.Unmarshal([]byte(token), &object); err == nil {
+		id = object.ULID
+	}
+
+	parsed, err := ulid.ParseStrict(id)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return ulid.Time(parsed.Time()), true
+}
+
+// NextChangeULID returns the ULID of a change made at `now` after the change of ULID `latest`, if any: a ULID of the
+// time if it is after the time of `latest`, or else the least ULID greater than `latest`. The datastores that
+// serialize the writes of a store order its changelog like its writes with it, so that a conditional write (see
+// WithExpectedChangelogToken) observes every change committed before it even if the clocks of the writers disagree.
+func NextChangeULID(now time.Time, latest string) string {
+	floor, err := ulid.ParseStrict(latest)
+	if err != nil || ulid.Timestamp(now) > floor.Time() {
+		return ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
+	}
+
+	next := floor
+	for i := len(next) - 1; i >= 6; i-- { // the first 6 bytes are the time, the others the entropy
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+
+	return next.String()
+}
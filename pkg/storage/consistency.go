@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// maxConsistencyTokenClockSkew is how far in the future the time of a consistency token may be, since the token of a
+// write is created from the clock of the server which committed it.
+const maxConsistencyTokenClockSkew = time.Second
+
+// ErrInvalidConsistencyToken is returned by ContextWithConsistencyToken when the token is not the ULID of a change, or
+// when it is dated in the future.
+var ErrInvalidConsistencyToken = errors.New("invalid consistency token")
+
+type consistencyTimeCtxKey struct{}
+
+// ContextWithConsistencyToken returns a context carrying a consistency token, which is the ULID of the last change a
+// write committed in the changelog, see WithCommittedChangeULID. The reads made with the context must observe the
+// write: neither the datastores nor the caches in front of them may serve them from an older state of the store, such
+// as a follower read or a cached result. The tokens dated in the future are rejected, as they would keep the reads
+// from being served by a follower read or a cache until then.
+func ContextWithConsistencyToken(ctx context.Context, token string) (context.Context, error) {
+	id, err := ulid.ParseStrict(token)
+	if err != nil {
+		return nil, ErrInvalidConsistencyToken
+	}
+
+	// the time of a ULID is truncated to the millisecond
+	consistencyTime := ulid.Time(id.Time()).Add(time.Millisecond)
+	if consistencyTime.After(time.Now().Add(maxConsistencyTokenClockSkew)) {
+		return nil, ErrInvalidConsistencyToken
+	}
+
+	return context.WithValue(ctx, consistencyTimeCtxKey{}, consistencyTime), nil
+}
+
+// ConsistencyTimeFromContext returns the time before which the writes identified by the consistency token carried by
+// the context were committed, or the zero time if the context carries no token. The reads made with the context must
+// observe the state of the store at that time or later.
+func ConsistencyTimeFromContext(ctx context.Context) time.Time {
+	consistencyTime, _ := ctx.Value(consistencyTimeCtxKey{}).(time.Time)
+	return consistencyTime
+}
+
+type strongConsistencyCtxKey struct{}
+
+// ContextWithStrongConsistency returns a context whose reads must observe every write committed before them: they
+// are neither served by a read replica nor by a follower read.
+func ContextWithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strongConsistencyCtxKey{}, true)
+}
+
+// StaleReadsAllowed reports whether a read made with the context may observe the store as it was up to `staleness`
+// ago, as the reads of a read replica lagging behind its primary do. They may not if the context requires strong
+// consistency, see ContextWithStrongConsistency, or if its consistency token may not be observed by such a read.
+func StaleReadsAllowed(ctx context.Context, staleness time.Duration) bool {
+	if strong, _ := ctx.Value(strongConsistencyCtxKey{}).(bool); strong {
+		return false
+	}
+
+	consistencyTime := ConsistencyTimeFromContext(ctx)
+	return consistencyTime.IsZero() || time.Since(consistencyTime) > staleness
+}
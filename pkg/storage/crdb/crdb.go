@@ -0,0 +1,474 @@
+// Package crdb contains an implementation of the storage interface that is optimized for CockroachDB.
+//
+// CockroachDB speaks the Postgres wire protocol and uses the same schema as the postgres datastore, so
+// most of the implementation is shared with it. On top of it, this datastore retries transactions that
+// fail with a serialization error (SQLSTATE 40001), and can serve tuple reads from follower replicas
+// by using `AS OF SYSTEM TIME` with a configurable staleness.
+package crdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/jackc/pgx/v5/pgconn"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/postgres"
+	"github.com/openfga/openfga/pkg/storage/sqlcommon"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("openfga/pkg/storage/crdb")
+
+const (
+	// serializationFailureCode is the SQLSTATE returned by CockroachDB when a transaction must be retried.
+	serializationFailureCode = "40001"
+
+	defaultMaxRetries = 5
+)
+
+// CRDB is a CockroachDB datastore. Everything that is not overridden here is delegated to the
+// postgres datastore.
+type CRDB struct {
+	*postgres.Postgres
+
+	stbl                  sq.StatementBuilderType
+	db                    *sqlcommon.Pool
+	followerReadStaleness time.Duration
+	maxRetries            uint64
+	readPageSize          int
+}
+
+var _ storage.OpenFGADatastore = (*CRDB)(nil)
+
+type CRDBOption func(c *CRDB)
+
+// WithFollowerReadStaleness makes tuple reads (e.g. the reads issued by Read, Check and ListObjects) use
+// `AS OF SYSTEM TIME` with the provided staleness, so that they can be served by the closest replica
+// instead of the leaseholder. Reads may then not observe writes made in the last 'staleness'. A staleness
+// of 0 (the default) disables follower reads.
+func WithFollowerReadStaleness(staleness time.Duration) CRDBOption {
+	return func(c *CRDB) {
+		c.followerReadStaleness = staleness
+	}
+}
+
+// WithMaxRetries sets the maximum number of times a transaction that failed with a serialization error is retried.
+func WithMaxRetries(maxRetries uint64) CRDBOption {
+	return func(c *CRDB) {
+		c.maxRetries = maxRetries
+	}
+}
+
+func New(uri string, cfg *sqlcommon.Config, opts ...CRDBOption) (*CRDB, error) {
+	db, err := postgres.OpenDB(uri, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cockroachdb connection: %w", err)
+	}
+
+	pool := sqlcommon.NewPool(db, "cockroachdb", cfg)
+	c := &CRDB{
+		Postgres:     postgres.NewWithPool(pool, cfg, postgres.WithoutStoreLocks()),
+		stbl:         sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(pool),
+		db:           pool,
+		maxRetries:   defaultMaxRetries,
+		readPageSize: cfg.ReadPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.followerReadStaleness > 0 {
+		cfg.Logger.Info("cockroachdb follower reads enabled", zap.Duration("staleness", c.followerReadStaleness))
+	}
+
+	return c, nil
+}
+
+// isRetryable reports whether the provided error is a CockroachDB transaction retry error.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == serializationFailureCode
+}
+
+// retry runs fn until it succeeds, fails with an error which is not retryable, or c.maxRetries is reached.
+func (c *CRDB) retry(ctx context.Context, fn func() error) error {
+	policy := backoff.WithContext(
+		backoff.WithMaxRetries(backoff.NewExponentialBackOff(), c.maxRetries),
+		ctx,
+	)
+
+	return backoff.Retry(func() error {
+		err := fn()
+		if err != nil && !isRetryable(err) {
+			return backoff.Permanent(err)
+		}
+
+		return err
+	}, policy)
+}
+
+// tupleTable returns the table expression to read tuples from, including the `AS OF SYSTEM TIME`
+// clause if follower reads are enabled. The reads which may not be stale are served by the
+// leaseholder, see storage.StaleReadsAllowed.
+func (c *CRDB) tupleTable(ctx context.Context) string {
+	if c.followerReadStaleness <= 0 || !storage.StaleReadsAllowed(ctx, c.followerReadStaleness) {
+		return "tuple"
+	}
+
+	return fmt.Sprintf("tuple AS OF SYSTEM TIME '-%dms'", c.followerReadStaleness.Milliseconds())
+}
+
+func (c *CRDB) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "crdb.Read")
+	defer span.End()
+
+	return c.read(ctx, store, storage.NewReadFilter(tupleKey), nil)
+}
+
+func (c *CRDB) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := tracer.Start(ctx, "crdb.ReadPage")
+	defer span.End()
+
+	iter, err := c.read(ctx, store, storage.NewReadFilter(tupleKey), &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Stop()
+
+	return iter.ToArray(opts)
+}
+
+func (c *CRDB) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := tracer.Start(ctx, "crdb.ReadPageWithFilter")
+	defer span.End()
+
+	iter, err := c.read(ctx, store, filter, &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Stop()
+
+	return iter.ToArray(opts)
+}
+
+func (c *CRDB) read(ctx context.Context, store string, filter storage.ReadFilter, opts *storage.PaginationOptions) (*sqlcommon.SQLTupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "crdb.read")
+	defer span.End()
+
+	sb := c.stbl.
+		Select(sqlcommon.TupleColumns...).
+		From(c.tupleTable(ctx)).
+		Where(sq.Eq{"store": store}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
+	if opts != nil {
+		sb = sb.OrderBy("ulid")
+	}
+
+	sb = sb.Where(sqlcommon.MatchesFilter(filter))
+	if opts != nil && opts.From != "" {
+		token, err := sqlcommon.UnmarshallContToken(opts.From)
+		if err != nil {
+			return nil, err
+		}
+		sb = sb.Where(sq.GtOrEq{"ulid": token.Ulid})
+	}
+	if opts != nil && opts.PageSize != 0 {
+		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
+	}
+
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	return sqlcommon.NewSQLTupleIterator(ctx, rows), nil
+}
+
+func (c *CRDB) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	ctx, span := tracer.Start(ctx, "crdb.ReadUserTuple")
+	defer span.End()
+
+	objectType, objectID := tupleUtils.SplitObject(tupleKey.GetObject())
+	userType := tupleUtils.GetUserTypeFromUser(tupleKey.GetUser())
+
+	var record sqlcommon.TupleRecord
+	err := c.stbl.
+		Select("object_type", "object_id", "relation", "_user", "expires_at").
+		From(c.tupleTable(ctx)).
+		Where(sq.Eq{
+			"store":       store,
+			"object_type": objectType,
+			"object_id":   objectID,
+			"relation":    tupleKey.GetRelation(),
+			"_user":       tupleKey.GetUser(),
+			"user_type":   userType,
+		}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx))).
+		QueryRowContext(ctx).
+		Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &record.ExpiresAt)
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	storage.TupleExpirationsFromContext(ctx).Observe(record.ExpiresAt.Time)
+
+	return record.AsTuple(), nil
+}
+
+func (c *CRDB) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "crdb.ReadUsersetTuples")
+	defer span.End()
+
+	sb := c.stbl.Select(sqlcommon.TupleColumns...).
+		From(c.tupleTable(ctx)).
+		Where(sq.Eq{"store": store}).
+		Where(sq.Eq{"user_type": tupleUtils.UserSet}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
+
+	objectType, objectID := tupleUtils.SplitObject(filter.Object)
+	if objectType != "" {
+		sb = sb.Where(sq.Eq{"object_type": objectType})
+	}
+	if objectID != "" {
+		sb = sb.Where(sq.Eq{"object_id": objectID})
+	}
+	if filter.Relation != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.Relation})
+	}
+	if len(filter.AllowedUserTypeRestrictions) > 0 {
+		orConditions := sq.Or{}
+		for _, userset := range filter.AllowedUserTypeRestrictions {
+			if _, ok := userset.RelationOrWildcard.(*openfgav1.RelationReference_Relation); ok {
+				orConditions = append(orConditions, sq.Like{"_user": userset.Type + ":%#" + userset.GetRelation()})
+			}
+			if _, ok := userset.RelationOrWildcard.(*openfgav1.RelationReference_Wildcard); ok {
+				orConditions = append(orConditions, sq.Eq{"_user": userset.Type + ":*"})
+			}
+		}
+		sb = sb.Where(orConditions)
+	}
+	// the tuples are read by pages ordered by their unique key in the store
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"object_type", "object_id", "relation", "_user"}, c.readPageSize), nil
+}
+
+func (c *CRDB) ReadStartingWithUser(ctx context.Context, store string, opts storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "crdb.ReadStartingWithUser")
+	defer span.End()
+
+	var targetUsersArg []string
+	for _, u := range opts.UserFilter {
+		targetUser := u.GetObject()
+		if u.GetRelation() != "" {
+			targetUser = strings.Join([]string{u.GetObject(), u.GetRelation()}, "#")
+		}
+		targetUsersArg = append(targetUsersArg, targetUser)
+	}
+
+	sb := c.stbl.
+		Select(sqlcommon.TupleColumns...).
+		From(c.tupleTable(ctx)).
+		Where(sq.Eq{
+			"store":       store,
+			"object_type": opts.ObjectType,
+			"relation":    opts.Relation,
+			"_user":       targetUsersArg,
+		}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
+
+	// the tuples are read by pages ordered by their unique key given their store, object type and relation
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"_user", "object_id"}, c.readPageSize), nil
+}
+
+// Snapshot see storage.SnapshotBackend.Snapshot. The reads of the snapshot are run in a read-only transaction, which
+// CockroachDB runs at its serializable isolation level, and are never follower reads.
+func (c *CRDB) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	ctx, span := tracer.Start(ctx, "crdb.Snapshot")
+	defer span.End()
+
+	return c.Postgres.Snapshot(ctx, store)
+}
+
+func (c *CRDB) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "crdb.Write")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.Write(ctx, store, deletes, writes, opts...)
+	})
+}
+
+// CommitStagedWrite see storage.StagedWriteBackend.CommitStagedWrite. The commit is retried like the writes.
+func (c *CRDB) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "crdb.CommitStagedWrite")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.CommitStagedWrite(ctx, store, id, opts...)
+	})
+}
+
+func (c *CRDB) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteWithExpiry")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteWithExpiry(ctx, store, deletes, writes, expiresAt, opts...)
+	})
+}
+
+func (c *CRDB) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteWithCondition")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteWithCondition(ctx, store, deletes, writes, condition, opts...)
+	})
+}
+
+func (c *CRDB) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "crdb.DeleteExpiredTuples")
+	defer span.End()
+
+	var deleted int
+	err := c.retry(ctx, func() error {
+		var err error
+		deleted, err = c.Postgres.DeleteExpiredTuples(ctx, limit)
+		return err
+	})
+
+	return deleted, err
+}
+
+func (c *CRDB) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "crdb.DeleteChanges")
+	defer span.End()
+
+	var deleted int
+	err := c.retry(ctx, func() error {
+		var err error
+		deleted, err = c.Postgres.DeleteChanges(ctx, store, before, limit)
+		return err
+	})
+
+	return deleted, err
+}
+
+func (c *CRDB) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteAuthorizationModel")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteAuthorizationModel(ctx, store, model)
+	})
+}
+
+func (c *CRDB) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations)
+	})
+}
+
+func (c *CRDB) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	ctx, span := tracer.Start(ctx, "crdb.CreateStore")
+	defer span.End()
+
+	var created *openfgav1.Store
+	err := c.retry(ctx, func() error {
+		var err error
+		created, err = c.Postgres.CreateStore(ctx, store)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (c *CRDB) DeleteStore(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "crdb.DeleteStore")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.DeleteStore(ctx, id)
+	})
+}
+
+func (c *CRDB) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	ctx, span := tracer.Start(ctx, "crdb.UndeleteStore")
+	defer span.End()
+
+	var store *openfgav1.Store
+	err := c.retry(ctx, func() error {
+		var err error
+		store, err = c.Postgres.UndeleteStore(ctx, id, deletedAfter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (c *CRDB) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "crdb.PurgeDeletedStores")
+	defer span.End()
+
+	var purged int
+	err := c.retry(ctx, func() error {
+		var err error
+		purged, err = c.Postgres.PurgeDeletedStores(ctx, deletedBefore, limit)
+		return err
+	})
+
+	return purged, err
+}
+
+func (c *CRDB) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteStoreMetadata")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteStoreMetadata(ctx, id, metadata)
+	})
+}
+
+func (c *CRDB) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "crdb.DeleteAuthorizationModel")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.DeleteAuthorizationModel(ctx, store, id)
+	})
+}
+
+func (c *CRDB) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "crdb.PinAuthorizationModel")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.PinAuthorizationModel(ctx, store, id)
+	})
+}
+
+func (c *CRDB) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
+	ctx, span := tracer.Start(ctx, "crdb.WriteAssertions")
+	defer span.End()
+
+	return c.retry(ctx, func() error {
+		return c.Postgres.WriteAssertions(ctx, store, modelID, assertions)
+	})
+}
@@ -0,0 +1,78 @@
+package crdb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/sqlcommon"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	retryableErr := sqlcommon.HandleSQLError(&pgconn.PgError{Code: serializationFailureCode})
+	require.True(t, isRetryable(retryableErr))
+
+	t.Run("retries_serialization_failures", func(t *testing.T) {
+		c := &CRDB{maxRetries: 3}
+
+		attempts := 0
+		err := c.retry(context.Background(), func() error {
+			attempts++
+			if attempts < 3 {
+				return retryableErr
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives_up_after_max_retries", func(t *testing.T) {
+		c := &CRDB{maxRetries: 2}
+
+		attempts := 0
+		err := c.retry(context.Background(), func() error {
+			attempts++
+			return retryableErr
+		})
+		require.ErrorIs(t, err, retryableErr)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("does_not_retry_other_errors", func(t *testing.T) {
+		c := &CRDB{maxRetries: 3}
+
+		otherErr := fmt.Errorf("some error")
+		attempts := 0
+		err := c.retry(context.Background(), func() error {
+			attempts++
+			return otherErr
+		})
+		require.ErrorIs(t, err, otherErr)
+		require.Equal(t, 1, attempts)
+	})
+}
+
+func TestTupleTable(t *testing.T) {
+	ctx := context.Background()
+	followerReads := &CRDB{followerReadStaleness: 4800 * time.Millisecond}
+
+	require.Equal(t, "tuple", (&CRDB{}).tupleTable(ctx))
+	require.Equal(t, "tuple AS OF SYSTEM TIME '-4800ms'", followerReads.tupleTable(ctx))
+
+	t.Run("recent_consistency_tokens_are_read_from_the_leaseholder", func(t *testing.T) {
+		ctx, err := storage.ContextWithConsistencyToken(ctx, ulid.Make().String())
+		require.NoError(t, err)
+		require.Equal(t, "tuple", followerReads.tupleTable(ctx))
+
+		ctx, err = storage.ContextWithConsistencyToken(ctx, ulid.MustNew(ulid.Timestamp(time.Now().Add(-time.Minute)), nil).String())
+		require.NoError(t, err)
+		require.Equal(t, "tuple AS OF SYSTEM TIME '-4800ms'", followerReads.tupleTable(ctx))
+	})
+}
@@ -18,18 +18,41 @@ var (
 	ErrMismatchObjectType       = errors.New("mismatched types in request and continuation token")
 	ErrExceededWriteBatchLimit  = errors.New("number of operations exceeded write batch limit")
 	ErrCancelled                = errors.New("request has been cancelled")
+	ErrChangelogConflict        = errors.New("the changelog of the store has changed since the expected changelog token")
+
+	// ErrExpectedChangelogTokenUnsupported is returned by the datastores that cannot check the changelog in the
+	// transaction of a write, see WithExpectedChangelogToken.
+	ErrExpectedChangelogTokenUnsupported = errors.New("expected changelog tokens are not supported by this datastore")
 )
 
 func ExceededMaxTypeDefinitionsLimitError(limit int) error {
 	return fmt.Errorf("exceeded number of allowed type definitions: %d", limit)
 }
 
+// InvalidWriteInput is the error of a write deleting a tuple which does not exist, or writing a tuple which already
+// exists. It wraps ErrInvalidWriteInput.
+type InvalidWriteInput struct {
+	TupleKey  *openfgav1.TupleKey
+	Operation openfgav1.TupleOperation
+}
+
+func (e *InvalidWriteInput) Error() string {
+	tk := e.TupleKey
+	if e.Operation == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+		return fmt.Sprintf("cannot delete a tuple which does not exist: user: '%s', relation: '%s', object: '%s': %s", tk.GetUser(), tk.GetRelation(), tk.GetObject(), ErrInvalidWriteInput)
+	}
+
+	return fmt.Sprintf("cannot write a tuple which already exists: user: '%s', relation: '%s', object: '%s': %s", tk.GetUser(), tk.GetRelation(), tk.GetObject(), ErrInvalidWriteInput)
+}
+
+func (e *InvalidWriteInput) Unwrap() error {
+	return ErrInvalidWriteInput
+}
+
 func InvalidWriteInputError(tk *openfgav1.TupleKey, operation openfgav1.TupleOperation) error {
 	switch operation {
-	case openfgav1.TupleOperation_TUPLE_OPERATION_DELETE:
-		return fmt.Errorf("cannot delete a tuple which does not exist: user: '%s', relation: '%s', object: '%s': %w", tk.GetUser(), tk.GetRelation(), tk.GetObject(), ErrInvalidWriteInput)
-	case openfgav1.TupleOperation_TUPLE_OPERATION_WRITE:
-		return fmt.Errorf("cannot write a tuple which already exists: user: '%s', relation: '%s', object: '%s': %w", tk.GetUser(), tk.GetRelation(), tk.GetObject(), ErrInvalidWriteInput)
+	case openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, openfgav1.TupleOperation_TUPLE_OPERATION_WRITE:
+		return &InvalidWriteInput{TupleKey: tk, Operation: operation}
 	default:
 		return nil
 	}
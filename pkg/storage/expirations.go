@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type tupleExpirationsCtxKey struct{}
+
+// TupleExpirations records the earliest expiration of the tuples read by a query, so that the results derived from
+// them are not reused once one of them expires. A TupleExpirations is attached to the context of the query with
+// ContextWithTupleExpirations, and the datastores record in it the expirations of the tuples returned by Read,
+// ReadUserTuple, ReadUsersetTuples and ReadStartingWithUser, from the same rows. It is safe for concurrent use.
+type TupleExpirations struct {
+	mu       sync.Mutex
+	earliest time.Time
+}
+
+// ContextWithTupleExpirations attaches the provided TupleExpirations to the parent context.
+func ContextWithTupleExpirations(parent context.Context, expirations *TupleExpirations) context.Context {
+	return context.WithValue(parent, tupleExpirationsCtxKey{}, expirations)
+}
+
+// TupleExpirationsFromContext returns the TupleExpirations attached to the provided context, or nil. The methods of
+// TupleExpirations are no-ops on a nil TupleExpirations.
+func TupleExpirationsFromContext(ctx context.Context) *TupleExpirations {
+	expirations, _ := ctx.Value(tupleExpirationsCtxKey{}).(*TupleExpirations)
+	return expirations
+}
+
+// Observe records that a tuple expiring at `expiresAt` was read.
+func (e *TupleExpirations) Observe(expiresAt time.Time) {
+	if e == nil || expiresAt.IsZero() {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.earliest.IsZero() || expiresAt.Before(e.earliest) {
+		e.earliest = expiresAt
+	}
+}
+
+// Earliest is the earliest expiration of the tuples read, or the zero time if none of them expires.
+func (e *TupleExpirations) Earliest() time.Time {
+	if e == nil {
+		return time.Time{}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.earliest
+}
+
+type expirationTimeCtxKey struct{}
+
+// ContextWithExpirationTime returns a context whose reads of tuples evaluate the expirations of the tuples as of
+// `asOf` instead of the current time, so that the reads of a past state of a store return the tuples which have
+// expired since, as long as they have not been deleted yet.
+func ContextWithExpirationTime(parent context.Context, asOf time.Time) context.Context {
+	return context.WithValue(parent, expirationTimeCtxKey{}, asOf)
+}
+
+// ExpirationTime returns the time as of which the reads made with the context evaluate the expirations of the tuples:
+// the time attached with ContextWithExpirationTime, or else the current time.
+func ExpirationTime(ctx context.Context) time.Time {
+	if asOf, ok := ctx.Value(expirationTimeCtxKey{}).(time.Time); ok {
+		return asOf
+	}
+
+	return time.Now()
+}
+
+// WithTupleExpirationsOf returns the parent context with the TupleExpirations and the expiration time attached to
+// ctx, if any, for the datastore wrappers which read with a new context.
+func WithTupleExpirationsOf(parent, ctx context.Context) context.Context {
+	if expirations := TupleExpirationsFromContext(ctx); expirations != nil {
+		parent = ContextWithTupleExpirations(parent, expirations)
+	}
+
+	if asOf, ok := ctx.Value(expirationTimeCtxKey{}).(time.Time); ok {
+		parent = ContextWithExpirationTime(parent, asOf)
+	}
+
+	return parent
+}
@@ -90,13 +90,24 @@ func NewStaticTupleKeyIterator(tupleKeys []*openfgav1.TupleKey) TupleKeyIterator
 	return iter
 }
 
+// tupleKeyNexter is implemented by the tuple iterators which can return the key of their next tuple without building
+// the tuple (e.g. without decoding its timestamp), as the datastore iterators do.
+type tupleKeyNexter interface {
+	NextKey() (*openfgav1.TupleKey, error)
+}
+
 type tupleKeyIterator struct {
-	iter TupleIterator
+	iter    TupleIterator
+	nextKey func() (*openfgav1.TupleKey, error)
 }
 
 var _ TupleKeyIterator = (*tupleKeyIterator)(nil)
 
 func (t *tupleKeyIterator) Next() (*openfgav1.TupleKey, error) {
+	if t.nextKey != nil {
+		return t.nextKey()
+	}
+
 	tuple, err := t.iter.Next()
 	return tuple.GetKey(), err
 }
@@ -106,8 +117,23 @@ func (t *tupleKeyIterator) Stop() {
 }
 
 // NewTupleKeyIteratorFromTupleIterator takes a TupleIterator and yields all of the TupleKeys from it as a TupleKeyIterator.
+// The tuples of the iterators which can return their keys alone, including the ones combined by NewCombinedIterator,
+// are never built.
 func NewTupleKeyIteratorFromTupleIterator(iter TupleIterator) TupleKeyIterator {
-	return &tupleKeyIterator{iter}
+	switch it := iter.(type) {
+	case *combinedIterator[*openfgav1.Tuple]:
+		iters := make([]TupleKeyIterator, 0, len(it.iters))
+		for _, iter := range it.iters {
+			if iter != nil {
+				iters = append(iters, NewTupleKeyIteratorFromTupleIterator(iter))
+			}
+		}
+		return NewCombinedIterator(iters...)
+	case tupleKeyNexter:
+		return &tupleKeyIterator{iter: iter, nextKey: it.NextKey}
+	}
+
+	return &tupleKeyIterator{iter: iter}
 }
 
 type staticIterator[T any] struct {
@@ -37,6 +37,52 @@ func TestStaticTupleKeyIterator(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+// keyOnlyTupleIterator is a tuple iterator which only returns the keys of its tuples.
+type keyOnlyTupleIterator struct {
+	keys []*openfgav1.TupleKey
+}
+
+func (k *keyOnlyTupleIterator) Next() (*openfgav1.Tuple, error) {
+	panic("the tuples of the iterator must not be built")
+}
+
+func (k *keyOnlyTupleIterator) NextKey() (*openfgav1.TupleKey, error) {
+	if len(k.keys) == 0 {
+		return nil, ErrIteratorDone
+	}
+
+	key := k.keys[0]
+	k.keys = k.keys[1:]
+
+	return key, nil
+}
+
+func (k *keyOnlyTupleIterator) Stop() {}
+
+func TestTupleKeyIteratorFromTupleIterator(t *testing.T) {
+	anne := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+	bob := tuple.NewTupleKey("document:1", "viewer", "user:bob")
+
+	iter := NewTupleKeyIteratorFromTupleIterator(NewCombinedIterator[*openfgav1.Tuple](
+		NewStaticTupleIterator([]*openfgav1.Tuple{{Key: anne}}),
+		&keyOnlyTupleIterator{keys: []*openfgav1.TupleKey{bob}},
+	))
+	defer iter.Stop()
+
+	var actual []*openfgav1.TupleKey
+	for {
+		tk, err := iter.Next()
+		if err != nil {
+			require.ErrorIs(t, err, ErrIteratorDone)
+			break
+		}
+
+		actual = append(actual, tk)
+	}
+
+	require.Equal(t, []*openfgav1.TupleKey{anne, bob}, actual)
+}
+
 func TestCombinedIterator(t *testing.T) {
 
 	expected := []*openfgav1.TupleKey{
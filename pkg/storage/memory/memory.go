@@ -76,32 +76,65 @@ const (
 type MemoryBackend struct {
 	maxTuplesPerWrite             int
 	maxTypesPerAuthorizationModel int
-	mu                            sync.Mutex
+	maxTuples                     int
+	maxChanges                    int
+
+	// mu guards the data of the backend: the reads hold it for reading, so that they run concurrently, and the
+	// writes hold it for writing.
+	mu sync.RWMutex
 
 	// TupleBackend
 	// map: store => set of tuples
 	tuples map[string][]*openfgav1.Tuple /* GUARDED_BY(mu) */
 
+	// TupleExpirationBackend
+	// map: tuple => expiry time of the tuple, for the tuples written with an expiry
+	expirations map[*openfgav1.Tuple]time.Time /* GUARDED_BY(mu) */
+
+	// TupleConditionBackend
+	// map: tuple => condition of the tuple, for the tuples written with a condition
+	conditions map[*openfgav1.Tuple]*storage.TupleCondition /* GUARDED_BY(mu) */
+
+	// StagedWriteBackend
+	// map: store | staged write id => staged deletes and writes
+	stagedWrites map[string]*stagedWrite
+
 	// ChangelogBackend
 	// map: store => set of changes
 	changes map[string][]*openfgav1.TupleChange
 
+	// map: store => number of changes deleted by DeleteChanges, which offsets the continuation tokens of ReadChanges
+	deletedChanges map[string]int
+
 	// AuthorizationModelBackend
 	// map: store = > map: type definition id => type definition
 	authorizationModels map[string]map[string]*AuthorizationModelEntry /* GUARDED_BY(mu_) */
 
-	// map: store id => store data
+	// map: store => id of the pinned authorization model
+	pinnedModels map[string]string
+
+	// map: store id => store data, including the soft-deleted stores until they are purged
 	stores map[string]*openfgav1.Store
 
+	// map: store id => store metadata
+	storeMetadata map[string]*storage.StoreMetadata
+
 	// map: store id | authz model id => assertions
 	assertions map[string][]*openfgav1.Assertion
 }
 
 var _ storage.OpenFGADatastore = (*MemoryBackend)(nil)
 
+// stagedWrite is the deletes and writes of the batches of a staged write.
+type stagedWrite struct {
+	deletes storage.Deletes
+	writes  storage.Writes
+}
+
 type AuthorizationModelEntry struct {
-	model  *openfgav1.AuthorizationModel
-	latest bool
+	model       *openfgav1.AuthorizationModel
+	annotations storage.ModelAnnotations
+	latest      bool
 }
 
 // New creates a new empty MemoryBackend.
@@ -110,9 +143,15 @@ func New(opts ...StorageOption) storage.OpenFGADatastore {
 		maxTuplesPerWrite:             defaultMaxTuplesPerWrite,
 		maxTypesPerAuthorizationModel: defaultMaxTypesPerAuthorizationModel,
 		tuples:                        make(map[string][]*openfgav1.Tuple, 0),
+		expirations:                   make(map[*openfgav1.Tuple]time.Time, 0),
+		conditions:                    make(map[*openfgav1.Tuple]*storage.TupleCondition, 0),
+		stagedWrites:                  make(map[string]*stagedWrite, 0),
 		changes:                       make(map[string][]*openfgav1.TupleChange, 0),
+		deletedChanges:                make(map[string]int, 0),
 		authorizationModels:           make(map[string]map[string]*AuthorizationModelEntry),
+		pinnedModels:                  make(map[string]string, 0),
 		stores:                        make(map[string]*openfgav1.Store, 0),
+		storeMetadata:                 make(map[string]*storage.StoreMetadata, 0),
 		assertions:                    make(map[string][]*openfgav1.Assertion, 0),
 	}
 
@@ -131,24 +170,67 @@ func WithMaxTypesPerAuthorizationModel(n int) StorageOption {
 	return func(ds *MemoryBackend) { ds.maxTypesPerAuthorizationModel = n }
 }
 
+// WithMaxTuples bounds the number of tuples held by the backend across all the stores. When a write exceeds it, the
+// least recently written tuples are evicted, and their eviction is recorded as a delete in the changelog. If 0, the
+// number of tuples is not bounded.
+func WithMaxTuples(n int) StorageOption {
+	return func(ds *MemoryBackend) { ds.maxTuples = n }
+}
+
+// WithMaxChanges bounds the number of changes held in the changelog of each store. When it is exceeded, the oldest
+// changes are deleted as by DeleteChanges. If 0, the number of changes is not bounded.
+func WithMaxChanges(n int) StorageOption {
+	return func(ds *MemoryBackend) { ds.maxChanges = n }
+}
+
 // Close closes any open connections and cleans up residual resources
 // used by this storage adapter instance.
 func (s *MemoryBackend) Close() {
 }
 
+// Reset deletes all the data of the backend: the stores, their tuples, changes, models and assertions.
+func (s *MemoryBackend) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tuples = make(map[string][]*openfgav1.Tuple, 0)
+	s.expirations = make(map[*openfgav1.Tuple]time.Time, 0)
+	s.conditions = make(map[*openfgav1.Tuple]*storage.TupleCondition, 0)
+	s.changes = make(map[string][]*openfgav1.TupleChange, 0)
+	s.deletedChanges = make(map[string]int, 0)
+	s.authorizationModels = make(map[string]map[string]*AuthorizationModelEntry)
+	s.pinnedModels = make(map[string]string, 0)
+	s.stores = make(map[string]*openfgav1.Store, 0)
+	s.storeMetadata = make(map[string]*storage.StoreMetadata, 0)
+	s.assertions = make(map[string][]*openfgav1.Assertion, 0)
+}
+
 // Read See storage.TupleBackend.Read
 func (s *MemoryBackend) Read(ctx context.Context, store string, key *openfgav1.TupleKey) (storage.TupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "memory.Read")
 	defer span.End()
 
-	return s.read(ctx, store, key, storage.PaginationOptions{})
+	return s.read(ctx, store, storage.NewReadFilter(key), storage.PaginationOptions{})
 }
 
 func (s *MemoryBackend) ReadPage(ctx context.Context, store string, key *openfgav1.TupleKey, paginationOptions storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
 	ctx, span := tracer.Start(ctx, "memory.ReadPage")
 	defer span.End()
 
-	it, err := s.read(ctx, store, key, paginationOptions)
+	it, err := s.read(ctx, store, storage.NewReadFilter(key), paginationOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return it.tuples, it.continuationToken, nil
+}
+
+// ReadPageWithFilter See storage.TupleBackend.ReadPageWithFilter
+func (s *MemoryBackend) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := tracer.Start(ctx, "memory.ReadPageWithFilter")
+	defer span.End()
+
+	it, err := s.read(ctx, store, filter, paginationOptions)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -157,16 +239,23 @@ func (s *MemoryBackend) ReadPage(ctx context.Context, store string, key *openfga
 }
 
 func (s *MemoryBackend) ReadChanges(ctx context.Context, store, objectType string, paginationOptions storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
-	_, span := tracer.Start(ctx, "memory.ReadChanges")
+	ctx, span := tracer.Start(ctx, "memory.ReadChanges")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.ReadChangesWithFilter(ctx, store, storage.ReadChangesFilter{ObjectType: objectType}, paginationOptions, horizonOffset)
+}
+
+// ReadChangesWithFilter See storage.ChangelogBackend.ReadChangesWithFilter
+func (s *MemoryBackend) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, paginationOptions storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	_, span := tracer.Start(ctx, "memory.ReadChangesWithFilter")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	var err error
 	var from int64
 	var typeInToken string
-	var continuationToken string
 	if paginationOptions.From != "" {
 		tokens := strings.Split(paginationOptions.From, "|")
 		if len(tokens) == 2 {
@@ -179,107 +268,291 @@ func (s *MemoryBackend) ReadChanges(ctx context.Context, store, objectType strin
 		}
 	}
 
-	if typeInToken != "" && typeInToken != objectType {
+	if typeInToken != "" && typeInToken != filter.ObjectType {
 		return nil, nil, storage.ErrMismatchObjectType
 	}
 
-	var allChanges []*openfgav1.TupleChange
-	now := time.Now().UTC()
-	for _, change := range s.changes[store] {
-		if objectType == "" || (objectType != "" && strings.HasPrefix(change.TupleKey.Object, objectType+":")) {
-			if change.Timestamp.AsTime().After(now.Add(-horizonOffset)) {
-				break
-			}
-			allChanges = append(allChanges, change)
-		}
-	}
-	if len(allChanges) == 0 {
-		return nil, nil, storage.ErrNotFound
+	// the continuation tokens are positions in the changelog of the store, including the deleted changes
+	deleted := s.deletedChanges[store]
+	next := int(from) - deleted
+	if next < 0 {
+		next = 0
 	}
 
 	pageSize := storage.DefaultPageSize
 	if paginationOptions.PageSize > 0 {
 		pageSize = paginationOptions.PageSize
 	}
-	to := int(from) + pageSize
-	if len(allChanges) < to {
-		to = len(allChanges)
+
+	changes := s.changes[store]
+	horizon := time.Now().UTC().Add(-horizonOffset)
+
+	var res []*openfgav1.TupleChange
+	for ; next < len(changes) && len(res) < pageSize; next++ {
+		change := changes[next]
+		if change.Timestamp.AsTime().After(horizon) {
+			break
+		}
+		if matchChange(filter, change) {
+			res = append(res, change)
+		}
 	}
-	res := allChanges[from:to]
 	if len(res) == 0 {
 		return nil, nil, storage.ErrNotFound
 	}
 
-	continuationToken = strconv.Itoa(len(allChanges))
-	if to != len(allChanges) {
-		continuationToken = strconv.Itoa(to)
+	return res, []byte(fmt.Sprintf("%d|%s", next+deleted, filter.ObjectType)), nil
+}
+
+// matchChange reports whether the change matches the filter, see storage.ReadChangesFilter.
+func matchChange(filter storage.ReadChangesFilter, change *openfgav1.TupleChange) bool {
+	if filter.ObjectType != "" && tupleUtils.GetType(change.GetTupleKey().GetObject()) != filter.ObjectType {
+		return false
+	}
+	if filter.Relation != "" && change.GetTupleKey().GetRelation() != filter.Relation {
+		return false
+	}
+	if filter.Operation != nil && change.GetOperation() != *filter.Operation {
+		return false
+	}
+	if !filter.StartTime.IsZero() && change.GetTimestamp().AsTime().Before(filter.StartTime) {
+		return false
 	}
-	continuationToken = continuationToken + fmt.Sprintf("|%s", objectType)
 
-	return res, []byte(continuationToken), nil
+	return true
 }
 
-func (s *MemoryBackend) read(ctx context.Context, store string, tk *openfgav1.TupleKey, paginationOptions storage.PaginationOptions) (*staticIterator, error) {
-	_, span := tracer.Start(ctx, "memory.read")
+// DeleteChanges See storage.ChangelogBackend.DeleteChanges
+func (s *MemoryBackend) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "memory.DeleteChanges")
 	defer span.End()
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var matches []*openfgav1.Tuple
-	if tk.GetObject() == "" && tk.GetRelation() == "" && tk.GetUser() == "" {
-		matches = make([]*openfgav1.Tuple, len(s.tuples[store]))
-		copy(matches, s.tuples[store])
-	} else {
-		for _, t := range s.tuples[store] {
-			if match(tk, t.Key) {
-				matches = append(matches, t)
-			}
+	stores := []string{store}
+	if store == "" {
+		stores = make([]string, 0, len(s.changes))
+		for id := range s.changes {
+			stores = append(stores, id)
 		}
+		sort.Strings(stores)
 	}
 
-	var err error
-	var from int
-	if paginationOptions.From != "" {
-		from, err = strconv.Atoi(paginationOptions.From)
-		if err != nil {
-			telemetry.TraceError(span, err)
-			return nil, err
+	var deleted int
+	for _, id := range stores {
+		changes := s.changes[id]
+
+		// the changes are in the order in which they occurred
+		n := 0
+		for n < len(changes) && deleted+n < limit && changes[n].Timestamp.AsTime().Before(before) {
+			n++
+		}
+
+		if n == 0 {
+			continue
 		}
+
+		s.deletedChanges[id] += n
+		s.changes[id] = changes[n:]
+		deleted += n
+	}
+
+	return deleted, nil
+}
+
+// ReadStoreStats See storage.StoreStatsBackend.ReadStoreStats
+func (s *MemoryBackend) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	_, span := tracer.Start(ctx, "memory.ReadStoreStats")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	stats := &storage.StoreStats{
+		Tuples:              map[string]int64{},
+		AuthorizationModels: int64(len(s.authorizationModels[store])),
+		Changes:             int64(len(s.changes[store])),
 	}
 
-	if from <= len(matches) {
-		matches = matches[from:]
+	for _, t := range s.tuples[store] {
+		if s.expired(t, now) {
+			continue
+		}
+
+		key := t.GetKey()
+		stats.Tuples[tupleUtils.ToObjectRelationString(tupleUtils.GetType(key.GetObject()), key.GetRelation())]++
 	}
 
+	return stats, nil
+}
+
+func (s *MemoryBackend) read(ctx context.Context, store string, filter storage.ReadFilter, paginationOptions storage.PaginationOptions) (*staticIterator, error) {
+	_, span := tracer.Start(ctx, "memory.read")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := storage.ExpirationTime(ctx)
+
+	var matches []*openfgav1.Tuple
+	for _, t := range s.tuples[store] {
+		if s.expired(t, now) {
+			continue
+		}
+
+		if filter.Matches(t.Key) {
+			matches = append(matches, t)
+		}
+	}
+
+	from, err := parsePosition(paginationOptions.From, len(matches))
+	if err != nil {
+		telemetry.TraceError(span, err)
+		return nil, err
+	}
+	matches = matches[from:]
+
 	to := paginationOptions.PageSize
 	if to != 0 && to < len(matches) {
+		s.observeExpirations(ctx, matches[:to])
 		return &staticIterator{tuples: matches[:to], continuationToken: []byte(strconv.Itoa(from + to))}, nil
 	}
 
+	s.observeExpirations(ctx, matches)
 	return &staticIterator{tuples: matches}, nil
 }
 
+// observeExpirations records the expirations of the tuples read in the storage.TupleExpirations of the context, if
+// any. It must be called with s.mu held.
+func (s *MemoryBackend) observeExpirations(ctx context.Context, tuples []*openfgav1.Tuple) {
+	expirations := storage.TupleExpirationsFromContext(ctx)
+	if expirations == nil {
+		return
+	}
+
+	for _, t := range tuples {
+		expirations.Observe(s.expirations[t])
+	}
+}
+
 // Write See storage.TupleBackend.Write
-func (s *MemoryBackend) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes) error {
+func (s *MemoryBackend) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
 	_, span := tracer.Start(ctx, "memory.Write")
 	defer span.End()
 
+	return s.write(store, deletes, writes, nil, nil, storage.NewTupleWriteOptions(opts...))
+}
+
+// WriteWithExpiry See storage.TupleExpirationBackend.WriteWithExpiry
+func (s *MemoryBackend) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "memory.WriteWithExpiry")
+	defer span.End()
+
+	return s.write(store, deletes, writes, &expiresAt, nil, storage.NewTupleWriteOptions(opts...))
+}
+
+// WriteWithCondition See storage.TupleConditionBackend.WriteWithCondition
+func (s *MemoryBackend) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "memory.WriteWithCondition")
+	defer span.End()
+
+	return s.write(store, deletes, writes, nil, condition, storage.NewTupleWriteOptions(opts...))
+}
+
+// StageWrite see storage.StagedWriteBackend.StageWrite.
+func (s *MemoryBackend) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	_, span := tracer.Start(ctx, "memory.StageWrite")
+	defer span.End()
+
+	if len(deletes)+len(writes) > s.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	staged, ok := s.stagedWrites[store+"|"+id]
+	if !ok {
+		staged = &stagedWrite{}
+		s.stagedWrites[store+"|"+id] = staged
+	}
+	staged.deletes = append(staged.deletes, deletes...)
+	staged.writes = append(staged.writes, writes...)
+
+	return nil
+}
+
+// CommitStagedWrite see storage.StagedWriteBackend.CommitStagedWrite.
+func (s *MemoryBackend) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	_, span := tracer.Start(ctx, "memory.CommitStagedWrite")
+	defer span.End()
+
+	s.mu.RLock()
+	staged, ok := s.stagedWrites[store+"|"+id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if err := s.write(store, staged.deletes, staged.writes, nil, nil, storage.NewTupleWriteOptions(opts...)); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	delete(s.stagedWrites, store+"|"+id)
+
+	return nil
+}
+
+// DiscardStagedWrite see storage.StagedWriteBackend.DiscardStagedWrite.
+func (s *MemoryBackend) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	_, span := tracer.Start(ctx, "memory.DiscardStagedWrite")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.stagedWrites, store+"|"+id)
+
+	return nil
+}
+
+func (s *MemoryBackend) write(store string, deletes storage.Deletes, writes storage.Writes, expiresAt *time.Time, condition *storage.TupleCondition, opts storage.TupleWriteOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opts.ExpectedChangelogToken != nil {
+		if err := s.checkChangelogToken(store, *opts.ExpectedChangelogToken); err != nil {
+			return err
+		}
+	}
+
 	now := timestamppb.Now()
 
-	if err := validateTuples(s.tuples[store], deletes, writes); err != nil {
+	// expired tuples behave as if they had been deleted, so they are deleted before the
+	// deletes and writes are validated
+	s.deleteExpiredTuples(store, now.AsTime(), len(s.tuples[store]))
+
+	if err := validateTuples(s.tuples[store], deletes, writes, opts); err != nil {
 		return err
 	}
 
+	var changed bool
 	var tuples []*openfgav1.Tuple
 Delete:
 	for _, t := range s.tuples[store] {
 		for _, k := range deletes {
 			if match(k, t.Key) {
+				delete(s.expirations, t)
+				delete(s.conditions, t)
 				s.changes[store] = append(s.changes[store], &openfgav1.TupleChange{TupleKey: t.Key, Operation: openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, Timestamp: now})
+				changed = true
 				continue Delete
 			}
 		}
@@ -293,21 +566,224 @@ Write:
 				continue Write
 			}
 		}
-		tuples = append(tuples, &openfgav1.Tuple{Key: t, Timestamp: now})
+		tuple := &openfgav1.Tuple{Key: t, Timestamp: now}
+		if expiresAt != nil {
+			s.expirations[tuple] = *expiresAt
+		}
+		if condition != nil {
+			s.conditions[tuple] = condition
+		}
+		tuples = append(tuples, tuple)
 		s.changes[store] = append(s.changes[store], &openfgav1.TupleChange{TupleKey: t, Operation: openfgav1.TupleOperation_TUPLE_OPERATION_WRITE, Timestamp: now})
+		changed = true
 	}
 	s.tuples[store] = tuples
+
+	s.evictTuples(now)
+	s.trimChanges()
+
+	// the changes have no ULID, so the ULID of the last change is the one of its time
+	if changed {
+		opts.CommitChange(storage.NextChangeULID(now.AsTime(), ""))
+	}
+
 	return nil
 }
 
-func validateTuples(tuples []*openfgav1.Tuple, deletes, writes []*openfgav1.TupleKey) error {
+// evictTuples deletes the least recently written tuples of the stores while there are more than maxTuples, recording
+// the deletes in the changelog. It must be called with mu held.
+func (s *MemoryBackend) evictTuples(now *timestamppb.Timestamp) {
+	if s.maxTuples <= 0 {
+		return
+	}
+
+	var count int
+	for _, tuples := range s.tuples {
+		count += len(tuples)
+	}
+
+	for ; count > s.maxTuples; count-- {
+		// the tuples of a store are in the order in which they were written
+		var oldest string
+		for store, tuples := range s.tuples {
+			if len(tuples) == 0 {
+				continue
+			}
+
+			if oldest == "" || tuples[0].GetTimestamp().AsTime().Before(s.tuples[oldest][0].GetTimestamp().AsTime()) {
+				oldest = store
+			}
+		}
+
+		t := s.tuples[oldest][0]
+		delete(s.expirations, t)
+		delete(s.conditions, t)
+		s.changes[oldest] = append(s.changes[oldest], &openfgav1.TupleChange{TupleKey: t.Key, Operation: openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, Timestamp: now})
+		s.tuples[oldest] = s.tuples[oldest][1:]
+	}
+}
+
+// trimChanges deletes the oldest changes of the stores which have more than maxChanges. It must be called with mu
+// held.
+func (s *MemoryBackend) trimChanges() {
+	if s.maxChanges <= 0 {
+		return
+	}
+
+	for store, changes := range s.changes {
+		if n := len(changes) - s.maxChanges; n > 0 {
+			s.deletedChanges[store] += n
+			s.changes[store] = changes[n:]
+		}
+	}
+}
+
+// DeleteExpiredTuples See storage.TupleExpirationBackend.DeleteExpiredTuples
+func (s *MemoryBackend) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "memory.DeleteExpiredTuples")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var deleted int
+	for store := range s.tuples {
+		if deleted >= limit {
+			break
+		}
+
+		deleted += s.deleteExpiredTuples(store, now, limit-deleted)
+	}
+
+	s.trimChanges()
+
+	return deleted, nil
+}
+
+// deleteExpiredTuples deletes at most `limit` tuples of the store that are expired at `now`, recording the
+// deletes in the changelog, and returns the number of tuples deleted. It must be called with mu held.
+func (s *MemoryBackend) deleteExpiredTuples(store string, now time.Time, limit int) int {
+	var deleted int
+	tuples := make([]*openfgav1.Tuple, 0, len(s.tuples[store]))
+	for _, t := range s.tuples[store] {
+		if deleted < limit && s.expired(t, now) {
+			delete(s.expirations, t)
+			delete(s.conditions, t)
+			s.changes[store] = append(s.changes[store], &openfgav1.TupleChange{TupleKey: t.Key, Operation: openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, Timestamp: timestamppb.New(now)})
+			deleted++
+			continue
+		}
+		tuples = append(tuples, t)
+	}
+
+	if deleted > 0 {
+		s.tuples[store] = tuples
+	}
+
+	return deleted
+}
+
+// ReadTupleExpirations See storage.TupleExpirationBackend.ReadTupleExpirations
+func (s *MemoryBackend) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	_, span := tracer.Start(ctx, "memory.ReadTupleExpirations")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	expirations := map[string]time.Time{}
+	for _, t := range s.tuples[store] {
+		expiresAt, ok := s.expirations[t]
+		if !ok || !expiresAt.After(now) || !match(filter, t.Key) {
+			continue
+		}
+
+		expirations[tupleUtils.TupleKeyToString(t.Key)] = expiresAt
+	}
+
+	return expirations, nil
+}
+
+// ReadTupleConditions See storage.TupleConditionBackend.ReadTupleConditions
+func (s *MemoryBackend) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	_, span := tracer.Start(ctx, "memory.ReadTupleConditions")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := storage.ExpirationTime(ctx)
+
+	conditions := map[string]*storage.TupleCondition{}
+	for _, t := range s.tuples[store] {
+		condition, ok := s.conditions[t]
+		if !ok || s.expired(t, now) || !match(filter, t.Key) {
+			continue
+		}
+
+		conditions[tupleUtils.TupleKeyToString(t.Key)] = condition
+	}
+
+	return conditions, nil
+}
+
+// Snapshot See storage.SnapshotBackend.Snapshot. The snapshot is a copy of the tuples of the store, taken when it is
+// created.
+func (s *MemoryBackend) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	_, span := tracer.Start(ctx, "memory.Snapshot")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tuples := make([]*openfgav1.Tuple, len(s.tuples[store]))
+	copy(tuples, s.tuples[store])
+
+	expirations := map[*openfgav1.Tuple]time.Time{}
+	conditions := map[*openfgav1.Tuple]*storage.TupleCondition{}
+	for _, t := range tuples {
+		if expiresAt, ok := s.expirations[t]; ok {
+			expirations[t] = expiresAt
+		}
+		if condition, ok := s.conditions[t]; ok {
+			conditions[t] = condition
+		}
+	}
+
+	return NewSnapshot(store, tuples, expirations, conditions), nil
+}
+
+// NewSnapshot returns a reader of a copy of the tuples of a store, in the order in which they were written, along with
+// the expiration times and the conditions of the tuples which have one. It serves the snapshots of the datastores
+// which take them by copying the tuples of the store.
+func NewSnapshot(store string, tuples []*openfgav1.Tuple, expirations map[*openfgav1.Tuple]time.Time, conditions map[*openfgav1.Tuple]*storage.TupleCondition) storage.SnapshotReader {
+	return &MemoryBackend{
+		tuples:      map[string][]*openfgav1.Tuple{store: tuples},
+		expirations: expirations,
+		conditions:  conditions,
+	}
+}
+
+// expired reports whether the tuple is expired at `now`. It must be called with mu held.
+func (s *MemoryBackend) expired(t *openfgav1.Tuple, now time.Time) bool {
+	expiresAt, ok := s.expirations[t]
+	return ok && !expiresAt.After(now)
+}
+
+// validateTuples returns an error if a tuple that does not exist is deleted or a tuple that already exists is
+// written, unless the options ignore it. The ignored deletes and writes are skipped by Write.
+func validateTuples(tuples []*openfgav1.Tuple, deletes, writes []*openfgav1.TupleKey, opts storage.TupleWriteOptions) error {
 	for _, tk := range deletes {
-		if !find(tuples, tk) {
+		if opts.OnMissingDelete != storage.OnMissingDeleteIgnore && !find(tuples, tk) {
 			return storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE)
 		}
 	}
 	for _, tk := range writes {
-		if find(tuples, tk) {
+		if opts.OnDuplicateInsert != storage.OnDuplicateInsertIgnore && find(tuples, tk) {
 			return storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_WRITE)
 		}
 	}
@@ -328,11 +804,13 @@ func (s *MemoryBackend) ReadUserTuple(ctx context.Context, store string, key *op
 	_, span := tracer.Start(ctx, "memory.ReadUserTuple")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	now := storage.ExpirationTime(ctx)
 	for _, t := range s.tuples[store] {
-		if match(key, t.Key) {
+		if match(key, t.Key) && !s.expired(t, now) {
+			s.observeExpirations(ctx, []*openfgav1.Tuple{t})
 			return t, nil
 		}
 	}
@@ -346,11 +824,17 @@ func (s *MemoryBackend) ReadUsersetTuples(ctx context.Context, store string, fil
 	_, span := tracer.Start(ctx, "memory.ReadUsersetTuples")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := storage.ExpirationTime(ctx)
 
 	var matches []*openfgav1.Tuple
 	for _, t := range s.tuples[store] {
+		if s.expired(t, now) {
+			continue
+		}
+
 		if match(&openfgav1.TupleKey{
 			Object:   filter.Object,
 			Relation: filter.Relation,
@@ -372,6 +856,7 @@ func (s *MemoryBackend) ReadUsersetTuples(ctx context.Context, store string, fil
 		}
 	}
 
+	s.observeExpirations(ctx, matches)
 	return &staticIterator{tuples: matches}, nil
 }
 
@@ -383,11 +868,17 @@ func (s *MemoryBackend) ReadStartingWithUser(
 	_, span := tracer.Start(ctx, "memory.ReadStartingWithUser")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := storage.ExpirationTime(ctx)
 
 	var matches []*openfgav1.Tuple
 	for _, t := range s.tuples[store] {
+		if s.expired(t, now) {
+			continue
+		}
+
 		if tupleUtils.GetType(t.Key.GetObject()) != filter.ObjectType {
 			continue
 		}
@@ -408,6 +899,8 @@ func (s *MemoryBackend) ReadStartingWithUser(
 		}
 
 	}
+
+	s.observeExpirations(ctx, matches)
 	return &staticIterator{tuples: matches}, nil
 }
 
@@ -441,8 +934,8 @@ func (s *MemoryBackend) ReadAuthorizationModel(ctx context.Context, store string
 	_, span := tracer.Start(ctx, "memory.ReadAuthorizationModel")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	tm, ok := s.authorizationModels[store]
 	if !ok {
@@ -461,14 +954,35 @@ func (s *MemoryBackend) ReadAuthorizationModel(ctx context.Context, store string
 	return nil, storage.ErrNotFound
 }
 
+// ReadAuthorizationModelAnnotations See storage.AuthorizationModelReadBackend.ReadAuthorizationModelAnnotations
+func (s *MemoryBackend) ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (storage.ModelAnnotations, error) {
+	_, span := tracer.Start(ctx, "memory.ReadAuthorizationModelAnnotations")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.authorizationModels[store][id]
+	if !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return nil, storage.ErrNotFound
+	}
+
+	if entry.annotations == nil {
+		return storage.ModelAnnotations{}, nil
+	}
+
+	return entry.annotations, nil
+}
+
 // ReadAuthorizationModels See storage.AuthorizationModelBackend.ReadAuthorizationModels
 // options.From is expected to be a number
 func (s *MemoryBackend) ReadAuthorizationModels(ctx context.Context, store string, options storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
 	_, span := tracer.Start(ctx, "memory.ReadAuthorizationModels")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	models := make([]*openfgav1.AuthorizationModel, 0, len(s.authorizationModels[store]))
 	for _, entry := range s.authorizationModels[store] {
@@ -480,23 +994,19 @@ func (s *MemoryBackend) ReadAuthorizationModels(ctx context.Context, store strin
 		return models[i].Id > models[j].Id
 	})
 
-	var from int64 = 0
 	continuationToken := ""
-	var err error
 
 	pageSize := storage.DefaultPageSize
 	if options.PageSize > 0 {
 		pageSize = options.PageSize
 	}
 
-	if options.From != "" {
-		from, err = strconv.ParseInt(options.From, 10, 32)
-		if err != nil {
-			return nil, nil, err
-		}
+	from, err := parsePosition(options.From, len(models))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	to := int(from) + pageSize
+	to := from + pageSize
 	if len(models) < to {
 		to = len(models)
 	}
@@ -514,8 +1024,8 @@ func (s *MemoryBackend) FindLatestAuthorizationModelID(ctx context.Context, stor
 	_, span := tracer.Start(ctx, "memory.FindLatestAuthorizationModelID")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	tm, ok := s.authorizationModels[store]
 	if !ok {
@@ -531,11 +1041,123 @@ func (s *MemoryBackend) FindLatestAuthorizationModelID(ctx context.Context, stor
 	return nsc.Id, nil
 }
 
+// DeleteAuthorizationModel See storage.TypeDefinitionWriteBackend.DeleteAuthorizationModel
+func (s *MemoryBackend) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	_, span := tracer.Start(ctx, "memory.DeleteAuthorizationModel")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.authorizationModels[store][id]
+	if !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return storage.ErrNotFound
+	}
+
+	delete(s.authorizationModels[store], id)
+	delete(s.assertions, fmt.Sprintf("%s|%s", store, id))
+
+	if s.pinnedModels[store] == id {
+		delete(s.pinnedModels, store)
+	}
+
+	if entry.latest {
+		// the model ids are ULIDs, so the newest remaining model has the greatest id
+		var newest *AuthorizationModelEntry
+		for modelID, remaining := range s.authorizationModels[store] {
+			if newest == nil || modelID > newest.model.GetId() {
+				newest = remaining
+			}
+		}
+
+		if newest != nil {
+			newest.latest = true
+		}
+	}
+
+	return nil
+}
+
+// PinAuthorizationModel See storage.PinnedAuthorizationModelBackend.PinAuthorizationModel
+func (s *MemoryBackend) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	_, span := tracer.Start(ctx, "memory.PinAuthorizationModel")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.authorizationModels[store][id]; !ok {
+		telemetry.TraceError(span, storage.ErrNotFound)
+		return storage.ErrNotFound
+	}
+
+	s.pinnedModels[store] = id
+
+	return nil
+}
+
+// UnpinAuthorizationModel See storage.PinnedAuthorizationModelBackend.UnpinAuthorizationModel
+func (s *MemoryBackend) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	_, span := tracer.Start(ctx, "memory.UnpinAuthorizationModel")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pinnedModels, store)
+
+	return nil
+}
+
+// ReadPinnedAuthorizationModelID See storage.AuthorizationModelReadBackend.ReadPinnedAuthorizationModelID
+func (s *MemoryBackend) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	_, span := tracer.Start(ctx, "memory.ReadPinnedAuthorizationModelID")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pinnedModels[store], nil
+}
+
 // WriteAuthorizationModel See storage.TypeDefinitionWriteBackend.WriteAuthorizationModel
 func (s *MemoryBackend) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
 	_, span := tracer.Start(ctx, "memory.WriteAuthorizationModel")
 	defer span.End()
 
+	return s.writeAuthorizationModel(store, model, nil)
+}
+
+// WriteAuthorizationModelWithAnnotations See storage.TypeDefinitionWriteBackend.WriteAuthorizationModelWithAnnotations
+func (s *MemoryBackend) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	_, span := tracer.Start(ctx, "memory.WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	return s.writeAuthorizationModel(store, model, annotations)
+}
+
+// checkChangelogToken returns ErrChangelogConflict unless the continuation token of ReadChangesWithFilter is the
+// position of the end of the changelog of the store.
+func (s *MemoryBackend) checkChangelogToken(store, token string) error {
+	var position int
+	if token != "" {
+		from, _, _ := strings.Cut(token, "|")
+
+		var err error
+		if position, err = strconv.Atoi(from); err != nil {
+			return storage.ErrInvalidContinuationToken
+		}
+	}
+
+	if position != s.deletedChanges[store]+len(s.changes[store]) {
+		return storage.ErrChangelogConflict
+	}
+
+	return nil
+}
+
+func (s *MemoryBackend) writeAuthorizationModel(store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -548,8 +1170,9 @@ func (s *MemoryBackend) WriteAuthorizationModel(ctx context.Context, store strin
 	}
 
 	s.authorizationModels[store][model.Id] = &AuthorizationModelEntry{
-		model:  model,
-		latest: true,
+		model:       model,
+		annotations: annotations,
+		latest:      true,
 	}
 
 	return nil
@@ -584,10 +1207,153 @@ func (s *MemoryBackend) DeleteStore(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.stores, id)
+	store, ok := s.activeStore(id)
+	if !ok {
+		return nil
+	}
+
+	s.stores[id] = &openfgav1.Store{
+		Id:        store.Id,
+		Name:      store.Name,
+		CreatedAt: store.CreatedAt,
+		UpdatedAt: store.UpdatedAt,
+		DeletedAt: timestamppb.New(time.Now().UTC()),
+	}
+
+	return nil
+}
+
+func (s *MemoryBackend) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	_, span := tracer.Start(ctx, "memory.UndeleteStore")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, ok := s.stores[id]
+	if !ok || store.DeletedAt == nil || store.DeletedAt.AsTime().Before(deletedAfter) {
+		return nil, storage.ErrNotFound
+	}
+
+	s.stores[id] = &openfgav1.Store{
+		Id:        store.Id,
+		Name:      store.Name,
+		CreatedAt: store.CreatedAt,
+		UpdatedAt: timestamppb.New(time.Now().UTC()),
+	}
+
+	return s.stores[id], nil
+}
+
+func (s *MemoryBackend) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	_, span := tracer.Start(ctx, "memory.PurgeDeletedStores")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged []*openfgav1.Store
+	for _, store := range s.stores {
+		if store.DeletedAt != nil && store.DeletedAt.AsTime().Before(deletedBefore) {
+			purged = append(purged, store)
+		}
+	}
+
+	// from the first deleted to the last deleted
+	sort.Slice(purged, func(i, j int) bool {
+		return purged[i].DeletedAt.AsTime().Before(purged[j].DeletedAt.AsTime())
+	})
+	if len(purged) > limit {
+		purged = purged[:limit]
+	}
+
+	for _, store := range purged {
+		for _, t := range s.tuples[store.Id] {
+			delete(s.expirations, t)
+			delete(s.conditions, t)
+		}
+
+		delete(s.tuples, store.Id)
+		delete(s.changes, store.Id)
+		delete(s.deletedChanges, store.Id)
+		delete(s.authorizationModels, store.Id)
+		delete(s.pinnedModels, store.Id)
+		delete(s.storeMetadata, store.Id)
+		delete(s.stores, store.Id)
+
+		for assertionsID := range s.assertions {
+			if strings.HasPrefix(assertionsID, store.Id+"|") {
+				delete(s.assertions, assertionsID)
+			}
+		}
+	}
+
+	return len(purged), nil
+}
+
+// activeStore returns the store with the provided id, unless it does not exist or it was deleted.
+func (s *MemoryBackend) activeStore(id string) (*openfgav1.Store, bool) {
+	store, ok := s.stores[id]
+	if !ok || store.DeletedAt != nil {
+		return nil, false
+	}
+
+	return store, true
+}
+
+func (s *MemoryBackend) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	_, span := tracer.Start(ctx, "memory.WriteStoreMetadata")
+	defer span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, ok := s.activeStore(id)
+	if !ok {
+		return storage.ErrNotFound
+	}
+
+	s.stores[id] = &openfgav1.Store{
+		Id:        store.Id,
+		Name:      store.Name,
+		CreatedAt: store.CreatedAt,
+		UpdatedAt: timestamppb.New(time.Now().UTC()),
+	}
+	s.storeMetadata[id] = copyStoreMetadata(metadata)
+
 	return nil
 }
 
+func (s *MemoryBackend) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	_, span := tracer.Start(ctx, "memory.ReadStoreMetadata")
+	defer span.End()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.activeStore(id); !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	return copyStoreMetadata(s.storeMetadata[id]), nil
+}
+
+func copyStoreMetadata(metadata *storage.StoreMetadata) *storage.StoreMetadata {
+	if metadata == nil {
+		return &storage.StoreMetadata{}
+	}
+
+	copied := &storage.StoreMetadata{Description: metadata.Description}
+	if len(metadata.Labels) > 0 {
+		copied.Labels = make(map[string]string, len(metadata.Labels))
+		for key, value := range metadata.Labels {
+			copied.Labels[key] = value
+		}
+	}
+
+	return copied
+}
+
 func (s *MemoryBackend) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
 	_, span := tracer.Start(ctx, "memory.WriteAssertions")
 	defer span.End()
@@ -605,8 +1371,8 @@ func (s *MemoryBackend) ReadAssertions(ctx context.Context, store, modelID strin
 	_, span := tracer.Start(ctx, "memory.ReadAssertions")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	assertionsID := fmt.Sprintf("%s|%s", store, modelID)
 	assertions, ok := s.assertions[assertionsID]
@@ -630,46 +1396,60 @@ func (s *MemoryBackend) GetStore(ctx context.Context, storeID string) (*openfgav
 	_, span := tracer.Start(ctx, "memory.GetStore")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if s.stores[storeID] == nil {
+	store, ok := s.activeStore(storeID)
+	if !ok {
 		return nil, storage.ErrNotFound
 	}
 
-	return s.stores[storeID], nil
+	return store, nil
 }
 
 func (s *MemoryBackend) ListStores(ctx context.Context, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
 	_, span := tracer.Start(ctx, "memory.ListStores")
 	defer span.End()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.listStores(storage.ListStoresFilter{}, paginationOptions)
+}
+
+func (s *MemoryBackend) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	_, span := tracer.Start(ctx, "memory.ListStoresWithFilter")
+	defer span.End()
+
+	return s.listStores(filter, paginationOptions)
+}
+
+func (s *MemoryBackend) listStores(filter storage.ListStoresFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	stores := make([]*openfgav1.Store, 0, len(s.stores))
 	for _, t := range s.stores {
+		if t.DeletedAt != nil || !strings.HasPrefix(t.Name, filter.NamePrefix) || !hasLabels(s.storeMetadata[t.Id], filter.Labels) {
+			continue
+		}
 		stores = append(stores, t)
 	}
 
 	// from oldest to newest
 	sort.SliceStable(stores, func(i, j int) bool {
+		if filter.SortOrder == storage.StoreSortByName && stores[i].Name != stores[j].Name {
+			return stores[i].Name < stores[j].Name
+		}
 		return stores[i].Id < stores[j].Id
 	})
 
-	var err error
-	var from int64 = 0
-	if paginationOptions.From != "" {
-		from, err = strconv.ParseInt(paginationOptions.From, 10, 32)
-		if err != nil {
-			return nil, nil, err
-		}
+	from, err := parsePosition(paginationOptions.From, len(stores))
+	if err != nil {
+		return nil, nil, err
 	}
 	pageSize := storage.DefaultPageSize
 	if paginationOptions.PageSize > 0 {
 		pageSize = paginationOptions.PageSize
 	}
-	to := int(from) + pageSize
+	to := from + pageSize
 	if len(stores) < to {
 		to = len(stores)
 	}
@@ -686,6 +1466,38 @@ func (s *MemoryBackend) ListStores(ctx context.Context, paginationOptions storag
 	return res, []byte(continuationToken), nil
 }
 
+// parsePosition parses the continuation token of a list of n items, which is the position in the list of the next page.
+// The positions past the end of the list, e.g. of the tokens issued before items were deleted, are the end of the list.
+func parsePosition(token string, n int) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	position, err := strconv.Atoi(token)
+	if err != nil || position < 0 {
+		return 0, storage.ErrInvalidContinuationToken
+	}
+
+	if position > n {
+		return n, nil
+	}
+
+	return position, nil
+}
+
+func hasLabels(metadata *storage.StoreMetadata, labels map[string]string) bool {
+	for key, value := range labels {
+		if metadata == nil {
+			return false
+		}
+		if label, ok := metadata.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (s *MemoryBackend) IsReady(ctx context.Context) (bool, error) {
 	return true, nil
 }
@@ -1,11 +1,15 @@
 package memory
 
 import (
+	"context"
 	"testing"
 
+	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/storage/test"
 	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,6 +18,49 @@ func TestMemdbStorage(t *testing.T) {
 	test.RunAllTests(t, ds)
 }
 
+func TestMemdbStorageEviction(t *testing.T) {
+	ctx := context.Background()
+	ds := New(WithMaxTuples(3), WithMaxChanges(4)).(*MemoryBackend)
+
+	for _, tk := range []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	} {
+		require.NoError(t, ds.Write(ctx, "store1", nil, storage.Writes{tk}))
+	}
+	require.NoError(t, ds.Write(ctx, "store2", nil, storage.Writes{
+		tuple.NewTupleKey("document:3", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:4", "viewer", "user:jon"),
+	}))
+
+	// the least recently written tuple was evicted
+	require.Len(t, ds.tuples["store1"], 1)
+	require.Equal(t, "document:2", ds.tuples["store1"][0].GetKey().GetObject())
+	require.Len(t, ds.tuples["store2"], 2)
+
+	changes := ds.changes["store1"]
+	require.Len(t, changes, 3)
+	require.Equal(t, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, changes[2].GetOperation())
+	require.Equal(t, "document:1", changes[2].GetTupleKey().GetObject())
+
+	require.NoError(t, ds.Write(ctx, "store1", storage.Deletes{
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}, storage.Writes{
+		tuple.NewTupleKey("document:5", "viewer", "user:jon"),
+	}))
+
+	// the oldest changes were deleted
+	changes = ds.changes["store1"]
+	require.Len(t, changes, 4)
+	require.Equal(t, 1, ds.deletedChanges["store1"])
+	require.Equal(t, "document:2", changes[0].GetTupleKey().GetObject())
+
+	ds.Reset()
+	require.Empty(t, ds.tuples)
+	require.Empty(t, ds.changes)
+	require.Empty(t, ds.deletedChanges)
+}
+
 func TestStaticTupleIteratorNoRace(t *testing.T) {
 	iter := &staticIterator{
 		tuples: []*openfgav1.Tuple{
@@ -37,3 +84,35 @@ func TestStaticTupleIteratorNoRace(t *testing.T) {
 		require.NoError(t, err)
 	}()
 }
+
+func FuzzPagination(f *testing.F) {
+	ctx := context.Background()
+	ds := New()
+
+	store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "store"})
+	require.NoError(f, err)
+
+	require.NoError(f, ds.Write(ctx, store.GetId(), nil, storage.Writes{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon"),
+		tuple.NewTupleKey("document:2", "viewer", "user:jon"),
+	}))
+	require.NoError(f, ds.WriteAuthorizationModel(ctx, store.GetId(), &openfgav1.AuthorizationModel{
+		Id:              ulid.Make().String(),
+		SchemaVersion:   typesystem.SchemaVersion1_1,
+		TypeDefinitions: []*openfgav1.TypeDefinition{{Type: "user"}},
+	}))
+
+	for _, token := range []string{"", "0", "1", "2", "3", "-1", "1|document", "2147483648", "x"} {
+		f.Add(token)
+	}
+
+	f.Fuzz(func(t *testing.T, token string) {
+		opts := storage.PaginationOptions{PageSize: 1, From: token}
+
+		// the malformed tokens are errors, not panics
+		_, _, _ = ds.ReadPage(ctx, store.GetId(), tuple.NewTupleKey("document:", "", ""), opts)
+		_, _, _ = ds.ReadAuthorizationModels(ctx, store.GetId(), opts)
+		_, _, _ = ds.ListStores(ctx, opts)
+		_, _, _ = ds.ReadChanges(ctx, store.GetId(), "", opts, 0)
+	})
+}
@@ -29,16 +29,58 @@ var tracer = otel.Tracer("openfga/pkg/storage/mysql")
 
 type MySQL struct {
 	stbl                   sq.StatementBuilderType
-	db                     *sql.DB
+	db                     *sqlcommon.Pool
 	logger                 logger.Logger
 	maxTuplesPerWriteField int
 	maxTypesPerModelField  int
+	readPageSize           int
+
+	// the read replica the tuple reads are routed to, if any, see sqlcommon.WithReadURI
+	replica     *sqlcommon.Pool
+	replicaStbl sq.StatementBuilderType
+	replicaLag  *sqlcommon.ReplicaLag
+
+	// serializeWrites makes every write transaction lock its store, see sqlcommon.WithSerializeWrites
+	serializeWrites bool
 }
 
 var _ storage.OpenFGADatastore = (*MySQL)(nil)
 
 func New(uri string, cfg *sqlcommon.Config) (*MySQL, error) {
+	db, err := openDB(uri, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := sqlcommon.NewPool(db, "mysql", cfg)
+	m := &MySQL{
+		stbl:                   sq.StatementBuilder.RunWith(pool),
+		db:                     pool,
+		logger:                 cfg.Logger,
+		maxTuplesPerWriteField: cfg.MaxTuplesPerWriteField,
+		maxTypesPerModelField:  cfg.MaxTypesPerModelField,
+		readPageSize:           cfg.ReadPageSize,
+		serializeWrites:        cfg.SerializeWrites,
+	}
+
+	if cfg.ReadURI != "" {
+		replica, err := openDB(cfg.ReadURI, cfg)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to initialize the mysql read replica: %w", err)
+		}
+
+		m.replica = sqlcommon.NewPool(replica, "mysql_replica", cfg)
+		m.replicaStbl = sq.StatementBuilder.RunWith(m.replica)
+		m.replicaLag = sqlcommon.NewReplicaLag(m.replica, measureReplicaLag, cfg.ReplicaMaxLag)
+	}
 
+	return m, nil
+}
+
+// openDB opens a connection pool to a MySQL database with the provided config, and waits until the database can be
+// reached.
+func openDB(uri string, cfg *sqlcommon.Config) (*sql.DB, error) {
 	if cfg.Username != "" || cfg.Password != "" {
 		dsnCfg, err := mysql.ParseDSN(uri)
 		if err != nil {
@@ -92,32 +134,93 @@ func New(uri string, cfg *sqlcommon.Config) (*MySQL, error) {
 		return nil, fmt.Errorf("failed to initialize mysql connection: %w", err)
 	}
 
-	return &MySQL{
-		stbl:                   sq.StatementBuilder.RunWith(db),
-		db:                     db,
-		logger:                 cfg.Logger,
-		maxTuplesPerWriteField: cfg.MaxTuplesPerWriteField,
-		maxTypesPerModelField:  cfg.MaxTypesPerModelField,
-	}, nil
+	return db, nil
 }
 
 // Close closes the datastore and cleans up any residual resources.
 func (m *MySQL) Close() {
 	m.db.Close()
+
+	if m.replica != nil {
+		m.replica.Close()
+	}
+}
+
+// measureReplicaLag measures the replication lag of the read replica from the Seconds_Behind_Source column of its
+// replication status, which requires MySQL 8.0.22 or later and the REPLICATION CLIENT privilege.
+// The lag is 0 if the replica isn't a replica, and unknown if its replication isn't running.
+func measureReplicaLag(ctx context.Context, replica *sqlcommon.Pool) (time.Duration, error) {
+	rows, err := replica.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	var seconds sql.NullFloat64
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if column == "Seconds_Behind_Source" {
+			dest[i] = &seconds
+		} else {
+			dest[i] = new(sql.RawBytes)
+		}
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	if !seconds.Valid {
+		return 0, sqlcommon.ErrReplicaLagUnknown
+	}
+
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
+
+// readStbl returns the statement builder of the tuple reads, which are routed to the read replica if there is one
+// whose measured replication lag is within the staleness the read allows.
+func (m *MySQL) readStbl(ctx context.Context) sq.StatementBuilderType {
+	if m.replica == nil || !m.replicaLag.StaleReadsAllowed(ctx) {
+		return m.stbl
+	}
+
+	return m.replicaStbl
 }
 
 func (m *MySQL) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "mysql.Read")
 	defer span.End()
 
-	return m.read(ctx, store, tupleKey, nil)
+	return m.read(ctx, store, storage.NewReadFilter(tupleKey), nil)
 }
 
 func (m *MySQL) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
 	ctx, span := tracer.Start(ctx, "mysql.ReadPage")
 	defer span.End()
 
-	iter, err := m.read(ctx, store, tupleKey, &opts)
+	iter, err := m.read(ctx, store, storage.NewReadFilter(tupleKey), &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Stop()
+
+	return iter.ToArray(opts)
+}
+
+func (m *MySQL) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadPageWithFilter")
+	defer span.End()
+
+	iter, err := m.read(ctx, store, filter, &opts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -126,30 +229,19 @@ func (m *MySQL) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.
 	return iter.ToArray(opts)
 }
 
-func (m *MySQL) read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts *storage.PaginationOptions) (*sqlcommon.SQLTupleIterator, error) {
+func (m *MySQL) read(ctx context.Context, store string, filter storage.ReadFilter, opts *storage.PaginationOptions) (*sqlcommon.SQLTupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "mysql.read")
 	defer span.End()
 
-	sb := m.stbl.
-		Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := m.readStbl(ctx).
+		Select(sqlcommon.TupleColumns...).
 		From("tuple").
-		Where(sq.Eq{"store": store})
+		Where(sq.Eq{"store": store}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
 	if opts != nil {
 		sb = sb.OrderBy("ulid")
 	}
-	objectType, objectID := tupleUtils.SplitObject(tupleKey.GetObject())
-	if objectType != "" {
-		sb = sb.Where(sq.Eq{"object_type": objectType})
-	}
-	if objectID != "" {
-		sb = sb.Where(sq.Eq{"object_id": objectID})
-	}
-	if tupleKey.GetRelation() != "" {
-		sb = sb.Where(sq.Eq{"relation": tupleKey.GetRelation()})
-	}
-	if tupleKey.GetUser() != "" {
-		sb = sb.Where(sq.Eq{"_user": tupleKey.GetUser()})
-	}
+	sb = sb.Where(sqlcommon.MatchesFilter(filter))
 	if opts != nil && opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
@@ -166,10 +258,23 @@ func (m *MySQL) read(ctx context.Context, store string, tupleKey *openfgav1.Tupl
 		return nil, sqlcommon.HandleSQLError(err)
 	}
 
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	return sqlcommon.NewSQLTupleIterator(ctx, rows), nil
 }
 
-func (m *MySQL) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes) error {
+// storeLockStatement locks the row of a store until the end of the transaction. The writes of a store without a row,
+// which the server never makes, are not serialized.
+const storeLockStatement = "SELECT id FROM store WHERE id = ? FOR UPDATE"
+
+// dbInfo returns the DBInfo used by the common sql methods.
+func (m *MySQL) dbInfo() *sqlcommon.DBInfo {
+	return sqlcommon.NewDBInfo(m.db, m.stbl, sq.Expr("NOW()"),
+		sqlcommon.WithOnConflictDoNothing("ON DUPLICATE KEY UPDATE ulid = ulid"),
+		sqlcommon.WithStoreLock(storeLockStatement),
+		sqlcommon.WithSerializedWrites(m.serializeWrites),
+	)
+}
+
+func (m *MySQL) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
 	ctx, span := tracer.Start(ctx, "mysql.Write")
 	defer span.End()
 
@@ -179,7 +284,103 @@ func (m *MySQL) Write(ctx context.Context, store string, deletes storage.Deletes
 
 	now := time.Now().UTC()
 
-	return sqlcommon.Write(ctx, sqlcommon.NewDBInfo(m.db, m.stbl, sq.Expr("NOW()")), store, deletes, writes, now)
+	return sqlcommon.Write(ctx, m.dbInfo(), store, deletes, writes, now, opts...)
+}
+
+// StageWrite see storage.StagedWriteBackend.StageWrite.
+func (m *MySQL) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	ctx, span := tracer.Start(ctx, "mysql.StageWrite")
+	defer span.End()
+
+	if len(deletes)+len(writes) > m.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	return sqlcommon.StageWrite(ctx, m.dbInfo(), store, id, deletes, writes)
+}
+
+// CommitStagedWrite see storage.StagedWriteBackend.CommitStagedWrite.
+func (m *MySQL) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "mysql.CommitStagedWrite")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return sqlcommon.CommitStagedWrite(ctx, m.dbInfo(), store, id, now, opts...)
+}
+
+// DiscardStagedWrite see storage.StagedWriteBackend.DiscardStagedWrite.
+func (m *MySQL) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	ctx, span := tracer.Start(ctx, "mysql.DiscardStagedWrite")
+	defer span.End()
+
+	return sqlcommon.DiscardStagedWrite(ctx, m.dbInfo(), store, id)
+}
+
+func (m *MySQL) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "mysql.WriteWithExpiry")
+	defer span.End()
+
+	if len(deletes)+len(writes) > m.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+	return sqlcommon.WriteWithExpiry(ctx, m.dbInfo(), store, deletes, writes, expiresAt, now, opts...)
+}
+
+func (m *MySQL) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "mysql.WriteWithCondition")
+	defer span.End()
+
+	if len(deletes)+len(writes) > m.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+	return sqlcommon.WriteWithCondition(ctx, m.dbInfo(), store, deletes, writes, condition, now, opts...)
+}
+
+func (m *MySQL) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadTupleConditions")
+	defer span.End()
+
+	return sqlcommon.ReadTupleConditions(ctx, sqlcommon.NewDBInfo(m.db, m.readStbl(ctx), sq.Expr("NOW()")), store, filter, storage.ExpirationTime(ctx))
+}
+
+func (m *MySQL) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadTupleExpirations")
+	defer span.End()
+
+	return sqlcommon.ReadTupleExpirations(ctx, sqlcommon.NewDBInfo(m.db, m.readStbl(ctx), sq.Expr("NOW()")), store, filter, time.Now())
+}
+
+// Snapshot see storage.SnapshotBackend.Snapshot. The reads of the snapshot are run in a read-only repeatable read
+// transaction, whose consistent snapshot is taken by its first read.
+func (m *MySQL) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	ctx, span := tracer.Start(ctx, "mysql.Snapshot")
+	defer span.End()
+
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	return sqlcommon.NewSnapshotReader(tx, &MySQL{
+		stbl:                   sq.StatementBuilder.RunWith(tx),
+		db:                     m.db,
+		logger:                 m.logger,
+		maxTuplesPerWriteField: m.maxTuplesPerWriteField,
+		maxTypesPerModelField:  m.maxTypesPerModelField,
+		readPageSize:           m.readPageSize,
+	}), nil
+}
+
+func (m *MySQL) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "mysql.DeleteExpiredTuples")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return sqlcommon.DeleteExpiredTuples(ctx, m.dbInfo(), limit, now)
 }
 
 func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
@@ -190,8 +391,8 @@ func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openf
 	userType := tupleUtils.GetUserTypeFromUser(tupleKey.GetUser())
 
 	var record sqlcommon.TupleRecord
-	err := m.stbl.
-		Select("object_type", "object_id", "relation", "_user").
+	err := m.readStbl(ctx).
+		Select("object_type", "object_id", "relation", "_user", "expires_at").
 		From("tuple").
 		Where(sq.Eq{
 			"store":       store,
@@ -201,12 +402,15 @@ func (m *MySQL) ReadUserTuple(ctx context.Context, store string, tupleKey *openf
 			"_user":       tupleKey.GetUser(),
 			"user_type":   userType,
 		}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx))).
 		QueryRowContext(ctx).
-		Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User)
+		Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &record.ExpiresAt)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
 	}
 
+	storage.TupleExpirationsFromContext(ctx).Observe(record.ExpiresAt.Time)
+
 	return record.AsTuple(), nil
 }
 
@@ -214,10 +418,11 @@ func (m *MySQL) ReadUsersetTuples(ctx context.Context, store string, filter stor
 	ctx, span := tracer.Start(ctx, "mysql.ReadUsersetTuples")
 	defer span.End()
 
-	sb := m.stbl.Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := m.readStbl(ctx).Select(sqlcommon.TupleColumns...).
 		From("tuple").
 		Where(sq.Eq{"store": store}).
-		Where(sq.Eq{"user_type": tupleUtils.UserSet})
+		Where(sq.Eq{"user_type": tupleUtils.UserSet}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
 
 	objectType, objectID := tupleUtils.SplitObject(filter.Object)
 	if objectType != "" {
@@ -241,12 +446,8 @@ func (m *MySQL) ReadUsersetTuples(ctx context.Context, store string, filter stor
 		}
 		sb = sb.Where(orConditions)
 	}
-	rows, err := sb.QueryContext(ctx)
-	if err != nil {
-		return nil, sqlcommon.HandleSQLError(err)
-	}
-
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	// the tuples are read by pages ordered by their unique key in the store
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"object_type", "object_id", "relation", "_user"}, m.readPageSize), nil
 }
 
 func (m *MySQL) ReadStartingWithUser(ctx context.Context, store string, opts storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
@@ -262,26 +463,32 @@ func (m *MySQL) ReadStartingWithUser(ctx context.Context, store string, opts sto
 		targetUsersArg = append(targetUsersArg, targetUser)
 	}
 
-	rows, err := m.stbl.
-		Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := m.readStbl(ctx).
+		Select(sqlcommon.TupleColumns...).
 		From("tuple").
 		Where(sq.Eq{
 			"store":       store,
 			"object_type": opts.ObjectType,
 			"relation":    opts.Relation,
 			"_user":       targetUsersArg,
-		}).QueryContext(ctx)
-	if err != nil {
-		return nil, sqlcommon.HandleSQLError(err)
-	}
+		}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
 
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	// the tuples are read by pages ordered by their unique key given their store, object type and relation
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"_user", "object_id"}, m.readPageSize), nil
 }
 
 func (m *MySQL) MaxTuplesPerWrite() int {
 	return m.maxTuplesPerWriteField
 }
 
+func (m *MySQL) ReadAuthorizationModelAnnotations(ctx context.Context, store string, modelID string) (storage.ModelAnnotations, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadAuthorizationModelAnnotations")
+	defer span.End()
+
+	return sqlcommon.ReadAuthorizationModelAnnotations(ctx, m.dbInfo(), store, modelID)
+}
+
 func (m *MySQL) ReadAuthorizationModel(ctx context.Context, store string, modelID string) (*openfgav1.AuthorizationModel, error) {
 	ctx, span := tracer.Start(ctx, "mysql.ReadAuthorizationModel")
 	defer span.End()
@@ -441,6 +648,17 @@ func (m *MySQL) WriteAuthorizationModel(ctx context.Context, store string, model
 	ctx, span := tracer.Start(ctx, "mysql.WriteAuthorizationModel")
 	defer span.End()
 
+	return m.writeAuthorizationModel(ctx, store, model, nil)
+}
+
+func (m *MySQL) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	ctx, span := tracer.Start(ctx, "mysql.WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	return m.writeAuthorizationModel(ctx, store, model, annotations)
+}
+
+func (m *MySQL) writeAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
 	schemaVersion := model.GetSchemaVersion()
 	typeDefinitions := model.GetTypeDefinitions()
 
@@ -454,7 +672,7 @@ func (m *MySQL) WriteAuthorizationModel(ctx context.Context, store string, model
 
 	sb := m.stbl.
 		Insert("authorization_model").
-		Columns("store", "authorization_model_id", "schema_version", "type", "type_definition")
+		Columns("store", "authorization_model_id", "schema_version", "type", "type_definition", "annotations")
 
 	for _, td := range typeDefinitions {
 		marshalledTypeDef, err := proto.Marshal(td)
@@ -462,7 +680,12 @@ func (m *MySQL) WriteAuthorizationModel(ctx context.Context, store string, model
 			return err
 		}
 
-		sb = sb.Values(store, model.Id, schemaVersion, td.GetType(), marshalledTypeDef)
+		marshalledAnnotations, err := sqlcommon.MarshalTypeAnnotations(annotations[td.GetType()])
+		if err != nil {
+			return err
+		}
+
+		sb = sb.Values(store, model.Id, schemaVersion, td.GetType(), marshalledTypeDef, marshalledAnnotations)
 	}
 
 	_, err := sb.ExecContext(ctx)
@@ -557,59 +780,28 @@ func (m *MySQL) ListStores(ctx context.Context, opts storage.PaginationOptions)
 	ctx, span := tracer.Start(ctx, "mysql.ListStores")
 	defer span.End()
 
-	sb := m.stbl.Select("id", "name", "created_at", "updated_at").
-		From("store").
-		Where(sq.Eq{"deleted_at": nil}).
-		OrderBy("id")
-
-	if opts.From != "" {
-		token, err := sqlcommon.UnmarshallContToken(opts.From)
-		if err != nil {
-			return nil, nil, err
-		}
-		sb = sb.Where(sq.GtOrEq{"id": token.Ulid})
-	}
-	if opts.PageSize > 0 {
-		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
-	}
+	return sqlcommon.ListStores(ctx, m.dbInfo(), storage.ListStoresFilter{}, opts)
+}
 
-	rows, err := sb.QueryContext(ctx)
-	if err != nil {
-		return nil, nil, sqlcommon.HandleSQLError(err)
-	}
-	defer rows.Close()
+func (m *MySQL) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ListStoresWithFilter")
+	defer span.End()
 
-	var stores []*openfgav1.Store
-	var id string
-	for rows.Next() {
-		var name string
-		var createdAt, updatedAt time.Time
-		err := rows.Scan(&id, &name, &createdAt, &updatedAt)
-		if err != nil {
-			return nil, nil, sqlcommon.HandleSQLError(err)
-		}
+	return sqlcommon.ListStores(ctx, m.dbInfo(), filter, opts)
+}
 
-		stores = append(stores, &openfgav1.Store{
-			Id:        id,
-			Name:      name,
-			CreatedAt: timestamppb.New(createdAt),
-			UpdatedAt: timestamppb.New(updatedAt),
-		})
-	}
+func (m *MySQL) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	ctx, span := tracer.Start(ctx, "mysql.WriteStoreMetadata")
+	defer span.End()
 
-	if err := rows.Err(); err != nil {
-		return nil, nil, sqlcommon.HandleSQLError(err)
-	}
+	return sqlcommon.WriteStoreMetadata(ctx, m.dbInfo(), id, metadata)
+}
 
-	if len(stores) > opts.PageSize {
-		contToken, err := json.Marshal(sqlcommon.NewContToken(id, ""))
-		if err != nil {
-			return nil, nil, err
-		}
-		return stores[:opts.PageSize], contToken, nil
-	}
+func (m *MySQL) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadStoreMetadata")
+	defer span.End()
 
-	return stores, nil, nil
+	return sqlcommon.ReadStoreMetadata(ctx, m.dbInfo(), id)
 }
 
 func (m *MySQL) DeleteStore(ctx context.Context, id string) error {
@@ -628,7 +820,49 @@ func (m *MySQL) DeleteStore(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *MySQL) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	ctx, span := tracer.Start(ctx, "mysql.UndeleteStore")
+	defer span.End()
+
+	return sqlcommon.UndeleteStore(ctx, m.dbInfo(), id, deletedAfter)
+}
+
+func (m *MySQL) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "mysql.PurgeDeletedStores")
+	defer span.End()
+
+	return sqlcommon.PurgeDeletedStores(ctx, m.dbInfo(), deletedBefore, limit)
+}
+
 // WriteAssertions is slightly different between Postgres and MySQL
+func (m *MySQL) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "mysql.DeleteAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.DeleteAuthorizationModel(ctx, m.dbInfo(), store, id)
+}
+
+func (m *MySQL) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "mysql.PinAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.PinAuthorizationModel(ctx, m.dbInfo(), store, id)
+}
+
+func (m *MySQL) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	ctx, span := tracer.Start(ctx, "mysql.UnpinAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.UnpinAuthorizationModel(ctx, m.dbInfo(), store)
+}
+
+func (m *MySQL) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadPinnedAuthorizationModelID")
+	defer span.End()
+
+	return sqlcommon.ReadPinnedAuthorizationModelID(ctx, m.dbInfo(), store)
+}
+
 func (m *MySQL) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
 	ctx, span := tracer.Start(ctx, "mysql.WriteAssertions")
 	defer span.End()
@@ -690,21 +924,43 @@ func (m *MySQL) ReadChanges(
 	ctx, span := tracer.Start(ctx, "mysql.ReadChanges")
 	defer span.End()
 
+	return m.ReadChangesWithFilter(ctx, store, storage.ReadChangesFilter{ObjectType: objectTypeFilter}, opts, horizonOffset)
+}
+
+func (m *MySQL) ReadChangesWithFilter(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadChangesWithFilter")
+	defer span.End()
+
 	sb := m.stbl.Select("ulid", "object_type", "object_id", "relation", "_user", "operation", "inserted_at").
 		From("changelog").
 		Where(sq.Eq{"store": store}).
 		Where(fmt.Sprintf("inserted_at <= NOW() - INTERVAL %d MICROSECOND", horizonOffset.Microseconds())).
 		OrderBy("inserted_at asc")
 
-	if objectTypeFilter != "" {
-		sb = sb.Where(sq.Eq{"object_type": objectTypeFilter})
+	if filter.ObjectType != "" {
+		sb = sb.Where(sq.Eq{"object_type": filter.ObjectType})
+	}
+	if filter.Relation != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.Relation})
+	}
+	if filter.Operation != nil {
+		sb = sb.Where(sq.Eq{"operation": int(*filter.Operation)})
+	}
+	if !filter.StartTime.IsZero() {
+		sb = sb.Where(sq.GtOrEq{"inserted_at": filter.StartTime})
 	}
 	if opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
 			return nil, nil, err
 		}
-		if token.ObjectType != objectTypeFilter {
+		if token.ObjectType != filter.ObjectType {
 			return nil, nil, storage.ErrMismatchObjectType
 		}
 
@@ -747,7 +1003,7 @@ func (m *MySQL) ReadChanges(
 		return nil, nil, storage.ErrNotFound
 	}
 
-	contToken, err := json.Marshal(sqlcommon.NewContToken(ulid, objectTypeFilter))
+	contToken, err := json.Marshal(sqlcommon.NewContToken(ulid, filter.ObjectType))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -755,13 +1011,27 @@ func (m *MySQL) ReadChanges(
 	return changes, contToken, nil
 }
 
+func (m *MySQL) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "mysql.DeleteChanges")
+	defer span.End()
+
+	return sqlcommon.DeleteChanges(ctx, m.dbInfo(), store, before, limit)
+}
+
+func (m *MySQL) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	ctx, span := tracer.Start(ctx, "mysql.ReadStoreStats")
+	defer span.End()
+
+	return sqlcommon.ReadStoreStats(ctx, sqlcommon.NewDBInfo(m.db, m.readStbl(ctx), sq.Expr("NOW()")), store, time.Now())
+}
+
 // IsReady reports whether this MySQL datastore instance is ready
 // to accept connections.
 func (m *MySQL) IsReady(ctx context.Context) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	if err := m.db.PingContext(ctx); err != nil {
+	if err := m.db.DB().PingContext(ctx); err != nil {
 		return false, err
 	}
 
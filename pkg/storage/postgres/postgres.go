@@ -30,15 +30,72 @@ var tracer = otel.Tracer("openfga/pkg/storage/postgres")
 
 type Postgres struct {
 	stbl                   sq.StatementBuilderType
-	db                     *sql.DB
+	db                     *sqlcommon.Pool
 	logger                 logger.Logger
 	maxTuplesPerWriteField int
 	maxTypesPerModelField  int
+	readPageSize           int
+
+	// the read replica the tuple reads are routed to, if any, see sqlcommon.WithReadURI
+	replica     *sqlcommon.Pool
+	replicaStbl sq.StatementBuilderType
+	replicaLag  *sqlcommon.ReplicaLag
+
+	// storeLocks serializes the write transactions of a store with an advisory lock, see sqlcommon.WithStoreLock
+	storeLocks bool
+
+	// serializeWrites makes every write transaction take the advisory lock, see sqlcommon.WithSerializeWrites
+	serializeWrites bool
+}
+
+// storeLockStatement takes the advisory lock of a store until the end of the transaction. The locks of the stores
+// are namespaced by the first key, so that they don't collide with the advisory locks of other applications.
+const storeLockStatement = "SELECT pg_advisory_xact_lock(hashtext('openfga.store'), hashtext($1))"
+
+type PostgresOption func(p *Postgres)
+
+// WithoutStoreLocks disables the advisory locks that serialize the write transactions of a store, for the Postgres
+// compatible databases that don't support them and whose transactions are serializable, e.g. CockroachDB.
+func WithoutStoreLocks() PostgresOption {
+	return func(p *Postgres) {
+		p.storeLocks = false
+	}
 }
 
 var _ storage.OpenFGADatastore = (*Postgres)(nil)
 
+// likeEscaper escapes the special characters of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 func New(uri string, cfg *sqlcommon.Config) (*Postgres, error) {
+	db, err := OpenDB(uri, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewWithDB(db, cfg)
+
+	if cfg.ReadURI != "" {
+		replica, err := OpenDB(cfg.ReadURI, cfg)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("failed to initialize the postgres read replica: %w", err)
+		}
+
+		p.replica = sqlcommon.NewPool(replica, "postgres_replica", cfg)
+		p.replicaStbl = sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(p.replica)
+		p.replicaLag = sqlcommon.NewReplicaLag(p.replica, measureReplicaLag, cfg.ReplicaMaxLag)
+	}
+
+	return p, nil
+}
+
+// OpenDB opens a connection pool to a Postgres compatible database with the provided config,
+// and waits until the database can be reached.
+//
+// The statements are prepared once per connection and cached by pgx, see the 'statement_cache_capacity' and
+// 'default_query_exec_mode' parameters of the uri (e.g. 'default_query_exec_mode=exec' behind a transaction pooler).
+func OpenDB(uri string, cfg *sqlcommon.Config) (*sql.DB, error) {
 
 	if cfg.Username != "" || cfg.Password != "" {
 		parsed, err := url.Parse(uri)
@@ -105,33 +162,79 @@ func New(uri string, cfg *sqlcommon.Config) (*Postgres, error) {
 		return nil, fmt.Errorf("failed to initialize postgres connection: %w", err)
 	}
 
-	return &Postgres{
-		stbl:                   sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(db),
-		db:                     db,
+	return db, nil
+}
+
+// NewWithDB constructs a Postgres datastore which uses the provided connection pool.
+func NewWithDB(db *sql.DB, cfg *sqlcommon.Config) *Postgres {
+	return NewWithPool(sqlcommon.NewPool(db, "postgres", cfg), cfg)
+}
+
+// NewWithPool constructs a Postgres datastore which runs its queries with the provided pool.
+func NewWithPool(pool *sqlcommon.Pool, cfg *sqlcommon.Config, opts ...PostgresOption) *Postgres {
+	p := &Postgres{
+		stbl:                   sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(pool),
+		db:                     pool,
 		logger:                 cfg.Logger,
 		maxTuplesPerWriteField: cfg.MaxTuplesPerWriteField,
 		maxTypesPerModelField:  cfg.MaxTypesPerModelField,
-	}, nil
+		readPageSize:           cfg.ReadPageSize,
+		storeLocks:             true,
+		serializeWrites:        cfg.SerializeWrites,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
 }
 
 // Close closes any open connections and cleans up residual resources
 // used by this storage adapter instance.
 func (p *Postgres) Close() {
 	p.db.Close()
+
+	if p.replica != nil {
+		p.replica.Close()
+	}
+}
+
+// replicaLagStatement returns the replication lag of a read replica in seconds: the time since the last transaction
+// it replayed, or 0 if it replayed every change it received or isn't a replica. It is NULL if the replica didn't
+// replay any transaction yet.
+const replicaLagStatement = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp())
+END`
+
+// measureReplicaLag measures the replication lag of the read replica, see sqlcommon.ReplicaLag.
+func measureReplicaLag(ctx context.Context, replica *sqlcommon.Pool) (time.Duration, error) {
+	return sqlcommon.ScanReplicaLag(replica.QueryRowContext(ctx, replicaLagStatement))
+}
+
+// readStbl returns the statement builder of the tuple reads, which are routed to the read replica if there is one
+// whose measured replication lag is within the staleness the read allows.
+func (p *Postgres) readStbl(ctx context.Context) sq.StatementBuilderType {
+	if p.replica == nil || !p.replicaLag.StaleReadsAllowed(ctx) {
+		return p.stbl
+	}
+
+	return p.replicaStbl
 }
 
 func (p *Postgres) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "postgres.Read")
 	defer span.End()
 
-	return p.read(ctx, store, tupleKey, nil)
+	return p.read(ctx, store, storage.NewReadFilter(tupleKey), nil)
 }
 
 func (p *Postgres) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
 	ctx, span := tracer.Start(ctx, "postgres.ReadPage")
 	defer span.End()
 
-	iter, err := p.read(ctx, store, tupleKey, &opts)
+	iter, err := p.read(ctx, store, storage.NewReadFilter(tupleKey), &opts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -140,31 +243,33 @@ func (p *Postgres) ReadPage(ctx context.Context, store string, tupleKey *openfga
 	return iter.ToArray(opts)
 }
 
-func (p *Postgres) read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts *storage.PaginationOptions) (*sqlcommon.SQLTupleIterator, error) {
+func (p *Postgres) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadPageWithFilter")
+	defer span.End()
+
+	iter, err := p.read(ctx, store, filter, &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer iter.Stop()
+
+	return iter.ToArray(opts)
+}
+
+func (p *Postgres) read(ctx context.Context, store string, filter storage.ReadFilter, opts *storage.PaginationOptions) (*sqlcommon.SQLTupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "postgres.read")
 	defer span.End()
 
-	sb := p.stbl.
-		Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := p.readStbl(ctx).
+		Select(sqlcommon.TupleColumns...).
 		From("tuple").
-		Where(sq.Eq{"store": store})
+		Where(sq.Eq{"store": store}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
 	if opts != nil {
 		sb = sb.OrderBy("ulid")
 	}
 
-	objectType, objectID := tupleUtils.SplitObject(tupleKey.GetObject())
-	if objectType != "" {
-		sb = sb.Where(sq.Eq{"object_type": objectType})
-	}
-	if objectID != "" {
-		sb = sb.Where(sq.Eq{"object_id": objectID})
-	}
-	if tupleKey.GetRelation() != "" {
-		sb = sb.Where(sq.Eq{"relation": tupleKey.GetRelation()})
-	}
-	if tupleKey.GetUser() != "" {
-		sb = sb.Where(sq.Eq{"_user": tupleKey.GetUser()})
-	}
+	sb = sb.Where(sqlcommon.MatchesFilter(filter))
 	if opts != nil && opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
@@ -181,10 +286,20 @@ func (p *Postgres) read(ctx context.Context, store string, tupleKey *openfgav1.T
 		return nil, sqlcommon.HandleSQLError(err)
 	}
 
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	return sqlcommon.NewSQLTupleIterator(ctx, rows), nil
 }
 
-func (p *Postgres) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes) error {
+// dbInfo returns the DBInfo used by the common sql methods.
+func (p *Postgres) dbInfo() *sqlcommon.DBInfo {
+	opts := []sqlcommon.DBInfoOption{sqlcommon.WithOnConflictDoNothing("ON CONFLICT DO NOTHING")}
+	if p.storeLocks {
+		opts = append(opts, sqlcommon.WithStoreLock(storeLockStatement), sqlcommon.WithSerializedWrites(p.serializeWrites))
+	}
+
+	return sqlcommon.NewDBInfo(p.db, p.stbl, "NOW()", opts...)
+}
+
+func (p *Postgres) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
 	ctx, span := tracer.Start(ctx, "postgres.Write")
 	defer span.End()
 
@@ -193,7 +308,103 @@ func (p *Postgres) Write(ctx context.Context, store string, deletes storage.Dele
 	}
 
 	now := time.Now().UTC()
-	return sqlcommon.Write(ctx, sqlcommon.NewDBInfo(p.db, p.stbl, "NOW()"), store, deletes, writes, now)
+	return sqlcommon.Write(ctx, p.dbInfo(), store, deletes, writes, now, opts...)
+}
+
+// StageWrite see storage.StagedWriteBackend.StageWrite.
+func (p *Postgres) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	ctx, span := tracer.Start(ctx, "postgres.StageWrite")
+	defer span.End()
+
+	if len(deletes)+len(writes) > p.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	return sqlcommon.StageWrite(ctx, p.dbInfo(), store, id, deletes, writes)
+}
+
+// CommitStagedWrite see storage.StagedWriteBackend.CommitStagedWrite.
+func (p *Postgres) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "postgres.CommitStagedWrite")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return sqlcommon.CommitStagedWrite(ctx, p.dbInfo(), store, id, now, opts...)
+}
+
+// DiscardStagedWrite see storage.StagedWriteBackend.DiscardStagedWrite.
+func (p *Postgres) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	ctx, span := tracer.Start(ctx, "postgres.DiscardStagedWrite")
+	defer span.End()
+
+	return sqlcommon.DiscardStagedWrite(ctx, p.dbInfo(), store, id)
+}
+
+func (p *Postgres) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "postgres.WriteWithExpiry")
+	defer span.End()
+
+	if len(deletes)+len(writes) > p.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+	return sqlcommon.WriteWithExpiry(ctx, p.dbInfo(), store, deletes, writes, expiresAt, now, opts...)
+}
+
+func (p *Postgres) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	ctx, span := tracer.Start(ctx, "postgres.WriteWithCondition")
+	defer span.End()
+
+	if len(deletes)+len(writes) > p.MaxTuplesPerWrite() {
+		return storage.ErrExceededWriteBatchLimit
+	}
+
+	now := time.Now().UTC()
+	return sqlcommon.WriteWithCondition(ctx, p.dbInfo(), store, deletes, writes, condition, now, opts...)
+}
+
+func (p *Postgres) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadTupleConditions")
+	defer span.End()
+
+	return sqlcommon.ReadTupleConditions(ctx, sqlcommon.NewDBInfo(p.db, p.readStbl(ctx), "NOW()"), store, filter, storage.ExpirationTime(ctx))
+}
+
+func (p *Postgres) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadTupleExpirations")
+	defer span.End()
+
+	return sqlcommon.ReadTupleExpirations(ctx, sqlcommon.NewDBInfo(p.db, p.readStbl(ctx), "NOW()"), store, filter, time.Now())
+}
+
+// Snapshot see storage.SnapshotBackend.Snapshot. The reads of the snapshot are run in a read-only repeatable read
+// transaction, whose snapshot is taken by its first read.
+func (p *Postgres) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	ctx, span := tracer.Start(ctx, "postgres.Snapshot")
+	defer span.End()
+
+	tx, err := p.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, sqlcommon.HandleSQLError(err)
+	}
+
+	return sqlcommon.NewSnapshotReader(tx, &Postgres{
+		stbl:                   sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(tx),
+		db:                     p.db,
+		logger:                 p.logger,
+		maxTuplesPerWriteField: p.maxTuplesPerWriteField,
+		maxTypesPerModelField:  p.maxTypesPerModelField,
+		readPageSize:           p.readPageSize,
+	}), nil
+}
+
+func (p *Postgres) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteExpiredTuples")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return sqlcommon.DeleteExpiredTuples(ctx, p.dbInfo(), limit, now)
 }
 
 func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
@@ -204,8 +415,8 @@ func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *op
 	userType := tupleUtils.GetUserTypeFromUser(tupleKey.GetUser())
 
 	var record sqlcommon.TupleRecord
-	err := p.stbl.
-		Select("object_type", "object_id", "relation", "_user").
+	err := p.readStbl(ctx).
+		Select("object_type", "object_id", "relation", "_user", "expires_at").
 		From("tuple").
 		Where(sq.Eq{
 			"store":       store,
@@ -215,12 +426,15 @@ func (p *Postgres) ReadUserTuple(ctx context.Context, store string, tupleKey *op
 			"_user":       tupleKey.GetUser(),
 			"user_type":   userType,
 		}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx))).
 		QueryRowContext(ctx).
-		Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User)
+		Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &record.ExpiresAt)
 	if err != nil {
 		return nil, sqlcommon.HandleSQLError(err)
 	}
 
+	storage.TupleExpirationsFromContext(ctx).Observe(record.ExpiresAt.Time)
+
 	return record.AsTuple(), nil
 }
 
@@ -228,10 +442,11 @@ func (p *Postgres) ReadUsersetTuples(ctx context.Context, store string, filter s
 	ctx, span := tracer.Start(ctx, "postgres.ReadUsersetTuples")
 	defer span.End()
 
-	sb := p.stbl.Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := p.readStbl(ctx).Select(sqlcommon.TupleColumns...).
 		From("tuple").
 		Where(sq.Eq{"store": store}).
-		Where(sq.Eq{"user_type": tupleUtils.UserSet})
+		Where(sq.Eq{"user_type": tupleUtils.UserSet}).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
 
 	objectType, objectID := tupleUtils.SplitObject(filter.Object)
 	if objectType != "" {
@@ -244,58 +459,76 @@ func (p *Postgres) ReadUsersetTuples(ctx context.Context, store string, filter s
 		sb = sb.Where(sq.Eq{"relation": filter.Relation})
 	}
 	if len(filter.AllowedUserTypeRestrictions) > 0 {
-		orConditions := sq.Or{}
-		for _, userset := range filter.AllowedUserTypeRestrictions {
-			if _, ok := userset.RelationOrWildcard.(*openfgav1.RelationReference_Relation); ok {
-				orConditions = append(orConditions, sq.Like{"_user": userset.Type + ":%#" + userset.GetRelation()})
-			}
-			if _, ok := userset.RelationOrWildcard.(*openfgav1.RelationReference_Wildcard); ok {
-				orConditions = append(orConditions, sq.Eq{"_user": userset.Type + ":*"})
-			}
-		}
-		sb = sb.Where(orConditions)
-	}
-	rows, err := sb.QueryContext(ctx)
-	if err != nil {
-		return nil, sqlcommon.HandleSQLError(err)
+		// the patterns are bound as a single array so that the statement, and its cached prepared statement, is the
+		// same for any number of type restrictions
+		sb = sb.Where(sq.Expr("_user LIKE ANY(?)", userTypeRestrictionPatterns(filter.AllowedUserTypeRestrictions)))
 	}
-
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	// the tuples are read by pages ordered by their unique key in the store
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"object_type", "object_id", "relation", "_user"}, p.readPageSize), nil
 }
 
 func (p *Postgres) ReadStartingWithUser(ctx context.Context, store string, opts storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
 	ctx, span := tracer.Start(ctx, "postgres.ReadStartingWithUser")
 	defer span.End()
 
-	var targetUsersArg []string
-	for _, u := range opts.UserFilter {
-		targetUser := u.GetObject()
-		if u.GetRelation() != "" {
-			targetUser = strings.Join([]string{u.GetObject(), u.GetRelation()}, "#")
-		}
-		targetUsersArg = append(targetUsersArg, targetUser)
-	}
-
-	rows, err := p.stbl.
-		Select("store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at").
+	sb := p.readStbl(ctx).
+		Select(sqlcommon.TupleColumns...).
 		From("tuple").
 		Where(sq.Eq{
 			"store":       store,
 			"object_type": opts.ObjectType,
 			"relation":    opts.Relation,
-			"_user":       targetUsersArg,
-		}).QueryContext(ctx)
-	if err != nil {
-		return nil, sqlcommon.HandleSQLError(err)
+		}).
+		// the users are bound as a single array rather than an IN list so that the statement, and its cached
+		// prepared statement, is the same for any number of users
+		Where(sq.Expr("_user = ANY(?)", targetUsers(opts.UserFilter))).
+		Where(sqlcommon.NotExpired(storage.ExpirationTime(ctx)))
+
+	// the tuples are read by pages ordered by their unique key given their store, object type and relation
+	return sqlcommon.NewPaginatedTupleIterator(ctx, sb, []string{"_user", "object_id"}, p.readPageSize), nil
+}
+
+// userTypeRestrictionPatterns returns the LIKE patterns of the users of the userset tuples allowed by the type
+// restrictions, e.g. 'group:%#member' for the members of the groups and 'user:*' for the wildcard of the users.
+func userTypeRestrictionPatterns(restrictions []*openfgav1.RelationReference) []string {
+	patterns := make([]string, 0, len(restrictions))
+	for _, userset := range restrictions {
+		switch userset.RelationOrWildcard.(type) {
+		case *openfgav1.RelationReference_Relation:
+			patterns = append(patterns, likeEscaper.Replace(userset.Type)+":%#"+likeEscaper.Replace(userset.GetRelation()))
+		case *openfgav1.RelationReference_Wildcard:
+			patterns = append(patterns, likeEscaper.Replace(userset.Type)+":*")
+		}
 	}
 
-	return sqlcommon.NewSQLTupleIterator(rows), nil
+	return patterns
+}
+
+// targetUsers returns the users of the user filter, e.g. 'group:eng#member'.
+func targetUsers(userFilter []*openfgav1.ObjectRelation) []string {
+	users := make([]string, 0, len(userFilter))
+	for _, u := range userFilter {
+		targetUser := u.GetObject()
+		if u.GetRelation() != "" {
+			targetUser = strings.Join([]string{u.GetObject(), u.GetRelation()}, "#")
+		}
+		users = append(users, targetUser)
+	}
+
+	return users
 }
 
 func (p *Postgres) MaxTuplesPerWrite() int {
 	return p.maxTuplesPerWriteField
 }
 
+func (p *Postgres) ReadAuthorizationModelAnnotations(ctx context.Context, store string, modelID string) (storage.ModelAnnotations, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadAuthorizationModelAnnotations")
+	defer span.End()
+
+	return sqlcommon.ReadAuthorizationModelAnnotations(ctx, p.dbInfo(), store, modelID)
+}
+
 func (p *Postgres) ReadAuthorizationModel(ctx context.Context, store string, modelID string) (*openfgav1.AuthorizationModel, error) {
 	ctx, span := tracer.Start(ctx, "postgres.ReadAuthorizationModel")
 	defer span.End()
@@ -455,6 +688,17 @@ func (p *Postgres) WriteAuthorizationModel(ctx context.Context, store string, mo
 	ctx, span := tracer.Start(ctx, "postgres.WriteAuthorizationModel")
 	defer span.End()
 
+	return p.writeAuthorizationModel(ctx, store, model, nil)
+}
+
+func (p *Postgres) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	ctx, span := tracer.Start(ctx, "postgres.WriteAuthorizationModelWithAnnotations")
+	defer span.End()
+
+	return p.writeAuthorizationModel(ctx, store, model, annotations)
+}
+
+func (p *Postgres) writeAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
 	schemaVersion := model.GetSchemaVersion()
 	typeDefinitions := model.GetTypeDefinitions()
 
@@ -468,7 +712,7 @@ func (p *Postgres) WriteAuthorizationModel(ctx context.Context, store string, mo
 
 	sb := p.stbl.
 		Insert("authorization_model").
-		Columns("store", "authorization_model_id", "schema_version", "type", "type_definition")
+		Columns("store", "authorization_model_id", "schema_version", "type", "type_definition", "annotations")
 
 	for _, td := range typeDefinitions {
 		marshalledTypeDef, err := proto.Marshal(td)
@@ -476,7 +720,12 @@ func (p *Postgres) WriteAuthorizationModel(ctx context.Context, store string, mo
 			return err
 		}
 
-		sb = sb.Values(store, model.Id, schemaVersion, td.GetType(), marshalledTypeDef)
+		marshalledAnnotations, err := sqlcommon.MarshalTypeAnnotations(annotations[td.GetType()])
+		if err != nil {
+			return err
+		}
+
+		sb = sb.Values(store, model.Id, schemaVersion, td.GetType(), marshalledTypeDef, marshalledAnnotations)
 	}
 
 	_, err := sb.ExecContext(ctx)
@@ -548,59 +797,28 @@ func (p *Postgres) ListStores(ctx context.Context, opts storage.PaginationOption
 	ctx, span := tracer.Start(ctx, "postgres.ListStores")
 	defer span.End()
 
-	sb := p.stbl.Select("id", "name", "created_at", "updated_at").
-		From("store").
-		Where(sq.Eq{"deleted_at": nil}).
-		OrderBy("id")
-
-	if opts.From != "" {
-		token, err := sqlcommon.UnmarshallContToken(opts.From)
-		if err != nil {
-			return nil, nil, err
-		}
-		sb = sb.Where(sq.GtOrEq{"id": token.Ulid})
-	}
-	if opts.PageSize > 0 {
-		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
-	}
+	return sqlcommon.ListStores(ctx, p.dbInfo(), storage.ListStoresFilter{}, opts)
+}
 
-	rows, err := sb.QueryContext(ctx)
-	if err != nil {
-		return nil, nil, sqlcommon.HandleSQLError(err)
-	}
-	defer rows.Close()
+func (p *Postgres) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListStoresWithFilter")
+	defer span.End()
 
-	var stores []*openfgav1.Store
-	var id string
-	for rows.Next() {
-		var name string
-		var createdAt, updatedAt time.Time
-		err := rows.Scan(&id, &name, &createdAt, &updatedAt)
-		if err != nil {
-			return nil, nil, sqlcommon.HandleSQLError(err)
-		}
+	return sqlcommon.ListStores(ctx, p.dbInfo(), filter, opts)
+}
 
-		stores = append(stores, &openfgav1.Store{
-			Id:        id,
-			Name:      name,
-			CreatedAt: timestamppb.New(createdAt),
-			UpdatedAt: timestamppb.New(updatedAt),
-		})
-	}
+func (p *Postgres) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	ctx, span := tracer.Start(ctx, "postgres.WriteStoreMetadata")
+	defer span.End()
 
-	if err := rows.Err(); err != nil {
-		return nil, nil, sqlcommon.HandleSQLError(err)
-	}
+	return sqlcommon.WriteStoreMetadata(ctx, p.dbInfo(), id, metadata)
+}
 
-	if len(stores) > opts.PageSize {
-		contToken, err := json.Marshal(sqlcommon.NewContToken(id, ""))
-		if err != nil {
-			return nil, nil, err
-		}
-		return stores[:opts.PageSize], contToken, nil
-	}
+func (p *Postgres) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadStoreMetadata")
+	defer span.End()
 
-	return stores, nil, nil
+	return sqlcommon.ReadStoreMetadata(ctx, p.dbInfo(), id)
 }
 
 func (p *Postgres) DeleteStore(ctx context.Context, id string) error {
@@ -619,7 +837,49 @@ func (p *Postgres) DeleteStore(ctx context.Context, id string) error {
 	return nil
 }
 
+func (p *Postgres) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	ctx, span := tracer.Start(ctx, "postgres.UndeleteStore")
+	defer span.End()
+
+	return sqlcommon.UndeleteStore(ctx, p.dbInfo(), id, deletedAfter)
+}
+
+func (p *Postgres) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "postgres.PurgeDeletedStores")
+	defer span.End()
+
+	return sqlcommon.PurgeDeletedStores(ctx, p.dbInfo(), deletedBefore, limit)
+}
+
 // WriteAssertions is slightly different between Postgres and MySQL
+func (p *Postgres) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.DeleteAuthorizationModel(ctx, p.dbInfo(), store, id)
+}
+
+func (p *Postgres) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	ctx, span := tracer.Start(ctx, "postgres.PinAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.PinAuthorizationModel(ctx, p.dbInfo(), store, id)
+}
+
+func (p *Postgres) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	ctx, span := tracer.Start(ctx, "postgres.UnpinAuthorizationModel")
+	defer span.End()
+
+	return sqlcommon.UnpinAuthorizationModel(ctx, p.dbInfo(), store)
+}
+
+func (p *Postgres) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadPinnedAuthorizationModelID")
+	defer span.End()
+
+	return sqlcommon.ReadPinnedAuthorizationModelID(ctx, p.dbInfo(), store)
+}
+
 func (p *Postgres) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
 	ctx, span := tracer.Start(ctx, "postgres.WriteAssertions")
 	defer span.End()
@@ -681,21 +941,43 @@ func (p *Postgres) ReadChanges(
 	ctx, span := tracer.Start(ctx, "postgres.ReadChanges")
 	defer span.End()
 
+	return p.ReadChangesWithFilter(ctx, store, storage.ReadChangesFilter{ObjectType: objectTypeFilter}, opts, horizonOffset)
+}
+
+func (p *Postgres) ReadChangesWithFilter(
+	ctx context.Context,
+	store string,
+	filter storage.ReadChangesFilter,
+	opts storage.PaginationOptions,
+	horizonOffset time.Duration,
+) ([]*openfgav1.TupleChange, []byte, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadChangesWithFilter")
+	defer span.End()
+
 	sb := p.stbl.Select("ulid", "object_type", "object_id", "relation", "_user", "operation", "inserted_at").
 		From("changelog").
 		Where(sq.Eq{"store": store}).
 		Where(fmt.Sprintf("inserted_at < NOW() - interval '%dms'", horizonOffset.Milliseconds())).
 		OrderBy("inserted_at asc")
 
-	if objectTypeFilter != "" {
-		sb = sb.Where(sq.Eq{"object_type": objectTypeFilter})
+	if filter.ObjectType != "" {
+		sb = sb.Where(sq.Eq{"object_type": filter.ObjectType})
+	}
+	if filter.Relation != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.Relation})
+	}
+	if filter.Operation != nil {
+		sb = sb.Where(sq.Eq{"operation": int(*filter.Operation)})
+	}
+	if !filter.StartTime.IsZero() {
+		sb = sb.Where(sq.GtOrEq{"inserted_at": filter.StartTime})
 	}
 	if opts.From != "" {
 		token, err := sqlcommon.UnmarshallContToken(opts.From)
 		if err != nil {
 			return nil, nil, err
 		}
-		if token.ObjectType != objectTypeFilter {
+		if token.ObjectType != filter.ObjectType {
 			return nil, nil, storage.ErrMismatchObjectType
 		}
 
@@ -738,7 +1020,7 @@ func (p *Postgres) ReadChanges(
 		return nil, nil, storage.ErrNotFound
 	}
 
-	contToken, err := json.Marshal(sqlcommon.NewContToken(ulid, objectTypeFilter))
+	contToken, err := json.Marshal(sqlcommon.NewContToken(ulid, filter.ObjectType))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -746,13 +1028,27 @@ func (p *Postgres) ReadChanges(
 	return changes, contToken, nil
 }
 
+func (p *Postgres) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteChanges")
+	defer span.End()
+
+	return sqlcommon.DeleteChanges(ctx, p.dbInfo(), store, before, limit)
+}
+
+func (p *Postgres) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ReadStoreStats")
+	defer span.End()
+
+	return sqlcommon.ReadStoreStats(ctx, sqlcommon.NewDBInfo(p.db, p.readStbl(ctx), "NOW()"), store, time.Now())
+}
+
 // IsReady reports whether this Postgres datastore instance is ready
 // to accept connections.
 func (p *Postgres) IsReady(ctx context.Context) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	if err := p.db.PingContext(ctx); err != nil {
+	if err := p.db.DB().PingContext(ctx); err != nil {
 		return false, err
 	}
 
@@ -2,6 +2,13 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -143,3 +150,193 @@ func TestReadPageEnsureOrder(t *testing.T) {
 	require.Equal(t, firstTuple, tuples[1].Key)
 
 }
+
+// TestReadUsersetTuplesMatchesTheTypeRestrictionsLiterally asserts that the special characters of the LIKE patterns
+// of the type restrictions, e.g. '_', match literally.
+func TestReadUsersetTuplesMatchesTheTypeRestrictionsLiterally(t *testing.T) {
+	testDatastore := storagefixtures.RunDatastoreTestContainer(t, "postgres")
+
+	uri := testDatastore.GetConnectionURI(true)
+	ds, err := New(uri, sqlcommon.NewConfig())
+	require.NoError(t, err)
+	defer ds.Close()
+
+	ctx := context.Background()
+	store := "store"
+
+	err = ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "team_a:eng#can_view"),
+		tuple.NewTupleKey("document:1", "viewer", "teamXa:eng#canXview"),
+		tuple.NewTupleKey("document:1", "viewer", "team_a:eng#canXview"),
+	})
+	require.NoError(t, err)
+
+	tuples, err := ds.ReadUsersetTuples(ctx, store, storage.ReadUsersetTuplesFilter{
+		Object:   "document:1",
+		Relation: "viewer",
+		AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("team_a", "can_view"),
+		},
+	})
+	require.NoError(t, err)
+
+	iter := storage.NewTupleKeyIteratorFromTupleIterator(tuples)
+	defer iter.Stop()
+
+	got, err := iter.Next()
+	require.NoError(t, err)
+	require.Equal(t, "team_a:eng#can_view", got.GetUser())
+
+	_, err = iter.Next()
+	require.ErrorIs(t, err, storage.ErrIteratorDone)
+}
+
+// recordingDriver is a database driver whose queries return no rows and are recorded along with their arguments.
+type recordingDriver struct {
+	mu      sync.Mutex
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{driver: d}, nil }
+
+type recordingConn struct {
+	driver *recordingDriver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+// CheckNamedValue accepts the arrays bound by the queries, which pgx encodes itself.
+func (c *recordingConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type recordingStmt struct {
+	driver *recordingDriver
+	query  string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("statements are not supported")
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+
+	s.driver.queries = append(s.driver.queries, recordedQuery{query: s.query, args: args})
+	return emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return sqlcommon.TupleColumns }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+var recordingDriverCount atomic.Int32
+
+// newRecordingPostgres returns a Postgres datastore whose queries are recorded by the returned driver.
+func newRecordingPostgres(t *testing.T) (*Postgres, *recordingDriver) {
+	d := &recordingDriver{}
+	name := fmt.Sprintf("postgres_recording_%d", recordingDriverCount.Add(1))
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+
+	ds := NewWithDB(db, sqlcommon.NewConfig())
+	t.Cleanup(ds.Close)
+
+	return ds, d
+}
+
+// readAll reads the tuples of the iterator until it is done.
+func readAll(t *testing.T, iter storage.TupleIterator, err error) {
+	require.NoError(t, err)
+	defer iter.Stop()
+
+	_, err = iter.Next()
+	require.ErrorIs(t, err, storage.ErrIteratorDone)
+}
+
+func TestReadUsersetTuplesBindsTheTypeRestrictionsAsAnArray(t *testing.T) {
+	ctx := context.Background()
+	ds, d := newRecordingPostgres(t)
+
+	iter, err := ds.ReadUsersetTuples(ctx, "store", storage.ReadUsersetTuplesFilter{
+		Object:   "document:1",
+		Relation: "viewer",
+		AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("group", "member"),
+		},
+	})
+	readAll(t, iter, err)
+
+	iter, err = ds.ReadUsersetTuples(ctx, "store", storage.ReadUsersetTuplesFilter{
+		Object:   "document:1",
+		Relation: "viewer",
+		AllowedUserTypeRestrictions: []*openfgav1.RelationReference{
+			typesystem.DirectRelationReference("group", "member"),
+			typesystem.DirectRelationReference("team_a", "can_view"),
+			typesystem.WildcardRelationReference("user"),
+		},
+	})
+	readAll(t, iter, err)
+
+	require.Len(t, d.queries, 2)
+	require.Contains(t, d.queries[0].query, "_user LIKE ANY(")
+	require.Equal(t, d.queries[0].query, d.queries[1].query)
+	require.Contains(t, d.queries[0].args, []string{"group:%#member"})
+	require.Contains(t, d.queries[1].args, []string{`group:%#member`, `team\_a:%#can\_view`, `user:*`})
+}
+
+func TestReadStartingWithUserBindsTheUsersAsAnArray(t *testing.T) {
+	ctx := context.Background()
+	ds, d := newRecordingPostgres(t)
+
+	iter, err := ds.ReadStartingWithUser(ctx, "store", storage.ReadStartingWithUserFilter{
+		ObjectType: "document",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}},
+	})
+	readAll(t, iter, err)
+
+	iter, err = ds.ReadStartingWithUser(ctx, "store", storage.ReadStartingWithUserFilter{
+		ObjectType: "document",
+		Relation:   "viewer",
+		UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}, {Object: "group:eng", Relation: "member"}},
+	})
+	readAll(t, iter, err)
+
+	require.Len(t, d.queries, 2)
+	require.Contains(t, d.queries[0].query, "_user = ANY(")
+	require.Equal(t, d.queries[0].query, d.queries[1].query)
+	require.Contains(t, d.queries[0].args, []string{"user:jon"})
+	require.Contains(t, d.queries[1].args, []string{"user:jon", "group:eng#member"})
+}
+
+func TestLikeEscaper(t *testing.T) {
+	for value, expected := range map[string]string{
+		"group":       "group",
+		"team_a":      `team\_a`,
+		"100%":        `100\%`,
+		`back\slash`:  `back\\slash`,
+		`a_%\b`:       `a\_\%\\b`,
+		"group:*#all": "group:*#all",
+	} {
+		require.Equal(t, expected, likeEscaper.Replace(value), value)
+	}
+}
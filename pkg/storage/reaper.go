@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTupleReaperInterval  = 1 * time.Minute
+	defaultTupleReaperBatchSize = 100
+)
+
+// TupleReaper periodically deletes the expired tuples of a datastore, see TupleExpirationBackend.
+type TupleReaper struct {
+	backend   TupleExpirationBackend
+	logger    logger.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+type TupleReaperOption func(r *TupleReaper)
+
+// WithTupleReaperInterval sets how long the reaper waits between two deletions of the expired tuples.
+func WithTupleReaperInterval(interval time.Duration) TupleReaperOption {
+	return func(r *TupleReaper) {
+		r.interval = interval
+	}
+}
+
+// WithTupleReaperBatchSize sets the maximum number of expired tuples deleted in a single transaction.
+func WithTupleReaperBatchSize(batchSize int) TupleReaperOption {
+	return func(r *TupleReaper) {
+		r.batchSize = batchSize
+	}
+}
+
+func WithTupleReaperLogger(l logger.Logger) TupleReaperOption {
+	return func(r *TupleReaper) {
+		r.logger = l
+	}
+}
+
+func NewTupleReaper(backend TupleExpirationBackend, opts ...TupleReaperOption) *TupleReaper {
+	r := &TupleReaper{
+		backend:   backend,
+		logger:    logger.NewNoopLogger(),
+		interval:  defaultTupleReaperInterval,
+		batchSize: defaultTupleReaperBatchSize,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run deletes the expired tuples every interval until the context is cancelled. Errors are logged
+// and the deletion is retried on the next interval.
+func (r *TupleReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := r.Reap(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("failed to delete the expired tuples", zap.Error(err))
+		}
+
+		if deleted > 0 {
+			r.logger.Debug("deleted the expired tuples", zap.Int("count", deleted))
+		}
+	}
+}
+
+// Reap deletes every expired tuple, one batch at a time, and returns the number of tuples deleted.
+func (r *TupleReaper) Reap(ctx context.Context) (int, error) {
+	var total int
+	for {
+		deleted, err := r.backend.DeleteExpiredTuples(ctx, r.batchSize)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+
+		if deleted < r.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+)
+
+// DatastoreConfig is the configuration of the datastore the server is run with, passed to the factory of the
+// registered datastore engine it selects.
+type DatastoreConfig struct {
+	// URI is the connection uri of the datastore.
+	URI string
+
+	Username string
+	Password string
+
+	Logger logger.Logger
+
+	MaxTuplesPerWrite             int
+	MaxTypesPerAuthorizationModel int
+
+	// MaxOpenConns, MaxIdleConns, ConnMaxIdleTime and ConnMaxLifetime configure the pool of connections to the
+	// datastore, if any.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+}
+
+// DatastoreFactory returns a datastore connected with the configuration of the server.
+type DatastoreFactory func(cfg *DatastoreConfig) (OpenFGADatastore, error)
+
+// builtinEngines are the datastore engines built into the server.
+var builtinEngines = []string{"memory", "postgres", "mysql", "cockroachdb", "cassandra", "bolt"}
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]DatastoreFactory)
+)
+
+// Register makes a datastore engine available by the provided name, which selects it as the 'datastore.engine'
+// config value of the server. It is meant to be called from the init function of the package providing the
+// datastore. If Register is called twice with the same name, with the name of an engine built into the server
+// ('memory', 'postgres', 'mysql', 'cockroachdb', 'cassandra' and 'bolt') or if factory is nil, it panics.
+func Register(name string, factory DatastoreFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+
+	for _, builtin := range builtinEngines {
+		if name == builtin {
+			panic(fmt.Sprintf("storage: Register called for the builtin datastore engine '%s'", name))
+		}
+	}
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("storage: Register called twice for datastore engine '%s'", name))
+	}
+
+	factories[name] = factory
+}
+
+// LookupFactory returns the factory of the datastore engine registered with the provided name, if any.
+func LookupFactory(name string) (DatastoreFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[name]
+	return factory, ok
+}
+
+// RegisteredEngines returns the sorted names of the registered datastore engines.
+func RegisteredEngines() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
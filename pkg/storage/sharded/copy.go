@@ -0,0 +1,232 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+)
+
+// copyPageSize is the number of tuples and authorization models read at once by CopyStore.
+const copyPageSize = 100
+
+// CopyStore copies a store from the datastore `src` to the datastore `dst`: the store and its metadata, its
+// authorization models with their annotations and assertions, and its unexpired tuples with their conditions and
+// expiration times. The changelog is not copied: the changes of the copy are the writes of its tuples. The store
+// should not be written to while it is copied, and it must not exist in `dst`.
+func CopyStore(ctx context.Context, src, dst storage.OpenFGADatastore, id string) error {
+	store, err := src.GetStore(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read the store: %w", err)
+	}
+
+	if _, err := dst.CreateStore(ctx, &openfgav1.Store{Id: store.GetId(), Name: store.GetName()}); err != nil {
+		return fmt.Errorf("failed to create the store: %w", err)
+	}
+
+	metadata, err := src.ReadStoreMetadata(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read the store metadata: %w", err)
+	}
+	if metadata.Description != "" || len(metadata.Labels) > 0 {
+		if err := dst.WriteStoreMetadata(ctx, id, metadata); err != nil {
+			return fmt.Errorf("failed to write the store metadata: %w", err)
+		}
+	}
+
+	if err := copyModels(ctx, src, dst, id); err != nil {
+		return err
+	}
+
+	return copyTuples(ctx, src, dst, id)
+}
+
+// copyModels copies the authorization models of the store from the oldest to the newest, so that the latest model
+// of the copy is the latest model of the store, and pins the pinned model of the store.
+func copyModels(ctx context.Context, src, dst storage.OpenFGADatastore, store string) error {
+	var models []*openfgav1.AuthorizationModel
+	opts := storage.PaginationOptions{PageSize: copyPageSize}
+	for {
+		page, contToken, err := src.ReadAuthorizationModels(ctx, store, opts)
+		if err != nil {
+			return fmt.Errorf("failed to read the authorization models: %w", err)
+		}
+		models = append(models, page...)
+
+		if len(contToken) == 0 {
+			break
+		}
+		opts.From = string(contToken)
+	}
+
+	// the model IDs are ULIDs
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].GetId() < models[j].GetId()
+	})
+
+	for _, model := range models {
+		annotations, err := src.ReadAuthorizationModelAnnotations(ctx, store, model.GetId())
+		if err != nil {
+			return fmt.Errorf("failed to read the annotations of the authorization model '%s': %w", model.GetId(), err)
+		}
+
+		if len(annotations) > 0 {
+			err = dst.WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations)
+		} else {
+			err = dst.WriteAuthorizationModel(ctx, store, model)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write the authorization model '%s': %w", model.GetId(), err)
+		}
+
+		assertions, err := src.ReadAssertions(ctx, store, model.GetId())
+		if err != nil {
+			return fmt.Errorf("failed to read the assertions of the authorization model '%s': %w", model.GetId(), err)
+		}
+
+		if len(assertions) > 0 {
+			if err := dst.WriteAssertions(ctx, store, model.GetId(), assertions); err != nil {
+				return fmt.Errorf("failed to write the assertions of the authorization model '%s': %w", model.GetId(), err)
+			}
+		}
+	}
+
+	pinnedModelID, err := src.ReadPinnedAuthorizationModelID(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to read the pinned authorization model: %w", err)
+	}
+
+	if pinnedModelID != "" {
+		if err := dst.PinAuthorizationModel(ctx, store, pinnedModelID); err != nil {
+			return fmt.Errorf("failed to pin the authorization model '%s': %w", pinnedModelID, err)
+		}
+	}
+
+	return nil
+}
+
+// copyTuples copies the tuples of the store in the order in which they were written. The tuples without a condition
+// or an expiration time are written in batches, and the others one at a time.
+func copyTuples(ctx context.Context, src, dst storage.OpenFGADatastore, store string) error {
+	conditions, err := src.ReadTupleConditions(ctx, store, &openfgav1.TupleKey{})
+	if err != nil {
+		return fmt.Errorf("failed to read the tuple conditions: %w", err)
+	}
+
+	expirations, err := src.ReadTupleExpirations(ctx, store, &openfgav1.TupleKey{})
+	if err != nil {
+		return fmt.Errorf("failed to read the tuple expirations: %w", err)
+	}
+
+	batchSize := dst.MaxTuplesPerWrite()
+	var batch storage.Writes
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := dst.Write(ctx, store, nil, batch)
+		batch = nil
+		return err
+	}
+
+	opts := storage.PaginationOptions{PageSize: copyPageSize}
+	for {
+		tuples, contToken, err := src.ReadPageWithFilter(ctx, store, storage.ReadFilter{}, opts)
+		if err != nil {
+			return fmt.Errorf("failed to read the tuples: %w", err)
+		}
+
+		for _, t := range tuples {
+			tk := tupleUtils.NewTupleKey(t.GetKey().GetObject(), t.GetKey().GetRelation(), t.GetKey().GetUser())
+			key := tupleUtils.TupleKeyToString(tk)
+
+			condition, conditional := conditions[key]
+			expiresAt, expiring := expirations[key]
+			switch {
+			case conditional:
+				err = dst.WriteWithCondition(ctx, store, nil, storage.Writes{tk}, condition)
+			case expiring:
+				if !expiresAt.After(time.Now()) {
+					continue
+				}
+				err = dst.WriteWithExpiry(ctx, store, nil, storage.Writes{tk}, expiresAt)
+			default:
+				batch = append(batch, tk)
+				if len(batch) >= batchSize {
+					err = flush()
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write the tuples: %w", err)
+			}
+		}
+
+		if len(contToken) == 0 {
+			break
+		}
+		opts.From = string(contToken)
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to write the tuples: %w", err)
+	}
+
+	return nil
+}
+
+// Move is the move of a store from the shard it is on to the shard that owns it.
+type Move struct {
+	Store string
+	From  string
+	To    string
+}
+
+// Moves returns the moves of the stores which are on a shard other than their owner, e.g. because a shard was added
+// or removed from the datastore, or a store was pinned. The deleted stores are left where they are until purged.
+func (s *Sharded) Moves(ctx context.Context) ([]Move, error) {
+	var moves []Move
+	for _, shard := range s.shards {
+		stores, err := listAllStores(ctx, shard.Datastore, storage.ListStoresFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the stores of the shard '%s': %w", shard.Name, err)
+		}
+
+		for _, store := range stores {
+			if owner := s.Owner(store.GetId()); owner != shard.Name {
+				moves = append(moves, Move{Store: store.GetId(), From: shard.Name, To: owner})
+			}
+		}
+	}
+
+	return moves, nil
+}
+
+// Move copies the store to its new shard with CopyStore, and then deletes it from its old shard. The store is not
+// found by the servers which route it to its new shard until it is copied, and it must not be written to while it
+// is copied, so the stores should be moved while the servers are stopped.
+func (s *Sharded) Move(ctx context.Context, move Move) error {
+	from, ok := s.byName[move.From]
+	if !ok {
+		return fmt.Errorf("unknown shard '%s'", move.From)
+	}
+
+	to, ok := s.byName[move.To]
+	if !ok {
+		return fmt.Errorf("unknown shard '%s'", move.To)
+	}
+
+	if err := CopyStore(ctx, from, to, move.Store); err != nil {
+		if errors.Is(err, storage.ErrCollision) {
+			return fmt.Errorf("the store '%s' already exists on the shard '%s': %w", move.Store, move.To, err)
+		}
+		return err
+	}
+
+	return from.DeleteStore(ctx, move.Store)
+}
@@ -0,0 +1,480 @@
+// Package sharded contains an implementation of the storage interface that spreads the stores across several
+// datastores, the shards. Every store lives on a single shard, its owner, to which all of its operations are routed,
+// so the operations of a store are as consistent as those of its shard.
+//
+// The owner of a store is chosen by rendezvous hashing of the store ID over the names of the shards, so adding or
+// removing a shard only moves the stores it gains or loses. A store may also be pinned to a shard, which overrides
+// its hash. The stores on a shard other than their owner, e.g. once a shard is added or drained, are moved with Moves
+// and Move.
+package sharded
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("openfga/pkg/storage/sharded")
+
+var _ storage.OpenFGADatastore = (*Sharded)(nil)
+
+// Shard is one of the datastores of a Sharded datastore. Its name determines the stores it owns, so it must not
+// change once the shard holds stores.
+type Shard struct {
+	Name      string
+	Datastore storage.OpenFGADatastore
+
+	// Draining shards own no stores, except those pinned to them, so that their stores are moved to the other shards
+	// before they are removed.
+	Draining bool
+}
+
+// Sharded is a datastore whose stores are spread across several shards.
+type Sharded struct {
+	shards []Shard
+	byName map[string]storage.OpenFGADatastore
+	pins   map[string]string
+}
+
+type Option func(s *Sharded)
+
+// WithPins pins stores to shards: the store whose ID is a key of `pins` is owned by the shard named by its value,
+// whatever its hash.
+func WithPins(pins map[string]string) Option {
+	return func(s *Sharded) {
+		for store, shard := range pins {
+			s.pins[store] = shard
+		}
+	}
+}
+
+// New returns a datastore that spreads the stores across the shards. The shards must have distinct, non-empty,
+// names, and the stores must be pinned to shards that exist.
+func New(shards []Shard, opts ...Option) (*Sharded, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("a sharded datastore needs at least one shard")
+	}
+
+	s := &Sharded{
+		shards: shards,
+		byName: make(map[string]storage.OpenFGADatastore, len(shards)),
+		pins:   map[string]string{},
+	}
+
+	draining := 0
+	for _, shard := range shards {
+		if shard.Draining {
+			draining++
+		}
+		if shard.Name == "" {
+			return nil, errors.New("the shards of a sharded datastore must be named")
+		}
+		if _, ok := s.byName[shard.Name]; ok {
+			return nil, fmt.Errorf("the shard '%s' is configured twice", shard.Name)
+		}
+		s.byName[shard.Name] = shard.Datastore
+	}
+
+	if draining == len(shards) {
+		return nil, errors.New("a sharded datastore needs at least one shard that is not draining")
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	for store, shard := range s.pins {
+		if _, ok := s.byName[shard]; !ok {
+			return nil, fmt.Errorf("the store '%s' is pinned to the unknown shard '%s'", store, shard)
+		}
+	}
+
+	return s, nil
+}
+
+// Owner returns the name of the shard that owns the store: the shard it is pinned to, if any, or else the shard
+// which is not draining with the highest hash of its name and the store ID.
+func (s *Sharded) Owner(store string) string {
+	if shard, ok := s.pins[store]; ok {
+		return shard
+	}
+
+	var owner string
+	var highest uint64
+	for _, shard := range s.shards {
+		if shard.Draining {
+			continue
+		}
+
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(shard.Name))
+		_, _ = h.Write([]byte{'/'})
+		_, _ = h.Write([]byte(store))
+
+		if score := h.Sum64(); owner == "" || score > highest {
+			owner, highest = shard.Name, score
+		}
+	}
+
+	return owner
+}
+
+// Shard returns the datastore of the shard with the provided name, if any.
+func (s *Sharded) Shard(name string) (storage.OpenFGADatastore, bool) {
+	ds, ok := s.byName[name]
+	return ds, ok
+}
+
+// owner returns the datastore of the shard that owns the store.
+func (s *Sharded) owner(store string) storage.OpenFGADatastore {
+	return s.byName[s.Owner(store)]
+}
+
+func (s *Sharded) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return s.owner(store).Read(ctx, store, tk)
+}
+
+func (s *Sharded) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return s.owner(store).ReadPage(ctx, store, tk, opts)
+}
+
+func (s *Sharded) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return s.owner(store).ReadPageWithFilter(ctx, store, filter, opts)
+}
+
+func (s *Sharded) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	return s.owner(store).ReadUserTuple(ctx, store, tk)
+}
+
+func (s *Sharded) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	return s.owner(store).ReadUsersetTuples(ctx, store, filter)
+}
+
+func (s *Sharded) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	return s.owner(store).ReadStartingWithUser(ctx, store, filter)
+}
+
+func (s *Sharded) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	return s.owner(store).Write(ctx, store, deletes, writes, opts...)
+}
+
+// MaxTuplesPerWrite returns the smallest maximum of the shards, so that a write accepted for a store is accepted by
+// whichever shard owns it.
+func (s *Sharded) MaxTuplesPerWrite() int {
+	max := s.shards[0].Datastore.MaxTuplesPerWrite()
+	for _, shard := range s.shards[1:] {
+		if n := shard.Datastore.MaxTuplesPerWrite(); n < max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+func (s *Sharded) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	return s.owner(store).WriteWithExpiry(ctx, store, deletes, writes, expiresAt, opts...)
+}
+
+// DeleteExpiredTuples deletes the expired tuples of the shards in turn, until `limit` tuples are deleted.
+func (s *Sharded) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	return s.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.DeleteExpiredTuples(ctx, remaining)
+	})
+}
+
+func (s *Sharded) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	return s.owner(store).ReadTupleExpirations(ctx, store, filter)
+}
+
+func (s *Sharded) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	return s.owner(store).WriteWithCondition(ctx, store, deletes, writes, condition, opts...)
+}
+
+func (s *Sharded) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	return s.owner(store).StageWrite(ctx, store, id, deletes, writes)
+}
+
+func (s *Sharded) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	return s.owner(store).CommitStagedWrite(ctx, store, id, opts...)
+}
+
+func (s *Sharded) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	return s.owner(store).DiscardStagedWrite(ctx, store, id)
+}
+
+func (s *Sharded) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	return s.owner(store).ReadTupleConditions(ctx, store, filter)
+}
+
+func (s *Sharded) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	return s.owner(store).Snapshot(ctx, store)
+}
+
+func (s *Sharded) ReadAuthorizationModel(ctx context.Context, store string, id string) (*openfgav1.AuthorizationModel, error) {
+	return s.owner(store).ReadAuthorizationModel(ctx, store, id)
+}
+
+func (s *Sharded) ReadAuthorizationModels(ctx context.Context, store string, opts storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
+	return s.owner(store).ReadAuthorizationModels(ctx, store, opts)
+}
+
+func (s *Sharded) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	return s.owner(store).FindLatestAuthorizationModelID(ctx, store)
+}
+
+func (s *Sharded) ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (storage.ModelAnnotations, error) {
+	return s.owner(store).ReadAuthorizationModelAnnotations(ctx, store, id)
+}
+
+// MaxTypesPerAuthorizationModel returns the smallest maximum of the shards.
+func (s *Sharded) MaxTypesPerAuthorizationModel() int {
+	max := s.shards[0].Datastore.MaxTypesPerAuthorizationModel()
+	for _, shard := range s.shards[1:] {
+		if n := shard.Datastore.MaxTypesPerAuthorizationModel(); n < max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+func (s *Sharded) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
+	return s.owner(store).WriteAuthorizationModel(ctx, store, model)
+}
+
+func (s *Sharded) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	return s.owner(store).WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations)
+}
+
+func (s *Sharded) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	return s.owner(store.GetId()).CreateStore(ctx, store)
+}
+
+func (s *Sharded) DeleteStore(ctx context.Context, id string) error {
+	return s.owner(id).DeleteStore(ctx, id)
+}
+
+func (s *Sharded) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	return s.owner(id).UndeleteStore(ctx, id, deletedAfter)
+}
+
+// PurgeDeletedStores purges the deleted stores of the shards in turn, until `limit` stores are purged.
+func (s *Sharded) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	return s.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.PurgeDeletedStores(ctx, deletedBefore, remaining)
+	})
+}
+
+func (s *Sharded) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+	return s.owner(id).GetStore(ctx, id)
+}
+
+func (s *Sharded) ListStores(ctx context.Context, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	ctx, span := tracer.Start(ctx, "sharded.ListStores")
+	defer span.End()
+
+	return s.listStores(ctx, storage.ListStoresFilter{}, opts)
+}
+
+func (s *Sharded) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	ctx, span := tracer.Start(ctx, "sharded.ListStoresWithFilter")
+	defer span.End()
+
+	return s.listStores(ctx, filter, opts)
+}
+
+// storeContToken is the continuation token of the stores, the ID and, if they are sorted by name, the name of the
+// next store.
+type storeContToken struct {
+	Ulid string `json:"ulid"`
+	Name string `json:"name,omitempty"`
+}
+
+// listStores reads every store selected by the filter from every shard, which are then merged and sorted. The
+// tokens of the shards can't be merged, so each page reads all the stores.
+func (s *Sharded) listStores(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	var token *storeContToken
+	if opts.From != "" {
+		token = &storeContToken{}
+		if err := json.Unmarshal([]byte(opts.From), token); err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+	}
+
+	sortByName := filter.SortOrder == storage.StoreSortByName
+
+	var stores []*openfgav1.Store
+	for _, shard := range s.shards {
+		shardStores, err := listAllStores(ctx, shard.Datastore, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, store := range shardStores {
+			if token != nil {
+				if sortByName && (store.GetName() < token.Name || (store.GetName() == token.Name && store.GetId() < token.Ulid)) {
+					continue
+				}
+				if !sortByName && store.GetId() < token.Ulid {
+					continue
+				}
+			}
+
+			stores = append(stores, store)
+		}
+	}
+
+	sort.Slice(stores, func(i, j int) bool {
+		if sortByName && stores[i].GetName() != stores[j].GetName() {
+			return stores[i].GetName() < stores[j].GetName()
+		}
+		return stores[i].GetId() < stores[j].GetId()
+	})
+
+	if len(stores) == 0 {
+		return nil, nil, nil
+	}
+
+	pageSize := storage.DefaultPageSize
+	if opts.PageSize > 0 {
+		pageSize = opts.PageSize
+	}
+
+	var contToken []byte
+	if len(stores) > pageSize {
+		next := storeContToken{Ulid: stores[pageSize].GetId()}
+		if sortByName {
+			next.Name = stores[pageSize].GetName()
+		}
+
+		var err error
+		contToken, err = json.Marshal(next)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stores = stores[:pageSize]
+	}
+
+	return stores, contToken, nil
+}
+
+// listAllStores reads every page of the stores of a datastore selected by the filter.
+func listAllStores(ctx context.Context, ds storage.OpenFGADatastore, filter storage.ListStoresFilter) ([]*openfgav1.Store, error) {
+	var stores []*openfgav1.Store
+	opts := storage.PaginationOptions{PageSize: storage.DefaultPageSize}
+	for {
+		page, contToken, err := ds.ListStoresWithFilter(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, page...)
+
+		if len(contToken) == 0 {
+			return stores, nil
+		}
+		opts.From = string(contToken)
+	}
+}
+
+func (s *Sharded) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	return s.owner(id).WriteStoreMetadata(ctx, id, metadata)
+}
+
+func (s *Sharded) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	return s.owner(id).ReadStoreMetadata(ctx, id)
+}
+
+func (s *Sharded) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	return s.owner(store).DeleteAuthorizationModel(ctx, store, id)
+}
+
+func (s *Sharded) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	return s.owner(store).PinAuthorizationModel(ctx, store, id)
+}
+
+func (s *Sharded) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	return s.owner(store).UnpinAuthorizationModel(ctx, store)
+}
+
+func (s *Sharded) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	return s.owner(store).ReadPinnedAuthorizationModelID(ctx, store)
+}
+
+func (s *Sharded) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
+	return s.owner(store).WriteAssertions(ctx, store, modelID, assertions)
+}
+
+func (s *Sharded) ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error) {
+	return s.owner(store).ReadAssertions(ctx, store, modelID)
+}
+
+func (s *Sharded) ReadChanges(ctx context.Context, store, objectType string, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	return s.owner(store).ReadChanges(ctx, store, objectType, opts, horizonOffset)
+}
+
+func (s *Sharded) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	return s.owner(store).ReadChangesWithFilter(ctx, store, filter, opts, horizonOffset)
+}
+
+// DeleteChanges deletes the changes of the store from its owner or, if the store is empty, the changes of the
+// shards in turn, until `limit` changes are deleted.
+func (s *Sharded) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	if store != "" {
+		return s.owner(store).DeleteChanges(ctx, store, before, limit)
+	}
+
+	return s.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.DeleteChanges(ctx, "", before, remaining)
+	})
+}
+
+func (s *Sharded) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	return s.owner(store).ReadStoreStats(ctx, store)
+}
+
+// fanOut runs an operation deleting at most `limit` items on the shards in turn, with the limit left by the
+// previous shards, and returns the number of items deleted.
+func (s *Sharded) fanOut(limit int, op func(ds storage.OpenFGADatastore, remaining int) (int, error)) (int, error) {
+	total := 0
+	for _, shard := range s.shards {
+		if total >= limit {
+			break
+		}
+
+		n, err := op(shard.Datastore, limit-total)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("shard '%s': %w", shard.Name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// IsReady reports whether every shard is ready.
+func (s *Sharded) IsReady(ctx context.Context) (bool, error) {
+	for _, shard := range s.shards {
+		ready, err := shard.Datastore.IsReady(ctx)
+		if err != nil || !ready {
+			return ready, err
+		}
+	}
+
+	return true, nil
+}
+
+// Close closes every shard.
+func (s *Sharded) Close() {
+	for _, shard := range s.shards {
+		shard.Datastore.Close()
+	}
+}
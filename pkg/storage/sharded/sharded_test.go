@@ -0,0 +1,191 @@
+package sharded
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/test"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func newShards(names ...string) []Shard {
+	shards := make([]Shard, 0, len(names))
+	for _, name := range names {
+		shards = append(shards, Shard{Name: name, Datastore: memory.New()})
+	}
+
+	return shards
+}
+
+func TestShardedDatastore(t *testing.T) {
+	ds, err := New(newShards("a", "b", "c"))
+	require.NoError(t, err)
+
+	test.RunAllTests(t, ds)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	require.Error(t, err)
+
+	_, err = New(newShards("a", "a"))
+	require.ErrorContains(t, err, "configured twice")
+
+	_, err = New(newShards("a", "b"), WithPins(map[string]string{"store": "c"}))
+	require.ErrorContains(t, err, "unknown shard 'c'")
+
+	shards := newShards("a")
+	shards[0].Draining = true
+	_, err = New(shards)
+	require.ErrorContains(t, err, "not draining")
+}
+
+func TestOwner(t *testing.T) {
+	ds, err := New(newShards("a", "b", "c"), WithPins(map[string]string{"pinned": "a"}))
+	require.NoError(t, err)
+
+	require.Equal(t, "a", ds.Owner("pinned"))
+
+	owners := map[string]int{}
+	for i := 0; i < 300; i++ {
+		owners[ds.Owner(ulid.Make().String())]++
+	}
+	require.Len(t, owners, 3)
+
+	// adding a shard only moves the stores it gains
+	grown, err := New(newShards("a", "b", "c", "d"))
+	require.NoError(t, err)
+
+	for i := 0; i < 300; i++ {
+		store := ulid.Make().String()
+		if owner := grown.Owner(store); owner != "d" {
+			require.Equal(t, ds.Owner(store), owner)
+		}
+	}
+}
+
+func TestListStores(t *testing.T) {
+	ctx := context.Background()
+
+	ds, err := New(newShards("a", "b", "c"))
+	require.NoError(t, err)
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		store, err := ds.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "store"})
+		require.NoError(t, err)
+		ids = append(ids, store.GetId())
+	}
+
+	var listed []string
+	opts := storage.PaginationOptions{PageSize: 3}
+	for {
+		stores, contToken, err := ds.ListStores(ctx, opts)
+		require.NoError(t, err)
+		for _, store := range stores {
+			listed = append(listed, store.GetId())
+		}
+
+		if len(contToken) == 0 {
+			break
+		}
+		opts.From = string(contToken)
+	}
+	require.Equal(t, ids, listed)
+
+	_, _, err = ds.ListStores(ctx, storage.PaginationOptions{From: "bad"})
+	require.ErrorIs(t, err, storage.ErrInvalidContinuationToken)
+}
+
+func TestMove(t *testing.T) {
+	ctx := context.Background()
+
+	shards := newShards("a", "b")
+	ds, err := New(shards)
+	require.NoError(t, err)
+
+	storeID := ulid.Make().String()
+	_, err = ds.CreateStore(ctx, &openfgav1.Store{Id: storeID, Name: "store"})
+	require.NoError(t, err)
+	err = ds.WriteStoreMetadata(ctx, storeID, &storage.StoreMetadata{Description: "moved"})
+	require.NoError(t, err)
+
+	model := &openfgav1.AuthorizationModel{
+		Id:            ulid.Make().String(),
+		SchemaVersion: "1.1",
+		TypeDefinitions: []*openfgav1.TypeDefinition{
+			{Type: "user"},
+			{
+				Type: "document",
+				Relations: map[string]*openfgav1.Userset{
+					"viewer": {Userset: &openfgav1.Userset_This{This: &openfgav1.DirectUserset{}}},
+				},
+			},
+		},
+	}
+	err = ds.WriteAuthorizationModel(ctx, storeID, model)
+	require.NoError(t, err)
+
+	tk1 := tuple.NewTupleKey("document:1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:2", "viewer", "user:jon")
+	tk3 := tuple.NewTupleKey("document:3", "viewer", "user:jon")
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, ds.Write(ctx, storeID, nil, storage.Writes{tk1}))
+	require.NoError(t, ds.WriteWithExpiry(ctx, storeID, nil, storage.Writes{tk2}, expiresAt))
+	require.NoError(t, ds.WriteWithCondition(ctx, storeID, nil, storage.Writes{tk3}, &storage.TupleCondition{Expression: "true"}))
+
+	from := ds.Owner(storeID)
+
+	// draining the owner of the store moves it to the other shard
+	for i := range shards {
+		shards[i].Draining = shards[i].Name == from
+	}
+	drained, err := New(shards)
+	require.NoError(t, err)
+
+	moves, err := drained.Moves(ctx)
+	require.NoError(t, err)
+	require.Len(t, moves, 1)
+	require.Equal(t, storeID, moves[0].Store)
+	require.Equal(t, from, moves[0].From)
+
+	err = drained.Move(ctx, moves[0])
+	require.NoError(t, err)
+
+	moves, err = drained.Moves(ctx)
+	require.NoError(t, err)
+	require.Empty(t, moves)
+
+	source, _ := drained.Shard(from)
+	_, err = source.GetStore(ctx, storeID)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = drained.GetStore(ctx, storeID)
+	require.NoError(t, err)
+
+	metadata, err := drained.ReadStoreMetadata(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, "moved", metadata.Description)
+
+	latest, err := drained.FindLatestAuthorizationModelID(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, model.GetId(), latest)
+
+	tuples, _, err := drained.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, tuples, 3)
+
+	expirations, err := drained.ReadTupleExpirations(ctx, storeID, &openfgav1.TupleKey{})
+	require.NoError(t, err)
+	require.True(t, expiresAt.Equal(expirations[tuple.TupleKeyToString(tk2)]))
+
+	conditions, err := drained.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{})
+	require.NoError(t, err)
+	require.Equal(t, "true", conditions[tuple.TupleKeyToString(tk3)].Expression)
+}
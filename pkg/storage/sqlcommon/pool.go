@@ -0,0 +1,205 @@
+package sqlcommon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrConnAcquireTimeout is returned by the queries which could not acquire a connection of the pool within the
+// acquire timeout, see WithConnAcquireTimeout.
+var ErrConnAcquireTimeout = errors.New("timed out acquiring a connection to the datastore")
+
+var (
+	connAcquireDelayMsHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                            "datastore_connection_acquire_delay_ms",
+		Help:                            "Time spent waiting for a connection of the datastore connection pool",
+		Buckets:                         []float64{1, 3, 5, 10, 25, 50, 100, 1000, 5000}, // milliseconds
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	}, []string{"db_name"})
+
+	connAcquireTimeoutsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "datastore_connection_acquire_timeouts_total",
+		Help: "The total number of queries which timed out waiting for a connection of the datastore connection pool",
+	}, []string{"db_name"})
+
+	queryDurationMsHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                            "datastore_query_duration_ms",
+		Help:                            "Time spent by the datastore answering a query, once a connection was acquired",
+		Buckets:                         []float64{1, 3, 5, 10, 25, 50, 100, 1000, 5000}, // milliseconds
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	}, []string{"db_name", "query_name"})
+)
+
+// Pool is the connection pool the SQL datastores run their queries with. It bounds the time spent waiting for a
+// connection, see WithConnAcquireTimeout, and reports as Prometheus metrics labelled with its name the usage of the
+// pool (the go_sql_* metrics), the time spent waiting for a connection and the latency of the queries by query name.
+type Pool struct {
+	db             *sql.DB
+	name           string
+	acquireTimeout time.Duration
+	statsCollector prometheus.Collector
+}
+
+var _ sq.RunnerContext = (*Pool)(nil)
+
+// NewPool returns a Pool running the queries with the connections of db. The name labels its metrics, e.g.
+// 'postgres'.
+func NewPool(db *sql.DB, name string, cfg *Config) *Pool {
+	p := &Pool{
+		db:             db,
+		name:           name,
+		acquireTimeout: cfg.ConnAcquireTimeout,
+		statsCollector: collectors.NewDBStatsCollector(db, name),
+	}
+
+	if err := prometheus.Register(p.statsCollector); err != nil {
+		// another pool with the same name (e.g. of another datastore of the process) already reports its usage
+		p.statsCollector = nil
+	}
+
+	return p
+}
+
+// DB returns the underlying connection pool.
+func (p *Pool) DB() *sql.DB {
+	return p.db
+}
+
+// Close closes the connection pool and stops reporting its usage.
+func (p *Pool) Close() error {
+	if p.statsCollector != nil {
+		prometheus.Unregister(p.statsCollector)
+	}
+
+	return p.db.Close()
+}
+
+// conn acquires a connection of the pool, waiting at most the acquire timeout.
+func (p *Pool) conn(ctx context.Context) (*sql.Conn, error) {
+	acquireCtx := ctx
+	if p.acquireTimeout > 0 {
+		var cancel context.CancelFunc
+		acquireCtx, cancel = context.WithTimeout(ctx, p.acquireTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	conn, err := p.db.Conn(acquireCtx)
+	connAcquireDelayMsHistogram.WithLabelValues(p.name).Observe(float64(time.Since(start).Milliseconds()))
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+			connAcquireTimeoutsCounter.WithLabelValues(p.name).Inc()
+			return nil, ErrConnAcquireTimeout
+		}
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// release returns the connection to the pool once the rows, the row or the transaction obtained from it are closed.
+func release(conn *sql.Conn) {
+	// Close blocks until the connection is no longer in use
+	go func() {
+		_ = conn.Close()
+	}()
+}
+
+func (p *Pool) observeQuery(query string, start time.Time) {
+	queryDurationMsHistogram.WithLabelValues(p.name, queryName(query)).Observe(float64(time.Since(start).Milliseconds()))
+}
+
+func (p *Pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release(conn)
+
+	defer p.observeQuery(query, time.Now())
+	return conn.QueryContext(ctx, query, args...)
+}
+
+func (p *Pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) sq.RowScanner {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return &errRow{err: err}
+	}
+	defer release(conn)
+
+	defer p.observeQuery(query, time.Now())
+	return conn.QueryRowContext(ctx, query, args...)
+}
+
+// errRow is the row of a query which failed before being run.
+type errRow struct {
+	err error
+}
+
+func (r *errRow) Scan(...interface{}) error {
+	return r.err
+}
+
+func (p *Pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	defer p.observeQuery(query, time.Now())
+	return conn.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on a connection of the pool, waiting at most the acquire timeout for it.
+func (p *Pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release(conn)
+
+	return conn.BeginTx(ctx, opts)
+}
+
+func (p *Pool) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return p.QueryContext(context.Background(), query, args...)
+}
+
+func (p *Pool) QueryRow(query string, args ...interface{}) sq.RowScanner {
+	return p.QueryRowContext(context.Background(), query, args...)
+}
+
+func (p *Pool) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+// queryName returns the name of the query labelling its latency, made of its statement and its table, e.g.
+// 'select_tuple'.
+func queryName(query string) string {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	statement := fields[0]
+	for i, field := range fields[:len(fields)-1] {
+		if field == "from" || field == "into" || (statement == "update" && i == 0) {
+			return statement + "_" + strings.Trim(fields[i+1], "`\"")
+		}
+	}
+
+	return statement
+}
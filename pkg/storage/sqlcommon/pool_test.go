@@ -0,0 +1,142 @@
+package sqlcommon
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver is a database driver whose queries return the rows of fakePages, or no rows once they are all returned.
+type fakeDriver struct{}
+
+var (
+	fakeMu sync.Mutex
+	// fakePages holds the rows returned by the next queries of the fake driver
+	fakePages [][][]driver.Value
+	// fakeQueries records the queries of the fake driver, followed by their arguments
+	fakeQueries [][]interface{}
+)
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+
+	query := []interface{}{s.query}
+	for _, arg := range args {
+		query = append(query, arg)
+	}
+	fakeQueries = append(fakeQueries, query)
+
+	rows := &fakeRows{}
+	if len(fakePages) > 0 {
+		rows.rows, fakePages = fakePages[0], fakePages[1:]
+	}
+
+	return rows, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"n"}
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func init() {
+	sql.Register("sqlcommon_fake", fakeDriver{})
+}
+
+func TestPoolConnAcquireTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlcommon_fake", "")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	pool := NewPool(db, "fake", NewConfig(WithConnAcquireTimeout(10*time.Millisecond)))
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	rows, err := pool.QueryContext(ctx, "SELECT n FROM tuple")
+	require.NoError(t, err)
+
+	// the only connection is in use until the rows are closed
+	_, err = pool.ExecContext(ctx, "DELETE FROM tuple")
+	require.ErrorIs(t, err, ErrConnAcquireTimeout)
+
+	var n int
+	err = pool.QueryRowContext(ctx, "SELECT n FROM tuple").Scan(&n)
+	require.ErrorIs(t, err, ErrConnAcquireTimeout)
+
+	_, err = pool.BeginTx(ctx, nil)
+	require.ErrorIs(t, err, ErrConnAcquireTimeout)
+
+	require.NoError(t, rows.Close())
+
+	require.Eventually(t, func() bool {
+		_, err := pool.ExecContext(ctx, "DELETE FROM tuple")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	tx, err := pool.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+}
+
+func TestQueryName(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT store FROM tuple WHERE store = $1":          "select_tuple",
+		"SELECT store FROM tuple AS OF SYSTEM TIME '-5s'":   "select_tuple",
+		"INSERT INTO changelog (store) VALUES (?)":          "insert_changelog",
+		"UPDATE store SET updated_at = NOW() WHERE id = $1": "update_store",
+		"DELETE FROM `authorization_model` WHERE id = ?":    "delete_authorization_model",
+		"SELECT 1": "select",
+	} {
+		require.Equal(t, expected, queryName(query), query)
+	}
+}
@@ -0,0 +1,114 @@
+package sqlcommon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// replicaLagInterval is how often at most the replication lag of a read replica is measured.
+const replicaLagInterval = time.Second
+
+// ErrReplicaLagUnknown is returned by the measurements of the replication lag of a read replica which is not
+// replicating, e.g. because its replication is stopped.
+var ErrReplicaLagUnknown = errors.New("the replication lag of the read replica is unknown")
+
+// MeasureReplicaLag measures the replication lag of the read replica the queries of the pool are run against.
+type MeasureReplicaLag func(ctx context.Context, replica *Pool) (time.Duration, error)
+
+// ReplicaLag routes the tuple reads to a read replica only while its replication lag, as measured by its query at
+// most once per second, is known to be within the maximum lag and the staleness the reads allow.
+type ReplicaLag struct {
+	replica *Pool
+	measure MeasureReplicaLag
+	maxLag  time.Duration
+
+	mu         sync.Mutex
+	measuring  bool
+	measured   bool
+	lag        time.Duration
+	measuredAt time.Time
+}
+
+// NewReplicaLag returns the ReplicaLag of the read replica, whose maximum lag is maxLag, see WithReplicaMaxLag.
+func NewReplicaLag(replica *Pool, measure MeasureReplicaLag, maxLag time.Duration) *ReplicaLag {
+	return &ReplicaLag{
+		replica: replica,
+		measure: measure,
+		maxLag:  maxLag,
+	}
+}
+
+// StaleReadsAllowed reports whether the tuple read of the context may be routed to the read replica. The lag of the
+// replica is bounded by the lag it last measured plus the time elapsed since, since the replica can't fall further
+// behind than that. The reads are routed to the primary while the lag is unknown, e.g. because its last measurement
+// failed, or exceeds the maximum lag or the staleness the reads allow, see storage.StaleReadsAllowed.
+func (r *ReplicaLag) StaleReadsAllowed(ctx context.Context) bool {
+	if !storage.StaleReadsAllowed(ctx, 0) {
+		return false
+	}
+
+	lag, ok := r.Lag(ctx)
+	return ok && lag <= r.maxLag && storage.StaleReadsAllowed(ctx, lag)
+}
+
+// Lag returns the upper bound of the replication lag of the read replica, measuring it again if its last measurement
+// is older than a second or than half the maximum lag. Its concurrent callers don't wait for the measurement and use
+// the previous one. It returns false if the lag is unknown.
+func (r *ReplicaLag) Lag(ctx context.Context) (time.Duration, bool) {
+	r.mu.Lock()
+	interval := replicaLagInterval
+	if r.maxLag/2 < interval {
+		interval = r.maxLag / 2
+	}
+
+	if r.measuring || (r.measured && time.Since(r.measuredAt) < interval) {
+		defer r.mu.Unlock()
+		return r.bound()
+	}
+	r.measuring = true
+	r.mu.Unlock()
+
+	start := time.Now()
+	lag, err := r.measure(ctx, r.replica)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.measuring = false
+	if err == nil {
+		r.measured, r.lag, r.measuredAt = true, lag, start
+	} else if ctx.Err() == nil {
+		r.measured = false
+	}
+
+	return r.bound()
+}
+
+// bound returns the upper bound of the replication lag from its last measurement. It must be called with mu held.
+func (r *ReplicaLag) bound() (time.Duration, bool) {
+	if !r.measured {
+		return 0, false
+	}
+
+	return r.lag + time.Since(r.measuredAt), true
+}
+
+// ScanReplicaLag returns the replication lag from the seconds returned by its measurement query, which are NULL if
+// the lag is unknown.
+func ScanReplicaLag(row interface{ Scan(...interface{}) error }) (time.Duration, error) {
+	var seconds sql.NullFloat64
+	if err := row.Scan(&seconds); err != nil {
+		return 0, err
+	}
+
+	if !seconds.Valid {
+		return 0, ErrReplicaLagUnknown
+	}
+
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
@@ -0,0 +1,107 @@
+package sqlcommon
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaLagStaleReadsAllowed(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		lag          time.Duration
+		err          error
+		measurements int
+	)
+	measure := func(context.Context, *Pool) (time.Duration, error) {
+		measurements++
+		return lag, err
+	}
+
+	t.Run("reads_are_routed_to_the_replica_within_the_max_lag", func(t *testing.T) {
+		lag, err, measurements = time.Millisecond, nil, 0
+		replicaLag := NewReplicaLag(nil, measure, 5*time.Second)
+
+		require.True(t, replicaLag.StaleReadsAllowed(ctx))
+		require.True(t, replicaLag.StaleReadsAllowed(ctx))
+		require.Equal(t, 1, measurements)
+	})
+
+	t.Run("reads_are_routed_to_the_primary_beyond_the_max_lag", func(t *testing.T) {
+		lag, err, measurements = 10*time.Second, nil, 0
+		replicaLag := NewReplicaLag(nil, measure, 5*time.Second)
+
+		require.False(t, replicaLag.StaleReadsAllowed(ctx))
+	})
+
+	t.Run("reads_are_routed_to_the_primary_if_the_lag_is_unknown", func(t *testing.T) {
+		lag, err, measurements = 0, ErrReplicaLagUnknown, 0
+		replicaLag := NewReplicaLag(nil, measure, 5*time.Second)
+
+		require.False(t, replicaLag.StaleReadsAllowed(ctx))
+	})
+
+	t.Run("reads_are_routed_to_the_primary_once_a_measurement_fails", func(t *testing.T) {
+		lag, err, measurements = 0, nil, 0
+		replicaLag := NewReplicaLag(nil, measure, 20*time.Millisecond)
+		require.True(t, replicaLag.StaleReadsAllowed(ctx))
+
+		err = errors.New("connection refused")
+		time.Sleep(20 * time.Millisecond)
+		require.False(t, replicaLag.StaleReadsAllowed(ctx))
+		require.Equal(t, 2, measurements)
+	})
+
+	t.Run("reads_more_recent_than_the_lag_are_routed_to_the_primary", func(t *testing.T) {
+		lag, err, measurements = time.Second, nil, 0
+		replicaLag := NewReplicaLag(nil, measure, 5*time.Second)
+
+		recentCtx, err := storage.ContextWithConsistencyToken(ctx, ulid.Make().String())
+		require.NoError(t, err)
+		require.False(t, replicaLag.StaleReadsAllowed(recentCtx))
+
+		token := ulid.MustNew(ulid.Timestamp(time.Now().Add(-2*time.Second)), nil).String()
+		olderCtx, err := storage.ContextWithConsistencyToken(ctx, token)
+		require.NoError(t, err)
+		require.True(t, replicaLag.StaleReadsAllowed(olderCtx))
+	})
+
+	t.Run("strongly_consistent_reads_are_routed_to_the_primary", func(t *testing.T) {
+		lag, err, measurements = 0, nil, 0
+		replicaLag := NewReplicaLag(nil, measure, 5*time.Second)
+
+		require.False(t, replicaLag.StaleReadsAllowed(storage.ContextWithStrongConsistency(ctx)))
+		require.Equal(t, 0, measurements)
+	})
+}
+
+func TestScanReplicaLag(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlcommon_fake", "")
+	require.NoError(t, err)
+
+	pool := NewPool(db, "fake_replica", NewConfig())
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	fakeMu.Lock()
+	fakePages = [][][]driver.Value{{{1.5}}, {{nil}}}
+	fakeMu.Unlock()
+
+	lag, err := ScanReplicaLag(pool.QueryRowContext(ctx, "SELECT 1.5"))
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, lag)
+
+	_, err = ScanReplicaLag(pool.QueryRowContext(ctx, "SELECT NULL"))
+	require.ErrorIs(t, err, ErrReplicaLagUnknown)
+}
@@ -7,16 +7,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/go-sql-driver/mysql"
-	"github.com/oklog/ulid/v2"
+	"github.com/jackc/pgx/v5/pgconn"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/logger"
 	"github.com/openfga/openfga/pkg/storage"
 	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -31,8 +36,24 @@ type Config struct {
 	MaxIdleConns    int
 	ConnMaxIdleTime time.Duration
 	ConnMaxLifetime time.Duration
+
+	// ConnAcquireTimeout is the maximum amount of time a query waits for a connection, see WithConnAcquireTimeout.
+	ConnAcquireTimeout time.Duration
+
+	// ReadURI is the URI of the read replica the tuple reads are routed to, if any, see WithReadURI.
+	ReadURI       string
+	ReplicaMaxLag time.Duration
+
+	// ReadPageSize is the number of tuples read per query by the paginated tuple reads, see WithReadPageSize.
+	ReadPageSize int
+
+	// SerializeWrites serializes every write of a store, see WithSerializeWrites.
+	SerializeWrites bool
 }
 
+// DefaultReadPageSize is the default number of tuples read per query by the paginated tuple reads.
+const DefaultReadPageSize = 1000
+
 type DatastoreOption func(*Config)
 
 func WithUsername(username string) DatastoreOption {
@@ -89,6 +110,54 @@ func WithConnMaxLifetime(d time.Duration) DatastoreOption {
 	}
 }
 
+// WithConnAcquireTimeout sets the maximum amount of time a query waits for a connection of the pool when all of them
+// are in use. The queries which time out fail with ErrConnAcquireTimeout. If 0, the queries wait until their context
+// is done.
+func WithConnAcquireTimeout(d time.Duration) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.ConnAcquireTimeout = d
+	}
+}
+
+// WithReadURI routes the tuple reads (e.g. the reads issued by Read, Check and ListObjects) to the read replica of
+// the URI, which is connected to with the same credentials and pool settings as the primary. The writes and every
+// other read (e.g. of the changelog and of the authorization models) are routed to the primary, as are the tuple
+// reads which may not be stale, see storage.StaleReadsAllowed and WithReplicaMaxLag.
+func WithReadURI(uri string) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.ReadURI = uri
+	}
+}
+
+// WithReplicaMaxLag sets the maximum replication lag of the read replica, see WithReadURI. The lag of the replica is
+// measured at most once per second, and the tuple reads are routed to the primary while it exceeds the maximum lag
+// or is unknown, or while their consistency token may not have been replicated yet, see ReplicaLag.
+func WithReplicaMaxLag(d time.Duration) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.ReplicaMaxLag = d
+	}
+}
+
+// WithReadPageSize sets the number of tuples read per query by the reads of ReadUsersetTuples and
+// ReadStartingWithUser, see PaginatedTupleIterator. It bounds the number of tuples held in memory by each of their
+// iterators.
+func WithReadPageSize(pageSize int) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.ReadPageSize = pageSize
+	}
+}
+
+// WithSerializeWrites serializes every write transaction of a store with a lock, see WithStoreLock. By default only
+// the conditional writes (see storage.WithExpectedChangelogToken) take the lock, so that they are serialized with
+// each other but may miss an unconditional write committed concurrently. Serializing every write makes the
+// conditional writes observe every change committed before them, at the cost of the write throughput of a store:
+// every write waits for the lock and reads the latest change of the store.
+func WithSerializeWrites(serialize bool) DatastoreOption {
+	return func(cfg *Config) {
+		cfg.SerializeWrites = serialize
+	}
+}
+
 func NewConfig(opts ...DatastoreOption) *Config {
 	cfg := &Config{}
 
@@ -108,6 +177,10 @@ func NewConfig(opts ...DatastoreOption) *Config {
 		cfg.MaxTypesPerModelField = storage.DefaultMaxTypesPerAuthorizationModel
 	}
 
+	if cfg.ReadPageSize == 0 {
+		cfg.ReadPageSize = DefaultReadPageSize
+	}
+
 	return cfg
 }
 
@@ -119,19 +192,49 @@ type TupleRecord struct {
 	User       string
 	Ulid       string
 	InsertedAt time.Time
+	ExpiresAt  sql.NullTime
 }
 
+// TupleColumns are the columns of the tuple table selected by the queries whose rows are read by a SQLTupleIterator.
+var TupleColumns = []string{"store", "object_type", "object_id", "relation", "_user", "ulid", "inserted_at", "expires_at"}
+
 func (t *TupleRecord) AsTuple() *openfgav1.Tuple {
 	return &openfgav1.Tuple{
-		Key: &openfgav1.TupleKey{
-			Object:   tupleUtils.BuildObject(t.ObjectType, t.ObjectID),
-			Relation: t.Relation,
-			User:     t.User,
-		},
+		Key:       t.AsTupleKey(),
 		Timestamp: timestamppb.New(t.InsertedAt),
 	}
 }
 
+func (t *TupleRecord) AsTupleKey() *openfgav1.TupleKey {
+	return &openfgav1.TupleKey{
+		Object:   tupleUtils.BuildObject(t.ObjectType, t.ObjectID),
+		Relation: t.Relation,
+		User:     t.User,
+	}
+}
+
+// column returns the value of a column of the tuple table read into the record.
+func (t *TupleRecord) column(name string) interface{} {
+	switch name {
+	case "store":
+		return t.Store
+	case "object_type":
+		return t.ObjectType
+	case "object_id":
+		return t.ObjectID
+	case "relation":
+		return t.Relation
+	case "_user":
+		return t.User
+	case "ulid":
+		return t.Ulid
+	case "inserted_at":
+		return t.InsertedAt
+	default:
+		panic(fmt.Sprintf("unknown tuple column '%s'", name))
+	}
+}
+
 type ContToken struct {
 	Ulid       string `json:"ulid"`
 	ObjectType string `json:"ObjectType"`
@@ -153,22 +256,25 @@ func UnmarshallContToken(from string) (*ContToken, error) {
 }
 
 type SQLTupleIterator struct {
-	rows     *sql.Rows
-	resultCh chan *TupleRecord
-	errCh    chan error
+	rows        *sql.Rows
+	expirations *storage.TupleExpirations
+
+	// record is the record the rows are scanned into, reused for every row
+	record TupleRecord
 }
 
 var _ storage.TupleIterator = (*SQLTupleIterator)(nil)
 
-// NewSQLTupleIterator returns a SQL tuple iterator
-func NewSQLTupleIterator(rows *sql.Rows) *SQLTupleIterator {
+// NewSQLTupleIterator returns a SQL tuple iterator over rows of the TupleColumns. The expirations of the tuples
+// returned are recorded in the storage.TupleExpirations of the context, if any.
+func NewSQLTupleIterator(ctx context.Context, rows *sql.Rows) *SQLTupleIterator {
 	return &SQLTupleIterator{
-		rows:     rows,
-		resultCh: make(chan *TupleRecord, 1),
-		errCh:    make(chan error, 1),
+		rows:        rows,
+		expirations: storage.TupleExpirationsFromContext(ctx),
 	}
 }
 
+// next scans the next row. The record returned is overwritten by the following call.
 func (t *SQLTupleIterator) next() (*TupleRecord, error) {
 	if !t.rows.Next() {
 		if err := t.rows.Err(); err != nil {
@@ -177,13 +283,15 @@ func (t *SQLTupleIterator) next() (*TupleRecord, error) {
 		return nil, storage.ErrIteratorDone
 	}
 
-	var record TupleRecord
-	err := t.rows.Scan(&record.Store, &record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &record.Ulid, &record.InsertedAt)
+	record := &t.record
+	err := t.rows.Scan(&record.Store, &record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &record.Ulid, &record.InsertedAt, &record.ExpiresAt)
 	if err != nil {
 		return nil, err
 	}
 
-	return &record, nil
+	t.expirations.Observe(record.ExpiresAt.Time)
+
+	return record, nil
 }
 
 // ToArray converts the tupleIterator to an []*openfgav1.Tuple and a possibly empty continuation token. If the
@@ -228,10 +336,169 @@ func (t *SQLTupleIterator) Next() (*openfgav1.Tuple, error) {
 	return record.AsTuple(), nil
 }
 
+// NextKey returns the key of the next tuple, without building the tuple. See
+// storage.NewTupleKeyIteratorFromTupleIterator.
+func (t *SQLTupleIterator) NextKey() (*openfgav1.TupleKey, error) {
+	record, err := t.next()
+	if err != nil {
+		return nil, err
+	}
+
+	return record.AsTupleKey(), nil
+}
+
 func (t *SQLTupleIterator) Stop() {
 	t.rows.Close()
 }
 
+// pageBufferPool pools the buffers the PaginatedTupleIterators read their pages into.
+var pageBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([]TupleRecord)
+	},
+}
+
+// PaginatedTupleIterator is a tuple iterator which reads the tuples selected by a query by pages, using keyset
+// pagination over a unique key of the tuples. A page is read once the previous one was consumed, so at most one page
+// of tuples is held in memory and no connection is held between the pages: the reads are paced by the consumer of the
+// iterator. The pages are read by distinct queries, so the tuples written while iterating may or may not be returned.
+//
+// The pages are read into a buffer taken from a pool, which is returned to it once the iterator is done or stopped.
+type PaginatedTupleIterator struct {
+	ctx      context.Context
+	sb       sq.SelectBuilder
+	keys     []string
+	pageSize int
+
+	buf  *[]TupleRecord
+	page []TupleRecord
+	last *TupleRecord
+	done bool
+}
+
+var _ storage.TupleIterator = (*PaginatedTupleIterator)(nil)
+
+// NewPaginatedTupleIterator returns an iterator over the tuples selected by sb, which must select the columns read by
+// a SQLTupleIterator. The tuples are ordered by the keys, which must be columns of the tuple table forming a unique key
+// of the selected tuples (e.g. '_user' and 'object_id' if the store, the object type and the relation are set), and
+// are read by pages of pageSize tuples. The expirations of the tuples of a page are recorded in the
+// storage.TupleExpirations of the context, if any, once the page is read.
+func NewPaginatedTupleIterator(ctx context.Context, sb sq.SelectBuilder, keys []string, pageSize int) *PaginatedTupleIterator {
+	return &PaginatedTupleIterator{
+		ctx:      ctx,
+		sb:       sb.OrderBy(keys...).Limit(uint64(pageSize)),
+		keys:     keys,
+		pageSize: pageSize,
+	}
+}
+
+// nextRecord returns the next record, which is valid until the next page is read.
+func (t *PaginatedTupleIterator) nextRecord() (*TupleRecord, error) {
+	if len(t.page) == 0 {
+		if t.done {
+			t.release()
+			return nil, storage.ErrIteratorDone
+		}
+
+		if err := t.readPage(); err != nil {
+			return nil, err
+		}
+
+		if len(t.page) == 0 {
+			t.release()
+			return nil, storage.ErrIteratorDone
+		}
+	}
+
+	record := &t.page[0]
+	t.page = t.page[1:]
+
+	return record, nil
+}
+
+func (t *PaginatedTupleIterator) Next() (*openfgav1.Tuple, error) {
+	record, err := t.nextRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	return record.AsTuple(), nil
+}
+
+// NextKey returns the key of the next tuple, without building the tuple. See
+// storage.NewTupleKeyIteratorFromTupleIterator.
+func (t *PaginatedTupleIterator) NextKey() (*openfgav1.TupleKey, error) {
+	record, err := t.nextRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	return record.AsTupleKey(), nil
+}
+
+// readPage reads the page of tuples following the last tuple read into the buffer.
+func (t *PaginatedTupleIterator) readPage() error {
+	sb := t.sb
+	if t.last != nil {
+		values := make([]interface{}, 0, len(t.keys))
+		for _, key := range t.keys {
+			values = append(values, t.last.column(key))
+		}
+		sb = sb.Where(sq.Expr("("+strings.Join(t.keys, ", ")+") > ("+strings.Repeat("?, ", len(t.keys)-1)+"?)", values...))
+	}
+
+	rows, err := sb.QueryContext(t.ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	iter := NewSQLTupleIterator(t.ctx, rows)
+	defer iter.Stop()
+
+	if t.buf == nil {
+		t.buf = pageBufferPool.Get().(*[]TupleRecord)
+		if cap(*t.buf) < t.pageSize {
+			*t.buf = make([]TupleRecord, 0, t.pageSize)
+		}
+	}
+
+	page := (*t.buf)[:0]
+	for {
+		record, err := iter.next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+			return HandleSQLError(err)
+		}
+		page = append(page, *record)
+	}
+	t.page = page
+
+	// a partial page is the last one
+	t.done = len(page) < t.pageSize
+	if len(page) > 0 {
+		last := page[len(page)-1]
+		t.last = &last
+	}
+
+	return nil
+}
+
+// release returns the buffer to the pool.
+func (t *PaginatedTupleIterator) release() {
+	if t.buf != nil {
+		pageBufferPool.Put(t.buf)
+		t.buf = nil
+	}
+}
+
+func (t *PaginatedTupleIterator) Stop() {
+	t.page = nil
+	t.done = true
+	t.release()
+}
+
 func HandleSQLError(err error, args ...interface{}) error {
 	if errors.Is(err, sql.ErrNoRows) {
 		return storage.ErrNotFound
@@ -258,24 +525,152 @@ func HandleSQLError(err error, args ...interface{}) error {
 
 // DBInfo encapsulates DB information for use in common method
 type DBInfo struct {
-	db      *sql.DB
+	db      *Pool
 	stbl    sq.StatementBuilderType
 	sqlTime interface{}
+
+	// onConflictDoNothing is the suffix of an INSERT that makes it a no-op if the row already exists
+	onConflictDoNothing string
+
+	// storeLock is the statement that serializes the write transactions of a store, see WithStoreLock
+	storeLock string
+
+	// serializeWrites makes every write transaction take the store lock, see WithSerializedWrites
+	serializeWrites bool
+}
+
+type DBInfoOption func(*DBInfo)
+
+// WithOnConflictDoNothing sets the suffix of an INSERT statement that makes it a no-op if the row already
+// exists (e.g. 'ON CONFLICT DO NOTHING'). It is required to ignore the duplicate writes, see
+// storage.OnDuplicateInsertIgnore.
+func WithOnConflictDoNothing(suffix string) DBInfoOption {
+	return func(i *DBInfo) {
+		i.onConflictDoNothing = suffix
+	}
+}
+
+// WithStoreLock sets the statement run at the beginning of the conditional write transactions (see
+// storage.WithExpectedChangelogToken), with the id of the store as its only argument, which holds a lock on the
+// store until the transaction ends (e.g. 'SELECT pg_advisory_xact_lock(hashtext($1))'). It serializes the
+// conditional writes of a store, so that two of them cannot both observe the same latest change, and so that their
+// changes are ordered like the writes in the changelog. Without it, the conditional writes are serializable instead,
+// which is only enough on the datastores whose serializable transactions conflict with all the others, e.g.
+// CockroachDB. See WithSerializedWrites to take the lock in every write transaction.
+func WithStoreLock(statement string) DBInfoOption {
+	return func(i *DBInfo) {
+		i.storeLock = statement
+	}
+}
+
+// WithSerializedWrites makes every write transaction of a store take its lock, see WithStoreLock and
+// WithSerializeWrites.
+func WithSerializedWrites(serialize bool) DBInfoOption {
+	return func(i *DBInfo) {
+		i.serializeWrites = serialize
+	}
 }
 
 // NewDBInfo constructs a DBInfo objet
-func NewDBInfo(db *sql.DB, stbl sq.StatementBuilderType, sqlTime interface{}) *DBInfo {
-	return &DBInfo{
+func NewDBInfo(db *Pool, stbl sq.StatementBuilderType, sqlTime interface{}, opts ...DBInfoOption) *DBInfo {
+	i := &DBInfo{
 		db:      db,
 		stbl:    stbl,
 		sqlTime: sqlTime,
 	}
+
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	return i
+}
+
+// NotExpired returns the condition matching the tuples that are not expired at `now`.
+func NotExpired(now time.Time) sq.Sqlizer {
+	return sq.Or{sq.Eq{"expires_at": nil}, sq.Gt{"expires_at": now}}
+}
+
+// MatchesFilter returns the condition matching the tuples that match the filter, see storage.ReadFilter.
+func MatchesFilter(filter storage.ReadFilter) sq.Sqlizer {
+	conditions := sq.And{}
+
+	objectType, objectID := tupleUtils.SplitObject(filter.Object)
+	if objectType != "" {
+		conditions = append(conditions, sq.Eq{"object_type": objectType})
+	}
+	if objectID != "" {
+		conditions = append(conditions, sq.Eq{"object_id": objectID})
+	}
+	switch len(filter.Relations) {
+	case 0:
+	case 1:
+		conditions = append(conditions, sq.Eq{"relation": filter.Relations[0]})
+	default:
+		conditions = append(conditions, sq.Eq{"relation": filter.Relations})
+	}
+	if filter.User != "" {
+		conditions = append(conditions, sq.Eq{"_user": filter.User})
+	}
+	if len(filter.UserTypes) > 0 {
+		userTypeConditions := sq.Or{}
+		for _, userType := range filter.UserTypes {
+			switch userType.GetRelationOrWildcard().(type) {
+			case *openfgav1.RelationReference_Wildcard:
+				userTypeConditions = append(userTypeConditions, sq.Eq{"_user": userType.GetType() + ":*"})
+			case *openfgav1.RelationReference_Relation:
+				userTypeConditions = append(userTypeConditions, sq.Like{"_user": userType.GetType() + ":%#" + userType.GetRelation()})
+			default:
+				// the wildcards are stored with the usersets
+				userTypeConditions = append(userTypeConditions, sq.And{
+					sq.Eq{"user_type": tupleUtils.User},
+					sq.Like{"_user": userType.GetType() + ":%"},
+				})
+			}
+		}
+		conditions = append(conditions, userTypeConditions)
+	}
+
+	return conditions
 }
 
 // Write provides the common method for writing to database across sql storage
-func Write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.Deletes, writes storage.Writes, now time.Time) error {
+func Write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.Deletes, writes storage.Writes, now time.Time, opts ...storage.TupleWriteOption) error {
+	return write(ctx, dbInfo, store, deletes, writes, nil, nil, now, storage.NewTupleWriteOptions(opts...))
+}
 
-	txn, err := dbInfo.db.BeginTx(ctx, nil)
+// WriteWithExpiry provides the common method for writing tuples that expire at `expiresAt` to database across sql storage
+func WriteWithExpiry(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, now time.Time, opts ...storage.TupleWriteOption) error {
+	return write(ctx, dbInfo, store, deletes, writes, &expiresAt, nil, now, storage.NewTupleWriteOptions(opts...))
+}
+
+// WriteWithCondition provides the common method for writing tuples with a condition to database across sql storage
+func WriteWithCondition(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, now time.Time, opts ...storage.TupleWriteOption) error {
+	return write(ctx, dbInfo, store, deletes, writes, nil, condition, now, storage.NewTupleWriteOptions(opts...))
+}
+
+func write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.Deletes, writes storage.Writes, expiresAt *time.Time, condition *storage.TupleCondition, now time.Time, opts storage.TupleWriteOptions) error {
+	ignoreDuplicates := opts.OnDuplicateInsert == storage.OnDuplicateInsertIgnore
+	if ignoreDuplicates && dbInfo.onConflictDoNothing == "" {
+		return fmt.Errorf("ignoring duplicate writes is not supported by this datastore")
+	}
+
+	var conditionExpression, conditionParameters *string
+	if condition != nil {
+		conditionExpression = &condition.Expression
+
+		if condition.Parameters != nil {
+			marshalled, err := protojson.Marshal(condition.Parameters)
+			if err != nil {
+				return err
+			}
+
+			parameters := string(marshalled)
+			conditionParameters = &parameters
+		}
+	}
+
+	txn, err := dbInfo.db.BeginTx(ctx, writeTxOptions(dbInfo, opts))
 	if err != nil {
 		return HandleSQLError(err)
 	}
@@ -283,6 +678,165 @@ func Write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.De
 		_ = txn.Rollback()
 	}()
 
+	lastChange, err := writeTx(ctx, dbInfo, txn, store, deletes, writes, expiresAt, conditionExpression, conditionParameters, now, opts)
+	if err != nil {
+		return handleWriteError(err, opts)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return handleWriteError(HandleSQLError(err), opts)
+	}
+
+	opts.CommitChange(lastChange)
+	return nil
+}
+
+// writeTxOptions returns the options of the transaction of a write. Unless the write transactions of a store are
+// serialized by a lock, see WithStoreLock, the conditional writes (see storage.WithExpectedChangelogToken) are
+// serializable, so that two concurrent conditional writes cannot both observe the same latest change.
+func writeTxOptions(dbInfo *DBInfo, opts storage.TupleWriteOptions) *sql.TxOptions {
+	if opts.ExpectedChangelogToken == nil || dbInfo.storeLock != "" {
+		return nil
+	}
+
+	return &sql.TxOptions{Isolation: sql.LevelSerializable}
+}
+
+// serializedWrite reports whether a write transaction takes the lock of its store and orders its changes after the
+// latest change of the store, see WithStoreLock and WithSerializedWrites.
+func serializedWrite(dbInfo *DBInfo, opts storage.TupleWriteOptions) bool {
+	return dbInfo.storeLock != "" && (dbInfo.serializeWrites || opts.ExpectedChangelogToken != nil)
+}
+
+// lockStores takes the locks of the stores in the transaction, see WithStoreLock. The locks are taken before the
+// first read of the transaction, so that its reads observe every change committed before it, and in the order of
+// the stores, so that the transactions locking several stores cannot deadlock.
+func lockStores(ctx context.Context, dbInfo *DBInfo, txn *sql.Tx, stores ...string) error {
+	if dbInfo.storeLock == "" {
+		return nil
+	}
+
+	stores = append([]string(nil), stores...)
+	sort.Strings(stores)
+
+	for i, store := range stores {
+		if i > 0 && store == stores[i-1] {
+			continue
+		}
+
+		if _, err := txn.ExecContext(ctx, dbInfo.storeLock, store); err != nil {
+			return HandleSQLError(err)
+		}
+	}
+
+	return nil
+}
+
+// handleWriteError returns storage.ErrChangelogConflict if a conditional write failed because of a concurrent
+// transaction.
+func handleWriteError(err error, opts storage.TupleWriteOptions) error {
+	if opts.ExpectedChangelogToken == nil {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "40001" { // Postgres serialization failure
+		return storage.ErrChangelogConflict
+	}
+
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == 1213 { // MySQL deadlock
+		return storage.ErrChangelogConflict
+	}
+
+	return err
+}
+
+// checkChangelogToken returns storage.ErrChangelogConflict unless the continuation token of ReadChangesWithFilter is
+// the ULID of the latest change of the store.
+func checkChangelogToken(token, latest string) error {
+	var expected string
+	if token != "" {
+		contToken, err := UnmarshallContToken(token)
+		if err != nil {
+			return err
+		}
+		expected = contToken.Ulid
+	}
+
+	if latest != expected {
+		return storage.ErrChangelogConflict
+	}
+
+	return nil
+}
+
+// latestChangeULID returns the ULID of the latest change of the store, or an empty string if it has none.
+func latestChangeULID(ctx context.Context, dbInfo *DBInfo, txn *sql.Tx, store string) (string, error) {
+	var latest string
+	err := dbInfo.stbl.
+		Select("ulid").
+		From("changelog").
+		Where(sq.Eq{"store": store}).
+		OrderBy("ulid DESC").
+		Limit(1).
+		RunWith(txn).
+		QueryRowContext(ctx).
+		Scan(&latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", HandleSQLError(err)
+	}
+
+	return latest, nil
+}
+
+// changeULIDs returns the generator of the ULIDs of the changes of a write made at `now` after the change of ULID
+// `latest`, see storage.NextChangeULID.
+func changeULIDs(now time.Time, latest string) func() string {
+	return func() string {
+		latest = storage.NextChangeULID(now, latest)
+		return latest
+	}
+}
+
+// writeTx applies the deletes and writes, along with their changes, in the transaction, and returns the ULID of the
+// last change, or an empty ULID if the write changed nothing.
+func writeTx(
+	ctx context.Context,
+	dbInfo *DBInfo,
+	txn *sql.Tx,
+	store string,
+	deletes storage.Deletes,
+	writes storage.Writes,
+	expiresAt *time.Time,
+	conditionExpression, conditionParameters *string,
+	now time.Time,
+	opts storage.TupleWriteOptions,
+) (string, error) {
+	ignoreDuplicates := opts.OnDuplicateInsert == storage.OnDuplicateInsertIgnore
+
+	var latest string
+	if opts.ExpectedChangelogToken != nil || serializedWrite(dbInfo, opts) {
+		if err := lockStores(ctx, dbInfo, txn, store); err != nil {
+			return "", err
+		}
+
+		var err error
+		latest, err = latestChangeULID(ctx, dbInfo, txn, store)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if opts.ExpectedChangelogToken != nil {
+		if err := checkChangelogToken(*opts.ExpectedChangelogToken, latest); err != nil {
+			return "", err
+		}
+	}
+
+	nextULID := changeULIDs(now, latest)
+	var lastChange string
+
 	changelogBuilder := dbInfo.stbl.
 		Insert("changelog").
 		Columns("store", "object_type", "object_id", "relation", "_user", "operation", "ulid", "inserted_at")
@@ -290,7 +844,7 @@ func Write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.De
 	deleteBuilder := dbInfo.stbl.Delete("tuple")
 
 	for _, tk := range deletes {
-		id := ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
+		id := nextULID()
 		objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
 
 		res, err := deleteBuilder.
@@ -302,53 +856,1199 @@ func Write(ctx context.Context, dbInfo *DBInfo, store string, deletes storage.De
 				"_user":       tk.GetUser(),
 				"user_type":   tupleUtils.GetUserTypeFromUser(tk.GetUser()),
 			}).
+			Where(NotExpired(now)).
 			RunWith(txn). // Part of a txn
 			ExecContext(ctx)
 		if err != nil {
-			return HandleSQLError(err, tk)
+			return "", HandleSQLError(err, tk)
 		}
 
 		rowsAffected, err := res.RowsAffected()
 		if err != nil {
-			return HandleSQLError(err)
+			return "", HandleSQLError(err)
 		}
 
 		if rowsAffected != 1 {
-			return storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE)
+			if opts.OnMissingDelete == storage.OnMissingDeleteIgnore {
+				continue
+			}
+
+			return "", storage.InvalidWriteInputError(tk, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE)
 		}
 
 		changelogBuilder = changelogBuilder.Values(store, objectType, objectID, tk.GetRelation(), tk.GetUser(), openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, id, dbInfo.sqlTime)
+		lastChange = id
 	}
 
 	insertBuilder := dbInfo.stbl.
 		Insert("tuple").
-		Columns("store", "object_type", "object_id", "relation", "_user", "user_type", "ulid", "inserted_at")
+		Columns("store", "object_type", "object_id", "relation", "_user", "user_type", "ulid", "inserted_at", "expires_at", "condition_expression", "condition_parameters")
 
 	for _, tk := range writes {
-		id := ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
 		objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
 
-		_, err = insertBuilder.
-			Values(store, objectType, objectID, tk.GetRelation(), tk.GetUser(), tupleUtils.GetUserTypeFromUser(tk.GetUser()), id, dbInfo.sqlTime).
+		// an expired tuple behaves as if it had been deleted, so it is replaced if it hasn't been deleted yet
+		res, err := deleteBuilder.
+			Where(sq.Eq{
+				"store":       store,
+				"object_type": objectType,
+				"object_id":   objectID,
+				"relation":    tk.GetRelation(),
+				"_user":       tk.GetUser(),
+				"user_type":   tupleUtils.GetUserTypeFromUser(tk.GetUser()),
+			}).
+			Where(sq.LtOrEq{"expires_at": now}).
+			RunWith(txn). // Part of a txn
+			ExecContext(ctx)
+		if err != nil {
+			return "", HandleSQLError(err, tk)
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return "", HandleSQLError(err)
+		}
+
+		if rowsAffected == 1 {
+			id := nextULID()
+			changelogBuilder = changelogBuilder.Values(store, objectType, objectID, tk.GetRelation(), tk.GetUser(), openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, id, dbInfo.sqlTime)
+			lastChange = id
+		}
+
+		id := nextULID()
+
+		ib := insertBuilder.
+			Values(store, objectType, objectID, tk.GetRelation(), tk.GetUser(), tupleUtils.GetUserTypeFromUser(tk.GetUser()), id, dbInfo.sqlTime, expiresAt, conditionExpression, conditionParameters)
+		if ignoreDuplicates {
+			ib = ib.Suffix(dbInfo.onConflictDoNothing)
+		}
+
+		res, err = ib.
 			RunWith(txn). // Part of a txn
 			ExecContext(ctx)
 		if err != nil {
-			return HandleSQLError(err, tk)
+			return "", HandleSQLError(err, tk)
+		}
+
+		if ignoreDuplicates {
+			rowsAffected, err := res.RowsAffected()
+			if err != nil {
+				return "", HandleSQLError(err)
+			}
+
+			if rowsAffected == 0 {
+				continue // the tuple already exists
+			}
 		}
 
 		changelogBuilder = changelogBuilder.Values(store, objectType, objectID, tk.GetRelation(), tk.GetUser(), openfgav1.TupleOperation_TUPLE_OPERATION_WRITE, id, dbInfo.sqlTime)
+		lastChange = id
 	}
 
-	if len(writes) > 0 || len(deletes) > 0 {
+	if lastChange != "" {
 		_, err := changelogBuilder.RunWith(txn).ExecContext(ctx) // Part of a txn
 		if err != nil {
-			return HandleSQLError(err)
+			return "", HandleSQLError(err)
 		}
 	}
 
-	if err := txn.Commit(); err != nil {
+	return lastChange, nil
+}
+
+// StageWrite provides the common method for staging deletes and writes across sql storage, see
+// storage.StagedWriteBackend.StageWrite.
+func StageWrite(ctx context.Context, dbInfo *DBInfo, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	if len(deletes)+len(writes) == 0 {
+		return nil
+	}
+
+	insertBuilder := dbInfo.stbl.
+		Insert("staged_write").
+		Columns("store", "id", "operation", "object_type", "object_id", "relation", "_user", "staged_at")
+
+	for operation, tupleKeys := range map[openfgav1.TupleOperation][]*openfgav1.TupleKey{
+		openfgav1.TupleOperation_TUPLE_OPERATION_DELETE: deletes,
+		openfgav1.TupleOperation_TUPLE_OPERATION_WRITE:  writes,
+	} {
+		for _, tk := range tupleKeys {
+			objectType, objectID := tupleUtils.SplitObject(tk.GetObject())
+			insertBuilder = insertBuilder.Values(store, id, operation, objectType, objectID, tk.GetRelation(), tk.GetUser(), dbInfo.sqlTime)
+		}
+	}
+
+	if _, err := insertBuilder.RunWith(dbInfo.db).ExecContext(ctx); err != nil {
 		return HandleSQLError(err)
 	}
 
 	return nil
 }
+
+// CommitStagedWrite provides the common method for committing a staged write across sql storage: the staged deletes
+// and writes are read and applied, and the staged write is deleted, in a single transaction. See
+// storage.StagedWriteBackend.CommitStagedWrite.
+func CommitStagedWrite(ctx context.Context, dbInfo *DBInfo, store, id string, now time.Time, opts ...storage.TupleWriteOption) error {
+	o := storage.NewTupleWriteOptions(opts...)
+	if o.OnDuplicateInsert == storage.OnDuplicateInsertIgnore && dbInfo.onConflictDoNothing == "" {
+		return fmt.Errorf("ignoring duplicate writes is not supported by this datastore")
+	}
+
+	txn, err := dbInfo.db.BeginTx(ctx, writeTxOptions(dbInfo, o))
+	if err != nil {
+		return HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	// the lock is taken before the first read, so that the reads of the transaction observe every change committed
+	// before it, see writeTx
+	if serializedWrite(dbInfo, o) {
+		if err := lockStores(ctx, dbInfo, txn, store); err != nil {
+			return err
+		}
+	}
+
+	rows, err := dbInfo.stbl.
+		Select("operation", "object_type", "object_id", "relation", "_user").
+		From("staged_write").
+		Where(sq.Eq{"store": store, "id": id}).
+		RunWith(txn).
+		QueryContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	var deletes storage.Deletes
+	var writes storage.Writes
+	for rows.Next() {
+		var operation openfgav1.TupleOperation
+		var objectType, objectID, relation, user string
+		if err := rows.Scan(&operation, &objectType, &objectID, &relation, &user); err != nil {
+			return HandleSQLError(err)
+		}
+
+		tk := tupleUtils.NewTupleKey(tupleUtils.BuildObject(objectType, objectID), relation, user)
+		if operation == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+			deletes = append(deletes, tk)
+		} else {
+			writes = append(writes, tk)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return HandleSQLError(err)
+	}
+	_ = rows.Close()
+
+	if len(deletes)+len(writes) == 0 {
+		return nil
+	}
+
+	lastChange, err := writeTx(ctx, dbInfo, txn, store, deletes, writes, nil, nil, nil, now, o)
+	if err != nil {
+		return handleWriteError(err, o)
+	}
+
+	_, err = dbInfo.stbl.
+		Delete("staged_write").
+		Where(sq.Eq{"store": store, "id": id}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return handleWriteError(HandleSQLError(err), o)
+	}
+
+	o.CommitChange(lastChange)
+	return nil
+}
+
+// DiscardStagedWrite provides the common method for discarding a staged write across sql storage, see
+// storage.StagedWriteBackend.DiscardStagedWrite.
+func DiscardStagedWrite(ctx context.Context, dbInfo *DBInfo, store, id string) error {
+	_, err := dbInfo.stbl.
+		Delete("staged_write").
+		Where(sq.Eq{"store": store, "id": id}).
+		RunWith(dbInfo.db).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	return nil
+}
+
+// ReadTupleConditions provides the common method for reading the conditions of the conditional tuples matching
+// the filter across sql storage, see storage.TupleConditionBackend.ReadTupleConditions.
+func ReadTupleConditions(ctx context.Context, dbInfo *DBInfo, store string, filter *openfgav1.TupleKey, now time.Time) (map[string]*storage.TupleCondition, error) {
+	objectType, objectID := tupleUtils.SplitObject(filter.GetObject())
+
+	sb := dbInfo.stbl.
+		Select("object_type", "object_id", "relation", "_user", "condition_expression", "condition_parameters").
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		Where(sq.NotEq{"condition_expression": nil}).
+		Where(NotExpired(now))
+	if objectType != "" {
+		sb = sb.Where(sq.Eq{"object_type": objectType})
+	}
+	if objectID != "" {
+		sb = sb.Where(sq.Eq{"object_id": objectID})
+	}
+	if filter.GetRelation() != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.GetRelation()})
+	}
+	if filter.GetUser() != "" {
+		sb = sb.Where(sq.Eq{"_user": filter.GetUser()})
+	}
+
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	conditions := map[string]*storage.TupleCondition{}
+	for rows.Next() {
+		var record TupleRecord
+		var expression string
+		var parameters sql.NullString
+		if err := rows.Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &expression, &parameters); err != nil {
+			return nil, HandleSQLError(err)
+		}
+
+		condition := &storage.TupleCondition{Expression: expression}
+		if parameters.Valid {
+			condition.Parameters = &structpb.Struct{}
+			if err := protojson.Unmarshal([]byte(parameters.String), condition.Parameters); err != nil {
+				return nil, err
+			}
+		}
+
+		tk := tupleUtils.NewTupleKey(tupleUtils.BuildObject(record.ObjectType, record.ObjectID), record.Relation, record.User)
+		conditions[tupleUtils.TupleKeyToString(tk)] = condition
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	return conditions, nil
+}
+
+// ReadTupleExpirations provides the common method for reading the expiration times of the tuples matching the
+// filter across sql storage, see storage.TupleExpirationBackend.ReadTupleExpirations.
+func ReadTupleExpirations(ctx context.Context, dbInfo *DBInfo, store string, filter *openfgav1.TupleKey, now time.Time) (map[string]time.Time, error) {
+	objectType, objectID := tupleUtils.SplitObject(filter.GetObject())
+
+	sb := dbInfo.stbl.
+		Select("object_type", "object_id", "relation", "_user", "expires_at").
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		Where(sq.NotEq{"expires_at": nil}).
+		Where(NotExpired(now))
+	if objectType != "" {
+		sb = sb.Where(sq.Eq{"object_type": objectType})
+	}
+	if objectID != "" {
+		sb = sb.Where(sq.Eq{"object_id": objectID})
+	}
+	if filter.GetRelation() != "" {
+		sb = sb.Where(sq.Eq{"relation": filter.GetRelation()})
+	}
+	if filter.GetUser() != "" {
+		sb = sb.Where(sq.Eq{"_user": filter.GetUser()})
+	}
+
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	expirations := map[string]time.Time{}
+	for rows.Next() {
+		var record TupleRecord
+		var expiresAt time.Time
+		if err := rows.Scan(&record.ObjectType, &record.ObjectID, &record.Relation, &record.User, &expiresAt); err != nil {
+			return nil, HandleSQLError(err)
+		}
+
+		tk := tupleUtils.NewTupleKey(tupleUtils.BuildObject(record.ObjectType, record.ObjectID), record.Relation, record.User)
+		expirations[tupleUtils.TupleKeyToString(tk)] = expiresAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	return expirations, nil
+}
+
+// MarshalTypeAnnotations returns the value of the annotations column of the row of a type definition in the
+// authorization_model table: the JSON encoding of the annotations, or NULL if the type has none.
+func MarshalTypeAnnotations(annotations *storage.TypeAnnotations) (interface{}, error) {
+	if annotations == nil || (len(annotations.Annotations) == 0 && len(annotations.Relations) == 0) {
+		return nil, nil
+	}
+
+	marshalled, err := json.Marshal(annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(marshalled), nil
+}
+
+// ReadAuthorizationModelAnnotations provides the common method for reading the annotations of an authorization
+// model across sql storage, see storage.AuthorizationModelReadBackend.ReadAuthorizationModelAnnotations.
+func ReadAuthorizationModelAnnotations(ctx context.Context, dbInfo *DBInfo, store string, modelID string) (storage.ModelAnnotations, error) {
+	rows, err := dbInfo.stbl.
+		Select("type", "annotations").
+		From("authorization_model").
+		Where(sq.Eq{
+			"store":                  store,
+			"authorization_model_id": modelID,
+		}).QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	found := false
+	annotations := storage.ModelAnnotations{}
+	for rows.Next() {
+		found = true
+
+		var typeName string
+		var marshalled sql.NullString
+		if err := rows.Scan(&typeName, &marshalled); err != nil {
+			return nil, HandleSQLError(err)
+		}
+
+		if !marshalled.Valid {
+			continue
+		}
+
+		var typeAnnotations storage.TypeAnnotations
+		if err := json.Unmarshal([]byte(marshalled.String), &typeAnnotations); err != nil {
+			return nil, err
+		}
+
+		annotations[typeName] = &typeAnnotations
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+
+	return annotations, nil
+}
+
+// storeContToken is the continuation token of ListStores. Without a name, it is the ContToken of the stores
+// listed from the oldest to the newest.
+type storeContToken struct {
+	Ulid string `json:"ulid"`
+	Name string `json:"name,omitempty"`
+}
+
+// DeleteAuthorizationModel deletes an authorization model of a store along with its assertions and pin. See
+// storage.TypeDefinitionWriteBackend.
+func DeleteAuthorizationModel(ctx context.Context, dbInfo *DBInfo, store string, id string) error {
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	res, err := dbInfo.stbl.
+		Delete("authorization_model").
+		Where(sq.Eq{"store": store, "authorization_model_id": id}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrNotFound
+	}
+
+	for _, table := range []string{"assertion", "pinned_authorization_model"} {
+		_, err := dbInfo.stbl.
+			Delete(table).
+			Where(sq.Eq{"store": store, "authorization_model_id": id}).
+			RunWith(txn).
+			ExecContext(ctx)
+		if err != nil {
+			return HandleSQLError(err)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return HandleSQLError(err)
+	}
+
+	return nil
+}
+
+// PinAuthorizationModel pins an authorization model of a store. See storage.PinnedAuthorizationModelBackend.
+func PinAuthorizationModel(ctx context.Context, dbInfo *DBInfo, store string, id string) error {
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	var modelID string
+	err = dbInfo.stbl.
+		Select("authorization_model_id").
+		From("authorization_model").
+		Where(sq.Eq{"store": store, "authorization_model_id": id}).
+		Limit(1).
+		RunWith(txn).
+		QueryRowContext(ctx).
+		Scan(&modelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return HandleSQLError(err)
+	}
+
+	_, err = dbInfo.stbl.
+		Delete("pinned_authorization_model").
+		Where(sq.Eq{"store": store}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	_, err = dbInfo.stbl.
+		Insert("pinned_authorization_model").
+		Columns("store", "authorization_model_id", "pinned_at").
+		Values(store, id, dbInfo.sqlTime).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return HandleSQLError(err)
+	}
+
+	return nil
+}
+
+// UnpinAuthorizationModel unpins the pinned model of a store. See storage.PinnedAuthorizationModelBackend.
+func UnpinAuthorizationModel(ctx context.Context, dbInfo *DBInfo, store string) error {
+	_, err := dbInfo.stbl.
+		Delete("pinned_authorization_model").
+		Where(sq.Eq{"store": store}).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	return nil
+}
+
+// ReadPinnedAuthorizationModelID returns the id of the pinned model of a store. See
+// storage.AuthorizationModelReadBackend.
+func ReadPinnedAuthorizationModelID(ctx context.Context, dbInfo *DBInfo, store string) (string, error) {
+	var modelID string
+	err := dbInfo.stbl.
+		Select("authorization_model_id").
+		From("pinned_authorization_model").
+		Where(sq.Eq{"store": store}).
+		QueryRowContext(ctx).
+		Scan(&modelID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", HandleSQLError(err)
+	}
+
+	return modelID, nil
+}
+
+// ListStores lists the stores selected by the filter. See storage.StoresBackend.
+func ListStores(ctx context.Context, dbInfo *DBInfo, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	sb := dbInfo.stbl.Select("id", "name", "created_at", "updated_at").
+		From("store").
+		Where(sq.Eq{"deleted_at": nil})
+
+	if filter.NamePrefix != "" {
+		sb = sb.Where(sq.Expr("SUBSTR(name, 1, ?) = ?", utf8.RuneCountInString(filter.NamePrefix), filter.NamePrefix))
+	}
+
+	labelKeys := make([]string, 0, len(filter.Labels))
+	for key := range filter.Labels {
+		labelKeys = append(labelKeys, key)
+	}
+	sort.Strings(labelKeys)
+
+	for _, key := range labelKeys {
+		sb = sb.Where(sq.Expr("id IN (SELECT store FROM store_label WHERE label_key = ? AND label_value = ?)", key, filter.Labels[key]))
+	}
+
+	sortByName := filter.SortOrder == storage.StoreSortByName
+	if sortByName {
+		sb = sb.OrderBy("name", "id")
+	} else {
+		sb = sb.OrderBy("id")
+	}
+
+	if opts.From != "" {
+		var token storeContToken
+		if err := json.Unmarshal([]byte(opts.From), &token); err != nil {
+			return nil, nil, storage.ErrInvalidContinuationToken
+		}
+
+		if sortByName {
+			sb = sb.Where(sq.Or{
+				sq.Gt{"name": token.Name},
+				sq.And{sq.Eq{"name": token.Name}, sq.GtOrEq{"id": token.Ulid}},
+			})
+		} else {
+			sb = sb.Where(sq.GtOrEq{"id": token.Ulid})
+		}
+	}
+	if opts.PageSize > 0 {
+		sb = sb.Limit(uint64(opts.PageSize + 1)) // + 1 is used to determine whether to return a continuation token.
+	}
+
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return nil, nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	var stores []*openfgav1.Store
+	var id, name string
+	for rows.Next() {
+		var createdAt, updatedAt time.Time
+		err := rows.Scan(&id, &name, &createdAt, &updatedAt)
+		if err != nil {
+			return nil, nil, HandleSQLError(err)
+		}
+
+		stores = append(stores, &openfgav1.Store{
+			Id:        id,
+			Name:      name,
+			CreatedAt: timestamppb.New(createdAt),
+			UpdatedAt: timestamppb.New(updatedAt),
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, HandleSQLError(err)
+	}
+
+	if len(stores) > opts.PageSize {
+		token := storeContToken{Ulid: id}
+		if sortByName {
+			token.Name = name
+		}
+
+		contToken, err := json.Marshal(token)
+		if err != nil {
+			return nil, nil, err
+		}
+		return stores[:opts.PageSize], contToken, nil
+	}
+
+	return stores, nil, nil
+}
+
+// WriteStoreMetadata replaces the metadata of a store. See storage.StoresBackend.
+func WriteStoreMetadata(ctx context.Context, dbInfo *DBInfo, id string, metadata *storage.StoreMetadata) error {
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	var storeID string
+	err = dbInfo.stbl.
+		Select("id").
+		From("store").
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		RunWith(txn).
+		QueryRowContext(ctx).
+		Scan(&storeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return HandleSQLError(err)
+	}
+
+	_, err = dbInfo.stbl.
+		Update("store").
+		Set("description", metadata.Description).
+		Set("updated_at", dbInfo.sqlTime).
+		Where(sq.Eq{"id": id}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	_, err = dbInfo.stbl.
+		Delete("store_label").
+		Where(sq.Eq{"store": id}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return HandleSQLError(err)
+	}
+
+	if len(metadata.Labels) > 0 {
+		insertBuilder := dbInfo.stbl.
+			Insert("store_label").
+			Columns("store", "label_key", "label_value")
+
+		for key, value := range metadata.Labels {
+			insertBuilder = insertBuilder.Values(id, key, value)
+		}
+
+		_, err = insertBuilder.RunWith(txn).ExecContext(ctx)
+		if err != nil {
+			return HandleSQLError(err)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return HandleSQLError(err)
+	}
+
+	return nil
+}
+
+// ReadStoreMetadata returns the metadata of a store. See storage.StoresBackend.
+func ReadStoreMetadata(ctx context.Context, dbInfo *DBInfo, id string) (*storage.StoreMetadata, error) {
+	var description sql.NullString
+	err := dbInfo.stbl.
+		Select("description").
+		From("store").
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		QueryRowContext(ctx).
+		Scan(&description)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNotFound
+		}
+		return nil, HandleSQLError(err)
+	}
+
+	rows, err := dbInfo.stbl.
+		Select("label_key", "label_value").
+		From("store_label").
+		Where(sq.Eq{"store": id}).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	metadata := &storage.StoreMetadata{Description: description.String}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, HandleSQLError(err)
+		}
+
+		if metadata.Labels == nil {
+			metadata.Labels = map[string]string{}
+		}
+		metadata.Labels[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	return metadata, nil
+}
+
+// UndeleteStore restores a soft-deleted store. See storage.StoresBackend.
+func UndeleteStore(ctx context.Context, dbInfo *DBInfo, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	res, err := dbInfo.stbl.
+		Update("store").
+		Set("deleted_at", nil).
+		Set("updated_at", dbInfo.sqlTime).
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.GtOrEq{"deleted_at": deletedAfter}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	var name string
+	var createdAt, updatedAt time.Time
+	err = dbInfo.stbl.
+		Select("name", "created_at", "updated_at").
+		From("store").
+		Where(sq.Eq{"id": id}).
+		RunWith(txn).
+		QueryRowContext(ctx).
+		Scan(&name, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	return &openfgav1.Store{
+		Id:        id,
+		Name:      name,
+		CreatedAt: timestamppb.New(createdAt),
+		UpdatedAt: timestamppb.New(updatedAt),
+	}, nil
+}
+
+// PurgeDeletedStores permanently deletes the stores deleted before `deletedBefore`, one transaction per store.
+// See storage.StoresBackend.
+func PurgeDeletedStores(ctx context.Context, dbInfo *DBInfo, deletedBefore time.Time, limit int) (int, error) {
+	rows, err := dbInfo.stbl.
+		Select("id").
+		From("store").
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.Lt{"deleted_at": deletedBefore}).
+		OrderBy("deleted_at").
+		Limit(uint64(limit)).
+		QueryContext(ctx)
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	var storeIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, HandleSQLError(err)
+		}
+		storeIDs = append(storeIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	purged := 0
+	for _, id := range storeIDs {
+		ok, err := purgeStore(ctx, dbInfo, id, deletedBefore)
+		if err != nil {
+			return purged, err
+		}
+
+		if ok {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// purgeStore permanently deletes a store and its data, and returns false if the store was restored since it was
+// selected for purging. The store row is deleted first, so that a concurrent UndeleteStore waits for the purge.
+func purgeStore(ctx context.Context, dbInfo *DBInfo, id string, deletedBefore time.Time) (bool, error) {
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	res, err := dbInfo.stbl.
+		Delete("store").
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		Where(sq.Lt{"deleted_at": deletedBefore}).
+		RunWith(txn).
+		ExecContext(ctx)
+	if err != nil {
+		return false, HandleSQLError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, HandleSQLError(err)
+	}
+
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	for _, table := range []string{"tuple", "changelog", "authorization_model", "pinned_authorization_model", "assertion", "store_label"} {
+		_, err := dbInfo.stbl.
+			Delete(table).
+			Where(sq.Eq{"store": id}).
+			RunWith(txn).
+			ExecContext(ctx)
+		if err != nil {
+			return false, HandleSQLError(err)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return false, HandleSQLError(err)
+	}
+
+	return true, nil
+}
+
+// DeleteChanges deletes the oldest changes that occurred before `before`. See storage.ChangelogBackend.
+func DeleteChanges(ctx context.Context, dbInfo *DBInfo, store string, before time.Time, limit int) (int, error) {
+	sb := dbInfo.stbl.
+		Select("store", "ulid", "object_type").
+		From("changelog").
+		Where(sq.Lt{"inserted_at": before}).
+		OrderBy("inserted_at").
+		Limit(uint64(limit))
+	if store != "" {
+		sb = sb.Where(sq.Eq{"store": store})
+	}
+
+	rows, err := sb.QueryContext(ctx)
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	var keys sq.Or
+	for rows.Next() {
+		var changeStore, ulid, objectType string
+		if err := rows.Scan(&changeStore, &ulid, &objectType); err != nil {
+			return 0, HandleSQLError(err)
+		}
+		keys = append(keys, sq.Eq{"store": changeStore, "ulid": ulid, "object_type": objectType})
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	res, err := dbInfo.stbl.
+		Delete("changelog").
+		Where(keys).
+		ExecContext(ctx)
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	return int(deleted), nil
+}
+
+// ReadStoreStats returns the sizes of the data of a store, counted at `now`. See storage.StoreStatsBackend.
+func ReadStoreStats(ctx context.Context, dbInfo *DBInfo, store string, now time.Time) (*storage.StoreStats, error) {
+	rows, err := dbInfo.stbl.
+		Select("object_type", "relation", "COUNT(*)").
+		From("tuple").
+		Where(sq.Eq{"store": store}).
+		Where(NotExpired(now)).
+		GroupBy("object_type", "relation").
+		QueryContext(ctx)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	stats := &storage.StoreStats{Tuples: map[string]int64{}}
+	for rows.Next() {
+		var objectType, relation string
+		var count int64
+		if err := rows.Scan(&objectType, &relation, &count); err != nil {
+			return nil, HandleSQLError(err)
+		}
+		stats.Tuples[tupleUtils.ToObjectRelationString(objectType, relation)] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	// an authorization model is stored as one row per type definition
+	err = dbInfo.stbl.
+		Select("COUNT(DISTINCT authorization_model_id)").
+		From("authorization_model").
+		Where(sq.Eq{"store": store}).
+		QueryRowContext(ctx).
+		Scan(&stats.AuthorizationModels)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	err = dbInfo.stbl.
+		Select("COUNT(*)").
+		From("changelog").
+		Where(sq.Eq{"store": store}).
+		QueryRowContext(ctx).
+		Scan(&stats.Changes)
+	if err != nil {
+		return nil, HandleSQLError(err)
+	}
+
+	return stats, nil
+}
+
+// DeleteExpiredTuples provides the common method for deleting the tuples expired at `now` across sql storage.
+// At most `limit` tuples are deleted, and every delete is recorded in the changelog.
+func DeleteExpiredTuples(ctx context.Context, dbInfo *DBInfo, limit int, now time.Time) (int, error) {
+	rows, err := dbInfo.stbl.
+		Select("store", "object_type", "object_id", "relation", "_user").
+		From("tuple").
+		Where(sq.LtOrEq{"expires_at": now}).
+		Limit(uint64(limit)).
+		QueryContext(ctx)
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+	defer rows.Close()
+
+	var records []*TupleRecord
+	for rows.Next() {
+		var record TupleRecord
+		if err := rows.Scan(&record.Store, &record.ObjectType, &record.ObjectID, &record.Relation, &record.User); err != nil {
+			return 0, HandleSQLError(err)
+		}
+		records = append(records, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	txn, err := dbInfo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, HandleSQLError(err)
+	}
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	stores := make([]string, 0, len(records))
+	for _, record := range records {
+		stores = append(stores, record.Store)
+	}
+
+	serialized := serializedWrite(dbInfo, storage.TupleWriteOptions{})
+	if serialized {
+		if err := lockStores(ctx, dbInfo, txn, stores...); err != nil {
+			return 0, err
+		}
+	}
+
+	nextULIDs := map[string]func() string{}
+	for _, store := range stores {
+		if _, ok := nextULIDs[store]; ok {
+			continue
+		}
+
+		var latest string
+		if serialized {
+			latest, err = latestChangeULID(ctx, dbInfo, txn, store)
+			if err != nil {
+				return 0, err
+			}
+		}
+		nextULIDs[store] = changeULIDs(now, latest)
+	}
+
+	changelogBuilder := dbInfo.stbl.
+		Insert("changelog").
+		Columns("store", "object_type", "object_id", "relation", "_user", "operation", "ulid", "inserted_at")
+
+	var deleted int
+	for _, record := range records {
+		res, err := dbInfo.stbl.
+			Delete("tuple").
+			Where(sq.Eq{
+				"store":       record.Store,
+				"object_type": record.ObjectType,
+				"object_id":   record.ObjectID,
+				"relation":    record.Relation,
+				"_user":       record.User,
+			}).
+			Where(sq.LtOrEq{"expires_at": now}).
+			RunWith(txn). // Part of a txn
+			ExecContext(ctx)
+		if err != nil {
+			return 0, HandleSQLError(err)
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return 0, HandleSQLError(err)
+		}
+
+		// the tuple may have been deleted or replaced concurrently
+		if rowsAffected != 1 {
+			continue
+		}
+
+		id := nextULIDs[record.Store]()
+		changelogBuilder = changelogBuilder.Values(record.Store, record.ObjectType, record.ObjectID, record.Relation, record.User, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, id, dbInfo.sqlTime)
+		deleted++
+	}
+
+	if deleted > 0 {
+		if _, err := changelogBuilder.RunWith(txn).ExecContext(ctx); err != nil { // Part of a txn
+			return 0, HandleSQLError(err)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return 0, HandleSQLError(err)
+	}
+
+	return deleted, nil
+}
+
+// ConditionalTupleReader is a storage.RelationshipTupleReader that can read the conditions of the tuples.
+type ConditionalTupleReader interface {
+	storage.RelationshipTupleReader
+	ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error)
+}
+
+var _ storage.SnapshotReader = (*snapshotReader)(nil)
+
+// snapshotReader reads the tuples from a read-only transaction whose isolation level makes its reads all observe the
+// same snapshot. A transaction runs one query at a time, so the reads are serialized and their rows are read before
+// the next read starts.
+type snapshotReader struct {
+	mu     sync.Mutex
+	reader ConditionalTupleReader
+	tx     *sql.Tx
+}
+
+// NewSnapshotReader returns a storage.SnapshotReader over a transaction, whose reads are those of the reader, which
+// must run its queries within the transaction. Closing the reader rolls the transaction back.
+func NewSnapshotReader(tx *sql.Tx, reader ConditionalTupleReader) storage.SnapshotReader {
+	return &snapshotReader{reader: reader, tx: tx}
+}
+
+func (s *snapshotReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readAll(s.reader.Read(ctx, store, tk))
+}
+
+func (s *snapshotReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reader.ReadPage(ctx, store, tk, opts)
+}
+
+func (s *snapshotReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reader.ReadUserTuple(ctx, store, tk)
+}
+
+func (s *snapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readAll(s.reader.ReadUsersetTuples(ctx, store, filter))
+}
+
+func (s *snapshotReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readAll(s.reader.ReadStartingWithUser(ctx, store, filter))
+}
+
+func (s *snapshotReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reader.ReadTupleConditions(ctx, store, filter)
+}
+
+func (s *snapshotReader) Close() {
+	// the transaction is read-only, and it is already rolled back if its context is done
+	_ = s.tx.Rollback()
+}
+
+// readAll reads the tuples of the iterator, which it stops, and returns them in a static iterator.
+func readAll(iter storage.TupleIterator, err error) (storage.TupleIterator, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Stop()
+
+	var tuples []*openfgav1.Tuple
+	for {
+		t, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, storage.ErrIteratorDone) {
+				return storage.NewStaticTupleIterator(tuples), nil
+			}
+			return nil, err
+		}
+		tuples = append(tuples, t)
+	}
+}
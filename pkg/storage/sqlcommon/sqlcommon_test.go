@@ -1,13 +1,19 @@
 package sqlcommon
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"testing"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/go-sql-driver/mysql"
+	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/storage"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
 	"github.com/stretchr/testify/require"
 )
 
@@ -55,3 +61,53 @@ func TestHandleSQLError(t *testing.T) {
 		require.ErrorIs(t, err, storage.ErrNotFound)
 	})
 }
+
+func TestPaginatedTupleIterator(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlcommon_fake", "")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	row := func(objectID, user string) []driver.Value {
+		return []driver.Value{"store", "document", objectID, "viewer", user, ulid.Make().String(), time.Now(), nil}
+	}
+
+	fakeMu.Lock()
+	fakePages = [][][]driver.Value{
+		{row("1", "user:anne"), row("2", "user:anne")},
+		{row("1", "user:bob")},
+	}
+	fakeQueries = nil
+	fakeMu.Unlock()
+
+	sb := sq.StatementBuilder.RunWith(db).
+		Select(TupleColumns...).
+		From("tuple").
+		Where(sq.Eq{"store": "store"})
+
+	iter := NewPaginatedTupleIterator(ctx, sb, []string{"_user", "object_id"}, 2)
+	defer iter.Stop()
+
+	var tuples []string
+	for {
+		tup, err := iter.Next()
+		if err != nil {
+			require.ErrorIs(t, err, storage.ErrIteratorDone)
+			break
+		}
+		tuples = append(tuples, tupleUtils.TupleKeyToString(tup.GetKey()))
+	}
+	require.Equal(t, []string{"document:1#viewer@user:anne", "document:2#viewer@user:anne", "document:1#viewer@user:bob"}, tuples)
+
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+
+	// the second page starts after the last tuple of the first one, and the partial second page is the last one
+	require.Len(t, fakeQueries, 2)
+	require.Contains(t, fakeQueries[0][0], "ORDER BY _user, object_id LIMIT 2")
+	require.Contains(t, fakeQueries[1][0], "(_user, object_id) > (?, ?)")
+	require.Equal(t, []interface{}{"user:anne", "2"}, fakeQueries[1][2:])
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	tupleUtils "github.com/openfga/openfga/pkg/tuple"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -41,6 +43,98 @@ type Deletes = []*openfgav1.TupleKey
 type TupleBackend interface {
 	RelationshipTupleReader
 	RelationshipTupleWriter
+
+	// ReadPageWithFilter is similar to ReadPage, but the tuples are matched by a ReadFilter, which can match several
+	// relations and the types of the users in a single query. The tuples returned are ordered by ULID.
+	ReadPageWithFilter(ctx context.Context, store string, filter ReadFilter, opts PaginationOptions) ([]*openfgav1.Tuple, []byte, error)
+}
+
+// ReadFilter specifies the tuples matched by ReadPageWithFilter. Every field is optional, and the empty filter
+// matches every tuple of the store.
+type ReadFilter struct {
+	// Object is an object, or an object type followed by a colon (e.g. 'document:') to match every object of the type.
+	Object string
+
+	// Relations matches the tuples of any of the relations.
+	Relations []string
+
+	User string
+
+	// UserTypes matches the tuples whose user matches any of the type restrictions, as in an authorization model:
+	// 'user' matches the users of type user but neither the wildcard 'user:*' nor the usersets, 'user:*' matches the
+	// wildcard, and 'group#member' matches the usersets of the relation.
+	UserTypes []*openfgav1.RelationReference
+}
+
+// NewReadFilter returns the ReadFilter matching the same tuples as a Read of the tuple key.
+func NewReadFilter(tk *openfgav1.TupleKey) ReadFilter {
+	filter := ReadFilter{
+		Object: tk.GetObject(),
+		User:   tk.GetUser(),
+	}
+	if tk.GetRelation() != "" {
+		filter.Relations = []string{tk.GetRelation()}
+	}
+
+	return filter
+}
+
+// Matches reports whether the tuple key matches the filter.
+func (f ReadFilter) Matches(tk *openfgav1.TupleKey) bool {
+	if f.Object != "" {
+		objectType, objectID := tupleUtils.SplitObject(f.Object)
+		if objectID == "" && objectType != tupleUtils.GetType(tk.GetObject()) {
+			return false
+		}
+		if objectID != "" && f.Object != tk.GetObject() {
+			return false
+		}
+	}
+
+	if f.User != "" && f.User != tk.GetUser() {
+		return false
+	}
+
+	if len(f.Relations) > 0 {
+		matched := false
+		for _, relation := range f.Relations {
+			if relation == tk.GetRelation() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.UserTypes) > 0 {
+		for _, userType := range f.UserTypes {
+			if matchUserType(userType, tk.GetUser()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// matchUserType reports whether the user matches the type restriction.
+func matchUserType(userType *openfgav1.RelationReference, user string) bool {
+	userObject, userRelation := tupleUtils.SplitObjectRelation(user)
+	if tupleUtils.GetType(userObject) != userType.GetType() {
+		return false
+	}
+
+	switch userType.GetRelationOrWildcard().(type) {
+	case *openfgav1.RelationReference_Wildcard:
+		return tupleUtils.IsWildcard(user)
+	case *openfgav1.RelationReference_Relation:
+		return userRelation == userType.GetRelation()
+	default:
+		return userRelation == "" && !tupleUtils.IsWildcard(user)
+	}
 }
 
 type RelationshipTupleReader interface {
@@ -110,12 +204,104 @@ type RelationshipTupleWriter interface {
 	// It is expected that
 	// - there is at most 10 deletes/writes
 	// - no duplicate item in delete/write list
-	Write(ctx context.Context, store string, d Deletes, w Writes) error
+	// The options control whether writing an existing tuple or deleting a missing tuple fails the write, see
+	// TupleWriteOptions.
+	Write(ctx context.Context, store string, d Deletes, w Writes, opts ...TupleWriteOption) error
 
 	// MaxTuplesPerWrite returns the maximum number of items allowed in a single write transaction
 	MaxTuplesPerWrite() int
 }
 
+// OnDuplicateInsert defines what happens when a tuple that already exists is written.
+type OnDuplicateInsert int
+
+const (
+	// OnDuplicateInsertError fails the write with an ErrInvalidWriteInput error.
+	OnDuplicateInsertError OnDuplicateInsert = iota
+
+	// OnDuplicateInsertIgnore leaves the existing tuple unchanged and carries on with the write.
+	OnDuplicateInsertIgnore
+)
+
+// OnMissingDelete defines what happens when a tuple that does not exist is deleted.
+type OnMissingDelete int
+
+const (
+	// OnMissingDeleteError fails the write with an ErrInvalidWriteInput error.
+	OnMissingDeleteError OnMissingDelete = iota
+
+	// OnMissingDeleteIgnore carries on with the write.
+	OnMissingDeleteIgnore
+)
+
+// TupleWriteOptions defines the options of a tuple write. By default, the whole write fails if a tuple
+// that already exists is written or a tuple that does not exist is deleted. Ignored writes and deletes are
+// not recorded in the changelog.
+type TupleWriteOptions struct {
+	OnDuplicateInsert OnDuplicateInsert
+	OnMissingDelete   OnMissingDelete
+
+	// ExpectedChangelogToken, if not nil, makes the write a conditional write, see WithExpectedChangelogToken.
+	ExpectedChangelogToken *string
+
+	// CommittedChangeULID, if not nil, receives the ULID of the last change of the write, see
+	// WithCommittedChangeULID.
+	CommittedChangeULID *string
+}
+
+// CommitChange records the ULID of the last change of a committed write in CommittedChangeULID, if set. The datastores
+// call it once the write is committed, unless it changed nothing.
+func (o TupleWriteOptions) CommitChange(id string) {
+	if o.CommittedChangeULID != nil && id != "" {
+		*o.CommittedChangeULID = id
+	}
+}
+
+type TupleWriteOption func(*TupleWriteOptions)
+
+func WithOnDuplicateInsert(onDuplicateInsert OnDuplicateInsert) TupleWriteOption {
+	return func(o *TupleWriteOptions) {
+		o.OnDuplicateInsert = onDuplicateInsert
+	}
+}
+
+func WithOnMissingDelete(onMissingDelete OnMissingDelete) TupleWriteOption {
+	return func(o *TupleWriteOptions) {
+		o.OnMissingDelete = onMissingDelete
+	}
+}
+
+// WithExpectedChangelogToken applies the write only if the changelog of the store has not changed since the token,
+// which is the continuation token returned by a ReadChangesWithFilter of the store that read its latest change, or
+// empty if the store has no changes. Otherwise, the write fails with ErrChangelogConflict. The tokens of a
+// ReadChangesWithFilter filtered by object type only track the changes of the type with some datastores, so
+// unfiltered reads should be used. The changelog is checked in the transaction of the write, so concurrent
+// conditional writes cannot both succeed.
+func WithExpectedChangelogToken(token string) TupleWriteOption {
+	return func(o *TupleWriteOptions) {
+		o.ExpectedChangelogToken = &token
+	}
+}
+
+// WithCommittedChangeULID sets `id` to the ULID of the last change the write recorded in the changelog, once the write is
+// committed. It is left unchanged if the write changed nothing, e.g. if every tuple was ignored. The ULID is the
+// consistency token of the write, see ContextWithConsistencyToken.
+func WithCommittedChangeULID(id *string) TupleWriteOption {
+	return func(o *TupleWriteOptions) {
+		o.CommittedChangeULID = id
+	}
+}
+
+// NewTupleWriteOptions returns the TupleWriteOptions resulting from applying the options to the defaults.
+func NewTupleWriteOptions(opts ...TupleWriteOption) TupleWriteOptions {
+	var o TupleWriteOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
 // ReadStartingWithUserFilter specifies the filter options that will be used to constrain the ReadStartingWithUser
 // query.
 type ReadStartingWithUserFilter struct {
@@ -130,6 +316,35 @@ type ReadUsersetTuplesFilter struct {
 	AllowedUserTypeRestrictions []*openfgav1.RelationReference // optional
 }
 
+// ModelAnnotations are the key/value annotations (e.g. a description, an owner or a deprecation notice) of the
+// types of an authorization model and of their relations, keyed by type. They are not part of the
+// openfgav1.AuthorizationModel, so they are written and read separately.
+type ModelAnnotations map[string]*TypeAnnotations
+
+// TypeAnnotations are the annotations of a type and the annotations of its relations, keyed by relation.
+type TypeAnnotations struct {
+	Annotations map[string]string            `json:"annotations,omitempty"`
+	Relations   map[string]map[string]string `json:"relations,omitempty"`
+}
+
+// GetAnnotations returns the annotations of the type. It is safe to call on a nil TypeAnnotations.
+func (a *TypeAnnotations) GetAnnotations() map[string]string {
+	if a == nil {
+		return nil
+	}
+
+	return a.Annotations
+}
+
+// GetRelations returns the annotations of the relations of the type. It is safe to call on a nil TypeAnnotations.
+func (a *TypeAnnotations) GetRelations() map[string]map[string]string {
+	if a == nil {
+		return nil
+	}
+
+	return a.Relations
+}
+
 // AuthorizationModelReadBackend Provides a Read interface for managing type definitions.
 type AuthorizationModelReadBackend interface {
 	// ReadAuthorizationModel Read the store type definition corresponding to `id`.
@@ -139,6 +354,14 @@ type AuthorizationModelReadBackend interface {
 	ReadAuthorizationModels(ctx context.Context, store string, options PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error)
 
 	FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error)
+
+	// ReadAuthorizationModelAnnotations returns the annotations of the authorization model `id`, which are
+	// empty if the model was written without annotations.
+	ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (ModelAnnotations, error)
+
+	// ReadPinnedAuthorizationModelID returns the id of the pinned model of the store, or an empty string if no
+	// model is pinned. See PinnedAuthorizationModelBackend.
+	ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error)
 }
 
 // TypeDefinitionWriteBackend Provides a write interface for managing typed definition.
@@ -148,19 +371,96 @@ type TypeDefinitionWriteBackend interface {
 
 	// WriteAuthorizationModel writes an authorization model for the given store.
 	WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error
+
+	// WriteAuthorizationModelWithAnnotations is like WriteAuthorizationModel, but the annotations of the types and
+	// relations of the model are written along with it.
+	WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations ModelAnnotations) error
+
+	// DeleteAuthorizationModel deletes the authorization model `id` of the store along with its annotations and
+	// assertions, and unpins it if it is pinned. It returns ErrNotFound if the store has no such model. Once the
+	// latest model is deleted, the latest model of the store is the newest remaining one.
+	DeleteAuthorizationModel(ctx context.Context, store string, id string) error
+}
+
+// PinnedAuthorizationModelBackend provides an interface for pinning an authorization model of a store, which the
+// requests without an authorization model id are then evaluated against instead of the latest model of the store.
+// The models written after the pinned model are not used until they are pinned.
+type PinnedAuthorizationModelBackend interface {
+	// PinAuthorizationModel pins the authorization model `id` of the store, replacing the pinned model if any. It
+	// returns ErrNotFound if the store has no such model.
+	PinAuthorizationModel(ctx context.Context, store string, id string) error
+
+	// UnpinAuthorizationModel unpins the pinned model of the store, if any, so that the latest model is used again.
+	UnpinAuthorizationModel(ctx context.Context, store string) error
 }
 
 // AuthorizationModelBackend provides an R/W interface for managing type definition.
 type AuthorizationModelBackend interface {
 	AuthorizationModelReadBackend
 	TypeDefinitionWriteBackend
+	PinnedAuthorizationModelBackend
+}
+
+// StoreMetadata is the mutable metadata of a store. It is not part of the openfgav1.Store, so it is written
+// and read separately.
+type StoreMetadata struct {
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// StoreSortOrder is the order of the stores returned by ListStoresWithFilter.
+type StoreSortOrder int
+
+const (
+	// StoreSortByCreation lists the stores from the oldest to the newest, which is the order of ListStores.
+	StoreSortByCreation StoreSortOrder = iota
+
+	// StoreSortByName lists the stores by name, and the stores with the same name from the oldest to the newest.
+	StoreSortByName
+)
+
+// ListStoresFilter selects the stores returned by ListStoresWithFilter. The zero value selects every store.
+type ListStoresFilter struct {
+	// NamePrefix selects the stores whose name starts with it.
+	NamePrefix string
+
+	// Labels selects the stores which have every one of these labels, with the same value.
+	Labels map[string]string
+
+	SortOrder StoreSortOrder
 }
 
 type StoresBackend interface {
 	CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error)
+
+	// DeleteStore soft-deletes a store: it is no longer returned by GetStore and ListStores, but its data is kept
+	// until it is purged by PurgeDeletedStores, and it can be restored by UndeleteStore until then.
 	DeleteStore(ctx context.Context, id string) error
+
+	// UndeleteStore restores a store that was deleted at or after `deletedAfter` and not purged yet, and returns
+	// it. It returns ErrNotFound if there is no such store.
+	UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error)
+
+	// PurgeDeletedStores permanently deletes at most `limit` stores that were deleted before `deletedBefore`,
+	// along with their tuples, changelog, authorization models, assertions and metadata, and returns the number
+	// of stores purged.
+	PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
+
 	GetStore(ctx context.Context, id string) (*openfgav1.Store, error)
 	ListStores(ctx context.Context, paginationOptions PaginationOptions) ([]*openfgav1.Store, []byte, error)
+
+	// ListStoresWithFilter is like ListStores, but only the stores selected by the filter are listed, in the
+	// order of the filter. The continuation tokens of ListStores and of ListStoresWithFilter are only valid for
+	// the same filter.
+	ListStoresWithFilter(ctx context.Context, filter ListStoresFilter, paginationOptions PaginationOptions) ([]*openfgav1.Store, []byte, error)
+
+	// WriteStoreMetadata replaces the metadata of a store and updates the time it was last updated. It returns
+	// ErrNotFound if the store does not exist.
+	WriteStoreMetadata(ctx context.Context, id string, metadata *StoreMetadata) error
+
+	// ReadStoreMetadata returns the metadata of a store, which is empty if it was never written. It returns
+	// ErrNotFound if the store does not exist.
+	ReadStoreMetadata(ctx context.Context, id string) (*StoreMetadata, error)
 }
 
 type AssertionsBackend interface {
@@ -168,20 +468,145 @@ type AssertionsBackend interface {
 	ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error)
 }
 
+// ReadChangesFilter specifies the changes returned by ReadChangesWithFilter. Every field is optional.
+type ReadChangesFilter struct {
+	ObjectType string
+	Relation   string
+
+	// Operation matches either the writes or the deletes of tuples.
+	Operation *openfgav1.TupleOperation
+
+	// StartTime matches the changes that occurred at or after it.
+	StartTime time.Time
+}
+
 type ChangelogBackend interface {
 
 	// ReadChanges returns the writes and deletes that have occurred for tuples of a given object type within a store.
 	// The horizonOffset should be specified using a unit no more granular than a millisecond and should be interpreted
 	// as a millisecond duration.
 	ReadChanges(ctx context.Context, store, objectType string, paginationOptions PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error)
+
+	// ReadChangesWithFilter is like ReadChanges, but the changes are matched by a ReadChangesFilter. The continuation
+	// tokens are bound to the object type of the filter, like those of ReadChanges.
+	ReadChangesWithFilter(ctx context.Context, store string, filter ReadChangesFilter, paginationOptions PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error)
+
+	// DeleteChanges deletes at most `limit` of the changes that occurred before `before` in the provided store, or
+	// in every store if it is empty, and returns the number of changes deleted. The oldest changes are deleted
+	// first. The continuation tokens returned by ReadChanges remain valid: reading from a token whose changes
+	// were deleted continues with the oldest change left.
+	DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error)
+}
+
+// TupleExpirationBackend provides an interface for managing tuples that expire. Expired tuples are
+// never returned by the reads of the TupleBackend, and they are eventually deleted by DeleteExpiredTuples.
+type TupleExpirationBackend interface {
+
+	// WriteWithExpiry is like Write, but the tuples in `w` expire at `expiresAt`. Once expired, a tuple
+	// behaves as if it had been deleted: it can't be deleted anymore and it can be written again.
+	WriteWithExpiry(ctx context.Context, store string, d Deletes, w Writes, expiresAt time.Time, opts ...TupleWriteOption) error
+
+	// DeleteExpiredTuples deletes at most `limit` expired tuples across every store, recording the
+	// deletes in the changelog, and returns the number of tuples deleted.
+	DeleteExpiredTuples(ctx context.Context, limit int) (int, error)
+
+	// ReadTupleExpirations returns the expiration times of the unexpired tuples matching the filter that
+	// were written with one, keyed by tuple.TupleKeyToString. The filter is interpreted as in
+	// ReadTupleConditions.
+	ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error)
+}
+
+// TupleCondition is a condition attached to a tuple when it is written. A conditional tuple is only
+// considered by Check and ListObjects when its condition is satisfied, see package condition.
+type TupleCondition struct {
+	// Expression is a CEL expression that must evaluate to a bool.
+	Expression string
+
+	// Parameters are the values written with the tuple that the expression can reference.
+	Parameters *structpb.Struct
+}
+
+// TupleConditionBackend provides an interface for managing conditional tuples. The reads of the
+// TupleBackend return conditional tuples like any other tuple: it is up to the caller to look up and
+// evaluate their conditions.
+type TupleConditionBackend interface {
+
+	// WriteWithCondition is like Write, but the tuples in `w` are written with the condition `condition`.
+	WriteWithCondition(ctx context.Context, store string, d Deletes, w Writes, condition *TupleCondition, opts ...TupleWriteOption) error
+
+	// ReadTupleConditions returns the conditions of the conditional tuples matching the filter, keyed by
+	// tuple.TupleKeyToString. The object of the filter may be an object type followed by a colon (e.g.
+	// 'document:') to match every object of the type. The object, relation and user are optional.
+	ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*TupleCondition, error)
+}
+
+// StagedWriteBackend provides an interface for writing atomically more tuples than MaxTuplesPerWrite: the deletes and
+// writes are staged in batches of at most MaxTuplesPerWrite tuples, and then committed all at once.
+type StagedWriteBackend interface {
+
+	// StageWrite adds the deletes and writes to the staged write `id` of the store, which is created by its first
+	// batch. The staged tuples are neither validated nor read until the staged write is committed.
+	StageWrite(ctx context.Context, store, id string, d Deletes, w Writes) error
+
+	// CommitStagedWrite applies the deletes and writes of every batch of the staged write like a single Write, without
+	// the MaxTuplesPerWrite limit: every delete is applied before the writes, and either every tuple is applied or
+	// none is. The staged write is discarded once committed, and kept if the commit fails.
+	CommitStagedWrite(ctx context.Context, store, id string, opts ...TupleWriteOption) error
+
+	// DiscardStagedWrite discards the batches of the staged write. Discarding a staged write that does not exist is
+	// not an error.
+	DiscardStagedWrite(ctx context.Context, store, id string) error
+}
+
+// SnapshotReader reads the tuples of a store, and their conditions, from a snapshot of the store. It must be closed
+// once done with to release the snapshot.
+type SnapshotReader interface {
+	RelationshipTupleReader
+	ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*TupleCondition, error)
+	Close()
+}
+
+// SnapshotBackend provides an interface for reading the tuples of a store consistently across several reads.
+type SnapshotBackend interface {
+
+	// Snapshot returns a reader whose reads of the store all observe the same state of its tuples: the writes
+	// committed after the snapshot was taken are not observed. The snapshot is taken at the latest by the first read.
+	// The reader may be bound to the context, in which case its reads fail once the context is done.
+	Snapshot(ctx context.Context, store string) (SnapshotReader, error)
+}
+
+// StoreStats are the sizes of the data of a store.
+type StoreStats struct {
+	// Tuples is the number of unexpired tuples of the store per object type and relation, keyed by
+	// 'objectType#relation'.
+	Tuples map[string]int64
+
+	// AuthorizationModels is the number of authorization model versions of the store.
+	AuthorizationModels int64
+
+	// Changes is the number of changes in the changelog of the store.
+	Changes int64
+}
+
+// StoreStatsBackend provides an interface for reading the sizes of the data of a store, aggregated by the datastore
+// rather than by reading every tuple and change.
+type StoreStatsBackend interface {
+
+	// ReadStoreStats returns the StoreStats of the store, which are empty if the store has no data.
+	ReadStoreStats(ctx context.Context, store string) (*StoreStats, error)
 }
 
 type OpenFGADatastore interface {
 	TupleBackend
+	TupleExpirationBackend
+	TupleConditionBackend
+	StagedWriteBackend
+	SnapshotBackend
 	AuthorizationModelBackend
 	StoresBackend
 	AssertionsBackend
 	ChangelogBackend
+	StoreStatsBackend
 
 	// IsReady reports whether the datastore is ready to accept traffic.
 	IsReady(ctx context.Context) (bool, error)
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -46,3 +49,82 @@ func TestNewPaginationOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestStaleReadsAllowed(t *testing.T) {
+	ctx := context.Background()
+	require.True(t, StaleReadsAllowed(ctx, time.Second))
+	require.False(t, StaleReadsAllowed(ContextWithStrongConsistency(ctx), time.Second))
+
+	ctx, err := ContextWithConsistencyToken(ctx, ulid.Make().String())
+	require.NoError(t, err)
+	require.False(t, StaleReadsAllowed(ctx, time.Second))
+	require.True(t, StaleReadsAllowed(ctx, -time.Second))
+
+	_, err = ContextWithConsistencyToken(ctx, ulid.MustNew(ulid.Timestamp(time.Now().Add(time.Hour)), nil).String())
+	require.ErrorIs(t, err, ErrInvalidConsistencyToken)
+}
+
+func TestRegister(t *testing.T) {
+	_, ok := LookupFactory("test_engine")
+	require.False(t, ok)
+
+	var received *DatastoreConfig
+	Register("test_engine", func(cfg *DatastoreConfig) (OpenFGADatastore, error) {
+		received = cfg
+		return nil, nil
+	})
+	t.Cleanup(func() {
+		factoriesMu.Lock()
+		defer factoriesMu.Unlock()
+		delete(factories, "test_engine")
+	})
+
+	factory, ok := LookupFactory("test_engine")
+	require.True(t, ok)
+	require.Contains(t, RegisteredEngines(), "test_engine")
+
+	cfg := &DatastoreConfig{URI: "test://localhost"}
+	_, err := factory(cfg)
+	require.NoError(t, err)
+	require.Same(t, cfg, received)
+
+	require.Panics(t, func() {
+		Register("test_engine", func(*DatastoreConfig) (OpenFGADatastore, error) { return nil, nil })
+	})
+	require.Panics(t, func() {
+		Register("postgres", func(*DatastoreConfig) (OpenFGADatastore, error) { return nil, nil })
+	})
+	require.Panics(t, func() {
+		Register("other_engine", nil)
+	})
+}
+
+func TestNextChangeULID(t *testing.T) {
+	now := time.Now()
+
+	require.Len(t, NextChangeULID(now, ""), 26)
+
+	// a change of the same millisecond as the latest one is ordered after it
+	latest := ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
+	next := NextChangeULID(now, latest)
+	require.Greater(t, next, latest)
+
+	// and so is a change of an earlier millisecond, e.g. read before waiting on a lock
+	require.Greater(t, NextChangeULID(now.Add(-time.Second), next), next)
+
+	require.Greater(t, NextChangeULID(now.Add(time.Second), next), next)
+}
+
+func TestContinuationTokenTime(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	id := ulid.MustNew(ulid.Timestamp(now), ulid.DefaultEntropy()).String()
+
+	for _, token := range []string{id + "|", id + "|document", `{"ulid":"` + id + `","ObjectType":""}`} {
+		tokenTime, ok := ContinuationTokenTime(token)
+		require.True(t, ok, token)
+		require.True(t, now.Equal(tokenTime), token)
+	}
+
+	_, ok := ContinuationTokenTime("3|document")
+	require.False(t, ok)
+}
@@ -0,0 +1,167 @@
+package storagewrappers
+
+import (
+	"context"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/condition"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// ConditionalTupleReader is a storage.RelationshipTupleReader that can read the conditions of the tuples.
+type ConditionalTupleReader interface {
+	storage.RelationshipTupleReader
+	ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error)
+}
+
+var _ storage.RelationshipTupleReader = (*conditionEvaluatingTupleReader)(nil)
+
+type conditionEvaluatingTupleReader struct {
+	storage.RelationshipTupleReader
+	conditions ConditionalTupleReader
+
+	mu       sync.Mutex
+	compiled map[string]*condition.Condition
+}
+
+// NewConditionEvaluatingTupleReader returns a wrapper over a datastore whose Read, ReadUserTuple, ReadUsersetTuples
+// and ReadStartingWithUser only return the conditional tuples whose condition is satisfied. The conditions are
+// evaluated with the request context carried by the context of the read, see condition.NewContext. A condition
+// that can't be evaluated fails the read.
+func NewConditionEvaluatingTupleReader(ds ConditionalTupleReader) storage.RelationshipTupleReader {
+	return &conditionEvaluatingTupleReader{
+		RelationshipTupleReader: ds,
+		conditions:              ds,
+		compiled:                map[string]*condition.Condition{},
+	}
+}
+
+func (c *conditionEvaluatingTupleReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	iter, err := c.RelationshipTupleReader.Read(ctx, store, tk)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.filter(ctx, store, &openfgav1.TupleKey{Object: tk.GetObject(), Relation: tk.GetRelation(), User: tk.GetUser()}, iter)
+}
+
+func (c *conditionEvaluatingTupleReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	t, err := c.RelationshipTupleReader.ReadUserTuple(ctx, store, tk)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := c.conditions.ReadTupleConditions(ctx, store, t.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	satisfied, err := c.satisfied(ctx, conditions, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if !satisfied {
+		return nil, storage.ErrNotFound
+	}
+
+	return t, nil
+}
+
+func (c *conditionEvaluatingTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	iter, err := c.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.filter(ctx, store, &openfgav1.TupleKey{Object: filter.Object, Relation: filter.Relation}, iter)
+}
+
+func (c *conditionEvaluatingTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	iter, err := c.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.filter(ctx, store, &openfgav1.TupleKey{Object: tuple.BuildObject(filter.ObjectType, ""), Relation: filter.Relation}, iter)
+}
+
+// filter returns an iterator over the tuples of iter that are not conditional or whose condition is satisfied.
+// The conditions are read with the conditionsFilter, which must match every tuple of iter.
+func (c *conditionEvaluatingTupleReader) filter(ctx context.Context, store string, conditionsFilter *openfgav1.TupleKey, iter storage.TupleIterator) (storage.TupleIterator, error) {
+	conditions, err := c.conditions.ReadTupleConditions(ctx, store, conditionsFilter)
+	if err != nil {
+		iter.Stop()
+		return nil, err
+	}
+
+	if len(conditions) == 0 {
+		return iter, nil
+	}
+
+	return &conditionFilteredTupleIterator{ctx: ctx, reader: c, conditions: conditions, iter: iter}, nil
+}
+
+// satisfied reports whether the tuple is not conditional or its condition is satisfied.
+func (c *conditionEvaluatingTupleReader) satisfied(ctx context.Context, conditions map[string]*storage.TupleCondition, t *openfgav1.Tuple) (bool, error) {
+	tc, ok := conditions[tuple.TupleKeyToString(t.GetKey())]
+	if !ok {
+		return true, nil
+	}
+
+	compiled, err := c.compile(tc.Expression)
+	if err != nil {
+		return false, err
+	}
+
+	return compiled.Evaluate(tc.Parameters, condition.FromContext(ctx))
+}
+
+func (c *conditionEvaluatingTupleReader) compile(expression string) (*condition.Condition, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if compiled, ok := c.compiled[expression]; ok {
+		return compiled, nil
+	}
+
+	compiled, err := condition.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	c.compiled[expression] = compiled
+
+	return compiled, nil
+}
+
+type conditionFilteredTupleIterator struct {
+	ctx        context.Context
+	reader     *conditionEvaluatingTupleReader
+	conditions map[string]*storage.TupleCondition
+	iter       storage.TupleIterator
+}
+
+func (f *conditionFilteredTupleIterator) Next() (*openfgav1.Tuple, error) {
+	for {
+		t, err := f.iter.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		satisfied, err := f.reader.satisfied(f.ctx, f.conditions, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if satisfied {
+			return t, nil
+		}
+	}
+}
+
+func (f *conditionFilteredTupleIterator) Stop() {
+	f.iter.Stop()
+}
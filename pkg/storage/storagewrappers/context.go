@@ -5,6 +5,7 @@ import (
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/tenancy"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -25,10 +26,11 @@ func NewContextWrapper(inner storage.OpenFGADatastore) *ContextTracerWrapper {
 }
 
 // queryContext returns a new context (not a child context) with a timeout and
-// the same span data as the supplied context.
+// the same span data, tenant and tuple expirations as the supplied context.
 func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
 	span := trace.SpanFromContext(ctx)
-	return trace.ContextWithSpan(context.Background(), span), func() {}
+	queryCtx := tenancy.WithTenantOf(trace.ContextWithSpan(context.Background(), span), ctx)
+	return storage.WithTupleExpirationsOf(queryCtx, ctx), func() {}
 }
 
 func (c *ContextTracerWrapper) Close() {
@@ -49,6 +51,13 @@ func (c *ContextTracerWrapper) ReadPage(ctx context.Context, store string, tuple
 	return c.OpenFGADatastore.ReadPage(queryCtx, store, tupleKey, opts)
 }
 
+func (c *ContextTracerWrapper) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.OpenFGADatastore.ReadPageWithFilter(queryCtx, store, filter, opts)
+}
+
 func (c *ContextTracerWrapper) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
 	queryCtx, cancel := queryContext(ctx)
 	defer cancel()
@@ -69,3 +78,55 @@ func (c *ContextTracerWrapper) ReadStartingWithUser(ctx context.Context, store s
 
 	return c.OpenFGADatastore.ReadStartingWithUser(queryCtx, store, opts)
 }
+
+func (c *ContextTracerWrapper) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	snapshot, err := c.OpenFGADatastore.Snapshot(queryCtx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contextSnapshotReader{snapshot}, nil
+}
+
+// contextSnapshotReader passes a new context to the reads of a snapshot, like ContextTracerWrapper does.
+type contextSnapshotReader struct {
+	storage.SnapshotReader
+}
+
+func (c *contextSnapshotReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.SnapshotReader.Read(queryCtx, store, tupleKey)
+}
+
+func (c *contextSnapshotReader) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.SnapshotReader.ReadPage(queryCtx, store, tupleKey, opts)
+}
+
+func (c *contextSnapshotReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.SnapshotReader.ReadUserTuple(queryCtx, store, tupleKey)
+}
+
+func (c *contextSnapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.SnapshotReader.ReadUsersetTuples(queryCtx, store, filter)
+}
+
+func (c *contextSnapshotReader) ReadStartingWithUser(ctx context.Context, store string, opts storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	queryCtx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return c.SnapshotReader.ReadStartingWithUser(queryCtx, store, opts)
+}
@@ -0,0 +1,543 @@
+package storagewrappers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encrypter"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// EncryptingDatastore is a wrapper around a datastore that encrypts the users of the tuples it writes, and decrypts
+// the users of the tuples it reads, so that the subject identifiers are not stored in plaintext. Only the ID of the
+// user is encrypted: its type and the relation of a userset are kept, as the datastores select the tuples by them,
+// and the wildcards are not encrypted.
+//
+// The users are looked up by equality, so the encrypter must be deterministic (see
+// encrypter.NewDeterministicEncrypter). The tuples written without encryption can't be read once it is enabled, and
+// the encrypted IDs, encoded in base64, are about 4/3 of the length of the IDs plus 38 characters long, which must
+// fit in the user column of the datastore.
+type EncryptingDatastore struct {
+	storage.OpenFGADatastore
+	encrypter encrypter.Encrypter
+}
+
+var _ storage.OpenFGADatastore = (*EncryptingDatastore)(nil)
+
+func NewEncryptingDatastore(inner storage.OpenFGADatastore, e encrypter.Encrypter) *EncryptingDatastore {
+	return &EncryptingDatastore{
+		OpenFGADatastore: inner,
+		encrypter:        e,
+	}
+}
+
+// userEncrypter encrypts and decrypts the IDs of the users of the tuples.
+type userEncrypter struct {
+	encrypter encrypter.Encrypter
+}
+
+// splitUser splits a user into the prefix kept in plaintext (its type and a colon) and its ID, followed by the
+// relation of a userset. The ID of a wildcard is empty.
+func splitUser(user string) (prefix, id, relation string) {
+	if tuple.IsWildcard(user) {
+		return user, "", ""
+	}
+
+	object, relation := tuple.SplitObjectRelation(user)
+	if i := strings.Index(object, ":"); i >= 0 {
+		prefix, id = object[:i+1], object[i+1:]
+	} else {
+		id = object
+	}
+
+	if relation != "" {
+		relation = "#" + relation
+	}
+
+	return prefix, id, relation
+}
+
+func (u userEncrypter) encryptUser(user string) (string, error) {
+	prefix, id, relation := splitUser(user)
+	if id == "" {
+		return user, nil
+	}
+
+	encrypted, err := u.encrypter.Encrypt([]byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt the user '%s': %w", user, err)
+	}
+
+	return prefix + base64.RawURLEncoding.EncodeToString(encrypted) + relation, nil
+}
+
+func (u userEncrypter) decryptUser(user string) (string, error) {
+	prefix, id, relation := splitUser(user)
+	if id == "" {
+		return user, nil
+	}
+
+	encrypted, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the user '%s': %w", user, err)
+	}
+
+	decrypted, err := u.encrypter.Decrypt(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the user '%s': %w", user, err)
+	}
+
+	return prefix + string(decrypted) + relation, nil
+}
+
+// encryptKey returns a copy of the tuple key with an encrypted user. The key is returned as is if it has no user.
+func (u userEncrypter) encryptKey(tk *openfgav1.TupleKey) (*openfgav1.TupleKey, error) {
+	if tk.GetUser() == "" {
+		return tk, nil
+	}
+
+	user, err := u.encryptUser(tk.GetUser())
+	if err != nil {
+		return nil, err
+	}
+
+	return &openfgav1.TupleKey{Object: tk.GetObject(), Relation: tk.GetRelation(), User: user}, nil
+}
+
+func (u userEncrypter) encryptKeys(tks []*openfgav1.TupleKey) ([]*openfgav1.TupleKey, error) {
+	if len(tks) == 0 {
+		return tks, nil
+	}
+
+	encrypted := make([]*openfgav1.TupleKey, 0, len(tks))
+	for _, tk := range tks {
+		etk, err := u.encryptKey(tk)
+		if err != nil {
+			return nil, err
+		}
+		encrypted = append(encrypted, etk)
+	}
+
+	return encrypted, nil
+}
+
+func (u userEncrypter) decryptKey(tk *openfgav1.TupleKey) (*openfgav1.TupleKey, error) {
+	if tk.GetUser() == "" {
+		return tk, nil
+	}
+
+	user, err := u.decryptUser(tk.GetUser())
+	if err != nil {
+		return nil, err
+	}
+
+	return &openfgav1.TupleKey{Object: tk.GetObject(), Relation: tk.GetRelation(), User: user}, nil
+}
+
+func (u userEncrypter) decryptTuple(t *openfgav1.Tuple) (*openfgav1.Tuple, error) {
+	tk, err := u.decryptKey(t.GetKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return &openfgav1.Tuple{Key: tk, Timestamp: t.GetTimestamp()}, nil
+}
+
+func (u userEncrypter) decryptTuples(tuples []*openfgav1.Tuple) ([]*openfgav1.Tuple, error) {
+	if len(tuples) == 0 {
+		return tuples, nil
+	}
+
+	decrypted := make([]*openfgav1.Tuple, 0, len(tuples))
+	for _, t := range tuples {
+		dt, err := u.decryptTuple(t)
+		if err != nil {
+			return nil, err
+		}
+		decrypted = append(decrypted, dt)
+	}
+
+	return decrypted, nil
+}
+
+// decryptKeyed returns the map with its tuple keys, as returned by tuple.TupleKeyToString, decrypted.
+func decryptKeyed[T any](u userEncrypter, keyed map[string]T) (map[string]T, error) {
+	decrypted := make(map[string]T, len(keyed))
+	for key, value := range keyed {
+		// the key is 'object#relation@user', and only the user may have a '#' or an '@'
+		object, rest, _ := strings.Cut(key, "#")
+		relation, user, _ := strings.Cut(rest, "@")
+
+		tk, err := u.decryptKey(tuple.NewTupleKey(object, relation, user))
+		if err != nil {
+			return nil, err
+		}
+
+		decrypted[tuple.TupleKeyToString(tk)] = value
+	}
+
+	return decrypted, nil
+}
+
+func (u userEncrypter) encryptReadFilter(filter storage.ReadFilter) (storage.ReadFilter, error) {
+	if filter.User == "" {
+		return filter, nil
+	}
+
+	user, err := u.encryptUser(filter.User)
+	if err != nil {
+		return storage.ReadFilter{}, err
+	}
+
+	filter.User = user
+	return filter, nil
+}
+
+func (u userEncrypter) encryptStartingWithUserFilter(filter storage.ReadStartingWithUserFilter) (storage.ReadStartingWithUserFilter, error) {
+	users := make([]*openfgav1.ObjectRelation, 0, len(filter.UserFilter))
+	for _, user := range filter.UserFilter {
+		object, err := u.encryptUser(user.GetObject())
+		if err != nil {
+			return storage.ReadStartingWithUserFilter{}, err
+		}
+		users = append(users, &openfgav1.ObjectRelation{Object: object, Relation: user.GetRelation()})
+	}
+
+	filter.UserFilter = users
+	return filter, nil
+}
+
+func (u userEncrypter) decryptChanges(changes []*openfgav1.TupleChange) ([]*openfgav1.TupleChange, error) {
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	decrypted := make([]*openfgav1.TupleChange, 0, len(changes))
+	for _, change := range changes {
+		tk, err := u.decryptKey(change.GetTupleKey())
+		if err != nil {
+			return nil, err
+		}
+
+		decrypted = append(decrypted, &openfgav1.TupleChange{
+			TupleKey:  tk,
+			Operation: change.GetOperation(),
+			Timestamp: change.GetTimestamp(),
+		})
+	}
+
+	return decrypted, nil
+}
+
+// decryptingIterator decrypts the users of the tuples of an iterator.
+type decryptingIterator struct {
+	iter      storage.TupleIterator
+	encrypter userEncrypter
+}
+
+var _ storage.TupleIterator = (*decryptingIterator)(nil)
+
+func (d *decryptingIterator) Next() (*openfgav1.Tuple, error) {
+	t, err := d.iter.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.encrypter.decryptTuple(t)
+}
+
+// NextKey returns the key of the next tuple, without building the tuple if the iterator can do so.
+func (d *decryptingIterator) NextKey() (*openfgav1.TupleKey, error) {
+	var tk *openfgav1.TupleKey
+	var err error
+	if nexter, ok := d.iter.(interface {
+		NextKey() (*openfgav1.TupleKey, error)
+	}); ok {
+		tk, err = nexter.NextKey()
+	} else {
+		var t *openfgav1.Tuple
+		t, err = d.iter.Next()
+		tk = t.GetKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return d.encrypter.decryptKey(tk)
+}
+
+func (d *decryptingIterator) Stop() {
+	d.iter.Stop()
+}
+
+// encryptingTupleReader encrypts the users of the tuple reads of a reader, and decrypts the users of the tuples read.
+type encryptingTupleReader struct {
+	reader    storage.SnapshotReader
+	encrypter userEncrypter
+}
+
+func (e *encryptingTupleReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	etk, err := e.encrypter.encryptKey(tk)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := e.reader.Read(ctx, store, etk)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingIterator{iter: iter, encrypter: e.encrypter}, nil
+}
+
+func (e *encryptingTupleReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	etk, err := e.encrypter.encryptKey(tk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tuples, contToken, err := e.reader.ReadPage(ctx, store, etk, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tuples, err = e.encrypter.decryptTuples(tuples)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tuples, contToken, nil
+}
+
+func (e *encryptingTupleReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	etk, err := e.encrypter.encryptKey(tk)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := e.reader.ReadUserTuple(ctx, store, etk)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.encrypter.decryptTuple(t)
+}
+
+func (e *encryptingTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	iter, err := e.reader.ReadUsersetTuples(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingIterator{iter: iter, encrypter: e.encrypter}, nil
+}
+
+func (e *encryptingTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	filter, err := e.encrypter.encryptStartingWithUserFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := e.reader.ReadStartingWithUser(ctx, store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingIterator{iter: iter, encrypter: e.encrypter}, nil
+}
+
+func (e *encryptingTupleReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	efilter, err := e.encrypter.encryptKey(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := e.reader.ReadTupleConditions(ctx, store, efilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptKeyed(e.encrypter, conditions)
+}
+
+func (e *encryptingTupleReader) Close() {
+	e.reader.Close()
+}
+
+func (e *EncryptingDatastore) users() userEncrypter {
+	return userEncrypter{encrypter: e.encrypter}
+}
+
+// reader returns the tuple reads of the datastore, with the users encrypted.
+func (e *EncryptingDatastore) reader() *encryptingTupleReader {
+	return &encryptingTupleReader{reader: e.OpenFGADatastore, encrypter: e.users()}
+}
+
+func (e *EncryptingDatastore) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return e.reader().Read(ctx, store, tk)
+}
+
+func (e *EncryptingDatastore) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return e.reader().ReadPage(ctx, store, tk, opts)
+}
+
+func (e *EncryptingDatastore) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	filter, err := e.users().encryptReadFilter(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tuples, contToken, err := e.OpenFGADatastore.ReadPageWithFilter(ctx, store, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tuples, err = e.users().decryptTuples(tuples)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tuples, contToken, nil
+}
+
+func (e *EncryptingDatastore) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	return e.reader().ReadUserTuple(ctx, store, tk)
+}
+
+func (e *EncryptingDatastore) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	return e.reader().ReadUsersetTuples(ctx, store, filter)
+}
+
+func (e *EncryptingDatastore) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	return e.reader().ReadStartingWithUser(ctx, store, filter)
+}
+
+func (e *EncryptingDatastore) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	return e.reader().ReadTupleConditions(ctx, store, filter)
+}
+
+func (e *EncryptingDatastore) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	efilter, err := e.users().encryptKey(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	expirations, err := e.OpenFGADatastore.ReadTupleExpirations(ctx, store, efilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptKeyed(e.users(), expirations)
+}
+
+func (e *EncryptingDatastore) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	snapshot, err := e.OpenFGADatastore.Snapshot(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptingTupleReader{reader: snapshot, encrypter: e.users()}, nil
+}
+
+func (e *EncryptingDatastore) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	deletes, writes, err := e.encryptWrite(deletes, writes)
+	if err != nil {
+		return err
+	}
+
+	return e.decryptWriteError(e.OpenFGADatastore.Write(ctx, store, deletes, writes, opts...))
+}
+
+func (e *EncryptingDatastore) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	deletes, writes, err := e.encryptWrite(deletes, writes)
+	if err != nil {
+		return err
+	}
+
+	return e.decryptWriteError(e.OpenFGADatastore.WriteWithExpiry(ctx, store, deletes, writes, expiresAt, opts...))
+}
+
+func (e *EncryptingDatastore) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	deletes, writes, err := e.encryptWrite(deletes, writes)
+	if err != nil {
+		return err
+	}
+
+	return e.decryptWriteError(e.OpenFGADatastore.WriteWithCondition(ctx, store, deletes, writes, condition, opts...))
+}
+
+func (e *EncryptingDatastore) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	deletes, writes, err := e.encryptWrite(deletes, writes)
+	if err != nil {
+		return err
+	}
+
+	return e.OpenFGADatastore.StageWrite(ctx, store, id, deletes, writes)
+}
+
+func (e *EncryptingDatastore) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	return e.decryptWriteError(e.OpenFGADatastore.CommitStagedWrite(ctx, store, id, opts...))
+}
+
+// decryptWriteError decrypts the user of the tuple of an InvalidWriteInput error, which is returned to the client.
+func (e *EncryptingDatastore) decryptWriteError(err error) error {
+	var invalid *storage.InvalidWriteInput
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	tk, decryptErr := e.users().decryptKey(invalid.TupleKey)
+	if decryptErr != nil {
+		return err
+	}
+
+	return storage.InvalidWriteInputError(tk, invalid.Operation)
+}
+
+func (e *EncryptingDatastore) encryptWrite(deletes storage.Deletes, writes storage.Writes) (storage.Deletes, storage.Writes, error) {
+	deletes, err := e.users().encryptKeys(deletes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	writes, err = e.users().encryptKeys(writes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return deletes, writes, nil
+}
+
+func (e *EncryptingDatastore) ReadChanges(ctx context.Context, store, objectType string, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	changes, contToken, err := e.OpenFGADatastore.ReadChanges(ctx, store, objectType, opts, horizonOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes, err = e.users().decryptChanges(changes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return changes, contToken, nil
+}
+
+func (e *EncryptingDatastore) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	changes, contToken, err := e.OpenFGADatastore.ReadChangesWithFilter(ctx, store, filter, opts, horizonOffset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes, err = e.users().decryptChanges(changes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return changes, contToken, nil
+}
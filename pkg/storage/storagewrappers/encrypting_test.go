@@ -0,0 +1,62 @@
+package storagewrappers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/encrypter"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/test"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptingDatastore(t *testing.T) {
+	e, err := encrypter.NewDeterministicEncrypter("key")
+	require.NoError(t, err)
+
+	test.RunAllTests(t, NewEncryptingDatastore(memory.New(), e))
+}
+
+func TestEncryptingDatastoreStoresEncryptedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	e, err := encrypter.NewDeterministicEncrypter("key")
+	require.NoError(t, err)
+
+	inner := memory.New()
+	ds := NewEncryptingDatastore(inner, e)
+
+	storeID := ulid.Make().String()
+	tks := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:jon@example.com"),
+		tuple.NewTupleKey("document:1", "viewer", "group:eng#member"),
+		tuple.NewTupleKey("document:1", "viewer", "user:*"),
+	}
+	err = ds.Write(ctx, storeID, nil, tks)
+	require.NoError(t, err)
+
+	stored, _, err := inner.ReadPage(ctx, storeID, &openfgav1.TupleKey{}, storage.PaginationOptions{PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+
+	require.True(t, strings.HasPrefix(stored[0].GetKey().GetUser(), "user:"))
+	require.NotContains(t, stored[0].GetKey().GetUser(), "jon")
+	require.True(t, strings.HasPrefix(stored[1].GetKey().GetUser(), "group:"))
+	require.True(t, strings.HasSuffix(stored[1].GetKey().GetUser(), "#member"))
+	require.NotContains(t, stored[1].GetKey().GetUser(), "eng")
+	require.Equal(t, "user:*", stored[2].GetKey().GetUser())
+
+	_, err = ds.ReadUserTuple(ctx, storeID, tks[0])
+	require.NoError(t, err)
+
+	tuples, _, err := ds.ReadPage(ctx, storeID, &openfgav1.TupleKey{}, storage.PaginationOptions{PageSize: 10})
+	require.NoError(t, err)
+	for i, tp := range tuples {
+		require.Equal(t, tks[i].GetUser(), tp.GetKey().GetUser())
+	}
+}
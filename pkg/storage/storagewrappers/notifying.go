@@ -0,0 +1,53 @@
+package storagewrappers
+
+import (
+	"context"
+	"time"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// NotifyingDatastore is a wrapper around a datastore that notifies a function of the stores whose tuples
+// are changed, once the changes are committed, e.g. to export them without waiting for the next poll of
+// the changelog.
+type NotifyingDatastore struct {
+	storage.OpenFGADatastore
+	notify func(storeID string)
+}
+
+var _ storage.OpenFGADatastore = (*NotifyingDatastore)(nil)
+
+func NewNotifyingDatastore(inner storage.OpenFGADatastore, notify func(storeID string)) *NotifyingDatastore {
+	return &NotifyingDatastore{
+		OpenFGADatastore: inner,
+		notify:           notify,
+	}
+}
+
+func (n *NotifyingDatastore) Close() {
+	n.OpenFGADatastore.Close()
+}
+
+func (n *NotifyingDatastore) notifyIfCommitted(store string, err error) error {
+	if err == nil {
+		n.notify(store)
+	}
+
+	return err
+}
+
+func (n *NotifyingDatastore) Write(ctx context.Context, store string, d storage.Deletes, w storage.Writes, opts ...storage.TupleWriteOption) error {
+	return n.notifyIfCommitted(store, n.OpenFGADatastore.Write(ctx, store, d, w, opts...))
+}
+
+func (n *NotifyingDatastore) WriteWithExpiry(ctx context.Context, store string, d storage.Deletes, w storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	return n.notifyIfCommitted(store, n.OpenFGADatastore.WriteWithExpiry(ctx, store, d, w, expiresAt, opts...))
+}
+
+func (n *NotifyingDatastore) WriteWithCondition(ctx context.Context, store string, d storage.Deletes, w storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	return n.notifyIfCommitted(store, n.OpenFGADatastore.WriteWithCondition(ctx, store, d, w, condition, opts...))
+}
+
+func (n *NotifyingDatastore) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	return n.notifyIfCommitted(store, n.OpenFGADatastore.CommitStagedWrite(ctx, store, id, opts...))
+}
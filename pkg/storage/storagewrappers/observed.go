@@ -0,0 +1,502 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// DatastoreObserver is notified of the latency and outcome of every datastore call
+// made through an ObservedOpenFGADatastore.
+type DatastoreObserver interface {
+	ObserveDatastoreCall(latency time.Duration, err error)
+}
+
+// ObservedOpenFGADatastore is a wrapper around a datastore that reports the latency
+// and the errors of the underlying datastore calls to a DatastoreObserver. The latency
+// of the calls returning an iterator includes the time spent iterating over it.
+//
+// The datastore is not embedded, so that every method of storage.OpenFGADatastore must
+// be wrapped explicitly.
+type ObservedOpenFGADatastore struct {
+	inner    storage.OpenFGADatastore
+	observer DatastoreObserver
+}
+
+var _ storage.OpenFGADatastore = (*ObservedOpenFGADatastore)(nil)
+
+func NewObservedOpenFGADatastore(inner storage.OpenFGADatastore, observer DatastoreObserver) *ObservedOpenFGADatastore {
+	return &ObservedOpenFGADatastore{
+		inner:    inner,
+		observer: observer,
+	}
+}
+
+// observe reports the outcome of a datastore call that started at the provided time.
+// Errors that are caused by the request rather than by the datastore are reported as successes.
+func (o *ObservedOpenFGADatastore) observe(start time.Time, err error) {
+	if errors.Is(err, storage.ErrNotFound) ||
+		errors.Is(err, storage.ErrCollision) ||
+		errors.Is(err, storage.ErrInvalidContinuationToken) ||
+		errors.Is(err, storage.ErrInvalidWriteInput) ||
+		errors.Is(err, storage.ErrTransactionalWriteFailed) ||
+		errors.Is(err, storage.ErrMismatchObjectType) ||
+		errors.Is(err, storage.ErrExceededWriteBatchLimit) ||
+		errors.Is(err, storage.ErrCancelled) ||
+		errors.Is(err, context.Canceled) {
+		err = nil
+	}
+
+	o.observer.ObserveDatastoreCall(time.Since(start), err)
+}
+
+// observeIterator returns an iterator over the tuples of iter which reports the outcome of the
+// datastore call that started at the provided time once the iteration is done, see observedTupleIterator.
+// If the call failed, it is reported right away.
+func (o *ObservedOpenFGADatastore) observeIterator(start time.Time, iter storage.TupleIterator, err error) (storage.TupleIterator, error) {
+	if err != nil {
+		o.observe(start, err)
+		return nil, err
+	}
+
+	return &observedTupleIterator{iter: iter, datastore: o, elapsed: time.Since(start)}, nil
+}
+
+func (o *ObservedOpenFGADatastore) Close() {
+	o.inner.Close()
+}
+
+func (o *ObservedOpenFGADatastore) IsReady(ctx context.Context) (bool, error) {
+	return o.inner.IsReady(ctx)
+}
+
+func (o *ObservedOpenFGADatastore) MaxTuplesPerWrite() int {
+	return o.inner.MaxTuplesPerWrite()
+}
+
+func (o *ObservedOpenFGADatastore) MaxTypesPerAuthorizationModel() int {
+	return o.inner.MaxTypesPerAuthorizationModel()
+}
+
+func (o *ObservedOpenFGADatastore) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.Read(ctx, store, tupleKey)
+
+	return o.observeIterator(start, iter, err)
+}
+
+func (o *ObservedOpenFGADatastore) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	start := time.Now()
+	tuples, token, err := o.inner.ReadPage(ctx, store, tupleKey, opts)
+	o.observe(start, err)
+
+	return tuples, token, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	start := time.Now()
+	tuples, token, err := o.inner.ReadPageWithFilter(ctx, store, filter, opts)
+	o.observe(start, err)
+
+	return tuples, token, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	start := time.Now()
+	tuple, err := o.inner.ReadUserTuple(ctx, store, tupleKey)
+	o.observe(start, err)
+
+	return tuple, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.ReadUsersetTuples(ctx, store, filter)
+
+	return o.observeIterator(start, iter, err)
+}
+
+func (o *ObservedOpenFGADatastore) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.ReadStartingWithUser(ctx, store, filter)
+
+	return o.observeIterator(start, iter, err)
+}
+
+func (o *ObservedOpenFGADatastore) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	start := time.Now()
+	err := o.inner.Write(ctx, store, deletes, writes, opts...)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	start := time.Now()
+	err := o.inner.WriteWithExpiry(ctx, store, deletes, writes, expiresAt, opts...)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	start := time.Now()
+	err := o.inner.WriteWithCondition(ctx, store, deletes, writes, condition, opts...)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	start := time.Now()
+	err := o.inner.StageWrite(ctx, store, id, deletes, writes)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	start := time.Now()
+	err := o.inner.CommitStagedWrite(ctx, store, id, opts...)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	start := time.Now()
+	err := o.inner.DiscardStagedWrite(ctx, store, id)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	start := time.Now()
+	conditions, err := o.inner.ReadTupleConditions(ctx, store, filter)
+	o.observe(start, err)
+
+	return conditions, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	start := time.Now()
+	expirations, err := o.inner.ReadTupleExpirations(ctx, store, filter)
+	o.observe(start, err)
+
+	return expirations, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadAuthorizationModel(ctx context.Context, store string, id string) (*openfgav1.AuthorizationModel, error) {
+	start := time.Now()
+	model, err := o.inner.ReadAuthorizationModel(ctx, store, id)
+	o.observe(start, err)
+
+	return model, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (storage.ModelAnnotations, error) {
+	start := time.Now()
+	annotations, err := o.inner.ReadAuthorizationModelAnnotations(ctx, store, id)
+	o.observe(start, err)
+
+	return annotations, err
+}
+
+func (o *ObservedOpenFGADatastore) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	start := time.Now()
+	id, err := o.inner.FindLatestAuthorizationModelID(ctx, store)
+	o.observe(start, err)
+
+	return id, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	start := time.Now()
+	id, err := o.inner.ReadPinnedAuthorizationModelID(ctx, store)
+	o.observe(start, err)
+
+	return id, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadChanges(ctx context.Context, store, objectType string, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	start := time.Now()
+	changes, token, err := o.inner.ReadChanges(ctx, store, objectType, opts, horizonOffset)
+	o.observe(start, err)
+
+	return changes, token, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	start := time.Now()
+	changes, token, err := o.inner.ReadChangesWithFilter(ctx, store, filter, opts, horizonOffset)
+	o.observe(start, err)
+
+	return changes, token, err
+}
+
+func (o *ObservedOpenFGADatastore) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
+	start := time.Now()
+	err := o.inner.WriteAuthorizationModel(ctx, store, model)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	start := time.Now()
+	err := o.inner.WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) ReadAuthorizationModels(ctx context.Context, store string, options storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
+	start := time.Now()
+	models, token, err := o.inner.ReadAuthorizationModels(ctx, store, options)
+	o.observe(start, err)
+
+	return models, token, err
+}
+
+func (o *ObservedOpenFGADatastore) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	start := time.Now()
+	err := o.inner.DeleteAuthorizationModel(ctx, store, id)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	start := time.Now()
+	err := o.inner.PinAuthorizationModel(ctx, store, id)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	start := time.Now()
+	err := o.inner.UnpinAuthorizationModel(ctx, store)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	start := time.Now()
+	created, err := o.inner.CreateStore(ctx, store)
+	o.observe(start, err)
+
+	return created, err
+}
+
+func (o *ObservedOpenFGADatastore) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+	start := time.Now()
+	store, err := o.inner.GetStore(ctx, id)
+	o.observe(start, err)
+
+	return store, err
+}
+
+func (o *ObservedOpenFGADatastore) ListStores(ctx context.Context, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	start := time.Now()
+	stores, token, err := o.inner.ListStores(ctx, paginationOptions)
+	o.observe(start, err)
+
+	return stores, token, err
+}
+
+func (o *ObservedOpenFGADatastore) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, paginationOptions storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	start := time.Now()
+	stores, token, err := o.inner.ListStoresWithFilter(ctx, filter, paginationOptions)
+	o.observe(start, err)
+
+	return stores, token, err
+}
+
+func (o *ObservedOpenFGADatastore) DeleteStore(ctx context.Context, id string) error {
+	start := time.Now()
+	err := o.inner.DeleteStore(ctx, id)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	start := time.Now()
+	store, err := o.inner.UndeleteStore(ctx, id, deletedAfter)
+	o.observe(start, err)
+
+	return store, err
+}
+
+func (o *ObservedOpenFGADatastore) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	start := time.Now()
+	purged, err := o.inner.PurgeDeletedStores(ctx, deletedBefore, limit)
+	o.observe(start, err)
+
+	return purged, err
+}
+
+func (o *ObservedOpenFGADatastore) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	start := time.Now()
+	metadata, err := o.inner.ReadStoreMetadata(ctx, id)
+	o.observe(start, err)
+
+	return metadata, err
+}
+
+func (o *ObservedOpenFGADatastore) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	start := time.Now()
+	err := o.inner.WriteStoreMetadata(ctx, id, metadata)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	start := time.Now()
+	stats, err := o.inner.ReadStoreStats(ctx, store)
+	o.observe(start, err)
+
+	return stats, err
+}
+
+func (o *ObservedOpenFGADatastore) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
+	start := time.Now()
+	err := o.inner.WriteAssertions(ctx, store, modelID, assertions)
+	o.observe(start, err)
+
+	return err
+}
+
+func (o *ObservedOpenFGADatastore) ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error) {
+	start := time.Now()
+	assertions, err := o.inner.ReadAssertions(ctx, store, modelID)
+	o.observe(start, err)
+
+	return assertions, err
+}
+
+func (o *ObservedOpenFGADatastore) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	start := time.Now()
+	deleted, err := o.inner.DeleteChanges(ctx, store, before, limit)
+	o.observe(start, err)
+
+	return deleted, err
+}
+
+func (o *ObservedOpenFGADatastore) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	start := time.Now()
+	deleted, err := o.inner.DeleteExpiredTuples(ctx, limit)
+	o.observe(start, err)
+
+	return deleted, err
+}
+
+func (o *ObservedOpenFGADatastore) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	start := time.Now()
+	snapshot, err := o.inner.Snapshot(ctx, store)
+	o.observe(start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &observedSnapshotReader{inner: snapshot, datastore: o}, nil
+}
+
+// observedSnapshotReader reports the reads of a snapshot like ObservedOpenFGADatastore does.
+type observedSnapshotReader struct {
+	inner     storage.SnapshotReader
+	datastore *ObservedOpenFGADatastore
+}
+
+var _ storage.SnapshotReader = (*observedSnapshotReader)(nil)
+
+func (o *observedSnapshotReader) Close() {
+	o.inner.Close()
+}
+
+func (o *observedSnapshotReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.Read(ctx, store, tupleKey)
+
+	return o.datastore.observeIterator(start, iter, err)
+}
+
+func (o *observedSnapshotReader) ReadPage(ctx context.Context, store string, tupleKey *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	start := time.Now()
+	tuples, token, err := o.inner.ReadPage(ctx, store, tupleKey, opts)
+	o.datastore.observe(start, err)
+
+	return tuples, token, err
+}
+
+func (o *observedSnapshotReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	start := time.Now()
+	t, err := o.inner.ReadUserTuple(ctx, store, tupleKey)
+	o.datastore.observe(start, err)
+
+	return t, err
+}
+
+func (o *observedSnapshotReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.ReadUsersetTuples(ctx, store, filter)
+
+	return o.datastore.observeIterator(start, iter, err)
+}
+
+func (o *observedSnapshotReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	start := time.Now()
+	iter, err := o.inner.ReadStartingWithUser(ctx, store, filter)
+
+	return o.datastore.observeIterator(start, iter, err)
+}
+
+func (o *observedSnapshotReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	start := time.Now()
+	conditions, err := o.inner.ReadTupleConditions(ctx, store, filter)
+	o.datastore.observe(start, err)
+
+	return conditions, err
+}
+
+// observedTupleIterator reports the outcome of the datastore call which returned an iterator once the
+// iteration is done, i.e. when Next returns an error or Stop is called. The latency reported is the time
+// spent creating the iterator and in Next, and the error is the first one returned by Next.
+type observedTupleIterator struct {
+	iter      storage.TupleIterator
+	datastore *ObservedOpenFGADatastore
+	elapsed   time.Duration
+	once      sync.Once
+}
+
+func (o *observedTupleIterator) Next() (*openfgav1.Tuple, error) {
+	start := time.Now()
+	t, err := o.iter.Next()
+	o.elapsed += time.Since(start)
+	if errors.Is(err, storage.ErrIteratorDone) {
+		o.report(nil)
+	} else if err != nil {
+		o.report(err)
+	}
+
+	return t, err
+}
+
+func (o *observedTupleIterator) Stop() {
+	o.iter.Stop()
+	o.report(nil)
+}
+
+func (o *observedTupleIterator) report(err error) {
+	o.once.Do(func() {
+		o.datastore.observe(time.Now().Add(-o.elapsed), err)
+	})
+}
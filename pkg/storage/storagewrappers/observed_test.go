@@ -0,0 +1,107 @@
+package storagewrappers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (r *recordingObserver) ObserveDatastoreCall(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+}
+
+func (r *recordingObserver) observed() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]time.Duration(nil), r.latencies...)
+}
+
+// slowTupleIterator is a storage.TupleIterator whose every call to Next takes a while.
+type slowTupleIterator struct {
+	storage.TupleIterator
+	delay time.Duration
+}
+
+func (s *slowTupleIterator) Next() (*openfgav1.Tuple, error) {
+	time.Sleep(s.delay)
+	return s.TupleIterator.Next()
+}
+
+type slowReadDatastore struct {
+	storage.OpenFGADatastore
+}
+
+func (s *slowReadDatastore) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	iter, err := s.OpenFGADatastore.Read(ctx, store, tupleKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &slowTupleIterator{TupleIterator: iter, delay: 10 * time.Millisecond}, nil
+}
+
+func TestObservedOpenFGADatastore(t *testing.T) {
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	mem := memory.New()
+	t.Cleanup(mem.Close)
+
+	err := mem.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+	})
+	require.NoError(t, err)
+
+	t.Run("iteration_is_observed", func(t *testing.T) {
+		observer := &recordingObserver{}
+		ds := NewObservedOpenFGADatastore(&slowReadDatastore{OpenFGADatastore: mem}, observer)
+
+		iter, err := ds.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+		require.NoError(t, err)
+		require.Empty(t, observer.observed())
+
+		for {
+			_, err := iter.Next()
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				break
+			}
+		}
+		iter.Stop()
+
+		latencies := observer.observed()
+		require.Len(t, latencies, 1)
+		require.GreaterOrEqual(t, latencies[0], 30*time.Millisecond)
+	})
+
+	t.Run("every_method_is_observed", func(t *testing.T) {
+		observer := &recordingObserver{}
+		ds := NewObservedOpenFGADatastore(mem, observer)
+
+		_, err := ds.ReadAssertions(ctx, store, ulid.Make().String())
+		require.NoError(t, err)
+
+		_, err = ds.GetStore(ctx, store)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		require.Len(t, observer.observed(), 2)
+	})
+}
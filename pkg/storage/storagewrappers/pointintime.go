@@ -0,0 +1,230 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/tuple"
+)
+
+// ErrUnknownPointInTimeCondition is returned when reading the conditions of tuples deleted after the point in time:
+// the changelog does not record the conditions and expirations of the tuples, so whether a restored tuple was
+// conditional, or had expired at the point in time, is unknown.
+var ErrUnknownPointInTimeCondition = errors.New("the condition of a tuple deleted after the point in time is unknown")
+
+var _ ConditionalTupleReader = (*pointInTimeTupleReader)(nil)
+
+type pointInTimeTupleReader struct {
+	ConditionalTupleReader
+
+	// undone holds the tuples changed after the point in time, keyed by tuple.TupleKeyToString. The tuples which
+	// existed at the point in time are set, and the tuples which didn't are nil.
+	undone map[string]*openfgav1.Tuple
+
+	asOf time.Time
+}
+
+// NewPointInTimeTupleReader returns a wrapper over a datastore whose reads return the tuples of the store as they were
+// at a point in time `asOf`, by undoing the changes that occurred after it. The changes must be in the order in which
+// they occurred, see storage.ReadChangesAfter. The expirations of the tuples are evaluated as of the point in time,
+// see storage.ContextWithExpirationTime. The tuples deleted after the point in time are restored, but reading their
+// conditions fails with ErrUnknownPointInTimeCondition, so that a Check which would depend on them is rejected.
+func NewPointInTimeTupleReader(ds ConditionalTupleReader, changes []*openfgav1.TupleChange, asOf time.Time) ConditionalTupleReader {
+	undone := map[string]*openfgav1.Tuple{}
+	for _, change := range changes {
+		key := tuple.TupleKeyToString(change.GetTupleKey())
+		if _, ok := undone[key]; ok {
+			// only the first change after the point in time tells whether the tuple existed
+			continue
+		}
+
+		if change.GetOperation() == openfgav1.TupleOperation_TUPLE_OPERATION_DELETE {
+			undone[key] = &openfgav1.Tuple{Key: change.GetTupleKey()}
+		} else {
+			undone[key] = nil
+		}
+	}
+
+	return &pointInTimeTupleReader{ConditionalTupleReader: ds, undone: undone, asOf: asOf}
+}
+
+func (p *pointInTimeTupleReader) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	iter, err := p.ConditionalTupleReader.Read(storage.ContextWithExpirationTime(ctx, p.asOf), store, tk)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.undo(iter, func(t *openfgav1.TupleKey) bool {
+		return matchesTupleKey(tk, t)
+	}), nil
+}
+
+func (p *pointInTimeTupleReader) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	tuples, token, err := p.ConditionalTupleReader.ReadPage(storage.ContextWithExpirationTime(ctx, p.asOf), store, tk, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res := make([]*openfgav1.Tuple, 0, len(tuples))
+	for _, t := range tuples {
+		if _, ok := p.undone[tuple.TupleKeyToString(t.GetKey())]; !ok {
+			res = append(res, t)
+		}
+	}
+
+	// the restored tuples are all returned with the first page
+	if opts.From == "" {
+		for _, t := range p.undone {
+			if t != nil && matchesTupleKey(tk, t.GetKey()) {
+				res = append(res, t)
+			}
+		}
+	}
+
+	return res, token, nil
+}
+
+func (p *pointInTimeTupleReader) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	if t, ok := p.undone[tuple.TupleKeyToString(tk)]; ok {
+		if t == nil {
+			return nil, storage.ErrNotFound
+		}
+		return t, nil
+	}
+
+	return p.ConditionalTupleReader.ReadUserTuple(storage.ContextWithExpirationTime(ctx, p.asOf), store, tk)
+}
+
+func (p *pointInTimeTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	iter, err := p.ConditionalTupleReader.ReadUsersetTuples(storage.ContextWithExpirationTime(ctx, p.asOf), store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.undo(iter, func(t *openfgav1.TupleKey) bool {
+		if t.GetObject() != filter.Object || t.GetRelation() != filter.Relation {
+			return false
+		}
+
+		if tuple.GetUserTypeFromUser(t.GetUser()) != tuple.UserSet {
+			return false
+		}
+
+		if len(filter.AllowedUserTypeRestrictions) == 0 {
+			return true
+		}
+
+		userObject, userRelation := tuple.SplitObjectRelation(t.GetUser())
+		for _, restriction := range filter.AllowedUserTypeRestrictions {
+			if tuple.GetType(userObject) != restriction.GetType() {
+				continue
+			}
+
+			if restriction.GetWildcard() != nil && tuple.IsWildcard(t.GetUser()) {
+				return true
+			}
+
+			if restriction.GetRelation() != "" && restriction.GetRelation() == userRelation {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+func (p *pointInTimeTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	iter, err := p.ConditionalTupleReader.ReadStartingWithUser(storage.ContextWithExpirationTime(ctx, p.asOf), store, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.undo(iter, func(t *openfgav1.TupleKey) bool {
+		if tuple.GetType(t.GetObject()) != filter.ObjectType || t.GetRelation() != filter.Relation {
+			return false
+		}
+
+		for _, u := range filter.UserFilter {
+			targetUser := u.GetObject()
+			if u.GetRelation() != "" {
+				targetUser = tuple.ToObjectRelationString(targetUser, u.GetRelation())
+			}
+
+			if t.GetUser() == targetUser {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+func (p *pointInTimeTupleReader) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	for _, t := range p.undone {
+		if t != nil && matchesTupleKey(filter, t.GetKey()) {
+			return nil, ErrUnknownPointInTimeCondition
+		}
+	}
+
+	return p.ConditionalTupleReader.ReadTupleConditions(storage.ContextWithExpirationTime(ctx, p.asOf), store, filter)
+}
+
+// undo removes the tuples changed after the point in time from the iterator, and adds the tuples which existed at
+// the point in time and match the read.
+func (p *pointInTimeTupleReader) undo(iter storage.TupleIterator, matches func(*openfgav1.TupleKey) bool) storage.TupleIterator {
+	var restored []*openfgav1.Tuple
+	for _, t := range p.undone {
+		if t != nil && matches(t.GetKey()) {
+			restored = append(restored, t)
+		}
+	}
+
+	return storage.NewCombinedIterator[*openfgav1.Tuple](storage.NewStaticTupleIterator(restored), &undoneTupleIterator{undone: p.undone, iter: iter})
+}
+
+// undoneTupleIterator skips the tuples changed after the point in time.
+type undoneTupleIterator struct {
+	undone map[string]*openfgav1.Tuple
+	iter   storage.TupleIterator
+}
+
+func (u *undoneTupleIterator) Next() (*openfgav1.Tuple, error) {
+	for {
+		t, err := u.iter.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := u.undone[tuple.TupleKeyToString(t.GetKey())]; !ok {
+			return t, nil
+		}
+	}
+}
+
+func (u *undoneTupleIterator) Stop() {
+	u.iter.Stop()
+}
+
+// matchesTupleKey reports whether the tuple key matches the tuple key of a Read, whose object may be an object type
+// followed by a colon.
+func matchesTupleKey(filter, tk *openfgav1.TupleKey) bool {
+	if filter.GetObject() != "" {
+		objectType, objectID := tuple.SplitObject(filter.GetObject())
+		if objectID == "" {
+			if objectType != tuple.GetType(tk.GetObject()) {
+				return false
+			}
+		} else if filter.GetObject() != tk.GetObject() {
+			return false
+		}
+	}
+
+	if filter.GetRelation() != "" && filter.GetRelation() != tk.GetRelation() {
+		return false
+	}
+
+	return filter.GetUser() == "" || filter.GetUser() == tk.GetUser()
+}
@@ -0,0 +1,55 @@
+package storagewrappers
+
+import (
+	"context"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/cache"
+	"github.com/openfga/openfga/pkg/storage"
+	"google.golang.org/protobuf/proto"
+)
+
+// modelCacheKeyPrefix is bumped whenever the format of the cached models changes.
+const modelCacheKeyPrefix = "model/v1/"
+
+var _ storage.OpenFGADatastore = (*sharedCachedOpenFGADatastore)(nil)
+
+type sharedCachedOpenFGADatastore struct {
+	storage.OpenFGADatastore
+	cache cache.Cache
+}
+
+// NewSharedCachedOpenFGADatastore returns a wrapper over a datastore that caches the *openfgav1.AuthorizationModel
+// returned by storage.ReadAuthorizationModel in a cache shared with other servers (e.g. a cache.RedisCache), so
+// that a model is only read from the datastore once for the whole fleet. The models are stored in their protobuf
+// wire format. The cache is not closed when the datastore is closed.
+func NewSharedCachedOpenFGADatastore(inner storage.OpenFGADatastore, c cache.Cache) *sharedCachedOpenFGADatastore {
+	return &sharedCachedOpenFGADatastore{
+		OpenFGADatastore: inner,
+		cache:            c,
+	}
+}
+
+func (c *sharedCachedOpenFGADatastore) ReadAuthorizationModel(ctx context.Context, storeID, modelID string) (*openfgav1.AuthorizationModel, error) {
+	cacheKey := fmt.Sprintf("%s%s/%s", modelCacheKeyPrefix, storeID, modelID)
+
+	// errors of the shared cache are treated as cache misses
+	if value, err := c.cache.Get(ctx, cacheKey); err == nil {
+		var model openfgav1.AuthorizationModel
+		if err := proto.Unmarshal(value, &model); err == nil {
+			return &model, nil
+		}
+	}
+
+	model, err := c.OpenFGADatastore.ReadAuthorizationModel(ctx, storeID, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, err := proto.Marshal(model); err == nil {
+		_ = c.cache.Set(ctx, cacheKey, value, ttl) // models are immutable, see cachedOpenFGADatastore
+	}
+
+	return model, nil
+}
@@ -0,0 +1,142 @@
+package storagewrappers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/telemetry"
+	"github.com/openfga/openfga/pkg/tuple"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("openfga/pkg/storage/storagewrappers")
+
+type tracedTupleReader struct {
+	storage.RelationshipTupleReader
+}
+
+var _ storage.RelationshipTupleReader = (*tracedTupleReader)(nil)
+
+// NewTracedTupleReader returns a wrapper over a datastore that traces every Read, ReadUserTuple, ReadUsersetTuples
+// and ReadStartingWithUser call with a span, whose attributes are the object type and the relation queried and,
+// once the returned iterator is exhausted or stopped, the number of tuples it returned ("result_count").
+func NewTracedTupleReader(wrapped storage.RelationshipTupleReader) storage.RelationshipTupleReader {
+	return &tracedTupleReader{RelationshipTupleReader: wrapped}
+}
+
+func (r *tracedTupleReader) Read(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	objectType, _ := tuple.SplitObject(tupleKey.GetObject())
+
+	ctx, span := tracer.Start(ctx, "datastore.Read", trace.WithAttributes(
+		attribute.String("store_id", store),
+		attribute.String("object_type", objectType),
+		attribute.String("relation", tupleKey.GetRelation()),
+	))
+
+	iter, err := r.RelationshipTupleReader.Read(ctx, store, tupleKey)
+	return traceIterator(span, iter, err)
+}
+
+func (r *tracedTupleReader) ReadUserTuple(ctx context.Context, store string, tupleKey *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	objectType, _ := tuple.SplitObject(tupleKey.GetObject())
+
+	ctx, span := tracer.Start(ctx, "datastore.ReadUserTuple", trace.WithAttributes(
+		attribute.String("store_id", store),
+		attribute.String("object_type", objectType),
+		attribute.String("relation", tupleKey.GetRelation()),
+	))
+	defer span.End()
+
+	t, err := r.RelationshipTupleReader.ReadUserTuple(ctx, store, tupleKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			span.SetAttributes(attribute.Int("result_count", 0))
+		} else {
+			telemetry.TraceError(span, err)
+		}
+
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("result_count", 1))
+
+	return t, nil
+}
+
+func (r *tracedTupleReader) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	objectType, _ := tuple.SplitObject(filter.Object)
+
+	ctx, span := tracer.Start(ctx, "datastore.ReadUsersetTuples", trace.WithAttributes(
+		attribute.String("store_id", store),
+		attribute.String("object_type", objectType),
+		attribute.String("relation", filter.Relation),
+	))
+
+	iter, err := r.RelationshipTupleReader.ReadUsersetTuples(ctx, store, filter)
+	return traceIterator(span, iter, err)
+}
+
+func (r *tracedTupleReader) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	ctx, span := tracer.Start(ctx, "datastore.ReadStartingWithUser", trace.WithAttributes(
+		attribute.String("store_id", store),
+		attribute.String("object_type", filter.ObjectType),
+		attribute.String("relation", filter.Relation),
+	))
+
+	iter, err := r.RelationshipTupleReader.ReadStartingWithUser(ctx, store, filter)
+	return traceIterator(span, iter, err)
+}
+
+// traceIterator returns the iterator returned by a traced call, which ends its span once it is exhausted or
+// stopped. The span is ended right away if the call failed.
+func traceIterator(span trace.Span, iter storage.TupleIterator, err error) (storage.TupleIterator, error) {
+	if err != nil {
+		telemetry.TraceError(span, err)
+		span.End()
+		return nil, err
+	}
+
+	return &tracedTupleIterator{TupleIterator: iter, span: span}, nil
+}
+
+type tracedTupleIterator struct {
+	storage.TupleIterator
+	span  trace.Span
+	count int
+	once  sync.Once
+}
+
+var _ storage.TupleIterator = (*tracedTupleIterator)(nil)
+
+func (i *tracedTupleIterator) Next() (*openfgav1.Tuple, error) {
+	t, err := i.TupleIterator.Next()
+	if err != nil {
+		if !errors.Is(err, storage.ErrIteratorDone) {
+			telemetry.TraceError(i.span, err)
+		}
+		i.end()
+
+		return nil, err
+	}
+
+	i.count++
+
+	return t, nil
+}
+
+func (i *tracedTupleIterator) Stop() {
+	i.TupleIterator.Stop()
+	i.end()
+}
+
+func (i *tracedTupleIterator) end() {
+	i.once.Do(func() {
+		i.span.SetAttributes(attribute.Int("result_count", i.count))
+		i.span.End()
+	})
+}
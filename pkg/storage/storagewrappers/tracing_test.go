@@ -0,0 +1,73 @@
+package storagewrappers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedTupleReader(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+	})
+
+	ctx := context.Background()
+	store := ulid.Make().String()
+
+	ds := memory.New()
+	t.Cleanup(ds.Close)
+
+	err := ds.Write(ctx, store, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "viewer", "user:bob"),
+	})
+	require.NoError(t, err)
+
+	reader := NewTracedTupleReader(ds)
+
+	iter, err := reader.Read(ctx, store, tuple.NewTupleKey("document:1", "viewer", ""))
+	require.NoError(t, err)
+
+	for {
+		_, err := iter.Next()
+		if err == storage.ErrIteratorDone {
+			break
+		}
+		require.NoError(t, err)
+	}
+	iter.Stop()
+
+	_, err = reader.ReadUserTuple(ctx, store, tuple.NewTupleKey("document:1", "viewer", "user:charlie"))
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	// the spans of the memory datastore are children of those of the wrapper
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if strings.HasPrefix(span.Name(), "datastore.") {
+			spans = append(spans, span)
+		}
+	}
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "datastore.Read", spans[0].Name())
+	require.Contains(t, spans[0].Attributes(), attribute.String("object_type", "document"))
+	require.Contains(t, spans[0].Attributes(), attribute.String("relation", "viewer"))
+	require.Contains(t, spans[0].Attributes(), attribute.Int("result_count", 2))
+
+	require.Equal(t, "datastore.ReadUserTuple", spans[1].Name())
+	require.Contains(t, spans[1].Attributes(), attribute.Int("result_count", 0))
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/openfga/openfga/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultStoreRetentionPeriod = 7 * 24 * time.Hour
+
+	defaultStorePurgerInterval  = 1 * time.Hour
+	defaultStorePurgerBatchSize = 10
+)
+
+// StorePurger periodically purges the stores that were deleted longer than the retention period ago, see
+// StoresBackend.PurgeDeletedStores.
+type StorePurger struct {
+	backend         StoresBackend
+	logger          logger.Logger
+	retentionPeriod time.Duration
+	interval        time.Duration
+	batchSize       int
+}
+
+type StorePurgerOption func(p *StorePurger)
+
+// WithStorePurgerRetentionPeriod sets how long the deleted stores are kept, and can be restored, before they are
+// purged.
+func WithStorePurgerRetentionPeriod(retentionPeriod time.Duration) StorePurgerOption {
+	return func(p *StorePurger) {
+		p.retentionPeriod = retentionPeriod
+	}
+}
+
+// WithStorePurgerInterval sets how long the purger waits between two purges of the deleted stores.
+func WithStorePurgerInterval(interval time.Duration) StorePurgerOption {
+	return func(p *StorePurger) {
+		p.interval = interval
+	}
+}
+
+// WithStorePurgerBatchSize sets the maximum number of stores purged in a single call to the datastore.
+func WithStorePurgerBatchSize(batchSize int) StorePurgerOption {
+	return func(p *StorePurger) {
+		p.batchSize = batchSize
+	}
+}
+
+func WithStorePurgerLogger(l logger.Logger) StorePurgerOption {
+	return func(p *StorePurger) {
+		p.logger = l
+	}
+}
+
+func NewStorePurger(backend StoresBackend, opts ...StorePurgerOption) *StorePurger {
+	p := &StorePurger{
+		backend:         backend,
+		logger:          logger.NewNoopLogger(),
+		retentionPeriod: DefaultStoreRetentionPeriod,
+		interval:        defaultStorePurgerInterval,
+		batchSize:       defaultStorePurgerBatchSize,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Run purges the expired deleted stores every interval until the context is cancelled. Errors are logged and
+// the purge is retried on the next interval.
+func (p *StorePurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := p.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.logger.Error("failed to purge the deleted stores", zap.Error(err))
+		}
+
+		if purged > 0 {
+			p.logger.Info("purged the deleted stores", zap.Int("count", purged))
+		}
+	}
+}
+
+// Purge purges every store deleted longer than the retention period ago, one batch at a time, and returns the
+// number of stores purged.
+func (p *StorePurger) Purge(ctx context.Context) (int, error) {
+	deletedBefore := time.Now().Add(-p.retentionPeriod)
+
+	var total int
+	for {
+		purged, err := p.backend.PurgeDeletedStores(ctx, deletedBefore, p.batchSize)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+
+		if purged < p.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Package tenancy contains an implementation of the storage interface that isolates the stores of tenants in
+// datastores of their own, e.g. separate databases or schemas, while the other stores share a datastore.
+//
+// The datastore of a store is the datastore of the tenant it is routed to by the routing table, see WithRoutes, or
+// else the datastore of the tenant of the context, see ContextWithTenant, or else the shared datastore. The stores
+// created with a tenant in the context are created in the datastore of the tenant, so their requests must carry the
+// tenant too, unless they are added to the routing table.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+var _ storage.OpenFGADatastore = (*Tenancy)(nil)
+
+// ContextWithTenant returns a context whose operations are routed to the datastore of the tenant, unless their store
+// is routed to another datastore by the routing table.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return storage.ContextWithTenant(ctx, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	return storage.TenantFromContext(ctx)
+}
+
+// WithTenantOf returns a context with the tenant of `from`, if any. The operations made in the background, or with
+// a context detached from the request, must carry the tenant of the request to be routed to its datastore.
+func WithTenantOf(ctx, from context.Context) context.Context {
+	return storage.WithTenantOf(ctx, from)
+}
+
+// Tenant is a tenant whose stores are isolated in a datastore of their own.
+type Tenant struct {
+	Name      string
+	Datastore storage.OpenFGADatastore
+}
+
+// Tenancy is a datastore routing the stores of the tenants to their datastores.
+type Tenancy struct {
+	shared  storage.OpenFGADatastore
+	tenants []Tenant
+	byName  map[string]storage.OpenFGADatastore
+	routes  map[string]string
+}
+
+type Option func(t *Tenancy)
+
+// WithRoutes sets the routing table: the store whose ID is a key of `routes` is routed to the tenant named by its
+// value, whatever the tenant of the context.
+func WithRoutes(routes map[string]string) Option {
+	return func(t *Tenancy) {
+		for store, tenant := range routes {
+			t.routes[store] = tenant
+		}
+	}
+}
+
+// New returns a datastore that routes the stores of the tenants to their datastores, and the other stores to the
+// shared datastore. The tenants must have distinct, non-empty, names, and the stores must be routed to tenants that
+// exist.
+func New(shared storage.OpenFGADatastore, tenants []Tenant, opts ...Option) (*Tenancy, error) {
+	t := &Tenancy{
+		shared:  shared,
+		tenants: tenants,
+		byName:  make(map[string]storage.OpenFGADatastore, len(tenants)),
+		routes:  map[string]string{},
+	}
+
+	for _, tenant := range tenants {
+		if tenant.Name == "" {
+			return nil, errors.New("the tenants must be named")
+		}
+		if _, ok := t.byName[tenant.Name]; ok {
+			return nil, fmt.Errorf("the tenant '%s' is configured twice", tenant.Name)
+		}
+		t.byName[tenant.Name] = tenant.Datastore
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	for store, tenant := range t.routes {
+		if _, ok := t.byName[tenant]; !ok {
+			return nil, fmt.Errorf("the store '%s' is routed to the unknown tenant '%s'", store, tenant)
+		}
+	}
+
+	return t, nil
+}
+
+// HasTenant reports whether the tenant exists.
+func (t *Tenancy) HasTenant(tenant string) bool {
+	_, ok := t.byName[tenant]
+	return ok
+}
+
+// Route returns the tenant the store is routed to by the routing table, if any.
+func (t *Tenancy) Route(store string) (string, bool) {
+	tenant, ok := t.routes[store]
+	return tenant, ok
+}
+
+// route returns the datastore of the store.
+func (t *Tenancy) route(ctx context.Context, store string) storage.OpenFGADatastore {
+	if tenant, ok := t.routes[store]; ok {
+		return t.byName[tenant]
+	}
+
+	return t.tenantDatastore(ctx)
+}
+
+// tenantDatastore returns the datastore of the tenant of the context or, if it has none or an unknown one, the
+// shared datastore.
+func (t *Tenancy) tenantDatastore(ctx context.Context) storage.OpenFGADatastore {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		if ds, ok := t.byName[tenant]; ok {
+			return ds
+		}
+	}
+
+	return t.shared
+}
+
+func (t *Tenancy) Read(ctx context.Context, store string, tk *openfgav1.TupleKey) (storage.TupleIterator, error) {
+	return t.route(ctx, store).Read(ctx, store, tk)
+}
+
+func (t *Tenancy) ReadPage(ctx context.Context, store string, tk *openfgav1.TupleKey, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return t.route(ctx, store).ReadPage(ctx, store, tk, opts)
+}
+
+func (t *Tenancy) ReadPageWithFilter(ctx context.Context, store string, filter storage.ReadFilter, opts storage.PaginationOptions) ([]*openfgav1.Tuple, []byte, error) {
+	return t.route(ctx, store).ReadPageWithFilter(ctx, store, filter, opts)
+}
+
+func (t *Tenancy) ReadUserTuple(ctx context.Context, store string, tk *openfgav1.TupleKey) (*openfgav1.Tuple, error) {
+	return t.route(ctx, store).ReadUserTuple(ctx, store, tk)
+}
+
+func (t *Tenancy) ReadUsersetTuples(ctx context.Context, store string, filter storage.ReadUsersetTuplesFilter) (storage.TupleIterator, error) {
+	return t.route(ctx, store).ReadUsersetTuples(ctx, store, filter)
+}
+
+func (t *Tenancy) ReadStartingWithUser(ctx context.Context, store string, filter storage.ReadStartingWithUserFilter) (storage.TupleIterator, error) {
+	return t.route(ctx, store).ReadStartingWithUser(ctx, store, filter)
+}
+
+func (t *Tenancy) Write(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, opts ...storage.TupleWriteOption) error {
+	return t.route(ctx, store).Write(ctx, store, deletes, writes, opts...)
+}
+
+// MaxTuplesPerWrite returns the smallest maximum of the datastores, so that a write accepted for a store is accepted by
+// whichever datastore holds it.
+func (t *Tenancy) MaxTuplesPerWrite() int {
+	max := t.shared.MaxTuplesPerWrite()
+	for _, tenant := range t.tenants {
+		if n := tenant.Datastore.MaxTuplesPerWrite(); n < max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+func (t *Tenancy) WriteWithExpiry(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, expiresAt time.Time, opts ...storage.TupleWriteOption) error {
+	return t.route(ctx, store).WriteWithExpiry(ctx, store, deletes, writes, expiresAt, opts...)
+}
+
+// DeleteExpiredTuples deletes the expired tuples of the datastores in turn, until `limit` tuples are deleted.
+func (t *Tenancy) DeleteExpiredTuples(ctx context.Context, limit int) (int, error) {
+	return t.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.DeleteExpiredTuples(ctx, remaining)
+	})
+}
+
+func (t *Tenancy) ReadTupleExpirations(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]time.Time, error) {
+	return t.route(ctx, store).ReadTupleExpirations(ctx, store, filter)
+}
+
+func (t *Tenancy) WriteWithCondition(ctx context.Context, store string, deletes storage.Deletes, writes storage.Writes, condition *storage.TupleCondition, opts ...storage.TupleWriteOption) error {
+	return t.route(ctx, store).WriteWithCondition(ctx, store, deletes, writes, condition, opts...)
+}
+
+func (t *Tenancy) StageWrite(ctx context.Context, store, id string, deletes storage.Deletes, writes storage.Writes) error {
+	return t.route(ctx, store).StageWrite(ctx, store, id, deletes, writes)
+}
+
+func (t *Tenancy) CommitStagedWrite(ctx context.Context, store, id string, opts ...storage.TupleWriteOption) error {
+	return t.route(ctx, store).CommitStagedWrite(ctx, store, id, opts...)
+}
+
+func (t *Tenancy) DiscardStagedWrite(ctx context.Context, store, id string) error {
+	return t.route(ctx, store).DiscardStagedWrite(ctx, store, id)
+}
+
+func (t *Tenancy) ReadTupleConditions(ctx context.Context, store string, filter *openfgav1.TupleKey) (map[string]*storage.TupleCondition, error) {
+	return t.route(ctx, store).ReadTupleConditions(ctx, store, filter)
+}
+
+func (t *Tenancy) Snapshot(ctx context.Context, store string) (storage.SnapshotReader, error) {
+	return t.route(ctx, store).Snapshot(ctx, store)
+}
+
+func (t *Tenancy) ReadAuthorizationModel(ctx context.Context, store string, id string) (*openfgav1.AuthorizationModel, error) {
+	return t.route(ctx, store).ReadAuthorizationModel(ctx, store, id)
+}
+
+func (t *Tenancy) ReadAuthorizationModels(ctx context.Context, store string, opts storage.PaginationOptions) ([]*openfgav1.AuthorizationModel, []byte, error) {
+	return t.route(ctx, store).ReadAuthorizationModels(ctx, store, opts)
+}
+
+func (t *Tenancy) FindLatestAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	return t.route(ctx, store).FindLatestAuthorizationModelID(ctx, store)
+}
+
+func (t *Tenancy) ReadAuthorizationModelAnnotations(ctx context.Context, store string, id string) (storage.ModelAnnotations, error) {
+	return t.route(ctx, store).ReadAuthorizationModelAnnotations(ctx, store, id)
+}
+
+// MaxTypesPerAuthorizationModel returns the smallest maximum of the datastores.
+func (t *Tenancy) MaxTypesPerAuthorizationModel() int {
+	max := t.shared.MaxTypesPerAuthorizationModel()
+	for _, tenant := range t.tenants {
+		if n := tenant.Datastore.MaxTypesPerAuthorizationModel(); n < max {
+			max = n
+		}
+	}
+
+	return max
+}
+
+func (t *Tenancy) WriteAuthorizationModel(ctx context.Context, store string, model *openfgav1.AuthorizationModel) error {
+	return t.route(ctx, store).WriteAuthorizationModel(ctx, store, model)
+}
+
+func (t *Tenancy) WriteAuthorizationModelWithAnnotations(ctx context.Context, store string, model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) error {
+	return t.route(ctx, store).WriteAuthorizationModelWithAnnotations(ctx, store, model, annotations)
+}
+
+func (t *Tenancy) CreateStore(ctx context.Context, store *openfgav1.Store) (*openfgav1.Store, error) {
+	return t.route(ctx, store.GetId()).CreateStore(ctx, store)
+}
+
+func (t *Tenancy) DeleteStore(ctx context.Context, id string) error {
+	return t.route(ctx, id).DeleteStore(ctx, id)
+}
+
+func (t *Tenancy) UndeleteStore(ctx context.Context, id string, deletedAfter time.Time) (*openfgav1.Store, error) {
+	return t.route(ctx, id).UndeleteStore(ctx, id, deletedAfter)
+}
+
+// PurgeDeletedStores purges the deleted stores of the datastores in turn, until `limit` stores are purged.
+func (t *Tenancy) PurgeDeletedStores(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	return t.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.PurgeDeletedStores(ctx, deletedBefore, remaining)
+	})
+}
+
+func (t *Tenancy) GetStore(ctx context.Context, id string) (*openfgav1.Store, error) {
+	return t.route(ctx, id).GetStore(ctx, id)
+}
+
+// ListStores lists the stores of the datastore of the tenant of the context or, if it has none, of the shared
+// datastore. The stores routed to a tenant by the routing table are listed with the tenant of the datastore they
+// are in.
+func (t *Tenancy) ListStores(ctx context.Context, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	return t.tenantDatastore(ctx).ListStores(ctx, opts)
+}
+
+func (t *Tenancy) ListStoresWithFilter(ctx context.Context, filter storage.ListStoresFilter, opts storage.PaginationOptions) ([]*openfgav1.Store, []byte, error) {
+	return t.tenantDatastore(ctx).ListStoresWithFilter(ctx, filter, opts)
+}
+
+func (t *Tenancy) WriteStoreMetadata(ctx context.Context, id string, metadata *storage.StoreMetadata) error {
+	return t.route(ctx, id).WriteStoreMetadata(ctx, id, metadata)
+}
+
+func (t *Tenancy) ReadStoreMetadata(ctx context.Context, id string) (*storage.StoreMetadata, error) {
+	return t.route(ctx, id).ReadStoreMetadata(ctx, id)
+}
+
+func (t *Tenancy) DeleteAuthorizationModel(ctx context.Context, store string, id string) error {
+	return t.route(ctx, store).DeleteAuthorizationModel(ctx, store, id)
+}
+
+func (t *Tenancy) PinAuthorizationModel(ctx context.Context, store string, id string) error {
+	return t.route(ctx, store).PinAuthorizationModel(ctx, store, id)
+}
+
+func (t *Tenancy) UnpinAuthorizationModel(ctx context.Context, store string) error {
+	return t.route(ctx, store).UnpinAuthorizationModel(ctx, store)
+}
+
+func (t *Tenancy) ReadPinnedAuthorizationModelID(ctx context.Context, store string) (string, error) {
+	return t.route(ctx, store).ReadPinnedAuthorizationModelID(ctx, store)
+}
+
+func (t *Tenancy) WriteAssertions(ctx context.Context, store, modelID string, assertions []*openfgav1.Assertion) error {
+	return t.route(ctx, store).WriteAssertions(ctx, store, modelID, assertions)
+}
+
+func (t *Tenancy) ReadAssertions(ctx context.Context, store, modelID string) ([]*openfgav1.Assertion, error) {
+	return t.route(ctx, store).ReadAssertions(ctx, store, modelID)
+}
+
+func (t *Tenancy) ReadChanges(ctx context.Context, store, objectType string, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	return t.route(ctx, store).ReadChanges(ctx, store, objectType, opts, horizonOffset)
+}
+
+func (t *Tenancy) ReadChangesWithFilter(ctx context.Context, store string, filter storage.ReadChangesFilter, opts storage.PaginationOptions, horizonOffset time.Duration) ([]*openfgav1.TupleChange, []byte, error) {
+	return t.route(ctx, store).ReadChangesWithFilter(ctx, store, filter, opts, horizonOffset)
+}
+
+// DeleteChanges deletes the changes of the store from its datastore or, if the store is empty, the changes of the
+// datastores in turn, until `limit` changes are deleted.
+func (t *Tenancy) DeleteChanges(ctx context.Context, store string, before time.Time, limit int) (int, error) {
+	if store != "" {
+		return t.route(ctx, store).DeleteChanges(ctx, store, before, limit)
+	}
+
+	return t.fanOut(limit, func(ds storage.OpenFGADatastore, remaining int) (int, error) {
+		return ds.DeleteChanges(ctx, "", before, remaining)
+	})
+}
+
+func (t *Tenancy) ReadStoreStats(ctx context.Context, store string) (*storage.StoreStats, error) {
+	return t.route(ctx, store).ReadStoreStats(ctx, store)
+}
+
+// fanOut runs an operation deleting at most `limit` items on the shared datastore and the datastores of the tenants
+// in turn, with the limit left by the previous datastores, and returns the number of items deleted.
+func (t *Tenancy) fanOut(limit int, op func(ds storage.OpenFGADatastore, remaining int) (int, error)) (int, error) {
+	total, err := op(t.shared, limit)
+	if err != nil {
+		return total, err
+	}
+
+	for _, tenant := range t.tenants {
+		if total >= limit {
+			break
+		}
+
+		n, err := op(tenant.Datastore, limit-total)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("tenant '%s': %w", tenant.Name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// IsReady reports whether the shared datastore and the datastores of the tenants are ready.
+func (t *Tenancy) IsReady(ctx context.Context) (bool, error) {
+	ready, err := t.shared.IsReady(ctx)
+	if err != nil || !ready {
+		return ready, err
+	}
+
+	for _, tenant := range t.tenants {
+		ready, err := tenant.Datastore.IsReady(ctx)
+		if err != nil || !ready {
+			return ready, err
+		}
+	}
+
+	return true, nil
+}
+
+// Close closes the shared datastore and the datastores of the tenants.
+func (t *Tenancy) Close() {
+	t.shared.Close()
+	for _, tenant := range t.tenants {
+		tenant.Datastore.Close()
+	}
+}
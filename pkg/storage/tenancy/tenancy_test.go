@@ -0,0 +1,88 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/openfga/openfga/pkg/storage/memory"
+	"github.com/openfga/openfga/pkg/storage/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenants(names ...string) []Tenant {
+	tenants := make([]Tenant, 0, len(names))
+	for _, name := range names {
+		tenants = append(tenants, Tenant{Name: name, Datastore: memory.New()})
+	}
+
+	return tenants
+}
+
+func TestTenancyDatastore(t *testing.T) {
+	ds, err := New(memory.New(), newTenants("acme", "globex"))
+	require.NoError(t, err)
+
+	test.RunAllTests(t, ds)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(memory.New(), newTenants("acme", "acme"))
+	require.ErrorContains(t, err, "configured twice")
+
+	_, err = New(memory.New(), newTenants(""))
+	require.ErrorContains(t, err, "must be named")
+
+	_, err = New(memory.New(), newTenants("acme"), WithRoutes(map[string]string{"store": "globex"}))
+	require.ErrorContains(t, err, "unknown tenant 'globex'")
+}
+
+func TestRouting(t *testing.T) {
+	ctx := context.Background()
+
+	shared := memory.New()
+	tenants := newTenants("acme", "globex")
+	routed := ulid.Make().String()
+
+	ds, err := New(shared, tenants, WithRoutes(map[string]string{routed: "acme"}))
+	require.NoError(t, err)
+	t.Cleanup(ds.Close)
+
+	sharedStore := ulid.Make().String()
+	_, err = ds.CreateStore(ctx, &openfgav1.Store{Id: sharedStore, Name: "shared"})
+	require.NoError(t, err)
+
+	globexCtx := ContextWithTenant(ctx, "globex")
+	globexStore := ulid.Make().String()
+	_, err = ds.CreateStore(globexCtx, &openfgav1.Store{Id: globexStore, Name: "globex"})
+	require.NoError(t, err)
+
+	// the routing table wins over the tenant of the context
+	_, err = ds.CreateStore(globexCtx, &openfgav1.Store{Id: routed, Name: "acme"})
+	require.NoError(t, err)
+
+	_, err = shared.GetStore(ctx, sharedStore)
+	require.NoError(t, err)
+	_, err = tenants[1].Datastore.GetStore(ctx, globexStore)
+	require.NoError(t, err)
+	_, err = tenants[0].Datastore.GetStore(ctx, routed)
+	require.NoError(t, err)
+
+	// the stores of a tenant are isolated from the requests of the other tenants
+	_, err = ds.GetStore(ctx, globexStore)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = ds.GetStore(ContextWithTenant(ctx, "acme"), globexStore)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = ds.GetStore(globexCtx, sharedStore)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = ds.GetStore(ctx, routed)
+	require.NoError(t, err)
+
+	stores, _, err := ds.ListStores(globexCtx, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	require.Equal(t, globexStore, stores[0].GetId())
+}
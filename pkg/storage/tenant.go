@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+type tenantCtxKey struct{}
+
+// ContextWithTenant returns a context carrying the tenant its operations are made for. The tenancy package routes
+// them to the datastore of the tenant, see tenancy.ContextWithTenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// WithTenantOf returns a context with the tenant of `from`, if any, see tenancy.WithTenantOf.
+func WithTenantOf(ctx, from context.Context) context.Context {
+	if tenant, ok := TenantFromContext(from); ok {
+		return ContextWithTenant(ctx, tenant)
+	}
+
+	return ctx
+}
+
+// StoreKey returns the key of the store in the datastore of the tenant of the context, which identifies it in the
+// caches shared by the tenants: the stores of two tenants may have the same ID.
+func StoreKey(ctx context.Context, storeID string) string {
+	tenant, _ := TenantFromContext(ctx)
+	return fmt.Sprintf("%s/%s", tenant, storeID)
+}
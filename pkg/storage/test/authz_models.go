@@ -161,3 +161,243 @@ func FindLatestAuthorizationModelIDTest(t *testing.T, datastore storage.OpenFGAD
 		require.Equal(t, newModel.Id, latestID)
 	})
 }
+
+func AuthorizationModelAnnotationsTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	typeDefinitions := []*openfgav1.TypeDefinition{
+		{Type: "user"},
+		{
+			Type: "document",
+			Relations: map[string]*openfgav1.Userset{
+				"viewer": {Userset: &openfgav1.Userset_This{This: &openfgav1.DirectUserset{}}},
+			},
+		},
+	}
+
+	t.Run("write_with_annotations,_then_read,_succeeds", func(t *testing.T) {
+		model := &openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: typeDefinitions,
+		}
+
+		annotations := storage.ModelAnnotations{
+			"document": {
+				Annotations: map[string]string{"owner": "docs-team"},
+				Relations: map[string]map[string]string{
+					"viewer": {"deprecated": "use can_view instead"},
+				},
+			},
+		}
+
+		err := datastore.WriteAuthorizationModelWithAnnotations(ctx, storeID, model, annotations)
+		require.NoError(t, err)
+
+		got, err := datastore.ReadAuthorizationModel(ctx, storeID, model.Id)
+		require.NoError(t, err)
+
+		if diff := cmp.Diff(model, got, cmpOpts...); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+
+		gotAnnotations, err := datastore.ReadAuthorizationModelAnnotations(ctx, storeID, model.Id)
+		require.NoError(t, err)
+		require.Equal(t, annotations, gotAnnotations)
+	})
+
+	t.Run("model_written_without_annotations_has_none", func(t *testing.T) {
+		model := &openfgav1.AuthorizationModel{
+			Id:              ulid.Make().String(),
+			SchemaVersion:   typesystem.SchemaVersion1_1,
+			TypeDefinitions: typeDefinitions,
+		}
+
+		err := datastore.WriteAuthorizationModel(ctx, storeID, model)
+		require.NoError(t, err)
+
+		gotAnnotations, err := datastore.ReadAuthorizationModelAnnotations(ctx, storeID, model.Id)
+		require.NoError(t, err)
+		require.Empty(t, gotAnnotations)
+	})
+
+	t.Run("annotations_of_a_model_which_does_not_exist_returns_not_found", func(t *testing.T) {
+		_, err := datastore.ReadAuthorizationModelAnnotations(ctx, storeID, ulid.Make().String())
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func PinnedAuthorizationModelTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	writeModel := func(t *testing.T, store string) string {
+		model := &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "user"},
+			},
+		}
+		err := datastore.WriteAuthorizationModel(ctx, store, model)
+		require.NoError(t, err)
+
+		return model.GetId()
+	}
+
+	t.Run("no_model_is_pinned_by_default", func(t *testing.T) {
+		store := ulid.Make().String()
+		writeModel(t, store)
+
+		pinnedID, err := datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Empty(t, pinnedID)
+	})
+
+	t.Run("pin_and_unpin_a_model", func(t *testing.T) {
+		store := ulid.Make().String()
+		oldModelID := writeModel(t, store)
+		newModelID := writeModel(t, store)
+
+		err := datastore.PinAuthorizationModel(ctx, store, oldModelID)
+		require.NoError(t, err)
+
+		pinnedID, err := datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, oldModelID, pinnedID)
+
+		// the pinned model does not change the latest model
+		latestID, err := datastore.FindLatestAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, newModelID, latestID)
+
+		err = datastore.PinAuthorizationModel(ctx, store, newModelID)
+		require.NoError(t, err)
+
+		pinnedID, err = datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, newModelID, pinnedID)
+
+		err = datastore.UnpinAuthorizationModel(ctx, store)
+		require.NoError(t, err)
+
+		pinnedID, err = datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Empty(t, pinnedID)
+
+		// unpinning twice is a no-op
+		err = datastore.UnpinAuthorizationModel(ctx, store)
+		require.NoError(t, err)
+	})
+
+	t.Run("pin_a_model_that_does_not_exist", func(t *testing.T) {
+		store := ulid.Make().String()
+		pinnedModelID := writeModel(t, store)
+
+		err := datastore.PinAuthorizationModel(ctx, store, pinnedModelID)
+		require.NoError(t, err)
+
+		err = datastore.PinAuthorizationModel(ctx, store, ulid.Make().String())
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		err = datastore.PinAuthorizationModel(ctx, ulid.Make().String(), pinnedModelID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		// the pinned model is unchanged
+		pinnedID, err := datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, pinnedModelID, pinnedID)
+	})
+}
+
+func DeleteAuthorizationModelTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	writeModel := func(t *testing.T, store string) string {
+		model := &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "user"},
+			},
+		}
+		err := datastore.WriteAuthorizationModelWithAnnotations(ctx, store, model, storage.ModelAnnotations{
+			"user": {Annotations: map[string]string{"owner": "identity"}},
+		})
+		require.NoError(t, err)
+
+		return model.GetId()
+	}
+
+	t.Run("delete_a_model_and_its_assertions", func(t *testing.T) {
+		store := ulid.Make().String()
+		oldModelID := writeModel(t, store)
+		newModelID := writeModel(t, store)
+
+		err := datastore.WriteAssertions(ctx, store, oldModelID, []*openfgav1.Assertion{
+			{TupleKey: &openfgav1.TupleKey{Object: "user:jon", Relation: "viewer", User: "user:jon"}, Expectation: false},
+		})
+		require.NoError(t, err)
+
+		err = datastore.DeleteAuthorizationModel(ctx, store, oldModelID)
+		require.NoError(t, err)
+
+		_, err = datastore.ReadAuthorizationModel(ctx, store, oldModelID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		assertions, err := datastore.ReadAssertions(ctx, store, oldModelID)
+		require.NoError(t, err)
+		require.Empty(t, assertions)
+
+		models, _, err := datastore.ReadAuthorizationModels(ctx, store, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, models, 1)
+		require.Equal(t, newModelID, models[0].GetId())
+
+		err = datastore.DeleteAuthorizationModel(ctx, store, oldModelID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("delete_the_latest_model", func(t *testing.T) {
+		store := ulid.Make().String()
+		oldModelID := writeModel(t, store)
+		newModelID := writeModel(t, store)
+
+		err := datastore.DeleteAuthorizationModel(ctx, store, newModelID)
+		require.NoError(t, err)
+
+		latestID, err := datastore.FindLatestAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, oldModelID, latestID)
+
+		err = datastore.DeleteAuthorizationModel(ctx, store, oldModelID)
+		require.NoError(t, err)
+
+		_, err = datastore.FindLatestAuthorizationModelID(ctx, store)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("delete_the_pinned_model", func(t *testing.T) {
+		store := ulid.Make().String()
+		pinnedModelID := writeModel(t, store)
+		otherModelID := writeModel(t, store)
+
+		err := datastore.PinAuthorizationModel(ctx, store, pinnedModelID)
+		require.NoError(t, err)
+
+		// deleting another model leaves the pin
+		err = datastore.DeleteAuthorizationModel(ctx, store, otherModelID)
+		require.NoError(t, err)
+
+		pinnedID, err := datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, pinnedModelID, pinnedID)
+
+		err = datastore.DeleteAuthorizationModel(ctx, store, pinnedModelID)
+		require.NoError(t, err)
+
+		pinnedID, err = datastore.ReadPinnedAuthorizationModelID(ctx, store)
+		require.NoError(t, err)
+		require.Empty(t, pinnedID)
+	})
+}
@@ -33,17 +33,31 @@ func RunAllTests(t *testing.T, ds storage.OpenFGADatastore) {
 	// tuples
 	t.Run("TestTupleWriteAndRead", func(t *testing.T) { TupleWritingAndReadingTest(t, ds) })
 	t.Run("TestTuplePaginationOptions", func(t *testing.T) { TuplePaginationOptionsTest(t, ds) })
+	t.Run("TestReadPageWithFilter", func(t *testing.T) { ReadPageWithFilterTest(t, ds) })
 	t.Run("TestReadChanges", func(t *testing.T) { ReadChangesTest(t, ds) })
+	t.Run("TestDeleteChanges", func(t *testing.T) { DeleteChangesTest(t, ds) })
 	t.Run("TestReadStartingWithUser", func(t *testing.T) { ReadStartingWithUserTest(t, ds) })
+	t.Run("TestTupleExpiry", func(t *testing.T) { TupleExpiryTest(t, ds) })
+	t.Run("TestConditionalWrite", func(t *testing.T) { ConditionalWriteTest(t, ds) })
+	t.Run("TestTupleCondition", func(t *testing.T) { TupleConditionTest(t, ds) })
+	t.Run("TestSnapshot", func(t *testing.T) { SnapshotTest(t, ds) })
+	t.Run("TestStagedWrite", func(t *testing.T) { StagedWriteTest(t, ds) })
+	t.Run("TestExpectedChangelogToken", func(t *testing.T) { ExpectedChangelogTokenTest(t, ds) })
 
 	// authorization models
 	t.Run("TestWriteAndReadAuthorizationModel", func(t *testing.T) { WriteAndReadAuthorizationModelTest(t, ds) })
 	t.Run("TestReadAuthorizationModels", func(t *testing.T) { ReadAuthorizationModelsTest(t, ds) })
 	t.Run("TestFindLatestAuthorizationModelID", func(t *testing.T) { FindLatestAuthorizationModelIDTest(t, ds) })
+	t.Run("TestAuthorizationModelAnnotations", func(t *testing.T) { AuthorizationModelAnnotationsTest(t, ds) })
+	t.Run("TestPinnedAuthorizationModel", func(t *testing.T) { PinnedAuthorizationModelTest(t, ds) })
+	t.Run("TestDeleteAuthorizationModel", func(t *testing.T) { DeleteAuthorizationModelTest(t, ds) })
 
 	// assertions
 	t.Run("TestWriteAndReadAssertions", func(t *testing.T) { AssertionsTest(t, ds) })
 
 	// stores
 	t.Run("TestStore", func(t *testing.T) { StoreTest(t, ds) })
+	t.Run("TestStoreMetadata", func(t *testing.T) { StoreMetadataTest(t, ds) })
+	t.Run("TestStoreDeletion", func(t *testing.T) { StoreDeletionTest(t, ds) })
+	t.Run("TestStoreStats", func(t *testing.T) { StoreStatsTest(t, ds) })
 }
@@ -9,6 +9,8 @@ import (
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/testutils"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -89,3 +91,259 @@ func StoreTest(t *testing.T, datastore storage.OpenFGADatastore) {
 		}
 	})
 }
+
+func StoreMetadataTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	// the stores are distinguished from the stores of the other tests by a unique prefix and a unique label
+	prefix := testutils.CreateRandomString(10)
+	team := ulid.Make().String()
+
+	var stores []*openfgav1.Store
+	for _, name := range []string{"charlie", "alpha", "bravo", "alpha"} {
+		store, err := datastore.CreateStore(ctx, &openfgav1.Store{
+			Id:   ulid.Make().String(),
+			Name: prefix + "-" + name,
+		})
+		require.NoError(t, err)
+
+		stores = append(stores, store)
+	}
+
+	storeIDs := func(stores []*openfgav1.Store) []string {
+		ids := make([]string, 0, len(stores))
+		for _, store := range stores {
+			ids = append(ids, store.Id)
+		}
+		return ids
+	}
+
+	t.Run("metadata_of_a_new_store_is_empty", func(t *testing.T) {
+		metadata, err := datastore.ReadStoreMetadata(ctx, stores[0].Id)
+		require.NoError(t, err)
+		require.Empty(t, metadata.Description)
+		require.Empty(t, metadata.Labels)
+	})
+
+	t.Run("metadata_is_replaced", func(t *testing.T) {
+		err := datastore.WriteStoreMetadata(ctx, stores[0].Id, &storage.StoreMetadata{
+			Description: "first",
+			Labels:      map[string]string{"team": "other", "env": "dev"},
+		})
+		require.NoError(t, err)
+
+		expected := &storage.StoreMetadata{
+			Description: "payments",
+			Labels:      map[string]string{"team": team, "env": "prod"},
+		}
+		err = datastore.WriteStoreMetadata(ctx, stores[0].Id, expected)
+		require.NoError(t, err)
+
+		metadata, err := datastore.ReadStoreMetadata(ctx, stores[0].Id)
+		require.NoError(t, err)
+		require.Equal(t, expected, metadata)
+	})
+
+	t.Run("metadata_of_non-existent_store_returns_not_found", func(t *testing.T) {
+		err := datastore.WriteStoreMetadata(ctx, ulid.Make().String(), &storage.StoreMetadata{Description: "missing"})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		_, err = datastore.ReadStoreMetadata(ctx, ulid.Make().String())
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	for _, store := range stores[1:3] {
+		err := datastore.WriteStoreMetadata(ctx, store.Id, &storage.StoreMetadata{
+			Labels: map[string]string{"team": team, "env": "dev"},
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("list_stores_by_name_prefix", func(t *testing.T) {
+		gotStores, ct, err := datastore.ListStoresWithFilter(ctx, storage.ListStoresFilter{NamePrefix: prefix + "-alpha"}, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+		require.NoError(t, err)
+		require.Empty(t, ct)
+		require.Equal(t, []string{stores[1].Id, stores[3].Id}, storeIDs(gotStores))
+	})
+
+	t.Run("list_stores_by_labels", func(t *testing.T) {
+		gotStores, _, err := datastore.ListStoresWithFilter(ctx, storage.ListStoresFilter{Labels: map[string]string{"team": team}}, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+		require.NoError(t, err)
+		require.Equal(t, storeIDs(stores[:3]), storeIDs(gotStores))
+
+		gotStores, _, err = datastore.ListStoresWithFilter(ctx, storage.ListStoresFilter{Labels: map[string]string{"team": team, "env": "dev"}}, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+		require.NoError(t, err)
+		require.Equal(t, storeIDs(stores[1:3]), storeIDs(gotStores))
+	})
+
+	t.Run("list_stores_by_name_with_pagination", func(t *testing.T) {
+		filter := storage.ListStoresFilter{NamePrefix: prefix, SortOrder: storage.StoreSortByName}
+
+		var gotStores []*openfgav1.Store
+		var from string
+		for {
+			page, ct, err := datastore.ListStoresWithFilter(ctx, filter, storage.PaginationOptions{PageSize: 1, From: from})
+			require.NoError(t, err)
+
+			gotStores = append(gotStores, page...)
+			if len(ct) == 0 {
+				break
+			}
+			from = string(ct)
+		}
+
+		require.Equal(t, []string{stores[1].Id, stores[3].Id, stores[2].Id, stores[0].Id}, storeIDs(gotStores))
+	})
+
+	t.Run("deleted_store_is_not_listed", func(t *testing.T) {
+		err := datastore.DeleteStore(ctx, stores[2].Id)
+		require.NoError(t, err)
+
+		gotStores, _, err := datastore.ListStoresWithFilter(ctx, storage.ListStoresFilter{Labels: map[string]string{"team": team, "env": "dev"}}, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+		require.NoError(t, err)
+		require.Equal(t, []string{stores[1].Id}, storeIDs(gotStores))
+	})
+}
+
+func StoreDeletionTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	createStore := func(t *testing.T) *openfgav1.Store {
+		store, err := datastore.CreateStore(ctx, &openfgav1.Store{
+			Id:   ulid.Make().String(),
+			Name: testutils.CreateRandomString(10),
+		})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, store.Id, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:anne")})
+		require.NoError(t, err)
+
+		return store
+	}
+
+	t.Run("deleted_store_is_restored", func(t *testing.T) {
+		store := createStore(t)
+
+		err := datastore.DeleteStore(ctx, store.Id)
+		require.NoError(t, err)
+
+		restored, err := datastore.UndeleteStore(ctx, store.Id, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, store.Id, restored.Id)
+		require.Equal(t, store.Name, restored.Name)
+
+		_, err = datastore.GetStore(ctx, store.Id)
+		require.NoError(t, err)
+
+		tuples, _, err := datastore.ReadPage(ctx, store.Id, nil, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+	})
+
+	t.Run("store_which_is_not_deleted_is_not_restored", func(t *testing.T) {
+		store := createStore(t)
+
+		_, err := datastore.UndeleteStore(ctx, store.Id, time.Time{})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		_, err = datastore.UndeleteStore(ctx, ulid.Make().String(), time.Time{})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("store_deleted_before_the_retention_period_is_not_restored", func(t *testing.T) {
+		store := createStore(t)
+
+		err := datastore.DeleteStore(ctx, store.Id)
+		require.NoError(t, err)
+
+		_, err = datastore.UndeleteStore(ctx, store.Id, time.Now().Add(time.Hour))
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("deleted_store_is_purged", func(t *testing.T) {
+		deleted := createStore(t)
+		kept := createStore(t)
+
+		err := datastore.DeleteStore(ctx, deleted.Id)
+		require.NoError(t, err)
+
+		// the stores deleted by the other tests may be purged as well
+		purged, err := datastore.PurgeDeletedStores(ctx, time.Now().Add(time.Hour), 100)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, purged, 1)
+
+		_, err = datastore.UndeleteStore(ctx, deleted.Id, time.Time{})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		tuples, _, err := datastore.ReadPage(ctx, deleted.Id, nil, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Empty(t, tuples)
+
+		tuples, _, err = datastore.ReadPage(ctx, kept.Id, nil, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, tuples, 1)
+	})
+
+	t.Run("recently_deleted_store_is_not_purged", func(t *testing.T) {
+		store := createStore(t)
+
+		err := datastore.DeleteStore(ctx, store.Id)
+		require.NoError(t, err)
+
+		_, err = datastore.PurgeDeletedStores(ctx, time.Now().Add(-time.Hour), 100)
+		require.NoError(t, err)
+
+		_, err = datastore.UndeleteStore(ctx, store.Id, time.Time{})
+		require.NoError(t, err)
+	})
+}
+
+func StoreStatsTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	stats, err := datastore.ReadStoreStats(ctx, storeID)
+	require.NoError(t, err)
+	require.Empty(t, stats.Tuples)
+	require.Zero(t, stats.AuthorizationModels)
+	require.Zero(t, stats.Changes)
+
+	// the models with several type definitions are counted once
+	for i := 0; i < 2; i++ {
+		err := datastore.WriteAuthorizationModel(ctx, storeID, &openfgav1.AuthorizationModel{
+			Id:            ulid.Make().String(),
+			SchemaVersion: typesystem.SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "user"},
+				{Type: "document"},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{
+		tuple.NewTupleKey("document:1", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:2", "viewer", "user:anne"),
+		tuple.NewTupleKey("document:1", "editor", "user:bob"),
+		tuple.NewTupleKey("folder:1", "viewer", "user:anne"),
+	})
+	require.NoError(t, err)
+
+	err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tuple.NewTupleKey("folder:1", "viewer", "user:anne")}, nil)
+	require.NoError(t, err)
+
+	// the expired tuples are not counted
+	err = datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:3", "viewer", "user:anne")}, time.Now().Add(500*time.Millisecond))
+	require.NoError(t, err)
+	time.Sleep(time.Second)
+
+	// the tuples of the other stores are not counted
+	err = datastore.Write(ctx, ulid.Make().String(), nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:anne")})
+	require.NoError(t, err)
+
+	stats, err = datastore.ReadStoreStats(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{"document#viewer": 2, "document#editor": 1}, stats.Tuples)
+	require.Equal(t, int64(2), stats.AuthorizationModels)
+	require.Equal(t, int64(6), stats.Changes)
+}
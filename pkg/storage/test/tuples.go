@@ -3,6 +3,8 @@ package test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/openfga/openfga/pkg/tuple"
 	"github.com/openfga/openfga/pkg/typesystem"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func ReadChangesTest(t *testing.T, datastore storage.OpenFGADatastore) {
@@ -129,6 +133,80 @@ func ReadChangesTest(t *testing.T, datastore storage.OpenFGADatastore) {
 			t.Errorf("mismatch (-want +got):\n%s", diff)
 		}
 	})
+
+	t.Run("read_changes_with_filter", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		viewer := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+		editor := tuple.NewTupleKey("document:1", "editor", "user:anne")
+		folderViewer := tuple.NewTupleKey("folder:1", "viewer", "user:anne")
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{viewer, editor, folderViewer})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{viewer}, nil)
+		require.NoError(t, err)
+
+		deleteOperation := openfgav1.TupleOperation_TUPLE_OPERATION_DELETE
+
+		changes, continuationToken, err := datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+		}, storage.PaginationOptions{PageSize: 1}, 0)
+		require.NoError(t, err)
+
+		expectedChanges := []*openfgav1.TupleChange{
+			{TupleKey: viewer, Operation: openfgav1.TupleOperation_TUPLE_OPERATION_WRITE},
+		}
+		if diff := cmp.Diff(expectedChanges, changes, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+
+		changes, _, err = datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+		}, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: string(continuationToken)}, 0)
+		require.NoError(t, err)
+
+		expectedChanges = []*openfgav1.TupleChange{
+			{TupleKey: viewer, Operation: deleteOperation},
+		}
+		if diff := cmp.Diff(expectedChanges, changes, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+
+		changes, _, err = datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{
+			Operation: &deleteOperation,
+		}, storage.PaginationOptions{PageSize: storage.DefaultPageSize}, 0)
+		require.NoError(t, err)
+		if diff := cmp.Diff(expectedChanges, changes, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+
+		_, _, err = datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{
+			StartTime: time.Now().Add(time.Minute),
+		}, storage.PaginationOptions{PageSize: storage.DefaultPageSize}, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		changes, _, err = datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{
+			StartTime: time.Now().Add(-time.Minute),
+		}, storage.PaginationOptions{PageSize: storage.DefaultPageSize}, 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+	})
+
+	t.Run("read_changes_with_filter_rejects_the_tokens_of_another_object_type", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tuple.NewTupleKey("document:1", "viewer", "user:anne")})
+		require.NoError(t, err)
+
+		_, continuationToken, err := datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{ObjectType: "document"}, storage.PaginationOptions{PageSize: 1}, 0)
+		require.NoError(t, err)
+
+		_, _, err = datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{ObjectType: "folder"}, storage.PaginationOptions{PageSize: 1, From: string(continuationToken)}, 0)
+		require.ErrorIs(t, err, storage.ErrMismatchObjectType)
+	})
 }
 
 func TupleWritingAndReadingTest(t *testing.T, datastore storage.OpenFGADatastore) {
@@ -554,6 +632,86 @@ func TuplePaginationOptionsTest(t *testing.T, datastore storage.OpenFGADatastore
 	})
 }
 
+func ReadPageWithFilterTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+
+	tupleKeys := []*openfgav1.TupleKey{
+		tuple.NewTupleKey("group:eng", "member", "user:anne"),
+		tuple.NewTupleKey("group:eng", "member", "user:*"),
+		tuple.NewTupleKey("group:eng", "member", "group:fga#member"),
+		tuple.NewTupleKey("group:eng", "admin", "user:bob"),
+		tuple.NewTupleKey("group:eng", "owner", "user:carl"),
+		tuple.NewTupleKey("group:eng", "member", "employee:dan"),
+		tuple.NewTupleKey("group:fga", "member", "user:erin"),
+	}
+
+	err := datastore.Write(ctx, storeID, nil, tupleKeys)
+	require.NoError(t, err)
+
+	readAll := func(t *testing.T, filter storage.ReadFilter) []*openfgav1.TupleKey {
+		var (
+			keys  []*openfgav1.TupleKey
+			token []byte
+		)
+		for {
+			tuples, contToken, err := datastore.ReadPageWithFilter(ctx, storeID, filter, storage.PaginationOptions{PageSize: 1, From: string(token)})
+			require.NoError(t, err)
+
+			for _, tup := range tuples {
+				keys = append(keys, tup.GetKey())
+			}
+
+			if len(contToken) == 0 {
+				return keys
+			}
+			token = contToken
+		}
+	}
+
+	t.Run("the_empty_filter_matches_every_tuple", func(t *testing.T) {
+		require.Len(t, readAll(t, storage.ReadFilter{}), len(tupleKeys))
+	})
+
+	t.Run("the_tuples_of_any_of_the_relations_are_matched", func(t *testing.T) {
+		got := readAll(t, storage.ReadFilter{Object: "group:eng", Relations: []string{"admin", "owner"}})
+
+		expected := []*openfgav1.TupleKey{tupleKeys[3], tupleKeys[4]}
+		if diff := cmp.Diff(expected, got, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("the_users_of_a_type_exclude_the_wildcard_and_the_usersets", func(t *testing.T) {
+		got := readAll(t, storage.ReadFilter{
+			Object:    "group:",
+			Relations: []string{"member"},
+			UserTypes: []*openfgav1.RelationReference{typesystem.DirectRelationReference("user", "")},
+		})
+
+		expected := []*openfgav1.TupleKey{tupleKeys[0], tupleKeys[6]}
+		if diff := cmp.Diff(expected, got, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("the_wildcards_and_the_usersets_are_matched_by_their_user_types", func(t *testing.T) {
+		got := readAll(t, storage.ReadFilter{
+			Object: "group:eng",
+			UserTypes: []*openfgav1.RelationReference{
+				typesystem.WildcardRelationReference("user"),
+				typesystem.DirectRelationReference("group", "member"),
+				typesystem.DirectRelationReference("employee", ""),
+			},
+		})
+
+		expected := []*openfgav1.TupleKey{tupleKeys[1], tupleKeys[2], tupleKeys[5]}
+		if diff := cmp.Diff(expected, got, cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
 func ReadStartingWithUserTest(t *testing.T, datastore storage.OpenFGADatastore) {
 	require := require.New(t)
 	ctx := context.Background()
@@ -690,3 +848,600 @@ func getObjects(tupleIterator storage.TupleIterator, require *require.Assertions
 	}
 	return objects
 }
+
+func TupleExpiryTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	tk := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+
+	t.Run("expired_tuples_are_not_read", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tk}, time.Now().Add(500*time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk)
+		require.NoError(t, err)
+
+		time.Sleep(time.Second)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		tuples, _, err := datastore.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Empty(t, tuples)
+
+		iter, err := datastore.ReadStartingWithUser(ctx, storeID, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}},
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		_, err = iter.Next()
+		require.ErrorIs(t, err, storage.ErrIteratorDone)
+	})
+
+	t.Run("expired_tuples_can_be_written_again_but_not_deleted", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tk}, time.Now().Add(500*time.Millisecond))
+		require.NoError(t, err)
+
+		time.Sleep(time.Second)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk}, nil)
+		require.ErrorContains(t, err, "cannot delete a tuple which does not exist")
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk})
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk)
+		require.NoError(t, err)
+	})
+
+	t.Run("expired_tuples_are_deleted", func(t *testing.T) {
+		storeID := ulid.Make().String()
+		tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+
+		err := datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tk}, time.Now().Add(500*time.Millisecond))
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+
+		time.Sleep(time.Second)
+
+		deleted, err := storage.NewTupleReaper(datastore, storage.WithTupleReaperBatchSize(1)).Reap(ctx)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, deleted, 1)
+
+		changes, _, err := datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: 10}, 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 3)
+		require.Equal(t, tk.GetObject(), changes[2].GetTupleKey().GetObject())
+		require.Equal(t, openfgav1.TupleOperation_TUPLE_OPERATION_DELETE, changes[2].GetOperation())
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.NoError(t, err)
+	})
+
+	t.Run("expirations_are_read", func(t *testing.T) {
+		storeID := ulid.Make().String()
+		tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+		tk3 := tuple.NewTupleKey("folder:folder1", "viewer", "user:jon")
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+		err := datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tk, tk3}, expiresAt)
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+
+		expirations, err := datastore.ReadTupleExpirations(ctx, storeID, &openfgav1.TupleKey{Object: "document:"})
+		require.NoError(t, err)
+		require.Len(t, expirations, 1)
+		require.True(t, expiresAt.Equal(expirations[tuple.TupleKeyToString(tk)]))
+
+		expirations, err = datastore.ReadTupleExpirations(ctx, storeID, &openfgav1.TupleKey{Object: "document:doc2"})
+		require.NoError(t, err)
+		require.Empty(t, expirations)
+	})
+
+	t.Run("expirations_of_the_tuples_read_are_recorded", func(t *testing.T) {
+		storeID := ulid.Make().String()
+		tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+		err := datastore.WriteWithExpiry(ctx, storeID, nil, []*openfgav1.TupleKey{tk}, expiresAt)
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+
+		expirations := &storage.TupleExpirations{}
+		_, err = datastore.ReadUserTuple(storage.ContextWithTupleExpirations(ctx, expirations), storeID, tk2)
+		require.NoError(t, err)
+		require.True(t, expirations.Earliest().IsZero())
+
+		_, err = datastore.ReadUserTuple(storage.ContextWithTupleExpirations(ctx, expirations), storeID, tk)
+		require.NoError(t, err)
+		require.True(t, expiresAt.Equal(expirations.Earliest()))
+
+		expirations = &storage.TupleExpirations{}
+		iter, err := datastore.ReadStartingWithUser(storage.ContextWithTupleExpirations(ctx, expirations), storeID, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}},
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		for {
+			_, err := iter.Next()
+			if errors.Is(err, storage.ErrIteratorDone) {
+				break
+			}
+			require.NoError(t, err)
+		}
+		require.True(t, expiresAt.Equal(expirations.Earliest()))
+	})
+}
+
+func ConditionalWriteTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	tk1 := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+
+	t.Run("duplicate_writes_are_ignored", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1, tk2})
+		require.ErrorIs(t, err, storage.ErrInvalidWriteInput)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1, tk2}, storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore))
+		require.NoError(t, err)
+
+		tuples, _, err := datastore.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: 10})
+		require.NoError(t, err)
+		require.Len(t, tuples, 2)
+
+		changes, _, err := datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: 10}, 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+	})
+
+	t.Run("the_last_change_committed_is_returned", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		var first string
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1}, storage.WithCommittedChangeULID(&first))
+		require.NoError(t, err)
+		_, err = ulid.ParseStrict(first)
+		require.NoError(t, err)
+
+		var second string
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1}, []*openfgav1.TupleKey{tk2}, storage.WithCommittedChangeULID(&second))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, second, first)
+
+		// a write which changed nothing has no change
+		var none string
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2}, storage.WithOnDuplicateInsert(storage.OnDuplicateInsertIgnore), storage.WithCommittedChangeULID(&none))
+		require.NoError(t, err)
+		require.Empty(t, none)
+	})
+
+	t.Run("missing_deletes_are_ignored", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1, tk2}, nil)
+		require.ErrorIs(t, err, storage.ErrInvalidWriteInput)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1, tk2}, nil, storage.WithOnMissingDelete(storage.OnMissingDeleteIgnore))
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		changes, _, err := datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: 10}, 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+	})
+}
+
+func TupleConditionTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	tk1 := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+	tk3 := tuple.NewTupleKey("document:doc1", "editor", "user:jon")
+
+	parameters, err := structpb.NewStruct(map[string]interface{}{"cidr": "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	condition := &storage.TupleCondition{
+		Expression: "inCIDR(context.ip, params.cidr)",
+		Parameters: parameters,
+	}
+
+	t.Run("conditions_are_read_for_the_matching_tuples", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.WriteWithCondition(ctx, storeID, nil, []*openfgav1.TupleKey{tk1, tk2}, condition)
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk3})
+		require.NoError(t, err)
+
+		// conditional tuples are read like any other tuple
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.NoError(t, err)
+
+		conditions, err := datastore.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{Object: "document:"})
+		require.NoError(t, err)
+		require.Len(t, conditions, 2)
+
+		got := conditions[tuple.TupleKeyToString(tk1)]
+		require.NotNil(t, got)
+		require.Equal(t, condition.Expression, got.Expression)
+		require.True(t, proto.Equal(condition.Parameters, got.Parameters))
+
+		conditions, err = datastore.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{Object: "document:doc1", Relation: "viewer", User: "user:jon"})
+		require.NoError(t, err)
+		require.Len(t, conditions, 1)
+		require.Contains(t, conditions, tuple.TupleKeyToString(tk1))
+
+		conditions, err = datastore.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{Object: "document:doc1", Relation: "editor"})
+		require.NoError(t, err)
+		require.Empty(t, conditions)
+	})
+
+	t.Run("conditions_are_deleted_with_their_tuples", func(t *testing.T) {
+		storeID := ulid.Make().String()
+
+		err := datastore.WriteWithCondition(ctx, storeID, nil, []*openfgav1.TupleKey{tk1}, &storage.TupleCondition{Expression: "true"})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1}, nil)
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+
+		conditions, err := datastore.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{Object: "document:doc1"})
+		require.NoError(t, err)
+		require.Empty(t, conditions)
+	})
+}
+
+func DeleteChangesTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+	otherStoreID := ulid.Make().String()
+
+	tk1 := tuple.NewTupleKey("document:1", "viewer", "user:anne")
+	tk2 := tuple.NewTupleKey("folder:1", "viewer", "user:anne")
+	tk3 := tuple.NewTupleKey("document:2", "viewer", "user:anne")
+
+	err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+	require.NoError(t, err)
+	err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2})
+	require.NoError(t, err)
+	err = datastore.Write(ctx, otherStoreID, nil, []*openfgav1.TupleKey{tk1})
+	require.NoError(t, err)
+
+	// the timestamps of the changes may be truncated to the second
+	time.Sleep(1 * time.Second)
+	before := time.Now()
+	time.Sleep(1 * time.Second)
+
+	err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk3})
+	require.NoError(t, err)
+
+	_, token, err := datastore.ReadChanges(ctx, storeID, "", storage.PaginationOptions{PageSize: 1}, 0)
+	require.NoError(t, err)
+
+	_, documentToken, err := datastore.ReadChanges(ctx, storeID, "document", storage.PaginationOptions{PageSize: 1}, 0)
+	require.NoError(t, err)
+
+	deleted, err := datastore.DeleteChanges(ctx, storeID, before, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+
+	deleted, err = datastore.DeleteChanges(ctx, storeID, before, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, deleted)
+
+	readKeys := func(objectType, from string) []*openfgav1.TupleKey {
+		changes, _, err := datastore.ReadChanges(ctx, storeID, objectType, storage.PaginationOptions{PageSize: storage.DefaultPageSize, From: from}, 0)
+		require.NoError(t, err)
+
+		var keys []*openfgav1.TupleKey
+		for _, change := range changes {
+			keys = append(keys, change.GetTupleKey())
+		}
+		return keys
+	}
+
+	t.Run("old_changes_are_deleted", func(t *testing.T) {
+		if diff := cmp.Diff([]*openfgav1.TupleKey{tk3}, readKeys("", ""), cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("continuation_tokens_remain_valid", func(t *testing.T) {
+		if diff := cmp.Diff([]*openfgav1.TupleKey{tk3}, readKeys("", string(token)), cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+
+		if diff := cmp.Diff([]*openfgav1.TupleKey{tk3}, readKeys("document", string(documentToken)), cmpOpts...); diff != "" {
+			t.Fatalf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("changes_of_other_stores_are_kept", func(t *testing.T) {
+		changes, _, err := datastore.ReadChanges(ctx, otherStoreID, "", storage.PaginationOptions{PageSize: storage.DefaultPageSize}, 0)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+	})
+}
+
+func SnapshotTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	storeID := ulid.Make().String()
+
+	tk1 := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:doc1", "viewer", "group:eng#member")
+	tk3 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+
+	err := datastore.WriteWithCondition(ctx, storeID, nil, []*openfgav1.TupleKey{tk1, tk2}, &storage.TupleCondition{Expression: "true"})
+	require.NoError(t, err)
+
+	snapshot, err := datastore.Snapshot(ctx, storeID)
+	require.NoError(t, err)
+	defer snapshot.Close()
+
+	readObjects := func(t *testing.T) []string {
+		iter, err := snapshot.ReadStartingWithUser(ctx, storeID, storage.ReadStartingWithUserFilter{
+			ObjectType: "document",
+			Relation:   "viewer",
+			UserFilter: []*openfgav1.ObjectRelation{{Object: "user:jon"}},
+		})
+		require.NoError(t, err)
+		defer iter.Stop()
+
+		var objects []string
+		for {
+			tup, err := iter.Next()
+			if err != nil {
+				require.ErrorIs(t, err, storage.ErrIteratorDone)
+				return objects
+			}
+			objects = append(objects, tup.GetKey().GetObject())
+		}
+	}
+
+	// the first read takes the snapshot at the latest
+	require.Equal(t, []string{"document:doc1"}, readObjects(t))
+
+	err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1, tk2}, []*openfgav1.TupleKey{tk3})
+	require.NoError(t, err)
+
+	t.Run("the_writes_after_the_snapshot_are_not_observed", func(t *testing.T) {
+		require.Equal(t, []string{"document:doc1"}, readObjects(t))
+
+		_, err := snapshot.ReadUserTuple(ctx, storeID, tk1)
+		require.NoError(t, err)
+
+		_, err = snapshot.ReadUserTuple(ctx, storeID, tk3)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		iter, err := snapshot.ReadUsersetTuples(ctx, storeID, storage.ReadUsersetTuplesFilter{Object: "document:doc1", Relation: "viewer"})
+		require.NoError(t, err)
+		tup, err := iter.Next()
+		require.NoError(t, err)
+		require.Equal(t, tk2.GetUser(), tup.GetKey().GetUser())
+		iter.Stop()
+
+		tuples, _, err := snapshot.ReadPage(ctx, storeID, &openfgav1.TupleKey{Object: "document:"}, storage.PaginationOptions{PageSize: storage.DefaultPageSize})
+		require.NoError(t, err)
+		require.Len(t, tuples, 2)
+
+		conditions, err := snapshot.ReadTupleConditions(ctx, storeID, &openfgav1.TupleKey{Object: "document:"})
+		require.NoError(t, err)
+		require.Len(t, conditions, 2)
+	})
+
+	t.Run("the_datastore_observes_the_writes", func(t *testing.T) {
+		_, err := datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk3)
+		require.NoError(t, err)
+	})
+}
+
+func StagedWriteTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	tk1 := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+	tk3 := tuple.NewTupleKey("document:doc3", "viewer", "group:eng#member")
+
+	t.Run("the_staged_batches_are_committed_together", func(t *testing.T) {
+		storeID := ulid.Make().String()
+		stagedID := ulid.Make().String()
+
+		err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+
+		err = datastore.StageWrite(ctx, storeID, stagedID, []*openfgav1.TupleKey{tk1}, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+		err = datastore.StageWrite(ctx, storeID, stagedID, nil, []*openfgav1.TupleKey{tk3})
+		require.NoError(t, err)
+
+		// nothing is written before the commit
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.NoError(t, err)
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		err = datastore.CommitStagedWrite(ctx, storeID, stagedID)
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.NoError(t, err)
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk3)
+		require.NoError(t, err)
+
+		// the committed staged write is discarded
+		err = datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk2, tk3}, nil)
+		require.NoError(t, err)
+		err = datastore.CommitStagedWrite(ctx, storeID, stagedID)
+		require.NoError(t, err)
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("a_failed_commit_applies_nothing", func(t *testing.T) {
+		storeID := ulid.Make().String()
+		stagedID := ulid.Make().String()
+
+		err := datastore.StageWrite(ctx, storeID, stagedID, []*openfgav1.TupleKey{tk1}, []*openfgav1.TupleKey{tk2})
+		require.NoError(t, err)
+
+		err = datastore.CommitStagedWrite(ctx, storeID, stagedID)
+		var invalid *storage.InvalidWriteInput
+		require.ErrorAs(t, err, &invalid)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		// the staged write is kept until it is discarded
+		err = datastore.CommitStagedWrite(ctx, storeID, stagedID)
+		require.ErrorAs(t, err, &invalid)
+
+		err = datastore.DiscardStagedWrite(ctx, storeID, stagedID)
+		require.NoError(t, err)
+		err = datastore.CommitStagedWrite(ctx, storeID, stagedID)
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk2)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("the_staged_batches_are_limited", func(t *testing.T) {
+		writes := make([]*openfgav1.TupleKey, datastore.MaxTuplesPerWrite()+1)
+		for i := range writes {
+			writes[i] = tuple.NewTupleKey(fmt.Sprintf("document:doc%d", i), "viewer", "user:jon")
+		}
+
+		err := datastore.StageWrite(ctx, ulid.Make().String(), ulid.Make().String(), nil, writes)
+		require.ErrorIs(t, err, storage.ErrExceededWriteBatchLimit)
+	})
+}
+
+func ExpectedChangelogTokenTest(t *testing.T, datastore storage.OpenFGADatastore) {
+	ctx := context.Background()
+
+	storeID := ulid.Make().String()
+
+	tk1 := tuple.NewTupleKey("document:doc1", "viewer", "user:jon")
+	tk2 := tuple.NewTupleKey("document:doc2", "viewer", "user:jon")
+	tk3 := tuple.NewTupleKey("folder:folder1", "viewer", "user:jon")
+
+	// the store has no changes yet
+	err := datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk1}, storage.WithExpectedChangelogToken(""))
+	if errors.Is(err, storage.ErrExpectedChangelogTokenUnsupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	latestToken := func(t *testing.T) string {
+		_, token, err := datastore.ReadChangesWithFilter(ctx, storeID, storage.ReadChangesFilter{}, storage.PaginationOptions{PageSize: 100}, 0)
+		require.NoError(t, err)
+		return string(token)
+	}
+
+	token := latestToken(t)
+
+	err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2}, storage.WithExpectedChangelogToken(""))
+	require.ErrorIs(t, err, storage.ErrChangelogConflict)
+
+	err = datastore.Write(ctx, storeID, nil, []*openfgav1.TupleKey{tk2}, storage.WithExpectedChangelogToken(token))
+	require.NoError(t, err)
+
+	t.Run("a_stale_token_fails_the_write", func(t *testing.T) {
+		err := datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1}, []*openfgav1.TupleKey{tk3}, storage.WithExpectedChangelogToken(token))
+		require.ErrorIs(t, err, storage.ErrChangelogConflict)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk1)
+		require.NoError(t, err)
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk3)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("the_latest_token_applies_the_write", func(t *testing.T) {
+		err := datastore.Write(ctx, storeID, []*openfgav1.TupleKey{tk1}, []*openfgav1.TupleKey{tk3}, storage.WithExpectedChangelogToken(latestToken(t)))
+		require.NoError(t, err)
+
+		_, err = datastore.ReadUserTuple(ctx, storeID, tk3)
+		require.NoError(t, err)
+	})
+
+	t.Run("a_concurrent_conditional_write_fails_the_write", func(t *testing.T) {
+		store, err := datastore.CreateStore(ctx, &openfgav1.Store{Id: ulid.Make().String(), Name: "openfga-test"})
+		require.NoError(t, err)
+
+		err = datastore.Write(ctx, store.Id, nil, []*openfgav1.TupleKey{tk1})
+		require.NoError(t, err)
+
+		for i := 0; i < 20; i++ {
+			_, token, err := datastore.ReadChangesWithFilter(ctx, store.Id, storage.ReadChangesFilter{}, storage.PaginationOptions{PageSize: 1000}, 0)
+			require.NoError(t, err)
+
+			first := tuple.NewTupleKey(fmt.Sprintf("document:a%d", i), "viewer", "user:jon")
+			second := tuple.NewTupleKey(fmt.Sprintf("document:b%d", i), "viewer", "user:jon")
+
+			var wg sync.WaitGroup
+			var firstErr, secondErr error
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				firstErr = datastore.Write(ctx, store.Id, nil, []*openfgav1.TupleKey{first}, storage.WithExpectedChangelogToken(string(token)))
+			}()
+			go func() {
+				defer wg.Done()
+				secondErr = datastore.Write(ctx, store.Id, nil, []*openfgav1.TupleKey{second}, storage.WithExpectedChangelogToken(string(token)))
+			}()
+			wg.Wait()
+
+			// only one of the writes is applied
+			changes, _, err := datastore.ReadChangesWithFilter(ctx, store.Id, storage.ReadChangesFilter{}, storage.PaginationOptions{PageSize: 10, From: string(token)}, 0)
+			require.NoError(t, err)
+			require.Len(t, changes, 1)
+
+			if firstErr != nil {
+				require.ErrorIs(t, firstErr, storage.ErrChangelogConflict)
+				require.NoError(t, secondErr)
+				require.Equal(t, second.GetObject(), changes[0].GetTupleKey().GetObject())
+			} else {
+				require.ErrorIs(t, secondErr, storage.ErrChangelogConflict)
+				require.Equal(t, first.GetObject(), changes[0].GetTupleKey().GetObject())
+			}
+		}
+	})
+}
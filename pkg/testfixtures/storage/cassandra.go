@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/gocql/gocql"
+	"github.com/oklog/ulid/v2"
+	"github.com/openfga/openfga/assets"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	cassandraImage    = "cassandra:4.1"
+	cassandraKeyspace = "openfga"
+)
+
+type cassandraTestContainer struct {
+	addr     string
+	username string
+	password string
+}
+
+// NewCassandraTestContainer returns an implementation of the DatastoreTestContainer interface
+// for Cassandra.
+func NewCassandraTestContainer() *cassandraTestContainer {
+	return &cassandraTestContainer{}
+}
+
+// GetDatabaseSchemaVersion returns 1, as the Cassandra schema is not versioned.
+func (c *cassandraTestContainer) GetDatabaseSchemaVersion() int64 {
+	return 1
+}
+
+// RunCassandraTestContainer runs a Cassandra container, connects to it, and returns a
+// bootstrapped implementation of the DatastoreTestContainer interface wired up for the
+// Cassandra datastore engine.
+func (c *cassandraTestContainer) RunCassandraTestContainer(t testing.TB) DatastoreTestContainer {
+	dockerClient, err := client.NewClientWithOpts(
+		client.FromEnv,
+		client.WithAPIVersionNegotiation(),
+	)
+	require.NoError(t, err)
+
+	allImages, err := dockerClient.ImageList(context.Background(), types.ImageListOptions{
+		All: true,
+	})
+	require.NoError(t, err)
+
+	foundCassandraImage := false
+	for _, image := range allImages {
+		for _, tag := range image.RepoTags {
+			if strings.Contains(tag, cassandraImage) {
+				foundCassandraImage = true
+				break
+			}
+		}
+	}
+
+	if !foundCassandraImage {
+		t.Logf("Pulling image %s", cassandraImage)
+		reader, err := dockerClient.ImagePull(context.Background(), cassandraImage, types.ImagePullOptions{})
+		require.NoError(t, err)
+
+		_, err = io.Copy(io.Discard, reader) // consume the image pull output to make sure it's done
+		require.NoError(t, err)
+	}
+
+	containerCfg := container.Config{
+		Env: []string{
+			"MAX_HEAP_SIZE=512M",
+			"HEAP_NEWSIZE=128M",
+		},
+		ExposedPorts: nat.PortSet{
+			nat.Port("9042/tcp"): {},
+		},
+		Image: cassandraImage,
+	}
+
+	hostCfg := container.HostConfig{
+		AutoRemove:      true,
+		PublishAllPorts: true,
+	}
+
+	name := fmt.Sprintf("cassandra-%s", ulid.Make().String())
+
+	cont, err := dockerClient.ContainerCreate(context.Background(), &containerCfg, &hostCfg, nil, nil, name)
+	require.NoError(t, err, "failed to create cassandra docker container")
+
+	stopContainer := func() {
+		t.Logf("stopping container %s", name)
+		timeoutSec := 5
+
+		err := dockerClient.ContainerStop(context.Background(), cont.ID, container.StopOptions{Timeout: &timeoutSec})
+		if err != nil && !client.IsErrNotFound(err) {
+			t.Logf("failed to stop cassandra container: %v", err)
+		}
+
+		dockerClient.Close()
+		t.Logf("stopped container %s", name)
+	}
+
+	err = dockerClient.ContainerStart(context.Background(), cont.ID, types.ContainerStartOptions{})
+	if err != nil {
+		stopContainer()
+		t.Fatalf("failed to start cassandra container: %v", err)
+	}
+
+	containerJSON, err := dockerClient.ContainerInspect(context.Background(), cont.ID)
+	require.NoError(t, err)
+
+	m, ok := containerJSON.NetworkSettings.Ports["9042/tcp"]
+	if !ok || len(m) == 0 {
+		t.Fatalf("failed to get host port mapping from cassandra container")
+	}
+
+	// spin up a goroutine to survive any test panics to expire/stop the running container
+	go func() {
+		time.Sleep(expireTimeout)
+		timeoutSec := 0
+
+		t.Logf("expiring container %s", name)
+		err := dockerClient.ContainerStop(context.Background(), cont.ID, container.StopOptions{Timeout: &timeoutSec})
+		if err != nil && !client.IsErrNotFound(err) {
+			t.Logf("failed to expire cassandra container: %v", err)
+		}
+		t.Logf("expired container %s", name)
+	}()
+
+	t.Cleanup(func() {
+		stopContainer()
+	})
+
+	cassandraTestContainer := &cassandraTestContainer{
+		addr: fmt.Sprintf("localhost:%s", m[0].HostPort),
+	}
+
+	cluster := gocql.NewCluster(cassandraTestContainer.addr)
+	cluster.Consistency = gocql.One
+
+	// cassandra takes a while to accept connections
+	var session *gocql.Session
+	backoffPolicy := backoff.NewExponentialBackOff()
+	backoffPolicy.MaxElapsedTime = 3 * time.Minute
+	err = backoff.Retry(
+		func() error {
+			session, err = cluster.CreateSession()
+			return err
+		},
+		backoffPolicy,
+	)
+	if err != nil {
+		stopContainer()
+		t.Fatalf("failed to connect to cassandra container: %v", err)
+	}
+
+	err = session.Query(fmt.Sprintf(
+		"CREATE KEYSPACE IF NOT EXISTS %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		cassandraKeyspace,
+	)).Exec()
+	require.NoError(t, err)
+	session.Close()
+
+	cluster.Keyspace = cassandraKeyspace
+	session, err = cluster.CreateSession()
+	require.NoError(t, err)
+	defer session.Close()
+
+	// the migrations are run as by cassandra.Migrate, which this package can't import as its tests use this package
+	entries, err := fs.ReadDir(assets.EmbedMigrations, assets.CassandraMigrationDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		script, err := fs.ReadFile(assets.EmbedMigrations, path.Join(assets.CassandraMigrationDir, entry.Name()))
+		require.NoError(t, err)
+
+		var lines []string
+		for _, line := range strings.Split(string(script), "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				lines = append(lines, line)
+			}
+		}
+
+		for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+			if stmt = strings.TrimSpace(stmt); stmt != "" {
+				require.NoError(t, session.Query(stmt).Exec())
+			}
+		}
+	}
+
+	return cassandraTestContainer
+}
+
+// GetConnectionURI returns the cassandra connection uri for the running cassandra test container.
+func (c *cassandraTestContainer) GetConnectionURI(includeCredentials bool) string {
+	creds := ""
+	if includeCredentials && c.username != "" {
+		creds = fmt.Sprintf("%s:%s@", c.username, c.password)
+	}
+
+	return fmt.Sprintf(
+		"cassandra://%s%s/%s?consistency=one",
+		creds,
+		c.addr,
+		cassandraKeyspace,
+	)
+}
+
+func (c *cassandraTestContainer) GetUsername() string {
+	return c.username
+}
+
+func (c *cassandraTestContainer) GetPassword() string {
+	return c.password
+}
@@ -46,6 +46,8 @@ func RunDatastoreTestContainer(t testing.TB, engine string) DatastoreTestContain
 		return NewMySQLTestContainer().RunMySQLTestContainer(t)
 	case "postgres":
 		return NewPostgresTestContainer().RunPostgresTestContainer(t)
+	case "cassandra":
+		return NewCassandraTestContainer().RunCassandraTestContainer(t)
 	case "memory":
 		return memoryTestContainer{}
 	default:
@@ -360,3 +360,34 @@ func TestGetObjectRelationAsString(t *testing.T) {
 		})
 	}
 }
+
+func FuzzSplitObjectRelation(f *testing.F) {
+	for _, s := range []string{"", "document:1", "group:eng#member", "user:*", "*", ":", "#", "a:b:c#d#e", "document:sand castle", "document:1#"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		objectType, objectID := SplitObject(s)
+		if objectType != "" {
+			require.Equal(t, s, BuildObject(objectType, objectID))
+		}
+
+		object, relation := SplitObjectRelation(s)
+		if relation != "" {
+			require.Equal(t, s, ToObjectRelationString(object, relation))
+		}
+
+		if IsValidObject(s) {
+			require.NotEmpty(t, objectType)
+			require.NotEmpty(t, objectID)
+			require.True(t, IsValidUser(s))
+		}
+
+		if IsTypedWildcard(s) {
+			require.True(t, IsWildcard(s))
+		}
+
+		_ = IsValidRelation(s)
+		_ = GetUserTypeFromUser(s)
+	})
+}
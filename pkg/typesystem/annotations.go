@@ -0,0 +1,88 @@
+package typesystem
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+// Well-known annotations of types and relations. Any other key may be used, the values of these are only
+// interpreted by tooling and by the warnings of the server.
+const (
+	// DescriptionAnnotation describes what a type or a relation represents.
+	DescriptionAnnotation = "description"
+
+	// OwnerAnnotation names the team or the person responsible for a type or a relation.
+	OwnerAnnotation = "owner"
+
+	// DeprecatedAnnotation marks a type or a relation as deprecated. Its value explains the deprecation, e.g.
+	// which relation replaces the deprecated one.
+	DeprecatedAnnotation = "deprecated"
+
+	// ModuleAnnotation names the module that a type was included from, see FlattenModules. It is set by the
+	// server when the model is written.
+	ModuleAnnotation = "module"
+)
+
+var ErrInvalidAnnotations = errors.New("invalid annotations")
+
+// ValidateAnnotations returns an error wrapping ErrInvalidAnnotations if the annotations reference a type or
+// a relation that is not defined in the model of the TypeSystem, or contain an empty key.
+func (t *TypeSystem) ValidateAnnotations(annotations storage.ModelAnnotations) error {
+	for objectType, typeAnnotations := range annotations {
+		if _, ok := t.GetTypeDefinition(objectType); !ok {
+			return fmt.Errorf("%w: the type '%s' is not defined", ErrInvalidAnnotations, objectType)
+		}
+
+		if _, ok := typeAnnotations.GetAnnotations()[""]; ok {
+			return fmt.Errorf("%w: the type '%s' has an annotation with an empty key", ErrInvalidAnnotations, objectType)
+		}
+
+		for relation, relationAnnotations := range typeAnnotations.GetRelations() {
+			if _, err := t.GetRelation(objectType, relation); err != nil {
+				return fmt.Errorf("%w: the relation '%s' is not defined on the type '%s'", ErrInvalidAnnotations, relation, objectType)
+			}
+
+			if _, ok := relationAnnotations[""]; ok {
+				return fmt.Errorf("%w: the relation '%s#%s' has an annotation with an empty key", ErrInvalidAnnotations, objectType, relation)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetAnnotations returns the annotations of the types and relations of the model, which are empty if the model
+// was written without annotations. The returned annotations must not be modified.
+func (t *TypeSystem) GetAnnotations() storage.ModelAnnotations {
+	if t.annotations == nil {
+		return storage.ModelAnnotations{}
+	}
+
+	return t.annotations
+}
+
+// GetTypeAnnotations returns the annotations of the provided type, or nil if it has none. The returned
+// annotations must not be modified.
+func (t *TypeSystem) GetTypeAnnotations(objectType string) map[string]string {
+	return t.annotations[objectType].GetAnnotations()
+}
+
+// GetRelationAnnotations returns the annotations of the provided relation, or nil if it has none. The returned
+// annotations must not be modified.
+func (t *TypeSystem) GetRelationAnnotations(objectType, relation string) map[string]string {
+	return t.annotations[objectType].GetRelations()[relation]
+}
+
+// GetDeprecation reports whether the provided relation, or the type it is defined on, is annotated with
+// DeprecatedAnnotation, and returns the explanation of the deprecation. The deprecation of the relation takes
+// precedence over the deprecation of its type.
+func (t *TypeSystem) GetDeprecation(objectType, relation string) (string, bool) {
+	if deprecation, ok := t.GetRelationAnnotations(objectType, relation)[DeprecatedAnnotation]; ok {
+		return deprecation, true
+	}
+
+	deprecation, ok := t.GetTypeAnnotations(objectType)[DeprecatedAnnotation]
+	return deprecation, ok
+}
@@ -0,0 +1,95 @@
+package typesystem
+
+import (
+	"testing"
+
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotations(t *testing.T) {
+	model := modelFromDSL(`
+	type user
+
+	type team
+	  relations
+	    define member: [user] as self
+
+	type document
+	  relations
+	    define viewer: [user] as self
+	    define reader: [user] as self
+	`)
+
+	annotations := storage.ModelAnnotations{
+		"team": {
+			Annotations: map[string]string{DeprecatedAnnotation: "use groups"},
+		},
+		"document": {
+			Annotations: map[string]string{OwnerAnnotation: "docs-team"},
+			Relations: map[string]map[string]string{
+				"reader": {
+					DescriptionAnnotation: "who can read the document",
+					DeprecatedAnnotation:  "use viewer instead",
+				},
+			},
+		},
+	}
+
+	t.Run("accessors", func(t *testing.T) {
+		typesys := NewWithAnnotations(model, annotations)
+
+		require.Equal(t, annotations, typesys.GetAnnotations())
+		require.Equal(t, map[string]string{OwnerAnnotation: "docs-team"}, typesys.GetTypeAnnotations("document"))
+		require.Nil(t, typesys.GetTypeAnnotations("user"))
+		require.Equal(t, "who can read the document", typesys.GetRelationAnnotations("document", "reader")[DescriptionAnnotation])
+		require.Nil(t, typesys.GetRelationAnnotations("document", "viewer"))
+		require.Nil(t, typesys.GetRelationAnnotations("folder", "viewer"))
+	})
+
+	t.Run("deprecation", func(t *testing.T) {
+		typesys := NewWithAnnotations(model, annotations)
+
+		deprecation, ok := typesys.GetDeprecation("document", "reader")
+		require.True(t, ok)
+		require.Equal(t, "use viewer instead", deprecation)
+
+		_, ok = typesys.GetDeprecation("document", "viewer")
+		require.False(t, ok)
+
+		deprecation, ok = typesys.GetDeprecation("team", "member")
+		require.True(t, ok)
+		require.Equal(t, "use groups", deprecation)
+	})
+
+	t.Run("model_without_annotations", func(t *testing.T) {
+		typesys := New(model)
+
+		require.Empty(t, typesys.GetAnnotations())
+		require.Nil(t, typesys.GetRelationAnnotations("document", "reader"))
+
+		_, ok := typesys.GetDeprecation("document", "reader")
+		require.False(t, ok)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		typesys := New(model)
+
+		require.NoError(t, typesys.ValidateAnnotations(annotations))
+
+		err := typesys.ValidateAnnotations(storage.ModelAnnotations{
+			"folder": {Annotations: map[string]string{OwnerAnnotation: "docs-team"}},
+		})
+		require.ErrorIs(t, err, ErrInvalidAnnotations)
+
+		err = typesys.ValidateAnnotations(storage.ModelAnnotations{
+			"document": {Relations: map[string]map[string]string{"editor": {OwnerAnnotation: "docs-team"}}},
+		})
+		require.ErrorIs(t, err, ErrInvalidAnnotations)
+
+		err = typesys.ValidateAnnotations(storage.ModelAnnotations{
+			"document": {Relations: map[string]map[string]string{"viewer": {"": "empty"}}},
+		})
+		require.ErrorIs(t, err, ErrInvalidAnnotations)
+	})
+}
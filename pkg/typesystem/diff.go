@@ -0,0 +1,242 @@
+package typesystem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ModelDiff is the structured difference between two authorization models.
+type ModelDiff struct {
+	AddedTypes       []string          `json:"added_types"`
+	RemovedTypes     []string          `json:"removed_types"`
+	AddedRelations   []string          `json:"added_relations"`
+	RemovedRelations []string          `json:"removed_relations"`
+	ChangedRelations []*RelationChange `json:"changed_relations"`
+
+	// PotentiallyExpandsAccess is true if any of the changed relations may grant access that the previous
+	// model did not.
+	PotentiallyExpandsAccess bool `json:"potentially_expands_access"`
+}
+
+// RelationChange is the change of the definition of a relation which exists in both models. The relations and
+// type restrictions are written as 'type#relation'.
+type RelationChange struct {
+	ObjectType string `json:"object_type"`
+	Relation   string `json:"relation"`
+
+	// FromRewrite and ToRewrite are the rewrites of the relation, in the DSL, if they changed.
+	FromRewrite string `json:"from_rewrite,omitempty"`
+	ToRewrite   string `json:"to_rewrite,omitempty"`
+
+	AddedTypeRestrictions   []string `json:"added_type_restrictions,omitempty"`
+	RemovedTypeRestrictions []string `json:"removed_type_restrictions,omitempty"`
+
+	// PotentiallyExpandsAccess is true unless the change provably narrows the relation: removing type
+	// restrictions, intersecting or excluding the previous rewrite, or removing operands of a union.
+	// Since the other relations may be defined in terms of this relation, their access may change too.
+	PotentiallyExpandsAccess bool `json:"potentially_expands_access"`
+}
+
+// Diff returns the difference between the from and to models. The types and relations are sorted.
+func Diff(from, to *openfgav1.AuthorizationModel) *ModelDiff {
+	fromTypes := New(from).typeDefinitions
+	toTypes := New(to).typeDefinitions
+
+	diff := &ModelDiff{
+		AddedTypes:       []string{},
+		RemovedTypes:     []string{},
+		AddedRelations:   []string{},
+		RemovedRelations: []string{},
+		ChangedRelations: []*RelationChange{},
+	}
+
+	for _, typeName := range sortedKeys(fromTypes, toTypes) {
+		fromTypedef, inFrom := fromTypes[typeName]
+		toTypedef, inTo := toTypes[typeName]
+
+		switch {
+		case !inFrom:
+			diff.AddedTypes = append(diff.AddedTypes, typeName)
+		case !inTo:
+			diff.RemovedTypes = append(diff.RemovedTypes, typeName)
+		}
+
+		fromRelations := relationsOf(fromTypedef)
+		toRelations := relationsOf(toTypedef)
+		for _, relationName := range sortedKeys(fromRelations, toRelations) {
+			fromRelation, inFrom := fromRelations[relationName]
+			toRelation, inTo := toRelations[relationName]
+
+			switch {
+			case !inFrom:
+				diff.AddedRelations = append(diff.AddedRelations, fmt.Sprintf("%s#%s", typeName, relationName))
+			case !inTo:
+				diff.RemovedRelations = append(diff.RemovedRelations, fmt.Sprintf("%s#%s", typeName, relationName))
+			default:
+				if change := diffRelation(typeName, fromRelation, toRelation); change != nil {
+					diff.ChangedRelations = append(diff.ChangedRelations, change)
+					diff.PotentiallyExpandsAccess = diff.PotentiallyExpandsAccess || change.PotentiallyExpandsAccess
+				}
+			}
+		}
+	}
+
+	return diff
+}
+
+// relationsOf returns the relations of the type definition with their type information, or nil.
+func relationsOf(typedef *openfgav1.TypeDefinition) map[string]*openfgav1.Relation {
+	if typedef == nil {
+		return nil
+	}
+
+	relations := make(map[string]*openfgav1.Relation, len(typedef.GetRelations()))
+	for name, rewrite := range typedef.GetRelations() {
+		relations[name] = &openfgav1.Relation{
+			Name:     name,
+			Rewrite:  rewrite,
+			TypeInfo: &openfgav1.RelationTypeInfo{DirectlyRelatedUserTypes: typedef.GetMetadata().GetRelations()[name].GetDirectlyRelatedUserTypes()},
+		}
+	}
+
+	return relations
+}
+
+func diffRelation(typeName string, from, to *openfgav1.Relation) *RelationChange {
+	change := &RelationChange{ObjectType: typeName, Relation: from.GetName()}
+
+	rewriteChanged := !proto.Equal(from.GetRewrite(), to.GetRewrite())
+	if rewriteChanged {
+		change.FromRewrite = RewriteString(from.GetRewrite())
+		change.ToRewrite = RewriteString(to.GetRewrite())
+	}
+
+	fromRestrictions := typeRestrictions(from)
+	toRestrictions := typeRestrictions(to)
+	for _, restriction := range sortedKeys(fromRestrictions, toRestrictions) {
+		switch {
+		case !fromRestrictions[restriction]:
+			change.AddedTypeRestrictions = append(change.AddedTypeRestrictions, restriction)
+		case !toRestrictions[restriction]:
+			change.RemovedTypeRestrictions = append(change.RemovedTypeRestrictions, restriction)
+		}
+	}
+
+	if !rewriteChanged && len(change.AddedTypeRestrictions) == 0 && len(change.RemovedTypeRestrictions) == 0 {
+		return nil
+	}
+
+	change.PotentiallyExpandsAccess = len(change.AddedTypeRestrictions) > 0 ||
+		(rewriteChanged && !narrows(from.GetRewrite(), to.GetRewrite()))
+
+	return change
+}
+
+func typeRestrictions(relation *openfgav1.Relation) map[string]bool {
+	restrictions := map[string]bool{}
+	for _, rr := range relation.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+		if rr.GetRelationOrWildcard() == nil {
+			restrictions[rr.GetType()] = true
+			continue
+		}
+
+		restrictions[GetRelationReferenceAsString(rr)] = true
+	}
+
+	return restrictions
+}
+
+// narrows returns true if the to rewrite provably grants a subset of the access granted by the from rewrite.
+func narrows(from, to *openfgav1.Userset) bool {
+	switch rw := to.GetUserset().(type) {
+	case *openfgav1.Userset_Intersection:
+		// 'from and x'
+		for _, child := range rw.Intersection.GetChild() {
+			if proto.Equal(child, from) {
+				return true
+			}
+		}
+	case *openfgav1.Userset_Difference:
+		// 'from but not x'
+		return proto.Equal(rw.Difference.GetBase(), from)
+	case *openfgav1.Userset_Union:
+		// a union of some of the operands of the previous union
+		fromUnion, ok := from.GetUserset().(*openfgav1.Userset_Union)
+		if !ok {
+			return false
+		}
+
+		for _, child := range rw.Union.GetChild() {
+			if !containsUserset(fromUnion.Union.GetChild(), child) {
+				return false
+			}
+		}
+		return true
+	}
+
+	// one of the operands of the previous union
+	if fromUnion, ok := from.GetUserset().(*openfgav1.Userset_Union); ok {
+		return containsUserset(fromUnion.Union.GetChild(), to)
+	}
+
+	return false
+}
+
+func containsUserset(usersets []*openfgav1.Userset, userset *openfgav1.Userset) bool {
+	for _, u := range usersets {
+		if proto.Equal(u, userset) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RewriteString returns the rewrite written in the DSL, e.g. '(editor or viewer from parent)'.
+func RewriteString(rewrite *openfgav1.Userset) string {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This:
+		return "self"
+	case *openfgav1.Userset_ComputedUserset:
+		return rw.ComputedUserset.GetRelation()
+	case *openfgav1.Userset_TupleToUserset:
+		return fmt.Sprintf("%s from %s", rw.TupleToUserset.GetComputedUserset().GetRelation(), rw.TupleToUserset.GetTupleset().GetRelation())
+	case *openfgav1.Userset_Union:
+		return joinRewrites(rw.Union.GetChild(), " or ")
+	case *openfgav1.Userset_Intersection:
+		return joinRewrites(rw.Intersection.GetChild(), " and ")
+	case *openfgav1.Userset_Difference:
+		return joinRewrites([]*openfgav1.Userset{rw.Difference.GetBase(), rw.Difference.GetSubtract()}, " but not ")
+	}
+
+	return ""
+}
+
+func joinRewrites(children []*openfgav1.Userset, operator string) string {
+	operands := make([]string, 0, len(children))
+	for _, child := range children {
+		operands = append(operands, RewriteString(child))
+	}
+
+	return "(" + strings.Join(operands, operator) + ")"
+}
+
+// sortedKeys returns the sorted union of the keys of the maps.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
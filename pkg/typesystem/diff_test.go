@@ -0,0 +1,104 @@
+package typesystem
+
+import (
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func modelFromDSL(dsl string) *openfgav1.AuthorizationModel {
+	return &openfgav1.AuthorizationModel{
+		SchemaVersion:   SchemaVersion1_1,
+		TypeDefinitions: parser.MustParse(dsl),
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := modelFromDSL(`
+	type user
+	type team
+	  relations
+	    define member: [user] as self
+
+	type document
+	  relations
+	    define owner: [user] as self
+	    define editor: [user] as self or owner
+	    define viewer: [user] as self
+	    define archived: [user] as self
+	`)
+
+	t.Run("identical_models", func(t *testing.T) {
+		diff := Diff(from, from)
+		require.Empty(t, diff.AddedTypes)
+		require.Empty(t, diff.RemovedTypes)
+		require.Empty(t, diff.AddedRelations)
+		require.Empty(t, diff.RemovedRelations)
+		require.Empty(t, diff.ChangedRelations)
+		require.False(t, diff.PotentiallyExpandsAccess)
+	})
+
+	t.Run("narrowing_changes", func(t *testing.T) {
+		to := modelFromDSL(`
+		type user
+		type folder
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define editor as owner
+		    define viewer: [user] as self but not archived
+		    define archived: [user] as self
+		    define commenter: [user] as self
+		`)
+
+		diff := Diff(from, to)
+		require.Equal(t, []string{"folder"}, diff.AddedTypes)
+		require.Equal(t, []string{"team"}, diff.RemovedTypes)
+		require.Equal(t, []string{"document#commenter"}, diff.AddedRelations)
+		require.Equal(t, []string{"team#member"}, diff.RemovedRelations)
+		require.Equal(t, []*RelationChange{
+			{
+				ObjectType:              "document",
+				Relation:                "editor",
+				FromRewrite:             "(self or owner)",
+				ToRewrite:               "owner",
+				RemovedTypeRestrictions: []string{"user"},
+			},
+			{
+				ObjectType:  "document",
+				Relation:    "viewer",
+				FromRewrite: "self",
+				ToRewrite:   "(self but not archived)",
+			},
+		}, diff.ChangedRelations)
+		require.False(t, diff.PotentiallyExpandsAccess)
+	})
+
+	t.Run("expanding_changes", func(t *testing.T) {
+		to := modelFromDSL(`
+		type user
+		type team
+		  relations
+		    define member: [user] as self
+
+		type document
+		  relations
+		    define owner: [user] as self
+		    define editor: [user] as self or owner
+		    define viewer: [user, user:*, team#member] as self or editor
+		    define archived: [user] as self
+		`)
+
+		diff := Diff(from, to)
+		require.Len(t, diff.ChangedRelations, 1)
+
+		change := diff.ChangedRelations[0]
+		require.Equal(t, "viewer", change.Relation)
+		require.Equal(t, []string{"team#member", "user:*"}, change.AddedTypeRestrictions)
+		require.True(t, change.PotentiallyExpandsAccess)
+		require.True(t, diff.PotentiallyExpandsAccess)
+	})
+}
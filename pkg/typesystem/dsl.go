@@ -0,0 +1,149 @@
+package typesystem
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// ErrInvalidDSL is returned by ParseDSL for the models with syntax errors.
+var ErrInvalidDSL = errors.New("invalid authorization model DSL")
+
+// ErrDSLSchemaVersion is returned by FormatDSL for the models whose schema version cannot be written in the DSL.
+var ErrDSLSchemaVersion = fmt.Errorf("only the models of schema version %s can be written in the DSL", SchemaVersion1_1)
+
+// ParseDSL parses an authorization model written in the DSL, e.g.
+//
+//	type user
+//
+//	type document
+//	  relations
+//	    define parent: [folder] as self
+//	    define viewer: [user, group#member] as self or viewer from parent
+//
+// The model is of schema version 1.1. Its id is not set.
+func ParseDSL(dsl string) (model *openfgav1.AuthorizationModel, err error) {
+	// the parser panics on some syntax errors, from which it cannot recover
+	defer func() {
+		if r := recover(); r != nil {
+			model, err = nil, fmt.Errorf("%w: %v", ErrInvalidDSL, r)
+		}
+	}()
+
+	typeDefinitions, err := parser.Parse(dsl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSL, err)
+	}
+
+	return &openfgav1.AuthorizationModel{
+		SchemaVersion:   SchemaVersion1_1,
+		TypeDefinitions: typeDefinitions,
+	}, nil
+}
+
+// FormatDSL writes the authorization model in the DSL, such that ParseDSL returns an equivalent model. The types are
+// written in the order of the model, and the relations of each type in alphabetical order.
+func FormatDSL(model *openfgav1.AuthorizationModel) (string, error) {
+	if model.GetSchemaVersion() != SchemaVersion1_1 {
+		return "", ErrDSLSchemaVersion
+	}
+
+	var sb strings.Builder
+	for i, typeDefinition := range model.GetTypeDefinitions() {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "type %s\n", typeDefinition.GetType())
+
+		relations := make([]string, 0, len(typeDefinition.GetRelations()))
+		for relation := range typeDefinition.GetRelations() {
+			relations = append(relations, relation)
+		}
+		if len(relations) == 0 {
+			continue
+		}
+		sort.Strings(relations)
+
+		sb.WriteString("  relations\n")
+		for _, relation := range relations {
+			rewrite := typeDefinition.GetRelations()[relation]
+			if err := validateDSLRewrite(rewrite); err != nil {
+				return "", fmt.Errorf("relation '%s#%s': %w", typeDefinition.GetType(), relation, err)
+			}
+
+			fmt.Fprintf(&sb, "    define %s", relation)
+
+			directlyRelatedTypes := typeDefinition.GetMetadata().GetRelations()[relation].GetDirectlyRelatedUserTypes()
+			if len(directlyRelatedTypes) > 0 {
+				refs := make([]string, 0, len(directlyRelatedTypes))
+				for _, ref := range directlyRelatedTypes {
+					refs = append(refs, relationReferenceDSL(ref))
+				}
+				fmt.Fprintf(&sb, ": [%s]", strings.Join(refs, ", "))
+			}
+
+			// the rewrites are grouped by RewriteString, the grouping of the whole rewrite is not needed
+			dsl := RewriteString(rewrite)
+			if isCompoundRewrite(rewrite) {
+				dsl = dsl[1 : len(dsl)-1]
+			}
+			fmt.Fprintf(&sb, " as %s\n", dsl)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+func relationReferenceDSL(ref *openfgav1.RelationReference) string {
+	switch ref.GetRelationOrWildcard().(type) {
+	case *openfgav1.RelationReference_Relation:
+		return ref.GetType() + "#" + ref.GetRelation()
+	case *openfgav1.RelationReference_Wildcard:
+		return ref.GetType() + ":*"
+	}
+
+	return ref.GetType()
+}
+
+func isCompoundRewrite(rewrite *openfgav1.Userset) bool {
+	switch rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_Union, *openfgav1.Userset_Intersection, *openfgav1.Userset_Difference:
+		return true
+	}
+
+	return false
+}
+
+// validateDSLRewrite returns an error if the rewrite, or one of its operands, has no DSL equivalent.
+func validateDSLRewrite(rewrite *openfgav1.Userset) error {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_This, *openfgav1.Userset_ComputedUserset, *openfgav1.Userset_TupleToUserset:
+		return nil
+	case *openfgav1.Userset_Union:
+		return validateDSLRewrites(rw.Union.GetChild())
+	case *openfgav1.Userset_Intersection:
+		return validateDSLRewrites(rw.Intersection.GetChild())
+	case *openfgav1.Userset_Difference:
+		return validateDSLRewrites([]*openfgav1.Userset{rw.Difference.GetBase(), rw.Difference.GetSubtract()})
+	}
+
+	return errors.New("the rewrite is empty")
+}
+
+func validateDSLRewrites(rewrites []*openfgav1.Userset) error {
+	if len(rewrites) == 0 {
+		return errors.New("the rewrite has no operands")
+	}
+
+	for _, rewrite := range rewrites {
+		if err := validateDSLRewrite(rewrite); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
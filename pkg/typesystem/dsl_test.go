@@ -0,0 +1,124 @@
+package typesystem
+
+import (
+	"context"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFormatDSL(t *testing.T) {
+	dsl := `type user
+
+type group
+  relations
+    define member: [user, group#member] as self
+
+type folder
+  relations
+    define viewer: [user, user:*] as self
+
+type document
+  relations
+    define blocked: [user] as self
+    define editor: [user] as self and member from parent
+    define owner: [user] as self
+    define parent: [folder] as self
+    define viewer: [user, group#member] as (self or editor) but not blocked or viewer from parent
+`
+
+	model, err := ParseDSL(dsl)
+	require.NoError(t, err)
+	require.Equal(t, SchemaVersion1_1, model.GetSchemaVersion())
+
+	formatted, err := FormatDSL(model)
+	require.NoError(t, err)
+
+	reparsed, err := ParseDSL(formatted)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(model, reparsed))
+
+	// the formatted DSL is stable
+	formattedAgain, err := FormatDSL(reparsed)
+	require.NoError(t, err)
+	require.Equal(t, formatted, formattedAgain)
+
+	t.Run("single_relation", func(t *testing.T) {
+		formatted, err := FormatDSL(modelFromDSL(`
+		type user
+		type document
+		  relations
+		    define viewer: [user] as self`))
+		require.NoError(t, err)
+		require.Equal(t, "type user\n\ntype document\n  relations\n    define viewer: [user] as self\n", formatted)
+	})
+
+	t.Run("schema_1_0", func(t *testing.T) {
+		_, err := FormatDSL(&openfgav1.AuthorizationModel{SchemaVersion: SchemaVersion1_0})
+		require.ErrorIs(t, err, ErrDSLSchemaVersion)
+	})
+
+	t.Run("empty_rewrite", func(t *testing.T) {
+		_, err := FormatDSL(&openfgav1.AuthorizationModel{
+			SchemaVersion: SchemaVersion1_1,
+			TypeDefinitions: []*openfgav1.TypeDefinition{
+				{Type: "document", Relations: map[string]*openfgav1.Userset{"viewer": {}}},
+			},
+		})
+		require.ErrorContains(t, err, "relation 'document#viewer'")
+	})
+}
+
+func TestParseDSL(t *testing.T) {
+	for _, dsl := range []string{
+		"",
+		"type document relations define",
+		"type document\n  relations\n    define viewer: [user] as",
+	} {
+		_, err := ParseDSL(dsl)
+		require.ErrorIs(t, err, ErrInvalidDSL, dsl)
+	}
+}
+
+func FuzzParseDSL(f *testing.F) {
+	f.Add(`type user
+
+type group
+  relations
+    define member: [user, group#member] as self
+
+type document
+  relations
+    define parent: [group] as self
+    define viewer: [user, user:*, group#member] as self or member from parent but not blocked
+    define blocked: [user] as self
+`)
+	f.Add("type user\n")
+	f.Add("type document\n  relations\n    define viewer as self")
+	f.Add("type document\n  relations\n    define viewer: [user] as (self or")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, dsl string) {
+		model, err := ParseDSL(dsl)
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidDSL)
+			return
+		}
+
+		if _, err := NewAndValidate(context.Background(), model); err != nil {
+			return
+		}
+
+		// the valid models are written back in the DSL and parsed to an equivalent model
+		formatted, err := FormatDSL(model)
+		if err != nil {
+			return
+		}
+
+		reparsed, err := ParseDSL(formatted)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(model, reparsed), formatted)
+	})
+}
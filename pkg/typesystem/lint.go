@@ -0,0 +1,320 @@
+package typesystem
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxRewriteDepth is the default nesting depth of a rewrite above which Lint warns.
+const DefaultMaxRewriteDepth = 5
+
+// Severity is the severity of a Diagnostic. A model with an error diagnostic cannot be written, while a
+// warning only flags a likely mistake or an expensive definition.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// The rules checked by Lint.
+const (
+	// RuleInvalidModel reports a model that is invalid as a whole, e.g. with an unsupported schema version.
+	RuleInvalidModel = "invalid-model"
+
+	// RuleInvalidRelation reports a relation whose definition is invalid for any other reason.
+	RuleInvalidRelation = "invalid-relation"
+
+	// RuleUndefinedType reports a relation referencing a type that is not defined.
+	RuleUndefinedType = "undefined-type"
+
+	// RuleUndefinedRelation reports a relation referencing a relation that is not defined.
+	RuleUndefinedRelation = "undefined-relation"
+
+	// RuleUnreachableRelation reports a relation that no tuple can ever satisfy.
+	RuleUnreachableRelation = "unreachable-relation"
+
+	// RuleCycle reports relations that are defined in terms of each other.
+	RuleCycle = "cycle"
+
+	// RuleRewriteDepth warns about a rewrite nested more deeply than the maximum rewrite depth.
+	RuleRewriteDepth = "rewrite-depth"
+
+	// RuleDuplicateOperand warns about a union, intersection or exclusion with identical operands.
+	RuleDuplicateOperand = "duplicate-operand"
+
+	// RuleUnusedType warns about a type without relations that no relation can be assigned.
+	RuleUnusedType = "unused-type"
+)
+
+// Diagnostic is a problem found by Lint in an authorization model.
+type Diagnostic struct {
+	Rule       string   `json:"rule"`
+	Severity   Severity `json:"severity"`
+	ObjectType string   `json:"object_type,omitempty"`
+	Relation   string   `json:"relation,omitempty"`
+	Message    string   `json:"message"`
+}
+
+// HasErrors returns true if any of the diagnostics is an error.
+func HasErrors(diagnostics []*Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+type linter struct {
+	maxRewriteDepth int
+}
+
+type LintOption func(l *linter)
+
+// WithMaxRewriteDepth sets the nesting depth of a rewrite above which Lint warns. A direct relationship,
+// a computed userset and a tuple to userset have a depth of 1, and every union, intersection or exclusion
+// adds 1 to the depth of its deepest operand.
+func WithMaxRewriteDepth(depth int) LintOption {
+	return func(l *linter) {
+		l.maxRewriteDepth = depth
+	}
+}
+
+// Lint checks the model and returns all the problems found, unlike NewAndValidate which stops at the first
+// one. The model is valid if and only if none of the diagnostics is an error. The diagnostics are sorted by
+// type and relation.
+func Lint(model *openfgav1.AuthorizationModel, opts ...LintOption) []*Diagnostic {
+	l := &linter{maxRewriteDepth: DefaultMaxRewriteDepth}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	t := New(model)
+
+	if !IsSchemaVersionSupported(t.GetSchemaVersion()) {
+		return []*Diagnostic{modelError(ErrInvalidSchemaVersion)}
+	}
+
+	if containsDuplicateType(model) {
+		return []*Diagnostic{modelError(ErrDuplicateTypes)}
+	}
+
+	if err := t.validateNames(); err != nil {
+		return []*Diagnostic{modelError(err)}
+	}
+
+	typeNames := make([]string, 0, len(t.typeDefinitions))
+	for typeName := range t.typeDefinitions {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	var diagnostics []*Diagnostic
+	for _, typeName := range typeNames {
+		relationMap := t.typeDefinitions[typeName].GetRelations()
+
+		relationNames := make([]string, 0, len(relationMap))
+		for relationName := range relationMap {
+			relationNames = append(relationNames, relationName)
+		}
+		sort.Strings(relationNames)
+
+		for _, relationName := range relationNames {
+			if d := undefinedRelatedType(t, typeName, relationName); d != nil {
+				diagnostics = append(diagnostics, d)
+				continue
+			}
+
+			if err := t.validateRelation(typeName, relationName, relationMap); err != nil {
+				diagnostics = append(diagnostics, relationError(typeName, relationName, err))
+				continue
+			}
+
+			rewrite := relationMap[relationName]
+			if depth := RewriteDepth(rewrite); depth > l.maxRewriteDepth {
+				diagnostics = append(diagnostics, &Diagnostic{
+					Rule:       RuleRewriteDepth,
+					Severity:   SeverityWarning,
+					ObjectType: typeName,
+					Relation:   relationName,
+					Message:    fmt.Sprintf("the rewrite is nested %d levels deep, more than %d levels are expensive to evaluate", depth, l.maxRewriteDepth),
+				})
+			}
+
+			if hasDuplicateOperand(rewrite) {
+				diagnostics = append(diagnostics, &Diagnostic{
+					Rule:       RuleDuplicateOperand,
+					Severity:   SeverityWarning,
+					ObjectType: typeName,
+					Relation:   relationName,
+					Message:    "the rewrite has a union, intersection or exclusion with identical operands",
+				})
+			}
+		}
+	}
+
+	if !HasErrors(diagnostics) {
+		// these checks assume that every relation is otherwise valid
+		for _, err := range []error{t.ensureNoCyclesInTupleToUsersetDefinitions(), t.ensureNoCyclesInComputedRewrite()} {
+			if err != nil {
+				diagnostics = append(diagnostics, relationError("", "", err))
+			}
+		}
+	}
+
+	if t.GetSchemaVersion() == SchemaVersion1_1 {
+		diagnostics = append(diagnostics, unusedTypes(t, typeNames)...)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].ObjectType != diagnostics[j].ObjectType {
+			return diagnostics[i].ObjectType < diagnostics[j].ObjectType
+		}
+		return diagnostics[i].Relation < diagnostics[j].Relation
+	})
+
+	return diagnostics
+}
+
+func modelError(err error) *Diagnostic {
+	return &Diagnostic{Rule: RuleInvalidModel, Severity: SeverityError, Message: err.Error()}
+}
+
+// relationError classifies the validation error of a relation.
+func relationError(typeName, relationName string, err error) *Diagnostic {
+	var invalidType *InvalidTypeError
+	if errors.As(err, &invalidType) {
+		typeName = invalidType.ObjectType
+	}
+
+	var invalidRelation *InvalidRelationError
+	if errors.As(err, &invalidRelation) {
+		typeName, relationName = invalidRelation.ObjectType, invalidRelation.Relation
+	}
+
+	rule := RuleInvalidRelation
+	var undefinedType *ObjectTypeUndefinedError
+	var undefinedRelation *RelationUndefinedError
+	switch {
+	case errors.Is(err, ErrNoEntryPointsLoop), errors.Is(err, ErrCycle):
+		rule = RuleCycle
+	case errors.Is(err, ErrNoEntrypoints):
+		rule = RuleUnreachableRelation
+	case errors.As(err, &undefinedType), errors.Is(err, ErrObjectTypeUndefined):
+		rule = RuleUndefinedType
+	case errors.As(err, &undefinedRelation), errors.Is(err, ErrRelationUndefined):
+		rule = RuleUndefinedRelation
+	}
+
+	return &Diagnostic{
+		Rule:       rule,
+		Severity:   SeverityError,
+		ObjectType: typeName,
+		Relation:   relationName,
+		Message:    err.Error(),
+	}
+}
+
+// undefinedRelatedType returns an error if a type restriction of the relation is an undefined type.
+func undefinedRelatedType(t *TypeSystem, typeName, relationName string) *Diagnostic {
+	for _, rr := range t.relations[typeName][relationName].GetTypeInfo().GetDirectlyRelatedUserTypes() {
+		if _, ok := t.typeDefinitions[rr.GetType()]; !ok {
+			return &Diagnostic{
+				Rule:       RuleUndefinedType,
+				Severity:   SeverityError,
+				ObjectType: typeName,
+				Relation:   relationName,
+				Message:    (&ObjectTypeUndefinedError{ObjectType: rr.GetType(), Err: ErrObjectTypeUndefined}).Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// RewriteDepth returns the nesting depth of the rewrite, see WithMaxRewriteDepth.
+func RewriteDepth(rewrite *openfgav1.Userset) int {
+	var children []*openfgav1.Userset
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_Union:
+		children = rw.Union.GetChild()
+	case *openfgav1.Userset_Intersection:
+		children = rw.Intersection.GetChild()
+	case *openfgav1.Userset_Difference:
+		children = []*openfgav1.Userset{rw.Difference.GetBase(), rw.Difference.GetSubtract()}
+	default:
+		return 1
+	}
+
+	deepest := 0
+	for _, child := range children {
+		if depth := RewriteDepth(child); depth > deepest {
+			deepest = depth
+		}
+	}
+
+	return deepest + 1
+}
+
+func hasDuplicateOperand(rewrite *openfgav1.Userset) bool {
+	var children []*openfgav1.Userset
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_Union:
+		children = rw.Union.GetChild()
+	case *openfgav1.Userset_Intersection:
+		children = rw.Intersection.GetChild()
+	case *openfgav1.Userset_Difference:
+		children = []*openfgav1.Userset{rw.Difference.GetBase(), rw.Difference.GetSubtract()}
+	default:
+		return false
+	}
+
+	for i, child := range children {
+		for _, other := range children[i+1:] {
+			if proto.Equal(child, other) {
+				return true
+			}
+		}
+
+		if hasDuplicateOperand(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unusedTypes returns a warning for every type that has no relations and is not a type restriction of any
+// relation.
+func unusedTypes(t *TypeSystem, typeNames []string) []*Diagnostic {
+	referenced := map[string]bool{}
+	for _, relations := range t.relations {
+		for _, relation := range relations {
+			for _, rr := range relation.GetTypeInfo().GetDirectlyRelatedUserTypes() {
+				referenced[rr.GetType()] = true
+			}
+		}
+	}
+
+	var diagnostics []*Diagnostic
+	for _, typeName := range typeNames {
+		if len(t.typeDefinitions[typeName].GetRelations()) > 0 || referenced[typeName] {
+			continue
+		}
+
+		diagnostics = append(diagnostics, &Diagnostic{
+			Rule:       RuleUnusedType,
+			Severity:   SeverityWarning,
+			ObjectType: typeName,
+			Message:    fmt.Sprintf("type '%s' has no relations and cannot be assigned to any relation", typeName),
+		})
+	}
+
+	return diagnostics
+}
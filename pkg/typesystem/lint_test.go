@@ -0,0 +1,92 @@
+package typesystem
+
+import (
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		opts     []LintOption
+		expected []*Diagnostic
+	}{
+		{
+			name: "valid_model",
+			model: `
+			type user
+
+			type document
+			  relations
+			    define editor: [user] as self
+			    define viewer: [user] as self or editor
+			`,
+		},
+		{
+			name: "all_invalid_relations_are_reported",
+			model: `
+			type user
+
+			type document
+			  relations
+			    define editor: [user] as self
+			    define owner as admin
+			    define viewer: [group] as self
+			    define action1 as editor and action2
+			    define action2 as editor and action1
+			`,
+			expected: []*Diagnostic{
+				{Rule: RuleCycle, Severity: SeverityError, ObjectType: "document", Relation: "action1"},
+				{Rule: RuleCycle, Severity: SeverityError, ObjectType: "document", Relation: "action2"},
+				{Rule: RuleUndefinedRelation, Severity: SeverityError, ObjectType: "document", Relation: "owner"},
+				{Rule: RuleUndefinedType, Severity: SeverityError, ObjectType: "document", Relation: "viewer"},
+			},
+		},
+		{
+			name: "warnings",
+			model: `
+			type user
+			type label
+
+			type document
+			  relations
+			    define editor: [user] as self
+			    define viewer as editor or editor
+			    define reader as (editor or viewer) and (editor but not viewer)
+			`,
+			opts: []LintOption{WithMaxRewriteDepth(2)},
+			expected: []*Diagnostic{
+				{Rule: RuleRewriteDepth, Severity: SeverityWarning, ObjectType: "document", Relation: "reader"},
+				{Rule: RuleDuplicateOperand, Severity: SeverityWarning, ObjectType: "document", Relation: "viewer"},
+				{Rule: RuleUnusedType, Severity: SeverityWarning, ObjectType: "label"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diagnostics := Lint(&openfgav1.AuthorizationModel{
+				SchemaVersion:   SchemaVersion1_1,
+				TypeDefinitions: parser.MustParse(test.model),
+			}, test.opts...)
+
+			for _, d := range diagnostics {
+				require.NotEmpty(t, d.Message)
+				d.Message = ""
+			}
+
+			require.Equal(t, test.expected, diagnostics)
+		})
+	}
+
+	t.Run("invalid_schema_version", func(t *testing.T) {
+		diagnostics := Lint(&openfgav1.AuthorizationModel{SchemaVersion: "0.9"})
+		require.Len(t, diagnostics, 1)
+		require.Equal(t, RuleInvalidModel, diagnostics[0].Rule)
+		require.True(t, HasErrors(diagnostics))
+	})
+}
@@ -0,0 +1,180 @@
+package typesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
+)
+
+var ErrInvalidModules = errors.New("invalid authorization model modules")
+
+// Module is a reusable fragment of authorization models, e.g. the core types of role-based access control shared by
+// the models of several products. The models include modules by name, see FlattenModules.
+type Module struct {
+	Name string
+
+	// Includes are the names of the modules that the module itself includes. Its type definitions may reference
+	// their types.
+	Includes []string
+
+	TypeDefinitions []*openfgav1.TypeDefinition
+}
+
+// ParseModule parses a module written in the DSL, see ParseDSL. The DSL may start with lines including other
+// modules, e.g.
+//
+//	include rbac-core
+//
+//	type document
+//	  relations
+//	    define viewer: [user, role#assignee] as self
+func ParseModule(name, dsl string) (*Module, error) {
+	module := &Module{Name: name}
+
+	lines := strings.Split(dsl, "\n")
+
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+
+		included, ok := strings.CutPrefix(line, "include ")
+		if !ok {
+			break
+		}
+		module.Includes = append(module.Includes, strings.TrimSpace(included))
+	}
+
+	rest := strings.Join(lines[i:], "\n")
+	if strings.TrimSpace(rest) == "" {
+		return module, nil
+	}
+
+	model, err := ParseDSL(rest)
+	if err != nil {
+		return nil, fmt.Errorf("module '%s': %w", name, err)
+	}
+	module.TypeDefinitions = model.GetTypeDefinitions()
+
+	return module, nil
+}
+
+// FlattenModules returns the type definitions of the included modules, and of the modules they include in turn,
+// followed by the provided type definitions, along with the ModuleAnnotation of every type included from a module.
+// A module included several times is flattened once. It returns an error wrapping ErrInvalidModules if a module is
+// not defined, includes itself, or defines a type that is defined elsewhere in the model.
+func FlattenModules(typeDefinitions []*openfgav1.TypeDefinition, includes []string, modules map[string]*Module) ([]*openfgav1.TypeDefinition, storage.ModelAnnotations, error) {
+	var flattened []*openfgav1.TypeDefinition
+	annotations := storage.ModelAnnotations{}
+
+	// the modules the types are defined by, "" for the types of the model itself
+	origins := map[string]string{}
+
+	add := func(typeDefinition *openfgav1.TypeDefinition, origin string) error {
+		objectType := typeDefinition.GetType()
+		if other, ok := origins[objectType]; ok {
+			return fmt.Errorf("%w: the type '%s' is defined by both %s and %s", ErrInvalidModules, objectType, describeOrigin(other), describeOrigin(origin))
+		}
+		origins[objectType] = origin
+
+		flattened = append(flattened, typeDefinition)
+		if origin != "" {
+			annotations[objectType] = &storage.TypeAnnotations{Annotations: map[string]string{ModuleAnnotation: origin}}
+		}
+
+		return nil
+	}
+
+	flattenedModules := map[string]struct{}{}
+	including := map[string]struct{}{}
+
+	var include func(name string, path []string) error
+	include = func(name string, path []string) error {
+		if _, ok := flattenedModules[name]; ok {
+			return nil
+		}
+
+		path = append(path, name)
+		if _, ok := including[name]; ok {
+			return fmt.Errorf("%w: the module '%s' includes itself (%s)", ErrInvalidModules, name, strings.Join(path, " -> "))
+		}
+
+		module, ok := modules[name]
+		if !ok {
+			return fmt.Errorf("%w: the module '%s' is not defined", ErrInvalidModules, name)
+		}
+
+		including[name] = struct{}{}
+		for _, included := range module.Includes {
+			if err := include(included, path); err != nil {
+				return err
+			}
+		}
+		delete(including, name)
+		flattenedModules[name] = struct{}{}
+
+		for _, typeDefinition := range module.TypeDefinitions {
+			if err := add(typeDefinition, name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, name := range includes {
+		if err := include(name, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, typeDefinition := range typeDefinitions {
+		if err := add(typeDefinition, ""); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return flattened, annotations, nil
+}
+
+func describeOrigin(origin string) string {
+	if origin == "" {
+		return "the model"
+	}
+
+	return fmt.Sprintf("the module '%s'", origin)
+}
+
+// ValidateModules returns an error if a module cannot be flattened, or if the model made of a module and the modules
+// it includes is invalid.
+func ValidateModules(ctx context.Context, modules map[string]*Module) error {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		typeDefinitions, _, err := FlattenModules(nil, []string{name}, modules)
+		if err != nil {
+			return err
+		}
+
+		_, err = NewAndValidate(ctx, &openfgav1.AuthorizationModel{
+			SchemaVersion:   SchemaVersion1_1,
+			TypeDefinitions: typeDefinitions,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: the module '%s': %v", ErrInvalidModules, name, err)
+		}
+	}
+
+	return nil
+}
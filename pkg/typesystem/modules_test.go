@@ -0,0 +1,142 @@
+package typesystem
+
+import (
+	"context"
+	"testing"
+
+	parser "github.com/craigpastro/openfga-dsl-parser/v2"
+	"github.com/openfga/openfga/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseModule(t *testing.T, name, dsl string) *Module {
+	t.Helper()
+
+	module, err := ParseModule(name, dsl)
+	require.NoError(t, err)
+
+	return module
+}
+
+func TestParseModule(t *testing.T) {
+	module := mustParseModule(t, "docs", `
+	include rbac-core
+	include  users
+
+	type document
+	  relations
+	    define viewer: [user, role#assignee] as self
+	`)
+	require.Equal(t, "docs", module.Name)
+	require.Equal(t, []string{"rbac-core", "users"}, module.Includes)
+	require.Len(t, module.TypeDefinitions, 1)
+	require.Equal(t, "document", module.TypeDefinitions[0].GetType())
+
+	module = mustParseModule(t, "empty", "include users\n")
+	require.Equal(t, []string{"users"}, module.Includes)
+	require.Empty(t, module.TypeDefinitions)
+
+	_, err := ParseModule("invalid", "include users\ntype document\n  relations\n    define viewer: [user] as")
+	require.ErrorIs(t, err, ErrInvalidDSL)
+}
+
+func TestFlattenModules(t *testing.T) {
+	modules := map[string]*Module{
+		"users": mustParseModule(t, "users", `type user`),
+		"rbac-core": mustParseModule(t, "rbac-core", `
+		include users
+
+		type role
+		  relations
+		    define assignee: [user] as self
+		`),
+		"groups": mustParseModule(t, "groups", `
+		include users
+
+		type group
+		  relations
+		    define member: [user] as self
+		`),
+	}
+
+	typeDefinitions, annotations, err := FlattenModules(parser.MustParse(`
+	type document
+	  relations
+	    define viewer: [user, role#assignee, group#member] as self
+	`), []string{"rbac-core", "groups"}, modules)
+	require.NoError(t, err)
+
+	var types []string
+	for _, typeDefinition := range typeDefinitions {
+		types = append(types, typeDefinition.GetType())
+	}
+	// the modules included by both rbac-core and groups are flattened once, before the modules including them
+	require.Equal(t, []string{"user", "role", "group", "document"}, types)
+
+	require.Equal(t, storage.ModelAnnotations{
+		"user":  {Annotations: map[string]string{ModuleAnnotation: "users"}},
+		"role":  {Annotations: map[string]string{ModuleAnnotation: "rbac-core"}},
+		"group": {Annotations: map[string]string{ModuleAnnotation: "groups"}},
+	}, annotations)
+
+	t.Run("undefined_module", func(t *testing.T) {
+		_, _, err := FlattenModules(nil, []string{"billing"}, modules)
+		require.ErrorIs(t, err, ErrInvalidModules)
+		require.ErrorContains(t, err, "the module 'billing' is not defined")
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		cyclic := map[string]*Module{
+			"a": {Name: "a", Includes: []string{"b"}},
+			"b": {Name: "b", Includes: []string{"c"}},
+			"c": {Name: "c", Includes: []string{"a"}},
+		}
+
+		_, _, err := FlattenModules(nil, []string{"a"}, cyclic)
+		require.ErrorIs(t, err, ErrInvalidModules)
+		require.ErrorContains(t, err, "a -> b -> c -> a")
+	})
+
+	t.Run("type_defined_by_the_model", func(t *testing.T) {
+		_, _, err := FlattenModules(parser.MustParse(`type user`), []string{"users"}, modules)
+		require.ErrorIs(t, err, ErrInvalidModules)
+		require.ErrorContains(t, err, "the type 'user' is defined by both the module 'users' and the model")
+	})
+
+	t.Run("type_defined_by_two_modules", func(t *testing.T) {
+		conflicting := map[string]*Module{
+			"users":       modules["users"],
+			"other-users": mustParseModule(t, "other-users", `type user`),
+		}
+
+		_, _, err := FlattenModules(nil, []string{"users", "other-users"}, conflicting)
+		require.ErrorIs(t, err, ErrInvalidModules)
+		require.ErrorContains(t, err, "the type 'user' is defined by both the module 'users' and the module 'other-users'")
+	})
+}
+
+func TestValidateModules(t *testing.T) {
+	ctx := context.Background()
+
+	modules := map[string]*Module{
+		"users": mustParseModule(t, "users", `type user`),
+		"rbac-core": mustParseModule(t, "rbac-core", `
+		include users
+
+		type role
+		  relations
+		    define assignee: [user] as self
+		`),
+	}
+	require.NoError(t, ValidateModules(ctx, modules))
+
+	// the module references a type that it does not include
+	modules["groups"] = mustParseModule(t, "groups", `
+	type group
+	  relations
+	    define member: [user] as self
+	`)
+	err := ValidateModules(ctx, modules)
+	require.ErrorIs(t, err, ErrInvalidModules)
+	require.ErrorContains(t, err, "the module 'groups'")
+}
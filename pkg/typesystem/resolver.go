@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/karlseguin/ccache/v3"
@@ -15,6 +16,10 @@ import (
 
 const (
 	typesystemCacheTTL = 168 * time.Hour // 7 days
+
+	// latestModelRefreshTimeout bounds the background refreshes of the latest models, which are not
+	// bound to the request that triggered them.
+	latestModelRefreshTimeout = 10 * time.Second
 )
 
 // TypesystemResolverFunc is a function that implementations can implement to provide lookup and
@@ -22,71 +27,264 @@ const (
 type TypesystemResolverFunc func(ctx context.Context, storeID, modelID string) (*TypeSystem, error)
 
 // MemoizedTypesystemResolverFunc returns a TypesystemResolverFunc that either fetches the provided authorization
-// model (if provided) or looks up the latest authorization model, and then it constructs a TypeSystem from
+// model (if provided) or looks up the pinned or else the latest authorization model, and then it constructs a TypeSystem from
 // the resolved model. The type-system resolution is memoized so if another lookup of the same model occurs,
 // then the earlier TypeSystem that was constructed will be used.
 //
 // The memoized resolver function is safe for concurrent use.
 func MemoizedTypesystemResolverFunc(datastore storage.AuthorizationModelReadBackend) TypesystemResolverFunc {
+	return NewTypesystemResolver(datastore).Resolve
+}
 
-	lookupGroup := singleflight.Group{}
+// TypesystemResolver resolves the TypeSystems of the authorization models of the stores. The TypeSystems are
+// cached by tenant, store and model ID, since a model never changes once written, see tenancy.StoreKey. The ID of the latest model of every
+// store can be cached too, see WithLatestModelTTL.
+//
+// A TypesystemResolver is safe for concurrent use.
+type TypesystemResolver struct {
+	datastore      storage.AuthorizationModelReadBackend
+	typesystems    *ccache.Cache[*TypeSystem]
+	typesystemTTL  time.Duration
+	latestModels   *ccache.Cache[string]
+	latestModelTTL time.Duration
+	lookupGroup    singleflight.Group
 
-	cache := ccache.New(ccache.Configure[*TypeSystem]())
+	mu sync.Mutex
 
-	return func(ctx context.Context, storeID, modelID string) (*TypeSystem, error) {
-		ctx, span := tracer.Start(ctx, "MemoizedTypesystemResolverFunc")
-		defer span.End()
+	// generation is incremented by every invalidation, so that the latest models read before an
+	// invalidation are not cached after it.
+	generation uint64
+	refreshing map[string]struct{}
+}
 
-		var err error
+type TypesystemResolverOption func(r *TypesystemResolver)
 
-		if modelID != "" {
-			if _, err := ulid.Parse(modelID); err != nil {
-				return nil, ErrModelNotFound
-			}
-		}
+// WithTypesystemCacheTTL sets how long a TypeSystem is cached after it is resolved. Defaults to 7 days.
+func WithTypesystemCacheTTL(ttl time.Duration) TypesystemResolverOption {
+	return func(r *TypesystemResolver) {
+		r.typesystemTTL = ttl
+	}
+}
 
-		if modelID == "" {
-			v, err, _ := lookupGroup.Do(fmt.Sprintf("FindLatestAuthorizationModelID:%s", storeID), func() (interface{}, error) {
-				return datastore.FindLatestAuthorizationModelID(ctx, storeID)
-			})
-			if err != nil {
-				if errors.Is(err, storage.ErrNotFound) {
-					return nil, ErrModelNotFound
-				}
+// WithLatestModelTTL caches the ID of the latest model of every store for the TTL, so that resolving the latest
+// model does not read the datastore. Once the TTL expires, the cached ID is still served for as long again while
+// it is refreshed in the background. The cached ID of a store is dropped by Invalidate, which must be called when
+// a model is written to the store. The models written through other servers are only observed once the cached ID
+// is refreshed. Defaults to 0, which does not cache the latest models.
+func WithLatestModelTTL(ttl time.Duration) TypesystemResolverOption {
+	return func(r *TypesystemResolver) {
+		r.latestModelTTL = ttl
+	}
+}
 
-				return nil, fmt.Errorf("failed to FindLatestAuthorizationModelID: %w", err)
-			}
+func NewTypesystemResolver(datastore storage.AuthorizationModelReadBackend, opts ...TypesystemResolverOption) *TypesystemResolver {
+	r := &TypesystemResolver{
+		datastore:     datastore,
+		typesystems:   ccache.New(ccache.Configure[*TypeSystem]()),
+		typesystemTTL: typesystemCacheTTL,
+		latestModels:  ccache.New(ccache.Configure[string]()),
+		refreshing:    map[string]struct{}{},
+	}
 
-			modelID = v.(string)
-		}
+	for _, opt := range opts {
+		opt(r)
+	}
 
-		key := fmt.Sprintf("%s/%s", storeID, modelID)
+	return r
+}
 
-		item := cache.Get(key)
-		if item != nil {
-			return item.Value(), nil
+// Resolve returns the TypeSystem of the model of the store. If modelID is empty, it returns the TypeSystem of the
+// pinned model of the store, or of the latest model of the store if no model is pinned.
+func (r *TypesystemResolver) Resolve(ctx context.Context, storeID, modelID string) (*TypeSystem, error) {
+	ctx, span := tracer.Start(ctx, "MemoizedTypesystemResolverFunc")
+	defer span.End()
+
+	var err error
+
+	if modelID != "" {
+		if _, err := ulid.Parse(modelID); err != nil {
+			return nil, ErrModelNotFound
 		}
+	}
 
-		v, err, _ := lookupGroup.Do(fmt.Sprintf("ReadAuthorizationModel:%s/%s", storeID, modelID), func() (interface{}, error) {
-			return datastore.ReadAuthorizationModel(ctx, storeID, modelID)
-		})
+	if modelID == "" {
+		modelID, err = r.latestModelID(ctx, storeID)
 		if err != nil {
 			if errors.Is(err, storage.ErrNotFound) {
 				return nil, ErrModelNotFound
 			}
 
-			return nil, fmt.Errorf("failed to ReadAuthorizationModel: %w", err)
+			return nil, err
+		}
+	}
+
+	key := fmt.Sprintf("%s/%s", storage.StoreKey(ctx, storeID), modelID)
+
+	item := r.typesystems.Get(key)
+	if item != nil {
+		return item.Value(), nil
+	}
+
+	v, err, _ := r.lookupGroup.Do("ReadAuthorizationModel:"+key, func() (interface{}, error) {
+		return r.datastore.ReadAuthorizationModel(ctx, storeID, modelID)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrModelNotFound
+		}
+
+		return nil, fmt.Errorf("failed to ReadAuthorizationModel: %w", err)
+	}
+
+	model := v.(*openfgav1.AuthorizationModel)
+
+	typesys, err := NewAndValidate(ctx, model)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+
+	v, err, _ = r.lookupGroup.Do(fmt.Sprintf("ReadAuthorizationModelAnnotations:%s/%s", storeID, modelID), func() (interface{}, error) {
+		return r.datastore.ReadAuthorizationModelAnnotations(ctx, storeID, modelID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ReadAuthorizationModelAnnotations: %w", err)
+	}
+
+	typesys.annotations, _ = v.(storage.ModelAnnotations)
+
+	r.typesystems.Set(key, typesys, r.typesystemTTL)
+
+	return typesys, nil
+}
+
+// Invalidate drops the cached ID of the latest model of the store of the tenant of the context. It must be called
+// when a model is written to the store, pinned or unpinned.
+func (r *TypesystemResolver) Invalidate(ctx context.Context, storeID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.latestModels.Delete(storage.StoreKey(ctx, storeID))
+}
+
+// InvalidateModel drops the cached TypeSystem of the model of the store of the tenant of the context, and the cached
+// ID of the latest model of the store. It must be called when a model is deleted.
+func (r *TypesystemResolver) InvalidateModel(ctx context.Context, storeID, modelID string) {
+	r.typesystems.Delete(fmt.Sprintf("%s/%s", storage.StoreKey(ctx, storeID), modelID))
+	r.Invalidate(ctx, storeID)
+}
+
+// Refresh drops every cached TypeSystem of the store of the tenant of the context and the cached ID of its latest
+// model, or those of every store of every tenant if the store is empty, so that they are read from the datastore
+// again.
+func (r *TypesystemResolver) Refresh(ctx context.Context, storeID string) {
+	if storeID == "" {
+		r.typesystems.Clear()
+	} else {
+		r.typesystems.DeletePrefix(storage.StoreKey(ctx, storeID) + "/")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	if storeID == "" {
+		r.latestModels.Clear()
+	} else {
+		r.latestModels.Delete(storage.StoreKey(ctx, storeID))
+	}
+}
+
+// latestModelID returns the ID of the latest model of the store, from the cache if it is there.
+func (r *TypesystemResolver) latestModelID(ctx context.Context, storeID string) (string, error) {
+	if r.latestModelTTL <= 0 {
+		return r.findLatestModelID(ctx, storeID)
+	}
+
+	item := r.latestModels.Get(storage.StoreKey(ctx, storeID))
+	if item != nil {
+		if !item.Expired() {
+			return item.Value(), nil
 		}
 
-		model := v.(*openfgav1.AuthorizationModel)
+		if time.Since(item.Expires()) < r.latestModelTTL {
+			r.refreshLatestModelID(ctx, storeID)
+			return item.Value(), nil
+		}
+	}
+
+	return r.findLatestModelID(ctx, storeID)
+}
+
+// refreshLatestModelID refreshes the cached ID of the latest model of the store of the tenant of the context in
+// the background, unless it is already being refreshed.
+func (r *TypesystemResolver) refreshLatestModelID(ctx context.Context, storeID string) {
+	key := storage.StoreKey(ctx, storeID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.refreshing[key]; ok {
+		return
+	}
+	r.refreshing[key] = struct{}{}
+
+	// the refresh outlives the request, but must read the datastore of its tenant
+	refreshCtx := storage.WithTenantOf(context.Background(), ctx)
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.refreshing, key)
+			r.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(refreshCtx, latestModelRefreshTimeout)
+		defer cancel()
+
+		// on failure, the stale ID is served until it is refreshed by a request
+		_, _ = r.findLatestModelID(ctx, storeID)
+	}()
+}
 
-		typesys, err := NewAndValidate(ctx, model)
+// findLatestModelID reads the ID of the model of the store used when no model ID is given from the datastore, which
+// is the pinned model of the store or else its latest model, and caches it.
+func (r *TypesystemResolver) findLatestModelID(ctx context.Context, storeID string) (string, error) {
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	key := storage.StoreKey(ctx, storeID)
+
+	v, err, _ := r.lookupGroup.Do("FindLatestAuthorizationModelID:"+key, func() (interface{}, error) {
+		pinnedModelID, err := r.datastore.ReadPinnedAuthorizationModelID(ctx, storeID)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+			return "", fmt.Errorf("failed to ReadPinnedAuthorizationModelID: %w", err)
 		}
 
-		cache.Set(key, typesys, typesystemCacheTTL)
+		if pinnedModelID != "" {
+			return pinnedModelID, nil
+		}
+
+		modelID, err := r.datastore.FindLatestAuthorizationModelID(ctx, storeID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return "", fmt.Errorf("failed to FindLatestAuthorizationModelID: %w", err)
+		}
 
-		return typesys, nil
+		return modelID, err
+	})
+	if err != nil {
+		return "", err
 	}
+
+	modelID := v.(string)
+	if r.latestModelTTL > 0 {
+		r.mu.Lock()
+		if r.generation == generation {
+			r.latestModels.Set(key, modelID, r.latestModelTTL)
+		}
+		r.mu.Unlock()
+	}
+
+	return modelID, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/oklog/ulid/v2"
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
 	mockstorage "github.com/openfga/openfga/internal/mocks"
+	"github.com/openfga/openfga/pkg/storage"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,6 +21,10 @@ func TestMemoizedTypesystemResolverFunc(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	storeID := ulid.Make().String()
 	modelID1 := ulid.Make().String()
@@ -41,6 +46,10 @@ func TestMemoizedTypesystemResolverFunc(t *testing.T) {
 				TypeDefinitions: typedefs,
 			}, nil),
 
+		mockDatastore.EXPECT().
+			ReadPinnedAuthorizationModelID(gomock.Any(), storeID).
+			Return("", nil),
+
 		mockDatastore.EXPECT().
 			FindLatestAuthorizationModelID(gomock.Any(), storeID).
 			Return(modelID2, nil),
@@ -83,11 +92,19 @@ func TestSingleFlightMemoizedTypesystemResolverFunc(t *testing.T) {
 	defer mockController.Finish()
 
 	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
 
 	storeID := ulid.Make().String()
 	modelID := ulid.Make().String()
 
 	gomock.InOrder(
+		mockDatastore.EXPECT().
+			ReadPinnedAuthorizationModelID(gomock.Any(), storeID).
+			Return("", nil),
+
 		mockDatastore.EXPECT().
 			FindLatestAuthorizationModelID(gomock.Any(), storeID).
 			DoAndReturn(func(ctx context.Context, storeID string) (string, error) {
@@ -122,3 +139,180 @@ func TestSingleFlightMemoizedTypesystemResolverFunc(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestTypesystemResolverLatestModelCache(t *testing.T) {
+	ctx := context.Background()
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storeID := ulid.Make().String()
+	modelID1 := ulid.Make().String()
+	modelID2 := ulid.Make().String()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string, modelID string) (*openfgav1.AuthorizationModel, error) {
+			return &openfgav1.AuthorizationModel{Id: modelID, SchemaVersion: SchemaVersion1_1}, nil
+		})
+
+	mockDatastore.EXPECT().
+		ReadPinnedAuthorizationModelID(gomock.Any(), storeID).
+		AnyTimes().
+		Return("", nil)
+
+	var mu sync.Mutex
+	latestModelID, lookups := modelID1, 0
+	mockDatastore.EXPECT().
+		FindLatestAuthorizationModelID(gomock.Any(), storeID).
+		AnyTimes().
+		DoAndReturn(func(context.Context, string) (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			lookups++
+			return latestModelID, nil
+		})
+	setLatestModelID := func(modelID string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		latestModelID = modelID
+	}
+	getLookups := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return lookups
+	}
+
+	resolver := NewTypesystemResolver(mockDatastore, WithLatestModelTTL(time.Second))
+
+	resolveLatest := func() string {
+		typesys, err := resolver.Resolve(ctx, storeID, "")
+		require.NoError(t, err)
+		return typesys.GetAuthorizationModelID()
+	}
+
+	require.Equal(t, modelID1, resolveLatest())
+	require.Equal(t, modelID1, resolveLatest())
+	require.Equal(t, 1, getLookups())
+
+	// a model written through the server invalidates the cached id
+	setLatestModelID(modelID2)
+	resolver.Invalidate(ctx, storeID)
+	require.Equal(t, modelID2, resolveLatest())
+	require.Equal(t, 2, getLookups())
+
+	// a refresh of the store, or of every store, drops the cached id too
+	setLatestModelID(modelID1)
+	resolver.Refresh(ctx, storeID)
+	require.Equal(t, modelID1, resolveLatest())
+	require.Equal(t, 3, getLookups())
+
+	setLatestModelID(modelID2)
+	resolver.Refresh(ctx, "")
+	require.Equal(t, modelID2, resolveLatest())
+	require.Equal(t, 4, getLookups())
+
+	// once the TTL expires, the stale id is served while it is refreshed in the background
+	setLatestModelID(modelID1)
+	time.Sleep(1200 * time.Millisecond)
+	require.Equal(t, modelID2, resolveLatest())
+	require.Eventually(t, func() bool { return resolveLatest() == modelID1 }, time.Second, 10*time.Millisecond)
+
+	// an id staler than twice the TTL is not served
+	setLatestModelID(modelID2)
+	time.Sleep(2100 * time.Millisecond)
+	require.Equal(t, modelID2, resolveLatest())
+}
+
+func TestTypesystemResolverTenants(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	storeID := ulid.Make().String()
+	acmeModelID := ulid.Make().String()
+	globexModelID := ulid.Make().String()
+
+	// the stores of the tenants have the same id, but are in different datastores
+	latestModelIDs := map[string]string{"acme": acmeModelID, "globex": globexModelID}
+	tenantOf := func(ctx context.Context) string {
+		tenant, _ := storage.TenantFromContext(ctx)
+		return tenant
+	}
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string, modelID string) (*openfgav1.AuthorizationModel, error) {
+			return &openfgav1.AuthorizationModel{Id: modelID, SchemaVersion: SchemaVersion1_1}, nil
+		})
+	mockDatastore.EXPECT().
+		ReadPinnedAuthorizationModelID(gomock.Any(), storeID).
+		AnyTimes().
+		Return("", nil)
+	mockDatastore.EXPECT().
+		FindLatestAuthorizationModelID(gomock.Any(), storeID).
+		AnyTimes().
+		DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+			return latestModelIDs[tenantOf(ctx)], nil
+		})
+
+	resolver := NewTypesystemResolver(mockDatastore, WithLatestModelTTL(time.Minute))
+
+	for tenant, modelID := range latestModelIDs {
+		typesys, err := resolver.Resolve(storage.ContextWithTenant(context.Background(), tenant), storeID, "")
+		require.NoError(t, err)
+		require.Equal(t, modelID, typesys.GetAuthorizationModelID())
+	}
+}
+
+func TestTypesystemResolverPinnedModel(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	ctx := context.Background()
+	storeID := ulid.Make().String()
+	pinnedModelID := ulid.Make().String()
+	latestModelID := ulid.Make().String()
+
+	mockDatastore := mockstorage.NewMockOpenFGADatastore(mockController)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModelAnnotations(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(nil, nil)
+	mockDatastore.EXPECT().
+		ReadAuthorizationModel(gomock.Any(), storeID, gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, _ string, modelID string) (*openfgav1.AuthorizationModel, error) {
+			return &openfgav1.AuthorizationModel{Id: modelID, SchemaVersion: SchemaVersion1_1}, nil
+		})
+	mockDatastore.EXPECT().
+		ReadPinnedAuthorizationModelID(gomock.Any(), storeID).
+		AnyTimes().
+		Return(pinnedModelID, nil)
+
+	resolver := NewTypesystemResolver(mockDatastore)
+
+	// the pinned model is resolved when no model id is given, without looking up the latest model
+	typesys, err := resolver.Resolve(ctx, storeID, "")
+	require.NoError(t, err)
+	require.Equal(t, pinnedModelID, typesys.GetAuthorizationModelID())
+
+	// the other models are still resolved by id
+	typesys, err = resolver.Resolve(ctx, storeID, latestModelID)
+	require.NoError(t, err)
+	require.Equal(t, latestModelID, typesys.GetAuthorizationModelID())
+}
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 
 	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/storage"
 	"github.com/openfga/openfga/pkg/tuple"
 	"go.opentelemetry.io/otel"
 	"golang.org/x/exp/maps"
@@ -151,6 +153,8 @@ type TypeSystem struct {
 	relations     map[string]map[string]*openfgav1.Relation
 	modelID       string
 	schemaVersion string
+	// [objectType] => annotations of the type and its relations
+	annotations storage.ModelAnnotations
 }
 
 // New creates a *TypeSystem from an *openfgav1.AuthorizationModel.
@@ -189,6 +193,15 @@ func New(model *openfgav1.AuthorizationModel) *TypeSystem {
 	}
 }
 
+// NewWithAnnotations is like New, but the TypeSystem also exposes the annotations of the types and relations of
+// the model, see GetAnnotations. It assumes that the annotations are valid, see ValidateAnnotations.
+func NewWithAnnotations(model *openfgav1.AuthorizationModel, annotations storage.ModelAnnotations) *TypeSystem {
+	t := New(model)
+	t.annotations = annotations
+
+	return t
+}
+
 // GetAuthorizationModelID returns the id for the authorization model this
 // TypeSystem was constructed for.
 func (t *TypeSystem) GetAuthorizationModelID() string {
@@ -1100,33 +1113,122 @@ func (t *TypeSystem) ensureNoCyclesInTupleToUsersetDefinitions() error {
 	return nil
 }
 
-// ensureNoCyclesInComputedRewrite throws an error on the following model because `folder` type is a cycle.
+// ensureNoCyclesInComputedRewrite throws an error on the following model because the relations of `folder` are
+// defined in terms of each other through computed usersets without an entrypoint, so that no relationship tuple can
+// ever relate a user to them.
 //
 //	 type folder
 //		 relations
 //		  define parent as child
 //		  define child as parent
+//
+// The cycles with an entrypoint are allowed, e.g.
+//
+//	 type folder
+//		 relations
+//		  define viewer: [user] or editor
+//		  define editor: viewer
+//
+// as are the cycles through tuple to usersets or usersets of the type restrictions, since they change the object
+// under evaluation and so are bounded by the relationship tuples.
 func (t *TypeSystem) ensureNoCyclesInComputedRewrite() error {
-	for objectType := range t.typeDefinitions {
-		relations, err := t.GetRelations(objectType)
-		if err == nil {
-			for sourceRelationName, relation := range relations {
-				switch source := relation.GetRewrite().Userset.(type) {
-				case *openfgav1.Userset_ComputedUserset:
-					target := source.ComputedUserset.GetRelation()
-					targetRelation, err := t.GetRelation(objectType, target)
-					if err == nil {
-						switch rewrite := targetRelation.GetRewrite().Userset.(type) {
-						case *openfgav1.Userset_ComputedUserset:
-							if rewrite.ComputedUserset.GetRelation() == sourceRelationName {
-								return &InvalidTypeError{ObjectType: objectType, Cause: ErrCycle}
-							}
-						}
-					}
+	objectTypes := make([]string, 0, len(t.relations))
+	for objectType := range t.relations {
+		objectTypes = append(objectTypes, objectType)
+	}
+
+	// range over the types and relations in sorted order to produce a deterministic outcome
+	sort.Strings(objectTypes)
+
+	for _, objectType := range objectTypes {
+		relations := t.relations[objectType]
+
+		relationNames := make([]string, 0, len(relations))
+		for relationName := range relations {
+			relationNames = append(relationNames, relationName)
+		}
+		sort.Strings(relationNames)
+
+		// relations which have been fully explored are done, while the relations of the current path are on it. The
+		// relations with an entrypoint are done from the start, since a cycle through them has the same entrypoint.
+		done := map[string]struct{}{}
+		for _, relationName := range relationNames {
+			ok, _, err := hasEntrypoints(t.relations, objectType, relationName, relations[relationName].GetRewrite(), map[string]map[string]struct{}{})
+			if err == nil && ok {
+				done[relationName] = struct{}{}
+			}
+		}
+
+		for _, relationName := range relationNames {
+			if cycle := findComputedCycle(relations, relationName, nil, map[string]int{}, done); cycle != nil {
+				return &InvalidRelationError{
+					ObjectType: objectType,
+					Relation:   cycle[0],
+					Cause:      fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> ")),
 				}
 			}
 		}
+	}
+
+	return nil
+}
+
+// findComputedCycle walks the computed usersets of the relation depth first and returns the first cycle found,
+// as the relations of the cycle ending with its first relation, or nil if there is none.
+func findComputedCycle(
+	relations map[string]*openfgav1.Relation,
+	relationName string,
+	path []string,
+	onPath map[string]int,
+	done map[string]struct{},
+) []string {
+	if i, ok := onPath[relationName]; ok {
+		return append(append([]string{}, path[i:]...), relationName)
+	}
+
+	if _, ok := done[relationName]; ok {
+		return nil
+	}
+
+	onPath[relationName] = len(path)
+	path = append(path, relationName)
+
+	for _, computedRelationName := range computedRelations(relations[relationName].GetRewrite()) {
+		if _, ok := relations[computedRelationName]; !ok {
+			continue
+		}
 
+		if cycle := findComputedCycle(relations, computedRelationName, path, onPath, done); cycle != nil {
+			return cycle
+		}
+	}
+
+	delete(onPath, relationName)
+	done[relationName] = struct{}{}
+
+	return nil
+}
+
+// computedRelations returns the relations of the computed usersets of the rewrite, including the ones nested in
+// unions, intersections and exclusions.
+func computedRelations(rewrite *openfgav1.Userset) []string {
+	switch rw := rewrite.GetUserset().(type) {
+	case *openfgav1.Userset_ComputedUserset:
+		return []string{rw.ComputedUserset.GetRelation()}
+	case *openfgav1.Userset_Union:
+		var res []string
+		for _, child := range rw.Union.GetChild() {
+			res = append(res, computedRelations(child)...)
+		}
+		return res
+	case *openfgav1.Userset_Intersection:
+		var res []string
+		for _, child := range rw.Intersection.GetChild() {
+			res = append(res, computedRelations(child)...)
+		}
+		return res
+	case *openfgav1.Userset_Difference:
+		return append(computedRelations(rw.Difference.GetBase()), computedRelations(rw.Difference.GetSubtract())...)
 	}
 
 	return nil
@@ -132,6 +132,42 @@ func TestNewAndValidate(t *testing.T) {
 			    define viewer: [document#viewer] as self or editor
 			`,
 		},
+		{
+			name: "computed_userset_cycle_with_entrypoint",
+			model: `
+			type user
+
+			type document
+			  relations
+			    define viewer: [user] as self or editor
+			    define editor as viewer
+			`,
+		},
+		{
+			name: "computed_userset_cycle_through_set_operations",
+			model: `
+			type user
+
+			type document
+			  relations
+			    define admin: [user] as self
+			    define owner: [user] as self or can_delete
+			    define editor as admin or owner
+			    define can_delete as editor but not viewer
+			    define viewer: [user] as self
+			`,
+		},
+		{
+			name: "tuple_to_userset_cycle_with_entrypoint",
+			model: `
+			type user
+
+			type folder
+			  relations
+			    define parent: [folder] as self
+			    define viewer: [user] as self or viewer from parent
+			`,
+		},
 	}
 
 	for _, test := range tests {
@@ -145,6 +181,46 @@ func TestNewAndValidate(t *testing.T) {
 	}
 }
 
+func TestEnsureNoCyclesInComputedRewrite(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		expectedError error
+	}{
+		{
+			name: "cycle_without_entrypoint",
+			model: `
+			type folder
+			  relations
+			    define parent as child
+			    define child as parent
+			`,
+			expectedError: ErrCycle,
+		},
+		{
+			name: "cycle_with_entrypoint",
+			model: `
+			type user
+
+			type folder
+			  relations
+			    define viewer: [user] as self or editor
+			    define editor as viewer
+			`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typesys := New(&openfgav1.AuthorizationModel{
+				SchemaVersion:   SchemaVersion1_1,
+				TypeDefinitions: parser.MustParse(test.model),
+			})
+			require.ErrorIs(t, typesys.ensureNoCyclesInComputedRewrite(), test.expectedError)
+		})
+	}
+}
+
 func TestSuccessfulRewriteValidations(t *testing.T) {
 	var tests = []struct {
 		name  string
@@ -0,0 +1,130 @@
+package writehook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// Webhook admits the writes by POSTing them as JSON to an HTTP endpoint, see Write. The endpoint responds with a JSON
+// object: `{"allowed": true}` admits the write as is, `{"allowed": true, "deletes": [...], "writes": [...]}` admits
+// the write with the tuples of the response instead, and `{"allowed": false, "reason": "..."}` rejects it. Any
+// response status other than 2xx is treated as a failure of the endpoint, which fails the write unless the webhook
+// fails open.
+type Webhook struct {
+	url      string
+	client   *http.Client
+	failOpen bool
+}
+
+var _ Hook = (*Webhook)(nil)
+
+type WebhookOption func(w *Webhook)
+
+// WithWebhookTimeout sets how long the endpoint has to respond. Defaults to 5s.
+func WithWebhookTimeout(timeout time.Duration) WebhookOption {
+	return func(w *Webhook) {
+		w.client.Timeout = timeout
+	}
+}
+
+// WithWebhookFailOpen admits the writes as is when the endpoint fails or cannot be reached, instead of failing them.
+func WithWebhookFailOpen(failOpen bool) WebhookOption {
+	return func(w *Webhook) {
+		w.failOpen = failOpen
+	}
+}
+
+func NewWebhook(url string, opts ...WebhookOption) *Webhook {
+	w := &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// webhookResponse is the response of the endpoint of a Webhook. If either MutatedDeletes or MutatedWrites is set, they
+// replace both the deletes and the writes of the write.
+type webhookResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+
+	MutatedDeletes json.RawMessage `json:"deletes"`
+	MutatedWrites  json.RawMessage `json:"writes"`
+}
+
+func (w *Webhook) Admit(ctx context.Context, write *Write) (*Write, error) {
+	resp, err := w.call(ctx, write)
+	if err != nil {
+		if w.failOpen {
+			return write, nil
+		}
+
+		return nil, err
+	}
+
+	if !resp.Allowed {
+		return nil, Reject(resp.Reason)
+	}
+
+	if resp.MutatedDeletes == nil && resp.MutatedWrites == nil {
+		return write, nil
+	}
+
+	mutated := &Write{
+		StoreID:              write.StoreID,
+		AuthorizationModelID: write.AuthorizationModelID,
+	}
+	if resp.MutatedDeletes != nil {
+		if err := json.Unmarshal(resp.MutatedDeletes, &mutated.Deletes); err != nil {
+			return nil, fmt.Errorf("invalid deletes in the response of the write webhook: %w", err)
+		}
+	}
+	if resp.MutatedWrites != nil {
+		if err := json.Unmarshal(resp.MutatedWrites, &mutated.Writes); err != nil {
+			return nil, fmt.Errorf("invalid writes in the response of the write webhook: %w", err)
+		}
+	}
+
+	return mutated, nil
+}
+
+func (w *Webhook) call(ctx context.Context, write *Write) (*webhookResponse, error) {
+	body, err := json.Marshal(write)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("write webhook responded with status %d", resp.StatusCode)
+	}
+
+	var decoded webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("invalid response of the write webhook: %w", err)
+	}
+
+	return &decoded, nil
+}
@@ -0,0 +1,66 @@
+// Package writehook contains the hooks that admit the writes of tuples before they are committed. A hook can reject a
+// write, e.g. to block the writes to protected relations, or mutate it, e.g. to normalize the ids of the objects.
+package writehook
+
+import (
+	"context"
+	"fmt"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+)
+
+// Write is a write of tuples submitted to the hooks.
+type Write struct {
+	StoreID              string                `json:"store_id"`
+	AuthorizationModelID string                `json:"authorization_model_id"`
+	Deletes              []*openfgav1.TupleKey `json:"deletes"`
+	Writes               []*openfgav1.TupleKey `json:"writes"`
+}
+
+// Hook admits the writes of tuples before they are committed.
+type Hook interface {
+	// Admit returns the write to commit, which is either the write or a mutated copy of it, or a *RejectedError if
+	// the write must not be committed. Any other error fails the write. The tuples of the returned write are
+	// validated against the authorization model like those of the original write.
+	Admit(ctx context.Context, write *Write) (*Write, error)
+}
+
+// HookFunc is an adapter to use a function as a Hook.
+type HookFunc func(ctx context.Context, write *Write) (*Write, error)
+
+func (f HookFunc) Admit(ctx context.Context, write *Write) (*Write, error) {
+	return f(ctx, write)
+}
+
+// RejectedError is the error of a hook rejecting a write.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("the write was rejected: %s", e.Reason)
+}
+
+// Reject returns the error of a hook rejecting a write for the reason.
+func Reject(reason string) error {
+	return &RejectedError{Reason: reason}
+}
+
+type chain []Hook
+
+// Chain returns the hook admitting the writes with every hook in turn, each hook receiving the write returned by the
+// previous one. A write rejected by a hook is not submitted to the next hooks.
+func Chain(hooks ...Hook) Hook {
+	return chain(hooks)
+}
+
+func (c chain) Admit(ctx context.Context, write *Write) (*Write, error) {
+	for _, hook := range c {
+		var err error
+		if write, err = hook.Admit(ctx, write); err != nil {
+			return nil, err
+		}
+	}
+
+	return write, nil
+}
@@ -0,0 +1,105 @@
+package writehook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openfgav1 "github.com/openfga/api/proto/openfga/v1"
+	"github.com/openfga/openfga/pkg/tuple"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+
+	var calls []string
+	hook := func(name string, reject bool) Hook {
+		return HookFunc(func(ctx context.Context, write *Write) (*Write, error) {
+			calls = append(calls, name)
+			if reject {
+				return nil, Reject(name)
+			}
+
+			mutated := *write
+			mutated.Writes = append(mutated.Writes, tuple.NewTupleKey("document:"+name, "viewer", "user:jon"))
+			return &mutated, nil
+		})
+	}
+
+	admitted, err := Chain(hook("a", false), hook("b", false)).Admit(ctx, &Write{StoreID: "store"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, calls)
+	require.Len(t, admitted.Writes, 2)
+	require.Equal(t, "document:b", admitted.Writes[1].GetObject())
+
+	calls = nil
+	_, err = Chain(hook("a", true), hook("b", false)).Admit(ctx, &Write{StoreID: "store"})
+	var rejected *RejectedError
+	require.ErrorAs(t, err, &rejected)
+	require.Equal(t, "a", rejected.Reason)
+	require.Equal(t, []string{"a"}, calls)
+}
+
+func TestWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	write := &Write{
+		StoreID: "store",
+		Writes:  []*openfgav1.TupleKey{tuple.NewTupleKey("document:README", "viewer", "user:jon")},
+	}
+
+	newServer := func(t *testing.T, status int, response string) string {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var received Write
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			require.Equal(t, "store", received.StoreID)
+			require.Equal(t, "document:README", received.Writes[0].GetObject())
+
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(response))
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv.URL
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		admitted, err := NewWebhook(newServer(t, http.StatusOK, `{"allowed": true}`)).Admit(ctx, write)
+		require.NoError(t, err)
+		require.Equal(t, write, admitted)
+	})
+
+	t.Run("mutated", func(t *testing.T) {
+		response := `{"allowed": true, "writes": [{"object": "document:readme", "relation": "viewer", "user": "user:jon"}]}`
+		admitted, err := NewWebhook(newServer(t, http.StatusOK, response)).Admit(ctx, write)
+		require.NoError(t, err)
+		require.Empty(t, admitted.Deletes)
+		require.Len(t, admitted.Writes, 1)
+		require.Equal(t, "document:readme", admitted.Writes[0].GetObject())
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		_, err := NewWebhook(newServer(t, http.StatusOK, `{"allowed": false, "reason": "the ids must be lowercase"}`)).Admit(ctx, write)
+		var rejected *RejectedError
+		require.ErrorAs(t, err, &rejected)
+		require.Equal(t, "the ids must be lowercase", rejected.Reason)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		url := newServer(t, http.StatusInternalServerError, "")
+
+		_, err := NewWebhook(url).Admit(ctx, write)
+		require.Error(t, err)
+
+		var rejected *RejectedError
+		require.False(t, errors.As(err, &rejected))
+
+		admitted, err := NewWebhook(url, WithWebhookFailOpen(true)).Admit(ctx, write)
+		require.NoError(t, err)
+		require.Equal(t, write, admitted)
+	})
+}
@@ -107,6 +107,13 @@ func testBadAuthModelID(t *testing.T, client ClientInterface) {
 	})
 
 	require.ErrorIs(t, err, serverErrors.AuthorizationModelNotFound(badModelID))
+
+	// the status of the error carries its structured description as details
+	require.Equal(t, &serverErrors.ErrorDetails{
+		Code:   int32(openfgav1.ErrorCode_authorization_model_not_found),
+		Reason: "authorization_model_not_found",
+		Field:  "authorization_model_id",
+	}, serverErrors.Describe(err))
 }
 
 func runSchema1_1CheckTests(t *testing.T, client ClientInterface) {